@@ -20,6 +20,7 @@ SPDX-License-Identifier: Apache-2.0
 //
 //     Produces:
 //     - application/json
+//     - application/problem+json
 //
 // swagger:meta
 package main
@@ -47,7 +48,15 @@ func main() {
 		logger.Fatalf(err.Error())
 	}
 
+	devCmd, err := startcmd.DevCmd(&startcmd.HTTPServer{})
+	if err != nil {
+		logger.Fatalf(err.Error())
+	}
+
 	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(devCmd)
+	rootCmd.AddCommand(startcmd.MigrateCmd())
+	rootCmd.AddCommand(startcmd.SignerCmd(&startcmd.HTTPServer{}))
 
 	if err := rootCmd.Execute(); err != nil {
 		logger.Fatalf("failed to run vct: %v", err)
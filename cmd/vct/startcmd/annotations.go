@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+)
+
+const (
+	auditorKeysFlagName  = "auditor-keys"
+	auditorKeysFlagUsage = "Comma-separated list of the auditors allowed to annotate entries" +
+		" <auditor>@<base64 public key>, e.g. fraud-desk@<key>. The annotations must be signed by the key of" +
+		" the auditor, entries can't be annotated if not set." +
+		" Alternatively, this can be set with the following environment variable: " + auditorKeysEnvKey
+	auditorKeysEnvKey = envPrefix + "AUDITOR_KEYS"
+
+	annotationSubscribersFlagName  = "annotation-subscribers"
+	annotationSubscribersFlagUsage = "Comma-separated list of the URLs every added annotation is posted to." +
+		" Alternatively, this can be set with the following environment variable: " + annotationSubscribersEnvKey
+	annotationSubscribersEnvKey = envPrefix + "ANNOTATION_SUBSCRIBERS"
+)
+
+type annotationParameters struct {
+	auditors    map[string][]byte
+	subscribers []string
+}
+
+// getAnnotations returns the public keys of the auditors annotating entries and the subscribers of the annotations.
+func getAnnotations(cmd *cobra.Command) (*annotationParameters, error) {
+	const auditorParts = 2
+
+	params := &annotationParameters{auditors: map[string][]byte{}}
+
+	if keysStr := cmdutils.GetUserSetOptionalVarFromString(cmd, auditorKeysFlagName,
+		auditorKeysEnvKey); keysStr != "" {
+		for _, val := range strings.Split(keysStr, ",") {
+			parts := strings.SplitN(strings.TrimSpace(val), "@", auditorParts)
+			if len(parts) != auditorParts || parts[0] == "" {
+				return nil, fmt.Errorf("invalid auditor key %q, format must be <auditor>@<public key>", val)
+			}
+
+			pubKey, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("public key of auditor %q is not base64: %w", parts[0], err)
+			}
+
+			params.auditors[parts[0]] = pubKey
+		}
+	}
+
+	if subscribersStr := cmdutils.GetUserSetOptionalVarFromString(cmd, annotationSubscribersFlagName,
+		annotationSubscribersEnvKey); subscribersStr != "" {
+		for _, subscriber := range strings.Split(subscribersStr, ",") {
+			u, err := url.Parse(strings.TrimSpace(subscriber))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("annotation subscriber %q must be an absolute http(s) URL", subscriber)
+			}
+
+			params.subscribers = append(params.subscribers, u.String())
+		}
+	}
+
+	return params, nil
+}
+
+func createAnnotationFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(auditorKeysFlagName, "", auditorKeysFlagUsage)
+	startCmd.Flags().String(annotationSubscribersFlagName, "", annotationSubscribersFlagUsage)
+}
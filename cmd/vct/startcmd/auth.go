@@ -0,0 +1,105 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/cobra"
+
+	"github.com/trustbloc/vct/pkg/controller/auth"
+)
+
+const (
+	readTokenFlagName  = "api-read-token"
+	readTokenFlagUsage = "Check for bearer token in the authorization header (optional). " +
+		" Alternatively, this can be set with the following environment variable: " + readTokenEnvKey
+	readTokenEnvKey = envPrefix + "API_READ_TOKEN"
+
+	writeTokenFlagName  = "api-write-token"
+	writeTokenFlagUsage = "Check for bearer token in the authorization header (optional). " +
+		" Alternatively, this can be set with the following environment variable: " + writeTokenEnvKey
+	writeTokenEnvKey = envPrefix + "API_WRITE_TOKEN"
+
+	authRolesFlagName  = "auth-roles"
+	authRolesFlagUsage = "Comma-separated list of role bindings granting the roles reader, submitter, auditor" +
+		" and admin to API keys (bearer tokens), OAuth scopes or mTLS identities (subject common names of" +
+		" verified client certificates). The read token is bound to the reader role and the write token to the" +
+		" submitter, auditor and admin roles. Endpoints of a role bound to nobody are forbidden; with the" +
+		" tokens only, the read and write endpoints are open if their token is not set." +
+		" Format must be <role>:<token|scope|mtls>:<value>. Examples: submitter:token:secret,admin:mtls:operator" +
+		" Alternatively, this can be set with the following environment variable: " + authRolesEnvKey
+	authRolesEnvKey = envPrefix + "AUTH_ROLES"
+
+	authScopesHeaderFlagName  = "auth-scopes-header"
+	authScopesHeaderFlagUsage = "Header with the space-separated OAuth scopes of the request, set by a trusted" +
+		" gateway which validates access tokens. Required for scope role bindings." +
+		" Alternatively, this can be set with the following environment variable: " + authScopesHeaderEnvKey
+	authScopesHeaderEnvKey = envPrefix + "AUTH_SCOPES_HEADER"
+)
+
+// getAuthBindings returns the role bindings of the API keys, the OAuth scopes and the mTLS identities.
+func getAuthBindings(cmd *cobra.Command) ([]auth.Binding, error) {
+	var bindings []auth.Binding
+
+	for _, raw := range getList(cmd, authRolesFlagName, authRolesEnvKey) {
+		binding, err := auth.ParseBinding(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("parse auth roles: %w", err)
+		}
+
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+// authorize authorizes the requests of the router with the role bindings and the legacy read and write tokens.
+func authorize(router *mux.Router, parameters *agentParameters) {
+	bindings := append(auth.LegacyBindings(parameters.readToken, parameters.writeToken), parameters.authBindings...)
+
+	if len(bindings) == 0 {
+		return
+	}
+
+	opts := []auth.Opt{auth.WithScopesHeader(parameters.authScopesHeader)}
+
+	// only the legacy tokens leave the read and write endpoints open
+	if len(parameters.authBindings) == 0 {
+		opts = append(opts, auth.WithOpenRoles())
+	}
+
+	authorizer := auth.New(bindings, opts...)
+
+	if len(parameters.authBindings) > 0 && len(authorizer.UnboundRoles()) > 0 {
+		logger.Warnf("Roles %v are bound to nobody, their endpoints are forbidden", authorizer.UnboundRoles())
+	}
+
+	router.Use(authorizer.Middleware())
+}
+
+// ValidateAuthorizationBearerToken validate token.
+func ValidateAuthorizationBearerToken(w http.ResponseWriter, r *http.Request, readToken, writeToken string) bool {
+	if status := auth.New(auth.LegacyBindings(readToken, writeToken), auth.WithOpenRoles()).Authorize(r); status != 0 {
+		w.WriteHeader(status)
+		w.Write([]byte("Unauthorised.\n")) // nolint:gosec,errcheck
+
+		return false
+	}
+
+	return true
+}
+
+func createAuthFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(readTokenFlagName, "", readTokenFlagUsage)
+	startCmd.Flags().String(writeTokenFlagName, "", writeTokenFlagUsage)
+	startCmd.Flags().String(authRolesFlagName, "", authRolesFlagUsage)
+	startCmd.Flags().String(authScopesHeaderFlagName, "", authScopesHeaderFlagUsage)
+}
@@ -0,0 +1,275 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/trillian"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/merklelog"
+	"github.com/trustbloc/vct/pkg/roughtime"
+)
+
+const (
+	trillianDBConnFlagName  = "trillian-db-conn"
+	trillianDBConnFlagUsage = "Trillian db conn" +
+		" Alternatively, this can be set with the following environment variable: " + trillianDBConnEnvKey
+	trillianDBConnEnvKey = envPrefix + "TRILLIAN_DB_CONN"
+
+	logBackendFlagName  = "log-backend"
+	logBackendFlagUsage = "Backend of the logs without a Trillian endpoint (trillian,native). The trillian backend runs" +
+		" the embedded Trillian log server and signer, the native backend implements the Merkle log over SQL" +
+		" without any Trillian server. Defaults to trillian." +
+		" Alternatively, this can be set with the following environment variable: " + logBackendEnvKey
+	logBackendEnvKey = envPrefix + "LOG_BACKEND"
+
+	nativeLogDBConnFlagName  = "native-log-db-conn"
+	nativeLogDBConnFlagUsage = "PostgreSQL connection string of the native log backend." +
+		" The logs are kept in memory if not set (for development only)." +
+		" Alternatively, this can be set with the following environment variable: " + nativeLogDBConnEnvKey
+	nativeLogDBConnEnvKey = envPrefix + "NATIVE_LOG_DB_CONN"
+
+	trillianBackend = "trillian"
+	nativeBackend   = "native"
+)
+
+// getLogBackend returns the backend of the logs without a Trillian endpoint, trillian if it is not set.
+func getLogBackend(cmd *cobra.Command) (string, error) {
+	logBackend := cmdutils.GetUserSetOptionalVarFromString(cmd, logBackendFlagName, logBackendEnvKey)
+	if logBackend == "" {
+		logBackend = trillianBackend
+	}
+
+	if logBackend != trillianBackend && logBackend != nativeBackend {
+		return "", fmt.Errorf("unsupported log backend: %s", logBackend)
+	}
+
+	return logBackend, nil
+}
+
+// logConnections are the connections to the Trillian servers of the logs by endpoint, a server is dialed once.
+type logConnections map[string]*grpc.ClientConn
+
+func (c logConnections) dial(endpoint string) (*grpc.ClientConn, error) {
+	if conn, ok := c[endpoint]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.Dial(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("grpc dial: %w", err)
+	}
+
+	c[endpoint] = conn
+
+	return conn, nil
+}
+
+func (c logConnections) close() {
+	for _, conn := range c {
+		conn.Close() // nolint: errcheck,gosec
+	}
+}
+
+// connectLogs connects the clients of the logs to their Trillian trees, created once, or to the native log backend
+// for the logs without an endpoint. The timestamps of the native log are taken from the Roughtime clock, if any.
+func connectLogs(parameters *agentParameters, cfg storage.Store, clock *roughtime.Clock) (logConnections, error) {
+	var nativeLog *merklelog.Log
+
+	if parameters.logBackend == nativeBackend {
+		var err error
+
+		nativeLog, err = createNativeLog(parameters.nativeLogDBConn)
+		if err != nil {
+			return nil, fmt.Errorf("create native log: %w", err)
+		}
+
+		if clock != nil {
+			nativeLog.SetClock(roughtimeSource{clock: clock}.time)
+		}
+	}
+
+	conns := logConnections{}
+
+	for i := range parameters.logs {
+		if err := conns.connect(&parameters.logs[i], nativeLog, cfg, parameters); err != nil {
+			conns.close()
+
+			return nil, err
+		}
+	}
+
+	return conns, nil
+}
+
+// connect connects the clients of the log, its read replica and its shadow.
+func (c logConnections) connect(l *command.Log, nativeLog *merklelog.Log, cfg storage.Store,
+	parameters *agentParameters) error {
+	if l.ReadEndpoint != "" {
+		readConn, err := c.dial(l.ReadEndpoint)
+		if err != nil {
+			return err
+		}
+
+		l.ReadClient = trillian.NewTrillianLogClient(readConn)
+	}
+
+	if l.ShadowEndpoint != "" {
+		shadowConn, err := c.dial(l.ShadowEndpoint)
+		if err != nil {
+			return err
+		}
+
+		shadowTree, err := createTreeAndInit(shadowConn, cfg, l.Alias+"-shadow", parameters.timeout,
+			parameters.syncTimeout)
+		if err != nil {
+			return fmt.Errorf("create shadow tree: %w", err)
+		}
+
+		l.ShadowID = shadowTree.TreeId
+		l.ShadowClient = trillian.NewTrillianLogClient(shadowConn)
+	}
+
+	if nativeLog != nil && l.Endpoint == embeddedLogServerHost {
+		id, err := initNativeTree(nativeLog, cfg, l.Alias, parameters.syncTimeout)
+		if err != nil {
+			return fmt.Errorf("init native tree: %w", err)
+		}
+
+		l.ID = id
+		l.Client = nativeLog
+
+		return nil
+	}
+
+	conn, err := c.dial(l.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	tree, err := createTreeAndInit(conn, cfg, l.Alias, parameters.timeout, parameters.syncTimeout)
+	if err != nil {
+		return fmt.Errorf("create tree: %w", err)
+	}
+
+	l.ID = tree.TreeId
+	l.Client = trillian.NewTrillianLogClient(conn)
+	l.AdminClient = trillian.NewTrillianAdminClient(conn)
+
+	return nil
+}
+
+func createTreeAndInit(conn *grpc.ClientConn, cfg storage.Store, alias string, timeout,
+	syncTimeout uint64) (*trillian.Tree, error) {
+	var tree *trillian.Tree
+
+	err := getOrInit(cfg, treeLogKey+"-"+alias, &tree, func() (interface{}, error) {
+		var (
+			createdTree *trillian.Tree
+			err         error
+		)
+
+		err = backoff.RetryNotify(func() error {
+			createdTree, err = trillian.NewTrillianAdminClient(conn).CreateTree(context.Background(),
+				&trillian.CreateTreeRequest{
+					Tree: &trillian.Tree{
+						TreeState:       trillian.TreeState_ACTIVE,
+						TreeType:        trillian.TreeType_LOG,
+						MaxRootDuration: durationpb.New(time.Hour),
+					},
+				})
+
+			return err // nolint: wrapcheck
+		}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), timeout), func(err error, duration time.Duration) {
+			logger.Warnf("create tree failed, will sleep for %v before trying again: %v", duration, err)
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("create tree: %w", err)
+		}
+
+		_, err = trillian.NewTrillianLogClient(conn).InitLog(context.Background(),
+			&trillian.InitLogRequest{LogId: createdTree.TreeId},
+		)
+
+		return createdTree, err // nolint: wrapcheck
+	}, syncTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("create and init tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// createNativeLog returns the native Merkle log over PostgreSQL, or in memory if no connection string is set.
+func createNativeLog(dbConn string) (*merklelog.Log, error) {
+	if dbConn == "" {
+		logger.Warnf("native log backend keeps the logs in memory, they are lost once the service stops")
+
+		return merklelog.New(merklelog.NewMemStorage()), nil
+	}
+
+	db, err := sql.Open("postgres", dbConn)
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+
+	storage, err := merklelog.NewSQLStorage(context.Background(), db)
+	if err != nil {
+		return nil, fmt.Errorf("create storage: %w", err)
+	}
+
+	return merklelog.New(storage), nil
+}
+
+// initNativeTree returns the ID of the tree of the log in the native backend, the tree is initialized on every
+// start since an in-memory storage loses the trees once the service stops.
+func initNativeTree(log *merklelog.Log, cfg storage.Store, alias string, syncTimeout uint64) (int64, error) {
+	var treeID int64
+
+	err := getOrInit(cfg, nativeTreeKey+"-"+alias, &treeID, func() (interface{}, error) {
+		id, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+		if err != nil {
+			return nil, fmt.Errorf("generate tree id: %w", err)
+		}
+
+		return id.Int64() + 1, nil
+	}, syncTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("get tree id: %w", err)
+	}
+
+	_, err = log.InitLog(context.Background(), &trillian.InitLogRequest{LogId: treeID})
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		return 0, fmt.Errorf("init log: %w", err)
+	}
+
+	return treeID, nil
+}
+
+func createBackendFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(trillianDBConnFlagName, "", trillianDBConnFlagUsage)
+	startCmd.Flags().String(logBackendFlagName, "", logBackendFlagUsage)
+	startCmd.Flags().String(nativeLogDBConnFlagName, "", nativeLogDBConnFlagUsage)
+}
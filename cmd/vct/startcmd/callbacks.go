@@ -0,0 +1,45 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+)
+
+const (
+	callbackHostsFlagName  = "callback-hosts"
+	callbackHostsFlagUsage = "Comma-separated list of the hosts (host or host:port) the callbacks of the submissions" +
+		" (callbackURL) may be posted to, the submissions with a callback to another host are rejected and no" +
+		" callback is accepted if not set. The callbacks never connect to loopback, private or link-local" +
+		" addresses. Alternatively, this can be set with the following environment variable: " + callbackHostsEnvKey
+	callbackHostsEnvKey = envPrefix + "CALLBACK_HOSTS"
+)
+
+// getCallbackHosts returns the hosts the callbacks of the submissions may be posted to.
+func getCallbackHosts(cmd *cobra.Command) []string {
+	hostsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, callbackHostsFlagName, callbackHostsEnvKey)
+	if hostsStr == "" {
+		return nil
+	}
+
+	var hosts []string
+
+	for _, host := range strings.Split(hostsStr, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
+
+func createCallbackFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(callbackHostsFlagName, "", callbackHostsFlagUsage)
+}
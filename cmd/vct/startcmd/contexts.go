@@ -0,0 +1,207 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	ldrest "github.com/hyperledger/aries-framework-go/pkg/controller/rest/ld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ldcontext/remote"
+	ldsvc "github.com/hyperledger/aries-framework-go/pkg/ld"
+	ldstore "github.com/hyperledger/aries-framework-go/pkg/store/ld"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	jsonld "github.com/piprate/json-gold/ld"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/rest"
+	"github.com/trustbloc/vct/pkg/ldloader"
+)
+
+const (
+	contextProviderFlagName  = "context-provider-url"
+	contextProviderFlagUsage = "Comma-separated list of remote context provider URLs to get JSON-LD contexts from." +
+		" Alternatively, this can be set with the following environment variable: " + contextProviderEnvKey
+	contextProviderEnvKey = envPrefix + "CONTEXT_PROVIDER_URL"
+
+	contextDirFlagName  = "context-dir"
+	contextDirFlagUsage = "Directory of the JSON-LD context documents to pin in addition to the contexts vendored in" +
+		" the binary, a JSON file per document with the url, documentURL and content fields. A pinned document is" +
+		" always used for its URL and is never replaced by a context provider or a fetched document." +
+		" Alternatively, this can be set with the following environment variable: " + contextDirEnvKey
+	contextDirEnvKey = envPrefix + "CONTEXT_DIR"
+
+	contextFetchPolicyFlagName  = "context-fetch-policy"
+	contextFetchPolicyFlagUsage = "Policy of the fetches of the JSON-LD contexts which are neither pinned nor provided" +
+		" by a context provider (off,allowlist,on). The contexts are fetched from their URL and cached, the" +
+		" allowlist policy fetches the URLs under a prefix of " + contextFetchAllowlistFlagName +
+		" only, redirects included. Defaults to off." +
+		" Alternatively, this can be set with the following environment variable: " + contextFetchPolicyEnvKey
+	contextFetchPolicyEnvKey = envPrefix + "CONTEXT_FETCH_POLICY"
+
+	contextFetchAllowlistFlagName  = "context-fetch-allowlist"
+	contextFetchAllowlistFlagUsage = "Comma-separated list of the URL prefixes of the JSON-LD contexts the allowlist" +
+		" fetch policy fetches: a URL matches a prefix with the same scheme and host if its path starts with the" +
+		" path segments of the prefix." +
+		" Alternatively, this can be set with the following environment variable: " + contextFetchAllowlistEnvKey
+	contextFetchAllowlistEnvKey = envPrefix + "CONTEXT_FETCH_ALLOWLIST"
+)
+
+type ldStoreProvider struct {
+	ContextStore        ldstore.ContextStore
+	RemoteProviderStore ldstore.RemoteProviderStore
+}
+
+func (p *ldStoreProvider) JSONLDContextStore() ldstore.ContextStore {
+	return p.ContextStore
+}
+
+func (p *ldStoreProvider) JSONLDRemoteProviderStore() ldstore.RemoteProviderStore {
+	return p.RemoteProviderStore
+}
+
+func createLDStoreProvider(provider storage.Provider) (*ldStoreProvider, error) {
+	contextStore, err := ldstore.NewContextStore(provider)
+	if err != nil {
+		return nil, fmt.Errorf("create JSON-LD context store: %w", err)
+	}
+
+	remoteProviderStore, err := ldstore.NewRemoteProviderStore(provider)
+	if err != nil {
+		return nil, fmt.Errorf("create remote provider store: %w", err)
+	}
+
+	return &ldStoreProvider{
+		ContextStore:        contextStore,
+		RemoteProviderStore: remoteProviderStore,
+	}, nil
+}
+
+// getContextLoaderOpts returns the options of the document loader: the pinned contexts and the fetch policy.
+func getContextLoaderOpts(cmd *cobra.Command) ([]ldloader.Opt, error) {
+	var opts []ldloader.Opt
+
+	if dir := cmdutils.GetUserSetOptionalVarFromString(cmd, contextDirFlagName, contextDirEnvKey); dir != "" {
+		docs, err := ldloader.ReadPinnedContexts(dir)
+		if err != nil {
+			return nil, fmt.Errorf("read pinned contexts: %w", err)
+		}
+
+		opts = append(opts, ldloader.WithPinnedContexts(docs...))
+	}
+
+	policy := cmdutils.GetUserSetOptionalVarFromString(cmd, contextFetchPolicyFlagName, contextFetchPolicyEnvKey)
+	allowlist := cmdutils.GetUserSetOptionalVarFromString(cmd, contextFetchAllowlistFlagName,
+		contextFetchAllowlistEnvKey)
+
+	if policy == "" {
+		policy = string(ldloader.FetchOff)
+	}
+
+	switch ldloader.FetchPolicy(policy) {
+	case ldloader.FetchOff, ldloader.FetchOn:
+	case ldloader.FetchAllowlist:
+		if allowlist == "" {
+			return nil, fmt.Errorf("%s is required by the allowlist context fetch policy",
+				contextFetchAllowlistFlagName)
+		}
+	default:
+		return nil, fmt.Errorf("context fetch policy %q is not supported", policy)
+	}
+
+	var allowed []string
+
+	if allowlist != "" {
+		for _, prefix := range strings.Split(allowlist, ",") {
+			allowed = append(allowed, strings.TrimSpace(prefix))
+		}
+	}
+
+	return append(opts, ldloader.WithFetchPolicy(ldloader.FetchPolicy(policy), allowed...)), nil
+}
+
+func createJSONLDDocumentLoader(ldStore *ldStoreProvider, httpClient *http.Client,
+	providerURLs []string, contextLoaderOpts []ldloader.Opt) (jsonld.DocumentLoader, error) {
+	var loaderOpts []ld.DocumentLoaderOpts
+
+	for _, u := range providerURLs {
+		loaderOpts = append(loaderOpts,
+			ld.WithRemoteProvider(
+				remote.NewProvider(u, remote.WithHTTPClient(httpClient)),
+			),
+		)
+	}
+
+	loader, err := ld.NewDocumentLoader(ldStore, loaderOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("new document loader: %w", err)
+	}
+
+	// the pinned contexts are never replaced, the contexts which are neither pinned nor provided are fetched
+	// according to the policy
+	pinned, err := ldloader.New(loader, append([]ldloader.Opt{ldloader.WithHTTPClient(&http.Client{
+		Timeout:   ldloader.DefaultFetchTimeout,
+		Transport: httpClient.Transport,
+	})}, contextLoaderOpts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("new pinned document loader: %w", err)
+	}
+
+	return pinned, nil
+}
+
+// createDocumentLoaders returns the JSON-LD document loaders of the logs and the stores of their contexts by alias.
+func createDocumentLoaders(aliases []string, store storeProvider, httpClient *http.Client,
+	parameters *agentParameters) (map[string]jsonld.DocumentLoader, map[string]*ldStoreProvider, error) {
+	loaders := map[string]jsonld.DocumentLoader{}
+	ldStoreProviders := map[string]*ldStoreProvider{}
+
+	for _, alias := range aliases {
+		storageProvider := &customizedStorageProvider{
+			alias:           strings.ReplaceAll(alias, "-", "_"),
+			StorageProvider: store,
+		}
+
+		ldStore, err := createLDStoreProvider(storageProvider)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create ld store provider: %w", err)
+		}
+
+		loader, err := createJSONLDDocumentLoader(ldStore, httpClient, parameters.contextProviderURLs,
+			parameters.contextLoaderOpts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create document loader: %w", err)
+		}
+
+		loaders[alias] = loader
+		ldStoreProviders[alias] = ldStore
+	}
+
+	return loaders, ldStoreProviders, nil
+}
+
+// handleContexts serves the JSON-LD context operations of the logs under their base path.
+func handleContexts(router *mux.Router, ldStoreProviders map[string]*ldStoreProvider) {
+	for alias, ldStore := range ldStoreProviders {
+		r := router.PathPrefix(strings.ReplaceAll(rest.BasePath, rest.AliasPath, "/"+alias)).Subrouter()
+
+		for _, handler := range ldrest.New(ldsvc.New(ldStore)).GetRESTHandlers() {
+			r.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+		}
+	}
+}
+
+func createContextFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(contextProviderFlagName, "", contextProviderFlagUsage)
+	startCmd.Flags().String(contextDirFlagName, "", contextDirFlagUsage)
+	startCmd.Flags().String(contextFetchPolicyFlagName, "", contextFetchPolicyFlagUsage)
+	startCmd.Flags().String(contextFetchAllowlistFlagName, "", contextFetchAllowlistFlagUsage)
+}
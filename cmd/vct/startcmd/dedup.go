@@ -0,0 +1,85 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	dedupStoreFlagName  = "dedup-store"
+	dedupStoreFlagUsage = "Persists the logged leaves in the database, the submissions of logged entries are answered" +
+		" from it without queueing their leaves. A filter of the stored leaves kept in memory spares the read of" +
+		" the database for the submissions of new entries, it is loaded at start." +
+		" Possible values [true] [false]. Defaults to false if not set." +
+		" Alternatively, this can be set with the following environment variable: " + dedupStoreEnvKey
+	dedupStoreEnvKey = envPrefix + "DEDUP_STORE"
+
+	dedupFilterCapacityFlagName  = "dedup-filter-capacity"
+	dedupFilterCapacityFlagUsage = "Number of leaves the in-memory filter of the dedup store is sized for" +
+		" (about 1.2 bytes per leaf), more leaves raise the rate of reads of the database. Defaults to 1000000." +
+		" Alternatively, this can be set with the following environment variable: " + dedupFilterCapacityEnvKey
+	dedupFilterCapacityEnvKey = envPrefix + "DEDUP_FILTER_CAPACITY"
+)
+
+type dedupParameters struct {
+	filterCapacity uint64
+}
+
+// getDedup returns the parameters of the dedup store, nil if the logged leaves are not persisted.
+func getDedup(cmd *cobra.Command) (*dedupParameters, error) {
+	enabledStr := cmdutils.GetUserSetOptionalVarFromString(cmd, dedupStoreFlagName, dedupStoreEnvKey)
+	if enabledStr == "" {
+		return nil, nil
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return nil, fmt.Errorf("dedup store is not a bool: %w", err)
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	params := &dedupParameters{filterCapacity: command.DefaultDedupFilterCapacity}
+
+	if capacityStr := cmdutils.GetUserSetOptionalVarFromString(cmd, dedupFilterCapacityFlagName,
+		dedupFilterCapacityEnvKey); capacityStr != "" {
+		params.filterCapacity, err = strconv.ParseUint(capacityStr, 10, 64)
+		if err != nil || params.filterCapacity == 0 {
+			return nil, fmt.Errorf("dedup filter capacity is not a positive number: %s", capacityStr)
+		}
+	}
+
+	return params, nil
+}
+
+// dedupStoreName is the name of the store of the logged leaves, the migration 3 indexes their tag.
+const dedupStoreName = "dedup"
+
+// openDedupStore opens the store of the logged leaves indexed by their tag.
+func openDedupStore(store storeProvider) (storage.Store, error) {
+	dedupStore, err := store.OpenStore(dedupStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	return dedupStore, nil
+}
+
+func createDedupFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(dedupStoreFlagName, "", dedupStoreFlagUsage)
+	startCmd.Flags().String(dedupFilterCapacityFlagName, "", dedupFilterCapacityFlagUsage)
+}
@@ -0,0 +1,245 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	spilog "github.com/hyperledger/aries-framework-go/spi/log"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/testutil/vcgen"
+)
+
+const (
+	devKeySeedFlagName  = "key-seed"
+	devKeySeedEnvKey    = envPrefix + "DEV_KEY_SEED"
+	devKeySeedFlagUsage = "Seed the key of the log and the key of the issuer of the sample credentials are derived" +
+		" from, the log keeps its log ID across restarts. Defaults to " + defaultDevKeySeed + " if not set." +
+		" Alternatively, this can be set with the following environment variable: " + devKeySeedEnvKey
+
+	devSamplesFlagName  = "samples"
+	devSamplesEnvKey    = envPrefix + "DEV_SAMPLES"
+	devSamplesFlagUsage = "Number of sample credentials submitted to every writable log once the service is up," +
+		" 0 disables them. Defaults to 3 if not set." +
+		" Alternatively, this can be set with the following environment variable: " + devSamplesEnvKey
+
+	defaultDevKeySeed = "vct-dev"
+	defaultDevSamples = 3
+	devKeyID          = "vct-dev-log-key"
+	devSeedAttempts   = 60
+)
+
+// devDefaults are the values of the start flags vct dev runs with unless they are set by the user: the embedded
+// Trillian and the stores in memory, the local KMS and the dev mode (did:web over HTTP).
+var devDefaults = []struct{ flag, envKey, value string }{ // nolint: gochecknoglobals
+	{agentHostFlagName, agentHostEnvKey, "localhost:5678"},
+	{agentMetricsHostFlagName, agentMetricsHostEnvKey, "localhost:9099"},
+	{logsFlagName, logsEnvKey, "maple2021:rw"},
+	{datasourceNameFlagName, datasourceNameEnvKey, "mem://dev"},
+	{kmsTypeFlagName, kmsTypeEnvKey, string(kmsLocal)},
+	{devModeFlagName, devModeFlagEnvKey, "true"},
+}
+
+// DevCmd returns the Cobra dev command: the start command with a local development configuration, a log key
+// derived from a seed, verbose logging and sample credentials. It is not meant to run in production.
+func DevCmd(server server) (*cobra.Command, error) {
+	devCmd := createStartCMD(server)
+	devCmd.Use = "dev"
+	devCmd.Short = "Starts vct service for local development"
+	devCmd.Long = `Starts verifiable credentials transparency service with the embedded Trillian, in-memory stores,` +
+		` a deterministic log key and sample credentials, for local development only`
+
+	start := devCmd.RunE
+
+	devCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		for _, d := range devDefaults {
+			if _, ok := os.LookupEnv(d.envKey); ok || cmd.Flags().Changed(d.flag) {
+				continue
+			}
+
+			if err := cmd.Flags().Set(d.flag, d.value); err != nil {
+				return fmt.Errorf("set %s: %w", d.flag, err)
+			}
+		}
+
+		seed := cmdutils.GetUserSetOptionalVarFromString(cmd, devKeySeedFlagName, devKeySeedEnvKey)
+		if seed == "" {
+			seed = defaultDevKeySeed
+		}
+
+		if err := cmd.Flags().Set(devKeySeedFlagName, seed); err != nil {
+			return fmt.Errorf("set %s: %w", devKeySeedFlagName, err)
+		}
+
+		samples := defaultDevSamples
+
+		if samplesStr := cmdutils.GetUserSetOptionalVarFromString(cmd, devSamplesFlagName,
+			devSamplesEnvKey); samplesStr != "" {
+			var err error
+
+			samples, err = strconv.Atoi(samplesStr)
+			if err != nil || samples < 0 {
+				return fmt.Errorf("samples is not a number(positive): %s", samplesStr)
+			}
+		}
+
+		log.SetLevel("", spilog.DEBUG)
+
+		logger.Warnf("Running in the development mode: the stores are in memory and the keys are derived from" +
+			" a public seed, do not use it in production")
+
+		host := cmdutils.GetUserSetOptionalVarFromString(cmd, agentHostFlagName, agentHostEnvKey)
+		logsVal := cmdutils.GetUserSetOptionalVarFromString(cmd, logsFlagName, logsEnvKey)
+
+		if samples > 0 {
+			go seedSamples(host, writableAliases(logsVal), seed, samples)
+		}
+
+		return start(cmd, args)
+	}
+
+	createFlags(devCmd)
+
+	devCmd.Flags().String(devKeySeedFlagName, "", devKeySeedFlagUsage)
+	devCmd.Flags().String(devSamplesFlagName, "", devSamplesFlagUsage)
+
+	return devCmd, nil
+}
+
+// devKeySeed returns the seed of the log key set by vct dev, the flag is not defined by the start command.
+func devKeySeed(cmd *cobra.Command) string {
+	if cmd.Flags().Lookup(devKeySeedFlagName) == nil {
+		return ""
+	}
+
+	return cmdutils.GetUserSetOptionalVarFromString(cmd, devKeySeedFlagName, devKeySeedEnvKey)
+}
+
+// importDevKey imports the log key derived from the seed into the local KMS, unless it is already imported.
+func importDevKey(km keyManager, seed string) (string, error) {
+	if _, err := km.Get(devKeyID); err == nil {
+		return devKeyID, nil
+	}
+
+	importer, ok := km.(interface {
+		ImportPrivateKey(privKey interface{}, kt kms.KeyType, opts ...kms.PrivateKeyOpts) (string, interface{}, error)
+	})
+	if !ok {
+		return "", errors.New("the log key of the dev mode requires the local kms")
+	}
+
+	keyID, _, err := importer.ImportPrivateKey(DevLogKey(seed), kms.ECDSAP256TypeIEEEP1363, kms.WithKeyID(devKeyID))
+	if err != nil {
+		return "", fmt.Errorf("import dev log key: %w", err)
+	}
+
+	return keyID, nil
+}
+
+// DevLogKey returns the ECDSA P-256 log key vct dev derives from the seed.
+func DevLogKey(seed string) *ecdsa.PrivateKey {
+	digest := sha256.Sum256([]byte("vct-dev-log-key-v1:" + seed))
+
+	curve := elliptic.P256()
+
+	// d in [1, N-1]
+	d := new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+	d.Add(d, big.NewInt(1))
+
+	key := &ecdsa.PrivateKey{D: d, PublicKey: ecdsa.PublicKey{Curve: curve}}
+	key.X, key.Y = curve.ScalarBaseMult(d.Bytes())
+
+	return key
+}
+
+// DevSampleCredentials returns the sample credentials vct dev submits: JWT credentials of a did:key issuer derived
+// from the seed, generated by vcgen.
+func DevSampleCredentials(seed string, count int) ([][]byte, error) {
+	g, err := vcgen.New("vct-dev-samples:"+seed, vcgen.WithIssuanceDate(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("create sample credentials generator: %w", err)
+	}
+
+	return g.Credentials(count) // nolint: wrapcheck
+}
+
+// writableAliases returns the aliases of the writable logs of the logs flag.
+func writableAliases(logsVal string) []string {
+	var aliases []string
+
+	logs, _ := parseLogs(logsVal, nil)
+
+	for _, l := range logs {
+		if strings.Contains(l.Permission, "w") {
+			aliases = append(aliases, l.Alias)
+		}
+	}
+
+	return aliases
+}
+
+// seedSamples submits the sample credentials to the logs once the API is up.
+func seedSamples(host string, aliases []string, seed string, count int) {
+	if strings.HasPrefix(host, unixSocketScheme) {
+		logger.Warnf("sample credentials are not submitted to a service on a unix socket")
+
+		return
+	}
+
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+
+	credentials, err := DevSampleCredentials(seed, count)
+	if err != nil {
+		logger.Errorf("create sample credentials: %v", err)
+
+		return
+	}
+
+	ctx := context.Background()
+
+	for _, alias := range aliases {
+		endpoint := "http://" + host + "/" + alias
+		client := vct.New(endpoint)
+
+		err = backoff.Retry(func() error {
+			_, er := client.GetSTH(ctx)
+
+			return er // nolint: wrapcheck
+		}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), devSeedAttempts))
+		if err != nil {
+			logger.Errorf("log %s is not up, sample credentials are not submitted: %v", alias, err)
+
+			continue
+		}
+
+		for _, credential := range credentials {
+			if _, err = client.AddVC(ctx, credential); err != nil {
+				logger.Errorf("submit sample credential to log %s: %v", alias, err)
+			}
+		}
+
+		logger.Infof("Submitted %d sample credentials to %s", len(credentials), endpoint)
+	}
+}
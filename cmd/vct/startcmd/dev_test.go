@@ -0,0 +1,126 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd_test
+
+import (
+	"context"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/cmd/vct/startcmd"
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+func freeHost(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	defer func() { require.NoError(t, lis.Close()) }()
+
+	return lis.Addr().String()
+}
+
+// issuerOf returns the issuer of the JWT credential.
+func issuerOf(t *testing.T, jwt []byte) string {
+	t.Helper()
+
+	parts := strings.Split(string(jwt), ".")
+	require.Len(t, parts, 3)
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	require.True(t, strings.HasPrefix(claims.Issuer, "did:key:"))
+
+	return claims.Issuer
+}
+
+func TestDevCmd(t *testing.T) {
+	t.Run("Contents", func(t *testing.T) {
+		devCmd, err := startcmd.DevCmd(&mockServer{})
+		require.NoError(t, err)
+
+		require.Equal(t, "dev", devCmd.Use)
+		require.Equal(t, "Starts vct service for local development", devCmd.Short)
+		require.NotNil(t, devCmd.Flags().Lookup("key-seed"))
+		require.NotNil(t, devCmd.Flags().Lookup(logsFlagName))
+	})
+
+	t.Run("Samples", func(t *testing.T) {
+		host := freeHost(t)
+
+		devCmd, err := startcmd.DevCmd(&startcmd.HTTPServer{})
+		require.NoError(t, err)
+
+		devCmd.SetArgs([]string{
+			"--" + agentHostFlagName, host,
+			"--metrics-host", freeHost(t),
+			"--" + logBackendFlagName, "native",
+			"--samples", "2",
+		})
+
+		go func() {
+			// serves until the test binary exits
+			_ = devCmd.Execute() // nolint: errcheck
+		}()
+
+		ctx := context.Background()
+		client := vct.New("http://" + host + "/maple2021")
+
+		require.Eventually(t, func() bool {
+			sth, er := client.GetSTH(ctx)
+
+			return er == nil && sth.TreeSize == 2
+		}, 30*time.Second, 100*time.Millisecond)
+
+		pubKey, err := client.GetPublicKey(ctx)
+		require.NoError(t, err)
+
+		key := startcmd.DevLogKey("vct-dev")
+		require.Equal(t, elliptic.Marshal(elliptic.P256(), key.X, key.Y), pubKey) // nolint: staticcheck
+	})
+
+	t.Run("Invalid samples", func(t *testing.T) {
+		devCmd, err := startcmd.DevCmd(&mockServer{})
+		require.NoError(t, err)
+
+		devCmd.SetArgs([]string{"--samples", "-1"})
+		require.EqualError(t, devCmd.Execute(), "samples is not a number(positive): -1")
+	})
+}
+
+func TestDevSampleCredentials(t *testing.T) {
+	credentials, err := startcmd.DevSampleCredentials("vct-dev", 2)
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+	require.NotEqual(t, credentials[0], credentials[1])
+
+	// the issuer is derived from the seed
+	again, err := startcmd.DevSampleCredentials("vct-dev", 1)
+	require.NoError(t, err)
+	require.Equal(t, issuerOf(t, credentials[0]), issuerOf(t, again[0]))
+
+	other, err := startcmd.DevSampleCredentials("another seed", 1)
+	require.NoError(t, err)
+	require.NotEqual(t, issuerOf(t, credentials[0]), issuerOf(t, other[0]))
+
+	require.NotEqual(t, startcmd.DevLogKey("vct-dev").D, startcmd.DevLogKey("another seed").D)
+}
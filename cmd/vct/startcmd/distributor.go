@@ -0,0 +1,147 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/publisher"
+)
+
+const (
+	sthDistributorURLsFlagName  = "sth-distributor-urls"
+	sthDistributorURLsFlagUsage = "Comma-separated list of the endpoints each new tree head of the logs is pushed" +
+		" to (witness networks, gossip hubs or monitors), the tree heads are only served on request if not set." +
+		" The logs are polled at the publish interval. Alternatively, this can be set with the following" +
+		" environment variable: " + sthDistributorURLsEnvKey
+	sthDistributorURLsEnvKey = envPrefix + "STH_DISTRIBUTOR_URLS"
+
+	sthDistributorTokenFlagName  = "sth-distributor-token"
+	sthDistributorTokenFlagUsage = "Bearer token sent to the STH distributors." +
+		" Alternatively, this can be set with the following environment variable: " + sthDistributorTokenEnvKey
+	sthDistributorTokenEnvKey = envPrefix + "STH_DISTRIBUTOR_TOKEN"
+
+	sthDeliveryKey = "sth-delivery-"
+)
+
+type distributorParameters struct {
+	endpoints []publisher.DistributorEndpoint
+	interval  time.Duration
+	sourceURL string
+	logURL    string // the base URL the logs are identified by
+}
+
+// getDistributorParameters returns nil if the tree heads are not distributed.
+func getDistributorParameters(cmd *cobra.Command, baseURL string) (*distributorParameters, error) {
+	urls := cmdutils.GetUserSetOptionalVarFromString(cmd, sthDistributorURLsFlagName, sthDistributorURLsEnvKey)
+	if urls == "" {
+		return nil, nil
+	}
+
+	interval, sourceURL, err := getSourceParameters(cmd, baseURL, "sth distribution")
+	if err != nil {
+		return nil, err
+	}
+
+	token := cmdutils.GetUserSetOptionalVarFromString(cmd, sthDistributorTokenFlagName, sthDistributorTokenEnvKey)
+
+	var endpoints []publisher.DistributorEndpoint
+	for _, u := range strings.Split(urls, ",") {
+		endpoints = append(endpoints, publisher.DistributorEndpoint{URL: u, Token: token})
+	}
+
+	logURL := strings.TrimSuffix(baseURL, "/")
+	if logURL == "" {
+		logURL = sourceURL
+	}
+
+	return &distributorParameters{
+		endpoints: endpoints,
+		interval:  interval,
+		sourceURL: sourceURL,
+		logURL:    logURL,
+	}, nil
+}
+
+func createDistributorFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(sthDistributorURLsFlagName, "", sthDistributorURLsFlagUsage)
+	startCmd.Flags().String(sthDistributorTokenFlagName, "", sthDistributorTokenFlagUsage)
+}
+
+// startDistributors starts distributing the tree heads of the logs, the deliveries are stored so the tree heads
+// delivered are not pushed again after a restart. The pushes are signed as webhooks.
+func startDistributors(params *distributorParameters, cfg storage.Store, aliases []string, readToken string,
+	httpClient publisher.HTTPClient, mf monitoring.MetricFactory, sign func(*http.Request, []byte) error) error {
+	for _, alias := range aliases {
+		alias := alias
+
+		deliveries, err := getDeliveries(cfg, alias, params.endpoints)
+		if err != nil {
+			return err
+		}
+
+		d := publisher.NewDistributor(
+			vct.New(params.sourceURL+"/"+alias, vct.WithAuthReadToken(readToken)),
+			params.logURL+"/"+alias,
+			params.endpoints,
+			httpClient,
+			publisher.WithDeliveries(deliveries),
+			publisher.WithOnDelivery(func(delivery *publisher.Delivery) error {
+				src, er := json.Marshal(delivery)
+				if er != nil {
+					return fmt.Errorf("marshal delivery: %w", er)
+				}
+
+				return cfg.Put(sthDeliveryKey+alias+"@"+delivery.Endpoint, src) // nolint: wrapcheck
+			}),
+			publisher.WithDistributorMetrics(mf),
+			publisher.WithRequestSigner(sign),
+		)
+
+		go d.Run(context.Background(), params.interval)
+	}
+
+	return nil
+}
+
+// getDeliveries returns the stored deliveries of the tree heads of the log to the endpoints.
+func getDeliveries(cfg storage.Store, alias string,
+	endpoints []publisher.DistributorEndpoint) ([]*publisher.Delivery, error) {
+	var deliveries []*publisher.Delivery
+
+	for _, endpoint := range endpoints {
+		src, err := cfg.Get(sthDeliveryKey + alias + "@" + endpoint.URL)
+		if errors.Is(err, storage.ErrDataNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("get STH delivery of log %s to %s: %w", alias, endpoint.URL, err)
+		}
+
+		var delivery *publisher.Delivery
+		if err = json.Unmarshal(src, &delivery); err != nil {
+			return nil, fmt.Errorf("unmarshal STH delivery of log %s to %s: %w", alias, endpoint.URL, err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
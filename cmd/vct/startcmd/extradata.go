@@ -0,0 +1,134 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+const (
+	encryptExtraDataFlagName  = "encrypt-extra-data"
+	encryptExtraDataFlagUsage = "Encrypts the extra data of leaves (the proofs of credentials) at rest with an envelope" +
+		" key (AES256GCM) of the KMS, it is decrypted on reads. The key is created unless it is set by " +
+		extraDataKeyIDFlagName + ". Possible values [true] [false]. Defaults to false if not set." +
+		" Alternatively, this can be set with the following environment variable: " + encryptExtraDataEnvKey
+	encryptExtraDataEnvKey = envPrefix + "ENCRYPT_EXTRA_DATA"
+
+	extraDataKeyIDFlagName  = "extra-data-key-id"
+	extraDataKeyIDFlagUsage = "ID of the envelope key of the KMS encrypting the extra data of leaves at rest," +
+		" enables the encryption. Alternatively, this can be set with the following environment variable: " +
+		extraDataKeyIDEnvKey
+	extraDataKeyIDEnvKey = envPrefix + "EXTRA_DATA_KEY_ID"
+
+	reEncryptionIntervalFlagName  = "extra-data-reencryption-interval"
+	reEncryptionIntervalFlagUsage = "Re-encrypts the extra data of leaves with the active envelope key in the" +
+		" background: the logs are scanned at start, on every rotation of the key on the admin path " +
+		rest.ExtraDataKeyPath + " and at the interval (e.g. 1h). The leaves of the native log backend are" +
+		" rewritten, the leaves of Trillian are scanned only (their keys must be kept). Requires the extra data" +
+		" to be encrypted. Not re-encrypted if not set." +
+		" Alternatively, this can be set with the following environment variable: " + reEncryptionIntervalEnvKey
+	reEncryptionIntervalEnvKey = envPrefix + "EXTRA_DATA_REENCRYPTION_INTERVAL"
+)
+
+// getReEncryption returns the configuration of the re-encryption of the extra data, nil if it is not re-encrypted.
+func getReEncryption(cmd *cobra.Command) (*command.ReEncryptionConfig, error) {
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, reEncryptionIntervalFlagName,
+		reEncryptionIntervalEnvKey)
+	if intervalStr == "" {
+		return nil, nil
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("re-encryption interval is not a positive duration: %s", intervalStr)
+	}
+
+	return &command.ReEncryptionConfig{Interval: interval}, nil
+}
+
+// getRotatedExtraDataKey returns the envelope key of the extra data rotated to, empty if the key was not rotated.
+func getRotatedExtraDataKey(cfg storage.Store) (string, error) {
+	src, err := cfg.Get(rotatedKIDKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("get rotated extra data kid: %w", err)
+	}
+
+	var keyID string
+	if err = json.Unmarshal(src, &keyID); err != nil {
+		return "", fmt.Errorf("unmarshal rotated extra data kid: %w", err)
+	}
+
+	return keyID, nil
+}
+
+// storeExtraDataKey returns the func storing the envelope key of the extra data once it is rotated.
+func storeExtraDataKey(cfg storage.Store) func(string) error {
+	return func(keyID string) error {
+		src, err := json.Marshal(keyID)
+		if err != nil {
+			return fmt.Errorf("marshal extra data kid: %w", err)
+		}
+
+		return cfg.Put(rotatedKIDKey, src) // nolint: wrapcheck
+	}
+}
+
+// setExtraDataKey sets the envelope key the extra data is encrypted with: the key rotated to on the admin API, the
+// configured key or the key created once if the extra data is encrypted.
+func setExtraDataKey(cfg *command.Config, km keyManager, configStore storage.Store,
+	parameters *agentParameters) error {
+	extraDataKeyID := parameters.extraDataKeyID
+
+	if parameters.encryptExtraData && extraDataKeyID == "" {
+		err := getOrInit(configStore, extraDataKIDKey, &extraDataKeyID, func() (interface{}, error) {
+			kid, _, er := km.Create(kms.AES256GCMType)
+
+			return kid, er // nolint: wrapcheck
+		}, parameters.syncTimeout)
+		if err != nil {
+			return fmt.Errorf("create extra data kid: %w", err)
+		}
+	}
+
+	if extraDataKeyID != "" {
+		// the key rotated to on the admin API overrides the configured key
+		rotated, err := getRotatedExtraDataKey(configStore)
+		if err != nil {
+			return err
+		}
+
+		if rotated != "" {
+			extraDataKeyID = rotated
+		}
+	}
+
+	cfg.ExtraDataKeyID = extraDataKeyID
+	cfg.OnExtraDataKey = storeExtraDataKey(configStore)
+
+	return nil
+}
+
+func createExtraDataFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(encryptExtraDataFlagName, "", encryptExtraDataFlagUsage)
+	startCmd.Flags().String(extraDataKeyIDFlagName, "", extraDataKeyIDFlagUsage)
+	startCmd.Flags().String(reEncryptionIntervalFlagName, "", reEncryptionIntervalFlagUsage)
+}
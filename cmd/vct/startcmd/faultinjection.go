@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/faultinject"
+)
+
+const (
+	faultInjectionFlagName  = "fault-injection"
+	faultInjectionFlagUsage = "Testing only: injects faults in the calls to Trillian and the KMS." +
+		" Comma-separated list of rules <operation>:<fault>[:<probability>] with the faults error, corrupt and" +
+		" delay=<duration>, e.g. trillian.QueueLeaf:error:0.1,trillian.Get*:delay=2s,kms.Sign:corrupt, or none." +
+		" The rules are replaced on demand with PUT " + faultinject.AdminPath + ", which is only served if set." +
+		" Alternatively, this can be set with the following environment variable: " + faultInjectionEnvKey
+	faultInjectionEnvKey = envPrefix + "FAULT_INJECTION"
+)
+
+// getFaultInjection returns the fault injection rules, nil if the fault injection is disabled.
+func getFaultInjection(cmd *cobra.Command) ([]faultinject.Rule, error) {
+	spec := cmdutils.GetUserSetOptionalVarFromString(cmd, faultInjectionFlagName, faultInjectionEnvKey)
+	if spec == "" {
+		return nil, nil
+	}
+
+	if spec == "none" {
+		return []faultinject.Rule{}, nil
+	}
+
+	rules, err := faultinject.ParseRules(spec)
+	if err != nil {
+		return nil, fmt.Errorf("fault injection: %w", err)
+	}
+
+	return rules, nil
+}
+
+// injectFaults returns the injector of the rules and wraps the Trillian clients of the logs.
+// injectFaults injects the faults of the rules in the clients of the logs, the KMS and the crypto of the command.
+func injectFaults(rules []faultinject.Rule, cfg *command.Config) (*faultinject.Injector, error) {
+	injector, err := faultinject.New(rules)
+	if err != nil {
+		return nil, fmt.Errorf("fault injection: %w", err)
+	}
+
+	logger.Warnf("Fault injection is enabled with %d rules, not for production use", len(rules))
+
+	cfg.KMS, cfg.Crypto = injector.KeyManager(cfg.KMS), injector.Crypto(cfg.Crypto)
+
+	logs := cfg.Logs
+
+	for i := range logs {
+		logs[i].Client = injector.LogClient(logs[i].Client)
+
+		if logs[i].ReadClient != nil {
+			logs[i].ReadClient = injector.LogClient(logs[i].ReadClient)
+		}
+
+		if logs[i].ShadowClient != nil {
+			logs[i].ShadowClient = injector.LogClient(logs[i].ShadowClient)
+		}
+	}
+
+	return injector, nil
+}
+
+func createFaultInjectionFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(faultInjectionFlagName, "", faultInjectionFlagUsage)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+const (
+	disabledOperationsFlagName  = "disabled-operations"
+	disabledOperationsFlagUsage = "Comma-separated list of the operations of the REST API which are disabled, named" +
+		" after their path (e.g. get-entries for /{alias}/v1/get-entries, admin/submission-stats). An operation" +
+		" disabled for the deployment is not found (404), an operation disabled for a tenant is forbidden (403) for" +
+		" the logs of the tenant. Format must be <operation>[@<tenant>]. Examples: get-entries@maple,admin/usage" +
+		" Alternatively, this can be set with the following environment variable: " + disabledOperationsEnvKey
+	disabledOperationsEnvKey = envPrefix + "DISABLED_OPERATIONS"
+)
+
+// getFeatureFlags returns the operations disabled for the deployment and per tenant, nil if none is.
+func getFeatureFlags(cmd *cobra.Command) (*command.FeatureFlagsConfig, error) {
+	const operationParts = 2
+
+	operationsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, disabledOperationsFlagName,
+		disabledOperationsEnvKey)
+	if operationsStr == "" {
+		return nil, nil
+	}
+
+	cfg := &command.FeatureFlagsConfig{DisabledPerTenant: map[string][]string{}}
+
+	for _, val := range strings.Split(operationsStr, ",") {
+		parts := strings.SplitN(strings.TrimSpace(val), "@", operationParts)
+		if parts[0] == "" || len(parts) == operationParts && parts[1] == "" {
+			return nil, fmt.Errorf("disabled operation %q is not <operation>[@<tenant>]", val)
+		}
+
+		if len(parts) == 1 {
+			cfg.Disabled = append(cfg.Disabled, parts[0])
+
+			continue
+		}
+
+		cfg.DisabledPerTenant[parts[1]] = append(cfg.DisabledPerTenant[parts[1]], parts[0])
+	}
+
+	return cfg, nil
+}
+
+// checkFeatureFlags checks that the disabled operations are operations of the handlers, a misspelled operation
+// would be silently served.
+func checkFeatureFlags(cfg *command.FeatureFlagsConfig, handlers []rest.Handler) error {
+	if cfg == nil {
+		return nil
+	}
+
+	operations := map[string]struct{}{}
+	for _, h := range handlers {
+		operations[rest.OperationName(h.Path())] = struct{}{}
+	}
+
+	disabled := append([]string{}, cfg.Disabled...)
+	for _, tenantOperations := range cfg.DisabledPerTenant {
+		disabled = append(disabled, tenantOperations...)
+	}
+
+	for _, operation := range disabled {
+		if _, ok := operations[operation]; !ok {
+			return fmt.Errorf("disabled operation %s is not an operation of the REST API", operation)
+		}
+	}
+
+	return nil
+}
+
+func createFeatureFlagFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(disabledOperationsFlagName, "", disabledOperationsFlagUsage)
+}
@@ -0,0 +1,173 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/publisher"
+)
+
+const (
+	ipfsAPIURLFlagName  = "ipfs-api-url"
+	ipfsAPIURLFlagUsage = "URL of the RPC API of the IPFS node (Kubo) the entry bundles and the checkpoints of the" +
+		" logs are mirrored to (e.g. http://localhost:5001), the mirroring is disabled if not set. The roots of the" +
+		" mirrors are advertised in the webfinger metadata of the logs." +
+		" Alternatively, this can be set with the following environment variable: " + ipfsAPIURLEnvKey
+	ipfsAPIURLEnvKey = envPrefix + "IPFS_API_URL"
+
+	ipfsPinningServiceURLFlagName  = "ipfs-pinning-service-url"
+	ipfsPinningServiceURLFlagUsage = "Endpoint of the IPFS Pinning Service API the roots of the mirrors are pinned" +
+		" with (e.g. https://api.pinata.cloud/psa), the roots are only pinned on the IPFS node if not set." +
+		" Alternatively, this can be set with the following environment variable: " + ipfsPinningServiceURLEnvKey
+	ipfsPinningServiceURLEnvKey = envPrefix + "IPFS_PINNING_SERVICE_URL"
+
+	ipfsPinningServiceTokenFlagName  = "ipfs-pinning-service-token"
+	ipfsPinningServiceTokenFlagUsage = "Access token of the pinning service." +
+		" Alternatively, this can be set with the following environment variable: " + ipfsPinningServiceTokenEnvKey
+	ipfsPinningServiceTokenEnvKey = envPrefix + "IPFS_PINNING_SERVICE_TOKEN"
+
+	ipfsRootKey = "ipfs-root-"
+)
+
+type ipfsParameters struct {
+	apiURL       string
+	pinningURL   string
+	pinningToken string
+	interval     time.Duration
+	sourceURL    string
+}
+
+// getIPFSParameters returns nil if the mirroring is disabled, the logs are read from the source URL of the
+// publisher role at its interval.
+func getIPFSParameters(cmd *cobra.Command, baseURL string) (*ipfsParameters, error) {
+	apiURL := cmdutils.GetUserSetOptionalVarFromString(cmd, ipfsAPIURLFlagName, ipfsAPIURLEnvKey)
+	if apiURL == "" {
+		return nil, nil
+	}
+
+	interval, sourceURL, err := getSourceParameters(cmd, baseURL, "ipfs mirroring")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ipfsParameters{
+		apiURL: apiURL,
+		pinningURL: cmdutils.GetUserSetOptionalVarFromString(cmd, ipfsPinningServiceURLFlagName,
+			ipfsPinningServiceURLEnvKey),
+		pinningToken: cmdutils.GetUserSetOptionalVarFromString(cmd, ipfsPinningServiceTokenFlagName,
+			ipfsPinningServiceTokenEnvKey),
+		interval:  interval,
+		sourceURL: sourceURL,
+	}, nil
+}
+
+// getSourceParameters returns the interval and the source URL of the publisher role, the logs are read from
+// the source URL at the interval by the feature.
+func getSourceParameters(cmd *cobra.Command, baseURL, feature string) (time.Duration, string, error) {
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, publishIntervalFlagName, publishIntervalEnvKey)
+	if intervalStr == "" {
+		intervalStr = defaultPublishInterval
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return 0, "", fmt.Errorf("publish interval is not a valid duration: %s", intervalStr)
+	}
+
+	sourceURL := cmdutils.GetUserSetOptionalVarFromString(cmd, publishSourceURLFlagName, publishSourceURLEnvKey)
+	if sourceURL == "" {
+		sourceURL = baseURL
+	}
+
+	if sourceURL == "" {
+		return 0, "", fmt.Errorf("%s: neither %s nor %s is set", feature, publishSourceURLFlagName, baseURLFlagName)
+	}
+
+	return interval, strings.TrimSuffix(sourceURL, "/"), nil
+}
+
+func createIPFSFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(ipfsAPIURLFlagName, "", ipfsAPIURLFlagUsage)
+	startCmd.Flags().String(ipfsPinningServiceURLFlagName, "", ipfsPinningServiceURLFlagUsage)
+	startCmd.Flags().String(ipfsPinningServiceTokenFlagName, "", ipfsPinningServiceTokenFlagUsage)
+}
+
+// getIPFSRoots returns the stored roots of the mirrors of the logs.
+func getIPFSRoots(cfg storage.Store, logs []command.Log) (map[string]*command.IPFSRoot, error) {
+	roots := map[string]*command.IPFSRoot{}
+
+	for _, log := range logs {
+		src, err := cfg.Get(ipfsRootKey + log.Alias)
+		if errors.Is(err, storage.ErrDataNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("get IPFS root of log %s: %w", log.Alias, err)
+		}
+
+		var root *command.IPFSRoot
+		if err = json.Unmarshal(src, &root); err != nil {
+			return nil, fmt.Errorf("unmarshal IPFS root of log %s: %w", log.Alias, err)
+		}
+
+		roots[log.Alias] = root
+	}
+
+	return roots, nil
+}
+
+// startIPFSMirrors starts mirroring the logs to IPFS, every new root is stored and advertised by the command.
+func startIPFSMirrors(params *ipfsParameters, cmd *command.Cmd, cfg storage.Store, roots map[string]*command.IPFSRoot,
+	aliases []string, readToken string, httpClient publisher.HTTPClient) {
+	node := publisher.NewKuboNode(params.apiURL, httpClient)
+
+	for _, alias := range aliases {
+		alias := alias
+
+		opts := []publisher.IPFSOpt{
+			publisher.WithOnRoot(func(root *command.IPFSRoot) error {
+				src, err := json.Marshal(root)
+				if err != nil {
+					return fmt.Errorf("marshal IPFS root: %w", err)
+				}
+
+				if err = cfg.Put(ipfsRootKey+alias, src); err != nil {
+					return fmt.Errorf("store IPFS root: %w", err)
+				}
+
+				return cmd.SetIPFSRoot(alias, root) // nolint: wrapcheck
+			}),
+		}
+
+		if root, ok := roots[alias]; ok {
+			opts = append(opts, publisher.WithRoot(root.CID))
+		}
+
+		if params.pinningURL != "" {
+			opts = append(opts, publisher.WithPinningService(
+				publisher.NewPinningServiceClient(params.pinningURL, params.pinningToken, httpClient)))
+		}
+
+		bucket := publisher.NewIPFSBucket(node, alias, opts...)
+		source := vct.New(params.sourceURL+"/"+alias, vct.WithAuthReadToken(readToken))
+
+		go publisher.New(source, bucket).Run(context.Background(), params.interval)
+	}
+}
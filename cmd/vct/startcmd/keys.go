@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+// KeyStore gives the operator tools access to the keys the service keeps in its stores: the keys of the local KMS,
+// the ID of the active key of the log and the keystore of the web KMS.
+type KeyStore struct {
+	provider storeProvider
+	config   storage.Store
+}
+
+// OpenKeyStore opens the stores of the service at the datasource (see the dsn, database-prefix and timeout flags).
+func OpenKeyStore(dbURL, prefix string, timeout uint64) (*KeyStore, error) {
+	provider, err := createStoreProvider(dbURL, prefix, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("create store provider: %w", err)
+	}
+
+	config, err := provider.OpenStore("config")
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	return &KeyStore{provider: provider, config: config}, nil
+}
+
+// LocalKMS returns the local KMS of the service (kms type local).
+func (s *KeyStore) LocalKMS() (*localkms.LocalKMS, error) {
+	km, err := localkms.New(defaultMasterKeyURI, &kmsProvider{
+		storageProvider: s.provider,
+		secretLock:      &noop.NoLock{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create kms: %w", err)
+	}
+
+	return km, nil
+}
+
+// ActiveKeyID returns the ID of the key the log signs with unless it is set with the log-active-key-id flag, or an
+// empty string if the service has not created it yet.
+func (s *KeyStore) ActiveKeyID() (string, error) {
+	var keyID string
+
+	if err := s.get(kidKey, &keyID); err != nil {
+		return "", err
+	}
+
+	return keyID, nil
+}
+
+// Activate records the key as the key the log signs with, the service signs with it once restarted.
+func (s *KeyStore) Activate(keyID string) error {
+	src, err := json.Marshal(keyID)
+	if err != nil {
+		return fmt.Errorf("marshal key ID: %w", err)
+	}
+
+	if err = s.config.Put(kidKey, src); err != nil {
+		return fmt.Errorf("put config value for %q: %w", kidKey, err)
+	}
+
+	return nil
+}
+
+// WebKeyStore returns the URL of the keystore the service created at the web KMS endpoint (kms type web), or an
+// empty string if the service has not created it yet.
+func (s *KeyStore) WebKeyStore(kmsEndpoint string) (string, error) {
+	var keystoreURL string
+
+	if err := s.get(webKeyStoreKey, &keystoreURL); err != nil || keystoreURL == "" {
+		return "", err
+	}
+
+	return BuildKMSURL(kmsEndpoint, keystoreURL), nil
+}
+
+// Close closes the stores.
+func (s *KeyStore) Close() error {
+	return s.provider.Close() // nolint: wrapcheck
+}
+
+func (s *KeyStore) get(key string, v interface{}) error {
+	src, err := s.config.Get(key)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("get config value for %q: %w", key, err)
+	}
+
+	return json.Unmarshal(src, v) // nolint: wrapcheck
+}
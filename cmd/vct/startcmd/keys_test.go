@@ -0,0 +1,46 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/cmd/vct/startcmd"
+)
+
+func TestKeyStore(t *testing.T) {
+	_, err := startcmd.OpenKeyStore("mem", "", 0)
+	require.Contains(t, err.Error(), "create store provider")
+
+	store, err := startcmd.OpenKeyStore("mem://test", "", 0)
+	require.NoError(t, err)
+
+	defer func() { require.NoError(t, store.Close()) }()
+
+	keyID, err := store.ActiveKeyID()
+	require.NoError(t, err)
+	require.Empty(t, keyID)
+
+	km, err := store.LocalKMS()
+	require.NoError(t, err)
+
+	keyID, _, err = km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Activate(keyID))
+
+	active, err := store.ActiveKeyID()
+	require.NoError(t, err)
+	require.Equal(t, keyID, active)
+
+	keystoreURL, err := store.WebKeyStore("https://kms.example.com")
+	require.NoError(t, err)
+	require.Empty(t, keystoreURL)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	keyUsageThresholdsFlagName  = "key-usage-thresholds"
+	keyUsageThresholdsFlagUsage = "Comma-separated max numbers of signatures of a kind (sct, sth, statement, response," +
+		" admin, witness) produced with the keys of the log within a minute, e.g. sct:1000,sth:100. A higher volume" +
+		" is reported as an anomaly." +
+		" Alternatively, this can be set with the following environment variable: " + keyUsageThresholdsEnvKey
+	keyUsageThresholdsEnvKey = envPrefix + "KEY_USAGE_THRESHOLDS"
+)
+
+func getKeyUsageThresholds(cmd *cobra.Command) (map[command.SignatureKind]uint64, error) {
+	thresholdsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, keyUsageThresholdsFlagName,
+		keyUsageThresholdsEnvKey)
+	if thresholdsStr == "" {
+		return nil, nil
+	}
+
+	thresholds := map[command.SignatureKind]uint64{}
+
+	for _, threshold := range strings.Split(thresholdsStr, ",") {
+		parts := strings.Split(strings.TrimSpace(threshold), ":")
+		if len(parts) != 2 { // nolint: gomnd
+			return nil, fmt.Errorf("key usage threshold %q must be <kind>:<max>", threshold)
+		}
+
+		kind := command.SignatureKind(parts[0])
+
+		switch kind {
+		case command.SCTSignature, command.STHSignature, command.StatementSignature, command.ResponseSignature,
+			command.AdminSignature, command.WitnessSignature:
+		default:
+			return nil, fmt.Errorf("key usage threshold %q: unknown kind %s", threshold, kind)
+		}
+
+		max, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("key usage threshold %q: %w", threshold, err)
+		}
+
+		thresholds[kind] = max
+	}
+
+	return thresholds, nil
+}
+
+func createKeyUsageFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(keyUsageThresholdsFlagName, "", keyUsageThresholdsFlagUsage)
+}
@@ -0,0 +1,349 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/google/trillian/monitoring"
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	webcrypto "github.com/hyperledger/aries-framework-go/pkg/crypto/webkms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+	awssvc "github.com/trustbloc/kms/pkg/aws"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/signer"
+)
+
+// kmsMode kms mode.
+type kmsMode string
+
+// kms params.
+const (
+	kmsLocal  kmsMode = "local"
+	kmsWeb    kmsMode = "web"
+	kmsAWS    kmsMode = "aws"
+	kmsSigner kmsMode = "signer"
+
+	kmsTypeFlagName  = "kms-type"
+	kmsTypeEnvKey    = envPrefix + "KMS_TYPE"
+	kmsTypeFlagUsage = "KMS type (local,web,aws,signer). The signer type signs with a remote signer" +
+		" (comma-separated replicas in kms-endpoint) with the key of log-active-key-id." +
+		" Alternatively, this can be set with the following environment variable: " + kmsTypeEnvKey
+
+	kmsEndpointFlagName  = "kms-endpoint"
+	kmsEndpointEnvKey    = envPrefix + "KMS_ENDPOINT"
+	kmsEndpointFlagUsage = "KMS URL." +
+		" Alternatively, this can be set with the following environment variable: " + kmsEndpointEnvKey
+
+	logSignActiveKeyIDFlagName  = "log-active-key-id"
+	logSignActiveKeyIDEnvKey    = envPrefix + "LOG_SIGN_ACTIVE_KEY_ID"
+	logSignActiveKeyIDFlagUsage = "Log Sign Active Key ID." +
+		" Alternatively, this can be set with the following environment variable: " + logSignActiveKeyIDEnvKey
+
+	signerAuthTokenFlagName  = "signer-auth-token"
+	signerAuthTokenEnvKey    = envPrefix + "SIGNER_AUTH_TOKEN"
+	signerAuthTokenFlagUsage = "Bearer token of the requests to the remote signer (kms type signer)." +
+		" Alternatively, this can be set with the following environment variable: " + signerAuthTokenEnvKey
+)
+
+type kmsParameters struct {
+	kmsType            kmsMode
+	kmsEndpoint        string
+	logSignActiveKeyID string
+	signerAuthToken    string
+}
+
+type keyManager interface {
+	Create(kt kms.KeyType) (string, interface{}, error)
+	Get(keyID string) (interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error)
+	HealthCheck() error
+}
+
+type crypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+func getKmsParameters(cmd *cobra.Command) (*kmsParameters, error) {
+	kmsTypeStr, err := cmdutils.GetUserSetVarFromString(cmd, kmsTypeFlagName, kmsTypeEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	kmsType := kmsMode(kmsTypeStr)
+
+	if !supportedKmsType(kmsType) {
+		return nil, fmt.Errorf("unsupported kms type: %s", kmsType)
+	}
+
+	kmsEndpoint := cmdutils.GetUserSetOptionalVarFromString(cmd, kmsEndpointFlagName, kmsEndpointEnvKey)
+	logSignActiveKeyID := cmdutils.GetUserSetOptionalVarFromString(cmd, logSignActiveKeyIDFlagName,
+		logSignActiveKeyIDEnvKey)
+
+	// keys are managed by the remote signer, the log signs with a key of the signer
+	if kmsType == kmsSigner && (kmsEndpoint == "" || logSignActiveKeyID == "") {
+		return nil, fmt.Errorf("kms type %s requires %s and %s", kmsSigner, kmsEndpointFlagName,
+			logSignActiveKeyIDFlagName)
+	}
+
+	return &kmsParameters{
+		kmsType:            kmsType,
+		kmsEndpoint:        kmsEndpoint,
+		logSignActiveKeyID: logSignActiveKeyID,
+		signerAuthToken:    cmdutils.GetUserSetOptionalVarFromString(cmd, signerAuthTokenFlagName, signerAuthTokenEnvKey),
+	}, nil
+}
+
+func supportedKmsType(kmsType kmsMode) bool {
+	if kmsType != kmsLocal && kmsType != kmsWeb && kmsType != kmsAWS && kmsType != kmsSigner {
+		return false
+	}
+
+	return true
+}
+
+func createKMSAndCrypto(parameters *agentParameters, client *http.Client,
+	store storage.Provider, cfg storage.Store, mf monitoring.MetricFactory) (keyManager, crypto, error) {
+	switch parameters.kmsParams.kmsType {
+	case kmsLocal:
+		km, err := localkms.New(defaultMasterKeyURI, &kmsProvider{
+			storageProvider: store,
+			secretLock:      &noop.NoLock{},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("create kms: %w", err)
+		}
+
+		cr, err := tinkcrypto.New()
+		if err != nil {
+			return nil, nil, fmt.Errorf("create crypto: %w", err)
+		}
+
+		return km, cr, nil
+	case kmsWeb:
+		if strings.Contains(parameters.kmsParams.kmsEndpoint, "keystores") {
+			return webkms.New(parameters.kmsParams.kmsEndpoint, client),
+				webcrypto.New(parameters.kmsParams.kmsEndpoint, client), nil
+		}
+
+		var keystoreURL string
+
+		err := getOrInit(cfg, webKeyStoreKey, &keystoreURL, func() (interface{}, error) {
+			location, _, err := webkms.CreateKeyStore(client, parameters.kmsParams.kmsEndpoint, uuid.New().String(), "", nil)
+
+			return location, err
+		}, parameters.syncTimeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get or init: %w", err)
+		}
+
+		keystoreURL = BuildKMSURL(parameters.kmsParams.kmsEndpoint, keystoreURL)
+
+		return webkms.New(keystoreURL, client), webcrypto.New(keystoreURL, client), nil
+	case kmsAWS:
+		awsSvc, err := NewAWSKMS(parameters.kmsParams.kmsEndpoint, parameters.kmsParams.logSignActiveKeyID, mf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return awsSvc, awsSvc, nil
+	case kmsSigner:
+		remote, err := signer.New(strings.Split(parameters.kmsParams.kmsEndpoint, ","),
+			signer.WithHTTPClient(client), signer.WithAuthToken(parameters.kmsParams.signerAuthToken))
+		if err != nil {
+			return nil, nil, fmt.Errorf("create signer client: %w", err)
+		}
+
+		return remote, remote, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported kms type: %s", parameters.kmsParams.kmsType)
+}
+
+// NewAWSKMS returns the AWS KMS of the key (kms type aws), the region is taken from the URI of the key.
+func NewAWSKMS(kmsEndpoint, keyURI string, mf monitoring.MetricFactory) (*awssvc.Service, error) {
+	region, err := getRegion(keyURI)
+	if err != nil {
+		return nil, err
+	}
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Endpoint:                      &kmsEndpoint,
+		Region:                        aws.String(region),
+		CredentialsChainVerboseErrors: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	return awssvc.New(awsSession, NewAWSMetricsProvider(mf)), nil
+}
+
+func getRegion(keyURI string) (string, error) {
+	// keyURI must have the following format: 'aws-kms://arn:<partition>:kms:<region>:[:path]'.
+	// See http://docs.aws.amazon.com/general/latest/gr/aws-arns-and-namespaces.html.
+	re1 := regexp.MustCompile(`aws-kms://arn:(aws[a-zA-Z0-9-_]*):kms:([a-z0-9-]+):`)
+
+	r := re1.FindStringSubmatch(keyURI)
+
+	const subStringCount = 3
+
+	if len(r) != subStringCount {
+		return "", fmt.Errorf("extracting region from URI failed")
+	}
+
+	return r[2], nil
+}
+
+// BuildKMSURL builds kms URL.
+func BuildKMSURL(base, uri string) string {
+	if strings.HasPrefix(uri, "/") {
+		return base + uri
+	}
+
+	return uri
+}
+
+func createKID(km keyManager, cfg storage.Store, syncTimeout uint64) (string, error) {
+	var (
+		keyID   string
+		keyType = kms.ECDSAP256TypeIEEEP1363
+	)
+
+	err := getOrInit(cfg, kidKey, &keyID, func() (interface{}, error) {
+		kid, _, err := km.Create(keyType)
+
+		return kid, err // nolint: wrapcheck
+	}, syncTimeout)
+
+	return keyID, err
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
+
+// AWSMetricsProvider aws metrics provider.
+type AWSMetricsProvider struct {
+	signCount            monitoring.Counter
+	signTime             monitoring.Histogram
+	exportPublicKeyCount monitoring.Counter
+	exportPublicKeyTime  monitoring.Histogram
+}
+
+// NewAWSMetricsProvider return new instance.
+func NewAWSMetricsProvider(mf monitoring.MetricFactory) *AWSMetricsProvider {
+	if mf == nil {
+		mf = monitoring.InertMetricFactory{}
+	}
+
+	signCount := mf.NewCounter("aws_sign_count", "AWS sign count")
+	signTime := mf.NewHistogram("aws_sign_seconds", "AWS sign time")
+	exportPublicKeyCount := mf.NewCounter("aws_export_publickey_count", "AWS export public key count")
+	exportPublicKeyTime := mf.NewHistogram("aws_export_publickey_seconds", "AWS export public key time")
+
+	return &AWSMetricsProvider{
+		signCount:            signCount,
+		signTime:             signTime,
+		exportPublicKeyCount: exportPublicKeyCount,
+		exportPublicKeyTime:  exportPublicKeyTime,
+	}
+}
+
+// SignCount increments the number of sign hits.
+func (a *AWSMetricsProvider) SignCount() {
+	a.signCount.Inc()
+}
+
+// SignTime records the time for sign.
+func (a *AWSMetricsProvider) SignTime(value time.Duration) {
+	a.signTime.Observe(value.Seconds())
+}
+
+// ExportPublicKeyCount increments the number of export public key hits.
+func (a *AWSMetricsProvider) ExportPublicKeyCount() {
+	a.exportPublicKeyCount.Inc()
+}
+
+// ExportPublicKeyTime records the time for export public key.
+func (a *AWSMetricsProvider) ExportPublicKeyTime(value time.Duration) {
+	a.exportPublicKeyTime.Observe(value.Seconds())
+}
+
+// VerifyCount increments the number of verify hits.
+func (a *AWSMetricsProvider) VerifyCount() {}
+
+// VerifyTime records the time for verify.
+func (a *AWSMetricsProvider) VerifyTime(value time.Duration) {}
+
+// setKeys sets the key of the log, the keys of the signing purposes and the envelope key of the extra data.
+func setKeys(cfg *command.Config, km keyManager, configStore storage.Store, parameters *agentParameters) error {
+	keyID, err := getLogKeyID(km, configStore, parameters)
+	if err != nil {
+		return err
+	}
+
+	cfg.Key = command.Key{ID: keyID}
+
+	cfg.PurposeKeys, err = createPurposeKeys(km, configStore, parameters.signingKeyPurposes, parameters.syncTimeout)
+	if err != nil {
+		return err
+	}
+
+	return setExtraDataKey(cfg, km, configStore, parameters)
+}
+
+// getLogKeyID returns the ID of the key of the log: the active key ID if it is set, the key derived from the seed of
+// vct dev, or the key created once otherwise.
+func getLogKeyID(km keyManager, cfg storage.Store, parameters *agentParameters) (string, error) {
+	keyID := parameters.kmsParams.logSignActiveKeyID
+	if keyID != "" {
+		return keyID, nil
+	}
+
+	if parameters.devKeySeed != "" {
+		return importDevKey(km, parameters.devKeySeed)
+	}
+
+	keyID, err := createKID(km, cfg, parameters.syncTimeout)
+	if err != nil {
+		return "", fmt.Errorf("create kid: %w", err)
+	}
+
+	return keyID, nil
+}
+
+func createKMSFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(kmsTypeFlagName, "", kmsTypeFlagUsage)
+	startCmd.Flags().String(kmsEndpointFlagName, "", kmsEndpointFlagUsage)
+	startCmd.Flags().String(logSignActiveKeyIDFlagName, "", logSignActiveKeyIDFlagUsage)
+	startCmd.Flags().String(signerAuthTokenFlagName, "", signerAuthTokenFlagUsage)
+}
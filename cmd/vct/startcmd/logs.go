@@ -0,0 +1,211 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	logsFlagName      = "logs"
+	logsEnvKey        = envPrefix + "LOGS"
+	logsFlagShorthand = "l"
+	logsFlagUsage     = "Trillian logs comma separated. " +
+		" Format must be <alias>:<permission>@<endpoint>." +
+		" Examples: maple2021:rw@server.com,maple2020:r@server.com:9890" +
+		" Alternatively, this can be set with the following environment variable: " + logsEnvKey
+
+	issuersFlagName  = "issuers"
+	issuersFlagUsage = "Comma-Separated list of supported issuers." +
+		" Alternatively, this can be set with the following environment variable: " + issuersEnvKey
+	issuersEnvKey = envPrefix + "ISSUERS"
+
+	logReadReplicasFlagName  = "log-read-replicas"
+	logReadReplicasFlagUsage = "Comma-Separated list of Trillian read replicas used to serve read endpoints." +
+		" Format must be <alias>@<endpoint>. Examples: maple2021@replica.eu.com:8090" +
+		" Alternatively, this can be set with the following environment variable: " + logReadReplicasEnvKey
+	logReadReplicasEnvKey = envPrefix + "LOG_READ_REPLICAS"
+
+	logShadowsFlagName  = "log-shadows"
+	logShadowsFlagUsage = "Comma-Separated list of Trillian servers the writes of a log are mirrored to" +
+		" (dual-write shadow mode), a new tree is created for each shadow log." +
+		" Differences between the log and its shadow are reported by the get-shadow-status endpoint." +
+		" Format must be <alias>@<endpoint>. Examples: maple2021@new-trillian.com:8090" +
+		" Alternatively, this can be set with the following environment variable: " + logShadowsEnvKey
+	logShadowsEnvKey = envPrefix + "LOG_SHADOWS"
+
+	logTenantsFlagName  = "log-tenants"
+	logTenantsFlagUsage = "Comma-Separated list of the tenants (customers) of the logs, the metrics and the usage" +
+		" reported by the /admin/usage endpoint are labeled with the tenant. A log without a tenant is its own tenant." +
+		" Format must be <alias>@<tenant>. Examples: maple2020@maple,maple2021@maple" +
+		" Alternatively, this can be set with the following environment variable: " + logTenantsEnvKey
+	logTenantsEnvKey = envPrefix + "LOG_TENANTS"
+)
+
+// getLogs returns the logs with their issuers, read replicas, shadows and tenants, and whether the embedded Trillian
+// serves the logs without an endpoint.
+func getLogs(cmd *cobra.Command) ([]command.Log, bool, error) {
+	logsVal, err := cmdutils.GetUserSetVarFromString(cmd, logsFlagName, logsEnvKey, false)
+	if err != nil {
+		return nil, false, fmt.Errorf("get variable (%s or %s): %w", logsFlagName, logsEnvKey, err)
+	}
+
+	logs, startTrillian := parseLogs(logsVal, getList(cmd, issuersFlagName, issuersEnvKey))
+
+	logs, err = parseReadReplicas(logs, getList(cmd, logReadReplicasFlagName, logReadReplicasEnvKey))
+	if err != nil {
+		return nil, false, fmt.Errorf("parse read replicas: %w", err)
+	}
+
+	logs, err = parseShadows(logs, getList(cmd, logShadowsFlagName, logShadowsEnvKey))
+	if err != nil {
+		return nil, false, fmt.Errorf("parse shadows: %w", err)
+	}
+
+	logs, err = parseTenants(logs, getList(cmd, logTenantsFlagName, logTenantsEnvKey))
+	if err != nil {
+		return nil, false, fmt.Errorf("parse tenants: %w", err)
+	}
+
+	return logs, startTrillian, nil
+}
+
+// aliasesOf returns the aliases of the logs.
+func aliasesOf(logs []command.Log) []string {
+	aliases := make([]string, 0, len(logs))
+	for _, l := range logs {
+		aliases = append(aliases, l.Alias)
+	}
+
+	return aliases
+}
+
+func parseLogs(logsRaw string, issuersRaw []string) ([]command.Log, bool) { //nolint:funlen
+	logsSet := map[string]command.Log{}
+
+	issuersSet := map[string]map[string]struct{}{}
+
+	starTrillian := false
+
+	for _, issuerRaw := range issuersRaw {
+		parts := strings.Split(issuerRaw, "@")
+
+		alias := strings.TrimSpace(parts[0])
+		issuer := strings.TrimSpace(parts[1])
+
+		if _, ok := issuersSet[alias]; !ok {
+			issuersSet[alias] = map[string]struct{}{}
+		}
+
+		issuersSet[alias][issuer] = struct{}{}
+	}
+
+	for _, rawLog := range strings.Split(logsRaw, ",") {
+		var alias string
+
+		var permission string
+
+		var endpoint string
+
+		if strings.Contains(rawLog, "@") {
+			parts := strings.Split(rawLog, "@")
+			apParts := strings.Split(parts[0], ":")
+
+			alias = apParts[0]
+			permission = apParts[1]
+			endpoint = parts[1]
+		} else {
+			parts := strings.Split(rawLog, ":")
+			alias = parts[0]
+			permission = parts[1]
+			endpoint = embeddedLogServerHost
+			starTrillian = true
+		}
+
+		logEntity := command.Log{
+			Alias:      strings.TrimSpace(alias),
+			Permission: strings.TrimSpace(permission),
+			Endpoint:   strings.TrimSpace(endpoint),
+		}
+
+		var issuers []string
+		for issuer := range issuersSet[logEntity.Alias] {
+			issuers = append(issuers, issuer)
+		}
+
+		logEntity.Issuers = issuers
+
+		logsSet[logEntity.Alias] = logEntity
+	}
+
+	var result []command.Log
+	for _, v := range logsSet {
+		result = append(result, v)
+	}
+
+	return result, starTrillian
+}
+
+func parseReadReplicas(logs []command.Log, replicasRaw []string) ([]command.Log, error) {
+	return parseLogEndpoints(logs, replicasRaw, "read replica", "endpoint", func(log *command.Log, endpoint string) {
+		log.ReadEndpoint = endpoint
+	})
+}
+
+func parseShadows(logs []command.Log, shadowsRaw []string) ([]command.Log, error) {
+	return parseLogEndpoints(logs, shadowsRaw, "shadow", "endpoint", func(log *command.Log, endpoint string) {
+		log.ShadowEndpoint = endpoint
+	})
+}
+
+func parseTenants(logs []command.Log, tenantsRaw []string) ([]command.Log, error) {
+	return parseLogEndpoints(logs, tenantsRaw, "tenant", "tenant", func(log *command.Log, tenant string) {
+		log.Tenant = tenant
+	})
+}
+
+// parseLogEndpoints parses <alias>@<value> values (e.g. an endpoint) and sets the value of the log with the alias.
+func parseLogEndpoints(logs []command.Log, raw []string, name, value string,
+	set func(log *command.Log, endpoint string)) ([]command.Log, error) {
+	const endpointParts = 2
+
+	endpoints := map[string]string{}
+
+	for _, val := range raw {
+		parts := strings.SplitN(val, "@", endpointParts)
+		if len(parts) != endpointParts {
+			return nil, fmt.Errorf("invalid %s %q, format must be <alias>@<%s>", name, val, value)
+		}
+
+		endpoints[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	for i := range logs {
+		set(&logs[i], endpoints[logs[i].Alias])
+		delete(endpoints, logs[i].Alias)
+	}
+
+	for alias := range endpoints {
+		return nil, fmt.Errorf("%s for unknown log %q", name, alias)
+	}
+
+	return logs, nil
+}
+
+func createLogsFlags(startCmd *cobra.Command) {
+	startCmd.Flags().StringP(logsFlagName, logsFlagShorthand, "", logsFlagUsage)
+	startCmd.Flags().String(issuersFlagName, "", issuersFlagUsage)
+	startCmd.Flags().String(logReadReplicasFlagName, "", logReadReplicasFlagUsage)
+	startCmd.Flags().String(logShadowsFlagName, "", logShadowsFlagUsage)
+	startCmd.Flags().String(logTenantsFlagName, "", logTenantsFlagUsage)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/storage/migration"
+)
+
+const (
+	autoMigrateFlagName  = "auto-migrate"
+	autoMigrateFlagUsage = "Apply pending migrations of the VCT stores on startup." +
+		" If disabled, the service refuses to start until 'vct migrate' is run." +
+		" Possible values [true] [false]. Defaults to true if not set." +
+		" Alternatively, this can be set with the following environment variable: " + autoMigrateEnvKey
+	autoMigrateEnvKey = envPrefix + "AUTO_MIGRATE"
+)
+
+// storeMigrations returns migrations of the VCT stores, a new migration must be appended with the next version.
+func storeMigrations() []migration.Migration {
+	return []migration.Migration{{
+		Version:     1,
+		Description: "create config store",
+		Migrate: func(provider storage.Provider) error {
+			_, err := provider.OpenStore("config")
+
+			return err // nolint: wrapcheck
+		},
+	}, {
+		Version:     2,
+		Description: "create receipts, annotations and timeindex stores",
+		Migrate: func(provider storage.Provider) error {
+			for _, name := range []string{"receipts", "annotations", "timeindex"} {
+				if _, err := provider.OpenStore(name); err != nil {
+					return fmt.Errorf("open store %s: %w", name, err)
+				}
+			}
+
+			return nil
+		},
+	}, {
+		Version:     3,
+		Description: "create dedup store indexed by the dedup tag",
+		Migrate: func(provider storage.Provider) error {
+			if _, err := provider.OpenStore(dedupStoreName); err != nil {
+				return fmt.Errorf("open store %s: %w", dedupStoreName, err)
+			}
+
+			err := provider.SetStoreConfig(dedupStoreName,
+				storage.StoreConfiguration{TagNames: []string{command.DedupTagName}})
+			if err != nil {
+				return fmt.Errorf("set store config %s: %w", dedupStoreName, err)
+			}
+
+			return nil
+		},
+	}}
+}
+
+// MigrateCmd returns the Cobra migrate command.
+func MigrateCmd() *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrates vct stores",
+		Long:  `Applies pending migrations of the verifiable credentials transparency stores`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			datasourceName := cmdutils.GetUserSetOptionalVarFromString(cmd, datasourceNameFlagName,
+				datasourceNameEnvKey)
+			databasePrefix := cmdutils.GetUserSetOptionalVarFromString(cmd, databasePrefixFlagName,
+				databasePrefixEnvKey)
+			timeoutStr := cmdutils.GetUserSetOptionalVarFromString(cmd, timeoutFlagName, timeoutEnvKey)
+
+			if timeoutStr == "" {
+				timeoutStr = defaultTimeout
+			}
+
+			timeout, err := strconv.ParseUint(timeoutStr, 10, 64)
+			if err != nil {
+				return fmt.Errorf("timeout is not a number(positive): %w", err)
+			}
+
+			store, err := createStoreProvider(datasourceName, databasePrefix, timeout)
+			if err != nil {
+				return fmt.Errorf("create store provider: %w", err)
+			}
+
+			defer func() {
+				if err = store.Close(); err != nil {
+					logger.Errorf("store close: %v", err)
+				}
+			}()
+
+			return migrateStores(store, true)
+		},
+	}
+
+	migrateCmd.Flags().StringP(datasourceNameFlagName, datasourceNameFlagShorthand, "mem://test", datasourceNameFlagUsage)
+	migrateCmd.Flags().String(databasePrefixFlagName, "", databasePrefixFlagUsage)
+	migrateCmd.Flags().String(timeoutFlagName, "0", timeoutFlagUsage)
+
+	return migrateCmd
+}
+
+func migrateStores(provider storage.Provider, apply bool) error {
+	migrator, err := migration.New(provider, storeMigrations()...)
+	if err != nil {
+		return fmt.Errorf("create migrator: %w", err)
+	}
+
+	if apply {
+		if err = migrator.Migrate(); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+
+		return nil
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return fmt.Errorf("pending migrations: %w", err)
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migration(s) of the stores, run 'vct migrate' or enable %s",
+			len(pending), autoMigrateFlagName)
+	}
+
+	return nil
+}
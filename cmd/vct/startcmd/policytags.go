@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	policyTagsJurisdictionsFlagName  = "policy-tags-jurisdictions"
+	policyTagsJurisdictionsFlagUsage = "Comma-separated list of the jurisdictions (ISO 3166 codes, e.g. CA,FR) the" +
+		" policy tags of the submissions may have, a country accepts its subdivisions (e.g. CA-QC). Any" +
+		" jurisdiction is accepted if not set." +
+		" Alternatively, this can be set with the following environment variable: " + policyTagsJurisdictionsEnvKey
+	policyTagsJurisdictionsEnvKey = envPrefix + "POLICY_TAGS_JURISDICTIONS"
+
+	policyTagsAssuranceLevelsFlagName  = "policy-tags-assurance-levels"
+	policyTagsAssuranceLevelsFlagUsage = "Comma-separated list of the assurance levels the policy tags of the" +
+		" submissions may have. Defaults to the eIDAS levels (low,substantial,high) if not set." +
+		" Alternatively, this can be set with the following environment variable: " +
+		policyTagsAssuranceLevelsEnvKey
+	policyTagsAssuranceLevelsEnvKey = envPrefix + "POLICY_TAGS_ASSURANCE_LEVELS"
+)
+
+// getPolicyTags returns the schema of the policy tags of the submissions, nil if the default schema is used. The
+// schema is validated by the command.
+func getPolicyTags(cmd *cobra.Command) *command.PolicyTagsSchema {
+	jurisdictionsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, policyTagsJurisdictionsFlagName,
+		policyTagsJurisdictionsEnvKey)
+	levelsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, policyTagsAssuranceLevelsFlagName,
+		policyTagsAssuranceLevelsEnvKey)
+
+	if jurisdictionsStr == "" && levelsStr == "" {
+		return nil
+	}
+
+	schema := &command.PolicyTagsSchema{}
+
+	for dst, str := range map[*[]string]string{
+		&schema.Jurisdictions:   jurisdictionsStr,
+		&schema.AssuranceLevels: levelsStr,
+	} {
+		if str == "" {
+			continue
+		}
+
+		for _, val := range strings.Split(str, ",") {
+			*dst = append(*dst, strings.TrimSpace(val))
+		}
+	}
+
+	return schema
+}
+
+func createPolicyTagsFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(policyTagsJurisdictionsFlagName, "", policyTagsJurisdictionsFlagUsage)
+	startCmd.Flags().String(policyTagsAssuranceLevelsFlagName, "", policyTagsAssuranceLevelsFlagUsage)
+}
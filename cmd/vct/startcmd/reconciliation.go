@@ -0,0 +1,68 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+const (
+	reconciliationIntervalFlagName  = "reconciliation-interval"
+	reconciliationIntervalFlagUsage = "Interval the counters of the logs (the submissions accepted, the entries of" +
+		" the dedup store and the leaves indexed) are reconciled with the leaves sequenced by Trillian at (e.g. 5m)," +
+		" the drift is reported by metrics and on the admin path " + rest.ReconciliationPath + "." +
+		" Not reconciled if not set." +
+		" Alternatively, this can be set with the following environment variable: " + reconciliationIntervalEnvKey
+	reconciliationIntervalEnvKey = envPrefix + "RECONCILIATION_INTERVAL"
+
+	reconciliationStaleAfterFlagName  = "reconciliation-stale-after"
+	reconciliationStaleAfterFlagUsage = "Age a submission which is not sequenced yet is reported stale at by the" +
+		" reconciliation (e.g. 30m). Defaults to 1h." +
+		" Alternatively, this can be set with the following environment variable: " + reconciliationStaleAfterEnvKey
+	reconciliationStaleAfterEnvKey = envPrefix + "RECONCILIATION_STALE_AFTER"
+)
+
+// getReconciliation returns the configuration of the reconciliation of the logs, nil if they are not reconciled.
+func getReconciliation(cmd *cobra.Command) (*command.ReconciliationConfig, error) {
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, reconciliationIntervalFlagName,
+		reconciliationIntervalEnvKey)
+	if intervalStr == "" {
+		return nil, nil
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("reconciliation interval is not a positive duration: %s", intervalStr)
+	}
+
+	cfg := &command.ReconciliationConfig{Interval: interval}
+
+	staleAfterStr := cmdutils.GetUserSetOptionalVarFromString(cmd, reconciliationStaleAfterFlagName,
+		reconciliationStaleAfterEnvKey)
+	if staleAfterStr == "" {
+		return cfg, nil
+	}
+
+	cfg.StaleAfter, err = time.ParseDuration(staleAfterStr)
+	if err != nil || cfg.StaleAfter <= 0 {
+		return nil, fmt.Errorf("reconciliation stale age is not a positive duration: %s", staleAfterStr)
+	}
+
+	return cfg, nil
+}
+
+func createReconciliationFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(reconciliationIntervalFlagName, "", reconciliationIntervalFlagUsage)
+	startCmd.Flags().String(reconciliationStaleAfterFlagName, "", reconciliationStaleAfterFlagUsage)
+}
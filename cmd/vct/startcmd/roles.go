@@ -0,0 +1,335 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	trillianstorage "github.com/google/trillian/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/cmd/internal/serverutil"
+	"github.com/trustbloc/vct/cmd/log_server/startcmd"
+	logsignerstart "github.com/trustbloc/vct/cmd/log_signer/startcmd"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/publisher"
+	"github.com/trustbloc/vct/pkg/storage/memory"
+	"github.com/trustbloc/vct/pkg/storage/postgres"
+)
+
+const (
+	rolesFlagName  = "roles"
+	rolesFlagUsage = "Comma-separated list of the roles the server runs: frontend (the REST API), sequencer (the" +
+		" embedded Trillian log server and log signer, which sequence the queued entries and sign the tree heads of" +
+		" the logs without an endpoint), publisher (publishes the checkpoints and tiles of the logs to a bucket) and" +
+		" witness (pushes the tree heads of the logs to the witness networks of " + sthDistributorURLsFlagName + ")." +
+		" The roles run in one process or separately, e.g. frontends configured with the endpoint of a sequencer" +
+		" (<alias>:<permission>@<sequencer-host>:8090) sharing its Trillian database. Only one sequencer may run per" +
+		" Trillian database, a second one fails to start. Defaults to frontend,sequencer,witness." +
+		" Alternatively, this can be set with the following environment variable: " + rolesEnvKey
+	rolesEnvKey = envPrefix + "ROLES"
+
+	publishBucketFlagName  = "publish-bucket"
+	publishBucketFlagUsage = "Bucket the logs are published to by the publisher role: s3://<bucket>/<prefix> or" +
+		" a directory. Each log is published under its alias." +
+		" Alternatively, this can be set with the following environment variable: " + publishBucketEnvKey
+	publishBucketEnvKey = envPrefix + "PUBLISH_BUCKET"
+
+	publishS3EndpointFlagName  = "publish-s3-endpoint"
+	publishS3EndpointFlagUsage = "Endpoint of a storage compatible with the S3 API the logs are published to" +
+		" (e.g. https://storage.googleapis.com for Google Cloud Storage). Defaults to AWS S3 if not set." +
+		" Alternatively, this can be set with the following environment variable: " + publishS3EndpointEnvKey
+	publishS3EndpointEnvKey = envPrefix + "PUBLISH_S3_ENDPOINT"
+
+	publishS3RegionFlagName  = "publish-s3-region"
+	publishS3RegionFlagUsage = "Region of the S3 bucket the logs are published to. Defaults to " +
+		publisher.DefaultS3Region + " if not set." +
+		" Alternatively, this can be set with the following environment variable: " + publishS3RegionEnvKey
+	publishS3RegionEnvKey = envPrefix + "PUBLISH_S3_REGION"
+
+	publishIntervalFlagName  = "publish-interval"
+	publishIntervalFlagUsage = "Interval the logs are published at (e.g. 30s). Defaults to " +
+		defaultPublishInterval + " if not set." +
+		" Alternatively, this can be set with the following environment variable: " + publishIntervalEnvKey
+	publishIntervalEnvKey = envPrefix + "PUBLISH_INTERVAL"
+
+	publishSourceURLFlagName  = "publish-source-url"
+	publishSourceURLFlagUsage = "URL of the frontend the published logs are read from (e.g. https://vct.example.com)." +
+		" Defaults to the base URL if not set." +
+		" Alternatively, this can be set with the following environment variable: " + publishSourceURLEnvKey
+	publishSourceURLEnvKey = envPrefix + "PUBLISH_SOURCE_URL"
+)
+
+const (
+	frontendRole  = "frontend"
+	sequencerRole = "sequencer"
+	publisherRole = "publisher"
+	witnessRole   = "witness"
+
+	defaultRoles           = frontendRole + "," + sequencerRole + "," + witnessRole
+	defaultPublishInterval = "1m"
+
+	// sequencerLockID is the key of the advisory lock of the Trillian database held by its sequencer.
+	sequencerLockID = 0x766374 // "vct"
+)
+
+type roles map[string]bool
+
+func (r roles) String() string {
+	var names []string
+
+	for name := range r {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
+
+type publishParameters struct {
+	bucket     string
+	s3Endpoint string
+	s3Region   string
+	interval   time.Duration
+	sourceURL  string
+}
+
+func getRoles(cmd *cobra.Command) (roles, error) {
+	rolesStr := cmdutils.GetUserSetOptionalVarFromString(cmd, rolesFlagName, rolesEnvKey)
+	if rolesStr == "" {
+		rolesStr = defaultRoles
+	}
+
+	result := roles{}
+
+	for _, role := range strings.Split(rolesStr, ",") {
+		role = strings.TrimSpace(role)
+
+		switch role {
+		case frontendRole, sequencerRole, publisherRole, witnessRole:
+			result[role] = true
+		default:
+			return nil, fmt.Errorf("unsupported role: %s", role)
+		}
+	}
+
+	return result, nil
+}
+
+func getPublishParameters(cmd *cobra.Command, baseURL string) (*publishParameters, error) {
+	bucket, err := cmdutils.GetUserSetVarFromString(cmd, publishBucketFlagName, publishBucketEnvKey, false)
+	if err != nil {
+		return nil, fmt.Errorf("publisher role: %w", err)
+	}
+
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, publishIntervalFlagName, publishIntervalEnvKey)
+	if intervalStr == "" {
+		intervalStr = defaultPublishInterval
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("publish interval is not a valid duration: %s", intervalStr)
+	}
+
+	sourceURL := cmdutils.GetUserSetOptionalVarFromString(cmd, publishSourceURLFlagName, publishSourceURLEnvKey)
+	if sourceURL == "" {
+		sourceURL = baseURL
+	}
+
+	if sourceURL == "" {
+		return nil, fmt.Errorf("publisher role: neither %s nor %s is set", publishSourceURLFlagName, baseURLFlagName)
+	}
+
+	return &publishParameters{
+		bucket:     bucket,
+		s3Endpoint: cmdutils.GetUserSetOptionalVarFromString(cmd, publishS3EndpointFlagName, publishS3EndpointEnvKey),
+		s3Region:   cmdutils.GetUserSetOptionalVarFromString(cmd, publishS3RegionFlagName, publishS3RegionEnvKey),
+		interval:   interval,
+		sourceURL:  strings.TrimSuffix(sourceURL, "/"),
+	}, nil
+}
+
+func createRolesFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(rolesFlagName, "", rolesFlagUsage)
+	startCmd.Flags().String(publishBucketFlagName, "", publishBucketFlagUsage)
+	startCmd.Flags().String(publishS3EndpointFlagName, "", publishS3EndpointFlagUsage)
+	startCmd.Flags().String(publishS3RegionFlagName, "", publishS3RegionFlagUsage)
+	startCmd.Flags().String(publishIntervalFlagName, "", publishIntervalFlagUsage)
+	startCmd.Flags().String(publishSourceURLFlagName, "", publishSourceURLFlagUsage)
+}
+
+// startRoles starts the embedded Trillian of the sequencer role and the publishers of the publisher role, it returns
+// whether the instance runs the agent: the frontend role or the STH distributors of the witness role (see
+// startAgent).
+func startRoles(cmd *cobra.Command, parameters *agentParameters) (bool, error) {
+	roles, err := getRoles(cmd)
+	if err != nil {
+		return false, err
+	}
+
+	parameters.frontend = roles[frontendRole]
+
+	// the witness role pushes the tree heads to the witness networks, it has nothing to run without them
+	if !roles[witnessRole] {
+		parameters.distributor = nil
+	}
+
+	var publish *publishParameters
+
+	if roles[publisherRole] {
+		publish, err = getPublishParameters(cmd, parameters.baseURL)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// the logs without an endpoint are served natively, the embedded Trillian is not needed
+	runSequencer := roles[sequencerRole] && parameters.embeddedTrillian && parameters.logBackend == trillianBackend
+
+	if !roles[frontendRole] && !roles[publisherRole] && !runSequencer && parameters.distributor == nil {
+		return false, fmt.Errorf("roles %s have nothing to run: the sequencer role only runs the embedded Trillian"+
+			" of the logs without an endpoint, the witness role only runs with %s", roles, sthDistributorURLsFlagName)
+	}
+
+	if runSequencer {
+		if err = startSequencer(parameters.trillianDBConn); err != nil {
+			return false, err
+		}
+	}
+
+	if publish != nil {
+		if err = startPublishers(publish, aliasesOf(parameters.logs), parameters.readToken); err != nil {
+			return false, err
+		}
+	}
+
+	if !roles[frontendRole] {
+		logger.Infof("Running the roles %s without the frontend", roles)
+	}
+
+	return roles[frontendRole] || parameters.distributor != nil, nil
+}
+
+// startSequencer starts the embedded Trillian log server and log signer, in memory if the db conn is not set. The
+// log signer is the master of all the trees (ForceMaster, there is no election): the sequencer holds an advisory
+// lock of the Trillian database, so a second sequencer of the database fails to start.
+func startSequencer(trillianDBConnStr string) error {
+	if trillianDBConnStr != "" {
+		if err := lockSequencer(trillianDBConnStr); err != nil {
+			return err
+		}
+	}
+
+	if err := trillianstorage.RegisterProvider("mem", memory.NewMemoryStorageProvider); err != nil {
+		logger.Errorf(err.Error())
+	}
+
+	postgres.PGConnStr = trillianDBConnStr
+
+	if err := trillianstorage.RegisterProvider("postgres", postgres.NewPGProvider); err != nil {
+		logger.Errorf(err.Error())
+	}
+
+	go func() {
+		startCMD := startcmd.CMD{
+			RPCEndpoint:              embeddedLogServerHost,
+			ETCDService:              "trillian-logserver",
+			QuotaSystem:              "noop",
+			StorageSystem:            "mem",
+			TreeDeleteThreshold:      serverutil.DefaultTreeDeleteThreshold,
+			TreeDeleteMinRunInterval: serverutil.DefaultTreeDeleteMinInterval,
+		}
+
+		if trillianDBConnStr != "" {
+			startCMD.PGConnStr = trillianDBConnStr
+			startCMD.StorageSystem = "postgres"
+		}
+
+		// start log server
+		if err := startCMD.Start(); err != nil {
+			panic(err)
+		}
+	}()
+
+	go func() {
+		startCMD := logsignerstart.CMD{
+			RPCEndpoint:           embeddedLogSignerHost,
+			HealthzTimeout:        5 * time.Second,        //nolint: gomnd
+			SequencerIntervalFlag: 100 * time.Millisecond, //nolint: gomnd
+			QuotaSystem:           "noop",
+			BatchSizeFlag:         1000, //nolint: gomnd
+			StorageSystem:         "mem",
+			NumSeqFlag:            10, //nolint: gomnd
+			ForceMaster:           true,
+			PREElectionPause:      1 * time.Second,
+		}
+
+		if trillianDBConnStr != "" {
+			startCMD.PGConnStr = trillianDBConnStr
+			startCMD.StorageSystem = "postgres"
+		}
+
+		// start log signer
+		if err := startCMD.Start(); err != nil {
+			panic(err)
+		}
+	}()
+
+	return nil
+}
+
+// lockSequencer takes the advisory lock of the sequencer of the Trillian database, the lock is held by the connection
+// until the process exits.
+func lockSequencer(trillianDBConnStr string) error {
+	db, err := sql.Open("postgres", trillianDBConnStr)
+	if err != nil {
+		return fmt.Errorf("sequencer role: open trillian db: %w", err)
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("sequencer role: connect trillian db: %w", err)
+	}
+
+	var locked bool
+
+	err = conn.QueryRowContext(context.Background(), "SELECT pg_try_advisory_lock($1)", sequencerLockID).Scan(&locked)
+	if err != nil {
+		return fmt.Errorf("sequencer role: lock trillian db: %w", err)
+	}
+
+	if !locked {
+		return fmt.Errorf("sequencer role: another sequencer runs on the trillian db, only one may run per database")
+	}
+
+	return nil
+}
+
+// startPublishers starts publishing the logs, each log is published under its alias.
+func startPublishers(params *publishParameters, aliases []string, readToken string) error {
+	for _, alias := range aliases {
+		bucket, err := publisher.OpenBucket(strings.TrimSuffix(params.bucket, "/")+"/"+alias, params.s3Endpoint,
+			params.s3Region)
+		if err != nil {
+			return fmt.Errorf("open bucket: %w", err)
+		}
+
+		p := publisher.New(vct.New(params.sourceURL+"/"+alias, vct.WithAuthReadToken(readToken)), bucket)
+
+		go p.Run(context.Background(), params.interval)
+	}
+
+	return nil
+}
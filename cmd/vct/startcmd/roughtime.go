@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/roughtime"
+)
+
+const (
+	roughtimeServersFlagName  = "roughtime-servers"
+	roughtimeServersFlagUsage = "Comma-separated list of Roughtime servers <address>@<base64 Ed25519 public key>" +
+		" the timestamps of the SCTs (and of the tree heads of the native log backend) are derived from instead of" +
+		" the local clock, e.g. roughtime.example.com:2002@<key>. The servers are tried in order, the signed" +
+		" response of the server is returned with the SCTs as their time attestation. The local clock if not set." +
+		" Alternatively, this can be set with the following environment variable: " + roughtimeServersEnvKey
+	roughtimeServersEnvKey = envPrefix + "ROUGHTIME_SERVERS"
+
+	roughtimeSyncIntervalFlagName  = "roughtime-sync-interval"
+	roughtimeSyncIntervalFlagUsage = "Interval of the syncs with the Roughtime servers (e.g 30s). Defaults to 1m." +
+		" Submissions are rejected once the time was not synced for 5 intervals." +
+		" Alternatively, this can be set with the following environment variable: " + roughtimeSyncIntervalEnvKey
+	roughtimeSyncIntervalEnvKey = envPrefix + "ROUGHTIME_SYNC_INTERVAL"
+
+	defaultRoughtimeSyncInterval = time.Minute
+	roughtimeMaxAgeIntervals     = 5
+)
+
+type roughtimeParameters struct {
+	servers      []roughtime.Server
+	syncInterval time.Duration
+}
+
+// getRoughtime returns the parameters of the Roughtime servers, nil if the local clock is used.
+func getRoughtime(cmd *cobra.Command) (*roughtimeParameters, error) {
+	spec := cmdutils.GetUserSetOptionalVarFromString(cmd, roughtimeServersFlagName, roughtimeServersEnvKey)
+	if spec == "" {
+		return nil, nil
+	}
+
+	servers, err := roughtime.ParseServers(spec)
+	if err != nil {
+		return nil, fmt.Errorf("roughtime servers: %w", err)
+	}
+
+	params := &roughtimeParameters{servers: servers, syncInterval: defaultRoughtimeSyncInterval}
+
+	if intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, roughtimeSyncIntervalFlagName,
+		roughtimeSyncIntervalEnvKey); intervalStr != "" {
+		params.syncInterval, err = time.ParseDuration(intervalStr)
+		if err != nil || params.syncInterval <= 0 {
+			return nil, fmt.Errorf("roughtime sync interval is not a positive duration: %s", intervalStr)
+		}
+	}
+
+	return params, nil
+}
+
+// startRoughtime syncs the clock with the Roughtime servers and keeps it synced at the interval, the service does
+// not start if none of the servers answers.
+func startRoughtime(params *roughtimeParameters) (*roughtime.Clock, error) {
+	clock := roughtime.NewClock(params.servers, roughtimeMaxAgeIntervals*params.syncInterval)
+
+	if err := clock.Sync(context.Background()); err != nil {
+		return nil, fmt.Errorf("sync trusted time: %w", err)
+	}
+
+	go clock.Run(context.Background(), params.syncInterval)
+
+	return clock, nil
+}
+
+// startClock starts the Roughtime clock the timestamps of the SCTs are taken from, nil if the local clock is used.
+func startClock(cfg *command.Config, params *roughtimeParameters) (*roughtime.Clock, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	clock, err := startRoughtime(params)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.TimeSource = roughtimeSource{clock: clock}
+
+	return clock, nil
+}
+
+// roughtimeSource attests the timestamps of the SCTs with the signed responses of the Roughtime servers.
+type roughtimeSource struct {
+	clock *roughtime.Clock
+}
+
+func (s roughtimeSource) Now() (time.Time, *command.TimeAttestation, error) {
+	now, resp, err := s.clock.Now()
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("roughtime: %w", err)
+	}
+
+	return now, &command.TimeAttestation{
+		Source:   command.TimeAttestationRoughtime,
+		Server:   resp.Server,
+		Nonce:    resp.Nonce,
+		Response: resp.Raw,
+		Midpoint: uint64(resp.Midpoint.UnixNano()) / uint64(time.Millisecond),
+		Radius:   uint64((resp.Radius + time.Millisecond - 1) / time.Millisecond),
+	}, nil
+}
+
+func (s roughtimeSource) time() (time.Time, error) {
+	now, _, err := s.Now()
+
+	return now, err
+}
+
+func createRoughtimeFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(roughtimeServersFlagName, "", roughtimeServersFlagUsage)
+	startCmd.Flags().String(roughtimeSyncIntervalFlagName, "", roughtimeSyncIntervalFlagUsage)
+}
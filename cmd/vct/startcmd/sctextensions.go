@@ -0,0 +1,51 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	sctExtensionsFlagName  = "sct-extensions"
+	sctExtensionsFlagUsage = "Comma-separated list of the registered extensions added to the SCTs, signed with them:" +
+		" shard-id (the alias of the log), policy-tags (the policy tags of the entry) and anchor-commitment (the" +
+		" hash of the anchored tree head). None by default. Examples: shard-id,policy-tags" +
+		" Alternatively, this can be set with the following environment variable: " + sctExtensionsEnvKey
+	sctExtensionsEnvKey = envPrefix + "SCT_EXTENSIONS"
+)
+
+// getSCTExtensions returns the registered extensions added to the SCTs, nil if none is.
+func getSCTExtensions(cmd *cobra.Command) ([]command.SCTExtensionType, error) {
+	extensionsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, sctExtensionsFlagName, sctExtensionsEnvKey)
+	if extensionsStr == "" {
+		return nil, nil
+	}
+
+	var extensions []command.SCTExtensionType
+
+	for _, name := range strings.Split(extensionsStr, ",") {
+		extension, err := command.ParseSCTExtensionType(strings.TrimSpace(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sctExtensionsFlagName, err)
+		}
+
+		extensions = append(extensions, extension)
+	}
+
+	return extensions, nil
+}
+
+func createSCTExtensionFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(sctExtensionsFlagName, "", sctExtensionsFlagUsage)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/trillian/monitoring/prometheus"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/signer"
+)
+
+const (
+	signerTokenFlagUsage = "Bearer token the requests to the signer must carry. The signer refuses to start" +
+		" without it or " + tlsClientCACertsFlagName + ", unless " + signerInsecureFlagName + " is set." +
+		" Alternatively, this can be set with the following environment variable: " + signerAuthTokenEnvKey
+	signerInsecureFlagName  = "signer-insecure"
+	signerInsecureEnvKey    = envPrefix + "SIGNER_INSECURE"
+	signerInsecureFlagUsage = "Serves the signer requests without authentication if neither " +
+		signerAuthTokenFlagName + " nor " + tlsClientCACertsFlagName + " is set (for testing only)." +
+		" Alternatively, this can be set with the following environment variable: " + signerInsecureEnvKey
+	signerKMSTypeFlagUsage = "KMS type of the keys of the signer (local,web,aws)." +
+		" Alternatively, this can be set with the following environment variable: " + kmsTypeEnvKey
+)
+
+// SignerCmd returns the Cobra signer command: it serves the remote signer protocol (see package signer) with the
+// keys of a KMS, so the vct services signing with kms type signer never hold the key of the log.
+func SignerCmd(server server) *cobra.Command {
+	signerCmd := &cobra.Command{
+		Use:   "signer",
+		Short: "Starts the remote signer",
+		Long: `Starts the remote signer serving the signatures of the keys of a KMS to the verifiable credentials` +
+			` transparency services (kms type signer)`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host, err := cmdutils.GetUserSetVarFromString(cmd, agentHostFlagName, agentHostEnvKey, false)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			kmsParams, err := getKmsParameters(cmd)
+			if err != nil {
+				return err
+			}
+
+			if kmsParams.kmsType == kmsSigner {
+				return fmt.Errorf("kms type %s is not supported by the signer", kmsSigner)
+			}
+
+			timeoutStr := cmdutils.GetUserSetOptionalVarFromString(cmd, timeoutFlagName, timeoutEnvKey)
+			if timeoutStr == "" {
+				timeoutStr = defaultTimeout
+			}
+
+			timeout, err := strconv.ParseUint(timeoutStr, 10, 64)
+			if err != nil {
+				return fmt.Errorf("timeout is not a number(positive): %w", err)
+			}
+
+			auth, err := getSignerAuth(cmd)
+			if err != nil {
+				return err
+			}
+
+			return startSigner(server, &agentParameters{
+				host:           host,
+				kmsParams:      kmsParams,
+				datasourceName: cmdutils.GetUserSetOptionalVarFromString(cmd, datasourceNameFlagName, datasourceNameEnvKey),
+				databasePrefix: cmdutils.GetUserSetOptionalVarFromString(cmd, databasePrefixFlagName, databasePrefixEnvKey),
+				timeout:        timeout,
+				syncTimeout:    timeout,
+				tlsParams: &tlsParameters{
+					serveCertPath: cmdutils.GetUserSetOptionalVarFromString(cmd, tlsServeCertPathFlagName,
+						tlsServeCertPathEnvKey),
+					serveKeyPath: cmdutils.GetUserSetOptionalVarFromString(cmd, tlsServeKeyPathFlagName,
+						tlsServeKeyPathFlagEnvKey),
+					clientCACerts: auth.clientCACerts,
+				},
+			}, auth)
+		},
+	}
+
+	signerCmd.Flags().StringP(agentHostFlagName, agentHostFlagShorthand, "", agentHostFlagUsage)
+	signerCmd.Flags().String(kmsTypeFlagName, "", signerKMSTypeFlagUsage)
+	signerCmd.Flags().String(kmsEndpointFlagName, "", kmsEndpointFlagUsage)
+	signerCmd.Flags().String(logSignActiveKeyIDFlagName, "", logSignActiveKeyIDFlagUsage)
+	signerCmd.Flags().String(signerAuthTokenFlagName, "", signerTokenFlagUsage)
+	signerCmd.Flags().StringP(datasourceNameFlagName, datasourceNameFlagShorthand, "", datasourceNameFlagUsage)
+	signerCmd.Flags().String(databasePrefixFlagName, "", databasePrefixFlagUsage)
+	signerCmd.Flags().String(timeoutFlagName, defaultTimeout, timeoutFlagUsage)
+	signerCmd.Flags().String(tlsServeCertPathFlagName, "", tlsServeCertPathFlagUsage)
+	signerCmd.Flags().String(tlsServeKeyPathFlagName, "", tlsServeKeyPathFlagUsage)
+	signerCmd.Flags().String(tlsClientCACertsFlagName, "", tlsClientCACertsFlagUsage)
+	signerCmd.Flags().String(signerInsecureFlagName, "false", signerInsecureFlagUsage)
+
+	return signerCmd
+}
+
+// signerAuth is the authentication of the requests to the signer.
+type signerAuth struct {
+	token         string
+	clientCACerts []string
+	insecure      bool
+}
+
+// getSignerAuth returns the authentication of the requests to the signer, the requests must carry the token or a
+// client certificate of the client CA certs. Without both, the signer is insecure only if it is explicitly allowed.
+func getSignerAuth(cmd *cobra.Command) (*signerAuth, error) {
+	auth := &signerAuth{
+		token: cmdutils.GetUserSetOptionalVarFromString(cmd, signerAuthTokenFlagName, signerAuthTokenEnvKey),
+	}
+
+	if clientCACerts := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsClientCACertsFlagName,
+		tlsClientCACertsEnvKey); clientCACerts != "" {
+		auth.clientCACerts = strings.Split(clientCACerts, ",")
+	}
+
+	if insecureStr := cmdutils.GetUserSetOptionalVarFromString(cmd, signerInsecureFlagName,
+		signerInsecureEnvKey); insecureStr != "" {
+		var err error
+
+		auth.insecure, err = strconv.ParseBool(insecureStr)
+		if err != nil {
+			return nil, fmt.Errorf("signer insecure is not a bool: %w", err)
+		}
+	}
+
+	if auth.token == "" && len(auth.clientCACerts) == 0 && !auth.insecure {
+		return nil, fmt.Errorf("the requests to the signer are not authenticated, set %s or %s (or %s)",
+			signerAuthTokenFlagName, tlsClientCACertsFlagName, signerInsecureFlagName)
+	}
+
+	return auth, nil
+}
+
+// startSigner serves the signer protocol with the keys of the KMS. The key of log-active-key-id is created
+// once if it is not set, its ID is logged to be set as the log-active-key-id of the vct services. Only that key
+// is served, the requests are authenticated with the token and/or the client certificates (mTLS).
+func startSigner(server server, parameters *agentParameters, auth *signerAuth) error {
+	if len(parameters.tlsParams.clientCACerts) > 0 && parameters.tlsParams.serveCertPath == "" {
+		return fmt.Errorf("%s requires %s", tlsClientCACertsFlagName, tlsServeCertPathFlagName)
+	}
+
+	clientCAs, err := getClientCAs(parameters.tlsParams)
+	if err != nil {
+		return err
+	}
+
+	store, err := createStoreProvider(parameters.datasourceName, parameters.databasePrefix, parameters.timeout)
+	if err != nil {
+		return fmt.Errorf("create store provider: %w", err)
+	}
+
+	defer func() {
+		if err = store.Close(); err != nil {
+			logger.Errorf("store close: %v", err)
+		}
+	}()
+
+	configStore, err := store.OpenStore("config")
+	if err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+
+	km, cr, err := createKMSAndCrypto(parameters, &http.Client{Timeout: time.Minute}, store, configStore,
+		prometheus.MetricFactory{})
+	if err != nil {
+		return err
+	}
+
+	keyID := parameters.kmsParams.logSignActiveKeyID
+	if keyID == "" {
+		keyID, err = createKID(km, configStore, parameters.syncTimeout)
+		if err != nil {
+			return fmt.Errorf("create kid: %w", err)
+		}
+	}
+
+	logger.Infof("Starting the signer of the key %s on host [%s]", keyID, parameters.host)
+
+	return server.ListenAndServe( // nolint: wrapcheck
+		parameters.host,
+		signer.NewHandler(km, cr, []string{keyID}, signerHandlerOpts(auth.token, clientCAs != nil)...),
+		parameters.tlsParams.serveCertPath,
+		parameters.tlsParams.serveKeyPath,
+		clientCAs,
+	)
+}
+
+// signerHandlerOpts returns the options authenticating the requests to the signer with the token and the client
+// certificates.
+func signerHandlerOpts(token string, clientCertificates bool) []signer.HandlerOpt {
+	var opts []signer.HandlerOpt
+
+	if token != "" {
+		opts = append(opts, signer.WithToken(token))
+	}
+
+	if clientCertificates {
+		opts = append(opts, signer.WithClientCertificates())
+	}
+
+	if len(opts) == 0 {
+		logger.Warnf("The requests to the signer are not authenticated (%s)", signerInsecureFlagName)
+	}
+
+	return opts
+}
@@ -0,0 +1,91 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	signingKeyPurposesFlagName  = "signing-key-purposes"
+	signingKeyPurposesFlagUsage = "Comma-separated list of the purposes signed with a key of the KMS distinct" +
+		" from the key of the log <purpose>[@<key ID>]: response (the responses of the REST API), witness (the" +
+		" cosignatures of the anchored tree heads), admin (the responses of the admin API) and webhook (the" +
+		" callbacks and the pushes to the STH distributors, signed with the key of the log otherwise)." +
+		" A key is created for a purpose without key ID, a purpose which is not listed is not signed." +
+		" Examples: response,witness@<key ID>" +
+		" Alternatively, this can be set with the following environment variable: " + signingKeyPurposesEnvKey
+	signingKeyPurposesEnvKey = envPrefix + "SIGNING_KEY_PURPOSES"
+)
+
+// getSigningKeyPurposes returns the key IDs of the purposes signed with a key distinct from the key of the log.
+func getSigningKeyPurposes(cmd *cobra.Command) (map[command.KeyPurpose]string, error) {
+	const purposeParts = 2
+
+	purposes := map[command.KeyPurpose]string{}
+
+	purposesStr := cmdutils.GetUserSetOptionalVarFromString(cmd, signingKeyPurposesFlagName, signingKeyPurposesEnvKey)
+	if purposesStr == "" {
+		return purposes, nil
+	}
+
+	for _, val := range strings.Split(purposesStr, ",") {
+		parts := strings.SplitN(strings.TrimSpace(val), "@", purposeParts)
+
+		purpose, err := command.ParseKeyPurpose(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("signing key purposes: %w", err)
+		}
+
+		if len(parts) == purposeParts {
+			if parts[1] == "" {
+				return nil, fmt.Errorf("signing key purposes: key ID of the %s purpose is empty", purpose)
+			}
+
+			purposes[purpose] = parts[1]
+		} else {
+			purposes[purpose] = ""
+		}
+	}
+
+	return purposes, nil
+}
+
+// createPurposeKeys returns the keys of the purposes, the key of a purpose without key ID is created once.
+func createPurposeKeys(km keyManager, cfg storage.Store, purposes map[command.KeyPurpose]string,
+	syncTimeout uint64) (map[command.KeyPurpose]command.Key, error) {
+	keys := map[command.KeyPurpose]command.Key{}
+
+	for purpose, keyID := range purposes {
+		if keyID == "" {
+			err := getOrInit(cfg, kidKey+"-"+string(purpose), &keyID, func() (interface{}, error) {
+				kid, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+
+				return kid, err // nolint: wrapcheck
+			}, syncTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("create %s kid: %w", purpose, err)
+			}
+		}
+
+		keys[purpose] = command.Key{ID: keyID}
+	}
+
+	return keys, nil
+}
+
+func createSigningKeyFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(signingKeyPurposesFlagName, "", signingKeyPurposesFlagUsage)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+const (
+	treeHeadSLAIntervalFlagName  = "tree-head-sla-interval"
+	treeHeadSLAIntervalFlagUsage = "Interval a new tree head of every log must be published within (e.g 1h, the max" +
+		" root duration of the trees created by the service), the publication of the tree heads is monitored and" +
+		" reported over rolling windows on the admin path " + rest.TreeHeadSLAPath + " (as JSON or CSV)." +
+		" Not monitored if not set." +
+		" Alternatively, this can be set with the following environment variable: " + treeHeadSLAIntervalEnvKey
+	treeHeadSLAIntervalEnvKey = envPrefix + "TREE_HEAD_SLA_INTERVAL"
+
+	treeHeadSLAWindowsFlagName  = "tree-head-sla-windows"
+	treeHeadSLAWindowsFlagUsage = "Comma-separated list of the rolling windows the tree head SLA is reported over" +
+		" (e.g. 1h,24h,720h). Defaults to 1h,24h,168h." +
+		" Alternatively, this can be set with the following environment variable: " + treeHeadSLAWindowsEnvKey
+	treeHeadSLAWindowsEnvKey = envPrefix + "TREE_HEAD_SLA_WINDOWS"
+)
+
+// getTreeHeadSLA returns the configuration of the monitoring of the tree heads, nil if they are not monitored.
+func getTreeHeadSLA(cmd *cobra.Command) (*command.TreeHeadSLAConfig, error) {
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, treeHeadSLAIntervalFlagName,
+		treeHeadSLAIntervalEnvKey)
+	if intervalStr == "" {
+		return nil, nil
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("tree head SLA interval is not a positive duration: %s", intervalStr)
+	}
+
+	cfg := &command.TreeHeadSLAConfig{Interval: interval}
+
+	windowsStr := cmdutils.GetUserSetOptionalVarFromString(cmd, treeHeadSLAWindowsFlagName, treeHeadSLAWindowsEnvKey)
+	if windowsStr == "" {
+		return cfg, nil
+	}
+
+	for _, val := range strings.Split(windowsStr, ",") {
+		window, er := time.ParseDuration(strings.TrimSpace(val))
+		if er != nil || window <= 0 {
+			return nil, fmt.Errorf("tree head SLA window is not a positive duration: %s", val)
+		}
+
+		cfg.Windows = append(cfg.Windows, window)
+	}
+
+	return cfg, nil
+}
+
+func createTreeHeadSLAFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(treeHeadSLAIntervalFlagName, "", treeHeadSLAIntervalFlagUsage)
+	startCmd.Flags().String(treeHeadSLAWindowsFlagName, "", treeHeadSLAWindowsFlagUsage)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	verificationSnapshotsFlagName  = "verification-snapshots"
+	verificationSnapshotsFlagUsage = "Serves the signed verification snapshots of the logs (latest tree head, keys and" +
+		" metadata) on the public path /{alias}/verification-snapshot, which may be cached until the next update and" +
+		" read from any origin, for the verification widgets of third-party sites." +
+		" Possible values [true] [false]. Defaults to false if not set." +
+		" Alternatively, this can be set with the following environment variable: " + verificationSnapshotsEnvKey
+	verificationSnapshotsEnvKey = envPrefix + "VERIFICATION_SNAPSHOTS"
+
+	verificationSnapshotIntervalFlagName  = "verification-snapshot-interval"
+	verificationSnapshotIntervalFlagUsage = "Interval the verification snapshots are refreshed at (e.g 30s)." +
+		" Defaults to the interval the logs are published at (" + publishIntervalFlagName + ") or 1m if not set." +
+		" Alternatively, this can be set with the following environment variable: " +
+		verificationSnapshotIntervalEnvKey
+	verificationSnapshotIntervalEnvKey = envPrefix + "VERIFICATION_SNAPSHOT_INTERVAL"
+
+	verificationSnapshotMetadataFlagName  = "verification-snapshot-metadata"
+	verificationSnapshotMetadataFlagUsage = "Comma-separated list of <key>=<value> metadata served in the" +
+		" verification snapshots, e.g. operator=Example Inc.,contact=log@example.com." +
+		" Alternatively, this can be set with the following environment variable: " +
+		verificationSnapshotMetadataEnvKey
+	verificationSnapshotMetadataEnvKey = envPrefix + "VERIFICATION_SNAPSHOT_METADATA"
+)
+
+// getVerificationSnapshots returns the configuration of the verification snapshots, nil if they are not served.
+func getVerificationSnapshots(cmd *cobra.Command) (*command.VerificationSnapshotConfig, error) {
+	const metadataParts = 2
+
+	enabledStr := cmdutils.GetUserSetOptionalVarFromString(cmd, verificationSnapshotsFlagName,
+		verificationSnapshotsEnvKey)
+	if enabledStr == "" {
+		return nil, nil
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return nil, fmt.Errorf("verification snapshots is not a bool: %w", err)
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	cfg := &command.VerificationSnapshotConfig{Interval: command.DefaultSnapshotInterval}
+
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, verificationSnapshotIntervalFlagName,
+		verificationSnapshotIntervalEnvKey)
+	if intervalStr == "" {
+		intervalStr = cmdutils.GetUserSetOptionalVarFromString(cmd, publishIntervalFlagName, publishIntervalEnvKey)
+	}
+
+	if intervalStr != "" {
+		cfg.Interval, err = time.ParseDuration(intervalStr)
+		if err != nil || cfg.Interval <= 0 {
+			return nil, fmt.Errorf("verification snapshot interval is not a positive duration: %s", intervalStr)
+		}
+	}
+
+	metadataStr := cmdutils.GetUserSetOptionalVarFromString(cmd, verificationSnapshotMetadataFlagName,
+		verificationSnapshotMetadataEnvKey)
+	if metadataStr == "" {
+		return cfg, nil
+	}
+
+	cfg.Metadata = map[string]string{}
+
+	for _, val := range strings.Split(metadataStr, ",") {
+		parts := strings.SplitN(strings.TrimSpace(val), "=", metadataParts)
+		if len(parts) != metadataParts || parts[0] == "" {
+			return nil, fmt.Errorf("verification snapshot metadata %q is not a <key>=<value> pair", val)
+		}
+
+		cfg.Metadata[parts[0]] = parts[1]
+	}
+
+	return cfg, nil
+}
+
+func createSnapshotFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(verificationSnapshotsFlagName, "", verificationSnapshotsFlagUsage)
+	startCmd.Flags().String(verificationSnapshotIntervalFlagName, "", verificationSnapshotIntervalFlagUsage)
+	startCmd.Flags().String(verificationSnapshotMetadataFlagName, "", verificationSnapshotMetadataFlagUsage)
+}
@@ -10,10 +10,15 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -23,122 +28,1213 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/trustbloc/vct/cmd/vct/startcmd"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/signer"
 )
 
 const (
-	agentHostFlagName         = "api-host"
-	kmsEndpointFlagName       = "kms-endpoint"
-	logKeyIDFlagName          = "log-active-key-id"
-	kmsTypeFlagName           = "kms-type"
-	logsFlagName              = "logs"
-	devModeFlagName           = "dev-mode"
-	issuersFlagName           = "issuers"
-	datasourceNameFlagName    = "dsn"
-	tlsSystemCertPoolFlagName = "tls-systemcertpool"
-	tlsCACertsFlagName        = "tls-cacerts"
-	timeoutFlagName           = "timeout"
-	syncTimeoutFlagName       = "sync-timeout"
-	readTokenFlagName         = "api-read-token"
+	agentHostFlagName             = "api-host"
+	kmsEndpointFlagName           = "kms-endpoint"
+	logKeyIDFlagName              = "log-active-key-id"
+	kmsTypeFlagName               = "kms-type"
+	logsFlagName                  = "logs"
+	devModeFlagName               = "dev-mode"
+	issuersFlagName               = "issuers"
+	datasourceNameFlagName        = "dsn"
+	tlsSystemCertPoolFlagName     = "tls-systemcertpool"
+	tlsCACertsFlagName            = "tls-cacerts"
+	timeoutFlagName               = "timeout"
+	syncTimeoutFlagName           = "sync-timeout"
+	readTokenFlagName             = "api-read-token"
+	autoMigrateFlagName           = "auto-migrate"
+	readReplicasFlagName          = "log-read-replicas"
+	replicaStalenessFlagName      = "log-read-replica-max-staleness"
+	maxClockSkewFlagName          = "max-clock-skew"
+	maxEntrySizeFlagName          = "max-entry-size"
+	proofCacheSizeFlagName        = "proof-cache-size"
+	keyUsageLimitsFlagName        = "key-usage-thresholds"
+	recoveryKeyFlagName           = "recovery-public-key"
+	shadowsFlagName               = "log-shadows"
+	tenantsFlagName               = "log-tenants"
+	roughtimeServersFlagName      = "roughtime-servers"
+	roughtimeSyncIntervalFlagName = "roughtime-sync-interval"
+	auditorKeysFlagName           = "auditor-keys"
+	annotationSubscribersFlagName = "annotation-subscribers"
+	signingKeyPurposesFlagName    = "signing-key-purposes"
+	didFlagName                   = "did"
+	dedupStoreFlagName            = "dedup-store"
+	dedupFilterCapacityFlagName   = "dedup-filter-capacity"
+	readOnlyFlagName              = "read-only"
+	presentationsFlagName         = "presentations"
+	warmCacheFlagName             = "warm-cache"
+	authRolesFlagName             = "auth-roles"
+	logPayloadsFlagName           = "log-payloads"
+	encryptExtraDataFlagName      = "encrypt-extra-data"
+	trustRegistryTTLFlagName      = "trust-registry-cache-ttl"
+	logBackendFlagName            = "log-backend"
+	trillianDBConnFlagName        = "trillian-db-conn"
+	rolesFlagName                 = "roles"
+	faultInjectionFlagName        = "fault-injection"
+	publishBucketFlagName         = "publish-bucket"
+	publishIntervalFlagName       = "publish-interval"
+	baseURLFlagName               = "base-url"
+	ipfsAPIURLFlagName            = "ipfs-api-url"
+	sthDistributorURLsFlagName    = "sth-distributor-urls"
+	disabledOperationsFlagName    = "disabled-operations"
+	sctExtensionsFlagName         = "sct-extensions"
+)
+
+const (
+	verificationSnapshotsFlagName        = "verification-snapshots"
+	verificationSnapshotIntervalFlagName = "verification-snapshot-interval"
+	verificationSnapshotMetadataFlagName = "verification-snapshot-metadata"
+	treeHeadSLAIntervalFlagName          = "tree-head-sla-interval"
+	treeHeadSLAWindowsFlagName           = "tree-head-sla-windows"
+	reconciliationIntervalFlagName       = "reconciliation-interval"
+	reconciliationStaleAfterFlagName     = "reconciliation-stale-after"
+	reEncryptionIntervalFlagName         = "extra-data-reencryption-interval"
+	submissionTTLFlagName                = "submission-ttl"
+	submissionRequeueFlagName            = "submission-requeue"
+	submissionMaxRequeuesFlagName        = "submission-max-requeues"
+	policyTagsJurisdictionsFlagName      = "policy-tags-jurisdictions"
+	policyTagsAssuranceLevelsFlagName    = "policy-tags-assurance-levels"
+	contextDirFlagName                   = "context-dir"
+	contextFetchPolicyFlagName           = "context-fetch-policy"
+	contextFetchAllowlistFlagName        = "context-fetch-allowlist"
 )
 
 type mockServer struct{}
 
-func (s *mockServer) ListenAndServe(host string, handler http.Handler, certFile, keyFile string) error {
-	return nil
-}
+func (s *mockServer) ListenAndServe(host string, handler http.Handler, certFile, keyFile string,
+	clientCAs *x509.CertPool) error {
+	return nil
+}
+
+func TestStartCmdContents(t *testing.T) {
+	startCmd, err := startcmd.Cmd(&mockServer{})
+	require.NoError(t, err)
+
+	require.Equal(t, "start", startCmd.Use)
+	require.Equal(t, "Starts vct service", startCmd.Short)
+	require.Equal(t, "Starts verifiable credentials transparency service", startCmd.Long)
+}
+
+func TestBuildKMSURL(t *testing.T) {
+	require.Equal(t, startcmd.BuildKMSURL("https://kms.com", "/keys"), "https://kms.com/keys")
+	require.Equal(t, startcmd.BuildKMSURL("oops", "https://kms.com/keys"), "https://kms.com/keys")
+}
+
+func TestCmd(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		lis, err := net.Listen("tcp", "localhost:50051")
+		require.NoError(t, err)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		logServer := NewMockTrillianLogServer(ctrl)
+		logServer.EXPECT().InitLog(gomock.Any(), gomock.Any()).Return(&trillian.InitLogResponse{}, nil)
+
+		adminServer := NewMockTrillianAdminServer(ctrl)
+		adminServer.EXPECT().CreateTree(gomock.Any(), gomock.Any()).Return(&trillian.Tree{}, nil)
+
+		s := grpc.NewServer()
+
+		trillian.RegisterTrillianLogServer(s, logServer)
+		trillian.RegisterTrillianAdminServer(s, adminServer)
+
+		go func() {
+			require.NoError(t, s.Serve(lis))
+		}()
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + kmsTypeFlagName, "local",
+			"--" + readTokenFlagName, "tk1",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with embedded trillian", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with native log backend", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw,maple2022:r",
+			"--" + logBackendFlagName, "native",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with dedup store", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + dedupStoreFlagName, "true",
+			"--" + dedupFilterCapacityFlagName, "1000",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with warm cache", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + warmCacheFlagName, "true",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with verification snapshots", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + verificationSnapshotsFlagName, "true",
+			"--" + verificationSnapshotIntervalFlagName, "30s",
+			"--" + verificationSnapshotMetadataFlagName, "operator=Example Inc.,contact=log@example.com",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with tree head SLA", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + treeHeadSLAIntervalFlagName, "1h",
+			"--" + treeHeadSLAWindowsFlagName, "24h, 720h",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with reconciliation", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + reconciliationIntervalFlagName, "5m",
+			"--" + reconciliationStaleAfterFlagName, "30m",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with extra data re-encryption", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + encryptExtraDataFlagName, "true",
+			"--" + reEncryptionIntervalFlagName, "1h",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with submission TTL", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + submissionTTLFlagName, "24h",
+			"--" + submissionRequeueFlagName, "true",
+			"--" + submissionMaxRequeuesFlagName, "5",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with webhook key", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + signingKeyPurposesFlagName, "webhook",
+			"--" + didFlagName, "did:web:vct.example.com",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with disabled operations", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + disabledOperationsFlagName, "get-entries@maple2021,admin/submission-stats",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Unknown disabled operation", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + disabledOperationsFlagName, "get-entrie@maple2021",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.EqualError(t, startCmd.Execute(),
+			"disabled operation get-entrie is not an operation of the REST API")
+	})
+
+	t.Run("Success with SCT extensions", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + sctExtensionsFlagName, "shard-id, policy-tags,anchor-commitment",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Success with policy tags", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + policyTagsJurisdictionsFlagName, "CA, FR",
+			"--" + policyTagsAssuranceLevelsFlagName, "loa2,loa3",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Bad policy tags jurisdictions", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + policyTagsJurisdictionsFlagName, "Canada",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `policy tags schema: jurisdiction "Canada" is not an ISO 3166 code`)
+	})
+
+	t.Run("Success with pinned contexts", func(t *testing.T) {
+		dir := t.TempDir()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "context.json"), []byte(`{
+			"url": "https://example.com/context",
+			"content": {"@context": {"name": "https://schema.org/name"}}
+		}`), 0o600))
+
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + contextDirFlagName, dir,
+			"--" + contextFetchPolicyFlagName, "allowlist",
+			"--" + contextFetchAllowlistFlagName, "https://w3id.org/, https://www.w3.org/",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Bad context fetch policy", func(t *testing.T) {
+		for policy, msg := range map[string]string{
+			"allowlist": "context-fetch-allowlist is required by the allowlist context fetch policy",
+			"always":    `context fetch policy "always" is not supported`,
+		} {
+			startCmd, err := startcmd.Cmd(&mockServer{})
+			require.NoError(t, err)
+
+			args := []string{
+				"--" + agentHostFlagName, "",
+				"--" + logsFlagName, "maple2021:rw",
+				"--" + contextFetchPolicyFlagName, policy,
+				"--" + kmsTypeFlagName, "local",
+			}
+			startCmd.SetArgs(args)
+			err = startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), msg)
+		}
+	})
+
+	t.Run("Bad context dir", func(t *testing.T) {
+		dir := t.TempDir()
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "context.json"), []byte(`{}`), 0o600))
+
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + contextDirFlagName, dir,
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read pinned contexts: context context.json is not a context document")
+	})
+
+	t.Run("Unsupported log backend", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "etcd",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported log backend: etcd")
+	})
+
+	t.Run("Frontend and publisher roles", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + kmsTypeFlagName, "local",
+			"--" + rolesFlagName, "frontend,publisher",
+			"--" + publishBucketFlagName, t.TempDir(),
+			"--" + publishIntervalFlagName, "1h",
+			"--" + baseURLFlagName, "http://localhost:5678",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Fault injection", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + logBackendFlagName, "native",
+			"--" + kmsTypeFlagName, "local",
+			"--" + faultInjectionFlagName, "trillian.QueueLeaf:error:0.5,kms.Sign:delay=1ms",
+		}
+		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("Invalid fault injection", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + kmsTypeFlagName, "local",
+			"--" + faultInjectionFlagName, "trillian.QueueLeaf",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "fault injection: rule")
+	})
+
+	t.Run("Unsupported role", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + kmsTypeFlagName, "local",
+			"--" + rolesFlagName, "frontend,monitor",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.EqualError(t, err, "unsupported role: monitor")
+	})
+
+	t.Run("Witness role without distributors", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--" + rolesFlagName, "witness",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the witness role only runs with sth-distributor-urls")
+	})
+
+	t.Run("Sequencer role with unreachable database", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + kmsTypeFlagName, "local",
+			"--" + rolesFlagName, "sequencer",
+			"--" + trillianDBConnFlagName, "postgres://localhost:1/trillian?sslmode=disable&connect_timeout=1",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sequencer role: connect trillian db")
+	})
+
+	t.Run("Publisher role without bucket", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw",
+			"--" + kmsTypeFlagName, "local",
+			"--" + rolesFlagName, "publisher",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "publisher role: Neither publish-bucket (command line flag) nor")
+	})
+
+	t.Run("Sequencer role without embedded log", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--" + rolesFlagName, "sequencer",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "roles sequencer have nothing to run")
+	})
+
+	t.Run("wrong dev mode flag", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + devModeFlagName, "wrong",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "dev mode is not a bool")
+	})
+
+	t.Run("No logs", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+
+		err = startCmd.Execute()
+
+		require.Contains(t, err.Error(), "nor VCT_LOGS (environment variable) have been set")
+	})
+
+	t.Run("Wrong read replica", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + readReplicasFlagName, "localhost:50052",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "format must be <alias>@<endpoint>")
+	})
+
+	t.Run("Read replica of unknown log", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + readReplicasFlagName, "oak2021@localhost:50052",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `read replica for unknown log "oak2021"`)
+	})
+
+	t.Run("Bad read only", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + readOnlyFlagName, "maybe",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read only is not a bool")
+	})
+
+	t.Run("Bad presentations", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + presentationsFlagName, "maybe",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "presentations is not a bool")
+	})
+
+	t.Run("IPFS mirroring without source URL", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + ipfsAPIURLFlagName, "http://localhost:5001",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.EqualError(t, err, "ipfs mirroring: neither publish-source-url nor base-url is set")
+	})
+
+	t.Run("STH distribution without source URL", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + sthDistributorURLsFlagName, "https://witness.example.com/checkpoints",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.EqualError(t, err, "sth distribution: neither publish-source-url nor base-url is set")
+	})
+
+	t.Run("Bad IPFS publish interval", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + ipfsAPIURLFlagName, "http://localhost:5001",
+			"--" + publishIntervalFlagName, "often",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.EqualError(t, err, "publish interval is not a valid duration: often")
+	})
+
+	t.Run("Bad warm cache", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + warmCacheFlagName, "maybe",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "warm cache is not a bool")
+	})
+
+	t.Run("Bad log payloads", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + logPayloadsFlagName, "maybe",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "log payloads is not a bool")
+	})
+
+	t.Run("Bad encrypt extra data", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + encryptExtraDataFlagName, "maybe",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "encrypt extra data is not a bool")
+	})
+
+	t.Run("Bad trust registry cache TTL", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + trustRegistryTTLFlagName, "soon",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "trust registry cache TTL is not a duration")
+	})
+
+	t.Run("Bad auth roles", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + authRolesFlagName, "owner:token:secret",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse auth roles: unknown role")
+	})
+
+	t.Run("mTLS roles without client CAs", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + authRolesFlagName, "admin:mtls:operator",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.EqualError(t, err, "mtls role bindings require tls-client-cacerts")
+	})
+
+	t.Run("Shadow of unknown log", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + shadowsFlagName, "oak2021@localhost:50052",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `shadow for unknown log "oak2021"`)
+	})
+
+	t.Run("Invalid tenant", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + tenantsFlagName, "maple2021",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "format must be <alias>@<tenant>")
+	})
+
+	t.Run("Tenant of unknown log", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + tenantsFlagName, "oak2021@oak",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `tenant for unknown log "oak2021"`)
+	})
+
+	t.Run("Invalid roughtime servers", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + roughtimeServersFlagName, "roughtime.example.com:2002",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must be <address>@<public key>")
+	})
+
+	t.Run("Invalid auditor key", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + auditorKeysFlagName, "fraud-desk@key!",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `public key of auditor "fraud-desk" is not base64`)
+
+		startCmd.SetArgs(append(args, "--"+auditorKeysFlagName, "fraud-desk"))
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "format must be <auditor>@<public key>")
+	})
+
+	t.Run("Invalid annotation subscriber", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + annotationSubscribersFlagName, "/annotations",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `annotation subscriber "/annotations" must be an absolute http(s) URL`)
+	})
+
+	t.Run("Invalid dedup filter capacity", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + dedupStoreFlagName, "true",
+			"--" + dedupFilterCapacityFlagName, "0",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "dedup filter capacity is not a positive number: 0")
+	})
+
+	t.Run("Invalid signing key purpose", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
 
-func TestStartCmdContents(t *testing.T) {
-	startCmd, err := startcmd.Cmd(&mockServer{})
-	require.NoError(t, err)
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + signingKeyPurposesFlagName, "response,log",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `signing key purposes: unknown key purpose "log"`)
+	})
 
-	require.Equal(t, "start", startCmd.Use)
-	require.Equal(t, "Starts vct service", startCmd.Short)
-	require.Equal(t, "Starts verifiable credentials transparency service", startCmd.Long)
-}
+	t.Run("Invalid roughtime sync interval", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
 
-func TestBuildKMSURL(t *testing.T) {
-	require.Equal(t, startcmd.BuildKMSURL("https://kms.com", "/keys"), "https://kms.com/keys")
-	require.Equal(t, startcmd.BuildKMSURL("oops", "https://kms.com/keys"), "https://kms.com/keys")
-}
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + roughtimeServersFlagName, "roughtime.example.com:2002@" +
+				base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize)),
+			"--" + roughtimeSyncIntervalFlagName, "-1m",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "roughtime sync interval is not a positive duration: -1m")
+	})
 
-func TestCmd(t *testing.T) {
-	t.Run("Success", func(t *testing.T) {
+	t.Run("Bad read replica max staleness", func(t *testing.T) {
 		startCmd, err := startcmd.Cmd(&mockServer{})
 		require.NoError(t, err)
 
-		lis, err := net.Listen("tcp", "localhost:50051")
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + replicaStalenessFlagName, "1",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "read replica max staleness is not a duration")
+	})
+
+	t.Run("Bad key usage thresholds", func(t *testing.T) {
+		for thresholds, expected := range map[string]string{
+			"sct":           `key usage threshold "sct" must be <kind>:<max>`,
+			"sct:10,key:10": `key usage threshold "key:10": unknown kind key`,
+			"sth:-1":        `key usage threshold "sth:-1"`,
+		} {
+			startCmd, err := startcmd.Cmd(&mockServer{})
+			require.NoError(t, err)
+
+			args := []string{
+				"--" + agentHostFlagName, "",
+				"--" + logsFlagName, "maple2021:rw@localhost:50051",
+				"--" + keyUsageLimitsFlagName, thresholds,
+				"--" + kmsTypeFlagName, "local",
+			}
+			startCmd.SetArgs(args)
+			err = startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), expected)
+		}
+	})
+
+	t.Run("Bad max clock skew", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
 		require.NoError(t, err)
 
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + maxClockSkewFlagName, "1",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "max clock skew is not a duration")
+	})
 
-		logServer := NewMockTrillianLogServer(ctrl)
-		logServer.EXPECT().InitLog(gomock.Any(), gomock.Any()).Return(&trillian.InitLogResponse{}, nil)
+	t.Run("Bad verification snapshots", func(t *testing.T) {
+		for _, tc := range []struct {
+			flag, value, err string
+		}{
+			{verificationSnapshotsFlagName, "maybe", "verification snapshots is not a bool"},
+			{verificationSnapshotIntervalFlagName, "-1m", "verification snapshot interval is not a positive duration"},
+			{verificationSnapshotMetadataFlagName, "operator", `verification snapshot metadata "operator" is not`},
+		} {
+			startCmd, err := startcmd.Cmd(&mockServer{})
+			require.NoError(t, err)
+
+			args := []string{
+				"--" + agentHostFlagName, "",
+				"--" + logsFlagName, "maple2021:rw@localhost:50051",
+				"--" + verificationSnapshotsFlagName, "true",
+				"--" + kmsTypeFlagName, "local",
+				"--" + tc.flag, tc.value,
+			}
+			startCmd.SetArgs(args)
+			err = startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		}
+	})
 
-		adminServer := NewMockTrillianAdminServer(ctrl)
-		adminServer.EXPECT().CreateTree(gomock.Any(), gomock.Any()).Return(&trillian.Tree{}, nil)
+	t.Run("Bad tree head SLA", func(t *testing.T) {
+		for _, tc := range []struct {
+			interval, windows, err string
+		}{
+			{"0s", "", "tree head SLA interval is not a positive duration"},
+			{"1h", "24h,day", "tree head SLA window is not a positive duration: day"},
+		} {
+			startCmd, err := startcmd.Cmd(&mockServer{})
+			require.NoError(t, err)
+
+			args := []string{
+				"--" + agentHostFlagName, "",
+				"--" + logsFlagName, "maple2021:rw@localhost:50051",
+				"--" + treeHeadSLAIntervalFlagName, tc.interval,
+				"--" + treeHeadSLAWindowsFlagName, tc.windows,
+				"--" + kmsTypeFlagName, "local",
+			}
+			startCmd.SetArgs(args)
+			err = startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		}
+	})
 
-		s := grpc.NewServer()
+	t.Run("Bad reconciliation", func(t *testing.T) {
+		for _, tc := range []struct {
+			interval, staleAfter, err string
+		}{
+			{"5", "", "reconciliation interval is not a positive duration: 5"},
+			{"5m", "-1h", "reconciliation stale age is not a positive duration: -1h"},
+		} {
+			startCmd, err := startcmd.Cmd(&mockServer{})
+			require.NoError(t, err)
+
+			args := []string{
+				"--" + agentHostFlagName, "",
+				"--" + logsFlagName, "maple2021:rw@localhost:50051",
+				"--" + reconciliationIntervalFlagName, tc.interval,
+				"--" + reconciliationStaleAfterFlagName, tc.staleAfter,
+				"--" + kmsTypeFlagName, "local",
+			}
+			startCmd.SetArgs(args)
+			err = startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		}
+	})
 
-		trillian.RegisterTrillianLogServer(s, logServer)
-		trillian.RegisterTrillianAdminServer(s, adminServer)
+	t.Run("Bad re-encryption interval", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
 
-		go func() {
-			require.NoError(t, s.Serve(lis))
-		}()
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + reEncryptionIntervalFlagName, "hourly",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "re-encryption interval is not a positive duration: hourly")
+	})
+
+	t.Run("Bad submission TTL", func(t *testing.T) {
+		for _, tc := range []struct {
+			flag, value, err string
+		}{
+			{submissionTTLFlagName, "day", "submission TTL is not a positive duration: day"},
+			{submissionRequeueFlagName, "maybe", "submission requeue is not a bool"},
+			{submissionMaxRequeuesFlagName, "0", "submission max requeues is not a positive number: 0"},
+		} {
+			startCmd, err := startcmd.Cmd(&mockServer{})
+			require.NoError(t, err)
+
+			args := []string{
+				"--" + agentHostFlagName, "",
+				"--" + logsFlagName, "maple2021:rw@localhost:50051",
+				"--" + submissionTTLFlagName, "24h",
+				"--" + kmsTypeFlagName, "local",
+				"--" + tc.flag, tc.value,
+			}
+			startCmd.SetArgs(args)
+			err = startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		}
+	})
+
+	t.Run("Bad disabled operation", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
 
 		args := []string{
 			"--" + agentHostFlagName, "",
 			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + disabledOperationsFlagName, "get-entries@",
 			"--" + kmsTypeFlagName, "local",
-			"--" + readTokenFlagName, "tk1",
 		}
 		startCmd.SetArgs(args)
-		require.NoError(t, startCmd.Execute())
+		require.EqualError(t, startCmd.Execute(), `disabled operation "get-entries@" is not <operation>[@<tenant>]`)
 	})
 
-	t.Run("Success with embedded trillian", func(t *testing.T) {
+	t.Run("Unknown SCT extension", func(t *testing.T) {
 		startCmd, err := startcmd.Cmd(&mockServer{})
 		require.NoError(t, err)
 
 		args := []string{
 			"--" + agentHostFlagName, "",
-			"--" + logsFlagName, "maple2021:rw",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + sctExtensionsFlagName, "shard-id,tenant",
 			"--" + kmsTypeFlagName, "local",
 		}
 		startCmd.SetArgs(args)
-		require.NoError(t, startCmd.Execute())
+		require.EqualError(t, startCmd.Execute(), `sct-extensions: SCT extension "tenant" is not registered`)
 	})
 
-	t.Run("wrong dev mode flag", func(t *testing.T) {
+	t.Run("Bad proof cache size", func(t *testing.T) {
 		startCmd, err := startcmd.Cmd(&mockServer{})
 		require.NoError(t, err)
 
 		args := []string{
 			"--" + agentHostFlagName, "",
 			"--" + logsFlagName, "maple2021:rw@localhost:50051",
-			"--" + devModeFlagName, "wrong",
+			"--" + proofCacheSizeFlagName, "-1",
 			"--" + kmsTypeFlagName, "local",
 		}
 		startCmd.SetArgs(args)
 		err = startCmd.Execute()
 		require.Error(t, err)
-		require.Contains(t, err.Error(), "dev mode is not a bool")
+		require.Contains(t, err.Error(), "proof cache size is not a positive number: -1")
 	})
 
-	t.Run("No logs", func(t *testing.T) {
+	t.Run("Bad max entry size", func(t *testing.T) {
 		startCmd, err := startcmd.Cmd(&mockServer{})
 		require.NoError(t, err)
 
 		args := []string{
 			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + maxEntrySizeFlagName, "0",
 			"--" + kmsTypeFlagName, "local",
 		}
 		startCmd.SetArgs(args)
-
 		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "max entry size is not a positive number: 0")
+	})
 
-		require.Contains(t, err.Error(), "nor VCT_LOGS (environment variable) have been set")
+	t.Run("Bad recovery public key", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + recoveryKeyFlagName, "!key",
+			"--" + kmsTypeFlagName, "local",
+		}
+		startCmd.SetArgs(args)
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "recovery public key is not base64")
 	})
 
 	t.Run("Create tree (unavailable)", func(t *testing.T) {
@@ -336,6 +1432,24 @@ func TestCmd(t *testing.T) {
 		require.Contains(t, err.Error(), "unsupported kms type")
 	})
 
+	t.Run("signer without key ID", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		args := []string{
+			"--" + agentHostFlagName, ":98989",
+			"--" + logsFlagName, "11111:rw@https://vct.example.com",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--" + kmsTypeFlagName, "signer",
+			"--" + kmsEndpointFlagName, "https://signer1.example.com,https://signer2.example.com",
+		}
+		startCmd.SetArgs(args)
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "kms type signer requires kms-endpoint and log-active-key-id")
+	})
+
 	t.Run("kms type empty", func(t *testing.T) {
 		startCmd, err := startcmd.Cmd(&mockServer{})
 		require.NoError(t, err)
@@ -411,6 +1525,206 @@ func TestCmd(t *testing.T) {
 	})
 }
 
+func TestHTTPServer_ListenAndServe(t *testing.T) {
+	t.Run("Unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "vct.sock")
+
+		// a stale socket file must not prevent the server from starting.
+		require.NoError(t, os.WriteFile(socketPath, nil, 0o600))
+
+		router := http.NewServeMux()
+		router.HandleFunc("/healthcheck", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		go func() {
+			_ = (&startcmd.HTTPServer{}).ListenAndServe("unix://"+socketPath, router, "", "", nil) // nolint: errcheck
+		}()
+
+		client := vct.New("http://vct/maple2021", vct.WithDialer(vct.UnixSocketDialer(socketPath)))
+
+		require.Eventually(t, func() bool {
+			return client.HealthCheck(context.Background()) == nil
+		}, time.Second*5, time.Millisecond*50)
+	})
+
+	t.Run("Unix socket listen error", func(t *testing.T) {
+		err := (&startcmd.HTTPServer{}).ListenAndServe("unix://"+filepath.Join(t.TempDir(), "no", "vct.sock"),
+			http.NewServeMux(), "", "", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "listen unix socket")
+	})
+}
+
+func TestMigrateCmd(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		migrateCmd := startcmd.MigrateCmd()
+		migrateCmd.SetArgs([]string{"--" + datasourceNameFlagName, "mem://test"})
+
+		require.NoError(t, migrateCmd.Execute())
+	})
+
+	t.Run("Unsupported driver (DSN)", func(t *testing.T) {
+		migrateCmd := startcmd.MigrateCmd()
+		migrateCmd.SetArgs([]string{"--" + datasourceNameFlagName, "mysql://test"})
+
+		err := migrateCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported storage driver: mysql")
+	})
+
+	t.Run("Pending migrations on start", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		startCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + kmsTypeFlagName, "local",
+			"--" + autoMigrateFlagName, "false",
+		})
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "migrate stores: 3 pending migration(s) of the stores")
+	})
+
+	t.Run("Wrong auto migrate flag", func(t *testing.T) {
+		startCmd, err := startcmd.Cmd(&mockServer{})
+		require.NoError(t, err)
+
+		startCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "",
+			"--" + logsFlagName, "maple2021:rw@localhost:50051",
+			"--" + kmsTypeFlagName, "local",
+			"--" + autoMigrateFlagName, "wrong",
+		})
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "auto migrate is not a bool")
+	})
+}
+
+type handlerServer struct {
+	handler http.Handler
+}
+
+func (s *handlerServer) ListenAndServe(_ string, handler http.Handler, _, _ string, _ *x509.CertPool) error {
+	s.handler = handler
+
+	return nil
+}
+
+func TestSignerCmd(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		server := &handlerServer{}
+
+		signerCmd := startcmd.SignerCmd(server)
+		signerCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--signer-auth-token", "token",
+		})
+
+		require.NoError(t, signerCmd.Execute())
+		require.NotNil(t, server.handler)
+
+		req := httptest.NewRequest(http.MethodGet, signer.HealthCheckPath, nil)
+		req.Header.Set("Authorization", "Bearer token")
+
+		rec := httptest.NewRecorder()
+		server.handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		server.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/keys/kid", nil))
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// only the key of the log is served
+		req = httptest.NewRequest(http.MethodGet, "/v1/keys/kid", nil)
+		req.Header.Set("Authorization", "Bearer token")
+
+		rec = httptest.NewRecorder()
+		server.handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Contains(t, rec.Body.String(), "key kid is not served")
+	})
+
+	t.Run("Not authenticated", func(t *testing.T) {
+		signerCmd := startcmd.SignerCmd(&mockServer{})
+		signerCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--" + datasourceNameFlagName, "mem://test",
+		})
+
+		require.EqualError(t, signerCmd.Execute(), "the requests to the signer are not authenticated,"+
+			" set signer-auth-token or tls-client-cacerts (or signer-insecure)")
+	})
+
+	t.Run("Insecure", func(t *testing.T) {
+		server := &handlerServer{}
+
+		signerCmd := startcmd.SignerCmd(server)
+		signerCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--signer-insecure", "true",
+		})
+
+		require.NoError(t, signerCmd.Execute())
+
+		rec := httptest.NewRecorder()
+		server.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, signer.HealthCheckPath, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Insecure is not a bool", func(t *testing.T) {
+		signerCmd := startcmd.SignerCmd(&mockServer{})
+		signerCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--signer-insecure", "yes",
+		})
+
+		require.Contains(t, signerCmd.Execute().Error(), "signer insecure is not a bool")
+	})
+
+	t.Run("Client CA certs without TLS", func(t *testing.T) {
+		signerCmd := startcmd.SignerCmd(&mockServer{})
+		signerCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "localhost:8090",
+			"--" + kmsTypeFlagName, "local",
+			"--" + datasourceNameFlagName, "mem://test",
+			"--tls-client-cacerts", "ca.pem",
+		})
+
+		require.EqualError(t, signerCmd.Execute(), "tls-client-cacerts requires tls-serve-cert")
+	})
+
+	t.Run("Signer KMS type", func(t *testing.T) {
+		signerCmd := startcmd.SignerCmd(&mockServer{})
+		signerCmd.SetArgs([]string{
+			"--" + agentHostFlagName, "localhost:8090",
+			"--" + kmsTypeFlagName, "signer",
+			"--" + kmsEndpointFlagName, "https://signer.example.com",
+			"--" + logKeyIDFlagName, "kid",
+		})
+
+		require.EqualError(t, signerCmd.Execute(), "kms type signer is not supported by the signer")
+	})
+
+	t.Run("No host", func(t *testing.T) {
+		signerCmd := startcmd.SignerCmd(&mockServer{})
+		signerCmd.SetArgs([]string{"--" + kmsTypeFlagName, "local"})
+
+		require.Error(t, signerCmd.Execute())
+	})
+}
+
 func TestValidateAuthorizationBearerToken(t *testing.T) {
 	require.True(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
 		&http.Request{RequestURI: "/healthcheck"}, "read", "write"))
@@ -423,6 +1737,42 @@ func TestValidateAuthorizationBearerToken(t *testing.T) {
 
 	require.True(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
 		&http.Request{RequestURI: "/add-vc"}, "read", ""))
+
+	require.False(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+		&http.Request{
+			RequestURI: "/maple2021/v1/add-revocation",
+			Header:     map[string][]string{"Authorization": {"Bearer read"}},
+		}, "read", "write"))
+
+	require.False(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+		&http.Request{
+			RequestURI: "/maple2021/v1/add-anchor",
+			Header:     map[string][]string{"Authorization": {"Bearer read"}},
+		}, "read", "write"))
+
+	require.False(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+		&http.Request{
+			RequestURI: "/maple2021/v1/add-entry",
+			Header:     map[string][]string{"Authorization": {"Bearer read"}},
+		}, "read", "write"))
+
+	require.False(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+		&http.Request{
+			RequestURI: "/admin/read-only",
+			Header:     map[string][]string{"Authorization": {"Bearer read"}},
+		}, "read", "write"))
+
+	require.True(t, startcmd.ValidateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+		&http.Request{
+			RequestURI: "/admin/read-only",
+			Header:     map[string][]string{"Authorization": {"Bearer write"}},
+		}, "read", "write"))
+
+	rw := httptest.NewRecorder()
+
+	require.False(t, startcmd.ValidateAuthorizationBearerToken(rw, &http.Request{RequestURI: "/maple2021/v1/get-sth"},
+		"read", "write"))
+	require.Equal(t, http.StatusUnauthorized, rw.Code)
 }
 
 func TestAwsMetricsProvider(t *testing.T) {
@@ -0,0 +1,268 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// setState sets the state of the logs set on the admin API, persisted in the config store: the compromise of the
+// key, the frozen logs, the policies, the retired shards and the IPFS roots.
+func setState(cfg *command.Config, configStore storage.Store, logs []command.Log) error {
+	var err error
+
+	cfg.Compromise, err = getCompromise(configStore)
+	if err != nil {
+		return err
+	}
+
+	cfg.Freezes, err = getFreezes(configStore, logs)
+	if err != nil {
+		return err
+	}
+
+	cfg.Policies, err = getPolicies(configStore, logs)
+	if err != nil {
+		return err
+	}
+
+	cfg.RetiredShards, err = getRetiredShards(configStore)
+	if err != nil {
+		return err
+	}
+
+	cfg.IPFSRoots, err = getIPFSRoots(configStore, logs)
+	if err != nil {
+		return err
+	}
+
+	cfg.OnCompromise = storeCompromise(configStore)
+	cfg.OnFreeze = storeFreeze(configStore)
+	cfg.OnPolicy = storePolicy(configStore)
+	cfg.OnRetire = storeRetiredShard(configStore)
+
+	return nil
+}
+
+// getCompromise returns the compromise statement stored once the key of the log was marked compromised.
+func getCompromise(cfg storage.Store) (*command.SignedCompromiseStatement, error) {
+	src, err := cfg.Get(compromiseKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get compromise statement: %w", err)
+	}
+
+	var statement *command.SignedCompromiseStatement
+	if err = json.Unmarshal(src, &statement); err != nil {
+		return nil, fmt.Errorf("unmarshal compromise statement: %w", err)
+	}
+
+	return statement, nil
+}
+
+// storeCompromise returns the func storing the compromise statement once the key of the log is marked compromised.
+func storeCompromise(cfg storage.Store) func(*command.SignedCompromiseStatement) error {
+	return func(statement *command.SignedCompromiseStatement) error {
+		src, err := json.Marshal(statement)
+		if err != nil {
+			return fmt.Errorf("marshal compromise statement: %w", err)
+		}
+
+		return cfg.Put(compromiseKey, src) // nolint: wrapcheck
+	}
+}
+
+// getFreezes returns the final tree heads stored once the logs were frozen (alias -> final tree head).
+func getFreezes(cfg storage.Store, logs []command.Log) (map[string]*command.SignedFinalTreeHead, error) {
+	freezes := map[string]*command.SignedFinalTreeHead{}
+
+	for _, log := range logs {
+		src, err := cfg.Get(finalTreeHeadKey + log.Alias)
+		if errors.Is(err, storage.ErrDataNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("get final tree head of log %s: %w", log.Alias, err)
+		}
+
+		var final *command.SignedFinalTreeHead
+		if err = json.Unmarshal(src, &final); err != nil {
+			return nil, fmt.Errorf("unmarshal final tree head of log %s: %w", log.Alias, err)
+		}
+
+		freezes[log.Alias] = final
+	}
+
+	return freezes, nil
+}
+
+// storeFreeze returns the func storing the final tree head of a log once it is frozen.
+func storeFreeze(cfg storage.Store) func(string, *command.SignedFinalTreeHead) error {
+	return func(alias string, final *command.SignedFinalTreeHead) error {
+		src, err := json.Marshal(final)
+		if err != nil {
+			return fmt.Errorf("marshal final tree head: %w", err)
+		}
+
+		return cfg.Put(finalTreeHeadKey+alias, src) // nolint: wrapcheck
+	}
+}
+
+// getPolicies returns the stored policy history of the logs (alias -> versions of the policy, oldest first).
+func getPolicies(cfg storage.Store, logs []command.Log) (map[string][]command.SignedPolicyDocument, error) {
+	policies := map[string][]command.SignedPolicyDocument{}
+
+	for _, log := range logs {
+		history, err := getPolicyHistory(cfg, log.Alias)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(history) > 0 {
+			policies[log.Alias] = history
+		}
+	}
+
+	return policies, nil
+}
+
+func getPolicyHistory(cfg storage.Store, alias string) ([]command.SignedPolicyDocument, error) {
+	src, err := cfg.Get(policyKey + alias)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get policy history of log %s: %w", alias, err)
+	}
+
+	var history []command.SignedPolicyDocument
+	if err = json.Unmarshal(src, &history); err != nil {
+		return nil, fmt.Errorf("unmarshal policy history of log %s: %w", alias, err)
+	}
+
+	return history, nil
+}
+
+// storePolicy returns the func appending a published version of the policy of a log to its stored history.
+func storePolicy(cfg storage.Store) func(string, *command.SignedPolicyDocument) error {
+	return func(alias string, signed *command.SignedPolicyDocument) error {
+		history, err := getPolicyHistory(cfg, alias)
+		if err != nil {
+			return err
+		}
+
+		src, err := json.Marshal(append(history, *signed))
+		if err != nil {
+			return fmt.Errorf("marshal policy history: %w", err)
+		}
+
+		return cfg.Put(policyKey+alias, src) // nolint: wrapcheck
+	}
+}
+
+// getRetiredShards returns the archive of the retired shards, it is kept when their logs are no longer served.
+func getRetiredShards(cfg storage.Store) ([]command.RetiredShard, error) {
+	src, err := cfg.Get(retiredShardsKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get retired shards: %w", err)
+	}
+
+	var shards []command.RetiredShard
+	if err = json.Unmarshal(src, &shards); err != nil {
+		return nil, fmt.Errorf("unmarshal retired shards: %w", err)
+	}
+
+	return shards, nil
+}
+
+// storeRetiredShard returns the func adding a shard to the stored archive once its log is frozen, the shard
+// replaces a shard of the same log.
+func storeRetiredShard(cfg storage.Store) func(*command.RetiredShard) error {
+	return func(shard *command.RetiredShard) error {
+		shards, err := getRetiredShards(cfg)
+		if err != nil {
+			return err
+		}
+
+		archive := []command.RetiredShard{}
+
+		for _, s := range shards {
+			if s.Alias != shard.Alias || !bytes.Equal(s.LogID, shard.LogID) {
+				archive = append(archive, s)
+			}
+		}
+
+		src, err := json.Marshal(append(archive, *shard))
+		if err != nil {
+			return fmt.Errorf("marshal retired shards: %w", err)
+		}
+
+		return cfg.Put(retiredShardsKey, src) // nolint: wrapcheck
+	}
+}
+
+func getOrInit(cfg storage.Store, key string, v interface{}, initFn func() (interface{}, error), timeout uint64) error {
+	src, err := cfg.Get(key)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return fmt.Errorf("get config value for %q: %w", key, err)
+	}
+
+	if err == nil {
+		time.Sleep(time.Second * time.Duration(timeout))
+
+		var src2 []byte
+
+		src2, err = cfg.Get(key)
+		if err != nil && errors.Is(err, storage.ErrDataNotFound) {
+			return getOrInit(cfg, key, v, initFn, timeout)
+		}
+
+		if err != nil {
+			return fmt.Errorf("get config value for %q: %w", key, err)
+		}
+
+		if reflect.DeepEqual(src, src2) {
+			return json.Unmarshal(src, v) // nolint: wrapcheck
+		}
+
+		return getOrInit(cfg, key, v, initFn, timeout)
+	}
+
+	val, err := initFn()
+	if err != nil {
+		return fmt.Errorf("init config value for %q: %w", key, err)
+	}
+
+	src, err = json.Marshal(val)
+	if err != nil {
+		return fmt.Errorf("marshal config value for %q: %w", key, err)
+	}
+
+	if err = cfg.Put(key, src); err != nil {
+		return fmt.Errorf("marshal config value for %q: %w", key, err)
+	}
+
+	return getOrInit(cfg, key, v, initFn, timeout)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	submissionTTLFlagName  = "submission-ttl"
+	submissionTTLFlagUsage = "TTL of the submissions (e.g. 24h, the maximum merge delay promised by the SCTs), the" +
+		" submissions queued by the instance which are not sequenced within it are listed on the public path" +
+		" /{alias}/unmerged and counted by the unmerged_submissions metric. The submissions do not expire if not set." +
+		" Alternatively, this can be set with the following environment variable: " + submissionTTLEnvKey
+	submissionTTLEnvKey = envPrefix + "SUBMISSION_TTL"
+
+	submissionRequeueFlagName  = "submission-requeue"
+	submissionRequeueFlagUsage = "Re-queues the unmerged submissions to the log once per TTL, up to" +
+		" --" + submissionMaxRequeuesFlagName + " times. Defaults to false." +
+		" Alternatively, this can be set with the following environment variable: " + submissionRequeueEnvKey
+	submissionRequeueEnvKey = envPrefix + "SUBMISSION_REQUEUE"
+
+	submissionMaxRequeuesFlagName  = "submission-max-requeues"
+	submissionMaxRequeuesFlagUsage = "Number of times an unmerged submission is re-queued. Defaults to 3." +
+		" Alternatively, this can be set with the following environment variable: " + submissionMaxRequeuesEnvKey
+	submissionMaxRequeuesEnvKey = envPrefix + "SUBMISSION_MAX_REQUEUES"
+)
+
+// getSubmissionTTL returns the configuration of the TTL of the submissions, nil if they do not expire.
+func getSubmissionTTL(cmd *cobra.Command) (*command.SubmissionTTLConfig, error) {
+	ttlStr := cmdutils.GetUserSetOptionalVarFromString(cmd, submissionTTLFlagName, submissionTTLEnvKey)
+	if ttlStr == "" {
+		return nil, nil
+	}
+
+	ttl, err := time.ParseDuration(ttlStr)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("submission TTL is not a positive duration: %s", ttlStr)
+	}
+
+	cfg := &command.SubmissionTTLConfig{TTL: ttl}
+
+	if requeueStr := cmdutils.GetUserSetOptionalVarFromString(cmd, submissionRequeueFlagName,
+		submissionRequeueEnvKey); requeueStr != "" {
+		cfg.Requeue, err = strconv.ParseBool(requeueStr)
+		if err != nil {
+			return nil, fmt.Errorf("submission requeue is not a bool: %w", err)
+		}
+	}
+
+	if maxRequeuesStr := cmdutils.GetUserSetOptionalVarFromString(cmd, submissionMaxRequeuesFlagName,
+		submissionMaxRequeuesEnvKey); maxRequeuesStr != "" {
+		cfg.MaxRequeues, err = strconv.Atoi(maxRequeuesStr)
+		if err != nil || cfg.MaxRequeues <= 0 {
+			return nil, fmt.Errorf("submission max requeues is not a positive number: %s", maxRequeuesStr)
+		}
+	}
+
+	return cfg, nil
+}
+
+func createSubmissionTTLFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(submissionTTLFlagName, "", submissionTTLFlagUsage)
+	startCmd.Flags().String(submissionRequeueFlagName, "", submissionRequeueFlagUsage)
+	startCmd.Flags().String(submissionMaxRequeuesFlagName, "", submissionMaxRequeuesFlagUsage)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+	tlsutils "github.com/trustbloc/edge-core/pkg/utils/tls"
+)
+
+const (
+	tlsSystemCertPoolFlagName  = "tls-systemcertpool"
+	tlsSystemCertPoolFlagUsage = "Use system certificate pool." +
+		" Possible values [true] [false]. Defaults to false if not set." +
+		" Alternatively, this can be set with the following environment variable: " + tlsSystemCertPoolEnvKey
+	tlsSystemCertPoolEnvKey = envPrefix + "TLS_SYSTEMCERTPOOL"
+
+	tlsCACertsFlagName  = "tls-cacerts"
+	tlsCACertsFlagUsage = "Comma-Separated list of ca certs path." +
+		" Alternatively, this can be set with the following environment variable: " + tlsCACertsEnvKey
+	tlsCACertsEnvKey = envPrefix + "TLS_CACERTS"
+
+	tlsServeCertPathFlagName  = "tls-serve-cert"
+	tlsServeCertPathFlagUsage = "Path to the server certificate to use when serving HTTPS." +
+		" Alternatively, this can be set with the following environment variable: " + tlsServeCertPathEnvKey
+	tlsServeCertPathEnvKey = envPrefix + "TLS_SERVE_CERT"
+
+	tlsServeKeyPathFlagName  = "tls-serve-key"
+	tlsServeKeyPathFlagUsage = "Path to the private key to use when serving HTTPS." +
+		" Alternatively, this can be set with the following environment variable: " + tlsServeKeyPathFlagEnvKey
+	tlsServeKeyPathFlagEnvKey = envPrefix + "TLS_SERVE_KEY"
+
+	tlsClientCACertsFlagName  = "tls-client-cacerts"
+	tlsClientCACertsFlagUsage = "Comma-Separated list of ca certs path client certificates are verified with." +
+		" Required for mtls role bindings." +
+		" Alternatively, this can be set with the following environment variable: " + tlsClientCACertsEnvKey
+	tlsClientCACertsEnvKey = envPrefix + "TLS_CLIENT_CACERTS"
+)
+
+type tlsParameters struct {
+	systemCertPool bool
+	caCerts        []string
+	serveCertPath  string
+	serveKeyPath   string
+	clientCACerts  []string
+}
+
+func getTLS(cmd *cobra.Command) (*tlsParameters, error) {
+	tlsSystemCertPoolString := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsSystemCertPoolFlagName,
+		tlsSystemCertPoolEnvKey)
+	tlsCACerts := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsCACertsFlagName, tlsCACertsEnvKey)
+	tlsServeCertPath := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsServeCertPathFlagName, tlsServeCertPathEnvKey)
+	tlsServeKeyPath := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsServeKeyPathFlagName, tlsServeKeyPathFlagEnvKey)
+	tlsClientCACerts := cmdutils.GetUserSetOptionalVarFromString(cmd, tlsClientCACertsFlagName, tlsClientCACertsEnvKey)
+
+	tlsSystemCertPool := false
+
+	if tlsSystemCertPoolString != "" {
+		var err error
+
+		tlsSystemCertPool, err = strconv.ParseBool(tlsSystemCertPoolString)
+		if err != nil {
+			return nil, fmt.Errorf("parse cert pool: %w", err)
+		}
+	}
+
+	var caCerts []string
+	if tlsCACerts != "" {
+		caCerts = strings.Split(tlsCACerts, ",")
+	}
+
+	var clientCACerts []string
+	if tlsClientCACerts != "" {
+		clientCACerts = strings.Split(tlsClientCACerts, ",")
+	}
+
+	return &tlsParameters{
+		systemCertPool: tlsSystemCertPool,
+		caCerts:        caCerts,
+		serveCertPath:  tlsServeCertPath,
+		serveKeyPath:   tlsServeKeyPath,
+		clientCACerts:  clientCACerts,
+	}, nil
+}
+
+// createHTTPClient returns the client of the outgoing requests, it verifies the servers with the CA certs.
+func createHTTPClient(params *tlsParameters) (*http.Client, error) {
+	rootCAs, err := tlsutils.GetCertPool(params.systemCertPool, params.caCerts)
+	if err != nil {
+		return nil, fmt.Errorf("get cert pool: %w", err)
+	}
+
+	return &http.Client{
+		Timeout: time.Minute,
+		Transport: &http.Transport{
+			ForceAttemptHTTP2: true,
+			TLSClientConfig: &tls.Config{
+				RootCAs:    rootCAs,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}, nil
+}
+
+// getClientCAs returns the pool of the CA certs the client certificates are verified with, nil if none is set.
+func getClientCAs(params *tlsParameters) (*x509.CertPool, error) {
+	if len(params.clientCACerts) == 0 {
+		return nil, nil
+	}
+
+	clientCAs, err := tlsutils.GetCertPool(false, params.clientCACerts)
+	if err != nil {
+		return nil, fmt.Errorf("get client cert pool: %w", err)
+	}
+
+	return clientCAs, nil
+}
+
+func createTLSFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(tlsSystemCertPoolFlagName, "false", tlsSystemCertPoolFlagUsage)
+	startCmd.Flags().String(tlsCACertsFlagName, "", tlsCACertsFlagUsage)
+	startCmd.Flags().String(tlsServeCertPathFlagName, "", tlsServeCertPathFlagUsage)
+	startCmd.Flags().String(tlsServeKeyPathFlagName, "", tlsServeKeyPathFlagUsage)
+	startCmd.Flags().String(tlsClientCACertsFlagName, "", tlsClientCACertsFlagUsage)
+}
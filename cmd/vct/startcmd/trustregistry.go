@@ -0,0 +1,82 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/trustregistry"
+)
+
+const (
+	trustRegistryURLFlagName  = "trust-registry-url"
+	trustRegistryURLFlagUsage = "URL of a trust registry (ToIP Trust Registry Query Protocol) the issuer of every" +
+		" submitted credential is checked against, credentials of issuers it does not authorize are rejected." +
+		" The decision is recorded in the extensions of the entry. Not checked if not set." +
+		" Alternatively, this can be set with the following environment variable: " + trustRegistryURLEnvKey
+	trustRegistryURLEnvKey = envPrefix + "TRUST_REGISTRY_URL"
+
+	trustRegistryAuthorityIDFlagName  = "trust-registry-authority-id"
+	trustRegistryAuthorityIDFlagUsage = "ID of the authority (e.g. the ecosystem governance authority) issuers are" +
+		" checked to be authorized by in the trust registry." +
+		" Alternatively, this can be set with the following environment variable: " + trustRegistryAuthorityIDEnvKey
+	trustRegistryAuthorityIDEnvKey = envPrefix + "TRUST_REGISTRY_AUTHORITY_ID"
+
+	trustRegistryCacheTTLFlagName  = "trust-registry-cache-ttl"
+	trustRegistryCacheTTLFlagUsage = "Time the decisions of the trust registry are cached for (e.g 5m)." +
+		" Defaults to 10m if not set." +
+		" Alternatively, this can be set with the following environment variable: " + trustRegistryCacheTTLEnvKey
+	trustRegistryCacheTTLEnvKey = envPrefix + "TRUST_REGISTRY_CACHE_TTL"
+)
+
+type trustRegistryParameters struct {
+	url         string
+	authorityID string
+	cacheTTL    time.Duration
+}
+
+func getTrustRegistry(cmd *cobra.Command) (*trustRegistryParameters, error) {
+	params := &trustRegistryParameters{
+		url: cmdutils.GetUserSetOptionalVarFromString(cmd, trustRegistryURLFlagName, trustRegistryURLEnvKey),
+		authorityID: cmdutils.GetUserSetOptionalVarFromString(cmd, trustRegistryAuthorityIDFlagName,
+			trustRegistryAuthorityIDEnvKey),
+	}
+
+	if cacheTTLStr := cmdutils.GetUserSetOptionalVarFromString(cmd, trustRegistryCacheTTLFlagName,
+		trustRegistryCacheTTLEnvKey); cacheTTLStr != "" {
+		cacheTTL, err := time.ParseDuration(cacheTTLStr)
+		if err != nil {
+			return nil, fmt.Errorf("trust registry cache TTL is not a duration: %w", err)
+		}
+
+		params.cacheTTL = cacheTTL
+	}
+
+	return params, nil
+}
+
+// createTrustRegistry returns the trust registry of the parameters, nil if not configured.
+func createTrustRegistry(params *trustRegistryParameters, httpClient *http.Client) command.TrustRegistry {
+	if params.url == "" {
+		return nil
+	}
+
+	return trustregistry.New(params.url, trustregistry.WithAuthorityID(params.authorityID),
+		trustregistry.WithHTTPClient(httpClient))
+}
+
+func createTrustRegistryFlags(startCmd *cobra.Command) {
+	startCmd.Flags().String(trustRegistryURLFlagName, "", trustRegistryURLFlagUsage)
+	startCmd.Flags().String(trustRegistryAuthorityIDFlagName, "", trustRegistryAuthorityIDFlagUsage)
+	startCmd.Flags().String(trustRegistryCacheTTLFlagName, "", trustRegistryCacheTTLFlagUsage)
+}
@@ -0,0 +1,343 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditcmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	envPrefix = "VCTCTL_"
+
+	vctURLFlagName  = "vct-url"
+	vctURLEnvKey    = envPrefix + "VCT_URL"
+	vctURLFlagUsage = "URL of the log the export is taken from (e.g. https://vct.example.com/maple2021)." +
+		" Alternatively, this can be set with the following environment variable: " + vctURLEnvKey
+
+	firstFlagName  = "first"
+	firstEnvKey    = envPrefix + "AUDIT_FIRST"
+	firstFlagUsage = "Tree size the exported range starts at, the first exported entry has this leaf index." +
+		" Alternatively, this can be set with the following environment variable: " + firstEnvKey
+
+	secondFlagName  = "second"
+	secondEnvKey    = envPrefix + "AUDIT_SECOND"
+	secondFlagUsage = "Tree size the exported range ends at (exclusive)." +
+		" Alternatively, this can be set with the following environment variable: " + secondEnvKey
+
+	outputFlagName  = "output"
+	outputEnvKey    = envPrefix + "AUDIT_OUTPUT"
+	outputFlagUsage = "File the export is written to. Defaults to the standard output if not set." +
+		" Alternatively, this can be set with the following environment variable: " + outputEnvKey
+
+	authReadTokenFlagName  = "auth-read-token"
+	authReadTokenEnvKey    = envPrefix + "AUTH_READ_TOKEN"
+	authReadTokenFlagUsage = "Bearer token used to retrieve the export." +
+		" Alternatively, this can be set with the following environment variable: " + authReadTokenEnvKey
+
+	exportFlagName  = "export"
+	exportEnvKey    = envPrefix + "AUDIT_EXPORT"
+	exportFlagUsage = "File with the export to verify or '-' to read it from the standard input." +
+		" Alternatively, this can be set with the following environment variable: " + exportEnvKey
+
+	publicKeyFlagName  = "public-key"
+	publicKeyEnvKey    = envPrefix + "AUDIT_PUBLIC_KEY"
+	publicKeyFlagUsage = "Base64-encoded public key of the log (as published by its webfinger) the export or the" +
+		" sample is verified with. Alternatively, this can be set with the following environment variable: " + publicKeyEnvKey
+
+	countFlagName  = "count"
+	countEnvKey    = envPrefix + "AUDIT_COUNT"
+	countFlagUsage = "Number of entries of the sample." +
+		" Alternatively, this can be set with the following environment variable: " + countEnvKey
+
+	seedFlagName  = "seed"
+	seedEnvKey    = envPrefix + "AUDIT_SEED"
+	seedFlagUsage = "Seed the sample is derived from along with the root hash of the tree, e.g. the value of a" +
+		" randomness beacon so the log can't predict the sample." +
+		" Alternatively, this can be set with the following environment variable: " + seedEnvKey
+
+	sampleOutputFlagUsage = "File the verified sample is written to. The sample is not written if not set." +
+		" Alternatively, this can be set with the following environment variable: " + outputEnvKey
+
+	stdinExport = "-"
+)
+
+// Cmd returns the Cobra audit command.
+func Cmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Exports and verifies signed audit extracts of a log",
+		Long: "Exports the entries of a tree-size range of a log with their proofs and the chain of signed tree heads" +
+			" as a signed, timestamped document, and verifies such a document offline with the public key of the log." +
+			" Samples the entries of a log pseudo-randomly to spot audit it without downloading all of its entries",
+	}
+
+	auditCmd.AddCommand(exportCmd(), verifyCmd(), sampleCmd())
+
+	return auditCmd
+}
+
+func exportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports a signed audit extract of a tree-size range of a log",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vctURL, err := cmdutils.GetUserSetVarFromString(cmd, vctURLFlagName, vctURLEnvKey, false)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			first, err := getUint(cmd, firstFlagName, firstEnvKey)
+			if err != nil {
+				return err
+			}
+
+			second, err := getUint(cmd, secondFlagName, secondEnvKey)
+			if err != nil {
+				return err
+			}
+
+			client := vct.New(vctURL, vct.WithAuthReadToken(
+				cmdutils.GetUserSetOptionalVarFromString(cmd, authReadTokenFlagName, authReadTokenEnvKey)))
+
+			export, err := client.GetAuditExport(cmd.Context(), first, second)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			out := cmd.OutOrStdout()
+
+			if output := cmdutils.GetUserSetOptionalVarFromString(cmd, outputFlagName, outputEnvKey); output != "" {
+				f, er := os.Create(filepath.Clean(output))
+				if er != nil {
+					return fmt.Errorf("create output: %w", er)
+				}
+
+				defer f.Close() // nolint: errcheck
+
+				out = f
+			}
+
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(export) // nolint: wrapcheck
+		},
+	}
+
+	cmd.Flags().String(vctURLFlagName, "", vctURLFlagUsage)
+	cmd.Flags().String(firstFlagName, "", firstFlagUsage)
+	cmd.Flags().String(secondFlagName, "", secondFlagUsage)
+	cmd.Flags().String(outputFlagName, "", outputFlagUsage)
+	cmd.Flags().String(authReadTokenFlagName, "", authReadTokenFlagUsage)
+
+	return cmd
+}
+
+// Verification is the summary of a verified export.
+type Verification struct {
+	Alias          string    `json:"alias"`
+	FirstTreeSize  int64     `json:"first_tree_size"`
+	SecondTreeSize int64     `json:"second_tree_size"`
+	Entries        int       `json:"entries"`
+	STHTreeSize    uint64    `json:"sth_tree_size"`
+	STHTimestamp   time.Time `json:"sth_timestamp"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+func verifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verifies a signed audit extract offline",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, err := cmdutils.GetUserSetVarFromString(cmd, exportFlagName, exportEnvKey, false)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			publicKeyStr, err := cmdutils.GetUserSetVarFromString(cmd, publicKeyFlagName, publicKeyEnvKey, false)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			publicKey, err := base64.StdEncoding.DecodeString(publicKeyStr)
+			if err != nil {
+				return fmt.Errorf("decode public key: %w", err)
+			}
+
+			export, err := readExport(source, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+
+			if err = vct.VerifyAuditExport(export, publicKey); err != nil {
+				return fmt.Errorf("verify audit export: %w", err)
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(Verification{ // nolint: wrapcheck
+				Alias:          export.Export.Alias,
+				FirstTreeSize:  export.Export.FirstTreeSize,
+				SecondTreeSize: export.Export.SecondTreeSize,
+				Entries:        len(export.Export.Entries),
+				STHTreeSize:    export.Export.STH.TreeSize,
+				STHTimestamp:   time.Unix(0, int64(export.Export.STH.Timestamp)*int64(time.Millisecond)).UTC(),
+				Timestamp:      time.Unix(0, int64(export.Export.Timestamp)*int64(time.Millisecond)).UTC(),
+			})
+		},
+	}
+
+	cmd.Flags().String(exportFlagName, "", exportFlagUsage)
+	cmd.Flags().String(publicKeyFlagName, "", publicKeyFlagUsage)
+
+	return cmd
+}
+
+// SampleVerification is the summary of a verified sample.
+type SampleVerification struct {
+	Seed         string    `json:"seed"`
+	LeafIndices  []int64   `json:"leaf_indices"`
+	STHTreeSize  uint64    `json:"sth_tree_size"`
+	STHTimestamp time.Time `json:"sth_timestamp"`
+}
+
+func sampleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sample",
+		Short: "Retrieves and verifies a pseudo-random sample of the entries of a log",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vctURL, err := cmdutils.GetUserSetVarFromString(cmd, vctURLFlagName, vctURLEnvKey, false)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			count, err := getUint(cmd, countFlagName, countEnvKey)
+			if err != nil {
+				return err
+			}
+
+			publicKeyStr, err := cmdutils.GetUserSetVarFromString(cmd, publicKeyFlagName, publicKeyEnvKey, false)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			publicKey, err := base64.StdEncoding.DecodeString(publicKeyStr)
+			if err != nil {
+				return fmt.Errorf("decode public key: %w", err)
+			}
+
+			seed := cmdutils.GetUserSetOptionalVarFromString(cmd, seedFlagName, seedEnvKey)
+
+			client := vct.New(vctURL, vct.WithAuthReadToken(
+				cmdutils.GetUserSetOptionalVarFromString(cmd, authReadTokenFlagName, authReadTokenEnvKey)))
+
+			sample, err := client.GetRandomEntries(cmd.Context(), int(count), seed)
+			if err != nil {
+				return err // nolint: wrapcheck
+			}
+
+			if err = vct.VerifyRandomEntries(sample, seed, int(count), publicKey); err != nil {
+				return fmt.Errorf("verify sample: %w", err)
+			}
+
+			if output := cmdutils.GetUserSetOptionalVarFromString(cmd, outputFlagName, outputEnvKey); output != "" {
+				if err = writeJSON(output, sample); err != nil {
+					return err
+				}
+			}
+
+			indices := make([]int64, len(sample.Entries))
+			for i, entry := range sample.Entries {
+				indices[i] = entry.LeafIndex
+			}
+
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(SampleVerification{ // nolint: wrapcheck
+				Seed:         sample.Seed,
+				LeafIndices:  indices,
+				STHTreeSize:  sample.STH.TreeSize,
+				STHTimestamp: time.Unix(0, int64(sample.STH.Timestamp)*int64(time.Millisecond)).UTC(),
+			})
+		},
+	}
+
+	cmd.Flags().String(vctURLFlagName, "", vctURLFlagUsage)
+	cmd.Flags().String(countFlagName, "", countFlagUsage)
+	cmd.Flags().String(seedFlagName, "", seedFlagUsage)
+	cmd.Flags().String(publicKeyFlagName, "", publicKeyFlagUsage)
+	cmd.Flags().String(outputFlagName, "", sampleOutputFlagUsage)
+	cmd.Flags().String(authReadTokenFlagName, "", authReadTokenFlagUsage)
+
+	return cmd
+}
+
+func writeJSON(output string, v interface{}) error {
+	f, err := os.Create(filepath.Clean(output))
+	if err != nil {
+		return fmt.Errorf("create output: %w", err)
+	}
+
+	defer f.Close() // nolint: errcheck
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v) // nolint: wrapcheck
+}
+
+func readExport(source string, stdin io.Reader) (*command.GetAuditExportResponse, error) {
+	r := stdin
+
+	if source != stdinExport {
+		f, err := os.Open(filepath.Clean(source))
+		if err != nil {
+			return nil, fmt.Errorf("open export: %w", err)
+		}
+
+		defer f.Close() // nolint: errcheck
+
+		r = f
+	}
+
+	var export *command.GetAuditExportResponse
+
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("decode export: %w", err)
+	}
+
+	return export, nil
+}
+
+func getUint(cmd *cobra.Command, flagName, envKey string) (uint64, error) {
+	str, err := cmdutils.GetUserSetVarFromString(cmd, flagName, envKey, false)
+	if err != nil {
+		return 0, err // nolint: wrapcheck
+	}
+
+	val, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number(positive): %w", flagName, err)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auditcmd_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/cmd/vctctl/auditcmd"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// publicKey of the log auditExport.json was exported from.
+const publicKey = "BDt4QyegzW6ItfFtnDGmAkb8oZK22sD7tR/WrsKe2d/BiThLBHuhmDIRIA7ZTnbXgiLSxcGU5uodFnjmHHD4Jck="
+
+// nolint: gochecknoglobals
+var (
+	//go:embed testdata/auditExport.json
+	auditExport []byte
+)
+
+func execute(stdin string, args ...string) (string, error) {
+	cmd := auditcmd.Cmd()
+	cmd.SetArgs(args)
+	cmd.SetIn(strings.NewReader(stdin))
+
+	var out bytes.Buffer
+
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+
+	return out.String(), err // nolint: wrapcheck
+}
+
+func TestExportAndVerify(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/maple2021/v1/get-audit-export" || r.Header.Get("Authorization") != "Bearer read" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		require.Equal(t, "1", r.URL.Query().Get("first"))
+		require.Equal(t, "3", r.URL.Query().Get("second"))
+
+		_, _ = w.Write(auditExport)
+	}))
+	defer ts.Close()
+
+	output := filepath.Join(t.TempDir(), "export.json")
+
+	_, err := execute("", "export",
+		"--vct-url", ts.URL+"/maple2021",
+		"--first", "1",
+		"--second", "3",
+		"--output", output,
+		"--auth-read-token", "read",
+	)
+	require.NoError(t, err)
+
+	out, err := execute("", "verify", "--export", output, "--public-key", publicKey)
+	require.NoError(t, err)
+
+	var verification *auditcmd.Verification
+	require.NoError(t, json.Unmarshal([]byte(out), &verification))
+	require.Equal(t, "maple2021", verification.Alias)
+	require.Equal(t, int64(1), verification.FirstTreeSize)
+	require.Equal(t, int64(3), verification.SecondTreeSize)
+	require.Equal(t, 2, verification.Entries)
+	require.Equal(t, uint64(5), verification.STHTreeSize)
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("Stdin", func(t *testing.T) {
+		_, err := execute(string(auditExport), "verify", "--export", "-", "--public-key", publicKey)
+		require.NoError(t, err)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		tampered := strings.Replace(string(auditExport), `"first_tree_size": 1`, `"first_tree_size": 0`, 1)
+
+		_, err := execute(tampered, "verify", "--export", "-", "--public-key", publicKey)
+		require.Contains(t, err.Error(), "verify audit export: export signature")
+	})
+
+	t.Run("Wrong public key", func(t *testing.T) {
+		_, err := execute(string(auditExport), "verify", "--export", "-", "--public-key", "invalid")
+		require.Contains(t, err.Error(), "decode public key")
+	})
+
+	t.Run("No export", func(t *testing.T) {
+		_, err := execute("", "verify", "--export", filepath.Join(t.TempDir(), "none"), "--public-key", publicKey)
+		require.Contains(t, err.Error(), "open export")
+
+		_, err = execute("{", "verify", "--export", "-", "--public-key", publicKey)
+		require.Contains(t, err.Error(), "decode export")
+	})
+}
+
+func TestExport(t *testing.T) {
+	t.Run("Invalid range", func(t *testing.T) {
+		_, err := execute("", "export", "--vct-url", "http://localhost", "--first", "one", "--second", "2")
+		require.Contains(t, err.Error(), "first is not a number(positive)")
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		ts := httptest.NewServer(http.NotFoundHandler())
+		defer ts.Close()
+
+		_, err := execute("", "export", "--vct-url", ts.URL+"/maple2021", "--first", "1", "--second", "2")
+		require.Contains(t, err.Error(), "get audit export")
+	})
+}
+
+func TestSample(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	leaf := []byte("leaf")
+	root := sha256.Sum256(append([]byte{0}, leaf...))
+
+	statement, err := json.Marshal(command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      1,
+		TreeSize:       1,
+		SHA256RootHash: root[:],
+	})
+	require.NoError(t, err)
+
+	signature, err := json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{Signature: command.EDDSASignature, Type: kms.ED25519},
+		Signature: ed25519.Sign(priv, statement),
+	})
+	require.NoError(t, err)
+
+	sample, err := json.Marshal(command.GetRandomEntriesResponse{
+		Seed:    "beacon",
+		STH:     command.GetSTHResponse{TreeSize: 1, Timestamp: 1, SHA256RootHash: root[:], TreeHeadSignature: signature},
+		Entries: []command.AuditEntry{{LeafIndex: 0, LeafInput: leaf}},
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/maple2021/v1/get-random-entries" || r.Header.Get("Authorization") != "Bearer read" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		require.Equal(t, "5", r.URL.Query().Get("count"))
+
+		_, _ = w.Write(sample)
+	}))
+	defer ts.Close()
+
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+
+	t.Run("Success", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "sample.json")
+
+		out, err := execute("", "sample",
+			"--vct-url", ts.URL+"/maple2021",
+			"--count", "5",
+			"--seed", "beacon",
+			"--public-key", publicKey,
+			"--output", output,
+			"--auth-read-token", "read",
+		)
+		require.NoError(t, err)
+		require.FileExists(t, output)
+
+		var verification *auditcmd.SampleVerification
+		require.NoError(t, json.Unmarshal([]byte(out), &verification))
+		require.Equal(t, "beacon", verification.Seed)
+		require.Equal(t, []int64{0}, verification.LeafIndices)
+		require.Equal(t, uint64(1), verification.STHTreeSize)
+	})
+
+	t.Run("Another seed", func(t *testing.T) {
+		_, err := execute("", "sample", "--vct-url", ts.URL+"/maple2021", "--count", "5", "--seed", "other",
+			"--public-key", publicKey, "--auth-read-token", "read")
+		require.Contains(t, err.Error(), `verify sample: sample is derived from seed "beacon"`)
+	})
+
+	t.Run("Invalid flags", func(t *testing.T) {
+		_, err := execute("", "sample", "--vct-url", ts.URL, "--count", "five", "--public-key", publicKey)
+		require.Contains(t, err.Error(), "count is not a number(positive)")
+
+		_, err = execute("", "sample", "--vct-url", ts.URL, "--count", "5", "--public-key", "invalid")
+		require.Contains(t, err.Error(), "decode public key")
+	})
+
+	t.Run("Server error", func(t *testing.T) {
+		_, err := execute("", "sample", "--vct-url", ts.URL+"/maple2022", "--count", "5", "--public-key", publicKey)
+		require.Contains(t, err.Error(), "get random entries")
+	})
+}
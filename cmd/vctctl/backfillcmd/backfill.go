@@ -0,0 +1,524 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backfillcmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+var logger = log.New("vctctl/backfill")
+
+const (
+	envPrefix = "VCTCTL_"
+
+	vctURLFlagName  = "vct-url"
+	vctURLEnvKey    = envPrefix + "VCT_URL"
+	vctURLFlagUsage = "URL of the log the credentials are added to (e.g. https://vct.example.com/maple2021)." +
+		" Alternatively, this can be set with the following environment variable: " + vctURLEnvKey
+
+	sourceFlagName  = "source"
+	sourceEnvKey    = envPrefix + "BACKFILL_SOURCE"
+	sourceFlagUsage = "Credentials to import: a directory with a credential per file, a file with a credential" +
+		" per line or '-' to read credentials per line from the standard input." +
+		" Alternatively, this can be set with the following environment variable: " + sourceEnvKey
+
+	rateFlagName  = "rate"
+	rateEnvKey    = envPrefix + "BACKFILL_RATE"
+	rateFlagUsage = "Maximum number of credentials submitted per second. Defaults to 10 if not set." +
+		" Alternatively, this can be set with the following environment variable: " + rateEnvKey
+
+	checkpointFlagName  = "checkpoint"
+	checkpointEnvKey    = envPrefix + "BACKFILL_CHECKPOINT"
+	checkpointFlagUsage = "File the progress is recorded to, a backfill started with the same file resumes" +
+		" skipping the credentials added before. Defaults to " + defaultCheckpoint + " if not set." +
+		" Alternatively, this can be set with the following environment variable: " + checkpointEnvKey
+
+	reportFlagName  = "report"
+	reportEnvKey    = envPrefix + "BACKFILL_REPORT"
+	reportFlagUsage = "File the reconciliation report is written to. Defaults to the standard output if not set." +
+		" Alternatively, this can be set with the following environment variable: " + reportEnvKey
+
+	maxRetriesFlagName  = "max-retries"
+	maxRetriesEnvKey    = envPrefix + "BACKFILL_MAX_RETRIES"
+	maxRetriesFlagUsage = "Number of retries of a submission failed with a transient error. Defaults to 3 if not set." +
+		" Alternatively, this can be set with the following environment variable: " + maxRetriesEnvKey
+
+	authReadTokenFlagName  = "auth-read-token"
+	authReadTokenEnvKey    = envPrefix + "AUTH_READ_TOKEN"
+	authReadTokenFlagUsage = "Bearer token used to reconcile the submitted credentials." +
+		" Alternatively, this can be set with the following environment variable: " + authReadTokenEnvKey
+
+	authWriteTokenFlagName  = "auth-write-token"
+	authWriteTokenEnvKey    = envPrefix + "AUTH_WRITE_TOKEN"
+	authWriteTokenFlagUsage = "Bearer token used to submit the credentials." +
+		" Alternatively, this can be set with the following environment variable: " + authWriteTokenEnvKey
+
+	stdinSource = "-"
+
+	defaultRate       = 10
+	defaultMaxRetries = 3
+	defaultCheckpoint = "backfill.checkpoint"
+)
+
+// Cmd returns the Cobra backfill command.
+func Cmd() *cobra.Command {
+	backfillCmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Imports existing credentials into a log",
+		Long: "Submits existing credentials to a log at a controlled rate, records the progress to a checkpoint" +
+			" so an interrupted backfill can be resumed and reports the credentials the log has not confirmed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params, err := getParameters(cmd)
+			if err != nil {
+				return err
+			}
+
+			return run(cmd.Context(), params, cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+
+	backfillCmd.Flags().String(vctURLFlagName, "", vctURLFlagUsage)
+	backfillCmd.Flags().String(sourceFlagName, "", sourceFlagUsage)
+	backfillCmd.Flags().String(rateFlagName, "", rateFlagUsage)
+	backfillCmd.Flags().String(checkpointFlagName, "", checkpointFlagUsage)
+	backfillCmd.Flags().String(reportFlagName, "", reportFlagUsage)
+	backfillCmd.Flags().String(maxRetriesFlagName, "", maxRetriesFlagUsage)
+	backfillCmd.Flags().String(authReadTokenFlagName, "", authReadTokenFlagUsage)
+	backfillCmd.Flags().String(authWriteTokenFlagName, "", authWriteTokenFlagUsage)
+
+	return backfillCmd
+}
+
+type parameters struct {
+	vctURL         string
+	source         string
+	rate           uint64
+	checkpoint     string
+	report         string
+	maxRetries     uint64
+	authReadToken  string
+	authWriteToken string
+}
+
+func getParameters(cmd *cobra.Command) (*parameters, error) {
+	vctURL, err := cmdutils.GetUserSetVarFromString(cmd, vctURLFlagName, vctURLEnvKey, false)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	source, err := cmdutils.GetUserSetVarFromString(cmd, sourceFlagName, sourceEnvKey, false)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	rate, err := getUint(cmd, rateFlagName, rateEnvKey, defaultRate)
+	if err != nil {
+		return nil, err
+	}
+
+	if rate == 0 {
+		return nil, fmt.Errorf("%s must be positive", rateFlagName)
+	}
+
+	maxRetries, err := getUint(cmd, maxRetriesFlagName, maxRetriesEnvKey, defaultMaxRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := cmdutils.GetUserSetOptionalVarFromString(cmd, checkpointFlagName, checkpointEnvKey)
+	if checkpoint == "" {
+		checkpoint = defaultCheckpoint
+	}
+
+	return &parameters{
+		vctURL:         vctURL,
+		source:         source,
+		rate:           rate,
+		checkpoint:     checkpoint,
+		report:         cmdutils.GetUserSetOptionalVarFromString(cmd, reportFlagName, reportEnvKey),
+		maxRetries:     maxRetries,
+		authReadToken:  cmdutils.GetUserSetOptionalVarFromString(cmd, authReadTokenFlagName, authReadTokenEnvKey),
+		authWriteToken: cmdutils.GetUserSetOptionalVarFromString(cmd, authWriteTokenFlagName, authWriteTokenEnvKey),
+	}, nil
+}
+
+func getUint(cmd *cobra.Command, flagName, envKey string, defaultValue uint64) (uint64, error) {
+	str := cmdutils.GetUserSetOptionalVarFromString(cmd, flagName, envKey)
+	if str == "" {
+		return defaultValue, nil
+	}
+
+	val, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number(positive): %w", flagName, err)
+	}
+
+	return val, nil
+}
+
+// Report is the reconciliation report of a backfill.
+type Report struct {
+	// Submitted is the number of credentials added by this run.
+	Submitted int `json:"submitted"`
+	// Skipped is the number of credentials added by the previous runs.
+	Skipped int `json:"skipped"`
+	// Failed lists credentials which could not be added.
+	Failed []Failure `json:"failed"`
+	// Confirmed is the number of added credentials the log reports as logged.
+	Confirmed int `json:"confirmed"`
+	// Missing lists added credentials the log does not report as logged (e.g. not integrated yet).
+	Missing []string `json:"missing"`
+	// Unverifiable is the number of added credentials without an ID or an issuer which can not be looked up.
+	Unverifiable int `json:"unverifiable"`
+	// Unreconciled lists added credentials which could not be looked up.
+	Unreconciled []Failure `json:"unreconciled"`
+}
+
+// Failure describes a credential that failed.
+type Failure struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// checkpointEntry is a line of the checkpoint file recording the outcome of a submission.
+type checkpointEntry struct {
+	Source       string `json:"source"`
+	Issuer       string `json:"issuer,omitempty"`
+	CredentialID string `json:"credential_id,omitempty"`
+	Timestamp    uint64 `json:"timestamp,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// record is a credential read from the source, its source identifies the credential across runs.
+type record struct {
+	source     string
+	credential []byte
+}
+
+func run(ctx context.Context, params *parameters, stdin io.Reader, stdout io.Writer) error {
+	done, err := loadCheckpoint(params.checkpoint)
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := os.OpenFile(params.checkpoint, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open checkpoint: %w", err)
+	}
+
+	defer func() {
+		if err = checkpoint.Close(); err != nil {
+			logger.Errorf("checkpoint close: %v", err)
+		}
+	}()
+
+	client := vct.New(params.vctURL,
+		vct.WithAuthReadToken(params.authReadToken),
+		vct.WithAuthWriteToken(params.authWriteToken),
+	)
+
+	b := &backfill{
+		client:     client,
+		interval:   time.Second / time.Duration(params.rate),
+		maxRetries: params.maxRetries,
+		done:       done,
+		checkpoint: json.NewEncoder(checkpoint),
+		report:     &Report{Failed: []Failure{}, Missing: []string{}, Unreconciled: []Failure{}},
+	}
+
+	if err = b.submit(ctx, params.source, stdin); err != nil {
+		return err
+	}
+
+	b.reconcile(ctx)
+
+	return writeReport(params.report, stdout, b.report)
+}
+
+type backfill struct {
+	client     *vct.Client
+	interval   time.Duration
+	maxRetries uint64
+	done       map[string]checkpointEntry
+	checkpoint *json.Encoder
+	report     *Report
+	// added keeps credentials added by this and the previous runs in the order they were added.
+	added []checkpointEntry
+}
+
+// submit adds credentials of the source which were not added before, one per interval.
+func (b *backfill) submit(ctx context.Context, source string, stdin io.Reader) error {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	return readSource(source, stdin, func(rec *record) error {
+		if entry, ok := b.done[rec.source]; ok && entry.Error == "" {
+			b.report.Skipped++
+			b.added = append(b.added, entry)
+
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("backfill interrupted: %w", ctx.Err())
+		case <-ticker.C:
+		}
+
+		entry := b.add(ctx, rec)
+
+		if err := b.checkpoint.Encode(entry); err != nil {
+			return fmt.Errorf("write checkpoint: %w", err)
+		}
+
+		if entry.Error != "" {
+			b.report.Failed = append(b.report.Failed, Failure{Source: entry.Source, Error: entry.Error})
+
+			return nil
+		}
+
+		b.report.Submitted++
+		b.added = append(b.added, entry)
+
+		return nil
+	})
+}
+
+// add adds the credential to the log retrying transient errors with an exponential backoff.
+func (b *backfill) add(ctx context.Context, rec *record) checkpointEntry {
+	entry := checkpointEntry{Source: rec.source}
+	entry.Issuer, entry.CredentialID = credentialID(rec.credential)
+
+	err := backoff.RetryNotify(func() error {
+		resp, err := b.client.AddVC(ctx, rec.credential)
+		if err != nil {
+			if !isTransient(err) {
+				return backoff.Permanent(err)
+			}
+
+			return err // nolint: wrapcheck
+		}
+
+		entry.Timestamp = resp.Timestamp
+
+		return nil
+	}, backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), b.maxRetries), ctx),
+		func(err error, duration time.Duration) {
+			logger.Warnf("add %s failed, will sleep for %v before trying again: %v", rec.source, duration, err)
+		})
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	return entry
+}
+
+// reconcile looks up the added credentials in the log by their issuer and ID.
+func (b *backfill) reconcile(ctx context.Context) {
+	for _, entry := range b.added {
+		if entry.Issuer == "" || entry.CredentialID == "" {
+			b.report.Unverifiable++
+
+			continue
+		}
+
+		_, err := b.client.GetCredentialStatus(ctx, entry.Issuer, entry.CredentialID)
+
+		var vctErr *vct.Error
+
+		switch {
+		case err == nil:
+			b.report.Confirmed++
+		case errors.As(err, &vctErr) && vctErr.Status == http.StatusNotFound:
+			b.report.Missing = append(b.report.Missing, entry.Source)
+		default:
+			b.report.Unreconciled = append(b.report.Unreconciled, Failure{Source: entry.Source, Error: err.Error()})
+		}
+	}
+}
+
+// isTransient returns true if the submission may succeed if retried.
+func isTransient(err error) bool {
+	var vctErr *vct.Error
+	if !errors.As(err, &vctErr) {
+		return true
+	}
+
+	return vctErr.Status == http.StatusTooManyRequests || vctErr.Status >= http.StatusInternalServerError
+}
+
+// credentialID returns the issuer and the ID of a JSON credential, JWT credentials are not decoded.
+func credentialID(credential []byte) (string, string) {
+	var vc struct {
+		ID     string          `json:"id"`
+		Issuer json.RawMessage `json:"issuer"`
+	}
+
+	if err := json.Unmarshal(credential, &vc); err != nil {
+		return "", ""
+	}
+
+	var issuer struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(vc.Issuer, &issuer.ID); err != nil {
+		_ = json.Unmarshal(vc.Issuer, &issuer) // nolint: errcheck
+	}
+
+	return issuer.ID, vc.ID
+}
+
+// loadCheckpoint returns the latest recorded outcome per source, a truncated last line is ignored.
+func loadCheckpoint(path string) (map[string]checkpointEntry, error) {
+	done := map[string]checkpointEntry{}
+
+	file, err := os.Open(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint: %w", err)
+	}
+
+	defer file.Close() // nolint: errcheck
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var entry checkpointEntry
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.Warnf("skip checkpoint line: %v", err)
+
+			continue
+		}
+
+		done[entry.Source] = entry
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	return done, nil
+}
+
+// readSource calls fn for every credential of the source. Credentials of a directory are identified
+// by their relative file path and read in lexical order, credentials of a stream by the line number.
+func readSource(source string, stdin io.Reader, fn func(*record) error) error {
+	if source == stdinSource {
+		return readLines("stdin", stdin, fn)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("stat source: %w", err)
+	}
+
+	if !info.IsDir() {
+		file, err := os.Open(filepath.Clean(source))
+		if err != nil {
+			return fmt.Errorf("open source: %w", err)
+		}
+
+		defer file.Close() // nolint: errcheck
+
+		return readLines(source, file, fn)
+	}
+
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error { // nolint: wrapcheck
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		credential, err := ioutil.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return fmt.Errorf("relative path: %w", err)
+		}
+
+		return fn(&record{source: filepath.ToSlash(rel), credential: bytes.TrimSpace(credential)})
+	})
+}
+
+func readLines(name string, r io.Reader, fn func(*record) error) error {
+	const maxCredentialSize = 10 * 1024 * 1024
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxCredentialSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		credential := bytes.TrimSpace(scanner.Bytes())
+		if len(credential) == 0 {
+			continue
+		}
+
+		rec := &record{
+			source:     name + ":" + strconv.Itoa(line),
+			credential: append([]byte(nil), credential...),
+		}
+
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func writeReport(path string, stdout io.Writer, report *Report) error {
+	out := stdout
+
+	if path != "" {
+		file, err := os.Create(filepath.Clean(path))
+		if err != nil {
+			return fmt.Errorf("create report: %w", err)
+		}
+
+		defer file.Close() // nolint: errcheck
+
+		out = file
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
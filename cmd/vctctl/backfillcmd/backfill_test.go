@@ -0,0 +1,226 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backfillcmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/cmd/vctctl/backfillcmd"
+)
+
+const issuer = "did:example:issuer"
+
+type server struct {
+	mu        sync.Mutex
+	added     []string
+	failOnce  map[string]bool
+	integrate map[string]bool
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/maple2021/v1/add-vc":
+		if r.Header.Get("Authorization") != "Bearer write" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body) // nolint: errcheck
+
+		var vc struct {
+			ID string `json:"id"`
+		}
+
+		if json.Unmarshal(body, &vc) != nil && !strings.HasPrefix(string(body), "ey") {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if s.failOnce[vc.ID] {
+			delete(s.failOnce, vc.ID)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		s.added = append(s.added, vc.ID)
+
+		_, _ = w.Write([]byte(`{"timestamp":1}`))
+	case "/maple2021/v1/get-credential-status":
+		if r.URL.Query().Get("issuer") != issuer || !s.integrate[r.URL.Query().Get("credential_id")] {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func execute(t *testing.T, stdin string, args ...string) (*backfillcmd.Report, error) {
+	t.Helper()
+
+	cmd := backfillcmd.Cmd()
+	cmd.SetArgs(args)
+	cmd.SetIn(strings.NewReader(stdin))
+
+	var out bytes.Buffer
+
+	cmd.SetOut(&out)
+
+	if err := cmd.Execute(); err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	var report *backfillcmd.Report
+	if out.Len() > 0 {
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	}
+
+	return report, nil
+}
+
+func TestBackfill(t *testing.T) {
+	t.Run("Directory", func(t *testing.T) {
+		srv := &server{failOnce: map[string]bool{"urn:b": true}, integrate: map[string]bool{"urn:a": true}}
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		dir := t.TempDir()
+		source := filepath.Join(dir, "credentials")
+
+		require.NoError(t, os.MkdirAll(filepath.Join(source, "nested"), 0o700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(source, "a.json"),
+			[]byte(`{"id":"urn:a","issuer":"`+issuer+`"}`), 0o600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(source, "nested", "b.json"),
+			[]byte(`{"id":"urn:b","issuer":{"id":"`+issuer+`"}}`), 0o600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(source, "c.json"), []byte(`invalid`), 0o600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(source, "d.jwt"), []byte("eyJhbGciOi.e30.\n"), 0o600))
+
+		args := []string{
+			"--vct-url", ts.URL + "/maple2021",
+			"--source", source,
+			"--rate", "1000",
+			"--checkpoint", filepath.Join(dir, "checkpoint"),
+			"--auth-write-token", "write",
+		}
+
+		report, err := execute(t, "", args...)
+		require.NoError(t, err)
+		require.Equal(t, 3, report.Submitted)
+		require.Equal(t, 0, report.Skipped)
+		require.Len(t, report.Failed, 1)
+		require.Equal(t, "c.json", report.Failed[0].Source)
+		require.Equal(t, 1, report.Confirmed)
+		require.Equal(t, []string{"nested/b.json"}, report.Missing)
+		require.Equal(t, 1, report.Unverifiable)
+		require.Equal(t, []string{"urn:a", "", "urn:b"}, srv.added)
+
+		// resumes from the checkpoint, the failed credential is retried
+		srv.integrate["urn:b"] = true
+
+		reportFile := filepath.Join(dir, "report.json")
+
+		report, err = execute(t, "", append(args, "--report", reportFile)...)
+		require.NoError(t, err)
+		require.Nil(t, report)
+
+		f, err := os.Open(filepath.Clean(reportFile))
+		require.NoError(t, err)
+
+		defer f.Close() // nolint: errcheck
+
+		require.NoError(t, json.NewDecoder(f).Decode(&report))
+		require.Equal(t, 0, report.Submitted)
+		require.Equal(t, 3, report.Skipped)
+		require.Len(t, report.Failed, 1)
+		require.Equal(t, 2, report.Confirmed)
+		require.Empty(t, report.Missing)
+		require.Len(t, srv.added, 3)
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		srv := &server{integrate: map[string]bool{"urn:a": true, "urn:b": true}}
+
+		ts := httptest.NewServer(srv)
+		defer ts.Close()
+
+		checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+
+		// a truncated last line of the checkpoint is ignored
+		require.NoError(t, ioutil.WriteFile(checkpoint, []byte(`{"source":"stdin:1","issuer":"`+issuer+
+			`","credential_id":"urn:a"}`+"\n"+`{"source":"stdin:3"`), 0o600))
+
+		report, err := execute(t, `{"id":"urn:a","issuer":"`+issuer+`"}`+"\n\n"+`{"id":"urn:b","issuer":"`+issuer+`"}`+"\n",
+			"--vct-url", ts.URL+"/maple2021",
+			"--source", "-",
+			"--rate", "1000",
+			"--checkpoint", checkpoint,
+			"--auth-write-token", "write",
+		)
+		require.NoError(t, err)
+		require.Equal(t, 1, report.Submitted)
+		require.Equal(t, 1, report.Skipped)
+		require.Equal(t, 2, report.Confirmed)
+		require.Equal(t, []string{"urn:b"}, srv.added)
+	})
+
+	t.Run("Permanent error", func(t *testing.T) {
+		ts := httptest.NewServer(&server{})
+		defer ts.Close()
+
+		report, err := execute(t, "{\"id\":\"urn:a\"}",
+			"--vct-url", ts.URL+"/maple2021",
+			"--source", "-",
+			"--checkpoint", filepath.Join(t.TempDir(), "checkpoint"),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 0, report.Submitted)
+		require.Len(t, report.Failed, 1)
+		require.Equal(t, "stdin:1", report.Failed[0].Source)
+		require.Contains(t, report.Failed[0].Error, "Unauthorized")
+	})
+
+	t.Run("No VCT URL", func(t *testing.T) {
+		_, err := execute(t, "", "--source", "-")
+		require.EqualError(t, err,
+			"Neither vct-url (command line flag) nor VCTCTL_VCT_URL (environment variable) have been set.")
+	})
+
+	t.Run("Invalid rate", func(t *testing.T) {
+		_, err := execute(t, "", "--vct-url", "http://localhost", "--source", "-", "--rate", "abc")
+		require.Contains(t, err.Error(), "rate is not a number(positive)")
+
+		_, err = execute(t, "", "--vct-url", "http://localhost", "--source", "-", "--rate", "0")
+		require.EqualError(t, err, "rate must be positive")
+	})
+
+	t.Run("No source", func(t *testing.T) {
+		_, err := execute(t, "", "--vct-url", "http://localhost", "--source", filepath.Join(t.TempDir(), "none"),
+			"--checkpoint", filepath.Join(t.TempDir(), "checkpoint"))
+		require.Contains(t, err.Error(), "stat source")
+	})
+}
@@ -0,0 +1,621 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyscmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/webkms"
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/cmd/vct/startcmd"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/signer"
+)
+
+const (
+	envPrefix = "VCTCTL_"
+
+	kmsTypeFlagName  = "kms-type"
+	kmsTypeEnvKey    = envPrefix + "KMS_TYPE"
+	kmsTypeFlagUsage = "KMS type of the service (local,web,aws,signer). The keys of the aws and signer types are" +
+		" provisioned in the KMS or in the HSM behind the signer, they are only exported and rotated to." +
+		" Alternatively, this can be set with the following environment variable: " + kmsTypeEnvKey
+
+	kmsEndpointFlagName  = "kms-endpoint"
+	kmsEndpointEnvKey    = envPrefix + "KMS_ENDPOINT"
+	kmsEndpointFlagUsage = "KMS URL of the service, the replicas of the signer for the signer type (comma-separated)." +
+		" Alternatively, this can be set with the following environment variable: " + kmsEndpointEnvKey
+
+	signerAuthTokenFlagName  = "signer-auth-token"
+	signerAuthTokenEnvKey    = envPrefix + "SIGNER_AUTH_TOKEN"
+	signerAuthTokenFlagUsage = "Bearer token of the requests to the remote signer (kms type signer)." +
+		" Alternatively, this can be set with the following environment variable: " + signerAuthTokenEnvKey
+
+	datasourceNameFlagName  = "dsn"
+	datasourceNameEnvKey    = envPrefix + "DSN"
+	datasourceNameFlagUsage = "Datasource Name of the stores of the service, e.g. 'mongodb://mongodb.example.com:27017'." +
+		" Required by the local type, the active key of the log is read from and recorded to the stores." +
+		" Alternatively, this can be set with the following environment variable: " + datasourceNameEnvKey
+
+	databasePrefixFlagName  = "database-prefix"
+	databasePrefixEnvKey    = envPrefix + "DATABASE_PREFIX"
+	databasePrefixFlagUsage = "Prefix of the databases of the service." +
+		" Alternatively, this can be set with the following environment variable: " + databasePrefixEnvKey
+
+	keyIDFlagName  = "key-id"
+	keyIDEnvKey    = envPrefix + "KEY_ID"
+	keyIDFlagUsage = "ID of the key (the URI of the key for the aws type). Defaults to the active key of the log" +
+		" recorded in the stores if not set. Alternatively, this can be set with the following environment variable: " +
+		keyIDEnvKey
+
+	newKeyIDFlagName  = "new-key-id"
+	newKeyIDEnvKey    = envPrefix + "NEW_KEY_ID"
+	newKeyIDFlagUsage = "ID of the key provisioned to rotate to. A key is generated if not set, it must be set for" +
+		" the aws and signer types. Alternatively, this can be set with the following environment variable: " +
+		newKeyIDEnvKey
+
+	keyFileFlagName  = "key-file"
+	keyFileEnvKey    = envPrefix + "KEY_FILE"
+	keyFileFlagUsage = "PEM file with the ECDSA P-256 private key to import (SEC 1 or PKCS #8) or '-' to read it" +
+		" from the standard input. Alternatively, this can be set with the following environment variable: " +
+		keyFileEnvKey
+
+	activateFlagName  = "activate"
+	activateEnvKey    = envPrefix + "ACTIVATE"
+	activateFlagUsage = "Record the new key as the active key of the log in the stores, the service signs with it" +
+		" once restarted. Possible values [true] [false]. Defaults to false if not set." +
+		" Alternatively, this can be set with the following environment variable: " + activateEnvKey
+
+	didFlagName  = "did"
+	didEnvKey    = envPrefix + "DID"
+	didFlagUsage = "DID of the log, the update of its DID document is included in the output of the rotation." +
+		" Alternatively, this can be set with the following environment variable: " + didEnvKey
+
+	kmsLocal  = "local"
+	kmsWeb    = "web"
+	kmsAWS    = "aws"
+	kmsSigner = "signer"
+
+	stdinKeyFile = "-"
+
+	// verificationMethodType is the type of the verification methods of the keys in the DID document of the log.
+	verificationMethodType = "JsonWebKey2020"
+)
+
+// Key is a key of the log.
+type Key struct {
+	KeyID     string      `json:"key_id"`
+	KeyType   kms.KeyType `json:"key_type"`
+	PublicKey []byte      `json:"public_key"`
+	LogID     []byte      `json:"log_id"`
+}
+
+// Rotation is the output of the rotation of the key of the log: the previous and the new key and the updates of
+// the metadata of the log.
+type Rotation struct {
+	Previous  *Key `json:"previous"`
+	Key       *Key `json:"key"`
+	Activated bool `json:"activated"`
+	// WebFinger holds the properties of the webfinger metadata of the log with the new key.
+	WebFinger map[string]interface{} `json:"webfinger"`
+	// DIDDocument is the update of the DID document of the log if the DID is set.
+	DIDDocument *DIDDocumentUpdate `json:"did_document,omitempty"`
+}
+
+// DIDDocumentUpdate is the update of the DID document of the log after a rotation: the verification method of the
+// new key is added and replaces the previous key as the assertion method. The verification method of the previous
+// key is kept, the SCTs and the retired shards of the previous key are still verified with it.
+type DIDDocumentUpdate struct {
+	ID                    string              `json:"id"`
+	AddVerificationMethod *VerificationMethod `json:"add_verification_method"`
+	AssertionMethod       []string            `json:"assertion_method"`
+}
+
+// VerificationMethod is a verification method of a DID document.
+type VerificationMethod struct {
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	Controller   string   `json:"controller"`
+	PublicKeyJwk *jwk.JWK `json:"publicKeyJwk"`
+}
+
+// Cmd returns the Cobra keys command.
+func Cmd() *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manages the keys of a log",
+		Long: "Generates, imports, exports and rotates the key the log signs with in the KMS of the service (local," +
+			" web, aws or the HSM behind a remote signer), and outputs the metadata of the log to update after a" +
+			" rotation",
+	}
+
+	keysCmd.AddCommand(generateCmd(), importCmd(), exportPublicCmd(), rotateCmd())
+
+	return keysCmd
+}
+
+func generateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generates an ECDSA P-256 key in the KMS",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := openBackend(cmd, "")
+			if err != nil {
+				return err
+			}
+
+			defer b.close()
+
+			if !b.managed() {
+				return fmt.Errorf("kms type %s: the keys are provisioned out of band", b.kmsType)
+			}
+
+			keyID, _, err := b.km.Create(kms.ECDSAP256TypeIEEEP1363)
+			if err != nil {
+				return fmt.Errorf("generate key: %w", err)
+			}
+
+			key, err := b.exportKey(keyID)
+			if err != nil {
+				return err
+			}
+
+			return write(cmd.OutOrStdout(), key)
+		},
+	}
+
+	addBackendFlags(cmd)
+
+	return cmd
+}
+
+func importCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Imports an ECDSA P-256 private key to the KMS",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			privKey, err := readPrivateKey(cmd)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(cmd, "")
+			if err != nil {
+				return err
+			}
+
+			defer b.close()
+
+			if !b.managed() {
+				return fmt.Errorf("kms type %s: the keys are provisioned out of band", b.kmsType)
+			}
+
+			var opts []kms.PrivateKeyOpts
+
+			if keyID := cmdutils.GetUserSetOptionalVarFromString(cmd, keyIDFlagName, keyIDEnvKey); keyID != "" {
+				opts = append(opts, kms.WithKeyID(keyID))
+			}
+
+			keyID, _, err := b.km.ImportPrivateKey(privKey, kms.ECDSAP256TypeIEEEP1363, opts...)
+			if err != nil {
+				return fmt.Errorf("import key: %w", err)
+			}
+
+			key, err := b.exportKey(keyID)
+			if err != nil {
+				return err
+			}
+
+			return write(cmd.OutOrStdout(), key)
+		},
+	}
+
+	addBackendFlags(cmd)
+	cmd.Flags().String(keyIDFlagName, "", "ID of the imported key, generated if not set."+
+		" Alternatively, this can be set with the following environment variable: "+keyIDEnvKey)
+	cmd.Flags().String(keyFileFlagName, "", keyFileFlagUsage)
+
+	return cmd
+}
+
+func exportPublicCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-public",
+		Short: "Exports the public key and the log ID of a key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keyID := cmdutils.GetUserSetOptionalVarFromString(cmd, keyIDFlagName, keyIDEnvKey)
+
+			b, err := openBackend(cmd, keyID)
+			if err != nil {
+				return err
+			}
+
+			defer b.close()
+
+			if keyID, err = b.keyID(keyID); err != nil {
+				return err
+			}
+
+			key, err := b.exportKey(keyID)
+			if err != nil {
+				return err
+			}
+
+			return write(cmd.OutOrStdout(), key)
+		},
+	}
+
+	addBackendFlags(cmd)
+	cmd.Flags().String(keyIDFlagName, "", keyIDFlagUsage)
+
+	return cmd
+}
+
+func rotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotates the key of the log to a new key",
+		Long: "Rotates the key of the log to a generated or a provisioned key and outputs the webfinger metadata and" +
+			" the DID document update of the log with the new key. The logs of the previous key should be frozen" +
+			" before the service signs with the new key, the verifiers check the SCTs of the previous key" +
+			" against the retired shards",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keyID := cmdutils.GetUserSetOptionalVarFromString(cmd, keyIDFlagName, keyIDEnvKey)
+
+			activate, err := getBool(cmd, activateFlagName, activateEnvKey)
+			if err != nil {
+				return err
+			}
+
+			b, err := openBackend(cmd, keyID)
+			if err != nil {
+				return err
+			}
+
+			defer b.close()
+
+			if activate && b.store == nil {
+				return fmt.Errorf("%s requires %s", activateFlagName, datasourceNameFlagName)
+			}
+
+			if keyID, err = b.keyID(keyID); err != nil {
+				return err
+			}
+
+			previous, err := b.exportKey(keyID)
+			if err != nil {
+				return err
+			}
+
+			key, err := b.newKey(cmdutils.GetUserSetOptionalVarFromString(cmd, newKeyIDFlagName, newKeyIDEnvKey))
+			if err != nil {
+				return err
+			}
+
+			if key.KeyID == previous.KeyID {
+				return errors.New("the new key is the key of the log")
+			}
+
+			if activate {
+				if err = b.store.Activate(key.KeyID); err != nil {
+					return fmt.Errorf("activate key: %w", err)
+				}
+			}
+
+			rotation := &Rotation{
+				Previous:  previous,
+				Key:       key,
+				Activated: activate,
+				WebFinger: map[string]interface{}{command.PublicKeyType: key.PublicKey, command.LogIDType: key.LogID},
+			}
+
+			if did := cmdutils.GetUserSetOptionalVarFromString(cmd, didFlagName, didEnvKey); did != "" {
+				if rotation.DIDDocument, err = didDocumentUpdate(did, key); err != nil {
+					return err
+				}
+			}
+
+			return write(cmd.OutOrStdout(), rotation)
+		},
+	}
+
+	addBackendFlags(cmd)
+	cmd.Flags().String(keyIDFlagName, "", keyIDFlagUsage)
+	cmd.Flags().String(newKeyIDFlagName, "", newKeyIDFlagUsage)
+	cmd.Flags().String(activateFlagName, "", activateFlagUsage)
+	cmd.Flags().String(didFlagName, "", didFlagUsage)
+
+	return cmd
+}
+
+func addBackendFlags(cmd *cobra.Command) {
+	cmd.Flags().String(kmsTypeFlagName, "", kmsTypeFlagUsage)
+	cmd.Flags().String(kmsEndpointFlagName, "", kmsEndpointFlagUsage)
+	cmd.Flags().String(signerAuthTokenFlagName, "", signerAuthTokenFlagUsage)
+	cmd.Flags().String(datasourceNameFlagName, "", datasourceNameFlagUsage)
+	cmd.Flags().String(databasePrefixFlagName, "", databasePrefixFlagUsage)
+}
+
+type keyManager interface {
+	Create(kt kms.KeyType) (string, interface{}, error)
+	ImportPrivateKey(privKey interface{}, kt kms.KeyType, opts ...kms.PrivateKeyOpts) (string, interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error)
+}
+
+// backend is the KMS of the service along with its stores, if the datasource is set.
+type backend struct {
+	kmsType string
+	km      keyManager
+	store   *startcmd.KeyStore
+}
+
+// openBackend opens the KMS of the kms type, the key URI is the URI of the key for the aws type.
+func openBackend(cmd *cobra.Command, keyURI string) (*backend, error) {
+	kmsType, err := cmdutils.GetUserSetVarFromString(cmd, kmsTypeFlagName, kmsTypeEnvKey, false)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	kmsEndpoint := cmdutils.GetUserSetOptionalVarFromString(cmd, kmsEndpointFlagName, kmsEndpointEnvKey)
+	b := &backend{kmsType: kmsType}
+
+	if dsn := cmdutils.GetUserSetOptionalVarFromString(cmd, datasourceNameFlagName, datasourceNameEnvKey); dsn != "" {
+		b.store, err = startcmd.OpenKeyStore(dsn,
+			cmdutils.GetUserSetOptionalVarFromString(cmd, databasePrefixFlagName, databasePrefixEnvKey), 0)
+		if err != nil {
+			return nil, err // nolint: wrapcheck
+		}
+	}
+
+	if err = b.openKMS(cmd, kmsEndpoint, keyURI); err != nil {
+		b.close()
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *backend) openKMS(cmd *cobra.Command, kmsEndpoint, keyURI string) error {
+	var err error
+
+	switch b.kmsType {
+	case kmsLocal:
+		if b.store == nil {
+			return fmt.Errorf("kms type %s requires %s", kmsLocal, datasourceNameFlagName)
+		}
+
+		b.km, err = b.store.LocalKMS()
+	case kmsWeb:
+		keystoreURL := kmsEndpoint
+
+		if !strings.Contains(kmsEndpoint, "keystores") {
+			if b.store == nil {
+				return fmt.Errorf("kms type %s requires the URL of a keystore in %s or %s", kmsWeb,
+					kmsEndpointFlagName, datasourceNameFlagName)
+			}
+
+			if keystoreURL, err = b.store.WebKeyStore(kmsEndpoint); err != nil {
+				return fmt.Errorf("get keystore: %w", err)
+			}
+
+			if keystoreURL == "" {
+				return fmt.Errorf("no keystore of the service at %s", kmsEndpoint)
+			}
+		}
+
+		b.km = webkms.New(keystoreURL, &http.Client{Timeout: time.Minute})
+	case kmsAWS:
+		if keyURI == "" {
+			return fmt.Errorf("kms type %s requires %s", kmsAWS, keyIDFlagName)
+		}
+
+		b.km, err = startcmd.NewAWSKMS(kmsEndpoint, keyURI, monitoring.InertMetricFactory{})
+	case kmsSigner:
+		b.km, err = newSigner(kmsEndpoint,
+			cmdutils.GetUserSetOptionalVarFromString(cmd, signerAuthTokenFlagName, signerAuthTokenEnvKey))
+	default:
+		return fmt.Errorf("unsupported kms type: %s", b.kmsType)
+	}
+
+	return err // nolint: wrapcheck
+}
+
+// managed reports whether the keys are generated and imported by the KMS, the keys of the aws and signer types are
+// provisioned out of band.
+func (b *backend) managed() bool {
+	return b.kmsType == kmsLocal || b.kmsType == kmsWeb
+}
+
+// keyID returns the key ID, or the active key of the log recorded in the stores if the key ID is not set.
+func (b *backend) keyID(keyID string) (string, error) {
+	if keyID != "" {
+		return keyID, nil
+	}
+
+	if b.store == nil {
+		return "", fmt.Errorf("%s or %s is required", keyIDFlagName, datasourceNameFlagName)
+	}
+
+	keyID, err := b.store.ActiveKeyID()
+	if err != nil {
+		return "", fmt.Errorf("get active key: %w", err)
+	}
+
+	if keyID == "" {
+		return "", errors.New("the service has no active key")
+	}
+
+	return keyID, nil
+}
+
+// newKey returns the provisioned key, or a key generated in the KMS if the key ID is not set.
+func (b *backend) newKey(keyID string) (*Key, error) {
+	if keyID == "" {
+		if !b.managed() {
+			return nil, fmt.Errorf("kms type %s requires %s", b.kmsType, newKeyIDFlagName)
+		}
+
+		var err error
+
+		if keyID, _, err = b.km.Create(kms.ECDSAP256TypeIEEEP1363); err != nil {
+			return nil, fmt.Errorf("generate key: %w", err)
+		}
+	}
+
+	return b.exportKey(keyID)
+}
+
+func (b *backend) exportKey(keyID string) (*Key, error) {
+	pubKey, kt, err := b.km.ExportPubKeyBytes(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("export public key of %s: %w", keyID, err)
+	}
+
+	logID := command.LogID(pubKey)
+
+	return &Key{KeyID: keyID, KeyType: kt, PublicKey: pubKey, LogID: logID[:]}, nil
+}
+
+func (b *backend) close() {
+	if b.store != nil {
+		_ = b.store.Close() // nolint: errcheck
+	}
+}
+
+// signerKMS is the client of the remote signer, keys are provisioned in the HSM behind the signer.
+type signerKMS struct {
+	*signer.Client
+}
+
+func newSigner(endpoints, token string) (*signerKMS, error) {
+	if endpoints == "" {
+		return nil, fmt.Errorf("kms type %s requires %s", kmsSigner, kmsEndpointFlagName)
+	}
+
+	client, err := signer.New(strings.Split(endpoints, ","), signer.WithAuthToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("create signer client: %w", err)
+	}
+
+	return &signerKMS{Client: client}, nil
+}
+
+// ImportPrivateKey is not supported, keys are managed by the signer.
+func (s *signerKMS) ImportPrivateKey(interface{}, kms.KeyType, ...kms.PrivateKeyOpts) (string, interface{}, error) {
+	return "", nil, signer.ErrKeyCreation
+}
+
+// didDocumentUpdate returns the update of the DID document of the log with the new key.
+func didDocumentUpdate(did string, key *Key) (*DIDDocumentUpdate, error) {
+	publicKeyJwk, err := jwksupport.PubKeyBytesToJWK(key.PublicKey, key.KeyType)
+	if err != nil {
+		return nil, fmt.Errorf("public key JWK: %w", err)
+	}
+
+	// the fragment is derived from the log ID, the key IDs of some KMS are URIs
+	id := did + "#" + base64.RawURLEncoding.EncodeToString(key.LogID)
+
+	return &DIDDocumentUpdate{
+		ID: did,
+		AddVerificationMethod: &VerificationMethod{
+			ID:           id,
+			Type:         verificationMethodType,
+			Controller:   did,
+			PublicKeyJwk: publicKeyJwk,
+		},
+		AssertionMethod: []string{id},
+	}, nil
+}
+
+// readPrivateKey reads the ECDSA P-256 private key of the key file.
+func readPrivateKey(cmd *cobra.Command) (*ecdsa.PrivateKey, error) {
+	keyFile, err := cmdutils.GetUserSetVarFromString(cmd, keyFileFlagName, keyFileEnvKey, false)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	var src []byte
+
+	if keyFile == stdinKeyFile {
+		src, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		src, err = os.ReadFile(filepath.Clean(keyFile))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(src)
+	if block == nil {
+		return nil, errors.New("key file is not PEM encoded")
+	}
+
+	var key interface{}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, errors.New("private key is not an ECDSA P-256 key")
+	}
+
+	return ecKey, nil
+}
+
+func getBool(cmd *cobra.Command, flagName, envKey string) (bool, error) {
+	str := cmdutils.GetUserSetOptionalVarFromString(cmd, flagName, envKey)
+	if str == "" {
+		return false, nil
+	}
+
+	v, err := strconv.ParseBool(str)
+	if err != nil {
+		return false, fmt.Errorf("%s is not a bool: %s", flagName, str)
+	}
+
+	return v, nil
+}
+
+func write(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v) // nolint: wrapcheck
+}
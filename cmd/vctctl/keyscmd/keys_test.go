@@ -0,0 +1,329 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyscmd_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/cmd/vct/startcmd"
+	"github.com/trustbloc/vct/cmd/vctctl/keyscmd"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/signer"
+)
+
+func execute(stdin string, args ...string) (string, error) {
+	cmd := keyscmd.Cmd()
+	cmd.SetArgs(args)
+	cmd.SetIn(strings.NewReader(stdin))
+
+	var out bytes.Buffer
+
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+
+	return out.String(), err // nolint: wrapcheck
+}
+
+func newLocalKMS(t *testing.T) *localkms.LocalKMS {
+	t.Helper()
+
+	store, err := startcmd.OpenKeyStore("mem://test", "", 0)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, store.Close()) })
+
+	km, err := store.LocalKMS()
+	require.NoError(t, err)
+
+	return km
+}
+
+// newWebKMS returns a web KMS serving a keystore of a local KMS.
+func newWebKMS(t *testing.T) string {
+	t.Helper()
+
+	km := newLocalKMS(t)
+
+	var ts *httptest.Server
+
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const keys = "/keystores/maple/keys"
+
+		switch {
+		case r.URL.Path == keys && r.Method == http.MethodPost:
+			keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+			require.NoError(t, err)
+
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"key_url": ts.URL + keys + "/" + keyID}))
+		case r.URL.Path == keys && r.Method == http.MethodPut:
+			var req struct {
+				Key   []byte `json:"key"`
+				KeyID string `json:"key_id"`
+			}
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			privKey, err := x509.ParsePKCS8PrivateKey(req.Key)
+			require.NoError(t, err)
+
+			keyID, _, err := km.ImportPrivateKey(privKey, kms.ECDSAP256TypeIEEEP1363, kms.WithKeyID(req.KeyID))
+			require.NoError(t, err)
+
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"key_url": ts.URL + keys + "/" + keyID}))
+		case strings.HasPrefix(r.URL.Path, keys+"/") && strings.HasSuffix(r.URL.Path, "/export"):
+			pubKey, kt, err := km.ExportPubKeyBytes(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, keys+"/"),
+				"/export"))
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"public_key": pubKey, "key_type": kt}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts.URL + "/keystores/maple"
+}
+
+func toKey(t *testing.T, out string) *keyscmd.Key {
+	t.Helper()
+
+	var key *keyscmd.Key
+
+	require.NoError(t, json.Unmarshal([]byte(out), &key))
+
+	logID := sha256.Sum256(key.PublicKey)
+	require.Equal(t, logID[:], key.LogID)
+
+	return key
+}
+
+func pemKey(t *testing.T, blockType string, key interface{}) string {
+	t.Helper()
+
+	var (
+		src []byte
+		err error
+	)
+
+	if blockType == "EC PRIVATE KEY" {
+		src, err = x509.MarshalECPrivateKey(key.(*ecdsa.PrivateKey))
+	} else {
+		src, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: src}))
+}
+
+func TestGenerateAndImport(t *testing.T) {
+	t.Run("Generate", func(t *testing.T) {
+		out, err := execute("", "generate", "--kms-type", "local", "--dsn", "mem://test")
+		require.NoError(t, err)
+
+		key := toKey(t, out)
+		require.NotEmpty(t, key.KeyID)
+		require.Equal(t, kms.ECDSAP256TypeIEEEP1363, key.KeyType)
+	})
+
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), privKey.X, privKey.Y) // nolint: staticcheck
+
+	t.Run("Import from the standard input", func(t *testing.T) {
+		out, err := execute(pemKey(t, "EC PRIVATE KEY", privKey), "import", "--kms-type", "local",
+			"--dsn", "mem://test", "--key-id", "maple", "--key-file", "-")
+		require.NoError(t, err)
+
+		key := toKey(t, out)
+		require.Equal(t, "maple", key.KeyID)
+		require.Equal(t, pubKey, key.PublicKey)
+	})
+
+	t.Run("Import to the web KMS", func(t *testing.T) {
+		keyFile := filepath.Join(t.TempDir(), "key.pem")
+		require.NoError(t, os.WriteFile(keyFile, []byte(pemKey(t, "PRIVATE KEY", privKey)), 0o600))
+
+		out, err := execute("", "import", "--kms-type", "web", "--kms-endpoint", newWebKMS(t),
+			"--key-file", keyFile)
+		require.NoError(t, err)
+		require.Equal(t, pubKey, toKey(t, out).PublicKey)
+	})
+
+	t.Run("Invalid key file", func(t *testing.T) {
+		_, err := execute("key", "import", "--kms-type", "local", "--dsn", "mem://test", "--key-file", "-")
+		require.EqualError(t, err, "key file is not PEM encoded")
+
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 1024) // nolint: gosec
+		require.NoError(t, err)
+
+		_, err = execute(pemKey(t, "PRIVATE KEY", rsaKey), "import", "--kms-type", "local", "--dsn", "mem://test",
+			"--key-file", "-")
+		require.EqualError(t, err, "private key is not an ECDSA P-256 key")
+
+		_, err = execute("", "import", "--kms-type", "local", "--key-file", filepath.Join(t.TempDir(), "key.pem"))
+		require.Contains(t, err.Error(), "read key file")
+	})
+
+	t.Run("Backend", func(t *testing.T) {
+		_, err := execute("", "generate", "--kms-type", "local")
+		require.EqualError(t, err, "kms type local requires dsn")
+
+		_, err = execute("", "generate", "--kms-type", "web", "--kms-endpoint", "https://kms.example.com")
+		require.EqualError(t, err, "kms type web requires the URL of a keystore in kms-endpoint or dsn")
+
+		_, err = execute("", "generate", "--kms-type", "web", "--kms-endpoint", "https://kms.example.com",
+			"--dsn", "mem://test")
+		require.EqualError(t, err, "no keystore of the service at https://kms.example.com")
+
+		_, err = execute("", "generate", "--kms-type", "aws")
+		require.EqualError(t, err, "kms type aws requires key-id")
+
+		_, err = execute("", "generate", "--kms-type", "signer", "--kms-endpoint", "https://signer.example.com")
+		require.EqualError(t, err, "kms type signer: the keys are provisioned out of band")
+
+		_, err = execute("", "generate", "--kms-type", "hsm")
+		require.EqualError(t, err, "unsupported kms type: hsm")
+	})
+}
+
+func TestExportPublic(t *testing.T) {
+	t.Run("Web KMS", func(t *testing.T) {
+		keystore := newWebKMS(t)
+
+		out, err := execute("", "generate", "--kms-type", "web", "--kms-endpoint", keystore)
+		require.NoError(t, err)
+
+		key := toKey(t, out)
+
+		out, err = execute("", "export-public", "--kms-type", "web", "--kms-endpoint", keystore,
+			"--key-id", key.KeyID)
+		require.NoError(t, err)
+		require.Equal(t, key, toKey(t, out))
+
+		_, err = execute("", "export-public", "--kms-type", "web", "--kms-endpoint", keystore, "--key-id", "unknown")
+		require.Contains(t, err.Error(), "export public key of unknown")
+	})
+
+	t.Run("Active key", func(t *testing.T) {
+		_, err := execute("", "export-public", "--kms-type", "local", "--dsn", "mem://test")
+		require.EqualError(t, err, "the service has no active key")
+
+		_, err = execute("", "export-public", "--kms-type", "web", "--kms-endpoint", newWebKMS(t))
+		require.EqualError(t, err, "key-id or dsn is required")
+	})
+}
+
+func TestRotate(t *testing.T) {
+	t.Run("Generated key", func(t *testing.T) {
+		keystore := newWebKMS(t)
+
+		out, err := execute("", "generate", "--kms-type", "web", "--kms-endpoint", keystore)
+		require.NoError(t, err)
+
+		previous := toKey(t, out)
+
+		out, err = execute("", "rotate", "--kms-type", "web", "--kms-endpoint", keystore, "--key-id",
+			previous.KeyID, "--did", "did:web:vct.example.com")
+		require.NoError(t, err)
+
+		var rotation *keyscmd.Rotation
+
+		require.NoError(t, json.Unmarshal([]byte(out), &rotation))
+		require.Equal(t, previous, rotation.Previous)
+		require.NotEqual(t, previous.KeyID, rotation.Key.KeyID)
+		require.False(t, rotation.Activated)
+		require.Equal(t, map[string]interface{}{
+			command.PublicKeyType: base64.StdEncoding.EncodeToString(rotation.Key.PublicKey),
+			command.LogIDType:     base64.StdEncoding.EncodeToString(rotation.Key.LogID),
+		}, rotation.WebFinger)
+
+		id := "did:web:vct.example.com#" + base64.RawURLEncoding.EncodeToString(rotation.Key.LogID)
+
+		update := rotation.DIDDocument
+		require.Equal(t, "did:web:vct.example.com", update.ID)
+		require.Equal(t, []string{id}, update.AssertionMethod)
+		require.Equal(t, id, update.AddVerificationMethod.ID)
+		require.Equal(t, "JsonWebKey2020", update.AddVerificationMethod.Type)
+		require.Equal(t, "P-256", update.AddVerificationMethod.PublicKeyJwk.Crv)
+
+		_, err = execute("", "rotate", "--kms-type", "web", "--kms-endpoint", keystore, "--key-id", previous.KeyID,
+			"--new-key-id", previous.KeyID)
+		require.EqualError(t, err, "the new key is the key of the log")
+
+		_, err = execute("", "rotate", "--kms-type", "web", "--kms-endpoint", keystore, "--key-id", previous.KeyID,
+			"--activate", "true")
+		require.EqualError(t, err, "activate requires dsn")
+
+		_, err = execute("", "rotate", "--kms-type", "web", "--kms-endpoint", keystore, "--activate", "yes")
+		require.EqualError(t, err, "activate is not a bool: yes")
+	})
+
+	t.Run("Key of the HSM", func(t *testing.T) {
+		km := newLocalKMS(t)
+
+		cr, err := tinkcrypto.New()
+		require.NoError(t, err)
+
+		previous, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		provisioned, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		ts := httptest.NewServer(signer.NewHandler(km, cr, []string{previous, provisioned}, signer.WithToken("token")))
+		defer ts.Close()
+
+		out, err := execute("", "rotate", "--kms-type", "signer", "--kms-endpoint", ts.URL,
+			"--signer-auth-token", "token", "--key-id", previous, "--new-key-id", provisioned)
+		require.NoError(t, err)
+
+		var rotation *keyscmd.Rotation
+
+		require.NoError(t, json.Unmarshal([]byte(out), &rotation))
+		require.Equal(t, previous, rotation.Previous.KeyID)
+		require.Equal(t, provisioned, rotation.Key.KeyID)
+		require.Nil(t, rotation.DIDDocument)
+
+		_, err = execute("", "rotate", "--kms-type", "signer", "--kms-endpoint", ts.URL,
+			"--signer-auth-token", "token", "--key-id", previous)
+		require.EqualError(t, err, "kms type signer requires new-key-id")
+
+		_, err = execute("", "rotate", "--kms-type", "signer", "--kms-endpoint", ts.URL, "--key-id", previous,
+			"--new-key-id", provisioned)
+		require.Contains(t, err.Error(), "export public key of "+previous)
+	})
+}
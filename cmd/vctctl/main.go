@@ -0,0 +1,36 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package main Verifiable Credential Transparency operator tool.
+package main
+
+import (
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/spf13/cobra"
+
+	"github.com/trustbloc/vct/cmd/vctctl/auditcmd"
+	"github.com/trustbloc/vct/cmd/vctctl/backfillcmd"
+	"github.com/trustbloc/vct/cmd/vctctl/keyscmd"
+	"github.com/trustbloc/vct/cmd/vctctl/publishcmd"
+)
+
+var logger = log.New("vctctl")
+
+// This is an application which operates vct logs.
+func main() {
+	rootCmd := &cobra.Command{
+		Use: "vctctl",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+
+	rootCmd.AddCommand(backfillcmd.Cmd(), auditcmd.Cmd(), publishcmd.Cmd(), keyscmd.Cmd())
+
+	if err := rootCmd.Execute(); err != nil {
+		logger.Fatalf("failed to run vctctl: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publishcmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/publisher"
+)
+
+const (
+	envPrefix = "VCTCTL_"
+
+	vctURLFlagName  = "vct-url"
+	vctURLEnvKey    = envPrefix + "VCT_URL"
+	vctURLFlagUsage = "URL of the log to publish (e.g. https://vct.example.com/maple2021)." +
+		" Alternatively, this can be set with the following environment variable: " + vctURLEnvKey
+
+	bucketFlagName  = "bucket"
+	bucketEnvKey    = envPrefix + "PUBLISH_BUCKET"
+	bucketFlagUsage = "Bucket the log is published to: s3://<bucket>/<prefix> or a directory." +
+		" Alternatively, this can be set with the following environment variable: " + bucketEnvKey
+
+	s3EndpointFlagName  = "s3-endpoint"
+	s3EndpointEnvKey    = envPrefix + "PUBLISH_S3_ENDPOINT"
+	s3EndpointFlagUsage = "Endpoint of a storage compatible with the S3 API" +
+		" (e.g. https://storage.googleapis.com for Google Cloud Storage). Defaults to AWS S3 if not set." +
+		" Alternatively, this can be set with the following environment variable: " + s3EndpointEnvKey
+
+	s3RegionFlagName  = "s3-region"
+	s3RegionEnvKey    = envPrefix + "PUBLISH_S3_REGION"
+	s3RegionFlagUsage = "Region of the S3 bucket. Defaults to " + publisher.DefaultS3Region + " if not set." +
+		" Alternatively, this can be set with the following environment variable: " + s3RegionEnvKey
+
+	intervalFlagName  = "interval"
+	intervalEnvKey    = envPrefix + "PUBLISH_INTERVAL"
+	intervalFlagUsage = "Interval the log is published at (e.g. 30s), 0 publishes the log once." +
+		" Defaults to " + defaultInterval + " if not set." +
+		" Alternatively, this can be set with the following environment variable: " + intervalEnvKey
+
+	authReadTokenFlagName  = "auth-read-token"
+	authReadTokenEnvKey    = envPrefix + "AUTH_READ_TOKEN"
+	authReadTokenFlagUsage = "Bearer token used to read the log." +
+		" Alternatively, this can be set with the following environment variable: " + authReadTokenEnvKey
+
+	defaultInterval = "1m"
+)
+
+// Cmd returns the Cobra publish command.
+func Cmd() *cobra.Command {
+	publishCmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Publishes a log as static resources to object storage",
+		Long: "Renders the checkpoint, the tiles and the entry bundles of a log to an S3 (or S3-compatible) bucket" +
+			" on a schedule, so reads can be served from the bucket while the log only handles writes. Only the" +
+			" tiles which grew since the published checkpoint are rendered",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params, err := getParameters(cmd)
+			if err != nil {
+				return err
+			}
+
+			bucket, err := createBucket(params)
+			if err != nil {
+				return err
+			}
+
+			p := publisher.New(vct.New(params.vctURL, vct.WithAuthReadToken(params.authReadToken)), bucket)
+
+			if params.interval == 0 {
+				size, er := p.Publish(cmd.Context())
+				if er != nil {
+					return er // nolint: wrapcheck
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "published tree size %d\n", size)
+
+				return nil
+			}
+
+			p.Run(cmd.Context(), params.interval)
+
+			return nil
+		},
+	}
+
+	publishCmd.Flags().String(vctURLFlagName, "", vctURLFlagUsage)
+	publishCmd.Flags().String(bucketFlagName, "", bucketFlagUsage)
+	publishCmd.Flags().String(s3EndpointFlagName, "", s3EndpointFlagUsage)
+	publishCmd.Flags().String(s3RegionFlagName, "", s3RegionFlagUsage)
+	publishCmd.Flags().String(intervalFlagName, "", intervalFlagUsage)
+	publishCmd.Flags().String(authReadTokenFlagName, "", authReadTokenFlagUsage)
+
+	return publishCmd
+}
+
+type parameters struct {
+	vctURL        string
+	bucket        string
+	s3Endpoint    string
+	s3Region      string
+	interval      time.Duration
+	authReadToken string
+}
+
+func getParameters(cmd *cobra.Command) (*parameters, error) {
+	vctURL, err := cmdutils.GetUserSetVarFromString(cmd, vctURLFlagName, vctURLEnvKey, false)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	bucket, err := cmdutils.GetUserSetVarFromString(cmd, bucketFlagName, bucketEnvKey, false)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	intervalStr := cmdutils.GetUserSetOptionalVarFromString(cmd, intervalFlagName, intervalEnvKey)
+	if intervalStr == "" {
+		intervalStr = defaultInterval
+	}
+
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval < 0 {
+		return nil, fmt.Errorf("%s is not a valid duration: %s", intervalFlagName, intervalStr)
+	}
+
+	return &parameters{
+		vctURL:        vctURL,
+		bucket:        bucket,
+		s3Endpoint:    cmdutils.GetUserSetOptionalVarFromString(cmd, s3EndpointFlagName, s3EndpointEnvKey),
+		s3Region:      cmdutils.GetUserSetOptionalVarFromString(cmd, s3RegionFlagName, s3RegionEnvKey),
+		interval:      interval,
+		authReadToken: cmdutils.GetUserSetOptionalVarFromString(cmd, authReadTokenFlagName, authReadTokenEnvKey),
+	}, nil
+}
+
+// createBucket returns the bucket of the bucket flag.
+func createBucket(params *parameters) (publisher.Bucket, error) {
+	bucket, err := publisher.OpenBucket(params.bucket, params.s3Endpoint, params.s3Region)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", bucketFlagName, err)
+	}
+
+	return bucket, nil
+}
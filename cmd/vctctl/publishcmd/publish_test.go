@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publishcmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/cmd/vctctl/publishcmd"
+)
+
+func execute(args ...string) (string, error) {
+	cmd := publishcmd.Cmd()
+	cmd.SetArgs(args)
+
+	var out bytes.Buffer
+
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+
+	err := cmd.Execute()
+
+	return out.String(), err // nolint: wrapcheck
+}
+
+func TestPublish(t *testing.T) {
+	tile := bytes.Repeat([]byte{1}, 3*32)
+	bundle := []byte{0, 0, 1, 'a', 0, 0, 1, 'b', 0, 0, 1, 'c'}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer read" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		switch r.URL.Path {
+		case "/maple2021/v1/get-sth":
+			_, _ = w.Write([]byte(`{"tree_size":3,"timestamp":1}`))
+		case "/maple2021/tile/0/000.p/3":
+			_, _ = w.Write(tile)
+		case "/maple2021/tile/entries/000.p/3":
+			_, _ = w.Write(bundle)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("Success", func(t *testing.T) {
+		dir := t.TempDir()
+
+		out, err := execute("--vct-url", ts.URL+"/maple2021", "--bucket", dir, "--interval", "0",
+			"--auth-read-token", "read")
+		require.NoError(t, err)
+		require.Equal(t, "published tree size 3\n", out)
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "tile", "0", "000.p", "3"))
+		require.NoError(t, err)
+		require.Equal(t, tile, data)
+
+		data, err = ioutil.ReadFile(filepath.Join(dir, "tile", "entries", "000.p", "3"))
+		require.NoError(t, err)
+		require.Equal(t, bundle, data)
+
+		data, err = ioutil.ReadFile(filepath.Join(dir, "checkpoint"))
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"tree_size":3`)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		_, err := execute("--vct-url", ts.URL+"/maple2021", "--bucket", t.TempDir(), "--interval", "0")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get STH")
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		_, err := execute("--bucket", t.TempDir())
+		require.Contains(t, err.Error(), "vct-url")
+
+		_, err = execute("--vct-url", ts.URL, "--bucket", t.TempDir(), "--interval", "often")
+		require.EqualError(t, err, "interval is not a valid duration: often")
+
+		_, err = execute("--vct-url", ts.URL, "--bucket", "s3:///prefix")
+		require.EqualError(t, err, "bucket: s3:///prefix is not a valid bucket URL")
+	})
+}
@@ -0,0 +1,415 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cbor implements the subset of CBOR (RFC 8949) needed to process COSE and ISO 18013-5 (mdoc) structures.
+//
+// Decoded values are uint64 or int64 (negative integers), []byte, string, []interface{},
+// map[interface{}]interface{}, Tag, bool, nil and float64. Map keys are int64 or string, unsigned integer
+// keys are decoded as int64 so that COSE labels can be looked up uniformly. Indefinite lengths are not supported.
+// Encode produces the deterministic encoding (RFC 8949, section 4.2.1).
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Major types.
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+)
+
+const (
+	simpleFalse = 20
+	simpleTrue  = 21
+	simpleNull  = 22
+	// simpleUndefined is decoded as nil.
+	simpleUndefined = 23
+
+	additionalUint8   = 24
+	additionalUint16  = 25
+	additionalUint32  = 26
+	additionalUint64  = 27
+	additionalIndefin = 31
+
+	// maxDepth is the max nesting of arrays, maps and tags.
+	maxDepth = 32
+)
+
+// TagEncodedCBOR is the tag of a byte string holding an encoded CBOR data item.
+const TagEncodedCBOR = 24
+
+// Tag is a tagged data item.
+type Tag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// Decode decodes exactly one data item.
+func Decode(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%d trailing bytes", len(d.data)-d.pos)
+	}
+
+	return v, nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("unexpected end of data")
+	}
+
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+
+	return b, nil
+}
+
+// head decodes the initial byte and the argument of a data item.
+func (d *decoder) head() (byte, byte, uint64, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	major, additional := b[0]>>5, b[0]&0x1f
+
+	switch {
+	case additional < additionalUint8:
+		return major, additional, uint64(additional), nil
+	case additional <= additionalUint64:
+		arg, er := d.read(1 << (additional - additionalUint8))
+		if er != nil {
+			return 0, 0, 0, er
+		}
+
+		var val uint64
+		for _, c := range arg {
+			val = val<<8 | uint64(c) // nolint: gomnd
+		}
+
+		return major, additional, val, nil
+	case additional == additionalIndefin:
+		return 0, 0, 0, errors.New("indefinite length is not supported")
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid additional information %d", additional)
+	}
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) { // nolint: gocyclo,cyclop
+	if depth > maxDepth {
+		return nil, errors.New("max nesting depth exceeded")
+	}
+
+	major, additional, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUnsigned:
+		return arg, nil
+	case majorNegative:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("negative integer overflows int64")
+		}
+
+		return -1 - int64(arg), nil
+	case majorBytes:
+		b, er := d.read(arg)
+		if er != nil {
+			return nil, er
+		}
+
+		return append([]byte(nil), b...), nil
+	case majorText:
+		b, er := d.read(arg)
+		if er != nil {
+			return nil, er
+		}
+
+		return string(b), nil
+	case majorArray:
+		// every element is at least one byte
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errors.New("unexpected end of data")
+		}
+
+		array := make([]interface{}, 0, arg)
+
+		for i := uint64(0); i < arg; i++ {
+			v, er := d.decode(depth + 1)
+			if er != nil {
+				return nil, er
+			}
+
+			array = append(array, v)
+		}
+
+		return array, nil
+	case majorMap:
+		return d.decodeMap(arg, depth)
+	case majorTag:
+		v, er := d.decode(depth + 1)
+		if er != nil {
+			return nil, er
+		}
+
+		return Tag{Number: arg, Content: v}, nil
+	default:
+		return decodeSimple(additional, arg)
+	}
+}
+
+func (d *decoder) decodeMap(n uint64, depth int) (interface{}, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("unexpected end of data")
+	}
+
+	m := make(map[interface{}]interface{}, n)
+
+	for i := uint64(0); i < n; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch k := key.(type) {
+		case uint64:
+			if k > math.MaxInt64 {
+				return nil, errors.New("map key overflows int64")
+			}
+
+			key = int64(k)
+		case int64, string:
+		default:
+			return nil, fmt.Errorf("map key of type %T is not supported", key)
+		}
+
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("duplicate map key %v", key)
+		}
+
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		m[key] = v
+	}
+
+	return m, nil
+}
+
+func decodeSimple(additional byte, arg uint64) (interface{}, error) {
+	switch additional {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull, simpleUndefined:
+		return nil, nil
+	case additionalUint16:
+		return halfToFloat(uint16(arg)), nil
+	case additionalUint32:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case additionalUint64:
+		return math.Float64frombits(arg), nil
+	default:
+		return nil, fmt.Errorf("simple value %d is not supported", arg)
+	}
+}
+
+func halfToFloat(h uint16) float64 {
+	const (
+		exponentBits = 0x1f
+		mantissaBits = 0x3ff
+		mantissaSize = 10
+		bias         = 15
+	)
+
+	exp := int(h>>mantissaSize) & exponentBits
+	mant := float64(h & mantissaBits)
+
+	var val float64
+
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, 1-bias-mantissaSize)
+	case exponentBits:
+		val = math.Inf(1)
+		if mant != 0 {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+(1<<mantissaSize), exp-bias-mantissaSize)
+	}
+
+	if h>>15 == 1 {
+		val = -val
+	}
+
+	return val
+}
+
+// Encode encodes the value deterministically. Supported are the decoded types, int and uint.
+func Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error { // nolint: gocyclo,cyclop
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if val {
+			buf.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			buf.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case int:
+		encodeInt(buf, int64(val))
+	case int64:
+		encodeInt(buf, val)
+	case uint:
+		writeHead(buf, majorUnsigned, uint64(val))
+	case uint64:
+		writeHead(buf, majorUnsigned, val)
+	case float64:
+		buf.WriteByte(majorSimple<<5 | additionalUint64)
+
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(val))
+		buf.Write(b[:])
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(val)))
+		buf.Write(val)
+	case string:
+		writeHead(buf, majorText, uint64(len(val)))
+		buf.WriteString(val)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(val)))
+
+		for _, e := range val {
+			if err := encode(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		return encodeMap(buf, val)
+	case Tag:
+		writeHead(buf, majorTag, val.Number)
+
+		return encode(buf, val.Content)
+	default:
+		return fmt.Errorf("type %T is not supported", v)
+	}
+
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, v int64) {
+	if v < 0 {
+		writeHead(buf, majorNegative, uint64(-1-v))
+
+		return
+	}
+
+	writeHead(buf, majorUnsigned, uint64(v))
+}
+
+// encodeMap encodes the map with its keys sorted by their encoding.
+func encodeMap(buf *bytes.Buffer, m map[interface{}]interface{}) error {
+	type entry struct {
+		key   []byte
+		value interface{}
+	}
+
+	entries := make([]entry, 0, len(m))
+
+	for k, v := range m {
+		key, err := Encode(k)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry{key: key, value: v})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	writeHead(buf, majorMap, uint64(len(entries)))
+
+	for _, e := range entries {
+		buf.Write(e.key)
+
+		if err := encode(buf, e.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeHead writes the initial byte and the argument in the shortest form.
+func writeHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < additionalUint8:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.WriteByte(major<<5 | additionalUint8)
+		buf.WriteByte(byte(arg))
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major<<5 | additionalUint16)
+
+		var b [2]byte
+		binary.BigEndian.PutUint16(b[:], uint16(arg))
+		buf.Write(b[:])
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major<<5 | additionalUint32)
+
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(arg))
+		buf.Write(b[:])
+	default:
+		buf.WriteByte(major<<5 | additionalUint64)
+
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], arg)
+		buf.Write(b[:])
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbor_test
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/internal/pkg/cbor"
+)
+
+func TestDecode(t *testing.T) {
+	// examples of RFC 8949, appendix A
+	for _, tc := range []struct {
+		encoded string
+		value   interface{}
+	}{
+		{"00", uint64(0)},
+		{"17", uint64(23)},
+		{"1818", uint64(24)},
+		{"1903e8", uint64(1000)},
+		{"1bffffffffffffffff", uint64(math.MaxUint64)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f90001", 5.960464477539063e-8},
+		{"f9c400", -4.0},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"6449455446", "IETF"},
+		{"83010203", []interface{}{uint64(1), uint64(2), uint64(3)}},
+		{"a201020304", map[interface{}]interface{}{int64(1): uint64(2), int64(3): uint64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{
+			"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)},
+		}},
+		{"d818456449455446", Tag{Number: TagEncodedCBOR, Content: []byte("dIETF")}},
+	} {
+		encoded, err := hex.DecodeString(tc.encoded)
+		require.NoError(t, err)
+
+		value, err := Decode(encoded)
+		require.NoError(t, err, tc.encoded)
+		require.Equal(t, tc.value, value, tc.encoded)
+	}
+}
+
+func TestDecode_Error(t *testing.T) {
+	for _, tc := range []struct {
+		encoded string
+		err     string
+	}{
+		{"", "unexpected end of data"},
+		{"0000", "1 trailing bytes"},
+		{"19", "unexpected end of data"},
+		{"1c", "invalid additional information 28"},
+		{"5f", "indefinite length is not supported"},
+		{"3bffffffffffffffff", "negative integer overflows int64"},
+		{"45010203", "unexpected end of data"},
+		{"9bffffffffffffffff", "unexpected end of data"},
+		{"a2010203", "unexpected end of data"},
+		{"a201020103", "duplicate map key 1"},
+		{"a1f401", "map key of type bool is not supported"},
+		{"f0", "simple value 16 is not supported"},
+	} {
+		encoded, err := hex.DecodeString(tc.encoded)
+		require.NoError(t, err)
+
+		_, err = Decode(encoded)
+		require.EqualError(t, err, tc.err, tc.encoded)
+	}
+
+	nested := make([]byte, 40)
+	for i := range nested {
+		nested[i] = 0x81
+	}
+
+	_, err := Decode(nested)
+	require.EqualError(t, err, "max nesting depth exceeded")
+}
+
+func TestEncode(t *testing.T) {
+	for _, tc := range []struct {
+		value   interface{}
+		encoded string
+	}{
+		{0, "00"},
+		{uint(24), "1818"},
+		{int64(-1000), "3903e7"},
+		{uint64(math.MaxUint64), "1bffffffffffffffff"},
+		{65536, "1a00010000"},
+		{1.1, "fb3ff199999999999a"},
+		{true, "f5"},
+		{false, "f4"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"IETF", "6449455446"},
+		{[]interface{}{1, []interface{}{2, 3}}, "8201820203"},
+		// keys are sorted by their encoding
+		{map[interface{}]interface{}{"b": 2, 10: 1, "a": 1, -1: 3}, "a40a012003616101616202"},
+		{Tag{Number: TagEncodedCBOR, Content: []byte("dIETF")}, "d818456449455446"},
+	} {
+		encoded, err := Encode(tc.value)
+		require.NoError(t, err)
+		require.Equal(t, tc.encoded, hex.EncodeToString(encoded), tc.value)
+	}
+
+	_, err := Encode([]interface{}{struct{}{}})
+	require.EqualError(t, err, "type struct {} is not supported")
+
+	_, err = Encode(map[interface{}]interface{}{struct{}{}: 1})
+	require.EqualError(t, err, "type struct {} is not supported")
+}
@@ -0,0 +1,206 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package scrub keeps payloads (credentials, leaf values) out of logs and error messages.
+// Issuer payloads frequently contain personal data, so by default a payload is described only by its
+// size and a short digest, which is enough to correlate it with the log entry. The debug mode, meant
+// for troubleshooting only, describes payloads by their content.
+package scrub
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/trillian"
+)
+
+// digestSize is the number of bytes of the SHA-256 digest a payload is described with.
+const digestSize = 8
+
+// jwtSegments is the number of segments of a JWT (the header, the claims and the signature).
+const jwtSegments = 3
+
+// minScrubbedLength is the length of the shortest string of a payload scrubbed from an error message, the shorter
+// ones would mangle the message.
+const minScrubbedLength = 3
+
+// nolint: gochecknoglobals
+var debug uint32 // 1 if payloads are described by their content, accessed atomically
+
+// SetDebug enables or disables the debug mode.
+func SetDebug(enabled bool) {
+	var val uint32
+	if enabled {
+		val = 1
+	}
+
+	atomic.StoreUint32(&debug, val)
+}
+
+// Debug returns true if the debug mode is enabled.
+func Debug() bool {
+	return atomic.LoadUint32(&debug) == 1
+}
+
+// Payload describes the payload, e.g. [128 bytes, sha256:9f86d081884c7d65].
+func Payload(payload []byte) string {
+	if Debug() {
+		return fmt.Sprintf("%q", payload)
+	}
+
+	if len(payload) == 0 {
+		return "[empty]"
+	}
+
+	digest := sha256.Sum256(payload)
+
+	return fmt.Sprintf("[%d bytes, sha256:%s]", len(payload), hex.EncodeToString(digest[:digestSize]))
+}
+
+// Leaf describes the leaf by its index and hashes with its value and extra data scrubbed.
+func Leaf(leaf *trillian.LogLeaf) string {
+	if leaf == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("leaf_index:%d merkle_leaf_hash:%s leaf_value:%s extra_data:%s",
+		leaf.GetLeafIndex(), hex.EncodeToString(leaf.GetMerkleLeafHash()),
+		Payload(leaf.GetLeafValue()), Payload(leaf.GetExtraData()),
+	)
+}
+
+// Error returns the error with the string values of the payload (a JSON document, a JWT or an SD-JWT) scrubbed from
+// its message, e.g. the errors of the parsers echoing the part of the payload they reject. The error is unwrapped
+// to the original one. The error is returned as is in the debug mode.
+func Error(err error, payload []byte) error {
+	if err == nil || Debug() {
+		return err
+	}
+
+	values := payloadStrings(payload)
+	if len(values) == 0 {
+		return err
+	}
+
+	replacements := make([]string, 0, 2*len(values)) // nolint: gomnd
+
+	for _, value := range values {
+		replacements = append(replacements, value, Payload([]byte(value)))
+	}
+
+	// the message is scanned once, the descriptions of the values are not scrubbed again
+	msg := strings.NewReplacer(replacements...).Replace(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+
+	return &scrubbedError{msg: msg, err: err}
+}
+
+type scrubbedError struct {
+	msg string
+	err error
+}
+
+func (e *scrubbedError) Error() string {
+	return e.msg
+}
+
+func (e *scrubbedError) Unwrap() error {
+	return e.err
+}
+
+// payloadStrings returns the strings of the payload which are scrubbed, the longest first so a string containing
+// another one is scrubbed whole (the replacer tries the strings in order).
+func payloadStrings(payload []byte) []string {
+	values := map[string]struct{}{}
+
+	if !collectStrings(payload, values) {
+		collectSegments(string(payload), values)
+	}
+
+	result := make([]string, 0, len(values))
+
+	for value := range values {
+		if len(value) >= minScrubbedLength {
+			result = append(result, value)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return len(result[i]) > len(result[j]) })
+
+	return result
+}
+
+// collectStrings adds the string values of the JSON document to the values, false if it is not JSON.
+func collectStrings(doc []byte, values map[string]struct{}) bool {
+	var v interface{}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return false
+	}
+
+	var walk func(v interface{})
+
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case string:
+			values[val] = struct{}{}
+			// the strings escaped by the JSON encoder of the message are scrubbed too
+			if quoted, err := json.Marshal(val); err == nil {
+				values[strings.Trim(string(quoted), `"`)] = struct{}{}
+			}
+
+			collectSegments(val, values)
+		case []interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range val {
+				walk(item)
+			}
+		}
+	}
+
+	walk(v)
+
+	return true
+}
+
+// collectSegments adds the encoded segments of the JWT (and the disclosures of an SD-JWT) and the strings they
+// encode to the values, the token is a JWT if its header is a JSON object.
+func collectSegments(token string, values map[string]struct{}) {
+	parts := strings.Split(token, "~")
+
+	segments := strings.Split(parts[0], ".")
+	if len(segments) != jwtSegments {
+		return
+	}
+
+	var header map[string]interface{}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(segments[0])
+	if err != nil || json.Unmarshal(decoded, &header) != nil {
+		return
+	}
+
+	for _, segment := range append(segments, parts[1:]...) {
+		decoded, err = base64.RawURLEncoding.DecodeString(segment)
+		if err != nil || segment == "" {
+			continue
+		}
+
+		values[segment] = struct{}{}
+
+		collectStrings(decoded, values)
+	}
+}
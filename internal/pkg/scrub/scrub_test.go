@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package scrub_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/internal/pkg/scrub"
+)
+
+func TestPayload(t *testing.T) {
+	require.False(t, Debug())
+	require.Equal(t, "[empty]", Payload(nil))
+	require.Equal(t, "[4 bytes, sha256:9f86d081884c7d65]", Payload([]byte("test")))
+
+	SetDebug(true)
+	defer SetDebug(false)
+
+	require.True(t, Debug())
+	require.Equal(t, `"test"`, Payload([]byte("test")))
+}
+
+func TestLeaf(t *testing.T) {
+	require.Equal(t, "<nil>", Leaf(nil))
+
+	leaf := &trillian.LogLeaf{
+		LeafIndex:      7,
+		MerkleLeafHash: []byte{0xab, 0xcd},
+		LeafValue:      []byte(`{"credentialSubject":{"name":"Jayden Doe"}}`),
+	}
+
+	require.Equal(t, "leaf_index:7 merkle_leaf_hash:abcd leaf_value:[43 bytes, sha256:0aa5f3cff9ef994b]"+
+		" extra_data:[empty]", Leaf(leaf))
+	require.NotContains(t, Leaf(leaf), "Jayden")
+
+	SetDebug(true)
+	defer SetDebug(false)
+
+	require.Contains(t, Leaf(leaf), "Jayden")
+}
+
+func TestError(t *testing.T) {
+	require.NoError(t, Error(nil, []byte(`{}`)))
+
+	payload := []byte(`{"credentialSubject":{"name":"Jayden Doe","birthDate":"2001-02-03"},"id":"ab"}`)
+	err := fmt.Errorf("parse credential: %w", errors.New(`invalid name "Jayden Doe" born 2001-02-03 (ab)`))
+
+	scrubbed := Error(err, payload)
+	require.ErrorIs(t, scrubbed, err)
+	require.NotContains(t, scrubbed.Error(), "Jayden")
+	require.NotContains(t, scrubbed.Error(), "2001-02-03")
+	require.Equal(t, `parse credential: invalid name "[10 bytes, sha256:0131352a3abdfe75]" born `+
+		`[10 bytes, sha256:a56b1bd367f907ac] (ab)`, scrubbed.Error())
+
+	t.Run("JWT", func(t *testing.T) {
+		enc := base64.RawURLEncoding.EncodeToString
+		claims := enc([]byte(`{"vc":{"credentialSubject":{"name":"Jayden Doe"}}}`))
+		jws := enc([]byte(`{"alg":"EdDSA"}`)) + "." + claims + ".c2ln"
+		disclosure := enc([]byte(`["salt","email","jayden@example.com"]`))
+
+		err = fmt.Errorf("claims %s of Jayden Doe, jayden@example.com", claims)
+
+		scrubbed = Error(err, []byte(jws+"~"+disclosure+"~"))
+		require.NotContains(t, scrubbed.Error(), "Jayden")
+		require.NotContains(t, scrubbed.Error(), "jayden")
+		require.NotContains(t, scrubbed.Error(), claims)
+
+		// a string which is not a JWT is scrubbed as is only
+		require.Equal(t, "loading https://example.com failed",
+			Error(errors.New("loading https://example.com failed"), []byte(`"a.b.c"`)).Error())
+	})
+
+	t.Run("Not scrubbed", func(t *testing.T) {
+		err = errors.New("unexpected end of JSON input")
+		require.Equal(t, err, Error(err, []byte(`{"name":"Jayden Doe"}`)))
+		require.Equal(t, err, Error(err, []byte(`not json`)))
+
+		SetDebug(true)
+		defer SetDebug(false)
+
+		err = errors.New("invalid name Jayden Doe")
+		require.Equal(t, err, Error(err, []byte(`{"name":"Jayden Doe"}`)))
+	})
+}
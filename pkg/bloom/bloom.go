@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package bloom implements a Bloom filter, a probabilistic set kept in memory: a key which was added is always
+// reported, a key which was not is reported with the false-positive rate the filter is sized for.
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+const wordSize = 64
+
+// Filter is a Bloom filter safe for concurrent use.
+type Filter struct {
+	mu     sync.RWMutex
+	words  []uint64
+	bits   uint64
+	hashes uint64
+}
+
+// New returns a filter sized for the capacity (number of keys) at the false-positive rate (e.g. 0.01),
+// the rate is higher once more keys than the capacity are added.
+func New(capacity uint64, falsePositiveRate float64) *Filter {
+	if capacity == 0 {
+		capacity = 1
+	}
+
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.01 // nolint: gomnd
+	}
+
+	// m = -n ln(p) / ln(2)^2 and k = m/n ln(2)
+	bits := uint64(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	bits = (bits + wordSize - 1) / wordSize * wordSize
+
+	hashes := uint64(math.Round(float64(bits) / float64(capacity) * math.Ln2))
+	if hashes == 0 {
+		hashes = 1
+	}
+
+	return &Filter{words: make([]uint64, bits/wordSize), bits: bits, hashes: hashes}
+}
+
+// positions returns the positions of the bits of the key (double hashing of its SHA-256 hash).
+func (f *Filter) positions(key []byte) []uint64 {
+	hash := sha256.Sum256(key)
+
+	h1 := binary.BigEndian.Uint64(hash[:8])
+	h2 := binary.BigEndian.Uint64(hash[8:16]) | 1
+
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % f.bits
+	}
+
+	return positions
+}
+
+// Add adds the key to the filter.
+func (f *Filter) Add(key []byte) {
+	positions := f.positions(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, p := range positions {
+		f.words[p/wordSize] |= 1 << (p % wordSize)
+	}
+}
+
+// MayContain reports whether the key may have been added, false if it was not.
+func (f *Filter) MayContain(key []byte) bool {
+	positions := f.positions(key)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, p := range positions {
+		if f.words[p/wordSize]&(1<<(p%wordSize)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
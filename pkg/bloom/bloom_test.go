@@ -0,0 +1,56 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bloom_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/bloom"
+)
+
+func key(i uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, i)
+
+	return b
+}
+
+func TestFilter(t *testing.T) {
+	const capacity = 10000
+
+	filter := bloom.New(capacity, 0.01)
+
+	for i := uint64(0); i < capacity; i++ {
+		filter.Add(key(i))
+	}
+
+	// the added keys are always reported
+	for i := uint64(0); i < capacity; i++ {
+		require.True(t, filter.MayContain(key(i)))
+	}
+
+	var falsePositives int
+
+	for i := uint64(capacity); i < 2*capacity; i++ {
+		if filter.MayContain(key(i)) {
+			falsePositives++
+		}
+	}
+
+	require.Less(t, falsePositives, capacity/50)
+}
+
+func TestFilter_Defaults(t *testing.T) {
+	filter := bloom.New(0, 0)
+	require.False(t, filter.MayContain([]byte("a")))
+
+	filter.Add([]byte("a"))
+	require.True(t, filter.MayContain([]byte("a")))
+}
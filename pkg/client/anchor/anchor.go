@@ -0,0 +1,398 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package anchor adapts VCT to anchor-origin services (e.g. Orb-style DID anchoring services): the anchor
+// credentials are submitted in batches, their submissions are retried while a log fails transiently and the
+// receipts of their SCTs are returned to the anchoring service.
+package anchor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+const (
+	defaultMaxBatchSize  = 100
+	defaultFlushInterval = time.Second
+	defaultConcurrency   = 10
+	defaultAttempts      = 5
+	defaultBackoff       = time.Second
+)
+
+// ErrClosed is returned when an anchor credential is submitted to a closed batcher.
+var ErrClosed = errors.New("batcher is closed")
+
+// nolint: gochecknoglobals
+var (
+	once               sync.Once
+	batches            monitoring.Counter
+	batchSize          monitoring.Histogram
+	submissionAttempts monitoring.Counter
+	submissionRetries  monitoring.Counter
+	submissionFailures monitoring.Counter
+	batchLatency       monitoring.Histogram
+)
+
+func createMetrics(mf monitoring.MetricFactory) {
+	batches = mf.NewCounter("anchor_batches", "Number of batches of anchor credentials submitted")
+	batchSize = mf.NewHistogram("anchor_batch_size", "Number of anchor credentials in a batch")
+	submissionAttempts = mf.NewCounter("anchor_submission_attempts", "Number of attempts to log an anchor credential")
+	submissionRetries = mf.NewCounter("anchor_submission_retries", "Number of retried submissions of anchor credentials")
+	submissionFailures = mf.NewCounter("anchor_submission_failures", "Number of anchor credentials not logged")
+	batchLatency = mf.NewHistogram("anchor_batch_latency", "Latency of logging a batch of anchor credentials in seconds")
+}
+
+// Submitter is the interface of the anchoring services to VCT: an anchor credential is logged and the receipt of
+// its SCTs is returned, e.g. to be embedded in the anchor as a proof of its witnessing.
+type Submitter interface {
+	Submit(ctx context.Context, anchor *verifiable.Credential) (*vct.Receipt, error)
+}
+
+// Result is the outcome of the submission of an anchor credential of a batch.
+type Result struct {
+	Anchor *verifiable.Credential
+	// Receipt of the SCTs accepted by the logging policy, nil if the submission failed.
+	Receipt *vct.Receipt
+	Err     error
+}
+
+type options struct {
+	maxBatchSize  int
+	flushInterval time.Duration
+	concurrency   int
+	attempts      int
+	backoff       time.Duration
+	mf            monitoring.MetricFactory
+	addVC         []vct.AddVCOpt
+}
+
+// Opt represents batcher option func.
+type Opt func(*options)
+
+// WithBatching sets the max number of anchor credentials of a batch (100 by default) and the max time an anchor
+// credential waits for its batch to be full before the batch is submitted (1s by default).
+func WithBatching(maxBatchSize int, flushInterval time.Duration) Opt {
+	return func(o *options) {
+		o.maxBatchSize = maxBatchSize
+		o.flushInterval = flushInterval
+	}
+}
+
+// WithConcurrency sets the max number of anchor credentials of a batch submitted at the same time (10 by default).
+func WithConcurrency(concurrency int) Opt {
+	return func(o *options) {
+		o.concurrency = concurrency
+	}
+}
+
+// WithRetries sets the number of attempts to get the SCTs required by the logging policy (5 by default) and
+// the backoff before the first retry (1s by default), doubled for every retry.
+func WithRetries(attempts int, backoff time.Duration) Opt {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithMetricFactory sets the factory of the metrics.
+func WithMetricFactory(mf monitoring.MetricFactory) Opt {
+	return func(o *options) {
+		o.mf = mf
+	}
+}
+
+// WithAddVCOpts sets the options of the submissions (e.g. vct.WithTenant).
+func WithAddVCOpts(opts ...vct.AddVCOpt) Opt {
+	return func(o *options) {
+		o.addVC = opts
+	}
+}
+
+// pending is an anchor credential waiting for its batch to be logged.
+type pending struct {
+	anchor *verifiable.Credential
+	done   chan Result
+}
+
+// Batcher logs the anchor credentials of an anchoring service in batches.
+//
+// The anchor credentials are submitted with their ID as the idempotency key: a retried submission returns
+// the SCT of the first submission, and the anchor credentials of a batch sharing an ID are submitted once.
+type Batcher struct {
+	logs          *vct.MultiClient
+	maxBatchSize  int
+	flushInterval time.Duration
+	concurrency   int
+	attempts      int
+	backoff       time.Duration
+	addVC         []vct.AddVCOpt
+
+	mu         sync.Mutex
+	batch      []*pending
+	generation uint64
+	closed     bool
+	inflight   sync.WaitGroup
+}
+
+var _ Submitter = (*Batcher)(nil)
+
+// New returns a batcher logging to the logs of the client, SCTs are accepted by its logging policy.
+func New(logs *vct.MultiClient, opts ...Opt) *Batcher {
+	op := &options{
+		maxBatchSize:  defaultMaxBatchSize,
+		flushInterval: defaultFlushInterval,
+		concurrency:   defaultConcurrency,
+		attempts:      defaultAttempts,
+		backoff:       defaultBackoff,
+	}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if op.maxBatchSize < 1 {
+		op.maxBatchSize = 1
+	}
+
+	if op.concurrency < 1 {
+		op.concurrency = 1
+	}
+
+	if op.attempts < 1 {
+		op.attempts = 1
+	}
+
+	if op.mf == nil {
+		op.mf = monitoring.InertMetricFactory{}
+	}
+
+	once.Do(func() { createMetrics(op.mf) })
+
+	return &Batcher{
+		logs:          logs,
+		maxBatchSize:  op.maxBatchSize,
+		flushInterval: op.flushInterval,
+		concurrency:   op.concurrency,
+		attempts:      op.attempts,
+		backoff:       op.backoff,
+		addVC:         op.addVC,
+	}
+}
+
+// Submit adds the anchor credential to the next batch and returns the receipt of its SCTs once the batch is
+// logged. The batch is submitted when it is full or when the flush interval elapsed since its first anchor
+// credential was added. The anchor credential is still logged if the context is done before its batch.
+func (b *Batcher) Submit(ctx context.Context, anchor *verifiable.Credential) (*vct.Receipt, error) {
+	p := &pending{anchor: anchor, done: make(chan Result, 1)}
+
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+
+		return nil, ErrClosed
+	}
+
+	b.batch = append(b.batch, p)
+
+	switch {
+	case len(b.batch) >= b.maxBatchSize:
+		b.flushLocked()
+	case len(b.batch) == 1:
+		generation := b.generation
+		time.AfterFunc(b.flushInterval, func() { b.flush(generation) })
+	}
+
+	b.mu.Unlock()
+
+	select {
+	case result := <-p.done:
+		return result.Receipt, result.Err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("submit anchor: %w", ctx.Err())
+	}
+}
+
+// Close submits the pending batch and waits for the batches being logged, the batcher accepts no more anchor
+// credentials.
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.flushLocked()
+	b.mu.Unlock()
+
+	b.inflight.Wait()
+}
+
+// flush submits the batch of the generation if it was not submitted yet (e.g. once full).
+func (b *Batcher) flush(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation {
+		b.flushLocked()
+	}
+}
+
+func (b *Batcher) flushLocked() {
+	if len(b.batch) == 0 {
+		return
+	}
+
+	batch := b.batch
+
+	b.batch = nil
+	b.generation++
+
+	b.inflight.Add(1)
+
+	go func() {
+		defer b.inflight.Done()
+
+		anchors := make([]*verifiable.Credential, len(batch))
+		for i, p := range batch {
+			anchors[i] = p.anchor
+		}
+
+		for i, result := range b.SubmitBatch(context.Background(), anchors) {
+			batch[i].done <- result
+		}
+	}()
+}
+
+// SubmitBatch logs the anchor credentials, up to the concurrency at the same time, and returns their results in
+// the same order. The anchor credentials whose logs failed transiently are retried together after the backoff of
+// the batch, so a log which is unavailable is not sent the retries of every anchor credential at different times.
+func (b *Batcher) SubmitBatch(ctx context.Context, anchors []*verifiable.Credential) []Result {
+	start := time.Now()
+
+	batches.Inc()
+	batchSize.Observe(float64(len(anchors)))
+
+	results := make([]Result, len(anchors))
+	duplicates := map[int]int{} // index -> index of the first anchor credential with the same ID
+	first := map[string]int{}
+
+	var todo []int
+
+	for i, anchor := range anchors {
+		results[i].Anchor = anchor
+
+		if anchor.ID != "" {
+			if j, ok := first[anchor.ID]; ok {
+				duplicates[i] = j
+
+				continue
+			}
+
+			first[anchor.ID] = i
+		}
+
+		todo = append(todo, i)
+	}
+
+	backoff := b.backoff
+
+	for attempt := 1; len(todo) > 0; attempt++ {
+		retries := b.submitAll(ctx, anchors, todo, results, attempt)
+		if len(retries) == 0 || attempt >= b.attempts {
+			break
+		}
+
+		submissionRetries.Add(float64(len(retries)))
+
+		select {
+		case <-ctx.Done():
+			for _, i := range retries {
+				results[i].Err = fmt.Errorf("submit anchor %s: %w", anchors[i].ID, ctx.Err())
+			}
+
+			retries = nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		todo = retries
+	}
+
+	for i, j := range duplicates {
+		results[i].Receipt, results[i].Err = results[j].Receipt, results[j].Err
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			submissionFailures.Inc()
+		}
+	}
+
+	batchLatency.Observe(time.Since(start).Seconds())
+
+	return results
+}
+
+// submitAll submits the anchor credentials of the indexes and returns the indexes of the submissions to retry.
+func (b *Batcher) submitAll(ctx context.Context, anchors []*verifiable.Credential, indexes []int, results []Result,
+	attempt int) []int {
+	retry := make([]bool, len(indexes))
+	limit := make(chan struct{}, b.concurrency)
+
+	var wg sync.WaitGroup
+
+	for k, i := range indexes {
+		wg.Add(1)
+
+		limit <- struct{}{}
+
+		go func(k, i int) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			results[i].Receipt, retry[k], results[i].Err = b.submit(ctx, anchors[i], attempt)
+		}(k, i)
+	}
+
+	wg.Wait()
+
+	var retries []int
+
+	for k, i := range indexes {
+		if retry[k] {
+			retries = append(retries, i)
+		}
+	}
+
+	return retries
+}
+
+// submit logs the anchor credential, it returns true if the submission failed and may succeed once retried.
+func (b *Batcher) submit(ctx context.Context, anchor *verifiable.Credential, attempt int) (*vct.Receipt, bool,
+	error) {
+	submissionAttempts.Inc()
+
+	opts := b.addVC
+	if anchor.ID != "" {
+		opts = append(append([]vct.AddVCOpt{}, b.addVC...), vct.WithIdempotencyKey(anchor.ID))
+	}
+
+	results, err := b.logs.AddVC(ctx, anchor, opts...)
+	if err == nil {
+		return vct.NewReceipt(results), false, nil
+	}
+
+	retry := errors.Is(err, vct.ErrPolicyNotSatisfied) && vct.Retryable(results)
+
+	return nil, retry, fmt.Errorf("submit anchor %s (attempt %d of %d): %w", anchor.ID, attempt, b.attempts, err)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anchor_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/client/anchor"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const sctTimestamp = 1619006293939
+
+// log is a log server signing the SCTs of the anchor credentials it knows, the submissions of every anchor
+// credential fail first with the status.
+type log struct {
+	failures int32
+	status   int
+
+	mu          sync.Mutex
+	anchors     map[string]*verifiable.Credential
+	submissions map[string]int32
+}
+
+func newAnchors(n int) []*verifiable.Credential {
+	anchors := make([]*verifiable.Credential, n)
+
+	for i := range anchors {
+		anchors[i] = &verifiable.Credential{
+			Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Types:   []string{"VerifiableCredential", "AnchorCredential"},
+			ID:      fmt.Sprintf("https://orb.domain1.com/vc/%d", i),
+			Issuer:  verifiable.Issuer{ID: "https://orb.domain1.com"},
+			Subject: fmt.Sprintf("hl:uEiBanchor%d", i),
+		}
+	}
+
+	return anchors
+}
+
+func newLog(t *testing.T, anchors []*verifiable.Credential, failures int32, status int) (*log, *vct.MultiClient) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+	logID := command.LogID(pubKey)
+
+	l := &log{
+		failures:    failures,
+		status:      status,
+		anchors:     map[string]*verifiable.Credential{},
+		submissions: map[string]int32{},
+	}
+
+	for _, anchor := range anchors {
+		l.anchors[anchor.ID] = anchor
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/.well-known/webfinger"):
+			require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+				Properties: map[string]interface{}{command.PublicKeyType: pubKey},
+			}))
+		case strings.HasSuffix(r.URL.Path, "/v1/add-vc"):
+			var envelope command.AddVCEnvelope
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&envelope))
+
+			id := envelope.Options.IdempotencyKey
+
+			l.mu.Lock()
+			l.submissions[id]++
+			submissions, anchor := l.submissions[id], l.anchors[id]
+			l.mu.Unlock()
+
+			if submissions <= l.failures {
+				w.WriteHeader(l.status)
+
+				return
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(command.AddVCResponse{
+				SVCTVersion: command.V1,
+				ID:          logID[:],
+				Timestamp:   sctTimestamp,
+				Signature:   signSCT(t, key, anchor),
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	logs, err := vct.NewMultiClient([]*vct.Client{vct.New(ts.URL + "/maple2021")}, vct.Policy{})
+	require.NoError(t, err)
+
+	return l, logs
+}
+
+func (l *log) submissionsOf(id string) int32 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.submissions[id]
+}
+
+func signSCT(t *testing.T, key *ecdsa.PrivateKey, anchor *verifiable.Credential) []byte {
+	t.Helper()
+
+	leaf, err := command.CreateLeaf(sctTimestamp, anchor)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(command.CreateVCTimestampSignature(leaf))
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature, err := json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{
+			Signature: command.ECDSASignature,
+			Type:      kms.ECDSAP256TypeIEEEP1363,
+		},
+		Signature: append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...),
+	})
+	require.NoError(t, err)
+
+	return signature
+}
+
+func TestBatcher_Submit(t *testing.T) {
+	t.Run("Batch submitted once full", func(t *testing.T) {
+		anchors := newAnchors(3)
+		l, logs := newLog(t, anchors, 0, http.StatusOK)
+
+		batcher := New(logs, WithBatching(len(anchors), time.Minute))
+		defer batcher.Close()
+
+		var wg sync.WaitGroup
+
+		for _, anchor := range anchors {
+			wg.Add(1)
+
+			go func(anchor *verifiable.Credential) {
+				defer wg.Done()
+
+				receipt, err := batcher.Submit(context.Background(), anchor)
+				require.NoError(t, err)
+				require.Len(t, receipt.SCTs, 1)
+				require.Equal(t, uint64(sctTimestamp), receipt.SCTs[0].Timestamp)
+				require.Contains(t, receipt.SCTs[0].Endpoint, "/maple2021")
+			}(anchor)
+		}
+
+		wg.Wait()
+
+		for _, anchor := range anchors {
+			require.Equal(t, int32(1), l.submissionsOf(anchor.ID))
+		}
+	})
+
+	t.Run("Batch submitted once the flush interval elapsed", func(t *testing.T) {
+		anchors := newAnchors(1)
+		_, logs := newLog(t, anchors, 0, http.StatusOK)
+
+		batcher := New(logs, WithBatching(100, 10*time.Millisecond))
+		defer batcher.Close()
+
+		receipt, err := batcher.Submit(context.Background(), anchors[0])
+		require.NoError(t, err)
+		require.Len(t, receipt.SCTs, 1)
+	})
+
+	t.Run("Context canceled", func(t *testing.T) {
+		anchors := newAnchors(1)
+		l, logs := newLog(t, anchors, 0, http.StatusOK)
+
+		batcher := New(logs, WithBatching(100, time.Minute))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := batcher.Submit(ctx, anchors[0])
+		require.EqualError(t, err, "submit anchor: context deadline exceeded")
+
+		// the pending batch is still logged on close
+		batcher.Close()
+		require.Equal(t, int32(1), l.submissionsOf(anchors[0].ID))
+
+		_, err = batcher.Submit(context.Background(), anchors[0])
+		require.ErrorIs(t, err, ErrClosed)
+	})
+}
+
+func TestBatcher_SubmitBatch(t *testing.T) {
+	t.Run("Retried while the log is unavailable", func(t *testing.T) {
+		anchors := newAnchors(4)
+		l, logs := newLog(t, anchors, 2, http.StatusServiceUnavailable)
+
+		results := New(logs, WithConcurrency(2), WithRetries(3, time.Millisecond)).SubmitBatch(
+			context.Background(), anchors)
+		require.Len(t, results, len(anchors))
+
+		for i, result := range results {
+			require.NoError(t, result.Err)
+			require.Equal(t, anchors[i], result.Anchor)
+			require.Len(t, result.Receipt.SCTs, 1)
+			require.Equal(t, int32(3), l.submissionsOf(anchors[i].ID))
+		}
+	})
+
+	t.Run("Attempts exhausted", func(t *testing.T) {
+		anchors := newAnchors(2)
+		l, logs := newLog(t, anchors, 5, http.StatusServiceUnavailable)
+
+		results := New(logs, WithRetries(2, time.Millisecond)).SubmitBatch(context.Background(), anchors)
+
+		for i, result := range results {
+			require.Contains(t, result.Err.Error(), fmt.Sprintf("submit anchor %s (attempt 2 of 2)", anchors[i].ID))
+			require.Nil(t, result.Receipt)
+			require.Equal(t, int32(2), l.submissionsOf(anchors[i].ID))
+		}
+	})
+
+	t.Run("Rejected submission is not retried", func(t *testing.T) {
+		anchors := newAnchors(1)
+		l, logs := newLog(t, anchors, 5, http.StatusBadRequest)
+
+		results := New(logs, WithRetries(3, time.Millisecond)).SubmitBatch(context.Background(), anchors)
+		require.Contains(t, results[0].Err.Error(), "(attempt 1 of 3)")
+		require.Equal(t, int32(1), l.submissionsOf(anchors[0].ID))
+	})
+
+	t.Run("Duplicates submitted once", func(t *testing.T) {
+		anchors := newAnchors(1)
+		l, logs := newLog(t, anchors, 0, http.StatusOK)
+
+		results := New(logs).SubmitBatch(context.Background(), []*verifiable.Credential{anchors[0], anchors[0]})
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+		require.Equal(t, results[0].Receipt, results[1].Receipt)
+		require.Equal(t, int32(1), l.submissionsOf(anchors[0].ID))
+	})
+
+	t.Run("Context canceled", func(t *testing.T) {
+		anchors := newAnchors(1)
+		_, logs := newLog(t, anchors, 5, http.StatusServiceUnavailable)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		results := New(logs, WithRetries(3, time.Minute)).SubmitBatch(ctx, anchors)
+		require.EqualError(t, results[0].Err, "submit anchor https://orb.domain1.com/vc/0: context deadline exceeded")
+	})
+}
@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package oid4vci makes transparency a drop-in for OpenID for Verifiable Credential Issuance (OID4VCI) issuers:
+// a credential is logged once issued and the receipt of its SCTs is returned in the credential response.
+package oid4vci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+// ReceiptParameter is the parameter of the credential response carrying the encoded receipt.
+const ReceiptParameter = "vct_receipt"
+
+const (
+	defaultAttempts = 3
+	defaultBackoff  = time.Second
+)
+
+// nolint: gochecknoglobals
+var (
+	once        sync.Once
+	logAttempts monitoring.Counter
+	logFailures monitoring.Counter
+	logLatency  monitoring.Histogram
+	receiptSCTs monitoring.Histogram
+)
+
+func createMetrics(mf monitoring.MetricFactory) {
+	logAttempts = mf.NewCounter("oid4vci_log_attempts", "Number of attempts to log an issued credential")
+	logFailures = mf.NewCounter("oid4vci_log_failures", "Number of issued credentials which failed to be logged")
+	logLatency = mf.NewHistogram("oid4vci_log_latency", "Latency of logging an issued credential in seconds")
+	receiptSCTs = mf.NewHistogram("oid4vci_receipt_scts", "Number of SCTs in the receipt of an issued credential")
+}
+
+type options struct {
+	attempts int
+	backoff  time.Duration
+	mf       monitoring.MetricFactory
+	addVC    []vct.AddVCOpt
+}
+
+// Opt represents issuer option func.
+type Opt func(*options)
+
+// WithRetries sets the number of attempts to get the SCTs required by the logging policy (3 by default) and
+// the backoff before the first retry (1s by default), doubled for every retry.
+func WithRetries(attempts int, backoff time.Duration) Opt {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithMetricFactory sets the factory of the metrics.
+func WithMetricFactory(mf monitoring.MetricFactory) Opt {
+	return func(o *options) {
+		o.mf = mf
+	}
+}
+
+// WithAddVCOpts sets the options of the submissions (e.g. vct.WithTenant).
+func WithAddVCOpts(opts ...vct.AddVCOpt) Opt {
+	return func(o *options) {
+		o.addVC = opts
+	}
+}
+
+// Issuer logs the credentials issued by an OID4VCI issuer.
+type Issuer struct {
+	logs     *vct.MultiClient
+	attempts int
+	backoff  time.Duration
+	addVC    []vct.AddVCOpt
+}
+
+// New returns an issuer logging to the logs of the client, SCTs are accepted by its logging policy.
+func New(logs *vct.MultiClient, opts ...Opt) *Issuer {
+	op := &options{attempts: defaultAttempts, backoff: defaultBackoff}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if op.attempts < 1 {
+		op.attempts = 1
+	}
+
+	if op.mf == nil {
+		op.mf = monitoring.InertMetricFactory{}
+	}
+
+	once.Do(func() { createMetrics(op.mf) })
+
+	return &Issuer{logs: logs, attempts: op.attempts, backoff: op.backoff, addVC: op.addVC}
+}
+
+// Log logs the issued credential and returns the receipt of its SCTs. The submission is retried while
+// the logging policy is not satisfied and a log failed transiently (e.g. it is unavailable).
+func (i *Issuer) Log(ctx context.Context, credential *verifiable.Credential) (*vct.Receipt, error) {
+	start := time.Now()
+	backoff := i.backoff
+
+	for attempt := 1; ; attempt++ {
+		logAttempts.Inc()
+
+		results, err := i.logs.AddVC(ctx, credential, i.addVC...)
+		if err == nil {
+			receipt := vct.NewReceipt(results)
+
+			logLatency.Observe(time.Since(start).Seconds())
+			receiptSCTs.Observe(float64(len(receipt.SCTs)))
+
+			return receipt, nil
+		}
+
+		if attempt >= i.attempts || !errors.Is(err, vct.ErrPolicyNotSatisfied) || !vct.Retryable(results) {
+			logFailures.Inc()
+
+			return nil, fmt.Errorf("log credential (attempt %d of %d): %w", attempt, i.attempts, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logFailures.Inc()
+
+			return nil, fmt.Errorf("log credential: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// CredentialResponse logs the issued credential and adds the encoded receipt to the OID4VCI credential
+// response (ReceiptParameter).
+func (i *Issuer) CredentialResponse(ctx context.Context, response map[string]interface{},
+	credential *verifiable.Credential) error {
+	receipt, err := i.Log(ctx, credential)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := receipt.Encode()
+	if err != nil {
+		return err // nolint: wrapcheck
+	}
+
+	response[ReceiptParameter] = encoded
+
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package oid4vci_test
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/internal/pkg/ldcontext"
+	. "github.com/trustbloc/vct/pkg/client/oid4vci"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// sctSignature is the signature of the bachelor degree credential logged at sctTimestamp by the log of sctPubKey.
+const (
+	sctSignature = `{"algorithm":{"hash":"SHA256","signature":"ECDSA","type":"ECDSAP256IEEEP1363"},` +
+		`"signature":"l8NfxVChPH7fG4cId6iNIbgpbRzxov+rwozdL4r5lRNXGiOTy7iAn2+Zg84VwkJoeJWvLGyO2a3WZnQKtNu/Lg=="}`
+	sctTimestamp = 1619006293939
+)
+
+// nolint: gochecknoglobals
+var (
+	//go:embed testdata/bachelor_degree.json
+	vcBachelorDegree []byte
+
+	sctPubKey = []byte{
+		4, 185, 70, 232, 62, 166, 17, 233, 172, 19, 143, 227, 170, 181, 184, 202, 177, 242, 247, 199, 73, 209,
+		108, 207, 87, 26, 199, 162, 21, 140, 117, 0, 143, 48, 20, 118, 255, 221, 200, 185, 227, 42, 213, 124,
+		156, 109, 160, 211, 29, 245, 44, 128, 46, 88, 117, 88, 240, 223, 241, 24, 209, 87, 214, 115, 101,
+	}
+)
+
+// newLog returns a log server failing the first submissions with the status and then returning the SCT.
+func newLog(t *testing.T, failures int32, status int) (*vct.Client, *int32) {
+	t.Helper()
+
+	logID := sha256.Sum256(sctPubKey)
+
+	var submissions int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/.well-known/webfinger"):
+			require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+				Properties: map[string]interface{}{command.PublicKeyType: sctPubKey},
+			}))
+		case strings.HasSuffix(r.URL.Path, "/v1/add-vc"):
+			if atomic.AddInt32(&submissions, 1) <= failures {
+				w.WriteHeader(status)
+
+				return
+			}
+
+			require.NoError(t, json.NewEncoder(w).Encode(command.AddVCResponse{
+				SVCTVersion: command.V1,
+				ID:          logID[:],
+				Timestamp:   sctTimestamp,
+				Signature:   []byte(sctSignature),
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return vct.New(ts.URL + "/maple2021"), &submissions
+}
+
+func TestIssuer_Log(t *testing.T) {
+	credential, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(ldcontext.DocumentLoader(t)),
+	)
+	require.NoError(t, err)
+
+	newIssuer := func(t *testing.T, client *vct.Client, opts ...Opt) *Issuer {
+		t.Helper()
+
+		logs, err := vct.NewMultiClient([]*vct.Client{client}, vct.Policy{})
+		require.NoError(t, err)
+
+		return New(logs, opts...)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		client, _ := newLog(t, 0, http.StatusOK)
+
+		response := map[string]interface{}{"format": "ldp_vc", "credential": credential}
+		require.NoError(t, newIssuer(t, client).CredentialResponse(context.Background(), response, credential))
+
+		receipt, err := vct.DecodeReceipt(response[ReceiptParameter].(string))
+		require.NoError(t, err)
+		require.Len(t, receipt.SCTs, 1)
+		require.Equal(t, uint64(sctTimestamp), receipt.SCTs[0].Timestamp)
+		require.Contains(t, receipt.SCTs[0].Endpoint, "/maple2021")
+	})
+
+	t.Run("Retried while the log is unavailable", func(t *testing.T) {
+		client, submissions := newLog(t, 2, http.StatusServiceUnavailable)
+
+		receipt, err := newIssuer(t, client, WithRetries(3, time.Millisecond)).Log(context.Background(), credential)
+		require.NoError(t, err)
+		require.Len(t, receipt.SCTs, 1)
+		require.Equal(t, int32(3), atomic.LoadInt32(submissions))
+	})
+
+	t.Run("Attempts exhausted", func(t *testing.T) {
+		client, submissions := newLog(t, 5, http.StatusServiceUnavailable)
+
+		response := map[string]interface{}{}
+		err := newIssuer(t, client, WithRetries(2, time.Millisecond)).CredentialResponse(context.Background(),
+			response, credential)
+		require.Contains(t, err.Error(), "log credential (attempt 2 of 2)")
+		require.Equal(t, int32(2), atomic.LoadInt32(submissions))
+		require.NotContains(t, response, ReceiptParameter)
+	})
+
+	t.Run("Rejected submission is not retried", func(t *testing.T) {
+		client, submissions := newLog(t, 5, http.StatusBadRequest)
+
+		_, err := newIssuer(t, client, WithRetries(3, time.Millisecond)).Log(context.Background(), credential)
+		require.Contains(t, err.Error(), "log credential (attempt 1 of 3)")
+		require.Equal(t, int32(1), atomic.LoadInt32(submissions))
+	})
+
+	t.Run("Context canceled", func(t *testing.T) {
+		client, _ := newLog(t, 5, http.StatusServiceUnavailable)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := newIssuer(t, client, WithRetries(3, time.Minute)).Log(ctx, credential)
+		require.EqualError(t, err, "log credential: context deadline exceeded")
+	})
+}
@@ -0,0 +1,288 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package oid4vp verifies the transparency of credentials presented in OpenID for Verifiable Presentations
+// (OID4VP) flows: the SCTs of the receipt of a credential are verified against the list of logs the verifier trusts.
+package oid4vp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// ReceiptsProperty is the property of a presentation carrying the encoded receipts of its credentials,
+// in the order of the credentials (an empty string for a credential without a receipt).
+const ReceiptsProperty = "vctReceipts"
+
+// defaultMaxClockSkew is the max time an SCT may be dated in the future by default.
+const defaultMaxClockSkew = 5 * time.Minute
+
+// SCTStatus is the outcome of the verification of an SCT.
+type SCTStatus string
+
+// SCT statuses.
+const (
+	// SCTValid is the status of a valid SCT of a listed log.
+	SCTValid SCTStatus = "valid"
+	// SCTUnknownLog is the status of an SCT of a log which is not listed, it is not verified.
+	SCTUnknownLog SCTStatus = "unknown_log"
+	// SCTInvalid is the status of an SCT which failed verification.
+	SCTInvalid SCTStatus = "invalid"
+)
+
+// Log is a log trusted by the verifier.
+type Log struct {
+	Endpoint  string
+	PublicKey []byte
+}
+
+// Assessment is the transparency assessment of a presented credential.
+type Assessment struct {
+	// Transparent is true if the credential has valid SCTs of at least the required number of listed logs.
+	Transparent bool `json:"transparent"`
+	// Logs is the number of distinct listed logs with a valid SCT.
+	Logs     int             `json:"logs"`
+	Required int             `json:"required"`
+	SCTs     []SCTAssessment `json:"scts"`
+}
+
+// SCTAssessment is the assessment of an SCT of the receipt.
+type SCTAssessment struct {
+	Endpoint  string    `json:"endpoint"`
+	LogID     string    `json:"log_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Status    SCTStatus `json:"status"`
+	// Skew is the time the SCT is dated ahead of the local clock, zero if it is dated in the past.
+	Skew time.Duration `json:"skew,omitempty"`
+	// Error is the reason the SCT is invalid.
+	Error string `json:"error,omitempty"`
+}
+
+type options struct {
+	requiredSCTs int
+	maxClockSkew time.Duration
+}
+
+// Opt represents verifier option func.
+type Opt func(*options)
+
+// WithRequiredSCTs sets the number of listed logs a credential must have a valid SCT of (1 by default).
+func WithRequiredSCTs(n int) Opt {
+	return func(o *options) {
+		o.requiredSCTs = n
+	}
+}
+
+// WithMaxClockSkew sets the max time an SCT may be dated in the future (5 minutes by default), it tolerates
+// the skew between the clocks of the logs and the local clock.
+func WithMaxClockSkew(d time.Duration) Opt {
+	return func(o *options) {
+		o.maxClockSkew = d
+	}
+}
+
+// Verifier verifies the SCTs of presented credentials against a list of logs.
+type Verifier struct {
+	logs         map[string]Log // log ID (base64) -> log
+	requiredSCTs int
+	maxClockSkew time.Duration
+}
+
+// New returns a verifier trusting the logs.
+func New(logs []Log, opts ...Opt) (*Verifier, error) {
+	op := &options{requiredSCTs: 1, maxClockSkew: defaultMaxClockSkew}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if len(logs) == 0 {
+		return nil, errors.New("no logs")
+	}
+
+	if op.maxClockSkew < 0 {
+		return nil, fmt.Errorf("max clock skew %s must not be negative", op.maxClockSkew)
+	}
+
+	verifier := &Verifier{logs: map[string]Log{}, requiredSCTs: op.requiredSCTs, maxClockSkew: op.maxClockSkew}
+
+	for _, log := range logs {
+		if len(log.PublicKey) == 0 {
+			return nil, fmt.Errorf("log %s has no public key", log.Endpoint)
+		}
+
+		verifier.logs[logID(log.PublicKey)] = log
+	}
+
+	if op.requiredSCTs < 1 || op.requiredSCTs > len(verifier.logs) {
+		return nil, fmt.Errorf("required SCTs %d must be between 1 and the number of logs %d", op.requiredSCTs,
+			len(verifier.logs))
+	}
+
+	return verifier, nil
+}
+
+// Verify assesses the SCTs of the receipt of the credential, a nil receipt is assessed as not transparent.
+func (v *Verifier) Verify(vc *verifiable.Credential, receipt *vct.Receipt) *Assessment {
+	assessment := &Assessment{Required: v.requiredSCTs, SCTs: []SCTAssessment{}}
+
+	if receipt == nil {
+		return assessment
+	}
+
+	valid := map[string]bool{}
+
+	for i := range receipt.SCTs {
+		sct := v.verify(vc, &receipt.SCTs[i])
+		if sct.Status == SCTValid {
+			valid[sct.LogID] = true
+		}
+
+		assessment.SCTs = append(assessment.SCTs, sct)
+	}
+
+	assessment.Logs = len(valid)
+	assessment.Transparent = assessment.Logs >= v.requiredSCTs
+
+	return assessment
+}
+
+func (v *Verifier) verify(vc *verifiable.Credential, sct *vct.ReceiptSCT) SCTAssessment {
+	id := base64.StdEncoding.EncodeToString(sct.ID)
+
+	assessment := SCTAssessment{
+		Endpoint:  sct.Endpoint,
+		LogID:     id,
+		Timestamp: time.Unix(0, int64(sct.Timestamp)*int64(time.Millisecond)).UTC(),
+	}
+
+	log, ok := v.logs[id]
+	if !ok {
+		assessment.Status = SCTUnknownLog
+
+		return assessment
+	}
+
+	// the endpoint of the receipt is informational, the listed endpoint is reported.
+	assessment.Endpoint = log.Endpoint
+
+	if err := vct.VerifySCT(&sct.AddVCResponse, log.PublicKey, vc); err != nil {
+		assessment.Status, assessment.Error = SCTInvalid, err.Error()
+
+		return assessment
+	}
+
+	if skew := time.Until(assessment.Timestamp); skew > 0 {
+		assessment.Skew = skew
+	}
+
+	if assessment.Skew > v.maxClockSkew {
+		assessment.Status, assessment.Error = SCTInvalid, fmt.Sprintf("SCT is dated %s in the future, max skew is %s",
+			assessment.Skew.Round(time.Second), v.maxClockSkew)
+
+		return assessment
+	}
+
+	assessment.Status = SCTValid
+
+	return assessment
+}
+
+// VerifyPresentation assesses the credentials of the presentation with their receipts (ReceiptsProperty).
+// The credentials are parsed with the options, the assessments are in the order of the credentials.
+func (v *Verifier) VerifyPresentation(vp *verifiable.Presentation,
+	opts ...verifiable.CredentialOpt) ([]*Assessment, error) {
+	credentials, err := vp.MarshalledCredentials()
+	if err != nil {
+		return nil, fmt.Errorf("marshal credentials: %w", err)
+	}
+
+	receipts, err := presentationReceipts(vp, len(credentials))
+	if err != nil {
+		return nil, err
+	}
+
+	assessments := make([]*Assessment, len(credentials))
+
+	for i, credential := range credentials {
+		vc, er := verifiable.ParseCredential(credential, opts...)
+		if er != nil {
+			return nil, fmt.Errorf("parse credential %d: %w", i, er)
+		}
+
+		assessments[i] = v.Verify(vc, receipts[i])
+	}
+
+	return assessments, nil
+}
+
+func presentationReceipts(vp *verifiable.Presentation, n int) ([]*vct.Receipt, error) {
+	receipts := make([]*vct.Receipt, n)
+
+	raw, ok := vp.CustomFields[ReceiptsProperty]
+	if !ok {
+		return receipts, nil
+	}
+
+	encoded, ok := raw.([]interface{})
+	if !ok || len(encoded) != n {
+		return nil, fmt.Errorf("%s must be an array of %d receipts", ReceiptsProperty, n)
+	}
+
+	for i, e := range encoded {
+		s, isString := e.(string)
+		if !isString {
+			return nil, fmt.Errorf("receipt %d must be a string", i)
+		}
+
+		if s == "" {
+			continue
+		}
+
+		receipt, err := vct.DecodeReceipt(s)
+		if err != nil {
+			return nil, fmt.Errorf("receipt %d: %w", i, err)
+		}
+
+		receipts[i] = receipt
+	}
+
+	return receipts, nil
+}
+
+// AddReceipts adds the encoded receipts of the credentials of the presentation (ReceiptsProperty),
+// in the order of the credentials. The holder adds the receipts before signing the presentation.
+func AddReceipts(vp *verifiable.Presentation, receipts ...string) error {
+	if len(receipts) != len(vp.Credentials()) {
+		return fmt.Errorf("got %d receipts for %d credentials", len(receipts), len(vp.Credentials()))
+	}
+
+	encoded := make([]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		encoded[i] = receipt
+	}
+
+	if vp.CustomFields == nil {
+		vp.CustomFields = verifiable.CustomFields{}
+	}
+
+	vp.CustomFields[ReceiptsProperty] = encoded
+
+	return nil
+}
+
+func logID(pubKey []byte) string {
+	id := command.LogID(pubKey)
+
+	return base64.StdEncoding.EncodeToString(id[:])
+}
@@ -0,0 +1,156 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package oid4vp_test
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/internal/pkg/ldcontext"
+	. "github.com/trustbloc/vct/pkg/client/oid4vp"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// sctSignature is the signature of the bachelor degree credential logged at sctTimestamp by the log of sctPubKey.
+const (
+	sctSignature = `{"algorithm":{"hash":"SHA256","signature":"ECDSA","type":"ECDSAP256IEEEP1363"},` +
+		`"signature":"l8NfxVChPH7fG4cId6iNIbgpbRzxov+rwozdL4r5lRNXGiOTy7iAn2+Zg84VwkJoeJWvLGyO2a3WZnQKtNu/Lg=="}`
+	sctTimestamp = 1619006293939
+	endpoint     = "https://vct.com/maple2021"
+)
+
+// nolint: gochecknoglobals
+var (
+	//go:embed testdata/bachelor_degree.json
+	vcBachelorDegree []byte
+
+	sctPubKey = []byte{
+		4, 185, 70, 232, 62, 166, 17, 233, 172, 19, 143, 227, 170, 181, 184, 202, 177, 242, 247, 199, 73, 209,
+		108, 207, 87, 26, 199, 162, 21, 140, 117, 0, 143, 48, 20, 118, 255, 221, 200, 185, 227, 42, 213, 124,
+		156, 109, 160, 211, 29, 245, 44, 128, 46, 88, 117, 88, 240, 223, 241, 24, 209, 87, 214, 115, 101,
+	}
+)
+
+func newReceipt(t *testing.T, pubKey []byte, signature string) *vct.Receipt {
+	t.Helper()
+
+	logID := sha256.Sum256(pubKey)
+
+	return &vct.Receipt{SCTs: []vct.ReceiptSCT{{
+		Endpoint: "https://mirror.vct.com/maple2021",
+		AddVCResponse: command.AddVCResponse{
+			SVCTVersion: command.V1,
+			ID:          logID[:],
+			Timestamp:   sctTimestamp,
+			Signature:   []byte(signature),
+		},
+	}}}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	require.EqualError(t, err, "no logs")
+
+	_, err = New([]Log{{Endpoint: endpoint}})
+	require.EqualError(t, err, "log https://vct.com/maple2021 has no public key")
+
+	_, err = New([]Log{{Endpoint: endpoint, PublicKey: sctPubKey}}, WithRequiredSCTs(2))
+	require.EqualError(t, err, "required SCTs 2 must be between 1 and the number of logs 1")
+
+	_, err = New([]Log{{Endpoint: endpoint, PublicKey: sctPubKey}}, WithMaxClockSkew(-time.Second))
+	require.EqualError(t, err, "max clock skew -1s must not be negative")
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	loader := ldcontext.DocumentLoader(t)
+
+	vc, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(loader),
+	)
+	require.NoError(t, err)
+
+	verifier, err := New([]Log{{Endpoint: endpoint, PublicKey: sctPubKey}})
+	require.NoError(t, err)
+
+	t.Run("Transparent", func(t *testing.T) {
+		assessment := verifier.Verify(vc, newReceipt(t, sctPubKey, sctSignature))
+		require.True(t, assessment.Transparent)
+		require.Equal(t, 1, assessment.Logs)
+		require.Equal(t, 1, assessment.Required)
+		require.Len(t, assessment.SCTs, 1)
+		require.Equal(t, SCTValid, assessment.SCTs[0].Status)
+		require.Equal(t, endpoint, assessment.SCTs[0].Endpoint)
+		require.Equal(t, int64(sctTimestamp), assessment.SCTs[0].Timestamp.UnixNano()/1e6)
+		require.Zero(t, assessment.SCTs[0].Skew)
+	})
+
+	t.Run("Not transparent", func(t *testing.T) {
+		require.False(t, verifier.Verify(vc, nil).Transparent)
+
+		receipt := newReceipt(t, sctPubKey, `{}`)
+		receipt.SCTs = append(receipt.SCTs, newReceipt(t, []byte("other key"), sctSignature).SCTs...)
+
+		assessment := verifier.Verify(vc, receipt)
+		require.False(t, assessment.Transparent)
+		require.Equal(t, 0, assessment.Logs)
+		require.Equal(t, SCTInvalid, assessment.SCTs[0].Status)
+		require.Contains(t, assessment.SCTs[0].Error, "verify SCT signature")
+		require.Equal(t, SCTUnknownLog, assessment.SCTs[1].Status)
+		require.Equal(t, "https://mirror.vct.com/maple2021", assessment.SCTs[1].Endpoint)
+	})
+
+	t.Run("Presentation", func(t *testing.T) {
+		receipt, err := newReceipt(t, sctPubKey, sctSignature).Encode()
+		require.NoError(t, err)
+
+		vp, err := verifiable.NewPresentation(verifiable.WithCredentials(vc, vc))
+		require.NoError(t, err)
+
+		require.EqualError(t, AddReceipts(vp, receipt), "got 1 receipts for 2 credentials")
+		require.NoError(t, AddReceipts(vp, receipt, ""))
+
+		raw, err := json.Marshal(vp)
+		require.NoError(t, err)
+
+		presented, err := verifiable.ParsePresentation(raw, verifiable.WithPresDisabledProofCheck(),
+			verifiable.WithPresJSONLDDocumentLoader(loader))
+		require.NoError(t, err)
+
+		assessments, err := verifier.VerifyPresentation(presented, verifiable.WithDisabledProofCheck(),
+			verifiable.WithNoCustomSchemaCheck(), verifiable.WithJSONLDDocumentLoader(loader))
+		require.NoError(t, err)
+		require.Len(t, assessments, 2)
+		require.True(t, assessments[0].Transparent)
+		require.False(t, assessments[1].Transparent)
+
+		presented.CustomFields[ReceiptsProperty] = []interface{}{receipt}
+		_, err = verifier.VerifyPresentation(presented)
+		require.EqualError(t, err, "vctReceipts must be an array of 2 receipts")
+
+		presented.CustomFields[ReceiptsProperty] = []interface{}{1, ""}
+		_, err = verifier.VerifyPresentation(presented)
+		require.EqualError(t, err, "receipt 0 must be a string")
+
+		presented.CustomFields[ReceiptsProperty] = []interface{}{base64.RawURLEncoding.EncodeToString([]byte("{}")), ""}
+		_, err = verifier.VerifyPresentation(presented)
+		require.EqualError(t, err, "receipt 0: receipt has no SCTs")
+
+		delete(presented.CustomFields, ReceiptsProperty)
+		_, err = verifier.VerifyPresentation(presented)
+		require.Contains(t, err.Error(), "parse credential 0")
+	})
+}
@@ -0,0 +1,394 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package slo measures the latency SLOs of a log from the side of its relying parties: the freshness of its tree
+// head, the latency of its proofs and, if the relying party submits credentials to it, the latency from the
+// submission of a credential to its inclusion in a tree head. The measurements are exported as metrics (e.g. to
+// Prometheus with the prometheus.MetricFactory of Trillian), labeled with the name of the log.
+package slo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// Probes of the log, the probe label of the metrics.
+const (
+	ProbeTreeHead  = "tree_head"
+	ProbeProof     = "proof"
+	ProbeInclusion = "inclusion"
+)
+
+const (
+	defaultInterval         = time.Minute
+	defaultInclusionTimeout = 5 * time.Minute
+	defaultPollInterval     = time.Second
+)
+
+// ErrNotIncluded is returned when a credential submitted is not included in a tree head within the timeout.
+var ErrNotIncluded = errors.New("credential is not included")
+
+// nolint: gochecknoglobals
+var (
+	once             sync.Once
+	probes           monitoring.Counter
+	probeFailures    monitoring.Counter
+	treeHeadLatency  monitoring.Histogram
+	treeHeadAge      monitoring.Gauge
+	treeSize         monitoring.Gauge
+	proofLatency     monitoring.Histogram
+	inclusionLatency monitoring.Histogram
+)
+
+func createMetrics(mf monitoring.MetricFactory) {
+	probes = mf.NewCounter("slo_probes", "Number of probes of the log", "log", "probe")
+	probeFailures = mf.NewCounter("slo_probe_failures", "Number of failed probes of the log", "log", "probe")
+	treeHeadLatency = mf.NewHistogram("slo_tree_head_latency",
+		"Latency of getting the tree head of the log in seconds", "log")
+	treeHeadAge = mf.NewGauge("slo_tree_head_age", "Age of the latest tree head of the log in seconds", "log")
+	treeSize = mf.NewGauge("slo_tree_size", "Size of the latest tree head of the log", "log")
+	proofLatency = mf.NewHistogram("slo_proof_latency",
+		"Latency of getting an inclusion proof from the log in seconds", "log")
+	inclusionLatency = mf.NewHistogram("slo_inclusion_latency",
+		"Latency from the submission of a credential to its inclusion in a tree head in seconds", "log")
+}
+
+// NewCredential returns a new credential to submit to the log, e.g. a test credential of an issuer the log accepts.
+type NewCredential func(ctx context.Context) (*verifiable.Credential, error)
+
+type options struct {
+	interval           time.Duration
+	newCredential      NewCredential
+	submissionInterval time.Duration
+	inclusionTimeout   time.Duration
+	pollInterval       time.Duration
+	addVC              []vct.AddVCOpt
+	mf                 monitoring.MetricFactory
+}
+
+// Opt represents prober option func.
+type Opt func(*options)
+
+// WithInterval sets the interval of the probes of the tree head and of the proofs (1m by default).
+func WithInterval(interval time.Duration) Opt {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithSubmissions enables the probes of the inclusion: a new credential is submitted to the log every interval
+// (the interval of the probes by default), then its inclusion proof is polled until it is included in a tree head.
+func WithSubmissions(newCredential NewCredential, interval time.Duration) Opt {
+	return func(o *options) {
+		o.newCredential = newCredential
+		o.submissionInterval = interval
+	}
+}
+
+// WithInclusionTimeout sets the max time a credential submitted takes to be included (5m by default, e.g. the MMD
+// of the log) and the interval its inclusion proof is polled at (1s by default).
+func WithInclusionTimeout(timeout, pollInterval time.Duration) Opt {
+	return func(o *options) {
+		o.inclusionTimeout = timeout
+		o.pollInterval = pollInterval
+	}
+}
+
+// WithAddVCOpts sets the options of the submissions (e.g. vct.WithTenant).
+func WithAddVCOpts(opts ...vct.AddVCOpt) Opt {
+	return func(o *options) {
+		o.addVC = opts
+	}
+}
+
+// WithMetricFactory sets the factory of the metrics.
+func WithMetricFactory(mf monitoring.MetricFactory) Opt {
+	return func(o *options) {
+		o.mf = mf
+	}
+}
+
+// Measurement is the outcome of a probe of the log.
+type Measurement struct {
+	Probe string
+	// Latency is the latency of the tree head, of the proof or from the submission to the inclusion.
+	Latency time.Duration
+	// TreeHead is the tree head of the log the probe got.
+	TreeHead *command.GetSTHResponse
+	// Age is the age of the tree head (tree_head probe).
+	Age time.Duration
+	// Skipped is true if there was nothing to probe, e.g. the proofs of an empty tree.
+	Skipped bool
+	Err     error
+}
+
+// Prober probes a log periodically and exports the measurements as metrics.
+type Prober struct {
+	name               string
+	log                *vct.Client
+	interval           time.Duration
+	newCredential      NewCredential
+	submissionInterval time.Duration
+	inclusionTimeout   time.Duration
+	pollInterval       time.Duration
+	addVC              []vct.AddVCOpt
+}
+
+// New returns a prober of the log, the metrics are labeled with the name of the log. The client should not cache
+// the tree heads (see vct.WithSTHCache), the age of a cached tree head would be measured.
+func New(name string, log *vct.Client, opts ...Opt) *Prober {
+	op := &options{
+		interval:         defaultInterval,
+		inclusionTimeout: defaultInclusionTimeout,
+		pollInterval:     defaultPollInterval,
+	}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if op.submissionInterval <= 0 {
+		op.submissionInterval = op.interval
+	}
+
+	if op.mf == nil {
+		op.mf = monitoring.InertMetricFactory{}
+	}
+
+	once.Do(func() { createMetrics(op.mf) })
+
+	return &Prober{
+		name:               name,
+		log:                log,
+		interval:           op.interval,
+		newCredential:      op.newCredential,
+		submissionInterval: op.submissionInterval,
+		inclusionTimeout:   op.inclusionTimeout,
+		pollInterval:       op.pollInterval,
+		addVC:              op.addVC,
+	}
+}
+
+// Run probes the log until the context is done: the tree head and the proofs every interval, the inclusion every
+// submission interval if the submissions are enabled. An inclusion probe does not delay the other probes.
+func (p *Prober) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	if p.newCredential != nil {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			run(ctx, p.submissionInterval, func() { p.ProbeInclusion(ctx) })
+		}()
+	}
+
+	run(ctx, p.interval, func() {
+		if m := p.ProbeTreeHead(ctx); m.Err == nil {
+			p.probeProof(ctx, m.TreeHead)
+		}
+	})
+
+	wg.Wait()
+}
+
+// run calls the probe now and every interval until the context is done.
+func run(ctx context.Context, interval time.Duration, probe func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		probe()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProbeTreeHead gets the tree head of the log and measures its latency and its age.
+func (p *Prober) ProbeTreeHead(ctx context.Context) Measurement {
+	m := Measurement{Probe: ProbeTreeHead}
+
+	start := time.Now()
+
+	m.TreeHead, m.Err = p.log.GetSTH(ctx)
+	if m.Err != nil {
+		return p.observe(m)
+	}
+
+	m.Latency = time.Now().Sub(start)
+	m.Age = time.Now().Sub(time.Unix(0, int64(m.TreeHead.Timestamp)*int64(time.Millisecond)))
+
+	treeHeadLatency.Observe(m.Latency.Seconds(), p.name)
+	treeHeadAge.Set(m.Age.Seconds(), p.name)
+	treeSize.Set(float64(m.TreeHead.TreeSize), p.name)
+
+	return p.observe(m)
+}
+
+// ProbeProof gets the latest tree head of the log and measures the latency of the inclusion proof of its latest
+// leaf (get-entry-and-proof).
+func (p *Prober) ProbeProof(ctx context.Context) Measurement {
+	sth, err := p.log.GetSTH(ctx)
+	if err != nil {
+		return p.observe(Measurement{Probe: ProbeProof, Err: err})
+	}
+
+	return p.probeProof(ctx, sth)
+}
+
+// probeProof measures the latency of the inclusion proof of the latest leaf of the tree head, skipped if the tree
+// is empty.
+func (p *Prober) probeProof(ctx context.Context, sth *command.GetSTHResponse) Measurement {
+	m := Measurement{Probe: ProbeProof, TreeHead: sth}
+
+	if sth.TreeSize == 0 {
+		m.Skipped = true
+
+		return m
+	}
+
+	start := time.Now()
+
+	if _, m.Err = p.log.GetEntryAndProof(ctx, sth.TreeSize-1, sth.TreeSize); m.Err != nil {
+		return p.observe(m)
+	}
+
+	m.Latency = time.Now().Sub(start)
+
+	proofLatency.Observe(m.Latency.Seconds(), p.name)
+
+	return p.observe(m)
+}
+
+// ProbeInclusion submits a new credential to the log and measures the latency until it is included in a tree
+// head, its inclusion proof is polled until the inclusion timeout. It is skipped if the submissions are not enabled.
+func (p *Prober) ProbeInclusion(ctx context.Context) Measurement {
+	m := Measurement{Probe: ProbeInclusion}
+
+	if p.newCredential == nil {
+		m.Skipped = true
+
+		return m
+	}
+
+	vc, err := p.newCredential(ctx)
+	if err != nil {
+		m.Err = fmt.Errorf("new credential: %w", err)
+
+		return p.observe(m)
+	}
+
+	credential, err := json.Marshal(vc)
+	if err != nil {
+		m.Err = fmt.Errorf("marshal credential: %w", err)
+
+		return p.observe(m)
+	}
+
+	start := time.Now()
+
+	sct, err := p.log.AddVC(ctx, credential, p.addVC...)
+	if err != nil {
+		m.Err = err
+
+		return p.observe(m)
+	}
+
+	hash, err := p.leafHash(sct, vc)
+	if err != nil {
+		m.Err = err
+
+		return p.observe(m)
+	}
+
+	m.TreeHead, m.Err = p.waitForInclusion(ctx, hash)
+	if m.Err != nil {
+		return p.observe(m)
+	}
+
+	m.Latency = time.Now().Sub(start)
+
+	inclusionLatency.Observe(m.Latency.Seconds(), p.name)
+
+	return p.observe(m)
+}
+
+// leafHash returns the leaf hash of the credential logged with the SCT.
+func (p *Prober) leafHash(sct *command.AddVCResponse, vc *verifiable.Credential) (string, error) {
+	var leafOpts []vct.LeafOpt
+
+	if sct.Extensions != "" {
+		extensions, err := base64.StdEncoding.DecodeString(sct.Extensions)
+		if err != nil {
+			return "", fmt.Errorf("decode extensions: %w", err)
+		}
+
+		leafOpts = append(leafOpts, vct.WithExtensions(extensions))
+	}
+
+	hash, err := p.log.CalculateLeafHash(sct.Timestamp, vc, leafOpts...)
+	if err != nil {
+		return "", fmt.Errorf("calculate leaf hash: %w", err)
+	}
+
+	return hash, nil
+}
+
+// waitForInclusion polls the inclusion proof of the leaf in the latest tree head until it is found, the tree head
+// is returned.
+func (p *Prober) waitForInclusion(ctx context.Context, hash string) (*command.GetSTHResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.inclusionTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	var err error
+
+	for {
+		var sth *command.GetSTHResponse
+
+		sth, err = p.log.GetSTH(ctx)
+		if err == nil {
+			if _, err = p.log.GetProofByHash(ctx, hash, sth.TreeSize); err == nil {
+				return sth, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w within %s: %v", ErrNotIncluded, p.inclusionTimeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// observe counts the probe and its failure.
+func (p *Prober) observe(m Measurement) Measurement {
+	probes.Inc(p.name, m.Probe)
+
+	if m.Err != nil {
+		probeFailures.Inc(p.name, m.Probe)
+	}
+
+	return m
+}
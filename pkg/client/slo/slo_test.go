@@ -0,0 +1,231 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package slo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/client/slo"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const sctTimestamp = 1619006293939
+
+// log is a log server including the credentials submitted once their inclusion proof is polled a number of times.
+type log struct {
+	t        *testing.T
+	age      time.Duration
+	treeSize uint64
+	polls    int
+	fail     bool
+
+	mu       sync.Mutex
+	leafHash string
+	requests map[string]int
+}
+
+func newLog(t *testing.T, l *log) *vct.Client {
+	t.Helper()
+
+	l.t = t
+	l.requests = map[string]int{}
+
+	server := httptest.NewServer(l)
+	t.Cleanup(server.Close)
+
+	return vct.New(server.URL + "/maple2021")
+}
+
+func (l *log) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requests[r.URL.Path]++
+
+	if l.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		return
+	}
+
+	var response interface{}
+
+	switch r.URL.Path {
+	case "/maple2021/v1/get-sth":
+		response = command.GetSTHResponse{
+			TreeSize:  l.treeSize,
+			Timestamp: uint64(time.Now().Add(-l.age).UnixNano() / int64(time.Millisecond)),
+		}
+	case "/maple2021/v1/get-entry-and-proof":
+		require.Equal(l.t, "41", r.URL.Query().Get("leaf_index"))
+		require.Equal(l.t, "42", r.URL.Query().Get("tree_size"))
+
+		response = command.GetEntryAndProofResponse{}
+	case "/maple2021/v1/add-vc":
+		response = command.AddVCResponse{Timestamp: sctTimestamp}
+	case "/maple2021/v1/get-proof-by-hash":
+		require.Equal(l.t, l.leafHash, r.URL.Query().Get("hash"))
+
+		if l.requests[r.URL.Path] <= l.polls {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		response = command.GetProofByHashResponse{LeafIndex: int64(l.treeSize)}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	require.NoError(l.t, json.NewEncoder(w).Encode(response))
+}
+
+func (l *log) count(path string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.requests[path]
+}
+
+func newCredential(t *testing.T, l *log) NewCredential {
+	t.Helper()
+
+	vc := &verifiable.Credential{
+		Context: []string{"https://www.w3.org/2018/credentials/v1"},
+		Types:   []string{"VerifiableCredential"},
+		ID:      "http://example.edu/credentials/1872",
+		Issuer:  verifiable.Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"},
+		Subject: "did:example:ebfeb1f712ebc6f1c276e12ec21",
+	}
+
+	hash, err := vct.CalculateLeafHash(sctTimestamp, vc)
+	require.NoError(t, err)
+
+	l.leafHash = hash
+
+	return func(context.Context) (*verifiable.Credential, error) {
+		return vc, nil
+	}
+}
+
+func TestProber_ProbeTreeHead(t *testing.T) {
+	l := &log{age: time.Minute, treeSize: 42}
+
+	m := New("maple2021", newLog(t, l)).ProbeTreeHead(context.Background())
+	require.NoError(t, m.Err)
+	require.Equal(t, ProbeTreeHead, m.Probe)
+	require.Equal(t, uint64(42), m.TreeHead.TreeSize)
+	require.InDelta(t, time.Minute.Seconds(), m.Age.Seconds(), 5)
+	require.Positive(t, m.Latency)
+
+	l.fail = true
+
+	m = New("maple2021", newLog(t, l)).ProbeTreeHead(context.Background())
+	require.Error(t, m.Err)
+	require.Nil(t, m.TreeHead)
+}
+
+func TestProber_ProbeProof(t *testing.T) {
+	l := &log{treeSize: 42}
+
+	m := New("maple2021", newLog(t, l)).ProbeProof(context.Background())
+	require.NoError(t, m.Err)
+	require.Equal(t, ProbeProof, m.Probe)
+	require.Positive(t, m.Latency)
+	require.Equal(t, 1, l.count("/maple2021/v1/get-entry-and-proof"))
+
+	t.Run("Empty tree", func(t *testing.T) {
+		empty := &log{}
+
+		m = New("maple2021", newLog(t, empty)).ProbeProof(context.Background())
+		require.NoError(t, m.Err)
+		require.True(t, m.Skipped)
+		require.Zero(t, empty.count("/maple2021/v1/get-entry-and-proof"))
+	})
+}
+
+func TestProber_ProbeInclusion(t *testing.T) {
+	t.Run("Included", func(t *testing.T) {
+		l := &log{treeSize: 42, polls: 2}
+		client := newLog(t, l)
+
+		m := New("maple2021", client, WithSubmissions(newCredential(t, l), 0),
+			WithInclusionTimeout(time.Minute, 10*time.Millisecond)).ProbeInclusion(context.Background())
+		require.NoError(t, m.Err)
+		require.Equal(t, ProbeInclusion, m.Probe)
+		require.GreaterOrEqual(t, m.Latency, 20*time.Millisecond)
+		require.Equal(t, uint64(42), m.TreeHead.TreeSize)
+		require.Equal(t, 1, l.count("/maple2021/v1/add-vc"))
+		require.Equal(t, 3, l.count("/maple2021/v1/get-proof-by-hash"))
+	})
+
+	t.Run("Not included", func(t *testing.T) {
+		l := &log{treeSize: 42, polls: 1000}
+
+		m := New("maple2021", newLog(t, l), WithSubmissions(newCredential(t, l), 0),
+			WithInclusionTimeout(50*time.Millisecond, 10*time.Millisecond)).ProbeInclusion(context.Background())
+		require.ErrorIs(t, m.Err, ErrNotIncluded)
+		require.Contains(t, m.Err.Error(), "within 50ms")
+	})
+
+	t.Run("New credential fails", func(t *testing.T) {
+		l := &log{}
+
+		m := New("maple2021", newLog(t, l), WithSubmissions(func(context.Context) (*verifiable.Credential, error) {
+			return nil, errors.New("issuer is down")
+		}, 0)).ProbeInclusion(context.Background())
+		require.EqualError(t, m.Err, "new credential: issuer is down")
+		require.Zero(t, l.count("/maple2021/v1/add-vc"))
+	})
+
+	t.Run("Submissions are not enabled", func(t *testing.T) {
+		m := New("maple2021", newLog(t, &log{})).ProbeInclusion(context.Background())
+		require.NoError(t, m.Err)
+		require.True(t, m.Skipped)
+	})
+}
+
+func TestProber_Run(t *testing.T) {
+	l := &log{treeSize: 42}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	prober := New("maple2021", newLog(t, l), WithInterval(10*time.Millisecond),
+		WithSubmissions(newCredential(t, l), 10*time.Millisecond), WithInclusionTimeout(time.Second, 10*time.Millisecond))
+
+	done := make(chan struct{})
+
+	go func() {
+		prober.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return l.count("/maple2021/v1/get-entry-and-proof") >= 3 && l.count("/maple2021/v1/add-vc") >= 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("prober did not stop")
+	}
+}
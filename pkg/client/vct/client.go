@@ -9,31 +9,49 @@ package vct
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/google/trillian/merkle/rfc6962/hasher"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	jsonld "github.com/piprate/json-gold/ld"
+
+	"golang.org/x/sync/singleflight"
 
 	"github.com/trustbloc/vct/pkg/controller/command"
+	vcterrors "github.com/trustbloc/vct/pkg/controller/errors"
 	"github.com/trustbloc/vct/pkg/controller/rest"
+	"github.com/trustbloc/vct/pkg/smt"
 )
 
 type clientOptions struct {
 	http           HTTPClient
 	authReadToken  string
 	authWriteToken string
+	basePath       *string
+	dialer         Dialer
+	hasher         Hasher
+	verifier       Verifier
+	hedgeDelay     time.Duration
+	mirrors        []string
+	apiVersion     string
+	final          *command.SignedFinalTreeHead
+	observer       Observer
+	sthCacheTTL    time.Duration
+	retiredShards  []command.RetiredShard // nil unless the archive is set
 }
 
 // ClientOpt represents client option func.
@@ -60,6 +78,44 @@ func WithAuthWriteToken(authToken string) ClientOpt {
 	}
 }
 
+// WithBasePath sets the path prefix under which the VCT server root is exposed
+// (e.g. "/transparency" when the server is published behind a reverse proxy).
+// Server-wide endpoints such as the health check are resolved against it.
+// By default, the prefix is derived from the endpoint by dropping its last path segment (the log alias).
+func WithBasePath(basePath string) ClientOpt {
+	return func(o *clientOptions) {
+		o.basePath = &basePath
+	}
+}
+
+// WithAPIVersion sets the version of the API the operations of the log are called with (rest.APIVersion1 by
+// default). The v2 API reports the errors as typed problem details only (rest.ErrorResponseV2).
+func WithAPIVersion(version string) ClientOpt {
+	return func(o *clientOptions) {
+		o.apiVersion = version
+	}
+}
+
+// WithDialer allows providing a custom dialer for the default HTTP client,
+// e.g. UnixSocketDialer to reach a VCT server listening on a Unix domain socket.
+// The dialer is ignored when an HTTP client is provided by WithHTTPClient.
+func WithDialer(dialer Dialer) ClientOpt {
+	return func(o *clientOptions) {
+		o.dialer = dialer
+	}
+}
+
+// Dialer dials a connection to the given address.
+type Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// UnixSocketDialer returns a dialer which connects to the given Unix domain socket regardless of the requested
+// address. The host part of the endpoint passed to New is then used only for the Host header.
+func UnixSocketDialer(socketPath string) Dialer {
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+	}
+}
+
 // HTTPClient represents HTTP client.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -68,33 +124,167 @@ type HTTPClient interface {
 // Client represents VCT REST client.
 type Client struct {
 	endpoint       string
+	basePath       string
 	http           HTTPClient
 	authReadToken  string
 	authWriteToken string
+	hasher         Hasher
+	verifier       Verifier
+	hedgeDelay     time.Duration
+	endpoints      []logEndpoint // the primary and the mirrors
+	apiVersion     string
+	observer       Observer
+	sthCacheTTL    time.Duration
+
+	sthGroup   singleflight.Group
+	sthMu      sync.Mutex
+	sth        *command.GetSTHResponse // the latest tree head fetched
+	sthFetched time.Time
+
+	healthMu       sync.Mutex
+	unhealthyUntil []time.Time // of the endpoints
+
+	tilesMu      sync.Mutex
+	tilesChecked bool
+	tiles        *Client // nil if the log does not advertise tiles
+
+	finalMu sync.Mutex
+	final   *command.SignedFinalTreeHead // nil unless the log is known to be frozen
+
+	shardsMu     sync.Mutex
+	shards       []command.RetiredShard // the archive of the retired shards
+	shardsPinned bool                   // true if the archive is set with WithRetiredShards
 }
 
 // New returns VCT REST client.
 func New(endpoint string, opts ...ClientOpt) *Client {
-	op := &clientOptions{http: &http.Client{
-		Timeout: time.Minute,
-	}}
+	op := &clientOptions{}
 
 	for _, fn := range opts {
 		fn(op)
 	}
 
+	if op.http == nil {
+		client := &http.Client{Timeout: time.Minute}
+
+		if op.dialer != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone() // nolint: forcetypeassert
+			transport.DialContext = op.dialer
+			client.Transport = transport
+		}
+
+		op.http = client
+	}
+
+	endpoints := []logEndpoint{newLogEndpoint(endpoint)}
+
+	for _, mirror := range op.mirrors {
+		endpoints = append(endpoints, newLogEndpoint(mirror))
+	}
+
+	basePath := parentPath(endpoints[0].path)
+	if op.basePath != nil {
+		basePath = *op.basePath
+	}
+
+	if op.hasher == nil {
+		op.hasher = DefaultHasher
+	}
+
+	if op.verifier == nil {
+		op.verifier = NewVerifier(op.hasher)
+	}
+
 	return &Client{
 		endpoint:       endpoint,
+		basePath:       basePath,
 		http:           op.http,
 		authReadToken:  op.authReadToken,
 		authWriteToken: op.authWriteToken,
+		hasher:         op.hasher,
+		verifier:       op.verifier,
+		hedgeDelay:     op.hedgeDelay,
+		endpoints:      endpoints,
+		unhealthyUntil: make([]time.Time, len(endpoints)),
+		apiVersion:     op.apiVersion,
+		final:          op.final,
+		observer:       op.observer,
+		sthCacheTTL:    op.sthCacheTTL,
+		shards:         op.retiredShards,
+		shardsPinned:   op.retiredShards != nil,
+	}
+}
+
+// AddVCOpt represents add-vc submission option func.
+type AddVCOpt func(*command.AddVCOptions)
+
+// WithTenant sets the tenant the submission is intended for.
+func WithTenant(tenant string) AddVCOpt {
+	return func(o *command.AddVCOptions) {
+		o.Tenant = tenant
+	}
+}
+
+// WithDryRun validates the credential without adding it to the log.
+func WithDryRun() AddVCOpt {
+	return func(o *command.AddVCOptions) {
+		o.DryRun = true
+	}
+}
+
+// WithCallbackURL sets the URL to be notified once the credential is queued.
+func WithCallbackURL(callbackURL string) AddVCOpt {
+	return func(o *command.AddVCOptions) {
+		o.CallbackURL = callbackURL
+	}
+}
+
+// WithIdempotencyKey sets the key used to deduplicate submissions.
+func WithIdempotencyKey(key string) AddVCOpt {
+	return func(o *command.AddVCOptions) {
+		o.IdempotencyKey = key
+	}
+}
+
+// WithSupersedes marks the submission as re-issuance of the logged credential with the given merkle leaf hash.
+func WithSupersedes(leafHash []byte) AddVCOpt {
+	return func(o *command.AddVCOptions) {
+		o.Supersedes = leafHash
 	}
 }
 
 // AddVC adds verifiable credential to log.
-func (c *Client) AddVC(ctx context.Context, credential []byte) (*command.AddVCResponse, error) {
+// If any option is provided, the credential is sent as an enveloped submission.
+func (c *Client) AddVC(ctx context.Context, credential []byte, opts ...AddVCOpt) (*command.AddVCResponse, error) {
+	body := credential
+
+	if len(opts) > 0 {
+		options := &command.AddVCOptions{}
+		for _, fn := range opts {
+			fn(options)
+		}
+
+		raw := json.RawMessage(credential)
+		if !json.Valid(credential) {
+			// JWT credentials are enveloped as JSON strings.
+			jwt, err := json.Marshal(string(credential))
+			if err != nil {
+				return nil, fmt.Errorf("marshal credential: %w", err)
+			}
+
+			raw = jwt
+		}
+
+		var err error
+
+		body, err = json.Marshal(command.AddVCEnvelope{Credential: raw, Options: options})
+		if err != nil {
+			return nil, fmt.Errorf("marshal envelope: %w", err)
+		}
+	}
+
 	var result *command.AddVCResponse
-	if err := c.do(ctx, rest.AddVCPath, &result, withMethod(http.MethodPost), withBody(credential),
+	if err := c.do(ctx, rest.AddVCPath, &result, withMethod(http.MethodPost), withBody(body),
 		withToken(c.authWriteToken)); err != nil {
 		return nil, fmt.Errorf("add VC: %w", err)
 	}
@@ -102,28 +292,295 @@ func (c *Client) AddVC(ctx context.Context, credential []byte) (*command.AddVCRe
 	return result, nil
 }
 
+// AddRevocation adds revocation event of a logged credential to log, the event must be signed by the issuer of the
+// credential (RevocationEvent.JWS).
+func (c *Client) AddRevocation(ctx context.Context, event *command.RevocationEvent) (*command.AddVCResponse, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal revocation event: %w", err)
+	}
+
+	var result *command.AddVCResponse
+	if err = c.do(ctx, rest.AddRevocationPath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("add revocation: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetRevocations retrieves revocation events of the credential by its leaf hash (base64),
+// all of them or a page of them if page options are given.
+func (c *Client) GetRevocations(ctx context.Context, leafHash string,
+	pageOpts ...PageOpt) (*command.GetRevocationsResponse, error) {
+	const leafHashParamName = "leaf_hash"
+
+	opts := append([]opt{
+		withValueAdd(leafHashParamName, leafHash),
+		withToken(c.authReadToken),
+	}, pageValues(pageRequest(pageOpts))...)
+
+	var result *command.GetRevocationsResponse
+	if err := c.do(ctx, rest.GetRevocationsPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get revocations: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetCredentialHistory retrieves the chain of re-issued credentials the credential with the given
+// base64 merkle leaf hash belongs to, oldest first.
+func (c *Client) GetCredentialHistory(ctx context.Context, leafHash string) (*command.GetCredentialHistoryResponse,
+	error) {
+	hash, err := base64.StdEncoding.DecodeString(leafHash)
+	if err != nil {
+		return nil, fmt.Errorf("decode leaf hash: %w", err)
+	}
+
+	path := strings.Replace(rest.GetCredentialHistoryPath, "{hash}", base64.URLEncoding.EncodeToString(hash), 1)
+
+	var result *command.GetCredentialHistoryResponse
+	if err = c.do(ctx, path, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get credential history: %w", err)
+	}
+
+	return result, nil
+}
+
+// AddEntry adds an entry of the given leaf type to log, see GetLeafTypes for the supported types.
+func (c *Client) AddEntry(ctx context.Context, entryType command.LogEntryType, entry []byte) (*command.AddVCResponse,
+	error) {
+	body, err := json.Marshal(command.AddEntryRequest{EntryType: entryType, Entry: entry})
+	if err != nil {
+		return nil, fmt.Errorf("marshal entry: %w", err)
+	}
+
+	var result *command.AddVCResponse
+	if err = c.do(ctx, rest.AddEntryPath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("add entry: %w", err)
+	}
+
+	return result, nil
+}
+
+// AddAnchor adds a signed tree head of another transparency log to log.
+func (c *Client) AddAnchor(ctx context.Context, anchor *command.STHAnchor) (*command.AddVCResponse, error) {
+	body, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("marshal anchor: %w", err)
+	}
+
+	var result *command.AddVCResponse
+	if err = c.do(ctx, rest.AddAnchorPath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("add anchor: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetAnchors retrieves anchored tree heads of the log by its ID (base64),
+// all of them or a page of them if page options are given.
+func (c *Client) GetAnchors(ctx context.Context, logID string,
+	pageOpts ...PageOpt) (*command.GetAnchorsResponse, error) {
+	const logIDParamName = "log_id"
+
+	opts := append([]opt{
+		withValueAdd(logIDParamName, logID),
+		withToken(c.authReadToken),
+	}, pageValues(pageRequest(pageOpts))...)
+
+	var result *command.GetAnchorsResponse
+	if err := c.do(ctx, rest.GetAnchorsPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get anchors: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetShadowStatus retrieves the outcome of mirroring writes of the log to its shadow log.
+func (c *Client) GetShadowStatus(ctx context.Context) (*command.GetShadowStatusResponse, error) {
+	var result *command.GetShadowStatusResponse
+	if err := c.do(ctx, rest.GetShadowStatusPath, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get shadow status: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetAuditExport retrieves the signed audit export of the entries added while the tree grew from the first to
+// the second size.
+func (c *Client) GetAuditExport(ctx context.Context, first, second uint64) (*command.GetAuditExportResponse, error) {
+	const (
+		firstParamName  = "first"
+		secondParamName = "second"
+	)
+
+	if err := c.checkTreeSize(second); err != nil {
+		return nil, fmt.Errorf("get audit export: %w", err)
+	}
+
+	opts := []opt{
+		withValueAdd(firstParamName, strconv.FormatUint(first, 10)),
+		withValueAdd(secondParamName, strconv.FormatUint(second, 10)),
+		withToken(c.authReadToken),
+	}
+
+	var result *command.GetAuditExportResponse
+	if err := c.do(ctx, rest.GetAuditExportPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get audit export: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetReadOnly retrieves the read-only (maintenance) mode of the service.
+func (c *Client) GetReadOnly(ctx context.Context) (bool, error) {
+	var result *command.ReadOnlyStatus
+	if err := c.do(ctx, rest.ReadOnlyPath, &result, withToken(c.authWriteToken)); err != nil {
+		return false, fmt.Errorf("get read-only: %w", err)
+	}
+
+	return result.ReadOnly, nil
+}
+
+// SetReadOnly enables or disables the read-only (maintenance) mode of the service.
+func (c *Client) SetReadOnly(ctx context.Context, readOnly bool) error {
+	body, err := json.Marshal(command.ReadOnlyStatus{ReadOnly: readOnly})
+	if err != nil {
+		return fmt.Errorf("marshal read-only status: %w", err)
+	}
+
+	var result *command.ReadOnlyStatus
+	if err = c.do(ctx, rest.ReadOnlyPath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return fmt.Errorf("set read-only: %w", err)
+	}
+
+	return nil
+}
+
+// GetLogStates retrieves the states of the logs of the service read from their Trillian trees.
+func (c *Client) GetLogStates(ctx context.Context) ([]command.LogState, error) {
+	var result *command.GetLogStatesResponse
+	if err := c.do(ctx, rest.LogStatePath, &result, withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("get log states: %w", err)
+	}
+
+	return result.States, nil
+}
+
+// SetLogState transitions the Trillian tree of the log to the state (e.g. command.LogStateDraining).
+func (c *Client) SetLogState(ctx context.Context, state string) (*command.LogState, error) {
+	body, err := json.Marshal(command.SetLogStateRequest{Alias: c.alias(), State: state})
+	if err != nil {
+		return nil, fmt.Errorf("marshal set log state request: %w", err)
+	}
+
+	var result *command.LogState
+	if err = c.do(ctx, rest.LogStatePath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("set log state: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetKeyUsage retrieves the counts of the signatures produced with the key of the log.
+func (c *Client) GetKeyUsage(ctx context.Context) (*command.GetKeyUsageResponse, error) {
+	var result *command.GetKeyUsageResponse
+	if err := c.do(ctx, rest.KeyUsagePath, &result, withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("get key usage: %w", err)
+	}
+
+	return result, nil
+}
+
+// MarkCompromised marks the key of the log compromised with a compromise statement signed by the recovery key
+// of the log, writes of the log are frozen for good.
+func (c *Client) MarkCompromised(ctx context.Context,
+	statement *command.SignedCompromiseStatement) (*command.SignedCompromiseStatement, error) {
+	body, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("marshal compromise statement: %w", err)
+	}
+
+	var result *command.SignedCompromiseStatement
+	if err = c.do(ctx, rest.CompromisePath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("mark compromised: %w", err)
+	}
+
+	return result, nil
+}
+
+// AddAnnotation attaches the annotation signed by an auditor to an entry of the log.
+func (c *Client) AddAnnotation(ctx context.Context,
+	annotation *command.SignedAnnotation) (*command.SignedAnnotation, error) {
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("marshal annotation: %w", err)
+	}
+
+	var result *command.SignedAnnotation
+	if err = c.do(ctx, rest.AddAnnotationPath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("add annotation: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetAnnotations retrieves the annotations of the entry with the leaf index, the annotations are verified with
+// VerifyAnnotation.
+func (c *Client) GetAnnotations(ctx context.Context, leafIndex int64) (*command.GetAnnotationsResponse, error) {
+	const leafIndexParamName = "leaf_index"
+
+	var result *command.GetAnnotationsResponse
+	if err := c.do(ctx, rest.GetAnnotationsPath, &result,
+		withValueAdd(leafIndexParamName, strconv.FormatInt(leafIndex, 10)),
+		withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get annotations: %w", err)
+	}
+
+	return result, nil
+}
+
+// VerifyAnnotation verifies that the annotation is signed by the key of its auditor.
+func VerifyAnnotation(signed *command.SignedAnnotation, auditorKey []byte) error {
+	if signed.Annotation.SignatureType != command.AnnotationSignatureType {
+		return errors.New("statement must be an annotation")
+	}
+
+	if err := command.VerifySignature(signed.Signature, auditorKey, signed.Annotation); err != nil {
+		return fmt.Errorf("annotation: %w", err)
+	}
+
+	return nil
+}
+
 // HealthCheck check health.
 func (c *Client) HealthCheck(ctx context.Context) error {
-	parseURL, err := url.Parse(c.endpoint)
+	healthCheckURL, err := buildURL(c.endpoint, joinPath(c.basePath, rest.HealthCheckPath), nil)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		parseURL.Scheme+"://"+parseURL.Host+rest.HealthCheckPath, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthCheckURL, nil)
 	if err != nil {
 		return fmt.Errorf("new request with context: %w", err)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.send(c.endpoints[0], rest.HealthCheckPath, req)
 	if err != nil {
-		return fmt.Errorf("http do: %w", err)
+		return err
 	}
 
 	defer resp.Body.Close() // nolint: errcheck
 
 	if resp.StatusCode != http.StatusOK {
-		return getError(resp.Body)
+		return getError(resp)
 	}
 
 	return nil
@@ -139,6 +596,121 @@ func (c *Client) Webfinger(ctx context.Context) (*command.WebFingerResponse, err
 	return result, nil
 }
 
+// GetLeafTypes returns leaf types supported by the log as published in the webfinger metadata.
+func (c *Client) GetLeafTypes(ctx context.Context) ([]command.LeafTypeMetadata, error) {
+	resp, err := c.Webfinger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// servers predating the leaf type registry support credentials only
+	raw, ok := resp.Properties[command.LeafTypesType]
+	if !ok {
+		return []command.LeafTypeMetadata{{EntryType: command.VCLogEntryType, Name: "vc"}}, nil
+	}
+
+	src, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("marshal leaf types: %w", err)
+	}
+
+	var types []command.LeafTypeMetadata
+	if err = json.Unmarshal(src, &types); err != nil {
+		return nil, fmt.Errorf("unmarshal leaf types: %w", err)
+	}
+
+	return types, nil
+}
+
+// GetCompromise returns the compromise statement of the log as published in the webfinger metadata,
+// it is nil unless the key of the log is marked compromised.
+func (c *Client) GetCompromise(ctx context.Context) (*command.SignedCompromiseStatement, error) {
+	resp, err := c.Webfinger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return getCompromise(resp)
+}
+
+func getCompromise(resp *command.WebFingerResponse) (*command.SignedCompromiseStatement, error) {
+	raw, ok := resp.Properties[command.CompromiseType]
+	if !ok {
+		return nil, nil
+	}
+
+	src, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("marshal compromise statement: %w", err)
+	}
+
+	var statement *command.SignedCompromiseStatement
+	if err = json.Unmarshal(src, &statement); err != nil {
+		return nil, fmt.Errorf("unmarshal compromise statement: %w", err)
+	}
+
+	return statement, nil
+}
+
+// VerifyCompromiseStatement verifies that the compromise statement is signed by the recovery key of the log.
+func VerifyCompromiseStatement(signed *command.SignedCompromiseStatement, recoveryKey []byte) error {
+	if signed.Statement.SignatureType != command.CompromiseSignatureType {
+		return errors.New("statement must be a compromise statement")
+	}
+
+	if err := command.VerifySignature(signed.Signature, recoveryKey, signed.Statement); err != nil {
+		return fmt.Errorf("compromise statement: %w", err)
+	}
+
+	return nil
+}
+
+// GetPublicKey returns the public key of the log as published in the webfinger metadata.
+// The log ID is derived from the public key (command.LogID), a published log ID must match it.
+// ErrLogCompromised is returned if the log publishes a compromise statement: SCTs and tree heads signed with
+// the key can't be trusted anymore, the statement may be checked with GetCompromise and VerifyCompromiseStatement.
+func (c *Client) GetPublicKey(ctx context.Context) ([]byte, error) {
+	resp, err := c.Webfinger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return publicKey(resp)
+}
+
+// publicKey returns the public key published in the webfinger metadata.
+func publicKey(resp *command.WebFingerResponse) ([]byte, error) {
+	compromise, err := getCompromise(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if compromise != nil {
+		return nil, fmt.Errorf("%w since %d", ErrLogCompromised, compromise.Statement.CompromisedSince)
+	}
+
+	encoded, ok := resp.Properties[command.PublicKeyType].(string)
+	if !ok {
+		return nil, errors.New("webfinger has no public key")
+	}
+
+	pubKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+
+	// servers predating the log ID property publish the public key only
+	if published, ok := resp.Properties[command.LogIDType].(string); ok {
+		logID := command.LogID(pubKey)
+
+		if published != base64.StdEncoding.EncodeToString(logID[:]) {
+			return nil, fmt.Errorf("log ID %s does not match the public key", published)
+		}
+	}
+
+	return pubKey, nil
+}
+
 // GetIssuers returns issuers.
 func (c *Client) GetIssuers(ctx context.Context) ([]string, error) {
 	var result []string
@@ -149,10 +721,15 @@ func (c *Client) GetIssuers(ctx context.Context) ([]string, error) {
 	return result, nil
 }
 
-// GetSTH retrieves latest signed tree head.
+// GetSTH retrieves latest signed tree head, ErrLogFrozen is returned if the log is known to be frozen (see
+// GetFinalTreeHead) and the tree head is not its final tree head.
 func (c *Client) GetSTH(ctx context.Context) (*command.GetSTHResponse, error) {
-	var result *command.GetSTHResponse
-	if err := c.do(ctx, rest.GetSTHPath, &result, withToken(c.authReadToken)); err != nil {
+	result, err := c.getSTH(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get STH: %w", err)
+	}
+
+	if err := c.checkTreeHead(result); err != nil {
 		return nil, fmt.Errorf("get STH: %w", err)
 	}
 
@@ -166,6 +743,10 @@ func (c *Client) GetSTHConsistency(ctx context.Context, first, second uint64) (*
 		secondParamName = "second"
 	)
 
+	if err := c.checkTreeSize(second); err != nil {
+		return nil, fmt.Errorf("get STH consistency: %w", err)
+	}
+
 	opts := []opt{
 		withValueAdd(firstParamName, strconv.FormatUint(first, 10)),
 		withValueAdd(secondParamName, strconv.FormatUint(second, 10)),
@@ -187,6 +768,10 @@ func (c *Client) GetProofByHash(ctx context.Context, hash string, treeSize uint6
 		treeSizeParamName = "tree_size"
 	)
 
+	if err := c.checkTreeSize(treeSize); err != nil {
+		return nil, fmt.Errorf("get proof by hash: %w", err)
+	}
+
 	opts := []opt{
 		withValueAdd(hashParamName, hash),
 		withValueAdd(treeSizeParamName, strconv.FormatUint(treeSize, 10)),
@@ -194,15 +779,16 @@ func (c *Client) GetProofByHash(ctx context.Context, hash string, treeSize uint6
 	}
 
 	var result *command.GetProofByHashResponse
-	if err := c.do(ctx, rest.GetProofByHashPath, &result, opts...); err != nil {
+	if err := c.doHedged(ctx, rest.GetProofByHashPath, &result, opts...); err != nil {
 		return nil, fmt.Errorf("get proof by hash: %w", err)
 	}
 
 	return result, nil
 }
 
-// GetEntries retrieves entries from log.
-func (c *Client) GetEntries(ctx context.Context, start, end uint64) (*command.GetEntriesResponse, error) {
+// GetEntries retrieves entries from log, the entries of the range matching the filters if any (see TagFilter).
+func (c *Client) GetEntries(ctx context.Context, start, end uint64,
+	filters ...TagFilter) (*command.GetEntriesResponse, error) {
 	const (
 		startParamName = "start"
 		endParamName   = "end"
@@ -214,6 +800,8 @@ func (c *Client) GetEntries(ctx context.Context, start, end uint64) (*command.Ge
 		withToken(c.authReadToken),
 	}
 
+	opts = append(opts, tagValues(filters)...)
+
 	var result *command.GetEntriesResponse
 	if err := c.do(ctx, rest.GetEntriesPath, &result, opts...); err != nil {
 		return nil, fmt.Errorf("get entries: %w", err)
@@ -222,6 +810,148 @@ func (c *Client) GetEntries(ctx context.Context, start, end uint64) (*command.Ge
 	return result, nil
 }
 
+// GetEntriesByTime retrieves entries sequenced within the given time range (bounds are inclusive), matching the
+// filters if any. A zero time means the range is not bounded on that side. The response has the indexes of the
+// leaves of the range, the entries beyond the first 1000 are retrieved with GetEntries from the next index.
+func (c *Client) GetEntriesByTime(ctx context.Context, from, to time.Time,
+	filters ...TagFilter) (*command.GetEntriesResponse, error) {
+	const (
+		fromTimeParamName = "from_time"
+		toTimeParamName   = "to_time"
+	)
+
+	opts := []opt{withToken(c.authReadToken)}
+
+	if !from.IsZero() {
+		opts = append(opts, withValueAdd(fromTimeParamName, from.Format(time.RFC3339Nano)))
+	}
+
+	if !to.IsZero() {
+		opts = append(opts, withValueAdd(toTimeParamName, to.Format(time.RFC3339Nano)))
+	}
+
+	if from.IsZero() && to.IsZero() {
+		opts = append(opts, withValueAdd(fromTimeParamName, time.Unix(0, 0).UTC().Format(time.RFC3339Nano)))
+	}
+
+	opts = append(opts, tagValues(filters)...)
+
+	var result *command.GetEntriesResponse
+	if err := c.do(ctx, rest.GetEntriesPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get entries by time: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetReceipt retrieves the SCT issued for the credential submitted with the idempotency key (see
+// command.AddVCOptions), it recovers a lost SCT without resubmitting. The key must not contain a slash.
+func (c *Client) GetReceipt(ctx context.Context, idempotencyKey string) (*command.AddVCResponse, error) {
+	path := strings.Replace(rest.GetReceiptPath, "{key}", idempotencyKey, 1)
+
+	var result *command.AddVCResponse
+	if err := c.do(ctx, path, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get receipt: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetEntryByCID retrieves the first logged entry addressed by the CID (see command.EntryCID), the entry is checked
+// against the CID and the leaf, so it can be pinned under the CID as is.
+func (c *Client) GetEntryByCID(ctx context.Context, cid string) (*command.GetEntryByCIDResponse, error) {
+	digest, err := command.ParseEntryCID(cid)
+	if err != nil {
+		return nil, fmt.Errorf("get entry by CID: %w", err)
+	}
+
+	path := strings.Replace(rest.EntryByCIDPath, "{cid}", url.PathEscape(cid), 1)
+
+	var result *command.GetEntryByCIDResponse
+	if err = c.do(ctx, path, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get entry by CID: %w", err)
+	}
+
+	entryDigest := sha256.Sum256(result.Entry)
+	if !bytes.Equal(entryDigest[:], digest) {
+		return nil, fmt.Errorf("get entry by CID: entry does not match CID %s", cid)
+	}
+
+	var leaf command.MerkleTreeLeaf
+	if err = json.Unmarshal(result.LeafInput, &leaf); err != nil || leaf.TimestampedEntry == nil ||
+		!bytes.Equal(leaf.TimestampedEntry.VCEntry, result.Entry) {
+		return nil, fmt.Errorf("get entry by CID: entry is not the entry of the leaf")
+	}
+
+	return result, nil
+}
+
+// GetTile retrieves the hashes of a tile of the tree (see rest.TilePath), the width is zero for a full tile.
+// The tiles are static resources, a verifier may as well fetch them from a CDN in front of the log.
+func (c *Client) GetTile(ctx context.Context, level int, index uint64, width int) ([][]byte, error) {
+	// the layout of rest.TilePath
+	path := fmt.Sprintf("%s/tile/%d/%s", rest.AliasPath, level, rest.TileIndexPath(int64(index), width))
+
+	var tile []byte
+	if err := c.do(ctx, path, &tile, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get tile: %w", err)
+	}
+
+	if len(tile) != tileSize(width)*sha256.Size {
+		return nil, fmt.Errorf("get tile: tile of %d bytes does not hold %d hashes", len(tile), tileSize(width))
+	}
+
+	hashes := make([][]byte, 0, tileSize(width))
+
+	for i := 0; i < len(tile); i += sha256.Size {
+		hashes = append(hashes, tile[i:i+sha256.Size])
+	}
+
+	return hashes, nil
+}
+
+// GetEntryBundle retrieves the leaf values of the entries of a tile of the level 0 (see rest.EntryBundlePath),
+// the width is zero for a full bundle.
+func (c *Client) GetEntryBundle(ctx context.Context, index uint64, width int) ([][]byte, error) {
+	// the layout of rest.EntryBundlePath
+	path := fmt.Sprintf("%s/tile/entries/%s", rest.AliasPath, rest.TileIndexPath(int64(index), width))
+
+	var bundle []byte
+	if err := c.do(ctx, path, &bundle, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get entry bundle: %w", err)
+	}
+
+	entries := make([][]byte, 0, tileSize(width))
+
+	for len(bundle) > 0 {
+		if len(bundle) < 3 {
+			return nil, errors.New("get entry bundle: truncated entry size")
+		}
+
+		size := int(bundle[0])<<16 | int(bundle[1])<<8 | int(bundle[2])
+		if len(bundle) < 3+size {
+			return nil, errors.New("get entry bundle: truncated entry")
+		}
+
+		entries = append(entries, bundle[3:3+size])
+		bundle = bundle[3+size:]
+	}
+
+	if len(entries) != tileSize(width) {
+		return nil, fmt.Errorf("get entry bundle: got %d entries instead of %d", len(entries), tileSize(width))
+	}
+
+	return entries, nil
+}
+
+func tileSize(width int) int {
+	if width == 0 {
+		return command.TileWidth
+	}
+
+	return width
+}
+
 // GetEntryAndProof retrieves entry and merkle audit proof from log.
 func (c *Client) GetEntryAndProof(ctx context.Context, leafIndex, treeSize uint64) (*command.GetEntryAndProofResponse, error) { // nolint: lll
 	const (
@@ -229,6 +959,10 @@ func (c *Client) GetEntryAndProof(ctx context.Context, leafIndex, treeSize uint6
 		treeSizeParamName  = "tree_size"
 	)
 
+	if err := c.checkTreeSize(treeSize); err != nil {
+		return nil, fmt.Errorf("get entry and proof: %w", err)
+	}
+
 	opts := []opt{
 		withValueAdd(leafIndexParamName, strconv.FormatUint(leafIndex, 10)),
 		withValueAdd(treeSizeParamName, strconv.FormatUint(treeSize, 10)),
@@ -243,11 +977,96 @@ func (c *Client) GetEntryAndProof(ctx context.Context, leafIndex, treeSize uint6
 	return result, nil
 }
 
-// CalculateLeafHash calculates hash for given credentials.
-func CalculateLeafHash(timestamp uint64, vc *verifiable.Credential) (string, error) {
+// GetProofOfAbsence retrieves signed statement that no entry with the credential ID of the issuer was logged.
+func (c *Client) GetProofOfAbsence(ctx context.Context, issuer, credentialID string) (*command.GetProofOfAbsenceResponse, error) { // nolint: lll
+	const (
+		issuerParamName       = "issuer"
+		credentialIDParamName = "credential_id"
+	)
+
+	opts := []opt{
+		withValueAdd(issuerParamName, issuer),
+		withValueAdd(credentialIDParamName, credentialID),
+		withToken(c.authReadToken),
+	}
+
+	var result *command.GetProofOfAbsenceResponse
+	if err := c.do(ctx, rest.GetProofOfAbsencePath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get proof of absence: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetMapRoot retrieves the signed root of the map of credential IDs to their latest log entries.
+func (c *Client) GetMapRoot(ctx context.Context) (*command.GetMapRootResponse, error) {
+	var result *command.GetMapRootResponse
+	if err := c.do(ctx, rest.GetMapRootPath, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get map root: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetCredentialStatus retrieves the latest log entry of the credential of the issuer and its inclusion proof in the
+// signed map.
+func (c *Client) GetCredentialStatus(ctx context.Context, issuer, credentialID string) (*command.GetCredentialStatusResponse, error) { // nolint: lll
+	const (
+		issuerParamName       = "issuer"
+		credentialIDParamName = "credential_id"
+	)
+
+	opts := []opt{
+		withValueAdd(issuerParamName, issuer),
+		withValueAdd(credentialIDParamName, credentialID),
+		withToken(c.authReadToken),
+	}
+
+	var result *command.GetCredentialStatusResponse
+	if err := c.do(ctx, rest.GetCredentialStatusPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get credential status: %w", err)
+	}
+
+	return result, nil
+}
+
+// LeafOpt represents leaf option func.
+type LeafOpt func(*command.MerkleTreeLeaf)
+
+// WithExtensions sets the extensions of the leaf, as returned (decoded) in the add-vc response.
+func WithExtensions(extensions []byte) LeafOpt {
+	return func(leaf *command.MerkleTreeLeaf) {
+		leaf.TimestampedEntry.Extensions = extensions
+	}
+}
+
+func createLeaf(timestamp uint64, vc *verifiable.Credential, opts []LeafOpt) (*command.MerkleTreeLeaf, error) {
 	leaf, err := command.CreateLeaf(timestamp, vc)
 	if err != nil {
-		return "", fmt.Errorf("create leaf: %w", err)
+		return nil, fmt.Errorf("create leaf: %w", err)
+	}
+
+	for _, fn := range opts {
+		fn(leaf)
+	}
+
+	return leaf, nil
+}
+
+// CalculateLeafHash calculates hash for given credentials with DefaultHasher.
+func CalculateLeafHash(timestamp uint64, vc *verifiable.Credential, opts ...LeafOpt) (string, error) {
+	return calculateLeafHash(DefaultHasher, timestamp, vc, opts)
+}
+
+// CalculateLeafHash calculates hash for given credentials with the hasher of the client.
+func (c *Client) CalculateLeafHash(timestamp uint64, vc *verifiable.Credential, opts ...LeafOpt) (string, error) {
+	return calculateLeafHash(c.hasher, timestamp, vc, opts)
+}
+
+func calculateLeafHash(h Hasher, timestamp uint64, vc *verifiable.Credential, opts []LeafOpt) (string, error) {
+	leaf, err := createLeaf(timestamp, vc, opts)
+	if err != nil {
+		return "", err
 	}
 
 	leafData, err := json.Marshal(leaf)
@@ -255,23 +1074,33 @@ func CalculateLeafHash(timestamp uint64, vc *verifiable.Credential) (string, err
 		return "", fmt.Errorf("marshal leaf: %w", err)
 	}
 
-	return base64.StdEncoding.EncodeToString(hasher.DefaultHasher.HashLeaf(leafData)), nil
+	return base64.StdEncoding.EncodeToString(h.HashLeaf(leafData)), nil
 }
 
 // VerifyVCTimestampSignature verifies VC timestamp signature.
-func VerifyVCTimestampSignature(signature, pubKey []byte, timestamp uint64, vc *verifiable.Credential) error {
+func VerifyVCTimestampSignature(signature, pubKey []byte, timestamp uint64, vc *verifiable.Credential,
+	opts ...LeafOpt) error {
+	return verifyVCTimestampSignature(signature, pubKey, timestamp, vc, nil, opts)
+}
+
+// verifyVCTimestampSignature verifies VC timestamp signature, including the extensions of the SCT.
+func verifyVCTimestampSignature(signature, pubKey []byte, timestamp uint64, vc *verifiable.Credential,
+	sctExtensions []command.SCTExtension, opts []LeafOpt) error {
 	var sig *command.DigitallySigned
 
 	if err := json.Unmarshal(signature, &sig); err != nil {
 		return fmt.Errorf("unmarshal signature: %w", err)
 	}
 
-	leaf, err := command.CreateLeaf(timestamp, vc)
+	leaf, err := createLeaf(timestamp, vc, opts)
 	if err != nil {
-		return fmt.Errorf("create leaf: %w", err)
+		return err
 	}
 
-	data, err := json.Marshal(command.CreateVCTimestampSignature(leaf))
+	statement := command.CreateVCTimestampSignature(leaf)
+	statement.SCTExtensions = sctExtensions
+
+	data, err := json.Marshal(statement)
 	if err != nil {
 		return fmt.Errorf("marshal VC timestamp signature: %w", err)
 	}
@@ -284,11 +1113,178 @@ func VerifyVCTimestampSignature(signature, pubKey []byte, timestamp uint64, vc *
 	return (&tinkcrypto.Crypto{}).Verify(sig.Signature, data, kh) // nolint: wrapcheck
 }
 
+// VerifyProofOfAbsence verifies the signature and the non-inclusion proof of the proof of absence.
+func VerifyProofOfAbsence(resp *command.GetProofOfAbsenceResponse, pubKey []byte) error {
+	err := command.VerifySignature(resp.Signature, pubKey, command.NonInclusionSignature{
+		Version:           command.V1,
+		SignatureType:     command.NonInclusionSignatureType,
+		Timestamp:         resp.Timestamp,
+		TreeSize:          resp.TreeSize,
+		Issuer:            resp.Issuer,
+		CredentialID:      resp.CredentialID,
+		SHA256MapRootHash: resp.SHA256MapRootHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = smt.VerifyNonInclusion(resp.SHA256MapRootHash, command.CredentialKey(resp.Issuer, resp.CredentialID),
+		resp.AuditPath)
+	if err != nil {
+		return fmt.Errorf("verify non-inclusion: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyMapRootSignature verifies the signature of the map root.
+func VerifyMapRootSignature(root *command.GetMapRootResponse, pubKey []byte) error {
+	return command.VerifySignature(root.MapRootSignature, pubKey, command.MapRootSignature{
+		Version:           command.V1,
+		SignatureType:     command.MapRootSignatureType,
+		Timestamp:         root.Timestamp,
+		TreeSize:          root.TreeSize,
+		SHA256MapRootHash: root.SHA256MapRootHash,
+	})
+}
+
+// VerifyCredentialStatus verifies the map root signature and the inclusion proof of the credential status.
+func VerifyCredentialStatus(resp *command.GetCredentialStatusResponse, pubKey []byte) error {
+	if err := VerifyMapRootSignature(&resp.MapRoot, pubKey); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(resp.Entry)
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+
+	err = smt.VerifyInclusion(resp.MapRoot.SHA256MapRootHash, command.CredentialKey(resp.Issuer, resp.CredentialID),
+		value, resp.AuditPath)
+	if err != nil {
+		return fmt.Errorf("verify inclusion: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyDerivedCredential verifies that the credential derived from a BBS+ credential (selective disclosure)
+// was derived from the base credential of the leaf, as returned by get-entries or get-entry-and-proof.
+// The derived credential can be linked to the leaf this way since it reveals only statements of the base credential.
+// The BBS+ proof of the derived credential must be verified separately, e.g. when it is parsed.
+func VerifyDerivedCredential(derived *verifiable.Credential, leafInput []byte, loader jsonld.DocumentLoader) error {
+	var leaf *command.MerkleTreeLeaf
+	if err := json.Unmarshal(leafInput, &leaf); err != nil {
+		return fmt.Errorf("unmarshal leaf: %w", err)
+	}
+
+	if leaf == nil || leaf.TimestampedEntry == nil || leaf.TimestampedEntry.EntryType != command.VCLogEntryType {
+		return errors.New("leaf is not a credential")
+	}
+
+	return command.VerifyDerivation(derived, leaf.TimestampedEntry.VCEntry, loader) // nolint: wrapcheck
+}
+
+// VerifyAuditExport verifies the signatures of the audit export and its STH, that the chain of tree heads
+// is consistent and ends with the STH and that every entry is included in the tree of the second size.
+// The proofs are verified with DefaultHasher.
+func VerifyAuditExport(resp *command.GetAuditExportResponse, pubKey []byte) error {
+	return verifyAuditExport(DefaultHasher, NewVerifier(DefaultHasher), resp, pubKey)
+}
+
+// VerifyAuditExport verifies the audit export as VerifyAuditExport does, with the hasher and the verifier
+// of the client.
+func (c *Client) VerifyAuditExport(resp *command.GetAuditExportResponse, pubKey []byte) error {
+	return verifyAuditExport(c.hasher, c.verifier, resp, pubKey)
+}
+
+// nolint: gocyclo,cyclop
+func verifyAuditExport(h Hasher, verifier Verifier, resp *command.GetAuditExportResponse, pubKey []byte) error {
+	export := resp.Export
+
+	if err := command.VerifySignature(resp.Signature, pubKey, export); err != nil {
+		return fmt.Errorf("export signature: %w", err)
+	}
+
+	if export.SignatureType != command.AuditExportSignatureType {
+		return fmt.Errorf("signature type %d is not an audit export", export.SignatureType)
+	}
+
+	err := command.VerifySignature(export.STH.TreeHeadSignature, pubKey, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      export.STH.Timestamp,
+		TreeSize:       export.STH.TreeSize,
+		SHA256RootHash: export.STH.SHA256RootHash,
+	})
+	if err != nil {
+		return fmt.Errorf("tree head signature: %w", err)
+	}
+
+	if len(export.TreeHeads) == 0 {
+		return errors.New("no tree heads")
+	}
+
+	var secondRoot []byte
+
+	for i, head := range export.TreeHeads {
+		if head.TreeSize == export.SecondTreeSize {
+			secondRoot = head.SHA256RootHash
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		prev := export.TreeHeads[i-1]
+
+		err = verifier.VerifyConsistencyProof(prev.TreeSize, head.TreeSize, prev.SHA256RootHash, head.SHA256RootHash,
+			head.Consistency)
+		if err != nil {
+			return fmt.Errorf("consistency of tree size %d with %d: %w", head.TreeSize, prev.TreeSize, err)
+		}
+	}
+
+	first, last := export.TreeHeads[0], export.TreeHeads[len(export.TreeHeads)-1]
+
+	if export.FirstTreeSize > 0 && first.TreeSize != export.FirstTreeSize {
+		return fmt.Errorf("tree heads do not start with tree size %d", export.FirstTreeSize)
+	}
+
+	if uint64(last.TreeSize) != export.STH.TreeSize || !bytes.Equal(last.SHA256RootHash, export.STH.SHA256RootHash) {
+		return errors.New("tree heads do not end with the STH")
+	}
+
+	if secondRoot == nil {
+		return fmt.Errorf("no tree head of tree size %d", export.SecondTreeSize)
+	}
+
+	if int64(len(export.Entries)) != export.SecondTreeSize-export.FirstTreeSize {
+		return fmt.Errorf("got %d entries, expected %d", len(export.Entries),
+			export.SecondTreeSize-export.FirstTreeSize)
+	}
+
+	for i, entry := range export.Entries {
+		if entry.LeafIndex != export.FirstTreeSize+int64(i) {
+			return fmt.Errorf("unexpected leaf index %d of entry %d", entry.LeafIndex, i)
+		}
+
+		err = verifier.VerifyInclusionProof(entry.LeafIndex, export.SecondTreeSize, entry.AuditPath, secondRoot,
+			h.HashLeaf(entry.LeafInput))
+		if err != nil {
+			return fmt.Errorf("inclusion of leaf %d: %w", entry.LeafIndex, err)
+		}
+	}
+
+	return nil
+}
+
 type options struct {
-	method string
-	body   io.Reader
-	values url.Values
-	token  string
+	method   string
+	body     io.Reader
+	values   url.Values
+	token    string
+	rotation int
 }
 
 type opt func(*options)
@@ -317,15 +1313,46 @@ func withToken(val string) opt {
 	}
 }
 
+func withRotation(val int) opt {
+	return func(o *options) {
+		o.rotation = val
+	}
+}
+
 func (c *Client) do(ctx context.Context, path string, v interface{}, opts ...opt) error {
 	op := &options{method: http.MethodGet, values: url.Values{}}
 	for _, fn := range opts {
 		fn(op)
 	}
 
-	path = strings.Replace(path, rest.AliasPath, "", 1)
+	if len(c.endpoints) > 1 && isRead(path, op) {
+		return c.doRead(ctx, path, v, op)
+	}
+
+	return c.doEndpoint(ctx, c.endpoints[0], path, v, op)
+}
+
+func (c *Client) doEndpoint(ctx context.Context, endpoint logEndpoint, path string, v interface{},
+	op *options) error {
+	base, apiPath := endpoint.path, path
+
+	if c.apiVersion == rest.APIVersion2 && strings.HasPrefix(path, rest.BasePath+"/") {
+		path = rest.V2BasePath + strings.TrimPrefix(path, rest.BasePath)
+	}
 
-	req, err := http.NewRequestWithContext(ctx, op.method, c.endpoint+path+"?"+op.values.Encode(), op.body)
+	if strings.HasPrefix(path, rest.AliasPath) {
+		path = strings.Replace(path, rest.AliasPath, "", 1)
+	} else {
+		// endpoints of the service (e.g. admin) are not log specific.
+		base = c.basePath
+	}
+
+	reqURL, err := buildURL(endpoint.url, joinPath(base, path), op.values)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, op.method, reqURL, op.body)
 	if err != nil {
 		return fmt.Errorf("new request with context: %w", err)
 	}
@@ -334,32 +1361,211 @@ func (c *Client) do(ctx context.Context, path string, v interface{}, opts ...opt
 		req.Header.Add("Authorization", "Bearer "+op.token)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.send(endpoint, apiPath, req)
 	if err != nil {
-		return fmt.Errorf("http do: %w", err)
+		return err
 	}
 
 	defer resp.Body.Close() // nolint: errcheck
 
 	if resp.StatusCode != http.StatusOK {
-		return getError(resp.Body)
+		return getError(resp)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if _, err = buf.ReadFrom(resp.Body); err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if raw, ok := v.(*[]byte); ok {
+		*raw = append([]byte(nil), buf.Bytes()...)
+
+		return nil
 	}
 
-	return json.NewDecoder(resp.Body).Decode(&v) // nolint: wrapcheck
+	return json.Unmarshal(buf.Bytes(), &v) // nolint: wrapcheck
 }
 
-func getError(reader io.Reader) error {
-	msgBytes, err := ioutil.ReadAll(reader)
+// buildURL replaces the path of the given endpoint and merges the given values into its query.
+func buildURL(endpoint, path string, values url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse endpoint: %w", err)
+	}
+
+	u.Path = path
+	u.RawPath = ""
+
+	query := u.Query()
+
+	for key, vals := range values {
+		for _, val := range vals {
+			query.Add(key, val)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// joinPath joins URL path elements making sure there is exactly one slash between them.
+func joinPath(base, elem string) string {
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(elem, "/")
+}
+
+// parentPath returns the path without its last segment.
+func parentPath(path string) string {
+	p := strings.TrimRight(path, "/")
+
+	return p[:strings.LastIndex(p, "/")+1]
+}
+
+// ErrLogCompromised is returned when the key of the log is marked compromised.
+var ErrLogCompromised = errors.New("log is compromised")
+
+// Classes of the errors returned by the VCT server, an *Error matches its class with errors.Is.
+var (
+	// ErrNotFound is matched by the errors of the resources the log does not have (e.g. an unknown entry).
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized is matched by the requests rejected for their credentials (unauthorized or forbidden).
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRejected is matched by the other requests the server rejected as invalid, they fail again if retried.
+	ErrRejected = errors.New("rejected")
+	// ErrUnavailable is matched by the errors which may not persist (see Error.Retryable).
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// requestIDHeader is the header of the ID a server (or its gateway) assigned to the request.
+const requestIDHeader = "X-Request-Id"
+
+// Error represents an error returned by the VCT server (RFC 7807 problem details).
+type Error struct {
+	// Type is a stable problem type URI (see errors.ProblemType* constants).
+	Type   string
+	Title  string
+	Status int
+	Detail string
+	// Code is the error code of the server: the last segment of the problem type (e.g. not-found), empty for the
+	// errors which are not problem details.
+	Code string
+	// RequestID is the ID the server (or its gateway) assigned to the request (X-Request-Id header), if any.
+	RequestID string
+	// RetryAfter is the time to wait before retrying the request (Retry-After header), zero if not set.
+	RetryAfter time.Duration
+	// Body is the body of the response.
+	Body []byte
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+
+	return e.Title
+}
+
+// Retryable reports whether the error may not persist, the request may then succeed if it is retried (after
+// RetryAfter if set): the server errors other than not implemented, timeouts, rate limits and the writes rejected
+// in the read-only mode. The writes of compromised and frozen logs are never retryable.
+func (e *Error) Retryable() bool {
+	switch e.Type {
+	case vcterrors.ProblemTypeUnavailable, vcterrors.ProblemTypeReadOnly, vcterrors.ProblemTypeTimeout:
+		return true
+	case vcterrors.ProblemTypeCompromised, vcterrors.ProblemTypeFrozen, vcterrors.ProblemTypeNotImplemented:
+		return false
+	}
+
+	switch e.Status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	}
+
+	return e.Status >= http.StatusInternalServerError
+}
+
+// Is reports whether the error matches the target: its class (ErrNotFound, ErrUnauthorized, ErrRejected or
+// ErrUnavailable), ErrLogCompromised for the writes frozen by a compromised log and ErrLogFrozen for the writes of a
+// frozen log.
+func (e *Error) Is(target error) bool {
+	switch target { // nolint: errorlint
+	case ErrLogCompromised:
+		return e.Type == vcterrors.ProblemTypeCompromised
+	case ErrLogFrozen:
+		return e.Type == vcterrors.ProblemTypeFrozen
+	case ErrUnavailable:
+		return e.Retryable()
+	case ErrNotFound:
+		return e.Status == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.Status == http.StatusUnauthorized || e.Status == http.StatusForbidden
+	case ErrRejected:
+		return e.Status >= http.StatusBadRequest && e.Status < http.StatusInternalServerError && !e.Retryable() &&
+			e.Status != http.StatusNotFound && e.Status != http.StatusUnauthorized && e.Status != http.StatusForbidden
+	}
+
+	return false
+}
+
+func getError(resp *http.Response) error {
+	msgBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("read message body: %w", err)
 	}
 
+	vctErr := &Error{
+		Status:     resp.StatusCode,
+		Title:      http.StatusText(resp.StatusCode),
+		Detail:     string(msgBytes),
+		RequestID:  resp.Header.Get(requestIDHeader),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		Body:       msgBytes,
+	}
+
 	var errMsg *rest.ErrorResponse
 
 	err = json.Unmarshal(msgBytes, &errMsg)
-	if err != nil {
-		return fmt.Errorf("%s", msgBytes)
+	if err != nil || errMsg == nil {
+		return vctErr
+	}
+
+	// errors from older servers have a message only.
+	vctErr.Detail = errMsg.Detail
+	if vctErr.Detail == "" {
+		vctErr.Detail = errMsg.Message
+	}
+
+	if errMsg.Status != 0 {
+		vctErr.Status = errMsg.Status
+	}
+
+	vctErr.Type, vctErr.Title = errMsg.Type, errMsg.Title
+
+	if strings.HasPrefix(errMsg.Type, vcterrors.ProblemTypeBase) {
+		vctErr.Code = strings.TrimPrefix(errMsg.Type, vcterrors.ProblemTypeBase)
+	}
+
+	return vctErr
+}
+
+// retryAfter parses the Retry-After header: a number of seconds or an HTTP date, zero if it is not set or invalid.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && time.Until(date) > 0 {
+		return time.Until(date)
 	}
 
-	return errors.New(errMsg.Message)
+	return 0
 }
@@ -11,30 +11,47 @@ package vct_test
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	_ "embed"
 	"encoding/json"
+	goerrors "errors"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/ld"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
 	mockldstore "github.com/hyperledger/aries-framework-go/pkg/mock/ld"
 	ldstore "github.com/hyperledger/aries-framework-go/pkg/store/ld"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
 	"github.com/stretchr/testify/require"
 
 	vctldcontext "github.com/trustbloc/vct/internal/pkg/ldcontext"
 	"github.com/trustbloc/vct/pkg/client/vct"
 	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/errors"
 	"github.com/trustbloc/vct/pkg/controller/rest"
 )
 
 const endpoint = "https://example.com"
 
-//go:embed testdata/bachelor_degree.json
-var vcBachelorDegree []byte // nolint: gochecknoglobals
+// nolint: gochecknoglobals
+var (
+	//go:embed testdata/bachelor_degree.json
+	vcBachelorDegree []byte
+	//go:embed testdata/bbs_credential.json
+	vcBBS []byte
+)
 
 func TestClient_AddVC(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
@@ -77,6 +94,53 @@ func TestClient_AddVC(t *testing.T) {
 		require.Equal(t, fakeResp, bytesResp)
 	})
 
+	t.Run("Enveloped submission", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			credential []byte
+			expected   string
+		}{{
+			name:       "JSON-LD",
+			credential: []byte(`{"id":"vc"}`),
+			expected: `{"credential":{"id":"vc"},"options":{"tenant":"maple2021","dryRun":true,` +
+				`"callbackURL":"https://callback.com","idempotencyKey":"key","supersedes":"aGFzaA=="}}`,
+		}, {
+			name:       "JWT",
+			credential: []byte(`eyJhbGciOiJFUzI1NiJ9.e30.sig`),
+			expected: `{"credential":"eyJhbGciOiJFUzI1NiJ9.e30.sig","options":{"tenant":"maple2021",` +
+				`"dryRun":true,"callbackURL":"https://callback.com","idempotencyKey":"key","supersedes":"aGFzaA=="}}`,
+		}}
+
+		for _, tc := range tests {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				httpClient := NewMockHTTPClient(ctrl)
+				httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+					body, err := ioutil.ReadAll(req.Body)
+					require.NoError(t, err)
+					require.JSONEq(t, tc.expected, string(body))
+				}).Return(&http.Response{
+					Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+					StatusCode: http.StatusOK,
+				}, nil)
+
+				client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+				_, err := client.AddVC(context.Background(), tc.credential,
+					vct.WithTenant("maple2021"),
+					vct.WithDryRun(),
+					vct.WithCallbackURL("https://callback.com"),
+					vct.WithIdempotencyKey("key"),
+					vct.WithSupersedes([]byte("hash")),
+				)
+				require.NoError(t, err)
+			})
+		}
+	})
+
 	t.Run("Error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -235,6 +299,70 @@ func TestClient_Webfinger(t *testing.T) {
 	})
 }
 
+func TestClient_GetPublicKey(t *testing.T) {
+	newClient := func(t *testing.T, properties map[string]interface{}) *vct.Client {
+		t.Helper()
+
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+
+		fakeResp, err := json.Marshal(command.WebFingerResponse{Properties: properties})
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		return vct.New(endpoint, vct.WithHTTPClient(httpClient))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		pubKey, err := newClient(t, map[string]interface{}{
+			command.PublicKeyType: "cHVibGljIGtleQ==",
+			command.LogIDType:     "9WmobTwsjX3aJrXb6iC9XBnus138Y/23JLrE8hwieFA=",
+		}).GetPublicKey(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []byte("public key"), pubKey)
+	})
+
+	t.Run("No log ID", func(t *testing.T) {
+		pubKey, err := newClient(t, map[string]interface{}{
+			command.PublicKeyType: "cHVibGljIGtleQ==",
+		}).GetPublicKey(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []byte("public key"), pubKey)
+	})
+
+	t.Run("Log ID does not match", func(t *testing.T) {
+		_, err := newClient(t, map[string]interface{}{
+			command.PublicKeyType: "cHVibGljIGtleQ==",
+			command.LogIDType:     "bG9nIGlk",
+		}).GetPublicKey(context.Background())
+		require.EqualError(t, err, "log ID bG9nIGlk does not match the public key")
+	})
+
+	t.Run("No public key", func(t *testing.T) {
+		_, err := newClient(t, map[string]interface{}{}).GetPublicKey(context.Background())
+		require.EqualError(t, err, "webfinger has no public key")
+	})
+
+	t.Run("Compromised", func(t *testing.T) {
+		_, err := newClient(t, map[string]interface{}{
+			command.PublicKeyType:  "cHVibGljIGtleQ==",
+			command.CompromiseType: map[string]interface{}{"statement": map[string]interface{}{"compromised_since": 1000}},
+		}).GetPublicKey(context.Background())
+		require.ErrorIs(t, err, vct.ErrLogCompromised)
+		require.EqualError(t, err, "log is compromised since 1000")
+
+		_, err = newClient(t, map[string]interface{}{
+			command.CompromiseType: "statement",
+		}).GetPublicKey(context.Background())
+		require.Contains(t, err.Error(), "unmarshal compromise statement")
+	})
+}
+
 func TestClient_GetSTH(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -339,6 +467,54 @@ func TestClient_GetSTHConsistency(t *testing.T) {
 	})
 }
 
+func TestClient_GetAuditExport(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expected := command.GetAuditExportResponse{
+			Export:    command.AuditExport{Alias: "maple2021", FirstTreeSize: 1, SecondTreeSize: 2},
+			Signature: []byte("signature"),
+		}
+
+		fakeResp, err := json.Marshal(expected)
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/v1/get-audit-export", req.URL.Path)
+			require.Equal(t, "1", req.URL.Query().Get("first"))
+			require.Equal(t, "2", req.URL.Query().Get("second"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+		resp, err := client.GetAuditExport(context.Background(), 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, &expected, resp)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fakeResp, err := json.Marshal(rest.ErrorResponse{Message: "error"})
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusInternalServerError,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+		_, err = client.GetAuditExport(context.Background(), 1, 2)
+		require.EqualError(t, err, "get audit export: error")
+	})
+}
+
 func TestClient_GetProofByHash(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -444,6 +620,740 @@ func TestClient_GetEntries(t *testing.T) {
 	})
 }
 
+func TestClient_GetEntriesByTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	from := time.Date(2021, time.October, 12, 0, 0, 0, 0, time.UTC)
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "2021-10-12T00:00:00Z", req.URL.Query().Get("from_time"))
+		require.Equal(t, "2021-10-13T00:00:00Z", req.URL.Query().Get("to_time"))
+		require.Empty(t, req.URL.Query().Get("start"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"entries":[]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+	resp, err := client.GetEntriesByTime(context.Background(), from, from.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, resp.Entries)
+}
+
+func TestClient_GetProofOfAbsence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "did:example:issuer", req.URL.Query().Get("issuer"))
+		require.Equal(t, "urn:credential:1", req.URL.Query().Get("credential_id"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"tree_size":2,"credential_id":"urn:credential:1"}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+	resp, err := client.GetProofOfAbsence(context.Background(), "did:example:issuer", "urn:credential:1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.TreeSize)
+
+	require.Contains(t, vct.VerifyProofOfAbsence(resp, nil).Error(), "unmarshal signature")
+}
+
+func TestClient_GetCredentialStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/get-map-root", req.URL.Path)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"tree_size":2}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "did:example:issuer", req.URL.Query().Get("issuer"))
+		require.Equal(t, "urn:credential:1", req.URL.Query().Get("credential_id"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"entry":{"leaf_index":1},"map_root":{"tree_size":2}}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	root, err := client.GetMapRoot(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), root.TreeSize)
+
+	resp, err := client.GetCredentialStatus(context.Background(), "did:example:issuer", "urn:credential:1")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.Entry.LeafIndex)
+	require.Equal(t, uint64(2), resp.MapRoot.TreeSize)
+
+	require.Contains(t, vct.VerifyCredentialStatus(resp, nil).Error(), "unmarshal signature")
+}
+
+func TestClient_Revocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "/v1/add-revocation", req.URL.Path)
+
+		var event *command.RevocationEvent
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&event))
+		require.Equal(t, command.SuspendedStatus, event.Status)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"timestamp":1}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "aGFzaA==", req.URL.Query().Get("leaf_hash"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"leaf_hash":"aGFzaA==","events":[]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	resp, err := client.AddRevocation(context.Background(), &command.RevocationEvent{
+		LeafHash: []byte("hash"),
+		Status:   command.SuspendedStatus,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.Timestamp)
+
+	revocations, err := client.GetRevocations(context.Background(), "aGFzaA==")
+	require.NoError(t, err)
+	require.Equal(t, []byte("hash"), revocations.LeafHash)
+	require.Empty(t, revocations.Events)
+}
+
+func TestClient_AddEntry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "/v1/add-entry", req.URL.Path)
+
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"alias":"","entry_type":103,"entry":{"hash":"aGFzaA=="}}`, string(body))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"timestamp":1}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	resp, err := client.AddEntry(context.Background(), command.CommitmentLogEntryType, []byte(`{"hash":"aGFzaA=="}`))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.Timestamp)
+}
+
+func TestClient_GetLeafTypes(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body: ioutil.NopCloser(bytes.NewBufferString(`{"properties":{"https://trustbloc.dev/ns/leaf-types":[` +
+				`{"entry_type":100,"name":"vc"},{"entry_type":103,"name":"commitment","submittable":true}]}}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		types, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetLeafTypes(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []command.LeafTypeMetadata{
+			{EntryType: command.VCLogEntryType, Name: "vc"},
+			{EntryType: command.CommitmentLogEntryType, Name: "commitment", Submittable: true},
+		}, types)
+	})
+
+	t.Run("No leaf types", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"properties":{}}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		types, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetLeafTypes(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []command.LeafTypeMetadata{{EntryType: command.VCLogEntryType, Name: "vc"}}, types)
+	})
+
+	t.Run("Invalid leaf types", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"properties":{"https://trustbloc.dev/ns/leaf-types":"vc"}}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		_, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetLeafTypes(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal leaf types")
+	})
+}
+
+func TestClient_Anchors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "/v1/add-anchor", req.URL.Path)
+
+		var anchor *command.STHAnchor
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&anchor))
+		require.Equal(t, uint64(2), anchor.STH.TreeSize)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"timestamp":1}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "aWQ=", req.URL.Query().Get("log_id"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"log_id":"aWQ=","anchors":[]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	resp, err := client.AddAnchor(context.Background(), &command.STHAnchor{
+		LogID: []byte("id"),
+		STH:   command.GetSTHResponse{TreeSize: 2},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), resp.Timestamp)
+
+	anchors, err := client.GetAnchors(context.Background(), "aWQ=")
+	require.NoError(t, err)
+	require.Equal(t, []byte("id"), anchors.LogID)
+	require.Empty(t, anchors.Anchors)
+}
+
+func TestClient_GetShadowStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/get-shadow-status", req.URL.Path)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"mirrored":2,"diverged":1,"divergences":[{"reason":"r"}]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	resp, err := client.GetShadowStatus(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), resp.Mirrored)
+	require.Equal(t, uint64(1), resp.Diverged)
+	require.Equal(t, "r", resp.Divergences[0].Reason)
+}
+
+func TestClient_ReadOnly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "https://vct.com/transparency/admin/read-only", req.URL.String())
+		require.Equal(t, "Bearer write", req.Header.Get("Authorization"))
+
+		var status *command.ReadOnlyStatus
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&status))
+		require.True(t, status.ReadOnly)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"read_only":true}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodGet, req.Method)
+		require.Equal(t, "/transparency/admin/read-only", req.URL.Path)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"read_only":true}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"type":"https://trustbloc.dev/ns/vct/problems/read-only","status":503,"detail":"read-only"}`)),
+		StatusCode: http.StatusServiceUnavailable,
+	}, nil)
+
+	client := vct.New("https://vct.com/transparency/maple2024", vct.WithHTTPClient(httpClient),
+		vct.WithAuthWriteToken("write"))
+
+	require.NoError(t, client.SetReadOnly(context.Background(), true))
+
+	readOnly, err := client.GetReadOnly(context.Background())
+	require.NoError(t, err)
+	require.True(t, readOnly)
+
+	_, err = client.AddVC(context.Background(), []byte(`{}`))
+
+	var vctErr *vct.Error
+	require.ErrorAs(t, err, &vctErr)
+	require.Equal(t, errors.ProblemTypeReadOnly, vctErr.Type)
+	require.Equal(t, http.StatusServiceUnavailable, vctErr.Status)
+}
+
+func TestClient_LogState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "/transparency/admin/log-state", req.URL.Path)
+		require.Equal(t, "Bearer write", req.Header.Get("Authorization"))
+
+		var request *command.SetLogStateRequest
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+		require.Equal(t, &command.SetLogStateRequest{Alias: "maple2024", State: command.LogStateDraining}, request)
+	}).Return(&http.Response{
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"alias":"maple2024","state":"draining","tree_state":"DRAINING"}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodGet, req.Method)
+		require.Equal(t, "/transparency/admin/log-state", req.URL.Path)
+	}).Return(&http.Response{
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"states":[{"alias":"maple2024","state":"draining","tree_state":"DRAINING"}]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New("https://vct.com/transparency/maple2024", vct.WithHTTPClient(httpClient),
+		vct.WithAuthWriteToken("write"))
+
+	state, err := client.SetLogState(context.Background(), command.LogStateDraining)
+	require.NoError(t, err)
+	require.Equal(t, command.LogStateDraining, state.State)
+
+	states, err := client.GetLogStates(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []command.LogState{*state}, states)
+}
+
+func TestClient_GetKeyUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodGet, req.Method)
+		require.Equal(t, "https://vct.com/transparency/admin/key-usage", req.URL.String())
+		require.Equal(t, "Bearer write", req.Header.Get("Authorization"))
+	}).Return(&http.Response{
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"log_id":"bG9n","usage":[{"kind":"sct","signatures":3,"window":1,"threshold":2,"anomalies":1}]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New("https://vct.com/transparency/maple2024", vct.WithHTTPClient(httpClient),
+		vct.WithAuthWriteToken("write"))
+
+	resp, err := client.GetKeyUsage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []byte("log"), resp.LogID)
+	require.Equal(t, []command.KeyUsage{{
+		Kind: command.SCTSignature, Signatures: 3, Window: 1, Threshold: 2, Anomalies: 1,
+	}}, resp.Usage)
+}
+
+func TestClient_Compromise(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	statement := &command.SignedCompromiseStatement{
+		Statement: command.CompromiseStatement{
+			Version:          command.V1,
+			SignatureType:    command.CompromiseSignatureType,
+			LogID:            []byte("log"),
+			CompromisedSince: 1000,
+		},
+		Signature: []byte("signature"),
+	}
+
+	src, err := json.Marshal(statement)
+	require.NoError(t, err)
+
+	webfinger, err := json.Marshal(command.WebFingerResponse{Properties: map[string]interface{}{
+		command.CompromiseType: statement,
+	}})
+	require.NoError(t, err)
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "https://vct.com/transparency/admin/compromise", req.URL.String())
+		require.Equal(t, "Bearer write", req.Header.Get("Authorization"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBuffer(src)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBuffer(webfinger)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"type":"https://trustbloc.dev/ns/vct/problems/compromised","status":410,"detail":"compromised"}`)),
+		StatusCode: http.StatusGone,
+	}, nil)
+
+	client := vct.New("https://vct.com/transparency/maple2024", vct.WithHTTPClient(httpClient),
+		vct.WithAuthWriteToken("write"))
+
+	marked, err := client.MarkCompromised(context.Background(), statement)
+	require.NoError(t, err)
+	require.Equal(t, statement, marked)
+
+	published, err := client.GetCompromise(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, statement, published)
+
+	published, err = client.GetCompromise(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, published)
+
+	_, err = client.AddVC(context.Background(), []byte(`{}`))
+	require.ErrorIs(t, err, vct.ErrLogCompromised)
+}
+
+func TestVerifyAnnotation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	auditorKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+
+	annotation := command.Annotation{
+		Version:       command.V1,
+		SignatureType: command.AnnotationSignatureType,
+		LogID:         []byte("log"),
+		LeafIndex:     7,
+		Auditor:       "fraud-desk",
+		Kind:          command.AnnotationFraudulent,
+	}
+
+	data, err := json.Marshal(annotation)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature, err := json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{
+			Signature: command.ECDSASignature,
+			Type:      kms.ECDSAP256TypeIEEEP1363,
+		},
+		Signature: append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...),
+	})
+	require.NoError(t, err)
+
+	signed := &command.SignedAnnotation{Annotation: annotation, Signature: signature}
+
+	require.NoError(t, vct.VerifyAnnotation(signed, auditorKey))
+
+	signed.Annotation.LeafIndex = 8
+	require.Contains(t, vct.VerifyAnnotation(signed, auditorKey).Error(), "annotation: verify")
+
+	signed.Annotation.SignatureType = command.CompromiseSignatureType
+	require.EqualError(t, vct.VerifyAnnotation(signed, auditorKey), "statement must be an annotation")
+}
+
+func TestVerifyCompromiseStatement(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	recoveryKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+
+	statement := command.CompromiseStatement{
+		Version:          command.V1,
+		SignatureType:    command.CompromiseSignatureType,
+		LogID:            []byte("log"),
+		CompromisedSince: 1000,
+	}
+
+	data, err := json.Marshal(statement)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature, err := json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{
+			Signature: command.ECDSASignature,
+			Type:      kms.ECDSAP256TypeIEEEP1363,
+		},
+		Signature: append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...),
+	})
+	require.NoError(t, err)
+
+	signed := &command.SignedCompromiseStatement{Statement: statement, Signature: signature}
+
+	require.NoError(t, vct.VerifyCompromiseStatement(signed, recoveryKey))
+
+	signed.Statement.CompromisedSince = 2000
+	require.Contains(t, vct.VerifyCompromiseStatement(signed, recoveryKey).Error(), "compromise statement: verify")
+
+	signed.Statement.SignatureType = command.TreeHeadSignatureType
+	require.EqualError(t, vct.VerifyCompromiseStatement(signed, recoveryKey),
+		"statement must be a compromise statement")
+}
+
+func TestClient_GetCredentialHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/get-credential-history/----", req.URL.Path)
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"entries":[{"leaf_index":1,"supersedes":"aGFzaA=="}]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	history, err := client.GetCredentialHistory(context.Background(), "++++")
+	require.NoError(t, err)
+	require.Len(t, history.Entries, 1)
+	require.Equal(t, []byte("hash"), history.Entries[0].Supersedes)
+
+	_, err = client.GetCredentialHistory(context.Background(), "!")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "decode leaf hash")
+}
+
+func TestClient_GetReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/get-receipt/key 1", req.URL.Path)
+		require.Equal(t, "Bearer read", req.Header.Get("Authorization"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"svct_version":0,"timestamp":1,"signature":"c2ln"}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient), vct.WithAuthReadToken("read"))
+
+	sct, err := client.GetReceipt(context.Background(), "key 1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), sct.Timestamp)
+	require.Equal(t, []byte("sig"), sct.Signature)
+}
+
+func TestClient_GetEntryByCID(t *testing.T) {
+	entry := []byte(`{"id":"http://example.gov/credentials/3732"}`)
+	cid := command.EntryCID(entry)
+
+	leafInput, err := json.Marshal(command.MerkleTreeLeaf{TimestampedEntry: &command.TimestampedEntry{
+		EntryType: command.VCLogEntryType,
+		VCEntry:   entry,
+	}})
+	require.NoError(t, err)
+
+	getEntry := func(t *testing.T, resp *command.GetEntryByCIDResponse) (*command.GetEntryByCIDResponse, error) {
+		t.Helper()
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		src, err := json.Marshal(resp)
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/entry/"+cid, req.URL.Path)
+			require.Equal(t, "Bearer read", req.Header.Get("Authorization"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(src)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient), vct.WithAuthReadToken("read"))
+
+		return client.GetEntryByCID(context.Background(), cid)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		resp, err := getEntry(t, &command.GetEntryByCIDResponse{CID: cid, LeafIndex: 3, Entry: entry,
+			LeafInput: leafInput})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), resp.LeafIndex)
+		require.Equal(t, entry, resp.Entry)
+	})
+
+	t.Run("Entry does not match", func(t *testing.T) {
+		_, err := getEntry(t, &command.GetEntryByCIDResponse{CID: cid, Entry: []byte(`{}`), LeafInput: leafInput})
+		require.EqualError(t, err, "get entry by CID: entry does not match CID "+cid)
+	})
+
+	t.Run("Entry of another leaf", func(t *testing.T) {
+		_, err := getEntry(t, &command.GetEntryByCIDResponse{CID: cid, Entry: entry, LeafInput: []byte(`{}`)})
+		require.EqualError(t, err, "get entry by CID: entry is not the entry of the leaf")
+	})
+
+	t.Run("Invalid CID", func(t *testing.T) {
+		_, err := vct.New(endpoint).GetEntryByCID(context.Background(), "QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG")
+		require.EqualError(t, err, "get entry by CID: validation failed: decode CID: selected encoding not supported")
+	})
+}
+
+func TestClient_Annotations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/add-annotation", req.URL.Path)
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "Bearer write", req.Header.Get("Authorization"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"annotation":{"leaf_index":7},"signature":"c2ln"}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/get-annotations", req.URL.Path)
+		require.Equal(t, "7", req.URL.Query().Get("leaf_index"))
+		require.Equal(t, "Bearer read", req.Header.Get("Authorization"))
+	}).Return(&http.Response{
+		Body: ioutil.NopCloser(bytes.NewBufferString(
+			`{"leaf_index":7,"annotations":[{"annotation":{"leaf_index":7},"signature":"c2ln"}]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient), vct.WithAuthReadToken("read"),
+		vct.WithAuthWriteToken("write"))
+
+	added, err := client.AddAnnotation(context.Background(), &command.SignedAnnotation{
+		Annotation: command.Annotation{LeafIndex: 7},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), added.Annotation.LeafIndex)
+
+	resp, err := client.GetAnnotations(context.Background(), 7)
+	require.NoError(t, err)
+	require.Equal(t, []command.SignedAnnotation{*added}, resp.Annotations)
+}
+
+func TestClient_GetTile(t *testing.T) {
+	tile := bytes.Repeat([]byte{1}, 2*sha256.Size)
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/tile/1/x001/x234/067.p/2", req.URL.Path)
+			require.Equal(t, "Bearer read", req.Header.Get("Authorization"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(tile)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient), vct.WithAuthReadToken("read"))
+
+		hashes, err := client.GetTile(context.Background(), 1, 1234067, 2)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{tile[:sha256.Size], tile[sha256.Size:]}, hashes)
+	})
+
+	t.Run("Wrong size", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(tile)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		_, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetTile(context.Background(), 0, 0, 0)
+		require.EqualError(t, err, "get tile: tile of 64 bytes does not hold 256 hashes")
+	})
+}
+
+func TestClient_GetEntryBundle(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/tile/entries/005.p/2", req.URL.Path)
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer([]byte{0, 0, 1, 'a', 0, 0, 2, 'b', 'c'})),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		entries, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetEntryBundle(context.Background(), 5, 2)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("a"), []byte("bc")}, entries)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer([]byte{0, 0, 2, 'a'})),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		_, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetEntryBundle(context.Background(), 5, 1)
+		require.EqualError(t, err, "get entry bundle: truncated entry")
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"status":404,"message":"not found"}`)),
+			StatusCode: http.StatusNotFound,
+		}, nil)
+
+		_, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetEntryBundle(context.Background(), 5, 0)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "not found")
+	})
+}
+
 func TestClient_GetEntryAndProof(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -498,6 +1408,225 @@ func TestClient_GetEntryAndProof(t *testing.T) {
 	})
 }
 
+func TestClient_BasePath(t *testing.T) {
+	tests := []struct {
+		name        string
+		endpoint    string
+		opts        []vct.ClientOpt
+		sthURL      string
+		healthCheck string
+	}{{
+		name:        "Root",
+		endpoint:    "https://vct.com/maple2024",
+		sthURL:      "https://vct.com/maple2024/v1/get-sth",
+		healthCheck: "https://vct.com/healthcheck",
+	}, {
+		name:        "Trailing slash",
+		endpoint:    "https://vct.com/maple2024/",
+		sthURL:      "https://vct.com/maple2024/v1/get-sth",
+		healthCheck: "https://vct.com/healthcheck",
+	}, {
+		name:        "Reverse-proxy prefix",
+		endpoint:    "https://vct.com/transparency/maple2024",
+		sthURL:      "https://vct.com/transparency/maple2024/v1/get-sth",
+		healthCheck: "https://vct.com/transparency/healthcheck",
+	}, {
+		name:        "Explicit base path",
+		endpoint:    "https://vct.com/tenant-a/logs/maple2024",
+		opts:        []vct.ClientOpt{vct.WithBasePath("/tenant-a/")},
+		sthURL:      "https://vct.com/tenant-a/logs/maple2024/v1/get-sth",
+		healthCheck: "https://vct.com/tenant-a/healthcheck",
+	}, {
+		name:        "API v2",
+		endpoint:    "https://vct.com/maple2024",
+		opts:        []vct.ClientOpt{vct.WithAPIVersion(rest.APIVersion2)},
+		sthURL:      "https://vct.com/maple2024/v2/get-sth",
+		healthCheck: "https://vct.com/healthcheck",
+	}, {
+		name:        "Endpoint query is kept",
+		endpoint:    "https://vct.com/maple2024?tenant=a",
+		sthURL:      "https://vct.com/maple2024/v1/get-sth?tenant=a",
+		healthCheck: "https://vct.com/healthcheck?tenant=a",
+	}}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var urls []string
+
+			httpClient := NewMockHTTPClient(ctrl)
+			httpClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				urls = append(urls, req.URL.String())
+
+				return &http.Response{
+					Body:       ioutil.NopCloser(bytes.NewBufferString("{}")),
+					StatusCode: http.StatusOK,
+				}, nil
+			}).Times(2)
+
+			client := vct.New(tc.endpoint, append(tc.opts, vct.WithHTTPClient(httpClient))...)
+
+			_, err := client.GetSTH(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, client.HealthCheck(context.Background()))
+
+			require.Equal(t, []string{tc.sthURL, tc.healthCheck}, urls)
+		})
+	}
+
+	t.Run("Invalid endpoint", func(t *testing.T) {
+		client := vct.New("https://vct.com/%zz")
+
+		_, err := client.GetSTH(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse endpoint")
+	})
+}
+
+func TestClient_WithDialer(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var dialed bool
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/maple2021/v1/get-sth", r.URL.Path)
+
+			w.Write([]byte(`{"tree_size":1}`)) // nolint: errcheck,gosec
+		}))
+		defer server.Close()
+
+		client := vct.New("http://vct.sidecar/maple2021", vct.WithDialer(
+			func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialed = true
+
+				return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		))
+
+		resp, err := client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), resp.TreeSize)
+		require.True(t, dialed)
+	})
+
+	t.Run("Unix socket error", func(t *testing.T) {
+		client := vct.New(endpoint, vct.WithDialer(vct.UnixSocketDialer("/not/exist/vct.sock")))
+
+		_, err := client.GetSTH(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "http do")
+	})
+}
+
+func TestClient_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		header   http.Header
+		body     string
+		expected *vct.Error
+		class    error
+	}{{
+		name:   "Problem details",
+		status: http.StatusNotFound,
+		header: http.Header{"X-Request-Id": []string{"req-1"}},
+		body: `{"type":"https://trustbloc.dev/ns/vct/problems/not-found","title":"Not Found",` +
+			`"status":404,"detail":"alias \"maple\" is not supported","message":"alias \"maple\" is not supported"}`,
+		expected: &vct.Error{
+			Type:      errors.ProblemTypeNotFound,
+			Title:     "Not Found",
+			Status:    http.StatusNotFound,
+			Detail:    `alias "maple" is not supported`,
+			Code:      "not-found",
+			RequestID: "req-1",
+		},
+		class: vct.ErrNotFound,
+	}, {
+		name:     "Message only",
+		status:   http.StatusBadRequest,
+		body:     `{"message":"bad request"}`,
+		expected: &vct.Error{Status: http.StatusBadRequest, Detail: "bad request"},
+		class:    vct.ErrRejected,
+	}, {
+		name:     "Plain text",
+		status:   http.StatusUnauthorized,
+		body:     "Unauthorised.\n",
+		expected: &vct.Error{Status: http.StatusUnauthorized, Title: "Unauthorized", Detail: "Unauthorised.\n"},
+		class:    vct.ErrUnauthorized,
+	}, {
+		name:   "Read-only",
+		status: http.StatusServiceUnavailable,
+		header: http.Header{"Retry-After": []string{"30"}},
+		body: `{"type":"https://trustbloc.dev/ns/vct/problems/read-only","title":"Service Unavailable",` +
+			`"status":503,"detail":"read-only"}`,
+		expected: &vct.Error{
+			Type:       errors.ProblemTypeReadOnly,
+			Title:      "Service Unavailable",
+			Status:     http.StatusServiceUnavailable,
+			Detail:     "read-only",
+			Code:       "read-only",
+			RetryAfter: 30 * time.Second,
+		},
+		class: vct.ErrUnavailable,
+	}, {
+		name:     "Rate limited",
+		status:   http.StatusTooManyRequests,
+		body:     "slow down",
+		expected: &vct.Error{Status: http.StatusTooManyRequests, Title: "Too Many Requests", Detail: "slow down"},
+		class:    vct.ErrUnavailable,
+	}}
+
+	classes := []error{vct.ErrNotFound, vct.ErrUnauthorized, vct.ErrRejected, vct.ErrUnavailable}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			httpClient := NewMockHTTPClient(ctrl)
+			httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+				Header:     tc.header,
+				Body:       ioutil.NopCloser(bytes.NewBufferString(tc.body)),
+				StatusCode: tc.status,
+			}, nil)
+
+			_, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetSTH(context.Background())
+			require.Error(t, err)
+
+			var vctErr *vct.Error
+			require.True(t, goerrors.As(err, &vctErr))
+
+			tc.expected.Body = []byte(tc.body)
+			require.Equal(t, tc.expected, vctErr)
+			require.Equal(t, tc.class == vct.ErrUnavailable, vctErr.Retryable())
+
+			// an error is of one class only
+			for _, class := range classes {
+				require.Equal(t, class == tc.class, goerrors.Is(err, class), class)
+			}
+		})
+	}
+
+	t.Run("Retryable", func(t *testing.T) {
+		require.True(t, (&vct.Error{Status: http.StatusBadGateway}).Retryable())
+		require.True(t, (&vct.Error{Status: http.StatusRequestTimeout}).Retryable())
+		require.False(t, (&vct.Error{Status: http.StatusNotImplemented}).Retryable())
+		require.False(t, (&vct.Error{Status: http.StatusGone, Type: errors.ProblemTypeFrozen}).Retryable())
+		require.False(t, (&vct.Error{Status: http.StatusServiceUnavailable, Type: errors.ProblemTypeNotImplemented}).
+			Retryable())
+
+		frozen := &vct.Error{Status: http.StatusGone, Type: errors.ProblemTypeFrozen}
+		require.True(t, goerrors.Is(frozen, vct.ErrLogFrozen))
+		require.True(t, goerrors.Is(frozen, vct.ErrRejected))
+		require.False(t, goerrors.Is(frozen, vct.ErrLogCompromised))
+	})
+}
+
 var simpleVC = &verifiable.Credential{ // nolint: gochecknoglobals // global vc
 	Context: []string{"https://www.w3.org/2018/credentials/v1"},
 	Subject: "did:key:123",
@@ -520,6 +1649,12 @@ func TestCalculateLeafHash(t *testing.T) {
 		require.Equal(t, "IamzE8Fm5W3ToLgZWlqVHPqgBLiBompVIyGLWDo0SP8=", hash)
 	})
 
+	t.Run("With extensions", func(t *testing.T) {
+		hash, err := vct.CalculateLeafHash(12345, simpleVC, vct.WithExtensions([]byte(`{"supersedes":"aGFzaA=="}`)))
+		require.NoError(t, err)
+		require.NotEqual(t, "IamzE8Fm5W3ToLgZWlqVHPqgBLiBompVIyGLWDo0SP8=", hash)
+	})
+
 	t.Run("Marshal credential", func(t *testing.T) {
 		_, err := vct.CalculateLeafHash(12345, &verifiable.Credential{
 			Subject: make(chan int),
@@ -577,6 +1712,71 @@ func TestVerifyVCTimestampSignature(t *testing.T) {
 	})
 }
 
+func TestVerifyDerivedCredential(t *testing.T) {
+	loader := getLoader(t)
+	publicKeyFetcher := verifiable.NewVDRKeyResolver(vdr.New(vdr.WithVDR(key.New()))).PublicKeyFetcher()
+
+	base, err := verifiable.ParseCredential(vcBBS,
+		verifiable.WithPublicKeyFetcher(publicKeyFetcher),
+		verifiable.WithJSONLDDocumentLoader(loader),
+	)
+	require.NoError(t, err)
+
+	leaf, err := command.CreateLeaf(1619006293939, base)
+	require.NoError(t, err)
+
+	leafInput, err := json.Marshal(leaf)
+	require.NoError(t, err)
+
+	derive := func(t *testing.T) *verifiable.Credential {
+		t.Helper()
+
+		derived, err := base.GenerateBBSSelectiveDisclosure(map[string]interface{}{
+			"@context": []interface{}{
+				"https://www.w3.org/2018/credentials/v1",
+				"https://www.w3.org/2018/credentials/examples/v1",
+				"https://w3id.org/security/bbs/v1",
+			},
+			"type": []interface{}{"VerifiableCredential", "UniversityDegreeCredential"},
+			"credentialSubject": map[string]interface{}{
+				"@explicit": true,
+				"degree":    map[string]interface{}{},
+			},
+		}, []byte("nonce"), verifiable.WithPublicKeyFetcher(publicKeyFetcher),
+			verifiable.WithJSONLDDocumentLoader(loader))
+		require.NoError(t, err)
+
+		return derived
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		derived := derive(t)
+
+		require.True(t, command.IsDerivedCredential(derived))
+		require.False(t, command.IsDerivedCredential(base))
+		require.NoError(t, vct.VerifyDerivedCredential(derived, leafInput, loader))
+		require.Len(t, derived.Proofs, 1)
+	})
+
+	t.Run("Not derived from the base credential", func(t *testing.T) {
+		derived := derive(t)
+		derived.Issuer.ID = "did:example:76e12ec712ebc6f1c221ebfeb1f"
+
+		err := vct.VerifyDerivedCredential(derived, leafInput, loader)
+		require.Contains(t, err.Error(), "of the derived credential is not a statement of the base credential")
+	})
+
+	t.Run("Not a credential", func(t *testing.T) {
+		anchor, err := json.Marshal(&command.MerkleTreeLeaf{TimestampedEntry: &command.TimestampedEntry{
+			EntryType: command.STHAnchorLogEntryType,
+		}})
+		require.NoError(t, err)
+
+		require.EqualError(t, vct.VerifyDerivedCredential(derive(t), anchor, loader), "leaf is not a credential")
+		require.Contains(t, vct.VerifyDerivedCredential(derive(t), []byte(`[]`), loader).Error(), "unmarshal leaf")
+	})
+}
+
 type mockProvider struct {
 	ContextStore        ldstore.ContextStore
 	RemoteProviderStore ldstore.RemoteProviderStore
@@ -0,0 +1,174 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+const (
+	// sthFlight is the key of the tree head fetches shared by the concurrent GetSTH calls.
+	sthFlight = "sth"
+	// maxPooledBuffer is the capacity of the largest response buffer kept for reuse, larger buffers (e.g. of big
+	// entry bundles) are left to the garbage collector.
+	maxPooledBuffer = 1 << 20
+)
+
+// nolint: gochecknoglobals
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer) // nolint: forcetypeassert
+	buf.Reset()
+
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+// WithSTHCache sets the time the tree head fetched by GetSTH is served to the following calls, the tree head is
+// fetched by every call by default. The concurrent calls share a fetch whether the tree head is cached or not.
+func WithSTHCache(ttl time.Duration) ClientOpt {
+	return func(o *clientOptions) {
+		o.sthCacheTTL = ttl
+	}
+}
+
+// cachedSTH returns the tree head fetched within the TTL of the cache, false if there is none.
+func (c *Client) cachedSTH() (*command.GetSTHResponse, bool) {
+	if c.sthCacheTTL <= 0 {
+		return nil, false
+	}
+
+	c.sthMu.Lock()
+	defer c.sthMu.Unlock()
+
+	if c.sth == nil || time.Since(c.sthFetched) >= c.sthCacheTTL {
+		return nil, false
+	}
+
+	sth := *c.sth
+
+	return &sth, true
+}
+
+// getSTH fetches the latest tree head, the concurrent calls share one fetch. A call whose fetch was canceled by
+// the context of the call which started it fetches the tree head again.
+func (c *Client) getSTH(ctx context.Context) (*command.GetSTHResponse, error) {
+	if sth, ok := c.cachedSTH(); ok {
+		return sth, nil
+	}
+
+	for {
+		results := c.sthGroup.DoChan(sthFlight, func() (interface{}, error) {
+			var result *command.GetSTHResponse
+			if err := c.doHedged(ctx, rest.GetSTHPath, &result, withToken(c.authReadToken)); err != nil {
+				return nil, err
+			}
+
+			if result != nil {
+				c.sthMu.Lock()
+				c.sth, c.sthFetched = result, time.Now()
+				c.sthMu.Unlock()
+			}
+
+			return result, nil
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err() // nolint: wrapcheck
+		case res := <-results:
+			if res.Err != nil && ctx.Err() == nil && (errors.Is(res.Err, context.Canceled) ||
+				errors.Is(res.Err, context.DeadlineExceeded)) {
+				continue
+			}
+
+			if res.Err != nil {
+				return nil, res.Err // nolint: wrapcheck
+			}
+
+			result, _ := res.Val.(*command.GetSTHResponse) // nolint: errcheck
+			if result == nil {
+				return nil, nil
+			}
+
+			// every call gets its copy of the shared response
+			sth := *result
+
+			return &sth, nil
+		}
+	}
+}
+
+// Warmup prepares the client for latency-sensitive calls: it connects to the log and to its mirrors, verifies
+// the public key of the log (see GetPublicKey), discovers the tiles of the log and fetches its latest tree head
+// (kept for the calls of GetSTH if the STH cache is enabled, see WithSTHCache). Warmup may be called at any
+// time, e.g. after the endpoints of the log were unavailable.
+//
+// Warmup fails only if the primary fails: the errors of the mirrors it could not connect to are returned by
+// endpoint, the reads try these mirrors last until they are available again.
+func (c *Client) Warmup(ctx context.Context) (map[string]error, error) {
+	errs := make([]error, len(c.endpoints))
+	webfingers := make([]*command.WebFingerResponse, len(c.endpoints))
+
+	var wg sync.WaitGroup
+
+	for i := range c.endpoints {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			op := &options{method: http.MethodGet, values: url.Values{}, token: c.authReadToken}
+
+			errs[i] = c.doEndpoint(ctx, c.endpoints[i], rest.WebfingerPath, &webfingers[i], op)
+		}(i)
+	}
+
+	wg.Wait()
+
+	if errs[0] != nil {
+		return nil, fmt.Errorf("warm up %s: %w", c.endpoints[0].url, errs[0])
+	}
+
+	if _, err := publicKey(webfingers[0]); err != nil {
+		return nil, fmt.Errorf("warm up: %w", err)
+	}
+
+	c.setTileSource(webfingers[0])
+
+	if _, err := c.getSTH(ctx); err != nil {
+		return nil, fmt.Errorf("warm up: get STH: %w", err)
+	}
+
+	mirrorErrs := map[string]error{}
+
+	for i, err := range errs[1:] {
+		c.setHealthy(i+1, err == nil)
+
+		if err != nil {
+			mirrorErrs[c.endpoints[i+1].url] = err
+		}
+	}
+
+	return mirrorErrs, nil
+}
@@ -0,0 +1,206 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// newSTHServer returns a log server answering the tree head once the handler of the request returns.
+func newSTHServer(t *testing.T, handle func(r *http.Request)) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/v1/get-sth"):
+			atomic.AddInt32(&requests, 1)
+
+			handle(r)
+
+			require.NoError(t, json.NewEncoder(w).Encode(command.GetSTHResponse{TreeSize: 2}))
+		case strings.HasSuffix(r.URL.Path, "/.well-known/webfinger"):
+			require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+				Properties: map[string]interface{}{command.PublicKeyType: sctPubKey},
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts, &requests
+}
+
+func TestClient_GetSTH_Concurrent(t *testing.T) {
+	t.Run("Concurrent calls share a fetch", func(t *testing.T) {
+		release := make(chan struct{})
+
+		ts, requests := newSTHServer(t, func(*http.Request) { <-release })
+
+		client := vct.New(ts.URL + "/maple2021")
+
+		var wg sync.WaitGroup
+
+		sths := make([]*command.GetSTHResponse, 10)
+
+		for i := range sths {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				var err error
+
+				sths[i], err = client.GetSTH(context.Background())
+				require.NoError(t, err)
+			}(i)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+		for _, sth := range sths {
+			require.Equal(t, uint64(2), sth.TreeSize)
+		}
+
+		// every call gets its copy
+		require.NotSame(t, sths[0], sths[1])
+
+		_, err := client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int32(2), atomic.LoadInt32(requests))
+	})
+
+	t.Run("Cached tree head", func(t *testing.T) {
+		ts, requests := newSTHServer(t, func(*http.Request) {})
+
+		client := vct.New(ts.URL+"/maple2021", vct.WithSTHCache(time.Hour))
+
+		for i := 0; i < 3; i++ {
+			sth, err := client.GetSTH(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, uint64(2), sth.TreeSize)
+		}
+
+		require.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("Fetch canceled by the first call", func(t *testing.T) {
+		var first int32
+
+		ts, requests := newSTHServer(t, func(r *http.Request) {
+			if atomic.CompareAndSwapInt32(&first, 0, 1) {
+				<-r.Context().Done()
+			}
+		})
+
+		client := vct.New(ts.URL + "/maple2021")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		done := make(chan error)
+
+		go func() {
+			_, err := client.GetSTH(ctx)
+			done <- err
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+
+		sth, err := client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), sth.TreeSize)
+
+		require.Contains(t, (<-done).Error(), "context deadline exceeded")
+		require.Equal(t, int32(2), atomic.LoadInt32(requests))
+	})
+}
+
+func TestClient_Warmup(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ts, requests := newSTHServer(t, func(*http.Request) {})
+		mirror, _ := newSTHServer(t, func(*http.Request) {})
+
+		recorder := &callRecorder{}
+
+		client := vct.New(ts.URL+"/maple2021", vct.WithMirrors(mirror.URL+"/maple2021"),
+			vct.WithSTHCache(time.Hour), vct.WithObserver(recorder))
+
+		mirrorErrs, err := client.Warmup(context.Background())
+		require.NoError(t, err)
+		require.Empty(t, mirrorErrs)
+
+		// the log and its mirror are connected to
+		endpoints := map[string]bool{}
+		for _, call := range recorder.calls {
+			endpoints[call.Endpoint] = true
+		}
+
+		require.Equal(t, map[string]bool{ts.URL + "/maple2021": true, mirror.URL + "/maple2021": true}, endpoints)
+
+		// the tree head is cached
+		_, err = client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("Mirror unavailable", func(t *testing.T) {
+		ts, _ := newSTHServer(t, func(*http.Request) {})
+
+		client := vct.New(ts.URL+"/maple2021", vct.WithMirrors("http://127.0.0.1:1/maple2021"))
+
+		mirrorErrs, err := client.Warmup(context.Background())
+		require.NoError(t, err)
+		require.Len(t, mirrorErrs, 1)
+		require.Error(t, mirrorErrs["http://127.0.0.1:1/maple2021"])
+	})
+
+	t.Run("Primary unavailable", func(t *testing.T) {
+		mirror, _ := newSTHServer(t, func(*http.Request) {})
+
+		client := vct.New("http://127.0.0.1:1/maple2021", vct.WithMirrors(mirror.URL+"/maple2021"))
+
+		_, err := client.Warmup(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "warm up http://127.0.0.1:1/maple2021")
+	})
+
+	t.Run("Compromised log", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+				Properties: map[string]interface{}{
+					command.PublicKeyType:  sctPubKey,
+					command.CompromiseType: command.SignedCompromiseStatement{},
+				},
+			}))
+		}))
+		defer ts.Close()
+
+		_, err := vct.New(ts.URL + "/maple2021").Warmup(context.Background())
+		require.ErrorIs(t, err, vct.ErrLogCompromised)
+	})
+}
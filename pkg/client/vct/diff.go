@@ -0,0 +1,47 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// GetDiff retrieves the summary of the entries added while the tree grew from the first to the second size (the
+// counts by leaf type, issuer and credential type), with the entries themselves if requested.
+func (c *Client) GetDiff(ctx context.Context, first, second uint64, entries bool) (*command.GetDiffResponse, error) {
+	const (
+		firstParamName   = "first"
+		secondParamName  = "second"
+		entriesParamName = "entries"
+	)
+
+	if err := c.checkTreeSize(second); err != nil {
+		return nil, fmt.Errorf("get diff: %w", err)
+	}
+
+	opts := []opt{
+		withValueAdd(firstParamName, strconv.FormatUint(first, 10)),
+		withValueAdd(secondParamName, strconv.FormatUint(second, 10)),
+		withToken(c.authReadToken),
+	}
+
+	if entries {
+		opts = append(opts, withValueAdd(entriesParamName, strconv.FormatBool(entries)))
+	}
+
+	var result *command.GetDiffResponse
+	if err := c.do(ctx, rest.DiffPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get diff: %w", err)
+	}
+
+	return result, nil
+}
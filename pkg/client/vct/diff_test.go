@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+func TestClient_GetDiff(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expected := command.GetDiffResponse{
+			FirstTreeSize:   10,
+			SecondTreeSize:  12,
+			Added:           2,
+			EntryTypes:      map[string]uint64{"vc": 2},
+			Issuers:         map[string]uint64{"did:example:issuer": 2},
+			CredentialTypes: map[string]uint64{"VerifiableCredential": 2},
+			Entries:         []command.DiffEntry{{LeafIndex: 10}, {LeafIndex: 11}},
+		}
+
+		fakeResp, err := json.Marshal(expected)
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/v1/diff", req.URL.Path)
+			require.Equal(t, "10", req.URL.Query().Get("first"))
+			require.Equal(t, "12", req.URL.Query().Get("second"))
+			require.Equal(t, "true", req.URL.Query().Get("entries"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+		resp, err := client.GetDiff(context.Background(), 10, 12, true)
+		require.NoError(t, err)
+		require.Equal(t, &expected, resp)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fakeResp, err := json.Marshal(rest.ErrorResponse{Message: "error"})
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.NotContains(t, req.URL.Query(), "entries")
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusInternalServerError,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+		_, err = client.GetDiff(context.Background(), 10, 12, false)
+		require.EqualError(t, err, "get diff: error")
+	})
+}
@@ -0,0 +1,141 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// unhealthyPeriod is the time an endpoint which failed is tried after the healthy ones only.
+const unhealthyPeriod = 30 * time.Second
+
+// WithMirrors sets the endpoints of the mirrors of the log (e.g. https://mirror.example.com/maple2021).
+// The reads of the log fail over to the mirrors when the endpoint passed to New (the primary) is unavailable,
+// the writes and the endpoints of the service (e.g. the health check) are sent to the primary only.
+// The read token is sent to the mirrors.
+func WithMirrors(endpoints ...string) ClientOpt {
+	return func(o *clientOptions) {
+		o.mirrors = append(o.mirrors, endpoints...)
+	}
+}
+
+// logEndpoint is an endpoint of the log, the primary or a mirror.
+type logEndpoint struct {
+	url  string
+	path string
+}
+
+func newLogEndpoint(endpoint string) logEndpoint {
+	e := logEndpoint{url: endpoint}
+
+	// an invalid endpoint is reported by the first request.
+	if u, err := url.Parse(endpoint); err == nil {
+		e.path = u.Path
+	}
+
+	return e
+}
+
+// Endpoints returns the endpoints of the log, the primary first, followed by its mirrors.
+func (c *Client) Endpoints() []string {
+	endpoints := make([]string, len(c.endpoints))
+
+	for i, e := range c.endpoints {
+		endpoints[i] = e.url
+	}
+
+	return endpoints
+}
+
+// readOrder returns the indexes of the endpoints in the order the reads try them: the healthy endpoints first
+// (the primary first), the unhealthy ones as a last resort. The order is rotated by the rotation
+// (e.g. a hedged request prefers the next endpoint).
+func (c *Client) readOrder(rotation int) []int {
+	now := time.Now()
+
+	var healthy, unhealthy []int
+
+	c.healthMu.Lock()
+
+	for i := range c.endpoints {
+		if now.Before(c.unhealthyUntil[i]) {
+			unhealthy = append(unhealthy, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+
+	c.healthMu.Unlock()
+
+	order := make([]int, 0, len(c.endpoints))
+	order = append(order, healthy...)
+	order = append(order, unhealthy...)
+
+	rotation %= len(order)
+
+	return append(order[rotation:], order[:rotation]...)
+}
+
+func (c *Client) setHealthy(i int, healthy bool) {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+
+	if healthy {
+		c.unhealthyUntil[i] = time.Time{}
+	} else {
+		c.unhealthyUntil[i] = time.Now().Add(unhealthyPeriod)
+	}
+}
+
+// doRead does the read request of the log, failing over to the next endpoint while the endpoints are unavailable.
+func (c *Client) doRead(ctx context.Context, path string, v interface{}, op *options) error {
+	var err error
+
+	for _, i := range c.readOrder(op.rotation) {
+		err = c.doEndpoint(ctx, c.endpoints[i], path, v, op)
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if !unavailable(err) {
+			c.setHealthy(i, true)
+
+			return err
+		}
+
+		c.setHealthy(i, false)
+	}
+
+	return err
+}
+
+// isRead reports whether the request is a read of the log, which may be served by its mirrors.
+func isRead(path string, op *options) bool {
+	return op.method == http.MethodGet && strings.HasPrefix(path, rest.AliasPath)
+}
+
+// unavailable reports whether the request failed because the endpoint is unavailable (e.g. network errors and
+// server errors), other errors are the answer of the log.
+func unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var vctErr *Error
+	if errors.As(err, &vctErr) {
+		return vctErr.Status >= http.StatusInternalServerError
+	}
+
+	return true
+}
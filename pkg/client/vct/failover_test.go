@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	primary = "https://vct.com/maple2021"
+	mirror1 = "https://mirror1.vct.com/maple2021"
+	mirror2 = "https://mirror2.vct.com/logs/maple2021"
+)
+
+// hosts answers the requests with the status of their host, the tree head is returned if the status is OK.
+type hosts struct {
+	mu       sync.Mutex
+	status   map[string]int
+	requests []string
+}
+
+func (h *hosts) Do(req *http.Request) (*http.Response, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.requests = append(h.requests, req.URL.Host+req.URL.Path)
+
+	status, ok := h.status[req.URL.Host]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+
+	body := `{"tree_size":2}`
+	if status != http.StatusOK {
+		body = `{"detail":"error"}`
+	}
+
+	return &http.Response{Body: ioutil.NopCloser(bytes.NewBufferString(body)), StatusCode: status}, nil
+}
+
+func (h *hosts) reset() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	requests := h.requests
+	h.requests = nil
+
+	return requests
+}
+
+func TestClient_Failover(t *testing.T) {
+	t.Run("Reads fail over to the mirrors", func(t *testing.T) {
+		h := &hosts{status: map[string]int{
+			"mirror1.vct.com": http.StatusServiceUnavailable,
+			"mirror2.vct.com": http.StatusOK,
+		}}
+
+		client := vct.New(primary, vct.WithHTTPClient(h), vct.WithMirrors(mirror1, mirror2))
+		require.Equal(t, []string{primary, mirror1, mirror2}, client.Endpoints())
+
+		sth, err := client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), sth.TreeSize)
+		require.Equal(t, []string{
+			"vct.com/maple2021/v1/get-sth",
+			"mirror1.vct.com/maple2021/v1/get-sth",
+			"mirror2.vct.com/logs/maple2021/v1/get-sth",
+		}, h.reset())
+
+		// the unhealthy endpoints are tried last
+		_, err = client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"mirror2.vct.com/logs/maple2021/v1/get-sth"}, h.reset())
+
+		// all the endpoints are unavailable
+		h.status["mirror2.vct.com"] = http.StatusBadGateway
+
+		_, err = client.GetSTH(context.Background())
+		require.EqualError(t, err, "get STH: error")
+		require.Len(t, h.reset(), 3)
+
+		// the unhealthy endpoints are tried in turn, an endpoint which recovered is healthy again
+		h.status["vct.com"] = http.StatusOK
+
+		_, err = client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{"vct.com/maple2021/v1/get-sth"}, h.reset())
+
+		h.status["vct.com"] = http.StatusInternalServerError
+
+		_, err = client.GetSTH(context.Background())
+		require.Error(t, err)
+		require.Equal(t, []string{
+			"vct.com/maple2021/v1/get-sth",
+			"mirror1.vct.com/maple2021/v1/get-sth",
+			"mirror2.vct.com/logs/maple2021/v1/get-sth",
+		}, h.reset())
+	})
+
+	t.Run("Answers of the log are not failed over", func(t *testing.T) {
+		h := &hosts{status: map[string]int{
+			"vct.com":         http.StatusNotFound,
+			"mirror1.vct.com": http.StatusOK,
+		}}
+
+		client := vct.New(primary, vct.WithHTTPClient(h), vct.WithMirrors(mirror1))
+
+		_, err := client.GetProofByHash(context.Background(), "hash", 2)
+		require.EqualError(t, err, "get proof by hash: error")
+		require.Equal(t, []string{"vct.com/maple2021/v1/get-proof-by-hash"}, h.reset())
+	})
+
+	t.Run("Writes and the service are primary-only", func(t *testing.T) {
+		h := &hosts{status: map[string]int{"mirror1.vct.com": http.StatusOK}}
+
+		client := vct.New(primary, vct.WithHTTPClient(h), vct.WithMirrors(mirror1))
+
+		_, err := client.AddVC(context.Background(), []byte(`{}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection refused")
+
+		require.Error(t, client.HealthCheck(context.Background()))
+		require.Equal(t, []string{"vct.com/maple2021/v1/add-vc", "vct.com/healthcheck"}, h.reset())
+	})
+
+	t.Run("Hedged request is sent to the next endpoint", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer slow.Close()
+
+		fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewEncoder(w).Encode(command.GetSTHResponse{TreeSize: 3}))
+		}))
+		defer fast.Close()
+
+		client := vct.New(slow.URL+"/maple2021", vct.WithMirrors(fast.URL+"/maple2021"),
+			vct.WithHedging(10*time.Millisecond))
+
+		sth, err := client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), sth.TreeSize)
+	})
+}
@@ -0,0 +1,176 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// ErrLogFrozen is returned for the writes of a frozen log and for the tree sizes beyond its final size: the log
+// accepts no more entries and the proofs beyond its final tree head are not valid.
+var ErrLogFrozen = errors.New("log is frozen")
+
+// WithFinalTreeHead sets the final tree head of the frozen log (see VerifyFinalTreeHead), the tree heads and the
+// proofs beyond the final size are then rejected with ErrLogFrozen.
+func WithFinalTreeHead(final *command.SignedFinalTreeHead) ClientOpt {
+	return func(o *clientOptions) {
+		o.final = final
+	}
+}
+
+// FreezeLog freezes (retires) the log at its latest tree head, writes of the log are rejected for good.
+// The client rejects the tree sizes beyond the returned final tree head, once verified (see pinFinalTreeHead).
+func (c *Client) FreezeLog(ctx context.Context, reason string) (*command.SignedFinalTreeHead, error) {
+	body, err := json.Marshal(command.FreezeLogRequest{Alias: c.alias(), Reason: reason})
+	if err != nil {
+		return nil, fmt.Errorf("marshal freeze log request: %w", err)
+	}
+
+	var result *command.SignedFinalTreeHead
+	if err = c.do(ctx, rest.FreezePath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("freeze log: %w", err)
+	}
+
+	if err = c.pinFinalTreeHead(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetFinalTreeHead retrieves the final tree head of the log, it is nil unless the log is frozen. The final tree
+// head is verified against the public key of the log (see GetPublicKey), the client then rejects the tree sizes
+// beyond the final size. A final tree head which does not verify is rejected and not pinned.
+func (c *Client) GetFinalTreeHead(ctx context.Context) (*command.SignedFinalTreeHead, error) {
+	var result *command.SignedFinalTreeHead
+
+	err := c.do(ctx, rest.FinalTreeHeadPath, &result, withToken(c.authReadToken))
+
+	var vctErr *Error
+	if errors.As(err, &vctErr) && vctErr.Status == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get final tree head: %w", err)
+	}
+
+	if err = c.pinFinalTreeHead(ctx, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// VerifyFinalTreeHead verifies that the final tree head is signed by the key of the log, as well as the signature
+// of its tree head.
+func VerifyFinalTreeHead(signed *command.SignedFinalTreeHead, pubKey []byte) error {
+	statement := signed.Statement
+
+	if statement.SignatureType != command.FreezeSignatureType {
+		return errors.New("statement must be a final tree head")
+	}
+
+	if err := command.VerifySignature(signed.Signature, pubKey, statement); err != nil {
+		return fmt.Errorf("final tree head: %w", err)
+	}
+
+	err := command.VerifySignature(statement.STH.TreeHeadSignature, pubKey, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      statement.STH.Timestamp,
+		TreeSize:       statement.STH.TreeSize,
+		SHA256RootHash: statement.STH.SHA256RootHash,
+	})
+	if err != nil {
+		return fmt.Errorf("tree head signature: %w", err)
+	}
+
+	return nil
+}
+
+// alias returns the alias of the log, the last segment of the path of the endpoint.
+func (c *Client) alias() string {
+	return path.Base(strings.TrimRight(c.endpoints[0].path, "/"))
+}
+
+// pinFinalTreeHead verifies the final tree head against the public key of the log before the client rejects the
+// tree sizes beyond it.
+func (c *Client) pinFinalTreeHead(ctx context.Context, final *command.SignedFinalTreeHead) error {
+	if final == nil {
+		return nil
+	}
+
+	pubKey, err := c.GetPublicKey(ctx)
+	if err != nil {
+		return fmt.Errorf("get public key: %w", err)
+	}
+
+	if err = VerifyFinalTreeHead(final, pubKey); err != nil {
+		return fmt.Errorf("verify final tree head: %w", err)
+	}
+
+	c.setFinalTreeHead(final)
+
+	return nil
+}
+
+func (c *Client) setFinalTreeHead(final *command.SignedFinalTreeHead) {
+	if final == nil {
+		return
+	}
+
+	c.finalMu.Lock()
+	defer c.finalMu.Unlock()
+
+	c.final = final
+}
+
+func (c *Client) getFinalTreeHead() *command.SignedFinalTreeHead {
+	c.finalMu.Lock()
+	defer c.finalMu.Unlock()
+
+	return c.final
+}
+
+// checkTreeSize returns ErrLogFrozen if the log is known to be frozen at a smaller tree size.
+func (c *Client) checkTreeSize(treeSize uint64) error {
+	final := c.getFinalTreeHead()
+	if final == nil || treeSize <= final.Statement.STH.TreeSize {
+		return nil
+	}
+
+	return fmt.Errorf("%w: tree size %d is beyond the final size %d", ErrLogFrozen, treeSize,
+		final.Statement.STH.TreeSize)
+}
+
+// checkTreeHead returns ErrLogFrozen if the log is known to be frozen and the tree head is not its final tree
+// head.
+func (c *Client) checkTreeHead(sth *command.GetSTHResponse) error {
+	if err := c.checkTreeSize(sth.TreeSize); err != nil {
+		return err
+	}
+
+	final := c.getFinalTreeHead()
+	if final != nil && sth.TreeSize == final.Statement.STH.TreeSize &&
+		!bytes.Equal(sth.SHA256RootHash, final.Statement.STH.SHA256RootHash) {
+		return fmt.Errorf("%w: tree head of size %d does not match the final tree head", ErrLogFrozen, sth.TreeSize)
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	vcterrors "github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+func TestClient_FreezeLog(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+
+	sth := command.GetSTHResponse{TreeSize: 10, Timestamp: 1000, SHA256RootHash: []byte("root")}
+	sth.TreeHeadSignature = signStatement(t, key, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+
+	statement := command.FinalTreeHead{
+		Version:       command.V1,
+		SignatureType: command.FreezeSignatureType,
+		Timestamp:     2000,
+		Alias:         "maple2021",
+		STH:           sth,
+		Reason:        "retired",
+	}
+
+	final := &command.SignedFinalTreeHead{Statement: statement, Signature: signStatement(t, key, statement)}
+
+	var (
+		frozen    bool
+		latestSTH = sth
+		served    = final
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/freeze":
+			var req *command.FreezeLogRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, command.FreezeLogRequest{Alias: "maple2021", Reason: "retired"}, *req)
+
+			frozen = true
+
+			_ = json.NewEncoder(w).Encode(final) // nolint: errcheck
+		case "/maple2021/final-sth":
+			if !frozen {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(served) // nolint: errcheck
+		case "/maple2021/.well-known/webfinger":
+			_ = json.NewEncoder(w).Encode(command.WebFingerResponse{ // nolint: errcheck
+				Properties: map[string]interface{}{command.PublicKeyType: base64.StdEncoding.EncodeToString(pubKey)},
+			})
+		case "/maple2021/v1/get-sth":
+			_ = json.NewEncoder(w).Encode(latestSTH) // nolint: errcheck
+		case "/maple2021/v1/get-proof-by-hash":
+			_, _ = w.Write([]byte(`{"leaf_index":1}`))
+		case "/maple2021/v1/add-entry":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"type":"` + vcterrors.ProblemTypeFrozen + `","title":"Gone","status":410}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("Freeze", func(t *testing.T) {
+		client := vct.New(server.URL + "/maple2021")
+
+		notFrozen, er := client.GetFinalTreeHead(ctx)
+		require.NoError(t, er)
+		require.Nil(t, notFrozen)
+
+		_, er = client.GetProofByHash(ctx, "aGFzaA==", 20)
+		require.NoError(t, er)
+
+		frozenAt, er := client.FreezeLog(ctx, "retired")
+		require.NoError(t, er)
+		require.Equal(t, final, frozenAt)
+
+		_, er = client.GetProofByHash(ctx, "aGFzaA==", 20)
+		require.ErrorIs(t, er, vct.ErrLogFrozen)
+		require.EqualError(t, er, "get proof by hash: log is frozen: tree size 20 is beyond the final size 10")
+
+		_, er = client.GetProofByHash(ctx, "aGFzaA==", 10)
+		require.NoError(t, er)
+
+		_, er = client.AddEntry(ctx, command.CommitmentLogEntryType, []byte(`{}`))
+		require.ErrorIs(t, er, vct.ErrLogFrozen)
+	})
+
+	t.Run("Final tree head", func(t *testing.T) {
+		client := vct.New(server.URL + "/maple2021")
+
+		head, er := client.GetSTH(ctx)
+		require.NoError(t, er)
+		require.Equal(t, sth.TreeSize, head.TreeSize)
+
+		retrieved, er := client.GetFinalTreeHead(ctx)
+		require.NoError(t, er)
+		require.Equal(t, final, retrieved)
+		require.NoError(t, vct.VerifyFinalTreeHead(retrieved, pubKey))
+
+		for _, get := range []func() error{
+			func() error {
+				_, e := client.GetSTHConsistency(ctx, 5, 11)
+
+				return e
+			},
+			func() error {
+				_, e := client.GetEntryAndProof(ctx, 1, 11)
+
+				return e
+			},
+			func() error {
+				_, e := client.GetInclusionProof(ctx, 1, 11)
+
+				return e
+			},
+			func() error {
+				_, e := client.GetConsistencyProof(ctx, 5, 11)
+
+				return e
+			},
+			func() error {
+				_, e := client.GetAuditExport(ctx, 5, 11)
+
+				return e
+			},
+		} {
+			require.ErrorIs(t, get(), vct.ErrLogFrozen)
+		}
+
+		latestSTH = command.GetSTHResponse{TreeSize: 11, SHA256RootHash: []byte("another root")}
+
+		_, er = client.GetSTH(ctx)
+		require.EqualError(t, er, "get STH: log is frozen: tree size 11 is beyond the final size 10")
+
+		latestSTH = command.GetSTHResponse{TreeSize: 10, SHA256RootHash: []byte("another root")}
+
+		_, er = client.GetSTH(ctx)
+		require.EqualError(t, er, "get STH: log is frozen: tree head of size 10 does not match the final tree head")
+	})
+
+	t.Run("Final tree head does not verify", func(t *testing.T) {
+		tampered := *final
+		tampered.Statement.STH.TreeSize = 5
+
+		served = &tampered
+		defer func() { served = final }()
+
+		client := vct.New(server.URL + "/maple2021")
+
+		_, er := client.GetFinalTreeHead(ctx)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "verify final tree head")
+
+		// the final tree head is not pinned
+		_, er = client.GetProofByHash(ctx, "aGFzaA==", 10)
+		require.NoError(t, er)
+	})
+
+	t.Run("Pinned final tree head", func(t *testing.T) {
+		client := vct.New(server.URL+"/maple2021", vct.WithFinalTreeHead(final))
+
+		_, er := client.GetProofByHash(ctx, "aGFzaA==", 11)
+		require.ErrorIs(t, er, vct.ErrLogFrozen)
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		tampered := *final
+		tampered.Statement.STH.TreeSize = 20
+		require.Contains(t, vct.VerifyFinalTreeHead(&tampered, pubKey).Error(), "final tree head: verify")
+
+		badSTH := statement
+		badSTH.STH.SHA256RootHash = []byte("another root")
+		require.Contains(t, vct.VerifyFinalTreeHead(&command.SignedFinalTreeHead{
+			Statement: badSTH,
+			Signature: signStatement(t, key, badSTH),
+		}, pubKey).Error(), "tree head signature")
+
+		tampered.Statement.SignatureType = command.TreeHeadSignatureType
+		require.EqualError(t, vct.VerifyFinalTreeHead(&tampered, pubKey), "statement must be a final tree head")
+	})
+}
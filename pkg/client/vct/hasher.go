@@ -0,0 +1,54 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+)
+
+// Hasher computes the hashes of the Merkle tree of the log. It has the method set of the log hasher of trillian
+// (hashers.LogHasher), so forks of the log using e.g. domain-separated or prefixed hashing can provide their own.
+type Hasher interface {
+	// EmptyRoot returns the root hash of an empty tree.
+	EmptyRoot() []byte
+	// HashLeaf returns the hash of a leaf.
+	HashLeaf(leaf []byte) []byte
+	// HashChildren returns the hash of an interior node.
+	HashChildren(l, r []byte) []byte
+	// Size returns the number of bytes of a hash.
+	Size() int
+}
+
+// Verifier verifies the inclusion and consistency proofs of the log, logverifier.LogVerifier implements it.
+type Verifier interface {
+	VerifyInclusionProof(leafIndex, treeSize int64, proof [][]byte, root, leafHash []byte) error
+	VerifyConsistencyProof(snapshot1, snapshot2 int64, root1, root2 []byte, proof [][]byte) error
+}
+
+// DefaultHasher is the RFC 6962 hasher used by default.
+var DefaultHasher Hasher = hasher.DefaultHasher // nolint: gochecknoglobals
+
+// NewVerifier returns the verifier of the proofs of a tree of the hasher.
+func NewVerifier(h Hasher) Verifier {
+	return logverifier.New(h)
+}
+
+// WithHasher sets the hasher of the tree, used to compute the leaf hashes and the proofs from the tiles.
+// Defaults to DefaultHasher. The verifier defaults to the one of the hasher (NewVerifier).
+func WithHasher(h Hasher) ClientOpt {
+	return func(o *clientOptions) {
+		o.hasher = h
+	}
+}
+
+// WithVerifier sets the verifier of the proofs, e.g. to verify the proofs of a tree of another shape.
+func WithVerifier(v Verifier) ClientOpt {
+	return func(o *clientOptions) {
+		o.verifier = v
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+// prefixedHasher is a domain-separated hasher, the hashes are prefixed by the domain.
+type prefixedHasher struct {
+	domain string
+}
+
+func (h prefixedHasher) hash(prefix byte, data ...[]byte) []byte {
+	s := sha256.New()
+	_, _ = s.Write([]byte(h.domain))
+	_, _ = s.Write([]byte{prefix})
+
+	for _, d := range data {
+		_, _ = s.Write(d)
+	}
+
+	return s.Sum(nil)
+}
+
+func (h prefixedHasher) EmptyRoot() []byte {
+	return h.hash(0xff)
+}
+
+func (h prefixedHasher) HashLeaf(leaf []byte) []byte {
+	return h.hash(0, leaf)
+}
+
+func (h prefixedHasher) HashChildren(l, r []byte) []byte {
+	return h.hash(1, l, r)
+}
+
+func (h prefixedHasher) Size() int {
+	return sha256.Size
+}
+
+func TestClient_CalculateLeafHash(t *testing.T) {
+	hash, err := vct.New("").CalculateLeafHash(12345, simpleVC)
+	require.NoError(t, err)
+	require.Equal(t, "IamzE8Fm5W3ToLgZWlqVHPqgBLiBompVIyGLWDo0SP8=", hash)
+
+	hash, err = vct.New("", vct.WithHasher(prefixedHasher{domain: "fork"})).CalculateLeafHash(12345, simpleVC)
+	require.NoError(t, err)
+	require.NotEqual(t, "IamzE8Fm5W3ToLgZWlqVHPqgBLiBompVIyGLWDo0SP8=", hash)
+}
+
+func TestWithHasher(t *testing.T) {
+	leaves := make([][]byte, 300)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+
+	h := prefixedHasher{domain: "fork"}
+	verifier := vct.NewVerifier(h)
+
+	ts := httptest.NewServer(&tileServer{leaves: leaves, hasher: h, tiles: true})
+	defer ts.Close()
+
+	client := vct.New(ts.URL+"/maple2021", vct.WithHasher(h))
+
+	proof, err := client.GetInclusionProof(context.Background(), 5, 300)
+	require.NoError(t, err)
+	require.NoError(t, verifier.VerifyInclusionProof(5, 300, proof, treeHash(h, leaves), h.HashLeaf(leaves[5])))
+
+	proof, err = client.GetConsistencyProof(context.Background(), 7, 300)
+	require.NoError(t, err)
+	require.NoError(t, verifier.VerifyConsistencyProof(7, 300, treeHash(h, leaves[:7]), treeHash(h, leaves), proof))
+
+	// the proof computed with RFC 6962 does not verify against the tree of the hasher
+	proof, err = vct.New(ts.URL+"/maple2021").GetInclusionProof(context.Background(), 5, 300)
+	require.NoError(t, err)
+	require.Error(t, verifier.VerifyInclusionProof(5, 300, proof, treeHash(h, leaves), h.HashLeaf(leaves[5])))
+}
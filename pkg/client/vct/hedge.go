@@ -0,0 +1,84 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// WithHedging enables hedged reads of GetSTH and GetProofByHash: if the request is not answered within the delay,
+// a second request is sent and the first successful response is used, the other request is canceled.
+// It cuts the tail latency of the reads when the endpoint is served by several replicas, the second request is
+// sent to the next endpoint if the log has mirrors (see WithMirrors).
+// Hedging is disabled if the delay is not positive (default).
+func WithHedging(delay time.Duration) ClientOpt {
+	return func(o *clientOptions) {
+		o.hedgeDelay = delay
+	}
+}
+
+type hedgedResult struct {
+	raw []byte
+	err error
+}
+
+// doHedged does the read request, hedged if hedging is enabled. The error of the last failed request is returned
+// if none succeeded.
+func (c *Client) doHedged(ctx context.Context, path string, v interface{}, opts ...opt) error {
+	if c.hedgeDelay <= 0 {
+		return c.do(ctx, path, v, opts...)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgedResult, 2) // nolint: gomnd
+
+	send := func(sendOpts ...opt) {
+		go func() {
+			var raw []byte
+
+			err := c.do(ctx, path, &raw, sendOpts...)
+
+			results <- hedgedResult{raw: raw, err: err}
+		}()
+	}
+
+	send(opts...)
+
+	timer := time.NewTimer(c.hedgeDelay)
+	defer timer.Stop()
+
+	pending := 1
+
+	for {
+		select {
+		case <-timer.C:
+			send(append(opts[:len(opts):len(opts)], withRotation(1))...)
+
+			pending++
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				if err := json.Unmarshal(res.raw, v); err != nil {
+					return fmt.Errorf("unmarshal response: %w", err)
+				}
+
+				return nil
+			}
+
+			// a request which fails before the delay is not hedged
+			if pending == 0 {
+				return res.err
+			}
+		}
+	}
+}
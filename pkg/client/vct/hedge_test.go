@@ -0,0 +1,104 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// newReplicas serves the log by replicas answering the requests in turn, the first request is not answered
+// until it is canceled if slow is set, and fails otherwise.
+func newReplicas(t *testing.T, slow bool) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			if slow {
+				<-r.Context().Done()
+
+				return
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if r.URL.Path == "/maple2021/v1/get-sth" {
+			require.NoError(t, json.NewEncoder(w).Encode(command.GetSTHResponse{TreeSize: 2}))
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(command.GetProofByHashResponse{LeafIndex: 1}))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestClient_Hedging(t *testing.T) {
+	t.Run("Slow request is hedged", func(t *testing.T) {
+		server, requests := newReplicas(t, true)
+
+		client := vct.New(server.URL+"/maple2021", vct.WithHedging(10*time.Millisecond))
+
+		sth, err := client.GetSTH(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), sth.TreeSize)
+		require.Equal(t, int32(2), atomic.LoadInt32(requests))
+
+		proof, err := client.GetProofByHash(context.Background(), "hash", 2)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), proof.LeafIndex)
+		require.Equal(t, int32(3), atomic.LoadInt32(requests))
+	})
+
+	t.Run("Failed request is not hedged", func(t *testing.T) {
+		server, requests := newReplicas(t, false)
+
+		client := vct.New(server.URL+"/maple2021", vct.WithHedging(time.Minute))
+
+		_, err := client.GetSTH(context.Background())
+		require.Error(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("Hedging disabled", func(t *testing.T) {
+		server, requests := newReplicas(t, false)
+
+		_, err := vct.New(server.URL + "/maple2021").GetSTH(context.Background())
+		require.Error(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(requests))
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		server, _ := newReplicas(t, true)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		client := vct.New(server.URL+"/maple2021", vct.WithHedging(time.Minute))
+
+		_, err := client.GetSTH(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,290 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+var (
+	// ErrPolicyNotSatisfied is returned when a credential did not get the SCTs required by the logging policy.
+	ErrPolicyNotSatisfied = errors.New("logging policy is not satisfied")
+	// ErrLogNotAllowed is the error of the result of a log the credential is not submitted to by the policy.
+	ErrLogNotAllowed = errors.New("log is not allowed")
+)
+
+// Policy is the logging policy of an ecosystem a credential must satisfy.
+type Policy struct {
+	// RequiredSCTs is the number of valid SCTs of distinct allowed logs a credential must get.
+	// If zero, an SCT of every allowed log is required.
+	RequiredSCTs int
+	// AllowedLogs are the base64-encoded IDs of the logs whose SCTs are accepted. If empty, all logs are allowed.
+	// The credential is not submitted to logs which are not allowed.
+	AllowedLogs []string
+	// MaxSCTAge is the max age of an accepted SCT. If zero, SCTs of any age are accepted.
+	MaxSCTAge time.Duration
+	// MaxClockSkew is the tolerated skew between the clocks of the logs and the local clock: SCTs up to
+	// MaxSCTAge+MaxClockSkew old are accepted and, if not zero, SCTs dated more than MaxClockSkew in the future
+	// are rejected.
+	MaxClockSkew time.Duration
+}
+
+// SCTResult is the outcome of the submission of a credential to a log.
+type SCTResult struct {
+	Endpoint string
+	// LogID is the base64-encoded ID of the log, empty if the log could not be discovered.
+	LogID string
+	// SCT is the signed credential timestamp of the log, nil if the submission failed or was skipped.
+	SCT *command.AddVCResponse
+	// Err is the reason the SCT is not accepted by the policy, nil if it is accepted.
+	Err error
+}
+
+// MultiClient submits credentials to several logs and enforces the logging policy on the SCTs they return.
+type MultiClient struct {
+	clients []*Client
+	policy  Policy
+
+	mu         sync.Mutex
+	publicKeys map[*Client][]byte
+}
+
+// NewMultiClient returns a client submitting to the logs of the clients.
+func NewMultiClient(clients []*Client, policy Policy) (*MultiClient, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no logs")
+	}
+
+	if policy.RequiredSCTs < 0 || policy.RequiredSCTs > len(clients) {
+		return nil, fmt.Errorf("required SCTs %d must be between 0 and the number of logs %d",
+			policy.RequiredSCTs, len(clients))
+	}
+
+	if policy.MaxSCTAge < 0 {
+		return nil, fmt.Errorf("max SCT age %s must not be negative", policy.MaxSCTAge)
+	}
+
+	if policy.MaxClockSkew < 0 {
+		return nil, fmt.Errorf("max clock skew %s must not be negative", policy.MaxClockSkew)
+	}
+
+	for _, logID := range policy.AllowedLogs {
+		if _, err := base64.StdEncoding.DecodeString(logID); err != nil {
+			return nil, fmt.Errorf("allowed log %q is not base64-encoded: %w", logID, err)
+		}
+	}
+
+	return &MultiClient{
+		clients:    clients,
+		policy:     policy,
+		publicKeys: map[*Client][]byte{},
+	}, nil
+}
+
+// AddVC submits the credential to the allowed logs and verifies the returned SCTs.
+// The results of all logs are returned, the error wraps ErrPolicyNotSatisfied if the credential did not get
+// the required SCTs.
+func (m *MultiClient) AddVC(ctx context.Context, vc *verifiable.Credential, opts ...AddVCOpt) ([]SCTResult, error) {
+	credential, err := json.Marshal(vc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal credential: %w", err)
+	}
+
+	results := make([]SCTResult, len(m.clients))
+
+	var wg sync.WaitGroup
+
+	for i, client := range m.clients {
+		wg.Add(1)
+
+		go func(i int, client *Client) {
+			defer wg.Done()
+
+			results[i] = m.addVC(ctx, client, vc, credential, opts)
+		}(i, client)
+	}
+
+	wg.Wait()
+
+	var (
+		allowed  int
+		accepted = map[string]bool{}
+		reasons  []string
+	)
+
+	for _, result := range results {
+		if !errors.Is(result.Err, ErrLogNotAllowed) {
+			allowed++
+		}
+
+		if result.Err == nil {
+			// SCTs of the same log count once.
+			accepted[result.LogID] = true
+
+			continue
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s: %v", result.Endpoint, result.Err))
+	}
+
+	required := m.policy.RequiredSCTs
+	if required == 0 {
+		required = allowed
+	}
+
+	if required == 0 {
+		return results, fmt.Errorf("%w: no allowed logs", ErrPolicyNotSatisfied)
+	}
+
+	if len(accepted) < required {
+		return results, fmt.Errorf("%w: got %d of %d required SCTs: %s", ErrPolicyNotSatisfied, len(accepted),
+			required, strings.Join(reasons, "; "))
+	}
+
+	return results, nil
+}
+
+func (m *MultiClient) addVC(ctx context.Context, client *Client, vc *verifiable.Credential, credential []byte,
+	opts []AddVCOpt) SCTResult {
+	result := SCTResult{Endpoint: client.endpoint}
+
+	pubKey, err := m.publicKey(ctx, client)
+	if err != nil {
+		result.Err = err
+
+		return result
+	}
+
+	logID := command.LogID(pubKey)
+	result.LogID = base64.StdEncoding.EncodeToString(logID[:])
+
+	if len(m.policy.AllowedLogs) > 0 && !containsString(m.policy.AllowedLogs, result.LogID) {
+		result.Err = ErrLogNotAllowed
+
+		return result
+	}
+
+	result.SCT, err = client.AddVC(ctx, credential, opts...)
+	if err != nil {
+		result.Err = err
+
+		return result
+	}
+
+	result.Err = m.verifySCT(result.SCT, pubKey, vc)
+
+	return result
+}
+
+func (m *MultiClient) verifySCT(sct *command.AddVCResponse, pubKey []byte, vc *verifiable.Credential) error {
+	if err := VerifySCT(sct, pubKey, vc); err != nil {
+		return err
+	}
+
+	age := time.Since(time.Unix(0, int64(sct.Timestamp)*int64(time.Millisecond)))
+
+	if m.policy.MaxSCTAge > 0 && age > m.policy.MaxSCTAge+m.policy.MaxClockSkew {
+		return fmt.Errorf("SCT is older than %s", m.policy.MaxSCTAge)
+	}
+
+	if m.policy.MaxClockSkew > 0 && -age > m.policy.MaxClockSkew {
+		return fmt.Errorf("SCT is dated %s in the future", (-age).Round(time.Second))
+	}
+
+	return nil
+}
+
+// VerifySCT verifies that the SCT of the credential is issued by the log of the public key (its ID is derived
+// from the key) and verifies its signature, including the extensions of the entry and of the SCT.
+func VerifySCT(sct *command.AddVCResponse, pubKey []byte, vc *verifiable.Credential) error {
+	if logID := command.LogID(pubKey); !bytes.Equal(sct.ID, logID[:]) {
+		return errors.New("SCT is issued by another log")
+	}
+
+	var leafOpts []LeafOpt
+
+	if sct.Extensions != "" {
+		extensions, err := base64.StdEncoding.DecodeString(sct.Extensions)
+		if err != nil {
+			return fmt.Errorf("decode extensions: %w", err)
+		}
+
+		leafOpts = append(leafOpts, WithExtensions(extensions))
+	}
+
+	err := verifyVCTimestampSignature(sct.Signature, pubKey, sct.Timestamp, vc, sct.SCTExtensions, leafOpts)
+	if err != nil {
+		return fmt.Errorf("verify SCT signature: %w", err)
+	}
+
+	return nil
+}
+
+// publicKey returns the public key of the log as published by its webfinger.
+func (m *MultiClient) publicKey(ctx context.Context, client *Client) ([]byte, error) {
+	m.mu.Lock()
+	pubKey, ok := m.publicKeys[client]
+	m.mu.Unlock()
+
+	if ok {
+		return pubKey, nil
+	}
+
+	pubKey, err := client.GetPublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.publicKeys[client] = pubKey
+	m.mu.Unlock()
+
+	return pubKey, nil
+}
+
+// Retryable returns true if a log failed to return an SCT for a reason which may not persist, the submission
+// may then satisfy the policy if it is retried. SCTs which fail verification, errors of the server which are not
+// retryable (see Error.Retryable, e.g. the writes of a frozen log) and compromised logs are not retried.
+func Retryable(results []SCTResult) bool {
+	for _, result := range results {
+		if result.Err == nil || result.SCT != nil || errors.Is(result.Err, ErrLogNotAllowed) ||
+			errors.Is(result.Err, ErrLogCompromised) {
+			continue
+		}
+
+		var vctErr *Error
+		if errors.As(result.Err, &vctErr) && !vctErr.Retryable() {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, val := range values {
+		if val == v {
+			return true
+		}
+	}
+
+	return false
+}
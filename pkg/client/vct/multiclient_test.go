@@ -0,0 +1,226 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	goerrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// sctSignature is the signature of the bachelor degree credential logged at sctTimestamp by the log of sctPubKey.
+const (
+	sctSignature = `{"algorithm":{"hash":"SHA256","signature":"ECDSA","type":"ECDSAP256IEEEP1363"},` +
+		`"signature":"l8NfxVChPH7fG4cId6iNIbgpbRzxov+rwozdL4r5lRNXGiOTy7iAn2+Zg84VwkJoeJWvLGyO2a3WZnQKtNu/Lg=="}`
+	sctTimestamp = 1619006293939
+)
+
+// nolint: gochecknoglobals
+var sctPubKey = []byte{
+	4, 185, 70, 232, 62, 166, 17, 233, 172, 19, 143, 227, 170, 181, 184, 202, 177, 242, 247, 199, 73, 209,
+	108, 207, 87, 26, 199, 162, 21, 140, 117, 0, 143, 48, 20, 118, 255, 221, 200, 185, 227, 42, 213, 124,
+	156, 109, 160, 211, 29, 245, 44, 128, 46, 88, 117, 88, 240, 223, 241, 24, 209, 87, 214, 115, 101,
+}
+
+// newLog returns a log server publishing the public key and returning the signature for any credential.
+func newLog(t *testing.T, pubKey []byte, signature string) *httptest.Server {
+	t.Helper()
+
+	logID := sha256.Sum256(pubKey)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/.well-known/webfinger"):
+			require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+				Properties: map[string]interface{}{command.PublicKeyType: pubKey, command.LogIDType: logID[:]},
+			}))
+		case strings.HasSuffix(r.URL.Path, "/v1/add-vc"):
+			require.NoError(t, json.NewEncoder(w).Encode(command.AddVCResponse{
+				SVCTVersion: command.V1,
+				ID:          logID[:],
+				Timestamp:   sctTimestamp,
+				Signature:   []byte(signature),
+			}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestNewMultiClient(t *testing.T) {
+	client := vct.New(endpoint)
+
+	_, err := vct.NewMultiClient(nil, vct.Policy{})
+	require.EqualError(t, err, "no logs")
+
+	_, err = vct.NewMultiClient([]*vct.Client{client}, vct.Policy{RequiredSCTs: 2})
+	require.EqualError(t, err, "required SCTs 2 must be between 0 and the number of logs 1")
+
+	_, err = vct.NewMultiClient([]*vct.Client{client}, vct.Policy{MaxSCTAge: -time.Second})
+	require.EqualError(t, err, "max SCT age -1s must not be negative")
+
+	_, err = vct.NewMultiClient([]*vct.Client{client}, vct.Policy{MaxClockSkew: -time.Second})
+	require.EqualError(t, err, "max clock skew -1s must not be negative")
+
+	_, err = vct.NewMultiClient([]*vct.Client{client}, vct.Policy{AllowedLogs: []string{"log"}})
+	require.Contains(t, err.Error(), `allowed log "log" is not base64-encoded`)
+}
+
+func TestMultiClient_AddVC(t *testing.T) {
+	bachelorDegree, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(getLoader(t)),
+	)
+	require.NoError(t, err)
+
+	logID := sha256.Sum256(sctPubKey)
+
+	valid := vct.New(newLog(t, sctPubKey, sctSignature).URL + "/maple2021")
+	invalid := vct.New(newLog(t, sctPubKey, `{}`).URL + "/maple2021")
+	other := vct.New(newLog(t, []byte("other key"), sctSignature).URL + "/maple2021")
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(notFound.Close)
+
+	down := vct.New(notFound.URL + "/maple2021")
+
+	t.Run("Success", func(t *testing.T) {
+		client, err := vct.NewMultiClient([]*vct.Client{valid, other}, vct.Policy{
+			RequiredSCTs: 1,
+			AllowedLogs:  []string{base64.StdEncoding.EncodeToString(logID[:])},
+		})
+		require.NoError(t, err)
+
+		results, err := client.AddVC(context.Background(), bachelorDegree)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.NoError(t, results[0].Err)
+		require.Equal(t, base64.StdEncoding.EncodeToString(logID[:]), results[0].LogID)
+		require.Equal(t, uint64(sctTimestamp), results[0].SCT.Timestamp)
+
+		// the credential is not submitted to the log which is not allowed
+		require.EqualError(t, results[1].Err, "log is not allowed")
+		require.Nil(t, results[1].SCT)
+	})
+
+	t.Run("Every log required", func(t *testing.T) {
+		client, err := vct.NewMultiClient([]*vct.Client{valid, invalid, down}, vct.Policy{})
+		require.NoError(t, err)
+
+		results, err := client.AddVC(context.Background(), bachelorDegree)
+		require.True(t, goerrors.Is(err, vct.ErrPolicyNotSatisfied))
+		require.Contains(t, err.Error(), "got 1 of 3 required SCTs")
+		require.Len(t, results, 3)
+		require.NoError(t, results[0].Err)
+		require.Contains(t, results[1].Err.Error(), "verify SCT signature")
+		require.Contains(t, results[2].Err.Error(), "webfinger")
+	})
+
+	t.Run("SCTs of the same log count once", func(t *testing.T) {
+		client, err := vct.NewMultiClient([]*vct.Client{valid, valid}, vct.Policy{RequiredSCTs: 2})
+		require.NoError(t, err)
+
+		_, err = client.AddVC(context.Background(), bachelorDegree)
+		require.Contains(t, err.Error(), "got 1 of 2 required SCTs")
+	})
+
+	t.Run("SCT is too old", func(t *testing.T) {
+		client, err := vct.NewMultiClient([]*vct.Client{valid}, vct.Policy{MaxSCTAge: time.Hour})
+		require.NoError(t, err)
+
+		results, err := client.AddVC(context.Background(), bachelorDegree)
+		require.True(t, goerrors.Is(err, vct.ErrPolicyNotSatisfied))
+		require.EqualError(t, results[0].Err, "SCT is older than 1h0m0s")
+
+		// the SCT is accepted within the tolerated clock skew
+		client, err = vct.NewMultiClient([]*vct.Client{valid}, vct.Policy{
+			MaxSCTAge:    time.Hour,
+			MaxClockSkew: time.Since(time.Unix(0, sctTimestamp*int64(time.Millisecond))),
+		})
+		require.NoError(t, err)
+
+		_, err = client.AddVC(context.Background(), bachelorDegree)
+		require.NoError(t, err)
+	})
+
+	t.Run("No allowed logs", func(t *testing.T) {
+		client, err := vct.NewMultiClient([]*vct.Client{other}, vct.Policy{
+			AllowedLogs: []string{base64.StdEncoding.EncodeToString(logID[:])},
+		})
+		require.NoError(t, err)
+
+		_, err = client.AddVC(context.Background(), bachelorDegree)
+		require.EqualError(t, err, "logging policy is not satisfied: no allowed logs")
+	})
+
+	t.Run("Marshal credential", func(t *testing.T) {
+		client, err := vct.NewMultiClient([]*vct.Client{valid}, vct.Policy{})
+		require.NoError(t, err)
+
+		_, err = client.AddVC(context.Background(), &verifiable.Credential{Subject: make(chan int)})
+		require.Contains(t, err.Error(), "marshal credential")
+	})
+}
+
+func TestVerifySCT(t *testing.T) {
+	bachelorDegree, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(getLoader(t)),
+	)
+	require.NoError(t, err)
+
+	logID := command.LogID(sctPubKey)
+
+	sct := &command.AddVCResponse{
+		SVCTVersion: command.V1,
+		ID:          logID[:],
+		Timestamp:   sctTimestamp,
+		Signature:   []byte(sctSignature),
+	}
+
+	require.NoError(t, vct.VerifySCT(sct, sctPubKey, bachelorDegree))
+
+	// the SCT is bound to the key of the log, not to its endpoint
+	require.EqualError(t, vct.VerifySCT(sct, []byte("other key"), bachelorDegree), "SCT is issued by another log")
+
+	sct.ID = []byte("other log")
+	require.EqualError(t, vct.VerifySCT(sct, sctPubKey, bachelorDegree), "SCT is issued by another log")
+}
+
+func TestRetryable(t *testing.T) {
+	sct := &command.AddVCResponse{}
+
+	require.False(t, vct.Retryable(nil))
+	require.False(t, vct.Retryable([]vct.SCTResult{{SCT: sct}, {Err: vct.ErrLogNotAllowed}}))
+	require.False(t, vct.Retryable([]vct.SCTResult{{Err: vct.ErrLogCompromised}}))
+	require.False(t, vct.Retryable([]vct.SCTResult{{Err: &vct.Error{Status: http.StatusBadRequest}}}))
+	require.False(t, vct.Retryable([]vct.SCTResult{{SCT: sct, Err: goerrors.New("verify SCT signature")}}))
+
+	require.True(t, vct.Retryable([]vct.SCTResult{{SCT: sct}, {Err: &vct.Error{Status: http.StatusBadGateway}}}))
+	require.True(t, vct.Retryable([]vct.SCTResult{{Err: goerrors.New("connection refused")}}))
+	require.True(t, vct.Retryable([]vct.SCTResult{{Err: &vct.Error{Status: http.StatusTooManyRequests}}}))
+	require.False(t, vct.Retryable([]vct.SCTResult{{Err: &vct.Error{Status: http.StatusNotImplemented}}}))
+}
@@ -0,0 +1,117 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CallMetrics are the metrics of a call of the client to an endpoint of the log.
+type CallMetrics struct {
+	// Endpoint is the endpoint called: the endpoint passed to New or a mirror.
+	Endpoint string
+	Method   string
+	// Path is the path of the API called, with the alias as rest.AliasPath (e.g. rest.AddVCPath), so it can label
+	// the metrics of every log.
+	Path     string
+	Duration time.Duration
+	// Status is the status of the response, zero if no response was received.
+	Status int
+	// RequestBytes is the length of the body of the request.
+	RequestBytes int64
+	// ResponseBytes is the number of bytes of the body of the response read by the client.
+	ResponseBytes int64
+	// Err is the error of the call if no response was received, e.g. the endpoint is unreachable. The errors
+	// answered by the log are reported by their status.
+	Err error
+}
+
+// Observer observes the calls of the client, e.g. to report their metrics to the metrics system of the service.
+// ObserveCall is called once per call when its response is read, concurrently for concurrent calls.
+type Observer interface {
+	ObserveCall(metrics CallMetrics)
+}
+
+// ObserverFunc is an Observer calling the function.
+type ObserverFunc func(metrics CallMetrics)
+
+// ObserveCall calls the function.
+func (f ObserverFunc) ObserveCall(metrics CallMetrics) {
+	f(metrics)
+}
+
+// WithObserver sets the observer of the calls of the client. Reads failing over to mirrors and hedged reads are
+// observed per endpoint called.
+func WithObserver(observer Observer) ClientOpt {
+	return func(o *clientOptions) {
+		o.observer = observer
+	}
+}
+
+// send sends the request to the endpoint, the call is observed once the body of its response is closed.
+func (c *Client) send(endpoint logEndpoint, path string, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		err = fmt.Errorf("http do: %w", err)
+
+		if c.observer != nil {
+			c.observer.ObserveCall(CallMetrics{
+				Endpoint:     endpoint.url,
+				Method:       req.Method,
+				Path:         path,
+				Duration:     time.Since(start),
+				RequestBytes: req.ContentLength,
+				Err:          err,
+			})
+		}
+
+		return nil, err
+	}
+
+	if c.observer != nil {
+		resp.Body = &observedBody{ReadCloser: resp.Body, observer: c.observer, metrics: CallMetrics{
+			Endpoint:     endpoint.url,
+			Method:       req.Method,
+			Path:         path,
+			Status:       resp.StatusCode,
+			RequestBytes: req.ContentLength,
+		}, start: start}
+	}
+
+	return resp, nil
+}
+
+// observedBody counts the bytes of the body read and observes the call when the body is closed.
+type observedBody struct {
+	io.ReadCloser
+	observer Observer
+	metrics  CallMetrics
+	start    time.Time
+	once     sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.metrics.ResponseBytes += int64(n)
+
+	return n, err // nolint: wrapcheck
+}
+
+func (b *observedBody) Close() error {
+	b.once.Do(func() {
+		b.metrics.Duration = time.Since(b.start)
+		b.observer.ObserveCall(b.metrics)
+	})
+
+	return b.ReadCloser.Close() // nolint: wrapcheck
+}
@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+type callRecorder struct {
+	mu    sync.Mutex
+	calls []vct.CallMetrics
+}
+
+func (r *callRecorder) ObserveCall(metrics vct.CallMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, metrics)
+}
+
+func TestWithObserver(t *testing.T) {
+	const sth = `{"tree_size":1}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/v1/get-sth"):
+			_, err := w.Write([]byte(sth))
+			require.NoError(t, err)
+		case strings.HasSuffix(r.URL.Path, "/v1/add-vc"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer ts.Close()
+
+	recorder := &callRecorder{}
+	client := vct.New(ts.URL+"/maple2021", vct.WithObserver(recorder))
+
+	_, err := client.GetSTH(context.Background())
+	require.NoError(t, err)
+
+	_, err = client.AddVC(context.Background(), []byte(`{}`))
+	require.Error(t, err)
+
+	require.NoError(t, client.HealthCheck(context.Background()))
+
+	require.Len(t, recorder.calls, 3)
+
+	require.Equal(t, ts.URL+"/maple2021", recorder.calls[0].Endpoint)
+	require.Equal(t, http.MethodGet, recorder.calls[0].Method)
+	require.Equal(t, rest.GetSTHPath, recorder.calls[0].Path)
+	require.Equal(t, http.StatusOK, recorder.calls[0].Status)
+	require.Equal(t, int64(len(sth)), recorder.calls[0].ResponseBytes)
+	require.Positive(t, recorder.calls[0].Duration)
+	require.NoError(t, recorder.calls[0].Err)
+
+	require.Equal(t, http.MethodPost, recorder.calls[1].Method)
+	require.Equal(t, rest.AddVCPath, recorder.calls[1].Path)
+	require.Equal(t, http.StatusServiceUnavailable, recorder.calls[1].Status)
+	require.Equal(t, int64(len(`{}`)), recorder.calls[1].RequestBytes)
+
+	require.Equal(t, rest.HealthCheckPath, recorder.calls[2].Path)
+
+	t.Run("Unreachable endpoint", func(t *testing.T) {
+		recorder := &callRecorder{}
+		client := vct.New("http://127.0.0.1:1/maple2021", vct.WithObserver(vct.ObserverFunc(recorder.ObserveCall)))
+
+		_, err := client.GetSTH(context.Background())
+		require.Error(t, err)
+
+		require.Len(t, recorder.calls, 1)
+		require.Zero(t, recorder.calls[0].Status)
+		require.Contains(t, recorder.calls[0].Err.Error(), "http do")
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// PageOpt represents the pagination option of a list request.
+type PageOpt func(*command.PageRequest)
+
+// WithPageSize sets the size of the page, at most command.MaxPageSize.
+func WithPageSize(size int) PageOpt {
+	return func(page *command.PageRequest) {
+		page.PageSize = size
+	}
+}
+
+// WithPageToken requests the page of the token, the next page token of the previous page.
+func WithPageToken(token string) PageOpt {
+	return func(page *command.PageRequest) {
+		page.PageToken = token
+	}
+}
+
+func pageRequest(opts []PageOpt) command.PageRequest {
+	var page command.PageRequest
+
+	for _, fn := range opts {
+		fn(&page)
+	}
+
+	return page
+}
+
+// pageValues returns the query parameters of the page, none if the whole list is requested.
+func pageValues(page command.PageRequest) []opt {
+	var opts []opt
+
+	if page.PageSize != 0 {
+		opts = append(opts, withValueAdd("page_size", strconv.Itoa(page.PageSize)))
+	}
+
+	if page.PageToken != "" {
+		opts = append(opts, withValueAdd("page_token", page.PageToken))
+	}
+
+	return opts
+}
+
+// GetIssuersPage returns a page of the issuers, of command.DefaultPageSize issuers if the page size is not set.
+func (c *Client) GetIssuersPage(ctx context.Context, opts ...PageOpt) (*command.GetIssuersResponse, error) {
+	page := pageRequest(opts)
+	if page.PageSize == 0 {
+		page.PageSize = command.DefaultPageSize
+	}
+
+	var result *command.GetIssuersResponse
+	if err := c.do(ctx, rest.GetIssuersPath, &result,
+		append(pageValues(page), withToken(c.authReadToken))...); err != nil {
+		return nil, fmt.Errorf("get issuers: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+func TestClient_GetIssuersPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "/v1/get-issuers", req.URL.Path)
+		require.Equal(t, "100", req.URL.Query().Get("page_size"))
+		require.Empty(t, req.URL.Query().Get("page_token"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"issuers":["issuer_1"],"next_page_token":"next"}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "2", req.URL.Query().Get("page_size"))
+		require.Equal(t, "next", req.URL.Query().Get("page_token"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"issuers":["issuer_2"]}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+	page, err := client.GetIssuersPage(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"issuer_1"}, page.Issuers)
+	require.Equal(t, "next", page.NextPageToken)
+
+	page, err = client.GetIssuersPage(context.Background(), vct.WithPageSize(2), vct.WithPageToken(page.NextPageToken))
+	require.NoError(t, err)
+	require.Equal(t, []string{"issuer_2"}, page.Issuers)
+	require.Empty(t, page.NextPageToken)
+}
+
+func TestClient_GetRevocationsPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+		require.Equal(t, "aGFzaA==", req.URL.Query().Get("leaf_hash"))
+		require.Equal(t, "token", req.URL.Query().Get("page_token"))
+		require.Empty(t, req.URL.Query().Get("page_size"))
+	}).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"leaf_hash":"aGFzaA==","events":[],"next_page_token":"t"}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	revocations, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).GetRevocations(context.Background(),
+		"aGFzaA==", vct.WithPageToken("token"))
+	require.NoError(t, err)
+	require.Equal(t, "t", revocations.NextPageToken)
+}
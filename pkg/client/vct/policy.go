@@ -0,0 +1,78 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// PublishPolicy publishes the policy of the log as its next version, the latest version is returned unchanged if
+// the policy is the same.
+func (c *Client) PublishPolicy(ctx context.Context, policy *command.LogPolicy) (*command.SignedPolicyDocument, error) {
+	body, err := json.Marshal(command.PublishPolicyRequest{Alias: c.alias(), Policy: *policy})
+	if err != nil {
+		return nil, fmt.Errorf("marshal publish policy request: %w", err)
+	}
+
+	var result *command.SignedPolicyDocument
+	if err = c.do(ctx, rest.PublishPolicyPath, &result, withMethod(http.MethodPost), withBody(body),
+		withToken(c.authWriteToken)); err != nil {
+		return nil, fmt.Errorf("publish policy: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetPolicy retrieves a version of the signed policy document of the log, the latest version if it is zero.
+func (c *Client) GetPolicy(ctx context.Context, version uint64) (*command.SignedPolicyDocument, error) {
+	path := rest.PolicyPath
+	if version > 0 {
+		path = strings.Replace(rest.PolicyVersionPath, "{version:[0-9]+}", strconv.FormatUint(version, 10), 1)
+	}
+
+	var result *command.SignedPolicyDocument
+	if err := c.do(ctx, path, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get policy: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetPolicyHistory retrieves all the published versions of the signed policy document of the log, oldest first.
+func (c *Client) GetPolicyHistory(ctx context.Context) ([]command.SignedPolicyDocument, error) {
+	var result *command.GetPolicyHistoryResponse
+	if err := c.do(ctx, rest.PolicyHistoryPath, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get policy history: %w", err)
+	}
+
+	return result.Policies, nil
+}
+
+// VerifyPolicyHistory verifies that the versions of the policy of the log (oldest first, as returned by
+// GetPolicyHistory) form a chain and are signed by the key of the log. A client tracking the policy keeps the
+// history it verified and checks that the history it retrieves next starts with it.
+func VerifyPolicyHistory(alias string, history []command.SignedPolicyDocument, pubKey []byte) error {
+	if err := command.CheckPolicyHistory(alias, history); err != nil {
+		return fmt.Errorf("policy history: %w", err)
+	}
+
+	for i := range history {
+		if err := command.VerifySignature(history[i].Signature, pubKey, history[i].Statement); err != nil {
+			return fmt.Errorf("policy version %d: %w", history[i].Statement.PolicyVersion, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+func TestClient_Policy(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+
+	policy := command.LogPolicy{MMD: 86400, Contact: "ops@example.com"}
+
+	sign := func(version uint64, previous *command.SignedPolicyDocument,
+		p command.LogPolicy) command.SignedPolicyDocument {
+		statement := command.PolicyDocument{
+			Version:       command.V1,
+			SignatureType: command.PolicySignatureType,
+			Timestamp:     1000 * version,
+			Alias:         "maple2021",
+			PolicyVersion: version,
+			Policy:        p,
+		}
+
+		if previous != nil {
+			statement.Previous, err = command.PolicyDocumentHash(previous)
+			require.NoError(t, err)
+		}
+
+		return command.SignedPolicyDocument{Statement: statement, Signature: signStatement(t, key, statement)}
+	}
+
+	first := sign(1, nil, policy)
+
+	policy.Retention = "10 years"
+	second := sign(2, &first, policy)
+
+	history := []command.SignedPolicyDocument{first, second}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin/policy":
+			var req *command.PublishPolicyRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, command.PublishPolicyRequest{Alias: "maple2021", Policy: policy}, *req)
+
+			_ = json.NewEncoder(w).Encode(second) // nolint: errcheck
+		case "/maple2021/policy":
+			_ = json.NewEncoder(w).Encode(second) // nolint: errcheck
+		case "/maple2021/policy/1":
+			_ = json.NewEncoder(w).Encode(first) // nolint: errcheck
+		case "/maple2021/policy/history":
+			// nolint: errcheck
+			_ = json.NewEncoder(w).Encode(command.GetPolicyHistoryResponse{Policies: history})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := vct.New(server.URL + "/maple2021")
+
+	t.Run("Publish", func(t *testing.T) {
+		published, er := client.PublishPolicy(ctx, &policy)
+		require.NoError(t, er)
+		require.Equal(t, second, *published)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		latest, er := client.GetPolicy(ctx, 0)
+		require.NoError(t, er)
+		require.Equal(t, second, *latest)
+
+		version, er := client.GetPolicy(ctx, 1)
+		require.NoError(t, er)
+		require.Equal(t, first, *version)
+
+		_, er = client.GetPolicy(ctx, 3)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "get policy")
+
+		retrieved, er := client.GetPolicyHistory(ctx)
+		require.NoError(t, er)
+		require.Equal(t, history, retrieved)
+		require.NoError(t, vct.VerifyPolicyHistory("maple2021", retrieved, pubKey))
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		require.EqualError(t, vct.VerifyPolicyHistory("maple2022", history, pubKey),
+			"policy history: version 1 is not a v1 policy document of the log")
+
+		require.EqualError(t, vct.VerifyPolicyHistory("maple2021", history[1:], pubKey),
+			"policy history: history does not start with version 1")
+
+		// a version replaced after it was published breaks the chain
+		replaced := sign(1, nil, command.LogPolicy{MMD: 60, Contact: "ops@example.com"})
+		require.EqualError(t, vct.VerifyPolicyHistory("maple2021",
+			[]command.SignedPolicyDocument{replaced, second}, pubKey),
+			"policy history: version 2 does not follow version 1")
+
+		tampered := first
+		tampered.Statement.Policy.MMD = 60
+		er := vct.VerifyPolicyHistory("maple2021", []command.SignedPolicyDocument{tampered}, pubKey)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "policy version 1: verify")
+	})
+}
@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// WithPolicyTags tags the submission with the policy tags (e.g. the jurisdiction of the issuance), they are
+// recorded in the extensions of the entry.
+func WithPolicyTags(tags command.PolicyTags) AddVCOpt {
+	return func(o *command.AddVCOptions) {
+		o.PolicyTags = &tags
+	}
+}
+
+// TagFilter represents the filter of the entries by their policy tags.
+type TagFilter func(*command.PolicyTags)
+
+// WithJurisdiction filters the entries of the jurisdiction, including the entries of its subdivisions.
+func WithJurisdiction(jurisdiction string) TagFilter {
+	return func(tags *command.PolicyTags) {
+		tags.Jurisdiction = jurisdiction
+	}
+}
+
+// WithAssuranceLevel filters the entries of the assurance level.
+func WithAssuranceLevel(level string) TagFilter {
+	return func(tags *command.PolicyTags) {
+		tags.AssuranceLevel = level
+	}
+}
+
+// tagValues returns the query parameters of the filter.
+func tagValues(filters []TagFilter) []opt {
+	var tags command.PolicyTags
+
+	for _, fn := range filters {
+		fn(&tags)
+	}
+
+	var opts []opt
+
+	if tags.Jurisdiction != "" {
+		opts = append(opts, withValueAdd("jurisdiction", tags.Jurisdiction))
+	}
+
+	if tags.AssuranceLevel != "" {
+		opts = append(opts, withValueAdd("assurance_level", tags.AssuranceLevel))
+	}
+
+	return opts
+}
+
+// GetTaggedIssuers returns a page of the issuers of the entries matching the filter, in the order they first
+// logged a matching entry.
+func (c *Client) GetTaggedIssuers(ctx context.Context, filters []TagFilter,
+	opts ...PageOpt) (*command.GetIssuersResponse, error) {
+	values := tagValues(filters)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("get tagged issuers: no policy tags to filter by")
+	}
+
+	var result *command.GetIssuersResponse
+	if err := c.do(ctx, rest.GetIssuersPath, &result,
+		append(append(values, pageValues(pageRequest(opts))...), withToken(c.authReadToken))...); err != nil {
+		return nil, fmt.Errorf("get tagged issuers: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+func TestClient_PolicyTags(t *testing.T) {
+	t.Run("Tagged submission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			var envelope command.AddVCEnvelope
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&envelope))
+			require.Equal(t, &command.PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: "high"},
+				envelope.Options.PolicyTags)
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"svct_version":0}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		_, err := vct.New(endpoint, vct.WithHTTPClient(httpClient)).AddVC(context.Background(), []byte(`{}`),
+			vct.WithPolicyTags(command.PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: "high"}))
+		require.NoError(t, err)
+	})
+
+	t.Run("Filtered entries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "1", req.URL.Query().Get("start"))
+			require.Equal(t, "CA", req.URL.Query().Get("jurisdiction"))
+			require.Equal(t, "low", req.URL.Query().Get("assurance_level"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"entries":[{"leaf_index":2}]}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "2021-10-12T00:00:00Z", req.URL.Query().Get("from_time"))
+			require.Equal(t, "FR", req.URL.Query().Get("jurisdiction"))
+			require.Empty(t, req.URL.Query().Get("assurance_level"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"entries":[]}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+		resp, err := client.GetEntries(context.Background(), 1, 2,
+			vct.WithJurisdiction("CA"), vct.WithAssuranceLevel("low"))
+		require.NoError(t, err)
+		require.Equal(t, int64(2), *resp.Entries[0].LeafIndex)
+
+		resp, err = client.GetEntriesByTime(context.Background(),
+			time.Date(2021, time.October, 12, 0, 0, 0, 0, time.UTC), time.Time{}, vct.WithJurisdiction("FR"))
+		require.NoError(t, err)
+		require.Empty(t, resp.Entries)
+	})
+
+	t.Run("Tagged issuers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/v1/get-issuers", req.URL.Path)
+			require.Equal(t, "CA", req.URL.Query().Get("jurisdiction"))
+			require.Equal(t, "10", req.URL.Query().Get("page_size"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"issuers":["did:example:quebec"]}`)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+
+		page, err := client.GetTaggedIssuers(context.Background(), []vct.TagFilter{vct.WithJurisdiction("CA")},
+			vct.WithPageSize(10))
+		require.NoError(t, err)
+		require.Equal(t, []string{"did:example:quebec"}, page.Issuers)
+
+		_, err = client.GetTaggedIssuers(context.Background(), nil)
+		require.EqualError(t, err, "get tagged issuers: no policy tags to filter by")
+	})
+}
@@ -0,0 +1,72 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// Receipt is the transparency receipt of a credential: the SCTs of the logs the credential is logged to.
+// The credential is not changed by logging, so the receipt is delivered next to it (e.g. in the credential
+// response of the issuer) and presented with it.
+type Receipt struct {
+	SCTs []ReceiptSCT `json:"scts"`
+}
+
+// ReceiptSCT is an SCT of a receipt.
+type ReceiptSCT struct {
+	// Endpoint of the log which issued the SCT.
+	Endpoint string `json:"endpoint"`
+	command.AddVCResponse
+}
+
+// NewReceipt returns the receipt of the SCTs accepted by the logging policy.
+func NewReceipt(results []SCTResult) *Receipt {
+	receipt := &Receipt{SCTs: []ReceiptSCT{}}
+
+	for _, result := range results {
+		if result.Err == nil && result.SCT != nil {
+			receipt.SCTs = append(receipt.SCTs, ReceiptSCT{Endpoint: result.Endpoint, AddVCResponse: *result.SCT})
+		}
+	}
+
+	return receipt
+}
+
+// Encode returns the receipt in its embeddable form: base64url-encoded (unpadded) JSON.
+func (r *Receipt) Encode() (string, error) {
+	src, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("marshal receipt: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(src), nil
+}
+
+// DecodeReceipt decodes a receipt encoded by Receipt.Encode.
+func DecodeReceipt(encoded string) (*Receipt, error) {
+	src, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode receipt: %w", err)
+	}
+
+	var receipt *Receipt
+	if err = json.Unmarshal(src, &receipt); err != nil {
+		return nil, fmt.Errorf("unmarshal receipt: %w", err)
+	}
+
+	if receipt == nil || len(receipt.SCTs) == 0 {
+		return nil, errors.New("receipt has no SCTs")
+	}
+
+	return receipt, nil
+}
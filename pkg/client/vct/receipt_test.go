@@ -0,0 +1,46 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+func TestReceipt(t *testing.T) {
+	sct := &command.AddVCResponse{ID: []byte("log"), Timestamp: sctTimestamp, Signature: []byte(sctSignature)}
+
+	receipt := vct.NewReceipt([]vct.SCTResult{
+		{Endpoint: "https://vct.com/maple2021", SCT: sct},
+		{Endpoint: "https://vct.com/maple2022", SCT: sct, Err: errors.New("SCT is issued by another log")},
+		{Endpoint: "https://vct.com/maple2023", Err: vct.ErrLogNotAllowed},
+	})
+	require.Len(t, receipt.SCTs, 1)
+	require.Equal(t, "https://vct.com/maple2021", receipt.SCTs[0].Endpoint)
+
+	encoded, err := receipt.Encode()
+	require.NoError(t, err)
+	require.NotContains(t, encoded, "=")
+
+	decoded, err := vct.DecodeReceipt(encoded)
+	require.NoError(t, err)
+	require.Equal(t, receipt, decoded)
+
+	_, err = vct.DecodeReceipt("!")
+	require.Contains(t, err.Error(), "decode receipt")
+
+	_, err = vct.DecodeReceipt("W10")
+	require.Contains(t, err.Error(), "unmarshal receipt")
+
+	_, err = vct.DecodeReceipt("eyJzY3RzIjpbXX0")
+	require.EqualError(t, err, "receipt has no SCTs")
+}
@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// GetRandomEntries retrieves the pseudo-random sample of count entries of the latest tree head derived from the
+// seed, the sample is verified with VerifyRandomEntries.
+func (c *Client) GetRandomEntries(ctx context.Context, count int, seed string) (*command.GetRandomEntriesResponse, error) { // nolint: lll
+	const (
+		countParamName = "count"
+		seedParamName  = "seed"
+	)
+
+	opts := []opt{
+		withValueAdd(countParamName, strconv.Itoa(count)),
+		withToken(c.authReadToken),
+	}
+
+	if seed != "" {
+		opts = append(opts, withValueAdd(seedParamName, seed))
+	}
+
+	var result *command.GetRandomEntriesResponse
+	if err := c.do(ctx, rest.GetRandomEntriesPath, &result, opts...); err != nil {
+		return nil, fmt.Errorf("get random entries: %w", err)
+	}
+
+	return result, nil
+}
+
+// VerifyRandomEntries verifies the signature of the STH of the sample, that the sample was derived from the seed and
+// the STH (command.SampleIndices) and that every entry is included in the tree of the STH. The proofs are verified
+// with DefaultHasher.
+func VerifyRandomEntries(resp *command.GetRandomEntriesResponse, seed string, count int, pubKey []byte) error {
+	return verifyRandomEntries(DefaultHasher, NewVerifier(DefaultHasher), resp, seed, count, pubKey)
+}
+
+// VerifyRandomEntries verifies the sample as VerifyRandomEntries does, with the hasher and the verifier of the client.
+func (c *Client) VerifyRandomEntries(resp *command.GetRandomEntriesResponse, seed string, count int,
+	pubKey []byte) error {
+	return verifyRandomEntries(c.hasher, c.verifier, resp, seed, count, pubKey)
+}
+
+func verifyRandomEntries(h Hasher, verifier Verifier, resp *command.GetRandomEntriesResponse, seed string,
+	count int, pubKey []byte) error {
+	err := command.VerifySignature(resp.STH.TreeHeadSignature, pubKey, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      resp.STH.Timestamp,
+		TreeSize:       resp.STH.TreeSize,
+		SHA256RootHash: resp.STH.SHA256RootHash,
+	})
+	if err != nil {
+		return fmt.Errorf("tree head signature: %w", err)
+	}
+
+	if resp.Seed != seed {
+		return fmt.Errorf("sample is derived from seed %q, expected %q", resp.Seed, seed)
+	}
+
+	indices := command.SampleIndices(seed, resp.STH.SHA256RootHash, resp.STH.TreeSize, count)
+
+	if len(resp.Entries) != len(indices) {
+		return fmt.Errorf("got %d entries, expected %d", len(resp.Entries), len(indices))
+	}
+
+	for i, entry := range resp.Entries {
+		if entry.LeafIndex != indices[i] {
+			return fmt.Errorf("leaf index %d of entry %d is not in the sample", entry.LeafIndex, i)
+		}
+
+		err = verifier.VerifyInclusionProof(entry.LeafIndex, int64(resp.STH.TreeSize), entry.AuditPath,
+			resp.STH.SHA256RootHash, h.HashLeaf(entry.LeafInput))
+		if err != nil {
+			return fmt.Errorf("inclusion of leaf %d: %w", entry.LeafIndex, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+func TestClient_GetRandomEntries(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expected := command.GetRandomEntriesResponse{
+			Seed:    "beacon",
+			STH:     command.GetSTHResponse{TreeSize: 2},
+			Entries: []command.AuditEntry{{LeafIndex: 1, LeafInput: []byte("b")}},
+		}
+
+		fakeResp, err := json.Marshal(expected)
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.Equal(t, "/v1/get-random-entries", req.URL.Path)
+			require.Equal(t, "1", req.URL.Query().Get("count"))
+			require.Equal(t, "beacon", req.URL.Query().Get("seed"))
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusOK,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+		resp, err := client.GetRandomEntries(context.Background(), 1, "beacon")
+		require.NoError(t, err)
+		require.Equal(t, &expected, resp)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		fakeResp, err := json.Marshal(rest.ErrorResponse{Message: "error"})
+		require.NoError(t, err)
+
+		httpClient := NewMockHTTPClient(ctrl)
+		httpClient.EXPECT().Do(gomock.Any()).Do(func(req *http.Request) {
+			require.NotContains(t, req.URL.Query(), "seed")
+		}).Return(&http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(fakeResp)),
+			StatusCode: http.StatusInternalServerError,
+		}, nil)
+
+		client := vct.New(endpoint, vct.WithHTTPClient(httpClient))
+		_, err = client.GetRandomEntries(context.Background(), 1, "")
+		require.EqualError(t, err, "get random entries: error")
+	})
+}
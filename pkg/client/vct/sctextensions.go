@@ -0,0 +1,62 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// SCTExtensions are the extensions of an SCT, parsed.
+type SCTExtensions struct {
+	// ShardID is the alias of the log the entry is logged to, empty if not set.
+	ShardID string
+	// PolicyTags are the policy tags of the entry, nil if not set.
+	PolicyTags *command.PolicyTags
+	// AnchorCommitment is the SHA-256 hash of the anchored tree head, nil if not set.
+	AnchorCommitment []byte
+	// Unknown are the extensions of the types the client does not know, they are signed with the SCT anyway.
+	Unknown []command.SCTExtension
+}
+
+// ParseSCTExtensions parses the extensions of the SCT, the extensions of the types the client does not know are
+// returned as is (see SCTExtensions.Unknown), so the log can add new ones without breaking the client. The SCT
+// is expected to be verified (see VerifySCT).
+func ParseSCTExtensions(sct *command.AddVCResponse) (*SCTExtensions, error) {
+	parsed := &SCTExtensions{}
+	seen := map[command.SCTExtensionType]bool{}
+
+	for _, extension := range sct.SCTExtensions {
+		if seen[extension.Type] {
+			return nil, fmt.Errorf("duplicate SCT extension %s", extension.Type)
+		}
+
+		seen[extension.Type] = true
+
+		switch extension.Type {
+		case command.SCTExtensionShardID:
+			parsed.ShardID = string(extension.Data)
+		case command.SCTExtensionPolicyTags:
+			if err := json.Unmarshal(extension.Data, &parsed.PolicyTags); err != nil {
+				return nil, fmt.Errorf("unmarshal SCT extension %s: %w", extension.Type, err)
+			}
+		case command.SCTExtensionAnchorCommitment:
+			if len(extension.Data) != sha256.Size {
+				return nil, fmt.Errorf("SCT extension %s is not a SHA-256 hash", extension.Type)
+			}
+
+			parsed.AnchorCommitment = extension.Data
+		default:
+			parsed.Unknown = append(parsed.Unknown, extension)
+		}
+	}
+
+	return parsed, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+func TestParseSCTExtensions(t *testing.T) { // nolint: funlen
+	bachelorDegree, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(getLoader(t)),
+	)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+	logID := command.LogID(pubKey)
+
+	leaf, err := command.CreateLeaf(sctTimestamp, bachelorDegree)
+	require.NoError(t, err)
+
+	commitment := sha256.Sum256([]byte("anchor"))
+
+	extensions := []command.SCTExtension{
+		{Type: command.SCTExtensionShardID, Data: []byte("maple2021")},
+		{Type: command.SCTExtensionPolicyTags, Data: []byte(`{"jurisdiction":"CA-QC","assurance_level":"high"}`)},
+		{Type: command.SCTExtensionAnchorCommitment, Data: commitment[:]},
+		// registered by a newer log
+		{Type: 42, Data: []byte("unknown")},
+	}
+
+	statement := command.CreateVCTimestampSignature(leaf)
+	statement.SCTExtensions = extensions
+
+	sct := &command.AddVCResponse{
+		SVCTVersion:   command.V1,
+		ID:            logID[:],
+		Timestamp:     sctTimestamp,
+		SCTExtensions: extensions,
+		Signature:     signStatement(t, key, statement),
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		require.NoError(t, vct.VerifySCT(sct, pubKey, bachelorDegree))
+
+		parsed, err := vct.ParseSCTExtensions(sct)
+		require.NoError(t, err)
+		require.Equal(t, &vct.SCTExtensions{
+			ShardID: "maple2021",
+			PolicyTags: &command.PolicyTags{
+				Jurisdiction:   "CA-QC",
+				AssuranceLevel: command.AssuranceLevelHigh,
+			},
+			AnchorCommitment: commitment[:],
+			Unknown:          []command.SCTExtension{{Type: 42, Data: []byte("unknown")}},
+		}, parsed)
+	})
+
+	t.Run("No extensions", func(t *testing.T) {
+		parsed, err := vct.ParseSCTExtensions(&command.AddVCResponse{})
+		require.NoError(t, err)
+		require.Equal(t, &vct.SCTExtensions{}, parsed)
+	})
+
+	t.Run("Extensions are signed", func(t *testing.T) {
+		tampered := *sct
+		tampered.SCTExtensions = extensions[:1]
+
+		require.Error(t, vct.VerifySCT(&tampered, pubKey, bachelorDegree))
+	})
+
+	t.Run("Duplicate extension", func(t *testing.T) {
+		_, err := vct.ParseSCTExtensions(&command.AddVCResponse{SCTExtensions: append(extensions, extensions[0])})
+		require.EqualError(t, err, "duplicate SCT extension shard-id")
+	})
+
+	t.Run("Bad policy tags", func(t *testing.T) {
+		_, err := vct.ParseSCTExtensions(&command.AddVCResponse{SCTExtensions: []command.SCTExtension{
+			{Type: command.SCTExtensionPolicyTags, Data: []byte("{")},
+		}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unmarshal SCT extension policy-tags")
+	})
+
+	t.Run("Bad anchor commitment", func(t *testing.T) {
+		_, err := vct.ParseSCTExtensions(&command.AddVCResponse{SCTExtensions: []command.SCTExtension{
+			{Type: command.SCTExtensionAnchorCommitment, Data: []byte("anchor")},
+		}})
+		require.EqualError(t, err, "SCT extension anchor-commitment is not a SHA-256 hash")
+	})
+}
@@ -0,0 +1,181 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// ErrUnknownShard is returned for the SCTs and the proofs of a log which is neither the log of the client nor a
+// retired shard archived by the service.
+var ErrUnknownShard = errors.New("unknown shard")
+
+// WithRetiredShards sets the archive of the retired shards (e.g. kept by the verifier since the shards were
+// retired), instead of the archive of the service (GetRetiredShards). The shards are verified by the calls using
+// them (see VerifyRetiredShard).
+func WithRetiredShards(shards ...command.RetiredShard) ClientOpt {
+	return func(o *clientOptions) {
+		o.retiredShards = append([]command.RetiredShard{}, shards...)
+	}
+}
+
+// GetRetiredShards retrieves the archive of the retired shards of the service: the keys and the final tree heads
+// of the frozen logs, including the logs no longer served. The shards are verified (see VerifyRetiredShard).
+func (c *Client) GetRetiredShards(ctx context.Context) ([]command.RetiredShard, error) {
+	var result *command.GetRetiredShardsResponse
+	if err := c.do(ctx, rest.RetiredShardsPath, &result, withToken(c.authReadToken)); err != nil {
+		return nil, fmt.Errorf("get retired shards: %w", err)
+	}
+
+	if result == nil {
+		return nil, nil
+	}
+
+	for i := range result.Shards {
+		if err := VerifyRetiredShard(&result.Shards[i]); err != nil {
+			return nil, fmt.Errorf("retired shard %s: %w", result.Shards[i].Alias, err)
+		}
+	}
+
+	return result.Shards, nil
+}
+
+// VerifyRetiredShard verifies that the log ID of the shard is derived from its public key and that its final tree
+// head is issued for the shard and signed by its key (see VerifyFinalTreeHead).
+func VerifyRetiredShard(shard *command.RetiredShard) error {
+	if logID := command.LogID(shard.PublicKey); !bytes.Equal(shard.LogID, logID[:]) {
+		return errors.New("log ID is not derived from the public key")
+	}
+
+	statement := shard.FinalTreeHead.Statement
+
+	if !bytes.Equal(statement.LogID, shard.LogID) || statement.Alias != shard.Alias {
+		return errors.New("final tree head is issued for another log")
+	}
+
+	return VerifyFinalTreeHead(&shard.FinalTreeHead, shard.PublicKey)
+}
+
+// VerifySCT verifies the SCT of the credential (see VerifySCT) with the key of the log which issued it: the key of
+// the log of the client, or the key of the retired shards of the service the SCT is issued by (the logs of a
+// service share its key). An SCT of retired shards must be dated before the last of them was frozen.
+// ErrUnknownShard is returned if the log of the SCT is unknown.
+func (c *Client) VerifySCT(ctx context.Context, sct *command.AddVCResponse, vc *verifiable.Credential) error {
+	// a compromised key is not trusted for any shard
+	pubKey, err := c.GetPublicKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	if logID := command.LogID(pubKey); bytes.Equal(sct.ID, logID[:]) {
+		return VerifySCT(sct, pubKey, vc)
+	}
+
+	shards, err := c.retiredShards(ctx, sct.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = VerifySCT(sct, shards[0].PublicKey, vc); err != nil {
+		return fmt.Errorf("retired shard %s: %w", shards[0].Alias, err)
+	}
+
+	var frozen uint64
+
+	for _, shard := range shards {
+		if shard.FinalTreeHead.Statement.Timestamp > frozen {
+			frozen = shard.FinalTreeHead.Statement.Timestamp
+		}
+	}
+
+	if sct.Timestamp > frozen {
+		return fmt.Errorf("SCT is dated %d after the retired shards were frozen at %d", sct.Timestamp, frozen)
+	}
+
+	return nil
+}
+
+// VerifyRetiredInclusionProof verifies the inclusion proof of the leaf in the final tree of a retired shard of the
+// log ID (e.g. the ID of the SCT of the leaf), the proof is taken at the final tree size of the shard.
+// ErrUnknownShard is returned if no shard of the log ID is archived.
+func (c *Client) VerifyRetiredInclusionProof(ctx context.Context, logID []byte, leafIndex int64, proof [][]byte,
+	leafHash []byte) error {
+	shards, err := c.retiredShards(ctx, logID)
+	if err != nil {
+		return err
+	}
+
+	// the proof is bound to the root of the shard including the leaf
+	for _, shard := range shards {
+		sth := shard.FinalTreeHead.Statement.STH
+
+		err = c.verifier.VerifyInclusionProof(leafIndex, int64(sth.TreeSize), proof, sth.SHA256RootHash, leafHash)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("verify inclusion proof in the retired shards: %w", err)
+}
+
+// retiredShards returns the verified retired shards of the log ID. The archive of the service is fetched again
+// when the log ID is not in the archive fetched before, unless the archive is set with WithRetiredShards.
+func (c *Client) retiredShards(ctx context.Context, logID []byte) ([]command.RetiredShard, error) {
+	c.shardsMu.Lock()
+	archive, pinned := c.shards, c.shardsPinned
+	c.shardsMu.Unlock()
+
+	shards := findShards(archive, logID)
+
+	if len(shards) == 0 && !pinned {
+		fetched, err := c.GetRetiredShards(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.shardsMu.Lock()
+		c.shards = fetched
+		c.shardsMu.Unlock()
+
+		shards = findShards(fetched, logID)
+	}
+
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("%w: log ID %x", ErrUnknownShard, logID)
+	}
+
+	if pinned {
+		for i := range shards {
+			if err := VerifyRetiredShard(&shards[i]); err != nil {
+				return nil, fmt.Errorf("retired shard %s: %w", shards[i].Alias, err)
+			}
+		}
+	}
+
+	return shards, nil
+}
+
+// findShards returns the shards of the log ID.
+func findShards(archive []command.RetiredShard, logID []byte) []command.RetiredShard {
+	var shards []command.RetiredShard
+
+	for _, shard := range archive {
+		if bytes.Equal(shard.LogID, logID) {
+			shards = append(shards, shard)
+		}
+	}
+
+	return shards
+}
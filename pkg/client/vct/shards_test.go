@@ -0,0 +1,244 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// retiredShard returns the shard of the alias frozen at the final tree head of the root, signed by the key.
+func retiredShard(t *testing.T, key *ecdsa.PrivateKey, alias string, frozen uint64,
+	root []byte) command.RetiredShard {
+	t.Helper()
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+	logID := command.LogID(pubKey)
+
+	sth := command.GetSTHResponse{TreeSize: 2, Timestamp: frozen, SHA256RootHash: root}
+	sth.TreeHeadSignature = signStatement(t, key, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+
+	statement := command.FinalTreeHead{
+		Version:       command.V1,
+		SignatureType: command.FreezeSignatureType,
+		Timestamp:     frozen,
+		LogID:         logID[:],
+		Alias:         alias,
+		STH:           sth,
+	}
+
+	return command.RetiredShard{
+		Alias:         alias,
+		Tenant:        "maple",
+		LogID:         logID[:],
+		PublicKey:     pubKey,
+		FinalTreeHead: command.SignedFinalTreeHead{Statement: statement, Signature: signStatement(t, key, statement)},
+	}
+}
+
+func TestClient_RetiredShards(t *testing.T) { // nolint: funlen
+	bachelorDegree, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(getLoader(t)),
+	)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	h := hasher.DefaultHasher
+
+	// the final trees of the shards of the retired key have two leaves
+	leaves := [][]byte{h.HashLeaf([]byte("leaf 0")), h.HashLeaf([]byte("leaf 1")), h.HashLeaf([]byte("leaf 2"))}
+	maple2020 := retiredShard(t, key, "maple2020", sctTimestamp+1000, h.HashChildren(leaves[0], leaves[1]))
+	maple2021 := retiredShard(t, key, "maple2021", sctTimestamp+2000, h.HashChildren(leaves[2], leaves[1]))
+
+	leaf, err := command.CreateLeaf(sctTimestamp, bachelorDegree)
+	require.NoError(t, err)
+
+	// the SCT of the credential issued by the retired key
+	retiredSCT := &command.AddVCResponse{
+		SVCTVersion: command.V1,
+		ID:          maple2020.LogID,
+		Timestamp:   sctTimestamp,
+		Signature:   signStatement(t, key, command.CreateVCTimestampSignature(leaf)),
+	}
+
+	var archiveRequests int32
+
+	// the service signs with sctPubKey, the shards of the retired key are no longer served
+	newService := func(t *testing.T, shards ...command.RetiredShard) *httptest.Server {
+		t.Helper()
+
+		logID := sha256.Sum256(sctPubKey)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/.well-known/webfinger"):
+				require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+					Properties: map[string]interface{}{command.PublicKeyType: sctPubKey, command.LogIDType: logID[:]},
+				}))
+			case r.URL.Path == "/retired-shards":
+				atomic.AddInt32(&archiveRequests, 1)
+
+				require.NoError(t, json.NewEncoder(w).Encode(command.GetRetiredShardsResponse{Shards: shards}))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		t.Cleanup(ts.Close)
+
+		return ts
+	}
+
+	ctx := context.Background()
+
+	t.Run("SCT of the log", func(t *testing.T) {
+		atomic.StoreInt32(&archiveRequests, 0)
+
+		client := vct.New(newService(t, maple2020).URL + "/maple2022")
+
+		logID := sha256.Sum256(sctPubKey)
+
+		require.NoError(t, client.VerifySCT(ctx, &command.AddVCResponse{
+			SVCTVersion: command.V1,
+			ID:          logID[:],
+			Timestamp:   sctTimestamp,
+			Signature:   []byte(sctSignature),
+		}, bachelorDegree))
+		require.Zero(t, atomic.LoadInt32(&archiveRequests))
+	})
+
+	t.Run("SCT of a retired shard", func(t *testing.T) {
+		atomic.StoreInt32(&archiveRequests, 0)
+
+		client := vct.New(newService(t, maple2020, maple2021).URL + "/maple2022")
+
+		shards, er := client.GetRetiredShards(ctx)
+		require.NoError(t, er)
+		require.Equal(t, []command.RetiredShard{maple2020, maple2021}, shards)
+
+		require.NoError(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree))
+		require.NoError(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree))
+
+		// the archive is fetched once
+		require.Equal(t, int32(2), atomic.LoadInt32(&archiveRequests))
+
+		// the SCT must be dated before the shards are frozen
+		lateLeaf, er := command.CreateLeaf(sctTimestamp+3000, bachelorDegree)
+		require.NoError(t, er)
+
+		late := &command.AddVCResponse{
+			SVCTVersion: command.V1,
+			ID:          maple2020.LogID,
+			Timestamp:   sctTimestamp + 3000,
+			Signature:   signStatement(t, key, command.CreateVCTimestampSignature(lateLeaf)),
+		}
+
+		require.EqualError(t, client.VerifySCT(ctx, late, bachelorDegree), fmt.Sprintf(
+			"SCT is dated %d after the retired shards were frozen at %d", sctTimestamp+3000, sctTimestamp+2000))
+
+		tampered := *retiredSCT
+		tampered.Timestamp = sctTimestamp + 1
+
+		require.Contains(t, client.VerifySCT(ctx, &tampered, bachelorDegree).Error(), "retired shard maple2020")
+
+		unknown := *retiredSCT
+		unknown.ID = []byte("unknown")
+
+		require.ErrorIs(t, client.VerifySCT(ctx, &unknown, bachelorDegree), vct.ErrUnknownShard)
+	})
+
+	t.Run("Inclusion proof in a retired shard", func(t *testing.T) {
+		client := vct.New(newService(t, maple2020, maple2021).URL + "/maple2022")
+
+		require.NoError(t, client.VerifyRetiredInclusionProof(ctx, maple2020.LogID, 0, [][]byte{leaves[1]},
+			leaves[0]))
+		require.NoError(t, client.VerifyRetiredInclusionProof(ctx, maple2020.LogID, 0, [][]byte{leaves[1]},
+			leaves[2]))
+
+		er := client.VerifyRetiredInclusionProof(ctx, maple2020.LogID, 1, [][]byte{leaves[1]}, leaves[0])
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "verify inclusion proof in the retired shards")
+
+		er = client.VerifyRetiredInclusionProof(ctx, []byte("unknown"), 0, [][]byte{leaves[1]}, leaves[0])
+		require.ErrorIs(t, er, vct.ErrUnknownShard)
+	})
+
+	t.Run("Archive of the verifier", func(t *testing.T) {
+		atomic.StoreInt32(&archiveRequests, 0)
+
+		service := newService(t).URL + "/maple2022"
+
+		client := vct.New(service, vct.WithRetiredShards(maple2021))
+		require.NoError(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree))
+		require.Zero(t, atomic.LoadInt32(&archiveRequests))
+
+		tampered := maple2021
+		tampered.Alias = "maple2019"
+
+		client = vct.New(service, vct.WithRetiredShards(tampered))
+		require.EqualError(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree),
+			"retired shard maple2019: final tree head is issued for another log")
+
+		client = vct.New(service, vct.WithRetiredShards())
+		require.ErrorIs(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree), vct.ErrUnknownShard)
+		require.Zero(t, atomic.LoadInt32(&archiveRequests))
+	})
+
+	t.Run("Invalid archive", func(t *testing.T) {
+		tampered := maple2020
+		tampered.PublicKey = sctPubKey
+
+		client := vct.New(newService(t, tampered).URL + "/maple2022")
+
+		_, er := client.GetRetiredShards(ctx)
+		require.EqualError(t, er, "retired shard maple2020: log ID is not derived from the public key")
+
+		require.Contains(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree).Error(), "log ID is not derived")
+	})
+
+	t.Run("Compromised key", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, json.NewEncoder(w).Encode(command.WebFingerResponse{
+				Properties: map[string]interface{}{
+					command.PublicKeyType:  sctPubKey,
+					command.CompromiseType: command.SignedCompromiseStatement{},
+				},
+			}))
+		}))
+		defer ts.Close()
+
+		client := vct.New(ts.URL+"/maple2022", vct.WithRetiredShards(maple2020))
+		require.ErrorIs(t, client.VerifySCT(ctx, retiredSCT, bachelorDegree), vct.ErrLogCompromised)
+	})
+}
@@ -0,0 +1,96 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// GetSigningKeys returns the metadata of the signing keys of the log as published in the webfinger metadata: the key
+// of the log and the keys of the other purposes (e.g. command.ResponseKeyPurpose), none if the key of the log serves
+// no other purpose.
+func (c *Client) GetSigningKeys(ctx context.Context) ([]command.KeyMetadata, error) {
+	resp, err := c.Webfinger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := resp.Properties[command.KeysType]
+	if !ok {
+		return nil, nil
+	}
+
+	src, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("marshal signing keys: %w", err)
+	}
+
+	var keys []command.KeyMetadata
+	if err = json.Unmarshal(src, &keys); err != nil {
+		return nil, fmt.Errorf("unmarshal signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// VerifyResponseSignature verifies the signature of the response body to the request URI (the path and the query)
+// sent in the headers of the response, pubKey is the key of the response or admin purpose.
+func VerifyResponseSignature(header http.Header, requestURI string, body, pubKey []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(header.Get(rest.SignatureHeader))
+	if err != nil || len(signature) == 0 {
+		return errors.New("response is not signed")
+	}
+
+	keyID, err := base64.StdEncoding.DecodeString(header.Get(rest.SignatureKeyIDHeader))
+	if err != nil {
+		return fmt.Errorf("decode key ID: %w", err)
+	}
+
+	if expected := sha256.Sum256(pubKey); !bytes.Equal(keyID, expected[:]) {
+		return errors.New("response is signed by another key")
+	}
+
+	timestamp, err := strconv.ParseUint(header.Get(rest.SignatureTimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse timestamp: %w", err)
+	}
+
+	hash := sha256.Sum256(body)
+
+	return command.VerifySignature(signature, pubKey, command.ResponseStatement{
+		Version:       command.V1,
+		SignatureType: command.ResponseSignatureType,
+		Timestamp:     timestamp,
+		RequestURI:    requestURI,
+		SHA256Body:    hash[:],
+	})
+}
+
+// VerifyCosignature verifies that the anchored tree head is cosigned by the witness key of the log (the
+// witness_cosignature of the response to add-anchor).
+func VerifyCosignature(cosignature *command.Cosignature, witnessKey []byte) error {
+	if cosignature.Statement.SignatureType != command.CosignatureSignatureType {
+		return errors.New("statement must be a cosigned tree head")
+	}
+
+	if err := command.VerifySignature(cosignature.Signature, witnessKey, cosignature.Statement); err != nil {
+		return fmt.Errorf("cosignature: %w", err)
+	}
+
+	return nil
+}
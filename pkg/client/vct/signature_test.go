@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// signStatement returns the DigitallySigned signature of the statement by the ECDSA P-256 key.
+func signStatement(t *testing.T, key *ecdsa.PrivateKey, statement interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(statement)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature, err := json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{
+			Signature: command.ECDSASignature,
+			Type:      kms.ECDSAP256TypeIEEEP1363,
+		},
+		Signature: append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...),
+	})
+	require.NoError(t, err)
+
+	return signature
+}
+
+func TestVerifyResponseSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+	keyID := sha256.Sum256(pubKey)
+
+	const requestURI = "/maple2021/v1/get-sth"
+
+	body := []byte(`{"tree_size":1}`)
+	hash := sha256.Sum256(body)
+
+	header := http.Header{}
+	header.Set(rest.SignatureHeader, base64.StdEncoding.EncodeToString(signStatement(t, key,
+		command.ResponseStatement{
+			Version:       command.V1,
+			SignatureType: command.ResponseSignatureType,
+			Timestamp:     7,
+			RequestURI:    requestURI,
+			SHA256Body:    hash[:],
+		})))
+	header.Set(rest.SignatureKeyIDHeader, base64.StdEncoding.EncodeToString(keyID[:]))
+	header.Set(rest.SignatureTimestampHeader, "7")
+
+	require.NoError(t, vct.VerifyResponseSignature(header, requestURI, body, pubKey))
+
+	require.Contains(t, vct.VerifyResponseSignature(header, requestURI, []byte(`{}`), pubKey).Error(), "verify")
+	require.Contains(t, vct.VerifyResponseSignature(header, "/maple2021/v1/get-entries", body,
+		pubKey).Error(), "verify")
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	require.EqualError(t, vct.VerifyResponseSignature(header, requestURI, body,
+		elliptic.Marshal(elliptic.P256(), other.X, other.Y)), "response is signed by another key") // nolint: staticcheck
+
+	require.EqualError(t, vct.VerifyResponseSignature(http.Header{}, requestURI, body, pubKey),
+		"response is not signed")
+}
+
+func TestVerifyCosignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	witnessKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+
+	statement := command.CosignedTreeHead{
+		Version:        command.V1,
+		SignatureType:  command.CosignatureSignatureType,
+		LogID:          []byte("log"),
+		TreeSize:       2,
+		SHA256RootHash: []byte("root"),
+	}
+
+	cosignature := &command.Cosignature{Statement: statement, Signature: signStatement(t, key, statement)}
+
+	require.NoError(t, vct.VerifyCosignature(cosignature, witnessKey))
+
+	cosignature.Statement.TreeSize = 3
+	require.Contains(t, vct.VerifyCosignature(cosignature, witnessKey).Error(), "cosignature: verify")
+
+	cosignature.Statement.SignatureType = command.AnnotationSignatureType
+	require.EqualError(t, vct.VerifyCosignature(cosignature, witnessKey), "statement must be a cosigned tree head")
+}
+
+func TestClient_GetSigningKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	keys := []command.KeyMetadata{
+		{Purpose: command.LogKeyPurpose, KeyID: []byte("log id"), PublicKey: []byte("log key")},
+		{Purpose: command.ResponseKeyPurpose, KeyID: []byte("key id"), PublicKey: []byte("response key")},
+	}
+
+	webfinger, err := json.Marshal(command.WebFingerResponse{
+		Properties: map[string]interface{}{command.KeysType: keys},
+	})
+	require.NoError(t, err)
+
+	httpClient := NewMockHTTPClient(ctrl)
+	httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBuffer(webfinger)),
+		StatusCode: http.StatusOK,
+	}, nil)
+	httpClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{}`)),
+		StatusCode: http.StatusOK,
+	}, nil)
+
+	client := vct.New("https://vct.com/maple2021", vct.WithHTTPClient(httpClient))
+
+	published, err := client.GetSigningKeys(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, keys, published)
+
+	// the key of the log serves the log purpose only
+	published, err = client.GetSigningKeys(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, published)
+}
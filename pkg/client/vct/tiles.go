@@ -0,0 +1,232 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/compact"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// GetInclusionProof returns the audit path of the leaf in the tree of the size. The proof is computed locally from
+// the tiles if the log advertises them (command.TilesType), otherwise it is retrieved from get-entry-and-proof.
+func (c *Client) GetInclusionProof(ctx context.Context, leafIndex, treeSize uint64) ([][]byte, error) {
+	if err := c.checkTreeSize(treeSize); err != nil {
+		return nil, fmt.Errorf("get inclusion proof: %w", err)
+	}
+
+	tiles, err := c.tileSource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get inclusion proof: %w", err)
+	}
+
+	if tiles == nil {
+		resp, er := c.GetEntryAndProof(ctx, leafIndex, treeSize)
+		if er != nil {
+			return nil, er
+		}
+
+		return resp.AuditPath, nil
+	}
+
+	// the tree size passed to the node calculation is beyond the tree: all nodes are perfect subtrees,
+	// the hashes of the imperfect subtrees are rehashed.
+	nodes, err := merkle.CalcInclusionProofNodeAddresses(int64(treeSize), int64(leafIndex), int64(treeSize)+1)
+	if err != nil {
+		return nil, fmt.Errorf("get inclusion proof: %w", err)
+	}
+
+	proof, err := newTileReader(tiles, c.hasher, treeSize).proof(ctx, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("get inclusion proof: %w", err)
+	}
+
+	return proof, nil
+}
+
+// GetConsistencyProof returns the consistency proof between the trees of the sizes. The proof is computed locally
+// from the tiles if the log advertises them (command.TilesType), otherwise it is retrieved from get-sth-consistency.
+func (c *Client) GetConsistencyProof(ctx context.Context, first, second uint64) ([][]byte, error) {
+	if err := c.checkTreeSize(second); err != nil {
+		return nil, fmt.Errorf("get consistency proof: %w", err)
+	}
+
+	tiles, err := c.tileSource(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get consistency proof: %w", err)
+	}
+
+	if tiles == nil {
+		resp, er := c.GetSTHConsistency(ctx, first, second)
+		if er != nil {
+			return nil, er
+		}
+
+		return resp.Consistency, nil
+	}
+
+	nodes, err := merkle.CalcConsistencyProofNodeAddresses(int64(first), int64(second), int64(second)+1)
+	if err != nil {
+		return nil, fmt.Errorf("get consistency proof: %w", err)
+	}
+
+	proof, err := newTileReader(tiles, c.hasher, second).proof(ctx, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("get consistency proof: %w", err)
+	}
+
+	return proof, nil
+}
+
+// tileSource returns the client the tiles of the log are fetched with, nil if the log does not advertise tiles.
+// The webfinger is checked once, the tiles may be served by another host (e.g. a bucket or a CDN).
+func (c *Client) tileSource(ctx context.Context) (*Client, error) {
+	c.tilesMu.Lock()
+	defer c.tilesMu.Unlock()
+
+	if c.tilesChecked {
+		return c.tiles, nil
+	}
+
+	resp, err := c.Webfinger(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setTileSourceLocked(resp)
+
+	return c.tiles, nil
+}
+
+// setTileSource sets the client of the tiles advertised by the webfinger.
+func (c *Client) setTileSource(resp *command.WebFingerResponse) {
+	c.tilesMu.Lock()
+	defer c.tilesMu.Unlock()
+
+	c.setTileSourceLocked(resp)
+}
+
+func (c *Client) setTileSourceLocked(resp *command.WebFingerResponse) {
+	c.tiles = nil
+
+	if tilesURL, ok := resp.Properties[command.TilesType].(string); ok && tilesURL != "" {
+		c.tiles = c.tileClient(tilesURL)
+	}
+
+	c.tilesChecked = true
+}
+
+// tileClient returns the client of the tiles, the read token is sent only to the host of the log.
+func (c *Client) tileClient(tilesURL string) *Client {
+	if tilesURL == c.endpoint {
+		return c
+	}
+
+	opts := []ClientOpt{WithHTTPClient(c.http), WithObserver(c.observer)}
+
+	endpoint, err := url.Parse(c.endpoint)
+	if tiles, er := url.Parse(tilesURL); err == nil && er == nil && endpoint.Host == tiles.Host {
+		opts = append(opts, WithAuthReadToken(c.authReadToken))
+	}
+
+	return New(tilesURL, opts...)
+}
+
+type tileKey struct {
+	level uint
+	index uint64
+}
+
+// tileReader computes the hashes of the perfect subtrees of a tree from its tiles.
+type tileReader struct {
+	client   *Client
+	hasher   Hasher
+	treeSize uint64
+	tiles    map[tileKey][][]byte
+}
+
+func newTileReader(client *Client, h Hasher, treeSize uint64) *tileReader {
+	return &tileReader{client: client, hasher: h, treeSize: treeSize, tiles: map[tileKey][][]byte{}}
+}
+
+func (r *tileReader) proof(ctx context.Context, nodes []merkle.NodeFetch) ([][]byte, error) {
+	hashes := make([][]byte, len(nodes))
+
+	for i, node := range nodes {
+		hash, err := r.nodeHash(ctx, node.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[i] = hash
+	}
+
+	hashes, err := merkle.Rehash(hashes, nodes, r.hasher.HashChildren)
+	if err != nil {
+		return nil, fmt.Errorf("rehash: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// nodeHash computes the hash of the perfect subtree rooted at the node from the row of the tile it spans.
+func (r *tileReader) nodeHash(ctx context.Context, id compact.NodeID) ([]byte, error) {
+	height := id.Level % command.TileHeight
+	first := id.Index << height // the first node of the row of the tile
+	begin := first % command.TileWidth
+	end := begin + 1<<height
+
+	tile, err := r.tile(ctx, id.Level/command.TileHeight, first/command.TileWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(tile)) < end {
+		return nil, fmt.Errorf("no hashes of node %d at level %d", id.Index, id.Level)
+	}
+
+	hashes := append([][]byte(nil), tile[begin:end]...)
+
+	for len(hashes) > 1 {
+		for i := 0; i < len(hashes)/2; i++ {
+			hashes[i] = r.hasher.HashChildren(hashes[2*i], hashes[2*i+1])
+		}
+
+		hashes = hashes[:len(hashes)/2]
+	}
+
+	return hashes[0], nil
+}
+
+// tile fetches the tile as of the tree size: the tile is full or partial if the tree ends within it.
+func (r *tileReader) tile(ctx context.Context, level uint, index uint64) ([][]byte, error) {
+	key := tileKey{level: level, index: index}
+
+	if tile, ok := r.tiles[key]; ok {
+		return tile, nil
+	}
+
+	var width int
+
+	if nodes := r.treeSize >> (command.TileHeight * level); nodes < (index+1)*command.TileWidth {
+		width = int(nodes - index*command.TileWidth)
+	}
+
+	tile, err := r.client.GetTile(ctx, int(level), index, width)
+	if err != nil {
+		return nil, err
+	}
+
+	r.tiles[key] = tile
+
+	return tile, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+// mth returns the RFC 6962 Merkle tree hash of the leaves.
+func mth(leaves [][]byte) []byte {
+	return treeHash(hasher.DefaultHasher, leaves)
+}
+
+// treeHash returns the Merkle tree hash of the leaves with the hasher.
+func treeHash(h vct.Hasher, leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return h.HashLeaf(leaves[0])
+	}
+
+	k := 1
+	for k<<1 < len(leaves) {
+		k <<= 1
+	}
+
+	return h.HashChildren(treeHash(h, leaves[:k]), treeHash(h, leaves[k:]))
+}
+
+// tileServer serves the tiles of the tree of the leaves, the tiles are advertised if tiles is set.
+type tileServer struct {
+	leaves  [][]byte
+	hasher  vct.Hasher // defaults to RFC 6962
+	tiles   bool
+	fetched int
+	token   string
+}
+
+func (s *tileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const tilePrefix = "/maple2021/tile/"
+
+	s.token = r.Header.Get("Authorization")
+
+	switch {
+	case r.URL.Path == "/maple2021/.well-known/webfinger":
+		properties := map[string]interface{}{}
+		if s.tiles {
+			properties[command.TilesType] = "http://" + r.Host + "/maple2021"
+		}
+
+		_ = json.NewEncoder(w).Encode(command.WebFingerResponse{Properties: properties}) // nolint: errcheck
+	case r.URL.Path == "/maple2021/v1/get-entry-and-proof":
+		_, _ = w.Write([]byte(`{"audit_path":["cHJvb2Y="]}`))
+	case r.URL.Path == "/maple2021/v1/get-sth-consistency":
+		_, _ = w.Write([]byte(`{"consistency":["cHJvb2Y="]}`))
+	case strings.HasPrefix(r.URL.Path, tilePrefix):
+		s.fetched++
+
+		elements := strings.SplitN(strings.TrimPrefix(r.URL.Path, tilePrefix), "/", 2)
+
+		level, _ := strconv.Atoi(elements[0])               // nolint: errcheck
+		index, width, _ := rest.ParseTileIndex(elements[1]) // nolint: errcheck
+
+		if width == 0 {
+			width = command.TileWidth
+		}
+
+		shift := command.TileHeight * level
+
+		h := s.hasher
+		if h == nil {
+			h = vct.DefaultHasher
+		}
+
+		for i := 0; i < width; i++ {
+			node := (int(index)*command.TileWidth + i) << shift
+			_, _ = w.Write(treeHash(h, s.leaves[node:node+1<<shift]))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient_GetInclusionProof(t *testing.T) {
+	leaves := make([][]byte, 600)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+
+	verifier := logverifier.New(hasher.DefaultHasher)
+
+	t.Run("From tiles", func(t *testing.T) {
+		server := &tileServer{leaves: leaves, tiles: true}
+
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		client := vct.New(ts.URL+"/maple2021", vct.WithAuthReadToken("read"))
+
+		for _, tc := range []struct{ index, size uint64 }{{0, 1}, {5, 600}, {599, 600}, {300, 513}, {255, 256}} {
+			proof, err := client.GetInclusionProof(context.Background(), tc.index, tc.size)
+			require.NoError(t, err)
+			require.NoError(t, verifier.VerifyInclusionProof(int64(tc.index), int64(tc.size), proof,
+				mth(leaves[:tc.size]), hasher.DefaultHasher.HashLeaf(leaves[tc.index])), tc)
+		}
+
+		require.Equal(t, "Bearer read", server.token)
+
+		_, err := client.GetInclusionProof(context.Background(), 600, 600)
+		require.Error(t, err)
+	})
+
+	t.Run("From proof endpoint", func(t *testing.T) {
+		server := &tileServer{leaves: leaves}
+
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		proof, err := vct.New(ts.URL+"/maple2021").GetInclusionProof(context.Background(), 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("proof")}, proof)
+		require.Zero(t, server.fetched)
+	})
+
+	t.Run("Webfinger error", func(t *testing.T) {
+		ts := httptest.NewServer(http.NotFoundHandler())
+		defer ts.Close()
+
+		_, err := vct.New(ts.URL+"/maple2021").GetInclusionProof(context.Background(), 1, 2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get inclusion proof: webfinger")
+	})
+}
+
+func TestClient_GetConsistencyProof(t *testing.T) {
+	leaves := make([][]byte, 600)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+
+	verifier := logverifier.New(hasher.DefaultHasher)
+
+	t.Run("From tiles", func(t *testing.T) {
+		server := &tileServer{leaves: leaves, tiles: true}
+
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		client := vct.New(ts.URL + "/maple2021")
+
+		for _, tc := range []struct{ first, second uint64 }{{1, 600}, {256, 512}, {300, 600}, {511, 513}, {7, 8}} {
+			proof, err := client.GetConsistencyProof(context.Background(), tc.first, tc.second)
+			require.NoError(t, err)
+			require.NoError(t, verifier.VerifyConsistencyProof(int64(tc.first), int64(tc.second),
+				mth(leaves[:tc.first]), mth(leaves[:tc.second]), proof), tc)
+		}
+
+		_, err := client.GetConsistencyProof(context.Background(), 3, 2)
+		require.Error(t, err)
+	})
+
+	t.Run("From proof endpoint", func(t *testing.T) {
+		server := &tileServer{leaves: leaves}
+
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		proof, err := vct.New(ts.URL+"/maple2021").GetConsistencyProof(context.Background(), 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{[]byte("proof")}, proof)
+		require.Zero(t, server.fetched)
+	})
+}
@@ -0,0 +1,48 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/roughtime"
+)
+
+// VerifyTimeAttestation verifies that the time attestation of the SCT is signed by the Roughtime server of the
+// public key and that the SCT is not dated before the time reported by the server. The signature of the SCT is
+// verified with VerifySCT.
+func VerifyTimeAttestation(sct *command.AddVCResponse, serverKey ed25519.PublicKey) error {
+	attestation := sct.TimeAttestation
+	if attestation == nil {
+		return errors.New("SCT has no time attestation")
+	}
+
+	if attestation.Source != command.TimeAttestationRoughtime {
+		return fmt.Errorf("time source %q is not supported", attestation.Source)
+	}
+
+	resp, err := roughtime.Verify(serverKey, attestation.Nonce, attestation.Response)
+	if err != nil {
+		return fmt.Errorf("verify time attestation: %w", err)
+	}
+
+	midpoint := uint64(resp.Midpoint.UnixNano()) / uint64(time.Millisecond)
+	if midpoint != attestation.Midpoint {
+		return errors.New("midpoint of the time attestation does not match its response")
+	}
+
+	radius := uint64((resp.Radius + time.Millisecond - 1) / time.Millisecond)
+	if sct.Timestamp+radius < midpoint {
+		return errors.New("SCT is dated before its time attestation")
+	}
+
+	return nil
+}
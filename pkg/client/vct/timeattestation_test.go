@@ -0,0 +1,127 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vct_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/roughtime"
+)
+
+func roughtimeTag(name string) uint32 {
+	return binary.LittleEndian.Uint32([]byte(name))
+}
+
+func roughtimeUint(v uint64, size int) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+
+	return b[:size]
+}
+
+// roughtimeResponse returns a response to the nonce signed by the Roughtime server of the key, the nonce is the only
+// leaf of the tree of the batch.
+func roughtimeResponse(t *testing.T, key ed25519.PrivateKey, nonce []byte, midpoint time.Time) []byte {
+	t.Helper()
+
+	_, onlineKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	root := sha512.Sum512(append([]byte{0}, nonce...))
+
+	srep := roughtime.Encode(map[uint32][]byte{
+		roughtimeTag("ROOT"): root[:],
+		roughtimeTag("MIDP"): roughtimeUint(uint64(midpoint.UnixNano()/int64(time.Microsecond)), 8),
+		roughtimeTag("RADI"): roughtimeUint(1000000, 4),
+	})
+
+	dele := roughtime.Encode(map[uint32][]byte{
+		roughtimeTag("PUBK"): onlineKey.Public().(ed25519.PublicKey),
+		roughtimeTag("MINT"): roughtimeUint(0, 8),
+		roughtimeTag("MAXT"): roughtimeUint(uint64(midpoint.Add(time.Hour).UnixNano()/int64(time.Microsecond)), 8),
+	})
+
+	return roughtime.Encode(map[uint32][]byte{
+		roughtimeTag("SIG\x00"): ed25519.Sign(onlineKey, append([]byte("RoughTime v1 response signature\x00"), srep...)),
+		roughtimeTag("SREP"):    srep,
+		roughtimeTag("CERT"): roughtime.Encode(map[uint32][]byte{
+			roughtimeTag("DELE"): dele,
+			roughtimeTag("SIG\x00"): ed25519.Sign(key,
+				append([]byte("RoughTime v1 delegation signature--\x00"), dele...)),
+		}),
+		roughtimeTag("PATH"): {},
+		roughtimeTag("INDX"): roughtimeUint(0, 4),
+	})
+}
+
+func TestVerifyTimeAttestation(t *testing.T) {
+	serverKey, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	midpoint := time.Unix(1700000000, 0)
+	nonce := make([]byte, roughtime.NonceSize)
+
+	sct := func() *command.AddVCResponse {
+		return &command.AddVCResponse{
+			Timestamp: uint64(midpoint.Add(time.Minute).UnixNano() / int64(time.Millisecond)),
+			TimeAttestation: &command.TimeAttestation{
+				Source:   command.TimeAttestationRoughtime,
+				Server:   "roughtime.example.com:2002",
+				Nonce:    nonce,
+				Response: roughtimeResponse(t, key, nonce, midpoint),
+				Midpoint: uint64(midpoint.UnixNano() / int64(time.Millisecond)),
+				Radius:   1000,
+			},
+		}
+	}
+
+	require.NoError(t, vct.VerifyTimeAttestation(sct(), serverKey))
+
+	t.Run("No attestation", func(t *testing.T) {
+		require.EqualError(t, vct.VerifyTimeAttestation(&command.AddVCResponse{}, serverKey),
+			"SCT has no time attestation")
+	})
+
+	t.Run("Unsupported source", func(t *testing.T) {
+		resp := sct()
+		resp.TimeAttestation.Source = "nts"
+
+		require.EqualError(t, vct.VerifyTimeAttestation(resp, serverKey), `time source "nts" is not supported`)
+	})
+
+	t.Run("Another server", func(t *testing.T) {
+		otherKey, _, er := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, er)
+
+		require.EqualError(t, vct.VerifyTimeAttestation(sct(), otherKey),
+			"verify time attestation: invalid delegation signature")
+	})
+
+	t.Run("Midpoint does not match", func(t *testing.T) {
+		resp := sct()
+		resp.TimeAttestation.Midpoint++
+
+		require.EqualError(t, vct.VerifyTimeAttestation(resp, serverKey),
+			"midpoint of the time attestation does not match its response")
+	})
+
+	t.Run("SCT dated before the attestation", func(t *testing.T) {
+		resp := sct()
+		resp.Timestamp = uint64(midpoint.Add(-time.Minute).UnixNano() / int64(time.Millisecond))
+
+		require.EqualError(t, vct.VerifyTimeAttestation(resp, serverKey), "SCT is dated before its time attestation")
+	})
+}
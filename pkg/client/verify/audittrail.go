@@ -0,0 +1,432 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verify
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	// auditRecordTag tags the records of the audit trail in the store.
+	auditRecordTag = "verification"
+	// auditKeyDigits is the width of the sequences in the keys of the records, so the keys sort by sequence.
+	auditKeyDigits = 20
+)
+
+// AuditStore persists the audit trail, e.g. a local store of the storage provider.
+type AuditStore interface {
+	Put(key string, value []byte, tags ...storage.Tag) error
+	Query(expression string, options ...storage.QueryOption) (storage.Iterator, error)
+}
+
+// KeyManager manages the key the exports of the audit trail are signed with.
+type KeyManager interface {
+	Get(keyID string) (interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error)
+}
+
+// Crypto signs with the keys of the key manager.
+type Crypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+// AuditRecord is the record of a verification: its inputs, the tree head it used and its result.
+type AuditRecord struct {
+	// Sequence orders the records of the trail.
+	Sequence int64 `json:"sequence"`
+	// Timestamp is the time of the verification, in milliseconds.
+	Timestamp    uint64                 `json:"timestamp"`
+	CredentialID string                 `json:"credential_id,omitempty"`
+	LeafHash     []byte                 `json:"leaf_hash,omitempty"`
+	SCT          *command.AddVCResponse `json:"sct,omitempty"`
+	// PublicKey is the key of the log the SCT was verified with.
+	PublicKey []byte `json:"public_key,omitempty"`
+	// STH is the tree head the inclusion was verified in, nil if the inclusion was not verified.
+	STH       *command.GetSTHResponse `json:"sth,omitempty"`
+	LeafIndex int64                   `json:"leaf_index,omitempty"`
+	AuditPath [][]byte                `json:"audit_path,omitempty"`
+	Valid     bool                    `json:"valid"`
+	Error     string                  `json:"error,omitempty"`
+}
+
+// VerificationAudit is the signed, timestamped export of the records of an audit trail.
+type VerificationAudit struct {
+	Version       command.Version       `json:"version"`
+	SignatureType command.SignatureType `json:"signature_type"`
+	Timestamp     uint64                `json:"timestamp"`
+	// From and To bound the timestamps of the records, in milliseconds.
+	From    uint64        `json:"from"`
+	To      uint64        `json:"to"`
+	Records []AuditRecord `json:"records"`
+}
+
+// SignedVerificationAudit is the export of an audit trail with its DigitallySigned signature by the verifier.
+type SignedVerificationAudit struct {
+	Audit     VerificationAudit `json:"audit"`
+	Signature []byte            `json:"signature"`
+}
+
+// DefaultAuditPageSize is the max number of records of a page of a query if its limit is not set.
+const DefaultAuditPageSize = 100
+
+// Outcomes of the verifications a query filters the records by.
+const (
+	AuditOutcomeValid   = "valid"
+	AuditOutcomeInvalid = "invalid"
+)
+
+// AuditQuery filters the records of an audit trail, a filter which is not set matches every record.
+type AuditQuery struct {
+	// From and To bound the timestamps of the records (inclusive).
+	From time.Time
+	To   time.Time
+	// Outcome is AuditOutcomeValid or AuditOutcomeInvalid.
+	Outcome      string
+	CredentialID string
+	// LogID is the base64-encoded ID of the log the SCT was verified with (the hash of its public key).
+	LogID string
+	// Cursor is the cursor of the previous page, the first page is returned if it is not set.
+	Cursor string
+	// Limit is the max number of records of the page, DefaultAuditPageSize if it is not set.
+	Limit int
+}
+
+// AuditPage is a page of the records of a query.
+type AuditPage struct {
+	Records []AuditRecord `json:"records"`
+	// Cursor queries the next page, empty on the last page.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+func (q *AuditQuery) matches(record *AuditRecord) bool {
+	switch {
+	case !q.From.IsZero() && record.Timestamp < milliseconds(q.From),
+		!q.To.IsZero() && record.Timestamp > milliseconds(q.To),
+		q.Outcome == AuditOutcomeValid && !record.Valid,
+		q.Outcome == AuditOutcomeInvalid && record.Valid,
+		q.CredentialID != "" && record.CredentialID != q.CredentialID,
+		q.LogID != "" && logID(record.PublicKey) != q.LogID:
+		return false
+	}
+
+	return true
+}
+
+// auditCSVHeader is the header of the CSV export of the records of an audit trail.
+var auditCSVHeader = []string{ // nolint: gochecknoglobals
+	"sequence", "timestamp", "outcome", "credential_id", "log_id", "leaf_hash", "sct_timestamp", "tree_size",
+	"leaf_index", "error",
+}
+
+// WriteAuditCSV writes the records as CSV (e.g. a page of a query for a compliance review), a row per record. The
+// timestamps are in milliseconds, the leaf hash is base64-encoded, the tree size and the leaf index are empty if
+// the inclusion was not verified.
+func WriteAuditCSV(w io.Writer, records []AuditRecord) error {
+	cw := csv.NewWriter(w)
+
+	rows := [][]string{auditCSVHeader}
+
+	for i := range records {
+		record := &records[i]
+
+		outcome := AuditOutcomeInvalid
+		if record.Valid {
+			outcome = AuditOutcomeValid
+		}
+
+		var sctTimestamp, treeSize, leafIndex string
+
+		if record.SCT != nil {
+			sctTimestamp = strconv.FormatUint(record.SCT.Timestamp, 10)
+		}
+
+		if record.STH != nil {
+			treeSize = strconv.FormatUint(record.STH.TreeSize, 10)
+			leafIndex = strconv.FormatInt(record.LeafIndex, 10)
+		}
+
+		rows = append(rows, []string{
+			strconv.FormatInt(record.Sequence, 10),
+			strconv.FormatUint(record.Timestamp, 10),
+			outcome,
+			record.CredentialID,
+			logID(record.PublicKey),
+			base64.StdEncoding.EncodeToString(record.LeafHash),
+			sctTimestamp,
+			treeSize,
+			leafIndex,
+			record.Error,
+		})
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV: %w", err)
+	}
+
+	return nil
+}
+
+// logID returns the base64-encoded ID of the log of the public key, empty if the key is not known.
+func logID(pubKey []byte) string {
+	if len(pubKey) == 0 {
+		return ""
+	}
+
+	id := command.LogID(pubKey)
+
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+// AuditTrail records the verifications to a local store for the resolution of later disputes (e.g. to show which
+// SCT and which tree head a credential was accepted with), see WithAuditTrail. The records are exported in the
+// signed audit format (see Export and VerifyAudit).
+type AuditTrail struct {
+	store AuditStore
+	kh    interface{}
+	alg   *command.SignatureAndHashAlgorithm
+	crypt Crypto
+
+	mu   sync.Mutex
+	last int64
+}
+
+// NewAuditTrail returns the audit trail of the store, its exports are signed with the key of the key manager.
+func NewAuditTrail(store AuditStore, km KeyManager, cr Crypto, keyID string) (*AuditTrail, error) {
+	_, keyType, err := km.ExportPubKeyBytes(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("export pub key bytes: %w", err)
+	}
+
+	alg, err := command.SignatureAndHashAlgorithmByKeyType(keyType)
+	if err != nil {
+		return nil, fmt.Errorf("key type %v is not supported", keyType)
+	}
+
+	kh, err := km.Get(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("kms get kh: %w", err)
+	}
+
+	return &AuditTrail{store: store, kh: kh, alg: alg, crypt: cr}, nil
+}
+
+// Record persists the record, its sequence and its timestamp are set if they are not.
+func (t *AuditTrail) Record(record *AuditRecord) error {
+	now := time.Now()
+
+	if record.Timestamp == 0 {
+		record.Timestamp = uint64(now.UnixNano()) / uint64(time.Millisecond)
+	}
+
+	if record.Sequence == 0 {
+		record.Sequence = t.sequence(now)
+	}
+
+	src, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal audit record: %w", err)
+	}
+
+	key := fmt.Sprintf("%s-%0*d", auditRecordTag, auditKeyDigits, record.Sequence)
+
+	err = t.store.Put(key, src, storage.Tag{
+		Name:  auditRecordTag,
+		Value: strconv.FormatUint(record.Timestamp, 10),
+	})
+	if err != nil {
+		return fmt.Errorf("store audit record: %w", err)
+	}
+
+	return nil
+}
+
+// sequence returns an increasing sequence derived from the clock, so the sequences are unique across restarts.
+func (t *AuditTrail) sequence(now time.Time) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.last++
+	if seq := now.UnixNano(); seq > t.last {
+		t.last = seq
+	}
+
+	return t.last
+}
+
+// Records returns the records of the verifications between from and to (inclusive), ordered by sequence.
+func (t *AuditTrail) Records(from, to time.Time) ([]AuditRecord, error) {
+	lower, upper := milliseconds(from), milliseconds(to)
+
+	return t.records(func(record *AuditRecord) bool {
+		return record.Timestamp >= lower && record.Timestamp <= upper
+	})
+}
+
+// Query returns a page of the records matching the filters of the query, ordered by sequence. The next page is
+// queried with the cursor of the page.
+func (t *AuditTrail) Query(query *AuditQuery) (*AuditPage, error) {
+	var after int64
+
+	if query.Cursor != "" {
+		var err error
+
+		after, err = strconv.ParseInt(query.Cursor, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cursor %q is not valid", query.Cursor)
+		}
+	}
+
+	switch query.Outcome {
+	case "", AuditOutcomeValid, AuditOutcomeInvalid:
+	default:
+		return nil, fmt.Errorf("outcome %q is not %s or %s", query.Outcome, AuditOutcomeValid, AuditOutcomeInvalid)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultAuditPageSize
+	}
+
+	records, err := t.records(func(record *AuditRecord) bool {
+		return record.Sequence > after && query.matches(record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AuditPage{Records: records}
+
+	if len(records) > limit {
+		page.Records = records[:limit]
+		page.Cursor = strconv.FormatInt(page.Records[limit-1].Sequence, 10)
+	}
+
+	return page, nil
+}
+
+// records returns the records matching the filter, ordered by sequence.
+func (t *AuditTrail) records(match func(record *AuditRecord) bool) ([]AuditRecord, error) {
+	iter, err := t.store.Query(auditRecordTag)
+	if err != nil {
+		return nil, fmt.Errorf("query audit records: %w", err)
+	}
+
+	defer iter.Close() // nolint: errcheck
+
+	records := []AuditRecord{}
+
+	for {
+		ok, er := iter.Next()
+		if er != nil {
+			return nil, fmt.Errorf("next audit record: %w", er)
+		}
+
+		if !ok {
+			break
+		}
+
+		src, er := iter.Value()
+		if er != nil {
+			return nil, fmt.Errorf("audit record value: %w", er)
+		}
+
+		var record AuditRecord
+		if er = json.Unmarshal(src, &record); er != nil {
+			return nil, fmt.Errorf("unmarshal audit record: %w", er)
+		}
+
+		if match(&record) {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Sequence < records[j].Sequence })
+
+	return records, nil
+}
+
+// Export returns the signed export of the records of the verifications between from and to (inclusive).
+func (t *AuditTrail) Export(from, to time.Time) (*SignedVerificationAudit, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("export must end after it starts")
+	}
+
+	records, err := t.Records(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := VerificationAudit{
+		Version:       command.V1,
+		SignatureType: command.VerificationAuditSignatureType,
+		Timestamp:     milliseconds(time.Now()),
+		From:          milliseconds(from),
+		To:            milliseconds(to),
+		Records:       records,
+	}
+
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return nil, fmt.Errorf("marshal verification audit: %w", err)
+	}
+
+	signature, err := t.crypt.Sign(data, t.kh)
+	if err != nil {
+		return nil, fmt.Errorf("sign verification audit: %w", err)
+	}
+
+	signed, err := json.Marshal(command.DigitallySigned{Algorithm: *t.alg, Signature: signature})
+	if err != nil {
+		return nil, fmt.Errorf("marshal DigitallySigned payload: %w", err)
+	}
+
+	return &SignedVerificationAudit{Audit: audit, Signature: signed}, nil
+}
+
+// VerifyAudit verifies the signature of the export by the key of the verifier and that its records are ordered
+// within its bounds.
+func VerifyAudit(signed *SignedVerificationAudit, pubKey []byte) error {
+	audit := signed.Audit
+
+	if audit.Version != command.V1 || audit.SignatureType != command.VerificationAuditSignatureType {
+		return errors.New("export must be a v1 verification audit")
+	}
+
+	if err := command.VerifySignature(signed.Signature, pubKey, audit); err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+
+	for i, record := range audit.Records {
+		if record.Timestamp < audit.From || record.Timestamp > audit.To {
+			return fmt.Errorf("record %d is dated %d out of the export", record.Sequence, record.Timestamp)
+		}
+
+		if i > 0 && record.Sequence <= audit.Records[i-1].Sequence {
+			return fmt.Errorf("record %d is out of order", record.Sequence)
+		}
+	}
+
+	return nil
+}
+
+func milliseconds(t time.Time) uint64 {
+	return uint64(t.UnixNano()) / uint64(time.Millisecond)
+}
@@ -0,0 +1,305 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verify_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/internal/pkg/ldcontext"
+	"github.com/trustbloc/vct/pkg/client/verify"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
+
+// newAuditTrail returns an audit trail and the public key its exports are signed with.
+// The store is a mem store unless it is wrapped.
+func newAuditTrail(t *testing.T, wrap func(storage.Store) verify.AuditStore) (*verify.AuditTrail, []byte) {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://default/master/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	pubKey, _, err := km.ExportPubKeyBytes(keyID)
+	require.NoError(t, err)
+
+	store, err := mem.NewProvider().OpenStore("audit")
+	require.NoError(t, err)
+
+	var auditStore verify.AuditStore = store
+	if wrap != nil {
+		auditStore = wrap(store)
+	}
+
+	trail, err := verify.NewAuditTrail(auditStore, km, cr, keyID)
+	require.NoError(t, err)
+
+	return trail, pubKey
+}
+
+type failingStore struct {
+	storage.Store
+}
+
+func (failingStore) Put(string, []byte, ...storage.Tag) error {
+	return errors.New("disk full")
+}
+
+func TestAuditTrail(t *testing.T) { // nolint: funlen
+	vc, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(ldcontext.DocumentLoader(t)),
+	)
+	require.NoError(t, err)
+
+	t.Run("Batch", func(t *testing.T) {
+		trail, pubKey := newAuditTrail(t, nil)
+
+		l, sct := newLog(t, vc)
+		_, otherSCT := newLog(t, vc)
+
+		start := time.Now()
+
+		report := verify.Batch(context.Background(), []verify.Item{
+			{Log: l, Credential: vc, SCT: sct},
+			{Log: l, Credential: vc, SCT: otherSCT},
+		}, verify.WithInclusion(), verify.WithAuditTrail(trail), verify.WithWorkers(1))
+		require.Equal(t, 1, report.Valid)
+		require.NoError(t, report.Results[0].AuditErr)
+		require.NoError(t, report.Results[1].AuditErr)
+
+		records, err := trail.Records(start, time.Now())
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		valid := records[0]
+		require.True(t, valid.Valid)
+		require.Equal(t, vc.ID, valid.CredentialID)
+		require.Equal(t, sct, valid.SCT)
+		require.Equal(t, l.pubKey, valid.PublicKey)
+		require.Equal(t, l.sth, valid.STH)
+		require.Equal(t, l.proof, valid.AuditPath)
+		require.NotEmpty(t, valid.LeafHash)
+
+		invalid := records[1]
+		require.False(t, invalid.Valid)
+		require.Equal(t, "SCT is issued by another log", invalid.Error)
+		require.Greater(t, invalid.Sequence, valid.Sequence)
+
+		signed, err := trail.Export(start, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, command.VerificationAuditSignatureType, signed.Audit.SignatureType)
+		require.Equal(t, records, signed.Audit.Records)
+		require.NoError(t, verify.VerifyAudit(signed, pubKey))
+
+		// the export is signed by the verifier, not by the log
+		require.Error(t, verify.VerifyAudit(signed, l.pubKey))
+
+		signed.Audit.Records = signed.Audit.Records[1:]
+		require.Error(t, verify.VerifyAudit(signed, pubKey))
+	})
+
+	t.Run("Records in range", func(t *testing.T) {
+		trail, pubKey := newAuditTrail(t, nil)
+
+		day := time.Date(2021, time.April, 21, 0, 0, 0, 0, time.UTC)
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, trail.Record(&verify.AuditRecord{
+				Timestamp:    uint64(day.Add(time.Duration(i)*time.Hour).UnixNano() / int64(time.Millisecond)),
+				CredentialID: "urn:uuid:" + string(rune('a'+i)),
+				Valid:        true,
+			}))
+		}
+
+		records, err := trail.Records(day.Add(time.Hour), day.Add(3*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		require.Equal(t, "urn:uuid:b", records[0].CredentialID)
+		require.Equal(t, "urn:uuid:c", records[1].CredentialID)
+
+		signed, err := trail.Export(day, day.Add(time.Hour))
+		require.NoError(t, err)
+		require.Len(t, signed.Audit.Records, 2)
+		require.NoError(t, verify.VerifyAudit(signed, pubKey))
+
+		// a record out of the bounds of the export
+		signed.Audit.To--
+		require.Error(t, verify.VerifyAudit(signed, pubKey))
+
+		_, err = trail.Export(day, day.Add(-time.Hour))
+		require.EqualError(t, err, "export must end after it starts")
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		trail, _ := newAuditTrail(t, nil)
+
+		day := time.Date(2021, time.April, 21, 0, 0, 0, 0, time.UTC)
+		logKey := []byte("public key of the log")
+
+		for i := 0; i < 5; i++ {
+			record := &verify.AuditRecord{
+				Timestamp:    uint64(day.Add(time.Duration(i)*time.Hour).UnixNano() / int64(time.Millisecond)),
+				CredentialID: "urn:uuid:" + string(rune('a'+i)),
+				Valid:        i%2 == 0,
+			}
+
+			if i < 3 {
+				record.PublicKey = logKey
+				record.SCT = &command.AddVCResponse{Timestamp: record.Timestamp}
+				record.STH = &command.GetSTHResponse{TreeSize: uint64(i + 1)}
+				record.LeafIndex = int64(i)
+			}
+
+			if !record.Valid {
+				record.Error = "invalid SCT signature"
+			}
+
+			require.NoError(t, trail.Record(record))
+		}
+
+		page, err := trail.Query(&verify.AuditQuery{Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+		require.Equal(t, "urn:uuid:a", page.Records[0].CredentialID)
+		require.NotEmpty(t, page.Cursor)
+
+		page, err = trail.Query(&verify.AuditQuery{Limit: 2, Cursor: page.Cursor})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+		require.Equal(t, "urn:uuid:c", page.Records[0].CredentialID)
+
+		page, err = trail.Query(&verify.AuditQuery{Limit: 2, Cursor: page.Cursor})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, "urn:uuid:e", page.Records[0].CredentialID)
+		require.Empty(t, page.Cursor)
+
+		id := sha256.Sum256(logKey)
+
+		page, err = trail.Query(&verify.AuditQuery{
+			From:    day.Add(time.Hour),
+			Outcome: verify.AuditOutcomeInvalid,
+			LogID:   base64.StdEncoding.EncodeToString(id[:]),
+		})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		require.Equal(t, "urn:uuid:b", page.Records[0].CredentialID)
+
+		page, err = trail.Query(&verify.AuditQuery{To: day.Add(3 * time.Hour), Outcome: verify.AuditOutcomeValid})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+
+		page, err = trail.Query(&verify.AuditQuery{CredentialID: "urn:uuid:d"})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, verify.WriteAuditCSV(&buf, page.Records))
+
+		rows, err := csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, [][]string{
+			{
+				"sequence", "timestamp", "outcome", "credential_id", "log_id", "leaf_hash", "sct_timestamp",
+				"tree_size", "leaf_index", "error",
+			},
+			{
+				strconv.FormatInt(page.Records[0].Sequence, 10), strconv.FormatUint(page.Records[0].Timestamp, 10),
+				"invalid", "urn:uuid:d", "", "", "", "", "", "invalid SCT signature",
+			},
+		}, rows)
+
+		buf.Reset()
+
+		page, err = trail.Query(&verify.AuditQuery{Limit: 1})
+		require.NoError(t, err)
+		require.NoError(t, verify.WriteAuditCSV(&buf, page.Records))
+
+		rows, err = csv.NewReader(&buf).ReadAll()
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			strconv.FormatInt(page.Records[0].Sequence, 10), strconv.FormatUint(page.Records[0].Timestamp, 10),
+			"valid", "urn:uuid:a", base64.StdEncoding.EncodeToString(id[:]), "",
+			strconv.FormatUint(page.Records[0].Timestamp, 10), "1", "0", "",
+		}, rows[1])
+
+		_, err = trail.Query(&verify.AuditQuery{Cursor: "next"})
+		require.EqualError(t, err, `cursor "next" is not valid`)
+
+		_, err = trail.Query(&verify.AuditQuery{Outcome: "failed"})
+		require.EqualError(t, err, `outcome "failed" is not valid or invalid`)
+	})
+
+	t.Run("Not an export", func(t *testing.T) {
+		err := verify.VerifyAudit(&verify.SignedVerificationAudit{}, nil)
+		require.EqualError(t, err, "export must be a v1 verification audit")
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		trail, _ := newAuditTrail(t, func(store storage.Store) verify.AuditStore { return failingStore{store} })
+
+		l, sct := newLog(t, vc)
+
+		report := verify.Batch(context.Background(), []verify.Item{{Log: l, Credential: vc, SCT: sct}},
+			verify.WithAuditTrail(trail))
+		require.Equal(t, 1, report.Valid)
+		require.EqualError(t, report.Results[0].AuditErr, "store audit record: disk full")
+	})
+
+	t.Run("Key not found", func(t *testing.T) {
+		km, err := localkms.New("local-lock://default/master/key/", &kmsProvider{
+			storageProvider: mem.NewProvider(),
+			secretLock:      &noop.NoLock{},
+		})
+		require.NoError(t, err)
+
+		_, err = verify.NewAuditTrail(nil, km, nil, "unknown")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "export pub key bytes")
+	})
+}
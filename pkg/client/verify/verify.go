@@ -0,0 +1,384 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package verify verifies the transparency of many credentials concurrently, for the verifier backends processing
+// high volumes of credentials: the SCTs and the inclusion proofs of the credentials are verified by a bounded pool
+// of workers, and the public key and the tree head of each log are fetched once per batch. The verifications can be
+// recorded to an audit trail (see AuditTrail) for the resolution of later disputes.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const defaultWorkers = 8
+
+// ErrUntrustedKey is returned for the items of a log whose fetched public key is not the trusted key of the log
+// (see WithTrustedKey and WithTrustedLogID).
+var ErrUntrustedKey = errors.New("public key of the log is not trusted")
+
+// Log is the log an SCT is issued by, vct.Client implements it. The logs are compared to share their fetches,
+// so a Log must be comparable (e.g. a pointer).
+type Log interface {
+	GetPublicKey(ctx context.Context) ([]byte, error)
+	GetSTH(ctx context.Context) (*command.GetSTHResponse, error)
+	GetProofByHash(ctx context.Context, hash string, treeSize uint64) (*command.GetProofByHashResponse, error)
+	CalculateLeafHash(timestamp uint64, vc *verifiable.Credential, opts ...vct.LeafOpt) (string, error)
+}
+
+// Item is a credential and its SCT, verified against the log.
+type Item struct {
+	Log        Log
+	Credential *verifiable.Credential
+	SCT        *command.AddVCResponse
+}
+
+// Result is the outcome of the verification of an item.
+type Result struct {
+	// Err is the reason the item failed verification, nil if it is valid.
+	Err error
+	// LeafIndex is the index of the credential in the tree, set if the inclusion is verified (see WithInclusion).
+	LeafIndex int64
+	// TreeSize is the size of the tree head the inclusion is verified in, zero if it is not verified.
+	TreeSize uint64
+	// AuditErr is the error of the record of the verification to the audit trail (see WithAuditTrail).
+	AuditErr error
+}
+
+// Report is the outcome of a batch.
+type Report struct {
+	// Results are the results of the items, in the order of the items.
+	Results []Result
+	Valid   int
+	Invalid int
+	// Duration is the time the batch took.
+	Duration time.Duration
+}
+
+// Opt is an option of a batch.
+type Opt func(*options)
+
+type options struct {
+	workers   int
+	inclusion bool
+	verifier  vct.Verifier
+	trail     *AuditTrail
+	trusted   map[Log][]byte // log -> trusted log ID
+}
+
+// WithWorkers sets the max number of items verified concurrently, 8 by default.
+func WithWorkers(n int) Opt {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithInclusion verifies the inclusion of the credentials in the latest tree head of their log too, only the SCTs
+// are verified by default. A credential which is not merged yet (its SCT is newer than the tree head) is invalid.
+func WithInclusion() Opt {
+	return func(o *options) {
+		o.inclusion = true
+	}
+}
+
+// WithVerifier sets the verifier of the inclusion proofs, the verifier of vct.DefaultHasher by default. It must
+// match the hasher of the logs.
+func WithVerifier(v vct.Verifier) Opt {
+	return func(o *options) {
+		o.verifier = v
+	}
+}
+
+// WithTrustedKey pins the public key of the log: the key the log serves must be the key, the items of the log fail
+// with ErrUntrustedKey otherwise. The key of a log which is not pinned is trusted as served.
+func WithTrustedKey(log Log, pubKey []byte) Opt {
+	logID := command.LogID(pubKey)
+
+	return WithTrustedLogID(log, logID[:])
+}
+
+// WithTrustedLogID pins the log ID (the SHA-256 hash of the public key) of the log: the key the log serves must
+// have the log ID, the items of the log fail with ErrUntrustedKey otherwise.
+func WithTrustedLogID(log Log, logID []byte) Opt {
+	return func(o *options) {
+		if o.trusted == nil {
+			o.trusted = map[Log][]byte{}
+		}
+
+		o.trusted[log] = logID
+	}
+}
+
+// WithAuditTrail records the verifications of the items to the audit trail: their inputs, the tree heads they used
+// and their results. The items which were not verified (e.g. the items left when the context is done) are not
+// recorded.
+func WithAuditTrail(trail *AuditTrail) Opt {
+	return func(o *options) {
+		o.trail = trail
+	}
+}
+
+// logState is the public key and the tree head of a log, fetched once per batch by the first item of the log.
+type logState struct {
+	once   sync.Once
+	pubKey []byte
+	sth    *command.GetSTHResponse
+	err    error
+}
+
+type batch struct {
+	options
+	mu   sync.Mutex
+	logs map[Log]*logState
+}
+
+// Batch verifies the items concurrently with a bounded pool of workers (see WithWorkers), a failed item does not
+// stop the batch. The items left when the context is done fail with its error.
+func Batch(ctx context.Context, items []Item, opts ...Opt) *Report {
+	start := time.Now()
+
+	b := &batch{
+		options: options{workers: defaultWorkers, verifier: vct.NewVerifier(vct.DefaultHasher)},
+		logs:    map[Log]*logState{},
+	}
+
+	for _, opt := range opts {
+		opt(&b.options)
+	}
+
+	if b.workers < 1 {
+		b.workers = 1
+	}
+
+	report := &Report{Results: make([]Result, len(items))}
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < b.workers && w < len(items); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				report.Results[i] = b.verify(ctx, &items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		if ctx.Err() != nil {
+			report.Results[i] = Result{Err: ctx.Err()}
+
+			continue
+		}
+
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.Err == nil {
+			report.Valid++
+		} else {
+			report.Invalid++
+		}
+	}
+
+	report.Duration = time.Since(start)
+
+	return report
+}
+
+func (b *batch) verify(ctx context.Context, item *Item) Result {
+	if item.Log == nil || item.Credential == nil || item.SCT == nil {
+		return Result{Err: errors.New("item must have a log, a credential and an SCT")}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return Result{Err: err}
+	}
+
+	record := &AuditRecord{CredentialID: item.Credential.ID, SCT: item.SCT}
+
+	result := b.verifyItem(ctx, item, record)
+
+	if b.trail != nil {
+		record.Valid = result.Err == nil
+		if result.Err != nil {
+			record.Error = result.Err.Error()
+		}
+
+		result.AuditErr = b.trail.Record(record)
+	}
+
+	return result
+}
+
+// verifyItem verifies the item, the inputs of the verification and the tree head it used are set to the record.
+func (b *batch) verifyItem(ctx context.Context, item *Item, record *AuditRecord) Result {
+	state := b.log(ctx, item.Log)
+	if state.err != nil {
+		return Result{Err: state.err}
+	}
+
+	record.PublicKey = state.pubKey
+
+	if b.inclusion || b.trail != nil {
+		leafHash, err := calculateLeafHash(item)
+		if err != nil {
+			return Result{Err: err}
+		}
+
+		record.LeafHash = leafHash
+	}
+
+	if err := vct.VerifySCT(item.SCT, state.pubKey, item.Credential); err != nil {
+		return Result{Err: err}
+	}
+
+	if !b.inclusion {
+		return Result{}
+	}
+
+	record.STH = state.sth
+
+	proof, err := b.verifyInclusion(ctx, item, record.LeafHash, state.sth)
+	if proof != nil {
+		record.LeafIndex, record.AuditPath = proof.LeafIndex, proof.AuditPath
+	}
+
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	return Result{LeafIndex: proof.LeafIndex, TreeSize: state.sth.TreeSize}
+}
+
+// log returns the state of the log, the first item of the log fetches it for the others.
+func (b *batch) log(ctx context.Context, log Log) *logState {
+	b.mu.Lock()
+
+	state, ok := b.logs[log]
+	if !ok {
+		state = &logState{}
+		b.logs[log] = state
+	}
+
+	b.mu.Unlock()
+
+	state.once.Do(func() {
+		state.pubKey, state.err = log.GetPublicKey(ctx)
+		if state.err != nil {
+			return
+		}
+
+		if state.err = b.checkTrusted(log, state.pubKey); state.err != nil || !b.inclusion {
+			return
+		}
+
+		state.sth, state.err = log.GetSTH(ctx)
+		if state.err != nil {
+			return
+		}
+
+		state.err = verifySTH(state.sth, state.pubKey)
+	})
+
+	return state
+}
+
+// checkTrusted returns ErrUntrustedKey if the log ID of the log is pinned and the public key does not have it.
+func (b *batch) checkTrusted(log Log, pubKey []byte) error {
+	trusted, ok := b.trusted[log]
+	if !ok {
+		return nil
+	}
+
+	if logID := command.LogID(pubKey); !bytes.Equal(logID[:], trusted) {
+		return fmt.Errorf("%w: log ID %s, trusted %s", ErrUntrustedKey,
+			base64.StdEncoding.EncodeToString(logID[:]), base64.StdEncoding.EncodeToString(trusted))
+	}
+
+	return nil
+}
+
+// calculateLeafHash returns the hash of the leaf of the credential logged with the SCT.
+func calculateLeafHash(item *Item) ([]byte, error) {
+	var leafOpts []vct.LeafOpt
+
+	if item.SCT.Extensions != "" {
+		extensions, err := base64.StdEncoding.DecodeString(item.SCT.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("decode extensions: %w", err)
+		}
+
+		leafOpts = append(leafOpts, vct.WithExtensions(extensions))
+	}
+
+	hash, err := item.Log.CalculateLeafHash(item.SCT.Timestamp, item.Credential, leafOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("calculate leaf hash: %w", err)
+	}
+
+	leafHash, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("decode leaf hash: %w", err)
+	}
+
+	return leafHash, nil
+}
+
+// verifyInclusion verifies the inclusion of the leaf in the tree head, the proof is returned if it was fetched.
+func (b *batch) verifyInclusion(ctx context.Context, item *Item, leafHash []byte,
+	sth *command.GetSTHResponse) (*command.GetProofByHashResponse, error) {
+	if item.SCT.Timestamp > sth.Timestamp {
+		return nil, fmt.Errorf("SCT is dated %d after the tree head %d", item.SCT.Timestamp, sth.Timestamp)
+	}
+
+	proof, err := item.Log.GetProofByHash(ctx, base64.StdEncoding.EncodeToString(leafHash), sth.TreeSize)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	err = b.verifier.VerifyInclusionProof(proof.LeafIndex, int64(sth.TreeSize), proof.AuditPath, sth.SHA256RootHash,
+		leafHash)
+	if err != nil {
+		return proof, fmt.Errorf("verify inclusion proof: %w", err)
+	}
+
+	return proof, nil
+}
+
+func verifySTH(sth *command.GetSTHResponse, pubKey []byte) error {
+	err := command.VerifySignature(sth.TreeHeadSignature, pubKey, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+	if err != nil {
+		return fmt.Errorf("verify STH signature: %w", err)
+	}
+
+	return nil
+}
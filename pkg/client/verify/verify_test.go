@@ -0,0 +1,265 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verify_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/internal/pkg/ldcontext"
+	"github.com/trustbloc/vct/pkg/client/vct"
+	"github.com/trustbloc/vct/pkg/client/verify"
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const sctTimestamp = 1619006293939
+
+//go:embed testdata/bachelor_degree.json
+var vcBachelorDegree []byte // nolint: gochecknoglobals
+
+func signStatement(t *testing.T, key *ecdsa.PrivateKey, statement interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(statement)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(data)
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature, err := json.Marshal(command.DigitallySigned{
+		Algorithm: command.SignatureAndHashAlgorithm{
+			Signature: command.ECDSASignature,
+			Type:      kms.ECDSAP256TypeIEEEP1363,
+		},
+		Signature: append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...),
+	})
+	require.NoError(t, err)
+
+	return signature
+}
+
+// log is a log of two leaves: the leaf of the credential and another leaf.
+type log struct {
+	pubKey    []byte
+	sth       *command.GetSTHResponse
+	proof     [][]byte
+	keyErr    error
+	keyFetch  int32
+	sthFetch  int32
+	proofGets int32
+}
+
+func newLog(t *testing.T, vc *verifiable.Credential) (*log, *command.AddVCResponse) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	pubKey := elliptic.Marshal(elliptic.P256(), key.X, key.Y) // nolint: staticcheck
+	logID := command.LogID(pubKey)
+
+	leaf, err := command.CreateLeaf(sctTimestamp, vc)
+	require.NoError(t, err)
+
+	hash, err := vct.CalculateLeafHash(sctTimestamp, vc)
+	require.NoError(t, err)
+
+	leafHash, err := base64.StdEncoding.DecodeString(hash)
+	require.NoError(t, err)
+
+	other := hasher.DefaultHasher.HashLeaf([]byte("other"))
+
+	sth := &command.GetSTHResponse{
+		TreeSize:       2,
+		Timestamp:      sctTimestamp + 1000,
+		SHA256RootHash: hasher.DefaultHasher.HashChildren(leafHash, other),
+	}
+	sth.TreeHeadSignature = signStatement(t, key, command.TreeHeadSignature{
+		Version:        command.V1,
+		SignatureType:  command.TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+
+	return &log{pubKey: pubKey, sth: sth, proof: [][]byte{other}}, &command.AddVCResponse{
+		SVCTVersion: command.V1,
+		ID:          logID[:],
+		Timestamp:   sctTimestamp,
+		Signature:   signStatement(t, key, command.CreateVCTimestampSignature(leaf)),
+	}
+}
+
+func (l *log) GetPublicKey(context.Context) ([]byte, error) {
+	atomic.AddInt32(&l.keyFetch, 1)
+
+	return l.pubKey, l.keyErr
+}
+
+func (l *log) GetSTH(context.Context) (*command.GetSTHResponse, error) {
+	atomic.AddInt32(&l.sthFetch, 1)
+
+	return l.sth, nil
+}
+
+func (l *log) GetProofByHash(_ context.Context, _ string, treeSize uint64) (*command.GetProofByHashResponse, error) {
+	atomic.AddInt32(&l.proofGets, 1)
+
+	if treeSize != l.sth.TreeSize {
+		return nil, errors.New("unexpected tree size")
+	}
+
+	return &command.GetProofByHashResponse{LeafIndex: 0, AuditPath: l.proof}, nil
+}
+
+func (l *log) CalculateLeafHash(timestamp uint64, vc *verifiable.Credential, opts ...vct.LeafOpt) (string, error) {
+	return vct.CalculateLeafHash(timestamp, vc, opts...)
+}
+
+func TestBatch(t *testing.T) { // nolint: funlen
+	vc, err := verifiable.ParseCredential(vcBachelorDegree,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(ldcontext.DocumentLoader(t)),
+	)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		first, firstSCT := newLog(t, vc)
+		second, secondSCT := newLog(t, vc)
+
+		var items []verify.Item
+
+		for i := 0; i < 50; i++ {
+			items = append(items,
+				verify.Item{Log: first, Credential: vc, SCT: firstSCT},
+				verify.Item{Log: second, Credential: vc, SCT: secondSCT},
+			)
+		}
+
+		report := verify.Batch(context.Background(), items, verify.WithWorkers(4), verify.WithInclusion())
+		require.Equal(t, 100, report.Valid)
+		require.Zero(t, report.Invalid)
+		require.Len(t, report.Results, 100)
+
+		for _, result := range report.Results {
+			require.NoError(t, result.Err)
+			require.Equal(t, uint64(2), result.TreeSize)
+			require.Zero(t, result.LeafIndex)
+		}
+
+		// the key and the tree head of a log are fetched once per batch
+		for _, l := range []*log{first, second} {
+			require.Equal(t, int32(1), atomic.LoadInt32(&l.keyFetch))
+			require.Equal(t, int32(1), atomic.LoadInt32(&l.sthFetch))
+			require.Equal(t, int32(50), atomic.LoadInt32(&l.proofGets))
+		}
+	})
+
+	t.Run("SCTs only", func(t *testing.T) {
+		l, sct := newLog(t, vc)
+
+		report := verify.Batch(context.Background(), []verify.Item{{Log: l, Credential: vc, SCT: sct}})
+		require.Equal(t, 1, report.Valid)
+		require.Equal(t, verify.Result{}, report.Results[0])
+		require.Zero(t, atomic.LoadInt32(&l.sthFetch))
+		require.Zero(t, atomic.LoadInt32(&l.proofGets))
+	})
+
+	t.Run("Invalid items", func(t *testing.T) {
+		l, sct := newLog(t, vc)
+		other, otherSCT := newLog(t, vc)
+
+		broken, brokenSCT := newLog(t, vc)
+		broken.keyErr = errors.New("webfinger unavailable")
+
+		// not merged in the tree head
+		late := *sct
+		late.Timestamp = l.sth.Timestamp + 1
+
+		// included in another tree
+		excluded, _ := newLog(t, vc)
+		excluded.pubKey, excluded.sth = l.pubKey, l.sth
+		excluded.proof = [][]byte{hasher.DefaultHasher.HashLeaf([]byte("another"))}
+
+		report := verify.Batch(context.Background(), []verify.Item{
+			{Log: l, Credential: vc, SCT: sct},
+			{Log: l, Credential: vc, SCT: otherSCT},
+			{Log: broken, Credential: vc, SCT: brokenSCT},
+			{Log: l, Credential: vc, SCT: &late},
+			{Log: excluded, Credential: vc, SCT: sct},
+			{Log: other, Credential: vc},
+		}, verify.WithInclusion())
+		require.Equal(t, 1, report.Valid)
+		require.Equal(t, 5, report.Invalid)
+
+		require.NoError(t, report.Results[0].Err)
+		require.EqualError(t, report.Results[1].Err, "SCT is issued by another log")
+		require.EqualError(t, report.Results[2].Err, "webfinger unavailable")
+		require.Contains(t, report.Results[3].Err.Error(), "verify SCT signature")
+		require.Contains(t, report.Results[4].Err.Error(), "verify inclusion proof")
+		require.EqualError(t, report.Results[5].Err, "item must have a log, a credential and an SCT")
+	})
+
+	t.Run("Trusted keys", func(t *testing.T) {
+		l, sct := newLog(t, vc)
+		other, otherSCT := newLog(t, vc)
+
+		// the log serves the key of another log, the SCT is signed with it
+		impostor, _ := newLog(t, vc)
+		impostor.pubKey = other.pubKey
+
+		report := verify.Batch(context.Background(), []verify.Item{
+			{Log: l, Credential: vc, SCT: sct},
+			{Log: other, Credential: vc, SCT: otherSCT},
+			{Log: impostor, Credential: vc, SCT: otherSCT},
+		}, verify.WithTrustedKey(l, l.pubKey), verify.WithTrustedLogID(other, otherSCT.ID),
+			verify.WithTrustedKey(impostor, l.pubKey))
+		require.Equal(t, 2, report.Valid)
+		require.Equal(t, 1, report.Invalid)
+		require.ErrorIs(t, report.Results[2].Err, verify.ErrUntrustedKey)
+	})
+
+	t.Run("Bad tree head", func(t *testing.T) {
+		l, sct := newLog(t, vc)
+		l.sth.TreeSize = 3
+
+		report := verify.Batch(context.Background(), []verify.Item{{Log: l, Credential: vc, SCT: sct}},
+			verify.WithInclusion())
+		require.Equal(t, 1, report.Invalid)
+		require.Contains(t, report.Results[0].Err.Error(), "verify STH signature")
+	})
+
+	t.Run("Context canceled", func(t *testing.T) {
+		l, sct := newLog(t, vc)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report := verify.Batch(ctx, []verify.Item{{Log: l, Credential: vc, SCT: sct}, {Log: l, Credential: vc, SCT: sct}})
+		require.Equal(t, 2, report.Invalid)
+		require.ErrorIs(t, report.Results[0].Err, context.Canceled)
+		require.ErrorIs(t, report.Results[1].Err, context.Canceled)
+		require.Zero(t, atomic.LoadInt32(&l.keyFetch))
+	})
+}
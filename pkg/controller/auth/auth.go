@@ -0,0 +1,407 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package auth implements role-based authorization of the REST API.
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Role is a set of endpoints a principal is allowed to call.
+type Role string
+
+// Roles.
+const (
+	// RoleReader may call the read endpoints (tree heads, entries, proofs, lookups).
+	RoleReader Role = "reader"
+	// RoleSubmitter may only call the write endpoints (add-vc, add-revocation, add-anchor, add-entry).
+	RoleSubmitter Role = "submitter"
+	// RoleAuditor may call the read endpoints, annotate entries and inspect the operation of the service
+	// (metrics, admin settings) without changing it.
+	RoleAuditor Role = "auditor"
+	// RoleAdmin may call all endpoints, e.g. the changes of the JSON-LD contexts of the logs.
+	RoleAdmin Role = "admin"
+)
+
+// Kinds of principals a role may be bound to.
+const (
+	// KindToken is an API key sent as a bearer token.
+	KindToken = "token"
+	// KindMTLS is the subject common name of a verified TLS client certificate.
+	KindMTLS = "mtls"
+	// KindScope is an OAuth scope forwarded by a trusted gateway (see WithScopesHeader).
+	KindScope = "scope"
+)
+
+const (
+	healthCheckEndpoint   = "/healthcheck"
+	webFingerEndpoint     = "/.well-known/webfinger"
+	addVCEndpoint         = "/add-vc"
+	addRevocationEndpoint = "/add-revocation"
+	addAnchorEndpoint     = "/add-anchor"
+	addEntryEndpoint      = "/add-entry"
+	addAnnotationEndpoint = "/add-annotation"
+	adminEndpoint         = "/admin/"
+	metricsEndpoint       = "/metrics"
+	snapshotEndpoint      = "/verification-snapshot"
+	finalTreeHeadEndpoint = "/final-sth"
+	retiredShardsEndpoint = "/retired-shards"
+	policyEndpoint        = "/policy"
+	policyHistory         = "history"
+	// ldSegment is the path segment of the JSON-LD context endpoints of a log, i.e. /{alias}/v1/ld/...
+	ldSegment = "ld"
+	// debugParam is the query parameter of the proof endpoints returning the timing breakdown of the proof.
+	debugParam = "debug"
+)
+
+// nolint: gochecknoglobals
+var roles = []Role{RoleReader, RoleSubmitter, RoleAuditor, RoleAdmin}
+
+// Binding grants the role to a principal.
+type Binding struct {
+	Role  Role
+	Kind  string
+	Value string
+}
+
+// ParseBinding parses a binding in the format <role>:<kind>:<value>, e.g. submitter:token:secret.
+func ParseBinding(raw string) (Binding, error) {
+	const bindingParts = 3
+
+	parts := strings.SplitN(raw, ":", bindingParts)
+	if len(parts) != bindingParts || parts[2] == "" {
+		return Binding{}, fmt.Errorf("invalid role binding %q, format must be <role>:<kind>:<value>", raw)
+	}
+
+	binding := Binding{Role: Role(parts[0]), Kind: parts[1], Value: parts[2]}
+
+	if !containsRole(roles, binding.Role) {
+		return Binding{}, fmt.Errorf("unknown role %q", parts[0])
+	}
+
+	switch binding.Kind {
+	case KindToken, KindMTLS, KindScope:
+	default:
+		return Binding{}, fmt.Errorf("unknown principal kind %q", parts[1])
+	}
+
+	return binding, nil
+}
+
+// LegacyBindings returns the bindings of the read and write tokens: the read token grants the reader role,
+// the write token the submitter, auditor and admin roles. Empty tokens are not bound.
+func LegacyBindings(readToken, writeToken string) []Binding {
+	var bindings []Binding
+
+	if readToken != "" {
+		bindings = append(bindings,
+			Binding{Role: RoleReader, Kind: KindToken, Value: readToken},
+		)
+	}
+
+	if writeToken != "" {
+		bindings = append(bindings,
+			Binding{Role: RoleSubmitter, Kind: KindToken, Value: writeToken},
+			Binding{Role: RoleAuditor, Kind: KindToken, Value: writeToken},
+			Binding{Role: RoleAdmin, Kind: KindToken, Value: writeToken},
+		)
+	}
+
+	return bindings
+}
+
+// Opt represents authorizer option func.
+type Opt func(*Authorizer)
+
+// WithScopesHeader sets the header the space-separated OAuth scopes of the request are read from.
+// The header must be set by a gateway which validated the access token and strips the header sent by clients.
+func WithScopesHeader(name string) Opt {
+	return func(a *Authorizer) {
+		a.scopesHeader = name
+	}
+}
+
+// WithOpenRoles opens the endpoints intended for the reader and submitter roles if the roles are bound to nobody,
+// as the legacy read and write tokens do. The endpoints intended for the auditor and admin roles are never open.
+func WithOpenRoles() Opt {
+	return func(a *Authorizer) {
+		a.openByDefault = true
+	}
+}
+
+// Authorizer authorizes requests by the roles bound to their principals.
+// An endpoint intended for a role bound to nobody is forbidden, unless the role may be open (see WithOpenRoles).
+type Authorizer struct {
+	bindings      []Binding
+	open          []Role
+	unbound       []Role
+	openByDefault bool
+	scopesHeader  string
+}
+
+// New returns an authorizer.
+func New(bindings []Binding, opts ...Opt) *Authorizer {
+	a := &Authorizer{bindings: bindings}
+
+	for _, fn := range opts {
+		fn(a)
+	}
+
+	bound := map[Role]bool{}
+	for _, b := range bindings {
+		bound[b.Role] = true
+	}
+
+	for _, role := range roles {
+		if bound[role] {
+			continue
+		}
+
+		a.unbound = append(a.unbound, role)
+
+		if a.openByDefault && (role == RoleReader || role == RoleSubmitter) {
+			a.open = append(a.open, role)
+		}
+	}
+
+	return a
+}
+
+// OpenRoles returns the roles bound to nobody the endpoints of which are open (see WithOpenRoles).
+func (a *Authorizer) OpenRoles() []Role {
+	return a.open
+}
+
+// UnboundRoles returns the roles bound to nobody, the endpoints intended for them are forbidden unless they are open.
+func (a *Authorizer) UnboundRoles() []Role {
+	return a.unbound
+}
+
+// RequiredRoles returns the roles allowed to call the endpoint of the request, nil if the endpoint is public.
+// The first role is the role the endpoint is intended for, the others are more privileged roles.
+// The endpoints are classified on the path of the request, the query never changes the roles but the debug
+// parameter. The requests other than GET and HEAD to an endpoint not classified otherwise require the admin role.
+func RequiredRoles(r *http.Request) []Role {
+	u := requestURL(r)
+	path := u.Path
+	last := path[strings.LastIndex(path, "/")+1:]
+
+	switch {
+	case path == healthCheckEndpoint || path == retiredShardsEndpoint || isAliasPath(path, webFingerEndpoint) ||
+		isAliasPath(path, snapshotEndpoint) || isAliasPath(path, finalTreeHeadEndpoint) ||
+		isPolicyPath(path):
+		return nil
+	case "/"+last == addVCEndpoint || "/"+last == addRevocationEndpoint ||
+		"/"+last == addAnchorEndpoint || "/"+last == addEntryEndpoint:
+		return []Role{RoleSubmitter, RoleAdmin}
+	case "/"+last == addAnnotationEndpoint:
+		return []Role{RoleAuditor, RoleAdmin}
+	case isLDPath(path) && !isRead(r):
+		// the contexts and the remote providers (fetched by the server) are changed by the admins only
+		return []Role{RoleAdmin}
+	case strings.HasPrefix(path, adminEndpoint) && r.Method != http.MethodGet:
+		return []Role{RoleAdmin}
+	case strings.HasPrefix(path, adminEndpoint) || path == metricsEndpoint:
+		return []Role{RoleAuditor, RoleAdmin}
+	case u.Query().Has(debugParam) || !isRead(r):
+		return []Role{RoleAdmin}
+	default:
+		return []Role{RoleReader, RoleAuditor, RoleAdmin}
+	}
+}
+
+// requestURL returns the URL of the request, parsed from its request URI if it has none. A request URI which does
+// not parse has the root path.
+func requestURL(r *http.Request) *url.URL {
+	if r.URL != nil {
+		return r.URL
+	}
+
+	u, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil {
+		return &url.URL{Path: "/"}
+	}
+
+	return u
+}
+
+// isRead returns true if the request reads, i.e. its method is GET or HEAD.
+func isRead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// isLDPath returns true if the path is a JSON-LD context endpoint of a log, i.e. /{alias}/v1/ld/...
+func isLDPath(path string) bool {
+	parts := strings.SplitN(path, "/", 5) // nolint: gomnd
+
+	return len(parts) == 5 && parts[0] == "" && parts[1] != "" && parts[2] == "v1" && parts[3] == ldSegment
+}
+
+// isAliasPath returns true if the path is the endpoint of a log, i.e. /{alias}<endpoint>. The admin endpoints are
+// not endpoints of a log.
+func isAliasPath(path, endpoint string) bool {
+	alias := strings.TrimSuffix(path, endpoint)
+
+	return alias != path && len(alias) > 1 && strings.LastIndex(alias, "/") == 0 && alias+"/" != adminEndpoint
+}
+
+// isPolicyPath returns true if the path is a policy endpoint of a log: /{alias}/policy, /{alias}/policy/history or
+// /{alias}/policy/{version}.
+func isPolicyPath(path string) bool {
+	if isAliasPath(path, policyEndpoint) {
+		return true
+	}
+
+	i := strings.LastIndex(path, "/")
+	if i < 0 || !isAliasPath(path[:i], policyEndpoint) {
+		return false
+	}
+
+	version := path[i+1:]
+	if version == policyHistory {
+		return true
+	}
+
+	return version != "" && strings.Trim(version, "0123456789") == ""
+}
+
+// Authorize returns the status code the request is rejected with, or zero if the request is authorized.
+// A request with no known principal is rejected as unauthorized, otherwise as forbidden. A request to an endpoint
+// no principal may call (its roles are bound to nobody) is rejected as forbidden.
+func (a *Authorizer) Authorize(r *http.Request) int {
+	required := RequiredRoles(r)
+	if required == nil {
+		return 0
+	}
+
+	if containsRole(a.open, required[0]) {
+		return 0
+	}
+
+	granted, authenticated := a.roles(r)
+
+	for _, role := range granted {
+		if containsRole(required, role) {
+			return 0
+		}
+	}
+
+	if !authenticated && !a.unboundRoles(required) {
+		return http.StatusUnauthorized
+	}
+
+	return http.StatusForbidden
+}
+
+// unboundRoles returns true if all the roles are bound to nobody.
+func (a *Authorizer) unboundRoles(required []Role) bool {
+	for _, role := range required {
+		if !containsRole(a.unbound, role) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// roles returns the roles bound to the principals of the request and whether any principal is known.
+func (a *Authorizer) roles(r *http.Request) ([]Role, bool) {
+	var (
+		granted       []Role
+		authenticated bool
+	)
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	var identity string
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.PeerCertificates) > 0 {
+		identity = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	var scopes []string
+	if a.scopesHeader != "" {
+		scopes = strings.Fields(r.Header.Get(a.scopesHeader))
+	}
+
+	for _, b := range a.bindings {
+		var matched bool
+
+		switch b.Kind {
+		case KindToken:
+			matched = token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(b.Value)) == 1
+		case KindMTLS:
+			matched = identity != "" && identity == b.Value
+		case KindScope:
+			matched = containsString(scopes, b.Value)
+		}
+
+		if matched {
+			granted = append(granted, b.Role)
+			authenticated = true
+		}
+	}
+
+	return granted, authenticated
+}
+
+// Middleware returns the middleware rejecting requests which are not authorized.
+func (a *Authorizer) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status := a.Authorize(r); status != 0 {
+				w.WriteHeader(status)
+
+				if status == http.StatusUnauthorized {
+					w.Write([]byte("Unauthorised.\n")) // nolint:gosec,errcheck
+				} else {
+					w.Write([]byte("Forbidden.\n")) // nolint:gosec,errcheck
+				}
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasMTLSBindings returns true if a role is bound to a TLS client certificate.
+func HasMTLSBindings(bindings []Binding) bool {
+	for _, b := range bindings {
+		if b.Kind == KindMTLS {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsRole(roles []Role, role Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, val := range values {
+		if val == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,298 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auth_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/controller/auth"
+)
+
+func request(method, uri string, headers map[string]string) *http.Request {
+	r := &http.Request{Method: method, RequestURI: uri, Header: http.Header{}}
+
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+
+	return r
+}
+
+func withIdentity(r *http.Request, commonName string, verified bool) *http.Request {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	if verified {
+		r.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+
+	return r
+}
+
+func TestParseBinding(t *testing.T) {
+	binding, err := ParseBinding("submitter:token:se:cret")
+	require.NoError(t, err)
+	require.Equal(t, Binding{Role: RoleSubmitter, Kind: KindToken, Value: "se:cret"}, binding)
+
+	_, err = ParseBinding("submitter:token")
+	require.EqualError(t, err,
+		`invalid role binding "submitter:token", format must be <role>:<kind>:<value>`)
+
+	_, err = ParseBinding("submitter:token:")
+	require.Contains(t, err.Error(), "invalid role binding")
+
+	_, err = ParseBinding("owner:token:secret")
+	require.EqualError(t, err, `unknown role "owner"`)
+
+	_, err = ParseBinding("admin:password:secret")
+	require.EqualError(t, err, `unknown principal kind "password"`)
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	authorizer := New([]Binding{
+		{Role: RoleReader, Kind: KindToken, Value: "read"},
+		{Role: RoleSubmitter, Kind: KindToken, Value: "submit"},
+		{Role: RoleSubmitter, Kind: KindScope, Value: "vct:submit"},
+		{Role: RoleAuditor, Kind: KindScope, Value: "vct:audit"},
+		{Role: RoleAdmin, Kind: KindMTLS, Value: "operator"},
+	}, WithScopesHeader("X-Scopes"))
+
+	require.Empty(t, authorizer.OpenRoles())
+
+	for _, tc := range []struct {
+		name   string
+		r      *http.Request
+		status int
+	}{
+		{"Health check is public", request(http.MethodGet, "/healthcheck", nil), 0},
+		{"Webfinger is public", request(http.MethodGet, "/maple2021/.well-known/webfinger", nil), 0},
+		{"Verification snapshot is public", request(http.MethodGet, "/maple2021/verification-snapshot", nil), 0},
+		{"Final tree head is public", request(http.MethodGet, "/maple2021/final-sth", nil), 0},
+		{"Retired shards are public", request(http.MethodGet, "/retired-shards?tenant=maple", nil), 0},
+		{"Policy is public", request(http.MethodGet, "/maple2021/policy/history", nil), 0},
+		{"Policy published by auditor", request(http.MethodPost, "/admin/policy",
+			map[string]string{"X-Scopes": "vct:audit"}), http.StatusForbidden},
+		{"Read without principal", request(http.MethodGet, "/maple2021/v1/get-sth", nil), http.StatusUnauthorized},
+		{"Read with unknown token", request(http.MethodGet, "/maple2021/v1/get-sth",
+			map[string]string{"Authorization": "Bearer unknown"}), http.StatusUnauthorized},
+		{"Read by reader", request(http.MethodGet, "/maple2021/v1/get-sth",
+			map[string]string{"Authorization": "Bearer read"}), 0},
+		{"Read by submitter", request(http.MethodGet, "/maple2021/v1/get-sth",
+			map[string]string{"Authorization": "Bearer submit"}), http.StatusForbidden},
+		{"Read by auditor", request(http.MethodGet, "/maple2021/v1/get-sth",
+			map[string]string{"X-Scopes": "openid vct:audit"}), 0},
+		{"Submit by submitter", request(http.MethodPost, "/maple2021/v1/add-vc",
+			map[string]string{"Authorization": "Bearer submit"}), 0},
+		{"Submit by submitter scope", request(http.MethodPost, "/maple2021/v1/add-entry",
+			map[string]string{"X-Scopes": "vct:submit"}), 0},
+		{"Submit by reader", request(http.MethodPost, "/maple2021/v1/add-revocation",
+			map[string]string{"Authorization": "Bearer read"}), http.StatusForbidden},
+		{"Submit by auditor", request(http.MethodPost, "/maple2021/v1/add-anchor",
+			map[string]string{"X-Scopes": "vct:audit"}), http.StatusForbidden},
+		{"Annotation by auditor", request(http.MethodPost, "/maple2021/v1/add-annotation",
+			map[string]string{"X-Scopes": "vct:audit"}), 0},
+		{"Annotation by submitter", request(http.MethodPost, "/maple2021/v1/add-annotation",
+			map[string]string{"Authorization": "Bearer submit"}), http.StatusForbidden},
+		{"Admin settings read by auditor", request(http.MethodGet, "/admin/read-only",
+			map[string]string{"X-Scopes": "vct:audit"}), 0},
+		{"Admin settings changed by auditor", request(http.MethodPost, "/admin/read-only",
+			map[string]string{"X-Scopes": "vct:audit"}), http.StatusForbidden},
+		{"Admin settings changed by admin", withIdentity(request(http.MethodPost, "/admin/read-only", nil),
+			"operator", true), 0},
+		{"Admin with unverified certificate", withIdentity(request(http.MethodPost, "/admin/read-only", nil),
+			"operator", false), http.StatusUnauthorized},
+		{"Submit by admin", withIdentity(request(http.MethodPost, "/maple2021/v1/add-vc", nil),
+			"operator", true), 0},
+		{"Proof debug by reader", request(http.MethodGet, "/maple2021/v1/get-proof-by-hash?tree_size=1&debug=true",
+			map[string]string{"Authorization": "Bearer read"}), http.StatusForbidden},
+		{"Proof debug by auditor", request(http.MethodGet, "/maple2021/v1/get-sth-consistency?debug=true",
+			map[string]string{"X-Scopes": "vct:audit"}), http.StatusForbidden},
+		{"Proof debug by admin", withIdentity(request(http.MethodGet,
+			"/maple2021/v1/get-entry-and-proof?debug=true", nil), "operator", true), 0},
+		{"Metrics read by reader", request(http.MethodGet, "/metrics",
+			map[string]string{"Authorization": "Bearer read"}), http.StatusForbidden},
+	} {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.status, authorizer.Authorize(tc.r))
+		})
+	}
+}
+
+func TestRequiredRoles(t *testing.T) {
+	// the query of the request never makes an endpoint public
+	for _, uri := range []string{
+		"/admin/freeze?x=/final-sth",
+		"/admin/read-only?x=/verification-snapshot",
+		"/admin/freeze?x=/.well-known/webfinger",
+		"/admin/log-state?x=/retired-shards",
+	} {
+		require.Equal(t, []Role{RoleAdmin}, RequiredRoles(httptest.NewRequest(http.MethodPost, uri, nil)), uri)
+		require.Equal(t, []Role{RoleAdmin}, RequiredRoles(request(http.MethodPost, uri, nil)), uri)
+	}
+
+	for _, uri := range []string{
+		"/maple2021/v1/add-vc?x=/policy",
+		"/maple2021/v1/add-entry?x=/.well-known/webfinger",
+		"/maple2021/add-revocation?x=/final-sth",
+	} {
+		require.Equal(t, []Role{RoleSubmitter, RoleAdmin}, RequiredRoles(httptest.NewRequest(http.MethodPost, uri, nil)),
+			uri)
+	}
+
+	require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/v1/get-sth?x=/add-vc", nil)))
+	require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/v1/.well-known/webfinger", nil)))
+	require.Nil(t, RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/.well-known/webfinger?x=1", nil)))
+	require.Nil(t, RequiredRoles(httptest.NewRequest(http.MethodGet, "/healthcheck?x=1", nil)))
+
+	require.Nil(t, RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/verification-snapshot?x=1", nil)))
+	require.Equal(t, []Role{RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodPost, "/admin/maple2021/verification-snapshot", nil)))
+	require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/v1/get-sth/verification-snapshot", nil)))
+
+	require.Nil(t, RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/final-sth?x=1", nil)))
+	require.Equal(t, []Role{RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodPost, "/admin/freeze/final-sth", nil)))
+
+	for _, uri := range []string{"/maple2021/policy", "/maple2021/policy/history", "/maple2021/policy/12?x=1"} {
+		require.Nil(t, RequiredRoles(httptest.NewRequest(http.MethodGet, uri, nil)), uri)
+	}
+
+	for _, uri := range []string{
+		"/maple2021/v1/get-sth/policy", "/maple2021/policy/v1", "/maple2021/policy/", "/policy", "/maple2021/policy-x",
+	} {
+		require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin},
+			RequiredRoles(httptest.NewRequest(http.MethodGet, uri, nil)), uri)
+	}
+
+	require.Equal(t, []Role{RoleAdmin}, RequiredRoles(httptest.NewRequest(http.MethodPost, "/admin/policy", nil)))
+
+	// the changes of the JSON-LD contexts require the admin role, the contexts are read by the readers
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/maple2021/v1/ld/context", nil),
+		httptest.NewRequest(http.MethodPost, "/maple2021/v1/ld/remote-provider", nil),
+		httptest.NewRequest(http.MethodDelete, "/maple2021/v1/ld/remote-provider/1", nil),
+		httptest.NewRequest(http.MethodPost, "/maple2021/v1/ld/remote-provider/1/refresh", nil),
+		httptest.NewRequest(http.MethodPost, "/maple2021/v1/ld/remote-providers/refresh", nil),
+	} {
+		require.Equal(t, []Role{RoleAdmin}, RequiredRoles(req), req.Method+" "+req.URL.Path)
+	}
+
+	require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodGet, "/maple2021/v1/ld/remote-providers", nil)))
+
+	// the other requests than reads to an unclassified endpoint fail closed
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch} {
+		require.Equal(t, []Role{RoleAdmin},
+			RequiredRoles(httptest.NewRequest(method, "/maple2021/v1/get-sth", nil)), method)
+	}
+
+	require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin},
+		RequiredRoles(httptest.NewRequest(http.MethodHead, "/maple2021/v1/get-sth", nil)))
+}
+
+func TestAuthorizer_OpenRoles(t *testing.T) {
+	bindings := []Binding{{Role: RoleSubmitter, Kind: KindToken, Value: "submit"}}
+
+	authorizer := New(bindings)
+
+	require.Empty(t, authorizer.OpenRoles())
+	require.Equal(t, []Role{RoleReader, RoleAuditor, RoleAdmin}, authorizer.UnboundRoles())
+
+	// the endpoints intended for the roles bound to nobody are forbidden
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodGet, "/maple2021/v1/get-sth", nil)))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodPost, "/admin/freeze", nil)))
+	require.Equal(t, http.StatusUnauthorized, authorizer.Authorize(request(http.MethodPost, "/maple2021/v1/add-vc",
+		nil)))
+
+	authorizer = New(bindings, WithOpenRoles())
+
+	require.Equal(t, []Role{RoleReader}, authorizer.OpenRoles())
+
+	// the endpoints intended for the reader role are open, the admin and auditor endpoints are not
+	require.Equal(t, 0, authorizer.Authorize(request(http.MethodGet, "/maple2021/v1/get-sth", nil)))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodGet, "/metrics", nil)))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodPost, "/admin/freeze",
+		map[string]string{"Authorization": "Bearer submit"})))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodPost,
+		"/maple2021/v1/add-annotation", nil)))
+}
+
+func TestLegacyBindings(t *testing.T) {
+	require.Empty(t, LegacyBindings("", ""))
+
+	authorizer := New(LegacyBindings("read", "write"))
+
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodPost, "/maple2021/v1/add-vc",
+		map[string]string{"Authorization": "Bearer read"})))
+	require.Equal(t, 0, authorizer.Authorize(request(http.MethodPost, "/maple2021/v1/add-vc",
+		map[string]string{"Authorization": "Bearer write"})))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodGet, "/admin/read-only",
+		map[string]string{"Authorization": "Bearer read"})))
+
+	// writes are open without the write token, the admin endpoints are not
+	authorizer = New(LegacyBindings("read", ""), WithOpenRoles())
+
+	require.Equal(t, 0, authorizer.Authorize(request(http.MethodPost, "/maple2021/v1/add-vc", nil)))
+	require.Equal(t, http.StatusUnauthorized, authorizer.Authorize(request(http.MethodGet, "/maple2021/v1/get-sth",
+		nil)))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodPost, "/admin/freeze", nil)))
+	require.Equal(t, http.StatusForbidden, authorizer.Authorize(request(http.MethodPost, "/admin/freeze",
+		map[string]string{"Authorization": "Bearer read"})))
+
+	// reads are open without the read token
+	authorizer = New(LegacyBindings("", "write"), WithOpenRoles())
+
+	require.Equal(t, 0, authorizer.Authorize(request(http.MethodGet, "/maple2021/v1/get-sth", nil)))
+	require.Equal(t, http.StatusUnauthorized, authorizer.Authorize(request(http.MethodGet, "/admin/read-only", nil)))
+}
+
+func TestHasMTLSBindings(t *testing.T) {
+	require.False(t, HasMTLSBindings([]Binding{{Role: RoleAdmin, Kind: KindToken, Value: "admin"}}))
+	require.True(t, HasMTLSBindings([]Binding{{Role: RoleAdmin, Kind: KindMTLS, Value: "operator"}}))
+}
+
+func TestAuthorizer_Middleware(t *testing.T) {
+	handler := New([]Binding{
+		{Role: RoleReader, Kind: KindToken, Value: "read"},
+		{Role: RoleSubmitter, Kind: KindToken, Value: "submit"},
+		{Role: RoleAuditor, Kind: KindToken, Value: "audit"},
+		{Role: RoleAdmin, Kind: KindToken, Value: "admin"},
+	}).Middleware()(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, request(http.MethodGet, "/maple2021/v1/get-sth", map[string]string{
+		"Authorization": "Bearer read",
+	}))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, request(http.MethodGet, "/maple2021/v1/get-sth", nil))
+	require.Equal(t, http.StatusUnauthorized, rw.Code)
+	require.Equal(t, "Unauthorised.\n", rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, request(http.MethodPost, "/admin/read-only", map[string]string{
+		"Authorization": "Bearer audit",
+	}))
+	require.Equal(t, http.StatusForbidden, rw.Code)
+	require.Equal(t, "Forbidden.\n", rw.Body.String())
+}
@@ -0,0 +1,170 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// defaultMaxClockSkew is how far in the future the timestamp of an anchored tree head may be by default.
+const defaultMaxClockSkew = 5 * time.Minute
+
+// CreateAnchorLeaf creates a leaf for the anchored tree head.
+func CreateAnchorLeaf(timestamp uint64, anchor *STHAnchor) (*MerkleTreeLeaf, error) {
+	entry, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, fmt.Errorf("marshal anchor: %w", err)
+	}
+
+	return createEntryLeaf(timestamp, STHAnchorLogEntryType, entry), nil
+}
+
+// AddAnchor adds a signed tree head of another transparency log to log.
+func (c *Cmd) AddAnchor(w io.Writer, r io.Reader) error {
+	var req *AddAnchorRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode AddAnchor request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty AddAnchor request", errors.ErrBadRequest)
+	}
+
+	entry, err := json.Marshal(req.Anchor)
+	if err != nil {
+		return fmt.Errorf("marshal anchor: %w", err)
+	}
+
+	resp, err := c.addEntry(req.Alias, STHAnchorLogEntryType, entry)
+	if err != nil {
+		return err
+	}
+
+	resp.WitnessCosignature, err = c.cosign(&req.Anchor)
+	if err != nil {
+		return fmt.Errorf("cosign tree head: %w", err)
+	}
+
+	return json.NewEncoder(w).Encode(resp) // nolint: wrapcheck
+}
+
+// checkAnchor checks that the tree head is signed by the anchored log and extends the tree head
+// anchored before: it must be newer, must not shrink the tree and must be consistent with it.
+// A tree head dated in the future is rejected, once anchored it would make every genuine tree head stale.
+func (c *Cmd) checkAnchor(alias string, anchor *STHAnchor) error {
+	sth := anchor.STH
+
+	err := VerifySignature(sth.TreeHeadSignature, anchor.PublicKey, TreeHeadSignature{
+		Version:        V1,
+		SignatureType:  TreeHeadSignatureType,
+		Timestamp:      sth.Timestamp,
+		TreeSize:       sth.TreeSize,
+		SHA256RootHash: sth.SHA256RootHash,
+	})
+	if err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("tree head signature: %w", err))
+	}
+
+	now := uint64(time.Now().UnixNano()) / uint64(time.Millisecond)
+
+	// the skew is observed in float, the timestamp of the tree head is not trusted yet and may overflow a duration
+	anchorClockSkew.Observe((float64(sth.Timestamp)-float64(now))/float64(time.Second/time.Millisecond), alias)
+
+	if sth.Timestamp > now+uint64(c.maxClockSkew/time.Millisecond) {
+		return errors.NewBadRequestError(fmt.Errorf("tree head of log %s is dated in the future, max skew is %s",
+			base64.StdEncoding.EncodeToString(anchor.LogID), c.maxClockSkew))
+	}
+
+	index, err := c.lockIndex(alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	anchors := index.anchors[string(anchor.LogID)]
+	if len(anchors) == 0 {
+		return nil
+	}
+
+	prev := anchors[len(anchors)-1].Anchor.STH
+
+	switch {
+	case sth.Timestamp <= prev.Timestamp:
+		return errors.NewBadRequestError(fmt.Errorf("tree head of log %s is not newer than the anchored one",
+			base64.StdEncoding.EncodeToString(anchor.LogID)))
+	case sth.TreeSize < prev.TreeSize:
+		return errors.NewBadRequestError(fmt.Errorf("tree size %d of log %s is smaller than anchored %d",
+			sth.TreeSize, base64.StdEncoding.EncodeToString(anchor.LogID), prev.TreeSize))
+	case sth.TreeSize == prev.TreeSize && !bytes.Equal(sth.SHA256RootHash, prev.SHA256RootHash):
+		return errors.NewBadRequestError(fmt.Errorf("root hash of log %s does not match the anchored one",
+			base64.StdEncoding.EncodeToString(anchor.LogID)))
+	case sth.TreeSize == prev.TreeSize || prev.TreeSize == 0:
+		return nil
+	}
+
+	err = logverifier.New(hasher.DefaultHasher).VerifyConsistencyProof(int64(prev.TreeSize), int64(sth.TreeSize),
+		prev.SHA256RootHash, sth.SHA256RootHash, anchor.Consistency)
+	if err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("verify consistency: %w", err))
+	}
+
+	return nil
+}
+
+// GetAnchors retrieves tree heads of the log anchored in the order they were logged.
+func (c *Cmd) GetAnchors(w io.Writer, r io.Reader) error {
+	var request *GetAnchorsRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetAnchors request: %w", err)
+	}
+
+	if request == nil {
+		return fmt.Errorf("%w: empty GetAnchors request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	index, err := c.lockIndex(request.Alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	anchors := index.anchors[string(request.LogID)]
+	if anchors == nil {
+		return errors.NewNotFoundError(fmt.Errorf("log %s is not anchored",
+			base64.StdEncoding.EncodeToString(request.LogID)))
+	}
+
+	begin, end, next, err := paginate(GetAnchors+"/"+request.Alias+"/"+string(request.LogID), request.PageRequest,
+		len(anchors))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(GetAnchorsResponse{ // nolint: wrapcheck
+		LogID:         request.LogID,
+		Anchors:       anchors[begin:end],
+		NextPageToken: next,
+	})
+}
@@ -0,0 +1,236 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// AnnotationStore persists the annotations of the entries (e.g. a store of the storage provider), they are not
+// logged in the tree.
+type AnnotationStore interface {
+	Put(key string, value []byte, tags ...storage.Tag) error
+	Get(key string) ([]byte, error)
+	GetBulk(keys ...string) ([][]byte, error)
+}
+
+// annotationKey returns the key of the annotations of the entry of the log.
+func annotationKey(alias string, leafIndex int64) string {
+	return alias + "/" + strconv.FormatInt(leafIndex, 10)
+}
+
+// verifyAnnotation verifies that the annotation refers to an entry of the log and is signed by a registered
+// auditor.
+func (c *Cmd) verifyAnnotation(alias string, signed *SignedAnnotation) error {
+	annotation := signed.Annotation
+
+	if annotation.Version != V1 || annotation.SignatureType != AnnotationSignatureType {
+		return errors.NewBadRequestError(fmt.Errorf("annotation must be a v1 annotation"))
+	}
+
+	if !bytes.Equal(annotation.LogID, c.VCLogID[:]) {
+		return errors.NewBadRequestError(fmt.Errorf("annotation is issued for another log"))
+	}
+
+	if annotation.Kind == "" {
+		return fmt.Errorf("%w: annotation kind is empty", errors.ErrValidation)
+	}
+
+	pubKey, ok := c.auditors[annotation.Auditor]
+	if !ok {
+		return errors.NewBadRequestError(fmt.Errorf("auditor %q is not registered", annotation.Auditor))
+	}
+
+	if err := VerifySignature(signed.Signature, pubKey, annotation); err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("annotation signature: %w", err))
+	}
+
+	treeSize, err := c.treeSize(alias)
+	if err != nil {
+		return fmt.Errorf("tree size: %w", err)
+	}
+
+	if annotation.LeafIndex < 0 || annotation.LeafIndex >= treeSize {
+		return errors.NewNotFoundError(fmt.Errorf("leaf index %d is not in the tree of size %d",
+			annotation.LeafIndex, treeSize))
+	}
+
+	return nil
+}
+
+// AddAnnotation attaches the annotation of a registered auditor to an entry of the log, e.g. that the credential
+// was reported fraudulent. The annotation is stored outside the tree, it is served with the entry and posted to
+// the annotation subscribers. An annotation added twice is stored once.
+func (c *Cmd) AddAnnotation(w io.Writer, r io.Reader) error {
+	var req *AddAnnotationRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode AddAnnotation request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty AddAnnotation request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(req.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	if c.annotations == nil || len(c.auditors) == 0 {
+		return errors.NewBadRequestError(fmt.Errorf("no auditors are registered"))
+	}
+
+	if c.isReadOnly() {
+		return fmt.Errorf("%w: the service is in maintenance, writes are rejected, retry later", errors.ErrReadOnly)
+	}
+
+	if err := c.verifyAnnotation(req.Alias, &req.Annotation); err != nil {
+		return err
+	}
+
+	added, err := c.storeAnnotation(req.Alias, req.Annotation)
+	if err != nil {
+		return err
+	}
+
+	if added {
+		event := AnnotationEvent{Alias: req.Alias, Annotation: req.Annotation}
+
+		for _, subscriber := range c.annotationSubscribers {
+			c.postCallback(c.hooks.subscribers, "annotation", subscriber, event)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(req.Annotation) // nolint: wrapcheck
+}
+
+// storeAnnotation appends the annotation to the annotations of its entry, it returns false if the annotation
+// was already stored.
+func (c *Cmd) storeAnnotation(alias string, signed SignedAnnotation) (bool, error) {
+	key := annotationKey(alias, signed.Annotation.LeafIndex)
+
+	c.annotationsMu.Lock()
+	defer c.annotationsMu.Unlock()
+
+	annotations, err := c.getAnnotations(key)
+	if err != nil {
+		return false, err
+	}
+
+	for _, annotation := range annotations {
+		if reflect.DeepEqual(annotation.Annotation, signed.Annotation) {
+			return false, nil
+		}
+	}
+
+	src, err := json.Marshal(append(annotations, signed))
+	if err != nil {
+		return false, fmt.Errorf("marshal annotations: %w", err)
+	}
+
+	if err = c.annotations.Put(key, src); err != nil {
+		return false, fmt.Errorf("store annotations: %w", err)
+	}
+
+	logger.Infof("auditor %s annotated entry %d of log %s as %s", signed.Annotation.Auditor,
+		signed.Annotation.LeafIndex, alias, signed.Annotation.Kind)
+
+	return true, nil
+}
+
+func (c *Cmd) getAnnotations(key string) ([]SignedAnnotation, error) {
+	src, err := c.annotations.Get(key)
+	if goerrors.Is(err, storage.ErrDataNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get annotations: %w", err)
+	}
+
+	var annotations []SignedAnnotation
+	if err = json.Unmarshal(src, &annotations); err != nil {
+		return nil, errors.NewStatusInternalServerError(fmt.Errorf("unmarshal annotations: %w", err))
+	}
+
+	return annotations, nil
+}
+
+// entryAnnotations returns the annotations of the entry, none if the annotations are not persisted.
+func (c *Cmd) entryAnnotations(alias string, leafIndex int64) ([]SignedAnnotation, error) {
+	if c.annotations == nil {
+		return nil, nil
+	}
+
+	return c.getAnnotations(annotationKey(alias, leafIndex))
+}
+
+// annotateEntries sets the annotations of the entries starting at the leaf index.
+func (c *Cmd) annotateEntries(alias string, start int64, entries []LeafEntry) error {
+	if c.annotations == nil || len(entries) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(entries))
+	for i := range entries {
+		keys[i] = annotationKey(alias, start+int64(i))
+	}
+
+	values, err := c.annotations.GetBulk(keys...)
+	if err != nil {
+		return fmt.Errorf("get annotations: %w", err)
+	}
+
+	for i, src := range values {
+		if src == nil {
+			continue
+		}
+
+		if err = json.Unmarshal(src, &entries[i].Annotations); err != nil {
+			return errors.NewStatusInternalServerError(fmt.Errorf("unmarshal annotations: %w", err))
+		}
+	}
+
+	return nil
+}
+
+// GetAnnotations retrieves the annotations of the entry in the order they were added.
+func (c *Cmd) GetAnnotations(w io.Writer, r io.Reader) error {
+	var request *GetAnnotationsRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("%w: decode GetAnnotations request: %v", errors.ErrBadRequest, err)
+	}
+
+	if request == nil {
+		return fmt.Errorf("%w: empty GetAnnotations request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	annotations, err := c.entryAnnotations(request.Alias, request.LeafIndex)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(GetAnnotationsResponse{ // nolint: wrapcheck
+		LeafIndex:   request.LeafIndex,
+		Annotations: append([]SignedAnnotation{}, annotations...),
+	})
+}
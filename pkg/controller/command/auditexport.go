@@ -0,0 +1,204 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/trillian/types"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// MaxAuditExportRange is the max number of entries of an audit export.
+const MaxAuditExportRange = 1000
+
+// GetAuditExport retrieves the signed audit export of a tree-size range of the log: the entries with their
+// audit paths, the chain of consistent tree heads up to the signed tree head and a signature over all of it.
+// The export is always read from the primary, so all proofs refer to the same tree.
+func (c *Cmd) GetAuditExport(w io.Writer, r io.Reader) error {
+	var request *GetAuditExportRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetAuditExport request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetAuditExport request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	export, err := c.auditExport(request)
+	if err != nil {
+		return err
+	}
+
+	signature, err := c.sign(export)
+	if err != nil {
+		return fmt.Errorf("sign AuditExport: %w", err)
+	}
+
+	return json.NewEncoder(w).Encode(GetAuditExportResponse{ // nolint: wrapcheck
+		Export:    *export,
+		Signature: signature,
+	})
+}
+
+func (c *Cmd) auditExport(request *GetAuditExportRequest) (*AuditExport, error) {
+	log := c.logs[request.Alias]
+
+	root, err := primaryLogRoot(log)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.TreeSize < uint64(request.SecondTreeSize) {
+		return nil, fmt.Errorf("%w: need tree size: %d for export but only got: %d",
+			errors.ErrBadRequest, request.SecondTreeSize, root.TreeSize,
+		)
+	}
+
+	entries := make([]AuditEntry, 0, request.SecondTreeSize-request.FirstTreeSize)
+
+	for index := request.FirstTreeSize; index < request.SecondTreeSize; index++ {
+		entry, er := c.auditEntry(log, index, request.SecondTreeSize)
+		if er != nil {
+			return nil, er
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	secondRoot, err := auditRoot(&entries[len(entries)-1], request.SecondTreeSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var heads []AuditTreeHead
+
+	if request.FirstTreeSize > 0 {
+		head, er := c.auditEntry(log, request.FirstTreeSize-1, request.FirstTreeSize)
+		if er != nil {
+			return nil, er
+		}
+
+		firstRoot, er := auditRoot(head, request.FirstTreeSize)
+		if er != nil {
+			return nil, er
+		}
+
+		heads = append(heads, AuditTreeHead{TreeSize: request.FirstTreeSize, SHA256RootHash: firstRoot})
+	}
+
+	heads = append(heads, AuditTreeHead{TreeSize: request.SecondTreeSize, SHA256RootHash: secondRoot})
+
+	if root.TreeSize > uint64(request.SecondTreeSize) {
+		heads = append(heads, AuditTreeHead{TreeSize: int64(root.TreeSize), SHA256RootHash: root.RootHash})
+	}
+
+	for i := 1; i < len(heads); i++ {
+		heads[i].Consistency, err = c.auditConsistency(log, heads[i-1].TreeSize, heads[i].TreeSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sth, err := c.signedTreeHead(*root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditExport{
+		Version:        V1,
+		SignatureType:  AuditExportSignatureType,
+		Timestamp:      uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		Alias:          request.Alias,
+		LogID:          c.VCLogID[:],
+		FirstTreeSize:  request.FirstTreeSize,
+		SecondTreeSize: request.SecondTreeSize,
+		Entries:        entries,
+		TreeHeads:      heads,
+		STH:            *sth,
+	}, nil
+}
+
+// primaryLogRoot returns the latest log root of the log read from the primary.
+func primaryLogRoot(log Log) (*types.LogRootV1, error) {
+	resp, err := log.Client.GetLatestSignedLogRoot(context.Background(),
+		&trillian.GetLatestSignedLogRootRequest{LogId: log.ID})
+	if err != nil {
+		return nil, fmt.Errorf("get latest signed log root: %w", err)
+	}
+
+	var root types.LogRootV1
+	if err = root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, err)
+	}
+
+	return &root, nil
+}
+
+func (c *Cmd) auditEntry(log Log, index, treeSize int64) (*AuditEntry, error) {
+	resp, err := log.Client.GetEntryAndProof(context.Background(), &trillian.GetEntryAndProofRequest{
+		LogId:     log.ID,
+		LeafIndex: index,
+		TreeSize:  treeSize,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get entry and proof: %w", err)
+	}
+
+	if resp.GetLeaf() == nil || len(resp.GetLeaf().GetLeafValue()) == 0 || resp.GetProof() == nil {
+		return nil, fmt.Errorf("%w: corrupted data received for leaf %d", errors.ErrInternal, index)
+	}
+
+	extraData, err := c.decryptExtraData(resp.GetLeaf().GetExtraData(), resp.GetLeaf().GetLeafValue())
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditEntry{
+		LeafIndex: index,
+		LeafInput: resp.GetLeaf().GetLeafValue(),
+		ExtraData: extraData,
+		AuditPath: resp.GetProof().GetHashes(),
+	}, nil
+}
+
+func (c *Cmd) auditConsistency(log Log, first, second int64) ([][]byte, error) {
+	resp, err := log.Client.GetConsistencyProof(context.Background(), &trillian.GetConsistencyProofRequest{
+		LogId:          log.ID,
+		FirstTreeSize:  first,
+		SecondTreeSize: second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get consistency proof: %w", err)
+	}
+
+	return resp.GetProof().GetHashes(), nil
+}
+
+// auditRoot calculates the root hash of the tree of the size from the audit path of its last leaf.
+func auditRoot(last *AuditEntry, treeSize int64) ([]byte, error) {
+	root, err := logverifier.New(hasher.DefaultHasher).RootFromInclusionProof(last.LeafIndex, treeSize,
+		last.AuditPath, hasher.DefaultHasher.HashLeaf(last.LeafInput))
+	if err != nil {
+		return nil, fmt.Errorf("%w: root from inclusion proof: %v", errors.ErrInternal, err)
+	}
+
+	return root, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ldprocessor "github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	jsonld "github.com/piprate/json-gold/ld"
+)
+
+// BBS+ proof types.
+const (
+	// BBSSignatureType is the type of the proof of a BBS+ credential signed by its issuer (the base form).
+	BBSSignatureType = "BbsBlsSignature2020"
+	// BBSSignatureProofType is the type of the zero-knowledge proof of a credential derived by its holder
+	// from a BBS+ credential, revealing a subset of its claims.
+	BBSSignatureProofType = "BbsBlsSignatureProof2020"
+
+	// blankNodePlaceholder is the prefix of a blank node identifier replaced in a derived credential,
+	// e.g. <urn:bnid:_:c14n0> for _:c14n0.
+	blankNodePlaceholder = "<urn:bnid:"
+)
+
+// IsDerivedCredential returns true if the credential has a BBS+ proof derived by its holder.
+// A derived credential reveals a subset of the claims of the base credential, it differs between presentations
+// and is not logged: the base credential is logged instead and derived credentials are linked to it
+// by VerifyDerivation.
+func IsDerivedCredential(vc *verifiable.Credential) bool {
+	for _, proof := range vc.Proofs {
+		if proof["type"] == BBSSignatureProofType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VerifyDerivation verifies that the derived credential reveals only statements of the logged base credential,
+// i.e. that it could have been derived from it. The statements are the canonical (URDNA2015) N-Quads the
+// BBS+ signature of the base credential signs. The BBS+ proof of the derived credential is not verified.
+func VerifyDerivation(derived *verifiable.Credential, base []byte, loader jsonld.DocumentLoader) error {
+	var baseDoc map[string]interface{}
+	if err := json.Unmarshal(base, &baseDoc); err != nil {
+		return fmt.Errorf("unmarshal base credential: %w", err)
+	}
+
+	delete(baseDoc, "proof")
+
+	baseStatements, err := canonicalStatements(baseDoc, loader)
+	if err != nil {
+		return fmt.Errorf("base credential: %w", err)
+	}
+
+	proofs := derived.Proofs
+	derived.Proofs = nil
+
+	defer func() { derived.Proofs = proofs }()
+
+	raw, err := json.Marshal(derived)
+	if err != nil {
+		return fmt.Errorf("marshal derived credential: %w", err)
+	}
+
+	var derivedDoc map[string]interface{}
+	if err = json.Unmarshal(raw, &derivedDoc); err != nil {
+		return fmt.Errorf("unmarshal derived credential: %w", err)
+	}
+
+	derivedStatements, err := canonicalStatements(derivedDoc, loader)
+	if err != nil {
+		return fmt.Errorf("derived credential: %w", err)
+	}
+
+	signed := map[string]bool{}
+	for _, statement := range baseStatements {
+		signed[statement] = true
+	}
+
+	for i, statement := range derivedStatements {
+		if !signed[restoreBlankNodes(statement)] {
+			return fmt.Errorf("statement %d of the derived credential is not a statement of the base credential", i)
+		}
+	}
+
+	return nil
+}
+
+func canonicalStatements(doc map[string]interface{}, loader jsonld.DocumentLoader) ([]string, error) {
+	canonical, err := ldprocessor.Default().GetCanonicalDocument(doc, ldprocessor.WithDocumentLoader(loader))
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize: %w", err)
+	}
+
+	var statements []string
+
+	for _, row := range strings.Split(string(canonical), "\n") {
+		if strings.TrimSpace(row) != "" {
+			statements = append(statements, row)
+		}
+	}
+
+	return statements, nil
+}
+
+// restoreBlankNodes restores the blank node identifiers of the base credential replaced in a derived credential,
+// e.g. <urn:bnid:_:c14n0> is restored to _:c14n0.
+func restoreBlankNodes(statement string) string {
+	for {
+		start := strings.Index(statement, blankNodePlaceholder)
+		if start < 0 {
+			return statement
+		}
+
+		end := strings.Index(statement[start:], ">")
+		if end < 0 {
+			return statement
+		}
+
+		end += start
+
+		statement = statement[:start] + statement[start+len(blankNodePlaceholder):end] + statement[end+1:]
+	}
+}
@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// maxCallbacksInFlight is the max number of the callbacks posted at the same time, the callbacks beyond it are
+	// dropped.
+	maxCallbacksInFlight = 100
+	// callbackTimeout bounds the time a callback takes to be posted.
+	callbackTimeout = 10 * time.Second
+)
+
+// callbacks holds the clients posting the callbacks of the submissions and the annotation events.
+type callbacks struct {
+	// hosts the callbacks of the submissions may be posted to (host or host:port).
+	hosts []string
+	// client posts the callbacks of the submissions, it does not connect to the private addresses unless allowed.
+	client HTTPClient
+	// subscribers posts the annotation events to the subscribers set by the operator.
+	subscribers HTTPClient
+	// slots bound the callbacks in flight.
+	slots chan struct{}
+}
+
+func newCallbacks(cfg *Config) *callbacks {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: callbackTimeout}
+	}
+
+	hosts := make([]string, 0, len(cfg.CallbackHosts))
+	for _, host := range cfg.CallbackHosts {
+		hosts = append(hosts, strings.ToLower(host))
+	}
+
+	return &callbacks{
+		hosts:       hosts,
+		client:      guardedClient(httpClient, cfg.CallbackAllowPrivate),
+		subscribers: httpClient,
+		slots:       make(chan struct{}, maxCallbacksInFlight),
+	}
+}
+
+// checkURL returns a validation error unless the host of the callback URL of a submission is allowed.
+func (c *callbacks) checkURL(callbackURL string) error {
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("%w: callbackURL %q must be an absolute http(s) URL", errors.ErrValidation, callbackURL)
+	}
+
+	host := strings.ToLower(u.Host)
+
+	for _, allowed := range c.hosts {
+		if allowed == host || allowed == strings.ToLower(u.Hostname()) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: callbackURL host %q is not allowed", errors.ErrValidation, u.Host)
+}
+
+// postCallback posts the payload (e.g. the add-vc response) to the callback URL in the background, the callback is
+// dropped if too many are in flight.
+func (c *Cmd) postCallback(client HTTPClient, kind, callbackURL string, v interface{}) {
+	select {
+	case c.hooks.slots <- struct{}{}:
+	default:
+		callbacksDropped.Add(1, kind)
+
+		logger.Warnf("callback %s is dropped, %d callbacks are in flight", callbackURL, maxCallbacksInFlight)
+
+		return
+	}
+
+	go func() {
+		defer func() { <-c.hooks.slots }()
+
+		c.notifyCallback(client, callbackURL, v)
+	}()
+}
+
+// guardedClient returns the client connecting to the public addresses only, unless private addresses are allowed.
+// The addresses are checked when connecting, so a host resolving to a private address is rejected whatever it
+// resolved to before. A client other than an *http.Client with an *http.Transport is used as is.
+func guardedClient(client HTTPClient, allowPrivate bool) HTTPClient {
+	if allowPrivate {
+		return client
+	}
+
+	httpClient, ok := client.(*http.Client)
+	if !ok {
+		return client
+	}
+
+	var transport *http.Transport
+
+	switch t := httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone() // nolint: forcetypeassert
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return client
+	}
+
+	dialer := &net.Dialer{Timeout: callbackTimeout, Control: dialPublic}
+
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	guarded := *httpClient
+	guarded.Transport = transport
+
+	if guarded.Timeout == 0 || guarded.Timeout > callbackTimeout {
+		guarded.Timeout = callbackTimeout
+	}
+
+	return &guarded
+}
+
+// dialPublic rejects the connections to the loopback, private, link-local, multicast and unspecified addresses.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("split host port: %w", err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("callback address %s is not public", host)
+	}
+
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// notifyCallback posts the payload to the callback URL with the client.
+func (c *Cmd) notifyCallback(client HTTPClient, callbackURL string, v interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		logger.Errorf("marshal callback payload: %v", err)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), callbackTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewBuffer(payload))
+	if err != nil {
+		logger.Errorf("new callback request: %v", err)
+
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if err = c.SignWebhook(req, payload); err != nil {
+		logger.Errorf("callback %s: %v", callbackURL, err)
+
+		return
+	}
+
+	cResp, err := client.Do(req)
+	if err != nil {
+		logger.Errorf("notify callback %s: %v", callbackURL, err)
+
+		return
+	}
+
+	cResp.Body.Close() // nolint: errcheck,gosec
+
+	if cResp.StatusCode/100 != 2 { // nolint: gomnd
+		logger.Warnf("callback %s responded with status %d", callbackURL, cResp.StatusCode)
+	}
+}
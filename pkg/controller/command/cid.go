@@ -0,0 +1,136 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	cidVersion1 = 1
+	// rawCodec is the multicodec of raw binary data, the entries are addressed as they are logged.
+	rawCodec = 0x55
+)
+
+// cidPrefix is the prefix of the CIDs of the entries, the version and the codec (both one byte varints).
+var cidPrefix = []byte{cidVersion1, rawCodec} // nolint: gochecknoglobals
+
+// EntryCID returns the CID of the logged form of an entry (TimestampedEntry.VCEntry): a CIDv1 of raw data with the
+// SHA2-256 multihash, encoded in base32 (bafkrei...). The entry can be pinned to IPFS under it as is.
+func EntryCID(entry []byte) string {
+	digest, _ := multihash.Sum(entry, multihash.SHA2_256, -1) // nolint: errcheck
+
+	cid := append(append([]byte(nil), cidPrefix...), digest...)
+
+	encoded, _ := multibase.Encode(multibase.Base32, cid) // nolint: errcheck
+
+	return encoded
+}
+
+// ParseEntryCID returns the SHA2-256 digest of the entry addressed by the CID, the CID is a CIDv1 of raw data
+// with the SHA2-256 multihash in any multibase encoding.
+func ParseEntryCID(cid string) ([]byte, error) {
+	_, data, err := multibase.Decode(cid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode CID: %v", errors.ErrValidation, err)
+	}
+
+	version, n := binary.Uvarint(data)
+	if n <= 0 || version != cidVersion1 {
+		return nil, fmt.Errorf("%w: CID must be a CIDv1", errors.ErrValidation)
+	}
+
+	codec, m := binary.Uvarint(data[n:])
+	if m <= 0 || codec != rawCodec {
+		return nil, fmt.Errorf("%w: CID must address raw data", errors.ErrValidation)
+	}
+
+	digest, err := multihash.Decode(data[n+m:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode multihash: %v", errors.ErrValidation, err)
+	}
+
+	if digest.Code != multihash.SHA2_256 {
+		return nil, fmt.Errorf("%w: CID must have a SHA2-256 multihash", errors.ErrValidation)
+	}
+
+	return digest.Digest, nil
+}
+
+// GetEntryByCID retrieves the first logged entry addressed by the CID (EntryCID).
+func (c *Cmd) GetEntryByCID(w io.Writer, r io.Reader) error {
+	var request *GetEntryByCIDRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("%w: decode GetEntryByCID request: %v", errors.ErrBadRequest, err)
+	}
+
+	if request == nil {
+		return fmt.Errorf("%w: empty GetEntryByCID request", errors.ErrBadRequest)
+	}
+
+	digest, err := ParseEntryCID(request.CID)
+	if err != nil {
+		return errors.NewBadRequestError(err)
+	}
+
+	if err = c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	leafIndex, ok, err := func() (int64, bool, error) {
+		index, er := c.lockIndex(request.Alias)
+		if er != nil {
+			return 0, false, fmt.Errorf("index credentials: %w", er)
+		}
+
+		defer index.mu.Unlock()
+
+		leafIndex, ok := index.entries[string(digest)]
+
+		return leafIndex, ok, nil
+	}()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.NewNotFoundError(fmt.Errorf("no entry with CID %s is logged", request.CID))
+	}
+
+	leaf, err := c.cachedLeaf(request.Alias, leafIndex)
+	if err != nil {
+		return err
+	}
+
+	var logged MerkleTreeLeaf
+	if err = json.Unmarshal(leaf.GetLeafValue(), &logged); err != nil || logged.TimestampedEntry == nil {
+		return fmt.Errorf("%w: leaf %d is not an entry", errors.ErrInternal, leafIndex)
+	}
+
+	extraData, err := c.decryptExtraData(leaf.GetExtraData(), leaf.GetLeafValue())
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(GetEntryByCIDResponse{ // nolint: wrapcheck
+		CID:            EntryCID(logged.TimestampedEntry.VCEntry),
+		LeafIndex:      leafIndex,
+		MerkleLeafHash: leaf.GetMerkleLeafHash(),
+		Entry:          logged.TimestampedEntry.VCEntry,
+		LeafInput:      leaf.GetLeafValue(),
+		ExtraData:      extraData,
+	})
+}
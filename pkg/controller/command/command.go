@@ -7,44 +7,121 @@ SPDX-License-Identifier: Apache-2.0
 package command
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"sync"
 	"time"
 
 	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
 	"github.com/google/trillian/monitoring"
 	"github.com/google/trillian/types"
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
 	jsonld "github.com/piprate/json-gold/ld"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 
+	"github.com/trustbloc/vct/internal/pkg/scrub"
 	"github.com/trustbloc/vct/pkg/controller/errors"
+	"github.com/trustbloc/vct/pkg/webhook"
 )
 
 // Command methods.
 const (
-	GetSTH            = "getSTH"
-	GetSTHConsistency = "getSTHConsistency"
-	GetEntries        = "getEntries"
-	GetProofByHash    = "getProofByHash"
-	GetEntryAndProof  = "getEntryAndProof"
-	GetIssuers        = "getIssuers"
-	Webfinger         = "webfinger"
-	AddVC             = "addVC"
+	GetSTH                = "getSTH"
+	GetSTHConsistency     = "getSTHConsistency"
+	GetEntries            = "getEntries"
+	GetProofByHash        = "getProofByHash"
+	GetEntryAndProof      = "getEntryAndProof"
+	GetIssuers            = "getIssuers"
+	Webfinger             = "webfinger"
+	AddVC                 = "addVC"
+	GetProofOfAbsence     = "getProofOfAbsence"
+	GetMapRoot            = "getMapRoot"
+	GetCredentialStatus   = "getCredentialStatus"
+	AddRevocation         = "addRevocation"
+	GetRevocations        = "getRevocations"
+	GetCredentialHistory  = "getCredentialHistory"
+	AddAnchor             = "addAnchor"
+	AddEntry              = "addEntry"
+	GetAnchors            = "getAnchors"
+	GetShadowStatus       = "getShadowStatus"
+	GetReadOnly           = "getReadOnly"
+	SetReadOnly           = "setReadOnly"
+	GetAuditExport        = "getAuditExport"
+	GetRandomEntries      = "getRandomEntries"
+	GetDiff               = "getDiff"
+	GetKeyUsage           = "getKeyUsage"
+	MarkCompromised       = "markCompromised"
+	GetReceipt            = "getReceipt"
+	GetTile               = "getTile"
+	GetEntryBundle        = "getEntryBundle"
+	GetUsage              = "getUsage"
+	GetSubmissionStats    = "getSubmissionStats"
+	GetPendingSubmissions = "getPendingSubmissions"
+	GetUnmerged           = "getUnmergedSubmissions"
+	AddAnnotation         = "addAnnotation"
+	GetAnnotations        = "getAnnotations"
+	GetSnapshot           = "getVerificationSnapshot"
+	FreezeLog             = "freezeLog"
+	GetFinalTreeHead      = "getFinalTreeHead"
+	GetTreeHeadSLA        = "getTreeHeadSLA"
+	GetReconciliation     = "getReconciliation"
+	GetRetiredShards      = "getRetiredShards"
+	PublishPolicy         = "publishPolicy"
+	GetPolicy             = "getPolicy"
+	GetPolicyHistory      = "getPolicyHistory"
+	GetEntryByCID         = "getEntryByCID"
+	GetExtraDataKey       = "getExtraDataKey"
+	RotateExtraDataKey    = "rotateExtraDataKey"
+	SimulatePolicy        = "simulatePolicy"
+	GetLogStates          = "getLogStates"
+	SetLogState           = "setLogState"
 
 	PublicKeyType = "https://trustbloc.dev/ns/public-key"
 	LedgerType    = "https://trustbloc.dev/ns/ledger-type"
+	LeafTypesType = "https://trustbloc.dev/ns/leaf-types"
+	LogIDType     = "https://trustbloc.dev/ns/log-id"
+	KeysType      = "https://trustbloc.dev/ns/keys"
+	// CompromiseType is the property of the signed compromise statement of a compromised log.
+	CompromiseType = "https://trustbloc.dev/ns/compromise"
+	// TilesType is the property of the URL the tiles of the log are served under (see rest.TilePath).
+	TilesType = "https://trustbloc.dev/ns/tiles"
+	// FinalTreeHeadType is the property of the signed final tree head of a frozen log.
+	FinalTreeHeadType = "https://trustbloc.dev/ns/final-tree-head"
+	// PolicyType is the property of the latest signed policy document of a log.
+	PolicyType = "https://trustbloc.dev/ns/policy"
+	// IPFSRootType is the property of the latest root of the mirror of a log on IPFS.
+	IPFSRootType = "https://trustbloc.dev/ns/ipfs-root"
+	// LogStateType is the property of the state of a log (LogState).
+	LogStateType = "https://trustbloc.dev/ns/log-state"
 )
 
+// DefaultMaxEntrySize is the max size of a submitted credential or entry if the Config does not set one.
+const DefaultMaxEntrySize = 1 << 20
+
+var logger = log.New("controller/command")
+
 // TrillianLogClient is the API client for TrillianLog service.
 type TrillianLogClient trillian.TrillianLogClient
 
+// TrillianAdminClient is the part of the API client for TrillianAdmin service managing the state of a tree.
+type TrillianAdminClient interface {
+	GetTree(ctx context.Context, in *trillian.GetTreeRequest, opts ...grpc.CallOption) (*trillian.Tree, error)
+	UpdateTree(ctx context.Context, in *trillian.UpdateTreeRequest, opts ...grpc.CallOption) (*trillian.Tree, error)
+}
+
 // Key holds info about a key that is using for signing.
 type Key struct {
 	ID string
@@ -62,6 +139,59 @@ type Cmd struct {
 	PubKey  []byte
 	alg     *SignatureAndHashAlgorithm
 	loaders map[string]jsonld.DocumentLoader
+	hooks   *callbacks
+
+	leafTypes           *leafTypes
+	timeIndex           *timeIndex
+	credentialIndexes   map[string]*credentialIndex // alias -> index
+	shadows             map[string]*shadow          // alias -> shadow
+	readOnly            uint32                      // 1 if writes are rejected (maintenance), accessed atomically
+	maxReplicaStaleness time.Duration
+	maxClockSkew        time.Duration
+	maxEntrySize        int
+	keyUsage            *keyUsage
+	recoveryKey         []byte
+	compromiseMu        sync.RWMutex
+	compromise          *SignedCompromiseStatement // nil unless the key of the log is marked compromised
+	onCompromise        func(*SignedCompromiseStatement) error
+	freezesMu           sync.RWMutex
+	freezes             map[string]*SignedFinalTreeHead // alias -> final tree head of the frozen logs
+	onFreeze            func(string, *SignedFinalTreeHead) error
+	retiredMu           sync.RWMutex
+	retired             []RetiredShard // the archive of the retired shards, in the order they were retired
+	onRetire            func(*RetiredShard) error
+	policiesMu          sync.RWMutex
+	policies            map[string][]SignedPolicyDocument // alias -> published versions of the policy
+	onPolicy            func(string, *SignedPolicyDocument) error
+	ipfsRootsMu         sync.RWMutex
+	ipfsRoots           map[string]*IPFSRoot // alias -> latest root of the mirror of the log on IPFS
+	extraData           *extraDataEncryption // nil if the extra data of leaves is not encrypted
+	keyRotation         *keyRotation         // nil if the extra data of leaves is not encrypted
+	receipts            ReceiptStore         // nil if receipts are not persisted
+	dedup               *dedup               // nil if the logged leaves are not persisted
+	trust               *trustCache          // nil if no trust registry is configured
+	policyTags          *PolicyTagsSchema
+	tiles               *tileCache
+	proofs              *proofCache  // nil if the proofs are always taken from Trillian
+	snapshots           *snapshots   // nil if the verification snapshots are not served
+	sla                 *treeHeadSLA // nil if the publication of the tree heads is not monitored
+	usage               *usage
+	pending             *pendingSubmissions
+	reconciliation      *reconciliation // nil if the logs are not reconciled
+	submissionTTL       *submissionTTL  // nil if the submissions do not expire
+	submissions         *submissionStats
+	features            *featureFlags
+	treeStates          *treeStates
+	sctExtensionTypes   []SCTExtensionType
+	timeSource          TimeSource
+
+	auditors              map[string][]byte
+	annotations           AnnotationStore
+	annotationsMu         sync.Mutex
+	annotationSubscribers []string
+
+	purposeKeys map[KeyPurpose]*signingKey
+	webhooks    *webhook.Signer
 }
 
 type permission int32
@@ -71,6 +201,9 @@ const (
 	write permission = 'w'
 )
 
+// idempotencyPrefix separates the identity hashes of the idempotency keys from the hashes of the entries.
+const idempotencyPrefix = "idem:"
+
 // Log represents the log.
 type Log struct {
 	// ID trillian`s log id.
@@ -80,6 +213,19 @@ type Log struct {
 	Endpoint   string
 	Issuers    []string
 	Client     TrillianLogClient
+	// ReadEndpoint and ReadClient of the read replica (optional), writes always go to the primary.
+	ReadEndpoint string
+	ReadClient   TrillianLogClient
+	// ShadowEndpoint, ShadowID and ShadowClient of the shadow log (optional), writes accepted by the log
+	// are mirrored to the shadow log in the background and differences are reported.
+	ShadowEndpoint string
+	ShadowID       int64
+	ShadowClient   TrillianLogClient
+	// Tenant (optional) is the customer the log is operated for, the usage and the metrics of the log are
+	// labeled with it (defaults to the alias).
+	Tenant string
+	// AdminClient (optional) reads and transitions the state of the Trillian tree of the log, see GetLogStates.
+	AdminClient TrillianAdminClient
 }
 
 // Config for the Cmd.
@@ -91,6 +237,131 @@ type Config struct {
 	DocumentLoaders map[string]jsonld.DocumentLoader // alias -> loader
 	Key             Key
 	BaseURL         string
+	HTTPClient      HTTPClient // used to notify submission callbacks and annotation subscribers
+	// CallbackHosts are the hosts (host or host:port) the callbacks of the submissions may be posted to, the
+	// submissions with a callback to another host are rejected. No callback is accepted if not set.
+	CallbackHosts []string
+	// CallbackAllowPrivate allows the callbacks of the submissions to connect to the loopback, private and
+	// link-local addresses, e.g. to test the callbacks locally.
+	CallbackAllowPrivate bool
+	// MaxReplicaStaleness is the max age of the log root served by a read replica (zero means no limit).
+	MaxReplicaStaleness time.Duration
+	// MaxClockSkew is the max time the timestamp of an anchored tree head may be ahead of the local clock,
+	// it tolerates the skew between the clocks of the logs (defaults to 5 minutes).
+	MaxClockSkew time.Duration
+	// MaxEntrySize is the max size in bytes of a submitted credential or entry, larger submissions are rejected
+	// (defaults to DefaultMaxEntrySize).
+	MaxEntrySize int
+	// LeafTypes are registered in addition to the built-in leaf types.
+	LeafTypes []LeafType
+	// Presentations registers the leaf type of the presentations signed by their holders
+	// (PresentationLogEntryType), e.g. consent receipts.
+	Presentations bool
+	// ReadOnly starts the service in the read-only (maintenance) mode, it can be toggled with SetReadOnly.
+	ReadOnly bool
+	// KeyUsageThresholds are the max numbers of signatures of a kind within a minute, a higher volume is
+	// reported as an anomaly. There is no threshold for the kinds not set.
+	KeyUsageThresholds map[SignatureKind]uint64
+	// RecoveryKey is the public key signing the compromise statement of the log (MarkCompromised), it is
+	// registered before the key of the log could be compromised. The log can't be marked compromised without it.
+	RecoveryKey []byte
+	// Compromise is the compromise statement of the log restored at start, if the log was marked compromised.
+	Compromise *SignedCompromiseStatement
+	// OnCompromise (optional) persists the compromise statement once the log is marked compromised.
+	OnCompromise func(*SignedCompromiseStatement) error
+	// Freezes are the final tree heads of the frozen logs (alias -> final tree head) restored at start.
+	Freezes map[string]*SignedFinalTreeHead
+	// OnFreeze (optional) persists the final tree head of the log once it is frozen.
+	OnFreeze func(alias string, final *SignedFinalTreeHead) error
+	// RetiredShards is the archive of the retired shards (the frozen logs, including the logs no longer served)
+	// restored at start, see GetRetiredShards.
+	RetiredShards []RetiredShard
+	// OnRetire (optional) persists a shard in the archive once its log is frozen, before the final tree head.
+	OnRetire func(shard *RetiredShard) error
+	// Policies (optional) are the published versions of the policies of the logs (alias -> versions, oldest
+	// first), restored at start, see GetPolicy.
+	Policies map[string][]SignedPolicyDocument
+	// OnPolicy (optional) persists a version of the policy of a log once it is published.
+	OnPolicy func(alias string, signed *SignedPolicyDocument) error
+	// IPFSRoots (optional) are the latest roots of the mirrors of the logs on IPFS (alias -> root) restored at
+	// start, see SetIPFSRoot.
+	IPFSRoots map[string]*IPFSRoot
+	// ExtraDataKeyID (optional) is the ID of the envelope key (e.g. AES256GCM) of the KMS encrypting the extra
+	// data of leaves (the proofs of credentials) at rest, the Crypto must implement Encrypter. The extra data is
+	// decrypted on reads, extra data stored before the encryption was enabled is served as is.
+	ExtraDataKeyID string
+	// OnExtraDataKey (optional) persists the envelope key of the extra data once it is rotated, see
+	// RotateExtraDataKey.
+	OnExtraDataKey func(keyID string) error
+	// ReEncryption (optional) enables the background re-encryption of the extra data of leaves with the active
+	// envelope key, see ReEncryptExtraData and GetExtraDataKey.
+	ReEncryption *ReEncryptionConfig
+	// TimeIndexStore (optional) persists the sequencing timestamps of the leaves looked up by the time-range
+	// queries, so the binary searches of the ranges reuse them after a restart and across the instances.
+	TimeIndexStore TimeIndexStore
+	// ReceiptStore (optional) persists the SCTs of the submissions with an idempotency key, they are served by
+	// GetReceipt.
+	ReceiptStore ReceiptStore
+	// DedupStore (optional) persists the logged leaves, the submissions of logged entries are answered from it
+	// without queueing their leaves. It must index DedupTagName.
+	DedupStore DedupStore
+	// DedupFilterCapacity is the number of leaves the in-memory filter of the dedup store is sized for (defaults
+	// to DefaultDedupFilterCapacity), the filter of the stored leaves is loaded when the command is created.
+	DedupFilterCapacity uint64
+	// ProofCacheSize is the number of nodes of the tree kept to compute the proofs without Trillian: the nodes of
+	// the proofs served by Trillian (and of the cached tiles) are reused by the proofs sharing them, a proof
+	// needing a node which isn't cached is taken from Trillian. The proofs are always taken from Trillian if 0.
+	ProofCacheSize int
+	// VerificationSnapshots (optional) enables the signed verification snapshots of the logs, see
+	// GetVerificationSnapshot.
+	VerificationSnapshots *VerificationSnapshotConfig
+	// TreeHeadSLA (optional) enables the monitoring of the publication of the tree heads versus the schedule, see
+	// MonitorTreeHeads and GetTreeHeadSLA.
+	TreeHeadSLA *TreeHeadSLAConfig
+	// Reconciliation (optional) enables the periodic reconciliation of the counters of the logs with the leaves
+	// sequenced by Trillian, see ReconcileLogs and GetReconciliation.
+	Reconciliation *ReconciliationConfig
+	// SubmissionTTL (optional) enables the TTL of the submissions queued by the instance, see MonitorUnmerged and
+	// GetUnmergedSubmissions.
+	SubmissionTTL *SubmissionTTLConfig
+	// SubmissionStats (optional) configures the stats of the submitted credentials per issuer, see
+	// GetSubmissionStats.
+	SubmissionStats *SubmissionStatsConfig
+	// FeatureFlags (optional) disables operations of the REST API for the deployment or per tenant, see
+	// CheckOperation.
+	FeatureFlags *FeatureFlagsConfig
+	// TreeStateTTL is the time the states of the Trillian trees of the logs are cached for (defaults to 10
+	// seconds), a write rejected by Trillian refreshes the state of the tree.
+	TreeStateTTL time.Duration
+	// SCTExtensions (optional) are the registered extensions added to the SCTs, see SCTExtensionType.
+	SCTExtensions []SCTExtensionType
+	// TrustRegistry (optional) is checked for the issuer of every submitted credential.
+	TrustRegistry TrustRegistry
+	// TrustRegistryTTL is the time decisions of the trust registry are cached for (defaults to 10 minutes).
+	TrustRegistryTTL time.Duration
+	// PolicyTags (optional) is the schema the policy tags of the submissions are validated against, any
+	// jurisdiction and the eIDAS assurance levels are accepted if not set.
+	PolicyTags *PolicyTagsSchema
+	// TimeSource (optional) tells the time of the timestamps of the SCTs, the local clock if not set.
+	TimeSource TimeSource
+	// Auditors are the public keys of the auditors allowed to annotate entries (auditor -> public key).
+	Auditors map[string][]byte
+	// AnnotationStore persists the annotations of the entries, they can't be added without it.
+	AnnotationStore AnnotationStore
+	// AnnotationSubscribers are the URLs every added annotation is posted to (AnnotationEvent).
+	AnnotationSubscribers []string
+	// PurposeKeys (optional) are the keys of the KMS serving the purposes other than signing the SCTs and the
+	// tree heads (e.g. ResponseKeyPurpose), the key of the log never serves them. A purpose without a key is not
+	// signed for.
+	PurposeKeys map[KeyPurpose]Key
+	// DID (optional) of the log, the key IDs of the webhook signatures are DID URLs of the verification methods
+	// of its DID document.
+	DID string
+}
+
+// HTTPClient represents HTTP client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
 }
 
 // KeyManager key manager.
@@ -109,11 +380,65 @@ type Crypto interface {
 var (
 	once                        sync.Once
 	addVCParseCredentialLatency monitoring.Histogram
+	shadowDivergences           monitoring.Counter
+	callbacksDropped            monitoring.Counter
+	anchorClockSkew             monitoring.Histogram
+	signatures                  monitoring.Counter
+	signatureLastUse            monitoring.Gauge
+	keyUsageAnomalies           monitoring.Counter
+	tenantEntries               monitoring.Counter
+	tenantDuplicates            monitoring.Counter
+	tenantEntryBytes            monitoring.Counter
+	tenantRequests              monitoring.Counter
+	tenantErrors                monitoring.Counter
+	tenantLatency               monitoring.Histogram
+	submissionSizes             monitoring.Histogram
+	submissionContexts          monitoring.Histogram
+	dedupHits                   monitoring.Counter
+	dedupFalsePositives         monitoring.Counter
+	proofCacheHits              monitoring.Counter
+	proofCacheMisses            monitoring.Counter
+	treeHeadAge                 monitoring.Gauge
+	reconciliationUnaccounted   monitoring.Gauge
+	reconciliationStale         monitoring.Gauge
+	reconciliationDedupDrift    monitoring.Gauge
+	reconciliationIndexLag      monitoring.Gauge
+	unmergedSubmissions         monitoring.Gauge
+	requeuedSubmissions         monitoring.Counter
+	reEncryptedLeaves           monitoring.Counter
+	reEncryptionRemaining       monitoring.Gauge
 )
 
 // nolint: lll
 func createMetrics(mf monitoring.MetricFactory) {
 	addVCParseCredentialLatency = mf.NewHistogram("add_vc_parse_credential_latency", "Latency of parse credential (add-vc operation)", "alias")
+	shadowDivergences = mf.NewCounter("shadow_divergences", "Number of leaves the shadow log failed to accept or accepted differently", "alias")
+	callbacksDropped = mf.NewCounter("callbacks_dropped", "Number of callbacks dropped as too many callbacks were in flight", "kind")
+	signatures = mf.NewCounter("signatures", "Number of signatures produced with the keys of the log", "kind")
+	signatureLastUse = mf.NewGauge("signature_last_use", "Time of the last signature produced with the keys of the log (unix seconds)", "kind")
+	keyUsageAnomalies = mf.NewCounter("key_usage_anomalies", "Number of windows the signing volume exceeded the threshold in", "kind")
+	anchorClockSkew = mf.NewHistogram("anchor_clock_skew", "Time the timestamp of an anchored tree head is ahead of the local clock in seconds (negative if behind)", "alias")
+	tenantEntries = mf.NewCounter("tenant_entries", "Number of entries logged per tenant", "tenant", "alias")
+	tenantDuplicates = mf.NewCounter("tenant_duplicate_entries", "Number of submissions of logged entries per tenant", "tenant", "alias")
+	tenantEntryBytes = mf.NewCounter("tenant_entry_bytes", "Size of the entries logged per tenant in bytes", "tenant", "alias")
+	tenantRequests = mf.NewCounter("tenant_requests", "Number of requests per tenant", "tenant", "alias", "operation", "code")
+	tenantErrors = mf.NewCounter("tenant_request_errors", "Number of failed requests per tenant", "tenant", "alias", "operation")
+	dedupHits = mf.NewCounter("dedup_hits", "Number of submissions of logged entries answered from the dedup store", "alias")
+	dedupFalsePositives = mf.NewCounter("dedup_false_positives", "Number of submissions of new entries the dedup filter reported as logged", "alias")
+	proofCacheHits = mf.NewCounter("proof_cache_hits", "Number of proofs computed from the cached nodes of the tree", "alias")
+	proofCacheMisses = mf.NewCounter("proof_cache_misses", "Number of proofs taken from Trillian because a node is not cached", "alias")
+	treeHeadAge = mf.NewGauge("tree_head_age", "Age of the latest tree head of the log in seconds", "alias")
+	reconciliationUnaccounted = mf.NewGauge("reconciliation_unaccounted_submissions", "Number of submissions accepted by the instance which are neither sequenced nor pending", "alias")
+	reconciliationStale = mf.NewGauge("reconciliation_stale_submissions", "Number of submissions accepted by the instance which are pending for longer than the stale age", "alias")
+	reconciliationDedupDrift = mf.NewGauge("reconciliation_dedup_drift", "Number of entries of the dedup store beyond the leaves of the log (sequenced or pending)", "alias")
+	unmergedSubmissions = mf.NewGauge("unmerged_submissions", "Number of submissions queued by the instance which are not sequenced within the TTL", "alias")
+	requeuedSubmissions = mf.NewCounter("requeued_submissions", "Number of unmerged submissions re-queued by the instance", "alias", "status")
+	reEncryptedLeaves = mf.NewCounter("re_encrypted_leaves", "Number of leaves whose extra data was re-encrypted with the active envelope key", "alias")
+	reEncryptionRemaining = mf.NewGauge("re_encryption_remaining_leaves", "Number of leaves of the log whose extra data may not be encrypted with the active envelope key", "alias")
+	reconciliationIndexLag = mf.NewGauge("reconciliation_index_lag", "Number of leaves of the log which are not indexed", "alias")
+	tenantLatency = mf.NewHistogram("tenant_request_latency", "Latency of requests per tenant in seconds", "tenant", "alias", "operation")
+	submissionSizes = mf.NewHistogramWithBuckets("submission_size_bytes", "Size of the submitted credentials in bytes", submissionSizeBuckets, "alias")
+	submissionContexts = mf.NewHistogramWithBuckets("submission_contexts", "Number of JSON-LD contexts of the submitted credentials", submissionContextsBuckets, "alias")
 }
 
 // New returns commands controller.
@@ -124,23 +449,19 @@ func New(cfg *Config, mf monitoring.MetricFactory) (*Cmd, error) {
 
 	once.Do(func() { createMetrics(mf) })
 
-	pubBytes, keyType, err := cfg.KMS.ExportPubKeyBytes(cfg.Key.ID)
+	logKey, err := newSigningKey(cfg.KMS, cfg.Key)
 	if err != nil {
-		return nil, fmt.Errorf("export pub key bytes: %w", err)
-	}
-
-	if len(pubBytes) == 0 {
-		return nil, fmt.Errorf("public key is empty")
+		return nil, err
 	}
 
-	alg, err := signatureAndHashAlgorithmByKeyType(keyType)
+	purposeKeys, err := newPurposeKeys(cfg.KMS, cfg.Key, cfg.PurposeKeys)
 	if err != nil {
-		return nil, fmt.Errorf("key type %v is not supported", keyType)
+		return nil, fmt.Errorf("purpose keys: %w", err)
 	}
 
-	kh, err := cfg.KMS.Get(cfg.Key.ID)
+	sctExtensionTypes, err := newSCTExtensionTypes(cfg.SCTExtensions)
 	if err != nil {
-		return nil, fmt.Errorf("kms get kh: %w", err)
+		return nil, err
 	}
 
 	logs := make(map[string]Log)
@@ -148,18 +469,139 @@ func New(cfg *Config, mf monitoring.MetricFactory) (*Cmd, error) {
 		logs[log.Alias] = log
 	}
 
-	return &Cmd{
+	cmd := &Cmd{
 		vdr:     cfg.VDR,
-		PubKey:  pubBytes,
-		VCLogID: sha256.Sum256(pubBytes),
+		PubKey:  logKey.pubKey,
+		VCLogID: LogID(logKey.pubKey),
 		logs:    logs,
 		kms:     cfg.KMS,
-		kh:      kh,
+		kh:      logKey.kh,
 		crypto:  cfg.Crypto,
-		alg:     alg,
+		alg:     logKey.alg,
 		baseURL: cfg.BaseURL,
 		loaders: cfg.DocumentLoaders,
-	}, nil
+		hooks:   newCallbacks(cfg),
+
+		timeIndex:           newTimeIndex(cfg.TimeIndexStore),
+		credentialIndexes:   newCredentialIndexes(logs),
+		shadows:             newShadows(logs),
+		maxReplicaStaleness: cfg.MaxReplicaStaleness,
+		maxClockSkew:        cfg.MaxClockSkew,
+		maxEntrySize:        cfg.MaxEntrySize,
+		keyUsage:            newKeyUsage(cfg.KeyUsageThresholds),
+		recoveryKey:         cfg.RecoveryKey,
+		onCompromise:        cfg.OnCompromise,
+		freezes:             map[string]*SignedFinalTreeHead{},
+		onFreeze:            cfg.OnFreeze,
+		onRetire:            cfg.OnRetire,
+		policies:            map[string][]SignedPolicyDocument{},
+		onPolicy:            cfg.OnPolicy,
+		ipfsRoots:           map[string]*IPFSRoot{},
+		receipts:            cfg.ReceiptStore,
+		trust:               newTrustCache(cfg.TrustRegistry, cfg.TrustRegistryTTL),
+		tiles:               newTileCache(),
+		proofs:              newProofCache(cfg.ProofCacheSize),
+		snapshots:           newSnapshots(cfg.VerificationSnapshots),
+		sla:                 newTreeHeadSLA(cfg.TreeHeadSLA),
+		usage:               newUsage(logs),
+		pending:             newPendingSubmissions(logs),
+		reconciliation:      newReconciliation(cfg.Reconciliation),
+		submissionTTL:       newSubmissionTTL(cfg.SubmissionTTL),
+		submissions:         newSubmissionStats(cfg.SubmissionStats),
+		features:            newFeatureFlags(cfg.FeatureFlags),
+		treeStates:          newTreeStates(cfg.TreeStateTTL),
+		sctExtensionTypes:   sctExtensionTypes,
+		timeSource:          cfg.TimeSource,
+
+		auditors:              cfg.Auditors,
+		annotations:           cfg.AnnotationStore,
+		annotationSubscribers: cfg.AnnotationSubscribers,
+
+		purposeKeys: purposeKeys,
+	}
+
+	if cmd.timeSource == nil {
+		cmd.timeSource = localClock{}
+	}
+
+	cmd.webhooks, err = cmd.newWebhookSigner(cfg.DID)
+	if err != nil {
+		return nil, fmt.Errorf("webhook signer: %w", err)
+	}
+
+	if cmd.maxClockSkew <= 0 {
+		cmd.maxClockSkew = defaultMaxClockSkew
+	}
+
+	if cmd.maxEntrySize <= 0 {
+		cmd.maxEntrySize = DefaultMaxEntrySize
+	}
+
+	if cfg.ReadOnly {
+		cmd.readOnly = 1
+	}
+
+	cmd.policyTags, err = newPolicyTagsSchema(cfg.PolicyTags)
+	if err != nil {
+		return nil, fmt.Errorf("policy tags schema: %w", err)
+	}
+
+	if cfg.Compromise != nil {
+		if err = cmd.verifyCompromise(cfg.Compromise); err != nil {
+			return nil, fmt.Errorf("restore compromise statement: %w", err)
+		}
+
+		cmd.compromise = cfg.Compromise
+	}
+
+	for alias, final := range cfg.Freezes {
+		if err = cmd.verifyFinalTreeHead(alias, final); err != nil {
+			return nil, fmt.Errorf("restore final tree head of log %s: %w", alias, err)
+		}
+
+		cmd.freezes[alias] = final
+	}
+
+	if err = cmd.restoreRetiredShards(cfg.RetiredShards); err != nil {
+		return nil, fmt.Errorf("restore retired shards: %w", err)
+	}
+
+	if err = cmd.restorePolicies(cfg.Policies); err != nil {
+		return nil, fmt.Errorf("restore policies: %w", err)
+	}
+
+	for alias, root := range cfg.IPFSRoots {
+		if err = cmd.SetIPFSRoot(alias, root); err != nil {
+			return nil, fmt.Errorf("restore IPFS root: %w", err)
+		}
+	}
+
+	if cfg.ExtraDataKeyID != "" {
+		cmd.extraData, err = newExtraDataEncryption(cfg.ExtraDataKeyID, cfg.KMS, cfg.Crypto)
+		if err != nil {
+			return nil, fmt.Errorf("extra data encryption: %w", err)
+		}
+
+		cmd.keyRotation = newKeyRotation(cfg.OnExtraDataKey, cfg.ReEncryption)
+	}
+
+	cmd.dedup, err = newDedup(cfg.DedupStore, cfg.DedupFilterCapacity)
+	if err != nil {
+		return nil, fmt.Errorf("load dedup filter: %w", err)
+	}
+
+	types := cmd.defaultLeafTypes()
+
+	if cfg.Presentations {
+		types = append(types, cmd.presentationLeafType())
+	}
+
+	cmd.leafTypes, err = newLeafTypes(append(types, cfg.LeafTypes...))
+	if err != nil {
+		return nil, fmt.Errorf("register leaf types: %w", err)
+	}
+
+	return cmd, nil
 }
 
 // GetHandlers returns list of all commands supported by this controller.
@@ -170,25 +612,103 @@ func (c *Cmd) GetHandlers() []Handler {
 		NewCmdHandler(GetEntries, c.GetEntries),
 		NewCmdHandler(GetProofByHash, c.GetProofByHash),
 		NewCmdHandler(GetEntryAndProof, c.GetEntryAndProof),
+		NewCmdHandler(GetProofOfAbsence, c.GetProofOfAbsence),
+		NewCmdHandler(GetMapRoot, c.GetMapRoot),
+		NewCmdHandler(GetCredentialStatus, c.GetCredentialStatus),
+		NewCmdHandler(AddRevocation, c.AddRevocation),
+		NewCmdHandler(GetRevocations, c.GetRevocations),
+		NewCmdHandler(GetCredentialHistory, c.GetCredentialHistory),
+		NewCmdHandler(AddAnchor, c.AddAnchor),
+		NewCmdHandler(AddEntry, c.AddEntry),
+		NewCmdHandler(GetAnchors, c.GetAnchors),
+		NewCmdHandler(GetShadowStatus, c.GetShadowStatus),
+		NewCmdHandler(GetReadOnly, c.GetReadOnly),
+		NewCmdHandler(SetReadOnly, c.SetReadOnly),
+		NewCmdHandler(GetKeyUsage, c.GetKeyUsage),
+		NewCmdHandler(GetUsage, c.GetUsage),
+		NewCmdHandler(GetSubmissionStats, c.GetSubmissionStats),
+		NewCmdHandler(GetPendingSubmissions, c.GetPendingSubmissions),
+		NewCmdHandler(GetUnmerged, c.GetUnmergedSubmissions),
+		NewCmdHandler(MarkCompromised, c.MarkCompromised),
+		NewCmdHandler(FreezeLog, c.FreezeLog),
+		NewCmdHandler(GetFinalTreeHead, c.GetFinalTreeHead),
+		NewCmdHandler(GetLogStates, c.GetLogStates),
+		NewCmdHandler(SetLogState, c.SetLogState),
+		NewCmdHandler(GetReceipt, c.GetReceipt),
+		NewCmdHandler(AddAnnotation, c.AddAnnotation),
+		NewCmdHandler(GetAnnotations, c.GetAnnotations),
+		NewCmdHandler(GetAuditExport, c.GetAuditExport),
+		NewCmdHandler(GetRandomEntries, c.GetRandomEntries),
+		NewCmdHandler(GetDiff, c.GetDiff),
+		NewCmdHandler(GetTile, c.GetTile),
+		NewCmdHandler(GetEntryBundle, c.GetEntryBundle),
 		NewCmdHandler(GetIssuers, c.GetIssuers),
 		NewCmdHandler(Webfinger, c.Webfinger),
+		NewCmdHandler(GetSnapshot, c.GetVerificationSnapshot),
+		NewCmdHandler(GetTreeHeadSLA, c.GetTreeHeadSLA),
+		NewCmdHandler(GetReconciliation, c.GetReconciliation),
+		NewCmdHandler(GetRetiredShards, c.GetRetiredShards),
+		NewCmdHandler(PublishPolicy, c.PublishPolicy),
+		NewCmdHandler(GetPolicy, c.GetPolicy),
+		NewCmdHandler(GetPolicyHistory, c.GetPolicyHistory),
+		NewCmdHandler(GetEntryByCID, c.GetEntryByCID),
+		NewCmdHandler(GetExtraDataKey, c.GetExtraDataKey),
+		NewCmdHandler(RotateExtraDataKey, c.RotateExtraDataKey),
+		NewCmdHandler(SimulatePolicy, c.SimulatePolicy),
 		NewCmdHandler(AddVC, c.AddVC),
 	}
 }
 
-// GetIssuers returns issuers.
+// GetIssuers returns issuers. The request is the alias of the log, all the issuers are returned, or
+// a GetIssuersRequest, a page of the issuers is returned (GetIssuersResponse). The issuers are the issuers of the
+// log, or the issuers of the indexed credentials if the request filters them by policy tags.
 func (c *Cmd) GetIssuers(w io.Writer, r io.Reader) error {
-	var alias string
+	var raw json.RawMessage
 
-	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
 		return fmt.Errorf("%w: decode alias failed", errors.ErrInternal)
 	}
 
-	if err := c.hasPermissions(alias, read); err != nil {
+	var request GetIssuersRequest
+
+	paged := json.Unmarshal(raw, &request.Alias) != nil
+	if paged {
+		if err := json.Unmarshal(raw, &request); err != nil {
+			return fmt.Errorf("%w: decode alias failed", errors.ErrInternal)
+		}
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
 		return fmt.Errorf("has permissions: %w", err)
 	}
 
-	return json.NewEncoder(w).Encode(c.logs[alias].Issuers) // nolint: wrapcheck
+	issuers, list := c.logs[request.Alias].Issuers, GetIssuers+"/"+request.Alias
+
+	if request.PolicyTags != (PolicyTags{}) {
+		index, err := c.lockIndex(request.Alias)
+		if err != nil {
+			return fmt.Errorf("index credentials: %w", err)
+		}
+
+		defer index.mu.Unlock()
+
+		issuers = index.taggedIssuers(request.PolicyTags)
+		list += "/" + request.Jurisdiction + "/" + request.AssuranceLevel
+	}
+
+	if !paged {
+		return json.NewEncoder(w).Encode(issuers) // nolint: wrapcheck
+	}
+
+	begin, end, next, err := paginate(list, request.PageRequest, len(issuers))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(GetIssuersResponse{ // nolint: wrapcheck
+		Issuers:       append([]string{}, issuers[begin:end]...),
+		NextPageToken: next,
+	})
 }
 
 // Webfinger returns discovery info.
@@ -200,27 +720,64 @@ func (c *Cmd) Webfinger(w io.Writer, r io.Reader) error {
 	}
 
 	sub := c.baseURL + "/" + alias
+
+	properties := map[string]interface{}{
+		PublicKeyType: c.PubKey,
+		LogIDType:     c.VCLogID[:],
+		LedgerType:    "vct-v1",
+		LeafTypesType: c.leafTypes.metadata(),
+		TilesType:     sub,
+	}
+
+	// the keys are published if a key serves another purpose than the key of the log
+	if len(c.purposeKeys) > 0 {
+		properties[KeysType] = c.keysMetadata()
+	}
+
+	if compromise := c.getCompromise(); compromise != nil {
+		properties[CompromiseType] = compromise
+	}
+
+	if final := c.getFinalTreeHead(alias); final != nil {
+		properties[FinalTreeHeadType] = final
+	}
+
+	// the state is published if the Trillian tree of the log is managed
+	if state, err := c.logState(alias, false); err == nil && state.TreeState != "" {
+		properties[LogStateType] = state
+	}
+
+	if policy := c.latestPolicy(alias); policy != nil {
+		properties[PolicyType] = policy
+	}
+
+	if root := c.getIPFSRoot(alias); root != nil {
+		properties[IPFSRootType] = root
+	}
+
 	// TODO: add alternate links
 	return json.NewEncoder(w).Encode(&WebFingerResponse{
-		Subject: sub,
-		Properties: map[string]interface{}{
-			PublicKeyType: c.PubKey,
-			LedgerType:    "vct-v1",
-		},
+		Subject:    sub,
+		Properties: properties,
 		Links: []WebFingerLink{
 			{Rel: "self", Href: sub},
 		},
 	}) // nolint: wrapcheck
 }
 
-// CreateLeaf creates MerkleTreeLeaf.
-func CreateLeaf(timestamp uint64, vc *verifiable.Credential) (*MerkleTreeLeaf, error) {
-	proofs := vc.Proofs
-	vc.Proofs = nil
+// LogID returns the ID of the log signing with the public key: the SHA-256 hash of the public key as published
+// in the webfinger. The ID binds SCTs and tree heads to the key of the log rather than to its endpoint.
+func LogID(pubKey []byte) [sha256.Size]byte {
+	return sha256.Sum256(pubKey)
+}
 
-	defer func() { vc.Proofs = proofs }()
+// CreateLeaf creates MerkleTreeLeaf. The credential is not modified, so the leaves of a credential can be created
+// concurrently.
+func CreateLeaf(timestamp uint64, vc *verifiable.Credential) (*MerkleTreeLeaf, error) {
+	withoutProofs := *vc
+	withoutProofs.Proofs = nil
 
-	credentialWithoutProofs, err := json.Marshal(vc)
+	credentialWithoutProofs, err := json.Marshal(&withoutProofs)
 	if err != nil {
 		return nil, fmt.Errorf("marshal credential: %w", err)
 	}
@@ -250,6 +807,16 @@ func (c *Cmd) hasPermissions(alias string, perm permission) error {
 	return errors.NewBadRequestError(fmt.Errorf("action forbidden for %q", alias))
 }
 
+// checkEntrySize rejects the submissions larger than the max entry size.
+func (c *Cmd) checkEntrySize(entry []byte) error {
+	if len(entry) > c.maxEntrySize {
+		return fmt.Errorf("%w: entry of %d bytes exceeds the max size of %d bytes",
+			errors.ErrBadRequest, len(entry), c.maxEntrySize)
+	}
+
+	return nil
+}
+
 // AddVC adds verifiable credential to log.
 func (c *Cmd) AddVC(w io.Writer, r io.Reader) error { // nolint: funlen
 	var req AddVCRequest
@@ -262,6 +829,35 @@ func (c *Cmd) AddVC(w io.Writer, r io.Reader) error { // nolint: funlen
 		return fmt.Errorf("has permissions: %w", err)
 	}
 
+	if err := c.checkEntrySize(req.VCEntry); err != nil {
+		return err
+	}
+
+	credential, options, err := parseSubmission(req.VCEntry)
+	if err != nil {
+		return fmt.Errorf("parse submission: %w", err)
+	}
+
+	if options.Tenant != "" && options.Tenant != req.Alias {
+		return fmt.Errorf("%w: tenant %q does not match log %q", errors.ErrBadRequest, options.Tenant, req.Alias)
+	}
+
+	if options.CallbackURL != "" {
+		if err = c.hooks.checkURL(options.CallbackURL); err != nil {
+			return fmt.Errorf("check callback: %w", err)
+		}
+	}
+
+	// only the issuer-signed JWT of an SD-JWT is logged (see SDJWT)
+	if IsSDJWT(credential) {
+		sdJWT, er := ParseSDJWT(string(credential))
+		if er != nil {
+			return errors.NewBadRequestError(fmt.Errorf("parse SD-JWT: %w", scrub.Error(er, credential)))
+		}
+
+		credential = []byte(sdJWT.IssuerJWT)
+	}
+
 	loader, ok := c.loaders[req.Alias]
 	if !ok {
 		return fmt.Errorf("no document loader found for alias %s", req.Alias)
@@ -269,74 +865,231 @@ func (c *Cmd) AddVC(w io.Writer, r io.Reader) error { // nolint: funlen
 
 	parseCredentialTime := time.Now()
 
-	vc, err := verifiable.ParseCredential(req.VCEntry, verifiable.WithPublicKeyFetcher(
+	vc, err := verifiable.ParseCredential(credential, verifiable.WithPublicKeyFetcher(
 		verifiable.NewVDRKeyResolver(c.vdr).PublicKeyFetcher(),
 	), verifiable.WithJSONLDDocumentLoader(loader))
+
+	c.recordSubmission(req.Alias, credential, vc, time.Since(parseCredentialTime))
+
 	if err != nil {
-		return errors.NewBadRequestError(fmt.Errorf("parse credential: %w", err))
+		return errors.NewBadRequestError(fmt.Errorf("parse credential: %w", scrub.Error(err, credential)))
 	}
 
 	addVCParseCredentialLatency.Observe(time.Since(parseCredentialTime).Seconds(), req.Alias)
 
+	if IsDerivedCredential(vc) {
+		return errors.NewBadRequestError(fmt.Errorf("derived credential (%s) is not logged, log its base credential",
+			BBSSignatureProofType))
+	}
+
 	if len(c.logs[req.Alias].Issuers) > 0 && !contains(c.logs[req.Alias].Issuers, vc.Issuer.ID) {
 		return fmt.Errorf("%w: issuer %s is not in a list", errors.ErrBadRequest, vc.Issuer.ID)
 	}
 
-	leaf, err := CreateLeaf(uint64(time.Now().UnixNano()/int64(time.Millisecond)), vc)
+	timestamp, attestation, err := c.timestamp()
+	if err != nil {
+		return err
+	}
+
+	leaf, err := CreateLeaf(timestamp, vc)
 	if err != nil {
 		return fmt.Errorf("create leaf: %w", err)
 	}
 
+	if err = c.checkTrust(leaf, vc.Issuer.ID); err != nil {
+		return err
+	}
+
+	if options.PolicyTags != nil && *options.PolicyTags != (PolicyTags{}) {
+		if err = c.tagLeaf(leaf, options.PolicyTags); err != nil {
+			return err
+		}
+	}
+
+	if options.Supersedes != nil {
+		if err = c.linkLeaf(req.Alias, leaf, vc.Issuer.ID, options.Supersedes); err != nil {
+			return err
+		}
+	}
+
+	if options.DryRun {
+		return json.NewEncoder(w).Encode(AddVCResponse{ // nolint: wrapcheck
+			SVCTVersion:     V1,
+			Timestamp:       leaf.TimestampedEntry.Timestamp,
+			ID:              c.VCLogID[:],
+			Extensions:      base64.StdEncoding.EncodeToString(leaf.TimestampedEntry.Extensions),
+			TimeAttestation: attestation,
+		})
+	}
+
+	resp, err := c.queueLeaf(req.Alias, leaf, vc.Proofs, options.IdempotencyKey)
+	if err != nil {
+		return err
+	}
+
+	resp = attest(resp, leaf, attestation)
+
+	c.storeReceipt(req.Alias, options.IdempotencyKey, resp)
+
+	if options.CallbackURL != "" {
+		c.postCallback(c.hooks.client, "submission", options.CallbackURL, resp)
+	}
+
+	return json.NewEncoder(w).Encode(resp) // nolint: wrapcheck
+}
+
+func (c *Cmd) queueLeaf(alias string, leaf *MerkleTreeLeaf, proofs []verifiable.Proof,
+	idempotencyKey string) (*AddVCResponse, error) {
+	if err := c.checkWritable(alias); err != nil {
+		return nil, err
+	}
+
 	leafData, err := json.Marshal(leaf)
 	if err != nil {
-		return errors.NewStatusInternalServerError(fmt.Errorf("marshal MerkleTreeLeaf: %w", err))
+		return nil, errors.NewStatusInternalServerError(fmt.Errorf("marshal MerkleTreeLeaf: %w", err))
 	}
 
-	extraData, err := json.Marshal(vc.Proofs)
+	extraData, err := json.Marshal(proofs)
 	if err != nil {
-		return errors.NewStatusInternalServerError(fmt.Errorf("marshal credential proofs: %w", err))
+		return nil, errors.NewStatusInternalServerError(fmt.Errorf("marshal credential proofs: %w", err))
 	}
 
-	leafIDHash := sha256.Sum256(leaf.TimestampedEntry.VCEntry)
+	extraData, err = c.encryptExtraData(extraData, leafData)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := c.logs[req.Alias].Client.QueueLeaf(context.Background(), &trillian.QueueLeafRequest{
-		LogId: c.logs[req.Alias].ID,
-		Leaf: &trillian.LogLeaf{
-			LeafValue:        leafData,
-			ExtraData:        extraData,
-			LeafIdentityHash: leafIDHash[:],
-		},
+	// extensions are part of the identity, a re-issued credential is not a duplicate of the superseded one
+	identity := append(append([]byte(nil), leaf.TimestampedEntry.VCEntry...), leaf.TimestampedEntry.Extensions...)
+
+	leafIDHash := sha256.Sum256(identity)
+	if idempotencyKey != "" {
+		leafIDHash = idempotencyHash(alias, idempotencyKey)
+	}
+
+	loggedValue, err := c.logLeaf(alias, &trillian.LogLeaf{
+		LeafValue:        leafData,
+		ExtraData:        extraData,
+		LeafIdentityHash: leafIDHash[:],
 	})
 	if err != nil {
-		return fmt.Errorf("queue leaf: %w", err)
+		return nil, err
 	}
 
-	if resp.QueuedLeaf == nil {
-		return fmt.Errorf("%w: no leaf", errors.ErrInternal)
+	var loggedLeaf MerkleTreeLeaf
+	if err = json.Unmarshal(loggedValue, &loggedLeaf); err != nil {
+		return nil, errors.NewStatusInternalServerError(fmt.Errorf("failed to reconstruct MerkleTreeLeaf: %w", err))
 	}
 
-	var loggedLeaf MerkleTreeLeaf
-	if err = json.Unmarshal(resp.QueuedLeaf.Leaf.LeafValue, &loggedLeaf); err != nil {
-		return errors.NewStatusInternalServerError(fmt.Errorf("failed to reconstruct MerkleTreeLeaf: %w", err))
+	// the entry logged with the key is the fingerprint of the key, a key is not reused for another entry
+	logged := append(append([]byte(nil), loggedLeaf.TimestampedEntry.VCEntry...),
+		loggedLeaf.TimestampedEntry.Extensions...)
+
+	if idempotencyKey != "" && !bytes.Equal(logged, identity) {
+		return nil, errors.NewConflictError(fmt.Errorf("idempotency key is used by another submission"))
+	}
+
+	sctExtensions, err := c.sctExtensions(alias, &loggedLeaf)
+	if err != nil {
+		return nil, errors.NewStatusInternalServerError(fmt.Errorf("SCT extensions: %w", err))
 	}
 
-	sct, err := c.signV1VCTS(&loggedLeaf)
+	sct, err := c.signV1VCTS(&loggedLeaf, sctExtensions)
 	if err != nil {
-		return fmt.Errorf("sign V1 VCTS: %w", err)
+		return nil, fmt.Errorf("sign V1 VCTS: %w", err)
 	}
 
 	signature, err := json.Marshal(sct)
 	if err != nil {
-		return fmt.Errorf("marshal DigitallySigned payload: %w", err)
+		return nil, fmt.Errorf("marshal DigitallySigned payload: %w", err)
+	}
+
+	return &AddVCResponse{
+		SVCTVersion:   V1,
+		Timestamp:     loggedLeaf.TimestampedEntry.Timestamp,
+		ID:            c.VCLogID[:],
+		Extensions:    base64.StdEncoding.EncodeToString(loggedLeaf.TimestampedEntry.Extensions),
+		SCTExtensions: sctExtensions,
+		Signature:     signature,
+		CID:           EntryCID(loggedLeaf.TimestampedEntry.VCEntry),
+	}, nil
+}
+
+// idempotencyHash returns the identity hash of the leaves submitted with the idempotency key, the key is scoped by
+// the log and its hash is separated from the hashes of the entries.
+func idempotencyHash(alias, idempotencyKey string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(idempotencyPrefix + alias + ":" + idempotencyKey))
+}
+
+// logLeaf queues the leaf to the log unless it is logged already (see dedup), it returns the value of the logged
+// leaf.
+func (c *Cmd) logLeaf(alias string, logLeaf *trillian.LogLeaf) ([]byte, error) {
+	if c.dedup != nil {
+		value, err := c.dedup.loggedLeaf(alias, logLeaf.LeafIdentityHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if value != nil {
+			dedupHits.Inc(alias)
+			c.recordEntry(alias, len(logLeaf.LeafValue), true)
+
+			return value, nil
+		}
 	}
 
-	return json.NewEncoder(w).Encode(AddVCResponse{ // nolint: wrapcheck
-		SVCTVersion: V1,
-		Timestamp:   loggedLeaf.TimestampedEntry.Timestamp,
-		ID:          c.VCLogID[:],
-		Extensions:  base64.StdEncoding.EncodeToString(loggedLeaf.TimestampedEntry.Extensions),
-		Signature:   signature,
+	resp, err := c.logs[alias].Client.QueueLeaf(context.Background(), &trillian.QueueLeafRequest{
+		LogId: c.logs[alias].ID,
+		Leaf:  logLeaf,
 	})
+	if err != nil {
+		return nil, c.queueLeafError(alias, err)
+	}
+
+	if resp.QueuedLeaf == nil {
+		return nil, fmt.Errorf("%w: no leaf", errors.ErrInternal)
+	}
+
+	c.mirrorLeaf(alias, logLeaf, resp.QueuedLeaf)
+
+	if resp.QueuedLeaf.GetStatus().GetCode() != int32(codes.AlreadyExists) {
+		c.recordPending(alias, resp.QueuedLeaf.Leaf)
+	}
+	c.recordEntry(alias, len(logLeaf.LeafValue), resp.QueuedLeaf.GetStatus().GetCode() == int32(codes.AlreadyExists))
+
+	if c.dedup != nil {
+		c.dedup.storeLeaf(alias, logLeaf.LeafIdentityHash, resp.QueuedLeaf.Leaf.LeafValue)
+	}
+
+	return resp.QueuedLeaf.Leaf.LeafValue, nil
+}
+
+// parseSubmission extracts the credential and the submission options from an add-vc entry.
+// Entries which are not an AddVCEnvelope are treated as raw credentials.
+func parseSubmission(entry []byte) ([]byte, *AddVCOptions, error) {
+	var envelope AddVCEnvelope
+
+	if err := json.Unmarshal(entry, &envelope); err != nil || len(envelope.Credential) == 0 {
+		return entry, &AddVCOptions{}, nil
+	}
+
+	if envelope.Options == nil {
+		envelope.Options = &AddVCOptions{}
+	}
+
+	if err := envelope.Options.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("validate options: %w", err)
+	}
+
+	credential := []byte(envelope.Credential)
+
+	// JWT credentials are enveloped as JSON strings.
+	var jwt string
+	if err := json.Unmarshal(envelope.Credential, &jwt); err == nil {
+		credential = []byte(jwt)
+	}
+
+	return credential, envelope.Options, nil
 }
 
 // GetSTH retrieves the latest signed tree head.
@@ -351,45 +1104,82 @@ func (c *Cmd) GetSTH(w io.Writer, r io.Reader) error {
 		return fmt.Errorf("has permissions: %w", err)
 	}
 
-	req := trillian.GetLatestSignedLogRootRequest{LogId: c.logs[alias].ID}
-
-	resp, err := c.logs[alias].Client.GetLatestSignedLogRoot(context.Background(), &req)
+	sth, err := c.latestSTH(alias)
 	if err != nil {
-		return fmt.Errorf("get latest signed log root: %w", err)
+		return err
 	}
 
-	if resp.GetSignedLogRoot() == nil {
-		return fmt.Errorf("%w: no signed log root returned", errors.ErrInternal)
-	}
+	return json.NewEncoder(w).Encode(sth) // nolint: wrapcheck
+}
 
-	var root types.LogRootV1
-	if err = root.UnmarshalBinary(resp.SignedLogRoot.GetLogRoot()); err != nil {
-		return fmt.Errorf("unmarshal binary: %w", err)
+// latestSTH signs the latest tree head of the log.
+func (c *Cmd) latestSTH(alias string) (*GetSTHResponse, error) {
+	root, err := c.latestLogRoot(alias)
+	if err != nil {
+		return nil, err
 	}
 
+	return c.signedTreeHead(*root)
+}
+
+// signedTreeHead signs the tree head of the log root.
+func (c *Cmd) signedTreeHead(root types.LogRootV1) (*GetSTHResponse, error) {
 	ths, err := c.signV1TreeHead(root)
 	if err != nil {
-		return fmt.Errorf("sign tree head (v1): %w", err)
+		return nil, fmt.Errorf("sign tree head (v1): %w", err)
 	}
 
 	treeHeadSignature, err := json.Marshal(ths)
 	if err != nil {
-		return fmt.Errorf("marshal DigitallySigned payload: %w", err)
+		return nil, fmt.Errorf("marshal DigitallySigned payload: %w", err)
 	}
 
-	return json.NewEncoder(w).Encode(GetSTHResponse{ // nolint: wrapcheck
+	return &GetSTHResponse{
 		TreeSize:          root.TreeSize,
 		SHA256RootHash:    root.RootHash,
 		Timestamp:         root.TimestampNanos / uint64(time.Millisecond),
 		TreeHeadSignature: treeHeadSignature,
+	}, nil
+}
+
+// latestLogRoot returns the latest log root of the log.
+func (c *Cmd) latestLogRoot(alias string) (*types.LogRootV1, error) {
+	req := trillian.GetLatestSignedLogRootRequest{LogId: c.logs[alias].ID}
+
+	var resp *trillian.GetLatestSignedLogRootResponse
+
+	err := c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLatestSignedLogRoot(context.Background(), &req)
+
+		return resp, er
 	})
+	if err != nil {
+		return nil, fmt.Errorf("get latest signed log root: %w", err)
+	}
+
+	if resp.GetSignedLogRoot() == nil {
+		return nil, fmt.Errorf("%w: no signed log root returned", errors.ErrInternal)
+	}
+
+	var root types.LogRootV1
+	if err = root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return nil, fmt.Errorf("unmarshal binary: %w", err)
+	}
+
+	return &root, nil
 }
 
-// GetEntries retrieves entries from log.
+// GetEntries retrieves entries from log, up to 1000 entries: the response to a larger range has the index of the
+// next leaf to retrieve, the response to a time range has the indexes of the leaves of the range.
 func (c *Cmd) GetEntries(w io.Writer, r io.Reader) error { // nolint: funlen
 	const maxRange = 1000
 
-	var request *GetEntriesRequest
+	var (
+		request *GetEntriesRequest
+		result  GetEntriesResponse
+	)
 
 	if err := json.NewDecoder(r).Decode(&request); err != nil {
 		return fmt.Errorf("decode GetEntries request: %w", err)
@@ -403,8 +1193,25 @@ func (c *Cmd) GetEntries(w io.Writer, r io.Reader) error { // nolint: funlen
 		return fmt.Errorf("has permissions: %w", err)
 	}
 
+	if request.FromTime != nil || request.ToTime != nil {
+		start, end, err := c.timeRange(request.Alias, request.FromTime, request.ToTime)
+		if err != nil {
+			return fmt.Errorf("time range: %w", err)
+		}
+
+		if start > end {
+			return json.NewEncoder(w).Encode(GetEntriesResponse{Entries: []LeafEntry{}}) // nolint: wrapcheck
+		}
+
+		request.Start, request.End = start, end
+		result.Start, result.End = &start, &end
+	}
+
 	if request.End-request.Start+1 > maxRange {
 		request.End = request.Start + maxRange - 1
+
+		next := request.End + 1
+		result.Next = &next
 	}
 
 	req := trillian.GetLeavesByRangeRequest{
@@ -413,7 +1220,15 @@ func (c *Cmd) GetEntries(w io.Writer, r io.Reader) error { // nolint: funlen
 		Count:      request.End + 1 - request.Start,
 	}
 
-	resp, err := c.logs[request.Alias].Client.GetLeavesByRange(context.Background(), &req)
+	var resp *trillian.GetLeavesByRangeResponse
+
+	err := c.read(request.Alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLeavesByRange(context.Background(), &req)
+
+		return resp, er
+	})
 	if err != nil {
 		return fmt.Errorf("get leaves by range: %w", err)
 	}
@@ -446,13 +1261,28 @@ func (c *Cmd) GetEntries(w io.Writer, r io.Reader) error { // nolint: funlen
 	entries := make([]LeafEntry, len(resp.Leaves))
 
 	for i, leaf := range resp.Leaves {
+		extraData, err := c.decryptExtraData(leaf.ExtraData, leaf.LeafValue)
+		if err != nil {
+			return err
+		}
+
 		entries[i] = LeafEntry{
 			LeafInput: leaf.LeafValue,
-			ExtraData: leaf.ExtraData,
+			ExtraData: extraData,
 		}
 	}
 
-	return json.NewEncoder(w).Encode(GetEntriesResponse{Entries: entries}) // nolint: wrapcheck
+	if err := c.annotateEntries(request.Alias, request.Start, entries); err != nil {
+		return err
+	}
+
+	if request.PolicyTags != (PolicyTags{}) {
+		entries = filterEntries(entries, request.Start, request.PolicyTags)
+	}
+
+	result.Entries = entries
+
+	return json.NewEncoder(w).Encode(result) // nolint: wrapcheck
 }
 
 // GetEntryAndProof retrieves entry and merkle audit proof from log.
@@ -471,41 +1301,105 @@ func (c *Cmd) GetEntryAndProof(w io.Writer, r io.Reader) error {
 		return fmt.Errorf("has permissions: %w", err)
 	}
 
+	tracer := newProofTracer(request.Debug)
+
+	leaf, auditPath, err := c.entryAndProof(request, tracer)
+	if err != nil {
+		return err
+	}
+
+	extraData, err := c.decryptExtraData(leaf.ExtraData, leaf.LeafValue)
+	if err != nil {
+		return err
+	}
+
+	tracer.step(traceStepExtraData)
+
+	annotations, err := c.entryAnnotations(request.Alias, request.LeafIndex)
+	if err != nil {
+		return err
+	}
+
+	tracer.step(traceStepAnnotation)
+
+	response := &GetEntryAndProofResponse{
+		LeafInput:   leaf.LeafValue,
+		ExtraData:   extraData,
+		AuditPath:   auditPath,
+		Annotations: annotations,
+	}
+
+	return tracer.encode(w, response, func(trace *ProofTrace) { response.Debug = trace })
+}
+
+// entryAndProof returns the leaf and its audit path, the path is computed from the proof cache if it holds its
+// nodes and only the leaf is read from the log.
+func (c *Cmd) entryAndProof(request *GetEntryAndProofRequest,
+	tracer *proofTracer) (*trillian.LogLeaf, [][]byte, error) {
+	auditPath, ok := c.cachedInclusionProof(request.Alias, uint64(request.LeafIndex), uint64(request.TreeSize))
+
+	tracer.step(traceStepProofCache)
+	tracer.cacheHit(ok)
+
+	if ok {
+		leaf, err := c.cachedLeaf(request.Alias, request.LeafIndex)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tracer.step(traceStepLeaf)
+
+		c.proofs.putLeaf(request.Alias, uint64(request.LeafIndex), hasher.DefaultHasher.HashLeaf(leaf.LeafValue))
+
+		return leaf, auditPath, nil
+	}
+
 	req := trillian.GetEntryAndProofRequest{
 		LogId:     c.logs[request.Alias].ID,
 		LeafIndex: request.LeafIndex,
 		TreeSize:  request.TreeSize,
 	}
 
-	resp, err := c.logs[request.Alias].Client.GetEntryAndProof(context.Background(), &req)
+	var resp *trillian.GetEntryAndProofResponse
+
+	err := c.read(request.Alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetEntryAndProof(context.Background(), &req)
+
+		return resp, er
+	})
 	if err != nil {
-		return fmt.Errorf("get entry and proof: %w", err)
+		return nil, nil, fmt.Errorf("get entry and proof: %w", err)
 	}
 
+	tracer.step(traceStepTrillian)
+
 	var currentRoot types.LogRootV1
 	if err := currentRoot.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
-		return fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, resp.GetSignedLogRoot().GetLogRoot())
+		return nil, nil, fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal,
+			resp.GetSignedLogRoot().GetLogRoot())
 	}
 
 	if currentRoot.TreeSize < uint64(request.TreeSize) {
-		return fmt.Errorf("%w: need tree size: %d for proof, got: %d",
+		return nil, nil, fmt.Errorf("%w: need tree size: %d for proof, got: %d",
 			errors.ErrBadRequest, req.TreeSize, currentRoot.TreeSize,
 		)
 	}
 
 	if resp.Leaf == nil || len(resp.Leaf.LeafValue) == 0 || resp.Proof == nil {
-		return fmt.Errorf("%w: corrupted data received: %v", errors.ErrInternal, resp)
+		return nil, nil, fmt.Errorf("%w: corrupted data received: %s", errors.ErrInternal,
+			scrub.Leaf(resp.GetLeaf()))
 	}
 
 	if request.TreeSize > 1 && len(resp.Proof.Hashes) == 0 {
-		return fmt.Errorf("%w: no proof: %v", errors.ErrInternal, resp)
+		return nil, nil, fmt.Errorf("%w: no proof: %s", errors.ErrInternal, scrub.Leaf(resp.GetLeaf()))
 	}
 
-	return json.NewEncoder(w).Encode(GetEntryAndProofResponse{ // nolint: wrapcheck
-		LeafInput: resp.Leaf.LeafValue,
-		ExtraData: resp.Leaf.ExtraData,
-		AuditPath: resp.Proof.Hashes,
-	})
+	c.cacheInclusionProof(request.Alias, &currentRoot, uint64(request.LeafIndex), uint64(request.TreeSize),
+		hasher.DefaultHasher.HashLeaf(resp.Leaf.LeafValue), resp.Proof.Hashes)
+
+	return resp.Leaf, resp.Proof.Hashes, nil
 }
 
 // GetProofByHash retrieves Merkle Audit proof from Log by leaf hash.
@@ -529,6 +1423,25 @@ func (c *Cmd) GetProofByHash(w io.Writer, r io.Reader) error {
 		return errors.NewBadRequestError(fmt.Errorf("invalid base64 hash: %w", err))
 	}
 
+	if len(leafHash) != sha256.Size {
+		return fmt.Errorf("%w: hash must be %d bytes, got %d", errors.ErrValidation, sha256.Size, len(leafHash))
+	}
+
+	tracer := newProofTracer(request.Debug)
+
+	if index, ok := c.proofs.leafIndexIn(request.Alias, leafHash, uint64(request.TreeSize)); ok {
+		if auditPath, ok := c.cachedInclusionProof(request.Alias, index, uint64(request.TreeSize)); ok {
+			tracer.step(traceStepProofCache)
+			tracer.cacheHit(true)
+
+			response := &GetProofByHashResponse{LeafIndex: int64(index), AuditPath: auditPath}
+
+			return tracer.encode(w, response, func(trace *ProofTrace) { response.Debug = trace })
+		}
+	}
+
+	tracer.step(traceStepProofCache)
+
 	req := trillian.GetInclusionProofByHashRequest{
 		LogId:           c.logs[request.Alias].ID,
 		LeafHash:        leafHash,
@@ -536,11 +1449,21 @@ func (c *Cmd) GetProofByHash(w io.Writer, r io.Reader) error {
 		OrderBySequence: true,
 	}
 
-	resp, err := c.logs[request.Alias].Client.GetInclusionProofByHash(context.Background(), &req)
+	var resp *trillian.GetInclusionProofByHashResponse
+
+	err = c.read(request.Alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetInclusionProofByHash(context.Background(), &req)
+
+		return resp, er
+	})
 	if err != nil {
 		return fmt.Errorf("get leaves by range: %w", err)
 	}
 
+	tracer.step(traceStepTrillian)
+
 	var currentRoot types.LogRootV1
 	if err := currentRoot.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
 		return fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, resp.GetSignedLogRoot().GetLogRoot())
@@ -556,10 +1479,12 @@ func (c *Cmd) GetProofByHash(w io.Writer, r io.Reader) error {
 		return fmt.Errorf("%w: no proof", errors.ErrNotFound)
 	}
 
-	return json.NewEncoder(w).Encode(GetProofByHashResponse{ // nolint: wrapcheck
-		LeafIndex: resp.Proof[0].LeafIndex,
-		AuditPath: resp.Proof[0].Hashes,
-	})
+	c.cacheInclusionProof(request.Alias, &currentRoot, uint64(resp.Proof[0].LeafIndex), uint64(request.TreeSize),
+		leafHash, resp.Proof[0].Hashes)
+
+	response := &GetProofByHashResponse{LeafIndex: resp.Proof[0].LeafIndex, AuditPath: resp.Proof[0].Hashes}
+
+	return tracer.encode(w, response, func(trace *ProofTrace) { response.Debug = trace })
 }
 
 // GetSTHConsistency retrieves merkle consistency proofs between signed tree heads.
@@ -585,17 +1510,41 @@ func (c *Cmd) GetSTHConsistency(w io.Writer, r io.Reader) error {
 		return json.NewEncoder(w).Encode(GetSTHConsistencyResponse{}) // nolint: wrapcheck
 	}
 
+	tracer := newProofTracer(request.Debug)
+
+	proof, ok := c.cachedConsistencyProof(request.Alias, uint64(request.FirstTreeSize),
+		uint64(request.SecondTreeSize))
+
+	tracer.step(traceStepProofCache)
+	tracer.cacheHit(ok)
+
+	if ok {
+		response := &GetSTHConsistencyResponse{Consistency: proof}
+
+		return tracer.encode(w, response, func(trace *ProofTrace) { response.Debug = trace })
+	}
+
 	req := trillian.GetConsistencyProofRequest{
 		LogId:          c.logs[request.Alias].ID,
 		FirstTreeSize:  request.FirstTreeSize,
 		SecondTreeSize: request.SecondTreeSize,
 	}
 
-	resp, err := c.logs[request.Alias].Client.GetConsistencyProof(context.Background(), &req)
+	var resp *trillian.GetConsistencyProofResponse
+
+	err := c.read(request.Alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetConsistencyProof(context.Background(), &req)
+
+		return resp, er
+	})
 	if err != nil {
 		return fmt.Errorf("get consistency proof: %w", err)
 	}
 
+	tracer.step(traceStepTrillian)
+
 	var root types.LogRootV1
 	if err := root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
 		return fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, resp.GetSignedLogRoot().GetLogRoot())
@@ -607,9 +1556,12 @@ func (c *Cmd) GetSTHConsistency(w io.Writer, r io.Reader) error {
 		)
 	}
 
-	return json.NewEncoder(w).Encode(GetSTHConsistencyResponse{ // nolint: wrapcheck
-		Consistency: resp.Proof.GetHashes(),
-	})
+	c.cacheConsistencyProof(request.Alias, &root, uint64(request.FirstTreeSize), uint64(request.SecondTreeSize),
+		resp.Proof.GetHashes())
+
+	response := &GetSTHConsistencyResponse{Consistency: resp.Proof.GetHashes()}
+
+	return tracer.encode(w, response, func(trace *ProofTrace) { response.Debug = trace })
 }
 
 // CreateVCTimestampSignature creates VCTimestampSignature structure.
@@ -624,13 +1576,11 @@ func CreateVCTimestampSignature(leaf *MerkleTreeLeaf) *VCTimestampSignature {
 	}
 }
 
-func (c *Cmd) signV1VCTS(leaf *MerkleTreeLeaf) (DigitallySigned, error) {
-	data, err := json.Marshal(CreateVCTimestampSignature(leaf))
-	if err != nil {
-		return DigitallySigned{}, fmt.Errorf("marshal VCTimestampSignature: %w", err)
-	}
+func (c *Cmd) signV1VCTS(leaf *MerkleTreeLeaf, extensions []SCTExtension) (DigitallySigned, error) {
+	statement := CreateVCTimestampSignature(leaf)
+	statement.SCTExtensions = extensions
 
-	signature, err := c.crypto.Sign(data, c.kh)
+	signature, err := c.signBytes(SCTSignature, SigningBytesSCT(*statement))
 	if err != nil {
 		return DigitallySigned{}, fmt.Errorf("sign TreeHeadSignature: %w", err)
 	}
@@ -642,18 +1592,15 @@ func (c *Cmd) signV1VCTS(leaf *MerkleTreeLeaf) (DigitallySigned, error) {
 }
 
 func (c *Cmd) signV1TreeHead(root types.LogRootV1) (DigitallySigned, error) {
-	sthBytes, err := json.Marshal(TreeHeadSignature{
+	sthBytes := SigningBytesTreeHead(TreeHeadSignature{
 		Version:        V1,
 		SignatureType:  TreeHeadSignatureType,
 		Timestamp:      root.TimestampNanos / uint64(time.Millisecond),
 		TreeSize:       root.TreeSize,
 		SHA256RootHash: root.RootHash,
 	})
-	if err != nil {
-		return DigitallySigned{}, fmt.Errorf("marshal TreeHeadSignature: %w", err)
-	}
 
-	signature, err := c.crypto.Sign(sthBytes, c.kh)
+	signature, err := c.signBytes(STHSignature, sthBytes)
 	if err != nil {
 		return DigitallySigned{}, fmt.Errorf("sign TreeHeadSignature: %w", err)
 	}
@@ -664,7 +1611,67 @@ func (c *Cmd) signV1TreeHead(root types.LogRootV1) (DigitallySigned, error) {
 	}, nil
 }
 
-func signatureAndHashAlgorithmByKeyType(keyType kms.KeyType) (*SignatureAndHashAlgorithm, error) {
+func (c *Cmd) sign(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	signature, err := c.signBytes(StatementSignature, data)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	return json.Marshal(DigitallySigned{ // nolint: wrapcheck
+		Algorithm: *c.alg,
+		Signature: signature,
+	})
+}
+
+// signBytes signs the data with the key of the log and counts the signature.
+func (c *Cmd) signBytes(kind SignatureKind, data []byte) ([]byte, error) {
+	return c.signBytesWith(c.kh, kind, data)
+}
+
+// signBytesWith signs the data with the key handle and counts the signature of the kind.
+func (c *Cmd) signBytesWith(kh interface{}, kind SignatureKind, data []byte) ([]byte, error) {
+	signature, err := c.crypto.Sign(data, kh)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	c.keyUsage.record(kind)
+
+	return signature, nil
+}
+
+// VerifySignature verifies the DigitallySigned signature of the JSON-marshalled statement.
+func VerifySignature(signature, pubKey []byte, statement interface{}) error {
+	var sig *DigitallySigned
+
+	if err := json.Unmarshal(signature, &sig); err != nil {
+		return fmt.Errorf("unmarshal signature: %w", err)
+	}
+
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return fmt.Errorf("marshal statement: %w", err)
+	}
+
+	kh, err := (&localkms.LocalKMS{}).PubKeyBytesToHandle(pubKey, sig.Algorithm.Type)
+	if err != nil {
+		return fmt.Errorf("pub key to handle: %w", err)
+	}
+
+	if err = (&tinkcrypto.Crypto{}).Verify(sig.Signature, data, kh); err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+
+	return nil
+}
+
+// SignatureAndHashAlgorithmByKeyType returns the algorithm of the DigitallySigned signatures of the key type.
+func SignatureAndHashAlgorithmByKeyType(keyType kms.KeyType) (*SignatureAndHashAlgorithm, error) {
 	switch {
 	case keyType == kms.ECDSAP256DER || keyType == kms.ECDSAP256IEEEP1363 ||
 		keyType == kms.ECDSAP384DER || keyType == kms.ECDSAP384IEEEP1363 ||
@@ -11,17 +11,39 @@ package command_test
 
 import (
 	"bytes"
+	"context"
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
 	_ "embed"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/trillian/types"
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jwt"
+	ldprocessor "github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
@@ -29,14 +51,25 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
 	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
 	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
 	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/multiformats/go-multibase"
 	jsonld "github.com/piprate/json-gold/ld"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/trustbloc/vct/internal/pkg/cbor"
 	"github.com/trustbloc/vct/internal/pkg/ldcontext"
+	"github.com/trustbloc/vct/pkg/client/vct"
 	. "github.com/trustbloc/vct/pkg/controller/command"
 	"github.com/trustbloc/vct/pkg/controller/errors"
+	"github.com/trustbloc/vct/pkg/merklelog"
+	"github.com/trustbloc/vct/pkg/webhook"
 )
 
 // nolint: gochecknoglobals
@@ -45,6 +78,8 @@ var (
 	verifiableCredential []byte
 	//go:embed testdata/queuedLeafValue.json
 	queuedLeafValue []byte
+	//go:embed testdata/signing_vectors.json
+	signingVectors []byte
 
 	logRoot = []byte{
 		0, 1, 0, 0, 0, 0, 0, 0, 0, 1, 32, 182, 56, 230, 122, 160, 166, 224, 172, 78, 222,
@@ -176,6 +211,75 @@ func TestCmd_GetIssuers(t *testing.T) {
 		require.Equal(t, `["issuer_a","issuer_b"]`+"\n", fr.String())
 	})
 
+	t.Run("Pages", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), kms.ECDSAP256TypeIEEEP1363, nil)
+
+		cmd, err := New(&Config{
+			KMS: km, Key: Key{
+				ID: kid,
+			},
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Issuers:    []string{"issuer_a", "issuer_b", "issuer_c"},
+			}, {
+				Alias:      "other",
+				Permission: "r",
+				Issuers:    []string{"issuer_a", "issuer_b", "issuer_c"},
+			}},
+		}, nil)
+		require.NoError(t, err)
+
+		getIssuers := func(request GetIssuersRequest) (*GetIssuersResponse, error) {
+			src, err := json.Marshal(request)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+
+			if err = cmd.GetIssuers(&buf, bytes.NewBuffer(src)); err != nil {
+				return nil, err
+			}
+
+			var resp *GetIssuersResponse
+
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+			return resp, nil
+		}
+
+		resp, err := getIssuers(GetIssuersRequest{Alias: alias, PageRequest: PageRequest{PageSize: 2}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"issuer_a", "issuer_b"}, resp.Issuers)
+		require.NotEmpty(t, resp.NextPageToken)
+
+		next := resp.NextPageToken
+
+		resp, err = getIssuers(GetIssuersRequest{Alias: alias, PageRequest: PageRequest{PageSize: 2, PageToken: next}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"issuer_c"}, resp.Issuers)
+		require.Empty(t, resp.NextPageToken)
+
+		// the default page size
+		resp, err = getIssuers(GetIssuersRequest{Alias: alias, PageRequest: PageRequest{PageToken: next}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"issuer_c"}, resp.Issuers)
+
+		// the token is bound to the list
+		_, err = getIssuers(GetIssuersRequest{Alias: "other", PageRequest: PageRequest{PageToken: next}})
+		require.EqualError(t, err, "validation failed: page_token is not valid")
+
+		_, err = getIssuers(GetIssuersRequest{Alias: alias, PageRequest: PageRequest{PageToken: "!"}})
+		require.EqualError(t, err, "validation failed: page_token is not valid")
+
+		_, err = getIssuers(GetIssuersRequest{Alias: alias, PageRequest: PageRequest{PageSize: MaxPageSize + 1}})
+		require.EqualError(t, err, "validation failed: page_size 1001 is not within [1,1000]")
+	})
+
 	t.Run("Action forbidden", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -269,8 +373,17 @@ func TestCmd_Webfinger(t *testing.T) {
 	require.Equal(t, fr.String(), hr.String())
 
 	exp := `{"subject":"https://vct.com/maple2021",` +
-		`"properties":{"https://trustbloc.dev/ns/ledger-type":"vct-v1",` +
-		`"https://trustbloc.dev/ns/public-key":"cHVibGljIGtleQ=="},` +
+		`"properties":{"https://trustbloc.dev/ns/leaf-types":[` +
+		`{"entry_type":100,"name":"vc","submittable":false},` +
+		`{"entry_type":101,"name":"revocation","submittable":true},` +
+		`{"entry_type":102,"name":"sth-anchor","submittable":true},` +
+		`{"entry_type":103,"name":"commitment","submittable":true},` +
+		`{"entry_type":104,"name":"mdoc","submittable":true},` +
+		`{"entry_type":105,"name":"anoncreds-commitment","submittable":true}],` +
+		`"https://trustbloc.dev/ns/ledger-type":"vct-v1",` +
+		`"https://trustbloc.dev/ns/log-id":"9WmobTwsjX3aJrXb6iC9XBnus138Y/23JLrE8hwieFA=",` +
+		`"https://trustbloc.dev/ns/public-key":"cHVibGljIGtleQ==",` +
+		`"https://trustbloc.dev/ns/tiles":"https://vct.com/maple2021"},` +
 		`"links":[{"rel":"self","href":"https://vct.com/maple2021"}]}` + "\n"
 
 	require.Equal(t, exp, fr.String())
@@ -547,791 +660,1133 @@ func TestCmd_GetEntries(t *testing.T) {
 	})
 }
 
-func TestCmd_GetProofByHash(t *testing.T) {
+func TestCmd_GetEntries_TimeRange(t *testing.T) {
 	const (
-		kid     = "kid"
-		keyType = kms.ECDSAP256TypeIEEEP1363
+		kid      = "kid"
+		keyType  = kms.ECDSAP256TypeIEEEP1363
+		treeSize = 1500
 	)
 
-	t.Run("Success", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+	base := time.Date(2021, time.October, 12, 0, 0, 0, 0, time.UTC)
+
+	root, err := (&types.LogRootV1{TreeSize: treeSize}).MarshalBinary()
+	require.NoError(t, err)
+
+	var lookups int
+
+	newCmd := func(t *testing.T, ctrl *gomock.Controller, store ...TimeIndexStore) *Cmd {
+		t.Helper()
 
 		km := NewMockKeyManager(ctrl)
 		km.EXPECT().Get(kid).Return(nil, nil)
 		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
 
+		// leaf i is integrated at base + i hours
 		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetInclusionProofByHash(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetInclusionProofByHashResponse{
-				Proof:         []*trillian.Proof{{Hashes: [][]byte{{0, 1, 2}}}},
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
 			}, nil,
-		).Times(2)
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+				if req.Count == 1 {
+					lookups++
+				}
+
+				var leaves []*trillian.LogLeaf
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count && i < treeSize; i++ {
+					leaves = append(leaves, &trillian.LogLeaf{
+						LeafIndex:          i,
+						LeafValue:          []byte(fmt.Sprint(i)),
+						IntegrateTimestamp: timestamppb.New(base.Add(time.Duration(i) * time.Hour)),
+					})
+				}
+
+				return &trillian.GetLeavesByRangeResponse{
+					Leaves:        leaves,
+					SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+				}, nil
+			},
+		).AnyTimes()
 
-		cmd, err := New(&Config{
+		cfg := &Config{
 			KMS: km,
+			Key: Key{ID: kid},
 			Logs: []Log{{
 				Alias:      alias,
 				Permission: "r",
 				Client:     client,
 			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
+		}
+
+		if len(store) > 0 {
+			cfg.TimeIndexStore = store[0]
+		}
+
+		cmd, err := New(cfg, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		fr, frs := bytes.Buffer{}, GetProofByHashResponse{}
+		return cmd
+	}
 
-		require.NoError(t, cmd.GetProofByHash(&fr,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`)))
-		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+	getResponse := func(t *testing.T, cmd *Cmd, req GetEntriesRequest) *GetEntriesResponse {
+		t.Helper()
 
-		hr, hrs := bytes.Buffer{}, GetProofByHashResponse{}
+		src, err := json.Marshal(req)
+		require.NoError(t, err)
 
-		require.NoError(t, lookupHandler(t, cmd, GetProofByHash)(&hr,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`)))
-		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+		var buf bytes.Buffer
 
-		require.Equal(t, frs.AuditPath, hrs.AuditPath)
-		require.Equal(t, frs.LeafIndex, hrs.LeafIndex)
-		require.NotEmpty(t, frs.AuditPath)
-		require.NotEmpty(t, hrs.AuditPath)
+		require.NoError(t, cmd.GetEntries(&buf, bytes.NewBuffer(src)))
+
+		var resp *GetEntriesResponse
+
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp
+	}
+
+	getEntries := func(t *testing.T, cmd *Cmd, req GetEntriesRequest) []string {
+		t.Helper()
+
+		resp := getResponse(t, cmd, req)
+
+		var values []string
+		for _, entry := range resp.Entries {
+			values = append(values, string(entry.LeafInput))
+		}
+
+		return values
+	}
+
+	at := func(d time.Duration) *time.Time {
+		ts := base.Add(d)
+
+		return &ts
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, ctrl)
+
+		require.Equal(t, []string{"3", "4", "5"}, getEntries(t, cmd, GetEntriesRequest{
+			Alias: alias, FromTime: at(150 * time.Minute), ToTime: at(5 * time.Hour),
+		}))
+		require.Equal(t, []string{"1498", "1499"}, getEntries(t, cmd, GetEntriesRequest{
+			Alias: alias, FromTime: at(1498 * time.Hour),
+		}))
+		require.Equal(t, []string{"0", "1"}, getEntries(t, cmd, GetEntriesRequest{
+			Alias: alias, ToTime: at(90 * time.Minute),
+		}))
 	})
 
-	t.Run("Decode error", func(t *testing.T) {
+	t.Run("Paged", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd := newCmd(t, ctrl)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		resp := getResponse(t, cmd, GetEntriesRequest{Alias: alias, FromTime: at(150 * time.Minute)})
+		require.Len(t, resp.Entries, 1000)
+		require.Equal(t, "3", string(resp.Entries[0].LeafInput))
+		require.Equal(t, int64(3), *resp.Start)
+		require.Equal(t, int64(1499), *resp.End)
+		require.Equal(t, int64(1003), *resp.Next)
 
-		const expErr = "decode GetProofByHash request: EOF"
+		// the remaining entries of the range are retrieved from the next index
+		resp = getResponse(t, cmd, GetEntriesRequest{Alias: alias, Start: *resp.Next, End: *resp.End})
+		require.Len(t, resp.Entries, 497)
+		require.Equal(t, "1003", string(resp.Entries[0].LeafInput))
+		require.Nil(t, resp.Start)
+		require.Nil(t, resp.Next)
 
-		require.EqualError(t, cmd.GetProofByHash(nil, bytes.NewBuffer(nil)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetProofByHash)(nil, bytes.NewBuffer(nil)), expErr)
+		resp = getResponse(t, cmd, GetEntriesRequest{
+			Alias: alias, FromTime: at(150 * time.Minute), ToTime: at(5 * time.Hour),
+		})
+		require.Equal(t, int64(3), *resp.Start)
+		require.Equal(t, int64(5), *resp.End)
+		require.Nil(t, resp.Next)
 	})
 
-	t.Run("Validation error", func(t *testing.T) {
+	t.Run("No entries", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd := newCmd(t, ctrl)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
+		require.Empty(t, getEntries(t, cmd, GetEntriesRequest{Alias: alias, FromTime: at(2000 * time.Hour)}))
+		require.Empty(t, getEntries(t, cmd, GetEntriesRequest{Alias: alias, ToTime: at(-time.Hour)}))
+		require.Empty(t, getEntries(t, cmd, GetEntriesRequest{
+			Alias: alias, FromTime: at(70 * time.Minute), ToTime: at(80 * time.Minute),
+		}))
+	})
+
+	t.Run("Stored timestamps", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store, err := mem.NewProvider().OpenStore("timeindex")
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		const expErr = "validate GetProofByHash request: validation failed: tree_size value must be greater than zero"
+		req := GetEntriesRequest{Alias: alias, FromTime: at(150 * time.Minute), ToTime: at(5 * time.Hour)}
 
-		require.EqualError(t, cmd.GetProofByHash(nil,
-			bytes.NewBufferString(`{}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetProofByHash)(nil,
-			bytes.NewBufferString(`{}`),
-		), expErr)
+		lookups = 0
+		require.Equal(t, []string{"3", "4", "5"}, getEntries(t, newCmd(t, ctrl, store), req))
+		require.NotZero(t, lookups)
+
+		// the timestamps looked up by the first command are read from the store
+		lookups = 0
+		require.Equal(t, []string{"3", "4", "5"}, getEntries(t, newCmd(t, ctrl, store), req))
+		require.Zero(t, lookups)
 	})
 
-	t.Run("Unmarshal binary error", func(t *testing.T) {
+	t.Run("Invalid time range", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetInclusionProofByHash(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetInclusionProofByHashResponse{
-				Proof:         []*trillian.Proof{{Hashes: [][]byte{{0}}}},
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: []byte{0}},
-			}, nil,
-		).Times(2)
-
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
+		src, err := json.Marshal(GetEntriesRequest{Alias: alias, FromTime: at(time.Hour), ToTime: at(0)})
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
-
-		const expErr = "internal error: unmarshal binary: [0]"
 
-		require.EqualError(t, cmd.GetProofByHash(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetProofByHash)(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`),
-		), expErr)
+		err = newCmd(t, ctrl).GetEntries(&bytes.Buffer{}, bytes.NewBuffer(src))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not a valid range")
 	})
 }
 
-func TestCmd_GetSTHConsistency(t *testing.T) {
+func TestCmd_GetProofOfAbsence(t *testing.T) {
 	const (
-		kid     = "kid"
-		keyType = kms.ECDSAP256TypeIEEEP1363
+		keyType  = kms.ECDSAP256TypeIEEEP1363
+		treeSize = 1500
 	)
 
-	t.Run("Success", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+	root, err := (&types.LogRootV1{TreeSize: treeSize}).MarshalBinary()
+	require.NoError(t, err)
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+	newCmd := func(t *testing.T, client TrillianLogClient) *Cmd {
+		t.Helper()
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetConsistencyProof(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetConsistencyProofResponse{
-				Proof:         &trillian.Proof{Hashes: [][]byte{{0, 1, 2}}},
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
-			}, nil,
-		).Times(2)
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
 
 		cmd, err := New(&Config{
-			KMS: km,
+			KMS:    km,
+			Crypto: cr,
 			Logs: []Log{{
 				Alias:      alias,
 				Permission: "r",
 				Client:     client,
 			}},
-			Key: Key{
-				ID: kid,
-			},
+			Key: Key{ID: newKID},
 		}, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		fr, frs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+		return cmd
+	}
 
-		require.NoError(t, cmd.GetSTHConsistency(&fr,
-			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
-		))
-		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+	getProofOfAbsence := func(cmd *Cmd, issuer, credentialID string) (*GetProofOfAbsenceResponse, error) {
+		src, err := json.Marshal(GetProofOfAbsenceRequest{Alias: alias, Issuer: issuer, CredentialID: credentialID})
+		require.NoError(t, err)
 
-		hr, hrs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+		var buf bytes.Buffer
 
-		require.NoError(t, lookupHandler(t, cmd, GetSTHConsistency)(&hr,
-			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
-		))
-		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+		if err = cmd.GetProofOfAbsence(&buf, bytes.NewBuffer(src)); err != nil {
+			return nil, err
+		}
 
-		require.Equal(t, frs.Consistency, hrs.Consistency)
-		require.NotEmpty(t, frs.Consistency)
-	})
+		var resp *GetProofOfAbsenceResponse
 
-	t.Run("Success (empty)", func(t *testing.T) {
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+			}, nil,
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+				var leaves []*trillian.LogLeaf
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+					leaf, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: &TimestampedEntry{
+						EntryType: VCLogEntryType,
+						VCEntry:   []byte(fmt.Sprintf(`{"id":"urn:credential:%d","issuer":"did:example:issuer"}`, i)),
+					}})
+					require.NoError(t, err)
+
+					leaves = append(leaves, &trillian.LogLeaf{
+						LeafIndex:      i,
+						LeafValue:      leaf,
+						MerkleLeafHash: []byte(fmt.Sprint(i)),
+					})
+				}
+
+				return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
 			},
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-			}},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		).Times(2)
 
-		fr, frs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+		cmd := newCmd(t, client)
 
-		require.NoError(t, cmd.GetSTHConsistency(&fr,
-			bytes.NewBufferString(`{"alias":"maple2021"}`),
-		))
-		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+		resp, err := getProofOfAbsence(cmd, "did:example:issuer", "urn:credential:absent")
+		require.NoError(t, err)
+		require.Equal(t, uint64(treeSize), resp.TreeSize)
+		require.NoError(t, vct.VerifyProofOfAbsence(resp, cmd.PubKey))
 
-		hr, hrs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+		// a tampered statement is rejected
+		resp.TreeSize--
+		require.Error(t, vct.VerifyProofOfAbsence(resp, cmd.PubKey))
 
-		require.NoError(t, lookupHandler(t, cmd, GetSTHConsistency)(&hr,
-			bytes.NewBufferString(`{"alias":"maple2021"}`),
-		))
-		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+		// leaves are indexed once
+		_, err = getProofOfAbsence(cmd, "did:example:issuer", "urn:credential:7")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `credential "urn:credential:7" of did:example:issuer is logged`)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
 
-		require.Equal(t, frs.Consistency, hrs.Consistency)
-		require.Empty(t, frs.Consistency)
+		// the credential IDs are scoped by the issuer
+		resp, err = getProofOfAbsence(cmd, "did:example:other", "urn:credential:7")
+		require.NoError(t, err)
+		require.Equal(t, "did:example:other", resp.Issuer)
+		require.NoError(t, vct.VerifyProofOfAbsence(resp, cmd.PubKey))
+
+		// the statement is bound to the issuer
+		resp.Issuer = "did:example:issuer"
+		require.Error(t, vct.VerifyProofOfAbsence(resp, cmd.PubKey))
 	})
 
-	t.Run("Decode error", func(t *testing.T) {
+	t.Run("Built in the background", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		var cmd *Cmd
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+			}, nil,
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+				// the requests are rejected while the index is built, the leaves are fetched with the index unlocked
+				_, err := getProofOfAbsence(cmd, "did:example:issuer", "urn:credential:absent")
+				require.Error(t, err)
+				require.Contains(t, err.Error(), fmt.Sprintf("credential index of log %s is being built, %d leaves "+
+					"indexed", alias, req.StartIndex))
+				require.Equal(t, http.StatusServiceUnavailable, errors.StatusCodeFromError(err))
+
+				leaves := make([]*trillian.LogLeaf, 0, req.Count)
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+					leaves = append(leaves, &trillian.LogLeaf{LeafIndex: i, MerkleLeafHash: []byte(fmt.Sprint(i))})
+				}
+
+				return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
 			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		).Times(2)
 
-		const expErr = "decode GetSTHConsistency request: EOF"
+		cmd = newCmd(t, client)
 
-		require.EqualError(t, cmd.GetSTHConsistency(nil, bytes.NewBuffer(nil)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil, bytes.NewBuffer(nil)), expErr)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		cmd.BuildCredentialIndexes(ctx)
+
+		// the leaves are not fetched again once the index is built
+		resp, err := getProofOfAbsence(cmd, "did:example:issuer", "urn:credential:absent")
+		require.NoError(t, err)
+		require.Equal(t, uint64(treeSize), resp.TreeSize)
 	})
 
-	t.Run("Validation error", func(t *testing.T) {
+	t.Run("No credential ID", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
-
-		const expErr = "validate GetSTHConsistency request: validation failed: first_tree_size -1 and second_tree_size 0 values must be >= 0" // nolint: lll
+		_, err := getProofOfAbsence(newCmd(t, NewMockTrillianLogClient(ctrl)), "did:example:issuer", "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "credential_id is empty")
 
-		require.EqualError(t, cmd.GetSTHConsistency(nil,
-			bytes.NewBufferString(`{"first_tree_size": -1}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil,
-			bytes.NewBufferString(`{"first_tree_size": -1}`),
-		), expErr)
+		_, err = getProofOfAbsence(newCmd(t, NewMockTrillianLogClient(ctrl)), "", "urn:credential:absent")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "issuer is empty")
 	})
 
-	t.Run("Unmarshal binary error", func(t *testing.T) {
+	t.Run("Get leaves by range (error)", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
 		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetConsistencyProof(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetConsistencyProofResponse{
-				Proof:         &trillian.Proof{Hashes: [][]byte{{0, 1, 2}}},
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: []byte{0}},
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
 			}, nil,
-		).Times(2)
+		)
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("error"))
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		_, err := getProofOfAbsence(newCmd(t, client), "did:example:issuer", "urn:credential:absent")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "get leaves by range: error")
+	})
+}
 
-		const expErr = "internal error: unmarshal binary: [0]"
+func TestCmd_GetCredentialStatus(t *testing.T) {
+	const (
+		keyType  = kms.ECDSAP256TypeIEEEP1363
+		treeSize = 30
+	)
 
-		require.EqualError(t, cmd.GetSTHConsistency(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
-		), expErr)
+	root, err := (&types.LogRootV1{TreeSize: treeSize}).MarshalBinary()
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// leaf i logs credential i%10 of the issuer (i%10)%2, so the latest entry of credential n is leaf 20+n
+	issuers := []string{"did:example:issuer", "did:example:other"}
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+		&trillian.GetLatestSignedLogRootResponse{
+			SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+		}, nil,
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			var leaves []*trillian.LogLeaf
+
+			for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+				leaf, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: &TimestampedEntry{
+					EntryType: VCLogEntryType,
+					VCEntry:   []byte(fmt.Sprintf(`{"id":"urn:credential:%d","issuer":%q}`, i%10, issuers[i%10%2])),
+				}})
+				require.NoError(t, err)
+
+				leaves = append(leaves, &trillian.LogLeaf{
+					LeafIndex:      i,
+					LeafValue:      leaf,
+					MerkleLeafHash: []byte(fmt.Sprint(i)),
+				})
+			}
+
+			return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
+		},
+	)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "r",
+			Client:     client,
+		}},
+		Key: Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	t.Run("Map root", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		require.NoError(t, lookupHandler(t, cmd, GetMapRoot)(&buf, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+
+		var resp *GetMapRootResponse
+
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Equal(t, uint64(treeSize), resp.TreeSize)
+		require.NoError(t, vct.VerifyMapRootSignature(resp, cmd.PubKey))
+
+		resp.SHA256MapRootHash = []byte("root")
+		require.Error(t, vct.VerifyMapRootSignature(resp, cmd.PubKey))
 	})
 
-	t.Run("Alias maple2021 is not supported", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+	t.Run("Latest entry", func(t *testing.T) {
+		var buf bytes.Buffer
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		require.NoError(t, cmd.GetCredentialStatus(&buf, bytes.NewBufferString(
+			fmt.Sprintf(`{"alias":%q,"issuer":"did:example:other","credential_id":"urn:credential:3"}`, alias),
+		)))
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		var resp *GetCredentialStatusResponse
 
-		const expErr = "has permissions: alias \"maple2021\" is not supported"
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Equal(t, int64(23), resp.Entry.LeafIndex)
+		require.Equal(t, []byte("23"), resp.Entry.MerkleLeafHash)
+		require.NoError(t, vct.VerifyCredentialStatus(resp, cmd.PubKey))
 
-		require.EqualError(t, cmd.GetSTHConsistency(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
-		), expErr)
+		// an older entry does not match the map
+		resp.Entry = CredentialIndexEntry{LeafIndex: 13, MerkleLeafHash: []byte("13")}
+		require.Error(t, vct.VerifyCredentialStatus(resp, cmd.PubKey))
+	})
+
+	t.Run("Not logged", func(t *testing.T) {
+		err := cmd.GetCredentialStatus(&bytes.Buffer{}, bytes.NewBufferString(
+			fmt.Sprintf(`{"alias":%q,"issuer":"did:example:issuer","credential_id":"urn:credential:absent"}`, alias),
+		))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+		// the credential is logged by another issuer
+		err = cmd.GetCredentialStatus(&bytes.Buffer{}, bytes.NewBufferString(
+			fmt.Sprintf(`{"alias":%q,"issuer":"did:example:issuer","credential_id":"urn:credential:3"}`, alias),
+		))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `credential "urn:credential:3" of did:example:issuer is not logged`)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("No credential ID", func(t *testing.T) {
+		err := cmd.GetCredentialStatus(&bytes.Buffer{}, bytes.NewBufferString(
+			fmt.Sprintf(`{"alias":%q,"issuer":"did:example:issuer"}`, alias),
+		))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "credential_id is empty")
+
+		err = cmd.GetCredentialStatus(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf(`{"alias":%q}`, alias)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "issuer is empty")
 	})
 }
 
-func TestCmd_GetSTH(t *testing.T) {
-	const (
-		kid           = "kid"
-		logID   int64 = 123
-		keyType       = kms.ECDSAP256TypeIEEEP1363
-	)
+func TestCmd_AddRevocation(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
 
-	t.Run("Success", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+	leafHash := func(i int) []byte {
+		h := sha256.Sum256([]byte(fmt.Sprint(i)))
 
-		km, cr := createKMSAndCrypto(t)
-		newKID, _, err := km.Create(keyType)
+		return h[:]
+	}
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	issuer, keyID := fingerprint.CreateDIDKey(pubKey)
+
+	otherPubKey, otherPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherIssuer, otherKeyID := fingerprint.CreateDIDKey(otherPubKey)
+
+	// signed returns the event signed by the issuer with the claims
+	signed := func(t *testing.T, event RevocationEvent, claims RevocationClaims, privKey ed25519.PrivateKey,
+		keyID string) RevocationEvent {
+		t.Helper()
+
+		token, err := jwt.NewSigned(claims, jose.Headers{jose.HeaderKeyID: keyID}, ed25519Signer(privKey))
+		require.NoError(t, err)
+
+		event.JWS, err = token.Serialize(false)
+		require.NoError(t, err)
+
+		return event
+	}
+
+	// signedByIssuer returns the event signed by the issuer of the logged credentials
+	signedByIssuer := func(t *testing.T, event RevocationEvent) RevocationEvent {
+		t.Helper()
+
+		return signed(t, event, RevocationClaims{
+			Issuer:   issuer,
+			LeafHash: event.LeafHash,
+			Status:   event.Status,
+			Reason:   event.Reason,
+		}, privKey, keyID)
+	}
+
+	vcLeaf := func(t *testing.T, id string) []byte {
+		t.Helper()
+
+		leaf, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: &TimestampedEntry{
+			EntryType: VCLogEntryType,
+			VCEntry:   []byte(fmt.Sprintf(`{"id":%q,"issuer":%q}`, id, issuer)),
+		}})
+		require.NoError(t, err)
+
+		return leaf
+	}
+
+	revocationLeaf := func(t *testing.T, event *RevocationEvent) []byte {
+		t.Helper()
+
+		leaf, err := CreateRevocationLeaf(1, event)
+		require.NoError(t, err)
+
+		src, err := json.Marshal(leaf)
+		require.NoError(t, err)
+
+		return src
+	}
+
+	// leaf 0 is a credential, leaf 1 suspends it, leaf 2 is a credential revoked by leaf 3
+	newCmd := func(t *testing.T, ctrl *gomock.Controller) (*Cmd, *MockTrillianLogClient) {
+		t.Helper()
+
+		values := [][]byte{
+			vcLeaf(t, "urn:credential:0"),
+			revocationLeaf(t, &RevocationEvent{LeafHash: leafHash(0), Status: SuspendedStatus, Reason: "audit"}),
+			vcLeaf(t, "urn:credential:2"),
+			revocationLeaf(t, &RevocationEvent{LeafHash: leafHash(2), Status: RevokedStatus}),
+		}
+
+		root, err := (&types.LogRootV1{TreeSize: uint64(len(values))}).MarshalBinary()
 		require.NoError(t, err)
 
 		client := NewMockTrillianLogClient(ctrl)
 		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
 			&trillian.GetLatestSignedLogRootResponse{
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
 			}, nil,
-		).Times(2)
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+				var leaves []*trillian.LogLeaf
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+					leaves = append(leaves, &trillian.LogLeaf{
+						LeafIndex:      i,
+						LeafValue:      values[i],
+						MerkleLeafHash: leafHash(int(i)),
+					})
+				}
+
+				return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
+			},
+		)
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
 
 		cmd, err := New(&Config{
 			KMS:    km,
 			Crypto: cr,
+			VDR:    vdr.New(vdr.WithVDR(key.New())),
 			Logs: []Log{{
 				Alias:      alias,
-				Permission: "r",
+				Permission: "rw",
 				Client:     client,
 			}},
-			Key: Key{
-				ID: newKID,
-			},
+			Key: Key{ID: newKID},
 		}, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		fr, frs := bytes.Buffer{}, GetSTHResponse{}
+		return cmd, client
+	}
 
-		require.NoError(t, cmd.GetSTH(&fr, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
-		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+	addRevocation := func(cmd *Cmd, event RevocationEvent) error {
+		src, err := json.Marshal(AddRevocationRequest{Alias: alias, Event: event})
+		require.NoError(t, err)
 
-		hr, hrs := bytes.Buffer{}, GetSTHResponse{}
+		return lookupHandler(t, cmd, AddRevocation)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
 
-		require.NoError(t, lookupHandler(t, cmd, GetSTH)(&hr, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
-		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 
-		require.Equal(t, frs.TreeSize, hrs.TreeSize)
-		require.Equal(t, frs.SHA256RootHash, hrs.SHA256RootHash)
-		require.Equal(t, frs.Timestamp, hrs.Timestamp)
+		cmd, client := newCmd(t, ctrl)
 
-		require.NotEmpty(t, frs.TreeHeadSignature)
-		require.NotEmpty(t, hrs.TreeHeadSignature)
+		event := signedByIssuer(t, RevocationEvent{LeafHash: leafHash(0), Status: RevokedStatus, Reason: "compromised"})
+
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				var leaf *MerkleTreeLeaf
+				require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+				require.Equal(t, RevocationLogEntryType, leaf.TimestampedEntry.EntryType)
+
+				var logged RevocationEvent
+				require.NoError(t, json.Unmarshal(leaf.TimestampedEntry.VCEntry, &logged))
+				require.Equal(t, event, logged)
+
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+			},
+		)
+
+		require.NoError(t, addRevocation(cmd, event))
+
+		// the queued revocation event is not indexed yet, the credential is revoked anyway
+		err := addRevocation(cmd, signedByIssuer(t, RevocationEvent{LeafHash: leafHash(0), Status: RevokedStatus}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is revoked")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
 	})
 
-	t.Run("Latest signed log root (error)", func(t *testing.T) {
+	t.Run("Credential is not logged", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetLatestSignedLogRoot(
-			gomock.Any(), gomock.Any(),
-		).Return(nil, errors.New("error")).Times(2)
+		cmd, _ := newCmd(t, ctrl)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		err := addRevocation(cmd, signedByIssuer(t, RevocationEvent{LeafHash: leafHash(100), Status: RevokedStatus}))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
 
-		const expErr = "get latest signed log root: error"
-		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		// a revocation event can not be revoked
+		err = addRevocation(cmd, signedByIssuer(t, RevocationEvent{LeafHash: leafHash(1), Status: RevokedStatus}))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
 	})
 
-	t.Run("No signed log root", func(t *testing.T) {
+	t.Run("Credential is revoked", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd, _ := newCmd(t, ctrl)
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetLatestSignedLogRoot(
-			gomock.Any(), gomock.Any(),
-		).Return(nil, nil).Times(2)
+		err := addRevocation(cmd, signedByIssuer(t, RevocationEvent{LeafHash: leafHash(2), Status: ReinstatedStatus}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is revoked")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+	t.Run("Not signed by the issuer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 
-		const expErr = "internal error: no signed log root returned"
-		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		cmd, _ := newCmd(t, ctrl)
+
+		event := RevocationEvent{LeafHash: leafHash(0), Status: RevokedStatus}
+
+		// signed by another issuer
+		err := addRevocation(cmd, signed(t, event, RevocationClaims{
+			Issuer: otherIssuer, LeafHash: event.LeafHash, Status: event.Status,
+		}, otherPrivKey, otherKeyID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is signed by "+otherIssuer+", not by the issuer "+issuer)
+		require.Equal(t, http.StatusForbidden, errors.StatusCodeFromError(err))
+
+		// signed with the key of another issuer on behalf of the issuer
+		err = addRevocation(cmd, signed(t, event, RevocationClaims{
+			Issuer: issuer, LeafHash: event.LeafHash, Status: event.Status,
+		}, otherPrivKey, keyID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verify jws")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		// the signed claims are another event
+		err = addRevocation(cmd, signed(t, event, RevocationClaims{
+			Issuer: issuer, LeafHash: event.LeafHash, Status: SuspendedStatus,
+		}, privKey, keyID))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "jws claims do not match the event")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		event.JWS = "not a jws"
+
+		err = addRevocation(cmd, event)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "verify jws")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
 	})
 
-	t.Run("Corrupted bytes (unmarshal binary)", func(t *testing.T) {
+	t.Run("Invalid event", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
 		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetLatestSignedLogRootResponse{
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: []byte{0}},
-			}, nil,
-		).Times(2)
+		km.EXPECT().Get("kid").Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes("kid").Return([]byte(`public key`), keyType, nil)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
+		c, err := New(&Config{KMS: km, Key: Key{ID: "kid"}}, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		const expErr = "unmarshal binary: logRootBytes too short"
-		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		err = addRevocation(c, RevocationEvent{LeafHash: []byte("hash"), Status: RevokedStatus})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "leaf_hash must be 32 bytes")
+
+		err = addRevocation(c, RevocationEvent{LeafHash: leafHash(0), Status: "expired"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `status "expired" is not supported`)
+
+		err = addRevocation(c, RevocationEvent{LeafHash: leafHash(0), Status: RevokedStatus})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "jws is empty")
+
+		err = c.AddRevocation(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty AddRevocation request")
 	})
 
-	t.Run("Sign error", func(t *testing.T) {
+	t.Run("Get revocations", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd, _ := newCmd(t, ctrl)
 
-		cr := NewMockCrypto(ctrl)
-		cr.EXPECT().Sign(gomock.Any(), gomock.Any()).Return([]byte{}, errors.New("error")).Times(2)
+		getRevocations := func(hash []byte) (*GetRevocationsResponse, error) {
+			src, err := json.Marshal(GetRevocationsRequest{Alias: alias, LeafHash: hash})
+			require.NoError(t, err)
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetLatestSignedLogRootResponse{
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
-			}, nil,
-		).Times(2)
+			var buf bytes.Buffer
 
-		cmd, err := New(&Config{
-			KMS:    km,
-			Crypto: cr,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: kid,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+			if err = lookupHandler(t, cmd, GetRevocations)(&buf, bytes.NewBuffer(src)); err != nil {
+				return nil, err
+			}
 
-		const expErr = "sign tree head (v1): sign TreeHeadSignature: error"
-		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
-	})
-}
+			var resp *GetRevocationsResponse
 
-func TestCmd_GetEntryAndProof(t *testing.T) {
-	const (
-		logID   int64 = 123
-		keyType       = kms.ECDSAP256TypeIEEEP1363
-	)
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
 
-	t.Run("Success", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+			return resp, nil
+		}
 
-		km, cr := createKMSAndCrypto(t)
-		newKID, _, err := km.Create(keyType)
+		resp, err := getRevocations(leafHash(0))
 		require.NoError(t, err)
+		require.Len(t, resp.Events, 1)
+		require.Equal(t, int64(1), resp.Events[0].LeafIndex)
+		require.Equal(t, SuspendedStatus, resp.Events[0].Event.Status)
+		require.Equal(t, "audit", resp.Events[0].Event.Reason)
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetEntryAndProofResponse{
-				Proof: &trillian.Proof{},
-				Leaf:  &trillian.LogLeaf{LeafValue: []byte{0}},
-				SignedLogRoot: &trillian.SignedLogRoot{
-					LogRoot: logRoot,
-				},
-			}, nil,
-		).Times(2)
+		_, err = getRevocations(leafHash(100))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
 
-		cmd, err := New(&Config{
-			KMS:    km,
-			Crypto: cr,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: newKID,
-			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		err = cmd.GetRevocations(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty GetRevocations request")
 
-		fr, frs := bytes.Buffer{}, GetEntryAndProofResponse{}
+		var page bytes.Buffer
 
-		require.NoError(t, cmd.GetEntryAndProof(&fr,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`)))
-		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+		require.NoError(t, lookupHandler(t, cmd, GetRevocations)(&page, bytes.NewBufferString(
+			fmt.Sprintf(`{"alias":%q,"leaf_hash":%q,"page_size":1}`, alias,
+				base64.StdEncoding.EncodeToString(leafHash(0))),
+		)))
+		require.NotContains(t, page.String(), "next_page_token")
+		require.Contains(t, page.String(), `"leaf_index":1`)
 
-		hr, hrs := bytes.Buffer{}, GetEntryAndProofResponse{}
+		// the revocation event is the latest entry of the credential
+		var buf bytes.Buffer
 
-		require.NoError(t, lookupHandler(t, cmd, GetEntryAndProof)(&hr,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`)))
-		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+		require.NoError(t, cmd.GetCredentialStatus(&buf, bytes.NewBufferString(
+			fmt.Sprintf(`{"alias":%q,"issuer":%q,"credential_id":"urn:credential:2"}`, alias, issuer),
+		)))
 
-		require.Equal(t, frs.AuditPath, hrs.AuditPath)
-		require.Equal(t, frs.ExtraData, hrs.ExtraData)
-		require.Equal(t, frs.LeafInput, hrs.LeafInput)
+		var status *GetCredentialStatusResponse
+
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &status))
+		require.Equal(t, int64(3), status.Entry.LeafIndex)
 	})
+}
 
-	t.Run("Bad tree size", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+func TestCmd_CredentialHistory(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
 
-		km, cr := createKMSAndCrypto(t)
-		newKID, _, err := km.Create(keyType)
-		require.NoError(t, err)
+	var credential struct {
+		Issuer string `json:"issuer"`
+	}
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetEntryAndProofResponse{
-				Proof: &trillian.Proof{},
-				Leaf:  &trillian.LogLeaf{LeafValue: []byte{0}},
-				SignedLogRoot: &trillian.SignedLogRoot{
-					LogRoot: logRoot,
-				},
-			}, nil,
-		).Times(2)
+	require.NoError(t, json.Unmarshal(verifiableCredential, &credential))
 
-		cmd, err := New(&Config{
-			KMS:    km,
-			Crypto: cr,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "r",
-				Client:     client,
-			}},
-			Key: Key{
-				ID: newKID,
-			},
-		}, nil)
+	leafHash := func(i int) []byte {
+		h := sha256.Sum256([]byte(fmt.Sprint(i)))
+
+		return h[:]
+	}
+
+	vcLeaf := func(t *testing.T, id, issuer string, supersedes []byte) []byte {
+		t.Helper()
+
+		var extensions []byte
+
+		if supersedes != nil {
+			var err error
+
+			extensions, err = json.Marshal(EntryExtensions{Supersedes: supersedes})
+			require.NoError(t, err)
+		}
+
+		leaf, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: &TimestampedEntry{
+			EntryType:  VCLogEntryType,
+			VCEntry:    []byte(fmt.Sprintf(`{"id":%q,"issuer":%s}`, id, issuer)),
+			Extensions: extensions,
+		}})
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		const expErr = "bad request: need tree size: 2 for proof, got: 1"
+		return leaf
+	}
 
-		require.EqualError(t, cmd.GetEntryAndProof(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 2}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetEntryAndProof)(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 2}`),
-		), expErr)
-	})
+	revocationLeaf, err := CreateRevocationLeaf(1, &RevocationEvent{LeafHash: leafHash(1), Status: RevokedStatus})
+	require.NoError(t, err)
 
-	t.Run("Corrupted data received", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+	revocation, err := json.Marshal(revocationLeaf)
+	require.NoError(t, err)
 
-		km, cr := createKMSAndCrypto(t)
-		newKID, _, err := km.Create(keyType)
+	// leaf 1 re-issues leaf 0 and is revoked by leaf 2, leaf 3 has another issuer,
+	// leaf 4 supersedes the already superseded leaf 0 and starts a chain of its own
+	values := [][]byte{
+		vcLeaf(t, "urn:credential:0", fmt.Sprintf("%q", credential.Issuer), nil),
+		vcLeaf(t, "urn:credential:1", fmt.Sprintf(`{"id":%q}`, credential.Issuer), leafHash(0)),
+		revocation,
+		vcLeaf(t, "urn:credential:3", `"did:example:other"`, nil),
+		vcLeaf(t, "urn:credential:4", fmt.Sprintf("%q", credential.Issuer), leafHash(0)),
+	}
+
+	newCmd := func(t *testing.T, ctrl *gomock.Controller) (*Cmd, *MockTrillianLogClient) {
+		t.Helper()
+
+		root, err := (&types.LogRootV1{TreeSize: uint64(len(values))}).MarshalBinary()
 		require.NoError(t, err)
 
 		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
-			&trillian.GetEntryAndProofResponse{
-				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
 			}, nil,
-		).Times(2)
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+				var leaves []*trillian.LogLeaf
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+					leaves = append(leaves, &trillian.LogLeaf{
+						LeafIndex:      i,
+						LeafValue:      values[i],
+						MerkleLeafHash: leafHash(int(i)),
+					})
+				}
+
+				return &trillian.GetLeavesByRangeResponse{Leaves: leaves}, nil
+			},
+		).AnyTimes()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
 
 		cmd, err := New(&Config{
 			KMS:    km,
 			Crypto: cr,
 			Logs: []Log{{
 				Alias:      alias,
-				Permission: "r",
+				Permission: "rw",
 				Client:     client,
 			}},
-			Key: Key{
-				ID: newKID,
-			},
+			VDR:             vdr.New(vdr.WithVDR(key.New())),
+			Key:             Key{ID: newKID},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
 		}, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		const expErr = "internal error: corrupted data received: signed_log_root:{log_root:\"\\x00\\x01\\x00\\x00\\x00\\x00\\x00\\x00\\x00\\x01 \\xb68\\xe6z\\xa0\\xa6\\xe0\\xacN\\xde\\x1fO1\\xbcYH\\xbb\\xbe\\\\\\xc8r\\xc6pJ\\xb8\\x00\\x88\\xf7!\\xd9\\x05n\\x16q&\\xd9P\\xc3wa\\x00\\x00\\x00\\x00\\x00\\x00\\x00\\x01\\x00\\x00\"}" // nolint: lll
+		return cmd, client
+	}
 
-		require.EqualError(t, cmd.GetEntryAndProof(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`),
-		), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, GetEntryAndProof)(nil,
-			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`),
-		), expErr)
-	})
-}
+	getHistory := func(t *testing.T, cmd *Cmd, hash []byte) (*GetCredentialHistoryResponse, error) {
+		t.Helper()
 
-func TestCreateLeaf(t *testing.T) {
-	simpleVC := &verifiable.Credential{
-		Context: []string{"https://www.w3.org/2018/credentials/v1"},
-		Subject: "did:key:123",
-		Issuer:  verifiable.Issuer{ID: "did:key:123"},
-		Issued:  &util.TimeWrapper{},
-		Types:   []string{"VerifiableCredential"},
-		Proofs:  []verifiable.Proof{{}, {}},
+		src, err := json.Marshal(GetCredentialHistoryRequest{Alias: alias, LeafHash: hash})
+		require.NoError(t, err)
+
+		var resp bytes.Buffer
+
+		if err = lookupHandler(t, cmd, GetCredentialHistory)(&resp, bytes.NewBuffer(src)); err != nil {
+			return nil, err
+		}
+
+		var result *GetCredentialHistoryResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+
+		return result, nil
 	}
 
-	_, err := CreateLeaf(1, simpleVC)
-	require.NoError(t, err)
+	addVC := func(t *testing.T, cmd *Cmd, supersedes []byte) error {
+		t.Helper()
 
-	require.Len(t, simpleVC.Proofs, 2)
-}
+		envelope, err := json.Marshal(AddVCEnvelope{
+			Credential: verifiableCredential,
+			Options:    &AddVCOptions{Supersedes: supersedes},
+		})
+		require.NoError(t, err)
 
-func TestCmd_AddVC(t *testing.T) {
-	const (
-		kid     = "kid"
-		keyType = kms.ECDSAP256TypeIEEEP1363
-	)
+		src, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+		require.NoError(t, err)
 
-	documentLoader := ldcontext.DocumentLoader(t)
+		return cmd.AddVC(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
 
-	t.Run("Success", func(t *testing.T) {
+	t.Run("History", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km, cr := createKMSAndCrypto(t)
-		newKID, _, err := km.Create(keyType)
+		cmd, _ := newCmd(t, ctrl)
+
+		for _, hash := range [][]byte{leafHash(0), leafHash(1)} {
+			history, err := getHistory(t, cmd, hash)
+			require.NoError(t, err)
+			require.Len(t, history.Entries, 2)
+
+			require.Equal(t, int64(0), history.Entries[0].LeafIndex)
+			require.Equal(t, "urn:credential:0", history.Entries[0].CredentialID)
+			require.Nil(t, history.Entries[0].Supersedes)
+			require.Empty(t, history.Entries[0].Revocations)
+
+			require.Equal(t, int64(1), history.Entries[1].LeafIndex)
+			require.Equal(t, leafHash(0), history.Entries[1].Supersedes)
+			require.Len(t, history.Entries[1].Revocations, 1)
+			require.Equal(t, RevokedStatus, history.Entries[1].Revocations[0].Event.Status)
+		}
+
+		history, err := getHistory(t, cmd, leafHash(4))
 		require.NoError(t, err)
+		require.Len(t, history.Entries, 1)
+		require.Nil(t, history.Entries[0].Supersedes)
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(
-			&trillian.QueueLeafResponse{
-				QueuedLeaf: &trillian.QueuedLogLeaf{
-					Leaf: &trillian.LogLeaf{LeafValue: queuedLeafValue},
-				},
-			}, nil,
-		).Times(2)
+		_, err = getHistory(t, cmd, leafHash(2))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
 
-		cmd, err := New(&Config{
-			KMS:    km,
-			Crypto: cr,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "w",
-				Client:     client,
-			}},
-			VDR: vdr.New(vdr.WithVDR(key.New())),
-			Key: Key{
-				ID: newKID,
+		err = cmd.GetCredentialHistory(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty GetCredentialHistory request")
+	})
+
+	t.Run("Supersedes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, client := newCmd(t, ctrl)
+
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				var leaf *MerkleTreeLeaf
+				require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+
+				var extensions EntryExtensions
+				require.NoError(t, json.Unmarshal(leaf.TimestampedEntry.Extensions, &extensions))
+				require.Equal(t, leafHash(1), extensions.Supersedes)
+
+				identity := sha256.Sum256(append(leaf.TimestampedEntry.VCEntry, leaf.TimestampedEntry.Extensions...))
+				require.Equal(t, identity[:], req.Leaf.LeafIdentityHash)
+
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
 			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
-		}, nil)
+		)
+
+		require.NoError(t, addVC(t, cmd, leafHash(1)))
+	})
+
+	t.Run("Invalid link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, _ := newCmd(t, ctrl)
+
+		err := addVC(t, cmd, leafHash(100))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+		// a revocation event can not be superseded
+		err = addVC(t, cmd, leafHash(2))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+		err = addVC(t, cmd, leafHash(0))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is already superseded by")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		err = addVC(t, cmd, leafHash(3))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "has another issuer")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		err = addVC(t, cmd, []byte("hash"))
+		require.EqualError(t, err, "parse submission: validate options: validation failed: "+
+			"supersedes must be a 32-byte leaf hash")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+}
+
+func TestCmd_Anchor(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	km, cr := createKMSAndCrypto(t)
+
+	// the key of the anchored log
+	anchoredKID, anchoredKH, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	pubKey, _, err := km.ExportPubKeyBytes(anchoredKID)
+	require.NoError(t, err)
+
+	logID := sha256.Sum256(pubKey)
+
+	newAnchor := func(t *testing.T, timestamp, treeSize uint64, root []byte, consistency ...[]byte) STHAnchor {
+		t.Helper()
+
+		data, err := json.Marshal(TreeHeadSignature{
+			Version:        V1,
+			SignatureType:  TreeHeadSignatureType,
+			Timestamp:      timestamp,
+			TreeSize:       treeSize,
+			SHA256RootHash: root,
+		})
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		fr, frs := bytes.Buffer{}, AddVCResponse{}
+		signature, err := cr.Sign(data, anchoredKH)
+		require.NoError(t, err)
 
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
+		sth, err := json.Marshal(DigitallySigned{
+			Algorithm: SignatureAndHashAlgorithm{Signature: ECDSASignature, Type: keyType},
+			Signature: signature,
 		})
 		require.NoError(t, err)
 
-		require.NoError(t, cmd.AddVC(&fr, bytes.NewBuffer(req)))
-		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+		return STHAnchor{
+			LogID:     logID[:],
+			PublicKey: pubKey,
+			STH: GetSTHResponse{
+				TreeSize:          treeSize,
+				Timestamp:         timestamp,
+				SHA256RootHash:    root,
+				TreeHeadSignature: sth,
+			},
+			Consistency: consistency,
+		}
+	}
 
-		hr, hrs := bytes.Buffer{}, AddVCResponse{}
+	// leaves of the anchored log
+	first, second := hasher.DefaultHasher.HashLeaf([]byte("a")), hasher.DefaultHasher.HashLeaf([]byte("b"))
+	root := hasher.DefaultHasher.HashChildren(first, second)
 
-		require.NoError(t, lookupHandler(t, cmd, AddVC)(&hr, bytes.NewBuffer(req)))
-		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+	anchored := newAnchor(t, 1, 1, first)
 
-		require.Equal(t, frs.Timestamp, hrs.Timestamp)
-		require.Equal(t, frs.ID, hrs.ID)
-		require.Equal(t, frs.Extensions, hrs.Extensions)
-		require.Equal(t, frs.SVCTVersion, hrs.SVCTVersion)
+	newCmd := func(t *testing.T, ctrl *gomock.Controller, maxClockSkew time.Duration,
+		purposeKeys map[KeyPurpose]Key) (*Cmd, *MockTrillianLogClient) {
+		t.Helper()
 
-		require.NotEmpty(t, frs.Signature)
-		require.NotEmpty(t, hrs.Signature)
+		leaf, err := CreateAnchorLeaf(1, &anchored)
+		require.NoError(t, err)
 
-		var sig *DigitallySigned
-		require.NoError(t, json.Unmarshal(hrs.Signature, &sig))
+		value, err := json.Marshal(leaf)
+		require.NoError(t, err)
 
-		require.NotEmpty(t, sig.Signature)
-		require.NotEmpty(t, sig.Algorithm.Type)
-		require.NotEmpty(t, sig.Algorithm.Signature)
-	})
+		logRoot, err := (&types.LogRootV1{TreeSize: 1}).MarshalBinary()
+		require.NoError(t, err)
 
-	t.Run("Document loader error", func(t *testing.T) {
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLeavesByRangeResponse{Leaves: []*trillian.LogLeaf{{LeafValue: value}}}, nil,
+		).AnyTimes()
 
-		km, cr := createKMSAndCrypto(t)
 		newKID, _, err := km.Create(keyType)
 		require.NoError(t, err)
 
@@ -1340,189 +1795,423 @@ func TestCmd_AddVC(t *testing.T) {
 			Crypto: cr,
 			Logs: []Log{{
 				Alias:      alias,
-				Permission: "w",
-				Client:     NewMockTrillianLogClient(ctrl),
+				Permission: "rw",
+				Client:     client,
 			}},
-			VDR: vdr.New(vdr.WithVDR(key.New())),
-			Key: Key{
-				ID: newKID,
-			},
+			Key:          Key{ID: newKID},
+			MaxClockSkew: maxClockSkew,
+			PurposeKeys:  purposeKeys,
 		}, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
+		return cmd, client
+	}
+
+	addAnchor := func(cmd *Cmd, anchor STHAnchor) error {
+		src, err := json.Marshal(AddAnchorRequest{Alias: alias, Anchor: anchor})
 		require.NoError(t, err)
 
-		const expErr = "document loader"
-		require.Contains(t, cmd.AddVC(nil, bytes.NewBuffer(req)).Error(), expErr)
-		require.Contains(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)).Error(), expErr)
-	})
+		return lookupHandler(t, cmd, AddAnchor)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
 
-	t.Run("Copy vc failed", func(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd, client := newCmd(t, ctrl, 0, nil)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
+		anchor := newAnchor(t, 2, 2, root, second)
+
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				var leaf *MerkleTreeLeaf
+				require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+				require.Equal(t, STHAnchorLogEntryType, leaf.TimestampedEntry.EntryType)
+
+				var logged STHAnchor
+				require.NoError(t, json.Unmarshal(leaf.TimestampedEntry.VCEntry, &logged))
+				require.Equal(t, anchor, logged)
+
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
 			},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+		).Times(2)
 
-		const expErr = "decode AddVC request: internal error"
-		require.EqualError(t, cmd.AddVC(nil, &readerMock{errors.New("EOF")}), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, &readerMock{errors.New("EOF")}), expErr)
+		require.NoError(t, addAnchor(cmd, anchor))
+
+		// the same tree head signed later
+		anchor = newAnchor(t, 3, 1, first)
+		require.NoError(t, addAnchor(cmd, anchor))
 	})
 
-	t.Run("Parse credential", func(t *testing.T) {
+	t.Run("Witness cosignature", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		witnessKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Key: Key{
-				ID: kid,
+		witnessKey, _, err := km.ExportPubKeyBytes(witnessKID)
+		require.NoError(t, err)
+
+		cmd, client := newCmd(t, ctrl, 0, map[KeyPurpose]Key{WitnessKeyPurpose: {ID: witnessKID}})
+
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
 			},
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "w",
-			}},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
-		}, nil)
+		)
+
+		anchor := newAnchor(t, 2, 2, root, second)
+
+		src, err := json.Marshal(AddAnchorRequest{Alias: alias, Anchor: anchor})
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		const expErr = "parse credential: decode new credential: embedded proof is not JSON: unexpected end of JSON input"
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBufferString(`{"alias":"maple2021"}`)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBufferString(`{"alias":"maple2021"}`)), expErr)
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, AddAnchor)(&buf, bytes.NewBuffer(src)))
+
+		var resp *AddVCResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.NotNil(t, resp.WitnessCosignature)
+
+		statement := resp.WitnessCosignature.Statement
+		require.Equal(t, CosignatureSignatureType, statement.SignatureType)
+		require.Equal(t, anchor.LogID, statement.LogID)
+		require.Equal(t, anchor.STH.TreeSize, statement.TreeSize)
+		require.Equal(t, anchor.STH.SHA256RootHash, statement.SHA256RootHash)
+
+		require.NoError(t, VerifySignature(resp.WitnessCosignature.Signature, witnessKey, statement))
+		// the cosignature is not signed by the key of the log
+		require.Error(t, VerifySignature(resp.WitnessCosignature.Signature, cmd.PubKey, statement))
 	})
 
-	t.Run("Issuer is not trusted", func(t *testing.T) {
+	t.Run("Tolerated clock skew", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd, client := newCmd(t, ctrl, 2*time.Hour, nil)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "w",
-				Issuers:    []string{"issuer_a"},
-			}},
-			VDR: vdr.New(vdr.WithVDR(key.New())),
-			Key: Key{
-				ID: kid,
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
 			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
-
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
-		require.NoError(t, err)
+		)
 
-		const expErr = "bad request: issuer did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N2 is not in a list" // nolint: lll
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+		timestamp := uint64(time.Now().Add(time.Hour).UnixNano()) / uint64(time.Millisecond)
+		require.NoError(t, addAnchor(cmd, newAnchor(t, timestamp, 2, root, second)))
 	})
 
-	t.Run("Queue leaf error", func(t *testing.T) {
+	t.Run("Invalid anchor", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+		cmd, _ := newCmd(t, ctrl, 0, nil)
 
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(nil, errors.New("error")).Times(2)
+		tests := []struct {
+			name   string
+			anchor func() STHAnchor
+			err    string
+		}{{
+			name: "Log ID",
+			anchor: func() STHAnchor {
+				anchor := newAnchor(t, 2, 2, root, second)
+				anchor.LogID = first
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "w",
-				Client:     client,
-			}},
-			VDR: vdr.New(vdr.WithVDR(key.New())),
-			Key: Key{
-				ID: kid,
+				return anchor
 			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
-		}, nil)
-		require.NoError(t, err)
-		require.NotNil(t, cmd)
+			err: "log_id does not match public_key",
+		}, {
+			name: "Root hash",
+			anchor: func() STHAnchor {
+				return newAnchor(t, 2, 2, []byte("root"), second)
+			},
+			err: "sha256_root_hash must be 32 bytes",
+		}, {
+			name: "Signature",
+			anchor: func() STHAnchor {
+				anchor := newAnchor(t, 2, 2, root, second)
+				anchor.STH.TreeSize = 3
+
+				return anchor
+			},
+			err: "tree head signature",
+		}, {
+			name: "Future",
+			anchor: func() STHAnchor {
+				timestamp := uint64(time.Now().Add(time.Hour).UnixNano()) / uint64(time.Millisecond)
 
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
-		require.NoError(t, err)
+				return newAnchor(t, timestamp, 2, root, second)
+			},
+			err: "is dated in the future, max skew is 5m0s",
+		}, {
+			name: "Not newer",
+			anchor: func() STHAnchor {
+				return newAnchor(t, 1, 2, root, second)
+			},
+			err: "is not newer than the anchored one",
+		}, {
+			name: "Smaller tree",
+			anchor: func() STHAnchor {
+				return newAnchor(t, 2, 0, hasher.DefaultHasher.EmptyRoot())
+			},
+			err: "is smaller than anchored 1",
+		}, {
+			name: "Split view",
+			anchor: func() STHAnchor {
+				return newAnchor(t, 2, 1, second)
+			},
+			err: "does not match the anchored one",
+		}, {
+			name: "Consistency",
+			anchor: func() STHAnchor {
+				return newAnchor(t, 2, 2, root, first)
+			},
+			err: "verify consistency",
+		}}
+
+		for _, tc := range tests {
+			err := addAnchor(cmd, tc.anchor())
+			require.Error(t, err, tc.name)
+			require.Contains(t, err.Error(), tc.err, tc.name)
+			require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err), tc.name)
+		}
 
-		const expErr = "queue leaf: error"
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+		err := cmd.AddAnchor(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty AddAnchor request")
 	})
 
-	t.Run("No leaf", func(t *testing.T) {
+	t.Run("Get anchors", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
-		km := NewMockKeyManager(ctrl)
-		km.EXPECT().Get(kid).Return(nil, nil)
-		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
-
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(&trillian.QueueLeafResponse{}, nil).Times(2)
+		cmd, _ := newCmd(t, ctrl, 0, nil)
 
-		cmd, err := New(&Config{
-			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "w",
-				Client:     client,
-			}},
-			VDR: vdr.New(vdr.WithVDR(key.New())),
-			Key: Key{
-				ID: kid,
-			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
-		}, nil)
+		src, err := json.Marshal(GetAnchorsRequest{Alias: alias, LogID: logID[:]})
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
 
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetAnchors)(&buf, bytes.NewBuffer(src)))
+
+		var resp GetAnchorsResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Equal(t, logID[:], resp.LogID)
+		require.Len(t, resp.Anchors, 1)
+		require.Equal(t, anchored, resp.Anchors[0].Anchor)
+
+		src, err = json.Marshal(GetAnchorsRequest{Alias: alias, LogID: first})
 		require.NoError(t, err)
 
-		const expErr = "internal error: no leaf"
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+		err = lookupHandler(t, cmd, GetAnchors)(&buf, bytes.NewBuffer(src))
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+		err = cmd.GetAnchors(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty GetAnchors request")
 	})
+}
 
-	t.Run("Corrupted leaf", func(t *testing.T) {
+func TestCmd_AddEntry(t *testing.T) {
+	const (
+		keyType             = kms.ECDSAP256TypeIEEEP1363
+		customLogEntryType  = LogEntryType(200)
+		customLeafTypeName  = "custom"
+		customEmptyEntryErr = "custom entry is empty"
+	)
+
+	custom := LeafType{
+		EntryType: customLogEntryType,
+		Name:      customLeafTypeName,
+		Validate: func(entry []byte) error {
+			if string(entry) == `""` {
+				return errors.NewBadRequestError(fmt.Errorf(customEmptyEntryErr))
+			}
+
+			return nil
+		},
+		Serialize: func(entry []byte) ([]byte, error) { return entry, nil },
+	}
+
+	newCmd := func(t *testing.T, client TrillianLogClient, types ...LeafType) (*Cmd, error) {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		return New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "rw",
+				Client:     client,
+			}},
+			Key:       Key{ID: newKID},
+			LeafTypes: types,
+		}, nil)
+	}
+
+	addEntry := func(cmd *Cmd, entryType LogEntryType, entry string) error {
+		src, err := json.Marshal(AddEntryRequest{Alias: alias, EntryType: entryType, Entry: json.RawMessage(entry)})
+		require.NoError(t, err)
+
+		return lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var logged []*TimestampedEntry
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				var leaf *MerkleTreeLeaf
+				require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+
+				logged = append(logged, leaf.TimestampedEntry)
+
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+			},
+		).Times(3)
+
+		cmd, err := newCmd(t, client, custom)
+		require.NoError(t, err)
+
+		hash := sha256.Sum256([]byte("data"))
+
+		// the commitment is logged in its canonical form
+		require.NoError(t, addEntry(cmd, CommitmentLogEntryType,
+			fmt.Sprintf(`{"hash":%q,"data":"data"}`, base64.StdEncoding.EncodeToString(hash[:])),
+		))
+		require.NoError(t, addEntry(cmd, customLogEntryType, `"custom"`))
+
+		require.Equal(t, CommitmentLogEntryType, logged[0].EntryType)
+		require.JSONEq(t, fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:])),
+			string(logged[0].VCEntry))
+
+		require.Equal(t, customLogEntryType, logged[1].EntryType)
+		require.Equal(t, `"custom"`, string(logged[1].VCEntry))
+
+		// attribute values are not logged
+		require.NoError(t, addEntry(cmd, AnonCredsLogEntryType, `{
+			"schema_id": "Th7MpTaRZVRYnPiabds81Y:2:degree:1.0",
+			"cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag",
+			"blinded_attributes": {"name": "Y29tbWl0bWVudA=="},
+			"values": {"name": "Alice"}
+		}`))
+
+		require.Equal(t, AnonCredsLogEntryType, logged[2].EntryType)
+		require.JSONEq(t, `{
+			"schema_id": "Th7MpTaRZVRYnPiabds81Y:2:degree:1.0",
+			"cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag",
+			"blinded_attributes": {"name": "Y29tbWl0bWVudA=="}
+		}`, string(logged[2].VCEntry))
+	})
+
+	t.Run("Invalid entry", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, err := newCmd(t, NewMockTrillianLogClient(ctrl), custom)
+		require.NoError(t, err)
+
+		tests := []struct {
+			name      string
+			entryType LogEntryType
+			entry     string
+			err       string
+		}{{
+			name:      "Unsupported type",
+			entryType: 999,
+			entry:     `{}`,
+			err:       "leaf type 999 is not supported",
+		}, {
+			name:      "Credential",
+			entryType: VCLogEntryType,
+			entry:     `{}`,
+			err:       `leaf type "vc" can not be added as an entry`,
+		}, {
+			name:      "Commitment hash",
+			entryType: CommitmentLogEntryType,
+			entry:     `{"hash":"aGFzaA=="}`,
+			err:       "hash must be 32 bytes",
+		}, {
+			name:      "Commitment format",
+			entryType: CommitmentLogEntryType,
+			entry:     `[]`,
+			err:       "unmarshal entry",
+		}, {
+			name:      "AnonCreds schema",
+			entryType: AnonCredsLogEntryType,
+			entry:     `{"schema_id":"Th7MpTaRZVRYnPiabds81Y:3:degree:1.0"}`,
+			err:       "is not an AnonCreds schema identifier",
+		}, {
+			name:      "AnonCreds credential definition",
+			entryType: AnonCredsLogEntryType,
+			entry:     `{"schema_id":"did:indy:sovrin:Th7MpTaRZVRYnPiabds81Y/anoncreds/v0/SCHEMA/degree/1.0"}`,
+			err:       "is not an AnonCreds credential definition identifier",
+		}, {
+			name:      "AnonCreds attributes",
+			entryType: AnonCredsLogEntryType,
+			entry: `{"schema_id":"Th7MpTaRZVRYnPiabds81Y:2:degree:1.0",` +
+				`"cred_def_id":"did:indy:sovrin:Th7MpTaRZVRYnPiabds81Y/anoncreds/v0/CLAIM_DEF/12/tag"}`,
+			err: "blinded_attributes is empty",
+		}, {
+			name:      "AnonCreds commitment",
+			entryType: AnonCredsLogEntryType,
+			entry: `{"schema_id":"Th7MpTaRZVRYnPiabds81Y:2:degree:1.0",` +
+				`"cred_def_id":"Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag","blinded_attributes":{"name":""}}`,
+			err: "blinded attribute must have a name and a commitment",
+		}, {
+			name:      "Custom",
+			entryType: customLogEntryType,
+			entry:     `""`,
+			err:       customEmptyEntryErr,
+		}}
+
+		for _, tc := range tests {
+			err := addEntry(cmd, tc.entryType, tc.entry)
+			require.Error(t, err, tc.name)
+			require.Contains(t, err.Error(), tc.err, tc.name)
+			require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err), tc.name)
+		}
+
+		err = cmd.AddEntry(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty AddEntry request")
+	})
+
+	t.Run("Registration", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := newCmd(t, NewMockTrillianLogClient(ctrl), LeafType{EntryType: VCLogEntryType, Name: "other"})
+		require.EqualError(t, err, "register leaf types: leaf type 100 is already registered")
+
+		_, err = newCmd(t, NewMockTrillianLogClient(ctrl), LeafType{EntryType: customLogEntryType, Name: "vc"})
+		require.EqualError(t, err, `register leaf types: leaf type name "vc" is empty or already registered`)
+
+		_, err = newCmd(t, NewMockTrillianLogClient(ctrl), LeafType{
+			EntryType: customLogEntryType,
+			Name:      customLeafTypeName,
+			Validate:  custom.Validate,
+		})
+		require.EqualError(t, err, `register leaf types: leaf type "custom" has no serializer`)
+	})
+}
+
+func TestCmd_GetProofByHash(t *testing.T) {
+	const (
+		kid     = "kid"
+		keyType = kms.ECDSAP256TypeIEEEP1363
+	)
+
+	leafHash := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
@@ -1531,42 +2220,46 @@ func TestCmd_AddVC(t *testing.T) {
 		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
 
 		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(&trillian.QueueLeafResponse{
-			QueuedLeaf: &trillian.QueuedLogLeaf{
-				Leaf: &trillian.LogLeaf{
-					LeafValue: []byte(`[]`),
-				},
-			},
-		}, nil).Times(2)
+		client.EXPECT().GetInclusionProofByHash(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetInclusionProofByHashResponse{
+				Proof:         []*trillian.Proof{{Hashes: [][]byte{{0, 1, 2}}}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		).Times(2)
 
 		cmd, err := New(&Config{
 			KMS: km,
 			Logs: []Log{{
 				Alias:      alias,
-				Permission: "w",
+				Permission: "r",
 				Client:     client,
 			}},
-			VDR: vdr.New(vdr.WithVDR(key.New())),
 			Key: Key{
 				ID: kid,
 			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
 		}, nil)
 		require.NoError(t, err)
 		require.NotNil(t, cmd)
 
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
-		require.NoError(t, err)
+		fr, frs := bytes.Buffer{}, GetProofByHashResponse{}
 
-		const expErr = "failed to reconstruct MerkleTreeLeaf: json: cannot unmarshal array into Go value of type command.MerkleTreeLeaf" // nolint: lll
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+		require.NoError(t, cmd.GetProofByHash(&fr,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1,"hash":"`+leafHash+`"}`)))
+		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+
+		hr, hrs := bytes.Buffer{}, GetProofByHashResponse{}
+
+		require.NoError(t, lookupHandler(t, cmd, GetProofByHash)(&hr,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1,"hash":"`+leafHash+`"}`)))
+		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+
+		require.Equal(t, frs.AuditPath, hrs.AuditPath)
+		require.Equal(t, frs.LeafIndex, hrs.LeafIndex)
+		require.NotEmpty(t, frs.AuditPath)
+		require.NotEmpty(t, hrs.AuditPath)
 	})
 
-	t.Run("Sign error", func(t *testing.T) {
+	t.Run("Decode error", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
@@ -1574,47 +2267,49 @@ func TestCmd_AddVC(t *testing.T) {
 		km.EXPECT().Get(kid).Return(nil, nil)
 		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
 
-		cr := NewMockCrypto(ctrl)
-		cr.EXPECT().Sign(gomock.Any(), gomock.Any()).Return([]byte{}, errors.New("error")).Times(2)
-
-		client := NewMockTrillianLogClient(ctrl)
-		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(&trillian.QueueLeafResponse{
-			QueuedLeaf: &trillian.QueuedLogLeaf{
-				Leaf: &trillian.LogLeaf{
-					LeafValue: queuedLeafValue,
-				},
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
 			},
-		}, nil).Times(2)
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "decode GetProofByHash request: EOF"
+
+		require.EqualError(t, cmd.GetProofByHash(nil, bytes.NewBuffer(nil)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetProofByHash)(nil, bytes.NewBuffer(nil)), expErr)
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
 
 		cmd, err := New(&Config{
 			KMS: km,
-			Logs: []Log{{
-				Alias:      alias,
-				Permission: "w",
-				Client:     client,
-			}},
-			Crypto: cr,
-			VDR:    vdr.New(vdr.WithVDR(key.New())),
 			Key: Key{
 				ID: kid,
 			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
 		}, nil)
 		require.NoError(t, err)
 		require.NotNil(t, cmd)
 
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
-		require.NoError(t, err)
+		const expErr = "validate GetProofByHash request: validation failed: tree_size value must be greater than zero"
 
-		const expErr = "sign V1 VCTS: sign TreeHeadSignature: error"
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, cmd.GetProofByHash(nil,
+			bytes.NewBufferString(`{}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetProofByHash)(nil,
+			bytes.NewBufferString(`{}`),
+		), expErr)
 	})
 
-	t.Run("Action forbidden", func(t *testing.T) {
+	t.Run("Invalid hash", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
 
@@ -1627,77 +2322,7277 @@ func TestCmd_AddVC(t *testing.T) {
 			Logs: []Log{{
 				Alias:      alias,
 				Permission: "r",
+				Client:     NewMockTrillianLogClient(ctrl),
 			}},
-			Crypto: NewMockCrypto(ctrl),
-			VDR:    vdr.New(vdr.WithVDR(key.New())),
 			Key: Key{
 				ID: kid,
 			},
-			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
 		}, nil)
 		require.NoError(t, err)
-		require.NotNil(t, cmd)
-
-		req, err := json.Marshal(AddVCRequest{
-			Alias:   alias,
-			VCEntry: verifiableCredential,
-		})
-		require.NoError(t, err)
 
-		const expErr = "has permissions: action forbidden for \"maple2021\""
-		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
-		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+		err = cmd.GetProofByHash(nil, bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1,"hash":"aGFzaA=="}`))
+		require.EqualError(t, err, "validation failed: hash must be 32 bytes, got 4")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
 	})
-}
-
-func lookupHandler(t *testing.T, cmd *Cmd, name string) Exec {
-	t.Helper()
 
-	for _, handler := range cmd.GetHandlers() {
-		if handler.Method() == name {
-			return handler.Handle()
-		}
-	}
+	t.Run("Unmarshal binary error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 
-	t.Errorf("no handler for %s", name)
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
 
-	return func(rw io.Writer, req io.Reader) error {
-		return nil
-	}
-}
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetInclusionProofByHash(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetInclusionProofByHashResponse{
+				Proof:         []*trillian.Proof{{Hashes: [][]byte{{0}}}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: []byte{0}},
+			}, nil,
+		).Times(2)
 
-func createKMSAndCrypto(t *testing.T) (kms.KeyManager, crypto.Crypto) {
-	t.Helper()
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
 
-	const defaultMasterKeyURI = "local-lock://default/master/key/"
+		const expErr = "internal error: unmarshal binary: [0]"
 
-	local, err := localkms.New(defaultMasterKeyURI, &kmsProvider{
-		storageProvider: mem.NewProvider(),
-		secretLock:      &noop.NoLock{},
+		require.EqualError(t, cmd.GetProofByHash(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1,"hash":"`+leafHash+`"}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetProofByHash)(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1,"hash":"`+leafHash+`"}`),
+		), expErr)
 	})
-	require.NoError(t, err)
-
-	cr, err := tinkcrypto.New()
-	require.NoError(t, err)
-
-	return local, cr
 }
 
-type kmsProvider struct {
-	storageProvider storage.Provider
-	secretLock      secretlock.Service
-}
+func TestCmd_GetSTHConsistency(t *testing.T) {
+	const (
+		kid     = "kid"
+		keyType = kms.ECDSAP256TypeIEEEP1363
+	)
 
-func (k kmsProvider) StorageProvider() storage.Provider {
-	return k.storageProvider
-}
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
 
-func (k kmsProvider) SecretLock() secretlock.Service {
-	return k.secretLock
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetConsistencyProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetConsistencyProofResponse{
+				Proof:         &trillian.Proof{Hashes: [][]byte{{0, 1, 2}}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		fr, frs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+
+		require.NoError(t, cmd.GetSTHConsistency(&fr,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
+		))
+		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+
+		hr, hrs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+
+		require.NoError(t, lookupHandler(t, cmd, GetSTHConsistency)(&hr,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
+		))
+		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+
+		require.Equal(t, frs.Consistency, hrs.Consistency)
+		require.NotEmpty(t, frs.Consistency)
+	})
+
+	t.Run("Debug", func(t *testing.T) {
+		km := NewMockKeyManager(gomock.NewController(t))
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(gomock.NewController(t))
+		client.EXPECT().GetConsistencyProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetConsistencyProofResponse{
+				Proof:         &trillian.Proof{Hashes: [][]byte{{0, 1, 2}}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		)
+
+		cmd, err := New(&Config{
+			KMS:  km,
+			Logs: []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:  Key{ID: kid},
+		}, nil)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, cmd.GetSTHConsistency(&buf,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size":1,"second_tree_size":1,"debug":true}`),
+		))
+
+		var resp *GetSTHConsistencyResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Equal(t, [][]byte{{0, 1, 2}}, resp.Consistency)
+		require.NotNil(t, resp.Debug)
+		require.False(t, resp.Debug.CacheHit)
+		require.Len(t, resp.Debug.Steps, 3)
+		require.Equal(t, "trillian_rpc", resp.Debug.Steps[1].Name)
+		require.GreaterOrEqual(t, resp.Debug.TotalMicros, resp.Debug.Steps[1].Micros)
+	})
+
+	t.Run("Success (empty)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
+			},
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+			}},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		fr, frs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+
+		require.NoError(t, cmd.GetSTHConsistency(&fr,
+			bytes.NewBufferString(`{"alias":"maple2021"}`),
+		))
+		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+
+		hr, hrs := bytes.Buffer{}, GetSTHConsistencyResponse{}
+
+		require.NoError(t, lookupHandler(t, cmd, GetSTHConsistency)(&hr,
+			bytes.NewBufferString(`{"alias":"maple2021"}`),
+		))
+		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+
+		require.Equal(t, frs.Consistency, hrs.Consistency)
+		require.Empty(t, frs.Consistency)
+	})
+
+	t.Run("Decode error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "decode GetSTHConsistency request: EOF"
+
+		require.EqualError(t, cmd.GetSTHConsistency(nil, bytes.NewBuffer(nil)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil, bytes.NewBuffer(nil)), expErr)
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "validate GetSTHConsistency request: validation failed: first_tree_size -1 and second_tree_size 0 values must be >= 0" // nolint: lll
+
+		require.EqualError(t, cmd.GetSTHConsistency(nil,
+			bytes.NewBufferString(`{"first_tree_size": -1}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil,
+			bytes.NewBufferString(`{"first_tree_size": -1}`),
+		), expErr)
+	})
+
+	t.Run("Unmarshal binary error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetConsistencyProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetConsistencyProofResponse{
+				Proof:         &trillian.Proof{Hashes: [][]byte{{0, 1, 2}}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: []byte{0}},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "internal error: unmarshal binary: [0]"
+
+		require.EqualError(t, cmd.GetSTHConsistency(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
+		), expErr)
+	})
+
+	t.Run("Alias maple2021 is not supported", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "has permissions: alias \"maple2021\" is not supported"
+
+		require.EqualError(t, cmd.GetSTHConsistency(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTHConsistency)(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","first_tree_size": 1, "second_tree_size": 1}`),
+		), expErr)
+	})
+}
+
+func TestCmd_GetSTH(t *testing.T) {
+	const (
+		kid           = "kid"
+		logID   int64 = 123
+		keyType       = kms.ECDSAP256TypeIEEEP1363
+	)
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: newKID,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		fr, frs := bytes.Buffer{}, GetSTHResponse{}
+
+		require.NoError(t, cmd.GetSTH(&fr, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+
+		hr, hrs := bytes.Buffer{}, GetSTHResponse{}
+
+		require.NoError(t, lookupHandler(t, cmd, GetSTH)(&hr, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+
+		require.Equal(t, frs.TreeSize, hrs.TreeSize)
+		require.Equal(t, frs.SHA256RootHash, hrs.SHA256RootHash)
+		require.Equal(t, frs.Timestamp, hrs.Timestamp)
+
+		require.NotEmpty(t, frs.TreeHeadSignature)
+		require.NotEmpty(t, hrs.TreeHeadSignature)
+	})
+
+	t.Run("Latest signed log root (error)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(
+			gomock.Any(), gomock.Any(),
+		).Return(nil, errors.New("error")).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "get latest signed log root: error"
+		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+	})
+
+	t.Run("No signed log root", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(
+			gomock.Any(), gomock.Any(),
+		).Return(nil, nil).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "internal error: no signed log root returned"
+		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+	})
+
+	t.Run("Corrupted bytes (unmarshal binary)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: []byte{0}},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "unmarshal binary: logRootBytes too short"
+		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+	})
+
+	t.Run("Sign error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cr := NewMockCrypto(ctrl)
+		cr.EXPECT().Sign(gomock.Any(), gomock.Any()).Return([]byte{}, errors.New("error")).Times(2)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "sign tree head (v1): sign TreeHeadSignature: error"
+		require.EqualError(t, cmd.GetSTH(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetSTH)(nil, bytes.NewBufferString(fmt.Sprintf("%q", alias))), expErr)
+	})
+}
+
+func TestCmd_GetSTH_ReadReplica(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	freshRoot, err := (&types.LogRootV1{TimestampNanos: uint64(time.Now().UnixNano())}).MarshalBinary()
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T, primary, replica TrillianLogClient, staleness time.Duration) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     primary,
+				ReadClient: replica,
+			}},
+			Key:                 Key{ID: newKID},
+			MaxReplicaStaleness: staleness,
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	t.Run("Fresh replica", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		replica := NewMockTrillianLogClient(ctrl)
+		replica.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: freshRoot},
+			}, nil,
+		)
+
+		cmd := newCmd(t, NewMockTrillianLogClient(ctrl), replica, time.Minute)
+		require.NoError(t, cmd.GetSTH(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+	})
+
+	t.Run("Stale replica", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		replica := NewMockTrillianLogClient(ctrl)
+		replica.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		)
+
+		primary := NewMockTrillianLogClient(ctrl)
+		primary.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: freshRoot},
+			}, nil,
+		)
+
+		cmd := newCmd(t, primary, replica, time.Minute)
+		require.NoError(t, cmd.GetSTH(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+	})
+
+	t.Run("Staleness is not checked", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		replica := NewMockTrillianLogClient(ctrl)
+		replica.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		)
+
+		cmd := newCmd(t, NewMockTrillianLogClient(ctrl), replica, 0)
+		require.NoError(t, cmd.GetSTH(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+	})
+
+	t.Run("Replica error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		replica := NewMockTrillianLogClient(ctrl)
+		replica.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("error"))
+
+		primary := NewMockTrillianLogClient(ctrl)
+		primary.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		)
+
+		cmd := newCmd(t, primary, replica, time.Minute)
+		require.NoError(t, cmd.GetSTH(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+	})
+}
+
+func TestCmd_Shadow(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	newCmd := func(t *testing.T, primary, shadow TrillianLogClient) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:        alias,
+				Permission:   "rw",
+				Client:       primary,
+				ShadowID:     2,
+				ShadowClient: shadow,
+			}},
+			Key: Key{ID: newKID},
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	queued := func(hash string) func(_ interface{}, req *trillian.QueueLeafRequest,
+		_ ...interface{}) (*trillian.QueueLeafResponse, error) {
+		return func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			leaf := &trillian.LogLeaf{
+				LeafValue:        req.Leaf.LeafValue,
+				LeafIdentityHash: req.Leaf.LeafIdentityHash,
+				MerkleLeafHash:   []byte(hash),
+			}
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: leaf}}, nil
+		}
+	}
+
+	addCommitment := func(t *testing.T, cmd *Cmd) {
+		t.Helper()
+
+		hash := sha256.Sum256([]byte("data"))
+
+		src, err := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: CommitmentLogEntryType,
+			Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockTrillianLogClient(ctrl)
+		primary.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(queued("hash")).Times(3)
+		primary.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot}}, nil,
+		)
+
+		shadow := NewMockTrillianLogClient(ctrl)
+		gomock.InOrder(
+			shadow.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+					require.Equal(t, int64(2), req.LogId)
+
+					return queued("hash")(nil, req)
+				},
+			),
+			shadow.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(queued("other")),
+			shadow.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("unavailable")),
+		)
+		shadow.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot}}, nil,
+		)
+
+		cmd := newCmd(t, primary, shadow)
+
+		for i := 0; i < 3; i++ {
+			addCommitment(t, cmd)
+		}
+
+		var status *GetShadowStatusResponse
+
+		// the leaves are mirrored in the background
+		require.Eventually(t, func() bool {
+			var buf bytes.Buffer
+			require.NoError(t, lookupHandler(t, cmd, GetShadowStatus)(&buf,
+				bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &status))
+
+			return status.Mirrored+status.Diverged+status.Failed == 3
+		}, time.Second, 10*time.Millisecond)
+
+		require.Equal(t, uint64(1), status.Mirrored)
+		require.Equal(t, uint64(1), status.Diverged)
+		require.Equal(t, uint64(1), status.Failed)
+		require.Equal(t, int64(1), status.PrimaryTreeSize)
+		require.Equal(t, int64(1), status.ShadowTreeSize)
+		require.Len(t, status.Divergences, 2)
+		require.Equal(t, "merkle leaf hash does not match primary", status.Divergences[0].Reason)
+		require.Equal(t, "queue leaf: unavailable", status.Divergences[1].Reason)
+	})
+
+	t.Run("Slow shadow", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockTrillianLogClient(ctrl)
+		primary.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(queued("hash")).Times(2)
+
+		release := make(chan struct{})
+		mirrored := make(chan struct{}, 2)
+
+		shadow := NewMockTrillianLogClient(ctrl)
+		shadow.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				defer func() { mirrored <- struct{}{} }()
+
+				<-release
+
+				return queued("hash")(nil, req)
+			},
+		).Times(2)
+
+		cmd := newCmd(t, primary, shadow)
+
+		// the writes do not wait for the shadow log
+		addCommitment(t, cmd)
+		addCommitment(t, cmd)
+
+		close(release)
+		<-mirrored
+		<-mirrored
+	})
+
+	t.Run("No shadow", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, NewMockTrillianLogClient(ctrl), nil)
+
+		err := lookupHandler(t, cmd, GetShadowStatus)(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias)))
+		require.EqualError(t, err, `log "maple2021" has no shadow`)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Shadow root error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		primary := NewMockTrillianLogClient(ctrl)
+		primary.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot}}, nil,
+		)
+
+		shadow := NewMockTrillianLogClient(ctrl)
+		shadow.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("error"))
+
+		cmd := newCmd(t, primary, shadow)
+
+		err := lookupHandler(t, cmd, GetShadowStatus)(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias)))
+		require.EqualError(t, err, "get latest signed log root of shadow: error")
+	})
+}
+
+func TestCmd_ReadOnly(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		Key:      Key{ID: newKID},
+		ReadOnly: true,
+	}, nil)
+	require.NoError(t, err)
+
+	readOnly := func(t *testing.T) bool {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetReadOnly)(&buf, nil))
+
+		var status *ReadOnlyStatus
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &status))
+
+		return status.ReadOnly
+	}
+
+	hash := sha256.Sum256([]byte("data"))
+
+	src, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	require.True(t, readOnly(t))
+
+	err = lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	require.EqualError(t, err, "read-only: the service is in maintenance, writes are rejected, retry later")
+	require.Equal(t, http.StatusServiceUnavailable, errors.StatusCodeFromError(err))
+	require.Equal(t, errors.ProblemTypeReadOnly, errors.ProblemTypeFromError(err))
+
+	// reads are served
+	require.NoError(t, lookupHandler(t, cmd, GetIssuers)(&bytes.Buffer{}, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+
+	var buf bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, SetReadOnly)(&buf, bytes.NewBufferString(`{"read_only":false}`)))
+	require.JSONEq(t, `{"read_only":false}`, buf.String())
+	require.False(t, readOnly(t))
+
+	require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+
+	err = lookupHandler(t, cmd, SetReadOnly)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "decode SetReadOnly request")
+
+	err = lookupHandler(t, cmd, SetReadOnly)(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+	require.ErrorIs(t, err, errors.ErrBadRequest)
+	require.Contains(t, err.Error(), "empty SetReadOnly request")
+}
+
+func TestCmd_GetKeyUsage(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).Times(3)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		Key:                Key{ID: newKID},
+		KeyUsageThresholds: map[SignatureKind]uint64{SCTSignature: 2},
+	}, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("data %d", i)))
+
+		src, er := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: CommitmentLogEntryType,
+			Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+		})
+		require.NoError(t, er)
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, GetKeyUsage)(&buf, nil))
+
+	var resp *GetKeyUsageResponse
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+	require.Equal(t, cmd.VCLogID[:], resp.LogID)
+	require.Len(t, resp.Usage, 6)
+
+	sct := resp.Usage[0]
+	require.Equal(t, SCTSignature, sct.Kind)
+	require.Equal(t, uint64(3), sct.Signatures)
+	require.Equal(t, uint64(3), sct.Window)
+	require.Equal(t, uint64(2), sct.Threshold)
+	require.Equal(t, uint64(1), sct.Anomalies)
+	require.NotZero(t, sct.LastUsed)
+
+	require.Equal(t, KeyUsage{Kind: STHSignature}, resp.Usage[1])
+	require.Equal(t, KeyUsage{Kind: StatementSignature}, resp.Usage[2])
+	require.Equal(t, KeyUsage{Kind: WitnessSignature}, resp.Usage[5])
+}
+
+func TestCmd_GetUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	queued := func(code codes.Code) func(interface{}, *trillian.QueueLeafRequest,
+		...interface{}) (*trillian.QueueLeafResponse, error) {
+		return func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{
+				Leaf:   req.Leaf,
+				Status: &status.Status{Code: int32(code)},
+			}}, nil
+		}
+	}
+
+	client := NewMockTrillianLogClient(ctrl)
+	gomock.InOrder(
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(queued(codes.OK)),
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(queued(codes.AlreadyExists)),
+	)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{
+			{Alias: alias, Tenant: "maple", Permission: "rw", Client: client},
+			{Alias: "maple2020", Tenant: "maple", Permission: "rw", Client: client},
+			{Alias: "oak", Permission: "rw", Client: client},
+		},
+		Key: Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	hash := sha256.Sum256([]byte("data"))
+
+	src, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	// the second entry is a duplicate
+	require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+	require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+
+	cmd.RecordRequest(alias, "add-entry", true, http.StatusOK, time.Millisecond)
+	cmd.RecordRequest(alias, "add-entry", true, http.StatusOK, time.Millisecond)
+	cmd.RecordRequest("oak", "get-sth", false, http.StatusInternalServerError, time.Millisecond)
+	cmd.RecordRequest("unknown", "get-sth", false, http.StatusNotFound, time.Millisecond)
+
+	usage := func(req string) (*GetUsageResponse, error) {
+		var buf bytes.Buffer
+
+		if er := lookupHandler(t, cmd, GetUsage)(&buf, bytes.NewBufferString(req)); er != nil {
+			return nil, er
+		}
+
+		var resp *GetUsageResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	resp, err := usage("")
+	require.NoError(t, err)
+	require.NotZero(t, resp.Since)
+	require.Len(t, resp.Tenants, 2)
+
+	// the bytes of the logged leaf
+	entryBytes := resp.Tenants[0].Logs[1].EntryBytes
+	require.NotZero(t, entryBytes)
+
+	require.Equal(t, []TenantUsage{
+		{
+			Tenant: "maple",
+			Logs: []LogUsage{
+				{Alias: "maple2020"},
+				{Alias: alias, Entries: 1, Duplicates: 1, EntryBytes: entryBytes, Writes: 2},
+			},
+		},
+		{Tenant: "oak", Logs: []LogUsage{{Alias: "oak", Reads: 1, Errors: 1}}},
+	}, resp.Tenants)
+
+	resp, err = usage(`{"tenant":"oak"}`)
+	require.NoError(t, err)
+	require.Len(t, resp.Tenants, 1)
+	require.Equal(t, "oak", resp.Tenants[0].Tenant)
+
+	_, err = usage(`{"tenant":"pine"}`)
+	require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	require.EqualError(t, err, `tenant "pine" is not found`)
+
+	_, err = usage("{")
+	require.ErrorIs(t, err, errors.ErrBadRequest)
+}
+
+type timeSource struct {
+	now         time.Time
+	attestation *TimeAttestation
+	err         error
+}
+
+func (s *timeSource) Now() (time.Time, *TimeAttestation, error) {
+	return s.now, s.attestation, s.err
+}
+
+func TestCmd_TimeSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var logged *trillian.LogLeaf
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			// the first leaf is returned for its duplicates
+			if logged == nil {
+				logged = req.Leaf
+			}
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: logged}}, nil
+		},
+	).Times(2)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	source := &timeSource{
+		now:         time.Unix(1700000000, 0),
+		attestation: &TimeAttestation{Source: TimeAttestationRoughtime, Midpoint: 1700000000000},
+	}
+
+	cmd, err := New(&Config{
+		KMS:        km,
+		Crypto:     cr,
+		Logs:       []Log{{Alias: alias, Permission: "rw", Client: client}},
+		Key:        Key{ID: newKID},
+		TimeSource: source,
+	}, nil)
+	require.NoError(t, err)
+
+	hash := sha256.Sum256([]byte("data"))
+
+	src, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	addEntry := func() (*AddVCResponse, error) {
+		var buf bytes.Buffer
+
+		if er := lookupHandler(t, cmd, AddEntry)(&buf, bytes.NewBuffer(src)); er != nil {
+			return nil, er
+		}
+
+		var resp *AddVCResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	resp, err := addEntry()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1700000000000), resp.Timestamp)
+	require.Equal(t, source.attestation, resp.TimeAttestation)
+
+	// the SCT of the duplicate has the timestamp of the logged leaf, not the attested time
+	source.now = source.now.Add(time.Minute)
+
+	resp, err = addEntry()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1700000000000), resp.Timestamp)
+	require.Nil(t, resp.TimeAttestation)
+
+	// the entry is not logged if the time source is not available
+	source.err = fmt.Errorf("not synced")
+
+	_, err = addEntry()
+	require.EqualError(t, err, "trusted time: not synced")
+	require.Equal(t, http.StatusInternalServerError, errors.StatusCodeFromError(err))
+}
+
+func TestCmd_MarkCompromised(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	recoveryKID, recoveryKH, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	recoveryKey, _, err := km.ExportPubKeyBytes(recoveryKID)
+	require.NoError(t, err)
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	)
+
+	var stored *SignedCompromiseStatement
+
+	cfg := &Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		Key:         Key{ID: newKID},
+		RecoveryKey: recoveryKey,
+		OnCompromise: func(statement *SignedCompromiseStatement) error {
+			stored = statement
+
+			return nil
+		},
+	}
+
+	cmd, err := New(cfg, nil)
+	require.NoError(t, err)
+
+	newStatement := func(t *testing.T, kh interface{}, logID []byte) []byte {
+		t.Helper()
+
+		statement := CompromiseStatement{
+			Version:          V1,
+			SignatureType:    CompromiseSignatureType,
+			Timestamp:        uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+			LogID:            logID,
+			CompromisedSince: 1000,
+			Reason:           "key leaked",
+		}
+
+		data, er := json.Marshal(statement)
+		require.NoError(t, er)
+
+		signature, er := cr.Sign(data, kh)
+		require.NoError(t, er)
+
+		sig, er := json.Marshal(DigitallySigned{
+			Algorithm: SignatureAndHashAlgorithm{Signature: ECDSASignature, Type: keyType},
+			Signature: signature,
+		})
+		require.NoError(t, er)
+
+		src, er := json.Marshal(SignedCompromiseStatement{Statement: statement, Signature: sig})
+		require.NoError(t, er)
+
+		return src
+	}
+
+	t.Run("Rejected statements", func(t *testing.T) {
+		logKH, er := km.Get(newKID)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, cmd, MarkCompromised)(&bytes.Buffer{}, bytes.NewBuffer(newStatement(t, logKH, cmd.VCLogID[:])))
+		require.Contains(t, er.Error(), "statement signature")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(er))
+
+		er = lookupHandler(t, cmd, MarkCompromised)(&bytes.Buffer{}, bytes.NewBuffer(newStatement(t, recoveryKH, []byte("id"))))
+		require.EqualError(t, er, "statement is issued for another log")
+
+		er = lookupHandler(t, cmd, MarkCompromised)(&bytes.Buffer{}, bytes.NewBufferString(`{"statement":{"version":1}}`))
+		require.EqualError(t, er, "statement must be a v1 compromise statement")
+
+		er = lookupHandler(t, cmd, MarkCompromised)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+		require.Contains(t, er.Error(), "decode MarkCompromised request")
+
+		er = lookupHandler(t, cmd, MarkCompromised)(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, er, errors.ErrBadRequest)
+		require.Contains(t, er.Error(), "empty MarkCompromised request")
+
+		noRecovery, er := New(&Config{KMS: km, Crypto: cr, Key: Key{ID: newKID}}, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, noRecovery, MarkCompromised)(&bytes.Buffer{},
+			bytes.NewBuffer(newStatement(t, recoveryKH, cmd.VCLogID[:])))
+		require.EqualError(t, er, "no recovery key is registered")
+		require.Nil(t, stored)
+	})
+
+	statement := newStatement(t, recoveryKH, cmd.VCLogID[:])
+
+	var buf bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, MarkCompromised)(&buf, bytes.NewBuffer(statement)))
+	require.JSONEq(t, string(statement), buf.String())
+	require.NotNil(t, stored)
+
+	// writes are frozen
+	hash := sha256.Sum256([]byte("data"))
+
+	src, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	err = lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	require.EqualError(t, err, "compromised: the key of the log is compromised, writes are frozen")
+	require.Equal(t, http.StatusGone, errors.StatusCodeFromError(err))
+	require.Equal(t, errors.ProblemTypeCompromised, errors.ProblemTypeFromError(err))
+
+	err = lookupHandler(t, cmd, SetReadOnly)(&bytes.Buffer{}, bytes.NewBufferString(`{"read_only":false}`))
+	require.ErrorIs(t, err, errors.ErrCompromised)
+
+	buf.Reset()
+	require.NoError(t, lookupHandler(t, cmd, GetReadOnly)(&buf, nil))
+	require.JSONEq(t, `{"read_only":true}`, buf.String())
+
+	// the statement is published
+	buf.Reset()
+	require.NoError(t, lookupHandler(t, cmd, Webfinger)(&buf, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+
+	var webfinger *WebFingerResponse
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &webfinger))
+
+	published, err := json.Marshal(webfinger.Properties[CompromiseType])
+	require.NoError(t, err)
+	require.JSONEq(t, string(statement), string(published))
+
+	// the first statement is kept
+	buf.Reset()
+	require.NoError(t, lookupHandler(t, cmd, MarkCompromised)(&buf, bytes.NewBuffer(newStatement(t, recoveryKH,
+		cmd.VCLogID[:]))))
+	require.JSONEq(t, string(statement), buf.String())
+
+	t.Run("Restored", func(t *testing.T) {
+		cfg.Compromise = stored
+
+		restored, er := New(cfg, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, restored, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+		require.ErrorIs(t, er, errors.ErrCompromised)
+
+		cfg.RecoveryKey = nil
+
+		_, er = New(cfg, nil)
+		require.EqualError(t, er, "restore compromise statement: no recovery key is registered")
+
+		cfg.RecoveryKey, cfg.Compromise = recoveryKey, nil
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		cfg.OnCompromise = func(*SignedCompromiseStatement) error {
+			return fmt.Errorf("store error")
+		}
+
+		failing, er := New(cfg, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, failing, MarkCompromised)(&bytes.Buffer{}, bytes.NewBuffer(statement))
+		require.EqualError(t, er, "store compromise statement: store error")
+
+		require.NoError(t, lookupHandler(t, failing, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+	})
+}
+
+func TestCmd_ExtraDataEncryption(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	envelopeKID, _, err := km.Create(kms.AES256GCMType)
+	require.NoError(t, err)
+
+	var stored *trillian.LogLeaf
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			stored = req.Leaf
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	)
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _ *trillian.GetLeavesByRangeRequest,
+			_ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) {
+			return &trillian.GetLeavesByRangeResponse{
+				Leaves: []*trillian.LogLeaf{
+					stored,
+					{LeafIndex: 1, LeafValue: queuedLeafValue, ExtraData: []byte(`[]`)},
+				},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil
+		},
+	)
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _ *trillian.GetLeavesByRangeRequest,
+			_ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) {
+			return &trillian.GetLeavesByRangeResponse{
+				// the extra data is bound to its leaf
+				Leaves:        []*trillian.LogLeaf{{LeafValue: queuedLeafValue, ExtraData: stored.ExtraData}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil
+		},
+	)
+
+	cfg := &Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		Key:            Key{ID: newKID},
+		ExtraDataKeyID: envelopeKID,
+	}
+
+	cmd, err := New(cfg, nil)
+	require.NoError(t, err)
+
+	hash := sha256.Sum256([]byte("data"))
+
+	src, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+
+	var encrypted *EncryptedExtraData
+	require.NoError(t, json.Unmarshal(stored.ExtraData, &encrypted))
+	require.Equal(t, envelopeKID, encrypted.KeyID)
+	require.NotEmpty(t, encrypted.Ciphertext)
+
+	var buf bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, GetEntries)(&buf, bytes.NewBufferString(`{"alias":"maple2021","end":1}`)))
+
+	var resp *GetEntriesResponse
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+	require.Len(t, resp.Entries, 2)
+	require.Equal(t, []byte(`null`), resp.Entries[0].ExtraData)
+	// extra data stored before the encryption was enabled
+	require.Equal(t, []byte(`[]`), resp.Entries[1].ExtraData)
+
+	err = lookupHandler(t, cmd, GetEntries)(&bytes.Buffer{}, bytes.NewBufferString(`{"alias":"maple2021"}`))
+	require.Contains(t, err.Error(), "decrypt extra data")
+	require.Equal(t, http.StatusInternalServerError, errors.StatusCodeFromError(err))
+
+	t.Run("Crypto does not support encryption", func(t *testing.T) {
+		_, er := New(&Config{
+			KMS:            km,
+			Crypto:         struct{ Crypto }{cr},
+			Key:            Key{ID: newKID},
+			ExtraDataKeyID: envelopeKID,
+		}, nil)
+		require.EqualError(t, er, "extra data encryption: crypto does not support encryption")
+	})
+
+	t.Run("Unknown envelope key", func(t *testing.T) {
+		_, er := New(&Config{KMS: km, Crypto: cr, Key: Key{ID: newKID}, ExtraDataKeyID: "unknown"}, nil)
+		require.Contains(t, er.Error(), "extra data encryption: get envelope key unknown")
+	})
+}
+
+func TestCmd_RotateExtraDataKey(t *testing.T) { // nolint: funlen
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	envelopeKID, _, err := km.Create(kms.AES256GCMType)
+	require.NoError(t, err)
+
+	native, trillianLog := merklelog.New(merklelog.NewMemStorage()), merklelog.New(merklelog.NewMemStorage())
+
+	for _, log := range []*merklelog.Log{native, trillianLog} {
+		_, err = log.InitLog(ctx, &trillian.InitLogRequest{})
+		require.NoError(t, err)
+
+		// extra data stored before the encryption was enabled
+		_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{
+			Leaf: &trillian.LogLeaf{LeafValue: queuedLeafValue, ExtraData: []byte(`[]`)},
+		})
+		require.NoError(t, err)
+	}
+
+	var stored []string
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{
+			{Alias: alias, Permission: "rw", Client: native},
+			// the extra data of a Trillian log can't be rewritten
+			{Alias: "trillian", Permission: "rw", Client: struct{ TrillianLogClient }{trillianLog}},
+		},
+		Key:            Key{ID: newKID},
+		ExtraDataKeyID: envelopeKID,
+		OnExtraDataKey: func(keyID string) error {
+			stored = append(stored, keyID)
+
+			return nil
+		},
+		ReEncryption: &ReEncryptionConfig{BatchSize: 2},
+	}, nil)
+	require.NoError(t, err)
+
+	for _, logAlias := range []string{alias, "trillian"} {
+		for i := 0; i < 2; i++ {
+			hash := sha256.Sum256([]byte(fmt.Sprintf("data %d", i)))
+
+			src, er := json.Marshal(AddEntryRequest{
+				Alias:     logAlias,
+				EntryType: CommitmentLogEntryType,
+				Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+			})
+			require.NoError(t, er)
+
+			require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+		}
+	}
+
+	getStatus := func(t *testing.T) *ExtraDataKeyStatus {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetExtraDataKey)(&buf, nil))
+
+		var status *ExtraDataKeyStatus
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &status))
+
+		return status
+	}
+
+	status := getStatus(t)
+	require.Equal(t, envelopeKID, status.KeyID)
+	require.True(t, status.ReEncryption)
+	require.False(t, status.Done)
+	require.Empty(t, status.Logs)
+
+	go cmd.ReEncryptExtraData(ctx)
+
+	scanned := func(t *testing.T, keyID string) *ExtraDataKeyStatus {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			status = getStatus(t)
+
+			return status.KeyID == keyID && len(status.Logs) == 2 && status.Logs[0].Scanned == 3 &&
+				status.Logs[1].Scanned == 3
+		}, 5*time.Second, 10*time.Millisecond)
+
+		return status
+	}
+
+	// the extra data which is not encrypted is encrypted
+	status = scanned(t, envelopeKID)
+	require.Equal(t, LogReEncryption{
+		Alias: alias, Rewritable: true, TreeSize: 3, Scanned: 3, ReEncrypted: 1, Keys: map[string]uint64{envelopeKID: 3},
+	}, status.Logs[0])
+	require.Equal(t, LogReEncryption{
+		Alias: "trillian", TreeSize: 3, Scanned: 3, Retained: 1, Keys: map[string]uint64{envelopeKID: 2},
+		Plaintext: 1,
+	}, status.Logs[1])
+	// the leaves of the log which can't be re-encrypted are not waited for
+	require.True(t, status.Done)
+
+	var buf bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, RotateExtraDataKey)(&buf, bytes.NewBufferString(`{}`)))
+
+	var rotated *ExtraDataKeyStatus
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rotated))
+	require.NotEqual(t, envelopeKID, rotated.KeyID)
+	require.NotZero(t, rotated.RotatedAt)
+	require.Equal(t, []string{rotated.KeyID}, stored)
+
+	status = scanned(t, rotated.KeyID)
+	require.Equal(t, LogReEncryption{
+		Alias: alias, Rewritable: true, TreeSize: 3, Scanned: 3, ReEncrypted: 3, Keys: map[string]uint64{rotated.KeyID: 3},
+	}, status.Logs[0])
+	// the previous key must be kept for the log which can't be re-encrypted
+	require.Equal(t, LogReEncryption{
+		Alias: "trillian", TreeSize: 3, Scanned: 3, Retained: 3, Keys: map[string]uint64{envelopeKID: 2},
+		Plaintext: 1,
+	}, status.Logs[1])
+	require.True(t, status.Done)
+
+	for _, logAlias := range []string{alias, "trillian"} {
+		buf.Reset()
+		require.NoError(t, lookupHandler(t, cmd, GetEntries)(&buf,
+			bytes.NewBufferString(fmt.Sprintf(`{"alias":%q,"end":2}`, logAlias))))
+
+		var resp *GetEntriesResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Len(t, resp.Entries, 3)
+		require.Equal(t, []byte(`[]`), resp.Entries[0].ExtraData)
+		require.Equal(t, []byte(`null`), resp.Entries[1].ExtraData)
+	}
+
+	t.Run("Rotate to the active key", func(t *testing.T) {
+		src := fmt.Sprintf(`{"key_id":%q}`, rotated.KeyID)
+		require.NoError(t, lookupHandler(t, cmd, RotateExtraDataKey)(&bytes.Buffer{}, bytes.NewBufferString(src)))
+		require.Len(t, stored, 1)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		err = lookupHandler(t, cmd, RotateExtraDataKey)(&bytes.Buffer{}, bytes.NewBufferString(`{"key_id":"unknown"}`))
+		require.Contains(t, err.Error(), "get envelope key unknown")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Store fails", func(t *testing.T) {
+		failing, er := New(&Config{
+			KMS:            km,
+			Crypto:         cr,
+			Key:            Key{ID: newKID},
+			ExtraDataKeyID: envelopeKID,
+			OnExtraDataKey: func(string) error { return fmt.Errorf("store failed") },
+		}, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, failing, RotateExtraDataKey)(&bytes.Buffer{}, bytes.NewBufferString(`{}`))
+		require.EqualError(t, er, "store envelope key: store failed")
+		require.Equal(t, http.StatusInternalServerError, errors.StatusCodeFromError(er))
+
+		buf.Reset()
+		require.NoError(t, lookupHandler(t, failing, GetExtraDataKey)(&buf, nil))
+		require.Contains(t, buf.String(), `"key_id":"`+envelopeKID+`","re_encryption":false`)
+	})
+
+	t.Run("Not encrypted", func(t *testing.T) {
+		plain, er := New(&Config{KMS: km, Crypto: cr, Key: Key{ID: newKID}}, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, plain, GetExtraDataKey)(&bytes.Buffer{}, nil)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+
+		er = lookupHandler(t, plain, RotateExtraDataKey)(&bytes.Buffer{}, bytes.NewBufferString(`{}`))
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+	})
+}
+
+func TestCmd_GetAuditExport(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	leaves := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+
+	newCmd := func(t *testing.T, client TrillianLogClient) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:    Key{ID: newKID},
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	// newClient returns a log client serving the tree of the leaves.
+	newClient := func(ctrl *gomock.Controller) *MockTrillianLogClient {
+		root, err := (&types.LogRootV1{
+			TreeSize:       uint64(len(leaves)),
+			RootHash:       merkleRoot(leaves),
+			TimestampNanos: uint64(time.Now().UnixNano()),
+		}).MarshalBinary()
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+		).AnyTimes()
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetEntryAndProofRequest,
+				_ ...interface{}) (*trillian.GetEntryAndProofResponse, error) {
+				return &trillian.GetEntryAndProofResponse{
+					Leaf: &trillian.LogLeaf{LeafIndex: req.LeafIndex, LeafValue: leaves[req.LeafIndex]},
+					Proof: &trillian.Proof{
+						LeafIndex: req.LeafIndex,
+						Hashes:    merklePath(req.LeafIndex, leaves[:req.TreeSize]),
+					},
+				}, nil
+			}).AnyTimes()
+		client.EXPECT().GetConsistencyProof(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetConsistencyProofRequest,
+				_ ...interface{}) (*trillian.GetConsistencyProofResponse, error) {
+				return &trillian.GetConsistencyProofResponse{Proof: &trillian.Proof{
+					Hashes: merkleSubproof(req.FirstTreeSize, leaves[:req.SecondTreeSize], true),
+				}}, nil
+			}).AnyTimes()
+
+		return client
+	}
+
+	getExport := func(t *testing.T, cmd *Cmd, first, second int64) (*GetAuditExportResponse, error) {
+		t.Helper()
+
+		src, err := json.Marshal(GetAuditExportRequest{Alias: alias, FirstTreeSize: first, SecondTreeSize: second})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		if err = lookupHandler(t, cmd, GetAuditExport)(&buf, bytes.NewBuffer(src)); err != nil {
+			return nil, err
+		}
+
+		var resp *GetAuditExportResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, newClient(ctrl))
+
+		resp, err := getExport(t, cmd, 2, 4)
+		require.NoError(t, err)
+		require.Equal(t, AuditExportSignatureType, resp.Export.SignatureType)
+		require.Len(t, resp.Export.Entries, 2)
+		require.Equal(t, []byte("c"), resp.Export.Entries[0].LeafInput)
+		require.Len(t, resp.Export.TreeHeads, 3)
+		require.Equal(t, merkleRoot(leaves[:2]), resp.Export.TreeHeads[0].SHA256RootHash)
+		require.Equal(t, merkleRoot(leaves[:4]), resp.Export.TreeHeads[1].SHA256RootHash)
+		require.Equal(t, uint64(5), resp.Export.STH.TreeSize)
+		require.NoError(t, vct.VerifyAuditExport(resp, cmd.PubKey))
+
+		// the export is signed, a tampered entry is detected
+		resp.Export.Entries[0].LeafInput = []byte("x")
+		require.Contains(t, vct.VerifyAuditExport(resp, cmd.PubKey).Error(), "export signature")
+
+		// the whole tree
+		resp, err = getExport(t, cmd, 0, 5)
+		require.NoError(t, err)
+		require.Len(t, resp.Export.TreeHeads, 1)
+		require.NoError(t, vct.VerifyAuditExport(resp, cmd.PubKey))
+	})
+
+	t.Run("Invalid range", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, NewMockTrillianLogClient(ctrl))
+
+		_, err := getExport(t, cmd, 3, 3)
+		require.EqualError(t, err, "validate GetAuditExport request: validation failed: "+
+			"first_tree_size 3 and second_tree_size 3 values is not a valid range")
+
+		_, err = getExport(t, cmd, 0, MaxAuditExportRange+1)
+		require.Contains(t, err.Error(), "exceeds 1000 entries")
+	})
+
+	t.Run("Tree is too small", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, err := getExport(t, newCmd(t, newClient(ctrl)), 2, 6)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+		require.Contains(t, err.Error(), "need tree size: 6 for export but only got: 5")
+	})
+
+	t.Run("Get entry and proof (error)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot}}, nil,
+		)
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+
+		_, err := getExport(t, newCmd(t, client), 0, 1)
+		require.EqualError(t, err, "get entry and proof: error")
+	})
+}
+
+func TestCmd_GetRandomEntries(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	leaves := make([][]byte, 20)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+
+	newCmd := func(t *testing.T, client TrillianLogClient) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:    Key{ID: newKID},
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	// newClient returns a log client serving the tree of the leaves.
+	newClient := func(ctrl *gomock.Controller, leaves [][]byte) *MockTrillianLogClient {
+		root, err := (&types.LogRootV1{
+			TreeSize:       uint64(len(leaves)),
+			RootHash:       merkleRoot(leaves),
+			TimestampNanos: uint64(time.Now().UnixNano()),
+		}).MarshalBinary()
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+		).AnyTimes()
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetEntryAndProofRequest,
+				_ ...interface{}) (*trillian.GetEntryAndProofResponse, error) {
+				return &trillian.GetEntryAndProofResponse{
+					Leaf: &trillian.LogLeaf{LeafIndex: req.LeafIndex, LeafValue: leaves[req.LeafIndex]},
+					Proof: &trillian.Proof{
+						LeafIndex: req.LeafIndex,
+						Hashes:    merklePath(req.LeafIndex, leaves[:req.TreeSize]),
+					},
+				}, nil
+			}).AnyTimes()
+
+		return client
+	}
+
+	getSample := func(t *testing.T, cmd *Cmd, count int, seed string) (*GetRandomEntriesResponse, error) {
+		t.Helper()
+
+		src, err := json.Marshal(GetRandomEntriesRequest{Alias: alias, Count: count, Seed: seed})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		if err = lookupHandler(t, cmd, GetRandomEntries)(&buf, bytes.NewBuffer(src)); err != nil {
+			return nil, err
+		}
+
+		var resp *GetRandomEntriesResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, newClient(ctrl, leaves))
+
+		resp, err := getSample(t, cmd, 5, "beacon")
+		require.NoError(t, err)
+		require.Equal(t, uint64(20), resp.STH.TreeSize)
+		require.Len(t, resp.Entries, 5)
+		require.NoError(t, vct.VerifyRandomEntries(resp, "beacon", 5, cmd.PubKey))
+
+		seen := map[int64]bool{}
+
+		for _, entry := range resp.Entries {
+			require.False(t, seen[entry.LeafIndex])
+			require.Equal(t, leaves[entry.LeafIndex], entry.LeafInput)
+
+			seen[entry.LeafIndex] = true
+		}
+
+		// the sample is derived from the seed
+		again, err := getSample(t, cmd, 5, "beacon")
+		require.NoError(t, err)
+		require.Equal(t, resp.Entries, again.Entries)
+
+		other, err := getSample(t, cmd, 5, "other beacon")
+		require.NoError(t, err)
+		require.NotEqual(t, resp.Entries, other.Entries)
+
+		require.EqualError(t, vct.VerifyRandomEntries(resp, "other beacon", 5, cmd.PubKey),
+			`sample is derived from seed "beacon", expected "other beacon"`)
+		require.EqualError(t, vct.VerifyRandomEntries(resp, "beacon", 6, cmd.PubKey), "got 5 entries, expected 6")
+
+		// an entry chosen by the log is detected
+		resp.Entries[0] = again.Entries[1]
+		require.Contains(t, vct.VerifyRandomEntries(resp, "beacon", 5, cmd.PubKey).Error(), "is not in the sample")
+
+		resp.STH.TreeSize++
+		require.Contains(t, vct.VerifyRandomEntries(resp, "beacon", 5, cmd.PubKey).Error(), "tree head signature")
+	})
+
+	t.Run("Whole tree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, newClient(ctrl, leaves[:3]))
+
+		resp, err := getSample(t, cmd, MaxRandomEntries, "")
+		require.NoError(t, err)
+		require.Len(t, resp.Entries, 3)
+		require.NoError(t, vct.VerifyRandomEntries(resp, "", MaxRandomEntries, cmd.PubKey))
+
+		resp.Entries[0].LeafInput = []byte("x")
+		require.Contains(t, vct.VerifyRandomEntries(resp, "", MaxRandomEntries, cmd.PubKey).Error(), "inclusion of leaf")
+	})
+
+	t.Run("Empty tree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, newClient(ctrl, nil))
+
+		resp, err := getSample(t, cmd, 5, "")
+		require.NoError(t, err)
+		require.Empty(t, resp.Entries)
+		require.NoError(t, vct.VerifyRandomEntries(resp, "", 5, cmd.PubKey))
+	})
+
+	t.Run("Invalid count", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, NewMockTrillianLogClient(ctrl))
+
+		_, err := getSample(t, cmd, 0, "")
+		require.EqualError(t, err, "validate GetRandomEntries request: validation failed: "+
+			"count value must be between 1 and 100")
+
+		_, err = getSample(t, cmd, MaxRandomEntries+1, "")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Get entry and proof (error)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot}}, nil,
+		)
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+
+		_, err := getSample(t, newCmd(t, client), 1, "")
+		require.EqualError(t, err, "get entry and proof: error")
+	})
+}
+
+func TestCmd_GetTile(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	leaves := make([][]byte, 600)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+
+	newCmd := func(t *testing.T, client TrillianLogClient) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:    Key{ID: newKID},
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	// newClient returns a log client serving the tree of the leaves, at most 100 leaves per range.
+	newClient := func(ctrl *gomock.Controller) *MockTrillianLogClient {
+		root, err := (&types.LogRootV1{TreeSize: uint64(len(leaves))}).MarshalBinary()
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest,
+				_ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) {
+				resp := &trillian.GetLeavesByRangeResponse{}
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count && i < req.StartIndex+100; i++ {
+					resp.Leaves = append(resp.Leaves, &trillian.LogLeaf{LeafIndex: i, LeafValue: leaves[i]})
+				}
+
+				return resp, nil
+			}).AnyTimes()
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetEntryAndProofRequest,
+				_ ...interface{}) (*trillian.GetEntryAndProofResponse, error) {
+				return &trillian.GetEntryAndProofResponse{
+					Leaf: &trillian.LogLeaf{LeafIndex: req.LeafIndex, LeafValue: leaves[req.LeafIndex]},
+					Proof: &trillian.Proof{
+						LeafIndex: req.LeafIndex,
+						Hashes:    merklePath(req.LeafIndex, leaves[:req.TreeSize]),
+					},
+				}, nil
+			}).AnyTimes()
+
+		return client
+	}
+
+	getTile := func(t *testing.T, cmd *Cmd, level int, index int64, width int) ([]byte, error) {
+		t.Helper()
+
+		src, err := json.Marshal(GetTileRequest{Alias: alias, Level: level, Index: index, Width: width})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		if err = lookupHandler(t, cmd, GetTile)(&buf, bytes.NewBuffer(src)); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	leafHashes := func(leaves [][]byte) []byte {
+		var hashes []byte
+
+		for _, leaf := range leaves {
+			hashes = append(hashes, hasher.DefaultHasher.HashLeaf(leaf)...)
+		}
+
+		return hashes
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, newClient(ctrl))
+
+		tile, err := getTile(t, cmd, 0, 1, 0)
+		require.NoError(t, err)
+		require.Equal(t, leafHashes(leaves[256:512]), tile)
+
+		// a partial tile is a prefix of the full tile, served from the cache
+		tile, err = getTile(t, cmd, 0, 1, 10)
+		require.NoError(t, err)
+		require.Equal(t, leafHashes(leaves[256:266]), tile)
+
+		tile, err = getTile(t, cmd, 0, 2, 88)
+		require.NoError(t, err)
+		require.Equal(t, leafHashes(leaves[512:]), tile)
+
+		tile, err = getTile(t, cmd, 1, 0, 2)
+		require.NoError(t, err)
+		require.Equal(t, append(merkleRoot(leaves[:256]), merkleRoot(leaves[256:512])...), tile)
+	})
+
+	t.Run("Native log", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+
+		log := merklelog.New(merklelog.NewMemStorage())
+
+		_, err := log.InitLog(ctx, &trillian.InitLogRequest{})
+		require.NoError(t, err)
+
+		for _, leaf := range leaves {
+			_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{Leaf: &trillian.LogLeaf{LeafValue: leaf}})
+			require.NoError(t, err)
+		}
+
+		// the stored tiles are the same as the tiles computed from the proofs
+		native, computed := newCmd(t, log), newCmd(t, newClient(ctrl))
+
+		for _, tc := range []struct {
+			level int
+			index int64
+			width int
+		}{{0, 0, 0}, {0, 2, 88}, {0, 2, 50}, {1, 0, 1}, {1, 0, 2}} {
+			expected, er := getTile(t, computed, tc.level, tc.index, tc.width)
+			require.NoError(t, er)
+
+			tile, er := getTile(t, native, tc.level, tc.index, tc.width)
+			require.NoError(t, er)
+			require.Equal(t, expected, tile)
+		}
+	})
+
+	t.Run("Beyond the tree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, newClient(ctrl))
+
+		_, err := getTile(t, cmd, 0, 2, 0)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+		require.Contains(t, err.Error(), "tile 0/2 of width 256 is beyond the tree size 600")
+
+		_, err = getTile(t, cmd, 1, 0, 3)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := newCmd(t, NewMockTrillianLogClient(ctrl))
+
+		_, err := getTile(t, cmd, MaxTileLevel+1, 0, 0)
+		require.EqualError(t, err, "validate GetTile request: validation failed: tile level 8 is not within [0,7]")
+
+		_, err = getTile(t, cmd, 0, -1, 0)
+		require.EqualError(t, err, "validate GetTile request: validation failed: tile index -1 is negative")
+
+		_, err = getTile(t, cmd, 0, 0, TileWidth)
+		require.EqualError(t, err, "validate GetTile request: validation failed: "+
+			"width 256 of a partial tile is not within [1,255]")
+	})
+
+	t.Run("Get leaves by range (error)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot}}, nil,
+		)
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+
+		_, err := getTile(t, newCmd(t, client), 0, 0, 1)
+		require.EqualError(t, err, "get leaves by range: error")
+	})
+}
+
+func TestCmd_GetEntryBundle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	root, err := (&types.LogRootV1{TreeSize: 3}).MarshalBinary()
+	require.NoError(t, err)
+
+	leaves := [][]byte{[]byte("a"), []byte("bc"), make([]byte, 300)}
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+		&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest,
+			_ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) {
+			resp := &trillian.GetLeavesByRangeResponse{}
+
+			for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+				resp.Leaves = append(resp.Leaves, &trillian.LogLeaf{LeafIndex: i, LeafValue: leaves[i]})
+			}
+
+			return resp, nil
+		}).AnyTimes()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+		Key:    Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	getBundle := func(index int64, width int) ([]byte, error) {
+		src, er := json.Marshal(GetEntryBundleRequest{Alias: alias, Index: index, Width: width})
+		require.NoError(t, er)
+
+		var buf bytes.Buffer
+
+		if er = lookupHandler(t, cmd, GetEntryBundle)(&buf, bytes.NewBuffer(src)); er != nil {
+			return nil, er
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	bundle, err := getBundle(0, 3)
+	require.NoError(t, err)
+	require.Equal(t, append([]byte{0, 0, 1, 'a', 0, 0, 2, 'b', 'c', 0, 1, 44}, leaves[2]...), bundle)
+
+	_, err = getBundle(0, 0)
+	require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+	_, err = getBundle(-1, 0)
+	require.EqualError(t, err, "validate GetEntryBundle request: validation failed: tile index -1 is negative")
+}
+
+func TestCmd_WarmCache(t *testing.T) {
+	leaves := make([][]byte, 600)
+	for i := range leaves {
+		leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+	}
+
+	root, err := (&types.LogRootV1{TreeSize: uint64(len(leaves))}).MarshalBinary()
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T, client TrillianLogClient) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:    Key{ID: newKID},
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// the tree head is fetched and the two full tiles are computed once, by the warm-up
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+		).Times(1)
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest,
+				_ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) {
+				resp := &trillian.GetLeavesByRangeResponse{}
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count; i++ {
+					resp.Leaves = append(resp.Leaves, &trillian.LogLeaf{LeafIndex: i, LeafValue: leaves[i]})
+				}
+
+				return resp, nil
+			}).Times(2)
+
+		cmd := newCmd(t, client)
+		require.NoError(t, cmd.WarmCache(context.Background()))
+
+		for _, tc := range []struct {
+			index int64
+			width int
+			count int
+		}{{0, 0, TileWidth}, {1, 0, TileWidth}, {1, 10, 10}} {
+			src, er := json.Marshal(GetTileRequest{Alias: alias, Level: 0, Index: tc.index, Width: tc.width})
+			require.NoError(t, er)
+
+			var buf bytes.Buffer
+
+			require.NoError(t, lookupHandler(t, cmd, GetTile)(&buf, bytes.NewBuffer(src)))
+
+			var expected []byte
+
+			start := int(tc.index) * TileWidth
+			for _, leaf := range leaves[start : start+tc.count] {
+				expected = append(expected, hasher.DefaultHasher.HashLeaf(leaf)...)
+			}
+
+			require.Equal(t, expected, buf.Bytes())
+		}
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.EqualError(t, newCmd(t, client).WarmCache(ctx), "warm log maple2021: context canceled")
+	})
+
+	t.Run("Get latest signed log root (error)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+
+		require.EqualError(t, newCmd(t, client).WarmCache(context.Background()),
+			"warm log maple2021: get latest signed log root: error")
+	})
+}
+
+// merkleRoot returns the RFC 6962 Merkle tree hash of the leaves.
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return hasher.DefaultHasher.EmptyRoot()
+	case 1:
+		return hasher.DefaultHasher.HashLeaf(leaves[0])
+	}
+
+	k := splitPoint(len(leaves))
+
+	return hasher.DefaultHasher.HashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merklePath returns the RFC 6962 audit path of the leaf.
+func merklePath(index int64, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+
+	k := int64(splitPoint(len(leaves)))
+
+	if index < k {
+		return append(merklePath(index, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+
+	return append(merklePath(index-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// merkleSubproof returns the RFC 6962 consistency proof of the tree of the size with the tree of the leaves.
+func merkleSubproof(size int64, leaves [][]byte, complete bool) [][]byte {
+	if size == int64(len(leaves)) {
+		if complete {
+			return nil
+		}
+
+		return [][]byte{merkleRoot(leaves)}
+	}
+
+	k := int64(splitPoint(len(leaves)))
+
+	if size <= k {
+		return append(merkleSubproof(size, leaves[:k], complete), merkleRoot(leaves[k:]))
+	}
+
+	return append(merkleSubproof(size-k, leaves[k:], false), merkleRoot(leaves[:k]))
+}
+
+// splitPoint returns the largest power of two smaller than n.
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+
+	return k
+}
+
+func TestCmd_GetEntryAndProof(t *testing.T) {
+	const (
+		logID   int64 = 123
+		keyType       = kms.ECDSAP256TypeIEEEP1363
+	)
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetEntryAndProofResponse{
+				Proof: &trillian.Proof{},
+				Leaf:  &trillian.LogLeaf{LeafValue: []byte{0}},
+				SignedLogRoot: &trillian.SignedLogRoot{
+					LogRoot: logRoot,
+				},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: newKID,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		fr, frs := bytes.Buffer{}, GetEntryAndProofResponse{}
+
+		require.NoError(t, cmd.GetEntryAndProof(&fr,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`)))
+		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+
+		hr, hrs := bytes.Buffer{}, GetEntryAndProofResponse{}
+
+		require.NoError(t, lookupHandler(t, cmd, GetEntryAndProof)(&hr,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`)))
+		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+
+		require.Equal(t, frs.AuditPath, hrs.AuditPath)
+		require.Equal(t, frs.ExtraData, hrs.ExtraData)
+		require.Equal(t, frs.LeafInput, hrs.LeafInput)
+		require.Nil(t, frs.Debug)
+	})
+
+	t.Run("Debug", func(t *testing.T) {
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(gomock.NewController(t))
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetEntryAndProofResponse{
+				Proof:         &trillian.Proof{},
+				Leaf:          &trillian.LogLeaf{LeafValue: []byte{0}},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:    Key{ID: newKID},
+		}, nil)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, cmd.GetEntryAndProof(&buf,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1,"debug":true}`)))
+
+		var resp *GetEntryAndProofResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Equal(t, []byte{0}, resp.LeafInput)
+		require.NotNil(t, resp.Debug)
+		require.False(t, resp.Debug.CacheHit)
+
+		var steps []string
+		for _, step := range resp.Debug.Steps {
+			steps = append(steps, step.Name)
+		}
+
+		require.Equal(t, []string{"proof_cache", "trillian_rpc", "extra_data", "annotations", "encode"}, steps)
+	})
+
+	t.Run("Bad tree size", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetEntryAndProofResponse{
+				Proof: &trillian.Proof{},
+				Leaf:  &trillian.LogLeaf{LeafValue: []byte{0}},
+				SignedLogRoot: &trillian.SignedLogRoot{
+					LogRoot: logRoot,
+				},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: newKID,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "bad request: need tree size: 2 for proof, got: 1"
+
+		require.EqualError(t, cmd.GetEntryAndProof(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 2}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetEntryAndProof)(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 2}`),
+		), expErr)
+	})
+
+	t.Run("Corrupted data received", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetEntryAndProof(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetEntryAndProofResponse{
+				Leaf:          &trillian.LogLeaf{LeafValue: []byte(`{"credentialSubject":{"name":"Jayden Doe"}}`)},
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: logRoot},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+				Client:     client,
+			}},
+			Key: Key{
+				ID: newKID,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "internal error: corrupted data received: leaf_index:0 merkle_leaf_hash: " +
+			"leaf_value:[43 bytes, sha256:0aa5f3cff9ef994b] extra_data:[empty]"
+
+		require.EqualError(t, cmd.GetEntryAndProof(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`),
+		), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, GetEntryAndProof)(nil,
+			bytes.NewBufferString(`{"alias":"maple2021","tree_size": 1}`),
+		), expErr)
+	})
+}
+
+func TestCreateLeaf(t *testing.T) {
+	simpleVC := &verifiable.Credential{
+		Context: []string{"https://www.w3.org/2018/credentials/v1"},
+		Subject: "did:key:123",
+		Issuer:  verifiable.Issuer{ID: "did:key:123"},
+		Issued:  &util.TimeWrapper{},
+		Types:   []string{"VerifiableCredential"},
+		Proofs:  []verifiable.Proof{{}, {}},
+	}
+
+	_, err := CreateLeaf(1, simpleVC)
+	require.NoError(t, err)
+
+	require.Len(t, simpleVC.Proofs, 2)
+}
+
+func TestCmd_AddVC(t *testing.T) {
+	const (
+		kid     = "kid"
+		keyType = kms.ECDSAP256TypeIEEEP1363
+	)
+
+	documentLoader := ldcontext.DocumentLoader(t)
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(
+			&trillian.QueueLeafResponse{
+				QueuedLeaf: &trillian.QueuedLogLeaf{
+					Leaf: &trillian.LogLeaf{LeafValue: queuedLeafValue},
+				},
+			}, nil,
+		).Times(2)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: newKID,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		fr, frs := bytes.Buffer{}, AddVCResponse{}
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, cmd.AddVC(&fr, bytes.NewBuffer(req)))
+		require.NoError(t, json.Unmarshal(fr.Bytes(), &frs))
+
+		hr, hrs := bytes.Buffer{}, AddVCResponse{}
+
+		require.NoError(t, lookupHandler(t, cmd, AddVC)(&hr, bytes.NewBuffer(req)))
+		require.NoError(t, json.Unmarshal(hr.Bytes(), &hrs))
+
+		require.Equal(t, frs.Timestamp, hrs.Timestamp)
+		require.Equal(t, frs.ID, hrs.ID)
+		require.Equal(t, frs.Extensions, hrs.Extensions)
+		require.Equal(t, frs.SVCTVersion, hrs.SVCTVersion)
+
+		require.NotEmpty(t, frs.Signature)
+		require.NotEmpty(t, hrs.Signature)
+
+		var sig *DigitallySigned
+		require.NoError(t, json.Unmarshal(hrs.Signature, &sig))
+
+		require.NotEmpty(t, sig.Signature)
+		require.NotEmpty(t, sig.Algorithm.Type)
+		require.NotEmpty(t, sig.Algorithm.Signature)
+	})
+
+	t.Run("Document loader error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     NewMockTrillianLogClient(ctrl),
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: newKID,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "document loader"
+		require.Contains(t, cmd.AddVC(nil, bytes.NewBuffer(req)).Error(), expErr)
+		require.Contains(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)).Error(), expErr)
+	})
+
+	t.Run("Copy vc failed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
+			},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "decode AddVC request: internal error"
+		require.EqualError(t, cmd.AddVC(nil, &readerMock{errors.New("EOF")}), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, &readerMock{errors.New("EOF")}), expErr)
+	})
+
+	t.Run("Parse credential", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Key: Key{
+				ID: kid,
+			},
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+			}},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		const expErr = "parse credential: decode new credential: embedded proof is not JSON: unexpected end of JSON input"
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBufferString(`{"alias":"maple2021"}`)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBufferString(`{"alias":"maple2021"}`)), expErr)
+	})
+
+	t.Run("Parse credential error is scrubbed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS:             km,
+			Key:             Key{ID: kid},
+			Logs:            []Log{{Alias: alias, Permission: "w"}},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+
+		req, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: []byte(`{
+			"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":["VerifiableCredential"],
+			"issuer":"did:example:issuer",
+			"issuanceDate":"Jayden Doe",
+			"credentialSubject":{"id":"did:example:subject"}
+		}`)})
+		require.NoError(t, err)
+
+		err = lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse credential")
+		require.NotContains(t, err.Error(), "Jayden")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Issuer is not trusted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Issuers:    []string{"issuer_a"},
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: kid,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "bad request: issuer did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N2 is not in a list" // nolint: lll
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+	})
+
+	t.Run("Derived credential", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		VDR := vdr.New(vdr.WithVDR(key.New()))
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     NewMockTrillianLogClient(ctrl),
+			}},
+			VDR:             VDR,
+			Key:             Key{ID: kid},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+
+		publicKeyFetcher := verifiable.NewVDRKeyResolver(VDR).PublicKeyFetcher()
+
+		base, err := verifiable.ParseCredential(verifiableCredential,
+			verifiable.WithPublicKeyFetcher(publicKeyFetcher),
+			verifiable.WithJSONLDDocumentLoader(documentLoader),
+		)
+		require.NoError(t, err)
+
+		derived, err := base.GenerateBBSSelectiveDisclosure(map[string]interface{}{
+			"@context": []interface{}{
+				"https://www.w3.org/2018/credentials/v1",
+				"https://www.w3.org/2018/credentials/examples/v1",
+				"https://w3id.org/security/bbs/v1",
+			},
+			"type": []interface{}{"VerifiableCredential", "UniversityDegreeCredential"},
+		}, []byte("nonce"), verifiable.WithPublicKeyFetcher(publicKeyFetcher),
+			verifiable.WithJSONLDDocumentLoader(documentLoader))
+		require.NoError(t, err)
+
+		vcEntry, err := json.Marshal(derived)
+		require.NoError(t, err)
+
+		req, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: vcEntry})
+		require.NoError(t, err)
+
+		err = lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req))
+		require.EqualError(t, err,
+			"derived credential (BbsBlsSignatureProof2020) is not logged, log its base credential")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Queue leaf error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(nil, errors.New("error")).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: kid,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "queue leaf: error"
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+	})
+
+	t.Run("No leaf", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(&trillian.QueueLeafResponse{}, nil).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: kid,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "internal error: no leaf"
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+	})
+
+	t.Run("Corrupted leaf", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(&trillian.QueueLeafResponse{
+			QueuedLeaf: &trillian.QueuedLogLeaf{
+				Leaf: &trillian.LogLeaf{
+					LeafValue: []byte(`[]`),
+				},
+			},
+		}, nil).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: kid,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "failed to reconstruct MerkleTreeLeaf: json: cannot unmarshal array into Go value of type command.MerkleTreeLeaf" // nolint: lll
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+	})
+
+	t.Run("Sign error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cr := NewMockCrypto(ctrl)
+		cr.EXPECT().Sign(gomock.Any(), gomock.Any()).Return([]byte{}, errors.New("error")).Times(2)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(&trillian.QueueLeafResponse{
+			QueuedLeaf: &trillian.QueuedLogLeaf{
+				Leaf: &trillian.LogLeaf{
+					LeafValue: queuedLeafValue,
+				},
+			},
+		}, nil).Times(2)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			Crypto: cr,
+			VDR:    vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: kid,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "sign V1 VCTS: sign TreeHeadSignature: error"
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+	})
+
+	t.Run("Action forbidden", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "r",
+			}},
+			Crypto: NewMockCrypto(ctrl),
+			VDR:    vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: kid,
+			},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: documentLoader},
+		}, nil)
+		require.NoError(t, err)
+		require.NotNil(t, cmd)
+
+		req, err := json.Marshal(AddVCRequest{
+			Alias:   alias,
+			VCEntry: verifiableCredential,
+		})
+		require.NoError(t, err)
+
+		const expErr = "has permissions: action forbidden for \"maple2021\""
+		require.EqualError(t, cmd.AddVC(nil, bytes.NewBuffer(req)), expErr)
+		require.EqualError(t, lookupHandler(t, cmd, AddVC)(nil, bytes.NewBuffer(req)), expErr)
+	})
+
+	t.Run("Entry too large", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		km := NewMockKeyManager(ctrl)
+		km.EXPECT().Get(kid).Return(nil, nil)
+		km.EXPECT().ExportPubKeyBytes(kid).Return([]byte(`public key`), keyType, nil)
+
+		cmd, err := New(&Config{
+			KMS: km,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     NewMockTrillianLogClient(ctrl),
+			}},
+			Crypto:       NewMockCrypto(ctrl),
+			VDR:          vdr.New(vdr.WithVDR(key.New())),
+			Key:          Key{ID: kid},
+			MaxEntrySize: 100,
+		}, nil)
+		require.NoError(t, err)
+
+		req, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: verifiableCredential})
+		require.NoError(t, err)
+
+		err = cmd.AddVC(nil, bytes.NewBuffer(req))
+		require.EqualError(t, err, fmt.Sprintf("bad request: entry of %d bytes exceeds the max size of 100 bytes",
+			len(verifiableCredential)))
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+}
+
+func TestCmd_AddVC_Envelope(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	documentLoader := ldcontext.DocumentLoader(t)
+
+	newCmd := func(t *testing.T, client TrillianLogClient, opts ...func(*Config)) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cfg := &Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			VDR: vdr.New(vdr.WithVDR(key.New())),
+			Key: Key{
+				ID: newKID,
+			},
+			DocumentLoaders:      map[string]jsonld.DocumentLoader{alias: documentLoader},
+			CallbackHosts:        []string{"127.0.0.1"},
+			CallbackAllowPrivate: true,
+		}
+
+		for _, opt := range opts {
+			opt(cfg)
+		}
+
+		cmd, err := New(cfg, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	newRequest := func(t *testing.T, options *AddVCOptions) []byte {
+		t.Helper()
+
+		envelope, err := json.Marshal(AddVCEnvelope{Credential: verifiableCredential, Options: options})
+		require.NoError(t, err)
+
+		req, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+		require.NoError(t, err)
+
+		return req
+	}
+
+	t.Run("Idempotency key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var logged *trillian.LogLeaf
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				// the key is scoped by the log and separated from the identities of the entries
+				expected := sha256.Sum256([]byte("idem:maple2021:key-1"))
+				require.Equal(t, expected[:], req.Leaf.LeafIdentityHash)
+
+				if logged == nil {
+					logged = req.Leaf
+				}
+
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: logged}}, nil
+			},
+		).Times(3)
+
+		cmd := newCmd(t, client)
+
+		var resp bytes.Buffer
+
+		require.NoError(t, cmd.AddVC(&resp,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{Tenant: alias, IdempotencyKey: "key-1"})),
+		))
+
+		var result AddVCResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+		require.NotEmpty(t, result.Signature)
+
+		// a resubmission with the key returns the SCT of the logged credential
+		resp.Reset()
+		require.NoError(t, cmd.AddVC(&resp,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{Tenant: alias, IdempotencyKey: "key-1"})),
+		))
+
+		var again AddVCResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &again))
+		require.Equal(t, result.Timestamp, again.Timestamp)
+
+		// the key is not reused for another credential
+		var other map[string]interface{}
+		require.NoError(t, json.Unmarshal(verifiableCredential, &other))
+
+		delete(other, "proof")
+		other["id"] = "http://example.gov/credentials/other"
+
+		credential, err := json.Marshal(other)
+		require.NoError(t, err)
+
+		envelope, err := json.Marshal(AddVCEnvelope{
+			Credential: credential,
+			Options:    &AddVCOptions{Tenant: alias, IdempotencyKey: "key-1"},
+		})
+		require.NoError(t, err)
+
+		src, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+		require.NoError(t, err)
+
+		err = cmd.AddVC(&bytes.Buffer{}, bytes.NewBuffer(src))
+		require.EqualError(t, err, "idempotency key is used by another submission")
+		require.Equal(t, http.StatusConflict, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var resp bytes.Buffer
+
+		require.NoError(t, newCmd(t, NewMockTrillianLogClient(ctrl)).AddVC(&resp,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{DryRun: true})),
+		))
+
+		var result AddVCResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+		require.NotZero(t, result.Timestamp)
+		require.Empty(t, result.Signature)
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		notified := make(chan AddVCResponse, 1)
+
+		var verifier *webhook.Verifier
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := verifier.VerifyRequest(r)
+			require.NoError(t, err)
+
+			var result AddVCResponse
+			require.NoError(t, json.Unmarshal(body, &result))
+
+			notified <- result
+		}))
+		defer server.Close()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(
+			&trillian.QueueLeafResponse{
+				QueuedLeaf: &trillian.QueuedLogLeaf{
+					Leaf: &trillian.LogLeaf{LeafValue: queuedLeafValue},
+				},
+			}, nil,
+		)
+
+		cmd := newCmd(t, client)
+
+		pubKey, err := jwksupport.PubKeyBytesToJWK(cmd.PubKey, keyType)
+		require.NoError(t, err)
+
+		verifier = webhook.NewVerifier(webhook.StaticKeys(map[string]gocrypto.PublicKey{
+			base64.RawURLEncoding.EncodeToString(cmd.VCLogID[:]): pubKey.Key,
+		}))
+
+		var resp bytes.Buffer
+
+		require.NoError(t, cmd.AddVC(&resp,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{CallbackURL: server.URL})),
+		))
+
+		var result AddVCResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+
+		select {
+		case callback := <-notified:
+			require.Equal(t, result, callback)
+		case <-time.After(time.Second * 5):
+			t.Fatal("callback was not notified")
+		}
+	})
+
+	t.Run("Callback host not allowed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		err := newCmd(t, NewMockTrillianLogClient(ctrl)).AddVC(nil,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{CallbackURL: "http://169.254.169.254/latest/meta-data"})),
+		)
+		require.EqualError(t, err, `check callback: validation failed: callbackURL host "169.254.169.254" is not allowed`)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		// no callback is accepted without allowed hosts
+		err = newCmd(t, NewMockTrillianLogClient(ctrl), func(cfg *Config) { cfg.CallbackHosts = nil }).AddVC(nil,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{CallbackURL: "https://example.com/callback"})),
+		)
+		require.EqualError(t, err, `check callback: validation failed: callbackURL host "example.com" is not allowed`)
+	})
+
+	t.Run("Callback to a private address", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		notified := make(chan struct{}, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			notified <- struct{}{}
+		}))
+		defer server.Close()
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).Return(
+			&trillian.QueueLeafResponse{
+				QueuedLeaf: &trillian.QueuedLogLeaf{
+					Leaf: &trillian.LogLeaf{LeafValue: queuedLeafValue},
+				},
+			}, nil,
+		)
+
+		cmd := newCmd(t, client, func(cfg *Config) { cfg.CallbackAllowPrivate = false })
+
+		// the host is allowed, its address is not
+		require.NoError(t, cmd.AddVC(&bytes.Buffer{},
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{CallbackURL: server.URL})),
+		))
+
+		select {
+		case <-notified:
+			t.Fatal("callback to a loopback address was notified")
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("Tenant mismatch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		err := newCmd(t, NewMockTrillianLogClient(ctrl)).AddVC(nil,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{Tenant: "maple2020"})),
+		)
+		require.EqualError(t, err, `bad request: tenant "maple2020" does not match log "maple2021"`)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Invalid callback URL", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		err := newCmd(t, NewMockTrillianLogClient(ctrl)).AddVC(nil,
+			bytes.NewBuffer(newRequest(t, &AddVCOptions{CallbackURL: "/callback"})),
+		)
+		require.EqualError(t, err, "parse submission: validate options: validation failed: "+
+			`callbackURL "/callback" must be an absolute http(s) URL`)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (s ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), data), nil
+}
+
+func (s ed25519Signer) Headers() jose.Headers {
+	return jose.Headers{jose.HeaderAlgorithm: "EdDSA"}
+}
+
+func newDisclosure(t *testing.T, parts ...interface{}) string {
+	t.Helper()
+
+	raw, err := json.Marshal(parts)
+	require.NoError(t, err)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func disclosureDigest(disclosure string) string {
+	digest := sha256.Sum256([]byte(disclosure))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// newIssuerJWT returns the credential with the subject signed as a JWT by a did:key issuer.
+func newIssuerJWT(t *testing.T, subject map[string]interface{}) string {
+	t.Helper()
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	did, keyID := fingerprint.CreateDIDKey(pubKey)
+
+	vc, err := verifiable.ParseCredential([]byte(fmt.Sprintf(`{
+		"@context": ["https://www.w3.org/2018/credentials/v1", "https://www.w3.org/2018/credentials/examples/v1"],
+		"id": "http://example.gov/credentials/3732",
+		"type": ["VerifiableCredential", "UniversityDegreeCredential"],
+		"issuer": %q,
+		"issuanceDate": "2020-03-10T04:24:12.164Z",
+		"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}
+	}`, did)), verifiable.WithDisabledProofCheck(), verifiable.WithJSONLDDocumentLoader(ldcontext.DocumentLoader(t)))
+	require.NoError(t, err)
+
+	vc.Subject = subject
+
+	claims, err := vc.JWTClaims(false)
+	require.NoError(t, err)
+
+	jws, err := claims.MarshalJWS(verifiable.EdDSA, ed25519Signer(privKey), keyID)
+	require.NoError(t, err)
+
+	return jws
+}
+
+func TestEntryCID(t *testing.T) {
+	// the CID of an empty raw block
+	require.Equal(t, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku", EntryCID(nil))
+
+	digest, err := ParseEntryCID(EntryCID([]byte("entry")))
+	require.NoError(t, err)
+
+	expected := sha256.Sum256([]byte("entry"))
+	require.Equal(t, expected[:], digest)
+
+	for _, tc := range []struct {
+		cid string
+		err string
+	}{
+		{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "decode CID: selected encoding not supported"},
+		// dag-pb
+		{"bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi", "CID must address raw data"},
+		// identity multihash
+		{"bafkqaaa", "CID must have a SHA2-256 multihash"},
+		{"b", "CID must be a CIDv1"},
+	} {
+		_, err = ParseEntryCID(tc.cid)
+		require.EqualError(t, err, "validation failed: "+tc.err, tc.cid)
+	}
+}
+
+func TestCmd_GetEntryByCID(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	var leaves []*trillian.LogLeaf
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			leaf := req.Leaf
+			leaf.LeafIndex = int64(len(leaves))
+			leaf.MerkleLeafHash = hasher.DefaultHasher.HashLeaf(leaf.LeafValue)
+			leaves = append(leaves, leaf)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: leaf}}, nil
+		},
+	).AnyTimes()
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _ *trillian.GetLatestSignedLogRootRequest,
+			_ ...interface{}) (*trillian.GetLatestSignedLogRootResponse, error) {
+			root, err := (&types.LogRootV1{TreeSize: uint64(len(leaves))}).MarshalBinary()
+			require.NoError(t, err)
+
+			return &trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil
+		},
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			return &trillian.GetLeavesByRangeResponse{Leaves: leaves[req.StartIndex : req.StartIndex+req.Count]}, nil
+		},
+	).AnyTimes()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		Key: Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	getEntry := func(cid string) (*GetEntryByCIDResponse, error) {
+		src, err := json.Marshal(GetEntryByCIDRequest{Alias: alias, CID: cid})
+		require.NoError(t, err)
+
+		var resp bytes.Buffer
+		if err = lookupHandler(t, cmd, GetEntryByCID)(&resp, bytes.NewBuffer(src)); err != nil {
+			return nil, err
+		}
+
+		var result *GetEntryByCIDResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+
+		return result, nil
+	}
+
+	var cids []string
+
+	for i := 0; i < 2; i++ {
+		entry, err := json.Marshal(Commitment{Hash: bytes.Repeat([]byte{byte(i)}, sha256.Size)})
+		require.NoError(t, err)
+
+		src, err := json.Marshal(AddEntryRequest{Alias: alias, EntryType: CommitmentLogEntryType, Entry: entry})
+		require.NoError(t, err)
+
+		var resp bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&resp, bytes.NewBuffer(src)))
+
+		var sct *AddVCResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &sct))
+		require.Regexp(t, "^bafkrei", sct.CID)
+
+		cids = append(cids, sct.CID)
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		resp, err := getEntry(cids[1])
+		require.NoError(t, err)
+		require.Equal(t, cids[1], resp.CID)
+		require.Equal(t, int64(1), resp.LeafIndex)
+		require.Equal(t, leaves[1].MerkleLeafHash, resp.MerkleLeafHash)
+		require.Equal(t, leaves[1].LeafValue, resp.LeafInput)
+		require.Equal(t, cids[1], EntryCID(resp.Entry))
+
+		var leaf MerkleTreeLeaf
+		require.NoError(t, json.Unmarshal(resp.LeafInput, &leaf))
+		require.Equal(t, leaf.TimestampedEntry.VCEntry, resp.Entry)
+
+		// the CID is accepted in another multibase encoding
+		digest, err := ParseEntryCID(cids[0])
+		require.NoError(t, err)
+
+		base58, err := multibase.Encode(multibase.Base58BTC, append([]byte{0x01, 0x55, 0x12, 0x20}, digest...))
+		require.NoError(t, err)
+
+		resp, err = getEntry(base58)
+		require.NoError(t, err)
+		require.Equal(t, cids[0], resp.CID)
+		require.Equal(t, int64(0), resp.LeafIndex)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		cid := EntryCID([]byte("not logged"))
+
+		_, err := getEntry(cid)
+		require.EqualError(t, err, "no entry with CID "+cid+" is logged")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Invalid CID", func(t *testing.T) {
+		_, err := getEntry("bafkqaaa")
+		require.EqualError(t, err, "validation failed: CID must have a SHA2-256 multihash")
+	})
+
+	t.Run("Empty request", func(t *testing.T) {
+		err := cmd.GetEntryByCID(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty GetEntryByCID request")
+	})
+}
+
+func TestCmd_GetReceipt(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	receipts, err := mem.NewProvider().OpenStore("receipts")
+	require.NoError(t, err)
+
+	cfg := &Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		VDR:             vdr.New(vdr.WithVDR(key.New())),
+		Key:             Key{ID: newKID},
+		DocumentLoaders: map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
+		ReceiptStore:    receipts,
+	}
+
+	cmd, err := New(cfg, nil)
+	require.NoError(t, err)
+
+	envelope, err := json.Marshal(AddVCEnvelope{
+		Credential: verifiableCredential,
+		Options:    &AddVCOptions{IdempotencyKey: "key-1"},
+	})
+	require.NoError(t, err)
+
+	src, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+	require.NoError(t, err)
+
+	var sct bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, AddVC)(&sct, bytes.NewBuffer(src)))
+
+	var receipt bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, GetReceipt)(&receipt,
+		bytes.NewBufferString(`{"alias":"maple2021","idempotency_key":"key-1"}`)))
+	require.JSONEq(t, sct.String(), receipt.String())
+
+	err = lookupHandler(t, cmd, GetReceipt)(&bytes.Buffer{},
+		bytes.NewBufferString(`{"alias":"maple2021","idempotency_key":"key-2"}`))
+	require.EqualError(t, err, "no receipt for the idempotency key")
+	require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+	err = lookupHandler(t, cmd, GetReceipt)(&bytes.Buffer{}, bytes.NewBufferString(`{"alias":"maple2021"}`))
+	require.Contains(t, err.Error(), "idempotency key is empty")
+
+	err = lookupHandler(t, cmd, GetReceipt)(&bytes.Buffer{},
+		bytes.NewBufferString(`{"alias":"unknown","idempotency_key":"key-1"}`))
+	require.Contains(t, err.Error(), "has permissions")
+
+	err = lookupHandler(t, cmd, GetReceipt)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+	require.Contains(t, err.Error(), "decode GetReceipt request")
+
+	cfg.ReceiptStore = nil
+
+	cmd, err = New(cfg, nil)
+	require.NoError(t, err)
+
+	err = lookupHandler(t, cmd, GetReceipt)(&bytes.Buffer{},
+		bytes.NewBufferString(`{"alias":"maple2021","idempotency_key":"key-1"}`))
+	require.EqualError(t, err, "receipts are not persisted")
+}
+
+func TestParseSDJWT(t *testing.T) {
+	givenName := newDisclosure(t, "2GLC42sKQveCfGfryNRN9w", "given_name", "Jayden")
+	nationality := newDisclosure(t, "eluV5Og3gSNII8EYnsxA_A", "DE")
+	address := newDisclosure(t, "6Ij7tM-a5iVPGboS5tmvVA", "address", map[string]interface{}{
+		"_sd":         []string{disclosureDigest(givenName)},
+		"nationality": []interface{}{map[string]string{"...": disclosureDigest(nationality)}},
+	})
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"_sd_alg": "sha-256",
+		"vc": map[string]interface{}{
+			"credentialSubject": map[string]interface{}{"_sd": []string{disclosureDigest(address)}},
+		},
+	})
+	require.NoError(t, err)
+
+	issuerJWT := "eyJhbGciOiJFZERTQSJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+
+	t.Run("Success", func(t *testing.T) {
+		require.False(t, IsSDJWT([]byte(issuerJWT)))
+		require.False(t, IsSDJWT(verifiableCredential))
+		require.True(t, IsSDJWT([]byte(issuerJWT+"~")))
+
+		// nested disclosures in any order, with a key binding JWT
+		sdJWT, err := ParseSDJWT(issuerJWT + "~" + nationality + "~" + givenName + "~" + address + "~kb.jwt.sig")
+		require.NoError(t, err)
+		require.Equal(t, issuerJWT, sdJWT.IssuerJWT)
+		require.Equal(t, []string{nationality, givenName, address}, sdJWT.Disclosures)
+		require.Equal(t, "kb.jwt.sig", sdJWT.KeyBinding)
+
+		// nothing disclosed
+		sdJWT, err = ParseSDJWT(issuerJWT + "~")
+		require.NoError(t, err)
+		require.Empty(t, sdJWT.Disclosures)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		unsupportedAlg, err := json.Marshal(map[string]interface{}{"_sd_alg": "md5"})
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			name  string
+			sdJWT string
+			err   string
+		}{
+			{"No separator", issuerJWT, "SD-JWT must have at least one"},
+			{"Invalid JWT", "jwt~", "issuer-signed JWT: JWT must have 3 parts"},
+			{"Invalid payload", "a.b!.c~", "issuer-signed JWT: decode payload"},
+			{"Unsupported hash", "a." + base64.RawURLEncoding.EncodeToString(unsupportedAlg) + ".c~",
+				"_sd_alg md5 is not supported"},
+			{"Not committed", issuerJWT + "~" + newDisclosure(t, "salt", "family_name", "Doe") + "~",
+				"disclosure 0 does not match any digest"},
+			// the digest of the nested disclosure is in the undisclosed address
+			{"Parent not disclosed", issuerJWT + "~" + givenName + "~", "disclosure 0 does not match any digest"},
+			{"Repeated", issuerJWT + "~" + address + "~" + address + "~", "disclosure 1 is repeated"},
+			{"Invalid disclosure", issuerJWT + "~e30~", "disclosure 0: unmarshal"},
+			{"Invalid elements", issuerJWT + "~" + newDisclosure(t, "salt") + "~", "must have 2 or 3 elements"},
+			{"Invalid salt", issuerJWT + "~" + newDisclosure(t, 1, "DE") + "~", "salt must be a string"},
+			{"Invalid name", issuerJWT + "~" + newDisclosure(t, "salt", "_sd", "DE") + "~",
+				"claim name must be a string other than _sd and ..."},
+		} {
+			_, err := ParseSDJWT(tc.sdJWT)
+			require.Error(t, err, tc.name)
+			require.Contains(t, err.Error(), tc.err, tc.name)
+			require.NotContains(t, err.Error(), "Doe", tc.name)
+		}
+	})
+}
+
+func TestCmd_AddVC_SDJWT(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	givenName := newDisclosure(t, "2GLC42sKQveCfGfryNRN9w", "given_name", "Jayden")
+	familyName := newDisclosure(t, "eluV5Og3gSNII8EYnsxA_A", "family_name", "Doe")
+
+	issuerJWT := newIssuerJWT(t, map[string]interface{}{
+		"id":  "did:example:ebfeb1f712ebc6f1c276e12ec21",
+		"_sd": []string{disclosureDigest(givenName), disclosureDigest(familyName)},
+	})
+
+	var leaves []*MerkleTreeLeaf
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			var leaf *MerkleTreeLeaf
+			require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+
+			leaves = append(leaves, leaf)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).Times(2)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "w",
+			Client:     client,
+		}},
+		VDR:             vdr.New(vdr.WithVDR(key.New())),
+		Key:             Key{ID: newKID},
+		DocumentLoaders: map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
+	}, nil)
+	require.NoError(t, err)
+
+	addVC := func(sdJWT string) error {
+		req, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: []byte(sdJWT)})
+		require.NoError(t, err)
+
+		return lookupHandler(t, cmd, AddVC)(&bytes.Buffer{}, bytes.NewBuffer(req))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		require.NoError(t, addVC(issuerJWT+"~"+givenName+"~"+familyName+"~"))
+		require.NoError(t, addVC(issuerJWT+"~"+familyName+"~kb.jwt.sig"))
+
+		// the issuer-signed payload is logged, whatever is disclosed
+		require.Len(t, leaves, 2)
+		require.Equal(t, leaves[0].TimestampedEntry.VCEntry, leaves[1].TimestampedEntry.VCEntry)
+		require.Contains(t, string(leaves[0].TimestampedEntry.VCEntry), disclosureDigest(givenName))
+		require.NotContains(t, string(leaves[0].TimestampedEntry.VCEntry), "Jayden")
+	})
+
+	t.Run("Disclosure does not match", func(t *testing.T) {
+		err := addVC(issuerJWT + "~" + newDisclosure(t, "salt", "given_name", "Jane") + "~")
+		require.Contains(t, err.Error(), "parse SD-JWT: disclosure 0 does not match any digest")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Invalid issuer signature", func(t *testing.T) {
+		err := addVC(issuerJWT[:len(issuerJWT)-4] + "AAAA~" + givenName + "~")
+		require.Contains(t, err.Error(), "parse credential")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+}
+
+const mDocType = "org.iso.18013.5.1.mDL"
+
+// newMDoc returns an IssuerSigned structure disclosing the family name, signed with ES256 by a self-signed
+// document signer certificate.
+func newMDoc(t *testing.T, signerKey *ecdsa.PrivateKey, familyName string) map[interface{}]interface{} {
+	t.Helper()
+
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &signerKey.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	element := func(digestID uint64, identifier, value string) cbor.Tag {
+		item, err := cbor.Encode(map[interface{}]interface{}{
+			"digestID":          digestID,
+			"random":            []byte("random"),
+			"elementIdentifier": identifier,
+			"elementValue":      value,
+		})
+		require.NoError(t, err)
+
+		return cbor.Tag{Number: cbor.TagEncodedCBOR, Content: item}
+	}
+
+	digest := func(element cbor.Tag) []byte {
+		encoded, err := cbor.Encode(element)
+		require.NoError(t, err)
+
+		d := sha256.Sum256(encoded)
+
+		return d[:]
+	}
+
+	mso, err := cbor.Encode(map[interface{}]interface{}{
+		"version":         "1.0",
+		"digestAlgorithm": "SHA-256",
+		"docType":         mDocType,
+		"valueDigests": map[interface{}]interface{}{
+			"org.iso.18013.5.1": map[interface{}]interface{}{
+				0: digest(element(0, "family_name", "Doe")),
+				1: digest(element(1, "given_name", "Jane")),
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	payload, err := cbor.Encode(cbor.Tag{Number: cbor.TagEncodedCBOR, Content: mso})
+	require.NoError(t, err)
+
+	protected, err := cbor.Encode(map[interface{}]interface{}{1: -7})
+	require.NoError(t, err)
+
+	toBeSigned, err := cbor.Encode([]interface{}{"Signature1", protected, []byte{}, payload})
+	require.NoError(t, err)
+
+	hash := sha256.Sum256(toBeSigned)
+
+	r, s, err := ecdsa.Sign(rand.Reader, signerKey, hash[:])
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return map[interface{}]interface{}{
+		"nameSpaces": map[interface{}]interface{}{
+			"org.iso.18013.5.1": []interface{}{element(0, "family_name", familyName)},
+		},
+		"issuerAuth": cbor.Tag{Number: 18, Content: []interface{}{
+			protected, map[interface{}]interface{}{33: cert}, payload, signature,
+		}},
+	}
+}
+
+func TestCmd_AddEntry_MDoc(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	signerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var logged []*TimestampedEntry
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			var leaf *MerkleTreeLeaf
+			require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+
+			logged = append(logged, leaf.TimestampedEntry)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).AnyTimes()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		Key: Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	addMDoc := func(document interface{}) error {
+		encoded, err := cbor.Encode(document)
+		require.NoError(t, err)
+
+		entry, err := json.Marshal(MDoc{Document: encoded})
+		require.NoError(t, err)
+
+		src, err := json.Marshal(AddEntryRequest{Alias: alias, EntryType: MDocLogEntryType, Entry: entry})
+		require.NoError(t, err)
+
+		return lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		logged = nil
+
+		issuerSigned := newMDoc(t, signerKey, "Doe")
+
+		require.NoError(t, addMDoc(issuerSigned))
+		require.NoError(t, addMDoc(map[interface{}]interface{}{
+			"docType":      mDocType,
+			"issuerSigned": issuerSigned,
+		}))
+
+		// the issuer-signed mobile security object is logged, whatever is disclosed
+		delete(issuerSigned, "nameSpaces")
+		require.NoError(t, addMDoc(issuerSigned))
+
+		require.Len(t, logged, 3)
+		require.Equal(t, MDocLogEntryType, logged[0].EntryType)
+		require.Equal(t, logged[0].VCEntry, logged[1].VCEntry)
+		require.Equal(t, logged[0].VCEntry, logged[2].VCEntry)
+
+		var entry MDocEntry
+		require.NoError(t, json.Unmarshal(logged[0].VCEntry, &entry))
+		require.Equal(t, mDocType, entry.DocType)
+
+		issuerAuth, err := cbor.Decode(entry.IssuerAuth)
+		require.NoError(t, err)
+		require.Len(t, issuerAuth, 4)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		issuerAuth := func(issuerSigned map[interface{}]interface{}) []interface{} {
+			return issuerSigned["issuerAuth"].(cbor.Tag).Content.([]interface{})
+		}
+
+		invalidSignature := newMDoc(t, signerKey, "Doe")
+		issuerAuth(invalidSignature)[3] = make([]byte, 64)
+
+		otherSigner := newMDoc(t, signerKey, "Doe")
+		issuerAuth(otherSigner)[1] = issuerAuth(newMDoc(t, otherKey, "Doe"))[1]
+
+		noCertificate := newMDoc(t, signerKey, "Doe")
+		issuerAuth(noCertificate)[1] = map[interface{}]interface{}{}
+
+		for _, tc := range []struct {
+			name     string
+			document interface{}
+			err      string
+		}{
+			{"Not a map", []interface{}{}, "mdoc: document must be a map"},
+			{"Element does not match", newMDoc(t, signerKey, "Smith"),
+				"mdoc: name space org.iso.18013.5.1 element 0: digest 0 does not match"},
+			{"Invalid signature", invalidSignature, "mdoc: issuerAuth: invalid signature"},
+			{"Other signer", otherSigner, "mdoc: issuerAuth: invalid signature"},
+			{"No certificate", noCertificate, "mdoc: issuerAuth: no document signer certificate"},
+			{"No issuerAuth", map[interface{}]interface{}{}, "mdoc: issuerAuth: COSE_Sign1 must be an array"},
+			{"Other docType", map[interface{}]interface{}{
+				"docType":      "org.example.mdoc",
+				"issuerSigned": newMDoc(t, signerKey, "Doe"),
+			}, `mdoc: docType "org.example.mdoc" does not match`},
+		} {
+			err := addMDoc(tc.document)
+			require.Error(t, err, tc.name)
+			require.Contains(t, err.Error(), tc.err, tc.name)
+			require.NotContains(t, err.Error(), "Smith", tc.name)
+			require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err), tc.name)
+		}
+
+		src, err := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: MDocLogEntryType,
+			Entry:     json.RawMessage(`{"document":"/w=="}`),
+		})
+		require.NoError(t, err)
+
+		err = lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+		require.Contains(t, err.Error(), "mdoc: decode document: indefinite length is not supported")
+	})
+}
+
+func TestCmd_AddEntry_Presentation(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	var logged []*TimestampedEntry
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			var leaf *MerkleTreeLeaf
+			require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, &leaf))
+
+			logged = append(logged, leaf.TimestampedEntry)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).AnyTimes()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	loader := ldcontext.DocumentLoader(t)
+
+	cfg := &Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     client,
+		}},
+		VDR:             vdr.New(vdr.WithVDR(key.New())),
+		Key:             Key{ID: newKID},
+		DocumentLoaders: map[string]jsonld.DocumentLoader{alias: loader},
+		Presentations:   true,
+	}
+
+	cmd, err := New(cfg, nil)
+	require.NoError(t, err)
+
+	holderPubKey, holderKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holder, holderKeyID := fingerprint.CreateDIDKey(holderPubKey)
+
+	otherPubKey, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, otherKeyID := fingerprint.CreateDIDKey(otherPubKey)
+
+	credential := newIssuerJWT(t, map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"})
+
+	vc, err := verifiable.ParseCredential([]byte(credential), verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(loader))
+	require.NoError(t, err)
+
+	newPresentation := func(holder string) *verifiable.Presentation {
+		vp, err := verifiable.NewPresentation(verifiable.WithJWTCredentials(credential))
+		require.NoError(t, err)
+
+		vp.ID = "urn:uuid:3978344f-8596-4c3a-a978-8fcaba3903c5"
+		vp.Holder = holder
+
+		return vp
+	}
+
+	signed := func(holder string, privKey ed25519.PrivateKey, keyID, purpose string) json.RawMessage {
+		vp := newPresentation(holder)
+
+		require.NoError(t, vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType:           "Ed25519Signature2018",
+			Suite:                   ed25519signature2018.New(suite.WithSigner(ed25519Signer(privKey))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			VerificationMethod:      keyID,
+			Challenge:               "99612b24-63d9-11ea-b99f-4f66f3e4f81a",
+			Domain:                  "verifier.example.com",
+			Purpose:                 purpose,
+		}, ldprocessor.WithDocumentLoader(loader)))
+
+		src, err := vp.MarshalJSON()
+		require.NoError(t, err)
+
+		return src
+	}
+
+	signedJWT := func(privKey ed25519.PrivateKey, keyID string) json.RawMessage {
+		claims, err := newPresentation(holder).JWTClaims([]string{"did:example:verifier"}, false)
+		require.NoError(t, err)
+
+		jws, err := claims.MarshalJWS(verifiable.EdDSA, ed25519Signer(privKey), keyID)
+		require.NoError(t, err)
+
+		src, err := json.Marshal(jws)
+		require.NoError(t, err)
+
+		return src
+	}
+
+	addPresentation := func(cmd *Cmd, presentation json.RawMessage) error {
+		entry, err := json.Marshal(Presentation{Presentation: presentation})
+		require.NoError(t, err)
+
+		src, err := json.Marshal(AddEntryRequest{Alias: alias, EntryType: PresentationLogEntryType, Entry: entry})
+		require.NoError(t, err)
+
+		return lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		logged = nil
+
+		require.NoError(t, addPresentation(cmd, signed(holder, holderKey, holderKeyID, "authentication")))
+		require.NoError(t, addPresentation(cmd, signedJWT(holderKey, holderKeyID)))
+
+		require.Len(t, logged, 2)
+		require.Equal(t, PresentationLogEntryType, logged[0].EntryType)
+
+		presented := []PresentedCredential{{
+			ID:     vc.ID,
+			Types:  vc.Types,
+			Issuer: vc.Issuer.ID,
+		}}
+
+		var entry PresentationEntry
+		require.NoError(t, json.Unmarshal(logged[0].VCEntry, &entry))
+		require.Equal(t, holder, entry.Holder)
+		require.Equal(t, "urn:uuid:3978344f-8596-4c3a-a978-8fcaba3903c5", entry.ID)
+		require.Equal(t, []string{"VerifiablePresentation"}, entry.Types)
+		require.Equal(t, "verifier.example.com", entry.Verifier)
+		require.Equal(t, "99612b24-63d9-11ea-b99f-4f66f3e4f81a", entry.Challenge)
+		require.Equal(t, presented, entry.Credentials)
+
+		vp, err := verifiable.ParsePresentation(entry.Presentation,
+			verifiable.WithPresPublicKeyFetcher(verifiable.NewVDRKeyResolver(cfg.VDR).PublicKeyFetcher()),
+			verifiable.WithPresJSONLDDocumentLoader(loader))
+		require.NoError(t, err)
+		require.Equal(t, holder, vp.Holder)
+
+		entry = PresentationEntry{}
+		require.NoError(t, json.Unmarshal(logged[1].VCEntry, &entry))
+		require.Equal(t, holder, entry.Holder)
+		require.Equal(t, "did:example:verifier", entry.Verifier)
+		require.Empty(t, entry.Challenge)
+		require.Equal(t, presented, entry.Credentials)
+	})
+
+	t.Run("Not enabled", func(t *testing.T) {
+		cfg := *cfg
+		cfg.Presentations = false
+
+		disabled, err := New(&cfg, nil)
+		require.NoError(t, err)
+
+		err = addPresentation(disabled, signed(holder, holderKey, holderKeyID, "authentication"))
+		require.EqualError(t, err, "leaf type 106 is not supported")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		unsigned, err := newPresentation(holder).MarshalJSON()
+		require.NoError(t, err)
+
+		noHolder, err := newPresentation("").MarshalJSON()
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			name         string
+			presentation json.RawMessage
+			err          string
+		}{
+			{"Empty", nil, "validation failed: presentation is empty"},
+			{"No holder", noHolder, "validation failed: presentation has no holder"},
+			{"Not a JWT", json.RawMessage(`"presentation"`),
+				"validation failed: presentation: not a compact JWT"},
+			{"Unsigned", unsigned, "validation failed: presentation is not signed by its holder"},
+			{"Signed by another key", signed(holder, otherKey, otherKeyID, "authentication"),
+				"validation failed: proof of the presentation is not made by its holder " + holder},
+			{"Invalid proof purpose", signed(holder, holderKey, holderKeyID, "assertionMethod"),
+				"validation failed: proof purpose of the presentation must be authentication"},
+			{"Invalid signature", signed(holder, otherKey, holderKeyID, "authentication"), "check embedded proof"},
+			{"Invalid JWT signature", signedJWT(otherKey, holderKeyID), "decoding of Verifiable Presentation from JWS"},
+		} {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				logged = nil
+
+				err := addPresentation(cmd, tc.presentation)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				require.Empty(t, logged)
+			})
+		}
+	})
+}
+
+type trustRegistryMock struct {
+	authorized bool
+	err        error
+	calls      int
+}
+
+func (m *trustRegistryMock) Check(_ context.Context, _ string) (*TrustDecision, error) {
+	m.calls++
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &TrustDecision{Registry: "https://registry.example.com", Authorized: m.authorized}, nil
+}
+
+func TestCmd_AddVC_TrustRegistry(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	newCmd := func(t *testing.T, registry TrustRegistry) *Cmd {
+		t.Helper()
+
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+			},
+		).AnyTimes()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "w",
+				Client:     client,
+			}},
+			VDR:              vdr.New(vdr.WithVDR(key.New())),
+			Key:              Key{ID: newKID},
+			DocumentLoaders:  map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
+			TrustRegistry:    registry,
+			TrustRegistryTTL: time.Hour,
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	addVC := func(cmd *Cmd) (*AddVCResponse, error) {
+		req, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: verifiableCredential})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		if err = lookupHandler(t, cmd, AddVC)(&buf, bytes.NewBuffer(req)); err != nil {
+			return nil, err
+		}
+
+		var resp *AddVCResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	t.Run("Authorized", func(t *testing.T) {
+		registry := &trustRegistryMock{authorized: true}
+		cmd := newCmd(t, registry)
+
+		resp, err := addVC(cmd)
+		require.NoError(t, err)
+
+		_, err = addVC(cmd)
+		require.NoError(t, err)
+
+		// the decision is cached
+		require.Equal(t, 1, registry.calls)
+
+		// and recorded in the extensions of the entry
+		extensions, err := base64.StdEncoding.DecodeString(resp.Extensions)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"trust_registry":{"registry":"https://registry.example.com","authorized":true}}`,
+			string(extensions))
+	})
+
+	t.Run("Not authorized", func(t *testing.T) {
+		registry := &trustRegistryMock{}
+		cmd := newCmd(t, registry)
+
+		_, err := addVC(cmd)
+		require.Contains(t, err.Error(), "is not authorized by trust registry https://registry.example.com")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		_, err = addVC(cmd)
+		require.Error(t, err)
+		require.Equal(t, 1, registry.calls)
+	})
+
+	t.Run("Registry error", func(t *testing.T) {
+		registry := &trustRegistryMock{err: fmt.Errorf("unavailable")}
+		cmd := newCmd(t, registry)
+
+		_, err := addVC(cmd)
+		require.EqualError(t, err, "trust registry: unavailable")
+		require.Equal(t, http.StatusServiceUnavailable, errors.StatusCodeFromError(err))
+
+		// errors are not cached
+		_, err = addVC(cmd)
+		require.Error(t, err)
+		require.Equal(t, 2, registry.calls)
+	})
+}
+
+func lookupHandler(t *testing.T, cmd *Cmd, name string) Exec {
+	t.Helper()
+
+	for _, handler := range cmd.GetHandlers() {
+		if handler.Method() == name {
+			return handler.Handle()
+		}
+	}
+
+	t.Errorf("no handler for %s", name)
+
+	return func(rw io.Writer, req io.Reader) error {
+		return nil
+	}
+}
+
+func createKMSAndCrypto(t *testing.T) (kms.KeyManager, crypto.Crypto) {
+	t.Helper()
+
+	const defaultMasterKeyURI = "local-lock://default/master/key/"
+
+	local, err := localkms.New(defaultMasterKeyURI, &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	return local, cr
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
+
+type readerMock struct{ err error }
+
+func (r *readerMock) Read(p []byte) (n int, err error) {
+	return 0, r.err
+}
+
+func TestCmd_AddAnnotation(t *testing.T) {
+	const (
+		keyType  = kms.ECDSAP256TypeIEEEP1363
+		treeSize = 3
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	auditorKID, auditorKH, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	auditorKey, _, err := km.ExportPubKeyBytes(auditorKID)
+	require.NoError(t, err)
+
+	root, err := (&types.LogRootV1{TreeSize: treeSize}).MarshalBinary()
+	require.NoError(t, err)
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+		&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).Return(
+		&trillian.GetLeavesByRangeResponse{
+			Leaves: []*trillian.LogLeaf{
+				{LeafValue: queuedLeafValue, LeafIndex: 1},
+				{LeafValue: queuedLeafValue, LeafIndex: 2},
+			},
+			SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+		}, nil,
+	)
+
+	notified := make(chan AnnotationEvent, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event AnnotationEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+
+		notified <- event
+	}))
+	defer server.Close()
+
+	annotations, err := mem.NewProvider().OpenStore("annotations")
+	require.NoError(t, err)
+
+	cfg := &Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "r",
+			Client:     client,
+		}},
+		Key:                   Key{ID: newKID},
+		Auditors:              map[string][]byte{"fraud-desk": auditorKey},
+		AnnotationStore:       annotations,
+		AnnotationSubscribers: []string{server.URL},
+	}
+
+	cmd, err := New(cfg, nil)
+	require.NoError(t, err)
+
+	newAnnotation := func(t *testing.T, kh interface{}, annotation Annotation) []byte {
+		t.Helper()
+
+		data, er := json.Marshal(annotation)
+		require.NoError(t, er)
+
+		signature, er := cr.Sign(data, kh)
+		require.NoError(t, er)
+
+		sig, er := json.Marshal(DigitallySigned{
+			Algorithm: SignatureAndHashAlgorithm{Signature: ECDSASignature, Type: keyType},
+			Signature: signature,
+		})
+		require.NoError(t, er)
+
+		src, er := json.Marshal(AddAnnotationRequest{
+			Alias:      alias,
+			Annotation: SignedAnnotation{Annotation: annotation, Signature: sig},
+		})
+		require.NoError(t, er)
+
+		return src
+	}
+
+	annotation := Annotation{
+		Version:       V1,
+		SignatureType: AnnotationSignatureType,
+		Timestamp:     uint64(time.Now().UnixNano() / int64(time.Millisecond)),
+		LogID:         cmd.VCLogID[:],
+		LeafIndex:     2,
+		Auditor:       "fraud-desk",
+		Kind:          AnnotationFraudulent,
+		Comment:       "reported by the holder",
+	}
+
+	var resp bytes.Buffer
+	require.NoError(t, lookupHandler(t, cmd, AddAnnotation)(&resp,
+		bytes.NewBuffer(newAnnotation(t, auditorKH, annotation))))
+
+	var added SignedAnnotation
+	require.NoError(t, json.Unmarshal(resp.Bytes(), &added))
+	require.Equal(t, annotation, added.Annotation)
+
+	select {
+	case event := <-notified:
+		require.Equal(t, alias, event.Alias)
+		require.Equal(t, added, event.Annotation)
+	case <-time.After(time.Second * 5):
+		t.Fatal("subscriber was not notified")
+	}
+
+	t.Run("Added twice", func(t *testing.T) {
+		require.NoError(t, lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{},
+			bytes.NewBuffer(newAnnotation(t, auditorKH, annotation))))
+
+		var got bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetAnnotations)(&got,
+			bytes.NewBufferString(`{"alias":"maple2021","leaf_index":2}`)))
+
+		var result GetAnnotationsResponse
+		require.NoError(t, json.Unmarshal(got.Bytes(), &result))
+		require.Equal(t, []SignedAnnotation{added}, result.Annotations)
+
+		got.Reset()
+		require.NoError(t, lookupHandler(t, cmd, GetAnnotations)(&got,
+			bytes.NewBufferString(`{"alias":"maple2021","leaf_index":1}`)))
+		require.NoError(t, json.Unmarshal(got.Bytes(), &result))
+		require.Empty(t, result.Annotations)
+		require.NotNil(t, result.Annotations)
+
+		err := cmd.GetAnnotations(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+		require.Contains(t, err.Error(), "empty GetAnnotations request")
+	})
+
+	t.Run("Served with the entries", func(t *testing.T) {
+		var got bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetEntries)(&got,
+			bytes.NewBufferString(`{"alias":"maple2021","start":1,"end":2}`)))
+
+		var result GetEntriesResponse
+		require.NoError(t, json.Unmarshal(got.Bytes(), &result))
+		require.Len(t, result.Entries, 2)
+		require.Empty(t, result.Entries[0].Annotations)
+		require.Equal(t, []SignedAnnotation{added}, result.Entries[1].Annotations)
+	})
+
+	t.Run("Rejected annotations", func(t *testing.T) {
+		logKH, er := km.Get(newKID)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{}, bytes.NewBuffer(newAnnotation(t, logKH, annotation)))
+		require.Contains(t, er.Error(), "annotation signature")
+
+		unknown := annotation
+		unknown.Auditor = "unknown"
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{}, bytes.NewBuffer(newAnnotation(t, auditorKH, unknown)))
+		require.EqualError(t, er, `auditor "unknown" is not registered`)
+
+		outOfTree := annotation
+		outOfTree.LeafIndex = treeSize
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{},
+			bytes.NewBuffer(newAnnotation(t, auditorKH, outOfTree)))
+		require.EqualError(t, er, "leaf index 3 is not in the tree of size 3")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+
+		otherLog := annotation
+		otherLog.LogID = []byte("other")
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{},
+			bytes.NewBuffer(newAnnotation(t, auditorKH, otherLog)))
+		require.EqualError(t, er, "annotation is issued for another log")
+
+		noKind := annotation
+		noKind.Kind = ""
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{}, bytes.NewBuffer(newAnnotation(t, auditorKH, noKind)))
+		require.Contains(t, er.Error(), "annotation kind is empty")
+
+		compromise := annotation
+		compromise.SignatureType = CompromiseSignatureType
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{},
+			bytes.NewBuffer(newAnnotation(t, auditorKH, compromise)))
+		require.EqualError(t, er, "annotation must be a v1 annotation")
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+		require.Contains(t, er.Error(), "decode AddAnnotation request")
+
+		er = lookupHandler(t, cmd, AddAnnotation)(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, er, errors.ErrBadRequest)
+		require.Contains(t, er.Error(), "empty AddAnnotation request")
+
+		er = lookupHandler(t, cmd, GetAnnotations)(&bytes.Buffer{}, bytes.NewBufferString(`{"alias":"unknown"}`))
+		require.Contains(t, er.Error(), "has permissions")
+	})
+
+	t.Run("No auditors", func(t *testing.T) {
+		cfg.Auditors = nil
+
+		noAuditors, er := New(cfg, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, noAuditors, AddAnnotation)(&bytes.Buffer{},
+			bytes.NewBuffer(newAnnotation(t, auditorKH, annotation)))
+		require.EqualError(t, er, "no auditors are registered")
+	})
+}
+
+func TestCmd_PurposeKeys(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	km, cr := createKMSAndCrypto(t)
+
+	logKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	responseKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	responseKey, _, err := km.ExportPubKeyBytes(responseKID)
+	require.NoError(t, err)
+
+	newConfig := func(purposeKeys map[KeyPurpose]Key) *Config {
+		return &Config{
+			KMS:         km,
+			Crypto:      cr,
+			Logs:        []Log{{Alias: alias, Permission: "r", Client: NewMockTrillianLogClient(ctrl)}},
+			Key:         Key{ID: logKID},
+			PurposeKeys: purposeKeys,
+		}
+	}
+
+	cmd, err := New(newConfig(map[KeyPurpose]Key{ResponseKeyPurpose: {ID: responseKID}}), nil)
+	require.NoError(t, err)
+
+	t.Run("Webfinger", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, Webfinger)(&buf, bytes.NewBufferString(`"maple2021"`)))
+
+		var resp *struct {
+			Properties struct {
+				Keys []KeyMetadata `json:"https://trustbloc.dev/ns/keys"`
+			} `json:"properties"`
+		}
+
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		keyID := sha256.Sum256(responseKey)
+
+		keys := resp.Properties.Keys
+		require.Len(t, keys, 2)
+		require.Equal(t, LogKeyPurpose, keys[0].Purpose)
+		require.Equal(t, cmd.PubKey, keys[0].PublicKey)
+		require.Equal(t, cmd.VCLogID[:], keys[0].KeyID)
+		require.Equal(t, ResponseKeyPurpose, keys[1].Purpose)
+		require.Equal(t, responseKey, keys[1].PublicKey)
+		require.Equal(t, keyID[:], keys[1].KeyID)
+	})
+
+	t.Run("Sign response", func(t *testing.T) {
+		require.True(t, cmd.SignsResponses(ResponseKeyPurpose))
+		require.False(t, cmd.SignsResponses(AdminKeyPurpose))
+
+		body := []byte(`{"tree_size":1}`)
+
+		signed, er := cmd.SignResponse(ResponseKeyPurpose, "/maple2021/v1/get-sth", body)
+		require.NoError(t, er)
+
+		hash := sha256.Sum256(body)
+		statement := ResponseStatement{
+			Version:       V1,
+			SignatureType: ResponseSignatureType,
+			Timestamp:     signed.Timestamp,
+			RequestURI:    "/maple2021/v1/get-sth",
+			SHA256Body:    hash[:],
+		}
+
+		require.NoError(t, VerifySignature(signed.Signature, responseKey, statement))
+		require.Error(t, VerifySignature(signed.Signature, cmd.PubKey, statement))
+
+		signed, er = cmd.SignResponse(AdminKeyPurpose, "/admin/read-only", body)
+		require.NoError(t, er)
+		require.Nil(t, signed)
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetKeyUsage)(&buf, nil))
+
+		var usage *GetKeyUsageResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &usage))
+		require.Equal(t, ResponseSignature, usage.Usage[3].Kind)
+		require.Equal(t, uint64(1), usage.Usage[3].Signatures)
+		require.Equal(t, KeyUsage{Kind: AdminSignature}, usage.Usage[4])
+	})
+
+	t.Run("Sign webhook", func(t *testing.T) {
+		const did = "did:web:vct.example.com"
+
+		cfg := newConfig(map[KeyPurpose]Key{WebhookKeyPurpose: {ID: responseKID}})
+		cfg.DID = did
+
+		webhookCmd, er := New(cfg, nil)
+		require.NoError(t, er)
+
+		body := []byte(`{"tree_size":1}`)
+
+		req := httptest.NewRequest(http.MethodPost, "https://issuer.example.com/callback", bytes.NewBuffer(body))
+		require.NoError(t, webhookCmd.SignWebhook(req, body))
+
+		pubKey, er := jwksupport.PubKeyBytesToJWK(responseKey, keyType)
+		require.NoError(t, er)
+
+		keyID := sha256.Sum256(responseKey)
+
+		_, er = webhook.NewVerifier(webhook.StaticKeys(map[string]gocrypto.PublicKey{
+			did + "#" + base64.RawURLEncoding.EncodeToString(keyID[:]): pubKey.Key,
+		})).VerifyRequest(req)
+		require.NoError(t, er)
+
+		// the webhooks are signed with the key of the log without webhook key
+		require.NoError(t, cmd.SignWebhook(req, body))
+
+		_, er = webhook.NewVerifier(webhook.StaticKeys(map[string]gocrypto.PublicKey{
+			did + "#" + base64.RawURLEncoding.EncodeToString(keyID[:]): pubKey.Key,
+		})).VerifyRequest(req)
+		require.ErrorIs(t, er, webhook.ErrInvalidSignature)
+	})
+
+	t.Run("Log key", func(t *testing.T) {
+		_, er := New(newConfig(map[KeyPurpose]Key{AdminKeyPurpose: {ID: logKID}}), nil)
+		require.EqualError(t, er, "purpose keys: the key of the log can't serve the admin purpose")
+
+		_, er = New(newConfig(map[KeyPurpose]Key{LogKeyPurpose: {ID: responseKID}}), nil)
+		require.EqualError(t, er, `purpose keys: unknown key purpose "log"`)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		_, er := New(newConfig(map[KeyPurpose]Key{WitnessKeyPurpose: {ID: "unknown"}}), nil)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "purpose keys: witness key: export pub key bytes")
+	})
+
+	t.Run("Parse key purpose", func(t *testing.T) {
+		purpose, er := ParseKeyPurpose("witness")
+		require.NoError(t, er)
+		require.Equal(t, WitnessKeyPurpose, purpose)
+
+		_, er = ParseKeyPurpose("tls")
+		require.EqualError(t, er, `unknown key purpose "tls"`)
+	})
+}
+
+func TestCmd_Dedup(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	store, err := mem.NewProvider().OpenStore("dedup")
+	require.NoError(t, err)
+
+	client := NewMockTrillianLogClient(ctrl)
+
+	newCmd := func(t *testing.T) *Cmd {
+		t.Helper()
+
+		cmd, er := New(&Config{
+			KMS:        km,
+			Crypto:     cr,
+			Logs:       []Log{{Alias: alias, Permission: "rw", Client: client}},
+			Key:        Key{ID: newKID},
+			DedupStore: store,
+		}, nil)
+		require.NoError(t, er)
+
+		return cmd
+	}
+
+	addEntry := func(cmd *Cmd, data string) *AddVCResponse {
+		hash := sha256.Sum256([]byte(data))
+
+		src, er := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: CommitmentLogEntryType,
+			Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+		})
+		require.NoError(t, er)
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&buf, bytes.NewBuffer(src)))
+
+		var resp *AddVCResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp
+	}
+
+	// the leaves are queued once
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).Times(2)
+
+	cmd := newCmd(t)
+
+	first := addEntry(cmd, "a")
+
+	time.Sleep(time.Millisecond)
+
+	duplicate := addEntry(cmd, "a")
+	require.Equal(t, first.Timestamp, duplicate.Timestamp)
+
+	// the filter of the stored leaves is loaded by a new instance
+	restarted := newCmd(t)
+	require.Equal(t, first.Timestamp, addEntry(restarted, "a").Timestamp)
+
+	addEntry(restarted, "b")
+
+	t.Run("Store error", func(t *testing.T) {
+		_, er := New(&Config{
+			KMS:        km,
+			Crypto:     cr,
+			Key:        Key{ID: newKID},
+			DedupStore: &failingDedupStore{},
+		}, nil)
+		require.EqualError(t, er, "load dedup filter: query leaves: query failed")
+	})
+}
+
+// failingDedupStore is a dedup store which can't be queried.
+type failingDedupStore struct {
+	DedupStore
+}
+
+func (s *failingDedupStore) Query(string, ...storage.QueryOption) (storage.Iterator, error) {
+	return nil, fmt.Errorf("query failed")
+}
+
+func TestCmd_ProofCache(t *testing.T) {
+	ctx := context.Background()
+
+	log := merklelog.New(merklelog.NewMemStorage())
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{})
+	require.NoError(t, err)
+
+	for i := 0; i < 600; i++ {
+		_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{
+			Leaf: &trillian.LogLeaf{LeafValue: []byte(fmt.Sprintf("leaf %d", i))},
+		})
+		require.NoError(t, err)
+	}
+
+	newCmd := func(t *testing.T, client TrillianLogClient, proofCacheSize int) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, er := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, er)
+
+		cmd, er := New(&Config{
+			KMS:            km,
+			Crypto:         cr,
+			Logs:           []Log{{Alias: alias, Permission: "r", Client: client}},
+			Key:            Key{ID: newKID},
+			ProofCacheSize: proofCacheSize,
+		}, nil)
+		require.NoError(t, er)
+
+		return cmd
+	}
+
+	call := func(t *testing.T, cmd *Cmd, name string, req interface{}, resp interface{}) {
+		t.Helper()
+
+		src, er := json.Marshal(req)
+		require.NoError(t, er)
+
+		var buf bytes.Buffer
+
+		require.NoError(t, lookupHandler(t, cmd, name)(&buf, bytes.NewBuffer(src)))
+		require.NoError(t, json.Unmarshal(buf.Bytes(), resp))
+	}
+
+	client := &countingLogClient{TrillianLogClient: log}
+	cmd, uncached := newCmd(t, client, 1000), newCmd(t, log, 0)
+
+	inclusion := func(t *testing.T, leafIndex, treeSize int64) {
+		t.Helper()
+
+		var expected, resp GetEntryAndProofResponse
+
+		req := GetEntryAndProofRequest{Alias: alias, LeafIndex: leafIndex, TreeSize: treeSize}
+
+		call(t, uncached, GetEntryAndProof, req, &expected)
+		call(t, cmd, GetEntryAndProof, req, &resp)
+		require.Equal(t, expected, resp)
+	}
+
+	consistency := func(t *testing.T, first, second int64) {
+		t.Helper()
+
+		var expected, resp GetSTHConsistencyResponse
+
+		req := GetSTHConsistencyRequest{Alias: alias, FirstTreeSize: first, SecondTreeSize: second}
+
+		call(t, uncached, GetSTHConsistency, req, &expected)
+		call(t, cmd, GetSTHConsistency, req, &resp)
+		require.Equal(t, expected, resp)
+	}
+
+	// the first proof is taken from the log, the same proof and the proof of the sibling leaf are computed
+	inclusion(t, 100, 600)
+	require.Equal(t, 1, client.proofs)
+
+	inclusion(t, 100, 600)
+	inclusion(t, 101, 600)
+	require.Equal(t, 1, client.proofs)
+
+	// the leaf of the cached path is found by its hash
+	var byHash GetProofByHashResponse
+
+	call(t, cmd, GetProofByHash, GetProofByHashRequest{
+		Alias:    alias,
+		Hash:     base64.StdEncoding.EncodeToString(hasher.DefaultHasher.HashLeaf([]byte("leaf 101"))),
+		TreeSize: 600,
+	}, &byHash)
+	require.Equal(t, int64(101), byHash.LeafIndex)
+	require.Equal(t, 1, client.proofs)
+
+	// another part of the tree is a miss
+	inclusion(t, 400, 600)
+	require.Equal(t, 2, client.proofs)
+
+	consistency(t, 300, 600)
+	consistency(t, 300, 600)
+	require.Equal(t, 3, client.proofs)
+
+	t.Run("Cached tiles", func(t *testing.T) {
+		warmClient := &countingLogClient{TrillianLogClient: log}
+		warm := newCmd(t, warmClient, 1000)
+		require.NoError(t, warm.WarmCache(ctx))
+
+		// the complete subtrees of the path are folded from the pinned tiles
+		var expected, resp GetEntryAndProofResponse
+
+		req := GetEntryAndProofRequest{Alias: alias, LeafIndex: 100, TreeSize: 512}
+
+		call(t, uncached, GetEntryAndProof, req, &expected)
+		call(t, warm, GetEntryAndProof, req, &resp)
+		require.Equal(t, expected, resp)
+		require.Equal(t, 0, warmClient.proofs)
+	})
+}
+
+// countingLogClient counts the proofs taken from the log.
+type countingLogClient struct {
+	TrillianLogClient
+	proofs int
+}
+
+func (c *countingLogClient) GetEntryAndProof(ctx context.Context, req *trillian.GetEntryAndProofRequest,
+	opts ...grpc.CallOption) (*trillian.GetEntryAndProofResponse, error) {
+	c.proofs++
+
+	return c.TrillianLogClient.GetEntryAndProof(ctx, req, opts...)
+}
+
+func (c *countingLogClient) GetInclusionProofByHash(ctx context.Context, req *trillian.GetInclusionProofByHashRequest,
+	opts ...grpc.CallOption) (*trillian.GetInclusionProofByHashResponse, error) {
+	c.proofs++
+
+	return c.TrillianLogClient.GetInclusionProofByHash(ctx, req, opts...)
+}
+
+func (c *countingLogClient) GetConsistencyProof(ctx context.Context, req *trillian.GetConsistencyProofRequest,
+	opts ...grpc.CallOption) (*trillian.GetConsistencyProofResponse, error) {
+	c.proofs++
+
+	return c.TrillianLogClient.GetConsistencyProof(ctx, req, opts...)
+}
+
+func TestCmd_GetVerificationSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	log := merklelog.New(merklelog.NewMemStorage())
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{})
+	require.NoError(t, err)
+
+	queueLeaf := func(t *testing.T, value string) {
+		t.Helper()
+
+		_, er := log.QueueLeaf(ctx, &trillian.QueueLeafRequest{Leaf: &trillian.LogLeaf{LeafValue: []byte(value)}})
+		require.NoError(t, er)
+	}
+
+	newCmd := func(t *testing.T, snapshots *VerificationSnapshotConfig) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, er := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, er)
+
+		cmd, er := New(&Config{
+			KMS:                   km,
+			Crypto:                cr,
+			Logs:                  []Log{{Alias: alias, Permission: "r", Client: log}},
+			Key:                   Key{ID: newKID},
+			BaseURL:               "https://vct.example.com",
+			VerificationSnapshots: snapshots,
+		}, nil)
+		require.NoError(t, er)
+
+		return cmd
+	}
+
+	getSnapshot := func(t *testing.T, cmd *Cmd) *SignedVerificationSnapshot {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetSnapshot)(&buf, bytes.NewBufferString(`"maple2021"`)))
+
+		var snapshot *SignedVerificationSnapshot
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &snapshot))
+
+		return snapshot
+	}
+
+	queueLeaf(t, "leaf 0")
+
+	t.Run("Success", func(t *testing.T) {
+		cmd := newCmd(t, &VerificationSnapshotConfig{
+			Interval: time.Hour,
+			Metadata: map[string]string{"operator": "Example Inc."},
+		})
+
+		snapshot := getSnapshot(t, cmd)
+		require.Equal(t, SnapshotSignatureType, snapshot.Snapshot.SignatureType)
+		require.Equal(t, alias, snapshot.Snapshot.Alias)
+		require.Equal(t, cmd.VCLogID[:], snapshot.Snapshot.LogID)
+		require.Equal(t, "https://vct.example.com/maple2021", snapshot.Snapshot.URL)
+		require.Equal(t, uint64(1), snapshot.Snapshot.STH.TreeSize)
+		require.Equal(t, map[string]string{"operator": "Example Inc."}, snapshot.Snapshot.Metadata)
+		require.Equal(t, uint64(0), snapshot.Snapshot.NextUpdate%uint64(time.Hour/time.Millisecond))
+		require.Greater(t, snapshot.Snapshot.NextUpdate, snapshot.Snapshot.Timestamp)
+		require.Len(t, snapshot.Snapshot.Keys, 1)
+		require.NoError(t, VerifySignature(snapshot.Signature, cmd.PubKey, snapshot.Snapshot))
+
+		// the same snapshot is served until its next update
+		queueLeaf(t, "leaf 1")
+		require.Equal(t, snapshot, getSnapshot(t, cmd))
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		cmd := newCmd(t, &VerificationSnapshotConfig{Interval: 50 * time.Millisecond})
+
+		snapshot := getSnapshot(t, cmd)
+		require.Equal(t, uint64(2), snapshot.Snapshot.STH.TreeSize)
+
+		queueLeaf(t, "leaf 2")
+
+		time.Sleep(time.Until(time.Unix(0, int64(snapshot.Snapshot.NextUpdate)*int64(time.Millisecond))))
+
+		refreshed := getSnapshot(t, cmd)
+		require.Equal(t, uint64(3), refreshed.Snapshot.STH.TreeSize)
+		require.GreaterOrEqual(t, refreshed.Snapshot.Timestamp, snapshot.Snapshot.NextUpdate)
+		require.NoError(t, VerifySignature(refreshed.Signature, cmd.PubKey, refreshed.Snapshot))
+	})
+
+	t.Run("Not enabled", func(t *testing.T) {
+		err := lookupHandler(t, newCmd(t, nil), GetSnapshot)(&bytes.Buffer{}, bytes.NewBufferString(`"maple2021"`))
+		require.EqualError(t, err, "verification snapshots are not enabled")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("No permissions", func(t *testing.T) {
+		err := lookupHandler(t, newCmd(t, &VerificationSnapshotConfig{}), GetSnapshot)(&bytes.Buffer{},
+			bytes.NewBufferString(`"unknown"`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "has permissions")
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		err := lookupHandler(t, newCmd(t, &VerificationSnapshotConfig{}), GetSnapshot)(&bytes.Buffer{},
+			bytes.NewBufferString(`{`))
+		require.EqualError(t, err, "internal error: decode alias failed")
+	})
+}
+
+func TestCmd_FreezeLog(t *testing.T) {
+	ctx := context.Background()
+
+	log := merklelog.New(merklelog.NewMemStorage())
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{})
+	require.NoError(t, err)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T, freezes map[string]*SignedFinalTreeHead,
+		onFreeze func(string, *SignedFinalTreeHead) error) (*Cmd, error) {
+		t.Helper()
+
+		return New(&Config{
+			KMS:      km,
+			Crypto:   cr,
+			Logs:     []Log{{Alias: alias, Permission: "rw", Client: log}},
+			Key:      Key{ID: newKID},
+			Freezes:  freezes,
+			OnFreeze: onFreeze,
+		}, nil)
+	}
+
+	hash := sha256.Sum256([]byte("data"))
+
+	addEntry, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	freeze := func(t *testing.T, cmd *Cmd, reason string) (*SignedFinalTreeHead, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		src := fmt.Sprintf(`{"alias":%q,"reason":%q}`, alias, reason)
+		if er := lookupHandler(t, cmd, FreezeLog)(&buf, bytes.NewBufferString(src)); er != nil {
+			return nil, er
+		}
+
+		var final *SignedFinalTreeHead
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &final))
+
+		return final, nil
+	}
+
+	var final *SignedFinalTreeHead
+
+	t.Run("Freeze", func(t *testing.T) {
+		var stored *SignedFinalTreeHead
+
+		cmd, er := newCmd(t, nil, func(a string, f *SignedFinalTreeHead) error {
+			require.Equal(t, alias, a)
+			stored = f
+
+			return nil
+		})
+		require.NoError(t, er)
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(addEntry)))
+
+		er = lookupHandler(t, cmd, GetFinalTreeHead)(&bytes.Buffer{}, bytes.NewBufferString(`"maple2021"`))
+		require.EqualError(t, er, "log maple2021 is not frozen")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+
+		final, er = freeze(t, cmd, "retired")
+		require.NoError(t, er)
+		require.Equal(t, stored, final)
+		require.Equal(t, FreezeSignatureType, final.Statement.SignatureType)
+		require.Equal(t, alias, final.Statement.Alias)
+		require.Equal(t, "retired", final.Statement.Reason)
+		require.Equal(t, uint64(1), final.Statement.STH.TreeSize)
+		require.NoError(t, VerifySignature(final.Signature, cmd.PubKey, final.Statement))
+
+		// the log is frozen once
+		again, er := freeze(t, cmd, "another reason")
+		require.NoError(t, er)
+		require.Equal(t, final, again)
+
+		er = lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(addEntry))
+		require.EqualError(t, er, "frozen: the log is frozen at the tree size 1")
+		require.Equal(t, http.StatusGone, errors.StatusCodeFromError(er))
+		require.Equal(t, errors.ProblemTypeFrozen, errors.ProblemTypeFromError(er))
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetFinalTreeHead)(&buf, bytes.NewBufferString(`"maple2021"`)))
+
+		var served *SignedFinalTreeHead
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &served))
+		require.Equal(t, final, served)
+
+		buf.Reset()
+		require.NoError(t, lookupHandler(t, cmd, Webfinger)(&buf, bytes.NewBufferString(`"maple2021"`)))
+		require.Contains(t, buf.String(), FinalTreeHeadType)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		cmd, er := newCmd(t, map[string]*SignedFinalTreeHead{alias: final}, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(addEntry))
+		require.ErrorIs(t, er, errors.ErrFrozen)
+
+		tampered := *final
+		tampered.Statement.STH.TreeSize++
+
+		_, er = newCmd(t, map[string]*SignedFinalTreeHead{alias: &tampered}, nil)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "restore final tree head of log maple2021: statement signature")
+
+		_, er = newCmd(t, map[string]*SignedFinalTreeHead{"maple2022": final}, nil)
+		require.EqualError(t, er, "restore final tree head of log maple2022: statement is issued for another log")
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		cmd, er := newCmd(t, nil, func(string, *SignedFinalTreeHead) error {
+			return fmt.Errorf("store error")
+		})
+		require.NoError(t, er)
+
+		_, er = freeze(t, cmd, "retired")
+		require.EqualError(t, er, "store final tree head: store error")
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(addEntry)))
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		cmd, er := newCmd(t, nil, nil)
+		require.NoError(t, er)
+
+		er = lookupHandler(t, cmd, FreezeLog)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "decode FreezeLog request")
+
+		er = lookupHandler(t, cmd, FreezeLog)(&bytes.Buffer{}, bytes.NewBufferString(`null`))
+		require.ErrorIs(t, er, errors.ErrBadRequest)
+		require.Contains(t, er.Error(), "empty FreezeLog request")
+
+		er = lookupHandler(t, cmd, FreezeLog)(&bytes.Buffer{}, bytes.NewBufferString(`{"alias":"unknown"}`))
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "has permissions")
+	})
+
+	t.Run("Leaves not integrated", func(t *testing.T) {
+		pendingLog := merklelog.New(merklelog.NewMemStorage())
+
+		_, er := pendingLog.InitLog(ctx, &trillian.InitLogRequest{})
+		require.NoError(t, er)
+
+		cmd, er := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs:   []Log{{Alias: alias, Permission: "rw", Client: &unintegratedLog{TrillianLogClient: pendingLog}}},
+			Key:    Key{ID: newKID},
+		}, nil)
+		require.NoError(t, er)
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(addEntry)))
+
+		_, er = freeze(t, cmd, "retired")
+		require.EqualError(t, er, "1 leaves queued to log maple2021 are not integrated, wait for them to be"+
+			" integrated before freezing the log")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(er))
+
+		// the leaves queued before the read-only mode may still be integrated
+		require.NoError(t, lookupHandler(t, cmd, SetReadOnly)(&bytes.Buffer{}, bytes.NewBufferString(`{"read_only":true}`)))
+
+		_, er = freeze(t, cmd, "retired")
+		require.Error(t, er)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(er))
+	})
+}
+
+// unintegratedLog queues the leaves without integrating them.
+type unintegratedLog struct {
+	TrillianLogClient
+}
+
+func (l *unintegratedLog) QueueLeaf(_ context.Context, req *trillian.QueueLeafRequest,
+	_ ...grpc.CallOption) (*trillian.QueueLeafResponse, error) {
+	return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+}
+
+// treeAdmin is the admin client of the Trillian tree of a log, the log it wraps rejects the leaves unless the tree
+// is active.
+type treeAdmin struct {
+	TrillianLogClient
+	state   trillian.TreeState
+	updates int
+	err     error
+}
+
+func (a *treeAdmin) GetTree(_ context.Context, req *trillian.GetTreeRequest,
+	_ ...grpc.CallOption) (*trillian.Tree, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+
+	return &trillian.Tree{TreeId: req.TreeId, TreeState: a.state}, nil
+}
+
+func (a *treeAdmin) UpdateTree(_ context.Context, req *trillian.UpdateTreeRequest,
+	_ ...grpc.CallOption) (*trillian.Tree, error) {
+	if req.UpdateMask.GetPaths()[0] != "tree_state" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "unsupported path")
+	}
+
+	a.state = req.Tree.TreeState
+	a.updates++
+
+	return &trillian.Tree{TreeId: req.Tree.TreeId, TreeState: a.state}, nil
+}
+
+func (a *treeAdmin) QueueLeaf(ctx context.Context, req *trillian.QueueLeafRequest,
+	opts ...grpc.CallOption) (*trillian.QueueLeafResponse, error) {
+	if a.state != trillian.TreeState_ACTIVE {
+		return nil, grpcstatus.Errorf(codes.FailedPrecondition, "operation not allowed for %s trees", a.state)
+	}
+
+	return a.TrillianLogClient.QueueLeaf(ctx, req, opts...)
+}
+
+func TestCmd_LogState(t *testing.T) {
+	ctx := context.Background()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T) (*Cmd, *treeAdmin) {
+		t.Helper()
+
+		log := merklelog.New(merklelog.NewMemStorage())
+
+		_, er := log.InitLog(ctx, &trillian.InitLogRequest{})
+		require.NoError(t, er)
+
+		admin := &treeAdmin{TrillianLogClient: log, state: trillian.TreeState_ACTIVE}
+
+		cmd, er := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{
+				{Alias: alias, Permission: "rw", Client: admin, AdminClient: admin},
+				{Alias: "maple2020", Permission: "rw", Client: log},
+			},
+			Key:          Key{ID: newKID},
+			TreeStateTTL: time.Hour,
+		}, nil)
+		require.NoError(t, er)
+
+		return cmd, admin
+	}
+
+	hash := sha256.Sum256([]byte("data"))
+
+	addEntry := func(t *testing.T, cmd *Cmd) error {
+		t.Helper()
+
+		src, er := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: CommitmentLogEntryType,
+			Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+		})
+		require.NoError(t, er)
+
+		return lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
+
+	setState := func(t *testing.T, cmd *Cmd, state string) (*LogState, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		src := fmt.Sprintf(`{"alias":%q,"state":%q}`, alias, state)
+		if er := lookupHandler(t, cmd, SetLogState)(&buf, bytes.NewBufferString(src)); er != nil {
+			return nil, er
+		}
+
+		var logState *LogState
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &logState))
+
+		return logState, nil
+	}
+
+	getStates := func(t *testing.T, cmd *Cmd) []LogState {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetLogStates)(&buf, nil))
+
+		var resp *GetLogStatesResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp.States
+	}
+
+	t.Run("Draining", func(t *testing.T) {
+		cmd, admin := newCmd(t)
+
+		require.Equal(t, []LogState{
+			{Alias: "maple2020", State: LogStateActive},
+			{Alias: alias, State: LogStateActive, TreeState: "ACTIVE"},
+		}, getStates(t, cmd))
+
+		state, er := setState(t, cmd, LogStateDraining)
+		require.NoError(t, er)
+		require.Equal(t, &LogState{Alias: alias, State: LogStateDraining, TreeState: "DRAINING"}, state)
+		require.Equal(t, trillian.TreeState_DRAINING, admin.state)
+
+		er = addEntry(t, cmd)
+		require.EqualError(t, er, "draining: the log is draining, writes are rejected until it is active")
+		require.Equal(t, http.StatusServiceUnavailable, errors.StatusCodeFromError(er))
+		require.Equal(t, errors.ProblemTypeDraining, errors.ProblemTypeFromError(er))
+
+		// the state is published
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, Webfinger)(&buf, bytes.NewBufferString(fmt.Sprintf("%q", alias))))
+
+		var webfinger *WebFingerResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &webfinger))
+		require.Equal(t, map[string]interface{}{"alias": alias, "state": LogStateDraining, "tree_state": "DRAINING"},
+			webfinger.Properties[LogStateType])
+
+		// the same state is not updated again
+		_, er = setState(t, cmd, LogStateDraining)
+		require.NoError(t, er)
+		require.Equal(t, 1, admin.updates)
+
+		_, er = setState(t, cmd, LogStateActive)
+		require.NoError(t, er)
+		require.NoError(t, addEntry(t, cmd))
+	})
+
+	t.Run("Frozen by Trillian", func(t *testing.T) {
+		cmd, admin := newCmd(t)
+
+		require.NoError(t, addEntry(t, cmd))
+
+		// the tree is frozen while its state is cached, the leaf Trillian rejects refreshes it
+		admin.state = trillian.TreeState_FROZEN
+
+		er := addEntry(t, cmd)
+		require.EqualError(t, er, "tree frozen: the tree of the log is frozen, writes are rejected until it is active")
+		require.Equal(t, errors.ProblemTypeTreeFrozen, errors.ProblemTypeFromError(er))
+		require.Equal(t, LogStateFrozen, getStates(t, cmd)[1].State)
+		require.False(t, getStates(t, cmd)[1].Final)
+	})
+
+	t.Run("Frozen for good", func(t *testing.T) {
+		cmd, admin := newCmd(t)
+
+		require.NoError(t, addEntry(t, cmd))
+		require.NoError(t, lookupHandler(t, cmd, FreezeLog)(&bytes.Buffer{},
+			bytes.NewBufferString(fmt.Sprintf(`{"alias":%q}`, alias))))
+		require.Equal(t, trillian.TreeState_FROZEN, admin.state)
+
+		require.Equal(t, LogState{Alias: alias, State: LogStateFrozen, TreeState: "FROZEN", Final: true},
+			getStates(t, cmd)[1])
+
+		_, er := setState(t, cmd, LogStateActive)
+		require.EqualError(t, er, "frozen: the log is frozen at the tree size 1")
+		require.Equal(t, trillian.TreeState_FROZEN, admin.state)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cmd, admin := newCmd(t)
+
+		_, er := setState(t, cmd, "retired")
+		require.EqualError(t, er, `validate SetLogState request: validation failed: state "retired" is not one of`+
+			` active, draining or frozen`)
+
+		er = lookupHandler(t, cmd, SetLogState)(&bytes.Buffer{}, bytes.NewBufferString(`{`))
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(er))
+
+		er = lookupHandler(t, cmd, SetLogState)(&bytes.Buffer{},
+			bytes.NewBufferString(`{"alias":"maple2020","state":"draining"}`))
+		require.EqualError(t, er, "the tree state of log maple2020 is not managed")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+
+		er = lookupHandler(t, cmd, SetLogState)(&bytes.Buffer{},
+			bytes.NewBufferString(`{"alias":"unknown","state":"draining"}`))
+		require.Error(t, er)
+
+		// the writes are not rejected if the state of the tree is not known
+		admin.err = grpcstatus.Error(codes.Unavailable, "admin is down")
+
+		er = lookupHandler(t, cmd, GetLogStates)(&bytes.Buffer{}, nil)
+		require.EqualError(t, er, "get tree of log maple2021: rpc error: code = Unavailable desc = admin is down")
+		require.NoError(t, addEntry(t, cmd))
+
+		_, er = setState(t, cmd, LogStateDraining)
+		require.Error(t, er)
+		require.Zero(t, admin.updates)
+	})
+}
+
+func TestCmd_PublishPolicy(t *testing.T) { // nolint: funlen
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T, policies map[string][]SignedPolicyDocument,
+		onPolicy func(string, *SignedPolicyDocument) error) (*Cmd, error) {
+		t.Helper()
+
+		return New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "rw",
+				Client:     NewMockTrillianLogClient(nil),
+				Issuers:    []string{"did:example:oak"},
+			}},
+			Key:      Key{ID: newKID},
+			Policies: policies,
+			OnPolicy: onPolicy,
+		}, nil)
+	}
+
+	publish := func(t *testing.T, cmd *Cmd, policy string) (*SignedPolicyDocument, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		src := fmt.Sprintf(`{"alias":%q,"policy":%s}`, alias, policy)
+		if er := lookupHandler(t, cmd, PublishPolicy)(&buf, bytes.NewBufferString(src)); er != nil {
+			return nil, er
+		}
+
+		var signed *SignedPolicyDocument
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &signed))
+
+		return signed, nil
+	}
+
+	getPolicy := func(t *testing.T, cmd *Cmd, version uint64) (*SignedPolicyDocument, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		src := fmt.Sprintf(`{"alias":%q,"version":%d}`, alias, version)
+		if er := lookupHandler(t, cmd, GetPolicy)(&buf, bytes.NewBufferString(src)); er != nil {
+			return nil, er
+		}
+
+		var signed *SignedPolicyDocument
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &signed))
+
+		return signed, nil
+	}
+
+	var history []SignedPolicyDocument
+
+	t.Run("Publish", func(t *testing.T) {
+		var stored []SignedPolicyDocument
+
+		cmd, er := newCmd(t, nil, func(a string, signed *SignedPolicyDocument) error {
+			require.Equal(t, alias, a)
+			stored = append(stored, *signed)
+
+			return nil
+		})
+		require.NoError(t, er)
+
+		_, er = getPolicy(t, cmd, 0)
+		require.EqualError(t, er, "log maple2021 has no published policy")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+
+		first, er := publish(t, cmd, `{"mmd":86400,"contact":"ops@example.com"}`)
+		require.NoError(t, er)
+		require.Equal(t, PolicySignatureType, first.Statement.SignatureType)
+		require.Equal(t, uint64(1), first.Statement.PolicyVersion)
+		require.Empty(t, first.Statement.Previous)
+		require.Equal(t, []string{"did:example:oak"}, first.Statement.Policy.AcceptedIssuers)
+		require.NoError(t, VerifySignature(first.Signature, cmd.PubKey, first.Statement))
+
+		// the same policy is not published again
+		same, er := publish(t, cmd, `{"mmd":86400,"contact":"ops@example.com"}`)
+		require.NoError(t, er)
+		require.Equal(t, first, same)
+
+		second, er := publish(t, cmd, `{"mmd":86400,"contact":"ops@example.com","retention":"10 years"}`)
+		require.NoError(t, er)
+		require.Equal(t, uint64(2), second.Statement.PolicyVersion)
+
+		hash, er := PolicyDocumentHash(first)
+		require.NoError(t, er)
+		require.Equal(t, hash, second.Statement.Previous)
+
+		latest, er := getPolicy(t, cmd, 0)
+		require.NoError(t, er)
+		require.Equal(t, second, latest)
+
+		version, er := getPolicy(t, cmd, 1)
+		require.NoError(t, er)
+		require.Equal(t, first, version)
+
+		_, er = getPolicy(t, cmd, 3)
+		require.EqualError(t, er, "version 3 of the policy of log maple2021 is not published")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetPolicyHistory)(&buf, bytes.NewBufferString(`"maple2021"`)))
+
+		var resp *GetPolicyHistoryResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+		require.Equal(t, stored, resp.Policies)
+		require.NoError(t, vct.VerifyPolicyHistory(alias, resp.Policies, cmd.PubKey))
+
+		history = resp.Policies
+
+		buf.Reset()
+		require.NoError(t, lookupHandler(t, cmd, Webfinger)(&buf, bytes.NewBufferString(`"maple2021"`)))
+		require.Contains(t, buf.String(), PolicyType)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		cmd, er := newCmd(t, map[string][]SignedPolicyDocument{alias: history}, nil)
+		require.NoError(t, er)
+
+		third, er := publish(t, cmd, `{"mmd":3600,"contact":"ops@example.com"}`)
+		require.NoError(t, er)
+		require.Equal(t, uint64(3), third.Statement.PolicyVersion)
+
+		_, er = newCmd(t, map[string][]SignedPolicyDocument{alias: history[1:]}, nil)
+		require.EqualError(t, er, "restore policies: policy of log maple2021: history does not start with version 1")
+
+		tampered := append([]SignedPolicyDocument(nil), history...)
+		tampered[0].Statement.Policy.MMD = 60
+
+		_, er = newCmd(t, map[string][]SignedPolicyDocument{alias: tampered}, nil)
+		require.EqualError(t, er, "restore policies: policy of log maple2021: version 2 does not follow version 1")
+	})
+
+	t.Run("Store error", func(t *testing.T) {
+		cmd, er := newCmd(t, nil, func(string, *SignedPolicyDocument) error {
+			return fmt.Errorf("store error")
+		})
+		require.NoError(t, er)
+
+		_, er = publish(t, cmd, `{"mmd":86400,"contact":"ops@example.com"}`)
+		require.EqualError(t, er, "store policy document: store error")
+
+		_, er = getPolicy(t, cmd, 0)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(er))
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		cmd, er := newCmd(t, nil, nil)
+		require.NoError(t, er)
+
+		_, er = publish(t, cmd, `{"contact":"ops@example.com"}`)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "validate PublishPolicy request")
+
+		_, er = publish(t, cmd, `{"mmd":86400}`)
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "validate PublishPolicy request")
+
+		er = lookupHandler(t, cmd, PublishPolicy)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+		require.ErrorIs(t, er, errors.ErrBadRequest)
+
+		er = lookupHandler(t, cmd, GetPolicy)(&bytes.Buffer{}, bytes.NewBufferString(`[]`))
+		require.ErrorIs(t, er, errors.ErrBadRequest)
+
+		er = lookupHandler(t, cmd, GetPolicy)(&bytes.Buffer{}, bytes.NewBufferString(`{"alias":"unknown"}`))
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "has permissions")
+
+		er = lookupHandler(t, cmd, GetPolicyHistory)(&bytes.Buffer{}, bytes.NewBufferString(`"unknown"`))
+		require.Error(t, er)
+		require.Contains(t, er.Error(), "has permissions")
+	})
+}
+
+func TestCmd_GetTreeHeadSLA(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the tree head of the log returned by Trillian is published at the time returned by timestamp
+	logClient := func(timestamp func() time.Time) TrillianLogClient {
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(interface{}, *trillian.GetLatestSignedLogRootRequest,
+				...interface{}) (*trillian.GetLatestSignedLogRootResponse, error) {
+				root, err := (&types.LogRootV1{
+					TreeSize:       1,
+					TimestampNanos: uint64(timestamp().UnixNano()),
+				}).MarshalBinary()
+				require.NoError(t, err)
+
+				return &trillian.GetLatestSignedLogRootResponse{
+					SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+				}, nil
+			},
+		).AnyTimes()
+
+		return client
+	}
+
+	stale := time.Now().Add(-time.Hour)
+
+	newCmd := func(t *testing.T, sla *TreeHeadSLAConfig) *Cmd {
+		t.Helper()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{
+				{Alias: alias, Permission: "r", Client: logClient(time.Now)},
+				{Alias: "oak", Permission: "r", Client: logClient(func() time.Time { return stale })},
+			},
+			Key:         Key{ID: newKID},
+			TreeHeadSLA: sla,
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd
+	}
+
+	monitor := func(cmd *Cmd, d time.Duration) {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+
+		cmd.MonitorTreeHeads(ctx)
+	}
+
+	getSLA := func(t *testing.T, cmd *Cmd, req string) (*bytes.Buffer, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		return &buf, lookupHandler(t, cmd, GetTreeHeadSLA)(&buf, bytes.NewBufferString(req))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		cmd := newCmd(t, &TreeHeadSLAConfig{Interval: 50 * time.Millisecond, Windows: []time.Duration{time.Hour}})
+
+		monitor(cmd, 200*time.Millisecond)
+
+		buf, err := getSLA(t, cmd, `{}`)
+		require.NoError(t, err)
+
+		var report *TreeHeadSLAReport
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+
+		require.Equal(t, uint64(50), report.Interval)
+		require.Len(t, report.Logs, 2)
+
+		// the tree heads of the log are published in time
+		require.Equal(t, alias, report.Logs[0].Alias)
+		require.Len(t, report.Logs[0].Windows, 1)
+
+		window := report.Logs[0].Windows[0]
+		require.Equal(t, "1h", window.Window)
+		require.Equal(t, report.Since, window.From)
+		require.Equal(t, report.Timestamp, window.To)
+		require.Greater(t, window.TreeHeads, 1)
+		require.GreaterOrEqual(t, window.ExpectedTreeHeads, 4)
+		require.Zero(t, window.Violations)
+		require.Less(t, window.MaxGap, uint64(50))
+		require.Equal(t, float64(1), window.Compliance)
+
+		// no tree head of oak was published while it was monitored
+		window = report.Logs[1].Windows[0]
+		require.Equal(t, "oak", report.Logs[1].Alias)
+		require.Zero(t, window.TreeHeads)
+		require.Equal(t, 1, window.Violations)
+		require.GreaterOrEqual(t, window.MaxGap, uint64(time.Hour/time.Millisecond))
+		require.Zero(t, window.Compliance)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		cmd := newCmd(t, &TreeHeadSLAConfig{Interval: time.Hour})
+
+		monitor(cmd, 10*time.Millisecond)
+
+		buf, err := getSLA(t, cmd, `{"alias":"maple2021","format":"csv"}`)
+		require.NoError(t, err)
+
+		rows, err := csv.NewReader(buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		require.Equal(t, []string{
+			"alias", "window", "from", "to", "interval", "tree_heads", "expected_tree_heads", "max_gap",
+			"violations", "compliance",
+		}, rows[0])
+
+		for i, window := range []string{"1h", "24h", "168h"} {
+			require.Equal(t, alias, rows[i+1][0])
+			require.Equal(t, window, rows[i+1][1])
+			require.Equal(t, "3600000", rows[i+1][4])
+			require.Equal(t, "1.000000", rows[i+1][9])
+		}
+	})
+
+	t.Run("Not monitored", func(t *testing.T) {
+		cmd := newCmd(t, nil)
+
+		// returns immediately
+		cmd.MonitorTreeHeads(context.Background())
+
+		_, err := getSLA(t, cmd, `{}`)
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		cmd := newCmd(t, &TreeHeadSLAConfig{Interval: time.Hour})
+
+		_, err := getSLA(t, cmd, `{"alias":"unknown"}`)
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+		_, err = getSLA(t, cmd, `{"format":"xml"}`)
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+
+		_, err = getSLA(t, cmd, `[]`)
+		require.Error(t, err)
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+	})
+}
+
+func TestCmd_GetRetiredShards(t *testing.T) {
+	ctx := context.Background()
+
+	log := merklelog.New(merklelog.NewMemStorage())
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{})
+	require.NoError(t, err)
+
+	km, cr := createKMSAndCrypto(t)
+
+	newKID := func(t *testing.T) string {
+		t.Helper()
+
+		kid, _, er := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, er)
+
+		return kid
+	}
+
+	newCmd := func(kid string, cfg *Config) (*Cmd, error) {
+		cfg.KMS, cfg.Crypto, cfg.Key = km, cr, Key{ID: kid}
+
+		return New(cfg, nil)
+	}
+
+	getShards := func(t *testing.T, cmd *Cmd, req string) []RetiredShard {
+		t.Helper()
+
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetRetiredShards)(&buf, bytes.NewBufferString(req)))
+
+		var resp *GetRetiredShardsResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp.Shards
+	}
+
+	freeze := func(t *testing.T, cmd *Cmd) (*SignedFinalTreeHead, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		if er := lookupHandler(t, cmd, FreezeLog)(&buf, bytes.NewBufferString(`{"alias":"maple2021"}`)); er != nil {
+			return nil, er
+		}
+
+		var final *SignedFinalTreeHead
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &final))
+
+		return final, nil
+	}
+
+	logs := []Log{{Alias: alias, Tenant: "maple", Permission: "rw", Client: log}}
+	kid := newKID(t)
+
+	var retired *RetiredShard
+
+	cmd, err := newCmd(kid, &Config{
+		Logs: logs,
+		OnRetire: func(shard *RetiredShard) error {
+			retired = shard
+
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.Empty(t, getShards(t, cmd, `{}`))
+
+	final, err := freeze(t, cmd)
+	require.NoError(t, err)
+
+	t.Run("Archived once frozen", func(t *testing.T) {
+		require.NotNil(t, retired)
+		require.Equal(t, alias, retired.Alias)
+		require.Equal(t, "maple", retired.Tenant)
+		require.Equal(t, cmd.PubKey, retired.PublicKey)
+		require.Equal(t, cmd.VCLogID[:], retired.LogID)
+		require.Equal(t, *final, retired.FinalTreeHead)
+
+		require.Equal(t, []RetiredShard{*retired}, getShards(t, cmd, `{}`))
+		require.Equal(t, []RetiredShard{*retired}, getShards(t, cmd, `{"tenant":"maple"}`))
+		require.Empty(t, getShards(t, cmd, `{"tenant":"oak"}`))
+	})
+
+	t.Run("Served with another key", func(t *testing.T) {
+		// the next shard of the tenant is signed with another key, the retired shard is no longer served
+		next, er := newCmd(newKID(t), &Config{
+			Logs:          []Log{{Alias: "maple2022", Tenant: "maple", Permission: "rw", Client: log}},
+			RetiredShards: []RetiredShard{*retired},
+		})
+		require.NoError(t, er)
+		require.NotEqual(t, retired.PublicKey, next.PubKey)
+
+		require.Equal(t, []RetiredShard{*retired}, getShards(t, next, ``))
+	})
+
+	t.Run("Frozen log not archived", func(t *testing.T) {
+		restored, er := newCmd(kid, &Config{
+			Logs:    logs,
+			Freezes: map[string]*SignedFinalTreeHead{alias: final},
+		})
+		require.NoError(t, er)
+
+		require.Equal(t, []RetiredShard{*retired}, getShards(t, restored, `{}`))
+	})
+
+	t.Run("Invalid retired shard", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			tamper func(shard *RetiredShard)
+			err    string
+		}{
+			{"Key", func(shard *RetiredShard) { shard.PublicKey = []byte("key") }, "log ID is not derived"},
+			{"Alias", func(shard *RetiredShard) { shard.Alias = "maple2020" }, "issued for another log"},
+			{"Signature", func(shard *RetiredShard) {
+				shard.FinalTreeHead.Statement.Reason = "forged"
+			}, "statement signature"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				shard := *retired
+				tc.tamper(&shard)
+
+				_, er := newCmd(kid, &Config{Logs: logs, RetiredShards: []RetiredShard{shard}})
+				require.Error(t, er)
+				require.Contains(t, er.Error(), "restore retired shards")
+				require.Contains(t, er.Error(), tc.err)
+			})
+		}
+	})
+
+	t.Run("Archive error", func(t *testing.T) {
+		failing, er := newCmd(kid, &Config{
+			Logs:     logs,
+			OnRetire: func(*RetiredShard) error { return fmt.Errorf("store down") },
+		})
+		require.NoError(t, er)
+
+		_, er = freeze(t, failing)
+		require.EqualError(t, er, "store retired shard: store down")
+
+		// the log is not frozen
+		require.Error(t, lookupHandler(t, failing, GetFinalTreeHead)(&bytes.Buffer{},
+			bytes.NewBufferString(`"maple2021"`)))
+		require.Empty(t, getShards(t, failing, `{}`))
+	})
+}
+
+func TestCmd_PolicyTags(t *testing.T) { // nolint: funlen
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	var credential struct {
+		Issuer string `json:"issuer"`
+	}
+
+	require.NoError(t, json.Unmarshal(verifiableCredential, &credential))
+
+	leafHash := func(i int) []byte {
+		h := sha256.Sum256([]byte(fmt.Sprint(i)))
+
+		return h[:]
+	}
+
+	vcLeaf := func(t *testing.T, issuer string, tags *PolicyTags) []byte {
+		t.Helper()
+
+		var extensions []byte
+
+		if tags != nil {
+			var err error
+
+			extensions, err = json.Marshal(EntryExtensions{PolicyTags: tags})
+			require.NoError(t, err)
+		}
+
+		leaf, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: &TimestampedEntry{
+			EntryType:  VCLogEntryType,
+			VCEntry:    []byte(fmt.Sprintf(`{"issuer":%q}`, issuer)),
+			Extensions: extensions,
+		}})
+		require.NoError(t, err)
+
+		return leaf
+	}
+
+	// the issuers of Quebec and Ontario log credentials of Canada, leaf 2 has no tags
+	values := [][]byte{
+		vcLeaf(t, "did:example:quebec", &PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: AssuranceLevelHigh}),
+		vcLeaf(t, "did:example:france", &PolicyTags{Jurisdiction: "FR", AssuranceLevel: AssuranceLevelHigh}),
+		vcLeaf(t, "did:example:untagged", nil),
+		vcLeaf(t, "did:example:ontario", &PolicyTags{Jurisdiction: "CA-ON", AssuranceLevel: AssuranceLevelLow}),
+		vcLeaf(t, "did:example:quebec", &PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: AssuranceLevelLow}),
+	}
+
+	newCmd := func(t *testing.T, ctrl *gomock.Controller, schema *PolicyTagsSchema) (*Cmd, *MockTrillianLogClient) {
+		t.Helper()
+
+		root, err := (&types.LogRootV1{TreeSize: uint64(len(values))}).MarshalBinary()
+		require.NoError(t, err)
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+			&trillian.GetLatestSignedLogRootResponse{
+				SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+			}, nil,
+		).AnyTimes()
+		client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+				var leaves []*trillian.LogLeaf
+
+				for i := req.StartIndex; i < req.StartIndex+req.Count && i < int64(len(values)); i++ {
+					leaves = append(leaves, &trillian.LogLeaf{
+						LeafIndex:      i,
+						LeafValue:      values[i],
+						MerkleLeafHash: leafHash(int(i)),
+					})
+				}
+
+				return &trillian.GetLeavesByRangeResponse{
+					Leaves:        leaves,
+					SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root},
+				}, nil
+			},
+		).AnyTimes()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "rw",
+				Client:     client,
+				Issuers:    []string{credential.Issuer},
+			}},
+			VDR:             vdr.New(vdr.WithVDR(key.New())),
+			Key:             Key{ID: newKID},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
+			PolicyTags:      schema,
+		}, nil)
+		require.NoError(t, err)
+
+		return cmd, client
+	}
+
+	addVC := func(t *testing.T, cmd *Cmd, tags *PolicyTags) error {
+		t.Helper()
+
+		envelope, err := json.Marshal(AddVCEnvelope{
+			Credential: verifiableCredential,
+			Options:    &AddVCOptions{PolicyTags: tags, DryRun: true},
+		})
+		require.NoError(t, err)
+
+		src, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+		require.NoError(t, err)
+
+		var resp bytes.Buffer
+
+		if err = cmd.AddVC(&resp, bytes.NewBuffer(src)); err != nil {
+			return err
+		}
+
+		var result *AddVCResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+
+		extensions, err := base64.StdEncoding.DecodeString(result.Extensions)
+		require.NoError(t, err)
+
+		var ext EntryExtensions
+		if len(extensions) > 0 {
+			require.NoError(t, json.Unmarshal(extensions, &ext))
+		}
+
+		require.Equal(t, tags, ext.PolicyTags)
+
+		return nil
+	}
+
+	getEntries := func(t *testing.T, cmd *Cmd, filter PolicyTags) []LeafEntry {
+		t.Helper()
+
+		src, err := json.Marshal(GetEntriesRequest{Alias: alias, Start: 0, End: 4, PolicyTags: filter})
+		require.NoError(t, err)
+
+		var resp bytes.Buffer
+		require.NoError(t, cmd.GetEntries(&resp, bytes.NewBuffer(src)))
+
+		var result *GetEntriesResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+
+		return result.Entries
+	}
+
+	getIssuers := func(t *testing.T, cmd *Cmd, request *GetIssuersRequest) *GetIssuersResponse {
+		t.Helper()
+
+		src, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		var resp bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, GetIssuers)(&resp, bytes.NewBuffer(src)))
+
+		var result *GetIssuersResponse
+		require.NoError(t, json.Unmarshal(resp.Bytes(), &result))
+
+		return result
+	}
+
+	t.Run("Tagged submission", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, _ := newCmd(t, ctrl, &PolicyTagsSchema{Jurisdictions: []string{"CA", "FR"}})
+
+		require.NoError(t, addVC(t, cmd, &PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: AssuranceLevelSubstantial}))
+		require.NoError(t, addVC(t, cmd, &PolicyTags{Jurisdiction: "FR"}))
+		require.NoError(t, addVC(t, cmd, nil))
+	})
+
+	t.Run("Invalid tags", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, _ := newCmd(t, ctrl, &PolicyTagsSchema{
+			Jurisdictions:   []string{"CA"},
+			AssuranceLevels: []string{"loa2", "loa3"},
+		})
+
+		for tags, msg := range map[PolicyTags]string{
+			{Jurisdiction: "Canada"}:                    `policy tags: jurisdiction "Canada" is not an ISO 3166 code`,
+			{Jurisdiction: "FR"}:                        `policy tags: jurisdiction "FR" is not accepted`,
+			{Jurisdiction: "CAN"}:                       `policy tags: jurisdiction "CAN" is not an ISO 3166 code`,
+			{AssuranceLevel: AssuranceLevelSubstantial}: `policy tags: assurance level "substantial" is not one of loa2, loa3`,
+		} {
+			tags := tags
+
+			err := addVC(t, cmd, &tags)
+			require.EqualError(t, err, msg)
+			require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(err))
+		}
+
+		require.NoError(t, addVC(t, cmd, &PolicyTags{Jurisdiction: "CA-ON", AssuranceLevel: "loa3"}))
+	})
+
+	t.Run("Invalid schema", func(t *testing.T) {
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(keyType)
+		require.NoError(t, err)
+
+		_, err = New(&Config{
+			KMS:        km,
+			Crypto:     cr,
+			Key:        Key{ID: newKID},
+			PolicyTags: &PolicyTagsSchema{Jurisdictions: []string{"ca"}},
+		}, nil)
+		require.EqualError(t, err, `policy tags schema: jurisdiction "ca" is not an ISO 3166 code`)
+	})
+
+	t.Run("Filter entries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, _ := newCmd(t, ctrl, nil)
+
+		leafIndexes := func(entries []LeafEntry) []int64 {
+			indexes := []int64{}
+			for _, entry := range entries {
+				indexes = append(indexes, *entry.LeafIndex)
+			}
+
+			return indexes
+		}
+
+		require.Equal(t, []int64{0, 3, 4}, leafIndexes(getEntries(t, cmd, PolicyTags{Jurisdiction: "CA"})))
+		require.Equal(t, []int64{0, 4}, leafIndexes(getEntries(t, cmd, PolicyTags{Jurisdiction: "CA-QC"})))
+		require.Equal(t, []int64{3, 4}, leafIndexes(getEntries(t, cmd, PolicyTags{
+			Jurisdiction: "CA", AssuranceLevel: AssuranceLevelLow,
+		})))
+		require.Equal(t, []int64{0, 1}, leafIndexes(getEntries(t, cmd, PolicyTags{AssuranceLevel: AssuranceLevelHigh})))
+		require.Empty(t, getEntries(t, cmd, PolicyTags{Jurisdiction: "C"}))
+
+		// the entries are not filtered without tags
+		entries := getEntries(t, cmd, PolicyTags{})
+		require.Len(t, entries, len(values))
+		require.Nil(t, entries[0].LeafIndex)
+	})
+
+	t.Run("Filter issuers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd, _ := newCmd(t, ctrl, nil)
+
+		resp := getIssuers(t, cmd, &GetIssuersRequest{Alias: alias, PolicyTags: PolicyTags{Jurisdiction: "CA"}})
+		require.Equal(t, []string{"did:example:quebec", "did:example:ontario"}, resp.Issuers)
+
+		resp = getIssuers(t, cmd, &GetIssuersRequest{Alias: alias, PolicyTags: PolicyTags{
+			AssuranceLevel: AssuranceLevelHigh,
+		}})
+		require.Equal(t, []string{"did:example:quebec", "did:example:france"}, resp.Issuers)
+
+		resp = getIssuers(t, cmd, &GetIssuersRequest{Alias: alias, PolicyTags: PolicyTags{Jurisdiction: "DE"}})
+		require.Empty(t, resp.Issuers)
+		require.NotNil(t, resp.Issuers)
+
+		// the issuers of the log without tags
+		resp = getIssuers(t, cmd, &GetIssuersRequest{Alias: alias, PageRequest: PageRequest{PageSize: 10}})
+		require.Equal(t, []string{credential.Issuer}, resp.Issuers)
+
+		// the page tokens are bound to the filter
+		resp = getIssuers(t, cmd, &GetIssuersRequest{
+			Alias:       alias,
+			PageRequest: PageRequest{PageSize: 1},
+			PolicyTags:  PolicyTags{Jurisdiction: "CA"},
+		})
+		require.Equal(t, []string{"did:example:quebec"}, resp.Issuers)
+		require.NotEmpty(t, resp.NextPageToken)
+
+		resp = getIssuers(t, cmd, &GetIssuersRequest{
+			Alias:       alias,
+			PageRequest: PageRequest{PageToken: resp.NextPageToken},
+			PolicyTags:  PolicyTags{Jurisdiction: "CA"},
+		})
+		require.Equal(t, []string{"did:example:ontario"}, resp.Issuers)
+
+		first := getIssuers(t, cmd, &GetIssuersRequest{
+			Alias:       alias,
+			PageRequest: PageRequest{PageSize: 1},
+			PolicyTags:  PolicyTags{Jurisdiction: "CA"},
+		})
+
+		src, err := json.Marshal(&GetIssuersRequest{
+			Alias:       alias,
+			PageRequest: PageRequest{PageToken: first.NextPageToken},
+			PolicyTags:  PolicyTags{Jurisdiction: "FR"},
+		})
+		require.NoError(t, err)
+
+		err = lookupHandler(t, cmd, GetIssuers)(&bytes.Buffer{}, bytes.NewBuffer(src))
+		require.EqualError(t, err, "validation failed: page_token is not valid")
+	})
+}
+
+func TestCmd_GetSubmissionStats(t *testing.T) { // nolint: funlen
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var credential struct {
+		Issuer string `json:"issuer"`
+	}
+
+	require.NoError(t, json.Unmarshal(verifiableCredential, &credential))
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:             km,
+		Crypto:          cr,
+		Logs:            []Log{{Alias: alias, Permission: "rw", Client: NewMockTrillianLogClient(ctrl)}},
+		VDR:             vdr.New(vdr.WithVDR(key.New())),
+		Key:             Key{ID: newKID},
+		DocumentLoaders: map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
+		SubmissionStats: &SubmissionStatsConfig{Samples: 2, Issuers: 2},
+	}, nil)
+	require.NoError(t, err)
+
+	addVC := func(t *testing.T, vc []byte) error {
+		t.Helper()
+
+		envelope, er := json.Marshal(AddVCEnvelope{Credential: vc, Options: &AddVCOptions{DryRun: true}})
+		require.NoError(t, er)
+
+		src, er := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+		require.NoError(t, er)
+
+		return cmd.AddVC(&bytes.Buffer{}, bytes.NewBuffer(src))
+	}
+
+	stats := func(t *testing.T, req string) (*GetSubmissionStatsResponse, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		if er := lookupHandler(t, cmd, GetSubmissionStats)(&buf, bytes.NewBufferString(req)); er != nil {
+			return nil, er
+		}
+
+		var resp *GetSubmissionStatsResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	// the credentials of the issuers are rejected by the parser
+	rejected := func(issuer string, contexts, size int) []byte {
+		return []byte(fmt.Sprintf(`{"@context":["https://www.w3.org/2018/credentials/v1"%s],"issuer":%s,`+
+			`"type":"VerifiableCredential","padding":"%s"}`, bytes.Repeat([]byte(`,"https://example.com"`), contexts-1),
+			issuer, bytes.Repeat([]byte("a"), size)))
+	}
+
+	pathological := rejected(`{"id":"did:example:pathological"}`, 3, 4096)
+
+	require.NoError(t, addVC(t, verifiableCredential))
+	require.NoError(t, addVC(t, verifiableCredential))
+	require.Error(t, addVC(t, pathological))
+	require.Error(t, addVC(t, []byte(`"not a credential"`)))
+
+	resp, err := stats(t, `{"sort_by":"size"}`)
+	require.NoError(t, err)
+	require.NotZero(t, resp.Since)
+	require.Len(t, resp.Issuers, 2)
+
+	first, second := resp.Issuers[0], resp.Issuers[1]
+	require.Equal(t, "did:example:pathological", first.Issuer)
+	require.Equal(t, uint64(1), first.Submissions)
+	require.Equal(t, uint64(1), first.Rejected)
+	require.Equal(t, Percentiles{P50: 3, P95: 3, P99: 3, Max: 3}, first.Contexts)
+	require.Equal(t, float64(len(pathological)), first.Size.P95)
+
+	require.Equal(t, credential.Issuer, second.Issuer)
+	require.Equal(t, alias, second.Alias)
+	require.Equal(t, uint64(2), second.Submissions)
+	require.Zero(t, second.Rejected)
+	require.Equal(t, uint64(2*second.Size.Max), second.Bytes)
+	require.NotZero(t, second.ProcessingTime.Max)
+
+	resp, err = stats(t, fmt.Sprintf(`{"issuer":%q,"limit":1}`, credential.Issuer))
+	require.NoError(t, err)
+	require.Len(t, resp.Issuers, 1)
+	require.Equal(t, credential.Issuer, resp.Issuers[0].Issuer)
+
+	t.Run("Samples and issuers", func(t *testing.T) {
+		var sizes []float64
+
+		for i := 1; i <= 3; i++ {
+			vc := rejected(`"did:example:oak"`, 1, i*100)
+			require.Error(t, addVC(t, vc))
+
+			sizes = append(sizes, float64(len(vc)))
+		}
+
+		// the oldest samples are overwritten and the issuer which submitted least recently is evicted
+		resp, err = stats(t, `{"issuer":"did:example:oak"}`)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), resp.Issuers[0].Submissions)
+		require.Equal(t, Percentiles{P50: sizes[1], P95: sizes[2], P99: sizes[2], Max: sizes[2]}, resp.Issuers[0].Size)
+
+		resp, err = stats(t, "")
+		require.NoError(t, err)
+		require.Len(t, resp.Issuers, 2)
+		require.NotEqual(t, credential.Issuer, resp.Issuers[0].Issuer)
+		require.NotEqual(t, credential.Issuer, resp.Issuers[1].Issuer)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err = stats(t, `{"alias":"unknown"}`)
+		require.EqualError(t, err, `log "unknown" is not found`)
+
+		_, err = stats(t, `{"sort_by":"name"}`)
+		require.EqualError(t, err, `sort by "name" is not one of size, contexts, processing_time`)
+
+		_, err = stats(t, `[]`)
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+	})
+}
+
+func TestCmd_SetIPFSRoot(t *testing.T) {
+	km, cr := createKMSAndCrypto(t)
+	kid, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	root := &IPFSRoot{CID: "bafyreigbtj4x7ip5legnfznufuopl4sg4knzc2cof6duas4b3q2fy6swua", TreeSize: 7}
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{{
+			Alias:      alias,
+			Permission: "rw",
+			Client:     NewMockTrillianLogClient(nil),
+		}},
+		Key:       Key{ID: kid},
+		IPFSRoots: map[string]*IPFSRoot{alias: root},
+	}, nil)
+	require.NoError(t, err)
+
+	webfinger := func() map[string]interface{} {
+		var buf bytes.Buffer
+		require.NoError(t, lookupHandler(t, cmd, Webfinger)(&buf, bytes.NewBufferString(`"maple2021"`)))
+
+		var resp *WebFingerResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp.Properties
+	}
+
+	require.Equal(t, map[string]interface{}{"cid": root.CID, "tree_size": float64(7)}, webfinger()[IPFSRootType])
+
+	// a root of a smaller tree is ignored
+	require.NoError(t, cmd.SetIPFSRoot(alias, &IPFSRoot{CID: "bafyreiold", TreeSize: 3}))
+	require.Equal(t, root.CID, webfinger()[IPFSRootType].(map[string]interface{})["cid"])
+
+	require.NoError(t, cmd.SetIPFSRoot(alias, &IPFSRoot{CID: "bafyreinew", TreeSize: 9}))
+	require.Equal(t, "bafyreinew", webfinger()[IPFSRootType].(map[string]interface{})["cid"])
+
+	require.EqualError(t, cmd.SetIPFSRoot("unknown", root), "log unknown is not served")
+	require.EqualError(t, cmd.SetIPFSRoot(alias, &IPFSRoot{}), "root of the mirror of log maple2021 has no CID")
+
+	_, err = New(&Config{
+		KMS:       km,
+		Crypto:    cr,
+		Logs:      []Log{{Alias: alias, Permission: "rw", Client: NewMockTrillianLogClient(nil)}},
+		Key:       Key{ID: kid},
+		IPFSRoots: map[string]*IPFSRoot{"unknown": root},
+	}, nil)
+	require.EqualError(t, err, "restore IPFS root: log unknown is not served")
+}
+
+func TestCmd_GetPendingSubmissions(t *testing.T) { // nolint: funlen
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// an old leaf integrated before the submissions were queued, it is skipped by the search
+	sequenced := []*trillian.LogLeaf{{
+		LeafIndex:          0,
+		LeafIdentityHash:   []byte("old"),
+		IntegrateTimestamp: timestamppb.New(time.Now().Add(-time.Hour)),
+	}}
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _ *trillian.GetLatestSignedLogRootRequest, _ ...interface{}) (*trillian.GetLatestSignedLogRootResponse, error) { // nolint: lll
+			root, err := (&types.LogRootV1{TreeSize: uint64(len(sequenced))}).MarshalBinary()
+			require.NoError(t, err)
+
+			return &trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil
+		},
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			return &trillian.GetLeavesByRangeResponse{Leaves: sequenced[req.StartIndex : req.StartIndex+req.Count]}, nil
+		},
+	).AnyTimes()
+
+	var queued []*trillian.LogLeaf
+
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			req.Leaf.QueueTimestamp = timestamppb.Now()
+			queued = append(queued, req.Leaf)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).Times(2)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs:   []Log{{Alias: alias, Permission: "rw", Client: client}},
+		Key:    Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	addCommitment := func(t *testing.T, data string) {
+		t.Helper()
+
+		hash := sha256.Sum256([]byte(data))
+
+		src, er := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: CommitmentLogEntryType,
+			Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+		})
+		require.NoError(t, er)
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+	}
+
+	pending := func(t *testing.T, req string) (*GetPendingSubmissionsResponse, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		if er := lookupHandler(t, cmd, GetPendingSubmissions)(&buf, bytes.NewBufferString(req)); er != nil {
+			return nil, er
+		}
+
+		var resp *GetPendingSubmissionsResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	resp, err := pending(t, "")
+	require.NoError(t, err)
+	require.Len(t, resp.Logs, 1)
+	require.Equal(t, alias, resp.Logs[0].Alias)
+	require.Zero(t, resp.Logs[0].Pending)
+	require.Empty(t, resp.Logs[0].Submissions)
+
+	addCommitment(t, "first")
+	addCommitment(t, "second")
+
+	resp, err = pending(t, `{"alias":"maple2021","limit":1}`)
+	require.NoError(t, err)
+
+	logPending := resp.Logs[0]
+	require.Equal(t, int64(1), logPending.TreeSize)
+	require.Equal(t, 2, logPending.Pending)
+	require.Len(t, logPending.ByAge, 5)
+	require.Equal(t, "1m0s", logPending.ByAge[0].MaxAge)
+	require.Equal(t, 2, logPending.ByAge[0].Count)
+	require.Empty(t, logPending.ByAge[4].MaxAge)
+	require.Len(t, logPending.Submissions, 1)
+	require.Equal(t, queued[0].LeafIdentityHash, logPending.Submissions[0].LeafIdentityHash)
+	require.Equal(t, len(queued[0].LeafValue), logPending.Submissions[0].Size)
+	require.NotZero(t, logPending.Submissions[0].QueuedAt)
+
+	// the first submission is sequenced
+	sequenced = append(sequenced, &trillian.LogLeaf{
+		LeafIndex:          1,
+		LeafIdentityHash:   queued[0].LeafIdentityHash,
+		IntegrateTimestamp: timestamppb.Now(),
+	})
+
+	resp, err = pending(t, "")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), resp.Logs[0].TreeSize)
+	require.Equal(t, 1, resp.Logs[0].Pending)
+	require.Equal(t, queued[1].LeafIdentityHash, resp.Logs[0].Submissions[0].LeafIdentityHash)
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err = pending(t, `{"alias":"unknown"}`)
+		require.EqualError(t, err, `log "unknown" is not found`)
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+		_, err = pending(t, `[]`)
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+	})
+}
+
+func TestCmd_GetReconciliation(t *testing.T) { // nolint: funlen
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var sequenced []*trillian.LogLeaf
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _ *trillian.GetLatestSignedLogRootRequest, _ ...interface{}) (*trillian.GetLatestSignedLogRootResponse, error) { // nolint: lll
+			root, err := (&types.LogRootV1{TreeSize: uint64(len(sequenced))}).MarshalBinary()
+			require.NoError(t, err)
+
+			return &trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil
+		},
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			return &trillian.GetLeavesByRangeResponse{Leaves: sequenced[req.StartIndex : req.StartIndex+req.Count]}, nil
+		},
+	).AnyTimes()
+
+	var queued []*trillian.LogLeaf
+
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			req.Leaf.QueueTimestamp = timestamppb.Now()
+			queued = append(queued, req.Leaf)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).Times(2)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	store, err := mem.NewProvider().OpenStore("dedup")
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T, cfg *ReconciliationConfig) *Cmd {
+		t.Helper()
+
+		cmd, er := New(&Config{
+			KMS:            km,
+			Crypto:         cr,
+			Logs:           []Log{{Alias: alias, Permission: "rw", Client: client}},
+			Key:            Key{ID: newKID},
+			DedupStore:     store,
+			Reconciliation: cfg,
+		}, nil)
+		require.NoError(t, er)
+
+		return cmd
+	}
+
+	cmd := newCmd(t, &ReconciliationConfig{Interval: time.Hour, StaleAfter: time.Nanosecond})
+
+	reconciliation := func(t *testing.T, req string) (*ReconciliationReport, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		if er := lookupHandler(t, cmd, GetReconciliation)(&buf, bytes.NewBufferString(req)); er != nil {
+			return nil, er
+		}
+
+		var report *ReconciliationReport
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+
+		return report, nil
+	}
+
+	// the logs are reconciled once if the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd.ReconcileLogs(ctx)
+
+	report, err := reconciliation(t, "")
+	require.NoError(t, err)
+	require.NotZero(t, report.Timestamp)
+	require.False(t, report.Drift)
+	require.Equal(t, []LogReconciliation{{Alias: alias}}, report.Logs)
+
+	for _, data := range []string{"first", "second"} {
+		hash := sha256.Sum256([]byte(data))
+
+		src, er := json.Marshal(AddEntryRequest{
+			Alias:     alias,
+			EntryType: CommitmentLogEntryType,
+			Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+		})
+		require.NoError(t, er)
+
+		require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+	}
+
+	// the first submission is sequenced, the second one is pending
+	sequenced = append(sequenced, &trillian.LogLeaf{
+		LeafIndex:          0,
+		LeafIdentityHash:   queued[0].LeafIdentityHash,
+		IntegrateTimestamp: timestamppb.Now(),
+	})
+
+	// the latest reconciliation is returned unless the request asks to refresh it
+	report, err = reconciliation(t, `{"alias":"maple2021"}`)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), report.Logs[0].TreeSize)
+
+	report, err = reconciliation(t, `{"alias":"maple2021","refresh":true}`)
+	require.NoError(t, err)
+	require.True(t, report.Drift)
+	require.Equal(t, LogReconciliation{
+		Alias:         alias,
+		TreeSize:      1,
+		Accepted:      2,
+		Sequenced:     1,
+		Pending:       1,
+		Stale:         1,
+		DedupEntries:  2,
+		IndexedLeaves: 1,
+		Drift:         true,
+	}, report.Logs[0])
+
+	t.Run("Dedup drift", func(t *testing.T) {
+		for _, key := range []string{"00", "01"} {
+			require.NoError(t, store.Put(alias+"/"+key, []byte("{}"), storage.Tag{Name: DedupTagName}))
+		}
+
+		report, err = reconciliation(t, `{"refresh":true}`)
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), report.Logs[0].DedupEntries)
+		require.Equal(t, uint64(2), report.Logs[0].DedupDrift)
+	})
+
+	t.Run("Tree shrunk", func(t *testing.T) {
+		sequenced = nil
+
+		report, err = reconciliation(t, `{"refresh":true}`)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), report.Logs[0].ShrunkFrom)
+		require.True(t, report.Logs[0].Drift)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err = reconciliation(t, `{"alias":"unknown"}`)
+		require.EqualError(t, err, `log "unknown" is not found`)
+
+		_, err = reconciliation(t, `[]`)
+		require.ErrorIs(t, err, errors.ErrBadRequest)
+
+		cmd = newCmd(t, nil)
+
+		_, err = reconciliation(t, "")
+		require.EqualError(t, err, "logs are not reconciled")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
 }
 
-type readerMock struct{ err error }
+func TestCmd_UnmergedSubmissions(t *testing.T) { // nolint: funlen
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-func (r *readerMock) Read(p []byte) (n int, err error) {
-	return 0, r.err
+	var sequenced []*trillian.LogLeaf
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, _ *trillian.GetLatestSignedLogRootRequest, _ ...interface{}) (*trillian.GetLatestSignedLogRootResponse, error) { // nolint: lll
+			root, err := (&types.LogRootV1{TreeSize: uint64(len(sequenced))}).MarshalBinary()
+			require.NoError(t, err)
+
+			return &trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil
+		},
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			return &trillian.GetLeavesByRangeResponse{Leaves: sequenced[req.StartIndex : req.StartIndex+req.Count]}, nil
+		},
+	).AnyTimes()
+
+	var queued []*trillian.LogLeaf
+
+	// the submission was queued an hour ago, the TTL is 10m
+	client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+			req.Leaf.QueueTimestamp = timestamppb.New(time.Now().Add(-time.Hour))
+			queued = append(queued, req.Leaf)
+
+			return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+		},
+	).Times(2)
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	newCmd := func(t *testing.T, cfg *SubmissionTTLConfig) *Cmd {
+		t.Helper()
+
+		cmd, er := New(&Config{
+			KMS:           km,
+			Crypto:        cr,
+			Logs:          []Log{{Alias: alias, Permission: "rw", Client: client}},
+			Key:           Key{ID: newKID},
+			SubmissionTTL: cfg,
+		}, nil)
+		require.NoError(t, er)
+
+		return cmd
+	}
+
+	cmd := newCmd(t, &SubmissionTTLConfig{TTL: 10 * time.Minute, Requeue: true, MaxRequeues: 1})
+
+	unmerged := func(t *testing.T, alias string) (*GetUnmergedSubmissionsResponse, error) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		er := lookupHandler(t, cmd, GetUnmerged)(&buf, bytes.NewBufferString(fmt.Sprintf("%q", alias)))
+		if er != nil {
+			return nil, er
+		}
+
+		var resp *GetUnmergedSubmissionsResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	hash := sha256.Sum256([]byte("commitment"))
+
+	src, err := json.Marshal(AddEntryRequest{
+		Alias:     alias,
+		EntryType: CommitmentLogEntryType,
+		Entry:     json.RawMessage(fmt.Sprintf(`{"hash":%q}`, base64.StdEncoding.EncodeToString(hash[:]))),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, lookupHandler(t, cmd, AddEntry)(&bytes.Buffer{}, bytes.NewBuffer(src)))
+
+	resp, err := unmerged(t, alias)
+	require.NoError(t, err)
+	require.Equal(t, uint64((10 * time.Minute).Milliseconds()), resp.TTL)
+	require.Len(t, resp.Submissions, 1)
+	require.Equal(t, queued[0].LeafIdentityHash, resp.Submissions[0].LeafIdentityHash)
+	require.GreaterOrEqual(t, resp.Submissions[0].Age, uint64(time.Hour.Milliseconds()))
+	require.Zero(t, resp.Submissions[0].Requeues)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the unmerged submission is not re-queued in read-only mode
+	require.NoError(t, lookupHandler(t, cmd, SetReadOnly)(&bytes.Buffer{}, bytes.NewBufferString(`{"read_only":true}`)))
+
+	cmd.MonitorUnmerged(ctx)
+
+	require.Len(t, queued, 1)
+
+	resp, err = unmerged(t, alias)
+	require.NoError(t, err)
+	require.Zero(t, resp.Submissions[0].Requeues)
+
+	require.NoError(t, lookupHandler(t, cmd, SetReadOnly)(&bytes.Buffer{}, bytes.NewBufferString(`{"read_only":false}`)))
+
+	// the unmerged submission is re-queued once
+	cmd.MonitorUnmerged(ctx)
+	cmd.MonitorUnmerged(ctx)
+
+	require.Len(t, queued, 2)
+	require.Equal(t, queued[0].LeafValue, queued[1].LeafValue)
+	require.Equal(t, queued[0].LeafIdentityHash, queued[1].LeafIdentityHash)
+
+	resp, err = unmerged(t, alias)
+	require.NoError(t, err)
+	require.Len(t, resp.Submissions, 1)
+	require.Equal(t, 1, resp.Submissions[0].Requeues)
+	require.NotZero(t, resp.Submissions[0].RequeuedAt)
+
+	// the submission is sequenced
+	sequenced = append(sequenced, &trillian.LogLeaf{
+		LeafIndex:          0,
+		LeafIdentityHash:   queued[0].LeafIdentityHash,
+		IntegrateTimestamp: timestamppb.Now(),
+	})
+
+	resp, err = unmerged(t, alias)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.TreeSize)
+	require.Empty(t, resp.Submissions)
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err = unmerged(t, "unknown")
+		require.Error(t, err)
+
+		cmd = newCmd(t, nil)
+
+		_, err = unmerged(t, alias)
+		require.EqualError(t, err, "submission TTL is not enabled")
+		require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+	})
+}
+
+func TestCmd_CheckOperation(t *testing.T) {
+	km, cr := createKMSAndCrypto(t)
+	kid, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs: []Log{
+			{Alias: alias, Permission: "rw", Client: NewMockTrillianLogClient(nil), Tenant: "maple"},
+			{Alias: "oak2021", Permission: "rw", Client: NewMockTrillianLogClient(nil)},
+		},
+		Key: Key{ID: kid},
+		FeatureFlags: &FeatureFlagsConfig{
+			Disabled:          []string{"admin/submission-stats"},
+			DisabledPerTenant: map[string][]string{"maple": {"get-entries"}, "oak2021": {"add-vc"}},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, cmd.CheckOperation(alias, "get-sth"))
+	require.NoError(t, cmd.CheckOperation("oak2021", "get-entries"))
+	require.NoError(t, cmd.CheckOperation(alias, "add-vc"))
+	require.NoError(t, cmd.CheckOperation("", "get-entries"))
+
+	err = cmd.CheckOperation("", "admin/submission-stats")
+	require.EqualError(t, err, "operation admin/submission-stats is disabled")
+	require.Equal(t, http.StatusNotFound, errors.StatusCodeFromError(err))
+
+	err = cmd.CheckOperation(alias, "get-entries")
+	require.EqualError(t, err, "operation get-entries is disabled for tenant maple")
+	require.Equal(t, http.StatusForbidden, errors.StatusCodeFromError(err))
+	require.ErrorIs(t, err, errors.ErrDisabled)
+
+	// a log without a tenant is its own tenant
+	require.EqualError(t, cmd.CheckOperation("oak2021", "add-vc"), "operation add-vc is disabled for tenant oak2021")
+}
+
+func TestCmd_SCTExtensions(t *testing.T) { // nolint: funlen
+	var credential struct {
+		Issuer string `json:"issuer"`
+	}
+
+	require.NoError(t, json.Unmarshal(verifiableCredential, &credential))
+
+	newCmd := func(t *testing.T, extensions []SCTExtensionType) (*Cmd, *MerkleTreeLeaf, error) {
+		t.Helper()
+
+		ctrl := gomock.NewController(t)
+		t.Cleanup(ctrl.Finish)
+
+		logged := &MerkleTreeLeaf{}
+
+		client := NewMockTrillianLogClient(ctrl)
+		client.EXPECT().QueueLeaf(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, req *trillian.QueueLeafRequest, _ ...interface{}) (*trillian.QueueLeafResponse, error) {
+				require.NoError(t, json.Unmarshal(req.Leaf.LeafValue, logged))
+
+				return &trillian.QueueLeafResponse{QueuedLeaf: &trillian.QueuedLogLeaf{Leaf: req.Leaf}}, nil
+			},
+		).AnyTimes()
+
+		km, cr := createKMSAndCrypto(t)
+		newKID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		cmd, err := New(&Config{
+			KMS:    km,
+			Crypto: cr,
+			Logs: []Log{{
+				Alias:      alias,
+				Permission: "rw",
+				Client:     client,
+				Issuers:    []string{credential.Issuer},
+			}},
+			VDR:             vdr.New(vdr.WithVDR(key.New())),
+			Key:             Key{ID: newKID},
+			DocumentLoaders: map[string]jsonld.DocumentLoader{alias: ldcontext.DocumentLoader(t)},
+			SCTExtensions:   extensions,
+		}, nil)
+
+		return cmd, logged, err
+	}
+
+	addVC := func(t *testing.T, cmd *Cmd, tags *PolicyTags) *AddVCResponse {
+		t.Helper()
+
+		envelope, err := json.Marshal(AddVCEnvelope{
+			Credential: verifiableCredential,
+			Options:    &AddVCOptions{PolicyTags: tags},
+		})
+		require.NoError(t, err)
+
+		src, err := json.Marshal(AddVCRequest{Alias: alias, VCEntry: envelope})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, cmd.AddVC(&buf, bytes.NewBuffer(src)))
+
+		var resp *AddVCResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		cmd, logged, err := newCmd(t, []SCTExtensionType{
+			SCTExtensionPolicyTags, SCTExtensionShardID, SCTExtensionAnchorCommitment, SCTExtensionShardID,
+		})
+		require.NoError(t, err)
+
+		tags := &PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: AssuranceLevelHigh}
+
+		resp := addVC(t, cmd, tags)
+
+		data, err := json.Marshal(tags)
+		require.NoError(t, err)
+
+		// ordered by type, no anchor commitment for a credential
+		require.Equal(t, []SCTExtension{
+			{Type: SCTExtensionShardID, Data: []byte(alias)},
+			{Type: SCTExtensionPolicyTags, Data: data},
+		}, resp.SCTExtensions)
+
+		statement := CreateVCTimestampSignature(logged)
+		require.Error(t, VerifySignature(resp.Signature, cmd.PubKey, statement))
+
+		statement.SCTExtensions = resp.SCTExtensions
+		require.NoError(t, VerifySignature(resp.Signature, cmd.PubKey, statement))
+
+		// no policy tags
+		resp = addVC(t, cmd, nil)
+		require.Equal(t, []SCTExtension{{Type: SCTExtensionShardID, Data: []byte(alias)}}, resp.SCTExtensions)
+	})
+
+	t.Run("No extensions", func(t *testing.T) {
+		cmd, logged, err := newCmd(t, nil)
+		require.NoError(t, err)
+
+		resp := addVC(t, cmd, &PolicyTags{Jurisdiction: "FR"})
+		require.Empty(t, resp.SCTExtensions)
+		require.NoError(t, VerifySignature(resp.Signature, cmd.PubKey, CreateVCTimestampSignature(logged)))
+	})
+
+	t.Run("Not registered", func(t *testing.T) {
+		_, _, err := newCmd(t, []SCTExtensionType{SCTExtensionShardID, 42})
+		require.EqualError(t, err, "SCT extension 42 is not registered")
+	})
+
+	t.Run("Types", func(t *testing.T) {
+		for _, name := range []string{"shard-id", "policy-tags", "anchor-commitment"} {
+			extensionType, err := ParseSCTExtensionType(name)
+			require.NoError(t, err)
+			require.Equal(t, name, extensionType.String())
+		}
+
+		_, err := ParseSCTExtensionType("tenant")
+		require.EqualError(t, err, `SCT extension "tenant" is not registered`)
+		require.Equal(t, "unknown(42)", SCTExtensionType(42).String())
+	})
+}
+
+func TestSigningBytes(t *testing.T) { // nolint: funlen
+	var vectors struct {
+		Vectors []struct {
+			Description  string          `json:"description"`
+			Kind         string          `json:"kind"`
+			Statement    json.RawMessage `json:"statement"`
+			SigningInput string          `json:"signing_input"`
+			PublicKey    []byte          `json:"public_key"`
+			Signature    json.RawMessage `json:"signature"`
+		} `json:"vectors"`
+	}
+
+	require.NoError(t, json.Unmarshal(signingVectors, &vectors))
+	require.NotEmpty(t, vectors.Vectors)
+
+	// the structures as they were marshaled by encoding/json before the canonical serialization
+	type treeHead struct {
+		Version        Version       `json:"version"`
+		SignatureType  SignatureType `json:"signature_type"`
+		Timestamp      uint64        `json:"timestamp"`
+		TreeSize       uint64        `json:"tree_size"`
+		SHA256RootHash []byte        `json:"sha_256_root_hash"`
+	}
+
+	type sct struct {
+		SVCTVersion   Version        `json:"svct_version"`
+		SignatureType SignatureType  `json:"signature_type"`
+		Timestamp     uint64         `json:"timestamp"`
+		EntryType     LogEntryType   `json:"entry_type"`
+		VCEntry       []byte         `json:"vc_entry"`
+		Extensions    []byte         `json:"extensions"`
+		SCTExtensions []SCTExtension `json:"sct_extensions,omitempty"`
+	}
+
+	for _, vector := range vectors.Vectors {
+		vector := vector
+
+		t.Run(vector.Description, func(t *testing.T) {
+			var (
+				statement interface{}
+				signing   []byte
+				legacy    interface{}
+			)
+
+			switch vector.Kind {
+			case "tree_head":
+				var sth TreeHeadSignature
+				require.NoError(t, json.Unmarshal(vector.Statement, &sth))
+
+				statement, signing, legacy = sth, SigningBytesTreeHead(sth), treeHead(sth)
+			case "sct":
+				var s VCTimestampSignature
+				require.NoError(t, json.Unmarshal(vector.Statement, &s))
+
+				statement, signing, legacy = s, SigningBytesSCT(s), sct(s)
+			default:
+				t.Fatalf("unknown kind %s", vector.Kind)
+			}
+
+			require.Equal(t, vector.SigningInput, string(signing))
+
+			marshaled, err := json.Marshal(statement)
+			require.NoError(t, err)
+			require.Equal(t, vector.SigningInput, string(marshaled))
+
+			marshaled, err = json.Marshal(legacy)
+			require.NoError(t, err)
+			require.Equal(t, vector.SigningInput, string(marshaled))
+
+			require.NoError(t, VerifySignature(vector.Signature, vector.PublicKey, statement))
+		})
+	}
+}
+
+func TestCmd_GetDiff(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a leaf which is not an entry, then credentials of two issuers and revocations in turn
+	leaves := []*trillian.LogLeaf{{LeafIndex: 0, LeafValue: []byte("not an entry")}}
+
+	for i := 1; i < MaxDiffEntries+5; i++ {
+		entry := &TimestampedEntry{
+			Timestamp: uint64(i),
+			EntryType: RevocationLogEntryType,
+			VCEntry:   []byte(`{}`),
+		}
+
+		if i%2 == 1 {
+			issuer := `"did:example:a"`
+			if i%4 == 3 {
+				issuer = `{"id":"did:example:b"}`
+			}
+
+			entry.EntryType = VCLogEntryType
+			entry.VCEntry = []byte(fmt.Sprintf(`{"id":"urn:uuid:%d","issuer":%s,"type":["VerifiableCredential",`+
+				`"UniversityDegreeCredential"]}`, i, issuer))
+		}
+
+		value, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: entry})
+		require.NoError(t, err)
+
+		leaves = append(leaves, &trillian.LogLeaf{
+			LeafIndex:      int64(i),
+			LeafValue:      value,
+			MerkleLeafHash: []byte(fmt.Sprintf("hash-%d", i)),
+		})
+	}
+
+	root, err := (&types.LogRootV1{TreeSize: uint64(len(leaves))}).MarshalBinary()
+	require.NoError(t, err)
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+		&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			return &trillian.GetLeavesByRangeResponse{Leaves: leaves[req.StartIndex : req.StartIndex+req.Count]}, nil
+		},
+	).AnyTimes()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+		Key:    Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	getDiff := func(request GetDiffRequest) (*GetDiffResponse, error) {
+		request.Alias = alias
+
+		src, er := json.Marshal(request)
+		require.NoError(t, er)
+
+		var buf bytes.Buffer
+
+		if er = lookupHandler(t, cmd, GetDiff)(&buf, bytes.NewBuffer(src)); er != nil {
+			return nil, er
+		}
+
+		var resp *GetDiffResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	t.Run("Summary", func(t *testing.T) {
+		resp, er := getDiff(GetDiffRequest{SecondTreeSize: int64(len(leaves))})
+		require.NoError(t, er)
+		require.Equal(t, uint64(len(leaves)), resp.Added)
+		require.Equal(t, map[string]uint64{"unknown": 1, "vc": 502, "revocation": 502}, resp.EntryTypes)
+		require.Equal(t, map[string]uint64{"did:example:a": 251, "did:example:b": 251}, resp.Issuers)
+		require.Equal(t, map[string]uint64{"VerifiableCredential": 502, "UniversityDegreeCredential": 502},
+			resp.CredentialTypes)
+		require.Empty(t, resp.Entries)
+		require.False(t, resp.Truncated)
+	})
+
+	t.Run("Entries", func(t *testing.T) {
+		resp, er := getDiff(GetDiffRequest{FirstTreeSize: 3, SecondTreeSize: 5, Entries: true})
+		require.NoError(t, er)
+		require.Equal(t, uint64(2), resp.Added)
+		require.Equal(t, []DiffEntry{
+			{
+				LeafIndex:      3,
+				MerkleLeafHash: []byte("hash-3"),
+				EntryType:      VCLogEntryType,
+				Timestamp:      3,
+				CredentialID:   "urn:uuid:3",
+				Issuer:         "did:example:b",
+				Types:          []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			},
+			{LeafIndex: 4, MerkleLeafHash: []byte("hash-4"), EntryType: RevocationLogEntryType, Timestamp: 4},
+		}, resp.Entries)
+
+		resp, er = getDiff(GetDiffRequest{SecondTreeSize: int64(len(leaves)), Entries: true})
+		require.NoError(t, er)
+		require.Len(t, resp.Entries, MaxDiffEntries)
+		require.True(t, resp.Truncated)
+	})
+
+	t.Run("Nothing added", func(t *testing.T) {
+		resp, er := getDiff(GetDiffRequest{FirstTreeSize: 7, SecondTreeSize: 7, Entries: true})
+		require.NoError(t, er)
+		require.Zero(t, resp.Added)
+		require.Empty(t, resp.EntryTypes)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, er := getDiff(GetDiffRequest{FirstTreeSize: 5, SecondTreeSize: 3})
+		require.EqualError(t, er, "validate GetDiff request: validation failed: first_tree_size 5 and "+
+			"second_tree_size 3 values is not a valid range")
+
+		_, er = getDiff(GetDiffRequest{SecondTreeSize: MaxDiffRange + 1})
+		require.Contains(t, er.Error(), "exceeds 10000 entries")
+
+		_, er = getDiff(GetDiffRequest{SecondTreeSize: 2000})
+		require.EqualError(t, er, "bad request: need tree size: 2000 for diff but only got: 1005")
+		require.Equal(t, http.StatusBadRequest, errors.StatusCodeFromError(er))
+	})
+}
+
+func TestCmd_SimulatePolicy(t *testing.T) {
+	const keyType = kms.ECDSAP256TypeIEEEP1363
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// a leaf which is not an entry, a revocation, then credentials of several issuers, types and sizes
+	leaves := []*trillian.LogLeaf{{LeafIndex: 0, LeafValue: []byte("not an entry")}}
+
+	for i, entry := range []*TimestampedEntry{
+		{EntryType: RevocationLogEntryType, VCEntry: []byte(`{}`)},
+		{EntryType: VCLogEntryType, VCEntry: []byte(`{"id":"urn:uuid:2","issuer":"did:example:a",` +
+			`"type":["VerifiableCredential","UniversityDegreeCredential"]}`)},
+		{EntryType: VCLogEntryType, VCEntry: []byte(`{"id":"urn:uuid:3","issuer":{"id":"did:example:b"},` +
+			`"type":"VerifiableCredential"}`)},
+		{EntryType: VCLogEntryType, VCEntry: []byte(`{"id":"urn:uuid:4","issuer":"did:example:a",` +
+			`"type":["VerifiableCredential","PermanentResidentCard"],"credentialSubject":{"id":"did:example:c"}}`)},
+	} {
+		value, err := json.Marshal(MerkleTreeLeaf{TimestampedEntry: entry})
+		require.NoError(t, err)
+
+		leaves = append(leaves, &trillian.LogLeaf{LeafIndex: int64(i + 1), LeafValue: value})
+	}
+
+	root, err := (&types.LogRootV1{TreeSize: uint64(len(leaves))}).MarshalBinary()
+	require.NoError(t, err)
+
+	client := NewMockTrillianLogClient(ctrl)
+	client.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).Return(
+		&trillian.GetLatestSignedLogRootResponse{SignedLogRoot: &trillian.SignedLogRoot{LogRoot: root}}, nil,
+	).AnyTimes()
+	client.EXPECT().GetLeavesByRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ interface{}, req *trillian.GetLeavesByRangeRequest, _ ...interface{}) (*trillian.GetLeavesByRangeResponse, error) { // nolint: lll
+			return &trillian.GetLeavesByRangeResponse{Leaves: leaves[req.StartIndex : req.StartIndex+req.Count]}, nil
+		},
+	).AnyTimes()
+
+	km, cr := createKMSAndCrypto(t)
+	newKID, _, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	cmd, err := New(&Config{
+		KMS:    km,
+		Crypto: cr,
+		Logs:   []Log{{Alias: alias, Permission: "r", Client: client}},
+		Key:    Key{ID: newKID},
+	}, nil)
+	require.NoError(t, err)
+
+	simulatePolicy := func(request SimulatePolicyRequest) (*SimulatePolicyResponse, error) {
+		request.Alias = alias
+
+		src, er := json.Marshal(request)
+		require.NoError(t, er)
+
+		var buf bytes.Buffer
+
+		if er = lookupHandler(t, cmd, SimulatePolicy)(&buf, bytes.NewBuffer(src)); er != nil {
+			return nil, er
+		}
+
+		var resp *SimulatePolicyResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &resp))
+
+		return resp, nil
+	}
+
+	t.Run("Accepted", func(t *testing.T) {
+		resp, er := simulatePolicy(SimulatePolicyRequest{})
+		require.NoError(t, er)
+		require.Equal(t, int64(0), resp.StartIndex)
+		require.Equal(t, int64(len(leaves)), resp.TreeSize)
+		require.Equal(t, uint64(3), resp.Replayed)
+		require.Equal(t, uint64(2), resp.Skipped)
+		require.Zero(t, resp.Rejected)
+		require.Empty(t, resp.Rejections)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		resp, er := simulatePolicy(SimulatePolicyRequest{Policy: SimulatedPolicy{
+			AcceptedIssuers: []string{"did:example:a"},
+			AcceptedTypes:   []string{"UniversityDegreeCredential"},
+			MaxEntrySize:    130,
+		}})
+		require.NoError(t, er)
+		require.Equal(t, uint64(3), resp.Replayed)
+		require.Equal(t, uint64(2), resp.Rejected)
+		require.Equal(t, map[string]uint64{RejectedIssuer: 1, RejectedType: 2, RejectedSize: 1}, resp.Reasons)
+		require.Equal(t, map[string]uint64{"did:example:a": 1, "did:example:b": 1}, resp.Issuers)
+		require.Equal(t, []PolicyRejection{
+			{
+				LeafIndex:    3,
+				CredentialID: "urn:uuid:3",
+				Issuer:       "did:example:b",
+				Types:        []string{"VerifiableCredential"},
+				Size:         len(`{"id":"urn:uuid:3","issuer":{"id":"did:example:b"},"type":"VerifiableCredential"}`),
+				Reasons:      []string{RejectedIssuer, RejectedType},
+			},
+			{
+				LeafIndex:    4,
+				CredentialID: "urn:uuid:4",
+				Issuer:       "did:example:a",
+				Types:        []string{"VerifiableCredential", "PermanentResidentCard"},
+				Size: len(`{"id":"urn:uuid:4","issuer":"did:example:a","type":["VerifiableCredential",` +
+					`"PermanentResidentCard"],"credentialSubject":{"id":"did:example:c"}}`),
+				Reasons: []string{RejectedType, RejectedSize},
+			},
+		}, resp.Rejections)
+	})
+
+	t.Run("Latest entries", func(t *testing.T) {
+		resp, er := simulatePolicy(SimulatePolicyRequest{
+			Entries: 2,
+			Policy:  SimulatedPolicy{AcceptedIssuers: []string{"did:example:b"}},
+		})
+		require.NoError(t, er)
+		require.Equal(t, int64(3), resp.StartIndex)
+		require.Equal(t, uint64(2), resp.Replayed)
+		require.Equal(t, uint64(1), resp.Rejected)
+		require.Equal(t, int64(4), resp.Rejections[0].LeafIndex)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, er := simulatePolicy(SimulatePolicyRequest{Entries: MaxDiffRange + 1})
+		require.EqualError(t, er, "validate SimulatePolicy request: validation failed: entries 10001 is not in "+
+			"range [0,10000]")
+
+		_, er = simulatePolicy(SimulatePolicyRequest{Policy: SimulatedPolicy{MaxEntrySize: -1}})
+		require.EqualError(t, er, "validate SimulatePolicy request: validation failed: max_entry_size -1 is "+
+			"negative")
+	})
 }
@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// verifyCompromise verifies that the statement marks the key of the log compromised and is signed
+// by the recovery key.
+func (c *Cmd) verifyCompromise(signed *SignedCompromiseStatement) error {
+	if len(c.recoveryKey) == 0 {
+		return errors.NewBadRequestError(fmt.Errorf("no recovery key is registered"))
+	}
+
+	statement := signed.Statement
+
+	if statement.Version != V1 || statement.SignatureType != CompromiseSignatureType {
+		return errors.NewBadRequestError(fmt.Errorf("statement must be a v1 compromise statement"))
+	}
+
+	if !bytes.Equal(statement.LogID, c.VCLogID[:]) {
+		return errors.NewBadRequestError(fmt.Errorf("statement is issued for another log"))
+	}
+
+	if err := VerifySignature(signed.Signature, c.recoveryKey, statement); err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("statement signature: %w", err))
+	}
+
+	return nil
+}
+
+func (c *Cmd) getCompromise() *SignedCompromiseStatement {
+	c.compromiseMu.RLock()
+	defer c.compromiseMu.RUnlock()
+
+	return c.compromise
+}
+
+// checkCompromised returns ErrCompromised if the key of the log is marked compromised.
+func (c *Cmd) checkCompromised() error {
+	if c.getCompromise() != nil {
+		return fmt.Errorf("%w: the key of the log is compromised, writes are frozen", errors.ErrCompromised)
+	}
+
+	return nil
+}
+
+// MarkCompromised marks the key of the log compromised with a compromise statement signed by the
+// pre-registered recovery key. Writes of all logs are frozen for good and the statement is published in
+// the webfinger metadata, entries and proofs are still served for audits.
+func (c *Cmd) MarkCompromised(w io.Writer, r io.Reader) error {
+	var req *SignedCompromiseStatement
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode MarkCompromised request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty MarkCompromised request", errors.ErrBadRequest)
+	}
+
+	if err := c.verifyCompromise(req); err != nil {
+		return err
+	}
+
+	c.compromiseMu.Lock()
+	defer c.compromiseMu.Unlock()
+
+	if c.compromise == nil {
+		if c.onCompromise != nil {
+			if err := c.onCompromise(req); err != nil {
+				return fmt.Errorf("store compromise statement: %w", err)
+			}
+		}
+
+		c.compromise = req
+
+		logger.Errorf("the key of the log is marked compromised since %d: %s", req.Statement.CompromisedSince,
+			req.Statement.Reason)
+	}
+
+	return json.NewEncoder(w).Encode(c.compromise) // nolint: wrapcheck
+}
@@ -0,0 +1,189 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// credentialInfo keeps a logged credential along with its links in the chain of re-issued credentials.
+type credentialInfo struct {
+	ID             string
+	Issuer         string
+	LeafIndex      int64
+	MerkleLeafHash []byte
+	Timestamp      uint64
+	// Supersedes is the leaf hash of the credential this one re-issues.
+	Supersedes []byte
+	// SupersededBy is the leaf hash of the credential re-issuing this one.
+	SupersededBy []byte
+	// PolicyTags the credential was submitted with.
+	PolicyTags *PolicyTags
+}
+
+// addCredential indexes the credential and links it to the credential it supersedes.
+// Only the first credential superseding a leaf is linked, the rest are treated as chain roots.
+func (i *credentialIndex) addCredential(info *credentialInfo) {
+	if info.Supersedes != nil {
+		if prev, ok := i.credentials[string(info.Supersedes)]; ok && prev.SupersededBy == nil {
+			prev.SupersededBy = info.MerkleLeafHash
+		} else {
+			info.Supersedes = nil
+		}
+	}
+
+	i.credentials[string(info.MerkleLeafHash)] = info
+
+	if info.PolicyTags != nil {
+		i.tagged = append(i.tagged, info)
+	}
+}
+
+// issuerID returns the ID of the issuer which is either a string or an object with an ID.
+func issuerID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+
+	var issuer struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(raw, &issuer); err != nil {
+		return ""
+	}
+
+	return issuer.ID
+}
+
+// entryExtensions returns the extensions of the entry, none if they are not valid.
+func entryExtensions(extensions []byte) EntryExtensions {
+	var ext EntryExtensions
+	if len(extensions) == 0 || json.Unmarshal(extensions, &ext) != nil {
+		return EntryExtensions{}
+	}
+
+	return ext
+}
+
+// linkLeaf validates that the leaf may supersede the given logged credential and records the link
+// in the extensions of the leaf. A credential may only be superseded once and by the same issuer.
+func (c *Cmd) linkLeaf(alias string, leaf *MerkleTreeLeaf, issuer string, leafHash []byte) error {
+	index, err := c.lockIndex(alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	hash := base64.StdEncoding.EncodeToString(leafHash)
+
+	prev, ok := index.credentials[string(leafHash)]
+	if !ok {
+		return errors.NewNotFoundError(fmt.Errorf("credential with leaf hash %s is not logged", hash))
+	}
+
+	if prev.Issuer != issuer {
+		return errors.NewBadRequestError(fmt.Errorf("credential with leaf hash %s has another issuer", hash))
+	}
+
+	if prev.SupersededBy != nil {
+		return errors.NewBadRequestError(fmt.Errorf("credential with leaf hash %s is already superseded by %s",
+			hash, base64.StdEncoding.EncodeToString(prev.SupersededBy)))
+	}
+
+	return setExtensions(leaf, func(ext *EntryExtensions) { ext.Supersedes = leafHash })
+}
+
+// setExtensions updates the extensions of the leaf.
+func setExtensions(leaf *MerkleTreeLeaf, update func(ext *EntryExtensions)) error {
+	var ext EntryExtensions
+
+	if len(leaf.TimestampedEntry.Extensions) > 0 {
+		if err := json.Unmarshal(leaf.TimestampedEntry.Extensions, &ext); err != nil {
+			return fmt.Errorf("unmarshal extensions: %w", err)
+		}
+	}
+
+	update(&ext)
+
+	extensions, err := json.Marshal(ext)
+	if err != nil {
+		return fmt.Errorf("marshal extensions: %w", err)
+	}
+
+	leaf.TimestampedEntry.Extensions = extensions
+
+	return nil
+}
+
+// GetCredentialHistory retrieves the chain of re-issued credentials the leaf hash belongs to, oldest first.
+func (c *Cmd) GetCredentialHistory(w io.Writer, r io.Reader) error {
+	var request *GetCredentialHistoryRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetCredentialHistory request: %w", err)
+	}
+
+	if request == nil {
+		return fmt.Errorf("%w: empty GetCredentialHistory request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	index, err := c.lockIndex(request.Alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	info, ok := index.credentials[string(request.LeafHash)]
+	if !ok {
+		return errors.NewNotFoundError(fmt.Errorf("credential with leaf hash %s is not logged",
+			base64.StdEncoding.EncodeToString(request.LeafHash)))
+	}
+
+	// links are only made to the credentials indexed before, so the chain has no cycles
+	for info.Supersedes != nil {
+		info = index.credentials[string(info.Supersedes)]
+	}
+
+	var entries []CredentialHistoryEntry
+
+	for {
+		revocations := index.revocations[string(info.MerkleLeafHash)]
+		if revocations == nil {
+			revocations = []RevocationRecord{}
+		}
+
+		entries = append(entries, CredentialHistoryEntry{
+			LeafIndex:      info.LeafIndex,
+			MerkleLeafHash: info.MerkleLeafHash,
+			Timestamp:      info.Timestamp,
+			CredentialID:   info.ID,
+			Supersedes:     info.Supersedes,
+			Revocations:    revocations,
+		})
+
+		if info.SupersededBy == nil {
+			break
+		}
+
+		info = index.credentials[string(info.SupersededBy)]
+	}
+
+	return json.NewEncoder(w).Encode(GetCredentialHistoryResponse{Entries: entries}) // nolint: wrapcheck
+}
@@ -0,0 +1,513 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+	"github.com/trustbloc/vct/pkg/smt"
+)
+
+// credentialIndex is a sparse Merkle map of the credential IDs of the issuers (CredentialKey) to the latest log
+// entry of the credential (CredentialIndexEntry), either the credential itself or a revocation event. The IDs
+// are scoped by the issuer, so an issuer can not shadow the credentials of another issuer.
+// It is built from the log in the background (see BuildCredentialIndexes) or on demand, size is the number of log
+// leaves indexed so far.
+type credentialIndex struct {
+	mu          sync.Mutex
+	building    bool // the index is built in the background, the requests do not scan the log meanwhile
+	tree        *smt.Tree
+	latest      map[credentialRef]CredentialIndexEntry // issuer and credential ID -> latest entry
+	credentials map[string]*credentialInfo             // credential leaf hash -> credential
+	revocations map[string][]RevocationRecord          // credential leaf hash -> revocation events
+	anchors     map[string][]AnchorRecord              // anchored log ID -> anchored tree heads
+	tagged      []*credentialInfo                      // credentials with policy tags, in the order they were logged
+	entries     map[string]int64                       // SHA256 of the entry -> index of its first leaf (see EntryCID)
+	queued      map[string]queuedRevocation            // credential leaf hash -> last queued revocation event
+	size        int64
+}
+
+// credentialRef identifies a credential in the credential index.
+type credentialRef struct {
+	issuer string
+	id     string
+}
+
+// queuedRevocation is a revocation event queued to the log and not indexed yet, the later events of the
+// credential are checked against it (a queued event which is never sequenced is kept until the restart).
+type queuedRevocation struct {
+	status RevocationStatus
+	digest [sha256.Size]byte // SHA256 of the serialized event
+}
+
+func newCredentialIndexes(logs map[string]Log) map[string]*credentialIndex {
+	indexes := make(map[string]*credentialIndex, len(logs))
+
+	for alias := range logs {
+		indexes[alias] = &credentialIndex{
+			tree:        smt.New(),
+			latest:      map[credentialRef]CredentialIndexEntry{},
+			credentials: map[string]*credentialInfo{},
+			revocations: map[string][]RevocationRecord{},
+			anchors:     map[string][]AnchorRecord{},
+			entries:     map[string]int64{},
+			queued:      map[string]queuedRevocation{},
+		}
+	}
+
+	return indexes
+}
+
+func (i *credentialIndex) put(issuer, credentialID string, entry CredentialIndexEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal entry: %w", err)
+	}
+
+	if err = i.tree.Put(CredentialKey(issuer, credentialID), value); err != nil {
+		return fmt.Errorf("put: %w", err)
+	}
+
+	i.latest[credentialRef{issuer: issuer, id: credentialID}] = entry
+
+	return nil
+}
+
+// CredentialKey returns the key of the credential ID of the issuer in the credential index: the SHA256 hash of
+// the JSON array of the issuer and the credential ID.
+func CredentialKey(issuer, credentialID string) []byte {
+	src, _ := json.Marshal([]string{issuer, credentialID}) // nolint: errcheck
+
+	key := sha256.Sum256(src)
+
+	return key[:]
+}
+
+// GetProofOfAbsence returns a signed statement that no entry with the credential ID of the issuer was logged
+// as of the tree size, along with the non-inclusion proof in the credential index.
+func (c *Cmd) GetProofOfAbsence(w io.Writer, r io.Reader) error {
+	var request *GetProofOfAbsenceRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetProofOfAbsence request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetProofOfAbsence request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	index, err := c.lockIndex(request.Alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	if _, ok := index.latest[credentialRef{issuer: request.Issuer, id: request.CredentialID}]; ok {
+		return errors.NewBadRequestError(fmt.Errorf("credential %q of %s is logged", request.CredentialID,
+			request.Issuer))
+	}
+
+	proof, err := index.tree.Prove(CredentialKey(request.Issuer, request.CredentialID))
+	if err != nil {
+		return fmt.Errorf("prove: %w", err)
+	}
+
+	statement := NonInclusionSignature{
+		Version:           V1,
+		SignatureType:     NonInclusionSignatureType,
+		Timestamp:         uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		TreeSize:          uint64(index.size),
+		Issuer:            request.Issuer,
+		CredentialID:      request.CredentialID,
+		SHA256MapRootHash: index.tree.Root(),
+	}
+
+	signature, err := c.sign(statement)
+	if err != nil {
+		return fmt.Errorf("sign NonInclusionSignature: %w", err)
+	}
+
+	return json.NewEncoder(w).Encode(GetProofOfAbsenceResponse{ // nolint: wrapcheck
+		TreeSize:          statement.TreeSize,
+		Timestamp:         statement.Timestamp,
+		Issuer:            statement.Issuer,
+		CredentialID:      statement.CredentialID,
+		SHA256MapRootHash: statement.SHA256MapRootHash,
+		AuditPath:         proof,
+		Signature:         signature,
+	})
+}
+
+// GetMapRoot retrieves the signed root of the credential index.
+func (c *Cmd) GetMapRoot(w io.Writer, r io.Reader) error {
+	var alias string
+
+	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+		return fmt.Errorf("decode GetMapRoot request: %w", err)
+	}
+
+	if err := c.hasPermissions(alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	index, err := c.lockIndex(alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	root, err := c.signMapRoot(index)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(root) // nolint: wrapcheck
+}
+
+// GetCredentialStatus retrieves the latest log entry of the credential along with the inclusion proof
+// in the signed credential index.
+func (c *Cmd) GetCredentialStatus(w io.Writer, r io.Reader) error {
+	var request *GetCredentialStatusRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetCredentialStatus request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetCredentialStatus request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	index, err := c.lockIndex(request.Alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	entry, ok := index.latest[credentialRef{issuer: request.Issuer, id: request.CredentialID}]
+	if !ok {
+		return errors.NewNotFoundError(fmt.Errorf("credential %q of %s is not logged", request.CredentialID,
+			request.Issuer))
+	}
+
+	proof, err := index.tree.Prove(CredentialKey(request.Issuer, request.CredentialID))
+	if err != nil {
+		return fmt.Errorf("prove: %w", err)
+	}
+
+	root, err := c.signMapRoot(index)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(GetCredentialStatusResponse{ // nolint: wrapcheck
+		Issuer:       request.Issuer,
+		CredentialID: request.CredentialID,
+		Entry:        entry,
+		AuditPath:    proof,
+		MapRoot:      *root,
+	})
+}
+
+func (c *Cmd) signMapRoot(index *credentialIndex) (*GetMapRootResponse, error) {
+	statement := MapRootSignature{
+		Version:           V1,
+		SignatureType:     MapRootSignatureType,
+		Timestamp:         uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		TreeSize:          uint64(index.size),
+		SHA256MapRootHash: index.tree.Root(),
+	}
+
+	signature, err := c.sign(statement)
+	if err != nil {
+		return nil, fmt.Errorf("sign MapRootSignature: %w", err)
+	}
+
+	return &GetMapRootResponse{
+		TreeSize:          statement.TreeSize,
+		Timestamp:         statement.Timestamp,
+		SHA256MapRootHash: statement.SHA256MapRootHash,
+		MapRootSignature:  signature,
+	}, nil
+}
+
+// credentialIndexInterval is the interval the credential indexes are updated at in the background.
+const credentialIndexInterval = 10 * time.Second
+
+// BuildCredentialIndexes builds the credential indexes of the logs in the background until the context is done:
+// the leaves of the logs are indexed at start and then every interval, so the requests do not scan the logs.
+// The requests using the index of a log are rejected with 503 until it is built.
+func (c *Cmd) BuildCredentialIndexes(ctx context.Context) {
+	for _, index := range c.credentialIndexes {
+		index.mu.Lock()
+		index.building = true
+		index.mu.Unlock()
+	}
+
+	ticker := time.NewTicker(credentialIndexInterval)
+	defer ticker.Stop()
+
+	for {
+		for alias, index := range c.credentialIndexes {
+			err := c.catchUpIndex(alias, index)
+			if err != nil {
+				logger.Warnf("index credentials of log %s: %v", alias, err)
+			}
+
+			// the requests scan the log themselves if the index failed to be built
+			index.mu.Lock()
+			index.building = false
+			index.mu.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lockIndex returns the locked credential index of the log once it indexed the leaves of the log.
+func (c *Cmd) lockIndex(alias string) (*credentialIndex, error) {
+	index := c.credentialIndexes[alias]
+
+	if err := c.indexCredentials(alias, index); err != nil {
+		return nil, err
+	}
+
+	index.mu.Lock()
+
+	return index, nil
+}
+
+// indexCredentials adds leaves appended to the log since the last call to the index, it is rejected with 503
+// while the index is built in the background.
+func (c *Cmd) indexCredentials(alias string, index *credentialIndex) error {
+	index.mu.Lock()
+	building, size := index.building, index.size
+	index.mu.Unlock()
+
+	if building {
+		return errors.NewServiceUnavailableError(fmt.Errorf("credential index of log %s is being built, %d leaves "+
+			"indexed", alias, size))
+	}
+
+	return c.catchUpIndex(alias, index)
+}
+
+// catchUpIndex adds leaves appended to the log to the index. The leaves are fetched in batches with the index
+// unlocked, so a long scan of the log does not block the other users of the index.
+func (c *Cmd) catchUpIndex(alias string, index *credentialIndex) error {
+	treeSize, err := c.treeSize(alias)
+	if err != nil {
+		return err
+	}
+
+	for {
+		index.mu.Lock()
+		start := index.size
+		index.mu.Unlock()
+
+		if start >= treeSize {
+			return nil
+		}
+
+		var leaves []*trillian.LogLeaf
+
+		leaves, err = c.leavesToIndex(alias, start, treeSize)
+		if err != nil {
+			return err
+		}
+
+		index.mu.Lock()
+
+		// the batch may have been indexed by another caller meanwhile
+		if index.size == start {
+			err = index.addLeaves(leaves)
+		}
+
+		index.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// leavesToIndex returns a batch of the leaves of the log starting from start, up to the tree size.
+func (c *Cmd) leavesToIndex(alias string, start, treeSize int64) ([]*trillian.LogLeaf, error) {
+	const maxRange = 1000
+
+	req := trillian.GetLeavesByRangeRequest{
+		LogId:      c.logs[alias].ID,
+		StartIndex: start,
+		Count:      treeSize - start,
+	}
+
+	if req.Count > maxRange {
+		req.Count = maxRange
+	}
+
+	var resp *trillian.GetLeavesByRangeResponse
+
+	err := c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLeavesByRange(context.Background(), &req)
+
+		return resp, er
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get leaves by range: %w", err)
+	}
+
+	if len(resp.GetLeaves()) == 0 {
+		return nil, fmt.Errorf("%w: no leaves starting from %d", errors.ErrInternal, start)
+	}
+
+	return resp.GetLeaves(), nil
+}
+
+// addLeaves indexes the leaves following the indexed leaves.
+func (i *credentialIndex) addLeaves(leaves []*trillian.LogLeaf) error {
+	for _, leaf := range leaves {
+		if leaf.GetLeafIndex() != i.size {
+			return fmt.Errorf("%w: unexpected leaf index %d, expected %d",
+				errors.ErrInternal, leaf.GetLeafIndex(), i.size)
+		}
+
+		if err := i.add(leaf); err != nil {
+			return fmt.Errorf("index leaf %d: %w", leaf.GetLeafIndex(), err)
+		}
+
+		i.size++
+	}
+
+	return nil
+}
+
+// queue records the revocation event queued to the log until it is indexed.
+func (i *credentialIndex) queue(event *RevocationEvent, entry []byte) {
+	i.queued[string(event.LeafHash)] = queuedRevocation{status: event.Status, digest: sha256.Sum256(entry)}
+}
+
+// status returns the status of the credential: the status of its last queued or logged revocation event.
+func (i *credentialIndex) status(leafHash []byte) RevocationStatus {
+	if queued, ok := i.queued[string(leafHash)]; ok {
+		return queued.status
+	}
+
+	if events := i.revocations[string(leafHash)]; len(events) > 0 {
+		return events[len(events)-1].Event.Status
+	}
+
+	return ""
+}
+
+// add indexes the leaf, leaves which are not VCT entries are skipped.
+func (i *credentialIndex) add(leaf *trillian.LogLeaf) error {
+	var entry MerkleTreeLeaf
+	if err := json.Unmarshal(leaf.GetLeafValue(), &entry); err != nil || entry.TimestampedEntry == nil {
+		return nil
+	}
+
+	digest := sha256.Sum256(entry.TimestampedEntry.VCEntry)
+	if _, ok := i.entries[string(digest[:])]; !ok {
+		i.entries[string(digest[:])] = leaf.GetLeafIndex()
+	}
+
+	indexEntry := CredentialIndexEntry{
+		LeafIndex:      leaf.GetLeafIndex(),
+		MerkleLeafHash: leaf.GetMerkleLeafHash(),
+	}
+
+	switch entry.TimestampedEntry.EntryType {
+	case VCLogEntryType:
+		var vc struct {
+			ID     string          `json:"id"`
+			Issuer json.RawMessage `json:"issuer"`
+		}
+
+		if err := json.Unmarshal(entry.TimestampedEntry.VCEntry, &vc); err != nil {
+			return nil
+		}
+
+		ext := entryExtensions(entry.TimestampedEntry.Extensions)
+		issuer := issuerID(vc.Issuer)
+
+		i.addCredential(&credentialInfo{
+			ID:             vc.ID,
+			Issuer:         issuer,
+			LeafIndex:      leaf.GetLeafIndex(),
+			MerkleLeafHash: leaf.GetMerkleLeafHash(),
+			Timestamp:      entry.TimestampedEntry.Timestamp,
+			Supersedes:     ext.Supersedes,
+			PolicyTags:     ext.PolicyTags,
+		})
+
+		if vc.ID == "" {
+			return nil
+		}
+
+		return i.put(issuer, vc.ID, indexEntry)
+	case RevocationLogEntryType:
+		var event RevocationEvent
+		if err := json.Unmarshal(entry.TimestampedEntry.VCEntry, &event); err != nil {
+			return nil
+		}
+
+		if queued, ok := i.queued[string(event.LeafHash)]; ok && queued.digest == digest {
+			delete(i.queued, string(event.LeafHash))
+		}
+
+		i.revocations[string(event.LeafHash)] = append(i.revocations[string(event.LeafHash)], RevocationRecord{
+			LeafIndex:      leaf.GetLeafIndex(),
+			MerkleLeafHash: leaf.GetMerkleLeafHash(),
+			Timestamp:      entry.TimestampedEntry.Timestamp,
+			Event:          event,
+		})
+
+		// the revocation event becomes the latest entry of the credential
+		if info, ok := i.credentials[string(event.LeafHash)]; ok && info.ID != "" {
+			return i.put(info.Issuer, info.ID, indexEntry)
+		}
+	case STHAnchorLogEntryType:
+		var anchor STHAnchor
+		if err := json.Unmarshal(entry.TimestampedEntry.VCEntry, &anchor); err != nil {
+			return nil
+		}
+
+		i.anchors[string(anchor.LogID)] = append(i.anchors[string(anchor.LogID)], AnchorRecord{
+			LeafIndex:      leaf.GetLeafIndex(),
+			MerkleLeafHash: leaf.GetMerkleLeafHash(),
+			Timestamp:      entry.TimestampedEntry.Timestamp,
+			Anchor:         anchor,
+		})
+	}
+
+	return nil
+}
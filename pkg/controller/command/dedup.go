@@ -0,0 +1,131 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/hex"
+	goerrors "errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/bloom"
+)
+
+const (
+	// DedupTagName is the tag of the leaves in the dedup store, the store must index it.
+	DedupTagName = "dedup"
+	// DefaultDedupFilterCapacity is the number of leaves the filter of the dedup store is sized for by default.
+	DefaultDedupFilterCapacity = 1000000
+	// dedupFalsePositiveRate is the rate of the submissions of new entries which read the dedup store.
+	dedupFalsePositiveRate = 0.01
+	dedupLoadPageSize      = 1000
+)
+
+// DedupStore persists the leaves logged to the logs by their identity (e.g. a store of the storage provider).
+type DedupStore interface {
+	Put(key string, value []byte, tags ...storage.Tag) error
+	Get(key string) ([]byte, error)
+	Query(expression string, options ...storage.QueryOption) (storage.Iterator, error)
+}
+
+// dedup answers the submissions of logged entries from the dedup store without queueing their leaves. A filter
+// of the stored leaves kept in memory tells the submissions of new entries (the common case) apart without a
+// read of the store. A leaf which is not in the filter (e.g. logged by another instance) is queued, the log
+// deduplicates it.
+type dedup struct {
+	store  DedupStore
+	filter *bloom.Filter
+}
+
+// newDedup loads the filter of the leaves of the store, nil if there is no dedup store.
+func newDedup(store DedupStore, capacity uint64) (*dedup, error) {
+	if store == nil {
+		return nil, nil
+	}
+
+	if capacity == 0 {
+		capacity = DefaultDedupFilterCapacity
+	}
+
+	d := &dedup{store: store, filter: bloom.New(capacity, dedupFalsePositiveRate)}
+
+	iter, err := store.Query(DedupTagName, storage.WithPageSize(dedupLoadPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("query leaves: %w", err)
+	}
+
+	defer iter.Close() // nolint: errcheck
+
+	var count uint64
+
+	for {
+		ok, er := iter.Next()
+		if er != nil {
+			return nil, fmt.Errorf("next leaf: %w", er)
+		}
+
+		if !ok {
+			break
+		}
+
+		key, er := iter.Key()
+		if er != nil {
+			return nil, fmt.Errorf("leaf key: %w", er)
+		}
+
+		d.filter.Add([]byte(key))
+		count++
+	}
+
+	if count > capacity {
+		logger.Warnf("the dedup store holds %d leaves, more than the capacity of its filter (%d)", count, capacity)
+	}
+
+	return d, nil
+}
+
+// dedupKey returns the key of the leaf by its identity, the identities are scoped by the log.
+func dedupKey(alias string, leafIDHash []byte) string {
+	return alias + "/" + hex.EncodeToString(leafIDHash)
+}
+
+// loggedLeaf returns the value of the logged leaf of the identity, nil if the leaf is not stored.
+func (d *dedup) loggedLeaf(alias string, leafIDHash []byte) ([]byte, error) {
+	key := dedupKey(alias, leafIDHash)
+
+	if !d.filter.MayContain([]byte(key)) {
+		return nil, nil
+	}
+
+	value, err := d.store.Get(key)
+	if goerrors.Is(err, storage.ErrDataNotFound) {
+		dedupFalsePositives.Inc(alias)
+
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get logged leaf: %w", err)
+	}
+
+	return value, nil
+}
+
+// storeLeaf persists the logged leaf of the identity. The leaf is logged, a failure is logged only: the next
+// submission of the entry is deduplicated by the log.
+func (d *dedup) storeLeaf(alias string, leafIDHash, value []byte) {
+	key := dedupKey(alias, leafIDHash)
+
+	if err := d.store.Put(key, value, storage.Tag{Name: DedupTagName}); err != nil {
+		logger.Errorf("store logged leaf of log %s: %v", alias, err)
+
+		return
+	}
+
+	d.filter.Add([]byte(key))
+}
@@ -0,0 +1,197 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/trillian"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// MaxDiffRange is the max number of entries summarized by a diff or replayed by a policy simulation, each entry
+	// of the range is fetched from Trillian and parsed by the request.
+	MaxDiffRange = 10000
+	// MaxDiffEntries is the max number of entries listed by a diff.
+	MaxDiffEntries = 1000
+
+	diffPageSize     = 1000
+	unknownEntryType = "unknown"
+)
+
+// GetDiff summarizes the entries added while the tree grew from the first to the second size (GetDiffResponse):
+// the number of entries by leaf type, of credentials by issuer and by type, and lists the entries if requested.
+func (c *Cmd) GetDiff(w io.Writer, r io.Reader) error {
+	var request *GetDiffRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetDiff request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetDiff request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	treeSize, err := c.treeSize(request.Alias)
+	if err != nil {
+		return err
+	}
+
+	if treeSize < request.SecondTreeSize {
+		return fmt.Errorf("%w: need tree size: %d for diff but only got: %d",
+			errors.ErrBadRequest, request.SecondTreeSize, treeSize,
+		)
+	}
+
+	response := GetDiffResponse{
+		FirstTreeSize:   request.FirstTreeSize,
+		SecondTreeSize:  request.SecondTreeSize,
+		EntryTypes:      map[string]uint64{},
+		Issuers:         map[string]uint64{},
+		CredentialTypes: map[string]uint64{},
+	}
+
+	for start := request.FirstTreeSize; start < request.SecondTreeSize; start += diffPageSize {
+		count := request.SecondTreeSize - start
+		if count > diffPageSize {
+			count = diffPageSize
+		}
+
+		leaves, er := c.diffLeaves(request.Alias, start, count)
+		if er != nil {
+			return er
+		}
+
+		for _, leaf := range leaves {
+			c.addDiffEntry(&response, leaf, request.Entries)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+}
+
+// diffLeaves reads the count leaves of the log from the start.
+func (c *Cmd) diffLeaves(alias string, start, count int64) ([]*trillian.LogLeaf, error) {
+	req := trillian.GetLeavesByRangeRequest{LogId: c.logs[alias].ID, StartIndex: start, Count: count}
+
+	var resp *trillian.GetLeavesByRangeResponse
+
+	err := c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLeavesByRange(context.Background(), &req)
+
+		return resp, er
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get leaves by range: %w", err)
+	}
+
+	if int64(len(resp.GetLeaves())) != count {
+		return nil, fmt.Errorf("%w: got %d leaves in range [%d,%d)",
+			errors.ErrInternal, len(resp.GetLeaves()), start, start+count,
+		)
+	}
+
+	for i, leaf := range resp.GetLeaves() {
+		if leaf.GetLeafIndex() != start+int64(i) {
+			return nil, fmt.Errorf("%w: unexpected leaf index %d, expected %d",
+				errors.ErrInternal, leaf.GetLeafIndex(), start+int64(i))
+		}
+	}
+
+	return resp.GetLeaves(), nil
+}
+
+// addDiffEntry counts the leaf in the summary of the diff, and lists it if requested.
+func (c *Cmd) addDiffEntry(response *GetDiffResponse, leaf *trillian.LogLeaf, list bool) {
+	response.Added++
+
+	entry := DiffEntry{LeafIndex: leaf.GetLeafIndex(), MerkleLeafHash: leaf.GetMerkleLeafHash()}
+
+	var merkleLeaf MerkleTreeLeaf
+	if err := json.Unmarshal(leaf.GetLeafValue(), &merkleLeaf); err != nil || merkleLeaf.TimestampedEntry == nil {
+		response.EntryTypes[unknownEntryType]++
+	} else {
+		entry.EntryType = merkleLeaf.TimestampedEntry.EntryType
+		entry.Timestamp = merkleLeaf.TimestampedEntry.Timestamp
+
+		name := strconv.FormatUint(uint64(entry.EntryType), 10)
+		if t, ok := c.leafTypes.get(entry.EntryType); ok {
+			name = t.Name
+		}
+
+		response.EntryTypes[name]++
+
+		if entry.EntryType == VCLogEntryType {
+			countCredential(response, &entry, merkleLeaf.TimestampedEntry.VCEntry)
+		}
+	}
+
+	if !list {
+		return
+	}
+
+	if len(response.Entries) == MaxDiffEntries {
+		response.Truncated = true
+
+		return
+	}
+
+	response.Entries = append(response.Entries, entry)
+}
+
+// countCredential counts the credential by issuer and by type, credentials which are not JSON are not counted.
+func countCredential(response *GetDiffResponse, entry *DiffEntry, credential []byte) {
+	if !describeCredential(entry, credential) {
+		return
+	}
+
+	if entry.Issuer != "" {
+		response.Issuers[entry.Issuer]++
+	}
+
+	for _, t := range entry.Types {
+		response.CredentialTypes[t]++
+	}
+}
+
+// describeCredential sets the ID, the issuer and the types of the credential of the entry, false if the credential
+// is not JSON.
+func describeCredential(entry *DiffEntry, credential []byte) bool {
+	var vc struct {
+		ID     string          `json:"id"`
+		Issuer json.RawMessage `json:"issuer"`
+		Type   json.RawMessage `json:"type"`
+	}
+
+	if err := json.Unmarshal(credential, &vc); err != nil {
+		return false
+	}
+
+	entry.CredentialID = vc.ID
+	entry.Issuer = issuerID(vc.Issuer)
+
+	if err := json.Unmarshal(vc.Type, &entry.Types); err != nil {
+		var t string
+		if json.Unmarshal(vc.Type, &t) == nil && t != "" {
+			entry.Types = []string{t}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,168 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// Encrypter encrypts and decrypts with the keys of the KMS (AEAD), it is implemented by the crypto of the KMS.
+type Encrypter interface {
+	Encrypt(msg, aad []byte, kh interface{}) ([]byte, []byte, error)
+	Decrypt(cipher, aad, nonce []byte, kh interface{}) ([]byte, error)
+}
+
+// EncryptedExtraData is the extra data of a leaf encrypted at rest with the envelope key of the KMS.
+// The extra data of a credential (its proofs) is a JSON array, an object is encrypted extra data.
+type EncryptedExtraData struct {
+	KeyID      string `json:"kid"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// extraDataEncryption encrypts the extra data of leaves before they are queued and decrypts it on reads.
+// The extra data is bound to the leaf (its hash is the associated data), it can't be moved to another leaf.
+type extraDataEncryption struct {
+	kms       KeyManager
+	encrypter Encrypter
+
+	mu    sync.Mutex
+	keyID string                 // the active key, see RotateExtraDataKey
+	khs   map[string]interface{} // key ID -> key handle
+}
+
+func newExtraDataEncryption(keyID string, km KeyManager, cr Crypto) (*extraDataEncryption, error) {
+	encrypter, ok := cr.(Encrypter)
+	if !ok {
+		return nil, fmt.Errorf("crypto does not support encryption")
+	}
+
+	enc := &extraDataEncryption{keyID: keyID, kms: km, encrypter: encrypter, khs: map[string]interface{}{}}
+
+	if _, err := enc.keyHandle(keyID); err != nil {
+		return nil, err
+	}
+
+	return enc, nil
+}
+
+func (e *extraDataEncryption) keyHandle(keyID string) (interface{}, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if kh, ok := e.khs[keyID]; ok {
+		return kh, nil
+	}
+
+	kh, err := e.kms.Get(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get envelope key %s: %w", keyID, err)
+	}
+
+	e.khs[keyID] = kh
+
+	return kh, nil
+}
+
+func (e *extraDataEncryption) activeKeyID() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.keyID
+}
+
+func (e *extraDataEncryption) activate(keyID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.keyID = keyID
+}
+
+func (e *extraDataEncryption) encrypt(extraData, leafValue []byte) ([]byte, error) {
+	return e.encryptWith(e.activeKeyID(), extraData, leafValue)
+}
+
+func (e *extraDataEncryption) encryptWith(keyID string, extraData, leafValue []byte) ([]byte, error) {
+	kh, err := e.keyHandle(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	aad := sha256.Sum256(leafValue)
+
+	ciphertext, nonce, err := e.encrypter.Encrypt(extraData, aad[:], kh)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: %w", err)
+	}
+
+	return json.Marshal(EncryptedExtraData{KeyID: keyID, Nonce: nonce, Ciphertext: ciphertext}) // nolint: wrapcheck
+}
+
+// isEncrypted tells whether the extra data is encrypted, the extra data stored before the encryption was enabled
+// is not.
+func isEncrypted(extraData []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(extraData), []byte("{"))
+}
+
+func (e *extraDataEncryption) decrypt(extraData, leafValue []byte) ([]byte, error) {
+	if !isEncrypted(extraData) {
+		return extraData, nil
+	}
+
+	var encrypted *EncryptedExtraData
+	if err := json.Unmarshal(extraData, &encrypted); err != nil {
+		return nil, fmt.Errorf("unmarshal encrypted extra data: %w", err)
+	}
+
+	kh, err := e.keyHandle(encrypted.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	aad := sha256.Sum256(leafValue)
+
+	plaintext, err := e.encrypter.Decrypt(encrypted.Ciphertext, aad[:], encrypted.Nonce, kh)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// encryptExtraData returns the extra data of the leaf to store, it is encrypted if the encryption is enabled.
+func (c *Cmd) encryptExtraData(extraData, leafValue []byte) ([]byte, error) {
+	if c.extraData == nil {
+		return extraData, nil
+	}
+
+	encrypted, err := c.extraData.encrypt(extraData, leafValue)
+	if err != nil {
+		return nil, errors.NewStatusInternalServerError(fmt.Errorf("encrypt extra data: %w", err))
+	}
+
+	return encrypted, nil
+}
+
+// decryptExtraData returns the extra data of the leaf as submitted.
+func (c *Cmd) decryptExtraData(extraData, leafValue []byte) ([]byte, error) {
+	if c.extraData == nil {
+		return extraData, nil
+	}
+
+	decrypted, err := c.extraData.decrypt(extraData, leafValue)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decrypt extra data: %v", errors.ErrInternal, err)
+	}
+
+	return decrypted, nil
+}
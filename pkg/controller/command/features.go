@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// FeatureFlagsConfig disables operations of the REST API without code changes, e.g. get-entries to stop serving
+// the payloads of the entries. The operations are named after their paths (see rest.OperationName), e.g. get-entries
+// for /{alias}/v1/get-entries and admin/submission-stats for /admin/submission-stats.
+type FeatureFlagsConfig struct {
+	// Disabled are the operations disabled for the deployment, they are not found (404).
+	Disabled []string
+	// DisabledPerTenant are the operations disabled for the logs of a tenant (tenant -> operations), they are
+	// forbidden (403). A log without a tenant is its own tenant.
+	DisabledPerTenant map[string][]string
+}
+
+type featureFlags struct {
+	disabled          map[string]struct{}
+	disabledPerTenant map[string]map[string]struct{} // tenant -> operations
+}
+
+func newFeatureFlags(cfg *FeatureFlagsConfig) *featureFlags {
+	f := &featureFlags{disabled: map[string]struct{}{}, disabledPerTenant: map[string]map[string]struct{}{}}
+
+	if cfg == nil {
+		return f
+	}
+
+	for _, operation := range cfg.Disabled {
+		f.disabled[operation] = struct{}{}
+	}
+
+	for tenant, operations := range cfg.DisabledPerTenant {
+		f.disabledPerTenant[tenant] = map[string]struct{}{}
+
+		for _, operation := range operations {
+			f.disabledPerTenant[tenant][operation] = struct{}{}
+		}
+	}
+
+	return f
+}
+
+// CheckOperation returns an error if the operation is disabled for the deployment (errors.ErrDisabled, 404) or for
+// the tenant of the log (403), the alias is empty for the operations which are not of a log.
+func (c *Cmd) CheckOperation(alias, operation string) error {
+	if _, ok := c.features.disabled[operation]; ok {
+		return fmt.Errorf("operation %s is %w", operation, errors.ErrDisabled)
+	}
+
+	log, ok := c.logs[alias]
+	if !ok {
+		return nil
+	}
+
+	if _, ok = c.features.disabledPerTenant[log.tenant()][operation]; ok {
+		return errors.NewForbiddenError(fmt.Errorf("operation %s is %w for tenant %s", operation,
+			errors.ErrDisabled, log.tenant()))
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/trillian"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// verifyFinalTreeHead verifies that the final tree head freezes the log and is signed by the key of the log.
+func (c *Cmd) verifyFinalTreeHead(alias string, signed *SignedFinalTreeHead) error {
+	statement := signed.Statement
+
+	if statement.Version != V1 || statement.SignatureType != FreezeSignatureType {
+		return fmt.Errorf("statement must be a v1 final tree head")
+	}
+
+	if !bytes.Equal(statement.LogID, c.VCLogID[:]) || statement.Alias != alias {
+		return fmt.Errorf("statement is issued for another log")
+	}
+
+	if err := VerifySignature(signed.Signature, c.PubKey, statement); err != nil {
+		return fmt.Errorf("statement signature: %w", err)
+	}
+
+	return nil
+}
+
+// getFinalTreeHead returns the final tree head of the log, nil unless the log is frozen.
+func (c *Cmd) getFinalTreeHead(alias string) *SignedFinalTreeHead {
+	c.freezesMu.RLock()
+	defer c.freezesMu.RUnlock()
+
+	return c.freezes[alias]
+}
+
+// checkFrozen returns ErrFrozen if the log is frozen.
+func (c *Cmd) checkFrozen(alias string) error {
+	if final := c.getFinalTreeHead(alias); final != nil {
+		return frozenError(final)
+	}
+
+	return nil
+}
+
+func frozenError(final *SignedFinalTreeHead) error {
+	return fmt.Errorf("%w: the log is frozen at the tree size %d", errors.ErrFrozen, final.Statement.STH.TreeSize)
+}
+
+// checkIntegrated returns a bad request error unless the leaves queued by the instance to the log are integrated.
+// The read-only mode stops new leaves only, the leaves queued before it may still be integrated.
+func (c *Cmd) checkIntegrated(alias string) error {
+	if _, err := c.resolvePending(alias); err != nil {
+		return fmt.Errorf("resolve pending submissions: %w", err)
+	}
+
+	c.pending.mu.Lock()
+	pending := uint64(len(c.pending.logs[alias].leaves)) + c.pending.logs[alias].untracked
+	c.pending.mu.Unlock()
+
+	if pending > 0 {
+		return errors.NewBadRequestError(fmt.Errorf("%d leaves queued to log %s are not integrated, wait for them"+
+			" to be integrated before freezing the log", pending, alias))
+	}
+
+	return nil
+}
+
+// FreezeLog freezes (retires) the log at its latest tree head: the log accepts no more entries for good and the
+// final tree head signed by the key of the log is served permanently (GetFinalTreeHead), published in the
+// webfinger metadata and archived with the key of the log (GetRetiredShards), entries and proofs are still served
+// for audits. If the log has an admin client, its Trillian tree is frozen before the final tree head is signed:
+// the leaves queued are not integrated, set the log draining (SetLogState) and wait for them to be integrated
+// first. Otherwise the leaves queued before the freeze could be integrated after the final tree head: the log is
+// only frozen once the leaves queued by the instance are integrated (GetPendingSubmissions), even in read-only
+// mode, set the read-only mode and wait for the queued leaves to be integrated before freezing the log. A frozen log
+// is frozen once.
+func (c *Cmd) FreezeLog(w io.Writer, r io.Reader) error {
+	var req *FreezeLogRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode FreezeLog request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty FreezeLog request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(req.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	c.freezesMu.Lock()
+	defer c.freezesMu.Unlock()
+
+	final, ok := c.freezes[req.Alias]
+	if !ok {
+		if c.logs[req.Alias].AdminClient != nil {
+			if err := c.setTreeState(req.Alias, trillian.TreeState_FROZEN); err != nil {
+				return fmt.Errorf("freeze tree: %w", err)
+			}
+		} else if err := c.checkIntegrated(req.Alias); err != nil {
+			return err
+		}
+
+		sth, err := c.latestSTH(req.Alias)
+		if err != nil {
+			return err
+		}
+
+		statement := FinalTreeHead{
+			Version:       V1,
+			SignatureType: FreezeSignatureType,
+			Timestamp:     uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+			LogID:         c.VCLogID[:],
+			Alias:         req.Alias,
+			STH:           *sth,
+			Reason:        req.Reason,
+		}
+
+		signature, err := c.sign(statement)
+		if err != nil {
+			return fmt.Errorf("sign final tree head: %w", err)
+		}
+
+		final = &SignedFinalTreeHead{Statement: statement, Signature: signature}
+
+		if err = c.retireShard(req.Alias, final); err != nil {
+			return err
+		}
+
+		if c.onFreeze != nil {
+			if err = c.onFreeze(req.Alias, final); err != nil {
+				return fmt.Errorf("store final tree head: %w", err)
+			}
+		}
+
+		c.freezes[req.Alias] = final
+
+		logger.Warnf("log %s is frozen at the tree size %d: %s", req.Alias, sth.TreeSize, req.Reason)
+	}
+
+	return json.NewEncoder(w).Encode(final) // nolint: wrapcheck
+}
+
+// GetFinalTreeHead retrieves the final tree head of the frozen log (SignedFinalTreeHead), NotFound unless the
+// log is frozen. The final tree head never changes.
+func (c *Cmd) GetFinalTreeHead(w io.Writer, r io.Reader) error {
+	var alias string
+
+	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+		return fmt.Errorf("%w: decode alias failed", errors.ErrInternal)
+	}
+
+	if err := c.hasPermissions(alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	final := c.getFinalTreeHead(alias)
+	if final == nil {
+		return errors.NewNotFoundError(fmt.Errorf("log %s is not frozen", alias))
+	}
+
+	return json.NewEncoder(w).Encode(final) // nolint: wrapcheck
+}
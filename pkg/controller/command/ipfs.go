@@ -0,0 +1,41 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"fmt"
+)
+
+// SetIPFSRoot sets the latest root of the mirror of the log on IPFS (see publisher.IPFSBucket), it is advertised
+// in the webfinger metadata of the log. A root of a smaller tree than the current root is ignored.
+func (c *Cmd) SetIPFSRoot(alias string, root *IPFSRoot) error {
+	if _, ok := c.logs[alias]; !ok {
+		return fmt.Errorf("log %s is not served", alias)
+	}
+
+	if root == nil || root.CID == "" {
+		return fmt.Errorf("root of the mirror of log %s has no CID", alias)
+	}
+
+	c.ipfsRootsMu.Lock()
+	defer c.ipfsRootsMu.Unlock()
+
+	if current, ok := c.ipfsRoots[alias]; ok && current.TreeSize > root.TreeSize {
+		return nil
+	}
+
+	c.ipfsRoots[alias] = root
+
+	return nil
+}
+
+func (c *Cmd) getIPFSRoot(alias string) *IPFSRoot {
+	c.ipfsRootsMu.RLock()
+	defer c.ipfsRootsMu.RUnlock()
+
+	return c.ipfsRoots[alias]
+}
@@ -0,0 +1,401 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// DefaultReEncryptionBatchSize is the number of leaves read at once by the re-encryption by default.
+const DefaultReEncryptionBatchSize = 256
+
+// ExtraDataWriter is implemented by the log clients which can rewrite the extra data of the sequenced leaves (e.g.
+// the native Merkle log), the extra data is not hashed into the tree. The extra data of the leaves of the other
+// logs (e.g. Trillian) keeps the key it was encrypted with.
+type ExtraDataWriter interface {
+	UpdateExtraData(ctx context.Context, treeID, leafIndex int64, extraData []byte) error
+}
+
+// ReEncryptionConfig configures the background re-encryption of the extra data of leaves with the active envelope
+// key.
+type ReEncryptionConfig struct {
+	// Interval the logs which are not re-encrypted (e.g. after failures) and the leaves appended since the last
+	// scan are scanned again at, the logs are scanned on every rotation of the key regardless. Not scanned again if
+	// 0.
+	Interval time.Duration
+	// BatchSize is the number of leaves read at once (defaults to DefaultReEncryptionBatchSize).
+	BatchSize int64
+}
+
+// keyRotation tracks the rotations of the envelope key of the extra data and the re-encryption of the extra data
+// of the logs with the active key.
+type keyRotation struct {
+	mu        sync.Mutex
+	onRotate  func(string) error
+	rotatedAt uint64
+	reEncrypt bool
+	interval  time.Duration
+	batchSize int64
+	keyID     string                      // key the progress is for
+	logs      map[string]*LogReEncryption // alias -> progress of the re-encryption with the key
+	rotated   chan struct{}
+}
+
+func newKeyRotation(onRotate func(string) error, cfg *ReEncryptionConfig) *keyRotation {
+	r := &keyRotation{onRotate: onRotate, logs: map[string]*LogReEncryption{}, rotated: make(chan struct{}, 1)}
+
+	if cfg != nil {
+		r.reEncrypt, r.interval, r.batchSize = true, cfg.Interval, cfg.BatchSize
+	}
+
+	if r.batchSize <= 0 {
+		r.batchSize = DefaultReEncryptionBatchSize
+	}
+
+	return r
+}
+
+// progress returns a copy of the progress of the log with the key, the progress starts over with a new key.
+func (r *keyRotation) progress(alias, keyID string, rewritable bool) LogReEncryption {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keyID != keyID {
+		r.keyID, r.logs = keyID, map[string]*LogReEncryption{}
+	}
+
+	p, ok := r.logs[alias]
+	if !ok {
+		return LogReEncryption{Alias: alias, Rewritable: rewritable, Keys: map[string]uint64{}}
+	}
+
+	return copyProgress(p)
+}
+
+// setProgress keeps a copy of the progress of the log with the key unless the key rotated since.
+func (r *keyRotation) setProgress(keyID string, progress *LogReEncryption) {
+	if progress.Rewritable {
+		progress.Remaining = staleLeaves(progress, keyID) + uint64(progress.TreeSize-progress.Scanned)
+	} else {
+		progress.Retained = staleLeaves(progress, keyID)
+	}
+
+	p := copyProgress(progress)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keyID == keyID {
+		r.logs[progress.Alias] = &p
+	}
+
+	reEncryptionRemaining.Set(float64(progress.Remaining), progress.Alias)
+}
+
+func copyProgress(progress *LogReEncryption) LogReEncryption {
+	p := *progress
+	p.Keys = make(map[string]uint64, len(progress.Keys))
+
+	for kid, n := range progress.Keys {
+		p.Keys[kid] = n
+	}
+
+	return p
+}
+
+// staleLeaves returns the number of leaves of the log scanned which are not encrypted with the key.
+func staleLeaves(progress *LogReEncryption, keyID string) uint64 {
+	n := progress.Plaintext
+
+	for kid, count := range progress.Keys {
+		if kid != keyID {
+			n += count
+		}
+	}
+
+	return n
+}
+
+// RotateExtraDataKey rotates the envelope key encrypting the extra data of leaves (RotateExtraDataKeyRequest), the
+// extra data of the new leaves is encrypted with the key. The extra data encrypted with the previous keys is still
+// decrypted, it is re-encrypted with the key in the background if the re-encryption is enabled (see
+// ReEncryptExtraData). The response is the ExtraDataKeyStatus.
+func (c *Cmd) RotateExtraDataKey(w io.Writer, r io.Reader) error {
+	var request *RotateExtraDataKeyRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+		return fmt.Errorf("%w: decode RotateExtraDataKey request: %v", errors.ErrBadRequest, err)
+	}
+
+	if c.extraData == nil {
+		return errors.NewNotFoundError(fmt.Errorf("extra data is not encrypted"))
+	}
+
+	keyID := ""
+	if request != nil {
+		keyID = request.KeyID
+	}
+
+	c.keyRotation.mu.Lock()
+	defer c.keyRotation.mu.Unlock()
+
+	if keyID == "" {
+		kid, _, err := c.extraData.kms.Create(kms.AES256GCMType)
+		if err != nil {
+			return errors.NewStatusInternalServerError(fmt.Errorf("create envelope key: %w", err))
+		}
+
+		keyID = kid
+	}
+
+	if _, err := c.extraData.keyHandle(keyID); err != nil {
+		return errors.NewBadRequestError(err)
+	}
+
+	if previous := c.extraData.activeKeyID(); keyID != previous {
+		if c.keyRotation.onRotate != nil {
+			if err := c.keyRotation.onRotate(keyID); err != nil {
+				return errors.NewStatusInternalServerError(fmt.Errorf("store envelope key: %w", err))
+			}
+		}
+
+		c.extraData.activate(keyID)
+		c.keyRotation.rotatedAt = uint64(time.Now().UnixNano()) / uint64(time.Millisecond)
+
+		select {
+		case c.keyRotation.rotated <- struct{}{}:
+		default: // a re-encryption is already due
+		}
+
+		logger.Infof("envelope key of the extra data rotated from %s to %s", previous, keyID)
+	}
+
+	return json.NewEncoder(w).Encode(c.extraDataKeyStatus()) // nolint: wrapcheck
+}
+
+// GetExtraDataKey retrieves the envelope key encrypting the extra data of leaves and the progress of the
+// re-encryption of the logs with it (ExtraDataKeyStatus), NotFound if the extra data is not encrypted. Only the
+// extra data of the logs which can rewrite it (see ExtraDataWriter) is re-encrypted: the leaves of the other logs
+// (e.g. Trillian) are never re-encrypted, they are reported as retained with their keys. The metadata stores
+// (receipts, annotations, dedup, time index) are not encrypted, there is nothing to re-encrypt in them.
+func (c *Cmd) GetExtraDataKey(w io.Writer, _ io.Reader) error {
+	if c.extraData == nil {
+		return errors.NewNotFoundError(fmt.Errorf("extra data is not encrypted"))
+	}
+
+	c.keyRotation.mu.Lock()
+	defer c.keyRotation.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(c.extraDataKeyStatus()) // nolint: wrapcheck
+}
+
+// extraDataKeyStatus returns the status of the active key, the key rotation must be locked.
+func (c *Cmd) extraDataKeyStatus() *ExtraDataKeyStatus {
+	status := &ExtraDataKeyStatus{
+		KeyID:        c.extraData.activeKeyID(),
+		RotatedAt:    c.keyRotation.rotatedAt,
+		ReEncryption: c.keyRotation.reEncrypt,
+		Logs:         []LogReEncryption{},
+	}
+
+	if !status.ReEncryption {
+		return status
+	}
+
+	status.Done = c.keyRotation.keyID == status.KeyID && len(c.keyRotation.logs) == len(c.logs)
+
+	for _, alias := range c.aliases() {
+		if progress, ok := c.keyRotation.logs[alias]; ok && c.keyRotation.keyID == status.KeyID {
+			status.Logs = append(status.Logs, *progress)
+			status.Done = status.Done && progress.Remaining == 0
+		}
+	}
+
+	return status
+}
+
+// ReEncryptExtraData re-encrypts the extra data of the leaves of the logs with the active envelope key until the
+// context is done: the logs are scanned at start, on every rotation of the key and every interval. The extra data
+// which is not encrypted is encrypted. See GetExtraDataKey for the progress.
+func (c *Cmd) ReEncryptExtraData(ctx context.Context) {
+	if c.extraData == nil || !c.keyRotation.reEncrypt {
+		return
+	}
+
+	var tick <-chan time.Time
+
+	if c.keyRotation.interval > 0 {
+		ticker := time.NewTicker(c.keyRotation.interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		c.reEncrypt(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.keyRotation.rotated:
+		case <-tick:
+		}
+	}
+}
+
+func (c *Cmd) reEncrypt(ctx context.Context) {
+	keyID := c.extraData.activeKeyID()
+
+	for _, alias := range c.aliases() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := c.reEncryptLog(ctx, alias, keyID); err != nil {
+			logger.Warnf("re-encrypt the extra data of log %s: %v", alias, err)
+		}
+	}
+}
+
+// reEncryptLog scans the leaves of the log from the last leaf scanned with the key, a rewritable log which has
+// leaves left to re-encrypt (e.g. after failures) is scanned from the start. The scan stops once the key rotates.
+func (c *Cmd) reEncryptLog(ctx context.Context, alias, keyID string) error {
+	log := c.logs[alias]
+	writer, rewritable := log.Client.(ExtraDataWriter)
+
+	progress := c.keyRotation.progress(alias, keyID, rewritable)
+
+	if rewritable && progress.Remaining > uint64(progress.TreeSize-progress.Scanned) {
+		progress = LogReEncryption{Alias: alias, Rewritable: true, ReEncrypted: progress.ReEncrypted,
+			Keys: map[string]uint64{}}
+	}
+
+	resp, err := log.Client.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: log.ID})
+	if err != nil {
+		return fmt.Errorf("get latest signed log root: %w", err)
+	}
+
+	var root types.LogRootV1
+	if err = root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return fmt.Errorf("unmarshal log root: %w", err)
+	}
+
+	progress.TreeSize, progress.Error = int64(root.TreeSize), ""
+
+	defer func() { c.keyRotation.setProgress(keyID, &progress) }()
+
+	for progress.Scanned < progress.TreeSize {
+		if ctx.Err() != nil || c.extraData.activeKeyID() != keyID {
+			return nil
+		}
+
+		count := progress.TreeSize - progress.Scanned
+		if count > c.keyRotation.batchSize {
+			count = c.keyRotation.batchSize
+		}
+
+		leaves, er := tileLeaves(log, progress.Scanned, count)
+		if er != nil {
+			progress.Error = er.Error()
+
+			return er
+		}
+
+		for _, leaf := range leaves {
+			c.reEncryptLeaf(ctx, log, writer, leaf, keyID, &progress)
+		}
+
+		progress.Scanned += int64(len(leaves))
+
+		c.keyRotation.setProgress(keyID, &progress)
+	}
+
+	return nil
+}
+
+// reEncryptLeaf re-encrypts the extra data of the leaf with the key if the log can rewrite it, the leaf is counted
+// by the key its extra data ends up encrypted with.
+func (c *Cmd) reEncryptLeaf(ctx context.Context, log Log, writer ExtraDataWriter, leaf *trillian.LogLeaf,
+	keyID string, progress *LogReEncryption) {
+	extraData := leaf.GetExtraData()
+	if len(extraData) == 0 {
+		return
+	}
+
+	var kid string
+
+	if isEncrypted(extraData) {
+		var encrypted EncryptedExtraData
+		if err := json.Unmarshal(extraData, &encrypted); err == nil {
+			kid = encrypted.KeyID
+		}
+	}
+
+	if kid != keyID && writer != nil {
+		err := c.rewriteExtraData(ctx, log, writer, leaf, keyID)
+		if err == nil {
+			kid = keyID
+			progress.ReEncrypted++
+
+			reEncryptedLeaves.Inc(log.Alias)
+		} else {
+			progress.Failed++
+			progress.Error = err.Error()
+
+			logger.Warnf("re-encrypt the extra data of leaf %d of log %s: %v", leaf.GetLeafIndex(), log.Alias, err)
+		}
+	}
+
+	if kid == "" {
+		progress.Plaintext++
+	} else {
+		progress.Keys[kid]++
+	}
+}
+
+func (c *Cmd) rewriteExtraData(ctx context.Context, log Log, writer ExtraDataWriter, leaf *trillian.LogLeaf,
+	keyID string) error {
+	plaintext, err := c.extraData.decrypt(leaf.GetExtraData(), leaf.GetLeafValue())
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := c.extraData.encryptWith(keyID, plaintext, leaf.GetLeafValue())
+	if err != nil {
+		return err
+	}
+
+	if err = writer.UpdateExtraData(ctx, log.ID, leaf.GetLeafIndex(), encrypted); err != nil {
+		return fmt.Errorf("update extra data: %w", err)
+	}
+
+	return nil
+}
+
+// aliases returns the aliases of the logs, sorted.
+func (c *Cmd) aliases() []string {
+	aliases := make([]string, 0, len(c.logs))
+	for alias := range c.logs {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	return aliases
+}
@@ -0,0 +1,144 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// SignatureKind is the kind of statement signed with the key of the log or with the key of another purpose.
+type SignatureKind string
+
+// Signature kinds.
+const (
+	// SCTSignature is the kind of the signatures of SCTs.
+	SCTSignature SignatureKind = "sct"
+	// STHSignature is the kind of the signatures of tree heads.
+	STHSignature SignatureKind = "sth"
+	// StatementSignature is the kind of the signatures of other statements (map roots, proofs of absence,
+	// audit exports).
+	StatementSignature SignatureKind = "statement"
+	// ResponseSignature is the kind of the signatures of the responses of the REST API (the response key).
+	ResponseSignature SignatureKind = "response"
+	// AdminSignature is the kind of the signatures of the responses of the admin API (the admin key).
+	AdminSignature SignatureKind = "admin"
+	// WitnessSignature is the kind of the cosignatures of the anchored tree heads (the witness key).
+	WitnessSignature SignatureKind = "witness"
+)
+
+// keyUsageWindow is the window the thresholds of the signing volume apply to.
+const keyUsageWindow = time.Minute
+
+// nolint: gochecknoglobals
+var signatureKinds = []SignatureKind{
+	SCTSignature, STHSignature, StatementSignature, ResponseSignature, AdminSignature, WitnessSignature,
+}
+
+// signatureKindOf maps the purposes of the signing keys to the kinds of their signatures.
+// nolint: gochecknoglobals
+var signatureKindOf = map[KeyPurpose]SignatureKind{
+	ResponseKeyPurpose: ResponseSignature,
+	AdminKeyPurpose:    AdminSignature,
+	WitnessKeyPurpose:  WitnessSignature,
+}
+
+// keyUsage counts the signatures produced with the key of the log. A volume above the threshold of a kind
+// within a window is an anomaly, it may indicate that the key or the service is compromised.
+type keyUsage struct {
+	mu         sync.Mutex
+	thresholds map[SignatureKind]uint64
+	kinds      map[SignatureKind]*kindUsage
+}
+
+type kindUsage struct {
+	signatures  uint64
+	lastUsed    time.Time
+	windowStart time.Time
+	window      uint64 // signatures in the window
+	anomalies   uint64
+}
+
+func newKeyUsage(thresholds map[SignatureKind]uint64) *keyUsage {
+	usage := &keyUsage{thresholds: thresholds, kinds: map[SignatureKind]*kindUsage{}}
+
+	for _, kind := range signatureKinds {
+		usage.kinds[kind] = &kindUsage{}
+	}
+
+	return usage
+}
+
+func (u *keyUsage) record(kind SignatureKind) {
+	now := time.Now()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	usage := u.kinds[kind]
+
+	if now.Sub(usage.windowStart) >= keyUsageWindow {
+		usage.windowStart, usage.window = now, 0
+	}
+
+	usage.signatures++
+	usage.window++
+	usage.lastUsed = now
+
+	signatures.Inc(string(kind))
+	signatureLastUse.Set(float64(now.Unix()), string(kind))
+
+	// an anomaly is reported once per window
+	if threshold := u.thresholds[kind]; threshold > 0 && usage.window == threshold+1 {
+		usage.anomalies++
+
+		keyUsageAnomalies.Inc(string(kind))
+		logger.Warnf("key usage anomaly: more than %d %s signatures within %s", threshold, kind, keyUsageWindow)
+	}
+}
+
+func (u *keyUsage) get() []KeyUsage {
+	now := time.Now()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	result := make([]KeyUsage, 0, len(signatureKinds))
+
+	for _, kind := range signatureKinds {
+		usage := u.kinds[kind]
+
+		item := KeyUsage{
+			Kind:       kind,
+			Signatures: usage.signatures,
+			Threshold:  u.thresholds[kind],
+			Anomalies:  usage.anomalies,
+		}
+
+		if !usage.lastUsed.IsZero() {
+			item.LastUsed = uint64(usage.lastUsed.UnixNano()) / uint64(time.Millisecond)
+		}
+
+		if now.Sub(usage.windowStart) < keyUsageWindow {
+			item.Window = usage.window
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// GetKeyUsage retrieves the counts of the signatures produced with the key of the log since the start.
+func (c *Cmd) GetKeyUsage(w io.Writer, _ io.Reader) error {
+	return json.NewEncoder(w).Encode(GetKeyUsageResponse{ // nolint: wrapcheck
+		LogID: c.VCLogID[:],
+		Usage: c.keyUsage.get(),
+	})
+}
@@ -0,0 +1,336 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// LeafType describes a type of log entries.
+type LeafType struct {
+	EntryType LogEntryType
+	// Name identifies the type in the metadata (webfinger).
+	Name string
+	// Validate validates a submitted entry of the type, the type can not be submitted to add-entry
+	// if it is nil (e.g. credentials which have a dedicated endpoint).
+	Validate func(entry []byte) error
+	// Check (optional) checks a valid submitted entry against the state of the log.
+	Check func(alias string, entry []byte) error
+	// Lock (optional) locks the state the submitted entries of the type are checked against, from the check
+	// through the queueing of an entry, so concurrent submissions are checked against each other.
+	Lock func(alias string) (unlock func(), err error)
+	// Queued (optional) records the serialized entry once it is queued, the lock is still held.
+	Queued func(alias string, entry []byte)
+	// Serialize returns the canonical form of a submitted entry which is logged as VCEntry.
+	Serialize func(entry []byte) ([]byte, error)
+}
+
+// LeafTypeMetadata is the description of a supported leaf type published in the metadata.
+type LeafTypeMetadata struct {
+	EntryType LogEntryType `json:"entry_type"`
+	Name      string       `json:"name"`
+	// Submittable is true if entries of the type can be submitted to add-entry.
+	Submittable bool `json:"submittable"`
+}
+
+// leafTypes is a registry of leaf types.
+type leafTypes struct {
+	types map[LogEntryType]*LeafType
+	order []LogEntryType
+}
+
+func newLeafTypes(types []LeafType) (*leafTypes, error) {
+	registry := &leafTypes{types: map[LogEntryType]*LeafType{}}
+
+	names := map[string]struct{}{}
+
+	for i := range types {
+		t := types[i]
+
+		if _, ok := registry.types[t.EntryType]; ok {
+			return nil, fmt.Errorf("leaf type %d is already registered", t.EntryType)
+		}
+
+		if _, ok := names[t.Name]; ok || t.Name == "" {
+			return nil, fmt.Errorf("leaf type name %q is empty or already registered", t.Name)
+		}
+
+		if t.Validate != nil && t.Serialize == nil {
+			return nil, fmt.Errorf("leaf type %q has no serializer", t.Name)
+		}
+
+		registry.types[t.EntryType] = &t
+		registry.order = append(registry.order, t.EntryType)
+		names[t.Name] = struct{}{}
+	}
+
+	return registry, nil
+}
+
+func (r *leafTypes) get(entryType LogEntryType) (*LeafType, bool) {
+	t, ok := r.types[entryType]
+
+	return t, ok
+}
+
+func (r *leafTypes) metadata() []LeafTypeMetadata {
+	metadata := make([]LeafTypeMetadata, 0, len(r.order))
+
+	for _, entryType := range r.order {
+		metadata = append(metadata, LeafTypeMetadata{
+			EntryType:   entryType,
+			Name:        r.types[entryType].Name,
+			Submittable: r.types[entryType].Validate != nil,
+		})
+	}
+
+	return metadata
+}
+
+// defaultLeafTypes returns the built-in leaf types.
+func (c *Cmd) defaultLeafTypes() []LeafType {
+	return []LeafType{{
+		EntryType: VCLogEntryType,
+		Name:      "vc",
+	}, {
+		EntryType: RevocationLogEntryType,
+		Name:      "revocation",
+		Validate: func(entry []byte) error {
+			var event RevocationEvent
+			if err := unmarshalEntry(entry, &event); err != nil {
+				return err
+			}
+
+			if err := event.Validate(); err != nil {
+				return fmt.Errorf("validate revocation event: %w", err)
+			}
+
+			return nil
+		},
+		Check: func(alias string, entry []byte) error {
+			var event RevocationEvent
+			if err := unmarshalEntry(entry, &event); err != nil {
+				return err
+			}
+
+			return c.checkRevocation(alias, &event)
+		},
+		Lock: func(alias string) (func(), error) {
+			index, err := c.lockIndex(alias)
+			if err != nil {
+				return nil, fmt.Errorf("index credentials: %w", err)
+			}
+
+			return index.mu.Unlock, nil
+		},
+		Queued: func(alias string, entry []byte) {
+			var event RevocationEvent
+			if err := json.Unmarshal(entry, &event); err == nil {
+				c.credentialIndexes[alias].queue(&event, entry)
+			}
+		},
+		Serialize: jsonSerializer(func() interface{} { return &RevocationEvent{} }),
+	}, {
+		EntryType: STHAnchorLogEntryType,
+		Name:      "sth-anchor",
+		Validate: func(entry []byte) error {
+			var anchor STHAnchor
+			if err := unmarshalEntry(entry, &anchor); err != nil {
+				return err
+			}
+
+			if err := anchor.Validate(); err != nil {
+				return fmt.Errorf("validate anchor: %w", err)
+			}
+
+			return nil
+		},
+		Check: func(alias string, entry []byte) error {
+			var anchor STHAnchor
+			if err := unmarshalEntry(entry, &anchor); err != nil {
+				return err
+			}
+
+			return c.checkAnchor(alias, &anchor)
+		},
+		Serialize: jsonSerializer(func() interface{} { return &STHAnchor{} }),
+	}, {
+		EntryType: CommitmentLogEntryType,
+		Name:      "commitment",
+		Validate: func(entry []byte) error {
+			var commitment Commitment
+			if err := unmarshalEntry(entry, &commitment); err != nil {
+				return err
+			}
+
+			return commitment.Validate()
+		},
+		Serialize: jsonSerializer(func() interface{} { return &Commitment{} }),
+	}, {
+		EntryType: MDocLogEntryType,
+		Name:      "mdoc",
+		Validate: func(entry []byte) error {
+			_, err := mDocEntry(entry)
+
+			return err
+		},
+		Serialize: func(entry []byte) ([]byte, error) {
+			logged, err := mDocEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+
+			return json.Marshal(logged) // nolint: wrapcheck
+		},
+	}, {
+		EntryType: AnonCredsLogEntryType,
+		Name:      "anoncreds-commitment",
+		Validate: func(entry []byte) error {
+			var commitment AnonCredsCommitment
+			if err := unmarshalEntry(entry, &commitment); err != nil {
+				return err
+			}
+
+			return commitment.Validate()
+		},
+		Serialize: jsonSerializer(func() interface{} { return &AnonCredsCommitment{} }),
+	}}
+}
+
+// mDocEntry verifies the submitted mdoc and returns its logged form.
+func mDocEntry(entry []byte) (*MDocEntry, error) {
+	var mdoc MDoc
+	if err := unmarshalEntry(entry, &mdoc); err != nil {
+		return nil, err
+	}
+
+	logged, err := verifyMDoc(mdoc.Document)
+	if err != nil {
+		return nil, fmt.Errorf("%w: mdoc: %v", errors.ErrValidation, err)
+	}
+
+	return logged, nil
+}
+
+func unmarshalEntry(entry []byte, v interface{}) error {
+	if err := json.Unmarshal(entry, v); err != nil {
+		return fmt.Errorf("%w: unmarshal entry: %v", errors.ErrValidation, err)
+	}
+
+	return nil
+}
+
+// jsonSerializer returns a serializer re-encoding the entry decoded into the value, so unknown fields are dropped.
+func jsonSerializer(newValue func() interface{}) func([]byte) ([]byte, error) {
+	return func(entry []byte) ([]byte, error) {
+		v := newValue()
+
+		if err := unmarshalEntry(entry, v); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(v) // nolint: wrapcheck
+	}
+}
+
+func createEntryLeaf(timestamp uint64, entryType LogEntryType, entry []byte) *MerkleTreeLeaf {
+	return &MerkleTreeLeaf{
+		Version:  V1,
+		LeafType: TimestampedEntryLeafType,
+		TimestampedEntry: &TimestampedEntry{
+			EntryType: entryType,
+			Timestamp: timestamp,
+			VCEntry:   entry,
+		},
+	}
+}
+
+// AddEntry adds an entry of a registered leaf type to log.
+func (c *Cmd) AddEntry(w io.Writer, r io.Reader) error {
+	var req *AddEntryRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode AddEntry request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty AddEntry request", errors.ErrBadRequest)
+	}
+
+	resp, err := c.addEntry(req.Alias, req.EntryType, req.Entry)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp) // nolint: wrapcheck
+}
+
+// addEntry validates the entry by its leaf type and queues its canonical form.
+func (c *Cmd) addEntry(alias string, entryType LogEntryType, entry []byte) (*AddVCResponse, error) {
+	t, ok := c.leafTypes.get(entryType)
+	if !ok {
+		return nil, errors.NewBadRequestError(fmt.Errorf("leaf type %d is not supported", entryType))
+	}
+
+	if t.Validate == nil {
+		return nil, errors.NewBadRequestError(fmt.Errorf("leaf type %q can not be added as an entry", t.Name))
+	}
+
+	if err := c.checkEntrySize(entry); err != nil {
+		return nil, err
+	}
+
+	if err := t.Validate(entry); err != nil {
+		return nil, err
+	}
+
+	if err := c.hasPermissions(alias, write); err != nil {
+		return nil, fmt.Errorf("has permissions: %w", err)
+	}
+
+	if t.Lock != nil {
+		unlock, err := t.Lock(alias)
+		if err != nil {
+			return nil, err
+		}
+
+		defer unlock()
+	}
+
+	if t.Check != nil {
+		if err := t.Check(alias, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	serialized, err := t.Serialize(entry)
+	if err != nil {
+		return nil, fmt.Errorf("serialize %s entry: %w", t.Name, err)
+	}
+
+	timestamp, attestation, err := c.timestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := createEntryLeaf(timestamp, entryType, serialized)
+
+	resp, err := c.queueLeaf(alias, leaf, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Queued != nil {
+		t.Queued(alias, serialized)
+	}
+
+	return attest(resp, leaf, attestation), nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+func (c *Cmd) isReadOnly() bool {
+	return atomic.LoadUint32(&c.readOnly) == 1
+}
+
+// checkWritable returns ErrCompromised if writes are frozen as the key of the log is compromised, ErrFrozen
+// if the log is frozen, ErrDraining or ErrTreeFrozen if the Trillian tree of the log is not active and
+// ErrReadOnly if writes are rejected by the read-only (maintenance) mode.
+func (c *Cmd) checkWritable(alias string) error {
+	if err := c.checkCompromised(); err != nil {
+		return err
+	}
+
+	if err := c.checkFrozen(alias); err != nil {
+		return err
+	}
+
+	if err := c.checkTreeState(alias, false); err != nil {
+		return err
+	}
+
+	if c.isReadOnly() {
+		return fmt.Errorf("%w: the service is in maintenance, writes are rejected, retry later", errors.ErrReadOnly)
+	}
+
+	return nil
+}
+
+// GetReadOnly retrieves the read-only (maintenance) mode of the service, a compromised log is read-only.
+func (c *Cmd) GetReadOnly(w io.Writer, _ io.Reader) error {
+	return json.NewEncoder(w).Encode(ReadOnlyStatus{ // nolint: wrapcheck
+		ReadOnly: c.isReadOnly() || c.getCompromise() != nil,
+	})
+}
+
+// SetReadOnly enables or disables the read-only (maintenance) mode of the service.
+// Entries and proofs are served in the mode, writes of all logs are rejected with ErrReadOnly.
+func (c *Cmd) SetReadOnly(w io.Writer, r io.Reader) error {
+	var req *ReadOnlyStatus
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode SetReadOnly request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty SetReadOnly request", errors.ErrBadRequest)
+	}
+
+	if !req.ReadOnly {
+		if err := c.checkCompromised(); err != nil {
+			return err
+		}
+	}
+
+	var val uint32
+	if req.ReadOnly {
+		val = 1
+	}
+
+	if atomic.SwapUint32(&c.readOnly, val) != val {
+		logger.Infof("read-only mode is set to %t", req.ReadOnly)
+	}
+
+	return json.NewEncoder(w).Encode(ReadOnlyStatus{ReadOnly: req.ReadOnly}) // nolint: wrapcheck
+}
@@ -0,0 +1,339 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/trustbloc/vct/internal/pkg/cbor"
+)
+
+// COSE (RFC 8152) labels and algorithms.
+const (
+	coseSign1Tag      = 18
+	coseSign1Parts    = 4
+	coseHeaderAlg     = int64(1)
+	coseHeaderX5Chain = int64(33)
+	coseAlgES256      = int64(-7)
+	coseAlgES384      = int64(-35)
+	coseAlgES512      = int64(-36)
+	coseAlgEdDSA      = int64(-8)
+	coseSign1Context  = "Signature1"
+)
+
+// mDocDigestAlgorithms are the digest algorithms of the mobile security object.
+// nolint: gochecknoglobals
+var mDocDigestAlgorithms = map[string]crypto.Hash{
+	"SHA-256": crypto.SHA256,
+	"SHA-384": crypto.SHA384,
+	"SHA-512": crypto.SHA512,
+}
+
+// verifyMDoc verifies the mdoc and returns its logged form.
+//
+// The document is either a Document (with docType and issuerSigned) or an IssuerSigned structure.
+// The COSE_Sign1 signature of the mobile security object (MSO) is verified with the key of the document signer
+// certificate (x5chain) and every disclosed data element is checked against its digest in the MSO. The document
+// signer certificate is not checked against the issuing authority, the log makes transparent that the key
+// signed the MSO. Errors report the name space and the digest ID of a data element, never its value.
+func verifyMDoc(document []byte) (*MDocEntry, error) {
+	decoded, err := cbor.Decode(document)
+	if err != nil {
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+
+	issuerSigned, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("document must be a map")
+	}
+
+	var docType string
+
+	if signed, isDocument := issuerSigned["issuerSigned"]; isDocument {
+		if docType, ok = issuerSigned["docType"].(string); !ok {
+			return nil, errors.New("docType must be a string")
+		}
+
+		if issuerSigned, ok = signed.(map[interface{}]interface{}); !ok {
+			return nil, errors.New("issuerSigned must be a map")
+		}
+	}
+
+	issuerAuth, err := coseSign1(issuerSigned["issuerAuth"])
+	if err != nil {
+		return nil, fmt.Errorf("issuerAuth: %w", err)
+	}
+
+	if err = verifyCOSESign1(issuerAuth); err != nil {
+		return nil, fmt.Errorf("issuerAuth: %w", err)
+	}
+
+	mso, err := decodeEncodedCBOR(issuerAuth[2])
+	if err != nil {
+		return nil, fmt.Errorf("mobile security object: %w", err)
+	}
+
+	msoDocType, ok := mso["docType"].(string)
+	if !ok || msoDocType == "" {
+		return nil, errors.New("mobile security object: docType must be a non-empty string")
+	}
+
+	if docType != "" && docType != msoDocType {
+		return nil, fmt.Errorf("docType %q does not match the docType of the mobile security object", docType)
+	}
+
+	if err = verifyDataElements(mso, issuerSigned["nameSpaces"]); err != nil {
+		return nil, err
+	}
+
+	encoded, err := cbor.Encode([]interface{}(issuerAuth))
+	if err != nil {
+		return nil, fmt.Errorf("encode issuerAuth: %w", err)
+	}
+
+	return &MDocEntry{DocType: msoDocType, IssuerAuth: encoded}, nil
+}
+
+// coseSign1 returns the [protected, unprotected, payload, signature] parts of the (optionally tagged) COSE_Sign1.
+func coseSign1(v interface{}) ([]interface{}, error) {
+	if tag, ok := v.(cbor.Tag); ok && tag.Number == coseSign1Tag {
+		v = tag.Content
+	}
+
+	parts, ok := v.([]interface{})
+	if !ok || len(parts) != coseSign1Parts {
+		return nil, fmt.Errorf("COSE_Sign1 must be an array of %d elements", coseSign1Parts)
+	}
+
+	if _, ok = parts[0].([]byte); !ok {
+		return nil, errors.New("protected header must be a byte string")
+	}
+
+	if _, ok = parts[1].(map[interface{}]interface{}); !ok {
+		return nil, errors.New("unprotected header must be a map")
+	}
+
+	if _, ok = parts[2].([]byte); !ok {
+		return nil, errors.New("payload must be a byte string")
+	}
+
+	if _, ok = parts[3].([]byte); !ok {
+		return nil, errors.New("signature must be a byte string")
+	}
+
+	return parts, nil
+}
+
+func verifyCOSESign1(parts []interface{}) error {
+	protected, unprotected := parts[0].([]byte), parts[1].(map[interface{}]interface{}) // nolint: forcetypeassert
+
+	headers := map[interface{}]interface{}{}
+
+	if len(protected) > 0 {
+		decoded, err := cbor.Decode(protected)
+		if err != nil {
+			return fmt.Errorf("decode protected header: %w", err)
+		}
+
+		var ok bool
+		if headers, ok = decoded.(map[interface{}]interface{}); !ok {
+			return errors.New("protected header must be a map")
+		}
+	}
+
+	alg, ok := headers[coseHeaderAlg].(int64)
+	if !ok {
+		return errors.New("protected header has no alg")
+	}
+
+	x5chain, ok := headers[coseHeaderX5Chain]
+	if !ok {
+		x5chain = unprotected[coseHeaderX5Chain]
+	}
+
+	cert, err := leafCertificate(x5chain)
+	if err != nil {
+		return err
+	}
+
+	toBeSigned, err := cbor.Encode([]interface{}{coseSign1Context, protected, []byte{}, parts[2]})
+	if err != nil {
+		return fmt.Errorf("encode Sig_structure: %w", err)
+	}
+
+	return verifyCOSESignature(alg, cert.PublicKey, toBeSigned, parts[3].([]byte)) // nolint: forcetypeassert
+}
+
+// leafCertificate returns the first certificate of the x5chain which is a certificate or an array of certificates.
+func leafCertificate(x5chain interface{}) (*x509.Certificate, error) {
+	if chain, ok := x5chain.([]interface{}); ok && len(chain) > 0 {
+		x5chain = chain[0]
+	}
+
+	der, ok := x5chain.([]byte)
+	if !ok {
+		return nil, errors.New("no document signer certificate (x5chain)")
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse document signer certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+func verifyCOSESignature(alg int64, publicKey interface{}, toBeSigned, signature []byte) error {
+	if alg == coseAlgEdDSA {
+		pubKey, ok := publicKey.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pubKey, toBeSigned, signature) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	}
+
+	hashes := map[int64]crypto.Hash{coseAlgES256: crypto.SHA256, coseAlgES384: crypto.SHA384, coseAlgES512: crypto.SHA512}
+
+	hash, ok := hashes[alg]
+	if !ok {
+		return fmt.Errorf("alg %d is not supported", alg)
+	}
+
+	pubKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("alg %d requires an ECDSA key", alg)
+	}
+
+	// the signature is r || s, each of the size of the curve
+	size := (pubKey.Curve.Params().BitSize + 7) / 8 // nolint: gomnd
+	if len(signature) != 2*size {
+		return errors.New("invalid signature")
+	}
+
+	h := hash.New()
+	h.Write(toBeSigned) // nolint: errcheck
+
+	r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+
+	if !ecdsa.Verify(pubKey, h.Sum(nil), r, s) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// decodeEncodedCBOR decodes the map embedded as encoded CBOR (#6.24(bstr)) in the byte string.
+func decodeEncodedCBOR(data interface{}) (map[interface{}]interface{}, error) {
+	if raw, ok := data.([]byte); ok {
+		decoded, err := cbor.Decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decode: %w", err)
+		}
+
+		data = decoded
+	}
+
+	tag, ok := data.(cbor.Tag)
+	if !ok || tag.Number != cbor.TagEncodedCBOR {
+		return nil, errors.New("must be encoded CBOR (tag 24)")
+	}
+
+	raw, ok := tag.Content.([]byte)
+	if !ok {
+		return nil, errors.New("encoded CBOR must be a byte string")
+	}
+
+	decoded, err := cbor.Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("must be a map")
+	}
+
+	return m, nil
+}
+
+// verifyDataElements checks that every disclosed data element (IssuerSignedItemBytes) matches its digest
+// in the valueDigests of the mobile security object.
+func verifyDataElements(mso map[interface{}]interface{}, nameSpaces interface{}) error {
+	algorithm, _ := mso["digestAlgorithm"].(string) // nolint: errcheck
+
+	hash, ok := mDocDigestAlgorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("digestAlgorithm %q is not supported", algorithm)
+	}
+
+	valueDigests, ok := mso["valueDigests"].(map[interface{}]interface{})
+	if !ok {
+		return errors.New("mobile security object: valueDigests must be a map")
+	}
+
+	if nameSpaces == nil {
+		return nil
+	}
+
+	namespaces, ok := nameSpaces.(map[interface{}]interface{})
+	if !ok {
+		return errors.New("nameSpaces must be a map")
+	}
+
+	for ns, items := range namespaces {
+		digests, _ := valueDigests[ns].(map[interface{}]interface{}) // nolint: errcheck
+
+		elements, isArray := items.([]interface{})
+		if !isArray {
+			return fmt.Errorf("name space %v must be an array", ns)
+		}
+
+		for i, element := range elements {
+			if err := verifyDataElement(hash, digests, element); err != nil {
+				return fmt.Errorf("name space %v element %d: %w", ns, i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func verifyDataElement(hash crypto.Hash, digests map[interface{}]interface{}, element interface{}) error {
+	item, err := decodeEncodedCBOR(element)
+	if err != nil {
+		return err
+	}
+
+	digestID, ok := item["digestID"].(uint64)
+	if !ok {
+		return errors.New("digestID must be an unsigned integer")
+	}
+
+	// the digest is computed over IssuerSignedItemBytes, i.e. the tagged byte string
+	encoded, err := cbor.Encode(element)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	h := hash.New()
+	h.Write(encoded) // nolint: errcheck
+
+	digest, ok := digests[int64(digestID)].([]byte)
+	if !ok || !bytes.Equal(digest, h.Sum(nil)) {
+		return fmt.Errorf("digest %d does not match", digestID)
+	}
+
+	return nil
+}
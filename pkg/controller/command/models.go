@@ -7,11 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package command
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 
 	"github.com/trustbloc/vct/pkg/controller/errors"
+	"github.com/trustbloc/vct/pkg/smt"
 )
 
 // Version type definition.
@@ -36,8 +43,21 @@ type SignatureType uint64
 
 // SignatureType constants.
 const (
-	VCTimestampSignatureType SignatureType = 100
-	TreeHeadSignatureType    SignatureType = 101
+	VCTimestampSignatureType  SignatureType = 100
+	TreeHeadSignatureType     SignatureType = 101
+	NonInclusionSignatureType SignatureType = 102
+	MapRootSignatureType      SignatureType = 103
+	AuditExportSignatureType  SignatureType = 104
+	CompromiseSignatureType   SignatureType = 105
+	AnnotationSignatureType   SignatureType = 106
+	ResponseSignatureType     SignatureType = 107
+	CosignatureSignatureType  SignatureType = 108
+	SnapshotSignatureType     SignatureType = 109
+	FreezeSignatureType       SignatureType = 110
+	PolicySignatureType       SignatureType = 111
+	// VerificationAuditSignatureType is the type of the exports of the audit trails of the verifiers, they are
+	// signed by the verifiers rather than by the logs.
+	VerificationAuditSignatureType SignatureType = 112
 )
 
 // MerkleLeafType type definition.
@@ -53,7 +73,25 @@ type LogEntryType uint64
 
 // LogEntryType constants.
 const (
-	VCLogEntryType LogEntryType = 100
+	VCLogEntryType         LogEntryType = 100
+	RevocationLogEntryType LogEntryType = 101
+	STHAnchorLogEntryType  LogEntryType = 102
+	CommitmentLogEntryType LogEntryType = 103
+	MDocLogEntryType       LogEntryType = 104
+	AnonCredsLogEntryType  LogEntryType = 105
+	// PresentationLogEntryType is the type of the holder-signed presentations, it is opt-in
+	// (Config.Presentations).
+	PresentationLogEntryType LogEntryType = 106
+)
+
+// RevocationStatus is the status of a credential set by a revocation event.
+type RevocationStatus string
+
+// RevocationStatus constants.
+const (
+	RevokedStatus    RevocationStatus = "revoked"
+	SuspendedStatus  RevocationStatus = "suspended"
+	ReinstatedStatus RevocationStatus = "reinstated"
 )
 
 // GetEntryAndProofRequest represents the request to get-entry-and-proof.
@@ -61,6 +99,8 @@ type GetEntryAndProofRequest struct {
 	Alias     string `json:"alias"`
 	LeafIndex int64  `json:"leaf_index"`
 	TreeSize  int64  `json:"tree_size"`
+	// Debug returns the timing breakdown of the proof (ProofTrace) in the response.
+	Debug bool `json:"debug,omitempty"`
 }
 
 // Validate validates data.
@@ -89,6 +129,9 @@ type GetEntryAndProofResponse struct {
 	LeafInput []byte   `json:"leaf_input"`
 	ExtraData []byte   `json:"extra_data"`
 	AuditPath [][]byte `json:"audit_path"`
+	// Annotations of the entry by auditors, they are not logged in the tree.
+	Annotations []SignedAnnotation `json:"annotations,omitempty"`
+	Debug       *ProofTrace        `json:"debug,omitempty"`
 }
 
 // GetProofByHashRequest represents the request to the get-proof-by-hash.
@@ -96,6 +139,8 @@ type GetProofByHashRequest struct {
 	Alias    string `json:"alias"`
 	Hash     string `json:"hash"`
 	TreeSize int64  `json:"tree_size"`
+	// Debug returns the timing breakdown of the proof (ProofTrace) in the response.
+	Debug bool `json:"debug,omitempty"`
 }
 
 // Validate validates data.
@@ -113,26 +158,42 @@ func (r *GetProofByHashRequest) Validate() error {
 
 // GetProofByHashResponse represents the response to the get-proof-by-hash.
 type GetProofByHashResponse struct {
-	LeafIndex int64    `json:"leaf_index"`
-	AuditPath [][]byte `json:"audit_path"`
+	LeafIndex int64       `json:"leaf_index"`
+	AuditPath [][]byte    `json:"audit_path"`
+	Debug     *ProofTrace `json:"debug,omitempty"`
 }
 
 // GetEntriesRequest represents the request to the get-entries.
+// If FromTime or ToTime is set, entries are selected by the sequencing timestamp and Start/End are ignored.
 type GetEntriesRequest struct {
-	Alias string `json:"alias"`
-	Start int64  `json:"start"`
-	End   int64  `json:"end"`
+	Alias    string     `json:"alias"`
+	Start    int64      `json:"start"`
+	End      int64      `json:"end"`
+	FromTime *time.Time `json:"from_time,omitempty"`
+	ToTime   *time.Time `json:"to_time,omitempty"`
+	// PolicyTags (optional) filters the entries of the range by their policy tags (see PolicyTags.Match).
+	PolicyTags
 }
 
 // GetEntriesResponse represents the response to the get-entries.
 type GetEntriesResponse struct {
 	Entries []LeafEntry `json:"entries"`
+	// Start and End are the indexes of the first and the last leaf of the time range of a request by time.
+	Start *int64 `json:"start,omitempty"`
+	End   *int64 `json:"end,omitempty"`
+	// Next is the index of the first leaf of the range which is not served, set if the range exceeds the max
+	// number of entries of a response: the remaining entries are retrieved from it (start next, end End).
+	Next *int64 `json:"next,omitempty"`
 }
 
 // LeafEntry represents a leaf in the Log's Merkle tree.
 type LeafEntry struct {
 	LeafInput []byte `json:"leaf_input"`
 	ExtraData []byte `json:"extra_data"`
+	// Annotations of the entry by auditors, they are not logged in the tree.
+	Annotations []SignedAnnotation `json:"annotations,omitempty"`
+	// LeafIndex of the entry, set if the entries are filtered by their policy tags.
+	LeafIndex *int64 `json:"leaf_index,omitempty"`
 }
 
 // Validate validates data.
@@ -141,6 +202,15 @@ func (r *GetEntriesRequest) Validate() error {
 		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
 	}
 
+	if r.FromTime != nil || r.ToTime != nil {
+		if r.FromTime != nil && r.ToTime != nil && r.FromTime.After(*r.ToTime) {
+			return fmt.Errorf("%w: from_time %s and to_time %s values is not a valid range", errors.ErrValidation,
+				r.FromTime.Format(time.RFC3339), r.ToTime.Format(time.RFC3339))
+		}
+
+		return nil
+	}
+
 	if r.Start < 0 || r.End < 0 {
 		return fmt.Errorf("%w: start %d and end %d values must be >= 0", errors.ErrValidation, r.Start, r.End)
 	}
@@ -157,6 +227,8 @@ type GetSTHConsistencyRequest struct {
 	Alias          string `json:"alias"`
 	FirstTreeSize  int64  `json:"first_tree_size"`
 	SecondTreeSize int64  `json:"second_tree_size"`
+	// Debug returns the timing breakdown of the proof (ProofTrace) in the response.
+	Debug bool `json:"debug,omitempty"`
 }
 
 // Validate validates data.
@@ -182,7 +254,25 @@ func (r *GetSTHConsistencyRequest) Validate() error {
 
 // GetSTHConsistencyResponse represents the response to the get-sth-consistency.
 type GetSTHConsistencyResponse struct {
-	Consistency [][]byte `json:"consistency"`
+	Consistency [][]byte    `json:"consistency"`
+	Debug       *ProofTrace `json:"debug,omitempty"`
+}
+
+// ProofTrace is the timing breakdown of the generation of a proof, returned by the proof endpoints in the debug
+// mode. The steps are in the order they ran, a step which did not run is absent.
+type ProofTrace struct {
+	// CacheHit is true if the proof was computed from the proof cache and the tiles rather than read from Trillian.
+	CacheHit bool             `json:"cache_hit"`
+	Steps    []ProofTraceStep `json:"steps"`
+	// TotalMicros is the time (µs) spent generating the proof, including the encoding of the response.
+	TotalMicros int64 `json:"total_us"`
+}
+
+// ProofTraceStep is a step of the generation of a proof: proof_cache, trillian_rpc, leaf, extra_data, annotations
+// or encode.
+type ProofTraceStep struct {
+	Name   string `json:"name"`
+	Micros int64  `json:"duration_us"`
 }
 
 // GetSTHResponse represents the response to the get-sth.
@@ -202,6 +292,109 @@ type TreeHeadSignature struct {
 	SHA256RootHash []byte        `json:"sha_256_root_hash"`
 }
 
+// GetProofOfAbsenceRequest represents the request to the get-proof-of-absence.
+type GetProofOfAbsenceRequest struct {
+	Alias        string `json:"alias"`
+	Issuer       string `json:"issuer"`
+	CredentialID string `json:"credential_id"`
+}
+
+// Validate validates data.
+func (r *GetProofOfAbsenceRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.Issuer == "" {
+		return fmt.Errorf("%w: issuer is empty", errors.ErrValidation)
+	}
+
+	if r.CredentialID == "" {
+		return fmt.Errorf("%w: credential_id is empty", errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// GetProofOfAbsenceResponse represents the response to the get-proof-of-absence.
+type GetProofOfAbsenceResponse struct {
+	TreeSize          uint64     `json:"tree_size"`
+	Timestamp         uint64     `json:"timestamp"`
+	Issuer            string     `json:"issuer"`
+	CredentialID      string     `json:"credential_id"`
+	SHA256MapRootHash []byte     `json:"sha256_map_root_hash"`
+	AuditPath         *smt.Proof `json:"audit_path"`
+	Signature         []byte     `json:"signature"`
+}
+
+// NonInclusionSignature keeps the data over which the signature of a proof of absence is created.
+type NonInclusionSignature struct {
+	Version           Version       `json:"version"`
+	SignatureType     SignatureType `json:"signature_type"`
+	Timestamp         uint64        `json:"timestamp"`
+	TreeSize          uint64        `json:"tree_size"`
+	Issuer            string        `json:"issuer"`
+	CredentialID      string        `json:"credential_id"`
+	SHA256MapRootHash []byte        `json:"sha_256_map_root_hash"`
+}
+
+// CredentialIndexEntry is the value of a credential ID of an issuer in the credential index, it points at the
+// latest log entry of the credential.
+type CredentialIndexEntry struct {
+	LeafIndex      int64  `json:"leaf_index"`
+	MerkleLeafHash []byte `json:"merkle_leaf_hash"`
+}
+
+// GetMapRootResponse represents the response to the get-map-root.
+type GetMapRootResponse struct {
+	TreeSize          uint64 `json:"tree_size"`
+	Timestamp         uint64 `json:"timestamp"`
+	SHA256MapRootHash []byte `json:"sha256_map_root_hash"`
+	MapRootSignature  []byte `json:"map_root_signature"`
+}
+
+// MapRootSignature keeps the data over which the signature of the credential index root is created.
+type MapRootSignature struct {
+	Version           Version       `json:"version"`
+	SignatureType     SignatureType `json:"signature_type"`
+	Timestamp         uint64        `json:"timestamp"`
+	TreeSize          uint64        `json:"tree_size"`
+	SHA256MapRootHash []byte        `json:"sha_256_map_root_hash"`
+}
+
+// GetCredentialStatusRequest represents the request to the get-credential-status.
+type GetCredentialStatusRequest struct {
+	Alias        string `json:"alias"`
+	Issuer       string `json:"issuer"`
+	CredentialID string `json:"credential_id"`
+}
+
+// Validate validates data.
+func (r *GetCredentialStatusRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.Issuer == "" {
+		return fmt.Errorf("%w: issuer is empty", errors.ErrValidation)
+	}
+
+	if r.CredentialID == "" {
+		return fmt.Errorf("%w: credential_id is empty", errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// GetCredentialStatusResponse represents the response to the get-credential-status.
+type GetCredentialStatusResponse struct {
+	Issuer       string               `json:"issuer"`
+	CredentialID string               `json:"credential_id"`
+	Entry        CredentialIndexEntry `json:"entry"`
+	AuditPath    *smt.Proof           `json:"audit_path"`
+	MapRoot      GetMapRootResponse   `json:"map_root"`
+}
+
 // SignatureAndHashAlgorithm provides information about the algorithm used for the signature.
 type SignatureAndHashAlgorithm struct {
 	Signature SignatureAlgorithm `json:"signature"`
@@ -223,6 +416,11 @@ type MerkleTreeLeaf struct {
 }
 
 // TimestampedEntry is part of the MerkleTreeLeaf structure.
+// VCEntry keeps the entry serialized by its LeafType: the credential for VCLogEntryType,
+// the RevocationEvent for RevocationLogEntryType, the STHAnchor for STHAnchorLogEntryType,
+// the Commitment for CommitmentLogEntryType, the MDocEntry for MDocLogEntryType,
+// the AnonCredsCommitment for AnonCredsLogEntryType and the PresentationEntry for PresentationLogEntryType.
+// Extensions keep the EntryExtensions of the entry, if any.
 type TimestampedEntry struct {
 	Timestamp  uint64       `json:"timestamp"`
 	EntryType  LogEntryType `json:"entry_type"`
@@ -230,6 +428,33 @@ type TimestampedEntry struct {
 	Extensions []byte       `json:"extensions"`
 }
 
+// EntryExtensions represents the extensions of a TimestampedEntry.
+type EntryExtensions struct {
+	// Supersedes is the merkle leaf hash of the credential the entry re-issues.
+	Supersedes []byte `json:"supersedes,omitempty"`
+	// TrustRegistry is the decision of the trust registry the issuer of the entry was checked against.
+	TrustRegistry *TrustDecision `json:"trust_registry,omitempty"`
+	// PolicyTags are the policy tags the entry was submitted with.
+	PolicyTags *PolicyTags `json:"policy_tags,omitempty"`
+}
+
+// PolicyTags are the policy tags of an entry, set by the submitter and validated against the schema of the
+// service (PolicyTagsSchema), e.g. for the regulators monitoring the issuers of their jurisdiction.
+type PolicyTags struct {
+	// Jurisdiction of the issuance, an ISO 3166-1 alpha-2 country code or an ISO 3166-2 subdivision code (e.g. CA
+	// or CA-QC).
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+	// AssuranceLevel of the issuance, e.g. substantial.
+	AssuranceLevel string `json:"assurance_level,omitempty"`
+}
+
+// TrustDecision is the decision of a trust registry on an issuer.
+type TrustDecision struct {
+	// Registry identifies the trust registry (e.g. its endpoint).
+	Registry   string `json:"registry"`
+	Authorized bool   `json:"authorized"`
+}
+
 // VCTimestampSignature keeps the data over which the signature is created.
 type VCTimestampSignature struct {
 	SVCTVersion   Version       `json:"svct_version"`
@@ -238,6 +463,9 @@ type VCTimestampSignature struct {
 	EntryType     LogEntryType  `json:"entry_type"`
 	VCEntry       []byte        `json:"vc_entry"`
 	Extensions    []byte        `json:"extensions"`
+	// SCTExtensions are the extensions of the SCT (see SCTExtensionType), not set unless the log is configured
+	// to add any.
+	SCTExtensions []SCTExtension `json:"sct_extensions,omitempty"`
 }
 
 // AddVCResponse represents the response to add-vc.
@@ -246,15 +474,351 @@ type AddVCResponse struct {
 	ID          []byte  `json:"id"`
 	Timestamp   uint64  `json:"timestamp"`
 	Extensions  string  `json:"extensions"`
-	Signature   []byte  `json:"signature"`
+	// SCTExtensions are the registered extensions of the SCT (see SCTExtensionType), signed.
+	SCTExtensions []SCTExtension `json:"sct_extensions,omitempty"`
+	Signature     []byte         `json:"signature"`
+	// TimeAttestation is set if the timestamp is derived from a trusted time source.
+	TimeAttestation *TimeAttestation `json:"time_attestation,omitempty"`
+	// WitnessCosignature is the cosignature of the anchored tree head by the witness key (add-anchor).
+	WitnessCosignature *Cosignature `json:"witness_cosignature,omitempty"`
+	// CID addresses the logged entry (EntryCID), it is not signed.
+	CID string `json:"cid,omitempty"`
+}
+
+// TimeAttestationRoughtime is the source of the attestations of Roughtime servers.
+const TimeAttestationRoughtime = "roughtime"
+
+// TimeAttestation is the signed response of the trusted time source the timestamp of an SCT is derived from:
+// the timestamp is the midpoint of the response plus the time elapsed since the response was received.
+type TimeAttestation struct {
+	// Source is the protocol of the time source, e.g. roughtime.
+	Source string `json:"source"`
+	// Server is the address of the time server.
+	Server string `json:"server"`
+	// Nonce of the request of the time.
+	Nonce []byte `json:"nonce"`
+	// Response is the response signed by the time server.
+	Response []byte `json:"response"`
+	// Midpoint is the time reported by the time server (ms).
+	Midpoint uint64 `json:"midpoint"`
+	// Radius is the uncertainty of the midpoint (ms).
+	Radius uint64 `json:"radius"`
 }
 
 // AddVCRequest represents the request to add-vc.
+// VCEntry is either a raw credential or an AddVCEnvelope.
 type AddVCRequest struct {
 	Alias   string `json:"alias"`
 	VCEntry []byte `json:"vc_entry"`
 }
 
+// AddVCEnvelope represents an enveloped add-vc submission.
+// The credential is either a JSON-LD credential or a JWT (JSON string).
+type AddVCEnvelope struct {
+	Credential json.RawMessage `json:"credential"`
+	Options    *AddVCOptions   `json:"options,omitempty"`
+}
+
+// AddVCOptions represents the options of an enveloped add-vc submission.
+type AddVCOptions struct {
+	// Tenant must match the log alias the submission is sent to.
+	Tenant string `json:"tenant,omitempty"`
+	// DryRun validates the credential without adding it to the log, no signature is returned.
+	DryRun bool `json:"dryRun,omitempty"`
+	// CallbackURL is notified with the add-vc response once the credential is queued.
+	CallbackURL string `json:"callbackURL,omitempty"`
+	// IdempotencyKey is used instead of the credential to deduplicate submissions, a submission of another credential
+	// with the key of a logged credential is rejected with a conflict.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+	// Supersedes is the merkle leaf hash of a logged credential the submission re-issues.
+	Supersedes []byte `json:"supersedes,omitempty"`
+	// PolicyTags are recorded in the extensions of the entry, they are validated against the schema of the service.
+	PolicyTags *PolicyTags `json:"policyTags,omitempty"`
+}
+
+// Validate validates data.
+func (o *AddVCOptions) Validate() error {
+	if o == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if o.Supersedes != nil && len(o.Supersedes) != sha256.Size {
+		return fmt.Errorf("%w: supersedes must be a %d-byte leaf hash", errors.ErrValidation, sha256.Size)
+	}
+
+	if o.CallbackURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(o.CallbackURL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: callbackURL %q must be an absolute http(s) URL", errors.ErrValidation, o.CallbackURL)
+	}
+
+	return nil
+}
+
+// RevocationEvent changes the status of a previously logged credential.
+type RevocationEvent struct {
+	// LeafHash is the merkle leaf hash of the logged credential.
+	LeafHash []byte           `json:"leaf_hash"`
+	Status   RevocationStatus `json:"status"`
+	Reason   string           `json:"reason,omitempty"`
+	// JWS is the event signed by the issuer of the credential, a JWT of the RevocationClaims. Its key (kid) is
+	// resolved through the VDR from the issuer (iss), which must be the issuer of the logged credential.
+	JWS string `json:"jws"`
+}
+
+// RevocationClaims are the claims of the JWS of a revocation event, they must match the event.
+type RevocationClaims struct {
+	Issuer   string           `json:"iss"`
+	LeafHash []byte           `json:"leaf_hash"`
+	Status   RevocationStatus `json:"status"`
+	Reason   string           `json:"reason,omitempty"`
+}
+
+// Validate validates data.
+func (e *RevocationEvent) Validate() error {
+	if e == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if len(e.LeafHash) != sha256.Size {
+		return fmt.Errorf("%w: leaf_hash must be %d bytes", errors.ErrValidation, sha256.Size)
+	}
+
+	switch e.Status {
+	case RevokedStatus, SuspendedStatus, ReinstatedStatus:
+	default:
+		return fmt.Errorf("%w: status %q is not supported", errors.ErrValidation, e.Status)
+	}
+
+	if e.JWS == "" {
+		return fmt.Errorf("%w: jws is empty", errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// AddRevocationRequest represents the request to add-revocation.
+type AddRevocationRequest struct {
+	Alias string          `json:"alias"`
+	Event RevocationEvent `json:"event"`
+}
+
+// GetIssuersRequest represents the request to get-issuers of a page of the issuers.
+type GetIssuersRequest struct {
+	Alias string `json:"alias"`
+	PageRequest
+	// PolicyTags (optional) returns the issuers of the entries matching the policy tags instead of the issuers of
+	// the log, in the order they first logged a matching entry.
+	PolicyTags
+}
+
+// GetIssuersResponse represents the response to get-issuers of a page of the issuers.
+type GetIssuersResponse struct {
+	Issuers       []string `json:"issuers"`
+	NextPageToken string   `json:"next_page_token,omitempty"`
+}
+
+// GetRevocationsRequest represents the request to get-revocations.
+type GetRevocationsRequest struct {
+	Alias    string `json:"alias"`
+	LeafHash []byte `json:"leaf_hash"`
+	PageRequest
+}
+
+// GetRevocationsResponse represents the response to get-revocations.
+type GetRevocationsResponse struct {
+	LeafHash      []byte             `json:"leaf_hash"`
+	Events        []RevocationRecord `json:"events"`
+	NextPageToken string             `json:"next_page_token,omitempty"`
+}
+
+// RevocationRecord is a logged revocation event.
+type RevocationRecord struct {
+	LeafIndex      int64           `json:"leaf_index"`
+	MerkleLeafHash []byte          `json:"merkle_leaf_hash"`
+	Timestamp      uint64          `json:"timestamp"`
+	Event          RevocationEvent `json:"event"`
+}
+
+// STHAnchor is a signed tree head of another transparency log anchored in the log.
+type STHAnchor struct {
+	// LogID is the SHA256 hash of the public key of the anchored log.
+	LogID     []byte         `json:"log_id"`
+	PublicKey []byte         `json:"public_key"`
+	STH       GetSTHResponse `json:"sth"`
+	// Consistency is the proof that the tree head is consistent with the latest tree head of the log
+	// anchored before, it is required if the tree has grown since.
+	Consistency [][]byte `json:"consistency,omitempty"`
+}
+
+// Validate validates data.
+func (a *STHAnchor) Validate() error {
+	if len(a.PublicKey) == 0 {
+		return fmt.Errorf("%w: public_key is empty", errors.ErrValidation)
+	}
+
+	logID := LogID(a.PublicKey)
+	if !bytes.Equal(a.LogID, logID[:]) {
+		return fmt.Errorf("%w: log_id does not match public_key", errors.ErrValidation)
+	}
+
+	if len(a.STH.SHA256RootHash) != sha256.Size {
+		return fmt.Errorf("%w: sha256_root_hash must be %d bytes", errors.ErrValidation, sha256.Size)
+	}
+
+	if len(a.STH.TreeHeadSignature) == 0 {
+		return fmt.Errorf("%w: tree_head_signature is empty", errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// AddAnchorRequest represents the request to add-anchor.
+type AddAnchorRequest struct {
+	Alias  string    `json:"alias"`
+	Anchor STHAnchor `json:"anchor"`
+}
+
+// GetAnchorsRequest represents the request to get-anchors.
+type GetAnchorsRequest struct {
+	Alias string `json:"alias"`
+	LogID []byte `json:"log_id"`
+	PageRequest
+}
+
+// GetAnchorsResponse represents the response to get-anchors.
+type GetAnchorsResponse struct {
+	LogID         []byte         `json:"log_id"`
+	Anchors       []AnchorRecord `json:"anchors"`
+	NextPageToken string         `json:"next_page_token,omitempty"`
+}
+
+// AnchorRecord is a logged STHAnchor.
+type AnchorRecord struct {
+	LeafIndex      int64     `json:"leaf_index"`
+	MerkleLeafHash []byte    `json:"merkle_leaf_hash"`
+	Timestamp      uint64    `json:"timestamp"`
+	Anchor         STHAnchor `json:"anchor"`
+}
+
+// Commitment is a hash-only commitment to data which is not disclosed to the log.
+type Commitment struct {
+	// Hash is the SHA256 hash of the committed data.
+	Hash []byte `json:"hash"`
+}
+
+// Validate validates data.
+func (c *Commitment) Validate() error {
+	if len(c.Hash) != sha256.Size {
+		return fmt.Errorf("%w: hash must be %d bytes", errors.ErrValidation, sha256.Size)
+	}
+
+	return nil
+}
+
+// AnonCredsCommitment is a commitment to an AnonCreds credential which does not disclose its attribute values.
+type AnonCredsCommitment struct {
+	SchemaID  string `json:"schema_id"`
+	CredDefID string `json:"cred_def_id"`
+	// BlindedAttributes are the blinded commitments to the attribute values by attribute name.
+	BlindedAttributes map[string][]byte `json:"blinded_attributes"`
+}
+
+// Validate validates data.
+func (c *AnonCredsCommitment) Validate() error {
+	if !isAnonCredsSchemaID(c.SchemaID) {
+		return fmt.Errorf("%w: schema_id %q is not an AnonCreds schema identifier", errors.ErrValidation, c.SchemaID)
+	}
+
+	if !isAnonCredsCredDefID(c.CredDefID) {
+		return fmt.Errorf("%w: cred_def_id %q is not an AnonCreds credential definition identifier",
+			errors.ErrValidation, c.CredDefID)
+	}
+
+	if len(c.BlindedAttributes) == 0 {
+		return fmt.Errorf("%w: blinded_attributes is empty", errors.ErrValidation)
+	}
+
+	for name, commitment := range c.BlindedAttributes {
+		if name == "" || len(commitment) == 0 {
+			return fmt.Errorf("%w: blinded attribute must have a name and a commitment", errors.ErrValidation)
+		}
+	}
+
+	return nil
+}
+
+// isAnonCredsSchemaID returns true if the identifier is a URI (e.g. did:indy)
+// or a legacy Indy schema identifier <did>:2:<name>:<version>.
+func isAnonCredsSchemaID(id string) bool {
+	p := strings.Split(id, ":")
+
+	return isAnonCredsURI(id) || len(p) == 4 && p[0] != "" && p[1] == "2" && p[2] != "" && p[3] != "" // nolint: gomnd
+}
+
+// isAnonCredsCredDefID returns true if the identifier is a URI (e.g. did:indy)
+// or a legacy Indy credential definition identifier <did>:3:CL:<schema>:<tag>.
+func isAnonCredsCredDefID(id string) bool {
+	p := strings.Split(id, ":")
+
+	return isAnonCredsURI(id) || len(p) >= 5 && p[0] != "" && p[1] == "3" && p[2] == "CL" // nolint: gomnd
+}
+
+func isAnonCredsURI(id string) bool {
+	return strings.HasPrefix(id, "did:") || strings.Contains(id, "://")
+}
+
+// MDoc is a submitted ISO/IEC 18013-5 mobile document (mdoc).
+type MDoc struct {
+	// Document is the CBOR-encoded Document or IssuerSigned structure.
+	Document []byte `json:"document"`
+}
+
+// MDocEntry is the logged form of an mdoc: the mobile security object signed by its issuer.
+// The data elements of the mdoc are not logged, the mobile security object commits to them by their digests.
+type MDocEntry struct {
+	DocType string `json:"doc_type"`
+	// IssuerAuth is the deterministically encoded COSE_Sign1 of the issuer over the mobile security object.
+	IssuerAuth []byte `json:"issuer_auth"`
+}
+
+// Presentation is a submitted verifiable presentation signed by its holder.
+type Presentation struct {
+	// Presentation is the presentation with an embedded proof (JSON-LD) or a JWT presentation (JSON string).
+	Presentation json.RawMessage `json:"presentation"`
+}
+
+// PresentationEntry is the logged form of a presentation: its metadata and the presentation as signed by its holder.
+type PresentationEntry struct {
+	Holder string   `json:"holder"`
+	ID     string   `json:"id,omitempty"`
+	Types  []string `json:"types,omitempty"`
+	// Verifier is the domain of the proof of the presentation or the audience of the JWT.
+	Verifier string `json:"verifier,omitempty"`
+	// Challenge is the challenge of the proof of the presentation or the nonce of the JWT.
+	Challenge    string                `json:"challenge,omitempty"`
+	Credentials  []PresentedCredential `json:"credentials,omitempty"`
+	Presentation json.RawMessage       `json:"presentation"`
+}
+
+// PresentedCredential is the metadata of a credential of a logged presentation.
+type PresentedCredential struct {
+	ID     string   `json:"id,omitempty"`
+	Types  []string `json:"types,omitempty"`
+	Issuer string   `json:"issuer,omitempty"`
+}
+
+// AddEntryRequest represents the request to add-entry.
+// Entry is validated and serialized by the leaf type registered for EntryType.
+type AddEntryRequest struct {
+	Alias     string          `json:"alias"`
+	EntryType LogEntryType    `json:"entry_type"`
+	Entry     json.RawMessage `json:"entry"`
+}
+
 // WebFingerResponse web finger response.
 type WebFingerResponse struct {
 	Subject    string                 `json:"subject,omitempty"`
@@ -268,3 +832,1017 @@ type WebFingerLink struct {
 	Type string `json:"type,omitempty"`
 	Href string `json:"href,omitempty"`
 }
+
+// GetCredentialHistoryRequest represents the request to get-credential-history.
+type GetCredentialHistoryRequest struct {
+	Alias    string `json:"alias"`
+	LeafHash []byte `json:"leaf_hash"`
+}
+
+// GetEntryByCIDRequest represents the request to get an entry by its CID.
+type GetEntryByCIDRequest struct {
+	Alias string `json:"alias"`
+	CID   string `json:"cid"`
+}
+
+// GetEntryByCIDResponse is the first logged entry addressed by a CID.
+type GetEntryByCIDResponse struct {
+	CID            string `json:"cid"`
+	LeafIndex      int64  `json:"leaf_index"`
+	MerkleLeafHash []byte `json:"merkle_leaf_hash"`
+	// Entry is the logged form of the entry (TimestampedEntry.VCEntry) the CID is computed over.
+	Entry     []byte `json:"entry"`
+	LeafInput []byte `json:"leaf_input"`
+	ExtraData []byte `json:"extra_data"`
+}
+
+// GetReceiptRequest represents the request to get-receipt.
+type GetReceiptRequest struct {
+	Alias          string `json:"alias"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// Validate validates data.
+func (r *GetReceiptRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.IdempotencyKey == "" {
+		return fmt.Errorf("%w: idempotency key is empty", errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// GetTileRequest represents the request to get a tile of the tree. The tile at the level L and index N holds
+// the hashes of the nodes [N*TileWidth, N*TileWidth+Width) at the level L*TileHeight of the tree.
+type GetTileRequest struct {
+	Alias string `json:"alias"`
+	Level int    `json:"level"`
+	Index int64  `json:"index"`
+	// Width of a partial tile, zero for a full tile.
+	Width int `json:"width,omitempty"`
+}
+
+// Validate validates data.
+func (r *GetTileRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.Level < 0 || r.Level > MaxTileLevel {
+		return fmt.Errorf("%w: tile level %d is not within [0,%d]", errors.ErrValidation, r.Level, MaxTileLevel)
+	}
+
+	return validateTile(r.Index, r.Width)
+}
+
+// GetEntryBundleRequest represents the request to get the bundle of the entries
+// [Index*TileWidth, Index*TileWidth+Width), the leaves of the tile at the level 0 and the same index.
+type GetEntryBundleRequest struct {
+	Alias string `json:"alias"`
+	Index int64  `json:"index"`
+	// Width of a partial bundle, zero for a full bundle.
+	Width int `json:"width,omitempty"`
+}
+
+// Validate validates data.
+func (r *GetEntryBundleRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	return validateTile(r.Index, r.Width)
+}
+
+func validateTile(index int64, width int) error {
+	if index < 0 {
+		return fmt.Errorf("%w: tile index %d is negative", errors.ErrValidation, index)
+	}
+
+	if width < 0 || width >= TileWidth {
+		return fmt.Errorf("%w: width %d of a partial tile is not within [1,%d]", errors.ErrValidation, width,
+			TileWidth-1)
+	}
+
+	return nil
+}
+
+// GetCredentialHistoryResponse represents the response to get-credential-history.
+// Entries is the chain of re-issued credentials the leaf hash belongs to, oldest first.
+type GetCredentialHistoryResponse struct {
+	Entries []CredentialHistoryEntry `json:"entries"`
+}
+
+// CredentialHistoryEntry represents a logged credential in the chain of re-issued credentials.
+type CredentialHistoryEntry struct {
+	LeafIndex      int64              `json:"leaf_index"`
+	MerkleLeafHash []byte             `json:"merkle_leaf_hash"`
+	Timestamp      uint64             `json:"timestamp"`
+	CredentialID   string             `json:"credential_id,omitempty"`
+	Supersedes     []byte             `json:"supersedes,omitempty"`
+	Revocations    []RevocationRecord `json:"revocations"`
+}
+
+// GetShadowStatusResponse represents the response to get-shadow-status.
+type GetShadowStatusResponse struct {
+	// Mirrored is the number of leaves mirrored to the shadow log matching the primary.
+	Mirrored uint64 `json:"mirrored"`
+	// Failed is the number of leaves the shadow log failed to accept.
+	Failed uint64 `json:"failed"`
+	// Diverged is the number of leaves the shadow log accepted differently from the primary.
+	Diverged        uint64             `json:"diverged"`
+	PrimaryTreeSize int64              `json:"primary_tree_size"`
+	ShadowTreeSize  int64              `json:"shadow_tree_size"`
+	Divergences     []ShadowDivergence `json:"divergences"`
+}
+
+// ShadowDivergence describes a leaf the shadow log failed to accept or accepted differently from the primary.
+type ShadowDivergence struct {
+	Timestamp        uint64 `json:"timestamp"`
+	LeafIdentityHash []byte `json:"leaf_identity_hash"`
+	Reason           string `json:"reason"`
+}
+
+// ReadOnlyStatus represents the read-only (maintenance) mode of the service.
+type ReadOnlyStatus struct {
+	ReadOnly bool `json:"read_only"`
+}
+
+// SignedCompromiseStatement represents the request to mark-compromised, it is published in the webfinger
+// metadata of a compromised log.
+type SignedCompromiseStatement struct {
+	Statement CompromiseStatement `json:"statement"`
+	// Signature is the DigitallySigned signature of the statement by the recovery key of the log.
+	Signature []byte `json:"signature"`
+}
+
+// CompromiseStatement states that the key of the log is compromised.
+type CompromiseStatement struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	Timestamp     uint64        `json:"timestamp"`
+	LogID         []byte        `json:"log_id"`
+	// CompromisedSince is the timestamp (ms) from which SCTs and tree heads of the log are not trusted.
+	CompromisedSince uint64 `json:"compromised_since"`
+	Reason           string `json:"reason,omitempty"`
+}
+
+// FreezeLogRequest represents the request to freeze-log.
+type FreezeLogRequest struct {
+	Alias  string `json:"alias"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// IPFSRoot is the latest root of the mirror of a log on IPFS, the DAG-JSON manifest linking the checkpoint (a
+// GetSTHResponse) and the resources of the log, advertised in the webfinger metadata of the log.
+type IPFSRoot struct {
+	CID      string `json:"cid"`
+	TreeSize uint64 `json:"tree_size"`
+}
+
+// SignedFinalTreeHead is the attestation of the final tree head of a frozen log, it is served permanently and
+// published in the webfinger metadata of the log.
+type SignedFinalTreeHead struct {
+	Statement FinalTreeHead `json:"statement"`
+	// Signature is the DigitallySigned signature of the statement by the key of the log.
+	Signature []byte `json:"signature"`
+}
+
+// FinalTreeHead states that the log is frozen at the tree head: it accepts no more entries and the proofs of
+// tree sizes beyond the final size are not valid.
+type FinalTreeHead struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	// Timestamp is the timestamp (ms) the log was frozen at.
+	Timestamp uint64         `json:"timestamp"`
+	LogID     []byte         `json:"log_id"`
+	Alias     string         `json:"alias"`
+	STH       GetSTHResponse `json:"sth"`
+	Reason    string         `json:"reason,omitempty"`
+}
+
+// LogPolicy is the policy of a log published by its operator (PublishPolicy).
+type LogPolicy struct {
+	// MMD is the maximum merge delay in seconds: the time an entry is included in the tree within once its SCT
+	// is issued.
+	MMD uint64 `json:"mmd"`
+	// Retention is the time the entries are kept for, e.g. an ISO 8601 duration or "indefinite".
+	Retention string `json:"retention,omitempty"`
+	// AcceptedIssuers are the issuers the log accepts the credentials of, any issuer if empty.
+	AcceptedIssuers []string `json:"accepted_issuers,omitempty"`
+	// AcceptedTypes are the credential types the log accepts, any type if empty.
+	AcceptedTypes []string `json:"accepted_types,omitempty"`
+	// Contact of the operator, e.g. a mailto URI.
+	Contact string `json:"contact"`
+	// IncidentProcess describes or links (URL) the process the operator follows on an incident.
+	IncidentProcess string `json:"incident_process,omitempty"`
+}
+
+// PublishPolicyRequest represents the request to publish the next version of the policy of a log.
+type PublishPolicyRequest struct {
+	Alias  string    `json:"alias"`
+	Policy LogPolicy `json:"policy"`
+}
+
+// Validate validates data.
+func (r *PublishPolicyRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.Policy.MMD == 0 {
+		return fmt.Errorf("%w: mmd value must be greater than zero", errors.ErrValidation)
+	}
+
+	if r.Policy.Contact == "" {
+		return fmt.Errorf("%w: contact value is required", errors.ErrValidation)
+	}
+
+	return nil
+}
+
+// GetPolicyRequest represents the request to get a version of the policy of a log.
+type GetPolicyRequest struct {
+	Alias string `json:"alias"`
+	// Version of the policy, the latest version if zero.
+	Version uint64 `json:"version,omitempty"`
+}
+
+// GetPolicyHistoryResponse represents the response to get the policy history of a log.
+type GetPolicyHistoryResponse struct {
+	// Policies are the published versions of the policy, oldest first.
+	Policies []SignedPolicyDocument `json:"policies"`
+}
+
+// SignedPolicyDocument is a version of the policy of a log signed by the key of the log.
+type SignedPolicyDocument struct {
+	Statement PolicyDocument `json:"statement"`
+	// Signature is the DigitallySigned signature of the statement by the key of the log.
+	Signature []byte `json:"signature"`
+}
+
+// PolicyDocument states the policy of a log from its timestamp on. The versions of the policy form a hash chain,
+// so the history of the policy can't be rewritten.
+type PolicyDocument struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	// Timestamp is the timestamp (ms) the version was published at.
+	Timestamp uint64 `json:"timestamp"`
+	LogID     []byte `json:"log_id"`
+	Alias     string `json:"alias"`
+	// PolicyVersion is the version of the policy, starting at 1.
+	PolicyVersion uint64 `json:"policy_version"`
+	// Previous is the hash of the previous version (PolicyDocumentHash), empty for the first version.
+	Previous []byte    `json:"previous,omitempty"`
+	Policy   LogPolicy `json:"policy"`
+}
+
+// RetiredShard is the metadata of a retired (frozen) log of a tenant kept in the archive of the service, so the
+// SCTs and the proofs of the log can be verified once the log is no longer served or the service signs with
+// another key.
+type RetiredShard struct {
+	Alias  string `json:"alias"`
+	Tenant string `json:"tenant"`
+	// LogID is the ID of the log (the SHA-256 hash of its public key), the ID of the SCTs issued by the log.
+	LogID []byte `json:"log_id"`
+	// PublicKey is the public key the log signed its SCTs and tree heads with.
+	PublicKey []byte `json:"public_key"`
+	// FinalTreeHead is the final tree head of the log signed by its key.
+	FinalTreeHead SignedFinalTreeHead `json:"final_tree_head"`
+}
+
+// GetRetiredShardsRequest represents the request to get-retired-shards, the shards of all tenants are returned if
+// no tenant is set.
+type GetRetiredShardsRequest struct {
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// GetRetiredShardsResponse represents the response to get-retired-shards, the shards in the order they were
+// retired.
+type GetRetiredShardsResponse struct {
+	Shards []RetiredShard `json:"shards"`
+}
+
+// SignedAnnotation is the annotation of an entry of the log by an auditor, it is served with the entry.
+type SignedAnnotation struct {
+	Annotation Annotation `json:"annotation"`
+	// Signature is the DigitallySigned signature of the annotation by the key of the auditor.
+	Signature []byte `json:"signature"`
+}
+
+// Annotation is a statement of an auditor about an entry of the log, e.g. that the credential was reported
+// fraudulent.
+type Annotation struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	Timestamp     uint64        `json:"timestamp"`
+	LogID         []byte        `json:"log_id"`
+	LeafIndex     int64         `json:"leaf_index"`
+	// Auditor is the identity the public key of the auditor is registered for.
+	Auditor string `json:"auditor"`
+	// Kind of the annotation, e.g. AnnotationFraudulent.
+	Kind    string `json:"kind"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// AnnotationFraudulent is the kind of the annotation of a credential reported fraudulent.
+const AnnotationFraudulent = "fraudulent"
+
+// AddAnnotationRequest represents the request to add-annotation.
+type AddAnnotationRequest struct {
+	Alias      string           `json:"alias"`
+	Annotation SignedAnnotation `json:"annotation"`
+}
+
+// GetAnnotationsRequest represents the request to get-annotations.
+type GetAnnotationsRequest struct {
+	Alias     string `json:"alias"`
+	LeafIndex int64  `json:"leaf_index"`
+}
+
+// GetAnnotationsResponse represents the response to get-annotations.
+type GetAnnotationsResponse struct {
+	LeafIndex   int64              `json:"leaf_index"`
+	Annotations []SignedAnnotation `json:"annotations"`
+}
+
+// AnnotationEvent is posted to the annotation subscribers once an annotation is added.
+type AnnotationEvent struct {
+	Alias      string           `json:"alias"`
+	Annotation SignedAnnotation `json:"annotation"`
+}
+
+// KeyMetadata describes a signing key of the log and the purpose it serves, it is published in the webfinger
+// metadata.
+type KeyMetadata struct {
+	Purpose KeyPurpose `json:"purpose"`
+	// KeyID is the SHA-256 hash of the public key (the log ID for the key of the log).
+	KeyID     []byte                    `json:"key_id"`
+	PublicKey []byte                    `json:"public_key"`
+	Algorithm SignatureAndHashAlgorithm `json:"algorithm"`
+}
+
+// ResponseStatement is the statement signed by the response and admin keys: the body of the response to the
+// request URI.
+type ResponseStatement struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	Timestamp     uint64        `json:"timestamp"`
+	RequestURI    string        `json:"request_uri"`
+	SHA256Body    []byte        `json:"sha256_body"`
+}
+
+// SignedResponse is the signature of a response, it is sent in the response headers.
+type SignedResponse struct {
+	// KeyID is the SHA-256 hash of the public key of the signing key.
+	KeyID     []byte
+	Timestamp uint64
+	// Signature is the DigitallySigned signature of the ResponseStatement.
+	Signature []byte
+}
+
+// CosignedTreeHead is the statement of the witness key that it has seen the tree head of an anchored log.
+type CosignedTreeHead struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	Timestamp     uint64        `json:"timestamp"`
+	// LogID is the ID of the anchored log.
+	LogID             []byte `json:"log_id"`
+	TreeHeadTimestamp uint64 `json:"tree_head_timestamp"`
+	TreeSize          uint64 `json:"tree_size"`
+	SHA256RootHash    []byte `json:"sha256_root_hash"`
+}
+
+// Cosignature is the cosignature of an anchored tree head.
+type Cosignature struct {
+	Statement CosignedTreeHead `json:"statement"`
+	// Signature is the DigitallySigned signature of the statement by the witness key.
+	Signature []byte `json:"signature"`
+}
+
+// SignedVerificationSnapshot is the verification snapshot of a log, it is served to the verification widgets.
+type SignedVerificationSnapshot struct {
+	Snapshot VerificationSnapshot `json:"snapshot"`
+	// Signature is the DigitallySigned signature of the snapshot by the key of the log.
+	Signature []byte `json:"signature"`
+}
+
+// VerificationSnapshot is what a verification widget needs to check the SCTs and proofs of the log: the latest
+// tree head, the keys of the log and the metadata of the operator.
+type VerificationSnapshot struct {
+	Version       Version       `json:"version"`
+	SignatureType SignatureType `json:"signature_type"`
+	Timestamp     uint64        `json:"timestamp"`
+	// NextUpdate is the timestamp (ms) the snapshot is replaced at, it may be cached until then.
+	NextUpdate uint64         `json:"next_update"`
+	Alias      string         `json:"alias"`
+	LogID      []byte         `json:"log_id"`
+	URL        string         `json:"url"`
+	STH        GetSTHResponse `json:"sth"`
+	Keys       []KeyMetadata  `json:"keys"`
+	// Compromise is the compromise statement of the log, nil unless the key of the log is marked compromised.
+	Compromise *SignedCompromiseStatement `json:"compromise,omitempty"`
+	// Final is the final tree head of the log, nil unless the log is frozen.
+	Final    *SignedFinalTreeHead `json:"final,omitempty"`
+	Metadata map[string]string    `json:"metadata,omitempty"`
+}
+
+// GetKeyUsageResponse represents the response to get-key-usage.
+type GetKeyUsageResponse struct {
+	// LogID identifies the key of the log.
+	LogID []byte     `json:"log_id"`
+	Usage []KeyUsage `json:"usage"`
+}
+
+// KeyUsage is the count of the signatures of a kind produced since the start.
+type KeyUsage struct {
+	Kind       SignatureKind `json:"kind"`
+	Signatures uint64        `json:"signatures"`
+	// LastUsed is the timestamp (ms) of the last signature, zero if the key has not signed the kind.
+	LastUsed uint64 `json:"last_used,omitempty"`
+	// Window is the number of signatures within the current window (a minute).
+	Window uint64 `json:"window"`
+	// Threshold is the max number of signatures within a window, zero if there is no threshold.
+	Threshold uint64 `json:"threshold,omitempty"`
+	// Anomalies is the number of windows the threshold was exceeded in.
+	Anomalies uint64 `json:"anomalies"`
+}
+
+// GetUsageRequest represents the request to get-usage, the usage of all tenants is returned if no tenant is set.
+type GetUsageRequest struct {
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// GetUsageResponse represents the response to get-usage.
+type GetUsageResponse struct {
+	// Since is the timestamp (ms) the usage is counted from (the start of the service).
+	Since   uint64        `json:"since"`
+	Tenants []TenantUsage `json:"tenants"`
+}
+
+// TenantUsage is the usage of the logs of a tenant.
+type TenantUsage struct {
+	Tenant string     `json:"tenant"`
+	Logs   []LogUsage `json:"logs"`
+}
+
+// LogUsage is the count of the entries and the requests of a log.
+type LogUsage struct {
+	Alias string `json:"alias"`
+	// Entries is the number of entries logged (credentials, revocations, anchors and other entries).
+	Entries uint64 `json:"entries"`
+	// Duplicates is the number of submissions of entries logged before, they are not logged again.
+	Duplicates uint64 `json:"duplicates"`
+	// EntryBytes is the size of the logged entries.
+	EntryBytes uint64 `json:"entry_bytes"`
+	Reads      uint64 `json:"reads"`
+	Writes     uint64 `json:"writes"`
+	// Errors is the number of the reads and the writes which failed.
+	Errors uint64 `json:"errors"`
+}
+
+// GetSubmissionStatsRequest represents the request to get-submission-stats, the stats of the issuers of all the
+// logs are returned if no alias is set.
+type GetSubmissionStatsRequest struct {
+	Alias  string `json:"alias,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	// SortBy is the dimension the issuers are ranked by (the 95th percentile, descending): SubmissionSize,
+	// SubmissionContexts or SubmissionProcessingTime (the default).
+	SortBy string `json:"sort_by,omitempty"`
+	// Limit is the max number of issuers returned (defaults to 10).
+	Limit int `json:"limit,omitempty"`
+}
+
+// GetPendingSubmissionsRequest represents the request to get-pending-submissions.
+type GetPendingSubmissionsRequest struct {
+	// Alias (optional) of the log, all the logs are listed if not set.
+	Alias string `json:"alias,omitempty"`
+	// Limit is the max number of submissions listed per log (defaults to 100), the counts cover all of them.
+	Limit int `json:"limit,omitempty"`
+}
+
+// GetPendingSubmissionsResponse represents the response to get-pending-submissions.
+type GetPendingSubmissionsResponse struct {
+	Logs []LogPendingSubmissions `json:"logs"`
+}
+
+// LogPendingSubmissions are the submissions queued to a log by the instance which are not sequenced yet.
+type LogPendingSubmissions struct {
+	Alias string `json:"alias"`
+	// TreeSize is the size of the tree the sequenced submissions are matched up to.
+	TreeSize int64 `json:"tree_size"`
+	// Pending is the number of the pending submissions.
+	Pending int `json:"pending"`
+	// Untracked is the number of the submissions queued while the max number of pending submissions (10000) was
+	// tracked, they are neither listed nor counted as pending.
+	Untracked uint64 `json:"untracked,omitempty"`
+	// OldestAge is the age (ms) of the oldest pending submission.
+	OldestAge uint64 `json:"oldest_age"`
+	// ByAge counts the pending submissions by age, the last count has no max age.
+	ByAge []PendingAgeCount `json:"by_age"`
+	// Submissions are the pending submissions, oldest first.
+	Submissions []PendingSubmission `json:"submissions"`
+}
+
+// PendingAgeCount is the number of the pending submissions younger than the max age (e.g. 10m0s).
+type PendingAgeCount struct {
+	MaxAge string `json:"max_age,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// PendingSubmission is a leaf queued to the log which is not sequenced yet.
+type PendingSubmission struct {
+	LeafIdentityHash []byte `json:"leaf_identity_hash"`
+	MerkleLeafHash   []byte `json:"merkle_leaf_hash"`
+	// QueuedAt is the timestamp (ms) the leaf was queued at.
+	QueuedAt uint64 `json:"queued_at"`
+	// Age (ms) of the submission.
+	Age uint64 `json:"age"`
+	// Size of the leaf value.
+	Size int `json:"size"`
+}
+
+// GetUnmergedSubmissionsResponse represents the response to get-unmerged-submissions: the submissions queued to
+// the log by the instance which are not sequenced within the TTL, oldest first.
+type GetUnmergedSubmissionsResponse struct {
+	// TTL (ms) of the submissions.
+	TTL uint64 `json:"ttl"`
+	// TreeSize is the size of the tree the sequenced submissions are matched up to.
+	TreeSize    int64                `json:"tree_size"`
+	Submissions []UnmergedSubmission `json:"submissions"`
+}
+
+// UnmergedSubmission is a leaf queued to the log which is not sequenced within the TTL.
+type UnmergedSubmission struct {
+	LeafIdentityHash []byte `json:"leaf_identity_hash"`
+	MerkleLeafHash   []byte `json:"merkle_leaf_hash"`
+	// QueuedAt is the timestamp (ms) the leaf was queued at.
+	QueuedAt uint64 `json:"queued_at"`
+	// Age (ms) of the submission.
+	Age uint64 `json:"age"`
+	// Requeues is the number of times the leaf was re-queued and RequeuedAt the timestamp (ms) of the latest one.
+	Requeues   int    `json:"requeues,omitempty"`
+	RequeuedAt uint64 `json:"requeued_at,omitempty"`
+}
+
+// GetSubmissionStatsResponse represents the response to get-submission-stats.
+type GetSubmissionStatsResponse struct {
+	// Since is the timestamp (ms) the submissions are recorded from (the start of the service).
+	Since   uint64                  `json:"since"`
+	Issuers []IssuerSubmissionStats `json:"issuers"`
+}
+
+// IssuerSubmissionStats are the stats of the credentials submitted by an issuer to a log, the percentiles are
+// computed over the latest submissions.
+type IssuerSubmissionStats struct {
+	Alias       string `json:"alias"`
+	Issuer      string `json:"issuer"`
+	Submissions uint64 `json:"submissions"`
+	// Rejected is the number of submissions the credential of which could not be parsed or verified.
+	Rejected uint64 `json:"rejected"`
+	// Bytes is the size of the submitted credentials.
+	Bytes uint64 `json:"bytes"`
+	// LastSeen is the timestamp (ms) of the latest submission.
+	LastSeen uint64 `json:"last_seen"`
+	// Size is the size of the credentials in bytes.
+	Size Percentiles `json:"size"`
+	// Contexts is the number of the JSON-LD contexts of the credentials.
+	Contexts Percentiles `json:"contexts"`
+	// ProcessingTime is the time (ms) to parse the credentials, canonicalize them and verify their proofs.
+	ProcessingTime Percentiles `json:"processing_time"`
+}
+
+// Percentiles of a distribution.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// GetTreeHeadSLARequest represents the request to get-tree-head-sla, the report covers all the logs if no alias
+// is set.
+type GetTreeHeadSLARequest struct {
+	Alias string `json:"alias,omitempty"`
+	// Format of the report: TreeHeadSLAFormatJSON (TreeHeadSLAReport, the default) or TreeHeadSLAFormatCSV (a row
+	// per log and window).
+	Format string `json:"format,omitempty"`
+}
+
+// TreeHeadSLAReport is the report of the publication of the tree heads of the logs versus the schedule.
+type TreeHeadSLAReport struct {
+	// Timestamp (ms) the report is generated at, the windows end then.
+	Timestamp uint64 `json:"timestamp"`
+	// Interval (ms) of the schedule: a new tree head must be published at least every interval.
+	Interval uint64 `json:"interval"`
+	// Since is the timestamp (ms) the tree heads are monitored from (the start of the service), the windows start
+	// at the earliest then.
+	Since uint64           `json:"since"`
+	Logs  []LogTreeHeadSLA `json:"logs"`
+}
+
+// LogTreeHeadSLA is the publication of the tree heads of a log over the rolling windows.
+type LogTreeHeadSLA struct {
+	Alias   string              `json:"alias"`
+	Windows []TreeHeadSLAWindow `json:"windows"`
+}
+
+// TreeHeadSLAWindow is the publication of the tree heads of a log over a rolling window.
+type TreeHeadSLAWindow struct {
+	// Window is the length of the window (e.g. 24h).
+	Window string `json:"window"`
+	// From and To are the timestamps (ms) of the start and of the end of the window.
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	// TreeHeads is the number of tree heads published in the window.
+	TreeHeads int `json:"tree_heads"`
+	// ExpectedTreeHeads is the number of tree heads the schedule requires in the window.
+	ExpectedTreeHeads int `json:"expected_tree_heads"`
+	// MaxGap is the longest time (ms) without a new tree head.
+	MaxGap uint64 `json:"max_gap"`
+	// Violations is the number of times no new tree head was published within the interval.
+	Violations int `json:"violations"`
+	// Compliance is the fraction of the window the latest tree head was at most the interval old.
+	Compliance float64 `json:"compliance"`
+}
+
+// GetReconciliationRequest represents the request to get-reconciliation, the report covers all the logs if no
+// alias is set.
+type GetReconciliationRequest struct {
+	Alias string `json:"alias,omitempty"`
+	// Refresh reconciles the logs instead of returning the latest reconciliation.
+	Refresh bool `json:"refresh,omitempty"`
+}
+
+// ReconciliationReport is the reconciliation of the counters of the logs kept by the instance with the leaves
+// sequenced by Trillian, to catch the writes lost silently.
+type ReconciliationReport struct {
+	// Timestamp (ms) the logs were reconciled at.
+	Timestamp uint64 `json:"timestamp"`
+	// Since is the timestamp (ms) the submissions are counted from (the start of the service).
+	Since uint64 `json:"since"`
+	// Drift is set if any of the logs drifts.
+	Drift bool                `json:"drift"`
+	Logs  []LogReconciliation `json:"logs"`
+}
+
+// LogReconciliation is the reconciliation of a log: each submission accepted by the instance is either sequenced
+// or pending, the dedup store holds at most the leaves of the log and the index covers the tree.
+type LogReconciliation struct {
+	Alias string `json:"alias"`
+	// TreeSize is the number of leaves sequenced by Trillian.
+	TreeSize int64 `json:"tree_size"`
+	// ShrunkFrom is the tree size of the previous reconciliation if the tree shrank since.
+	ShrunkFrom int64 `json:"shrunk_from,omitempty"`
+	// Accepted is the number of new entries accepted by the instance since the start.
+	Accepted uint64 `json:"accepted"`
+	// Sequenced is the number of the accepted entries which were matched in the tree.
+	Sequenced uint64 `json:"sequenced"`
+	// Pending is the number of the accepted entries which are not sequenced yet.
+	Pending uint64 `json:"pending"`
+	// Stale is the number of the pending entries accepted longer ago than the stale age.
+	Stale uint64 `json:"stale"`
+	// Unaccounted is the number of the accepted entries which are neither sequenced nor pending.
+	Unaccounted uint64 `json:"unaccounted"`
+	// DedupEntries is the number of leaves of the log in the dedup store (zero if there is no dedup store) and
+	// DedupDrift the number of them beyond the leaves of the log, sequenced or pending. The pending leaves of the
+	// other instances sharing the store are not known, the drift is approximate while they have some.
+	DedupEntries uint64 `json:"dedup_entries,omitempty"`
+	DedupDrift   uint64 `json:"dedup_drift,omitempty"`
+	// IndexedLeaves is the number of leaves of the log indexed and IndexedEntries the number of distinct entries of
+	// the index, IndexLag is the number of leaves of the tree which are not indexed.
+	IndexedLeaves  int64 `json:"indexed_leaves"`
+	IndexedEntries int   `json:"indexed_entries"`
+	IndexLag       int64 `json:"index_lag"`
+	// Drift is set if any count drifts or the log could not be reconciled (see Error).
+	Drift bool   `json:"drift"`
+	Error string `json:"error,omitempty"`
+}
+
+// GetAuditExportRequest represents the request to get-audit-export.
+// The export covers the entries added while the tree grew from FirstTreeSize to SecondTreeSize.
+type GetAuditExportRequest struct {
+	Alias          string `json:"alias"`
+	FirstTreeSize  int64  `json:"first_tree_size"`
+	SecondTreeSize int64  `json:"second_tree_size"`
+}
+
+// Validate validates data.
+func (r *GetAuditExportRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.FirstTreeSize < 0 || r.FirstTreeSize >= r.SecondTreeSize {
+		return fmt.Errorf("%w: first_tree_size %d and second_tree_size %d values is not a valid range",
+			errors.ErrValidation, r.FirstTreeSize, r.SecondTreeSize,
+		)
+	}
+
+	if r.SecondTreeSize-r.FirstTreeSize > MaxAuditExportRange {
+		return fmt.Errorf("%w: range of first_tree_size %d and second_tree_size %d exceeds %d entries",
+			errors.ErrValidation, r.FirstTreeSize, r.SecondTreeSize, MaxAuditExportRange,
+		)
+	}
+
+	return nil
+}
+
+// GetAuditExportResponse represents the response to get-audit-export.
+type GetAuditExportResponse struct {
+	Export AuditExport `json:"export"`
+	// Signature is the DigitallySigned signature of the export by the log.
+	Signature []byte `json:"signature"`
+}
+
+// AuditExport is the signed, timestamped extract of a tree-size range of a log.
+type AuditExport struct {
+	Version        Version       `json:"version"`
+	SignatureType  SignatureType `json:"signature_type"`
+	Timestamp      uint64        `json:"timestamp"`
+	Alias          string        `json:"alias"`
+	LogID          []byte        `json:"log_id"`
+	FirstTreeSize  int64         `json:"first_tree_size"`
+	SecondTreeSize int64         `json:"second_tree_size"`
+	// Entries are the leaves in [FirstTreeSize, SecondTreeSize) with their audit paths in the tree of SecondTreeSize.
+	Entries []AuditEntry `json:"entries"`
+	// TreeHeads is the chain of tree heads from FirstTreeSize (unless zero) through SecondTreeSize to the STH,
+	// each tree head is proven consistent with the previous one.
+	TreeHeads []AuditTreeHead `json:"tree_heads"`
+	// STH is the signed tree head the chain ends with.
+	STH GetSTHResponse `json:"sth"`
+}
+
+// AuditEntry represents a leaf of the audit export.
+type AuditEntry struct {
+	LeafIndex int64    `json:"leaf_index"`
+	LeafInput []byte   `json:"leaf_input"`
+	ExtraData []byte   `json:"extra_data"`
+	AuditPath [][]byte `json:"audit_path"`
+}
+
+// GetRandomEntriesRequest represents the request to get-random-entries.
+type GetRandomEntriesRequest struct {
+	Alias string `json:"alias"`
+	Count int    `json:"count"`
+	// Seed is mixed with the root hash of the tree to derive the sample, see SampleIndices. An auditor sets it to
+	// a value the log can't predict (e.g. a randomness beacon) so the log can't bias the sample.
+	Seed string `json:"seed"`
+}
+
+// Validate validates data.
+func (r *GetRandomEntriesRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.Count < 1 || r.Count > MaxRandomEntries {
+		return fmt.Errorf("%w: count value must be between 1 and %d", errors.ErrValidation, MaxRandomEntries)
+	}
+
+	return nil
+}
+
+// GetRandomEntriesResponse represents the response to get-random-entries.
+type GetRandomEntriesResponse struct {
+	Seed string `json:"seed"`
+	// STH is the signed tree head the sample is derived from and the audit paths of the entries refer to.
+	STH GetSTHResponse `json:"sth"`
+	// Entries are the sampled leaves with their audit paths, in the order of SampleIndices.
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AuditTreeHead represents a tree head of the audit export chain.
+type AuditTreeHead struct {
+	TreeSize       int64  `json:"tree_size"`
+	SHA256RootHash []byte `json:"sha256_root_hash"`
+	// Consistency is the consistency proof with the previous tree head of the chain.
+	Consistency [][]byte `json:"consistency"`
+}
+
+// GetDiffRequest represents the request to diff.
+type GetDiffRequest struct {
+	Alias          string `json:"alias"`
+	FirstTreeSize  int64  `json:"first_tree_size"`
+	SecondTreeSize int64  `json:"second_tree_size"`
+	// Entries lists the entries added (up to MaxDiffEntries), only the summary is returned otherwise.
+	Entries bool `json:"entries"`
+}
+
+// Validate validates data.
+func (r *GetDiffRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.FirstTreeSize < 0 || r.FirstTreeSize > r.SecondTreeSize {
+		return fmt.Errorf("%w: first_tree_size %d and second_tree_size %d values is not a valid range",
+			errors.ErrValidation, r.FirstTreeSize, r.SecondTreeSize,
+		)
+	}
+
+	if r.SecondTreeSize-r.FirstTreeSize > MaxDiffRange {
+		return fmt.Errorf("%w: range of first_tree_size %d and second_tree_size %d exceeds %d entries",
+			errors.ErrValidation, r.FirstTreeSize, r.SecondTreeSize, MaxDiffRange,
+		)
+	}
+
+	return nil
+}
+
+// GetDiffResponse represents the response to diff: the summary of the entries added while the tree grew from the
+// first to the second size.
+type GetDiffResponse struct {
+	FirstTreeSize  int64 `json:"first_tree_size"`
+	SecondTreeSize int64 `json:"second_tree_size"`
+	// Added is the number of entries added.
+	Added uint64 `json:"added"`
+	// EntryTypes counts the entries added by name of their leaf type, "unknown" for the leaves which are not VCT
+	// entries.
+	EntryTypes map[string]uint64 `json:"entry_types"`
+	// Issuers counts the credentials added by issuer.
+	Issuers map[string]uint64 `json:"issuers"`
+	// CredentialTypes counts the credentials added by type, a credential is counted once per type.
+	CredentialTypes map[string]uint64 `json:"credential_types"`
+	// Entries are the entries added if requested, oldest first.
+	Entries []DiffEntry `json:"entries,omitempty"`
+	// Truncated is true if more than MaxDiffEntries were added, the first ones are listed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DiffEntry is an entry added between the tree sizes of a diff.
+type DiffEntry struct {
+	LeafIndex      int64        `json:"leaf_index"`
+	MerkleLeafHash []byte       `json:"merkle_leaf_hash"`
+	EntryType      LogEntryType `json:"entry_type"`
+	Timestamp      uint64       `json:"timestamp"`
+	// CredentialID, Issuer and Types of the credential entries.
+	CredentialID string   `json:"credential_id,omitempty"`
+	Issuer       string   `json:"issuer,omitempty"`
+	Types        []string `json:"types,omitempty"`
+}
+
+// RotateExtraDataKeyRequest represents the request to rotate the envelope key encrypting the extra data of leaves.
+type RotateExtraDataKeyRequest struct {
+	// KeyID (optional) of the envelope key of the KMS to rotate to, a new AES256GCM key is created if not set.
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// ExtraDataKeyStatus is the envelope key encrypting the extra data of leaves and the progress of the re-encryption
+// of the extra data of the logs with it.
+type ExtraDataKeyStatus struct {
+	// KeyID is the ID of the active envelope key, the extra data of the new leaves is encrypted with it.
+	KeyID string `json:"key_id"`
+	// RotatedAt is the time (ms) the instance rotated to the key at, 0 if the key was configured.
+	RotatedAt uint64 `json:"rotated_at,omitempty"`
+	// ReEncryption is true if the extra data of the logs is re-encrypted with the key in the background.
+	ReEncryption bool `json:"re_encryption"`
+	// Done is true once all the logs are scanned and the extra data of the leaves of the rewritable logs is
+	// encrypted with the key.
+	Done bool              `json:"done"`
+	Logs []LogReEncryption `json:"logs,omitempty"`
+}
+
+// LogReEncryption is the progress of the re-encryption of the extra data of a log with the active key.
+type LogReEncryption struct {
+	Alias string `json:"alias"`
+	// Rewritable is false if the log can't rewrite the extra data of its leaves (see ExtraDataWriter), the leaves
+	// are scanned only and never re-encrypted: the leaves not encrypted with the key are Retained, the keys they
+	// are encrypted with must be kept.
+	Rewritable bool `json:"rewritable"`
+	// TreeSize is the size of the tree being scanned, Scanned the number of its leaves scanned.
+	TreeSize int64 `json:"tree_size"`
+	Scanned  int64 `json:"scanned"`
+	// ReEncrypted is the number of leaves re-encrypted with the key.
+	ReEncrypted uint64 `json:"re_encrypted"`
+	// Remaining is the number of leaves of a rewritable log which may not be encrypted with the key: the leaves
+	// scanned encrypted with another key (or not encrypted) and the leaves not scanned yet.
+	Remaining uint64 `json:"remaining"`
+	// Retained is the number of leaves of a log which is not rewritable scanned encrypted with another key (or not
+	// encrypted), they keep it.
+	Retained uint64 `json:"retained,omitempty"`
+	// Keys counts the leaves scanned by the key their extra data is encrypted with, Plaintext the leaves scanned
+	// whose extra data is not encrypted.
+	Keys      map[string]uint64 `json:"keys,omitempty"`
+	Plaintext uint64            `json:"plaintext,omitempty"`
+	// Failed is the number of leaves which failed to be re-encrypted, Error the latest failure.
+	Failed uint64 `json:"failed,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SimulatePolicyRequest represents the request to replay the latest credentials logged against a proposed policy.
+type SimulatePolicyRequest struct {
+	Alias string `json:"alias"`
+	// Entries (optional) is the number of the latest entries replayed, DefaultSimulatedEntries if not set.
+	Entries int64           `json:"entries,omitempty"`
+	Policy  SimulatedPolicy `json:"policy"`
+}
+
+// Validate validates data.
+func (r *SimulatePolicyRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if r.Entries < 0 || r.Entries > MaxDiffRange {
+		return fmt.Errorf("%w: entries %d is not in range [0,%d]", errors.ErrValidation, r.Entries, MaxDiffRange)
+	}
+
+	if r.Policy.MaxEntrySize < 0 {
+		return fmt.Errorf("%w: max_entry_size %d is negative", errors.ErrValidation, r.Policy.MaxEntrySize)
+	}
+
+	return nil
+}
+
+// SimulatedPolicy is the proposed policy of a simulation, a field which is not set does not restrict.
+type SimulatedPolicy struct {
+	// AcceptedIssuers are the issuers accepted, any issuer if empty.
+	AcceptedIssuers []string `json:"accepted_issuers,omitempty"`
+	// AcceptedTypes are the credential types accepted (one of the types of the credential), any type if empty.
+	AcceptedTypes []string `json:"accepted_types,omitempty"`
+	// MaxEntrySize is the max size (bytes) of the credentials, any size if 0.
+	MaxEntrySize int `json:"max_entry_size,omitempty"`
+}
+
+// SimulatePolicyResponse represents the response to a policy simulation: the credentials of the entries in range
+// [start_index,tree_size) the proposed policy would have rejected.
+type SimulatePolicyResponse struct {
+	Alias      string `json:"alias"`
+	StartIndex int64  `json:"start_index"`
+	TreeSize   int64  `json:"tree_size"`
+	// Replayed is the number of credentials replayed.
+	Replayed uint64 `json:"replayed"`
+	// Skipped is the number of entries which are not credentials.
+	Skipped uint64 `json:"skipped"`
+	// Rejected is the number of credentials the policy would have rejected.
+	Rejected uint64 `json:"rejected"`
+	// Reasons counts the rejections by reason (issuer, type or size), a rejection may have several reasons.
+	Reasons map[string]uint64 `json:"reasons"`
+	// Issuers counts the rejections by issuer.
+	Issuers map[string]uint64 `json:"issuers"`
+	// Rejections are the credentials rejected, oldest first.
+	Rejections []PolicyRejection `json:"rejections,omitempty"`
+	// Truncated is true if more than MaxDiffEntries were rejected, the first ones are listed.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PolicyRejection is a credential a proposed policy would have rejected.
+type PolicyRejection struct {
+	LeafIndex    int64    `json:"leaf_index"`
+	CredentialID string   `json:"credential_id,omitempty"`
+	Issuer       string   `json:"issuer,omitempty"`
+	Types        []string `json:"types,omitempty"`
+	Size         int      `json:"size"`
+	Reasons      []string `json:"reasons"`
+}
+
+// States of a log, the state of its Trillian tree or frozen for good by the freeze of the log.
+const (
+	// LogStateActive is the state of a log accepting entries.
+	LogStateActive = "active"
+	// LogStateDraining is the state of a log accepting no more entries while the entries queued are integrated.
+	LogStateDraining = "draining"
+	// LogStateFrozen is the state of a log accepting no more entries, the entries queued are not integrated.
+	LogStateFrozen = "frozen"
+)
+
+// LogState represents the state of a log, published in the webfinger metadata of the log if its Trillian tree is
+// managed.
+type LogState struct {
+	Alias string `json:"alias"`
+	// State is LogStateActive, LogStateDraining or LogStateFrozen.
+	State string `json:"state"`
+	// TreeState is the state of the Trillian tree of the log (e.g. DRAINING), empty if it is not managed.
+	TreeState string `json:"tree_state,omitempty"`
+	// Final is true if the log is frozen for good at its final tree head (FreezeLog), it is never active again.
+	Final bool `json:"final,omitempty"`
+}
+
+// GetLogStatesResponse represents the response to get-log-states.
+type GetLogStatesResponse struct {
+	States []LogState `json:"states"`
+}
+
+// SetLogStateRequest represents the request to set-log-state.
+type SetLogStateRequest struct {
+	Alias string `json:"alias"`
+	// State is LogStateActive, LogStateDraining or LogStateFrozen.
+	State string `json:"state"`
+}
+
+// Validate validates the request.
+func (r *SetLogStateRequest) Validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: validate on nil value", errors.ErrValidation)
+	}
+
+	if _, ok := treeStateOf[r.State]; !ok {
+		return fmt.Errorf("%w: state %q is not one of %s, %s or %s", errors.ErrValidation, r.State,
+			LogStateActive, LogStateDraining, LogStateFrozen)
+	}
+
+	return nil
+}
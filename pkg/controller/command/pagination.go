@@ -0,0 +1,107 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// DefaultPageSize is the size of a page if only the page token is set.
+	DefaultPageSize = 100
+	// MaxPageSize is the maximum size of a page.
+	MaxPageSize = 1000
+
+	listIDSize = 8
+)
+
+// PageRequest is the pagination of a list request: the size of the page and the token of the page, as returned
+// as the next page token of the previous page. The whole list is returned if neither is set.
+type PageRequest struct {
+	PageSize  int    `json:"page_size,omitempty"`
+	PageToken string `json:"page_token,omitempty"`
+}
+
+func (p PageRequest) paged() bool {
+	return p.PageSize != 0 || p.PageToken != ""
+}
+
+// pageToken is the cursor encoded (base64) in a page token. The lists are append-only, so the offset of the next
+// page is stable; the token is bound to the list so it is not accepted by another one.
+type pageToken struct {
+	List   []byte `json:"l"`
+	Offset int    `json:"o"`
+}
+
+func listID(list string) []byte {
+	hash := sha256.Sum256([]byte(list))
+
+	return hash[:listIDSize]
+}
+
+// paginate returns the bounds of the page of the list of the length, and the token of the next page (empty
+// on the last page). The list identifies the list paginated (e.g. the endpoint and the alias of the log).
+func paginate(list string, page PageRequest, length int) (begin, end int, next string, err error) {
+	if !page.paged() {
+		return 0, length, "", nil
+	}
+
+	size := page.PageSize
+	if size == 0 {
+		size = DefaultPageSize
+	}
+
+	if size < 0 || size > MaxPageSize {
+		return 0, 0, "", fmt.Errorf("%w: page_size %d is not within [1,%d]", errors.ErrValidation, size, MaxPageSize)
+	}
+
+	id := listID(list)
+
+	if page.PageToken != "" {
+		begin, err = decodePageToken(page.PageToken, id)
+		if err != nil {
+			return 0, 0, "", err
+		}
+	}
+
+	if begin > length {
+		return 0, 0, "", fmt.Errorf("%w: page_token is beyond the list", errors.ErrValidation)
+	}
+
+	end = begin + size
+	if end >= length {
+		return begin, length, "", nil
+	}
+
+	data, err := json.Marshal(pageToken{List: id, Offset: end})
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("marshal page token: %w", err)
+	}
+
+	return begin, end, base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageToken(token string, id []byte) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("%w: page_token is not valid", errors.ErrValidation)
+	}
+
+	var cursor pageToken
+
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 || !bytes.Equal(cursor.List, id) {
+		return 0, fmt.Errorf("%w: page_token is not valid", errors.ErrValidation)
+	}
+
+	return cursor.Offset, nil
+}
@@ -0,0 +1,308 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// maxPendingSubmissions is the max number of pending submissions tracked per log, the submissions queued
+	// beyond it are only counted.
+	maxPendingSubmissions  = 10000
+	defaultPendingLimit    = 100
+	pendingLeavesRange     = 1000
+	pendingClockSkewMargin = time.Minute
+)
+
+// pendingAgeBuckets are the upper bounds of the ages the pending submissions are counted by.
+// nolint: gochecknoglobals
+var pendingAgeBuckets = []time.Duration{time.Minute, 10 * time.Minute, time.Hour, 24 * time.Hour}
+
+// pendingSubmissions tracks the leaves queued by the instance which are not sequenced yet (the merge backlog),
+// the sequenced leaves are matched by GetPendingSubmissions.
+type pendingSubmissions struct {
+	mu   sync.Mutex
+	logs map[string]*pendingLog // alias -> pending leaves
+}
+
+type pendingLog struct {
+	resolveMu sync.Mutex              // serializes the matching of the sequenced leaves
+	leaves    map[string]*pendingLeaf // leaf identity hash -> leaf
+	checked   int64                   // the leaves below are matched
+	untracked uint64
+	// sequencedCount is the number of leaves queued by the instance which were matched in the tree.
+	sequencedCount uint64
+}
+
+type pendingLeaf struct {
+	identityHash   []byte
+	merkleLeafHash []byte
+	queuedAt       time.Time
+	size           int
+	// leaf is kept to re-queue it once it is unmerged, nil if the unmerged submissions are not re-queued.
+	leaf       *trillian.LogLeaf
+	expired    bool // the leaf is not sequenced within the TTL (see MonitorUnmerged)
+	requeues   int
+	requeuedAt time.Time
+}
+
+func newPendingSubmissions(logs map[string]Log) *pendingSubmissions {
+	p := &pendingSubmissions{logs: map[string]*pendingLog{}}
+
+	for alias := range logs {
+		p.logs[alias] = &pendingLog{leaves: map[string]*pendingLeaf{}}
+	}
+
+	return p
+}
+
+// recordPending records the leaf queued to the log, it is pending until it is sequenced. The queue timestamp of
+// Trillian is used if set, the leaves are matched by their integrate timestamps.
+func (c *Cmd) recordPending(alias string, queued *trillian.LogLeaf) {
+	log, ok := c.pending.logs[alias]
+	if !ok || queued == nil {
+		return
+	}
+
+	queuedAt := time.Now()
+	if ts := queued.GetQueueTimestamp(); ts != nil {
+		queuedAt = ts.AsTime()
+	}
+
+	c.pending.mu.Lock()
+	defer c.pending.mu.Unlock()
+
+	if len(log.leaves) >= maxPendingSubmissions {
+		log.untracked++
+
+		return
+	}
+
+	leaf := &pendingLeaf{
+		identityHash:   queued.GetLeafIdentityHash(),
+		merkleLeafHash: queued.GetMerkleLeafHash(),
+		queuedAt:       queuedAt,
+		size:           len(queued.GetLeafValue()),
+	}
+
+	if c.submissionTTL != nil && c.submissionTTL.requeue {
+		leaf.leaf = queued
+	}
+
+	log.leaves[string(queued.GetLeafIdentityHash())] = leaf
+}
+
+// oldestPending returns the queue time of the oldest pending leaf of the log, false if none is pending.
+func (p *pendingSubmissions) oldestPending(log *pendingLog) (time.Time, int64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var oldest time.Time
+
+	for _, leaf := range log.leaves {
+		if oldest.IsZero() || leaf.queuedAt.Before(oldest) {
+			oldest = leaf.queuedAt
+		}
+	}
+
+	return oldest, log.checked, len(log.leaves) > 0
+}
+
+// sequenced removes the sequenced leaves of the log, the leaves below the tree size are matched.
+func (p *pendingSubmissions) sequenced(log *pendingLog, leaves []*trillian.LogLeaf, treeSize int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, leaf := range leaves {
+		if _, ok := log.leaves[string(leaf.GetLeafIdentityHash())]; ok {
+			delete(log.leaves, string(leaf.GetLeafIdentityHash()))
+
+			log.sequencedCount++
+		}
+	}
+
+	log.checked = treeSize
+}
+
+// resolvePending matches the pending leaves of the log with the leaves sequenced since the oldest of them was
+// queued, it returns the tree size they are matched up to. A pending leaf is sequenced after it was queued, so the
+// leaves integrated before are skipped by a binary search over the tree (see searchLeaf).
+func (c *Cmd) resolvePending(alias string) (int64, error) {
+	log := c.pending.logs[alias]
+
+	log.resolveMu.Lock()
+	defer log.resolveMu.Unlock()
+
+	treeSize, err := c.treeSize(alias)
+	if err != nil {
+		return 0, err
+	}
+
+	oldest, start, ok := c.pending.oldestPending(log)
+	if !ok {
+		c.pending.sequenced(log, nil, treeSize)
+
+		return treeSize, nil
+	}
+
+	first, err := c.searchLeaf(alias, treeSize, func(ts time.Time) bool {
+		return !ts.Before(oldest.Add(-pendingClockSkewMargin))
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if first > start {
+		start = first
+	}
+
+	for start < treeSize {
+		req := trillian.GetLeavesByRangeRequest{
+			LogId:      c.logs[alias].ID,
+			StartIndex: start,
+			Count:      treeSize - start,
+		}
+
+		if req.Count > pendingLeavesRange {
+			req.Count = pendingLeavesRange
+		}
+
+		var resp *trillian.GetLeavesByRangeResponse
+
+		err = c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+			var er error
+
+			resp, er = client.GetLeavesByRange(context.Background(), &req)
+
+			return resp, er
+		})
+		if err != nil {
+			return 0, fmt.Errorf("get leaves by range: %w", err)
+		}
+
+		if len(resp.GetLeaves()) == 0 {
+			return 0, fmt.Errorf("%w: no leaves starting from %d", errors.ErrInternal, start)
+		}
+
+		start += int64(len(resp.GetLeaves()))
+
+		c.pending.sequenced(log, resp.GetLeaves(), start)
+	}
+
+	return treeSize, nil
+}
+
+// GetPendingSubmissions lists the submissions queued by the instance which are not sequenced yet, oldest first,
+// with their ages and counts per log.
+func (c *Cmd) GetPendingSubmissions(w io.Writer, r io.Reader) error {
+	var request GetPendingSubmissionsRequest
+
+	if r != nil {
+		if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+			return fmt.Errorf("%w: decode GetPendingSubmissions request: %v", errors.ErrBadRequest, err)
+		}
+	}
+
+	aliases := make([]string, 0, len(c.logs))
+
+	if request.Alias != "" {
+		if _, ok := c.logs[request.Alias]; !ok {
+			return errors.NewNotFoundError(fmt.Errorf("log %q is not found", request.Alias))
+		}
+
+		aliases = append(aliases, request.Alias)
+	} else {
+		for alias := range c.logs {
+			aliases = append(aliases, alias)
+		}
+
+		sort.Strings(aliases)
+	}
+
+	if request.Limit <= 0 {
+		request.Limit = defaultPendingLimit
+	}
+
+	response := GetPendingSubmissionsResponse{Logs: []LogPendingSubmissions{}}
+
+	for _, alias := range aliases {
+		treeSize, err := c.resolvePending(alias)
+		if err != nil {
+			return fmt.Errorf("resolve pending submissions of log %s: %w", alias, err)
+		}
+
+		response.Logs = append(response.Logs, c.pending.report(alias, treeSize, request.Limit))
+	}
+
+	return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+}
+
+// report returns the pending submissions of the log, oldest first.
+func (p *pendingSubmissions) report(alias string, treeSize int64, limit int) LogPendingSubmissions {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log := p.logs[alias]
+	now := time.Now()
+
+	report := LogPendingSubmissions{
+		Alias:       alias,
+		TreeSize:    treeSize,
+		Pending:     len(log.leaves),
+		Untracked:   log.untracked,
+		ByAge:       make([]PendingAgeCount, len(pendingAgeBuckets)+1),
+		Submissions: []PendingSubmission{},
+	}
+
+	for i, bound := range pendingAgeBuckets {
+		report.ByAge[i].MaxAge = bound.String()
+	}
+
+	leaves := make([]*pendingLeaf, 0, len(log.leaves))
+	for _, leaf := range log.leaves {
+		leaves = append(leaves, leaf)
+	}
+
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].queuedAt.Before(leaves[j].queuedAt) })
+
+	for i, leaf := range leaves {
+		age := now.Sub(leaf.queuedAt)
+		if age < 0 {
+			age = 0
+		}
+
+		bucket := sort.Search(len(pendingAgeBuckets), func(k int) bool { return age < pendingAgeBuckets[k] })
+		report.ByAge[bucket].Count++
+
+		if i == 0 {
+			report.OldestAge = uint64(age / time.Millisecond)
+		}
+
+		if i < limit {
+			report.Submissions = append(report.Submissions, PendingSubmission{
+				LeafIdentityHash: leaf.identityHash,
+				MerkleLeafHash:   leaf.merkleLeafHash,
+				QueuedAt:         uint64(leaf.queuedAt.UnixNano()) / uint64(time.Millisecond),
+				Age:              uint64(age / time.Millisecond),
+				Size:             leaf.size,
+			})
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,241 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// restorePolicies restores the policy history of the logs, each history must be a chain of the versions of the
+// policy of its log. The signatures are not verified again: the versions published before a key rotation are
+// signed with the previous key of the log.
+func (c *Cmd) restorePolicies(histories map[string][]SignedPolicyDocument) error {
+	for alias, history := range histories {
+		if err := CheckPolicyHistory(alias, history); err != nil {
+			return fmt.Errorf("policy of log %s: %w", alias, err)
+		}
+
+		c.policies[alias] = append([]SignedPolicyDocument(nil), history...)
+	}
+
+	return nil
+}
+
+// CheckPolicyHistory checks that the versions of the policy of the log (oldest first) form a chain: each version
+// follows the previous version and refers to it by its hash. The signatures are not verified.
+func CheckPolicyHistory(alias string, history []SignedPolicyDocument) error {
+	for i := range history {
+		var previous *SignedPolicyDocument
+		if i > 0 {
+			previous = &history[i-1]
+		}
+
+		if err := checkPolicyLink(alias, previous, &history[i].Statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPolicyLink checks that the policy document is the version of the policy of the log following the previous
+// version (nil for the first version).
+func checkPolicyLink(alias string, previous *SignedPolicyDocument, statement *PolicyDocument) error {
+	if statement.Version != V1 || statement.SignatureType != PolicySignatureType || statement.Alias != alias {
+		return fmt.Errorf("version %d is not a v1 policy document of the log", statement.PolicyVersion)
+	}
+
+	if previous == nil {
+		if statement.PolicyVersion != 1 || len(statement.Previous) != 0 {
+			return fmt.Errorf("history does not start with version 1")
+		}
+
+		return nil
+	}
+
+	hash, err := PolicyDocumentHash(previous)
+	if err != nil {
+		return err
+	}
+
+	if statement.PolicyVersion != previous.Statement.PolicyVersion+1 || !bytes.Equal(statement.Previous, hash) {
+		return fmt.Errorf("version %d does not follow version %d", statement.PolicyVersion,
+			previous.Statement.PolicyVersion)
+	}
+
+	return nil
+}
+
+// PolicyDocumentHash returns the hash the next version of the policy refers to the signed policy document by:
+// the SHA-256 hash of its JSON encoding.
+func PolicyDocumentHash(signed *SignedPolicyDocument) ([]byte, error) {
+	src, err := json.Marshal(signed)
+	if err != nil {
+		return nil, fmt.Errorf("marshal policy document: %w", err)
+	}
+
+	hash := sha256.Sum256(src)
+
+	return hash[:], nil
+}
+
+// latestPolicy returns the latest version of the policy of the log, nil unless a policy is published.
+func (c *Cmd) latestPolicy(alias string) *SignedPolicyDocument {
+	c.policiesMu.RLock()
+	defer c.policiesMu.RUnlock()
+
+	history := c.policies[alias]
+	if len(history) == 0 {
+		return nil
+	}
+
+	return &history[len(history)-1]
+}
+
+// PublishPolicy publishes the policy of the log as its next version, signed by the key of the log and chained to
+// the previous version. The latest version is returned unchanged if the policy is the same. The accepted issuers
+// default to the issuers the log accepts.
+func (c *Cmd) PublishPolicy(w io.Writer, r io.Reader) error {
+	var req *PublishPolicyRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode PublishPolicy request: %v", errors.ErrBadRequest, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("validate PublishPolicy request: %w", err)
+	}
+
+	if err := c.hasPermissions(req.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	policy := req.Policy
+	if len(policy.AcceptedIssuers) == 0 {
+		policy.AcceptedIssuers = c.logs[req.Alias].Issuers
+	}
+
+	c.policiesMu.Lock()
+	defer c.policiesMu.Unlock()
+
+	history := c.policies[req.Alias]
+
+	statement := PolicyDocument{
+		Version:       V1,
+		SignatureType: PolicySignatureType,
+		Timestamp:     uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		LogID:         c.VCLogID[:],
+		Alias:         req.Alias,
+		PolicyVersion: 1,
+		Policy:        policy,
+	}
+
+	if len(history) > 0 {
+		latest := &history[len(history)-1]
+
+		if samePolicy(&latest.Statement.Policy, &policy) {
+			return json.NewEncoder(w).Encode(latest) // nolint: wrapcheck
+		}
+
+		hash, err := PolicyDocumentHash(latest)
+		if err != nil {
+			return err
+		}
+
+		statement.PolicyVersion = latest.Statement.PolicyVersion + 1
+		statement.Previous = hash
+	}
+
+	signature, err := c.sign(statement)
+	if err != nil {
+		return fmt.Errorf("sign policy document: %w", err)
+	}
+
+	signed := SignedPolicyDocument{Statement: statement, Signature: signature}
+
+	if c.onPolicy != nil {
+		if err = c.onPolicy(req.Alias, &signed); err != nil {
+			return fmt.Errorf("store policy document: %w", err)
+		}
+	}
+
+	c.policies[req.Alias] = append(history, signed)
+
+	logger.Infof("published version %d of the policy of log %s", statement.PolicyVersion, req.Alias)
+
+	return json.NewEncoder(w).Encode(signed) // nolint: wrapcheck
+}
+
+// samePolicy returns true if the policies have the same JSON encoding (empty lists are omitted).
+func samePolicy(a, b *LogPolicy) bool {
+	srcA, errA := json.Marshal(a)
+	srcB, errB := json.Marshal(b)
+
+	return errA == nil && errB == nil && bytes.Equal(srcA, srcB)
+}
+
+// GetPolicy retrieves the signed policy document of the log: the version of the request, or the latest version
+// if it is zero. NotFound unless the version is published.
+func (c *Cmd) GetPolicy(w io.Writer, r io.Reader) error {
+	var req *GetPolicyRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil || req == nil {
+		return fmt.Errorf("%w: decode GetPolicy request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(req.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	c.policiesMu.RLock()
+	defer c.policiesMu.RUnlock()
+
+	history := c.policies[req.Alias]
+
+	if len(history) == 0 {
+		return errors.NewNotFoundError(fmt.Errorf("log %s has no published policy", req.Alias))
+	}
+
+	version := req.Version
+	if version == 0 {
+		version = uint64(len(history))
+	}
+
+	if version > uint64(len(history)) {
+		return errors.NewNotFoundError(fmt.Errorf("version %d of the policy of log %s is not published", version,
+			req.Alias))
+	}
+
+	return json.NewEncoder(w).Encode(history[version-1]) // nolint: wrapcheck
+}
+
+// GetPolicyHistory retrieves all the published versions of the policy of the log, oldest first.
+func (c *Cmd) GetPolicyHistory(w io.Writer, r io.Reader) error {
+	var alias string
+
+	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+		return fmt.Errorf("%w: decode alias failed", errors.ErrInternal)
+	}
+
+	if err := c.hasPermissions(alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	c.policiesMu.RLock()
+	defer c.policiesMu.RUnlock()
+
+	return json.NewEncoder(w).Encode(GetPolicyHistoryResponse{ // nolint: wrapcheck
+		Policies: append([]SignedPolicyDocument{}, c.policies[alias]...),
+	})
+}
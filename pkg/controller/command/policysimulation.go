@@ -0,0 +1,167 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/google/trillian"
+)
+
+// DefaultSimulatedEntries is the number of the latest entries replayed by a policy simulation if not set.
+const DefaultSimulatedEntries = 1000
+
+// Reasons of the rejections of a policy simulation.
+const (
+	RejectedIssuer = "issuer"
+	RejectedType   = "type"
+	RejectedSize   = "size"
+)
+
+// SimulatePolicy replays the latest credentials logged (the submissions the log accepted) against the proposed
+// policy and reports the ones the policy would have rejected (SimulatePolicyResponse), nothing is enforced.
+func (c *Cmd) SimulatePolicy(w io.Writer, r io.Reader) error {
+	var request *SimulatePolicyRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode SimulatePolicy request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate SimulatePolicy request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	treeSize, err := c.treeSize(request.Alias)
+	if err != nil {
+		return err
+	}
+
+	entries := request.Entries
+	if entries == 0 {
+		entries = DefaultSimulatedEntries
+	}
+
+	startIndex := treeSize - entries
+	if startIndex < 0 {
+		startIndex = 0
+	}
+
+	response := SimulatePolicyResponse{
+		Alias:      request.Alias,
+		StartIndex: startIndex,
+		TreeSize:   treeSize,
+		Reasons:    map[string]uint64{},
+		Issuers:    map[string]uint64{},
+	}
+
+	for start := startIndex; start < treeSize; start += diffPageSize {
+		count := treeSize - start
+		if count > diffPageSize {
+			count = diffPageSize
+		}
+
+		leaves, er := c.diffLeaves(request.Alias, start, count)
+		if er != nil {
+			return er
+		}
+
+		for _, leaf := range leaves {
+			simulateLeaf(&response, &request.Policy, leaf)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+}
+
+// simulateLeaf replays the credential of the leaf against the policy, the leaves which are not credentials are
+// skipped.
+func simulateLeaf(response *SimulatePolicyResponse, policy *SimulatedPolicy, leaf *trillian.LogLeaf) {
+	var merkleLeaf MerkleTreeLeaf
+	if err := json.Unmarshal(leaf.GetLeafValue(), &merkleLeaf); err != nil || merkleLeaf.TimestampedEntry == nil ||
+		merkleLeaf.TimestampedEntry.EntryType != VCLogEntryType {
+		response.Skipped++
+
+		return
+	}
+
+	credential := merkleLeaf.TimestampedEntry.VCEntry
+
+	var entry DiffEntry
+	if !describeCredential(&entry, credential) {
+		response.Skipped++
+
+		return
+	}
+
+	response.Replayed++
+
+	reasons := policy.reject(&entry, len(credential))
+	if len(reasons) == 0 {
+		return
+	}
+
+	response.Rejected++
+
+	for _, reason := range reasons {
+		response.Reasons[reason]++
+	}
+
+	if entry.Issuer != "" {
+		response.Issuers[entry.Issuer]++
+	}
+
+	if len(response.Rejections) == MaxDiffEntries {
+		response.Truncated = true
+
+		return
+	}
+
+	response.Rejections = append(response.Rejections, PolicyRejection{
+		LeafIndex:    leaf.GetLeafIndex(),
+		CredentialID: entry.CredentialID,
+		Issuer:       entry.Issuer,
+		Types:        entry.Types,
+		Size:         len(credential),
+		Reasons:      reasons,
+	})
+}
+
+// reject returns the reasons the policy rejects the credential of the size for, none if it is accepted.
+func (p *SimulatedPolicy) reject(entry *DiffEntry, size int) []string {
+	var reasons []string
+
+	if len(p.AcceptedIssuers) > 0 && !contains(p.AcceptedIssuers, entry.Issuer) {
+		reasons = append(reasons, RejectedIssuer)
+	}
+
+	if len(p.AcceptedTypes) > 0 && !acceptsType(p.AcceptedTypes, entry.Types) {
+		reasons = append(reasons, RejectedType)
+	}
+
+	if p.MaxEntrySize > 0 && size > p.MaxEntrySize {
+		reasons = append(reasons, RejectedSize)
+	}
+
+	return reasons
+}
+
+// acceptsType returns true if one of the types is accepted.
+func acceptsType(accepted, types []string) bool {
+	for _, t := range types {
+		if contains(accepted, t) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,159 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// Assurance levels of eIDAS, the assurance levels of the policy tags accepted by default.
+const (
+	AssuranceLevelLow         = "low"
+	AssuranceLevelSubstantial = "substantial"
+	AssuranceLevelHigh        = "high"
+)
+
+// jurisdictionPattern matches the ISO 3166-1 alpha-2 country codes and the ISO 3166-2 subdivision codes.
+var jurisdictionPattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+// PolicyTagsSchema is the schema the policy tags of the submissions are validated against.
+type PolicyTagsSchema struct {
+	// Jurisdictions accepted, a country accepts its subdivisions (e.g. CA accepts CA-QC). Any jurisdiction is
+	// accepted if empty.
+	Jurisdictions []string
+	// AssuranceLevels accepted, the eIDAS levels (low, substantial and high) if empty.
+	AssuranceLevels []string
+}
+
+func newPolicyTagsSchema(cfg *PolicyTagsSchema) (*PolicyTagsSchema, error) {
+	schema := &PolicyTagsSchema{}
+	if cfg != nil {
+		schema.Jurisdictions = append(schema.Jurisdictions, cfg.Jurisdictions...)
+		schema.AssuranceLevels = append(schema.AssuranceLevels, cfg.AssuranceLevels...)
+	}
+
+	for _, jurisdiction := range schema.Jurisdictions {
+		if !jurisdictionPattern.MatchString(jurisdiction) {
+			return nil, fmt.Errorf("jurisdiction %q is not an ISO 3166 code", jurisdiction)
+		}
+	}
+
+	if len(schema.AssuranceLevels) == 0 {
+		schema.AssuranceLevels = []string{AssuranceLevelLow, AssuranceLevelSubstantial, AssuranceLevelHigh}
+	}
+
+	return schema, nil
+}
+
+// validate validates the policy tags against the schema.
+func (s *PolicyTagsSchema) validate(tags *PolicyTags) error {
+	if tags.Jurisdiction != "" {
+		if !jurisdictionPattern.MatchString(tags.Jurisdiction) {
+			return fmt.Errorf("jurisdiction %q is not an ISO 3166 code", tags.Jurisdiction)
+		}
+
+		if len(s.Jurisdictions) > 0 && !inJurisdictions(tags.Jurisdiction, s.Jurisdictions) {
+			return fmt.Errorf("jurisdiction %q is not accepted", tags.Jurisdiction)
+		}
+	}
+
+	if tags.AssuranceLevel != "" && !contains(s.AssuranceLevels, tags.AssuranceLevel) {
+		return fmt.Errorf("assurance level %q is not one of %s", tags.AssuranceLevel,
+			strings.Join(s.AssuranceLevels, ", "))
+	}
+
+	return nil
+}
+
+// inJurisdiction reports whether the jurisdiction is the other jurisdiction or one of its subdivisions.
+func inJurisdiction(jurisdiction, other string) bool {
+	return jurisdiction == other || strings.HasPrefix(jurisdiction, other+"-")
+}
+
+func inJurisdictions(jurisdiction string, others []string) bool {
+	for _, other := range others {
+		if inJurisdiction(jurisdiction, other) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Match reports whether the policy tags match the filter: the jurisdiction is the jurisdiction of the filter or
+// one of its subdivisions and the assurance level is the level of the filter, a tag not set in the filter matches
+// any value. Nil tags only match an empty filter.
+func (t *PolicyTags) Match(filter PolicyTags) bool {
+	if t == nil {
+		return filter == PolicyTags{}
+	}
+
+	if filter.Jurisdiction != "" && !inJurisdiction(t.Jurisdiction, filter.Jurisdiction) {
+		return false
+	}
+
+	return filter.AssuranceLevel == "" || t.AssuranceLevel == filter.AssuranceLevel
+}
+
+// tagLeaf validates the policy tags of the submission and records them in the extensions of the leaf.
+func (c *Cmd) tagLeaf(leaf *MerkleTreeLeaf, tags *PolicyTags) error {
+	if err := c.policyTags.validate(tags); err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("policy tags: %w", err))
+	}
+
+	return setExtensions(leaf, func(ext *EntryExtensions) { ext.PolicyTags = tags })
+}
+
+// filterEntries returns the entries of the range starting at the index whose policy tags match the filter, along
+// with their leaf indexes.
+func filterEntries(entries []LeafEntry, start int64, filter PolicyTags) []LeafEntry {
+	filtered := []LeafEntry{}
+
+	for i := range entries {
+		var leaf MerkleTreeLeaf
+		if err := json.Unmarshal(entries[i].LeafInput, &leaf); err != nil || leaf.TimestampedEntry == nil {
+			continue
+		}
+
+		if !entryExtensions(leaf.TimestampedEntry.Extensions).PolicyTags.Match(filter) {
+			continue
+		}
+
+		leafIndex := start + int64(i)
+
+		entry := entries[i]
+		entry.LeafIndex = &leafIndex
+
+		filtered = append(filtered, entry)
+	}
+
+	return filtered
+}
+
+// taggedIssuers returns the issuers of the indexed credentials whose policy tags match the filter, in the order
+// they first logged a matching credential.
+func (i *credentialIndex) taggedIssuers(filter PolicyTags) []string {
+	issuers := []string{}
+	seen := map[string]bool{}
+
+	for _, info := range i.tagged {
+		if info.Issuer == "" || seen[info.Issuer] || !info.PolicyTags.Match(filter) {
+			continue
+		}
+
+		seen[info.Issuer] = true
+
+		issuers = append(issuers, info.Issuer)
+	}
+
+	return issuers
+}
@@ -0,0 +1,289 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/internal/pkg/scrub"
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const authenticationProofPurpose = "authentication"
+
+// presentationLeafType returns the leaf type of the holder-signed presentations (PresentationLogEntryType).
+func (c *Cmd) presentationLeafType() LeafType {
+	return LeafType{
+		EntryType: PresentationLogEntryType,
+		Name:      "presentation",
+		Validate: func(entry []byte) error {
+			_, err := presentationEntry(entry)
+
+			return err
+		},
+		Check: func(alias string, entry []byte) error {
+			var presentation Presentation
+			if err := unmarshalEntry(entry, &presentation); err != nil {
+				return err
+			}
+
+			return c.verifyPresentation(alias, presentation.Presentation)
+		},
+		Serialize: func(entry []byte) ([]byte, error) {
+			logged, err := presentationEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+
+			return json.Marshal(logged) // nolint: wrapcheck
+		},
+	}
+}
+
+// verifyPresentation verifies the signature of the holder of the presentation: the JWT of the presentation or
+// its embedded proofs, which must be made by a verification method of the holder. The JWT credentials of the
+// presentation are verified too, the embedded proofs of the other credentials are not.
+func (c *Cmd) verifyPresentation(alias string, raw json.RawMessage) error {
+	loader, ok := c.loaders[alias]
+	if !ok {
+		return fmt.Errorf("no document loader found for alias %s", alias)
+	}
+
+	data := []byte(raw)
+
+	var jws string
+	if json.Unmarshal(raw, &jws) == nil {
+		data = []byte(jws)
+	}
+
+	vp, err := verifiable.ParsePresentation(data,
+		verifiable.WithPresPublicKeyFetcher(verifiable.NewVDRKeyResolver(c.vdr).PublicKeyFetcher()),
+		verifiable.WithPresJSONLDDocumentLoader(loader),
+	)
+	if err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("parse presentation: %w", scrub.Error(err, data)))
+	}
+
+	if vp.Holder == "" {
+		return fmt.Errorf("%w: presentation has no holder", errors.ErrValidation)
+	}
+
+	// the signature of a JWT presentation is verified with the key of its issuer, the holder
+	if jws != "" {
+		return nil
+	}
+
+	if len(vp.Proofs) == 0 {
+		return fmt.Errorf("%w: presentation is not signed by its holder", errors.ErrValidation)
+	}
+
+	for _, proof := range vp.Proofs {
+		method, _ := proof["verificationMethod"].(string) // nolint: errcheck
+
+		if strings.Split(method, "#")[0] != vp.Holder {
+			return fmt.Errorf("%w: proof of the presentation is not made by its holder %s", errors.ErrValidation,
+				vp.Holder)
+		}
+
+		if purpose, _ := proof["proofPurpose"].(string); purpose != authenticationProofPurpose { // nolint: errcheck
+			return fmt.Errorf("%w: proof purpose of the presentation must be %s", errors.ErrValidation,
+				authenticationProofPurpose)
+		}
+	}
+
+	return nil
+}
+
+// presentationEntry returns the logged form of the submitted presentation, its metadata is extracted without
+// verifying it (see verifyPresentation).
+func presentationEntry(entry []byte) (*PresentationEntry, error) {
+	var presentation Presentation
+	if err := unmarshalEntry(entry, &presentation); err != nil {
+		return nil, err
+	}
+
+	if len(presentation.Presentation) == 0 || string(presentation.Presentation) == "null" {
+		return nil, fmt.Errorf("%w: presentation is empty", errors.ErrValidation)
+	}
+
+	logged := &PresentationEntry{Presentation: presentation.Presentation}
+
+	var (
+		vp  rawPresentation
+		jws string
+	)
+
+	if json.Unmarshal(presentation.Presentation, &jws) == nil {
+		var claims struct {
+			Issuer   string          `json:"iss"`
+			ID       string          `json:"jti"`
+			Audience stringOrArray   `json:"aud"`
+			Nonce    string          `json:"nonce"`
+			VP       rawPresentation `json:"vp"`
+		}
+
+		if err := decodeJWTClaims(jws, &claims); err != nil {
+			return nil, fmt.Errorf("%w: presentation: %v", errors.ErrValidation, err)
+		}
+
+		vp = claims.VP
+		vp.Holder, logged.Challenge = claims.Issuer, claims.Nonce
+
+		if claims.ID != "" {
+			vp.ID = claims.ID
+		}
+
+		if len(claims.Audience) > 0 {
+			logged.Verifier = claims.Audience[0]
+		}
+	} else if err := json.Unmarshal(presentation.Presentation, &vp); err != nil {
+		return nil, fmt.Errorf("%w: presentation: %v", errors.ErrValidation, err)
+	}
+
+	if vp.Holder == "" {
+		return nil, fmt.Errorf("%w: presentation has no holder", errors.ErrValidation)
+	}
+
+	logged.Holder, logged.ID, logged.Types = vp.Holder, vp.ID, vp.Type
+
+	if len(vp.Proof) > 0 {
+		logged.Verifier, logged.Challenge = vp.Proof[0].Domain, vp.Proof[0].Challenge
+	}
+
+	for i, credential := range vp.Credentials {
+		presented, err := presentedCredential(credential)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential %d of the presentation: %v", errors.ErrValidation, i, err)
+		}
+
+		logged.Credentials = append(logged.Credentials, *presented)
+	}
+
+	return logged, nil
+}
+
+// presentedCredential returns the metadata of a credential of a presentation, a JWT or a JSON-LD credential.
+func presentedCredential(raw json.RawMessage) (*PresentedCredential, error) {
+	var vc struct {
+		ID     string          `json:"id"`
+		Type   stringOrArray   `json:"type"`
+		Issuer json.RawMessage `json:"issuer"`
+	}
+
+	var issuer string
+
+	var jws string
+	if json.Unmarshal(raw, &jws) == nil {
+		var claims struct {
+			Issuer string          `json:"iss"`
+			ID     string          `json:"jti"`
+			VC     json.RawMessage `json:"vc"`
+		}
+
+		if err := decodeJWTClaims(jws, &claims); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(claims.VC, &vc); err != nil {
+			return nil, fmt.Errorf("unmarshal vc claim: %w", err)
+		}
+
+		if claims.ID != "" {
+			vc.ID = claims.ID
+		}
+
+		issuer = claims.Issuer
+	} else if err := json.Unmarshal(raw, &vc); err != nil {
+		return nil, fmt.Errorf("unmarshal credential: %w", err)
+	}
+
+	// the issuer is a URI or an object with an id
+	if issuer == "" && len(vc.Issuer) > 0 && json.Unmarshal(vc.Issuer, &issuer) != nil {
+		var object struct {
+			ID string `json:"id"`
+		}
+
+		if err := json.Unmarshal(vc.Issuer, &object); err != nil {
+			return nil, fmt.Errorf("unmarshal issuer: %w", err)
+		}
+
+		issuer = object.ID
+	}
+
+	return &PresentedCredential{ID: vc.ID, Types: vc.Type, Issuer: issuer}, nil
+}
+
+// decodeJWTClaims decodes the claims of the compact JWT without verifying it.
+func decodeJWTClaims(jws string, claims interface{}) error {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 { // nolint: gomnd
+		return fmt.Errorf("not a compact JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("decode JWT payload: %w", err)
+	}
+
+	if err = json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("unmarshal JWT claims: %w", err)
+	}
+
+	return nil
+}
+
+// rawPresentation is the part of a presentation its metadata is extracted from.
+type rawPresentation struct {
+	ID          string            `json:"id"`
+	Type        stringOrArray     `json:"type"`
+	Holder      string            `json:"holder"`
+	Credentials credentialsOrOne  `json:"verifiableCredential"`
+	Proof       presentationProof `json:"proof"`
+}
+
+type proofOptions struct {
+	Challenge string `json:"challenge"`
+	Domain    string `json:"domain"`
+}
+
+type presentationProof []proofOptions
+
+func (p *presentationProof) UnmarshalJSON(data []byte) error {
+	return unmarshalOneOrMany(data, (*[]proofOptions)(p))
+}
+
+type credentialsOrOne []json.RawMessage
+
+func (c *credentialsOrOne) UnmarshalJSON(data []byte) error {
+	return unmarshalOneOrMany(data, (*[]json.RawMessage)(c))
+}
+
+type stringOrArray []string
+
+func (s *stringOrArray) UnmarshalJSON(data []byte) error {
+	return unmarshalOneOrMany(data, (*[]string)(s))
+}
+
+// unmarshalOneOrMany unmarshals a JSON array or a single value into the slice.
+func unmarshalOneOrMany(data []byte, v interface{}) error {
+	trimmed := strings.TrimSpace(string(data))
+
+	if trimmed == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, "[") {
+		return json.Unmarshal(data, v) // nolint: wrapcheck
+	}
+
+	return json.Unmarshal(append(append([]byte("["), data...), ']'), v) // nolint: wrapcheck
+}
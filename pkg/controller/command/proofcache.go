@@ -0,0 +1,373 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/trillian/types"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// maxNodeLookups bounds the lookups of the cache computing a proof, a proof needing more nodes is taken from
+// Trillian.
+const maxNodeLookups = 256
+
+// nodeRange is the range [start, end) of the leaves of a node of the tree, the hash of the node is the Merkle
+// tree hash of the leaves (RFC 6962 MTH). It never changes once the tree holds the leaves.
+type nodeRange struct {
+	start uint64
+	end   uint64
+}
+
+// splitPoint returns the size of the left subtree of a tree of size n > 1, the largest power of two below n.
+func splitPoint(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// inclusionRanges returns the ranges of the hashes of the audit path of the leaf in the tree (RFC 6962 PATH)
+// and the ranges of the subtrees they are the siblings in, from the leaf up to the root.
+func inclusionRanges(index, treeSize uint64) (siblings, subtrees []nodeRange) {
+	lo, hi := uint64(0), treeSize
+
+	for hi-lo > 1 {
+		k := splitPoint(hi - lo)
+
+		subtrees = append(subtrees, nodeRange{lo, hi})
+
+		if index < lo+k {
+			siblings = append(siblings, nodeRange{lo + k, hi})
+			hi = lo + k
+		} else {
+			siblings = append(siblings, nodeRange{lo, lo + k})
+			lo += k
+		}
+	}
+
+	for i, j := 0, len(siblings)-1; i < j; i, j = i+1, j-1 {
+		siblings[i], siblings[j] = siblings[j], siblings[i]
+		subtrees[i], subtrees[j] = subtrees[j], subtrees[i]
+	}
+
+	return siblings, subtrees
+}
+
+// consistencyRanges returns the ranges of the hashes of the consistency proof between the trees (RFC 6962
+// PROOF), 0 < first <= second.
+func consistencyRanges(first, second uint64) []nodeRange {
+	var subproof func(m, lo, hi uint64, complete bool) []nodeRange
+
+	subproof = func(m, lo, hi uint64, complete bool) []nodeRange {
+		if m == hi-lo {
+			if complete {
+				return nil
+			}
+
+			return []nodeRange{{lo, hi}}
+		}
+
+		k := splitPoint(hi - lo)
+
+		if m <= k {
+			return append(subproof(m, lo, lo+k, complete), nodeRange{lo + k, hi})
+		}
+
+		return append(subproof(m-k, lo+k, hi, false), nodeRange{lo, lo + k})
+	}
+
+	return subproof(first, 0, second, true)
+}
+
+type nodeKey struct {
+	alias string
+	nodeRange
+}
+
+type leafKey struct {
+	alias string
+	hash  string
+}
+
+// proofCache keeps the hashes of the nodes of the proofs served by Trillian and the indexes of their leaves, the
+// proofs sharing these nodes are computed from the cache. A node never changes.
+type proofCache struct {
+	mu     sync.Mutex
+	size   int
+	nodes  map[nodeKey][]byte
+	leaves map[leafKey]uint64
+}
+
+func newProofCache(size int) *proofCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &proofCache{size: size, nodes: map[nodeKey][]byte{}, leaves: map[leafKey]uint64{}}
+}
+
+func (c *proofCache) node(key nodeKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash, ok := c.nodes[key]
+
+	return hash, ok
+}
+
+// leafIndexIn returns the index of the first leaf of the hash if it is in the tree, false if it isn't cached.
+func (c *proofCache) leafIndexIn(alias string, leafHash []byte, treeSize uint64) (uint64, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	index, ok := c.leaves[leafKey{alias: alias, hash: string(leafHash)}]
+
+	return index, ok && index < treeSize
+}
+
+// putInclusion keeps the nodes of the audit path of the leaf and of the subtrees on the path, folded from the
+// leaf hash. A path not matching the tree size is not kept.
+func (c *proofCache) putInclusion(alias string, index, treeSize uint64, leafHash []byte, path [][]byte) {
+	siblings, subtrees := inclusionRanges(index, treeSize)
+	if len(path) != len(siblings) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict(len(path)*2 + 1)
+	c.setLeaf(alias, index, leafHash)
+
+	hash := leafHash
+
+	for i, sibling := range siblings {
+		if sibling.end <= index {
+			hash = hasher.DefaultHasher.HashChildren(path[i], hash)
+		} else {
+			hash = hasher.DefaultHasher.HashChildren(hash, path[i])
+		}
+
+		c.nodes[nodeKey{alias, sibling}] = path[i]
+		c.nodes[nodeKey{alias, subtrees[i]}] = hash
+	}
+}
+
+// putLeaf keeps the hash of the leaf and its index.
+func (c *proofCache) putLeaf(alias string, index uint64, leafHash []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict(1)
+	c.setLeaf(alias, index, leafHash)
+}
+
+func (c *proofCache) setLeaf(alias string, index uint64, leafHash []byte) {
+	key := leafKey{alias: alias, hash: string(leafHash)}
+
+	// the first leaf of the hash is kept, like the proofs by hash of Trillian
+	if known, ok := c.leaves[key]; !ok || index < known {
+		c.leaves[key] = index
+	}
+
+	c.nodes[nodeKey{alias, nodeRange{index, index + 1}}] = leafHash
+}
+
+// putConsistency keeps the nodes of the consistency proof, a proof not matching the tree sizes is not kept.
+func (c *proofCache) putConsistency(alias string, first, second uint64, proof [][]byte) {
+	ranges := consistencyRanges(first, second)
+	if len(proof) != len(ranges) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evict(len(proof))
+
+	for i, r := range ranges {
+		c.nodes[nodeKey{alias, r}] = proof[i]
+	}
+}
+
+// evict makes room for n nodes, it evicts arbitrary nodes and leaves like the tile cache.
+func (c *proofCache) evict(n int) {
+	for evicted := range c.nodes {
+		if len(c.nodes)+n <= c.size {
+			break
+		}
+
+		delete(c.nodes, evicted)
+	}
+
+	for evicted := range c.leaves {
+		if len(c.leaves) < c.size {
+			break
+		}
+
+		delete(c.leaves, evicted)
+	}
+}
+
+// nodeHash returns the hash of the node from the proof cache, from the cached tiles or folded from its cached
+// children, false if it isn't cached within the lookups left.
+func (c *Cmd) nodeHash(alias string, r nodeRange, lookups *int) ([]byte, bool) {
+	if *lookups <= 0 {
+		return nil, false
+	}
+
+	*lookups--
+
+	if hash, ok := c.proofs.node(nodeKey{alias, r}); ok {
+		return hash, true
+	}
+
+	if hash, ok := c.tileNodeHash(alias, r); ok {
+		return hash, true
+	}
+
+	if r.end-r.start < 2 { // nolint: gomnd
+		return nil, false
+	}
+
+	k := splitPoint(r.end - r.start)
+
+	left, ok := c.nodeHash(alias, nodeRange{r.start, r.start + k}, lookups)
+	if !ok {
+		return nil, false
+	}
+
+	right, ok := c.nodeHash(alias, nodeRange{r.start + k, r.end}, lookups)
+	if !ok {
+		return nil, false
+	}
+
+	return hasher.DefaultHasher.HashChildren(left, right), true
+}
+
+// tileNodeHash folds the hash of a complete subtree from the nodes of a cached full tile.
+func (c *Cmd) tileNodeHash(alias string, r nodeRange) ([]byte, bool) {
+	size := r.end - r.start
+	if size == 0 || size&(size-1) != 0 || r.start%size != 0 {
+		return nil, false
+	}
+
+	height := uint(bits.TrailingZeros64(size))
+	level, width := height/TileHeight, 1<<(height%TileHeight)
+	node := r.start >> (TileHeight * level)
+
+	hashes, ok := c.tiles.get(tileKey{alias: alias, level: level, index: node / TileWidth})
+	if !ok {
+		return nil, false
+	}
+
+	nodes := append([][]byte{}, hashes[node%TileWidth:node%TileWidth+uint64(width)]...)
+
+	for len(nodes) > 1 {
+		for i := 0; i < len(nodes)/2; i++ {
+			nodes[i] = hasher.DefaultHasher.HashChildren(nodes[2*i], nodes[2*i+1])
+		}
+
+		nodes = nodes[:len(nodes)/2]
+	}
+
+	return nodes[0], true
+}
+
+// cachedProof returns the hashes of the ranges, false if one of them isn't cached or the latest tree seen is
+// smaller than the tree size.
+func (c *Cmd) cachedProof(alias string, treeSize uint64, ranges []nodeRange) ([][]byte, bool) {
+	if c.proofs == nil || treeSize > c.tiles.treeSize(alias) {
+		return nil, false
+	}
+
+	lookups := maxNodeLookups
+	proof := make([][]byte, len(ranges))
+
+	for i, r := range ranges {
+		hash, ok := c.nodeHash(alias, r, &lookups)
+		if !ok {
+			proofCacheMisses.Inc(alias)
+
+			return nil, false
+		}
+
+		proof[i] = hash
+	}
+
+	proofCacheHits.Inc(alias)
+
+	return proof, true
+}
+
+// cachedInclusionProof returns the audit path of the leaf computed from the cache, false on a miss.
+func (c *Cmd) cachedInclusionProof(alias string, index, treeSize uint64) ([][]byte, bool) {
+	siblings, _ := inclusionRanges(index, treeSize)
+
+	return c.cachedProof(alias, treeSize, siblings)
+}
+
+// cachedConsistencyProof returns the consistency proof between the trees computed from the cache, false on
+// a miss.
+func (c *Cmd) cachedConsistencyProof(alias string, first, second uint64) ([][]byte, bool) {
+	return c.cachedProof(alias, second, consistencyRanges(first, second))
+}
+
+// cacheInclusionProof keeps the audit path served by Trillian, the tree size of the log root tells the latest
+// tree seen.
+func (c *Cmd) cacheInclusionProof(alias string, root *types.LogRootV1, index, treeSize uint64, leafHash []byte,
+	path [][]byte) {
+	if c.proofs == nil {
+		return
+	}
+
+	c.tiles.setTreeSize(alias, root.TreeSize)
+	c.proofs.putInclusion(alias, index, treeSize, leafHash, path)
+}
+
+// cacheConsistencyProof keeps the consistency proof served by Trillian.
+func (c *Cmd) cacheConsistencyProof(alias string, root *types.LogRootV1, first, second uint64, proof [][]byte) {
+	if c.proofs == nil {
+		return
+	}
+
+	c.tiles.setTreeSize(alias, root.TreeSize)
+	c.proofs.putConsistency(alias, first, second, proof)
+}
+
+// cachedLeaf reads the leaf of the audit path computed from the cache.
+func (c *Cmd) cachedLeaf(alias string, index int64) (*trillian.LogLeaf, error) {
+	req := trillian.GetLeavesByRangeRequest{LogId: c.logs[alias].ID, StartIndex: index, Count: 1}
+
+	var resp *trillian.GetLeavesByRangeResponse
+
+	err := c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLeavesByRange(context.Background(), &req)
+
+		return resp, er
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get leaves by range: %w", err)
+	}
+
+	if len(resp.GetLeaves()) != 1 || resp.Leaves[0].LeafIndex != index {
+		return nil, fmt.Errorf("%w: leaf %d is not returned", errors.ErrInternal, index)
+	}
+
+	return resp.Leaves[0], nil
+}
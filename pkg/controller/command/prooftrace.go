@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Steps of the generation of a proof.
+const (
+	traceStepProofCache = "proof_cache"
+	traceStepTrillian   = "trillian_rpc"
+	traceStepLeaf       = "leaf"
+	traceStepExtraData  = "extra_data"
+	traceStepAnnotation = "annotations"
+	traceStepEncode     = "encode"
+)
+
+// proofTracer times the steps of the generation of a proof in the debug mode, a nil tracer traces nothing.
+type proofTracer struct {
+	start time.Time
+	last  time.Time
+	trace ProofTrace
+}
+
+func newProofTracer(debug bool) *proofTracer {
+	if !debug {
+		return nil
+	}
+
+	now := time.Now()
+
+	return &proofTracer{start: now, last: now, trace: ProofTrace{Steps: []ProofTraceStep{}}}
+}
+
+// step records the time since the previous step as the step.
+func (t *proofTracer) step(name string) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now()
+
+	t.trace.Steps = append(t.trace.Steps, ProofTraceStep{Name: name, Micros: now.Sub(t.last).Microseconds()})
+	t.last = now
+}
+
+// cacheHit records whether the proof was computed from the proof cache.
+func (t *proofTracer) cacheHit(hit bool) {
+	if t == nil {
+		return
+	}
+
+	t.trace.CacheHit = hit
+}
+
+// encode encodes the response. In the debug mode the response is marshaled once to time the encoding, then the
+// trace is set (setTrace) and the response is encoded with it.
+func (t *proofTracer) encode(w io.Writer, response interface{}, setTrace func(*ProofTrace)) error {
+	if t == nil {
+		return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+	}
+
+	if _, err := json.Marshal(response); err != nil {
+		return fmt.Errorf("marshal response: %w", err)
+	}
+
+	t.step(traceStepEncode)
+	t.trace.TotalMicros = time.Since(t.start).Microseconds()
+
+	setTrace(&t.trace)
+
+	return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+}
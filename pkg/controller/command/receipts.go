@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// ReceiptStore persists the SCTs of the submissions with an idempotency key (e.g. a store of the storage provider).
+type ReceiptStore interface {
+	Put(key string, value []byte, tags ...storage.Tag) error
+	Get(key string) ([]byte, error)
+}
+
+// receiptKey returns the key of the receipt, idempotency keys are scoped by the log.
+func receiptKey(alias, idempotencyKey string) string {
+	hash := sha256.Sum256([]byte(idempotencyKey))
+
+	return alias + "/" + hex.EncodeToString(hash[:])
+}
+
+// storeReceipt persists the SCT of the submission. The SCT was issued, it is returned even if it can't be
+// persisted: a resubmission with the same idempotency key is deduplicated and persists it.
+func (c *Cmd) storeReceipt(alias, idempotencyKey string, sct *AddVCResponse) {
+	if c.receipts == nil || idempotencyKey == "" {
+		return
+	}
+
+	src, err := json.Marshal(sct)
+	if err != nil {
+		logger.Errorf("marshal receipt: %v", err)
+
+		return
+	}
+
+	if err = c.receipts.Put(receiptKey(alias, idempotencyKey), src); err != nil {
+		logger.Errorf("store receipt of log %s: %v", alias, err)
+	}
+}
+
+// GetReceipt retrieves the SCT issued for the submission with the idempotency key, it allows issuers to
+// recover lost SCTs without resubmitting.
+func (c *Cmd) GetReceipt(w io.Writer, r io.Reader) error {
+	var request *GetReceiptRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("%w: decode GetReceipt request: %v", errors.ErrBadRequest, err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetReceipt request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	if c.receipts == nil {
+		return errors.NewNotFoundError(fmt.Errorf("receipts are not persisted"))
+	}
+
+	src, err := c.receipts.Get(receiptKey(request.Alias, request.IdempotencyKey))
+	if goerrors.Is(err, storage.ErrDataNotFound) {
+		return errors.NewNotFoundError(fmt.Errorf("no receipt for the idempotency key"))
+	}
+
+	if err != nil {
+		return fmt.Errorf("get receipt: %w", err)
+	}
+
+	var sct *AddVCResponse
+	if err = json.Unmarshal(src, &sct); err != nil {
+		return errors.NewStatusInternalServerError(fmt.Errorf("unmarshal receipt: %w", err))
+	}
+
+	return json.NewEncoder(w).Encode(sct) // nolint: wrapcheck
+}
@@ -0,0 +1,289 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// DefaultReconciliationStaleAfter is the age a submission is reported stale at by default if it is not sequenced.
+const DefaultReconciliationStaleAfter = time.Hour
+
+// ReconciliationConfig configures the reconciliation of the counters of the logs with the leaves sequenced by
+// Trillian.
+type ReconciliationConfig struct {
+	// Interval the logs are reconciled at.
+	Interval time.Duration
+	// StaleAfter is the age a submission accepted by the instance is reported stale at if it is not sequenced yet
+	// (defaults to DefaultReconciliationStaleAfter).
+	StaleAfter time.Duration
+}
+
+// reconciliation keeps the latest reconciliation of the logs and the tree sizes it saw.
+type reconciliation struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	staleAfter time.Duration
+	latest     *ReconciliationReport
+	treeSizes  map[string]int64 // alias -> tree size of the latest reconciliation
+}
+
+func newReconciliation(cfg *ReconciliationConfig) *reconciliation {
+	if cfg == nil || cfg.Interval <= 0 {
+		return nil
+	}
+
+	staleAfter := cfg.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultReconciliationStaleAfter
+	}
+
+	return &reconciliation{interval: cfg.Interval, staleAfter: staleAfter, treeSizes: map[string]int64{}}
+}
+
+// ReconcileLogs reconciles the logs every interval until the context is done (see GetReconciliation), the drift
+// is reported by the reconciliation metrics and logged.
+func (c *Cmd) ReconcileLogs(ctx context.Context) {
+	if c.reconciliation == nil {
+		return
+	}
+
+	ticker := time.NewTicker(c.reconciliation.interval)
+	defer ticker.Stop()
+
+	for {
+		c.reconcile()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile compares the counters of the logs kept by the instance (the accepted submissions, the entries of the
+// dedup store and the leaves indexed) with the leaves sequenced by Trillian, the report is kept as the latest.
+func (c *Cmd) reconcile() *ReconciliationReport {
+	aliases := make([]string, 0, len(c.logs))
+	for alias := range c.logs {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	report := &ReconciliationReport{
+		Since: uint64(c.usage.since.UnixNano()) / uint64(time.Millisecond),
+		Logs:  make([]LogReconciliation, len(aliases)),
+	}
+
+	dedupEntries, err := c.countDedupEntries()
+	if err != nil {
+		logger.Warnf("count the entries of the dedup store: %v", err)
+	}
+
+	for i, alias := range aliases {
+		report.Logs[i] = c.reconcileLog(alias, dedupEntries)
+		report.Drift = report.Drift || report.Logs[i].Drift
+	}
+
+	report.Timestamp = uint64(time.Now().UnixNano()) / uint64(time.Millisecond)
+
+	c.reconciliation.mu.Lock()
+	c.reconciliation.latest = report
+	c.reconciliation.mu.Unlock()
+
+	return report
+}
+
+// nolint: funlen
+func (c *Cmd) reconcileLog(alias string, dedupEntries map[string]uint64) LogReconciliation {
+	result := LogReconciliation{Alias: alias}
+
+	treeSize, err := c.resolvePending(alias)
+	if err != nil {
+		result.Error = fmt.Sprintf("resolve pending submissions: %v", err)
+		result.Drift = true
+
+		logger.Warnf("reconcile log %s: %s", alias, result.Error)
+
+		return result
+	}
+
+	result.TreeSize = treeSize
+
+	c.reconciliation.mu.Lock()
+	previous, ok := c.reconciliation.treeSizes[alias]
+	c.reconciliation.treeSizes[alias] = treeSize
+	c.reconciliation.mu.Unlock()
+
+	if ok && treeSize < previous {
+		result.ShrunkFrom = previous
+	}
+
+	c.usage.mu.Lock()
+	result.Accepted = c.usage.logs[alias].Entries
+	c.usage.mu.Unlock()
+
+	result.Sequenced, result.Pending, result.Stale = c.pending.counts(alias, time.Now().Add(-c.reconciliation.staleAfter))
+
+	if accounted := result.Sequenced + result.Pending; result.Accepted > accounted {
+		result.Unaccounted = result.Accepted - accounted
+	}
+
+	if c.dedup != nil && dedupEntries != nil {
+		result.DedupEntries = dedupEntries[alias]
+
+		if logged := uint64(treeSize) + result.Pending; result.DedupEntries > logged {
+			result.DedupDrift = result.DedupEntries - logged
+		}
+	}
+
+	index := c.credentialIndexes[alias]
+
+	if err = c.indexCredentials(alias, index); err != nil {
+		result.Error = fmt.Sprintf("index credentials: %v", err)
+	}
+
+	index.mu.Lock()
+
+	result.IndexedLeaves = index.size
+	result.IndexedEntries = len(index.entries)
+
+	index.mu.Unlock()
+
+	if treeSize > result.IndexedLeaves {
+		result.IndexLag = treeSize - result.IndexedLeaves
+	}
+
+	result.Drift = result.Unaccounted > 0 || result.Stale > 0 || result.DedupDrift > 0 || result.IndexLag > 0 ||
+		result.ShrunkFrom > 0 || result.Error != ""
+
+	reconciliationUnaccounted.Set(float64(result.Unaccounted), alias)
+	reconciliationStale.Set(float64(result.Stale), alias)
+	reconciliationDedupDrift.Set(float64(result.DedupDrift), alias)
+	reconciliationIndexLag.Set(float64(result.IndexLag), alias)
+
+	if result.Drift {
+		logger.Warnf("log %s drifts from its tree of size %d: %d unaccounted, %d stale, dedup drift %d, "+
+			"index lag %d, shrunk from %d, error %q", alias, treeSize, result.Unaccounted, result.Stale,
+			result.DedupDrift, result.IndexLag, result.ShrunkFrom, result.Error)
+	}
+
+	return result
+}
+
+// counts returns the number of leaves of the log queued by the instance which were sequenced, the number of them
+// still pending (with the untracked ones) and the number of them pending since before the time.
+func (p *pendingSubmissions) counts(alias string, staleBefore time.Time) (uint64, uint64, uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log := p.logs[alias]
+
+	var stale uint64
+
+	for _, leaf := range log.leaves {
+		if leaf.queuedAt.Before(staleBefore) {
+			stale++
+		}
+	}
+
+	return log.sequencedCount, uint64(len(log.leaves)) + log.untracked, stale
+}
+
+// countDedupEntries counts the leaves of the dedup store per log, nil if there is no dedup store.
+func (c *Cmd) countDedupEntries() (map[string]uint64, error) {
+	if c.dedup == nil {
+		return nil, nil
+	}
+
+	iter, err := c.dedup.store.Query(DedupTagName, storage.WithPageSize(dedupLoadPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("query leaves: %w", err)
+	}
+
+	defer iter.Close() // nolint: errcheck
+
+	counts := map[string]uint64{}
+
+	for {
+		ok, er := iter.Next()
+		if er != nil {
+			return nil, fmt.Errorf("next leaf: %w", er)
+		}
+
+		if !ok {
+			return counts, nil
+		}
+
+		key, er := iter.Key()
+		if er != nil {
+			return nil, fmt.Errorf("leaf key: %w", er)
+		}
+
+		if i := strings.LastIndex(key, "/"); i > 0 {
+			counts[key[:i]]++
+		}
+	}
+}
+
+// GetReconciliation retrieves the latest reconciliation of the logs (ReconciliationReport), the logs are
+// reconciled if they were not yet or if the request asks to.
+func (c *Cmd) GetReconciliation(w io.Writer, r io.Reader) error {
+	var request GetReconciliationRequest
+
+	if r != nil {
+		if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+			return fmt.Errorf("%w: decode GetReconciliation request: %v", errors.ErrBadRequest, err)
+		}
+	}
+
+	if c.reconciliation == nil {
+		return errors.NewNotFoundError(fmt.Errorf("logs are not reconciled"))
+	}
+
+	if _, ok := c.logs[request.Alias]; request.Alias != "" && !ok {
+		return errors.NewNotFoundError(fmt.Errorf("log %q is not found", request.Alias))
+	}
+
+	c.reconciliation.mu.Lock()
+	report := c.reconciliation.latest
+	c.reconciliation.mu.Unlock()
+
+	if report == nil || request.Refresh {
+		report = c.reconcile()
+	}
+
+	if request.Alias == "" {
+		return json.NewEncoder(w).Encode(report) // nolint: wrapcheck
+	}
+
+	filtered := *report
+	filtered.Logs = []LogReconciliation{}
+	filtered.Drift = false
+
+	for _, log := range report.Logs {
+		if log.Alias == request.Alias {
+			filtered.Logs = append(filtered.Logs, log)
+			filtered.Drift = log.Drift
+		}
+	}
+
+	return json.NewEncoder(w).Encode(filtered) // nolint: wrapcheck
+}
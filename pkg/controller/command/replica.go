@@ -0,0 +1,54 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+)
+
+type signedLogRootResponse interface {
+	GetSignedLogRoot() *trillian.SignedLogRoot
+}
+
+// read calls the read replica of the log if the log has one, otherwise the primary is called.
+// The primary is used as a fallback if the replica fails or its log root is older than the staleness bound.
+func (c *Cmd) read(alias string, call func(client TrillianLogClient) (signedLogRootResponse, error)) error {
+	log := c.logs[alias]
+
+	if log.ReadClient != nil {
+		resp, err := call(log.ReadClient)
+		if err == nil && !c.isStale(resp) {
+			return nil
+		}
+
+		if err != nil {
+			logger.Warnf("read replica of %q failed, falling back to primary: %v", alias, err)
+		} else {
+			logger.Debugf("read replica of %q is stale, falling back to primary", alias)
+		}
+	}
+
+	_, err := call(log.Client)
+
+	return err
+}
+
+func (c *Cmd) isStale(resp signedLogRootResponse) bool {
+	if c.maxReplicaStaleness <= 0 {
+		return false
+	}
+
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return true
+	}
+
+	return time.Since(time.Unix(0, int64(root.TimestampNanos))) > c.maxReplicaStaleness
+}
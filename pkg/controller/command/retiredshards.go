@@ -0,0 +1,136 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// verifyRetiredShard verifies that the log ID of the shard is derived from its key and that its final tree head
+// freezes the log and is signed by its key.
+func verifyRetiredShard(shard *RetiredShard) error {
+	if logID := LogID(shard.PublicKey); !bytes.Equal(shard.LogID, logID[:]) {
+		return fmt.Errorf("log ID is not derived from the public key")
+	}
+
+	statement := shard.FinalTreeHead.Statement
+
+	if statement.Version != V1 || statement.SignatureType != FreezeSignatureType {
+		return fmt.Errorf("statement must be a v1 final tree head")
+	}
+
+	if !bytes.Equal(statement.LogID, shard.LogID) || statement.Alias != shard.Alias {
+		return fmt.Errorf("statement is issued for another log")
+	}
+
+	if err := VerifySignature(shard.FinalTreeHead.Signature, shard.PublicKey, statement); err != nil {
+		return fmt.Errorf("statement signature: %w", err)
+	}
+
+	return nil
+}
+
+// restoreRetiredShards restores the archive of the retired shards, the frozen logs which are not archived yet
+// (e.g. frozen before the archive was kept) are archived with the key of the log.
+func (c *Cmd) restoreRetiredShards(shards []RetiredShard) error {
+	for i := range shards {
+		if err := verifyRetiredShard(&shards[i]); err != nil {
+			return fmt.Errorf("retired shard %s: %w", shards[i].Alias, err)
+		}
+
+		c.retired = append(c.retired, shards[i])
+	}
+
+	for alias, final := range c.freezes {
+		if c.retiredShard(alias, c.VCLogID[:]) < 0 {
+			c.retired = append(c.retired, c.newRetiredShard(alias, final))
+		}
+	}
+
+	sort.SliceStable(c.retired, func(i, j int) bool {
+		return c.retired[i].FinalTreeHead.Statement.Timestamp < c.retired[j].FinalTreeHead.Statement.Timestamp
+	})
+
+	return nil
+}
+
+func (c *Cmd) newRetiredShard(alias string, final *SignedFinalTreeHead) RetiredShard {
+	return RetiredShard{
+		Alias:         alias,
+		Tenant:        c.logs[alias].tenant(),
+		LogID:         c.VCLogID[:],
+		PublicKey:     c.PubKey,
+		FinalTreeHead: *final,
+	}
+}
+
+// retiredShard returns the index of the archived shard, -1 if the shard is not archived.
+func (c *Cmd) retiredShard(alias string, logID []byte) int {
+	for i := range c.retired {
+		if c.retired[i].Alias == alias && bytes.Equal(c.retired[i].LogID, logID) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// retireShard archives the log frozen at the final tree head, the archive is persisted before the log is frozen.
+func (c *Cmd) retireShard(alias string, final *SignedFinalTreeHead) error {
+	shard := c.newRetiredShard(alias, final)
+
+	if c.onRetire != nil {
+		if err := c.onRetire(&shard); err != nil {
+			return fmt.Errorf("store retired shard: %w", err)
+		}
+	}
+
+	c.retiredMu.Lock()
+	defer c.retiredMu.Unlock()
+
+	// a shard archived by a freeze which failed to be stored is replaced
+	if i := c.retiredShard(alias, shard.LogID); i >= 0 {
+		c.retired = append(c.retired[:i], c.retired[i+1:]...)
+	}
+
+	c.retired = append(c.retired, shard)
+
+	return nil
+}
+
+// GetRetiredShards retrieves the archive of the retired shards of the service (GetRetiredShardsResponse): the
+// keys and the final tree heads of the frozen logs, including the logs no longer served. Every shard is
+// verifiable on its own, the archive serves the verifiers of old SCTs and proofs.
+func (c *Cmd) GetRetiredShards(w io.Writer, r io.Reader) error {
+	var request GetRetiredShardsRequest
+
+	if r != nil {
+		if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+			return fmt.Errorf("%w: decode GetRetiredShards request: %v", errors.ErrBadRequest, err)
+		}
+	}
+
+	resp := GetRetiredShardsResponse{Shards: []RetiredShard{}}
+
+	c.retiredMu.RLock()
+
+	for _, shard := range c.retired {
+		if request.Tenant == "" || shard.Tenant == request.Tenant {
+			resp.Shards = append(resp.Shards, shard)
+		}
+	}
+
+	c.retiredMu.RUnlock()
+
+	return json.NewEncoder(w).Encode(resp) // nolint: wrapcheck
+}
@@ -0,0 +1,160 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jwt"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/internal/pkg/scrub"
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// CreateRevocationLeaf creates a leaf for the revocation event.
+func CreateRevocationLeaf(timestamp uint64, event *RevocationEvent) (*MerkleTreeLeaf, error) {
+	entry, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal revocation event: %w", err)
+	}
+
+	return createEntryLeaf(timestamp, RevocationLogEntryType, entry), nil
+}
+
+// AddRevocation adds revocation event of a logged credential to log, the event must be signed by the issuer of
+// the credential.
+func (c *Cmd) AddRevocation(w io.Writer, r io.Reader) error {
+	var req *AddRevocationRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode AddRevocation request: %v", errors.ErrBadRequest, err)
+	}
+
+	if req == nil {
+		return fmt.Errorf("%w: empty AddRevocation request", errors.ErrBadRequest)
+	}
+
+	entry, err := json.Marshal(req.Event)
+	if err != nil {
+		return fmt.Errorf("marshal revocation event: %w", err)
+	}
+
+	resp, err := c.addEntry(req.Alias, RevocationLogEntryType, entry)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(resp) // nolint: wrapcheck
+}
+
+// checkRevocation checks that the event refers to a logged credential, it is signed by the issuer of the
+// credential and the credential is not revoked. The index is locked (see LeafType.Lock) through the
+// queueing of the event, so the queued events are checked too.
+func (c *Cmd) checkRevocation(alias string, event *RevocationEvent) error {
+	index := c.credentialIndexes[alias]
+
+	leafHash := base64.StdEncoding.EncodeToString(event.LeafHash)
+
+	info, ok := index.credentials[string(event.LeafHash)]
+	if !ok {
+		return errors.NewNotFoundError(fmt.Errorf("credential with leaf hash %s is not logged", leafHash))
+	}
+
+	if err := c.verifyRevocation(event, info.Issuer); err != nil {
+		return err
+	}
+
+	if index.status(event.LeafHash) == RevokedStatus {
+		return errors.NewBadRequestError(fmt.Errorf("credential with leaf hash %s is revoked", leafHash))
+	}
+
+	return nil
+}
+
+// verifyRevocation verifies the JWS of the event with the key of its issuer resolved through the VDR, the
+// issuer must be the issuer of the credential and the claims must match the event.
+func (c *Cmd) verifyRevocation(event *RevocationEvent, issuer string) error {
+	if issuer == "" {
+		return errors.NewBadRequestError(fmt.Errorf("credential with leaf hash %s has no issuer",
+			base64.StdEncoding.EncodeToString(event.LeafHash)))
+	}
+
+	token, err := jwt.Parse(event.JWS, jwt.WithSignatureVerifier(jwt.NewVerifier(
+		jwt.KeyResolverFunc(verifiable.NewVDRKeyResolver(c.vdr).PublicKeyFetcher()),
+	)))
+	if err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("verify jws: %w", scrub.Error(err, []byte(event.JWS))))
+	}
+
+	var claims RevocationClaims
+	if err = token.DecodeClaims(&claims); err != nil {
+		return errors.NewBadRequestError(fmt.Errorf("decode jws claims: %w", err))
+	}
+
+	if claims.Issuer != issuer {
+		return errors.NewForbiddenError(fmt.Errorf("event is signed by %s, not by the issuer %s of the credential",
+			claims.Issuer, issuer))
+	}
+
+	if !bytes.Equal(claims.LeafHash, event.LeafHash) || claims.Status != event.Status ||
+		claims.Reason != event.Reason {
+		return errors.NewBadRequestError(fmt.Errorf("jws claims do not match the event"))
+	}
+
+	return nil
+}
+
+// GetRevocations retrieves revocation events of the credential in the order they were logged.
+func (c *Cmd) GetRevocations(w io.Writer, r io.Reader) error {
+	var request *GetRevocationsRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetRevocations request: %w", err)
+	}
+
+	if request == nil {
+		return fmt.Errorf("%w: empty GetRevocations request", errors.ErrBadRequest)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	index, err := c.lockIndex(request.Alias)
+	if err != nil {
+		return fmt.Errorf("index credentials: %w", err)
+	}
+
+	defer index.mu.Unlock()
+
+	if _, ok := index.credentials[string(request.LeafHash)]; !ok {
+		return errors.NewNotFoundError(fmt.Errorf("credential with leaf hash %s is not logged",
+			base64.StdEncoding.EncodeToString(request.LeafHash)))
+	}
+
+	events := index.revocations[string(request.LeafHash)]
+	if events == nil {
+		events = []RevocationRecord{}
+	}
+
+	begin, end, next, err := paginate(GetRevocations+"/"+request.Alias+"/"+string(request.LeafHash),
+		request.PageRequest, len(events))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(GetRevocationsResponse{ // nolint: wrapcheck
+		LeafHash:      request.LeafHash,
+		Events:        events[begin:end],
+		NextPageToken: next,
+	})
+}
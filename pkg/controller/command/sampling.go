@@ -0,0 +1,124 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// MaxRandomEntries is the max number of entries of a sample.
+const MaxRandomEntries = 100
+
+// sampleDomain separates the hashes the sample is derived from from the other hashes of the log.
+const sampleDomain = "vct-sample-v1"
+
+// GetRandomEntries retrieves a pseudo-random sample of the entries of the latest signed tree head with their audit
+// paths. The sample is derived from the seed and the root hash of the tree (SampleIndices), so anyone can check
+// that the entries are the sample of the STH and spot audit the log without downloading all of its entries.
+// The sample is always read from the primary, so all proofs refer to the tree of the STH.
+func (c *Cmd) GetRandomEntries(w io.Writer, r io.Reader) error {
+	var request *GetRandomEntriesRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetRandomEntries request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetRandomEntries request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	log := c.logs[request.Alias]
+
+	root, err := primaryLogRoot(log)
+	if err != nil {
+		return err
+	}
+
+	sth, err := c.signedTreeHead(*root)
+	if err != nil {
+		return err
+	}
+
+	indices := SampleIndices(request.Seed, root.RootHash, root.TreeSize, request.Count)
+	entries := make([]AuditEntry, 0, len(indices))
+
+	for _, index := range indices {
+		entry, er := c.auditEntry(log, index, int64(root.TreeSize))
+		if er != nil {
+			return er
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	return json.NewEncoder(w).Encode(GetRandomEntriesResponse{ // nolint: wrapcheck
+		Seed:    request.Seed,
+		STH:     *sth,
+		Entries: entries,
+	})
+}
+
+// SampleIndices returns the distinct leaf indices of the sample of count entries (all the entries if the tree is
+// smaller) of the tree of the size and the root hash. The indices are drawn uniformly from the hash chain
+// SHA-256(SHA-256(sampleDomain || root hash || seed) || counter), the counter being a big-endian uint64.
+func SampleIndices(seed string, rootHash []byte, treeSize uint64, count int) []int64 {
+	if treeSize == 0 || count <= 0 {
+		return []int64{}
+	}
+
+	if uint64(count) > treeSize {
+		count = int(treeSize)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(sampleDomain)) // nolint: errcheck,gosec
+	h.Write(rootHash)             // nolint: errcheck,gosec
+	h.Write([]byte(seed))         // nolint: errcheck,gosec
+
+	key := h.Sum(nil)
+
+	// values from limit up are rejected so each index is equally likely
+	limit := math.MaxUint64 - math.MaxUint64%treeSize
+
+	var (
+		indices = make([]int64, 0, count)
+		drawn   = make(map[uint64]struct{}, count)
+		block   [sha256.Size + 8]byte
+	)
+
+	copy(block[:], key)
+
+	for counter := uint64(0); len(indices) < count; counter++ {
+		binary.BigEndian.PutUint64(block[sha256.Size:], counter)
+
+		sum := sha256.Sum256(block[:])
+
+		value := binary.BigEndian.Uint64(sum[:8])
+		if value >= limit {
+			continue
+		}
+
+		index := value % treeSize
+		if _, ok := drawn[index]; ok {
+			continue
+		}
+
+		drawn[index] = struct{}{}
+		indices = append(indices, int64(index))
+	}
+
+	return indices
+}
@@ -0,0 +1,120 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SCTExtensionType identifies an SCT extension, like the extension types of the SCTs of CT v2 (RFC 9162).
+type SCTExtensionType uint16
+
+// The registered SCT extensions. A verifier ignores the extensions of the types it does not know, they are signed
+// with the SCT anyway, so new signed metadata is added to the SCTs without breaking the verifiers.
+const (
+	// SCTExtensionShardID is the alias of the log (the shard) the entry is logged to, UTF-8.
+	SCTExtensionShardID SCTExtensionType = 1
+	// SCTExtensionPolicyTags are the PolicyTags of the entry, JSON.
+	SCTExtensionPolicyTags SCTExtensionType = 2
+	// SCTExtensionAnchorCommitment is the SHA-256 hash of the anchored tree head (the STHAnchor entry) of
+	// an add-anchor SCT.
+	SCTExtensionAnchorCommitment SCTExtensionType = 3
+)
+
+// nolint: gochecknoglobals
+var sctExtensionNames = map[SCTExtensionType]string{
+	SCTExtensionShardID:          "shard-id",
+	SCTExtensionPolicyTags:       "policy-tags",
+	SCTExtensionAnchorCommitment: "anchor-commitment",
+}
+
+// SCTExtension is an extension of an SCT, signed with it.
+type SCTExtension struct {
+	Type SCTExtensionType `json:"type"`
+	Data []byte           `json:"data"`
+}
+
+// String returns the registered name of the type, e.g. shard-id.
+func (t SCTExtensionType) String() string {
+	if name, ok := sctExtensionNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint16(t))
+}
+
+// ParseSCTExtensionType returns the registered type of the name, e.g. shard-id.
+func ParseSCTExtensionType(name string) (SCTExtensionType, error) {
+	for t, n := range sctExtensionNames {
+		if n == name {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("SCT extension %q is not registered", name)
+}
+
+// sctExtensions returns the extensions of the types the log adds to the SCT of the logged leaf, ordered by type.
+// An extension which does not apply to the leaf (e.g. the policy tags of an untagged entry) is not added.
+func (c *Cmd) sctExtensions(alias string, leaf *MerkleTreeLeaf) ([]SCTExtension, error) {
+	var extensions []SCTExtension
+
+	for _, t := range c.sctExtensionTypes {
+		switch t {
+		case SCTExtensionShardID:
+			extensions = append(extensions, SCTExtension{Type: t, Data: []byte(alias)})
+		case SCTExtensionPolicyTags:
+			tags := entryExtensions(leaf.TimestampedEntry.Extensions).PolicyTags
+			if tags == nil {
+				continue
+			}
+
+			data, err := json.Marshal(tags)
+			if err != nil {
+				return nil, fmt.Errorf("marshal policy tags: %w", err)
+			}
+
+			extensions = append(extensions, SCTExtension{Type: t, Data: data})
+		case SCTExtensionAnchorCommitment:
+			if leaf.TimestampedEntry.EntryType != STHAnchorLogEntryType {
+				continue
+			}
+
+			commitment := sha256.Sum256(leaf.TimestampedEntry.VCEntry)
+
+			extensions = append(extensions, SCTExtension{Type: t, Data: commitment[:]})
+		}
+	}
+
+	return extensions, nil
+}
+
+// newSCTExtensionTypes returns the registered types, ordered and unique.
+func newSCTExtensionTypes(types []SCTExtensionType) ([]SCTExtensionType, error) {
+	seen := map[SCTExtensionType]bool{}
+
+	var unique []SCTExtensionType
+
+	for _, t := range types {
+		if _, ok := sctExtensionNames[t]; !ok {
+			return nil, fmt.Errorf("SCT extension %d is not registered", uint16(t))
+		}
+
+		if !seen[t] {
+			seen[t] = true
+
+			unique = append(unique, t)
+		}
+	}
+
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+
+	return unique, nil
+}
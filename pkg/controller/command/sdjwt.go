@@ -0,0 +1,218 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	sdJWTSeparator  = "~"
+	sdDigestsClaim  = "_sd"
+	sdAlgClaim      = "_sd_alg"
+	sdArrayElement  = "..."
+	sdAlgSHA256     = "sha-256"
+	jwtParts        = 3
+	disclosureParts = 3
+)
+
+// SDJWT is an SD-JWT (selective disclosure for JWTs) credential in the compact format
+// <issuer-signed JWT>~<disclosure>~...~<key binding JWT>.
+//
+// Only the issuer-signed JWT is logged. Its claims commit to the selectively disclosable claims by their digests
+// (_sd), so the log makes transparent that the issuer issued the credential with these digests, but neither the
+// disclosed values nor which of them the holder disclosed. The disclosures and the key binding JWT differ between
+// presentations of the same credential and are never logged: the leaf, and so the SCT, is the same for every
+// presentation.
+type SDJWT struct {
+	IssuerJWT   string
+	Disclosures []string
+	KeyBinding  string
+}
+
+// IsSDJWT returns true if the credential is in the SD-JWT compact format.
+func IsSDJWT(credential []byte) bool {
+	return !json.Valid(credential) && bytes.Contains(credential, []byte(sdJWTSeparator))
+}
+
+// ParseSDJWT parses the SD-JWT and checks that every disclosure matches a digest of the issuer-signed payload
+// or of another disclosure. The signature of the issuer-signed JWT is not verified.
+func ParseSDJWT(raw string) (*SDJWT, error) {
+	parts := strings.Split(raw, sdJWTSeparator)
+	if len(parts) < 2 { // nolint: gomnd
+		return nil, fmt.Errorf("SD-JWT must have at least one %q separator", sdJWTSeparator)
+	}
+
+	sdJWT := &SDJWT{
+		IssuerJWT:   parts[0],
+		Disclosures: parts[1 : len(parts)-1],
+		KeyBinding:  parts[len(parts)-1],
+	}
+
+	payload, err := jwtPayload(sdJWT.IssuerJWT)
+	if err != nil {
+		return nil, fmt.Errorf("issuer-signed JWT: %w", err)
+	}
+
+	if err = checkSDAlg(payload); err != nil {
+		return nil, err
+	}
+
+	if err = verifyDisclosures(payload, sdJWT.Disclosures); err != nil {
+		return nil, err
+	}
+
+	return sdJWT, nil
+}
+
+func jwtPayload(jwt string) (map[string]interface{}, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != jwtParts {
+		return nil, fmt.Errorf("JWT must have %d parts", jwtParts)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+
+	var payload map[string]interface{}
+	if err = json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	return payload, nil
+}
+
+// checkSDAlg checks the hash algorithm of the digests, set either in the payload or in its vc claim.
+func checkSDAlg(payload map[string]interface{}) error {
+	alg, ok := payload[sdAlgClaim]
+	if vc, isVC := payload["vc"].(map[string]interface{}); !ok && isVC {
+		alg, ok = vc[sdAlgClaim]
+	}
+
+	if ok && alg != sdAlgSHA256 {
+		return fmt.Errorf("%s %v is not supported", sdAlgClaim, alg)
+	}
+
+	return nil
+}
+
+// verifyDisclosures checks that every disclosure is committed to by a digest. Disclosures may be nested, so a
+// disclosure matches a digest of the payload or of a matched disclosure.
+func verifyDisclosures(payload map[string]interface{}, disclosures []string) error {
+	values := make([]interface{}, len(disclosures))
+	byDigest := map[string]int{}
+
+	for i, disclosure := range disclosures {
+		value, err := decodeDisclosure(disclosure)
+		if err != nil {
+			return fmt.Errorf("disclosure %d: %w", i, err)
+		}
+
+		digest := sha256.Sum256([]byte(disclosure))
+		key := base64.RawURLEncoding.EncodeToString(digest[:])
+
+		if _, ok := byDigest[key]; ok {
+			return fmt.Errorf("disclosure %d is repeated", i)
+		}
+
+		values[i] = value
+		byDigest[key] = i
+	}
+
+	matched := make([]bool, len(disclosures))
+	pending := collectDigests(payload, nil)
+
+	for len(pending) > 0 {
+		digest := pending[0]
+		pending = pending[1:]
+
+		i, ok := byDigest[digest]
+		if !ok || matched[i] {
+			continue
+		}
+
+		matched[i] = true
+		pending = collectDigests(values[i], pending)
+	}
+
+	for i := range disclosures {
+		if !matched[i] {
+			return fmt.Errorf("disclosure %d does not match any digest", i)
+		}
+	}
+
+	return nil
+}
+
+// decodeDisclosure decodes the disclosure [<salt>, <name>, <value>] of an object property
+// or [<salt>, <value>] of an array element and returns its value.
+func decodeDisclosure(disclosure string) (interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(disclosure)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	var parts []interface{}
+	if err = json.Unmarshal(raw, &parts); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if len(parts) != disclosureParts && len(parts) != disclosureParts-1 {
+		return nil, fmt.Errorf("must have %d or %d elements", disclosureParts-1, disclosureParts)
+	}
+
+	if _, ok := parts[0].(string); !ok {
+		return nil, fmt.Errorf("salt must be a string")
+	}
+
+	if len(parts) == disclosureParts {
+		name, ok := parts[1].(string)
+		if !ok || name == sdDigestsClaim || name == sdArrayElement {
+			return nil, fmt.Errorf("claim name must be a string other than %s and %s", sdDigestsClaim, sdArrayElement)
+		}
+	}
+
+	return parts[len(parts)-1], nil
+}
+
+// collectDigests appends the digests of the selectively disclosable object properties (_sd) and array
+// elements ({"...": <digest>}) of the value.
+func collectDigests(value interface{}, digests []string) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if sd, ok := v[sdDigestsClaim].([]interface{}); ok {
+			for _, digest := range sd {
+				if s, isString := digest.(string); isString {
+					digests = append(digests, s)
+				}
+			}
+		}
+
+		if digest, ok := v[sdArrayElement].(string); ok && len(v) == 1 {
+			digests = append(digests, digest)
+		}
+
+		for name, val := range v {
+			if name != sdDigestsClaim {
+				digests = collectDigests(val, digests)
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			digests = collectDigests(val, digests)
+		}
+	}
+
+	return digests
+}
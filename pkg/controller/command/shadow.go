@@ -0,0 +1,223 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// maxShadowDivergences is the number of the most recent divergences kept per log.
+	maxShadowDivergences = 100
+	// shadowQueueSize is the number of leaves waiting to be mirrored per log, a leaf accepted while the queue is
+	// full is not mirrored and recorded as a failure.
+	shadowQueueSize = 1000
+	// shadowTimeout bounds the time the shadow log takes to queue a leaf.
+	shadowTimeout = 5 * time.Second
+)
+
+// shadow mirrors the writes of a log to its shadow log in the background and keeps the outcome.
+type shadow struct {
+	alias  string
+	logID  int64
+	client TrillianLogClient
+	queue  chan mirroredLeaf
+
+	mu          sync.Mutex
+	draining    bool
+	mirrored    uint64
+	failed      uint64
+	diverged    uint64
+	divergences []ShadowDivergence
+}
+
+// mirroredLeaf is a leaf accepted by the primary log to be mirrored.
+type mirroredLeaf struct {
+	leaf    *trillian.LogLeaf
+	primary *trillian.QueuedLogLeaf
+}
+
+func newShadows(logs map[string]Log) map[string]*shadow {
+	shadows := map[string]*shadow{}
+
+	for alias, log := range logs {
+		if log.ShadowClient != nil {
+			shadows[alias] = &shadow{
+				alias:  alias,
+				logID:  log.ShadowID,
+				client: log.ShadowClient,
+				queue:  make(chan mirroredLeaf, shadowQueueSize),
+			}
+		}
+	}
+
+	return shadows
+}
+
+func (s *shadow) record(leaf *trillian.LogLeaf, failed bool, reason string) {
+	logger.Warnf("shadow log of %q diverged: %s", s.alias, reason)
+	shadowDivergences.Add(1, s.alias)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if failed {
+		s.failed++
+	} else {
+		s.diverged++
+	}
+
+	s.divergences = append(s.divergences, ShadowDivergence{
+		Timestamp:        uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		LeafIdentityHash: leaf.LeafIdentityHash,
+		Reason:           reason,
+	})
+
+	if len(s.divergences) > maxShadowDivergences {
+		s.divergences = s.divergences[len(s.divergences)-maxShadowDivergences:]
+	}
+}
+
+// enqueue queues the leaf to be mirrored, the queue is drained in the background in order.
+func (s *shadow) enqueue(leaf *trillian.LogLeaf, primary *trillian.QueuedLogLeaf) {
+	select {
+	case s.queue <- mirroredLeaf{leaf: leaf, primary: primary}:
+	default:
+		s.record(leaf, true, "shadow queue is full")
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.draining {
+		s.draining = true
+
+		go s.drain()
+	}
+}
+
+// drain mirrors the queued leaves until the queue is empty.
+func (s *shadow) drain() {
+	for {
+		select {
+		case l := <-s.queue:
+			s.mirror(l.leaf, l.primary)
+		default:
+			s.mu.Lock()
+
+			if len(s.queue) == 0 {
+				s.draining = false
+				s.mu.Unlock()
+
+				return
+			}
+
+			s.mu.Unlock()
+		}
+	}
+}
+
+// mirror queues the leaf to the shadow log, a failure or a different outcome is recorded as a divergence.
+func (s *shadow) mirror(leaf *trillian.LogLeaf, primary *trillian.QueuedLogLeaf) {
+	ctx, cancel := context.WithTimeout(context.Background(), shadowTimeout)
+	defer cancel()
+
+	resp, err := s.client.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: s.logID, Leaf: leaf})
+
+	var (
+		failed bool
+		reason string
+	)
+
+	switch {
+	case err != nil:
+		failed, reason = true, fmt.Sprintf("queue leaf: %v", err)
+	case resp.GetQueuedLeaf() == nil:
+		failed, reason = true, "no leaf"
+	case resp.GetQueuedLeaf().GetStatus().GetCode() != primary.GetStatus().GetCode():
+		reason = fmt.Sprintf("status code %d, primary %d", resp.GetQueuedLeaf().GetStatus().GetCode(),
+			primary.GetStatus().GetCode())
+	case !bytes.Equal(resp.GetQueuedLeaf().GetLeaf().GetMerkleLeafHash(), primary.GetLeaf().GetMerkleLeafHash()):
+		reason = "merkle leaf hash does not match primary"
+	default:
+		s.mu.Lock()
+		s.mirrored++
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.record(leaf, failed, reason)
+}
+
+// mirrorLeaf queues the leaf accepted by the primary log to be mirrored to the shadow log of the alias, if any.
+// The outcome never affects the write: the leaf is mirrored in the background, a failure, a timeout or a
+// different outcome is recorded as a divergence.
+func (c *Cmd) mirrorLeaf(alias string, leaf *trillian.LogLeaf, primary *trillian.QueuedLogLeaf) {
+	if s, ok := c.shadows[alias]; ok {
+		s.enqueue(leaf, primary)
+	}
+}
+
+// GetShadowStatus retrieves the outcome of mirroring writes of the log to its shadow log.
+func (c *Cmd) GetShadowStatus(w io.Writer, r io.Reader) error {
+	var alias string
+
+	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+		return fmt.Errorf("decode GetShadowStatus request: %w", err)
+	}
+
+	if err := c.hasPermissions(alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	s, ok := c.shadows[alias]
+	if !ok {
+		return errors.NewNotFoundError(fmt.Errorf("log %q has no shadow", alias))
+	}
+
+	primaryTreeSize, err := c.treeSize(alias)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.logs[alias].ShadowClient.GetLatestSignedLogRoot(context.Background(),
+		&trillian.GetLatestSignedLogRootRequest{LogId: c.logs[alias].ShadowID})
+	if err != nil {
+		return fmt.Errorf("get latest signed log root of shadow: %w", err)
+	}
+
+	var root types.LogRootV1
+	if err = root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(GetShadowStatusResponse{ // nolint: wrapcheck
+		Mirrored:        s.mirrored,
+		Failed:          s.failed,
+		Diverged:        s.diverged,
+		PrimaryTreeSize: primaryTreeSize,
+		ShadowTreeSize:  int64(root.TreeSize),
+		Divergences:     append([]ShadowDivergence{}, s.divergences...),
+	})
+}
@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// The signing inputs of the tree heads and of the SCTs are serialized canonically, so verifiers in other languages
+// can rebuild them byte for byte without mimicking encoding/json:
+//
+//   - a JSON object without whitespace, with the members in the order of the specification;
+//   - the integers as unsigned decimals without leading zeros (0 is "0");
+//   - the byte strings as standard base64 with padding (RFC 4648, section 4) between double quotes, or null if
+//     they are absent (an empty byte string is "");
+//   - no other strings, so nothing is escaped.
+//
+// The tree head (TreeHeadSignature) is serialized as
+//
+//	{"version":V,"signature_type":101,"timestamp":T,"tree_size":N,"sha_256_root_hash":B}
+//
+// and the SCT (VCTimestampSignature) as
+//
+//	{"svct_version":V,"signature_type":100,"timestamp":T,"entry_type":E,"vc_entry":B,"extensions":B}
+//
+// with the member "sct_extensions":[{"type":N,"data":B},...] after "extensions" if the SCT has SCT extensions.
+// The serialization is the encoding/json serialization of the structures, which predates it.
+
+// SigningBytesTreeHead returns the canonical signing input of the tree head.
+func SigningBytesTreeHead(sth TreeHeadSignature) []byte {
+	var b signingBytes
+
+	b.object()
+	b.uint("version", uint64(sth.Version))
+	b.uint("signature_type", uint64(sth.SignatureType))
+	b.uint("timestamp", sth.Timestamp)
+	b.uint("tree_size", sth.TreeSize)
+	b.bytes("sha_256_root_hash", sth.SHA256RootHash)
+
+	return b.end()
+}
+
+// SigningBytesSCT returns the canonical signing input of the SCT.
+func SigningBytesSCT(sct VCTimestampSignature) []byte {
+	var b signingBytes
+
+	b.object()
+	b.uint("svct_version", uint64(sct.SVCTVersion))
+	b.uint("signature_type", uint64(sct.SignatureType))
+	b.uint("timestamp", sct.Timestamp)
+	b.uint("entry_type", uint64(sct.EntryType))
+	b.bytes("vc_entry", sct.VCEntry)
+	b.bytes("extensions", sct.Extensions)
+
+	if len(sct.SCTExtensions) > 0 {
+		b.name("sct_extensions")
+		b.buf = append(b.buf, '[')
+
+		for i, extension := range sct.SCTExtensions {
+			if i > 0 {
+				b.buf = append(b.buf, ',')
+			}
+
+			var e signingBytes
+
+			e.object()
+			e.uint("type", uint64(extension.Type))
+			e.bytes("data", extension.Data)
+
+			b.buf = append(b.buf, e.end()...)
+		}
+
+		b.buf = append(b.buf, ']')
+	}
+
+	return b.end()
+}
+
+// MarshalJSON marshals the tree head canonically, see SigningBytesTreeHead.
+func (s TreeHeadSignature) MarshalJSON() ([]byte, error) {
+	return SigningBytesTreeHead(s), nil
+}
+
+// MarshalJSON marshals the SCT canonically, see SigningBytesSCT.
+func (s VCTimestampSignature) MarshalJSON() ([]byte, error) {
+	return SigningBytesSCT(s), nil
+}
+
+// signingBytes builds a canonical signing input.
+type signingBytes struct {
+	buf     []byte
+	members int
+}
+
+func (b *signingBytes) object() {
+	b.buf = append(b.buf, '{')
+}
+
+func (b *signingBytes) name(name string) {
+	if b.members > 0 {
+		b.buf = append(b.buf, ',')
+	}
+
+	b.members++
+
+	b.buf = append(b.buf, '"')
+	b.buf = append(b.buf, name...)
+	b.buf = append(b.buf, '"', ':')
+}
+
+func (b *signingBytes) uint(name string, v uint64) {
+	b.name(name)
+	b.buf = strconv.AppendUint(b.buf, v, 10) // nolint: gomnd
+}
+
+func (b *signingBytes) bytes(name string, v []byte) {
+	b.name(name)
+
+	if v == nil {
+		b.buf = append(b.buf, "null"...)
+
+		return
+	}
+
+	b.buf = append(b.buf, '"')
+	b.buf = append(b.buf, base64.StdEncoding.EncodeToString(v)...)
+	b.buf = append(b.buf, '"')
+}
+
+func (b *signingBytes) end() []byte {
+	return append(b.buf, '}')
+}
@@ -0,0 +1,249 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/webhook"
+)
+
+// KeyPurpose is the purpose a signing key serves.
+type KeyPurpose string
+
+// Key purposes.
+const (
+	// LogKeyPurpose is the purpose of the key of the log: it signs the SCTs, the tree heads and the statements of
+	// the log (map roots, proofs of absence, audit exports).
+	LogKeyPurpose KeyPurpose = "log"
+	// ResponseKeyPurpose is the purpose of the key signing the responses of the REST API.
+	ResponseKeyPurpose KeyPurpose = "response"
+	// WitnessKeyPurpose is the purpose of the key cosigning the tree heads of the logs anchored to the log.
+	WitnessKeyPurpose KeyPurpose = "witness"
+	// AdminKeyPurpose is the purpose of the key signing the responses of the admin API.
+	AdminKeyPurpose KeyPurpose = "admin"
+	// WebhookKeyPurpose is the purpose of the key signing the webhooks (the callbacks of the submissions and the
+	// tree heads pushed to the distributors), the key of the log signs them if there is no webhook key.
+	WebhookKeyPurpose KeyPurpose = "webhook"
+)
+
+// nolint: gochecknoglobals
+var keyPurposes = []KeyPurpose{
+	LogKeyPurpose, ResponseKeyPurpose, WitnessKeyPurpose, AdminKeyPurpose, WebhookKeyPurpose,
+}
+
+// ParseKeyPurpose parses the purpose of a signing key other than the key of the log.
+func ParseKeyPurpose(s string) (KeyPurpose, error) {
+	for _, purpose := range keyPurposes[1:] {
+		if s == string(purpose) {
+			return purpose, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown key purpose %q", s)
+}
+
+// signingKey is a key of the KMS.
+type signingKey struct {
+	kh     interface{}
+	alg    *SignatureAndHashAlgorithm
+	pubKey []byte
+}
+
+func newSigningKey(km KeyManager, key Key) (*signingKey, error) {
+	pubBytes, keyType, err := km.ExportPubKeyBytes(key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("export pub key bytes: %w", err)
+	}
+
+	if len(pubBytes) == 0 {
+		return nil, fmt.Errorf("public key is empty")
+	}
+
+	alg, err := SignatureAndHashAlgorithmByKeyType(keyType)
+	if err != nil {
+		return nil, fmt.Errorf("key type %v is not supported", keyType)
+	}
+
+	kh, err := km.Get(key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("kms get kh: %w", err)
+	}
+
+	return &signingKey{kh: kh, alg: alg, pubKey: pubBytes}, nil
+}
+
+// newPurposeKeys returns the keys of the purposes, the key of the log can't serve another purpose.
+func newPurposeKeys(km KeyManager, logKey Key, keys map[KeyPurpose]Key) (map[KeyPurpose]*signingKey, error) {
+	purposeKeys := map[KeyPurpose]*signingKey{}
+
+	for purpose, key := range keys {
+		if _, err := ParseKeyPurpose(string(purpose)); err != nil {
+			return nil, err
+		}
+
+		if key.ID == logKey.ID {
+			return nil, fmt.Errorf("the key of the log can't serve the %s purpose", purpose)
+		}
+
+		signingKey, err := newSigningKey(km, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s key: %w", purpose, err)
+		}
+
+		purposeKeys[purpose] = signingKey
+	}
+
+	return purposeKeys, nil
+}
+
+// keysMetadata returns the metadata of the log key and of the keys of the other purposes.
+func (c *Cmd) keysMetadata() []KeyMetadata {
+	metadata := []KeyMetadata{{
+		Purpose:   LogKeyPurpose,
+		KeyID:     c.VCLogID[:],
+		PublicKey: c.PubKey,
+		Algorithm: *c.alg,
+	}}
+
+	for _, purpose := range keyPurposes[1:] {
+		key, ok := c.purposeKeys[purpose]
+		if !ok {
+			continue
+		}
+
+		keyID := sha256.Sum256(key.pubKey)
+
+		metadata = append(metadata, KeyMetadata{
+			Purpose:   purpose,
+			KeyID:     keyID[:],
+			PublicKey: key.pubKey,
+			Algorithm: *key.alg,
+		})
+	}
+
+	return metadata
+}
+
+// signWith signs the statement with the key of the purpose, nil if there is no key for the purpose. The signature is
+// counted in the key usage of the kind of the purpose.
+func (c *Cmd) signWith(purpose KeyPurpose, statement interface{}) ([]byte, error) {
+	key, ok := c.purposeKeys[purpose]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	signature, err := c.signBytesWith(key.kh, signatureKindOf[purpose], data)
+	if err != nil {
+		return nil, fmt.Errorf("sign with %s key: %w", purpose, err)
+	}
+
+	return json.Marshal(DigitallySigned{ // nolint: wrapcheck
+		Algorithm: *key.alg,
+		Signature: signature,
+	})
+}
+
+// SignsResponses reports whether there is a key signing the responses of the purpose.
+func (c *Cmd) SignsResponses(purpose KeyPurpose) bool {
+	_, ok := c.purposeKeys[purpose]
+
+	return ok
+}
+
+// SignResponse signs the response body to the request URI with the key of the purpose (ResponseKeyPurpose or
+// AdminKeyPurpose), nil if there is no key for the purpose.
+func (c *Cmd) SignResponse(purpose KeyPurpose, requestURI string, body []byte) (*SignedResponse, error) {
+	key, ok := c.purposeKeys[purpose]
+	if !ok {
+		return nil, nil
+	}
+
+	hash := sha256.Sum256(body)
+
+	statement := ResponseStatement{
+		Version:       V1,
+		SignatureType: ResponseSignatureType,
+		Timestamp:     uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		RequestURI:    requestURI,
+		SHA256Body:    hash[:],
+	}
+
+	signature, err := c.signWith(purpose, statement)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := sha256.Sum256(key.pubKey)
+
+	return &SignedResponse{KeyID: keyID[:], Timestamp: statement.Timestamp, Signature: signature}, nil
+}
+
+// newWebhookSigner returns the signer of the webhooks with the webhook key, or the key of the log if there is
+// none. The key ID is the base64url-encoded log ID of the key (as in the webfinger of the log), a DID URL of the
+// DID of the log if it is set.
+func (c *Cmd) newWebhookSigner(did string) (*webhook.Signer, error) {
+	kh, alg, pubKey := c.kh, c.alg, c.PubKey
+
+	if key, ok := c.purposeKeys[WebhookKeyPurpose]; ok {
+		kh, alg, pubKey = key.kh, key.alg, key.pubKey
+	}
+
+	logID := LogID(pubKey)
+
+	keyID := base64.RawURLEncoding.EncodeToString(logID[:])
+	if did != "" {
+		keyID = did + "#" + keyID
+	}
+
+	return webhook.NewSigner(c.crypto, kh, alg.Type, keyID) // nolint: wrapcheck
+}
+
+// SignWebhook signs the body of the webhook request (see package webhook).
+func (c *Cmd) SignWebhook(req *http.Request, body []byte) error {
+	if err := c.webhooks.SignRequest(req, body); err != nil {
+		return fmt.Errorf("sign webhook: %w", err)
+	}
+
+	return nil
+}
+
+// cosign cosigns the anchored tree head with the witness key, nil if there is no witness key.
+func (c *Cmd) cosign(anchor *STHAnchor) (*Cosignature, error) {
+	if _, ok := c.purposeKeys[WitnessKeyPurpose]; !ok {
+		return nil, nil
+	}
+
+	logID := LogID(anchor.PublicKey)
+
+	statement := CosignedTreeHead{
+		Version:           V1,
+		SignatureType:     CosignatureSignatureType,
+		Timestamp:         uint64(time.Now().UnixNano()) / uint64(time.Millisecond),
+		LogID:             logID[:],
+		TreeHeadTimestamp: anchor.STH.Timestamp,
+		TreeSize:          anchor.STH.TreeSize,
+		SHA256RootHash:    anchor.STH.SHA256RootHash,
+	}
+
+	signature, err := c.signWith(WitnessKeyPurpose, statement)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cosignature{Statement: statement, Signature: signature}, nil
+}
@@ -0,0 +1,333 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// Formats of the tree head SLA report.
+const (
+	TreeHeadSLAFormatJSON = "json"
+	TreeHeadSLAFormatCSV  = "csv"
+)
+
+// slaSamplesPerInterval is the number of times per interval of the schedule the latest tree heads are polled,
+// a tree head is seen at most a tenth of the interval after it was published.
+const slaSamplesPerInterval = 10
+
+// DefaultTreeHeadSLAWindows returns the rolling windows the tree head SLA is reported over by default: the last
+// hour, day and week.
+func DefaultTreeHeadSLAWindows() []time.Duration {
+	return []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour} // nolint: gomnd
+}
+
+// TreeHeadSLAConfig configures the monitoring of the publication of the tree heads.
+type TreeHeadSLAConfig struct {
+	// Interval of the schedule: a new tree head must be published at least every interval (e.g. the max root
+	// duration of the trees).
+	Interval time.Duration
+	// Windows are the rolling windows the publication is reported over (DefaultTreeHeadSLAWindows if empty).
+	Windows []time.Duration
+}
+
+// treeHeadSLA keeps the timestamps of the tree heads of the logs published within the longest window.
+type treeHeadSLA struct {
+	mu       sync.Mutex
+	interval time.Duration
+	windows  []time.Duration
+	since    time.Time
+	heads    map[string][]time.Time // alias -> timestamps of the tree heads, in order
+}
+
+func newTreeHeadSLA(cfg *TreeHeadSLAConfig) *treeHeadSLA {
+	if cfg == nil || cfg.Interval <= 0 {
+		return nil
+	}
+
+	windows := append([]time.Duration{}, cfg.Windows...)
+	if len(windows) == 0 {
+		windows = DefaultTreeHeadSLAWindows()
+	}
+
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+
+	return &treeHeadSLA{
+		interval: cfg.Interval,
+		windows:  windows,
+		since:    time.Now(),
+		heads:    map[string][]time.Time{},
+	}
+}
+
+// observe records the tree head published at the timestamp, the tree heads older than the longest window are
+// dropped except the latest of them (the window starts in its interval).
+func (s *treeHeadSLA) observe(alias string, timestamp, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heads := s.heads[alias]
+	if len(heads) > 0 && !timestamp.After(heads[len(heads)-1]) {
+		return
+	}
+
+	heads = append(heads, timestamp)
+
+	oldest := now.Add(-s.windows[len(s.windows)-1])
+
+	drop := 0
+	for drop+1 < len(heads) && !heads[drop+1].After(oldest) {
+		drop++
+	}
+
+	s.heads[alias] = append(heads[:0], heads[drop:]...)
+}
+
+// report returns the publication of the tree heads of the log over the windows ending at the time.
+func (s *treeHeadSLA) report(alias string, now time.Time) LogTreeHeadSLA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := LogTreeHeadSLA{Alias: alias, Windows: make([]TreeHeadSLAWindow, len(s.windows))}
+
+	for i, window := range s.windows {
+		result.Windows[i] = s.window(s.heads[alias], window, now)
+	}
+
+	return result
+}
+
+// window returns the publication of the tree heads over the window ending at the time, the window starts at the
+// earliest when the monitoring started. The latest tree head is late from the interval after it was published
+// until the next tree head (or the end of the window).
+func (s *treeHeadSLA) window(heads []time.Time, window time.Duration, now time.Time) TreeHeadSLAWindow {
+	from := now.Add(-window)
+	if from.Before(s.since) {
+		from = s.since
+	}
+
+	monitored := now.Sub(from)
+
+	result := TreeHeadSLAWindow{
+		Window:            formatWindow(window),
+		From:              uint64(from.UnixNano()) / uint64(time.Millisecond),
+		To:                uint64(now.UnixNano()) / uint64(time.Millisecond),
+		ExpectedTreeHeads: int(monitored / s.interval),
+		Compliance:        1,
+	}
+
+	var late, maxGap time.Duration
+
+	for i, head := range heads {
+		end := now
+		if i+1 < len(heads) {
+			end = heads[i+1]
+		}
+
+		if !end.After(from) {
+			continue
+		}
+
+		if head.After(from) {
+			result.TreeHeads++
+		}
+
+		gap := end.Sub(head)
+		if gap > maxGap {
+			maxGap = gap
+		}
+
+		if gap <= s.interval {
+			continue
+		}
+
+		result.Violations++
+
+		lateFrom := head.Add(s.interval)
+		if lateFrom.Before(from) {
+			lateFrom = from
+		}
+
+		if end.After(lateFrom) {
+			late += end.Sub(lateFrom)
+		}
+	}
+
+	// no tree head of the log was seen, the log is late from the interval after the monitoring started
+	if len(heads) == 0 {
+		lateFrom := s.since.Add(s.interval)
+		if lateFrom.Before(from) {
+			lateFrom = from
+		}
+
+		if now.After(lateFrom) {
+			late = now.Sub(lateFrom)
+			maxGap = now.Sub(s.since)
+			result.Violations = 1
+		}
+	}
+
+	result.MaxGap = uint64(maxGap / time.Millisecond)
+
+	if monitored > 0 {
+		result.Compliance = 1 - float64(late)/float64(monitored)
+	}
+
+	return result
+}
+
+// formatWindow formats the window without its zero minutes and seconds (e.g. 24h).
+func formatWindow(window time.Duration) string {
+	s := window.String()
+
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
+
+// MonitorTreeHeads polls the latest tree heads of the logs slaSamplesPerInterval times per interval of the
+// schedule until the context is done, for the tree head SLA report (GetTreeHeadSLA). Failed polls are logged,
+// a log which can't be polled is reported late once its latest tree head is older than the interval.
+func (c *Cmd) MonitorTreeHeads(ctx context.Context) {
+	if c.sla == nil {
+		return
+	}
+
+	ticker := time.NewTicker(c.sla.interval / slaSamplesPerInterval)
+	defer ticker.Stop()
+
+	for {
+		c.pollTreeHeads()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cmd) pollTreeHeads() {
+	for alias := range c.logs {
+		root, err := c.latestLogRoot(alias)
+		if err != nil {
+			logger.Warnf("poll the tree head of log %s: %v", alias, err)
+
+			continue
+		}
+
+		now := time.Now()
+		timestamp := time.Unix(0, int64(root.TimestampNanos))
+
+		c.sla.observe(alias, timestamp, now)
+
+		treeHeadAge.Set(now.Sub(timestamp).Seconds(), alias)
+	}
+}
+
+// GetTreeHeadSLA retrieves the tree head SLA report (TreeHeadSLAReport): how often the tree heads of the logs were
+// published versus the schedule over the rolling windows, as JSON or CSV (GetTreeHeadSLARequest).
+func (c *Cmd) GetTreeHeadSLA(w io.Writer, r io.Reader) error {
+	var request GetTreeHeadSLARequest
+
+	if r != nil {
+		if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+			return fmt.Errorf("%w: decode GetTreeHeadSLA request: %v", errors.ErrBadRequest, err)
+		}
+	}
+
+	if c.sla == nil {
+		return errors.NewNotFoundError(fmt.Errorf("tree head SLA is not monitored"))
+	}
+
+	if request.Format != "" && request.Format != TreeHeadSLAFormatJSON && request.Format != TreeHeadSLAFormatCSV {
+		return errors.NewBadRequestError(fmt.Errorf("format %q is not supported", request.Format))
+	}
+
+	aliases := make([]string, 0, len(c.logs))
+
+	for alias := range c.logs {
+		if request.Alias == "" || alias == request.Alias {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	if len(aliases) == 0 {
+		return errors.NewNotFoundError(fmt.Errorf("alias %q is not supported", request.Alias))
+	}
+
+	sort.Strings(aliases)
+
+	now := time.Now()
+
+	report := TreeHeadSLAReport{
+		Timestamp: uint64(now.UnixNano()) / uint64(time.Millisecond),
+		Interval:  uint64(c.sla.interval / time.Millisecond),
+		Since:     uint64(c.sla.since.UnixNano()) / uint64(time.Millisecond),
+		Logs:      make([]LogTreeHeadSLA, len(aliases)),
+	}
+
+	for i, alias := range aliases {
+		report.Logs[i] = c.sla.report(alias, now)
+	}
+
+	if request.Format == TreeHeadSLAFormatCSV {
+		return writeTreeHeadSLACSV(w, &report)
+	}
+
+	return json.NewEncoder(w).Encode(report) // nolint: wrapcheck
+}
+
+// writeTreeHeadSLACSV writes the report as CSV, a row per log and window.
+func writeTreeHeadSLACSV(w io.Writer, report *TreeHeadSLAReport) error {
+	cw := csv.NewWriter(w)
+
+	rows := [][]string{{
+		"alias", "window", "from", "to", "interval", "tree_heads", "expected_tree_heads", "max_gap", "violations",
+		"compliance",
+	}}
+
+	for _, log := range report.Logs {
+		for _, window := range log.Windows {
+			rows = append(rows, []string{
+				log.Alias,
+				window.Window,
+				strconv.FormatUint(window.From, 10),
+				strconv.FormatUint(window.To, 10),
+				strconv.FormatUint(report.Interval, 10),
+				strconv.Itoa(window.TreeHeads),
+				strconv.Itoa(window.ExpectedTreeHeads),
+				strconv.FormatUint(window.MaxGap, 10),
+				strconv.Itoa(window.Violations),
+				strconv.FormatFloat(window.Compliance, 'f', 6, 64), // nolint: gomnd
+			})
+		}
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("write CSV: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// DefaultSnapshotInterval is the interval the verification snapshots are refreshed at by default, the default
+// interval of the publisher.
+const DefaultSnapshotInterval = time.Minute
+
+// VerificationSnapshotConfig configures the verification snapshots.
+type VerificationSnapshotConfig struct {
+	// Interval the snapshots are refreshed at (defaults to DefaultSnapshotInterval), the snapshots of all the
+	// instances are refreshed at the same times: the multiples of the interval.
+	Interval time.Duration
+	// Metadata is served in the snapshots as is, e.g. the name and the contact of the operator of the log.
+	Metadata map[string]string
+}
+
+// snapshots keeps the snapshot of every log until its next update.
+type snapshots struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	metadata  map[string]string
+	snapshots map[string]*SignedVerificationSnapshot // alias -> snapshot
+}
+
+func newSnapshots(cfg *VerificationSnapshotConfig) *snapshots {
+	if cfg == nil {
+		return nil
+	}
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+
+	return &snapshots{
+		interval:  interval,
+		metadata:  cfg.Metadata,
+		snapshots: map[string]*SignedVerificationSnapshot{},
+	}
+}
+
+// GetVerificationSnapshot retrieves the signed verification snapshot of the log (SignedVerificationSnapshot):
+// the latest tree head, the keys of the log and the configured metadata. The snapshot is refreshed every
+// interval, the same snapshot is served until its next update so it can be cached until then.
+func (c *Cmd) GetVerificationSnapshot(w io.Writer, r io.Reader) error {
+	var alias string
+
+	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+		return fmt.Errorf("%w: decode alias failed", errors.ErrInternal)
+	}
+
+	if err := c.hasPermissions(alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	if c.snapshots == nil {
+		return errors.NewNotFoundError(fmt.Errorf("verification snapshots are not enabled"))
+	}
+
+	c.snapshots.mu.Lock()
+	defer c.snapshots.mu.Unlock()
+
+	now := time.Now()
+
+	snapshot, ok := c.snapshots.snapshots[alias]
+	if !ok || uint64(now.UnixNano())/uint64(time.Millisecond) >= snapshot.Snapshot.NextUpdate {
+		var err error
+
+		snapshot, err = c.newSnapshot(alias, now)
+		if err != nil {
+			return err
+		}
+
+		c.snapshots.snapshots[alias] = snapshot
+	}
+
+	return json.NewEncoder(w).Encode(snapshot) // nolint: wrapcheck
+}
+
+func (c *Cmd) newSnapshot(alias string, now time.Time) (*SignedVerificationSnapshot, error) {
+	sth, err := c.latestSTH(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	nextUpdate := now.Truncate(c.snapshots.interval).Add(c.snapshots.interval)
+
+	snapshot := VerificationSnapshot{
+		Version:       V1,
+		SignatureType: SnapshotSignatureType,
+		Timestamp:     uint64(now.UnixNano()) / uint64(time.Millisecond),
+		NextUpdate:    uint64(nextUpdate.UnixNano()) / uint64(time.Millisecond),
+		Alias:         alias,
+		LogID:         c.VCLogID[:],
+		URL:           c.baseURL + "/" + alias,
+		STH:           *sth,
+		Keys:          c.keysMetadata(),
+		Compromise:    c.getCompromise(),
+		Final:         c.getFinalTreeHead(alias),
+		Metadata:      c.snapshots.metadata,
+	}
+
+	signature, err := c.sign(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("sign verification snapshot: %w", err)
+	}
+
+	return &SignedVerificationSnapshot{Snapshot: snapshot, Signature: signature}, nil
+}
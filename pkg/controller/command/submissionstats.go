@@ -0,0 +1,323 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// Defaults of the submission stats.
+const (
+	DefaultSubmissionSamples = 1000
+	DefaultSubmissionIssuers = 10000
+	defaultSubmissionLimit   = 10
+)
+
+// Dimensions the issuers of the submission stats are ranked by.
+const (
+	SubmissionSize           = "size"
+	SubmissionContexts       = "contexts"
+	SubmissionProcessingTime = "processing_time"
+)
+
+// nolint: gochecknoglobals
+var (
+	submissionSizeBuckets     = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+	submissionContextsBuckets = []float64{1, 2, 3, 5, 8, 13, 21, 34}
+)
+
+// SubmissionStatsConfig configures the submission stats, see GetSubmissionStats.
+type SubmissionStatsConfig struct {
+	// Samples is the number of the latest submissions of an issuer the percentiles are computed over (defaults to
+	// DefaultSubmissionSamples).
+	Samples int
+	// Issuers is the max number of issuers tracked per log (defaults to DefaultSubmissionIssuers), the issuer
+	// which submitted least recently is evicted for a new issuer.
+	Issuers int
+}
+
+// submissionStats records the size, the number of JSON-LD contexts and the processing time (parsing, JSON-LD
+// canonicalization and the verification of the proofs) of the submitted credentials per log and issuer, so the
+// issuers submitting pathological documents can be identified. The distributions per log are reported as metrics.
+type submissionStats struct {
+	mu      sync.Mutex
+	since   time.Time
+	samples int
+	issuers int
+	logs    map[string]map[string]*issuerSubmissions // alias -> issuer -> submissions
+}
+
+type issuerSubmissions struct {
+	submissions uint64
+	rejected    uint64
+	bytes       uint64
+	lastSeen    time.Time
+	next        int // index of the next sample in the rings
+	sizes       []float64
+	contexts    []float64
+	times       []float64 // milliseconds
+}
+
+func newSubmissionStats(cfg *SubmissionStatsConfig) *submissionStats {
+	stats := &submissionStats{
+		since:   time.Now(),
+		samples: DefaultSubmissionSamples,
+		issuers: DefaultSubmissionIssuers,
+		logs:    map[string]map[string]*issuerSubmissions{},
+	}
+
+	if cfg != nil && cfg.Samples > 0 {
+		stats.samples = cfg.Samples
+	}
+
+	if cfg != nil && cfg.Issuers > 0 {
+		stats.issuers = cfg.Issuers
+	}
+
+	return stats
+}
+
+// recordSubmission records the submission of the credential to the log, the credential is nil if it was rejected
+// by the parser. Submissions whose issuer is unknown are not recorded.
+func (c *Cmd) recordSubmission(alias string, credential []byte, vc *verifiable.Credential, elapsed time.Duration) {
+	if _, ok := c.logs[alias]; !ok {
+		return
+	}
+
+	issuer, contexts := submissionIssuer(credential)
+	if vc != nil {
+		issuer, contexts = vc.Issuer.ID, len(vc.Context)+len(vc.CustomContext)
+	}
+
+	if issuer == "" {
+		return
+	}
+
+	submissionSizes.Observe(float64(len(credential)), alias)
+	submissionContexts.Observe(float64(contexts), alias)
+
+	c.submissions.record(alias, issuer, vc == nil, float64(len(credential)), float64(contexts),
+		float64(elapsed)/float64(time.Millisecond))
+}
+
+// submissionIssuer returns the issuer and the number of JSON-LD contexts of a rejected credential, the issuer is
+// empty unless the credential is a JSON-LD document with an issuer.
+func submissionIssuer(credential []byte) (string, int) {
+	var raw struct {
+		Context json.RawMessage `json:"@context"`
+		Issuer  json.RawMessage `json:"issuer"`
+	}
+
+	if err := json.Unmarshal(credential, &raw); err != nil {
+		return "", 0
+	}
+
+	var issuer struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(raw.Issuer, &issuer.ID); err != nil {
+		_ = json.Unmarshal(raw.Issuer, &issuer) // nolint: errcheck
+	}
+
+	var (
+		contexts int
+		list     []json.RawMessage
+	)
+
+	if err := json.Unmarshal(raw.Context, &list); err == nil {
+		contexts = len(list)
+	} else if len(raw.Context) > 0 {
+		contexts = 1
+	}
+
+	return issuer.ID, contexts
+}
+
+func (s *submissionStats) record(alias, issuer string, rejected bool, size, contexts, elapsed float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issuers, ok := s.logs[alias]
+	if !ok {
+		issuers = map[string]*issuerSubmissions{}
+		s.logs[alias] = issuers
+	}
+
+	submissions, ok := issuers[issuer]
+	if !ok {
+		if len(issuers) >= s.issuers {
+			evictIssuer(issuers)
+		}
+
+		submissions = &issuerSubmissions{}
+		issuers[issuer] = submissions
+	}
+
+	submissions.submissions++
+	submissions.bytes += uint64(size)
+	submissions.lastSeen = time.Now()
+
+	if rejected {
+		submissions.rejected++
+	}
+
+	if len(submissions.sizes) < s.samples {
+		submissions.sizes = append(submissions.sizes, size)
+		submissions.contexts = append(submissions.contexts, contexts)
+		submissions.times = append(submissions.times, elapsed)
+
+		return
+	}
+
+	submissions.sizes[submissions.next] = size
+	submissions.contexts[submissions.next] = contexts
+	submissions.times[submissions.next] = elapsed
+	submissions.next = (submissions.next + 1) % s.samples
+}
+
+// evictIssuer evicts the issuer which submitted least recently.
+func evictIssuer(issuers map[string]*issuerSubmissions) {
+	var (
+		oldest   string
+		lastSeen time.Time
+	)
+
+	for issuer, submissions := range issuers {
+		if oldest == "" || submissions.lastSeen.Before(lastSeen) {
+			oldest, lastSeen = issuer, submissions.lastSeen
+		}
+	}
+
+	delete(issuers, oldest)
+}
+
+// percentiles returns the percentiles of the samples.
+func percentiles(samples []float64) Percentiles {
+	if len(samples) == 0 {
+		return Percentiles{}
+	}
+
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	// nearest-rank percentile
+	rank := func(p float64) float64 {
+		return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+	}
+
+	return Percentiles{P50: rank(0.5), P95: rank(0.95), P99: rank(0.99), Max: sorted[len(sorted)-1]}
+}
+
+// GetSubmissionStats retrieves the submission stats of the issuers, ranked by the 95th percentile of a dimension
+// (the processing time by default) so the issuers submitting pathological documents come first.
+func (c *Cmd) GetSubmissionStats(w io.Writer, r io.Reader) error {
+	var request GetSubmissionStatsRequest
+
+	if r != nil {
+		if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+			return fmt.Errorf("%w: decode GetSubmissionStats request: %v", errors.ErrBadRequest, err)
+		}
+	}
+
+	if request.Alias != "" {
+		if _, ok := c.logs[request.Alias]; !ok {
+			return errors.NewNotFoundError(fmt.Errorf("log %q is not found", request.Alias))
+		}
+	}
+
+	if request.SortBy == "" {
+		request.SortBy = SubmissionProcessingTime
+	}
+
+	if request.SortBy != SubmissionSize && request.SortBy != SubmissionContexts &&
+		request.SortBy != SubmissionProcessingTime {
+		return errors.NewBadRequestError(fmt.Errorf("sort by %q is not one of %s, %s, %s", request.SortBy,
+			SubmissionSize, SubmissionContexts, SubmissionProcessingTime))
+	}
+
+	if request.Limit <= 0 {
+		request.Limit = defaultSubmissionLimit
+	}
+
+	issuers := c.submissions.get(request.Alias, request.Issuer)
+
+	rank := func(stats *IssuerSubmissionStats) float64 {
+		switch request.SortBy {
+		case SubmissionSize:
+			return stats.Size.P95
+		case SubmissionContexts:
+			return stats.Contexts.P95
+		default:
+			return stats.ProcessingTime.P95
+		}
+	}
+
+	sort.SliceStable(issuers, func(i, j int) bool { return rank(&issuers[i]) > rank(&issuers[j]) })
+
+	if len(issuers) > request.Limit {
+		issuers = issuers[:request.Limit]
+	}
+
+	return json.NewEncoder(w).Encode(GetSubmissionStatsResponse{ // nolint: wrapcheck
+		Since:   uint64(c.submissions.since.UnixNano()) / uint64(time.Millisecond),
+		Issuers: issuers,
+	})
+}
+
+// get returns the stats of the issuers of the log (all the logs if the alias is empty), of the issuer if set, in
+// the order of the aliases and the issuers.
+func (s *submissionStats) get(alias, issuer string) []IssuerSubmissionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []IssuerSubmissionStats{}
+
+	for logAlias, issuers := range s.logs {
+		if alias != "" && logAlias != alias {
+			continue
+		}
+
+		for id, submissions := range issuers {
+			if issuer != "" && id != issuer {
+				continue
+			}
+
+			result = append(result, IssuerSubmissionStats{
+				Alias:          logAlias,
+				Issuer:         id,
+				Submissions:    submissions.submissions,
+				Rejected:       submissions.rejected,
+				Bytes:          submissions.bytes,
+				LastSeen:       uint64(submissions.lastSeen.UnixNano()) / uint64(time.Millisecond),
+				Size:           percentiles(submissions.sizes),
+				Contexts:       percentiles(submissions.contexts),
+				ProcessingTime: percentiles(submissions.times),
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Alias != result[j].Alias {
+			return result[i].Alias < result[j].Alias
+		}
+
+		return result[i].Issuer < result[j].Issuer
+	})
+
+	return result
+}
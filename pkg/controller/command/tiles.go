@@ -0,0 +1,385 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/trillian/types"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// TileHeight is the number of levels of the tree a tile spans.
+	TileHeight = 8
+	// TileWidth is the number of hashes of a full tile.
+	TileWidth = 1 << TileHeight
+	// MaxTileLevel is the highest level of a tile, the level 8 would need a tree of 2^64 entries.
+	MaxTileLevel = 7
+	// maxEntryBundleSize is the max size of an entry of a bundle, the size is encoded with 24 bits.
+	maxEntryBundleSize = 1<<24 - 1
+	// maxCachedTiles is the number of tiles computed from the Trillian API that are kept in memory.
+	maxCachedTiles = 4096
+)
+
+// TileReader is implemented by the log clients storing the tree as tiles (e.g. the native Merkle log),
+// the tiles are read as they are stored instead of being computed from the proofs of the log.
+type TileReader interface {
+	GetTile(ctx context.Context, treeID int64, level uint, index uint64) ([][]byte, error)
+}
+
+// GetTile retrieves the hashes of a tile of the tree, concatenated. A tile never changes once it is published:
+// a partial tile is served only if the tree is large enough for its width, so both are immutable.
+func (c *Cmd) GetTile(w io.Writer, r io.Reader) error {
+	var request *GetTileRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetTile request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetTile request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	hashes, err := c.tile(request.Alias, uint(request.Level), uint64(request.Index), tileWidth(request.Width))
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if _, err = w.Write(hash); err != nil {
+			return fmt.Errorf("write tile: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetEntryBundle retrieves the leaf values of the entries of a tile of the level 0, each of them is prefixed
+// with its size as a 24-bit big-endian integer.
+func (c *Cmd) GetEntryBundle(w io.Writer, r io.Reader) error {
+	var request *GetEntryBundleRequest
+
+	if err := json.NewDecoder(r).Decode(&request); err != nil {
+		return fmt.Errorf("decode GetEntryBundle request: %w", err)
+	}
+
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validate GetEntryBundle request: %w", err)
+	}
+
+	if err := c.hasPermissions(request.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	width := tileWidth(request.Width)
+
+	log := c.logs[request.Alias]
+
+	if err := c.checkTileInTree(request.Alias, 0, uint64(request.Index), width); err != nil {
+		return err
+	}
+
+	leaves, err := tileLeaves(log, request.Index*TileWidth, int64(width))
+	if err != nil {
+		return err
+	}
+
+	for _, leaf := range leaves {
+		size := len(leaf.LeafValue)
+		if size > maxEntryBundleSize {
+			return fmt.Errorf("%w: entry %d of %d bytes does not fit a bundle",
+				errors.ErrInternal, leaf.LeafIndex, size)
+		}
+
+		if _, err = w.Write([]byte{byte(size >> 16), byte(size >> 8), byte(size)}); err != nil {
+			return fmt.Errorf("write entry bundle: %w", err)
+		}
+
+		if _, err = w.Write(leaf.LeafValue); err != nil {
+			return fmt.Errorf("write entry bundle: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// tile returns the first width hashes of the tile, they are read from the primary so the tile is consistent
+// with the signed tree heads.
+func (c *Cmd) tile(alias string, level uint, index uint64, width int) ([][]byte, error) {
+	log := c.logs[alias]
+
+	if err := c.checkTileInTree(alias, level, index, width); err != nil {
+		return nil, err
+	}
+
+	if reader, ok := log.Client.(TileReader); ok {
+		hashes, err := reader.GetTile(context.Background(), log.ID, level, index)
+		if err != nil {
+			return nil, fmt.Errorf("get tile: %w", err)
+		}
+
+		if len(hashes) < width {
+			return nil, fmt.Errorf("%w: tile %d/%d has only %d hashes", errors.ErrNotFound, level, index,
+				len(hashes))
+		}
+
+		return hashes[:width], nil
+	}
+
+	key := tileKey{alias: alias, level: level, index: index}
+
+	if hashes, ok := c.tiles.get(key); ok {
+		return hashes[:width], nil
+	}
+
+	var (
+		hashes [][]byte
+		err    error
+	)
+
+	if level == 0 {
+		hashes, err = tileLeafHashes(log, index, width)
+	} else {
+		hashes, err = tileNodeHashes(log, level, index, width)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if width == TileWidth {
+		c.tiles.put(key, hashes)
+	}
+
+	return hashes, nil
+}
+
+// checkTileInTree checks that the latest tree contains all the nodes of the tile. The tree only grows: a tile
+// within a tree size already seen is in the latest tree, the latest root is fetched only for the other tiles.
+func (c *Cmd) checkTileInTree(alias string, level uint, index uint64, width int) error {
+	inTree := func(treeSize uint64) bool {
+		return index*TileWidth+uint64(width) <= treeSize>>(TileHeight*level)
+	}
+
+	if inTree(c.tiles.treeSize(alias)) {
+		return nil
+	}
+
+	treeSize, err := latestTreeSize(c.logs[alias])
+	if err != nil {
+		return err
+	}
+
+	c.tiles.setTreeSize(alias, treeSize)
+
+	if !inTree(treeSize) {
+		return fmt.Errorf("%w: tile %d/%d of width %d is beyond the tree size %d",
+			errors.ErrNotFound, level, index, width, treeSize)
+	}
+
+	return nil
+}
+
+// latestTreeSize returns the size of the latest tree of the primary.
+func latestTreeSize(log Log) (uint64, error) {
+	resp, err := log.Client.GetLatestSignedLogRoot(context.Background(),
+		&trillian.GetLatestSignedLogRootRequest{LogId: log.ID})
+	if err != nil {
+		return 0, fmt.Errorf("get latest signed log root: %w", err)
+	}
+
+	var root types.LogRootV1
+	if err = root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return 0, fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, err)
+	}
+
+	return root.TreeSize, nil
+}
+
+// tileLeafHashes computes the hashes of a tile of the level 0, the leaf hashes.
+func tileLeafHashes(log Log, index uint64, width int) ([][]byte, error) {
+	leaves, err := tileLeaves(log, int64(index*TileWidth), int64(width))
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([][]byte, len(leaves))
+
+	for i, leaf := range leaves {
+		hashes[i] = hasher.DefaultHasher.HashLeaf(leaf.LeafValue)
+	}
+
+	return hashes, nil
+}
+
+// tileNodeHashes computes the hashes of a tile of the level above 0. The node n at the level L*TileHeight is
+// the root of the tree of the leaves [n<<(L*TileHeight), (n+1)<<(L*TileHeight)): its hash is folded from
+// the inclusion proof of the first leaf in the tree of size (n+1)<<(L*TileHeight), all siblings are on the right.
+func tileNodeHashes(log Log, level uint, index uint64, width int) ([][]byte, error) {
+	height := TileHeight * level
+	hashes := make([][]byte, width)
+
+	for i := range hashes {
+		node := index*TileWidth + uint64(i)
+
+		resp, err := log.Client.GetEntryAndProof(context.Background(), &trillian.GetEntryAndProofRequest{
+			LogId:     log.ID,
+			LeafIndex: int64(node << height),
+			TreeSize:  int64((node + 1) << height),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get entry and proof: %w", err)
+		}
+
+		proof := resp.GetProof().GetHashes()
+		if resp.GetLeaf() == nil || uint(len(proof)) < height {
+			return nil, fmt.Errorf("%w: no proof of node %d at level %d", errors.ErrInternal, node, height)
+		}
+
+		hash := hasher.DefaultHasher.HashLeaf(resp.GetLeaf().GetLeafValue())
+
+		for _, sibling := range proof[:height] {
+			hash = hasher.DefaultHasher.HashChildren(hash, sibling)
+		}
+
+		hashes[i] = hash
+	}
+
+	return hashes, nil
+}
+
+// tileLeaves retrieves the leaves [start, start+count) from the primary, the log may return fewer leaves
+// per call than requested.
+func tileLeaves(log Log, start, count int64) ([]*trillian.LogLeaf, error) {
+	leaves := make([]*trillian.LogLeaf, 0, count)
+
+	for int64(len(leaves)) < count {
+		next := start + int64(len(leaves))
+
+		resp, err := log.Client.GetLeavesByRange(context.Background(), &trillian.GetLeavesByRangeRequest{
+			LogId:      log.ID,
+			StartIndex: next,
+			Count:      count - int64(len(leaves)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get leaves by range: %w", err)
+		}
+
+		if len(resp.Leaves) == 0 || int64(len(leaves)+len(resp.Leaves)) > count {
+			return nil, fmt.Errorf("%w: got %d leaves starting at %d", errors.ErrInternal, len(resp.Leaves), next)
+		}
+
+		for i, leaf := range resp.Leaves {
+			if leaf.LeafIndex != next+int64(i) {
+				return nil, fmt.Errorf("%w: got leaf %d instead of %d", errors.ErrInternal, leaf.LeafIndex,
+					next+int64(i))
+			}
+		}
+
+		leaves = append(leaves, resp.Leaves...)
+	}
+
+	return leaves, nil
+}
+
+func tileWidth(width int) int {
+	if width == 0 {
+		return TileWidth
+	}
+
+	return width
+}
+
+type tileKey struct {
+	alias string
+	level uint
+	index uint64
+}
+
+// tileCache keeps the full tiles computed from the Trillian API, a full tile never changes. The pinned tiles
+// (the top of the tree preloaded by WarmCache) are never evicted.
+type tileCache struct {
+	mu        sync.Mutex
+	tiles     map[tileKey][][]byte
+	pinned    map[tileKey][][]byte
+	treeSizes map[string]uint64
+}
+
+func newTileCache() *tileCache {
+	return &tileCache{
+		tiles:     map[tileKey][][]byte{},
+		pinned:    map[tileKey][][]byte{},
+		treeSizes: map[string]uint64{},
+	}
+}
+
+func (c *tileCache) get(key tileKey) ([][]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if hashes, ok := c.pinned[key]; ok {
+		return hashes, true
+	}
+
+	hashes, ok := c.tiles[key]
+
+	return hashes, ok
+}
+
+func (c *tileCache) pin(key tileKey, hashes [][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.tiles, key)
+
+	c.pinned[key] = hashes
+}
+
+// treeSize returns the largest tree size seen for the log, 0 if none.
+func (c *tileCache) treeSize(alias string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.treeSizes[alias]
+}
+
+func (c *tileCache) setTreeSize(alias string, treeSize uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if treeSize > c.treeSizes[alias] {
+		c.treeSizes[alias] = treeSize
+	}
+}
+
+func (c *tileCache) put(key tileKey, hashes [][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// evicts an arbitrary tile, hot tiles are cached by the CDN in front of the log anyway
+	for evicted := range c.tiles {
+		if len(c.tiles) < maxCachedTiles {
+			break
+		}
+
+		delete(c.tiles, evicted)
+	}
+
+	c.tiles[key] = hashes
+}
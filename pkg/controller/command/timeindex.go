@@ -0,0 +1,233 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// maxTimeIndexEntries bounds the sequencing timestamps kept in memory, a binary search over a tree of 2^32
+// leaves looks up 32 of them.
+const maxTimeIndexEntries = 10000
+
+// TimeIndexStore represents the store of the sequencing timestamps of the leaves.
+type TimeIndexStore interface {
+	Put(key string, value []byte, tags ...storage.Tag) error
+	Get(key string) ([]byte, error)
+}
+
+type timeIndexKey struct {
+	alias string
+	index int64
+}
+
+// timeIndex keeps the sequencing timestamps of the leaves looked up so far (the checkpoints of the binary searches
+// of the time ranges), in memory up to maxTimeIndexEntries and in the store if any. Integrated leaves never change,
+// so the index is never invalidated.
+type timeIndex struct {
+	store TimeIndexStore
+
+	mu         sync.Mutex
+	timestamps map[timeIndexKey]time.Time
+}
+
+func newTimeIndex(store TimeIndexStore) *timeIndex {
+	return &timeIndex{store: store, timestamps: map[timeIndexKey]time.Time{}}
+}
+
+// storeKey returns the key of the timestamp of the leaf of the tree in the store.
+func storeKey(alias string, treeID, index int64) string {
+	return fmt.Sprintf("%s/%d/%d", alias, treeID, index)
+}
+
+// get returns the timestamp of the leaf kept in memory or in the store, the timestamps of the store are kept in
+// memory once read.
+func (i *timeIndex) get(alias string, treeID, index int64) (time.Time, bool) {
+	key := timeIndexKey{alias: alias, index: index}
+
+	i.mu.Lock()
+	ts, ok := i.timestamps[key]
+	i.mu.Unlock()
+
+	if ok || i.store == nil {
+		return ts, ok
+	}
+
+	src, err := i.store.Get(storeKey(alias, treeID, index))
+	if err != nil {
+		if !goerrors.Is(err, storage.ErrDataNotFound) {
+			logger.Warnf("get timestamp of leaf %d of log %s: %v", index, alias, err)
+		}
+
+		return time.Time{}, false
+	}
+
+	if err = ts.UnmarshalText(src); err != nil {
+		logger.Warnf("timestamp of leaf %d of log %s: %v", index, alias, err)
+
+		return time.Time{}, false
+	}
+
+	i.keep(key, ts)
+
+	return ts, true
+}
+
+// put keeps the timestamp of the leaf in memory and in the store.
+func (i *timeIndex) put(alias string, treeID, index int64, ts time.Time) {
+	i.keep(timeIndexKey{alias: alias, index: index}, ts)
+
+	if i.store == nil {
+		return
+	}
+
+	src, err := ts.MarshalText()
+	if err == nil {
+		err = i.store.Put(storeKey(alias, treeID, index), src)
+	}
+
+	if err != nil {
+		logger.Warnf("store timestamp of leaf %d of log %s: %v", index, alias, err)
+	}
+}
+
+// keep keeps the timestamp in memory, arbitrary timestamps are evicted once maxTimeIndexEntries are kept.
+func (i *timeIndex) keep(key timeIndexKey, ts time.Time) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for evicted := range i.timestamps {
+		if len(i.timestamps) < maxTimeIndexEntries {
+			break
+		}
+
+		delete(i.timestamps, evicted)
+	}
+
+	i.timestamps[key] = ts
+}
+
+// timeRange converts the time range to the range of leaf indexes. Leaves are sequenced in the order
+// of their integrate timestamps, so the bounds are found by a binary search over the tree.
+// The returned range is empty (start > end) if no leaf was integrated within the given time range.
+func (c *Cmd) timeRange(alias string, from, to *time.Time) (int64, int64, error) {
+	treeSize, err := c.treeSize(alias)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start, end := int64(0), treeSize-1
+
+	if from != nil {
+		start, err = c.searchLeaf(alias, treeSize, func(ts time.Time) bool { return !ts.Before(*from) })
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if to != nil {
+		end, err = c.searchLeaf(alias, treeSize, func(ts time.Time) bool { return ts.After(*to) })
+		if err != nil {
+			return 0, 0, err
+		}
+
+		end--
+	}
+
+	return start, end, nil
+}
+
+func (c *Cmd) treeSize(alias string) (int64, error) {
+	req := trillian.GetLatestSignedLogRootRequest{LogId: c.logs[alias].ID}
+
+	var resp *trillian.GetLatestSignedLogRootResponse
+
+	err := c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLatestSignedLogRoot(context.Background(), &req)
+
+		return resp, er
+	})
+	if err != nil {
+		return 0, fmt.Errorf("get latest signed log root: %w", err)
+	}
+
+	var root types.LogRootV1
+	if err := root.UnmarshalBinary(resp.GetSignedLogRoot().GetLogRoot()); err != nil {
+		return 0, fmt.Errorf("%w: unmarshal binary: %v", errors.ErrInternal, resp.GetSignedLogRoot().GetLogRoot())
+	}
+
+	return int64(root.TreeSize), nil
+}
+
+// searchLeaf returns the smallest leaf index in [0, treeSize) for which f is true, or treeSize if there is none.
+func (c *Cmd) searchLeaf(alias string, treeSize int64, f func(time.Time) bool) (int64, error) {
+	lo, hi := int64(0), treeSize
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2 // nolint: gomnd
+
+		ts, err := c.leafTimestamp(alias, mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if f(ts) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	return lo, nil
+}
+
+func (c *Cmd) leafTimestamp(alias string, index int64) (time.Time, error) {
+	if ts, ok := c.timeIndex.get(alias, c.logs[alias].ID, index); ok {
+		return ts, nil
+	}
+
+	req := trillian.GetLeavesByRangeRequest{
+		LogId:      c.logs[alias].ID,
+		StartIndex: index,
+		Count:      1,
+	}
+
+	var resp *trillian.GetLeavesByRangeResponse
+
+	err := c.read(alias, func(client TrillianLogClient) (signedLogRootResponse, error) {
+		var er error
+
+		resp, er = client.GetLeavesByRange(context.Background(), &req)
+
+		return resp, er
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get leaves by range: %w", err)
+	}
+
+	if len(resp.GetLeaves()) != 1 || resp.GetLeaves()[0].GetLeafIndex() != index {
+		return time.Time{}, fmt.Errorf("%w: leaf %d is not available", errors.ErrInternal, index)
+	}
+
+	ts := resp.GetLeaves()[0].GetIntegrateTimestamp().AsTime()
+
+	c.timeIndex.put(alias, c.logs[alias].ID, index, ts)
+
+	return ts, nil
+}
@@ -0,0 +1,49 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// TimeSource is the source of the time of the timestamps of the SCTs, e.g. an authenticated time source
+// (Roughtime) which does not trust the local clock.
+type TimeSource interface {
+	// Now returns the time and its attestation by the time source, nil if the time is not attested.
+	Now() (time.Time, *TimeAttestation, error)
+}
+
+// localClock is the default time source.
+type localClock struct{}
+
+func (localClock) Now() (time.Time, *TimeAttestation, error) {
+	return time.Now(), nil, nil
+}
+
+// timestamp returns the timestamp of a new leaf and its attestation. The leaf is not logged if the time source
+// is not available.
+func (c *Cmd) timestamp() (uint64, *TimeAttestation, error) {
+	now, attestation, err := c.timeSource.Now()
+	if err != nil {
+		return 0, nil, errors.NewStatusInternalServerError(fmt.Errorf("trusted time: %w", err))
+	}
+
+	return uint64(now.UnixNano() / int64(time.Millisecond)), attestation, nil
+}
+
+// attest sets the attestation of the timestamp of the leaf on the SCT, unless the SCT is the one of a duplicate
+// logged earlier.
+func attest(resp *AddVCResponse, leaf *MerkleTreeLeaf, attestation *TimeAttestation) *AddVCResponse {
+	if resp.Timestamp == leaf.TimestampedEntry.Timestamp {
+		resp.TimeAttestation = attestation
+	}
+
+	return resp
+}
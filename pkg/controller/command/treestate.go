@@ -0,0 +1,258 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/trillian"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const defaultTreeStateTTL = 10 * time.Second
+
+// treeStateOf maps the states of the logs to the states of their Trillian trees.
+// nolint: gochecknoglobals
+var treeStateOf = map[string]trillian.TreeState{
+	LogStateActive:   trillian.TreeState_ACTIVE,
+	LogStateDraining: trillian.TreeState_DRAINING,
+	LogStateFrozen:   trillian.TreeState_FROZEN,
+}
+
+// treeStates caches the states of the Trillian trees of the logs by alias.
+type treeStates struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	states map[string]cachedTreeState
+}
+
+type cachedTreeState struct {
+	state   trillian.TreeState
+	expires time.Time
+}
+
+func newTreeStates(ttl time.Duration) *treeStates {
+	if ttl <= 0 {
+		ttl = defaultTreeStateTTL
+	}
+
+	return &treeStates{ttl: ttl, states: map[string]cachedTreeState{}}
+}
+
+func (s *treeStates) get(alias string) (trillian.TreeState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, ok := s.states[alias]
+	if !ok || !time.Now().Before(cached.expires) {
+		return trillian.TreeState_UNKNOWN_TREE_STATE, false
+	}
+
+	return cached.state, true
+}
+
+func (s *treeStates) set(alias string, state trillian.TreeState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[alias] = cachedTreeState{state: state, expires: time.Now().Add(s.ttl)}
+}
+
+// treeState returns the state of the Trillian tree of the log, cached unless refresh is true. It is
+// UNKNOWN_TREE_STATE if the log has no admin client.
+func (c *Cmd) treeState(alias string, refresh bool) (trillian.TreeState, error) {
+	l := c.logs[alias]
+	if l.AdminClient == nil {
+		return trillian.TreeState_UNKNOWN_TREE_STATE, nil
+	}
+
+	if state, ok := c.treeStates.get(alias); ok && !refresh {
+		return state, nil
+	}
+
+	tree, err := l.AdminClient.GetTree(context.Background(), &trillian.GetTreeRequest{TreeId: l.ID})
+	if err != nil {
+		return trillian.TreeState_UNKNOWN_TREE_STATE, fmt.Errorf("get tree of log %s: %w", alias, err)
+	}
+
+	c.treeStates.set(alias, tree.TreeState)
+
+	return tree.TreeState, nil
+}
+
+// checkTreeState returns ErrDraining if the Trillian tree of the log is draining and ErrTreeFrozen if it is
+// frozen. The writes are not rejected if the state of the tree is not known, Trillian rejects them if they must be.
+func (c *Cmd) checkTreeState(alias string, refresh bool) error {
+	state, err := c.treeState(alias, refresh)
+	if err != nil {
+		logger.Warnf("check tree state: %v", err)
+
+		return nil
+	}
+
+	switch state { // nolint: exhaustive
+	case trillian.TreeState_DRAINING:
+		return fmt.Errorf("%w: the log is draining, writes are rejected until it is active", errors.ErrDraining)
+	case trillian.TreeState_FROZEN:
+		return fmt.Errorf("%w: the tree of the log is frozen, writes are rejected until it is active",
+			errors.ErrTreeFrozen)
+	default:
+		return nil
+	}
+}
+
+// queueLeafError returns the error of the leaf Trillian did not queue: a precondition failure refreshes the state
+// of the tree, the write rejected as the tree is no longer active is reported as such.
+func (c *Cmd) queueLeafError(alias string, err error) error {
+	if status.Code(err) == codes.FailedPrecondition {
+		if er := c.checkTreeState(alias, true); er != nil {
+			return er
+		}
+	}
+
+	return fmt.Errorf("queue leaf: %w", err)
+}
+
+// logState returns the state of the log: frozen for good if the log is frozen, the state of its Trillian tree
+// otherwise (active if the tree is not managed).
+func (c *Cmd) logState(alias string, refresh bool) (*LogState, error) {
+	state := &LogState{Alias: alias, State: LogStateActive}
+
+	treeState, err := c.treeState(alias, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if treeState != trillian.TreeState_UNKNOWN_TREE_STATE {
+		state.TreeState = treeState.String()
+	}
+
+	switch treeState { // nolint: exhaustive
+	case trillian.TreeState_DRAINING:
+		state.State = LogStateDraining
+	case trillian.TreeState_FROZEN:
+		state.State = LogStateFrozen
+	}
+
+	if c.getFinalTreeHead(alias) != nil {
+		state.State = LogStateFrozen
+		state.Final = true
+	}
+
+	return state, nil
+}
+
+// setTreeState transitions the Trillian tree of the log to the state, unless it is in the state.
+func (c *Cmd) setTreeState(alias string, state trillian.TreeState) error {
+	current, err := c.treeState(alias, true)
+	if err != nil {
+		return err
+	}
+
+	if current == state {
+		return nil
+	}
+
+	l := c.logs[alias]
+
+	tree, err := l.AdminClient.UpdateTree(context.Background(), &trillian.UpdateTreeRequest{
+		Tree:       &trillian.Tree{TreeId: l.ID, TreeState: state},
+		UpdateMask: &field_mask.FieldMask{Paths: []string{"tree_state"}},
+	})
+	if err != nil {
+		return fmt.Errorf("update tree of log %s: %w", alias, err)
+	}
+
+	c.treeStates.set(alias, tree.TreeState)
+
+	logger.Warnf("tree of log %s is %s (was %s)", alias, tree.TreeState, current)
+
+	return nil
+}
+
+// setLogState transitions the Trillian tree of the log to the state, a log frozen for good is only frozen. The log
+// is not frozen for good (FreezeLog) in between.
+func (c *Cmd) setLogState(alias, state string) error {
+	c.freezesMu.Lock()
+	defer c.freezesMu.Unlock()
+
+	if final := c.freezes[alias]; final != nil && state != LogStateFrozen {
+		return frozenError(final)
+	}
+
+	return c.setTreeState(alias, treeStateOf[state])
+}
+
+// GetLogStates retrieves the states of the logs (GetLogStatesResponse) read from their Trillian trees, sorted by
+// alias.
+func (c *Cmd) GetLogStates(w io.Writer, _ io.Reader) error {
+	aliases := make([]string, 0, len(c.logs))
+	for alias := range c.logs {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	response := GetLogStatesResponse{States: []LogState{}}
+
+	for _, alias := range aliases {
+		state, err := c.logState(alias, true)
+		if err != nil {
+			return err
+		}
+
+		response.States = append(response.States, *state)
+	}
+
+	return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+}
+
+// SetLogState transitions the Trillian tree of the log to the state: a draining log rejects the writes while
+// Trillian integrates the entries queued, a frozen log rejects the writes and its queued entries are not
+// integrated, an active log accepts the writes again. The log must have an admin client. A log frozen for good
+// (FreezeLog) is never active again.
+func (c *Cmd) SetLogState(w io.Writer, r io.Reader) error {
+	var req *SetLogStateRequest
+
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("%w: decode SetLogState request: %v", errors.ErrBadRequest, err)
+	}
+
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("validate SetLogState request: %w", err)
+	}
+
+	if err := c.hasPermissions(req.Alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	if c.logs[req.Alias].AdminClient == nil {
+		return errors.NewNotFoundError(fmt.Errorf("the tree state of log %s is not managed", req.Alias))
+	}
+
+	if err := c.setLogState(req.Alias, req.State); err != nil {
+		return err
+	}
+
+	state, err := c.logState(req.Alias, false)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(state) // nolint: wrapcheck
+}
@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// defaultTrustRegistryTTL is the time a decision of the trust registry is cached for if not configured.
+	defaultTrustRegistryTTL = 10 * time.Minute
+	// maxTrustCacheEntries is the number of cached decisions above which expired decisions are evicted.
+	maxTrustCacheEntries = 10000
+)
+
+// TrustRegistry checks issuers against an external trust registry (e.g. a ToIP trust registry or TRAIN).
+type TrustRegistry interface {
+	// Check returns the decision of the registry on the issuer. An error means that no decision was made
+	// (e.g. the registry is unavailable), it is not cached.
+	Check(ctx context.Context, issuer string) (*TrustDecision, error)
+}
+
+// trustCache caches the decisions of the trust registry by issuer.
+type trustCache struct {
+	registry TrustRegistry
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	decisions map[string]cachedDecision
+}
+
+type cachedDecision struct {
+	decision *TrustDecision
+	expires  time.Time
+}
+
+func newTrustCache(registry TrustRegistry, ttl time.Duration) *trustCache {
+	if registry == nil {
+		return nil
+	}
+
+	if ttl <= 0 {
+		ttl = defaultTrustRegistryTTL
+	}
+
+	return &trustCache{registry: registry, ttl: ttl, decisions: map[string]cachedDecision{}}
+}
+
+func (c *trustCache) check(issuer string) (*TrustDecision, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	cached, ok := c.decisions[issuer]
+	c.mu.Unlock()
+
+	if ok && now.Before(cached.expires) {
+		return cached.decision, nil
+	}
+
+	decision, err := c.registry.Check(context.Background(), issuer)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.decisions) >= maxTrustCacheEntries {
+		for key, d := range c.decisions {
+			if !now.Before(d.expires) {
+				delete(c.decisions, key)
+			}
+		}
+	}
+
+	c.decisions[issuer] = cachedDecision{decision: decision, expires: now.Add(c.ttl)}
+
+	return decision, nil
+}
+
+// checkTrust checks the issuer against the trust registry, if configured, and records the decision
+// in the extensions of the leaf. Entries of issuers the registry does not authorize are rejected.
+func (c *Cmd) checkTrust(leaf *MerkleTreeLeaf, issuer string) error {
+	if c.trust == nil {
+		return nil
+	}
+
+	decision, err := c.trust.check(issuer)
+	if err != nil {
+		return errors.NewServiceUnavailableError(fmt.Errorf("trust registry: %w", err))
+	}
+
+	if !decision.Authorized {
+		return fmt.Errorf("%w: issuer %s is not authorized by trust registry %s", errors.ErrBadRequest, issuer,
+			decision.Registry)
+	}
+
+	return setExtensions(leaf, func(ext *EntryExtensions) { ext.TrustRegistry = decision })
+}
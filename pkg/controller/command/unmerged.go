@@ -0,0 +1,261 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/trillian"
+	"google.golang.org/grpc/codes"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// DefaultMaxRequeues is the number of times an unmerged submission is re-queued by default.
+	DefaultMaxRequeues = 3
+	// unmergedChecksPerTTL is the number of times per TTL the pending submissions are checked, a submission is
+	// reported unmerged at most a quarter of the TTL after it expired.
+	unmergedChecksPerTTL = 4
+)
+
+// SubmissionTTLConfig configures the TTL of the submissions queued by the instance: a submission which is not
+// sequenced within the TTL is unmerged, the log did not honor the SCT issued for it.
+type SubmissionTTLConfig struct {
+	// TTL of the submissions, e.g. the maximum merge delay promised by the SCTs.
+	TTL time.Duration
+	// Requeue re-queues the unmerged submissions to the log, once per TTL.
+	Requeue bool
+	// MaxRequeues is the number of times an unmerged submission is re-queued (defaults to DefaultMaxRequeues).
+	MaxRequeues int
+}
+
+type submissionTTL struct {
+	ttl         time.Duration
+	requeue     bool
+	maxRequeues int
+}
+
+func newSubmissionTTL(cfg *SubmissionTTLConfig) *submissionTTL {
+	if cfg == nil || cfg.TTL <= 0 {
+		return nil
+	}
+
+	maxRequeues := cfg.MaxRequeues
+	if maxRequeues <= 0 {
+		maxRequeues = DefaultMaxRequeues
+	}
+
+	return &submissionTTL{ttl: cfg.TTL, requeue: cfg.Requeue, maxRequeues: maxRequeues}
+}
+
+// MonitorUnmerged checks the submissions pending in the logs unmergedChecksPerTTL times per TTL until the context
+// is done: the unmerged submissions are logged once and counted by the unmerged_submissions gauge, they are
+// re-queued if configured (see SubmissionTTLConfig) while the log accepts writes.
+func (c *Cmd) MonitorUnmerged(ctx context.Context) {
+	if c.submissionTTL == nil {
+		return
+	}
+
+	ticker := time.NewTicker(c.submissionTTL.ttl / unmergedChecksPerTTL)
+	defer ticker.Stop()
+
+	for {
+		for alias := range c.logs {
+			if err := c.checkUnmerged(alias); err != nil {
+				logger.Warnf("check unmerged submissions of log %s: %v", alias, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkUnmerged matches the pending submissions of the log with the sequenced leaves, the unmerged ones are
+// reported and re-queued.
+func (c *Cmd) checkUnmerged(alias string) error {
+	if _, err := c.resolvePending(alias); err != nil {
+		return fmt.Errorf("resolve pending submissions: %w", err)
+	}
+
+	now := time.Now()
+
+	// the re-queued leaves go through the gate of the writes, they are re-queued once the log is writable
+	writable := c.checkWritable(alias)
+
+	count, expired, requeue := c.pending.expire(alias, c.submissionTTL, now, writable == nil)
+
+	if writable != nil && c.submissionTTL.requeue && count > 0 {
+		logger.Infof("unmerged submissions to log %s are not re-queued: %v", alias, writable)
+	}
+
+	unmergedSubmissions.Set(float64(count), alias)
+
+	for _, leaf := range expired {
+		logger.Warnf("submission %x to log %s is not merged %s after it was queued", leaf.identityHash, alias,
+			now.Sub(leaf.queuedAt).Round(time.Second))
+	}
+
+	for _, leaf := range requeue {
+		c.requeueLeaf(alias, leaf)
+	}
+
+	return nil
+}
+
+// expire returns the number of pending leaves of the log queued longer ago than the TTL, the ones which expired
+// since the previous call and the ones to re-queue (copies of the leaves), none unless writable.
+func (p *pendingSubmissions) expire(alias string, ttl *submissionTTL, now time.Time, writable bool) (int,
+	[]pendingLeaf, []pendingLeaf) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		count            int
+		expired, requeue []pendingLeaf
+	)
+
+	for _, leaf := range p.logs[alias].leaves {
+		if now.Sub(leaf.queuedAt) < ttl.ttl {
+			continue
+		}
+
+		count++
+
+		if !leaf.expired {
+			leaf.expired = true
+
+			expired = append(expired, *leaf)
+		}
+
+		if !ttl.requeue || !writable || leaf.leaf == nil || leaf.requeues >= ttl.maxRequeues ||
+			now.Sub(leaf.lastQueuedAt()) < ttl.ttl {
+			continue
+		}
+
+		leaf.requeues++
+		leaf.requeuedAt = now
+
+		requeue = append(requeue, *leaf)
+	}
+
+	return count, expired, requeue
+}
+
+// unmerged returns copies of the pending leaves of the log queued longer ago than the TTL, oldest first.
+func (p *pendingSubmissions) unmerged(alias string, ttl time.Duration, now time.Time) []pendingLeaf {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var unmerged []pendingLeaf
+
+	for _, leaf := range p.logs[alias].leaves {
+		if now.Sub(leaf.queuedAt) >= ttl {
+			unmerged = append(unmerged, *leaf)
+		}
+	}
+
+	sort.Slice(unmerged, func(i, j int) bool { return unmerged[i].queuedAt.Before(unmerged[j].queuedAt) })
+
+	return unmerged
+}
+
+// lastQueuedAt returns the time the leaf was queued or re-queued at last.
+func (l *pendingLeaf) lastQueuedAt() time.Time {
+	if l.requeuedAt.After(l.queuedAt) {
+		return l.requeuedAt
+	}
+
+	return l.queuedAt
+}
+
+// requeueLeaf queues the unmerged leaf to the log again, Trillian answers AlreadyExists if it is still queued.
+func (c *Cmd) requeueLeaf(alias string, leaf pendingLeaf) {
+	resp, err := c.logs[alias].Client.QueueLeaf(context.Background(), &trillian.QueueLeafRequest{
+		LogId: c.logs[alias].ID,
+		Leaf: &trillian.LogLeaf{
+			LeafValue:        leaf.leaf.GetLeafValue(),
+			ExtraData:        leaf.leaf.GetExtraData(),
+			LeafIdentityHash: leaf.identityHash,
+		},
+	})
+	if err != nil {
+		requeuedSubmissions.Inc(alias, "failed")
+
+		logger.Errorf("re-queue submission %x to log %s: %v", leaf.identityHash, alias, err)
+
+		return
+	}
+
+	status := "queued"
+	if resp.GetQueuedLeaf().GetStatus().GetCode() == int32(codes.AlreadyExists) {
+		status = "exists"
+	}
+
+	requeuedSubmissions.Inc(alias, status)
+
+	logger.Infof("re-queued submission %x to log %s (attempt %d): %s", leaf.identityHash, alias, leaf.requeues,
+		status)
+}
+
+// GetUnmergedSubmissions lists the submissions to the log queued by the instance which are not sequenced within
+// the TTL, oldest first.
+func (c *Cmd) GetUnmergedSubmissions(w io.Writer, r io.Reader) error {
+	var alias string
+
+	if err := json.NewDecoder(r).Decode(&alias); err != nil {
+		return fmt.Errorf("%w: decode alias failed", errors.ErrInternal)
+	}
+
+	if err := c.hasPermissions(alias, read); err != nil {
+		return fmt.Errorf("has permissions: %w", err)
+	}
+
+	if c.submissionTTL == nil {
+		return errors.NewNotFoundError(fmt.Errorf("submission TTL is not enabled"))
+	}
+
+	treeSize, err := c.resolvePending(alias)
+	if err != nil {
+		return fmt.Errorf("resolve pending submissions: %w", err)
+	}
+
+	now := time.Now()
+
+	unmerged := c.pending.unmerged(alias, c.submissionTTL.ttl, now)
+
+	response := GetUnmergedSubmissionsResponse{
+		TTL:         uint64(c.submissionTTL.ttl / time.Millisecond),
+		TreeSize:    treeSize,
+		Submissions: make([]UnmergedSubmission, len(unmerged)),
+	}
+
+	for i, leaf := range unmerged {
+		response.Submissions[i] = UnmergedSubmission{
+			LeafIdentityHash: leaf.identityHash,
+			MerkleLeafHash:   leaf.merkleLeafHash,
+			QueuedAt:         uint64(leaf.queuedAt.UnixNano()) / uint64(time.Millisecond),
+			Age:              uint64(now.Sub(leaf.queuedAt) / time.Millisecond),
+			Requeues:         leaf.requeues,
+		}
+
+		if !leaf.requeuedAt.IsZero() {
+			response.Submissions[i].RequeuedAt = uint64(leaf.requeuedAt.UnixNano()) / uint64(time.Millisecond)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(response) // nolint: wrapcheck
+}
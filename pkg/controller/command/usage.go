@@ -0,0 +1,154 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+// usage counts the entries and the requests of the logs per tenant since the start, for billing and monitoring
+// multi-tenant deployments. The counts are reported by GetUsage and as metrics labeled with the tenant.
+type usage struct {
+	mu    sync.Mutex
+	since time.Time
+	logs  map[string]*LogUsage // alias -> usage
+}
+
+func newUsage(logs map[string]Log) *usage {
+	u := &usage{since: time.Now(), logs: map[string]*LogUsage{}}
+
+	for alias := range logs {
+		u.logs[alias] = &LogUsage{Alias: alias}
+	}
+
+	return u
+}
+
+// tenant returns the tenant of the log, the alias of the log if no tenant is configured.
+func (l Log) tenant() string {
+	if l.Tenant != "" {
+		return l.Tenant
+	}
+
+	return l.Alias
+}
+
+// recordEntry counts the entry of the size logged to the log, or its duplicate.
+func (c *Cmd) recordEntry(alias string, size int, duplicate bool) {
+	log, ok := c.logs[alias]
+	if !ok {
+		return
+	}
+
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	logUsage := c.usage.logs[alias]
+
+	if duplicate {
+		logUsage.Duplicates++
+
+		tenantDuplicates.Inc(log.tenant(), alias)
+
+		return
+	}
+
+	logUsage.Entries++
+	logUsage.EntryBytes += uint64(size)
+
+	tenantEntries.Inc(log.tenant(), alias)
+	tenantEntryBytes.Add(float64(size), log.tenant(), alias)
+}
+
+// RecordRequest counts the request of the operation (e.g. add-vc) to the log which completed with the status
+// after the latency. Requests to unknown logs are not counted, the aliases are not bounded.
+func (c *Cmd) RecordRequest(alias, operation string, write bool, status int, latency time.Duration) {
+	log, ok := c.logs[alias]
+	if !ok {
+		return
+	}
+
+	const statusClass = 100
+
+	tenantRequests.Inc(log.tenant(), alias, operation, strconv.Itoa(status/statusClass)+"xx")
+	tenantLatency.Observe(latency.Seconds(), log.tenant(), alias, operation)
+
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	logUsage := c.usage.logs[alias]
+
+	if write {
+		logUsage.Writes++
+	} else {
+		logUsage.Reads++
+	}
+
+	if status >= http.StatusBadRequest {
+		logUsage.Errors++
+
+		tenantErrors.Inc(log.tenant(), alias, operation)
+	}
+}
+
+// GetUsage retrieves the usage of the logs per tenant since the start, the request may select a tenant.
+func (c *Cmd) GetUsage(w io.Writer, r io.Reader) error {
+	var request GetUsageRequest
+
+	if r != nil {
+		if err := json.NewDecoder(r).Decode(&request); err != nil && err != io.EOF { // nolint: errorlint
+			return fmt.Errorf("%w: decode GetUsage request: %v", errors.ErrBadRequest, err)
+		}
+	}
+
+	tenants := map[string]*TenantUsage{}
+
+	c.usage.mu.Lock()
+
+	for alias, logUsage := range c.usage.logs {
+		tenant := c.logs[alias].tenant()
+		if request.Tenant != "" && tenant != request.Tenant {
+			continue
+		}
+
+		if _, ok := tenants[tenant]; !ok {
+			tenants[tenant] = &TenantUsage{Tenant: tenant}
+		}
+
+		tenants[tenant].Logs = append(tenants[tenant].Logs, *logUsage)
+	}
+
+	c.usage.mu.Unlock()
+
+	if request.Tenant != "" && len(tenants) == 0 {
+		return errors.NewNotFoundError(fmt.Errorf("tenant %q is not found", request.Tenant))
+	}
+
+	resp := GetUsageResponse{
+		Since:   uint64(c.usage.since.UnixNano()) / uint64(time.Millisecond),
+		Tenants: make([]TenantUsage, 0, len(tenants)),
+	}
+
+	for _, tenantUsage := range tenants {
+		sort.Slice(tenantUsage.Logs, func(i, j int) bool { return tenantUsage.Logs[i].Alias < tenantUsage.Logs[j].Alias })
+
+		resp.Tenants = append(resp.Tenants, *tenantUsage)
+	}
+
+	sort.Slice(resp.Tenants, func(i, j int) bool { return resp.Tenants[i].Tenant < resp.Tenants[j].Tenant })
+
+	return json.NewEncoder(w).Encode(resp) // nolint: wrapcheck
+}
@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// maxPinnedTiles is the number of full tiles of a log WarmCache pins, a tile above the level 0 is computed with
+// TileWidth proofs.
+const maxPinnedTiles = 64
+
+// WarmCache preloads the read cache after a restart: it fetches the latest tree head of the logs, so the tiles
+// of the tree are served without fetching it again, and pins the full tiles of the top levels of the trees
+// (as many levels as fit maxPinnedTiles) so the first proof requests don't all compute them from Trillian.
+// The logs storing their tree as tiles only have their tree head fetched.
+func (c *Cmd) WarmCache(ctx context.Context) error {
+	aliases := make([]string, 0, len(c.logs))
+	for alias := range c.logs {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		pinned, err := c.warmLog(ctx, alias)
+		if err != nil {
+			return fmt.Errorf("warm log %s: %w", alias, err)
+		}
+
+		logger.Infof("warmed the cache of log %s: %d tiles pinned", alias, pinned)
+	}
+
+	return nil
+}
+
+// warmLog pins the full tiles of the top levels of the tree of the log, it returns the number of pinned tiles.
+func (c *Cmd) warmLog(ctx context.Context, alias string) (int, error) {
+	log := c.logs[alias]
+
+	treeSize, err := latestTreeSize(log)
+	if err != nil {
+		return 0, err
+	}
+
+	c.tiles.setTreeSize(alias, treeSize)
+
+	if _, ok := log.Client.(TileReader); ok {
+		return 0, nil
+	}
+
+	var pinned int
+
+	for level := MaxTileLevel; level >= 0; level-- {
+		full := treeSize >> (TileHeight * uint(level+1))
+		if full == 0 {
+			continue
+		}
+
+		if uint64(pinned)+full > maxPinnedTiles {
+			break
+		}
+
+		for index := uint64(0); index < full; index++ {
+			if err = ctx.Err(); err != nil {
+				return pinned, err // nolint: wrapcheck
+			}
+
+			var hashes [][]byte
+
+			if level == 0 {
+				hashes, err = tileLeafHashes(log, index, TileWidth)
+			} else {
+				hashes, err = tileNodeHashes(log, uint(level), index, TileWidth)
+			}
+
+			if err != nil {
+				return pinned, fmt.Errorf("tile %d/%d: %w", level, index, err)
+			}
+
+			c.tiles.pin(tileKey{alias: alias, level: uint(level), index: index}, hashes)
+
+			pinned++
+		}
+	}
+
+	return pinned, nil
+}
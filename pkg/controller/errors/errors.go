@@ -20,6 +20,46 @@ var (
 	ErrBadRequest = NewBadRequestError(New("bad request"))
 	ErrNotFound   = NewNotFoundError(New("not found"))
 	ErrInternal   = NewStatusInternalServerError(New("internal error"))
+	// ErrReadOnly is returned for writes while the service is in the read-only (maintenance) mode,
+	// the write may be retried once the mode is disabled.
+	ErrReadOnly = NewServiceUnavailableError(New("read-only"))
+	// ErrCompromised is returned for writes once the key of the log is marked compromised, writes are frozen
+	// for good.
+	ErrCompromised = NewGoneError(New("compromised"))
+	// ErrFrozen is returned for writes to a frozen log, the log accepts no more entries.
+	ErrFrozen = NewGoneError(New("frozen"))
+	// ErrDraining is returned for writes to a log whose Trillian tree is draining: the entries queued are still
+	// integrated, the log accepts no more entries until it is active again.
+	ErrDraining = NewServiceUnavailableError(New("draining"))
+	// ErrTreeFrozen is returned for writes to a log whose Trillian tree is frozen, the log accepts no more entries
+	// until it is active again (the freeze of the log itself is final, see ErrFrozen).
+	ErrTreeFrozen = NewServiceUnavailableError(New("tree frozen"))
+	// ErrDisabled is returned for the operations disabled by the feature flags of the deployment, it is wrapped
+	// in a forbidden error for the operations disabled for a tenant.
+	ErrDisabled = NewNotFoundError(New("disabled"))
+)
+
+// Problem types (RFC 7807) returned by the service.
+// The type URIs are stable and may be used by clients to handle errors regardless of the message language.
+const (
+	ProblemTypeBase               = "https://trustbloc.dev/ns/vct/problems/"
+	ProblemTypeValidation         = ProblemTypeBase + "validation"
+	ProblemTypeBadRequest         = ProblemTypeBase + "bad-request"
+	ProblemTypeUnauthorized       = ProblemTypeBase + "unauthorized"
+	ProblemTypeForbidden          = ProblemTypeBase + "forbidden"
+	ProblemTypeNotFound           = ProblemTypeBase + "not-found"
+	ProblemTypeTimeout            = ProblemTypeBase + "timeout"
+	ProblemTypeConflict           = ProblemTypeBase + "conflict"
+	ProblemTypePreconditionFailed = ProblemTypeBase + "precondition-failed"
+	ProblemTypeInternal           = ProblemTypeBase + "internal"
+	ProblemTypeNotImplemented     = ProblemTypeBase + "not-implemented"
+	ProblemTypeUnavailable        = ProblemTypeBase + "unavailable"
+	ProblemTypeReadOnly           = ProblemTypeBase + "read-only"
+	ProblemTypeCompromised        = ProblemTypeBase + "compromised"
+	ProblemTypeFrozen             = ProblemTypeBase + "frozen"
+	ProblemTypeDraining           = ProblemTypeBase + "draining"
+	ProblemTypeTreeFrozen         = ProblemTypeBase + "tree-frozen"
+	ProblemTypeDisabled           = ProblemTypeBase + "disabled"
 )
 
 // StatusErr an error with status code.
@@ -58,6 +98,26 @@ func NewNotFoundError(err error) *StatusErr {
 	return &StatusErr{error: err, status: http.StatusNotFound}
 }
 
+// NewForbiddenError represents ForbiddenError.
+func NewForbiddenError(err error) *StatusErr {
+	return &StatusErr{error: err, status: http.StatusForbidden}
+}
+
+// NewServiceUnavailableError represents ServiceUnavailableError.
+func NewServiceUnavailableError(err error) *StatusErr {
+	return &StatusErr{error: err, status: http.StatusServiceUnavailable}
+}
+
+// NewConflictError represents ConflictError.
+func NewConflictError(err error) *StatusErr {
+	return &StatusErr{error: err, status: http.StatusConflict}
+}
+
+// NewGoneError represents GoneError.
+func NewGoneError(err error) *StatusErr {
+	return &StatusErr{error: err, status: http.StatusGone}
+}
+
 // StatusCodeFromError returns status code if an error implements an interface the func supports rpc errors as well.
 func StatusCodeFromError(e error) int {
 	if err, ok := e.(interface{ StatusCode() int }); ok { // nolint: errorlint
@@ -76,6 +136,60 @@ func StatusCodeFromError(e error) int {
 	return http.StatusInternalServerError
 }
 
+// ProblemTypeFromError returns the problem type URI for the given error.
+func ProblemTypeFromError(e error) string {
+	if errors.Is(e, ErrValidation) {
+		return ProblemTypeValidation
+	}
+
+	if errors.Is(e, ErrReadOnly) {
+		return ProblemTypeReadOnly
+	}
+
+	if errors.Is(e, ErrCompromised) {
+		return ProblemTypeCompromised
+	}
+
+	if errors.Is(e, ErrFrozen) {
+		return ProblemTypeFrozen
+	}
+
+	if errors.Is(e, ErrDraining) {
+		return ProblemTypeDraining
+	}
+
+	if errors.Is(e, ErrTreeFrozen) {
+		return ProblemTypeTreeFrozen
+	}
+
+	if errors.Is(e, ErrDisabled) {
+		return ProblemTypeDisabled
+	}
+
+	switch StatusCodeFromError(e) {
+	case http.StatusBadRequest:
+		return ProblemTypeBadRequest
+	case http.StatusUnauthorized:
+		return ProblemTypeUnauthorized
+	case http.StatusForbidden:
+		return ProblemTypeForbidden
+	case http.StatusNotFound:
+		return ProblemTypeNotFound
+	case http.StatusRequestTimeout:
+		return ProblemTypeTimeout
+	case http.StatusConflict:
+		return ProblemTypeConflict
+	case http.StatusPreconditionFailed:
+		return ProblemTypePreconditionFailed
+	case http.StatusNotImplemented:
+		return ProblemTypeNotImplemented
+	case http.StatusServiceUnavailable:
+		return ProblemTypeUnavailable
+	default:
+		return ProblemTypeInternal
+	}
+}
+
 func statusCodeFromRPCError(rpcCode codes.Code) int {
 	switch rpcCode { // nolint: exhaustive
 	case codes.OK:
@@ -26,6 +26,9 @@ func TestStatusCodeFromError(t *testing.T) {
 	require.Equal(t, StatusCodeFromError(NewStatusInternalServerError(New(errMsg))), http.StatusInternalServerError)
 	require.Equal(t, StatusCodeFromError(NewBadRequestError(New(errMsg))), http.StatusBadRequest)
 	require.Equal(t, StatusCodeFromError(NewNotFoundError(New(errMsg))), http.StatusNotFound)
+	require.Equal(t, StatusCodeFromError(NewServiceUnavailableError(New(errMsg))), http.StatusServiceUnavailable)
+	require.Equal(t, StatusCodeFromError(NewConflictError(New(errMsg))), http.StatusConflict)
+	require.Equal(t, StatusCodeFromError(NewForbiddenError(New(errMsg))), http.StatusForbidden)
 
 	// grpc errors
 	require.Equal(t, StatusCodeFromError(status.Error(codes.OK, errMsg)), http.StatusOK)
@@ -64,3 +67,37 @@ func TestStatusCodeFromError(t *testing.T) {
 	require.True(t, errors.Is(fmt.Errorf("wrapped: %w", ErrInternal), ErrInternal))
 	require.Equal(t, errors.Unwrap(NewBadRequestError(fmt.Errorf("wrapped: %w", ErrInternal))), ErrInternal)
 }
+
+func TestProblemTypeFromError(t *testing.T) {
+	const errMsg = "error"
+
+	require.Equal(t, ProblemTypeValidation, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrValidation)))
+	require.Equal(t, ProblemTypeBadRequest, ProblemTypeFromError(ErrBadRequest))
+	require.Equal(t, ProblemTypeNotFound, ProblemTypeFromError(ErrNotFound))
+	require.Equal(t, ProblemTypeInternal, ProblemTypeFromError(ErrInternal))
+	require.Equal(t, ProblemTypeReadOnly, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrReadOnly)))
+	require.Equal(t, ProblemTypeCompromised, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrCompromised)))
+	require.Equal(t, http.StatusGone, StatusCodeFromError(ErrCompromised))
+	require.Equal(t, ProblemTypeFrozen, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrFrozen)))
+	require.Equal(t, http.StatusGone, StatusCodeFromError(ErrFrozen))
+	require.Equal(t, ProblemTypeDraining, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrDraining)))
+	require.Equal(t, ProblemTypeTreeFrozen, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrTreeFrozen)))
+	require.Equal(t, http.StatusServiceUnavailable, StatusCodeFromError(ErrTreeFrozen))
+	require.Equal(t, ProblemTypeDisabled, ProblemTypeFromError(fmt.Errorf("wrapped: %w", ErrDisabled)))
+	require.Equal(t, http.StatusNotFound, StatusCodeFromError(ErrDisabled))
+	require.Equal(t, ProblemTypeDisabled, ProblemTypeFromError(NewForbiddenError(fmt.Errorf("wrapped: %w", ErrDisabled))))
+	require.Equal(t, http.StatusForbidden, StatusCodeFromError(NewForbiddenError(fmt.Errorf("wrapped: %w", ErrDisabled))))
+	require.Equal(t, ProblemTypeUnavailable, ProblemTypeFromError(NewServiceUnavailableError(New(errMsg))))
+	require.Equal(t, ProblemTypeConflict, ProblemTypeFromError(NewConflictError(New(errMsg))))
+	require.Equal(t, ProblemTypeInternal, ProblemTypeFromError(New(errMsg)))
+
+	// grpc errors
+	require.Equal(t, ProblemTypeTimeout, ProblemTypeFromError(status.Error(codes.DeadlineExceeded, errMsg)))
+	require.Equal(t, ProblemTypeForbidden, ProblemTypeFromError(status.Error(codes.PermissionDenied, errMsg)))
+	require.Equal(t, ProblemTypeUnauthorized, ProblemTypeFromError(status.Error(codes.Unauthenticated, errMsg)))
+	require.Equal(t, ProblemTypePreconditionFailed,
+		ProblemTypeFromError(status.Error(codes.FailedPrecondition, errMsg)))
+	require.Equal(t, ProblemTypeConflict, ProblemTypeFromError(status.Error(codes.Aborted, errMsg)))
+	require.Equal(t, ProblemTypeNotImplemented, ProblemTypeFromError(status.Error(codes.Unimplemented, errMsg)))
+	require.Equal(t, ProblemTypeUnavailable, ProblemTypeFromError(status.Error(codes.Unavailable, errMsg)))
+}
@@ -0,0 +1,45 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// featureGate is implemented by the commands which disable operations by feature flags
+// (see command.Cmd.CheckOperation).
+type featureGate interface {
+	CheckOperation(alias, operation string) error
+}
+
+// gateFeatures wraps the handlers to reject the requests of the disabled operations if the command disables them.
+func (c *Operation) gateFeatures(handlers []Handler) []Handler {
+	gate, ok := c.cmd.(featureGate)
+	if !ok {
+		return handlers
+	}
+
+	for i, h := range handlers {
+		handlers[i] = NewHTTPHandler(h.Path(), h.Method(), checkOperation(gate, OperationName(h.Path()), h.Handle()))
+	}
+
+	return handlers
+}
+
+func checkOperation(gate featureGate, operation string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := gate.CheckOperation(mux.Vars(r)[aliasVarName], operation); err != nil {
+			sendError(w, err)
+
+			return
+		}
+
+		next(w, r)
+	}
+}
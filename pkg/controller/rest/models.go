@@ -31,6 +31,9 @@ type addVCRequest struct { // nolint: unused,deadcode
 	Alias string `json:"alias"`
 
 	// Verifiable Credentials https://www.w3.org/TR/vc-data-model
+	// Alternatively, an enveloped submission may be sent:
+	// {"credential": <credential>, "options": {"tenant", "dryRun", "callbackURL", "idempotencyKey", "supersedes",
+	// "policyTags": {"jurisdiction", "assurance_level"}}}
 	//
 	// in: body
 	Body struct {
@@ -92,6 +95,21 @@ type getIssuersRequest struct { // nolint: unused,deadcode
 	// in: path
 	// required: true
 	Alias string `json:"alias"`
+
+	// PageSize size of the page of the list, the whole list is returned if neither the page size nor the page
+	// token is set
+	PageSize int `json:"page_size"`
+
+	// PageToken next_page_token of the previous page
+	PageToken string `json:"page_token"`
+
+	// Jurisdiction returns the issuers of the entries of the jurisdiction (or of its subdivisions) instead of the
+	// issuers of the log, as a page
+	Jurisdiction string `json:"jurisdiction"`
+
+	// AssuranceLevel returns the issuers of the entries of the assurance level instead of the issuers of the log,
+	// as a page
+	AssuranceLevel string `json:"assurance_level"`
 }
 
 // Response message
@@ -102,6 +120,17 @@ type getIssuersResponse struct { // nolint: unused,deadcode
 	Body []string
 }
 
+// Response message
+//
+// swagger:response getIssuersPageResponse
+type getIssuersPageResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		Issuers       []string `json:"issuers"`
+		NextPageToken string   `json:"next_page_token"`
+	}
+}
+
 // Request message
 //
 // swagger:parameters healthCheckRequest
@@ -152,6 +181,9 @@ type getSTHConsistencyRequest struct { // nolint: unused,deadcode
 
 	// Second
 	Second int `json:"second"`
+
+	// Debug returns the timing breakdown of the proof, admin only
+	Debug bool `json:"debug"`
 }
 
 // Response message
@@ -160,7 +192,8 @@ type getSTHConsistencyRequest struct { // nolint: unused,deadcode
 type getSTHConsistencyResponse struct { // nolint: unused,deadcode
 	// in: body
 	Body struct {
-		Consistency []string `json:"consistency"`
+		Consistency []string            `json:"consistency"`
+		Debug       *command.ProofTrace `json:"debug"`
 	}
 }
 
@@ -179,6 +212,9 @@ type getProofByHashRequest struct { // nolint: unused,deadcode
 
 	// Tree size
 	TreeSize int `json:"tree_size"`
+
+	// Debug returns the timing breakdown of the proof, admin only
+	Debug bool `json:"debug"`
 }
 
 // Response message
@@ -187,8 +223,9 @@ type getProofByHashRequest struct { // nolint: unused,deadcode
 type getProofByHashResponse struct { // nolint: unused,deadcode
 	// in: body
 	Body struct {
-		LeafIndex int64    `json:"leaf_index"`
-		AuditPath []string `json:"audit_path"`
+		LeafIndex int64               `json:"leaf_index"`
+		AuditPath []string            `json:"audit_path"`
+		Debug     *command.ProofTrace `json:"debug"`
 	}
 }
 
@@ -207,6 +244,18 @@ type getEntriesRequest struct { // nolint: unused,deadcode
 
 	// End
 	End int `json:"end"`
+
+	// FromTime RFC3339 time, the first entry sequenced at or after it is returned first (start and end are ignored)
+	FromTime string `json:"from_time"`
+
+	// ToTime RFC3339 time, the last entry sequenced at or before it is returned last (start and end are ignored)
+	ToTime string `json:"to_time"`
+
+	// Jurisdiction returns the entries of the range tagged with the jurisdiction (or one of its subdivisions)
+	Jurisdiction string `json:"jurisdiction"`
+
+	// AssuranceLevel returns the entries of the range tagged with the assurance level
+	AssuranceLevel string `json:"assurance_level"`
 }
 
 // Response message
@@ -216,8 +265,10 @@ type getEntriesResponse struct { // nolint: unused,deadcode
 	// in: body
 	Body struct {
 		Entries []struct {
-			LeafInput string `json:"leaf_input"`
-			ExtraData string `json:"extra_data"`
+			LeafInput   string             `json:"leaf_input"`
+			ExtraData   string             `json:"extra_data"`
+			Annotations []signedAnnotation `json:"annotations"`
+			LeafIndex   int64              `json:"leaf_index"`
 		} `json:"entries"`
 	}
 }
@@ -237,6 +288,9 @@ type getEntryAndProofRequest struct { // nolint: unused,deadcode
 
 	// TreeSize
 	TreeSize int `json:"tree_size"`
+
+	// Debug returns the timing breakdown of the proof, admin only
+	Debug bool `json:"debug"`
 }
 
 // Response message
@@ -245,8 +299,977 @@ type getEntryAndProofRequest struct { // nolint: unused,deadcode
 type getEntryAndProofResponse struct { // nolint: unused,deadcode
 	// in: body
 	Body struct {
-		LeafInput string   `json:"leaf_input"`
-		ExtraData string   `json:"extra_data"`
-		AuditPath []string `json:"audit_path"`
+		LeafInput   string              `json:"leaf_input"`
+		ExtraData   string              `json:"extra_data"`
+		AuditPath   []string            `json:"audit_path"`
+		Annotations []signedAnnotation  `json:"annotations"`
+		Debug       *command.ProofTrace `json:"debug"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getProofOfAbsenceRequest
+type getProofOfAbsenceRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Issuer
+	Issuer string `json:"issuer"`
+
+	// CredentialID
+	CredentialID string `json:"credential_id"`
+}
+
+// Response message
+//
+// swagger:response getProofOfAbsenceResponse
+type getProofOfAbsenceResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		TreeSize          int    `json:"tree_size"`
+		Timestamp         int    `json:"timestamp"`
+		Issuer            string `json:"issuer"`
+		CredentialID      string `json:"credential_id"`
+		SHA256MapRootHash string `json:"sha256_map_root_hash"`
+		AuditPath         struct {
+			Siblings      []string `json:"siblings"`
+			LeafKey       string   `json:"leaf_key"`
+			LeafValueHash string   `json:"leaf_value_hash"`
+		} `json:"audit_path"`
+		Signature string `json:"signature"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getMapRootRequest
+type getMapRootRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Response message
+//
+// swagger:response getMapRootResponse
+type getMapRootResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		TreeSize          uint64 `json:"tree_size"`
+		Timestamp         uint64 `json:"timestamp"`
+		SHA256MapRootHash string `json:"sha256_map_root_hash"`
+		MapRootSignature  string `json:"map_root_signature"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getCredentialStatusRequest
+type getCredentialStatusRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Issuer
+	Issuer string `json:"issuer"`
+
+	// CredentialID
+	CredentialID string `json:"credential_id"`
+}
+
+// Response message
+//
+// swagger:response getCredentialStatusResponse
+type getCredentialStatusResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		Issuer       string `json:"issuer"`
+		CredentialID string `json:"credential_id"`
+		Entry        struct {
+			LeafIndex      int    `json:"leaf_index"`
+			MerkleLeafHash string `json:"merkle_leaf_hash"`
+		} `json:"entry"`
+		AuditPath struct {
+			Siblings      []string `json:"siblings"`
+			LeafKey       string   `json:"leaf_key"`
+			LeafValueHash string   `json:"leaf_value_hash"`
+		} `json:"audit_path"`
+		MapRoot struct {
+			TreeSize          uint64 `json:"tree_size"`
+			Timestamp         uint64 `json:"timestamp"`
+			SHA256MapRootHash string `json:"sha256_map_root_hash"`
+			MapRootSignature  string `json:"map_root_signature"`
+		} `json:"map_root"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters addRevocationRequest
+type addRevocationRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// in: body
+	Body struct {
+		// Merkle leaf hash of the logged credential
+		LeafHash string `json:"leaf_hash"`
+		// One of revoked, suspended, reinstated
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getRevocationsRequest
+type getRevocationsRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// LeafHash Merkle leaf hash of the logged credential
+	LeafHash string `json:"leaf_hash"`
+
+	// PageSize size of the page of the list, the whole list is returned if neither the page size nor the page
+	// token is set
+	PageSize int `json:"page_size"`
+
+	// PageToken next_page_token of the previous page
+	PageToken string `json:"page_token"`
+}
+
+// Response message
+//
+// swagger:response getRevocationsResponse
+type getRevocationsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		LeafHash string `json:"leaf_hash"`
+		Events   []struct {
+			LeafIndex      int    `json:"leaf_index"`
+			MerkleLeafHash string `json:"merkle_leaf_hash"`
+			Timestamp      uint64 `json:"timestamp"`
+			Event          struct {
+				LeafHash string `json:"leaf_hash"`
+				Status   string `json:"status"`
+				Reason   string `json:"reason"`
+			} `json:"event"`
+		} `json:"events"`
+		NextPageToken string `json:"next_page_token"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getReceiptRequest
+type getReceiptRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Key idempotency key of the submission (add-vc options)
+	//
+	// in: path
+	// required: true
+	Key string `json:"key"`
+}
+
+type signedAnnotation struct { // nolint: unused,deadcode
+	Annotation struct {
+		Version       uint8  `json:"version"`
+		SignatureType uint64 `json:"signature_type"`
+		Timestamp     uint64 `json:"timestamp"`
+		LogID         string `json:"log_id"`
+		LeafIndex     int64  `json:"leaf_index"`
+		Auditor       string `json:"auditor"`
+		// Kind of the annotation, e.g. fraudulent
+		Kind    string `json:"kind"`
+		Comment string `json:"comment"`
+	} `json:"annotation"`
+	// Signature DigitallySigned signature by the key of the auditor
+	Signature string `json:"signature"`
+}
+
+// Request message
+//
+// swagger:parameters addAnnotationRequest
+type addAnnotationRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// in: body
+	Body signedAnnotation
+}
+
+// Response message
+//
+// swagger:response addAnnotationResponse
+type addAnnotationResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body signedAnnotation
+}
+
+// Request message
+//
+// swagger:parameters getAnnotationsRequest
+type getAnnotationsRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// LeafIndex index of the entry
+	LeafIndex int64 `json:"leaf_index"`
+}
+
+// Response message
+//
+// swagger:response getAnnotationsResponse
+type getAnnotationsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		LeafIndex   int64              `json:"leaf_index"`
+		Annotations []signedAnnotation `json:"annotations"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getCredentialHistoryRequest
+type getCredentialHistoryRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Hash URL-safe base64 Merkle leaf hash of a logged credential
+	//
+	// in: path
+	// required: true
+	Hash string `json:"hash"`
+}
+
+// Response message
+//
+// swagger:response getCredentialHistoryResponse
+type getCredentialHistoryResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		Entries []struct {
+			LeafIndex      int    `json:"leaf_index"`
+			MerkleLeafHash string `json:"merkle_leaf_hash"`
+			Timestamp      uint64 `json:"timestamp"`
+			CredentialID   string `json:"credential_id"`
+			// Merkle leaf hash of the credential the entry re-issues
+			Supersedes  string `json:"supersedes"`
+			Revocations []struct {
+				LeafIndex      int    `json:"leaf_index"`
+				MerkleLeafHash string `json:"merkle_leaf_hash"`
+				Timestamp      uint64 `json:"timestamp"`
+				Event          struct {
+					LeafHash string `json:"leaf_hash"`
+					Status   string `json:"status"`
+					Reason   string `json:"reason"`
+				} `json:"event"`
+			} `json:"revocations"`
+		} `json:"entries"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters addAnchorRequest
+type addAnchorRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// in: body
+	Body struct {
+		// SHA256 hash of the public key of the anchored log
+		LogID     string `json:"log_id"`
+		PublicKey string `json:"public_key"`
+		STH       struct {
+			TreeSize          uint64 `json:"tree_size"`
+			Timestamp         uint64 `json:"timestamp"`
+			SHA256RootHash    string `json:"sha256_root_hash"`
+			TreeHeadSignature string `json:"tree_head_signature"`
+		} `json:"sth"`
+		// Consistency proof from the tree head anchored before
+		Consistency []string `json:"consistency"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getAnchorsRequest
+type getAnchorsRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// LogID SHA256 hash of the public key of the anchored log
+	LogID string `json:"log_id"`
+
+	// PageSize size of the page of the list, the whole list is returned if neither the page size nor the page
+	// token is set
+	PageSize int `json:"page_size"`
+
+	// PageToken next_page_token of the previous page
+	PageToken string `json:"page_token"`
+}
+
+// Response message
+//
+// swagger:response getAnchorsResponse
+type getAnchorsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		LogID   string `json:"log_id"`
+		Anchors []struct {
+			LeafIndex      int    `json:"leaf_index"`
+			MerkleLeafHash string `json:"merkle_leaf_hash"`
+			Timestamp      uint64 `json:"timestamp"`
+			Anchor         struct {
+				LogID     string `json:"log_id"`
+				PublicKey string `json:"public_key"`
+				STH       struct {
+					TreeSize          uint64 `json:"tree_size"`
+					Timestamp         uint64 `json:"timestamp"`
+					SHA256RootHash    string `json:"sha256_root_hash"`
+					TreeHeadSignature string `json:"tree_head_signature"`
+				} `json:"sth"`
+				Consistency []string `json:"consistency"`
+			} `json:"anchor"`
+		} `json:"anchors"`
+		NextPageToken string `json:"next_page_token"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters addEntryRequest
+type addEntryRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// in: body
+	Body struct {
+		// Leaf type of the entry as published in webfinger
+		EntryType uint64 `json:"entry_type"`
+		// Entry of the leaf type, e.g. {"hash":"..."} for a commitment
+		Entry map[string]interface{} `json:"entry"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getShadowStatusRequest
+type getShadowStatusRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Response message
+//
+// swagger:response getShadowStatusResponse
+type getShadowStatusResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		Mirrored        uint64 `json:"mirrored"`
+		Failed          uint64 `json:"failed"`
+		Diverged        uint64 `json:"diverged"`
+		PrimaryTreeSize int64  `json:"primary_tree_size"`
+		ShadowTreeSize  int64  `json:"shadow_tree_size"`
+		Divergences     []struct {
+			Timestamp        uint64 `json:"timestamp"`
+			LeafIdentityHash string `json:"leaf_identity_hash"`
+			Reason           string `json:"reason"`
+		} `json:"divergences"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getReadOnlyRequest
+type getReadOnlyRequest struct{} // nolint: unused,deadcode
+
+// Request message
+//
+// swagger:parameters setReadOnlyRequest
+type setReadOnlyRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		ReadOnly bool `json:"read_only"`
 	}
 }
+
+// Response message
+//
+// swagger:response readOnlyResponse
+type readOnlyResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		ReadOnly bool `json:"read_only"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getKeyUsageRequest
+type getKeyUsageRequest struct{} // nolint: unused,deadcode
+
+// Response message
+//
+// swagger:response getKeyUsageResponse
+type getKeyUsageResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body struct {
+		LogID string `json:"log_id"`
+		Usage []struct {
+			Kind       string `json:"kind"`
+			Signatures uint64 `json:"signatures"`
+			LastUsed   uint64 `json:"last_used"`
+			Window     uint64 `json:"window"`
+			Threshold  uint64 `json:"threshold"`
+			Anomalies  uint64 `json:"anomalies"`
+		} `json:"usage"`
+	}
+}
+
+// Request message
+//
+// swagger:parameters getUsageRequest
+type getUsageRequest struct { // nolint: unused,deadcode
+	// Tenant of the logs, the usage of all tenants is returned if not set.
+	//
+	// in: query
+	Tenant string `json:"tenant"`
+}
+
+// Response message
+//
+// swagger:response getUsageResponse
+type getUsageResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetUsageResponse
+}
+
+// Request message
+//
+// swagger:parameters getSubmissionStatsRequest
+type getSubmissionStatsRequest struct { // nolint: unused,deadcode
+	// Alias of the log, the issuers of all the logs are returned if not set.
+	//
+	// in: query
+	Alias string `json:"alias"`
+	// Issuer, all the issuers are returned if not set.
+	//
+	// in: query
+	Issuer string `json:"issuer"`
+	// Dimension the issuers are ranked by (the 95th percentile, descending): size, contexts or processing_time (the
+	// default).
+	//
+	// in: query
+	SortBy string `json:"sort_by"`
+	// Max number of issuers returned (defaults to 10).
+	//
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// Response message
+//
+// swagger:response getSubmissionStatsResponse
+type getSubmissionStatsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetSubmissionStatsResponse
+}
+
+// Request message
+//
+// swagger:parameters getPendingSubmissionsRequest
+type getPendingSubmissionsRequest struct { // nolint: unused,deadcode
+	// Alias of the log, the submissions pending in all the logs are returned if not set.
+	//
+	// in: query
+	Alias string `json:"alias"`
+	// Max number of submissions listed per log (defaults to 100), all of them are counted.
+	//
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// Response message
+//
+// swagger:response getPendingSubmissionsResponse
+type getPendingSubmissionsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetPendingSubmissionsResponse
+}
+
+// Request message
+//
+// swagger:parameters getTreeHeadSLARequest
+type getTreeHeadSLARequest struct { // nolint: unused,deadcode
+	// Alias of the log, the report covers all the logs if not set.
+	//
+	// in: query
+	Alias string `json:"alias"`
+	// Format of the report: json (default) or csv.
+	//
+	// in: query
+	Format string `json:"format"`
+}
+
+// Response message
+//
+// swagger:response getTreeHeadSLAResponse
+type getTreeHeadSLAResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.TreeHeadSLAReport
+}
+
+// Request message
+//
+// swagger:parameters getReconciliationRequest
+type getReconciliationRequest struct { // nolint: unused,deadcode
+	// Alias of the log, the report covers all the logs if not set.
+	//
+	// in: query
+	Alias string `json:"alias"`
+	// Reconciles the logs instead of returning the latest reconciliation.
+	//
+	// in: query
+	Refresh bool `json:"refresh"`
+}
+
+// Response message
+//
+// swagger:response getReconciliationResponse
+type getReconciliationResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.ReconciliationReport
+}
+
+// Request message
+//
+// swagger:parameters markCompromisedRequest
+type markCompromisedRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body compromiseStatement
+}
+
+// Response message
+//
+// swagger:response markCompromisedResponse
+type markCompromisedResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body compromiseStatement
+}
+
+type compromiseStatement struct { // nolint: unused,deadcode
+	Statement struct {
+		Version          uint8  `json:"version"`
+		SignatureType    uint64 `json:"signature_type"`
+		Timestamp        uint64 `json:"timestamp"`
+		LogID            string `json:"log_id"`
+		CompromisedSince uint64 `json:"compromised_since"`
+		Reason           string `json:"reason"`
+	} `json:"statement"`
+	Signature string `json:"signature"`
+}
+
+// Request message
+//
+// swagger:parameters freezeLogRequest
+type freezeLogRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body command.FreezeLogRequest
+}
+
+// Request message
+//
+// swagger:parameters getLogStatesRequest
+type getLogStatesRequest struct{} // nolint: unused,deadcode
+
+// Response message
+//
+// swagger:response getLogStatesResponse
+type getLogStatesResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetLogStatesResponse
+}
+
+// Request message
+//
+// swagger:parameters setLogStateRequest
+type setLogStateRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body command.SetLogStateRequest
+}
+
+// Response message
+//
+// swagger:response logStateResponse
+type logStateResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.LogState
+}
+
+// Request message
+//
+// swagger:parameters getExtraDataKeyRequest
+type getExtraDataKeyRequest struct{} // nolint: unused,deadcode
+
+// Request message
+//
+// swagger:parameters rotateExtraDataKeyRequest
+type rotateExtraDataKeyRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body command.RotateExtraDataKeyRequest
+}
+
+// Response message
+//
+// swagger:response extraDataKeyResponse
+type extraDataKeyResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.ExtraDataKeyStatus
+}
+
+// Request message
+//
+// swagger:parameters getUnmergedSubmissionsRequest
+type getUnmergedSubmissionsRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Response message
+//
+// swagger:response getUnmergedSubmissionsResponse
+type getUnmergedSubmissionsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetUnmergedSubmissionsResponse
+}
+
+// Request message
+//
+// swagger:parameters getFinalTreeHeadRequest
+type getFinalTreeHeadRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Response message
+//
+// swagger:response finalTreeHeadResponse
+type finalTreeHeadResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.SignedFinalTreeHead
+}
+
+// Request message
+//
+// swagger:parameters publishPolicyRequest
+type publishPolicyRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body command.PublishPolicyRequest
+}
+
+// Request message
+//
+// swagger:parameters simulatePolicyRequest
+type simulatePolicyRequest struct { // nolint: unused,deadcode
+	// in: body
+	Body command.SimulatePolicyRequest
+}
+
+// Response message
+//
+// swagger:response simulatePolicyResponse
+type simulatePolicyResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.SimulatePolicyResponse
+}
+
+// Request message
+//
+// swagger:parameters getPolicyRequest
+type getPolicyRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Request message
+//
+// swagger:parameters getPolicyHistoryRequest
+type getPolicyHistoryRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Request message
+//
+// swagger:parameters getPolicyVersionRequest
+type getPolicyVersionRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Version of the policy, starting at 1.
+	//
+	// in: path
+	// required: true
+	Version uint64 `json:"version"`
+}
+
+// Request message
+//
+// swagger:parameters getEntryByCIDRequest
+type getEntryByCIDRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// CID of the entry
+	//
+	// in: path
+	// required: true
+	CID string `json:"cid"`
+}
+
+// Response message
+//
+// swagger:response getEntryByCIDResponse
+type getEntryByCIDResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetEntryByCIDResponse
+}
+
+// Response message
+//
+// swagger:response policyResponse
+type policyResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.SignedPolicyDocument
+}
+
+// Response message
+//
+// swagger:response getPolicyHistoryResponse
+type getPolicyHistoryResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetPolicyHistoryResponse
+}
+
+// Request message
+//
+// swagger:parameters getRetiredShardsRequest
+type getRetiredShardsRequest struct { // nolint: unused,deadcode
+	// Tenant of the shards, the shards of all tenants are returned if not set.
+	//
+	// in: query
+	Tenant string `json:"tenant"`
+}
+
+// Response message
+//
+// swagger:response getRetiredShardsResponse
+type getRetiredShardsResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetRetiredShardsResponse
+}
+
+// Request message
+//
+// swagger:parameters getAuditExportRequest
+type getAuditExportRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// First
+	First int `json:"first"`
+
+	// Second
+	Second int `json:"second"`
+}
+
+// Response message
+//
+// swagger:response getAuditExportResponse
+type getAuditExportResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetAuditExportResponse
+}
+
+// Request message
+//
+// swagger:parameters getRandomEntriesRequest
+type getRandomEntriesRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Count
+	Count int `json:"count"`
+
+	// Seed
+	Seed string `json:"seed"`
+}
+
+// Response message
+//
+// swagger:response getRandomEntriesResponse
+type getRandomEntriesResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetRandomEntriesResponse
+}
+
+// Request message
+//
+// swagger:parameters getDiffRequest
+type getDiffRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// First
+	First int `json:"first"`
+
+	// Second
+	Second int `json:"second"`
+
+	// Entries
+	Entries bool `json:"entries"`
+}
+
+// Response message
+//
+// swagger:response getDiffResponse
+type getDiffResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.GetDiffResponse
+}
+
+// Request message
+//
+// swagger:parameters getTileRequest
+type getTileRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Level of the tile, the tile holds the hashes at the level Level*8 of the tree
+	//
+	// in: path
+	// required: true
+	Level int `json:"level"`
+
+	// Index of the tile (e.g. x001/x234/067), suffixed with .p/<width> for a partial tile
+	//
+	// in: path
+	// required: true
+	Index string `json:"index"`
+}
+
+// Request message
+//
+// swagger:parameters getEntryBundleRequest
+type getEntryBundleRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+
+	// Index of the tile (e.g. x001/x234/067), suffixed with .p/<width> for a partial tile
+	//
+	// in: path
+	// required: true
+	Index string `json:"index"`
+}
+
+// Request message
+//
+// swagger:parameters getVerificationSnapshotRequest
+type getVerificationSnapshotRequest struct { // nolint: unused,deadcode
+	// Alias
+	//
+	// in: path
+	// required: true
+	Alias string `json:"alias"`
+}
+
+// Response message
+//
+// swagger:response getVerificationSnapshotResponse
+type getVerificationSnapshotResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body command.SignedVerificationSnapshot
+}
+
+// Response message
+//
+// swagger:response getTileResponse
+type getTileResponse struct { // nolint: unused,deadcode
+	// in: body
+	Body []byte
+}
@@ -8,6 +8,7 @@ package rest
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,25 +36,84 @@ var (
 
 // API endpoints.
 const (
-	aliasVarName          = "alias"
-	AliasPath             = "/{" + aliasVarName + "}"
-	BasePath              = AliasPath + "/v1"
-	AddVCPath             = BasePath + "/add-vc"
-	GetSTHPath            = BasePath + "/get-sth"
-	GetSTHConsistencyPath = BasePath + "/get-sth-consistency"
-	GetProofByHashPath    = BasePath + "/get-proof-by-hash"
-	GetEntriesPath        = BasePath + "/get-entries"
-	GetIssuersPath        = BasePath + "/get-issuers"
-	GetEntryAndProofPath  = BasePath + "/get-entry-and-proof"
-	WebfingerPath         = AliasPath + "/.well-known/webfinger"
-	HealthCheckPath       = "/healthcheck"
-	MetricsPath           = "/metrics"
+	aliasVarName             = "alias"
+	hashVarName              = "hash"
+	keyVarName               = "key"
+	levelVarName             = "level"
+	indexVarName             = "index"
+	versionVarName           = "version"
+	cidVarName               = "cid"
+	AliasPath                = "/{" + aliasVarName + "}"
+	BasePath                 = AliasPath + "/v1"
+	V2BasePath               = AliasPath + "/v2"
+	AddVCPath                = BasePath + "/add-vc"
+	GetSTHPath               = BasePath + "/get-sth"
+	GetSTHConsistencyPath    = BasePath + "/get-sth-consistency"
+	GetProofByHashPath       = BasePath + "/get-proof-by-hash"
+	GetEntriesPath           = BasePath + "/get-entries"
+	GetIssuersPath           = BasePath + "/get-issuers"
+	GetEntryAndProofPath     = BasePath + "/get-entry-and-proof"
+	GetProofOfAbsencePath    = BasePath + "/get-proof-of-absence"
+	GetMapRootPath           = BasePath + "/get-map-root"
+	GetCredentialStatusPath  = BasePath + "/get-credential-status"
+	AddRevocationPath        = BasePath + "/add-revocation"
+	GetRevocationsPath       = BasePath + "/get-revocations"
+	GetCredentialHistoryPath = BasePath + "/get-credential-history/{" + hashVarName + "}"
+	AddAnchorPath            = BasePath + "/add-anchor"
+	AddEntryPath             = BasePath + "/add-entry"
+	GetAnchorsPath           = BasePath + "/get-anchors"
+	GetShadowStatusPath      = BasePath + "/get-shadow-status"
+	GetAuditExportPath       = BasePath + "/get-audit-export"
+	GetRandomEntriesPath     = BasePath + "/get-random-entries"
+	DiffPath                 = BasePath + "/diff"
+	GetReceiptPath           = BasePath + "/get-receipt/{" + keyVarName + "}"
+	AddAnnotationPath        = BasePath + "/add-annotation"
+	GetAnnotationsPath       = BasePath + "/get-annotations"
+	WebfingerPath            = AliasPath + "/.well-known/webfinger"
+	TilePath                 = AliasPath + "/tile/{" + levelVarName + ":[0-9]+}/{" + indexVarName + ":.+}"
+	EntryBundlePath          = AliasPath + "/tile/entries/{" + indexVarName + ":.+}"
+	VerificationSnapshotPath = AliasPath + "/verification-snapshot"
+	FinalTreeHeadPath        = AliasPath + "/final-sth"
+	UnmergedPath             = AliasPath + "/unmerged"
+	PolicyPath               = AliasPath + "/policy"
+	PolicyHistoryPath        = AliasPath + "/policy/history"
+	PolicyVersionPath        = AliasPath + "/policy/{" + versionVarName + ":[0-9]+}"
+	EntryByCIDPath           = AliasPath + "/entry/{" + cidVarName + "}"
+	HealthCheckPath          = "/healthcheck"
+	RetiredShardsPath        = "/retired-shards"
+	ReadOnlyPath             = "/admin/read-only"
+	KeyUsagePath             = "/admin/key-usage"
+	UsagePath                = "/admin/usage"
+	SubmissionStatsPath      = "/admin/submission-stats"
+	PendingSubmissionsPath   = "/admin/pending-submissions"
+	TreeHeadSLAPath          = "/admin/tree-head-sla"
+	ReconciliationPath       = "/admin/reconciliation"
+	CompromisePath           = "/admin/compromise"
+	FreezePath               = "/admin/freeze"
+	LogStatePath             = "/admin/log-state"
+	PublishPolicyPath        = "/admin/policy"
+	ExtraDataKeyPath         = "/admin/extra-data-key"
+	PolicySimulationPath     = "/admin/policy-simulation"
+	MetricsPath              = "/metrics"
 )
 
 const (
-	success         = "success"
-	contentType     = "Content-Type"
-	applicationJSON = "application/json"
+	success                = "success"
+	contentType            = "Content-Type"
+	applicationJSON        = "application/json"
+	applicationProblemJSON = "application/problem+json"
+	retryAfter             = "Retry-After"
+	applicationOctetStream = "application/octet-stream"
+	textCSV                = "text/csv"
+	cacheControl           = "Cache-Control"
+	contentDisposition     = "Content-Disposition"
+	// immutable is the cache control of the tiles and of the final tree heads, they never change.
+	immutable = "public, max-age=31536000, immutable"
+	// readOnlyRetryAfter is the delay (seconds) to retry writes rejected by the read-only mode (or by a log whose
+	// Trillian tree is not active) after.
+	readOnlyRetryAfter = "60"
+	// allowOrigin lets the verification widgets of any origin read the snapshots without credentials.
+	allowOrigin = "Access-Control-Allow-Origin"
 )
 
 type db interface {
@@ -73,23 +133,61 @@ type healthCheckResp struct {
 
 // nolint: gochecknoglobals
 var (
-	once                     sync.Once
-	addVCCounter             monitoring.Counter
-	addVCLatency             monitoring.Histogram
-	getSTHCounter            monitoring.Counter
-	getSTHLatency            monitoring.Histogram
-	getSTHConsistencyCounter monitoring.Counter
-	getSTHConsistencyLatency monitoring.Histogram
-	getProofByHashCounter    monitoring.Counter
-	getProofByHashLatency    monitoring.Histogram
-	getEntriesCounter        monitoring.Counter
-	getEntriesLatency        monitoring.Histogram
-	getEntryAndProofCounter  monitoring.Counter
-	getEntryAndProofLatency  monitoring.Histogram
-	getIssuersCounter        monitoring.Counter
-	getIssuersLatency        monitoring.Histogram
-	webfingerCounter         monitoring.Counter
-	webfingerLatency         monitoring.Histogram
+	once                        sync.Once
+	addVCCounter                monitoring.Counter
+	addVCLatency                monitoring.Histogram
+	getSTHCounter               monitoring.Counter
+	getSTHLatency               monitoring.Histogram
+	getSTHConsistencyCounter    monitoring.Counter
+	getSTHConsistencyLatency    monitoring.Histogram
+	getProofByHashCounter       monitoring.Counter
+	getProofByHashLatency       monitoring.Histogram
+	getEntriesCounter           monitoring.Counter
+	getEntriesLatency           monitoring.Histogram
+	getEntryAndProofCounter     monitoring.Counter
+	getEntryAndProofLatency     monitoring.Histogram
+	getProofOfAbsenceCounter    monitoring.Counter
+	getProofOfAbsenceLatency    monitoring.Histogram
+	getMapRootCounter           monitoring.Counter
+	getMapRootLatency           monitoring.Histogram
+	getCredentialStatusCounter  monitoring.Counter
+	getCredentialStatusLatency  monitoring.Histogram
+	addRevocationCounter        monitoring.Counter
+	addRevocationLatency        monitoring.Histogram
+	getRevocationsCounter       monitoring.Counter
+	getRevocationsLatency       monitoring.Histogram
+	getCredentialHistoryCounter monitoring.Counter
+	getCredentialHistoryLatency monitoring.Histogram
+	addAnchorCounter            monitoring.Counter
+	addAnchorLatency            monitoring.Histogram
+	addEntryCounter             monitoring.Counter
+	addEntryLatency             monitoring.Histogram
+	getAnchorsCounter           monitoring.Counter
+	getAnchorsLatency           monitoring.Histogram
+	getShadowStatusCounter      monitoring.Counter
+	getShadowStatusLatency      monitoring.Histogram
+	getAuditExportCounter       monitoring.Counter
+	getAuditExportLatency       monitoring.Histogram
+	getRandomEntriesCounter     monitoring.Counter
+	getRandomEntriesLatency     monitoring.Histogram
+	getDiffCounter              monitoring.Counter
+	getDiffLatency              monitoring.Histogram
+	getReceiptCounter           monitoring.Counter
+	getReceiptLatency           monitoring.Histogram
+	addAnnotationCounter        monitoring.Counter
+	addAnnotationLatency        monitoring.Histogram
+	getAnnotationsCounter       monitoring.Counter
+	getAnnotationsLatency       monitoring.Histogram
+	getTileCounter              monitoring.Counter
+	getTileLatency              monitoring.Histogram
+	getEntryBundleCounter       monitoring.Counter
+	getEntryBundleLatency       monitoring.Histogram
+	getIssuersCounter           monitoring.Counter
+	getIssuersLatency           monitoring.Histogram
+	webfingerCounter            monitoring.Counter
+	webfingerLatency            monitoring.Histogram
+	getSnapshotCounter          monitoring.Counter
+	getSnapshotLatency          monitoring.Histogram
 )
 
 // nolint: lll
@@ -112,11 +210,55 @@ func createMetrics(mf monitoring.MetricFactory) {
 	getEntryAndProofCounter = mf.NewCounter("get_entry_and_proof", "Number of /get-entry-and-proof operation", "alias")
 	getEntryAndProofLatency = mf.NewHistogram("get_entry_and_proof_latency", "Latency of /get-entry-and-proof operation in seconds", "alias")
 
+	getProofOfAbsenceCounter = mf.NewCounter("get_proof_of_absence", "Number of /get-proof-of-absence operation", "alias")
+	getProofOfAbsenceLatency = mf.NewHistogram("get_proof_of_absence_latency", "Latency of /get-proof-of-absence operation in seconds", "alias")
+
+	getMapRootCounter = mf.NewCounter("get_map_root", "Number of /get-map-root operation", "alias")
+	getMapRootLatency = mf.NewHistogram("get_map_root_latency", "Latency of /get-map-root operation in seconds", "alias")
+
+	getCredentialStatusCounter = mf.NewCounter("get_credential_status", "Number of /get-credential-status operation", "alias")
+	getCredentialStatusLatency = mf.NewHistogram("get_credential_status_latency", "Latency of /get-credential-status operation in seconds", "alias")
+
+	addRevocationCounter = mf.NewCounter("add_revocation", "Number of /add-revocation operation", "alias")
+	addRevocationLatency = mf.NewHistogram("add_revocation_latency", "Latency of /add-revocation operation in seconds", "alias")
+
+	getRevocationsCounter = mf.NewCounter("get_revocations", "Number of /get-revocations operation", "alias")
+	getRevocationsLatency = mf.NewHistogram("get_revocations_latency", "Latency of /get-revocations operation in seconds", "alias")
+	getCredentialHistoryCounter = mf.NewCounter("get_credential_history", "Number of /get-credential-history operation", "alias")
+	getCredentialHistoryLatency = mf.NewHistogram("get_credential_history_latency", "Latency of /get-credential-history operation in seconds", "alias")
+	addAnchorCounter = mf.NewCounter("add_anchor", "Number of /add-anchor operation", "alias")
+	addAnchorLatency = mf.NewHistogram("add_anchor_latency", "Latency of /add-anchor operation in seconds", "alias")
+	addEntryCounter = mf.NewCounter("add_entry", "Number of /add-entry operation", "alias")
+	addEntryLatency = mf.NewHistogram("add_entry_latency", "Latency of /add-entry operation in seconds", "alias")
+	getAnchorsCounter = mf.NewCounter("get_anchors", "Number of /get-anchors operation", "alias")
+	getAnchorsLatency = mf.NewHistogram("get_anchors_latency", "Latency of /get-anchors operation in seconds", "alias")
+	getShadowStatusCounter = mf.NewCounter("get_shadow_status", "Number of /get-shadow-status operation", "alias")
+	getShadowStatusLatency = mf.NewHistogram("get_shadow_status_latency", "Latency of /get-shadow-status operation in seconds", "alias")
+	getAuditExportCounter = mf.NewCounter("get_audit_export", "Number of /get-audit-export operation", "alias")
+	getAuditExportLatency = mf.NewHistogram("get_audit_export_latency", "Latency of /get-audit-export operation in seconds", "alias")
+	getRandomEntriesCounter = mf.NewCounter("get_random_entries", "Number of /get-random-entries operation", "alias")
+	getRandomEntriesLatency = mf.NewHistogram("get_random_entries_latency", "Latency of /get-random-entries operation in seconds", "alias")
+	getDiffCounter = mf.NewCounter("get_diff", "Number of /diff operation", "alias")
+	getDiffLatency = mf.NewHistogram("get_diff_latency", "Latency of /diff operation in seconds", "alias")
+	getReceiptCounter = mf.NewCounter("get_receipt", "Number of /get-receipt operation", "alias")
+	getReceiptLatency = mf.NewHistogram("get_receipt_latency", "Latency of /get-receipt operation in seconds", "alias")
+	addAnnotationCounter = mf.NewCounter("add_annotation", "Number of /add-annotation operation", "alias")
+	addAnnotationLatency = mf.NewHistogram("add_annotation_latency", "Latency of /add-annotation operation in seconds", "alias")
+	getAnnotationsCounter = mf.NewCounter("get_annotations", "Number of /get-annotations operation", "alias")
+	getAnnotationsLatency = mf.NewHistogram("get_annotations_latency", "Latency of /get-annotations operation in seconds", "alias")
+	getTileCounter = mf.NewCounter("get_tile", "Number of /tile operation", "alias")
+	getTileLatency = mf.NewHistogram("get_tile_latency", "Latency of /tile operation in seconds", "alias")
+	getEntryBundleCounter = mf.NewCounter("get_entry_bundle", "Number of /tile/entries operation", "alias")
+	getEntryBundleLatency = mf.NewHistogram("get_entry_bundle_latency", "Latency of /tile/entries operation in seconds", "alias")
+
 	getIssuersCounter = mf.NewCounter("get_issuers", "Number of /get-issuers operation", "alias")
 	getIssuersLatency = mf.NewHistogram("get_issuers_latency", "Latency of /get-issuers operation in seconds", "alias")
 
 	webfingerCounter = mf.NewCounter("webfinger", "Number of /webfinger operation", "alias")
 	webfingerLatency = mf.NewHistogram("webfinger_latency", "Latency of /webfinger operation in seconds", "alias")
+
+	getSnapshotCounter = mf.NewCounter("get_verification_snapshot", "Number of /verification-snapshot operation", "alias")
+	getSnapshotLatency = mf.NewHistogram("get_verification_snapshot_latency", "Latency of /verification-snapshot operation in seconds", "alias")
 }
 
 // Cmd defines command methods.
@@ -128,7 +270,48 @@ type Cmd interface {
 	GetProofByHash(io.Writer, io.Reader) error
 	GetEntries(io.Writer, io.Reader) error
 	GetEntryAndProof(io.Writer, io.Reader) error
+	GetProofOfAbsence(io.Writer, io.Reader) error
+	GetMapRoot(io.Writer, io.Reader) error
+	GetCredentialStatus(io.Writer, io.Reader) error
+	AddRevocation(io.Writer, io.Reader) error
+	GetRevocations(io.Writer, io.Reader) error
+	GetCredentialHistory(io.Writer, io.Reader) error
+	AddAnchor(io.Writer, io.Reader) error
+	AddEntry(io.Writer, io.Reader) error
+	GetAnchors(io.Writer, io.Reader) error
+	GetShadowStatus(io.Writer, io.Reader) error
+	GetAuditExport(io.Writer, io.Reader) error
+	GetRandomEntries(io.Writer, io.Reader) error
+	GetDiff(io.Writer, io.Reader) error
+	GetReceipt(io.Writer, io.Reader) error
+	AddAnnotation(io.Writer, io.Reader) error
+	GetAnnotations(io.Writer, io.Reader) error
+	GetTile(io.Writer, io.Reader) error
+	GetEntryBundle(io.Writer, io.Reader) error
+	GetReadOnly(io.Writer, io.Reader) error
+	SetReadOnly(io.Writer, io.Reader) error
+	GetKeyUsage(io.Writer, io.Reader) error
+	GetUsage(io.Writer, io.Reader) error
+	GetSubmissionStats(io.Writer, io.Reader) error
+	GetPendingSubmissions(io.Writer, io.Reader) error
+	GetTreeHeadSLA(io.Writer, io.Reader) error
+	GetReconciliation(io.Writer, io.Reader) error
+	MarkCompromised(io.Writer, io.Reader) error
+	FreezeLog(io.Writer, io.Reader) error
+	GetLogStates(io.Writer, io.Reader) error
+	SetLogState(io.Writer, io.Reader) error
+	GetFinalTreeHead(io.Writer, io.Reader) error
+	GetUnmergedSubmissions(io.Writer, io.Reader) error
+	GetRetiredShards(io.Writer, io.Reader) error
+	PublishPolicy(io.Writer, io.Reader) error
+	GetPolicy(io.Writer, io.Reader) error
+	GetPolicyHistory(io.Writer, io.Reader) error
+	GetEntryByCID(io.Writer, io.Reader) error
 	Webfinger(io.Writer, io.Reader) error
+	GetVerificationSnapshot(io.Writer, io.Reader) error
+	GetExtraDataKey(io.Writer, io.Reader) error
+	RotateExtraDataKey(io.Writer, io.Reader) error
+	SimulatePolicy(io.Writer, io.Reader) error
 }
 
 // Operation represents REST API controller.
@@ -152,7 +335,7 @@ func New(cmd Cmd, db db, keyManager keyManager, mf monitoring.MetricFactory) *Op
 
 // GetRESTHandlers returns list of all handlers supported by this controller.
 func (c *Operation) GetRESTHandlers() []Handler {
-	return []Handler{
+	return c.recordUsage(c.signResponses(c.versionHandlers(c.gateFeatures([]Handler{
 		NewHTTPHandler(AddVCPath, http.MethodPost, c.AddVC),
 		NewHTTPHandler(GetSTHPath, http.MethodGet, c.GetSTH),
 		NewHTTPHandler(GetSTHConsistencyPath, http.MethodGet, c.GetSTHConsistency),
@@ -161,10 +344,53 @@ func (c *Operation) GetRESTHandlers() []Handler {
 		NewHTTPHandler(GetIssuersPath, http.MethodGet, c.GetIssuers),
 		NewHTTPHandler(WebfingerPath, http.MethodGet, c.Webfinger),
 		NewHTTPHandler(GetEntryAndProofPath, http.MethodGet, c.GetEntryAndProof),
+		NewHTTPHandler(GetProofOfAbsencePath, http.MethodGet, c.GetProofOfAbsence),
+		NewHTTPHandler(GetMapRootPath, http.MethodGet, c.GetMapRoot),
+		NewHTTPHandler(GetCredentialStatusPath, http.MethodGet, c.GetCredentialStatus),
+		NewHTTPHandler(AddRevocationPath, http.MethodPost, c.AddRevocation),
+		NewHTTPHandler(GetRevocationsPath, http.MethodGet, c.GetRevocations),
+		NewHTTPHandler(GetCredentialHistoryPath, http.MethodGet, c.GetCredentialHistory),
+		NewHTTPHandler(AddAnchorPath, http.MethodPost, c.AddAnchor),
+		NewHTTPHandler(AddEntryPath, http.MethodPost, c.AddEntry),
+		NewHTTPHandler(GetAnchorsPath, http.MethodGet, c.GetAnchors),
+		NewHTTPHandler(GetShadowStatusPath, http.MethodGet, c.GetShadowStatus),
+		NewHTTPHandler(GetAuditExportPath, http.MethodGet, c.GetAuditExport),
+		NewHTTPHandler(GetRandomEntriesPath, http.MethodGet, c.GetRandomEntries),
+		NewHTTPHandler(DiffPath, http.MethodGet, c.GetDiff),
+		NewHTTPHandler(GetReceiptPath, http.MethodGet, c.GetReceipt),
+		NewHTTPHandler(AddAnnotationPath, http.MethodPost, c.AddAnnotation),
+		NewHTTPHandler(GetAnnotationsPath, http.MethodGet, c.GetAnnotations),
+		NewHTTPHandler(EntryBundlePath, http.MethodGet, c.GetEntryBundle),
+		NewHTTPHandler(TilePath, http.MethodGet, c.GetTile),
+		NewHTTPHandler(VerificationSnapshotPath, http.MethodGet, c.GetVerificationSnapshot),
+		NewHTTPHandler(FinalTreeHeadPath, http.MethodGet, c.GetFinalTreeHead),
+		NewHTTPHandler(UnmergedPath, http.MethodGet, c.GetUnmergedSubmissions),
+		NewHTTPHandler(RetiredShardsPath, http.MethodGet, c.GetRetiredShards),
+		NewHTTPHandler(PolicyPath, http.MethodGet, c.GetPolicy),
+		NewHTTPHandler(PolicyHistoryPath, http.MethodGet, c.GetPolicyHistory),
+		NewHTTPHandler(PolicyVersionPath, http.MethodGet, c.GetPolicyVersion),
+		NewHTTPHandler(EntryByCIDPath, http.MethodGet, c.GetEntryByCID),
 		NewHTTPHandler(HealthCheckPath, http.MethodGet, c.HealthCheck),
+		// Admin
+		NewHTTPHandler(ReadOnlyPath, http.MethodGet, c.GetReadOnly),
+		NewHTTPHandler(ReadOnlyPath, http.MethodPost, c.SetReadOnly),
+		NewHTTPHandler(KeyUsagePath, http.MethodGet, c.GetKeyUsage),
+		NewHTTPHandler(UsagePath, http.MethodGet, c.GetUsage),
+		NewHTTPHandler(SubmissionStatsPath, http.MethodGet, c.GetSubmissionStats),
+		NewHTTPHandler(PendingSubmissionsPath, http.MethodGet, c.GetPendingSubmissions),
+		NewHTTPHandler(TreeHeadSLAPath, http.MethodGet, c.GetTreeHeadSLA),
+		NewHTTPHandler(ReconciliationPath, http.MethodGet, c.GetReconciliation),
+		NewHTTPHandler(CompromisePath, http.MethodPost, c.MarkCompromised),
+		NewHTTPHandler(FreezePath, http.MethodPost, c.FreezeLog),
+		NewHTTPHandler(LogStatePath, http.MethodGet, c.GetLogStates),
+		NewHTTPHandler(LogStatePath, http.MethodPost, c.SetLogState),
+		NewHTTPHandler(PublishPolicyPath, http.MethodPost, c.PublishPolicy),
+		NewHTTPHandler(ExtraDataKeyPath, http.MethodGet, c.GetExtraDataKey),
+		NewHTTPHandler(ExtraDataKeyPath, http.MethodPost, c.RotateExtraDataKey),
+		NewHTTPHandler(PolicySimulationPath, http.MethodPost, c.SimulatePolicy),
 		// Metrics
 		NewHTTPHandler(MetricsPath, http.MethodGet, c.metrics()),
-	}
+	}))))
 }
 
 func (c *Operation) metrics() http.HandlerFunc {
@@ -246,7 +472,7 @@ func (c *Operation) GetSTH(w http.ResponseWriter, r *http.Request) {
 
 // GetIssuers swagger:route GET /{alias}/v1/get-issuers vct getIssuersRequest
 //
-// Returns issuers.
+// Returns issuers. A page of the issuers (getIssuersPageResponse) is returned if page_size or page_token is set.
 //
 // Responses:
 //    default: genericError
@@ -254,6 +480,31 @@ func (c *Operation) GetSTH(w http.ResponseWriter, r *http.Request) {
 func (c *Operation) GetIssuers(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
 
+	page, err := pageRequest(r)
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
+	tags := policyTagsFilter(r)
+
+	// all the issuers are returned to the requests without pagination nor filter, as a list
+	req := []byte(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName]))
+
+	if page != (command.PageRequest{}) || tags != (command.PolicyTags{}) {
+		req, err = json.Marshal(command.GetIssuersRequest{
+			Alias:       mux.Vars(r)[aliasVarName],
+			PageRequest: page,
+			PolicyTags:  tags,
+		})
+		if err != nil {
+			sendError(w, fmt.Errorf("marshal GetIssuers request: %w", err))
+
+			return
+		}
+	}
+
 	execute(func(rw io.Writer, req io.Reader) error {
 		if err := c.cmd.GetIssuers(rw, req); err != nil {
 			return err
@@ -263,7 +514,417 @@ func (c *Operation) GetIssuers(w http.ResponseWriter, r *http.Request) {
 		getIssuersLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
 
 		return nil
-	}, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])))
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetReadOnly swagger:route GET /admin/read-only vct getReadOnlyRequest
+//
+// Retrieves the read-only (maintenance) mode of the service.
+//
+// Responses:
+//    default: genericError
+//        200: readOnlyResponse
+func (c *Operation) GetReadOnly(w http.ResponseWriter, _ *http.Request) {
+	execute(c.cmd.GetReadOnly, w, nil)
+}
+
+// SetReadOnly swagger:route POST /admin/read-only vct setReadOnlyRequest
+//
+// Enables or disables the read-only (maintenance) mode of the service. Writes are rejected with
+// 503 Service Unavailable in the mode, proofs and entries are served.
+//
+// Responses:
+//    default: genericError
+//        200: readOnlyResponse
+func (c *Operation) SetReadOnly(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.SetReadOnly, w, r.Body)
+}
+
+// GetKeyUsage swagger:route GET /admin/key-usage vct getKeyUsageRequest
+//
+// Retrieves the counts of the signatures produced with the key of the log (SCTs, tree heads and other
+// statements), the time of the last signature and the windows the signing volume exceeded the threshold in.
+//
+// Responses:
+//    default: genericError
+//        200: getKeyUsageResponse
+func (c *Operation) GetKeyUsage(w http.ResponseWriter, _ *http.Request) {
+	execute(c.cmd.GetKeyUsage, w, nil)
+}
+
+// GetUsage swagger:route GET /admin/usage vct getUsageRequest
+//
+// Retrieves the usage of the logs per tenant since the start (logged entries, duplicate submissions, reads,
+// writes and errors), for billing and monitoring the tenants of the service.
+//
+// Responses:
+//    default: genericError
+//        200: getUsageResponse
+func (c *Operation) GetUsage(w http.ResponseWriter, r *http.Request) {
+	req, err := json.Marshal(command.GetUsageRequest{Tenant: r.FormValue("tenant")})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetUsage request: %w", err))
+
+		return
+	}
+
+	execute(c.cmd.GetUsage, w, bytes.NewBuffer(req))
+}
+
+// GetSubmissionStats swagger:route GET /admin/submission-stats vct getSubmissionStatsRequest
+//
+// Retrieves the stats of the credentials submitted per issuer (size, JSON-LD contexts and processing time
+// percentiles), the issuers ranked by a dimension to identify the issuers submitting pathological documents.
+//
+// Responses:
+//    default: genericError
+//        200: getSubmissionStatsResponse
+func (c *Operation) GetSubmissionStats(w http.ResponseWriter, r *http.Request) {
+	var limit int
+
+	if value := r.FormValue("limit"); value != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(value); err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, "limit"))
+
+			return
+		}
+	}
+
+	req, err := json.Marshal(command.GetSubmissionStatsRequest{
+		Alias:  r.FormValue("alias"),
+		Issuer: r.FormValue("issuer"),
+		SortBy: r.FormValue("sort_by"),
+		Limit:  limit,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetSubmissionStats request: %w", err))
+
+		return
+	}
+
+	execute(c.cmd.GetSubmissionStats, w, bytes.NewBuffer(req))
+}
+
+// GetPendingSubmissions swagger:route GET /admin/pending-submissions vct getPendingSubmissionsRequest
+//
+// Retrieves the submissions queued by the instance which are not sequenced yet (the merge backlog), oldest first,
+// with their ages and the counts per age.
+//
+// Responses:
+//    default: genericError
+//        200: getPendingSubmissionsResponse
+func (c *Operation) GetPendingSubmissions(w http.ResponseWriter, r *http.Request) {
+	var limit int
+
+	if value := r.FormValue("limit"); value != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(value); err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, "limit"))
+
+			return
+		}
+	}
+
+	req, err := json.Marshal(command.GetPendingSubmissionsRequest{
+		Alias: r.FormValue("alias"),
+		Limit: limit,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetPendingSubmissions request: %w", err))
+
+		return
+	}
+
+	execute(c.cmd.GetPendingSubmissions, w, bytes.NewBuffer(req))
+}
+
+// GetTreeHeadSLA swagger:route GET /admin/tree-head-sla vct getTreeHeadSLARequest
+//
+// Retrieves the tree head SLA report: how often the tree heads of the logs were published versus the schedule
+// over the rolling windows, as JSON or as a downloadable CSV file (format=csv).
+//
+// Responses:
+//    default: genericError
+//        200: getTreeHeadSLAResponse
+func (c *Operation) GetTreeHeadSLA(w http.ResponseWriter, r *http.Request) {
+	format := r.FormValue("format")
+
+	req, err := json.Marshal(command.GetTreeHeadSLARequest{Alias: r.FormValue("alias"), Format: format})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetTreeHeadSLA request: %w", err))
+
+		return
+	}
+
+	if format != command.TreeHeadSLAFormatCSV {
+		execute(c.cmd.GetTreeHeadSLA, w, bytes.NewBuffer(req))
+
+		return
+	}
+
+	var buf bytes.Buffer
+
+	if err = c.cmd.GetTreeHeadSLA(&buf, bytes.NewBuffer(req)); err != nil {
+		sendError(w, err)
+
+		return
+	}
+
+	w.Header().Set(contentType, textCSV)
+	w.Header().Set(contentDisposition, `attachment; filename="tree-head-sla.csv"`)
+
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		logger.Errorf("write tree head SLA report: %v", err)
+	}
+}
+
+// GetReconciliation swagger:route GET /admin/reconciliation vct getReconciliationRequest
+//
+// Retrieves the latest reconciliation of the counters of the logs (the submissions accepted, the entries of the
+// dedup store and the leaves indexed) with the leaves sequenced by Trillian, the drift flags lost writes.
+//
+// Responses:
+//    default: genericError
+//        200: getReconciliationResponse
+func (c *Operation) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	var refresh bool
+
+	if value := r.FormValue("refresh"); value != "" {
+		var err error
+
+		if refresh, err = strconv.ParseBool(value); err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a boolean", errors.ErrValidation, "refresh"))
+
+			return
+		}
+	}
+
+	req, err := json.Marshal(command.GetReconciliationRequest{Alias: r.FormValue("alias"), Refresh: refresh})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetReconciliation request: %w", err))
+
+		return
+	}
+
+	execute(c.cmd.GetReconciliation, w, bytes.NewBuffer(req))
+}
+
+// MarkCompromised swagger:route POST /admin/compromise vct markCompromisedRequest
+//
+// Marks the key of the log compromised with a compromise statement signed by the pre-registered recovery key.
+// Writes are frozen for good (410 Gone) and the statement is published in the webfinger metadata.
+//
+// Responses:
+//    default: genericError
+//        200: markCompromisedResponse
+func (c *Operation) MarkCompromised(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.MarkCompromised, w, r.Body)
+}
+
+// FreezeLog swagger:route POST /admin/freeze vct freezeLogRequest
+//
+// Freezes (retires) the log at its latest tree head. Writes of the log are rejected for good (410 Gone) and the
+// final tree head signed by the key of the log is served at /{alias}/final-sth.
+//
+// Responses:
+//    default: genericError
+//        200: finalTreeHeadResponse
+func (c *Operation) FreezeLog(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.FreezeLog, w, r.Body)
+}
+
+// GetLogStates swagger:route GET /admin/log-state vct getLogStatesRequest
+//
+// Retrieves the states of the logs (active, draining or frozen) read from their Trillian trees.
+//
+// Responses:
+//    default: genericError
+//        200: getLogStatesResponse
+func (c *Operation) GetLogStates(w http.ResponseWriter, _ *http.Request) {
+	execute(c.cmd.GetLogStates, w, nil)
+}
+
+// SetLogState swagger:route POST /admin/log-state vct setLogStateRequest
+//
+// Transitions the Trillian tree of the log to the state. Writes of a draining or frozen log are rejected with
+// 503 Service Unavailable until it is active again.
+//
+// Responses:
+//    default: genericError
+//        200: logStateResponse
+func (c *Operation) SetLogState(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.SetLogState, w, r.Body)
+}
+
+// GetFinalTreeHead swagger:route GET /{alias}/final-sth vct getFinalTreeHeadRequest
+//
+// Retrieves the final tree head of the frozen log, it never changes.
+//
+// Responses:
+//    default: genericError
+//        200: finalTreeHeadResponse
+func (c *Operation) GetFinalTreeHead(w http.ResponseWriter, r *http.Request) {
+	executeImmutable(c.cmd.GetFinalTreeHead, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])),
+		applicationJSON)
+}
+
+// GetUnmergedSubmissions swagger:route GET /{alias}/unmerged vct getUnmergedSubmissionsRequest
+//
+// Retrieves the submissions to the log queued by the instance which are not sequenced within the submission TTL,
+// oldest first: the log did not honor the SCTs issued for them (yet).
+//
+// Responses:
+//    default: genericError
+//        200: getUnmergedSubmissionsResponse
+func (c *Operation) GetUnmergedSubmissions(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.GetUnmergedSubmissions, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])))
+}
+
+// GetExtraDataKey swagger:route GET /admin/extra-data-key vct getExtraDataKeyRequest
+//
+// Retrieves the envelope key encrypting the extra data of leaves at rest and the progress of the re-encryption of
+// the extra data of the logs with it. The leaves of the logs which can't rewrite their extra data (Trillian) are
+// never re-encrypted.
+//
+// Responses:
+//    default: genericError
+//        200: extraDataKeyResponse
+func (c *Operation) GetExtraDataKey(w http.ResponseWriter, _ *http.Request) {
+	execute(c.cmd.GetExtraDataKey, w, nil)
+}
+
+// RotateExtraDataKey swagger:route POST /admin/extra-data-key vct rotateExtraDataKeyRequest
+//
+// Rotates the envelope key encrypting the extra data of leaves at rest, a new key is created unless the request
+// sets one. The extra data encrypted with the previous keys is re-encrypted in the background if enabled.
+//
+// Responses:
+//    default: genericError
+//        200: extraDataKeyResponse
+func (c *Operation) RotateExtraDataKey(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.RotateExtraDataKey, w, r.Body)
+}
+
+// PublishPolicy swagger:route POST /admin/policy vct publishPolicyRequest
+//
+// Publishes the policy of the log as its next version, signed by the key of the log and chained to the previous
+// version. The latest version is returned unchanged if the policy is the same.
+//
+// Responses:
+//    default: genericError
+//        200: policyResponse
+func (c *Operation) PublishPolicy(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.PublishPolicy, w, r.Body)
+}
+
+// SimulatePolicy swagger:route POST /admin/policy-simulation vct simulatePolicyRequest
+//
+// Replays the latest credentials logged against a proposed policy (accepted issuers and types, max entry size) and
+// reports the ones the policy would have rejected, nothing is enforced.
+//
+// Responses:
+//    default: genericError
+//        200: simulatePolicyResponse
+func (c *Operation) SimulatePolicy(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.SimulatePolicy, w, r.Body)
+}
+
+// GetPolicy swagger:route GET /{alias}/policy vct getPolicyRequest
+//
+// Retrieves the latest signed policy document of the log.
+//
+// Responses:
+//    default: genericError
+//        200: policyResponse
+func (c *Operation) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	req, err := json.Marshal(command.GetPolicyRequest{Alias: mux.Vars(r)[aliasVarName]})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetPolicy request: %w", err))
+
+		return
+	}
+
+	execute(c.cmd.GetPolicy, w, bytes.NewBuffer(req))
+}
+
+// GetEntryByCID swagger:route GET /{alias}/entry/{cid} vct getEntryByCIDRequest
+//
+// Retrieves the first logged entry addressed by the CID (a CIDv1 of the raw entry with the SHA2-256 multihash),
+// it never changes.
+//
+// Responses:
+//    default: genericError
+//        200: getEntryByCIDResponse
+func (c *Operation) GetEntryByCID(w http.ResponseWriter, r *http.Request) {
+	req, err := json.Marshal(command.GetEntryByCIDRequest{
+		Alias: mux.Vars(r)[aliasVarName],
+		CID:   mux.Vars(r)[cidVarName],
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetEntryByCID request: %w", err))
+
+		return
+	}
+
+	executeImmutable(c.cmd.GetEntryByCID, w, bytes.NewBuffer(req), applicationJSON)
+}
+
+// GetPolicyVersion swagger:route GET /{alias}/policy/{version} vct getPolicyVersionRequest
+//
+// Retrieves a version of the signed policy document of the log, it never changes.
+//
+// Responses:
+//    default: genericError
+//        200: policyResponse
+func (c *Operation) GetPolicyVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.ParseUint(mux.Vars(r)[versionVarName], 10, 64)
+	if err != nil || version == 0 {
+		sendError(w, fmt.Errorf("%w: version must be a positive number", errors.ErrValidation))
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetPolicyRequest{Alias: mux.Vars(r)[aliasVarName], Version: version})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetPolicy request: %w", err))
+
+		return
+	}
+
+	executeImmutable(c.cmd.GetPolicy, w, bytes.NewBuffer(req), applicationJSON)
+}
+
+// GetPolicyHistory swagger:route GET /{alias}/policy/history vct getPolicyHistoryRequest
+//
+// Retrieves all the published versions of the signed policy document of the log, oldest first.
+//
+// Responses:
+//    default: genericError
+//        200: getPolicyHistoryResponse
+func (c *Operation) GetPolicyHistory(w http.ResponseWriter, r *http.Request) {
+	execute(c.cmd.GetPolicyHistory, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])))
+}
+
+// GetRetiredShards swagger:route GET /retired-shards vct getRetiredShardsRequest
+//
+// Retrieves the archive of the retired shards: the keys and the final tree heads of the frozen logs, including the
+// logs no longer served, to verify their SCTs and proofs.
+//
+// Responses:
+//    default: genericError
+//        200: getRetiredShardsResponse
+func (c *Operation) GetRetiredShards(w http.ResponseWriter, r *http.Request) {
+	req, err := json.Marshal(command.GetRetiredShardsRequest{Tenant: r.FormValue("tenant")})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetRetiredShards request: %w", err))
+
+		return
+	}
+
+	execute(c.cmd.GetRetiredShards, w, bytes.NewBuffer(req))
 }
 
 // HealthCheck swagger:route GET /healthcheck vct healthCheckRequest
@@ -361,10 +1022,18 @@ func (c *Operation) GetSTHConsistency(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	debug, err := debugParam(r)
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
 	req, err := json.Marshal(command.GetSTHConsistencyRequest{
 		Alias:          mux.Vars(r)[aliasVarName],
 		FirstTreeSize:  first,
 		SecondTreeSize: second,
+		Debug:          debug,
 	})
 	if err != nil {
 		sendError(w, fmt.Errorf("marshal GetSTHConsistency request: %w", err))
@@ -406,10 +1075,18 @@ func (c *Operation) GetProofByHash(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	debug, err := debugParam(r)
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
 	req, err := json.Marshal(command.GetProofByHashRequest{
 		Alias:    mux.Vars(r)[aliasVarName],
 		Hash:     r.FormValue(hashParamName),
 		TreeSize: treeSize,
+		Debug:    debug,
 	})
 	if err != nil {
 		sendError(w, fmt.Errorf("marshal GetProofByHash request: %w", err))
@@ -431,38 +1108,60 @@ func (c *Operation) GetProofByHash(w http.ResponseWriter, r *http.Request) {
 
 // GetEntries swagger:route GET /{alias}/v1/get-entries vct getEntriesRequest
 //
-// Retrieves entries from log.
+// Retrieves entries from log by the range of indexes or by the time range (from_time, to_time).
 //
 // Responses:
 //    default: genericError
 //        200: getEntriesResponse
 func (c *Operation) GetEntries(w http.ResponseWriter, r *http.Request) {
 	const (
-		startParamName = "start"
-		endParamName   = "end"
+		startParamName    = "start"
+		endParamName      = "end"
+		fromTimeParamName = "from_time"
+		toTimeParamName   = "to_time"
 	)
 
 	startTime := time.Now()
 
-	start, err := strconv.ParseInt(r.FormValue(startParamName), 10, 64)
-	if err != nil {
-		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, startParamName))
+	request := command.GetEntriesRequest{Alias: mux.Vars(r)[aliasVarName], PolicyTags: policyTagsFilter(r)}
 
-		return
-	}
+	for name, dst := range map[string]**time.Time{
+		fromTimeParamName: &request.FromTime,
+		toTimeParamName:   &request.ToTime,
+	} {
+		if r.FormValue(name) == "" {
+			continue
+		}
 
-	end, err := strconv.ParseInt(r.FormValue(endParamName), 10, 64)
-	if err != nil {
-		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, endParamName))
+		t, err := time.Parse(time.RFC3339, r.FormValue(name))
+		if err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a RFC3339 time", errors.ErrValidation, name))
 
-		return
+			return
+		}
+
+		*dst = &t
 	}
 
-	req, err := json.Marshal(command.GetEntriesRequest{
-		Alias: mux.Vars(r)[aliasVarName],
-		Start: start,
-		End:   end,
-	})
+	if request.FromTime == nil && request.ToTime == nil {
+		var err error
+
+		request.Start, err = strconv.ParseInt(r.FormValue(startParamName), 10, 64)
+		if err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, startParamName))
+
+			return
+		}
+
+		request.End, err = strconv.ParseInt(r.FormValue(endParamName), 10, 64)
+		if err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, endParamName))
+
+			return
+		}
+	}
+
+	req, err := json.Marshal(request)
 	if err != nil {
 		sendError(w, fmt.Errorf("marshal GetEntries request: %w", err))
 
@@ -510,10 +1209,18 @@ func (c *Operation) GetEntryAndProof(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	debug, err := debugParam(r)
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
 	req, err := json.Marshal(command.GetEntryAndProofRequest{
 		Alias:     mux.Vars(r)[aliasVarName],
 		LeafIndex: leafIndex,
 		TreeSize:  treeSize,
+		Debug:     debug,
 	})
 	if err != nil {
 		sendError(w, fmt.Errorf("marshal GetEntryAndProof request: %w", err))
@@ -533,23 +1240,939 @@ func (c *Operation) GetEntryAndProof(w http.ResponseWriter, r *http.Request) {
 	}, w, bytes.NewBuffer(req))
 }
 
-func execute(exec command.Exec, rw http.ResponseWriter, req io.Reader) {
-	rw.Header().Set(contentType, applicationJSON)
+// GetProofOfAbsence swagger:route GET /{alias}/v1/get-proof-of-absence vct getProofOfAbsenceRequest
+//
+// Retrieves signed statement that no entry with the credential ID of the issuer was logged as of the tree size.
+//
+// Responses:
+//    default: genericError
+//        200: getProofOfAbsenceResponse
+func (c *Operation) GetProofOfAbsence(w http.ResponseWriter, r *http.Request) {
+	const (
+		issuerParamName       = "issuer"
+		credentialIDParamName = "credential_id"
+	)
 
-	if err := exec(rw, req); err != nil {
-		sendError(rw, err)
+	start := time.Now()
+
+	req, err := json.Marshal(command.GetProofOfAbsenceRequest{
+		Alias:        mux.Vars(r)[aliasVarName],
+		Issuer:       r.FormValue(issuerParamName),
+		CredentialID: r.FormValue(credentialIDParamName),
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetProofOfAbsence request: %w", err))
+
+		return
 	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetProofOfAbsence(rw, req); err != nil {
+			return err
+		}
+
+		getProofOfAbsenceCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getProofOfAbsenceLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
 }
 
-// ErrorResponse represents REST error message.
-type ErrorResponse struct {
-	Message string `json:"message"`
+// GetMapRoot swagger:route GET /{alias}/v1/get-map-root vct getMapRootRequest
+//
+// Retrieves the signed root of the map of credential IDs to their latest log entries.
+//
+// Responses:
+//    default: genericError
+//        200: getMapRootResponse
+func (c *Operation) GetMapRoot(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetMapRoot(rw, req); err != nil {
+			return err
+		}
+
+		getMapRootCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getMapRootLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])))
 }
 
-func sendError(rw http.ResponseWriter, e error) {
-	rw.WriteHeader(errors.StatusCodeFromError(e))
+// GetShadowStatus swagger:route GET /{alias}/v1/get-shadow-status vct getShadowStatusRequest
+//
+// Retrieves the outcome of mirroring writes of the log to its shadow log.
+//
+// Responses:
+//    default: genericError
+//        200: getShadowStatusResponse
+func (c *Operation) GetShadowStatus(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetShadowStatus(rw, req); err != nil {
+			return err
+		}
+
+		getShadowStatusCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getShadowStatusLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])))
+}
+
+// GetReceipt swagger:route GET /{alias}/v1/get-receipt/{key} vct getReceiptRequest
+//
+// Retrieves the SCT issued for the credential submitted with the idempotency key, issuers recover lost SCTs
+// without resubmitting.
+//
+// Responses:
+//    default: genericError
+//        200: addVCResponse
+func (c *Operation) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	req, err := json.Marshal(command.GetReceiptRequest{
+		Alias:          mux.Vars(r)[aliasVarName],
+		IdempotencyKey: mux.Vars(r)[keyVarName],
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetReceipt request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetReceipt(rw, req); err != nil {
+			return err
+		}
+
+		getReceiptCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getReceiptLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// AddAnnotation swagger:route POST /{alias}/v1/add-annotation vct addAnnotationRequest
+//
+// Attaches the signed annotation of an auditor to an entry of the log, the annotation is not logged in the tree.
+//
+// Responses:
+//    default: genericError
+//        200: addAnnotationResponse
+func (c *Operation) AddAnnotation(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var annotation command.SignedAnnotation
+
+	if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+		sendError(w, fmt.Errorf("%w: decode annotation: %v", errors.ErrBadRequest, err))
+
+		return
+	}
+
+	req, err := json.Marshal(command.AddAnnotationRequest{
+		Alias:      mux.Vars(r)[aliasVarName],
+		Annotation: annotation,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: marshal AddAnnotationRequest", errors.ErrInternal))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.AddAnnotation(rw, req); err != nil {
+			return err
+		}
+
+		addAnnotationCounter.Add(1, mux.Vars(r)[aliasVarName])
+		addAnnotationLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetAnnotations swagger:route GET /{alias}/v1/get-annotations vct getAnnotationsRequest
+//
+// Retrieves the annotations of an entry of the log.
+//
+// Responses:
+//    default: genericError
+//        200: getAnnotationsResponse
+func (c *Operation) GetAnnotations(w http.ResponseWriter, r *http.Request) {
+	const leafIndexParamName = "leaf_index"
+
+	start := time.Now()
+
+	leafIndex, err := strconv.ParseInt(r.FormValue(leafIndexParamName), 10, 64)
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, leafIndexParamName))
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetAnnotationsRequest{
+		Alias:     mux.Vars(r)[aliasVarName],
+		LeafIndex: leafIndex,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetAnnotations request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetAnnotations(rw, req); err != nil {
+			return err
+		}
+
+		getAnnotationsCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getAnnotationsLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetTile swagger:route GET /{alias}/tile/{level}/{index} vct getTileRequest
+//
+// Retrieves the hashes of a tile of the tree (the tlog-tiles layout), a static resource cacheable forever.
+//
+// Responses:
+//    default: genericError
+//        200: getTileResponse
+func (c *Operation) GetTile(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	level, err := strconv.Atoi(mux.Vars(r)[levelVarName])
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: tile level is not a number", errors.ErrValidation))
+
+		return
+	}
+
+	index, width, err := ParseTileIndex(mux.Vars(r)[indexVarName])
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetTileRequest{
+		Alias: mux.Vars(r)[aliasVarName],
+		Level: level,
+		Index: index,
+		Width: width,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetTile request: %w", err))
+
+		return
+	}
+
+	executeTile(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetTile(rw, req); err != nil {
+			return err
+		}
+
+		getTileCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getTileLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetVerificationSnapshot swagger:route GET /{alias}/verification-snapshot vct getVerificationSnapshotRequest
+//
+// Retrieves the signed verification snapshot of the log for the verification widgets, it is cached until its
+// next update and readable from any origin.
+//
+// Responses:
+//    default: genericError
+//        200: getVerificationSnapshotResponse
+func (c *Operation) GetVerificationSnapshot(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	executeSnapshot(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetVerificationSnapshot(rw, req); err != nil {
+			return err
+		}
+
+		getSnapshotCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getSnapshotLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBufferString(fmt.Sprintf("%q", mux.Vars(r)[aliasVarName])))
+}
+
+// GetEntryBundle swagger:route GET /{alias}/tile/entries/{index} vct getEntryBundleRequest
+//
+// Retrieves the entries of a tile of the level 0, a static resource cacheable forever.
+//
+// Responses:
+//    default: genericError
+//        200: getTileResponse
+func (c *Operation) GetEntryBundle(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	index, width, err := ParseTileIndex(mux.Vars(r)[indexVarName])
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetEntryBundleRequest{
+		Alias: mux.Vars(r)[aliasVarName],
+		Index: index,
+		Width: width,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetEntryBundle request: %w", err))
+
+		return
+	}
+
+	executeTile(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetEntryBundle(rw, req); err != nil {
+			return err
+		}
+
+		getEntryBundleCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getEntryBundleLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetAuditExport swagger:route GET /{alias}/v1/get-audit-export vct getAuditExportRequest
+//
+// Retrieves the signed audit export of the entries added while the tree grew from the first to the second size.
+//
+// Responses:
+//    default: genericError
+//        200: getAuditExportResponse
+func (c *Operation) GetAuditExport(w http.ResponseWriter, r *http.Request) {
+	const (
+		firstParamName  = "first"
+		secondParamName = "second"
+	)
+
+	start := time.Now()
+
+	first, err := strconv.ParseInt(r.FormValue(firstParamName), 10, 64)
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, firstParamName))
+
+		return
+	}
+
+	second, err := strconv.ParseInt(r.FormValue(secondParamName), 10, 64)
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, secondParamName))
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetAuditExportRequest{
+		Alias:          mux.Vars(r)[aliasVarName],
+		FirstTreeSize:  first,
+		SecondTreeSize: second,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetAuditExport request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetAuditExport(rw, req); err != nil {
+			return err
+		}
+
+		getAuditExportCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getAuditExportLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetRandomEntries swagger:route GET /{alias}/v1/get-random-entries vct getRandomEntriesRequest
+//
+// Retrieves a pseudo-random sample of the entries of the latest tree head, derived from the seed and the root hash,
+// with their audit paths.
+//
+// Responses:
+//    default: genericError
+//        200: getRandomEntriesResponse
+func (c *Operation) GetRandomEntries(w http.ResponseWriter, r *http.Request) {
+	const (
+		countParamName = "count"
+		seedParamName  = "seed"
+	)
+
+	start := time.Now()
+
+	count, err := strconv.Atoi(r.FormValue(countParamName))
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, countParamName))
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetRandomEntriesRequest{
+		Alias: mux.Vars(r)[aliasVarName],
+		Count: count,
+		Seed:  r.FormValue(seedParamName),
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetRandomEntries request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetRandomEntries(rw, req); err != nil {
+			return err
+		}
+
+		getRandomEntriesCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getRandomEntriesLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetDiff swagger:route GET /{alias}/v1/diff vct getDiffRequest
+//
+// Summarizes the entries added while the tree grew from the first to the second size (counts by leaf type, issuer
+// and credential type) and lists them if requested.
+//
+// Responses:
+//    default: genericError
+//        200: getDiffResponse
+func (c *Operation) GetDiff(w http.ResponseWriter, r *http.Request) {
+	const (
+		firstParamName   = "first"
+		secondParamName  = "second"
+		entriesParamName = "entries"
+	)
+
+	start := time.Now()
+
+	first, err := strconv.ParseInt(r.FormValue(firstParamName), 10, 64)
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, firstParamName))
+
+		return
+	}
+
+	second, err := strconv.ParseInt(r.FormValue(secondParamName), 10, 64)
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, secondParamName))
+
+		return
+	}
+
+	var entries bool
+
+	if value := r.FormValue(entriesParamName); value != "" {
+		if entries, err = strconv.ParseBool(value); err != nil {
+			sendError(w, fmt.Errorf("%w: parameter %q is not a boolean", errors.ErrValidation, entriesParamName))
+
+			return
+		}
+	}
+
+	req, err := json.Marshal(command.GetDiffRequest{
+		Alias:          mux.Vars(r)[aliasVarName],
+		FirstTreeSize:  first,
+		SecondTreeSize: second,
+		Entries:        entries,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetDiff request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetDiff(rw, req); err != nil {
+			return err
+		}
+
+		getDiffCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getDiffLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetCredentialStatus swagger:route GET /{alias}/v1/get-credential-status vct getCredentialStatusRequest
+//
+// Retrieves the latest log entry of the credential of the issuer and its inclusion proof in the signed map.
+//
+// Responses:
+//    default: genericError
+//        200: getCredentialStatusResponse
+func (c *Operation) GetCredentialStatus(w http.ResponseWriter, r *http.Request) {
+	const (
+		issuerParamName       = "issuer"
+		credentialIDParamName = "credential_id"
+	)
+
+	start := time.Now()
+
+	req, err := json.Marshal(command.GetCredentialStatusRequest{
+		Alias:        mux.Vars(r)[aliasVarName],
+		Issuer:       r.FormValue(issuerParamName),
+		CredentialID: r.FormValue(credentialIDParamName),
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetCredentialStatus request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetCredentialStatus(rw, req); err != nil {
+			return err
+		}
+
+		getCredentialStatusCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getCredentialStatusLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// AddRevocation swagger:route POST /{alias}/v1/add-revocation vct addRevocationRequest
+//
+// Adds revocation event of a logged credential to log, signed by the issuer of the credential.
+//
+// Responses:
+//    default: genericError
+//        200: addVCResponse
+func (c *Operation) AddRevocation(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var event command.RevocationEvent
+
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		sendError(w, fmt.Errorf("%w: decode revocation event: %v", errors.ErrBadRequest, err))
+
+		return
+	}
+
+	req, err := json.Marshal(command.AddRevocationRequest{
+		Alias: mux.Vars(r)[aliasVarName],
+		Event: event,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: marshal AddRevocationRequest", errors.ErrInternal))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.AddRevocation(rw, req); err != nil {
+			return err
+		}
+
+		addRevocationCounter.Add(1, mux.Vars(r)[aliasVarName])
+		addRevocationLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetRevocations swagger:route GET /{alias}/v1/get-revocations vct getRevocationsRequest
+//
+// Retrieves revocation events of the credential.
+//
+// Responses:
+//    default: genericError
+//        200: getRevocationsResponse
+func (c *Operation) GetRevocations(w http.ResponseWriter, r *http.Request) {
+	const leafHashParamName = "leaf_hash"
+
+	start := time.Now()
+
+	leafHash, err := base64.StdEncoding.DecodeString(r.FormValue(leafHashParamName))
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not base64", errors.ErrValidation, leafHashParamName))
+
+		return
+	}
+
+	page, err := pageRequest(r)
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetRevocationsRequest{
+		Alias:       mux.Vars(r)[aliasVarName],
+		LeafHash:    leafHash,
+		PageRequest: page,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetRevocations request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetRevocations(rw, req); err != nil {
+			return err
+		}
+
+		getRevocationsCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getRevocationsLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetCredentialHistory swagger:route GET /{alias}/v1/get-credential-history/{hash} vct getCredentialHistoryRequest
+//
+// Retrieves the chain of re-issued credentials the credential belongs to, oldest first.
+//
+// Responses:
+//    default: genericError
+//        200: getCredentialHistoryResponse
+func (c *Operation) GetCredentialHistory(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	// the hash is URL-safe base64, standard base64 is accepted as long as it survives the path
+	leafHash, err := base64.URLEncoding.DecodeString(mux.Vars(r)[hashVarName])
+	if err != nil {
+		leafHash, err = base64.StdEncoding.DecodeString(mux.Vars(r)[hashVarName])
+	}
+
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: hash is not base64", errors.ErrValidation))
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetCredentialHistoryRequest{
+		Alias:    mux.Vars(r)[aliasVarName],
+		LeafHash: leafHash,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetCredentialHistory request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetCredentialHistory(rw, req); err != nil {
+			return err
+		}
+
+		getCredentialHistoryCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getCredentialHistoryLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// AddAnchor swagger:route POST /{alias}/v1/add-anchor vct addAnchorRequest
+//
+// Adds a signed tree head of another transparency log to log.
+//
+// Responses:
+//    default: genericError
+//        200: addVCResponse
+func (c *Operation) AddAnchor(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var anchor command.STHAnchor
+
+	if err := json.NewDecoder(r.Body).Decode(&anchor); err != nil {
+		sendError(w, fmt.Errorf("%w: decode anchor: %v", errors.ErrBadRequest, err))
+
+		return
+	}
+
+	req, err := json.Marshal(command.AddAnchorRequest{
+		Alias:  mux.Vars(r)[aliasVarName],
+		Anchor: anchor,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: marshal AddAnchorRequest", errors.ErrInternal))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.AddAnchor(rw, req); err != nil {
+			return err
+		}
+
+		addAnchorCounter.Add(1, mux.Vars(r)[aliasVarName])
+		addAnchorLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// AddEntry swagger:route POST /{alias}/v1/add-entry vct addEntryRequest
+//
+// Adds an entry of a supported leaf type to log.
+//
+// Responses:
+//    default: genericError
+//        200: addVCResponse
+func (c *Operation) AddEntry(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	var request command.AddEntryRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		sendError(w, fmt.Errorf("%w: decode entry: %v", errors.ErrBadRequest, err))
+
+		return
+	}
+
+	request.Alias = mux.Vars(r)[aliasVarName]
+
+	req, err := json.Marshal(request)
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: marshal AddEntryRequest", errors.ErrInternal))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.AddEntry(rw, req); err != nil {
+			return err
+		}
+
+		addEntryCounter.Add(1, mux.Vars(r)[aliasVarName])
+		addEntryLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+// GetAnchors swagger:route GET /{alias}/v1/get-anchors vct getAnchorsRequest
+//
+// Retrieves anchored tree heads of another transparency log.
+//
+// Responses:
+//    default: genericError
+//        200: getAnchorsResponse
+func (c *Operation) GetAnchors(w http.ResponseWriter, r *http.Request) {
+	const logIDParamName = "log_id"
+
+	start := time.Now()
+
+	logID, err := base64.StdEncoding.DecodeString(r.FormValue(logIDParamName))
+	if err != nil {
+		sendError(w, fmt.Errorf("%w: parameter %q is not base64", errors.ErrValidation, logIDParamName))
+
+		return
+	}
+
+	page, err := pageRequest(r)
+	if err != nil {
+		sendError(w, err)
+
+		return
+	}
+
+	req, err := json.Marshal(command.GetAnchorsRequest{
+		Alias:       mux.Vars(r)[aliasVarName],
+		LogID:       logID,
+		PageRequest: page,
+	})
+	if err != nil {
+		sendError(w, fmt.Errorf("marshal GetAnchors request: %w", err))
+
+		return
+	}
+
+	execute(func(rw io.Writer, req io.Reader) error {
+		if err := c.cmd.GetAnchors(rw, req); err != nil {
+			return err
+		}
+
+		getAnchorsCounter.Add(1, mux.Vars(r)[aliasVarName])
+		getAnchorsLatency.Observe(time.Since(start).Seconds(), mux.Vars(r)[aliasVarName])
+
+		return nil
+	}, w, bytes.NewBuffer(req))
+}
+
+func execute(exec command.Exec, rw http.ResponseWriter, req io.Reader) {
+	rw.Header().Set(contentType, applicationJSON)
+
+	if err := exec(rw, req); err != nil {
+		sendError(rw, err)
+	}
+}
+
+// executeTile serves the binary output of the command as an immutable resource, errors are not cached.
+func executeTile(exec command.Exec, rw http.ResponseWriter, req io.Reader) {
+	executeImmutable(exec, rw, req, applicationOctetStream)
+}
+
+// executeImmutable serves the output of the command as an immutable resource of the media type, errors are not
+// cached.
+func executeImmutable(exec command.Exec, rw http.ResponseWriter, req io.Reader, mediaType string) {
+	var buf bytes.Buffer
+
+	if err := exec(&buf, req); err != nil {
+		sendError(rw, err)
+
+		return
+	}
+
+	rw.Header().Set(contentType, mediaType)
+	rw.Header().Set(cacheControl, immutable)
+
+	if _, err := rw.Write(buf.Bytes()); err != nil {
+		logger.Errorf("write immutable response: %v", err)
+	}
+}
+
+// executeSnapshot serves the verification snapshot, it may be cached by any cache until its next update.
+func executeSnapshot(exec command.Exec, rw http.ResponseWriter, req io.Reader) {
+	var buf bytes.Buffer
+
+	if err := exec(&buf, req); err != nil {
+		sendError(rw, err)
+
+		return
+	}
+
+	var snapshot command.SignedVerificationSnapshot
+
+	if err := json.Unmarshal(buf.Bytes(), &snapshot); err != nil {
+		sendError(rw, fmt.Errorf("%w: unmarshal verification snapshot: %v", errors.ErrInternal, err))
+
+		return
+	}
+
+	maxAge := int64(snapshot.Snapshot.NextUpdate) - time.Now().UnixNano()/int64(time.Millisecond)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	rw.Header().Set(contentType, applicationJSON)
+	rw.Header().Set(cacheControl, fmt.Sprintf("public, max-age=%d", maxAge/int64(time.Second/time.Millisecond)))
+	rw.Header().Set(allowOrigin, "*")
+
+	if _, err := rw.Write(buf.Bytes()); err != nil {
+		logger.Errorf("write verification snapshot response: %v", err)
+	}
+}
+
+// pageRequest returns the pagination of a list request from the page_size and page_token parameters.
+// policyTagsFilter returns the policy tags the entries of the request are filtered by.
+func policyTagsFilter(r *http.Request) command.PolicyTags {
+	const (
+		jurisdictionParamName   = "jurisdiction"
+		assuranceLevelParamName = "assurance_level"
+	)
+
+	return command.PolicyTags{
+		Jurisdiction:   r.FormValue(jurisdictionParamName),
+		AssuranceLevel: r.FormValue(assuranceLevelParamName),
+	}
+}
+
+// DebugParamName is the parameter of the proof endpoints returning the timing breakdown of the proof, it is
+// restricted to the admin role (see auth.RequiredRoles).
+const DebugParamName = "debug"
+
+func debugParam(r *http.Request) (bool, error) {
+	if r.FormValue(DebugParamName) == "" {
+		return false, nil
+	}
+
+	debug, err := strconv.ParseBool(r.FormValue(DebugParamName))
+	if err != nil {
+		return false, fmt.Errorf("%w: parameter %q is not a bool", errors.ErrValidation, DebugParamName)
+	}
+
+	return debug, nil
+}
+
+func pageRequest(r *http.Request) (command.PageRequest, error) {
+	const (
+		pageSizeParamName  = "page_size"
+		pageTokenParamName = "page_token"
+	)
+
+	page := command.PageRequest{PageToken: r.FormValue(pageTokenParamName)}
+
+	if size := r.FormValue(pageSizeParamName); size != "" {
+		var err error
+
+		page.PageSize, err = strconv.Atoi(size)
+		if err != nil {
+			return page, fmt.Errorf("%w: parameter %q is not a number", errors.ErrValidation, pageSizeParamName)
+		}
+	}
+
+	return page, nil
+}
+
+// ErrorResponse represents REST error message (RFC 7807 problem details).
+type ErrorResponse struct {
+	Type   string `json:"type,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// Message is the same as Detail, kept for backward compatibility.
+	Message string `json:"message"`
+}
+
+// ErrorResponseV2 represents REST error message of the v2 API (RFC 7807 problem details), the problem is always
+// typed.
+type ErrorResponseV2 struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func sendError(rw http.ResponseWriter, e error) {
+	status := errors.StatusCodeFromError(e)
+
+	rw.Header().Set(contentType, applicationProblemJSON)
+
+	switch errors.ProblemTypeFromError(e) {
+	case errors.ProblemTypeReadOnly, errors.ProblemTypeDraining, errors.ProblemTypeTreeFrozen:
+		rw.Header().Set(retryAfter, readOnlyRetryAfter)
+	}
+
+	rw.WriteHeader(status)
+
+	var resp interface{} = ErrorResponse{
+		Type:    errors.ProblemTypeFromError(e),
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  e.Error(),
+		Message: e.Error(),
+	}
+
+	if apiVersion(rw) == APIVersion2 {
+		resp = ErrorResponseV2{
+			Type:   errors.ProblemTypeFromError(e),
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: e.Error(),
+		}
+	}
 
-	if err := json.NewEncoder(rw).Encode(ErrorResponse{Message: e.Error()}); err != nil {
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
 		logger.Errorf("send error response: %v", e)
 	}
 }
@@ -11,6 +11,7 @@ package rest_test
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,6 +19,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/mux"
@@ -87,6 +89,34 @@ func TestOperation_AddVC(t *testing.T) {
 	})
 }
 
+func TestOperation_ProblemDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetSTH(gomock.Any(), gomock.Any()).Return(fmt.Errorf("wrapped: %w", errors.ErrValidation))
+
+	router := mux.NewRouter()
+	handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), GetSTHPath)
+	router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, strings.Replace(GetSTHPath, "{alias}", alias, 1), nil))
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var problem ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	require.Equal(t, ErrorResponse{
+		Type:    errors.ProblemTypeValidation,
+		Title:   "Bad Request",
+		Status:  http.StatusBadRequest,
+		Detail:  "wrapped: validation failed",
+		Message: "wrapped: validation failed",
+	}, problem)
+}
+
 func TestOperation_GetSTH(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -144,6 +174,60 @@ func TestOperation_GetIssuers(t *testing.T) {
 
 		require.Equal(t, http.StatusOK, code)
 	})
+
+	t.Run("Page", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetIssuers(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetIssuersRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, command.PageRequest{PageSize: 2, PageToken: "token"}, req.PageRequest)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t, handlerLookup(t, operation, GetIssuersPath), nil,
+			strings.Replace(GetIssuersPath, "{alias}", alias, 1)+"?page_size=2&page_token=token",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Policy tags", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetIssuers(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetIssuersRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, command.PageRequest{}, req.PageRequest)
+			require.Equal(t, command.PolicyTags{Jurisdiction: "CA-QC", AssuranceLevel: "high"}, req.PolicyTags)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t, handlerLookup(t, operation, GetIssuersPath), nil,
+			strings.Replace(GetIssuersPath, "{alias}", alias, 1)+"?jurisdiction=CA-QC&assurance_level=high",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("page_size parameter is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t, handlerLookup(t, operation, GetIssuersPath), nil,
+			strings.Replace(GetIssuersPath, "{alias}", alias, 1)+"?page_size=two",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), `parameter \"page_size\" is not a number`)
+	})
 }
 
 func TestOperation_HealthCheck(t *testing.T) {
@@ -249,6 +333,180 @@ func TestOperation_GetSTHConsistency(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, code)
 		require.Contains(t, buf.String(), "validation failed: parameter \\\"second\\\" is not a number")
 	})
+
+	t.Run("Debug", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetSTHConsistency(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetSTHConsistencyRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.True(t, req.Debug)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetSTHConsistencyPath), nil,
+			strings.Replace(GetSTHConsistencyPath, "{alias}", alias, 1)+"?first=1&second=2&debug=true",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("debug parameter is not a bool", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetSTHConsistencyPath), nil,
+			GetSTHConsistencyPath+"?first=1&second=2&debug=often",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"debug\\\" is not a bool")
+	})
+}
+
+func TestOperation_GetAuditExport(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetAuditExport(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetAuditExportRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, int64(1), req.FirstTreeSize)
+			require.Equal(t, int64(2), req.SecondTreeSize)
+			require.Equal(t, alias, req.Alias)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAuditExportPath), nil,
+			strings.Replace(GetAuditExportPath, "{alias}", alias, 1)+"?first=1&second=2",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("first parameter is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAuditExportPath), nil,
+			GetAuditExportPath+"?first=one",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"first\\\" is not a number")
+	})
+
+	t.Run("second parameter is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAuditExportPath), nil,
+			GetAuditExportPath+"?first=1&second=second",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"second\\\" is not a number")
+	})
+}
+
+func TestOperation_GetDiff(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetDiff(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetDiffRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, int64(10), req.FirstTreeSize)
+			require.Equal(t, int64(20), req.SecondTreeSize)
+			require.True(t, req.Entries)
+			require.Equal(t, alias, req.Alias)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, DiffPath), nil,
+			strings.Replace(DiffPath, "{alias}", alias, 1)+"?first=10&second=20&entries=true",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("first parameter is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t, handlerLookup(t, operation, DiffPath), nil, DiffPath+"?first=one")
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"first\\\" is not a number")
+	})
+
+	t.Run("second parameter is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t, handlerLookup(t, operation, DiffPath), nil,
+			DiffPath+"?first=1&second=second")
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"second\\\" is not a number")
+	})
+
+	t.Run("entries parameter is not a boolean", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t, handlerLookup(t, operation, DiffPath), nil,
+			DiffPath+"?first=1&second=2&entries=all")
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"entries\\\" is not a boolean")
+	})
+}
+
+func TestOperation_GetRandomEntries(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetRandomEntries(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetRandomEntriesRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, 10, req.Count)
+			require.Equal(t, "beacon", req.Seed)
+			require.Equal(t, alias, req.Alias)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetRandomEntriesPath), nil,
+			strings.Replace(GetRandomEntriesPath, "{alias}", alias, 1)+"?count=10&seed=beacon",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("count parameter is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetRandomEntriesPath), nil,
+			GetRandomEntriesPath+"?count=ten",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"count\\\" is not a number")
+	})
 }
 
 func TestOperation_GetEntries(t *testing.T) {
@@ -275,6 +533,67 @@ func TestOperation_GetEntries(t *testing.T) {
 		require.Equal(t, http.StatusOK, code)
 	})
 
+	t.Run("Success (policy tags)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetEntries(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetEntriesRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, int64(1), req.Start)
+			require.Equal(t, int64(2), req.End)
+			require.Equal(t, command.PolicyTags{Jurisdiction: "FR"}, req.PolicyTags)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetEntriesPath), nil,
+			strings.Replace(GetEntriesPath, "{alias}", alias, 1)+"?start=1&end=2&jurisdiction=FR",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Success (time range)", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetEntries(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetEntriesRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, time.Date(2021, time.October, 12, 0, 0, 0, 0, time.UTC), req.FromTime.UTC())
+			require.Nil(t, req.ToTime)
+			require.Equal(t, alias, req.Alias)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetEntriesPath), nil,
+			strings.Replace(GetEntriesPath, "{alias}", alias, 1)+"?from_time=2021-10-12T00:00:00Z",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("from_time parameter is not a time", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetEntriesPath), nil,
+			GetEntriesPath+"?from_time=yesterday",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "validation failed: parameter \\\"from_time\\\" is not a RFC3339 time")
+	})
+
 	t.Run("start parameter is not a number", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -346,6 +665,1412 @@ func TestOperation_GetProofByHash(t *testing.T) {
 	})
 }
 
+func TestOperation_GetProofOfAbsence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetProofOfAbsence(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req *command.GetProofOfAbsenceRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, "did:example:issuer", req.Issuer)
+		require.Equal(t, "urn:credential:1", req.CredentialID)
+		require.Equal(t, alias, req.Alias)
+	}).Return(nil)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	_, code := sendRequestToHandler(t,
+		handlerLookup(t, operation, GetProofOfAbsencePath), nil,
+		strings.Replace(GetProofOfAbsencePath, "{alias}", alias, 1)+
+			"?issuer=did:example:issuer&credential_id=urn:credential:1",
+	)
+
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestOperation_GetMapRoot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetMapRoot(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req string
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req)
+	}).Return(nil)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	_, code := sendRequestToHandler(t,
+		handlerLookup(t, operation, GetMapRootPath), nil,
+		strings.Replace(GetMapRootPath, "{alias}", alias, 1),
+	)
+
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestOperation_GetShadowStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetShadowStatus(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req string
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req)
+	}).Return(nil)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	_, code := sendRequestToHandler(t,
+		handlerLookup(t, operation, GetShadowStatusPath), nil,
+		strings.Replace(GetShadowStatusPath, "{alias}", alias, 1),
+	)
+
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestOperation_ReadOnly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetReadOnly(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().GetKeyUsage(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().SetReadOnly(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req *command.ReadOnlyStatus
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.True(t, req.ReadOnly)
+	}).Return(nil)
+	cmd.EXPECT().AddVC(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: maintenance", errors.ErrReadOnly))
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, ReadOnlyPath, "").Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, ReadOnlyPath, `{"read_only":true}`).Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, KeyUsagePath, "").Code)
+
+	rr := serve(http.MethodPost, strings.Replace(AddVCPath, "{alias}", alias, 1), "{}")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	var resp *ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, errors.ProblemTypeReadOnly, resp.Type)
+}
+
+func TestOperation_LogState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetLogStates(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().SetLogState(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req *command.SetLogStateRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, &command.SetLogStateRequest{Alias: alias, State: command.LogStateDraining}, req)
+	}).Return(nil)
+	cmd.EXPECT().AddVC(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: the log is draining", errors.ErrDraining))
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, LogStatePath, "").Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, LogStatePath,
+		`{"alias":"maple2021","state":"draining"}`).Code)
+
+	rr := serve(http.MethodPost, strings.Replace(AddVCPath, "{alias}", alias, 1), "{}")
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	var resp *ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, errors.ProblemTypeDraining, resp.Type)
+}
+
+// usageCmd is a command which counts the requests to the logs.
+type usageCmd struct {
+	*MockCmd
+	requests []string
+}
+
+func (c *usageCmd) RecordRequest(alias, operation string, write bool, status int, _ time.Duration) {
+	c.requests = append(c.requests, fmt.Sprintf("%s %s %t %d", alias, operation, write, status))
+}
+
+// featureCmd is a command which disables the operations.
+type featureCmd struct {
+	*MockCmd
+	checked []string
+}
+
+func (c *featureCmd) CheckOperation(alias, operation string) error {
+	c.checked = append(c.checked, alias+" "+operation)
+
+	switch operation {
+	case "get-entries":
+		return fmt.Errorf("operation get-entries is %w", errors.ErrDisabled)
+	case "admin/submission-stats":
+		return errors.NewForbiddenError(fmt.Errorf("operation admin/submission-stats is %w for tenant maple",
+			errors.ErrDisabled))
+	default:
+		return nil
+	}
+}
+
+func TestOperation_FeatureFlags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := &featureCmd{MockCmd: NewMockCmd(ctrl)}
+	cmd.EXPECT().GetSTH(gomock.Any(), gomock.Any()).Return(nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range New(cmd, &mockService{}, &mockService{}, nil).GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(strings.Replace(GetSTHPath, "{alias}", alias, 1)).Code)
+
+	rr := serve(strings.Replace(GetEntriesPath, "{alias}", alias, 1) + "?start=0&end=1")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	var resp *ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, errors.ProblemTypeDisabled, resp.Type)
+	require.Equal(t, "operation get-entries is disabled", resp.Detail)
+
+	// the v2 errors are problem details
+	rr = serve(strings.Replace(V2BasePath, "{alias}", alias, 1) + "/get-entries?start=0&end=1")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, APIVersion2, rr.Header().Get(APIVersionHeader))
+
+	rr = serve(SubmissionStatsPath)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Contains(t, rr.Body.String(), errors.ProblemTypeDisabled)
+
+	require.Equal(t, []string{
+		alias + " get-sth",
+		alias + " get-entries",
+		alias + " get-entries",
+		" admin/submission-stats",
+	}, cmd.checked)
+}
+
+func TestOperation_GetUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := &usageCmd{MockCmd: NewMockCmd(ctrl)}
+	cmd.EXPECT().GetUsage(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req *command.GetUsageRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, "maple", req.Tenant)
+	}).Return(nil)
+	cmd.EXPECT().GetSTH(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().AddVC(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: invalid", errors.ErrValidation))
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path string) int {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, UsagePath+"?tenant=maple"))
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, strings.Replace(GetSTHPath, "{alias}", alias, 1)))
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, strings.Replace(AddVCPath, "{alias}", alias, 1)))
+
+	// only the requests to the logs are counted
+	require.Equal(t, []string{
+		alias + " get-sth false 200",
+		alias + " add-vc true 400",
+	}, cmd.requests)
+}
+
+func TestOperation_GetSubmissionStats(t *testing.T) {
+	serve := func(t *testing.T, cmd Cmd, query string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), SubmissionStatsPath)
+
+		req, err := http.NewRequestWithContext(context.Background(), handler.Method(), SubmissionStatsPath+query, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.Handle()(rr, req)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetSubmissionStats(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetSubmissionStatsRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, &command.GetSubmissionStatsRequest{
+				Alias:  alias,
+				Issuer: "did:example:maple",
+				SortBy: command.SubmissionSize,
+				Limit:  3,
+			}, req)
+		}).Return(nil)
+
+		rr := serve(t, cmd, "?alias="+alias+"&issuer=did:example:maple&sort_by=size&limit=3")
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rr := serve(t, NewMockCmd(ctrl), "?limit=ten")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `parameter \"limit\" is not a number`)
+	})
+}
+
+func TestOperation_GetPendingSubmissions(t *testing.T) {
+	serve := func(t *testing.T, cmd Cmd, query string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), PendingSubmissionsPath)
+
+		req, err := http.NewRequestWithContext(context.Background(), handler.Method(), PendingSubmissionsPath+query, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.Handle()(rr, req)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetPendingSubmissions(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetPendingSubmissionsRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, &command.GetPendingSubmissionsRequest{Alias: alias, Limit: 5}, req)
+		}).Return(nil)
+
+		rr := serve(t, cmd, "?alias="+alias+"&limit=5")
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Invalid limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rr := serve(t, NewMockCmd(ctrl), "?limit=five")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `parameter \"limit\" is not a number`)
+	})
+}
+
+func TestOperation_GetTreeHeadSLA(t *testing.T) {
+	serve := func(t *testing.T, cmd Cmd, query string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), TreeHeadSLAPath)
+
+		req, err := http.NewRequestWithContext(context.Background(), handler.Method(), TreeHeadSLAPath+query, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.Handle()(rr, req)
+
+		return rr
+	}
+
+	getSLA := func(alias, format string) func(io.Writer, io.Reader) error {
+		return func(w io.Writer, r io.Reader) error {
+			var req *command.GetTreeHeadSLARequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, format, req.Format)
+
+			_, err := w.Write([]byte("report"))
+
+			return err
+		}
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetTreeHeadSLA(gomock.Any(), gomock.Any()).DoAndReturn(getSLA(alias, ""))
+
+		rr := serve(t, cmd, "?alias="+alias)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.Empty(t, rr.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetTreeHeadSLA(gomock.Any(), gomock.Any()).DoAndReturn(getSLA("", command.TreeHeadSLAFormatCSV))
+
+		rr := serve(t, cmd, "?format=csv")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "report", rr.Body.String())
+		require.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+		require.Equal(t, `attachment; filename="tree-head-sla.csv"`, rr.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetTreeHeadSLA(gomock.Any(), gomock.Any()).Return(errors.ErrNotFound)
+
+		rr := serve(t, cmd, "?format=csv")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Empty(t, rr.Header().Get("Content-Disposition"))
+	})
+}
+
+func TestOperation_GetUnmergedSubmissions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetUnmergedSubmissions(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+		var req string
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req)
+
+		_, err := w.Write([]byte(`{"ttl":60000,"tree_size":1,"submissions":[]}`))
+
+		return err
+	})
+
+	router := mux.NewRouter()
+
+	for _, h := range New(cmd, &mockService{}, &mockService{}, nil).GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		strings.Replace(UnmergedPath, "{alias}", alias, 1), nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"ttl":60000,"tree_size":1,"submissions":[]}`, rr.Body.String())
+}
+
+func TestOperation_GetReconciliation(t *testing.T) {
+	serve := func(t *testing.T, cmd Cmd, query string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), ReconciliationPath)
+
+		req, err := http.NewRequestWithContext(context.Background(), handler.Method(), ReconciliationPath+query, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.Handle()(rr, req)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetReconciliation(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+			var req *command.GetReconciliationRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.True(t, req.Refresh)
+
+			return nil
+		})
+
+		rr := serve(t, cmd, "?alias="+alias+"&refresh=true")
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Not reconciled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetReconciliation(gomock.Any(), gomock.Any()).Return(errors.ErrNotFound)
+
+		rr := serve(t, cmd, "")
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("Invalid refresh", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rr := serve(t, NewMockCmd(ctrl), "?refresh=maybe")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `parameter \"refresh\" is not a boolean`)
+	})
+}
+
+func TestOperation_APIVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetSTH(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+	cmd.EXPECT().AddVC(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: invalid", errors.ErrValidation)).Times(2)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "/maple2021/v1/get-sth")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, APIVersion1, rr.Header().Get(APIVersionHeader))
+	require.Empty(t, rr.Header().Get("Deprecation"))
+
+	rr = serve(http.MethodGet, "/maple2021/v2/get-sth")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, APIVersion2, rr.Header().Get(APIVersionHeader))
+
+	// the unversioned paths are served as v1
+	rr = serve(http.MethodGet, "/maple2021/get-sth")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, APIVersion1, rr.Header().Get(APIVersionHeader))
+	require.Equal(t, "true", rr.Header().Get("Deprecation"))
+	require.Equal(t, `</maple2021/v1/get-sth>; rel="successor-version"`, rr.Header().Get("Link"))
+
+	// the v1 errors keep the message
+	rr = serve(http.MethodPost, "/maple2021/v1/add-vc")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), `"message":"validation failed: invalid"`)
+
+	rr = serve(http.MethodPost, "/maple2021/v2/add-vc")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var resp map[string]interface{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, map[string]interface{}{
+		"type":   errors.ProblemTypeValidation,
+		"title":  http.StatusText(http.StatusBadRequest),
+		"status": float64(http.StatusBadRequest),
+		"detail": "validation failed: invalid",
+	}, resp)
+}
+
+// signingCmd is a command which signs the responses of the purposes.
+type signingCmd struct {
+	*MockCmd
+	purposes []command.KeyPurpose
+}
+
+func (c *signingCmd) SignsResponses(purpose command.KeyPurpose) bool {
+	for _, p := range c.purposes {
+		if p == purpose {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *signingCmd) SignResponse(purpose command.KeyPurpose, requestURI string,
+	body []byte) (*command.SignedResponse, error) {
+	if requestURI == "/fail/v1/get-sth" {
+		return nil, fmt.Errorf("sign failed")
+	}
+
+	return &command.SignedResponse{
+		KeyID:     []byte(purpose),
+		Timestamp: 1,
+		Signature: []byte(requestURI + " " + string(body)),
+	}, nil
+}
+
+func TestOperation_SignResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := &signingCmd{MockCmd: NewMockCmd(ctrl), purposes: []command.KeyPurpose{command.ResponseKeyPurpose}}
+	cmd.EXPECT().GetSTH(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, _ io.Reader) error {
+		_, err := w.Write([]byte(`{"tree_size":1}`))
+
+		return err
+	}).Times(2)
+	cmd.EXPECT().GetReadOnly(gomock.Any(), gomock.Any()).Return(nil)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve(strings.Replace(GetSTHPath, "{alias}", alias, 1))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, `{"tree_size":1}`, rr.Body.String())
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("/maple2021/v1/get-sth "+`{"tree_size":1}`)),
+		rr.Header().Get(SignatureHeader))
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte(command.ResponseKeyPurpose)),
+		rr.Header().Get(SignatureKeyIDHeader))
+	require.Equal(t, "1", rr.Header().Get(SignatureTimestampHeader))
+
+	// there is no admin key
+	rr = serve(ReadOnlyPath)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Empty(t, rr.Header().Get(SignatureHeader))
+
+	// the health check is not signed
+	rr = serve(HealthCheckPath)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Empty(t, rr.Header().Get(SignatureHeader))
+
+	rr = serve("/fail/v1/get-sth")
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Empty(t, rr.Body.String())
+}
+
+func TestOperation_MarkCompromised(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().MarkCompromised(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().AddVC(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: frozen", errors.ErrCompromised))
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, CompromisePath).Code)
+
+	rr := serve(http.MethodPost, strings.Replace(AddVCPath, "{alias}", alias, 1))
+	require.Equal(t, http.StatusGone, rr.Code)
+	require.Empty(t, rr.Header().Get("Retry-After"))
+
+	var resp *ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, errors.ProblemTypeCompromised, resp.Type)
+}
+
+func TestOperation_FreezeLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().FreezeLog(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().AddVC(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: the log is frozen", errors.ErrFrozen))
+	cmd.EXPECT().GetFinalTreeHead(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+		var req string
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req)
+
+		_, err := w.Write([]byte(`{"statement":{"alias":"maple2021"}}`))
+
+		return err
+	})
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, FreezePath).Code)
+
+	rr := serve(http.MethodPost, strings.Replace(AddVCPath, "{alias}", alias, 1))
+	require.Equal(t, http.StatusGone, rr.Code)
+
+	var resp *ErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, errors.ProblemTypeFrozen, resp.Type)
+
+	rr = serve(http.MethodGet, "/"+alias+"/final-sth")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"statement":{"alias":"maple2021"}}`, rr.Body.String())
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	require.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+}
+
+func TestOperation_Policy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().PublishPolicy(gomock.Any(), gomock.Any()).Return(nil)
+	cmd.EXPECT().GetPolicy(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+		var req *command.GetPolicyRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req.Alias)
+
+		_, err := fmt.Fprintf(w, `{"statement":{"policy_version":%d}}`, req.Version)
+
+		return err
+	}).Times(2)
+	cmd.EXPECT().GetPolicyHistory(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+		var req string
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req)
+
+		_, err := w.Write([]byte(`{"policies":[]}`))
+
+		return err
+	})
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), method, path, bytes.NewBufferString("{}"))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, PublishPolicyPath).Code)
+
+	rr := serve(http.MethodGet, "/"+alias+"/policy")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"statement":{"policy_version":0}}`, rr.Body.String())
+	require.Empty(t, rr.Header().Get("Cache-Control"))
+
+	// a published version never changes
+	rr = serve(http.MethodGet, "/"+alias+"/policy/2")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"statement":{"policy_version":2}}`, rr.Body.String())
+	require.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+
+	rr = serve(http.MethodGet, "/"+alias+"/policy/history")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"policies":[]}`, rr.Body.String())
+
+	rr = serve(http.MethodGet, "/"+alias+"/policy/0")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "version must be a positive number")
+}
+
+func TestOperation_GetEntryByCID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cid := command.EntryCID([]byte("entry"))
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetEntryByCID(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+		var req *command.GetEntryByCIDRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req.Alias)
+
+		if req.CID != cid {
+			return errors.NewNotFoundError(fmt.Errorf("no entry with CID %s is logged", req.CID))
+		}
+
+		_, err := fmt.Fprintf(w, `{"cid":%q,"leaf_index":1}`, req.CID)
+
+		return err
+	}).Times(2)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	router := mux.NewRouter()
+
+	for _, h := range operation.GetRESTHandlers() {
+		router.HandleFunc(h.Path(), h.Handle()).Methods(h.Method())
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, path, nil)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve("/" + alias + "/entry/" + cid)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, fmt.Sprintf(`{"cid":%q,"leaf_index":1}`, cid), rr.Body.String())
+	require.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+
+	rr = serve("/" + alias + "/entry/" + command.EntryCID(nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Empty(t, rr.Header().Get("Cache-Control"))
+}
+
+func TestOperation_GetRetiredShards(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetRetiredShards(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+		var req *command.GetRetiredShardsRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, "maple", req.Tenant)
+
+		return json.NewEncoder(w).Encode(command.GetRetiredShardsResponse{
+			Shards: []command.RetiredShard{{Alias: alias, Tenant: "maple"}},
+		})
+	})
+
+	handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), RetiredShardsPath)
+
+	req, err := http.NewRequestWithContext(context.Background(), handler.Method(), RetiredShardsPath+"?tenant=maple",
+		nil)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.Handle()(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), `"alias":"maple2021"`)
+}
+
+func TestOperation_GetCredentialStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetCredentialStatus(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req *command.GetCredentialStatusRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, "did:example:issuer", req.Issuer)
+		require.Equal(t, "urn:credential:1", req.CredentialID)
+		require.Equal(t, alias, req.Alias)
+	}).Return(nil)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	_, code := sendRequestToHandler(t,
+		handlerLookup(t, operation, GetCredentialStatusPath), nil,
+		strings.Replace(GetCredentialStatusPath, "{alias}", alias, 1)+
+			"?issuer=did:example:issuer&credential_id=urn:credential:1",
+	)
+
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestOperation_AddRevocation(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().AddRevocation(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.AddRevocationRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, []byte("hash"), req.Event.LeafHash)
+			require.Equal(t, command.RevokedStatus, req.Event.Status)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddRevocationPath),
+			bytes.NewBufferString(`{"leaf_hash":"aGFzaA==","status":"revoked"}`),
+			strings.Replace(AddRevocationPath, "{alias}", alias, 1),
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddRevocationPath),
+			bytes.NewBufferString(`[]`), AddRevocationPath,
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestOperation_GetRevocations(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetRevocations(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetRevocationsRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, []byte("hash"), req.LeafHash)
+			require.Equal(t, command.PageRequest{PageSize: 10}, req.PageRequest)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetRevocationsPath), nil,
+			strings.Replace(GetRevocationsPath, "{alias}", alias, 1)+"?leaf_hash=aGFzaA==&page_size=10",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("leaf_hash parameter is not base64", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetRevocationsPath), nil,
+			GetRevocationsPath+"?leaf_hash=!",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "is not base64")
+	})
+}
+
+func TestOperation_AddAnchor(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().AddAnchor(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.AddAnchorRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, []byte("id"), req.Anchor.LogID)
+			require.Equal(t, uint64(2), req.Anchor.STH.TreeSize)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddAnchorPath),
+			bytes.NewBufferString(`{"log_id":"aWQ=","sth":{"tree_size":2}}`),
+			strings.Replace(AddAnchorPath, "{alias}", alias, 1),
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddAnchorPath),
+			bytes.NewBufferString(`[]`), AddAnchorPath,
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestOperation_AddEntry(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().AddEntry(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.AddEntryRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, command.CommitmentLogEntryType, req.EntryType)
+			require.JSONEq(t, `{"hash":"aGFzaA=="}`, string(req.Entry))
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddEntryPath),
+			bytes.NewBufferString(`{"entry_type":103,"entry":{"hash":"aGFzaA=="}}`),
+			strings.Replace(AddEntryPath, "{alias}", alias, 1),
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddEntryPath),
+			bytes.NewBufferString(`[]`), AddEntryPath,
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestOperation_GetAnchors(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetAnchors(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetAnchorsRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, []byte("id"), req.LogID)
+			require.Equal(t, "token", req.PageToken)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAnchorsPath), nil,
+			strings.Replace(GetAnchorsPath, "{alias}", alias, 1)+"?log_id=aWQ=&page_token=token",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("log_id parameter is not base64", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAnchorsPath), nil,
+			GetAnchorsPath+"?log_id=!",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "is not base64")
+	})
+}
+
+func TestOperation_GetCredentialHistory(t *testing.T) {
+	hash := []byte{0xfb, 0xef, 0xbe}
+
+	for _, encoded := range []string{base64.URLEncoding.EncodeToString(hash), base64.StdEncoding.EncodeToString(hash)} {
+		t.Run("Success "+encoded, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cmd := NewMockCmd(ctrl)
+			cmd.EXPECT().GetCredentialHistory(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+				var req *command.GetCredentialHistoryRequest
+				require.NoError(t, json.NewDecoder(r).Decode(&req))
+				require.Equal(t, alias, req.Alias)
+				require.Equal(t, hash, req.LeafHash)
+			}).Return(nil)
+
+			operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+			path := strings.Replace(GetCredentialHistoryPath, "{alias}", alias, 1)
+
+			_, code := sendRequestToHandler(t,
+				handlerLookup(t, operation, GetCredentialHistoryPath), nil,
+				strings.Replace(path, "{hash}", encoded, 1),
+			)
+
+			require.Equal(t, http.StatusOK, code)
+		})
+	}
+
+	t.Run("hash is not base64", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		buf, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetCredentialHistoryPath), nil,
+			strings.Replace(GetCredentialHistoryPath, "{hash}", "!", 1),
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, buf.String(), "hash is not base64")
+	})
+}
+
+func TestOperation_GetReceipt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cmd := NewMockCmd(ctrl)
+	cmd.EXPECT().GetReceipt(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+		var req *command.GetReceiptRequest
+		require.NoError(t, json.NewDecoder(r).Decode(&req))
+		require.Equal(t, alias, req.Alias)
+		require.Equal(t, "submission-1", req.IdempotencyKey)
+	}).Return(nil)
+
+	operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+	path := strings.Replace(GetReceiptPath, "{alias}", alias, 1)
+
+	_, code := sendRequestToHandler(t,
+		handlerLookup(t, operation, GetReceiptPath), nil,
+		strings.Replace(path, "{key}", "submission-1", 1),
+	)
+
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestOperation_AddAnnotation(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().AddAnnotation(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.AddAnnotationRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, int64(7), req.Annotation.Annotation.LeafIndex)
+			require.Equal(t, command.AnnotationFraudulent, req.Annotation.Annotation.Kind)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddAnnotationPath),
+			bytes.NewBufferString(`{"annotation":{"leaf_index":7,"kind":"fraudulent"},"signature":"c2ln"}`),
+			strings.Replace(AddAnnotationPath, "{alias}", alias, 1),
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, AddAnnotationPath),
+			bytes.NewBufferString(`[]`), AddAnnotationPath,
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestOperation_GetAnnotations(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetAnnotations(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetAnnotationsRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, int64(7), req.LeafIndex)
+		}).Return(nil)
+
+		operation := New(cmd, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAnnotationsPath), nil,
+			strings.Replace(GetAnnotationsPath, "{alias}", alias, 1)+"?leaf_index=7",
+		)
+
+		require.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Leaf index is not a number", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		_, code := sendRequestToHandler(t,
+			handlerLookup(t, operation, GetAnnotationsPath), nil,
+			strings.Replace(GetAnnotationsPath, "{alias}", alias, 1)+"?leaf_index=abc",
+		)
+
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestOperation_GetTile(t *testing.T) {
+	serve := func(t *testing.T, operation *Operation, lookup, path string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		handler := handlerLookup(t, operation, lookup)
+
+		req, err := http.NewRequestWithContext(context.Background(), handler.Method(), path, nil)
+		require.NoError(t, err)
+
+		router := mux.NewRouter()
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetTile(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer, r io.Reader) error {
+			var req *command.GetTileRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, 1, req.Level)
+			require.Equal(t, int64(1234067), req.Index)
+			require.Equal(t, 12, req.Width)
+
+			_, err := w.Write([]byte{1, 2, 3})
+
+			return err
+		})
+
+		rr := serve(t, New(cmd, &mockService{}, &mockService{}, nil), TilePath,
+			"/"+alias+"/tile/1/x001/x234/067.p/12")
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, []byte{1, 2, 3}, rr.Body.Bytes())
+		require.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+		require.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Entry bundle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetEntryBundle(gomock.Any(), gomock.Any()).Do(func(_ io.Writer, r io.Reader) {
+			var req *command.GetEntryBundleRequest
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req.Alias)
+			require.Equal(t, int64(5), req.Index)
+			require.Equal(t, 0, req.Width)
+		}).Return(nil)
+
+		rr := serve(t, New(cmd, &mockService{}, &mockService{}, nil), EntryBundlePath,
+			"/"+alias+"/tile/entries/005")
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetTile(gomock.Any(), gomock.Any()).Return(errors.ErrNotFound)
+
+		rr := serve(t, New(cmd, &mockService{}, &mockService{}, nil), TilePath, "/"+alias+"/tile/0/005")
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Empty(t, rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Invalid index", func(t *testing.T) {
+		operation := New(nil, &mockService{}, &mockService{}, nil)
+
+		rr := serve(t, operation, TilePath, "/"+alias+"/tile/0/5")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "tile index \\\"5\\\" is not valid")
+
+		rr = serve(t, operation, EntryBundlePath, "/"+alias+"/tile/entries/x000/005")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "is not canonical")
+	})
+}
+
+func TestOperation_GetVerificationSnapshot(t *testing.T) {
+	serve := func(t *testing.T, cmd Cmd) *httptest.ResponseRecorder {
+		t.Helper()
+
+		handler := handlerLookup(t, New(cmd, &mockService{}, &mockService{}, nil), VerificationSnapshotPath)
+
+		req, err := http.NewRequestWithContext(context.Background(), handler.Method(),
+			"/"+alias+"/verification-snapshot", nil)
+		require.NoError(t, err)
+
+		router := mux.NewRouter()
+		router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		nextUpdate := time.Now().Add(30*time.Second).UnixNano() / int64(time.Millisecond)
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetVerificationSnapshot(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer,
+			r io.Reader) error {
+			var req string
+			require.NoError(t, json.NewDecoder(r).Decode(&req))
+			require.Equal(t, alias, req)
+
+			return json.NewEncoder(w).Encode(command.SignedVerificationSnapshot{
+				Snapshot: command.VerificationSnapshot{Alias: alias, NextUpdate: uint64(nextUpdate)},
+			})
+		})
+
+		rr := serve(t, cmd)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), `"next_update":`)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.Regexp(t, `^public, max-age=(29|30)$`, rr.Header().Get("Cache-Control"))
+		require.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Past next update", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetVerificationSnapshot(gomock.Any(), gomock.Any()).DoAndReturn(func(w io.Writer,
+			_ io.Reader) error {
+			return json.NewEncoder(w).Encode(command.SignedVerificationSnapshot{})
+		})
+
+		rr := serve(t, cmd)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "public, max-age=0", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("Not enabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		cmd := NewMockCmd(ctrl)
+		cmd.EXPECT().GetVerificationSnapshot(gomock.Any(), gomock.Any()).Return(
+			errors.NewNotFoundError(fmt.Errorf("verification snapshots are not enabled")))
+
+		rr := serve(t, cmd)
+
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Empty(t, rr.Header().Get("Cache-Control"))
+	})
+}
+
 func TestOperation_GetEntryAndProof(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
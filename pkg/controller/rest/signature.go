@@ -0,0 +1,108 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// Headers of the signature of a response.
+const (
+	SignatureHeader          = "Vct-Signature"
+	SignatureKeyIDHeader     = "Vct-Signature-Key-Id"
+	SignatureTimestampHeader = "Vct-Signature-Timestamp"
+)
+
+// responseSigner is implemented by the commands which sign the responses with the keys of the response and admin
+// purposes (see command.Cmd.SignResponse).
+type responseSigner interface {
+	SignsResponses(purpose command.KeyPurpose) bool
+	SignResponse(purpose command.KeyPurpose, requestURI string, body []byte) (*command.SignedResponse, error)
+}
+
+// signResponses wraps the handlers to sign their responses if the command has a key for their purpose: the admin
+// key signs the responses of the admin API, the response key the others. The metrics and the health check are not
+// signed.
+func (c *Operation) signResponses(handlers []Handler) []Handler {
+	signer, ok := c.cmd.(responseSigner)
+	if !ok {
+		return handlers
+	}
+
+	for i, h := range handlers {
+		if h.Path() == MetricsPath || h.Path() == HealthCheckPath {
+			continue
+		}
+
+		purpose := command.ResponseKeyPurpose
+		if strings.HasPrefix(h.Path(), "/admin/") {
+			purpose = command.AdminKeyPurpose
+		}
+
+		if !signer.SignsResponses(purpose) {
+			continue
+		}
+
+		handlers[i] = NewHTTPHandler(h.Path(), h.Method(), signResponse(signer, purpose, h.Handle()))
+	}
+
+	return handlers
+}
+
+func signResponse(signer responseSigner, purpose command.KeyPurpose, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &bufferedWriter{header: http.Header{}, status: http.StatusOK}
+
+		next(rw, r)
+
+		signed, err := signer.SignResponse(purpose, r.URL.RequestURI(), rw.body.Bytes())
+		if err != nil {
+			logger.Errorf("sign response: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		for k, v := range rw.header {
+			w.Header()[k] = v
+		}
+
+		w.Header().Set(SignatureHeader, base64.StdEncoding.EncodeToString(signed.Signature))
+		w.Header().Set(SignatureKeyIDHeader, base64.StdEncoding.EncodeToString(signed.KeyID))
+		w.Header().Set(SignatureTimestampHeader, strconv.FormatUint(signed.Timestamp, 10))
+		w.WriteHeader(rw.status)
+
+		if _, err = w.Write(rw.body.Bytes()); err != nil {
+			logger.Errorf("write response: %v", err)
+		}
+	}
+}
+
+// bufferedWriter keeps the response until it is signed.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p) // nolint: wrapcheck
+}
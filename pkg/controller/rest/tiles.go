@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/trustbloc/vct/pkg/controller/errors"
+)
+
+const (
+	// partialTileSuffix separates the index of a partial tile from its width.
+	partialTileSuffix = ".p"
+	// maxTileIndexElements is enough for the index of a tile of a tree of 2^64 entries.
+	maxTileIndexElements = 6
+)
+
+// TileIndexPath encodes the index of a tile as a path (the tlog-tiles layout): the index is split into
+// zero-padded groups of three digits, all of them but the last prefixed with "x" (e.g. 1234067 is x001/x234/067),
+// a partial tile of the width is suffixed with ".p/<width>". The width is zero for a full tile.
+func TileIndexPath(index int64, width int) string {
+	path := fmt.Sprintf("%03d", index%1000)
+
+	for index >= 1000 {
+		index /= 1000
+		path = fmt.Sprintf("x%03d/%s", index%1000, path)
+	}
+
+	if width > 0 {
+		path += partialTileSuffix + "/" + strconv.Itoa(width)
+	}
+
+	return path
+}
+
+// ParseTileIndex parses the index and the width of a tile encoded by TileIndexPath, only the canonical encoding
+// is accepted so that a tile is cached under a single URL.
+func ParseTileIndex(path string) (int64, int, error) {
+	elements := strings.Split(path, "/")
+
+	var width int
+
+	if n := len(elements); n > 1 && strings.HasSuffix(elements[n-2], partialTileSuffix) {
+		w, err := strconv.Atoi(elements[n-1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: tile width %q is not a number", errors.ErrValidation, elements[n-1])
+		}
+
+		width = w
+		elements = elements[:n-1]
+		elements[n-2] = strings.TrimSuffix(elements[n-2], partialTileSuffix)
+	}
+
+	if len(elements) > maxTileIndexElements {
+		return 0, 0, fmt.Errorf("%w: tile index %q is too long", errors.ErrValidation, path)
+	}
+
+	var index int64
+
+	for i, element := range elements {
+		if i < len(elements)-1 {
+			if !strings.HasPrefix(element, "x") {
+				return 0, 0, fmt.Errorf("%w: tile index %q is not valid", errors.ErrValidation, path)
+			}
+
+			element = element[1:]
+		}
+
+		n, err := strconv.ParseUint(element, 10, 16)
+		if err != nil || len(element) != 3 {
+			return 0, 0, fmt.Errorf("%w: tile index %q is not valid", errors.ErrValidation, path)
+		}
+
+		index = index*1000 + int64(n)
+	}
+
+	if TileIndexPath(index, width) != path {
+		return 0, 0, fmt.Errorf("%w: tile index %q is not canonical", errors.ErrValidation, path)
+	}
+
+	return index, width, nil
+}
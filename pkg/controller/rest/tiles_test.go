@@ -0,0 +1,57 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+func TestTileIndexPath(t *testing.T) {
+	for _, tc := range []struct {
+		index int64
+		width int
+		path  string
+	}{
+		{0, 0, "000"},
+		{5, 0, "005"},
+		{999, 0, "999"},
+		{1000, 0, "x001/000"},
+		{1234067, 0, "x001/x234/067"},
+		{1234067, 12, "x001/x234/067.p/12"},
+		{1 << 56, 255, "x072/x057/x594/x037/x927/936.p/255"},
+	} {
+		require.Equal(t, tc.path, TileIndexPath(tc.index, tc.width))
+
+		index, width, err := ParseTileIndex(tc.path)
+		require.NoError(t, err)
+		require.Equal(t, tc.index, index)
+		require.Equal(t, tc.width, width)
+	}
+}
+
+func TestParseTileIndex(t *testing.T) {
+	for path, msg := range map[string]string{
+		"":                                  "is not valid",
+		"5":                                 "is not valid",
+		"0005":                              "is not valid",
+		"001/005":                           "is not valid",
+		"x00a/005":                          "is not valid",
+		"x000/005":                          "is not canonical",
+		"005.p/0":                           "is not canonical",
+		"005.p/012":                         "is not canonical",
+		"005.p/w":                           "tile width \"w\" is not a number",
+		"x001/x001/x001/x001/x001/x001/001": "is too long",
+	} {
+		_, _, err := ParseTileIndex(path)
+		require.Error(t, err, path)
+		require.Contains(t, err.Error(), msg, path)
+	}
+}
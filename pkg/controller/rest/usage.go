@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// usageRecorder is implemented by the commands which count the requests to the logs per tenant
+// (see command.Cmd.RecordRequest).
+type usageRecorder interface {
+	RecordRequest(alias, operation string, write bool, status int, latency time.Duration)
+}
+
+// recordUsage wraps the handlers of the logs to record their requests if the command counts them.
+func (c *Operation) recordUsage(handlers []Handler) []Handler {
+	recorder, ok := c.cmd.(usageRecorder)
+	if !ok {
+		return handlers
+	}
+
+	for i, h := range handlers {
+		if !strings.HasPrefix(h.Path(), AliasPath+"/") {
+			continue
+		}
+
+		handlers[i] = NewHTTPHandler(h.Path(), h.Method(),
+			recordRequest(recorder, OperationName(h.Path()), h.Method() == http.MethodPost, h.Handle()))
+	}
+
+	return handlers
+}
+
+func recordRequest(recorder usageRecorder, operation string, write bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next(rw, r)
+
+		recorder.RecordRequest(mux.Vars(r)[aliasVarName], operation, write, rw.status, time.Since(start))
+	}
+}
+
+// OperationName returns the name of the operation of the path, e.g. add-vc for /{alias}/v1/add-vc and the other
+// versions of the path, admin/usage for /admin/usage.
+func OperationName(path string) string {
+	if !strings.HasPrefix(path, AliasPath+"/") {
+		return strings.TrimPrefix(path, "/")
+	}
+
+	name := strings.TrimPrefix(path, AliasPath+"/")
+	name = strings.TrimPrefix(name, APIVersion1+"/")
+	name = strings.TrimPrefix(name, APIVersion2+"/")
+	name = strings.TrimPrefix(name, ".well-known/")
+
+	if i := strings.Index(name, "/{"); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+// statusWriter keeps the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// API versions.
+const (
+	// APIVersion1 is the version of the API served under BasePath and the unversioned paths.
+	APIVersion1 = "v1"
+	// APIVersion2 is the version of the API served under V2BasePath, its errors are typed problem details
+	// (ErrorResponseV2).
+	APIVersion2 = "v2"
+)
+
+const (
+	// APIVersionHeader is the header of the version of the API which served the response.
+	APIVersionHeader  = "Vct-Api-Version"
+	deprecationHeader = "Deprecation"
+	linkHeader        = "Link"
+)
+
+// versionHandlers serves the operations of the logs under every version of the API: the v1 handlers are served
+// under V2BasePath as well and under the unversioned paths (e.g. /{alias}/get-sth) for the clients which predate
+// the versioning. The unversioned paths are served as v1 and deprecated in favour of the v1 paths.
+func (c *Operation) versionHandlers(handlers []Handler) []Handler {
+	var versioned []Handler
+
+	for i, h := range handlers {
+		if !strings.HasPrefix(h.Path(), BasePath+"/") {
+			continue
+		}
+
+		path := strings.TrimPrefix(h.Path(), BasePath)
+
+		handlers[i] = NewHTTPHandler(h.Path(), h.Method(), withAPIVersion(APIVersion1, h.Handle()))
+
+		versioned = append(versioned,
+			NewHTTPHandler(V2BasePath+path, h.Method(), withAPIVersion(APIVersion2, h.Handle())),
+			NewHTTPHandler(AliasPath+path, h.Method(), deprecated(withAPIVersion(APIVersion1, h.Handle()))),
+		)
+	}
+
+	return append(handlers, versioned...)
+}
+
+func withAPIVersion(version string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, version)
+
+		next(&versionWriter{ResponseWriter: w, version: version}, r)
+	}
+}
+
+// deprecated marks the responses of the unversioned paths deprecated, the link points to the v1 path.
+func deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		alias := "/" + mux.Vars(r)[aliasVarName]
+
+		w.Header().Set(deprecationHeader, "true")
+		w.Header().Set(linkHeader, "<"+strings.Replace(r.URL.Path, alias, alias+"/"+APIVersion1, 1)+
+			`>; rel="successor-version"`)
+
+		next(w, r)
+	}
+}
+
+// versionWriter keeps the version of the API the response is written for.
+type versionWriter struct {
+	http.ResponseWriter
+	version string
+}
+
+// apiVersion returns the version of the API the response is written for, v1 for the endpoints which are not
+// versioned.
+func apiVersion(w http.ResponseWriter) string {
+	if vw, ok := w.(*versionWriter); ok {
+		return vw.version
+	}
+
+	return APIVersion1
+}
@@ -0,0 +1,244 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package faultinject injects faults (delays, errors and corrupted responses) in the calls of the server to Trillian
+// and to the KMS, so that integrators can test their retry and verification logic against realistic failures.
+// It is meant for test deployments only: the faults are enabled by configuration and changed on demand through
+// the admin endpoint of the injector.
+package faultinject
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+)
+
+var logger = log.New("faultinject")
+
+// AdminPath is the path of the endpoint the rules are retrieved (GET) and replaced (PUT) at.
+const AdminPath = "/admin/faults"
+
+// ErrFault is the error of an injected error fault.
+var ErrFault = errors.New("fault injected")
+
+// Fault is the kind of fault injected in a call.
+type Fault string
+
+const (
+	// Delay delays the call by the delay of the rule.
+	Delay Fault = "delay"
+	// Error fails the call, as the service being unavailable.
+	Error Fault = "error"
+	// Corrupt flips a bit of the data returned by the call (e.g. a root hash, a proof or a signature).
+	Corrupt Fault = "corrupt"
+)
+
+// Rule injects the fault in the calls of the operations matching the pattern (path.Match), the operations are
+// named after the service and the method: trillian.<method> (e.g. trillian.QueueLeaf) and kms.<method>
+// (kms.Create, kms.Get, kms.ExportPubKeyBytes and kms.Sign), e.g. trillian.* matches all the calls to Trillian.
+type Rule struct {
+	Operation string `json:"operation"`
+	Fault     Fault  `json:"fault"`
+	// Delay is the delay of a delay fault (e.g. 2s).
+	Delay string `json:"delay,omitempty"`
+	// Probability is the probability of the fault in a call, within (0,1]. The fault is injected in all the calls
+	// if not set.
+	Probability float64 `json:"probability,omitempty"`
+
+	delay time.Duration
+}
+
+func (r *Rule) validate() error {
+	if _, err := path.Match(r.Operation, ""); err != nil || r.Operation == "" {
+		return fmt.Errorf("operation %q is not a valid pattern", r.Operation)
+	}
+
+	switch r.Fault {
+	case Delay:
+		delay, err := time.ParseDuration(r.Delay)
+		if err != nil || delay <= 0 {
+			return fmt.Errorf("delay %q of operation %s is not a valid duration", r.Delay, r.Operation)
+		}
+
+		r.delay = delay
+	case Error, Corrupt:
+	default:
+		return fmt.Errorf("fault %q of operation %s is not supported", r.Fault, r.Operation)
+	}
+
+	if r.Probability < 0 || r.Probability > 1 {
+		return fmt.Errorf("probability %v of operation %s is not within (0,1]", r.Probability, r.Operation)
+	}
+
+	return nil
+}
+
+// ParseRules parses the comma-separated rules <operation>:<fault>[:<probability>], the fault of a delay is
+// delay=<duration>. Example: trillian.QueueLeaf:error:0.1,trillian.Get*:delay=2s,kms.Sign:corrupt.
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+
+	for _, ruleStr := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(ruleStr), ":")
+		if len(parts) < 2 || len(parts) > 3 { // nolint: gomnd
+			return nil, fmt.Errorf("rule %q must be <operation>:<fault>[:<probability>]", ruleStr)
+		}
+
+		rule := Rule{Operation: parts[0], Fault: Fault(parts[1])}
+
+		if delay := strings.TrimPrefix(parts[1], string(Delay)+"="); delay != parts[1] {
+			rule.Fault, rule.Delay = Delay, delay
+		}
+
+		if len(parts) == 3 { // nolint: gomnd
+			probability, err := strconv.ParseFloat(parts[2], 64)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: probability is not a number", ruleStr)
+			}
+
+			rule.Probability = probability
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Injector injects the faults of its rules in the calls of the wrapped clients.
+type Injector struct {
+	mu    sync.Mutex
+	rules []Rule
+	rand  *rand.Rand
+}
+
+// New returns the injector of the rules.
+func New(rules []Rule) (*Injector, error) {
+	i := &Injector{rand: rand.New(rand.NewSource(time.Now().UnixNano()))} // nolint: gosec
+
+	if err := i.SetRules(rules); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Rules returns the rules.
+func (i *Injector) Rules() []Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return append([]Rule{}, i.rules...)
+}
+
+// SetRules replaces the rules.
+func (i *Injector) SetRules(rules []Rule) error {
+	for j := range rules {
+		if err := rules[j].validate(); err != nil {
+			return err
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.rules = append([]Rule{}, rules...)
+
+	return nil
+}
+
+// inject injects the faults of the operation: the call is delayed, failed with the error returned,
+// and its response is to be corrupted if corrupt is set.
+func (i *Injector) inject(ctx context.Context, operation string) (corrupt bool, err error) {
+	var delay time.Duration
+
+	i.mu.Lock()
+
+	for _, rule := range i.rules {
+		if ok, _ := path.Match(rule.Operation, operation); !ok { // nolint: errcheck
+			continue
+		}
+
+		if rule.Probability > 0 && i.rand.Float64() >= rule.Probability {
+			continue
+		}
+
+		switch rule.Fault {
+		case Delay:
+			delay += rule.delay
+		case Error:
+			err = fmt.Errorf("%w in %s", ErrFault, operation)
+		case Corrupt:
+			corrupt = true
+		}
+	}
+
+	i.mu.Unlock()
+
+	if delay > 0 {
+		logger.Debugf("Delaying %s by %s", operation, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return false, ctx.Err() // nolint: wrapcheck
+		}
+	}
+
+	return corrupt, err
+}
+
+// ServeHTTP serves the admin endpoint: GET returns the rules, PUT replaces them.
+func (i *Injector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodPut {
+		var rules []Rule
+
+		err := json.NewDecoder(r.Body).Decode(&rules)
+		if err == nil {
+			err = i.SetRules(rules)
+		}
+
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			if er := json.NewEncoder(w).Encode(map[string]string{"message": err.Error()}); er != nil {
+				logger.Errorf("write error: %v", er)
+			}
+
+			return
+		}
+
+		logger.Warnf("Fault injection rules replaced: %d rules", len(rules))
+	}
+
+	if err := json.NewEncoder(w).Encode(i.Rules()); err != nil {
+		logger.Errorf("write rules: %v", err)
+	}
+}
+
+// flip flips the last bit of the data, in a copy.
+func flip(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)-1] ^= 1
+
+	return corrupted
+}
@@ -0,0 +1,208 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package faultinject_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/trustbloc/vct/pkg/faultinject"
+)
+
+type logClient struct {
+	trillian.TrillianLogClient
+	root []byte
+}
+
+func (c *logClient) GetLatestSignedLogRoot(context.Context, *trillian.GetLatestSignedLogRootRequest,
+	...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	return &trillian.GetLatestSignedLogRootResponse{
+		SignedLogRoot: &trillian.SignedLogRoot{LogRoot: c.root},
+	}, nil
+}
+
+func (c *logClient) GetInclusionProof(context.Context, *trillian.GetInclusionProofRequest,
+	...grpc.CallOption) (*trillian.GetInclusionProofResponse, error) {
+	return &trillian.GetInclusionProofResponse{Proof: &trillian.Proof{Hashes: [][]byte{{0, 0}}}}, nil
+}
+
+type keyManager struct{}
+
+func (k *keyManager) Create(kms.KeyType) (string, interface{}, error) {
+	return "kid", nil, nil
+}
+
+func (k *keyManager) Get(string) (interface{}, error) {
+	return "kh", nil
+}
+
+func (k *keyManager) ExportPubKeyBytes(string) ([]byte, kms.KeyType, error) {
+	return []byte{2, 2}, kms.ECDSAP256TypeIEEEP1363, nil
+}
+
+type signer struct{}
+
+func (s *signer) Sign([]byte, interface{}) ([]byte, error) {
+	return []byte{4, 4}, nil
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("trillian.QueueLeaf:error:0.1, trillian.Get*:delay=2s,kms.Sign:corrupt")
+	require.NoError(t, err)
+	require.Equal(t, []Rule{
+		{Operation: "trillian.QueueLeaf", Fault: Error, Probability: 0.1},
+		{Operation: "trillian.Get*", Fault: Delay, Delay: "2s"},
+		{Operation: "kms.Sign", Fault: Corrupt},
+	}, rules)
+
+	_, err = New(rules)
+	require.NoError(t, err)
+
+	_, err = ParseRules("trillian.QueueLeaf")
+	require.EqualError(t, err, `rule "trillian.QueueLeaf" must be <operation>:<fault>[:<probability>]`)
+
+	_, err = ParseRules("trillian.QueueLeaf:error:often")
+	require.EqualError(t, err, `rule "trillian.QueueLeaf:error:often": probability is not a number`)
+
+	for spec, msg := range map[string]string{
+		"trillian.QueueLeaf:drop":      `fault "drop" of operation trillian.QueueLeaf is not supported`,
+		"trillian.QueueLeaf:delay=x":   `delay "x" of operation trillian.QueueLeaf is not a valid duration`,
+		"trillian.QueueLeaf:error:1.5": `probability 1.5 of operation trillian.QueueLeaf is not within (0,1]`,
+		"[:error":                      `operation "[" is not a valid pattern`,
+	} {
+		rules, err = ParseRules(spec)
+		require.NoError(t, err)
+
+		_, err = New(rules)
+		require.EqualError(t, err, msg)
+	}
+}
+
+func TestInjector_LogClient(t *testing.T) {
+	root, err := (&types.LogRootV1{TreeSize: 1, RootHash: []byte{1, 1}}).MarshalBinary()
+	require.NoError(t, err)
+
+	injector, err := New(nil)
+	require.NoError(t, err)
+
+	client := injector.LogClient(&logClient{root: root})
+
+	resp, err := client.GetLatestSignedLogRoot(context.Background(), &trillian.GetLatestSignedLogRootRequest{})
+	require.NoError(t, err)
+	require.Equal(t, root, resp.SignedLogRoot.LogRoot)
+
+	t.Run("Error", func(t *testing.T) {
+		require.NoError(t, injector.SetRules([]Rule{{Operation: "trillian.*", Fault: Error}}))
+
+		_, err = client.GetLatestSignedLogRoot(context.Background(), &trillian.GetLatestSignedLogRootRequest{})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		require.Contains(t, err.Error(), "fault injected in trillian.GetLatestSignedLogRoot")
+	})
+
+	t.Run("Corrupt", func(t *testing.T) {
+		require.NoError(t, injector.SetRules([]Rule{{Operation: "trillian.*", Fault: Corrupt}}))
+
+		resp, err = client.GetLatestSignedLogRoot(context.Background(), &trillian.GetLatestSignedLogRootRequest{})
+		require.NoError(t, err)
+
+		var logRoot types.LogRootV1
+		require.NoError(t, logRoot.UnmarshalBinary(resp.SignedLogRoot.LogRoot))
+		require.Equal(t, []byte{1, 0}, logRoot.RootHash)
+		require.Equal(t, uint64(1), logRoot.TreeSize)
+
+		proof, err := client.GetInclusionProof(context.Background(), &trillian.GetInclusionProofRequest{})
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{{0, 1}}, proof.Proof.Hashes)
+	})
+
+	t.Run("Delay", func(t *testing.T) {
+		require.NoError(t, injector.SetRules([]Rule{{Operation: "trillian.GetLatest*", Fault: Delay, Delay: "1h"}}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err = client.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{})
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+		// other operations are not delayed
+		_, err = client.GetInclusionProof(ctx, &trillian.GetInclusionProofRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("Probability", func(t *testing.T) {
+		require.NoError(t, injector.SetRules([]Rule{{Operation: "*", Fault: Error, Probability: 0.5}}))
+
+		var failed int
+
+		for i := 0; i < 200; i++ {
+			if _, err = client.GetInclusionProof(context.Background(), &trillian.GetInclusionProofRequest{}); err != nil {
+				failed++
+			}
+		}
+
+		require.Greater(t, failed, 0)
+		require.Less(t, failed, 200)
+	})
+}
+
+func TestInjector_KMS(t *testing.T) {
+	injector, err := New([]Rule{{Operation: "kms.Sign", Fault: Corrupt}, {Operation: "kms.Get", Fault: Error}})
+	require.NoError(t, err)
+
+	km, cr := injector.KeyManager(&keyManager{}), injector.Crypto(&signer{})
+
+	signature, err := cr.Sign([]byte("msg"), "kh")
+	require.NoError(t, err)
+	require.Equal(t, []byte{4, 5}, signature)
+
+	_, err = km.Get("kid")
+	require.EqualError(t, err, "fault injected in kms.Get")
+
+	pubKey, _, err := km.ExportPubKeyBytes("kid")
+	require.NoError(t, err)
+	require.Equal(t, []byte{2, 2}, pubKey)
+
+	kid, _, err := km.Create(kms.ED25519Type)
+	require.NoError(t, err)
+	require.Equal(t, "kid", kid)
+}
+
+func TestInjector_ServeHTTP(t *testing.T) {
+	injector, err := New(nil)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	injector.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, AdminPath,
+		bytes.NewBufferString(`[{"operation":"kms.Sign","fault":"error"}]`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, []Rule{{Operation: "kms.Sign", Fault: Error}}, injector.Rules())
+
+	rec = httptest.NewRecorder()
+	injector.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, AdminPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `[{"operation":"kms.Sign","fault":"error"}]`+"\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	injector.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, AdminPath,
+		bytes.NewBufferString(`[{"operation":"kms.Sign","fault":"drop"}]`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `fault \"drop\" of operation kms.Sign is not supported`)
+	require.Len(t, injector.Rules(), 1)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package faultinject
+
+import (
+	"context"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+const kmsService = "kms."
+
+// KeyManager is the key manager the faults are injected in, as command.KeyManager.
+type KeyManager interface {
+	Create(kt kms.KeyType) (string, interface{}, error)
+	Get(keyID string) (interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error)
+}
+
+// Crypto is the crypto the faults are injected in, as command.Crypto.
+type Crypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+type keyManager struct {
+	KeyManager
+	injector *Injector
+}
+
+type crypto struct {
+	Crypto
+	injector *Injector
+}
+
+// KeyManager returns the key manager injecting the faults in the calls to the key manager, the corruptions flip
+// a bit of the exported public key.
+func (i *Injector) KeyManager(km KeyManager) KeyManager {
+	return &keyManager{KeyManager: km, injector: i}
+}
+
+// Crypto returns the crypto injecting the faults in the calls to the crypto, the corruptions flip a bit of
+// the signature.
+func (i *Injector) Crypto(cr Crypto) Crypto {
+	return &crypto{Crypto: cr, injector: i}
+}
+
+func (k *keyManager) Create(kt kms.KeyType) (string, interface{}, error) {
+	if _, err := k.injector.inject(context.Background(), kmsService+"Create"); err != nil {
+		return "", nil, err
+	}
+
+	return k.KeyManager.Create(kt) // nolint: wrapcheck
+}
+
+func (k *keyManager) Get(keyID string) (interface{}, error) {
+	if _, err := k.injector.inject(context.Background(), kmsService+"Get"); err != nil {
+		return nil, err
+	}
+
+	return k.KeyManager.Get(keyID) // nolint: wrapcheck
+}
+
+func (k *keyManager) ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error) {
+	corrupt, err := k.injector.inject(context.Background(), kmsService+"ExportPubKeyBytes")
+	if err != nil {
+		return nil, "", err
+	}
+
+	pubKey, kt, err := k.KeyManager.ExportPubKeyBytes(keyID)
+	if err == nil && corrupt {
+		pubKey = flip(pubKey)
+	}
+
+	return pubKey, kt, err // nolint: wrapcheck
+}
+
+func (c *crypto) Sign(msg []byte, kh interface{}) ([]byte, error) {
+	corrupt, err := c.injector.inject(context.Background(), kmsService+"Sign")
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := c.Crypto.Sign(msg, kh)
+	if err == nil && corrupt {
+		signature = flip(signature)
+	}
+
+	return signature, err // nolint: wrapcheck
+}
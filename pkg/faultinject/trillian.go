@@ -0,0 +1,191 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package faultinject
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const trillianService = "trillian."
+
+// logClient injects the faults in the calls to the Trillian log, the errors are Unavailable as of a Trillian
+// server down and the corruptions flip a bit of the root hash, of the proof or of the leaves returned.
+type logClient struct {
+	trillian.TrillianLogClient
+	injector *Injector
+}
+
+// LogClient returns the client injecting the faults in the calls to the client.
+func (i *Injector) LogClient(client trillian.TrillianLogClient) trillian.TrillianLogClient {
+	return &logClient{TrillianLogClient: client, injector: i}
+}
+
+func (c *logClient) inject(ctx context.Context, method string) (bool, error) {
+	corrupt, err := c.injector.inject(ctx, trillianService+method)
+	if errors.Is(err, ErrFault) {
+		return false, status.Error(codes.Unavailable, err.Error()) // nolint: wrapcheck
+	}
+
+	return corrupt, err
+}
+
+func (c *logClient) QueueLeaf(ctx context.Context, in *trillian.QueueLeafRequest,
+	opts ...grpc.CallOption) (*trillian.QueueLeafResponse, error) {
+	corrupt, err := c.inject(ctx, "QueueLeaf")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.QueueLeaf(ctx, in, opts...)
+	if err == nil && corrupt && resp.QueuedLeaf != nil && resp.QueuedLeaf.Leaf != nil {
+		resp.QueuedLeaf.Leaf.MerkleLeafHash = flip(resp.QueuedLeaf.Leaf.MerkleLeafHash)
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func (c *logClient) GetInclusionProof(ctx context.Context, in *trillian.GetInclusionProofRequest,
+	opts ...grpc.CallOption) (*trillian.GetInclusionProofResponse, error) {
+	corrupt, err := c.inject(ctx, "GetInclusionProof")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.GetInclusionProof(ctx, in, opts...)
+	if err == nil && corrupt {
+		corruptProof(resp.Proof)
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func (c *logClient) GetInclusionProofByHash(ctx context.Context, in *trillian.GetInclusionProofByHashRequest,
+	opts ...grpc.CallOption) (*trillian.GetInclusionProofByHashResponse, error) {
+	corrupt, err := c.inject(ctx, "GetInclusionProofByHash")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.GetInclusionProofByHash(ctx, in, opts...)
+	if err == nil && corrupt {
+		for _, proof := range resp.Proof {
+			corruptProof(proof)
+		}
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func (c *logClient) GetConsistencyProof(ctx context.Context, in *trillian.GetConsistencyProofRequest,
+	opts ...grpc.CallOption) (*trillian.GetConsistencyProofResponse, error) {
+	corrupt, err := c.inject(ctx, "GetConsistencyProof")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.GetConsistencyProof(ctx, in, opts...)
+	if err == nil && corrupt {
+		corruptProof(resp.Proof)
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func (c *logClient) GetLatestSignedLogRoot(ctx context.Context, in *trillian.GetLatestSignedLogRootRequest,
+	opts ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	corrupt, err := c.inject(ctx, "GetLatestSignedLogRoot")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.GetLatestSignedLogRoot(ctx, in, opts...)
+	if err == nil && corrupt {
+		corruptLogRoot(resp.SignedLogRoot)
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func (c *logClient) GetEntryAndProof(ctx context.Context, in *trillian.GetEntryAndProofRequest,
+	opts ...grpc.CallOption) (*trillian.GetEntryAndProofResponse, error) {
+	corrupt, err := c.inject(ctx, "GetEntryAndProof")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.GetEntryAndProof(ctx, in, opts...)
+	if err == nil && corrupt {
+		corruptProof(resp.Proof)
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func (c *logClient) InitLog(ctx context.Context, in *trillian.InitLogRequest,
+	opts ...grpc.CallOption) (*trillian.InitLogResponse, error) {
+	if _, err := c.inject(ctx, "InitLog"); err != nil {
+		return nil, err
+	}
+
+	return c.TrillianLogClient.InitLog(ctx, in, opts...) // nolint: wrapcheck
+}
+
+func (c *logClient) AddSequencedLeaves(ctx context.Context, in *trillian.AddSequencedLeavesRequest,
+	opts ...grpc.CallOption) (*trillian.AddSequencedLeavesResponse, error) {
+	if _, err := c.inject(ctx, "AddSequencedLeaves"); err != nil {
+		return nil, err
+	}
+
+	return c.TrillianLogClient.AddSequencedLeaves(ctx, in, opts...) // nolint: wrapcheck
+}
+
+func (c *logClient) GetLeavesByRange(ctx context.Context, in *trillian.GetLeavesByRangeRequest,
+	opts ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	corrupt, err := c.inject(ctx, "GetLeavesByRange")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.TrillianLogClient.GetLeavesByRange(ctx, in, opts...)
+	if err == nil && corrupt && len(resp.Leaves) > 0 {
+		resp.Leaves[0].LeafValue = flip(resp.Leaves[0].LeafValue)
+	}
+
+	return resp, err // nolint: wrapcheck
+}
+
+func corruptProof(proof *trillian.Proof) {
+	if proof != nil && len(proof.Hashes) > 0 {
+		proof.Hashes[0] = flip(proof.Hashes[0])
+	}
+}
+
+// corruptLogRoot flips a bit of the root hash, the root is returned as is if it can not be decoded.
+func corruptLogRoot(root *trillian.SignedLogRoot) {
+	if root == nil {
+		return
+	}
+
+	var logRoot types.LogRootV1
+
+	if err := logRoot.UnmarshalBinary(root.LogRoot); err != nil {
+		return
+	}
+
+	logRoot.RootHash = flip(logRoot.RootHash)
+
+	if data, err := logRoot.MarshalBinary(); err == nil {
+		root.LogRoot = data
+	}
+}
@@ -0,0 +1,491 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package ldloader implements the JSON-LD document loader the credentials of a log are canonicalized and verified
+// with. The context documents are pinned: the documents vendored in the binary and the documents set by the
+// operator are always served for their URLs and can't be replaced by a remote provider or a fetched document.
+// Fetching the other contexts from their URL is off by default, so a submission can't make the log depend on the
+// availability or the content of a remote host.
+package ldloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ldcontext"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ldcontext/embed"
+	jsonld "github.com/piprate/json-gold/ld"
+	"golang.org/x/sync/singleflight"
+)
+
+// FetchPolicy is the policy of the fetches of the context documents which are neither pinned nor stored.
+type FetchPolicy string
+
+// Fetch policies.
+const (
+	// FetchOff never fetches a context document (the default).
+	FetchOff FetchPolicy = "off"
+	// FetchAllowlist fetches the context documents of the URLs under an allowed prefix only: the URL has the scheme
+	// and the host of the prefix and its path starts with the path segments of the prefix.
+	FetchAllowlist FetchPolicy = "allowlist"
+	// FetchOn fetches any context document.
+	FetchOn FetchPolicy = "on"
+)
+
+// Defaults of the fetches.
+const (
+	DefaultFetchTimeout    = 5 * time.Second
+	DefaultMaxDocumentSize = 1 << 20
+	DefaultCacheTTL        = time.Hour
+	DefaultFailureTTL      = time.Minute
+	DefaultCacheSize       = 1000
+)
+
+const maxRedirects = 10
+
+var logger = log.New("ldloader")
+
+// ErrFetchDenied is returned for a context document which is not pinned nor stored and which the fetch policy
+// doesn't allow to fetch.
+var ErrFetchDenied = errors.New("context document is not pinned and fetching it is not allowed")
+
+// HTTPClient represents HTTP client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type options struct {
+	pinned          []ldcontext.Document
+	policy          FetchPolicy
+	allowed         []string
+	http            HTTPClient
+	maxDocumentSize int64
+	cacheTTL        time.Duration
+	failureTTL      time.Duration
+	cacheSize       int
+}
+
+// Opt represents loader option func.
+type Opt func(*options)
+
+// WithPinnedContexts pins the context documents in addition to the documents vendored in the binary, a document
+// replaces the vendored document of its URL.
+func WithPinnedContexts(docs ...ldcontext.Document) Opt {
+	return func(o *options) {
+		o.pinned = append(o.pinned, docs...)
+	}
+}
+
+// WithFetchPolicy sets the policy of the fetches (FetchOff by default), the allowed URL prefixes apply to
+// FetchAllowlist and to the redirects of the fetches.
+func WithFetchPolicy(policy FetchPolicy, allowed ...string) Opt {
+	return func(o *options) {
+		o.policy = policy
+		o.allowed = allowed
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the fetches, by default a client timing out after DefaultFetchTimeout.
+func WithHTTPClient(client HTTPClient) Opt {
+	return func(o *options) {
+		o.http = client
+	}
+}
+
+// WithMaxDocumentSize sets the max size in bytes of a fetched document (DefaultMaxDocumentSize by default).
+func WithMaxDocumentSize(size int64) Opt {
+	return func(o *options) {
+		o.maxDocumentSize = size
+	}
+}
+
+// WithCache sets the time the fetched documents and the failed fetches are cached for and the max number of
+// cached documents (DefaultCacheTTL, DefaultFailureTTL and DefaultCacheSize by default).
+func WithCache(ttl, failureTTL time.Duration, size int) Opt {
+	return func(o *options) {
+		o.cacheTTL = ttl
+		o.failureTTL = failureTTL
+		o.cacheSize = size
+	}
+}
+
+// Loader loads the context documents: the pinned documents first, then the documents of the store (e.g. the
+// contexts of the remote providers) and finally, if the fetch policy allows it, the documents fetched from their
+// URL. The fetched documents and the failed fetches are cached, concurrent fetches of a URL are shared.
+type Loader struct {
+	pinned          map[string]*jsonld.RemoteDocument
+	store           jsonld.DocumentLoader
+	policy          FetchPolicy
+	allowed         []*prefix
+	http            HTTPClient
+	maxDocumentSize int64
+	cacheTTL        time.Duration
+	failureTTL      time.Duration
+	cacheSize       int
+
+	mu    sync.Mutex
+	cache map[string]*cached
+	group singleflight.Group
+}
+
+// prefix is an allowed URL prefix, parsed.
+type prefix struct {
+	scheme   string
+	host     string
+	segments []string
+}
+
+type cached struct {
+	doc     *jsonld.RemoteDocument
+	err     error
+	expires time.Time
+}
+
+// New returns the loader of the pinned documents and of the documents of the store, the store may be nil.
+func New(store jsonld.DocumentLoader, opts ...Opt) (*Loader, error) {
+	op := &options{
+		policy:          FetchOff,
+		http:            &http.Client{Timeout: DefaultFetchTimeout},
+		maxDocumentSize: DefaultMaxDocumentSize,
+		cacheTTL:        DefaultCacheTTL,
+		failureTTL:      DefaultFailureTTL,
+		cacheSize:       DefaultCacheSize,
+	}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	switch op.policy {
+	case FetchOff, FetchOn:
+	case FetchAllowlist:
+		if len(op.allowed) == 0 {
+			return nil, errors.New("fetch policy allowlist requires allowed URLs")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported fetch policy: %s", op.policy)
+	}
+
+	allowed := make([]*prefix, 0, len(op.allowed))
+
+	for _, u := range op.allowed {
+		p, err := parsePrefix(u)
+		if err != nil {
+			return nil, err
+		}
+
+		allowed = append(allowed, p)
+	}
+
+	l := &Loader{
+		pinned:          map[string]*jsonld.RemoteDocument{},
+		store:           store,
+		policy:          op.policy,
+		allowed:         allowed,
+		maxDocumentSize: op.maxDocumentSize,
+		cacheTTL:        op.cacheTTL,
+		failureTTL:      op.failureTTL,
+		cacheSize:       op.cacheSize,
+		cache:           map[string]*cached{},
+	}
+
+	l.http = l.checkRedirects(op.http)
+
+	for _, doc := range append(append([]ldcontext.Document(nil), embed.Contexts...), op.pinned...) {
+		remote, err := remoteDocument(doc)
+		if err != nil {
+			return nil, fmt.Errorf("pinned context %s: %w", doc.URL, err)
+		}
+
+		l.pinned[doc.URL] = remote
+	}
+
+	return l, nil
+}
+
+// ReadPinnedContexts reads the context documents of the JSON files of the directory (a document per file, see
+// ldcontext.Document).
+func ReadPinnedContexts(dir string) ([]ldcontext.Document, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list contexts: %w", err)
+	}
+
+	docs := make([]ldcontext.Document, 0, len(files))
+
+	for _, file := range files {
+		src, er := os.ReadFile(filepath.Clean(file))
+		if er != nil {
+			return nil, fmt.Errorf("read context: %w", er)
+		}
+
+		var doc ldcontext.Document
+
+		if er = json.Unmarshal(src, &doc); er != nil || doc.URL == "" || len(doc.Content) == 0 {
+			return nil, fmt.Errorf("context %s is not a context document (url, documentURL and content)",
+				filepath.Base(file))
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// LoadDocument loads the context document of the URL.
+func (l *Loader) LoadDocument(u string) (*jsonld.RemoteDocument, error) {
+	if doc, ok := l.pinned[u]; ok {
+		return doc, nil
+	}
+
+	if l.store != nil {
+		doc, err := l.store.LoadDocument(u)
+		if err == nil {
+			return doc, nil
+		}
+
+		if !errors.Is(err, ld.ErrContextNotFound) {
+			return nil, err // nolint: wrapcheck
+		}
+	}
+
+	if !l.allows(u) {
+		return nil, fmt.Errorf("%w: %s", ErrFetchDenied, u)
+	}
+
+	if entry, ok := l.cached(u); ok {
+		return entry.doc, entry.err
+	}
+
+	v, err, _ := l.group.Do(u, func() (interface{}, error) {
+		doc, er := l.fetch(u)
+
+		l.remember(u, doc, er)
+
+		return doc, er
+	})
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	return v.(*jsonld.RemoteDocument), nil // nolint: forcetypeassert
+}
+
+func (l *Loader) allows(u string) bool {
+	switch l.policy {
+	case FetchOn:
+		return true
+	case FetchAllowlist:
+		parsed, err := url.Parse(u)
+		if err != nil {
+			return false
+		}
+
+		for _, p := range l.allowed {
+			if p.matches(parsed) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkRedirects returns the client checking that the fetch policy allows each redirect, a client other than an
+// *http.Client is used as is.
+func (l *Loader) checkRedirects(client HTTPClient) HTTPClient {
+	httpClient, ok := client.(*http.Client)
+	if !ok {
+		return client
+	}
+
+	checked := *httpClient
+	checked.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !l.allows(req.URL.String()) {
+			return fmt.Errorf("%w: redirect to %s", ErrFetchDenied, req.URL)
+		}
+
+		if httpClient.CheckRedirect != nil {
+			return httpClient.CheckRedirect(req, via)
+		}
+
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		return nil
+	}
+
+	return &checked
+}
+
+func parsePrefix(u string) (*prefix, error) {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, fmt.Errorf("allowed URL %s is not an HTTP URL", u)
+	}
+
+	return &prefix{
+		scheme:   parsed.Scheme,
+		host:     strings.ToLower(parsed.Host),
+		segments: segments(strings.TrimSuffix(parsed.Path, "/")),
+	}, nil
+}
+
+// matches reports whether the URL has the scheme and the host of the prefix and whether its path starts with the
+// path segments of the prefix. A path with dot segments never matches.
+func (p *prefix) matches(u *url.URL) bool {
+	if !strings.EqualFold(u.Scheme, p.scheme) || strings.ToLower(u.Host) != p.host || u.User != nil {
+		return false
+	}
+
+	path := segments(u.Path)
+	if len(path) < len(p.segments) {
+		return false
+	}
+
+	for _, segment := range path {
+		if segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	for i, segment := range p.segments {
+		if path[i] != segment {
+			return false
+		}
+	}
+
+	return true
+}
+
+// segments returns the segments of the path, without the leading slash.
+func segments(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+func (l *Loader) cached(u string) (*cached, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.cache[u]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// remember caches the fetched document or the failed fetch, the expired entries are evicted when the cache is full
+// and nothing is cached if it is still full.
+func (l *Loader) remember(u string, doc *jsonld.RemoteDocument, err error) {
+	ttl := l.cacheTTL
+	if err != nil {
+		ttl = l.failureTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.cache) >= l.cacheSize {
+		for key, entry := range l.cache {
+			if now.After(entry.expires) {
+				delete(l.cache, key)
+			}
+		}
+	}
+
+	if len(l.cache) >= l.cacheSize {
+		return
+	}
+
+	l.cache[u] = &cached{doc: doc, err: err, expires: now.Add(ttl)}
+}
+
+func (l *Loader) fetch(u string) (*jsonld.RemoteDocument, error) {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return nil, fmt.Errorf("fetch context %s: not an HTTP URL", u)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch context %s: %w", u, err)
+	}
+
+	req.Header.Set("Accept", "application/ld+json, application/json")
+
+	resp, err := l.http.Do(req)
+	if err != nil {
+		logger.Warnf("failed to fetch context %s: %v", u, err)
+
+		return nil, fmt.Errorf("fetch context %s: %w", u, err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch context %s: status %d", u, resp.StatusCode)
+	}
+
+	src, err := ioutil.ReadAll(io.LimitReader(resp.Body, l.maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch context %s: %w", u, err)
+	}
+
+	if int64(len(src)) > l.maxDocumentSize {
+		return nil, fmt.Errorf("fetch context %s: document exceeds %d bytes", u, l.maxDocumentSize)
+	}
+
+	documentURL := u
+	if resp.Request != nil && resp.Request.URL != nil {
+		documentURL = resp.Request.URL.String()
+	}
+
+	doc, err := remoteDocument(ldcontext.Document{URL: u, DocumentURL: documentURL, Content: src})
+	if err != nil {
+		return nil, fmt.Errorf("fetch context %s: %w", u, err)
+	}
+
+	return doc, nil
+}
+
+func remoteDocument(doc ldcontext.Document) (*jsonld.RemoteDocument, error) {
+	content, err := jsonld.DocumentFromReader(strings.NewReader(string(doc.Content)))
+	if err != nil {
+		return nil, fmt.Errorf("parse document: %w", err)
+	}
+
+	documentURL := doc.DocumentURL
+	if documentURL == "" {
+		documentURL = doc.URL
+	}
+
+	return &jsonld.RemoteDocument{DocumentURL: documentURL, Document: content}, nil
+}
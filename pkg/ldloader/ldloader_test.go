@@ -0,0 +1,253 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ldloader_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/ldcontext"
+	jsonld "github.com/piprate/json-gold/ld"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/ldloader"
+)
+
+const credentialsV1 = "https://www.w3.org/2018/credentials/v1"
+
+type storeLoader map[string]*jsonld.RemoteDocument
+
+func (s storeLoader) LoadDocument(u string) (*jsonld.RemoteDocument, error) {
+	if doc, ok := s[u]; ok {
+		return doc, nil
+	}
+
+	if u == "https://example.com/broken" {
+		return nil, errors.New("store is down")
+	}
+
+	return nil, ld.ErrContextNotFound
+}
+
+func contextServer(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+
+		switch r.URL.Path {
+		case "/context":
+			w.Header().Set("Content-Type", "application/ld+json")
+			w.Write([]byte(`{"@context":{"name":"https://schema.org/name"}}`)) // nolint: errcheck,gosec
+		case "/large":
+			w.Write([]byte(`{"@context":{"name":"` + strings.Repeat("a", 1024) + `"}}`)) // nolint: errcheck,gosec
+		case "/redirect/context":
+			http.Redirect(w, r, "/context", http.StatusFound)
+		case "/redirect/large":
+			http.Redirect(w, r, "/large", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func TestLoader_LoadDocument(t *testing.T) {
+	t.Run("Pinned", func(t *testing.T) {
+		var hits int32
+
+		ts := contextServer(t, &hits)
+
+		store := storeLoader{credentialsV1: {DocumentURL: "https://attacker.example.com"}}
+
+		loader, err := New(store, WithFetchPolicy(FetchOn), WithHTTPClient(ts.Client()),
+			WithPinnedContexts(ldcontext.Document{
+				URL:     ts.URL + "/context",
+				Content: json.RawMessage(`{"@context":{"pinned":"https://example.com/pinned"}}`),
+			}),
+		)
+		require.NoError(t, err)
+
+		doc, err := loader.LoadDocument(credentialsV1)
+		require.NoError(t, err)
+		require.Equal(t, credentialsV1, doc.DocumentURL)
+
+		doc, err = loader.LoadDocument(ts.URL + "/context")
+		require.NoError(t, err)
+		require.Contains(t, doc.Document.(map[string]interface{})["@context"], "pinned")
+		require.Zero(t, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("Store", func(t *testing.T) {
+		store := storeLoader{"https://example.com/stored": {DocumentURL: "https://example.com/stored"}}
+
+		loader, err := New(store)
+		require.NoError(t, err)
+
+		doc, err := loader.LoadDocument("https://example.com/stored")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/stored", doc.DocumentURL)
+
+		_, err = loader.LoadDocument("https://example.com/broken")
+		require.EqualError(t, err, "store is down")
+	})
+
+	t.Run("Fetch off", func(t *testing.T) {
+		var hits int32
+
+		ts := contextServer(t, &hits)
+
+		loader, err := New(storeLoader{}, WithHTTPClient(ts.Client()))
+		require.NoError(t, err)
+
+		_, err = loader.LoadDocument(ts.URL + "/context")
+		require.True(t, errors.Is(err, ErrFetchDenied))
+		require.Zero(t, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("Fetch allowlist", func(t *testing.T) {
+		var hits int32
+
+		ts := contextServer(t, &hits)
+
+		loader, err := New(nil, WithFetchPolicy(FetchAllowlist, ts.URL+"/context"), WithHTTPClient(ts.Client()))
+		require.NoError(t, err)
+
+		doc, err := loader.LoadDocument(ts.URL + "/context")
+		require.NoError(t, err)
+		require.Equal(t, ts.URL+"/context", doc.DocumentURL)
+
+		_, err = loader.LoadDocument(ts.URL + "/large")
+		require.True(t, errors.Is(err, ErrFetchDenied))
+
+		// cached
+		_, err = loader.LoadDocument(ts.URL + "/context")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("Fetch allowlist segments", func(t *testing.T) {
+		var hits int32
+
+		ts := contextServer(t, &hits)
+
+		loader, err := New(nil, WithFetchPolicy(FetchAllowlist, ts.URL+"/context", ts.URL+"/redirect/"),
+			WithHTTPClient(ts.Client()))
+		require.NoError(t, err)
+
+		for _, u := range []string{
+			ts.URL + "/contextual",
+			ts.URL + "/context/../large",
+			ts.URL + "@attacker.example.com/context",
+			strings.Replace(ts.URL, "http://", "https://", 1) + "/context",
+			"https://attacker.example.com/?" + ts.URL + "/context",
+		} {
+			_, err = loader.LoadDocument(u)
+			require.True(t, errors.Is(err, ErrFetchDenied), u)
+		}
+
+		require.Zero(t, atomic.LoadInt32(&hits))
+
+		doc, err := loader.LoadDocument(ts.URL + "/redirect/context")
+		require.NoError(t, err)
+		require.Equal(t, ts.URL+"/context", doc.DocumentURL)
+
+		_, err = loader.LoadDocument(ts.URL + "/redirect/large")
+		require.True(t, errors.Is(err, ErrFetchDenied))
+		require.Contains(t, err.Error(), "redirect to "+ts.URL+"/large")
+		require.Equal(t, int32(3), atomic.LoadInt32(&hits))
+	})
+
+	t.Run("Fetch errors", func(t *testing.T) {
+		var hits int32
+
+		ts := contextServer(t, &hits)
+
+		loader, err := New(nil, WithFetchPolicy(FetchOn), WithHTTPClient(ts.Client()), WithMaxDocumentSize(512),
+			WithCache(time.Hour, time.Hour, 10))
+		require.NoError(t, err)
+
+		_, err = loader.LoadDocument(ts.URL + "/large")
+		require.Contains(t, err.Error(), "document exceeds 512 bytes")
+
+		_, err = loader.LoadDocument(ts.URL + "/missing")
+		require.Contains(t, err.Error(), "status 404")
+
+		// failures are cached
+		_, err = loader.LoadDocument(ts.URL + "/missing")
+		require.Contains(t, err.Error(), "status 404")
+		require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+		_, err = loader.LoadDocument("urn:example:context")
+		require.Contains(t, err.Error(), "not an HTTP URL")
+	})
+
+	t.Run("Cache disabled", func(t *testing.T) {
+		var hits int32
+
+		ts := contextServer(t, &hits)
+
+		loader, err := New(nil, WithFetchPolicy(FetchOn), WithHTTPClient(ts.Client()), WithCache(0, 0, 10))
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = loader.LoadDocument(ts.URL + "/context")
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, int32(2), atomic.LoadInt32(&hits))
+	})
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil, WithFetchPolicy(FetchAllowlist))
+	require.EqualError(t, err, "fetch policy allowlist requires allowed URLs")
+
+	_, err = New(nil, WithFetchPolicy(FetchAllowlist, "w3id.org"))
+	require.EqualError(t, err, "allowed URL w3id.org is not an HTTP URL")
+
+	_, err = New(nil, WithFetchPolicy("sometimes"))
+	require.EqualError(t, err, "unsupported fetch policy: sometimes")
+
+	_, err = New(nil, WithPinnedContexts(ldcontext.Document{URL: "https://example.com", Content: []byte("{")}))
+	require.Contains(t, err.Error(), "pinned context https://example.com")
+}
+
+func TestReadPinnedContexts(t *testing.T) {
+	dir := t.TempDir()
+
+	docs, err := ReadPinnedContexts(dir)
+	require.NoError(t, err)
+	require.Empty(t, docs)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "context.json"), []byte(`{
+		"url": "https://example.com/context",
+		"content": {"@context": {"name": "https://schema.org/name"}}
+	}`), 0o600))
+
+	docs, err = ReadPinnedContexts(dir)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Equal(t, "https://example.com/context", docs[0].URL)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{}`), 0o600))
+
+	_, err = ReadPinnedContexts(dir)
+	require.EqualError(t, err, "context invalid.json is not a context document (url, documentURL and content)")
+}
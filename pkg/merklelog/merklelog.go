@@ -0,0 +1,560 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package merklelog implements the append-only Merkle log (RFC 6962) natively over a storage, without the
+// Trillian log server and signer. Log implements the Trillian log client, it is a drop-in replacement of
+// a Trillian log.
+//
+// The hashes of the tree are stored in tiles (à la Trillian tessera): a tile is a row of up to 256 hashes
+// of the level 0, 8, 16... The hashes of the levels in between are computed from the rows when proofs are
+// built, the hashes of imperfect subtrees are never stored. Leaves are integrated synchronously when they
+// are queued, a queued leaf has its index and is included in the next tree head.
+package merklelog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/compact"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Log is an append-only Merkle log, it implements trillian.TrillianLogClient.
+type Log struct {
+	storage Storage
+	hasher  *hasher.Hasher
+	ranges  *compact.RangeFactory
+	now     func() (time.Time, error)
+}
+
+// New returns a log over the storage.
+func New(storage Storage) *Log {
+	return &Log{
+		storage: storage,
+		hasher:  hasher.DefaultHasher,
+		ranges:  &compact.RangeFactory{Hash: hasher.DefaultHasher.HashChildren},
+		now:     func() (time.Time, error) { return time.Now(), nil },
+	}
+}
+
+// SetClock sets the clock of the timestamps of the tree heads and the leaves (e.g. a trusted time source),
+// the leaves are not integrated while the clock fails.
+func (l *Log) SetClock(now func() (time.Time, error)) {
+	l.now = now
+}
+
+// InitLog creates an empty tree, it fails with AlreadyExists if the tree exists.
+func (l *Log) InitLog(ctx context.Context, req *trillian.InitLogRequest,
+	_ ...grpc.CallOption) (*trillian.InitLogResponse, error) {
+	now, err := l.now()
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "clock: %v", err)
+	}
+
+	tree := &Tree{
+		ID:        req.LogId,
+		RootHash:  l.hasher.EmptyRoot(),
+		Timestamp: uint64(now.UnixNano()),
+	}
+
+	err = l.storage.WriteTx(ctx, func(tx Tx) error {
+		return tx.CreateTree(tree)
+	})
+	if errors.Is(err, ErrTreeExists) {
+		return nil, status.Errorf(codes.AlreadyExists, "log %d is already initialized", req.LogId)
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "create tree: %v", err)
+	}
+
+	root, err := signedLogRoot(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trillian.InitLogResponse{Created: root}, nil
+}
+
+// QueueLeaf appends the leaf to the log. A leaf with the same identity hash is not appended twice:
+// the existing leaf is returned with the AlreadyExists status.
+func (l *Log) QueueLeaf(ctx context.Context, req *trillian.QueueLeafRequest,
+	_ ...grpc.CallOption) (*trillian.QueueLeafResponse, error) {
+	if req.GetLeaf() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "no leaf")
+	}
+
+	leaf := cloneLeaf(req.Leaf)
+	leaf.MerkleLeafHash = l.hasher.HashLeaf(leaf.LeafValue)
+
+	if len(leaf.LeafIdentityHash) == 0 {
+		leaf.LeafIdentityHash = leaf.MerkleLeafHash
+	}
+
+	var queued *trillian.QueuedLogLeaf
+
+	err := l.storage.WriteTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		existing, err := tx.GetLeafByIdentityHash(req.LogId, leaf.LeafIdentityHash)
+		if err != nil {
+			return status.Errorf(codes.Internal, "get leaf by identity hash: %v", err)
+		}
+
+		if existing != nil {
+			queued = &trillian.QueuedLogLeaf{
+				Leaf:   existing,
+				Status: status.Newf(codes.AlreadyExists, "leaf already exists: %d", existing.LeafIndex).Proto(),
+			}
+
+			return nil
+		}
+
+		if err = l.append(tx, tree, leaf); err != nil {
+			return err
+		}
+
+		queued = &trillian.QueuedLogLeaf{Leaf: leaf}
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &trillian.QueueLeafResponse{QueuedLeaf: queued}, nil
+}
+
+// append integrates the leaf to the tree: the leaf is sequenced, the new hashes are stored in their tiles and
+// the tree head is updated.
+func (l *Log) append(tx Tx, tree *Tree, leaf *trillian.LogLeaf) error {
+	rng, err := l.ranges.NewRange(0, tree.Size, tree.Range)
+	if err != nil {
+		return status.Errorf(codes.Internal, "compact range: %v", err)
+	}
+
+	ids := []compact.NodeID{compact.NewNodeID(0, tree.Size)}
+	hashes := [][]byte{leaf.MerkleLeafHash}
+
+	err = rng.Append(leaf.MerkleLeafHash, func(id compact.NodeID, hash []byte) {
+		if id.Level%TileHeight == 0 {
+			ids = append(ids, id)
+			hashes = append(hashes, hash)
+		}
+	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "append to compact range: %v", err)
+	}
+
+	rootHash, err := rng.GetRootHash(nil)
+	if err != nil {
+		return status.Errorf(codes.Internal, "root hash: %v", err)
+	}
+
+	now, err := l.now()
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "clock: %v", err)
+	}
+
+	leaf.LeafIndex = int64(tree.Size)
+	if leaf.QueueTimestamp == nil {
+		leaf.QueueTimestamp = timestamppb.New(now)
+	}
+
+	leaf.IntegrateTimestamp = timestamppb.New(now)
+
+	if err = tx.AddLeaf(tree.ID, leaf); err != nil {
+		return status.Errorf(codes.Internal, "add leaf: %v", err)
+	}
+
+	if err = l.putNodes(tx, tree.ID, ids, hashes); err != nil {
+		return err
+	}
+
+	tree.Size = rng.End()
+	tree.RootHash = rootHash
+	tree.Timestamp = uint64(now.UnixNano())
+	tree.Revision++
+	tree.Range = rng.Hashes()
+
+	if err = tx.UpdateTree(tree); err != nil {
+		return status.Errorf(codes.Internal, "update tree: %v", err)
+	}
+
+	return nil
+}
+
+// AddSequencedLeaves is not supported, the logs are not pre-ordered.
+func (l *Log) AddSequencedLeaves(context.Context, *trillian.AddSequencedLeavesRequest,
+	...grpc.CallOption) (*trillian.AddSequencedLeavesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "pre-ordered logs are not supported")
+}
+
+// GetLatestSignedLogRoot returns the latest tree head and, if the first tree size is set, the consistency
+// proof from that size.
+func (l *Log) GetLatestSignedLogRoot(ctx context.Context, req *trillian.GetLatestSignedLogRootRequest,
+	_ ...grpc.CallOption) (*trillian.GetLatestSignedLogRootResponse, error) {
+	resp := &trillian.GetLatestSignedLogRootResponse{}
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		if resp.SignedLogRoot, err = signedLogRoot(tree); err != nil {
+			return err
+		}
+
+		if req.FirstTreeSize > 0 && uint64(req.FirstTreeSize) <= tree.Size {
+			resp.Proof, err = l.consistencyProof(tx, tree, req.FirstTreeSize, int64(tree.Size))
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// GetConsistencyProof returns the consistency proof between the tree sizes. If the second tree size is
+// beyond the tree, only the tree head is returned.
+func (l *Log) GetConsistencyProof(ctx context.Context, req *trillian.GetConsistencyProofRequest,
+	_ ...grpc.CallOption) (*trillian.GetConsistencyProofResponse, error) {
+	if req.FirstTreeSize <= 0 || req.SecondTreeSize <= 0 || req.FirstTreeSize > req.SecondTreeSize {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tree sizes %d and %d",
+			req.FirstTreeSize, req.SecondTreeSize)
+	}
+
+	resp := &trillian.GetConsistencyProofResponse{}
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		if resp.SignedLogRoot, err = signedLogRoot(tree); err != nil {
+			return err
+		}
+
+		if uint64(req.SecondTreeSize) > tree.Size {
+			return nil
+		}
+
+		resp.Proof, err = l.consistencyProof(tx, tree, req.FirstTreeSize, req.SecondTreeSize)
+
+		return err
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// GetInclusionProof returns the inclusion proof of the leaf at the index in the tree of the size. If the tree
+// size is beyond the tree, only the tree head is returned.
+func (l *Log) GetInclusionProof(ctx context.Context, req *trillian.GetInclusionProofRequest,
+	_ ...grpc.CallOption) (*trillian.GetInclusionProofResponse, error) {
+	if req.LeafIndex < 0 || req.TreeSize <= req.LeafIndex {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid leaf index %d for tree size %d",
+			req.LeafIndex, req.TreeSize)
+	}
+
+	resp := &trillian.GetInclusionProofResponse{}
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		if resp.SignedLogRoot, err = signedLogRoot(tree); err != nil {
+			return err
+		}
+
+		if uint64(req.TreeSize) > tree.Size {
+			return nil
+		}
+
+		resp.Proof, err = l.inclusionProof(tx, tree, req.LeafIndex, req.TreeSize)
+
+		return err
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// GetInclusionProofByHash returns the inclusion proofs of the leaves with the Merkle hash in the tree of
+// the size, ordered by the leaf index. If the tree size is beyond the tree, only the tree head is returned.
+func (l *Log) GetInclusionProofByHash(ctx context.Context, req *trillian.GetInclusionProofByHashRequest,
+	_ ...grpc.CallOption) (*trillian.GetInclusionProofByHashResponse, error) {
+	if req.TreeSize <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tree size %d", req.TreeSize)
+	}
+
+	resp := &trillian.GetInclusionProofByHashResponse{}
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		if resp.SignedLogRoot, err = signedLogRoot(tree); err != nil {
+			return err
+		}
+
+		if uint64(req.TreeSize) > tree.Size {
+			return nil
+		}
+
+		leaves, err := tx.GetLeavesByMerkleHash(req.LogId, req.LeafHash, req.TreeSize)
+		if err != nil {
+			return status.Errorf(codes.Internal, "get leaves by hash: %v", err)
+		}
+
+		if len(leaves) == 0 {
+			return status.Errorf(codes.NotFound, "no leaves with the hash in tree size %d", req.TreeSize)
+		}
+
+		for _, leaf := range leaves {
+			proof, er := l.inclusionProof(tx, tree, leaf.LeafIndex, req.TreeSize)
+			if er != nil {
+				return er
+			}
+
+			resp.Proof = append(resp.Proof, proof)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// GetEntryAndProof returns the leaf at the index and its inclusion proof in the tree of the size. If the tree
+// size is beyond the tree, only the tree head is returned.
+func (l *Log) GetEntryAndProof(ctx context.Context, req *trillian.GetEntryAndProofRequest,
+	_ ...grpc.CallOption) (*trillian.GetEntryAndProofResponse, error) {
+	if req.LeafIndex < 0 || req.TreeSize <= req.LeafIndex {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid leaf index %d for tree size %d",
+			req.LeafIndex, req.TreeSize)
+	}
+
+	resp := &trillian.GetEntryAndProofResponse{}
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		if resp.SignedLogRoot, err = signedLogRoot(tree); err != nil {
+			return err
+		}
+
+		if uint64(req.TreeSize) > tree.Size {
+			return nil
+		}
+
+		leaves, err := tx.GetLeaves(req.LogId, req.LeafIndex, 1)
+		if err != nil {
+			return status.Errorf(codes.Internal, "get leaves: %v", err)
+		}
+
+		if len(leaves) != 1 {
+			return status.Errorf(codes.Internal, "no leaf at index %d", req.LeafIndex)
+		}
+
+		resp.Leaf = leaves[0]
+		resp.Proof, err = l.inclusionProof(tx, tree, req.LeafIndex, req.TreeSize)
+
+		return err
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// GetLeavesByRange returns the leaves [start, start+count) within the tree.
+func (l *Log) GetLeavesByRange(ctx context.Context, req *trillian.GetLeavesByRangeRequest,
+	_ ...grpc.CallOption) (*trillian.GetLeavesByRangeResponse, error) {
+	if req.StartIndex < 0 || req.Count <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid range start %d count %d",
+			req.StartIndex, req.Count)
+	}
+
+	resp := &trillian.GetLeavesByRangeResponse{}
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, req.LogId)
+		if err != nil {
+			return err
+		}
+
+		if resp.SignedLogRoot, err = signedLogRoot(tree); err != nil {
+			return err
+		}
+
+		if uint64(req.StartIndex) >= tree.Size {
+			return nil
+		}
+
+		count := req.Count
+		if uint64(req.StartIndex+count) > tree.Size {
+			count = int64(tree.Size) - req.StartIndex
+		}
+
+		if resp.Leaves, err = tx.GetLeaves(req.LogId, req.StartIndex, count); err != nil {
+			return status.Errorf(codes.Internal, "get leaves: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return resp, nil
+}
+
+// UpdateExtraData replaces the extra data of the sequenced leaf (e.g. re-encrypted with another key). The extra
+// data is not hashed into the tree, the tree heads and the proofs are unchanged.
+func (l *Log) UpdateExtraData(ctx context.Context, treeID, leafIndex int64, extraData []byte) error {
+	err := l.storage.WriteTx(ctx, func(tx Tx) error {
+		tree, err := getTree(tx, treeID)
+		if err != nil {
+			return err
+		}
+
+		if leafIndex < 0 || uint64(leafIndex) >= tree.Size {
+			return status.Errorf(codes.NotFound, "leaf %d not found", leafIndex)
+		}
+
+		if err = tx.UpdateExtraData(treeID, leafIndex, extraData); errors.Is(err, ErrLeafNotFound) {
+			return status.Errorf(codes.NotFound, "leaf %d not found", leafIndex)
+		}
+
+		if err != nil {
+			return status.Errorf(codes.Internal, "update extra data: %v", err)
+		}
+
+		return nil
+	})
+
+	return toStatus(err)
+}
+
+// inclusionProof builds the inclusion proof from the perfect subtrees of the tree: the tree size passed to
+// the node calculation is beyond the snapshot, the hashes of the imperfect subtrees are rehashed.
+func (l *Log) inclusionProof(tx Tx, tree *Tree, index, size int64) (*trillian.Proof, error) {
+	nodes, err := merkle.CalcInclusionProofNodeAddresses(size, index, size+1)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	hashes, err := l.proofHashes(tx, tree, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trillian.Proof{LeafIndex: index, Hashes: hashes}, nil
+}
+
+// consistencyProof builds the consistency proof from the perfect subtrees of the tree.
+func (l *Log) consistencyProof(tx Tx, tree *Tree, first, second int64) (*trillian.Proof, error) {
+	nodes, err := merkle.CalcConsistencyProofNodeAddresses(first, second, second+1)
+	if err != nil {
+		return nil, err // nolint: wrapcheck
+	}
+
+	hashes, err := l.proofHashes(tx, tree, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &trillian.Proof{Hashes: hashes}, nil
+}
+
+func (l *Log) proofHashes(tx Tx, tree *Tree, nodes []merkle.NodeFetch) ([][]byte, error) {
+	ids := make([]compact.NodeID, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+
+	hashes, err := l.nodeHashes(tx, tree.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err = merkle.Rehash(hashes, nodes, l.hasher.HashChildren)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "rehash: %v", err)
+	}
+
+	return hashes, nil
+}
+
+func getTree(tx Tx, treeID int64) (*Tree, error) {
+	tree, err := tx.GetTree(treeID)
+	if errors.Is(err, ErrTreeNotFound) {
+		return nil, status.Errorf(codes.NotFound, "log %d not found", treeID)
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get tree: %v", err)
+	}
+
+	return tree, nil
+}
+
+func signedLogRoot(tree *Tree) (*trillian.SignedLogRoot, error) {
+	root := types.LogRootV1{
+		TreeSize:       tree.Size,
+		RootHash:       tree.RootHash,
+		TimestampNanos: tree.Timestamp,
+		Revision:       tree.Revision,
+	}
+
+	src, err := root.MarshalBinary()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal log root: %v", err)
+	}
+
+	return &trillian.SignedLogRoot{LogRoot: src}, nil
+}
+
+// toStatus returns the error as a status error, the errors of the transactions are internal.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	return status.Errorf(codes.Internal, "transaction: %v", err)
+}
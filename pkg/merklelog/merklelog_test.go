@@ -0,0 +1,377 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package merklelog_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/merkle/rfc6962/hasher"
+	"github.com/google/trillian/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/trustbloc/vct/pkg/merklelog"
+)
+
+const logID = 42
+
+// nolint: gochecknoglobals
+var _ trillian.TrillianLogClient = (*Log)(nil)
+
+func TestLog(t *testing.T) {
+	testLog(t, NewMemStorage())
+}
+
+func TestLog_Errors(t *testing.T) {
+	ctx := context.Background()
+	log := New(NewMemStorage())
+
+	t.Run("Log not found", func(t *testing.T) {
+		_, err := log.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: logID})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: logID, Leaf: &trillian.LogLeaf{}})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{LogId: logID})
+	require.NoError(t, err)
+
+	t.Run("Already initialized", func(t *testing.T) {
+		_, err = log.InitLog(ctx, &trillian.InitLogRequest{LogId: logID})
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("Invalid arguments", func(t *testing.T) {
+		_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: logID})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = log.GetInclusionProof(ctx, &trillian.GetInclusionProofRequest{LogId: logID, LeafIndex: 1, TreeSize: 1})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = log.GetEntryAndProof(ctx, &trillian.GetEntryAndProofRequest{LogId: logID, LeafIndex: -1, TreeSize: 1})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = log.GetInclusionProofByHash(ctx, &trillian.GetInclusionProofByHashRequest{LogId: logID})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = log.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+			LogId: logID, FirstTreeSize: 2, SecondTreeSize: 1,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = log.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: logID, StartIndex: 0})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Sequenced leaves", func(t *testing.T) {
+		_, err = log.AddSequencedLeaves(ctx, &trillian.AddSequencedLeavesRequest{LogId: logID})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("Leaf hash not found", func(t *testing.T) {
+		_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: logID, Leaf: &trillian.LogLeaf{LeafValue: []byte{1}}})
+		require.NoError(t, err)
+
+		_, err = log.GetInclusionProofByHash(ctx, &trillian.GetInclusionProofByHashRequest{
+			LogId: logID, LeafHash: []byte("unknown"), TreeSize: 1,
+		})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestLog_SetClock(t *testing.T) {
+	ctx := context.Background()
+	log := New(NewMemStorage())
+
+	now := time.Unix(1700000000, 0)
+
+	var clockErr error
+
+	log.SetClock(func() (time.Time, error) { return now, clockErr })
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{LogId: logID})
+	require.NoError(t, err)
+
+	_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: logID, Leaf: &trillian.LogLeaf{LeafValue: []byte{1}}})
+	require.NoError(t, err)
+
+	resp, err := log.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: logID})
+	require.NoError(t, err)
+
+	var root types.LogRootV1
+	require.NoError(t, root.UnmarshalBinary(resp.SignedLogRoot.LogRoot))
+	require.Equal(t, uint64(now.UnixNano()), root.TimestampNanos)
+
+	clockErr = errors.New("not synced")
+
+	_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: logID, Leaf: &trillian.LogLeaf{LeafValue: []byte{2}}})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = log.InitLog(ctx, &trillian.InitLogRequest{LogId: logID + 1})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestLog_Rollback(t *testing.T) {
+	ctx := context.Background()
+	storage := &failingStorage{Storage: NewMemStorage()}
+	log := New(storage)
+
+	_, err := log.InitLog(ctx, &trillian.InitLogRequest{LogId: logID})
+	require.NoError(t, err)
+
+	storage.fail = true
+
+	_, err = log.QueueLeaf(ctx, &trillian.QueueLeafRequest{LogId: logID, Leaf: &trillian.LogLeaf{LeafValue: []byte{1}}})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Contains(t, err.Error(), "update tree")
+
+	storage.fail = false
+
+	resp, err := log.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{LogId: logID, Count: 1})
+	require.NoError(t, err)
+	require.Empty(t, resp.Leaves)
+
+	// the leaf is appended once the storage recovers
+	queued, err := log.QueueLeaf(ctx, &trillian.QueueLeafRequest{
+		LogId: logID, Leaf: &trillian.LogLeaf{LeafValue: []byte{1}},
+	})
+	require.NoError(t, err)
+	require.Nil(t, queued.QueuedLeaf.Status)
+	require.Equal(t, int64(0), queued.QueuedLeaf.Leaf.LeafIndex)
+}
+
+// testLog appends leaves across several tiles and verifies the tree heads, the entries and the proofs.
+func testLog(t *testing.T, storage Storage) { // nolint: funlen
+	t.Helper()
+
+	const size = 600
+
+	ctx := context.Background()
+	log := New(storage)
+	verifier := logverifier.New(hasher.DefaultHasher)
+
+	created, err := log.InitLog(ctx, &trillian.InitLogRequest{LogId: logID})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), logRoot(t, created.Created).TreeSize)
+	require.Equal(t, hasher.DefaultHasher.EmptyRoot(), logRoot(t, created.Created).RootHash)
+
+	roots := make([][]byte, size+1)
+
+	for i := 0; i < size; i++ {
+		resp, er := log.QueueLeaf(ctx, &trillian.QueueLeafRequest{
+			LogId: logID,
+			Leaf: &trillian.LogLeaf{
+				LeafValue:        []byte(fmt.Sprintf("leaf %d", i%500)), // leaves 500+ repeat values
+				ExtraData:        []byte(fmt.Sprintf("extra %d", i)),
+				LeafIdentityHash: []byte(fmt.Sprintf("identity %d", i)),
+			},
+		})
+		require.NoError(t, er)
+		require.Equal(t, int64(i), resp.QueuedLeaf.Leaf.LeafIndex)
+
+		root, er := log.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: logID})
+		require.NoError(t, er)
+		require.Equal(t, uint64(i+1), logRoot(t, root.SignedLogRoot).TreeSize)
+
+		roots[i+1] = logRoot(t, root.SignedLogRoot).RootHash
+	}
+
+	sizes := []int64{1, 2, 3, 7, 8, 9, 255, 256, 257, 300, 511, 512, 513, size}
+
+	t.Run("Inclusion proofs", func(t *testing.T) {
+		for _, treeSize := range sizes {
+			for index := int64(0); index < treeSize; index += 1 + treeSize/40 {
+				resp, er := log.GetEntryAndProof(ctx, &trillian.GetEntryAndProofRequest{
+					LogId: logID, LeafIndex: index, TreeSize: treeSize,
+				})
+				require.NoError(t, er)
+				require.Equal(t, fmt.Sprintf("extra %d", index), string(resp.Leaf.ExtraData))
+				require.NoError(t, verifier.VerifyInclusionProof(index, treeSize, resp.Proof.Hashes,
+					roots[treeSize], resp.Leaf.MerkleLeafHash), "index %d, tree size %d", index, treeSize)
+			}
+		}
+	})
+
+	t.Run("Inclusion proofs by hash", func(t *testing.T) {
+		leafHash := hasher.DefaultHasher.HashLeaf([]byte("leaf 7"))
+
+		resp, er := log.GetInclusionProofByHash(ctx, &trillian.GetInclusionProofByHashRequest{
+			LogId: logID, LeafHash: leafHash, TreeSize: size, OrderBySequence: true,
+		})
+		require.NoError(t, er)
+		require.Len(t, resp.Proof, 2)
+		require.Equal(t, int64(7), resp.Proof[0].LeafIndex)
+		require.Equal(t, int64(507), resp.Proof[1].LeafIndex)
+
+		for _, proof := range resp.Proof {
+			require.NoError(t, verifier.VerifyInclusionProof(proof.LeafIndex, size, proof.Hashes, roots[size],
+				leafHash))
+		}
+
+		inclusion, er := log.GetInclusionProof(ctx, &trillian.GetInclusionProofRequest{
+			LogId: logID, LeafIndex: 507, TreeSize: 508,
+		})
+		require.NoError(t, er)
+		require.NoError(t, verifier.VerifyInclusionProof(507, 508, inclusion.Proof.Hashes, roots[508], leafHash))
+	})
+
+	t.Run("Consistency proofs", func(t *testing.T) {
+		for _, first := range sizes {
+			for _, second := range sizes {
+				if first > second {
+					continue
+				}
+
+				resp, er := log.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+					LogId: logID, FirstTreeSize: first, SecondTreeSize: second,
+				})
+				require.NoError(t, er)
+				require.NoError(t, verifier.VerifyConsistencyProof(first, second, roots[first], roots[second],
+					resp.Proof.Hashes), "first %d, second %d", first, second)
+			}
+		}
+
+		resp, er := log.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{
+			LogId: logID, FirstTreeSize: 257,
+		})
+		require.NoError(t, er)
+		require.NoError(t, verifier.VerifyConsistencyProof(257, size, roots[257], roots[size], resp.Proof.Hashes))
+	})
+
+	t.Run("Beyond the tree", func(t *testing.T) {
+		entry, er := log.GetEntryAndProof(ctx, &trillian.GetEntryAndProofRequest{
+			LogId: logID, LeafIndex: 1, TreeSize: size + 1,
+		})
+		require.NoError(t, er)
+		require.Nil(t, entry.Proof)
+		require.Equal(t, uint64(size), logRoot(t, entry.SignedLogRoot).TreeSize)
+
+		consistency, er := log.GetConsistencyProof(ctx, &trillian.GetConsistencyProofRequest{
+			LogId: logID, FirstTreeSize: 1, SecondTreeSize: size + 1,
+		})
+		require.NoError(t, er)
+		require.Nil(t, consistency.Proof)
+
+		byHash, er := log.GetInclusionProofByHash(ctx, &trillian.GetInclusionProofByHashRequest{
+			LogId: logID, LeafHash: hasher.DefaultHasher.HashLeaf([]byte("leaf 7")), TreeSize: size + 1,
+		})
+		require.NoError(t, er)
+		require.Empty(t, byHash.Proof)
+	})
+
+	t.Run("Leaves by range", func(t *testing.T) {
+		resp, er := log.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{
+			LogId: logID, StartIndex: size - 2, Count: 10,
+		})
+		require.NoError(t, er)
+		require.Len(t, resp.Leaves, 2)
+		require.Equal(t, int64(size-2), resp.Leaves[0].LeafIndex)
+		require.Equal(t, int64(size-1), resp.Leaves[1].LeafIndex)
+		require.NotNil(t, resp.Leaves[0].IntegrateTimestamp)
+
+		resp, er = log.GetLeavesByRange(ctx, &trillian.GetLeavesByRangeRequest{
+			LogId: logID, StartIndex: size, Count: 10,
+		})
+		require.NoError(t, er)
+		require.Empty(t, resp.Leaves)
+	})
+
+	t.Run("Extra data", func(t *testing.T) {
+		require.NoError(t, log.UpdateExtraData(ctx, logID, 3, []byte("re-encrypted 3")))
+
+		entry, er := log.GetEntryAndProof(ctx, &trillian.GetEntryAndProofRequest{
+			LogId: logID, LeafIndex: 3, TreeSize: size,
+		})
+		require.NoError(t, er)
+		require.Equal(t, "re-encrypted 3", string(entry.Leaf.ExtraData))
+		require.NoError(t, verifier.VerifyInclusionProof(3, size, entry.Proof.Hashes, roots[size],
+			entry.Leaf.MerkleLeafHash))
+
+		er = log.UpdateExtraData(ctx, logID, size, []byte("extra"))
+		require.Equal(t, codes.NotFound, status.Code(er))
+
+		er = log.UpdateExtraData(ctx, logID+1, 3, []byte("extra"))
+		require.Equal(t, codes.NotFound, status.Code(er))
+
+		require.NoError(t, log.UpdateExtraData(ctx, logID, 3, []byte("extra 3")))
+	})
+
+	t.Run("Tiles", func(t *testing.T) {
+		tile, er := log.GetTile(ctx, logID, 0, 2)
+		require.NoError(t, er)
+		require.Len(t, tile, size-2*TileWidth)
+		require.Equal(t, hasher.DefaultHasher.HashLeaf([]byte("leaf 12")), tile[0])
+
+		tile, er = log.GetTile(ctx, logID, 1, 0)
+		require.NoError(t, er)
+		require.Len(t, tile, 2)
+
+		// the level-8 hash is the root of the tree of the first 256 leaves
+		require.Equal(t, roots[TileWidth], tile[0])
+
+		_, er = log.GetTile(ctx, logID, 0, 3)
+		require.Equal(t, codes.NotFound, status.Code(er))
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		resp, er := log.QueueLeaf(ctx, &trillian.QueueLeafRequest{
+			LogId: logID,
+			Leaf:  &trillian.LogLeaf{LeafValue: []byte("another value"), LeafIdentityHash: []byte("identity 3")},
+		})
+		require.NoError(t, er)
+		require.Equal(t, int32(codes.AlreadyExists), resp.QueuedLeaf.Status.Code)
+		require.Equal(t, int64(3), resp.QueuedLeaf.Leaf.LeafIndex)
+		require.Equal(t, "leaf 3", string(resp.QueuedLeaf.Leaf.LeafValue))
+
+		root, er := log.GetLatestSignedLogRoot(ctx, &trillian.GetLatestSignedLogRootRequest{LogId: logID})
+		require.NoError(t, er)
+		require.Equal(t, uint64(size), logRoot(t, root.SignedLogRoot).TreeSize)
+	})
+}
+
+func logRoot(t *testing.T, signed *trillian.SignedLogRoot) *types.LogRootV1 {
+	t.Helper()
+
+	var root types.LogRootV1
+
+	require.NoError(t, root.UnmarshalBinary(signed.LogRoot))
+
+	return &root
+}
+
+// failingStorage fails to update the trees once the leaves and the tiles are written.
+type failingStorage struct {
+	Storage
+	fail bool
+}
+
+func (s *failingStorage) WriteTx(ctx context.Context, fn func(tx Tx) error) error {
+	return s.Storage.WriteTx(ctx, func(tx Tx) error {
+		return fn(&failingTx{Tx: tx, fail: s.fail})
+	})
+}
+
+type failingTx struct {
+	Tx
+	fail bool
+}
+
+func (tx *failingTx) UpdateTree(tree *Tree) error {
+	if tx.fail {
+		return errors.New("injected")
+	}
+
+	return tx.Tx.UpdateTree(tree)
+}
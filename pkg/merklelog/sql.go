@@ -0,0 +1,329 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package merklelog
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/trillian"
+	"github.com/lib/pq"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// nolint: gochecknoglobals
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS merkle_trees (
+		tree_id BIGINT PRIMARY KEY,
+		tree_size BIGINT NOT NULL,
+		root_hash BYTEA NOT NULL,
+		timestamp_nanos BIGINT NOT NULL,
+		revision BIGINT NOT NULL,
+		compact_range BYTEA NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS merkle_leaves (
+		tree_id BIGINT NOT NULL REFERENCES merkle_trees(tree_id) ON DELETE CASCADE,
+		leaf_index BIGINT NOT NULL,
+		leaf_identity_hash BYTEA NOT NULL,
+		merkle_leaf_hash BYTEA NOT NULL,
+		leaf_value BYTEA NOT NULL,
+		extra_data BYTEA,
+		queue_timestamp_nanos BIGINT NOT NULL,
+		integrate_timestamp_nanos BIGINT NOT NULL,
+		PRIMARY KEY (tree_id, leaf_index),
+		UNIQUE (tree_id, leaf_identity_hash)
+	)`,
+	`CREATE INDEX IF NOT EXISTS merkle_leaves_hash ON merkle_leaves (tree_id, merkle_leaf_hash)`,
+	`CREATE TABLE IF NOT EXISTS merkle_tiles (
+		tree_id BIGINT NOT NULL REFERENCES merkle_trees(tree_id) ON DELETE CASCADE,
+		tile_level INTEGER NOT NULL,
+		tile_index BIGINT NOT NULL,
+		hashes BYTEA NOT NULL,
+		PRIMARY KEY (tree_id, tile_level, tile_index)
+	)`,
+}
+
+const leafColumns = `leaf_index, leaf_identity_hash, merkle_leaf_hash, leaf_value, extra_data,
+	queue_timestamp_nanos, integrate_timestamp_nanos`
+
+// SQLStorage stores the logs in a PostgreSQL database.
+type SQLStorage struct {
+	db *sql.DB
+}
+
+// NewSQLStorage returns a storage over the database, the tables are created if they do not exist.
+func NewSQLStorage(ctx context.Context, db *sql.DB) (*SQLStorage, error) {
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("create schema: %w", err)
+		}
+	}
+
+	return &SQLStorage{db: db}, nil
+}
+
+// ReadTx runs fn within a read-only repeatable read transaction.
+func (s *SQLStorage) ReadTx(ctx context.Context, fn func(tx Tx) error) error {
+	return s.run(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, false, fn)
+}
+
+// WriteTx runs fn within a write transaction, GetTree locks the row of the tree.
+func (s *SQLStorage) WriteTx(ctx context.Context, fn func(tx Tx) error) error {
+	return s.run(ctx, nil, true, fn)
+}
+
+func (s *SQLStorage) run(ctx context.Context, opts *sql.TxOptions, write bool, fn func(tx Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	if err = fn(&sqlTx{ctx: ctx, tx: tx, write: write}); err != nil {
+		tx.Rollback() // nolint: errcheck,gosec
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+type sqlTx struct {
+	ctx   context.Context // nolint: containedctx
+	tx    *sql.Tx
+	write bool
+}
+
+func (tx *sqlTx) GetTree(treeID int64) (*Tree, error) {
+	query := `SELECT tree_size, root_hash, timestamp_nanos, revision, compact_range FROM merkle_trees
+		WHERE tree_id = $1`
+	if tx.write {
+		query += " FOR UPDATE"
+	}
+
+	var compactRange []byte
+
+	tree := &Tree{ID: treeID}
+
+	err := tx.tx.QueryRowContext(tx.ctx, query, treeID).Scan(&tree.Size, &tree.RootHash, &tree.Timestamp,
+		&tree.Revision, &compactRange)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTreeNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("select tree: %w", err)
+	}
+
+	if tree.Range, err = splitHashes(compactRange); err != nil {
+		return nil, fmt.Errorf("compact range: %w", err)
+	}
+
+	return tree, nil
+}
+
+func (tx *sqlTx) CreateTree(tree *Tree) error {
+	result, err := tx.tx.ExecContext(tx.ctx, `INSERT INTO merkle_trees
+		(tree_id, tree_size, root_hash, timestamp_nanos, revision, compact_range) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING`,
+		tree.ID, tree.Size, tree.RootHash, tree.Timestamp, tree.Revision, joinHashes(tree.Range))
+	if err != nil {
+		return fmt.Errorf("insert tree: %w", err)
+	}
+
+	if n, er := result.RowsAffected(); er == nil && n == 0 {
+		return ErrTreeExists
+	}
+
+	return nil
+}
+
+func (tx *sqlTx) UpdateTree(tree *Tree) error {
+	_, err := tx.tx.ExecContext(tx.ctx, `UPDATE merkle_trees
+		SET tree_size = $2, root_hash = $3, timestamp_nanos = $4, revision = $5, compact_range = $6
+		WHERE tree_id = $1`,
+		tree.ID, tree.Size, tree.RootHash, tree.Timestamp, tree.Revision, joinHashes(tree.Range))
+	if err != nil {
+		return fmt.Errorf("update tree: %w", err)
+	}
+
+	return nil
+}
+
+func (tx *sqlTx) GetLeafByIdentityHash(treeID int64, hash []byte) (*trillian.LogLeaf, error) {
+	leaves, err := tx.queryLeaves(`SELECT `+leafColumns+` FROM merkle_leaves
+		WHERE tree_id = $1 AND leaf_identity_hash = $2`, treeID, hash)
+	if err != nil || len(leaves) == 0 {
+		return nil, err
+	}
+
+	return leaves[0], nil
+}
+
+func (tx *sqlTx) GetLeavesByMerkleHash(treeID int64, hash []byte, treeSize int64) ([]*trillian.LogLeaf, error) {
+	return tx.queryLeaves(`SELECT `+leafColumns+` FROM merkle_leaves
+		WHERE tree_id = $1 AND merkle_leaf_hash = $2 AND leaf_index < $3 ORDER BY leaf_index`, treeID, hash, treeSize)
+}
+
+func (tx *sqlTx) GetLeaves(treeID, start, count int64) ([]*trillian.LogLeaf, error) {
+	return tx.queryLeaves(`SELECT `+leafColumns+` FROM merkle_leaves
+		WHERE tree_id = $1 AND leaf_index >= $2 AND leaf_index < $3 ORDER BY leaf_index`, treeID, start, start+count)
+}
+
+func (tx *sqlTx) queryLeaves(query string, args ...interface{}) ([]*trillian.LogLeaf, error) {
+	rows, err := tx.tx.QueryContext(tx.ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("select leaves: %w", err)
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	var leaves []*trillian.LogLeaf
+
+	for rows.Next() {
+		var queued, integrated int64
+
+		leaf := &trillian.LogLeaf{}
+
+		if err = rows.Scan(&leaf.LeafIndex, &leaf.LeafIdentityHash, &leaf.MerkleLeafHash, &leaf.LeafValue,
+			&leaf.ExtraData, &queued, &integrated); err != nil {
+			return nil, fmt.Errorf("scan leaf: %w", err)
+		}
+
+		leaf.QueueTimestamp = timestampFromNanos(queued)
+		leaf.IntegrateTimestamp = timestampFromNanos(integrated)
+
+		leaves = append(leaves, leaf)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("leaves rows: %w", err)
+	}
+
+	return leaves, nil
+}
+
+func (tx *sqlTx) AddLeaf(treeID int64, leaf *trillian.LogLeaf) error {
+	_, err := tx.tx.ExecContext(tx.ctx, `INSERT INTO merkle_leaves (tree_id, `+leafColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		treeID, leaf.LeafIndex, leaf.LeafIdentityHash, leaf.MerkleLeafHash, leaf.LeafValue, leaf.ExtraData,
+		leaf.QueueTimestamp.AsTime().UnixNano(), leaf.IntegrateTimestamp.AsTime().UnixNano())
+	if err != nil {
+		return fmt.Errorf("insert leaf: %w", err)
+	}
+
+	return nil
+}
+
+func (tx *sqlTx) UpdateExtraData(treeID, index int64, extraData []byte) error {
+	result, err := tx.tx.ExecContext(tx.ctx, `UPDATE merkle_leaves SET extra_data = $3
+		WHERE tree_id = $1 AND leaf_index = $2`, treeID, index, extraData)
+	if err != nil {
+		return fmt.Errorf("update extra data: %w", err)
+	}
+
+	if n, er := result.RowsAffected(); er == nil && n == 0 {
+		return ErrLeafNotFound
+	}
+
+	return nil
+}
+
+func (tx *sqlTx) GetTiles(treeID int64, ids []TileID) ([]*Tile, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// tiles are selected by pairs of arrays of levels and indexes
+	levels := make([]int64, len(ids))
+	indexes := make([]int64, len(ids))
+
+	for i, id := range ids {
+		levels[i], indexes[i] = int64(id.Level), int64(id.Index)
+	}
+
+	rows, err := tx.tx.QueryContext(tx.ctx, `SELECT t.tile_level, t.tile_index, t.hashes FROM merkle_tiles t
+		JOIN UNNEST($2::BIGINT[], $3::BIGINT[]) AS ids(tile_level, tile_index)
+		ON t.tile_level = ids.tile_level AND t.tile_index = ids.tile_index
+		WHERE t.tree_id = $1`, treeID, pq.Array(levels), pq.Array(indexes))
+	if err != nil {
+		return nil, fmt.Errorf("select tiles: %w", err)
+	}
+
+	defer rows.Close() // nolint: errcheck
+
+	var tiles []*Tile
+
+	for rows.Next() {
+		var hashes []byte
+
+		tile := &Tile{}
+
+		if err = rows.Scan(&tile.Level, &tile.Index, &hashes); err != nil {
+			return nil, fmt.Errorf("scan tile: %w", err)
+		}
+
+		if tile.Hashes, err = splitHashes(hashes); err != nil {
+			return nil, fmt.Errorf("tile %d/%d: %w", tile.Level, tile.Index, err)
+		}
+
+		tiles = append(tiles, tile)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("tiles rows: %w", err)
+	}
+
+	return tiles, nil
+}
+
+func (tx *sqlTx) PutTile(treeID int64, tile *Tile) error {
+	_, err := tx.tx.ExecContext(tx.ctx, `INSERT INTO merkle_tiles (tree_id, tile_level, tile_index, hashes)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (tree_id, tile_level, tile_index) DO UPDATE SET hashes = $4`,
+		treeID, tile.Level, tile.Index, joinHashes(tile.Hashes))
+	if err != nil {
+		return fmt.Errorf("upsert tile: %w", err)
+	}
+
+	return nil
+}
+
+// joinHashes concatenates the hashes, all of them are SHA-256 hashes.
+func joinHashes(hashes [][]byte) []byte {
+	result := make([]byte, 0, len(hashes)*sha256.Size)
+
+	for _, hash := range hashes {
+		result = append(result, hash...)
+	}
+
+	return result
+}
+
+func splitHashes(src []byte) ([][]byte, error) {
+	if len(src)%sha256.Size != 0 {
+		return nil, fmt.Errorf("%d bytes are not a sequence of hashes", len(src))
+	}
+
+	hashes := make([][]byte, 0, len(src)/sha256.Size)
+
+	for i := 0; i < len(src); i += sha256.Size {
+		hashes = append(hashes, src[i:i+sha256.Size])
+	}
+
+	return hashes, nil
+}
+
+func timestampFromNanos(nanos int64) *timestamppb.Timestamp {
+	return timestamppb.New(time.Unix(0, nanos))
+}
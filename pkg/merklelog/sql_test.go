@@ -0,0 +1,40 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package merklelog_test
+
+import (
+	"context"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/merklelog"
+	"github.com/trustbloc/vct/pkg/storage/postgres/testdb"
+)
+
+func TestSQLStorage(t *testing.T) {
+	if !testdb.PGAvailable() {
+		t.Skip("PG is not available")
+	}
+
+	ctx := context.Background()
+
+	db, done, err := testdb.NewTrillianDB(ctx)
+	require.NoError(t, err)
+
+	defer done(ctx)
+
+	storage, err := NewSQLStorage(ctx, db)
+	require.NoError(t, err)
+
+	testLog(t, storage)
+
+	// the schema is created once
+	_, err = NewSQLStorage(ctx, db)
+	require.NoError(t, err)
+}
@@ -0,0 +1,318 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package merklelog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/trillian"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrTreeNotFound is returned when the tree does not exist.
+	ErrTreeNotFound = errors.New("tree not found")
+	// ErrTreeExists is returned when the tree to create already exists.
+	ErrTreeExists = errors.New("tree already exists")
+	// ErrLeafNotFound is returned when the leaf does not exist.
+	ErrLeafNotFound = errors.New("leaf not found")
+)
+
+// Tree is the state of a log: its size, the root hash and the compact range the next leaf is appended to.
+type Tree struct {
+	ID        int64
+	Size      uint64
+	RootHash  []byte
+	Timestamp uint64 // nanoseconds since the epoch
+	Revision  uint64
+	// Range holds the hashes of the compact range [0, Size), the right border of the tree.
+	Range [][]byte
+}
+
+// Storage persists the trees, the leaves and the tiles of the logs.
+type Storage interface {
+	// ReadTx runs fn within a read transaction, the reads see a consistent snapshot of the trees.
+	ReadTx(ctx context.Context, fn func(tx Tx) error) error
+	// WriteTx runs fn within a write transaction, the writes are committed if fn succeeds. The writes of a
+	// tree are serialized: GetTree locks the tree until the transaction ends.
+	WriteTx(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx is a transaction of the storage.
+type Tx interface {
+	// GetTree returns the tree or ErrTreeNotFound.
+	GetTree(treeID int64) (*Tree, error)
+	// CreateTree creates the tree or returns ErrTreeExists.
+	CreateTree(tree *Tree) error
+	// UpdateTree updates the state of the tree.
+	UpdateTree(tree *Tree) error
+	// GetLeafByIdentityHash returns the leaf with the identity hash, nil if there is none.
+	GetLeafByIdentityHash(treeID int64, hash []byte) (*trillian.LogLeaf, error)
+	// GetLeavesByMerkleHash returns the leaves with the Merkle hash below the tree size ordered by their index.
+	GetLeavesByMerkleHash(treeID int64, hash []byte, treeSize int64) ([]*trillian.LogLeaf, error)
+	// GetLeaves returns the leaves [start, start+count) that exist.
+	GetLeaves(treeID, start, count int64) ([]*trillian.LogLeaf, error)
+	// AddLeaf adds the sequenced leaf.
+	AddLeaf(treeID int64, leaf *trillian.LogLeaf) error
+	// UpdateExtraData replaces the extra data of the leaf or returns ErrLeafNotFound.
+	UpdateExtraData(treeID, index int64, extraData []byte) error
+	// GetTiles returns the tiles that exist.
+	GetTiles(treeID int64, ids []TileID) ([]*Tile, error)
+	// PutTile creates or updates the tile.
+	PutTile(treeID int64, tile *Tile) error
+}
+
+// MemStorage is an in-memory storage, the logs are lost once the process stops.
+// It is meant for tests and development.
+type MemStorage struct {
+	mu    sync.RWMutex
+	trees map[int64]*memTree
+}
+
+type memTree struct {
+	tree       Tree
+	leaves     []*trillian.LogLeaf
+	byIdentity map[string]int64
+	byHash     map[string][]int64
+	tiles      map[TileID]*Tile
+}
+
+// NewMemStorage returns an empty in-memory storage.
+func NewMemStorage() *MemStorage {
+	return &MemStorage{trees: map[int64]*memTree{}}
+}
+
+// ReadTx runs fn within a read transaction.
+func (s *MemStorage) ReadTx(_ context.Context, fn func(tx Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(&memTx{storage: s})
+}
+
+// WriteTx runs fn within a write transaction, the writes of all trees are serialized.
+func (s *MemStorage) WriteTx(_ context.Context, fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memTx{storage: s}
+
+	if err := fn(tx); err != nil {
+		// the writes are applied in place, they are rolled back in the reverse order
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// memTx applies the writes in place and records how to roll them back, the storage is locked exclusively
+// while a write transaction runs.
+type memTx struct {
+	storage *MemStorage
+	undo    []func()
+}
+
+func (tx *memTx) get(treeID int64) (*memTree, error) {
+	t, ok := tx.storage.trees[treeID]
+	if !ok {
+		return nil, ErrTreeNotFound
+	}
+
+	return t, nil
+}
+
+func (tx *memTx) GetTree(treeID int64) (*Tree, error) {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := t.tree
+
+	return &tree, nil
+}
+
+func (tx *memTx) CreateTree(tree *Tree) error {
+	if _, ok := tx.storage.trees[tree.ID]; ok {
+		return ErrTreeExists
+	}
+
+	tx.storage.trees[tree.ID] = &memTree{
+		tree:       *tree,
+		byIdentity: map[string]int64{},
+		byHash:     map[string][]int64{},
+		tiles:      map[TileID]*Tile{},
+	}
+
+	tx.undo = append(tx.undo, func() { delete(tx.storage.trees, tree.ID) })
+
+	return nil
+}
+
+func (tx *memTx) UpdateTree(tree *Tree) error {
+	t, err := tx.get(tree.ID)
+	if err != nil {
+		return err
+	}
+
+	previous := t.tree
+	t.tree = *tree
+
+	tx.undo = append(tx.undo, func() { t.tree = previous })
+
+	return nil
+}
+
+func (tx *memTx) GetLeafByIdentityHash(treeID int64, hash []byte) (*trillian.LogLeaf, error) {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	index, ok := t.byIdentity[string(hash)]
+	if !ok {
+		return nil, nil
+	}
+
+	return cloneLeaf(t.leaves[index]), nil
+}
+
+func (tx *memTx) GetLeavesByMerkleHash(treeID int64, hash []byte, treeSize int64) ([]*trillian.LogLeaf, error) {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []*trillian.LogLeaf
+
+	// indexes are appended in sequence
+	for _, index := range t.byHash[string(hash)] {
+		if index < treeSize {
+			leaves = append(leaves, cloneLeaf(t.leaves[index]))
+		}
+	}
+
+	return leaves, nil
+}
+
+func (tx *memTx) GetLeaves(treeID, start, count int64) ([]*trillian.LogLeaf, error) {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []*trillian.LogLeaf
+
+	for i := start; i < start+count && i < int64(len(t.leaves)); i++ {
+		leaves = append(leaves, cloneLeaf(t.leaves[i]))
+	}
+
+	return leaves, nil
+}
+
+func (tx *memTx) AddLeaf(treeID int64, leaf *trillian.LogLeaf) error {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return err
+	}
+
+	if leaf.LeafIndex != int64(len(t.leaves)) {
+		return fmt.Errorf("leaf index %d is out of sequence", leaf.LeafIndex)
+	}
+
+	if _, ok := t.byIdentity[string(leaf.LeafIdentityHash)]; ok {
+		return fmt.Errorf("leaf identity hash must be unique")
+	}
+
+	identity, hash := string(leaf.LeafIdentityHash), string(leaf.MerkleLeafHash)
+
+	t.leaves = append(t.leaves, cloneLeaf(leaf))
+	t.byIdentity[identity] = leaf.LeafIndex
+	t.byHash[hash] = append(t.byHash[hash], leaf.LeafIndex)
+
+	tx.undo = append(tx.undo, func() {
+		t.leaves = t.leaves[:len(t.leaves)-1]
+		delete(t.byIdentity, identity)
+
+		if t.byHash[hash] = t.byHash[hash][:len(t.byHash[hash])-1]; len(t.byHash[hash]) == 0 {
+			delete(t.byHash, hash)
+		}
+	})
+
+	return nil
+}
+
+func (tx *memTx) UpdateExtraData(treeID, index int64, extraData []byte) error {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= int64(len(t.leaves)) {
+		return ErrLeafNotFound
+	}
+
+	// the stored leaves are replaced, not mutated: the leaves read before still have the previous extra data
+	previous := t.leaves[index]
+	leaf := cloneLeaf(previous)
+	leaf.ExtraData = append([]byte(nil), extraData...)
+	t.leaves[index] = leaf
+
+	tx.undo = append(tx.undo, func() {
+		t.leaves[index] = previous
+	})
+
+	return nil
+}
+
+func (tx *memTx) GetTiles(treeID int64, ids []TileID) ([]*Tile, error) {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var tiles []*Tile
+
+	for _, id := range ids {
+		if tile, ok := t.tiles[id]; ok {
+			tiles = append(tiles, &Tile{TileID: id, Hashes: append([][]byte(nil), tile.Hashes...)})
+		}
+	}
+
+	return tiles, nil
+}
+
+func (tx *memTx) PutTile(treeID int64, tile *Tile) error {
+	t, err := tx.get(treeID)
+	if err != nil {
+		return err
+	}
+
+	previous, existed := t.tiles[tile.TileID]
+	t.tiles[tile.TileID] = &Tile{TileID: tile.TileID, Hashes: append([][]byte(nil), tile.Hashes...)}
+
+	tx.undo = append(tx.undo, func() {
+		if existed {
+			t.tiles[tile.TileID] = previous
+		} else {
+			delete(t.tiles, tile.TileID)
+		}
+	})
+
+	return nil
+}
+
+func cloneLeaf(leaf *trillian.LogLeaf) *trillian.LogLeaf {
+	return proto.Clone(leaf).(*trillian.LogLeaf) // nolint: forcetypeassert
+}
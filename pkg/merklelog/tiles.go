@@ -0,0 +1,191 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package merklelog
+
+import (
+	"context"
+
+	"github.com/google/trillian/merkle/compact"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// TileHeight is the height of the tiles: a tile stores a row of hashes at a level divisible by the height,
+	// the hashes of the levels in between are computed from the row.
+	TileHeight = 8
+	// TileWidth is the number of hashes of a full tile.
+	TileWidth = 1 << TileHeight
+)
+
+// TileID identifies the tile storing the hashes of the nodes [Index*TileWidth, (Index+1)*TileWidth) at the
+// level Level*TileHeight of the tree.
+type TileID struct {
+	Level uint
+	Index uint64
+}
+
+// Tile is a row of node hashes. Once stored the hashes never change: the last tile of a row is partial and
+// only grows until it is full.
+type Tile struct {
+	TileID
+	Hashes [][]byte
+}
+
+// GetTile returns the hashes of the tile stored for the tree, a partial tile has less than TileWidth hashes.
+// The tiles are served as they are stored (see the tile-based read API of the log).
+func (l *Log) GetTile(ctx context.Context, treeID int64, level uint, index uint64) ([][]byte, error) {
+	var hashes [][]byte
+
+	err := l.storage.ReadTx(ctx, func(tx Tx) error {
+		if _, err := getTree(tx, treeID); err != nil {
+			return err
+		}
+
+		tiles, err := l.getTiles(tx, treeID, []TileID{{Level: level, Index: index}})
+		if err != nil {
+			return err
+		}
+
+		tile, ok := tiles[TileID{Level: level, Index: index}]
+		if !ok {
+			return status.Errorf(codes.NotFound, "tile %d/%d not found", level, index)
+		}
+
+		hashes = tile.Hashes
+
+		return nil
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return hashes, nil
+}
+
+// tileOf returns the tile storing the hash of the node and the position of the hash within the tile,
+// the level of the node must be divisible by the tile height.
+func tileOf(id compact.NodeID) (TileID, uint64) {
+	return TileID{Level: id.Level / TileHeight, Index: id.Index / TileWidth}, id.Index % TileWidth
+}
+
+// tileRange returns the tile the hash of the perfect subtree rooted at the node is computed from and the range
+// [begin, end) of the hashes within the tile.
+func tileRange(id compact.NodeID) (TileID, uint64, uint64) {
+	height := id.Level % TileHeight
+	first := id.Index << height // the first node of the row stored in the tile
+
+	return TileID{Level: id.Level / TileHeight, Index: first / TileWidth}, first % TileWidth,
+		first%TileWidth + 1<<height
+}
+
+// nodeHash computes the hash of the perfect subtree rooted at the node from the tiles.
+func (l *Log) nodeHash(tiles map[TileID]*Tile, id compact.NodeID) ([]byte, error) {
+	tileID, begin, end := tileRange(id)
+
+	tile, ok := tiles[tileID]
+	if !ok || uint64(len(tile.Hashes)) < end {
+		return nil, status.Errorf(codes.Internal, "no hashes of node %d at level %d", id.Index, id.Level)
+	}
+
+	hashes := append([][]byte(nil), tile.Hashes[begin:end]...)
+
+	for len(hashes) > 1 {
+		for i := 0; i < len(hashes)/2; i++ {
+			hashes[i] = l.hasher.HashChildren(hashes[2*i], hashes[2*i+1])
+		}
+
+		hashes = hashes[:len(hashes)/2]
+	}
+
+	return hashes[0], nil
+}
+
+// nodeHashes retrieves the hashes of the perfect subtrees rooted at the nodes.
+func (l *Log) nodeHashes(tx Tx, treeID int64, ids []compact.NodeID) ([][]byte, error) {
+	var tileIDs []TileID
+
+	seen := map[TileID]struct{}{}
+
+	for _, id := range ids {
+		tileID, _, _ := tileRange(id)
+
+		if _, ok := seen[tileID]; !ok {
+			seen[tileID] = struct{}{}
+			tileIDs = append(tileIDs, tileID)
+		}
+	}
+
+	tiles, err := l.getTiles(tx, treeID, tileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([][]byte, len(ids))
+
+	for i, id := range ids {
+		hashes[i], err = l.nodeHash(tiles, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return hashes, nil
+}
+
+// putNodes stores the hashes of the nodes at the levels divisible by the tile height in their tiles,
+// the hashes must be appended to the tiles in sequence.
+func (l *Log) putNodes(tx Tx, treeID int64, ids []compact.NodeID, hashes [][]byte) error {
+	var tileIDs []TileID
+
+	for _, id := range ids {
+		tileID, _ := tileOf(id)
+		tileIDs = append(tileIDs, tileID)
+	}
+
+	tiles, err := l.getTiles(tx, treeID, tileIDs)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		tileID, position := tileOf(id)
+
+		tile, ok := tiles[tileID]
+		if !ok {
+			tile = &Tile{TileID: tileID}
+			tiles[tileID] = tile
+		}
+
+		if uint64(len(tile.Hashes)) != position {
+			return status.Errorf(codes.Internal, "node %d at level %d is out of sequence", id.Index, id.Level)
+		}
+
+		tile.Hashes = append(tile.Hashes, hashes[i])
+
+		if err = tx.PutTile(treeID, tile); err != nil {
+			return status.Errorf(codes.Internal, "put tile: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *Log) getTiles(tx Tx, treeID int64, ids []TileID) (map[TileID]*Tile, error) {
+	tiles, err := tx.GetTiles(treeID, ids)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get tiles: %v", err)
+	}
+
+	result := make(map[TileID]*Tile, len(tiles))
+
+	for _, tile := range tiles {
+		result[tile.TileID] = tile
+	}
+
+	return result, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// DefaultS3Region is the region of the S3 buckets opened without a region.
+	DefaultS3Region = "us-east-1"
+
+	s3Scheme = "s3"
+)
+
+// OpenBucket opens the bucket of the URL: the S3 bucket of an s3://<bucket>/<prefix> URL, the directory otherwise.
+// The S3 credentials are taken from the default chain of the AWS SDK (e.g. AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY), the endpoint is set for storages compatible with the S3 API (path-style addressing).
+func OpenBucket(bucketURL, s3Endpoint, s3Region string) (Bucket, error) {
+	if !strings.HasPrefix(bucketURL, s3Scheme+"://") {
+		return NewDirBucket(bucketURL), nil
+	}
+
+	u, err := url.Parse(bucketURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("%s is not a valid bucket URL", bucketURL)
+	}
+
+	if s3Region == "" {
+		s3Region = DefaultS3Region
+	}
+
+	cfg := &aws.Config{Region: aws.String(s3Region)}
+
+	if s3Endpoint != "" {
+		cfg.Endpoint = aws.String(s3Endpoint)
+		cfg.S3ForcePathStyle = aws.Bool(true)
+	}
+
+	awsSession, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("new AWS session: %w", err)
+	}
+
+	return NewS3Bucket(s3.New(awsSession), u.Host, strings.Trim(u.Path, "/")), nil
+}
+
+// S3API is the subset of the S3 client used by the bucket.
+type S3API interface {
+	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
+	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+}
+
+// S3Bucket stores the resources in an S3 bucket, or any storage compatible with the S3 API
+// (e.g. Google Cloud Storage with the endpoint https://storage.googleapis.com and HMAC keys).
+type S3Bucket struct {
+	client S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Bucket returns the bucket, the keys of the objects are prefixed with the prefix (e.g. the alias of the log).
+func NewS3Bucket(client S3API, bucket, prefix string) *S3Bucket {
+	return &S3Bucket{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Put puts the object.
+func (b *S3Bucket) Put(ctx context.Context, key string, data []byte, contentType, cacheControl string) error {
+	_, err := b.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(b.bucket),
+		Key:          aws.String(path.Join(b.prefix, key)),
+		Body:         bytes.NewReader(data),
+		ContentType:  aws.String(contentType),
+		CacheControl: aws.String(cacheControl),
+	})
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+
+	return nil
+}
+
+// Get gets the object.
+func (b *S3Bucket) Get(ctx context.Context, key string) ([]byte, error) {
+	out, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(path.Join(b.prefix, key)),
+	})
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("get object: %w", err)
+	}
+
+	defer out.Body.Close() // nolint: errcheck
+
+	data, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read object: %w", err)
+	}
+
+	return data, nil
+}
+
+// DirBucket stores the resources as files of a directory (e.g. the root of a static web server or a mounted
+// bucket), the content type and the cache control are left to the server.
+type DirBucket struct {
+	dir string
+}
+
+// NewDirBucket returns the bucket.
+func NewDirBucket(dir string) *DirBucket {
+	return &DirBucket{dir: dir}
+}
+
+// Put writes the file of the object, the file is replaced atomically.
+func (b *DirBucket) Put(_ context.Context, key string, data []byte, _, _ string) error {
+	name := filepath.Join(b.dir, filepath.FromSlash(key))
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil { // nolint: gomnd
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp := name + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, data, 0o644); err != nil { // nolint: gosec,gomnd
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	if err := os.Rename(tmp, name); err != nil {
+		return fmt.Errorf("rename file: %w", err)
+	}
+
+	return nil
+}
+
+// Get reads the file of the object.
+func (b *DirBucket) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(b.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,335 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/trillian/monitoring"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	defaultDistributionAttempts = 3
+	defaultDistributionBackoff  = time.Second
+	maxDistributionErrorLength  = 512
+)
+
+// nolint: gochecknoglobals
+var (
+	distributorMetricsOnce sync.Once
+	distributions          monitoring.Counter
+	distributedTreeSize    monitoring.Gauge
+)
+
+// nolint: lll
+func createDistributorMetrics(mf monitoring.MetricFactory) {
+	distributions = mf.NewCounter("sth_distributions", "Number of attempts to push a tree head of the log to a distributor", "log", "endpoint", "status")
+	distributedTreeSize = mf.NewGauge("sth_distributed_tree_size", "Size of the latest tree head of the log delivered to a distributor", "log", "endpoint")
+}
+
+// DistributorEndpoint is an external endpoint the tree heads are pushed to (a witness network, a gossip hub or
+// a monitor), the token (optional) is sent as a bearer token.
+type DistributorEndpoint struct {
+	URL   string
+	Token string
+}
+
+// DistributedCheckpoint is the body of the push to a distributor: the checkpoint and the URL of the log.
+type DistributedCheckpoint struct {
+	Log        string                  `json:"log"`
+	Checkpoint *command.GetSTHResponse `json:"checkpoint"`
+}
+
+// Delivery tracks the tree heads pushed to a distributor.
+type Delivery struct {
+	Endpoint string `json:"endpoint"`
+	// TreeSize is the size of the latest tree head delivered, zero if none was.
+	TreeSize    uint64    `json:"tree_size"`
+	DeliveredAt time.Time `json:"delivered_at"`
+	// Failures is the number of deliveries which failed since the latest one.
+	Failures  int    `json:"failures,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Distributor pushes each new tree head of the log to the distributors, so the log is proactively transparent
+// instead of waiting to be polled. A failed push is retried with an exponential backoff, the distributors which
+// are still behind are retried with the latest tree head on the next run (a tree head supersedes the older ones).
+type Distributor struct {
+	source     Source
+	log        string
+	endpoints  []DistributorEndpoint
+	http       HTTPClient
+	attempts   int
+	backoff    time.Duration
+	onDelivery func(*Delivery) error
+	sign       func(req *http.Request, body []byte) error
+
+	mu         sync.Mutex
+	deliveries map[string]*Delivery // endpoint -> delivery
+}
+
+// DistributorOpt is an option of the distributor.
+type DistributorOpt func(*Distributor)
+
+// WithDeliveries restores the deliveries tracked before, the tree heads delivered are not pushed again.
+func WithDeliveries(deliveries []*Delivery) DistributorOpt {
+	return func(d *Distributor) {
+		for _, delivery := range deliveries {
+			if _, ok := d.deliveries[delivery.Endpoint]; ok {
+				d.deliveries[delivery.Endpoint] = delivery
+			}
+		}
+	}
+}
+
+// WithOnDelivery sets the function called with the delivery after each push, e.g. to store it.
+func WithOnDelivery(fn func(*Delivery) error) DistributorOpt {
+	return func(d *Distributor) {
+		d.onDelivery = fn
+	}
+}
+
+// WithRequestSigner sets the function signing the pushes to the distributors (e.g. the webhook signature of the
+// log), the pushes are not signed by default.
+func WithRequestSigner(sign func(req *http.Request, body []byte) error) DistributorOpt {
+	return func(d *Distributor) {
+		d.sign = sign
+	}
+}
+
+// WithRetry sets the number of attempts of a push in a run (3 by default) and the backoff before the second
+// attempt (1s by default), doubled after each attempt.
+func WithRetry(attempts int, backoff time.Duration) DistributorOpt {
+	return func(d *Distributor) {
+		d.attempts = attempts
+		d.backoff = backoff
+	}
+}
+
+// WithDistributorMetrics sets the factory of the metrics of the deliveries, the metrics are created once.
+func WithDistributorMetrics(mf monitoring.MetricFactory) DistributorOpt {
+	return func(*Distributor) {
+		distributorMetricsOnce.Do(func() { createDistributorMetrics(mf) })
+	}
+}
+
+// NewDistributor returns a distributor of the tree heads of the log (identified by its URL) to the endpoints.
+func NewDistributor(source Source, log string, endpoints []DistributorEndpoint, client HTTPClient,
+	opts ...DistributorOpt) *Distributor {
+	d := &Distributor{
+		source:     source,
+		log:        log,
+		endpoints:  endpoints,
+		http:       client,
+		attempts:   defaultDistributionAttempts,
+		backoff:    defaultDistributionBackoff,
+		deliveries: map[string]*Delivery{},
+	}
+
+	for _, endpoint := range endpoints {
+		d.deliveries[endpoint.URL] = &Delivery{Endpoint: endpoint.URL}
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	distributorMetricsOnce.Do(func() { createDistributorMetrics(monitoring.InertMetricFactory{}) })
+
+	return d
+}
+
+// Run distributes the tree heads of the log every interval until the context is done.
+func (d *Distributor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Distribute(ctx); err != nil {
+			logger.Errorf("distribute tree head of log %s: %v", d.log, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Distribute pushes the latest tree head of the log to the distributors which did not receive it, in parallel.
+// The failed deliveries are tracked (see Deliveries), they do not fail the distribution.
+func (d *Distributor) Distribute(ctx context.Context) error {
+	sth, err := d.source.GetSTH(ctx)
+	if err != nil {
+		return fmt.Errorf("get STH: %w", err)
+	}
+
+	body, err := json.Marshal(DistributedCheckpoint{Log: d.log, Checkpoint: sth})
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, endpoint := range d.endpoints {
+		if d.delivered(endpoint.URL) >= sth.TreeSize {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(endpoint DistributorEndpoint) {
+			defer wg.Done()
+
+			d.deliver(ctx, endpoint, sth.TreeSize, body)
+		}(endpoint)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// Deliveries returns the deliveries to the distributors, ordered by endpoint.
+func (d *Distributor) Deliveries() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deliveries := make([]Delivery, 0, len(d.deliveries))
+	for _, delivery := range d.deliveries {
+		deliveries = append(deliveries, *delivery)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].Endpoint < deliveries[j].Endpoint })
+
+	return deliveries
+}
+
+func (d *Distributor) delivered(endpoint string) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.deliveries[endpoint].TreeSize
+}
+
+// deliver pushes the tree head to the endpoint, it is retried while the error is transient.
+func (d *Distributor) deliver(ctx context.Context, endpoint DistributorEndpoint, treeSize uint64, body []byte) {
+	err := d.pushWithRetry(ctx, endpoint, body)
+	if err == nil {
+		distributedTreeSize.Set(float64(treeSize), d.log, endpoint.URL)
+	}
+
+	d.mu.Lock()
+
+	delivery := d.deliveries[endpoint.URL]
+
+	if err == nil {
+		delivery.TreeSize = treeSize
+		delivery.DeliveredAt = time.Now()
+		delivery.Failures = 0
+		delivery.LastError = ""
+
+		logger.Debugf("delivered tree head of size %d of log %s to %s", treeSize, d.log, endpoint.URL)
+	} else {
+		delivery.Failures++
+		delivery.LastError = err.Error()
+
+		logger.Warnf("deliver tree head of size %d of log %s to %s: %v", treeSize, d.log, endpoint.URL, err)
+	}
+
+	update := *delivery
+
+	d.mu.Unlock()
+
+	if d.onDelivery == nil {
+		return
+	}
+
+	if er := d.onDelivery(&update); er != nil {
+		logger.Errorf("track delivery of log %s to %s: %v", d.log, endpoint.URL, er)
+	}
+}
+
+func (d *Distributor) pushWithRetry(ctx context.Context, endpoint DistributorEndpoint, body []byte) error {
+	backoff := d.backoff
+
+	for attempt := 1; ; attempt++ {
+		retry, err := d.push(ctx, endpoint, body)
+		if err == nil {
+			distributions.Inc(d.log, endpoint.URL, "delivered")
+
+			return nil
+		}
+
+		distributions.Inc(d.log, endpoint.URL, "failed")
+
+		if !retry || attempt >= d.attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() // nolint: wrapcheck
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// push posts the tree head to the endpoint, it returns whether the error is transient (the endpoint is not
+// reachable, it is overloaded or it failed).
+func (d *Distributor) push(ctx context.Context, endpoint DistributorEndpoint, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", applicationJSON)
+
+	if endpoint.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+endpoint.Token)
+	}
+
+	if d.sign != nil {
+		if err = d.sign(req, body); err != nil {
+			return false, fmt.Errorf("sign: %w", err)
+		}
+	}
+
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("push: %w", err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(resp.Body) // nolint: errcheck
+	if len(msg) > maxDistributionErrorLength {
+		msg = msg[:maxDistributionErrorLength]
+	}
+
+	retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout
+
+	return retry, fmt.Errorf("push: status %d: %s", resp.StatusCode, msg)
+}
@@ -0,0 +1,397 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+// Multicodecs of the blocks of the mirror.
+const (
+	RawCodec     = 0x55
+	DAGJSONCodec = 0x0129
+)
+
+// IPFSNode stores the blocks of the mirror, KuboNode stores them on a node with the Kubo RPC API.
+type IPFSNode interface {
+	// PutBlock stores and pins the block, it returns the CID of the block.
+	PutBlock(ctx context.Context, data []byte, codec uint64) (string, error)
+	// GetBlock returns ErrNotFound if the block does not exist.
+	GetBlock(ctx context.Context, cid string) ([]byte, error)
+}
+
+// PinningService pins the roots of the mirror, PinningServiceClient pins them with the IPFS Pinning Service API.
+type PinningService interface {
+	Pin(ctx context.Context, cid, name string) error
+}
+
+// HTTPClient represents HTTP client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Link is a DAG-JSON link to a block.
+type Link struct {
+	CID string `json:"/"`
+}
+
+// IPFSManifest is the root of a published tree (DAG-JSON): the checkpoint and the resources published since the
+// previous tree, which is linked. A resource is resolved from the latest root back to the first one, so pinning
+// the latest root recursively pins the whole mirror. The fields are in the canonical (sorted) order of DAG-JSON.
+type IPFSManifest struct {
+	Checkpoint Link            `json:"checkpoint"`
+	Previous   *Link           `json:"previous,omitempty"`
+	Resources  map[string]Link `json:"resources"`
+	TreeSize   uint64          `json:"tree_size"`
+}
+
+// IPFSBucket mirrors the published resources of a log to IPFS: every resource is stored as a raw block and the
+// checkpoint completes a manifest, the new root of the mirror, which is pinned with the pinning service (if any).
+type IPFSBucket struct {
+	node    IPFSNode
+	pinning PinningService
+	name    string
+	onRoot  func(*command.IPFSRoot) error
+
+	mu        sync.Mutex
+	root      string
+	resources map[string]Link // published since the root
+}
+
+// IPFSOpt is an option of the IPFS bucket.
+type IPFSOpt func(*IPFSBucket)
+
+// WithPinningService pins the roots with the pinning service.
+func WithPinningService(pinning PinningService) IPFSOpt {
+	return func(b *IPFSBucket) {
+		b.pinning = pinning
+	}
+}
+
+// WithRoot sets the root published before, the mirror resumes from it.
+func WithRoot(root string) IPFSOpt {
+	return func(b *IPFSBucket) {
+		b.root = root
+	}
+}
+
+// WithOnRoot sets the func called with every new root, e.g. to advertise it in the metadata of the log.
+func WithOnRoot(onRoot func(*command.IPFSRoot) error) IPFSOpt {
+	return func(b *IPFSBucket) {
+		b.onRoot = onRoot
+	}
+}
+
+// NewIPFSBucket returns a bucket mirroring the log to the IPFS node, the name identifies the pins of the log.
+func NewIPFSBucket(node IPFSNode, name string, opts ...IPFSOpt) *IPFSBucket {
+	b := &IPFSBucket{node: node, name: name, resources: map[string]Link{}}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Root returns the CID of the latest manifest, empty if nothing was published.
+func (b *IPFSBucket) Root() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.root
+}
+
+// Put stores the resource as a raw block, the checkpoint is linked by a new manifest along with the resources
+// put since the previous one.
+func (b *IPFSBucket) Put(ctx context.Context, key string, data []byte, _, _ string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cid, err := b.putBlock(ctx, data, RawCodec)
+	if err != nil {
+		return err
+	}
+
+	if key != CheckpointKey {
+		b.resources[key] = Link{CID: cid}
+
+		return nil
+	}
+
+	var sth *command.GetSTHResponse
+	if err = json.Unmarshal(data, &sth); err != nil {
+		return fmt.Errorf("unmarshal checkpoint: %w", err)
+	}
+
+	manifest := IPFSManifest{Checkpoint: Link{CID: cid}, Resources: b.resources, TreeSize: sth.TreeSize}
+
+	if b.root != "" {
+		manifest.Previous = &Link{CID: b.root}
+	}
+
+	src, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	root, err := b.putBlock(ctx, src, DAGJSONCodec)
+	if err != nil {
+		return err
+	}
+
+	if b.pinning != nil {
+		if err = b.pinning.Pin(ctx, root, fmt.Sprintf("%s-%d", b.name, sth.TreeSize)); err != nil {
+			return fmt.Errorf("pin root %s: %w", root, err)
+		}
+	}
+
+	b.root, b.resources = root, map[string]Link{}
+
+	if b.onRoot != nil {
+		if err = b.onRoot(&command.IPFSRoot{CID: root, TreeSize: sth.TreeSize}); err != nil {
+			return fmt.Errorf("on root %s: %w", root, err)
+		}
+	}
+
+	return nil
+}
+
+// Get resolves the resource from the latest manifest back to the first one.
+func (b *IPFSBucket) Get(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	root := b.root
+	b.mu.Unlock()
+
+	for root != "" {
+		src, err := b.node.GetBlock(ctx, root)
+		if err != nil {
+			return nil, fmt.Errorf("get manifest %s: %w", root, err)
+		}
+
+		var manifest *IPFSManifest
+		if err = json.Unmarshal(src, &manifest); err != nil {
+			return nil, fmt.Errorf("unmarshal manifest %s: %w", root, err)
+		}
+
+		link, ok := manifest.Resources[key]
+		if key == CheckpointKey {
+			link, ok = manifest.Checkpoint, true
+		}
+
+		if ok {
+			return b.node.GetBlock(ctx, link.CID) // nolint: wrapcheck
+		}
+
+		root = ""
+
+		if manifest.Previous != nil {
+			root = manifest.Previous.CID
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// putBlock puts the block to the node, the CID returned by the node must be the CID of the data.
+func (b *IPFSBucket) putBlock(ctx context.Context, data []byte, codec uint64) (string, error) {
+	cid, err := b.node.PutBlock(ctx, data, codec)
+	if err != nil {
+		return "", fmt.Errorf("put block: %w", err)
+	}
+
+	if expected := BlockCID(data, codec); cid != expected {
+		return "", fmt.Errorf("node returned CID %s for the block %s", cid, expected)
+	}
+
+	return cid, nil
+}
+
+// BlockCID returns the CIDv1 of the block with the SHA2-256 multihash, encoded in base32.
+func BlockCID(data []byte, codec uint64) string {
+	digest := sha256.Sum256(data)
+
+	mh, _ := multihash.Encode(digest[:], multihash.SHA2_256) // nolint: errcheck
+
+	// version 1, the codec as a varint
+	prefix := []byte{1}
+	for ; codec >= 0x80; codec >>= 7 {
+		prefix = append(prefix, byte(codec)|0x80)
+	}
+
+	cid, _ := multibase.Encode(multibase.Base32, append(append(prefix, byte(codec)), mh...)) // nolint: errcheck
+
+	return cid
+}
+
+// KuboNode stores the blocks with the Kubo RPC API of an IPFS node (e.g. http://localhost:5001).
+type KuboNode struct {
+	apiURL string
+	http   HTTPClient
+}
+
+// NewKuboNode returns the client of the RPC API.
+func NewKuboNode(apiURL string, client HTTPClient) *KuboNode {
+	return &KuboNode{apiURL: strings.TrimSuffix(apiURL, "/"), http: client}
+}
+
+var codecNames = map[uint64]string{RawCodec: "raw", DAGJSONCodec: "dag-json"} // nolint: gochecknoglobals
+
+// PutBlock stores and pins the block (/api/v0/block/put).
+func (n *KuboNode) PutBlock(ctx context.Context, data []byte, codec uint64) (string, error) {
+	var body bytes.Buffer
+
+	form := multipart.NewWriter(&body)
+
+	part, err := form.CreateFormFile("file", "block")
+	if err != nil {
+		return "", fmt.Errorf("create form file: %w", err)
+	}
+
+	if _, err = part.Write(data); err != nil {
+		return "", fmt.Errorf("write form file: %w", err)
+	}
+
+	if err = form.Close(); err != nil {
+		return "", fmt.Errorf("close form: %w", err)
+	}
+
+	query := url.Values{"cid-codec": {codecNames[codec]}, "mhtype": {"sha2-256"}, "pin": {"true"}}
+
+	var result struct {
+		Key string `json:"Key"`
+	}
+
+	if err = n.call(ctx, "/api/v0/block/put?"+query.Encode(), &body, form.FormDataContentType(),
+		func(r io.Reader) error { return json.NewDecoder(r).Decode(&result) }); err != nil {
+		return "", err
+	}
+
+	return result.Key, nil
+}
+
+// GetBlock returns the block (/api/v0/block/get).
+func (n *KuboNode) GetBlock(ctx context.Context, cid string) ([]byte, error) {
+	var data []byte
+
+	err := n.call(ctx, "/api/v0/block/get?"+url.Values{"arg": {cid}}.Encode(), nil, "",
+		func(r io.Reader) error {
+			var er error
+
+			data, er = ioutil.ReadAll(r)
+
+			return er // nolint: wrapcheck
+		})
+
+	return data, err
+}
+
+// call posts to the RPC API, which takes POST requests only.
+func (n *KuboNode) call(ctx context.Context, path string, body io.Reader, contentType string,
+	decode func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.apiURL+path, body)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body) // nolint: errcheck
+
+		if resp.StatusCode == http.StatusInternalServerError && bytes.Contains(msg, []byte("not found")) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("%s: status %d: %s", strings.SplitN(path, "?", 2)[0], resp.StatusCode, msg) // nolint: gomnd
+	}
+
+	if err = decode(resp.Body); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// PinningServiceClient pins the CIDs with the IPFS Pinning Service API (POST /pins), the pinning service fetches
+// the blocks from the IPFS network.
+type PinningServiceClient struct {
+	endpoint string
+	token    string
+	http     HTTPClient
+	origins  []string
+}
+
+// NewPinningServiceClient returns the client of the pinning service, the origins (optional) are the multiaddrs of
+// the node the blocks are stored on.
+func NewPinningServiceClient(endpoint, token string, client HTTPClient, origins ...string) *PinningServiceClient {
+	return &PinningServiceClient{endpoint: strings.TrimSuffix(endpoint, "/"), token: token, http: client,
+		origins: origins}
+}
+
+// Pin requests the pinning service to pin the CID, the CID is pinned recursively.
+func (p *PinningServiceClient) Pin(ctx context.Context, cid, name string) error {
+	src, err := json.Marshal(struct {
+		CID     string   `json:"cid"`
+		Name    string   `json:"name"`
+		Origins []string `json:"origins,omitempty"`
+	}{CID: cid, Name: name, Origins: p.origins})
+	if err != nil {
+		return fmt.Errorf("marshal pin: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/pins", bytes.NewBuffer(src))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("pin: %w", err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	// the pin is queued (202) or already pinned (200)
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body) // nolint: errcheck
+
+		return fmt.Errorf("pin: status %d: %s", resp.StatusCode, msg)
+	}
+
+	return nil
+}
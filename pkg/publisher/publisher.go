@@ -0,0 +1,229 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package publisher renders a log as static resources (the checkpoint, the tiles and the entry bundles) to an
+// object storage bucket, so that reads are served entirely from the bucket (or a CDN in front of it) while
+// the live server only handles writes. The resources are laid out as the tile-based read API of the log.
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/controller/rest"
+)
+
+var logger = log.New("publisher")
+
+const (
+	// CheckpointKey is the key of the checkpoint, the signed tree head of the published tree.
+	CheckpointKey = "checkpoint"
+
+	applicationJSON        = "application/json"
+	applicationOctetStream = "application/octet-stream"
+	// immutable is the cache control of the tiles and the entry bundles, a published tile never changes.
+	immutable = "public, max-age=31536000, immutable"
+	// noCache is the cache control of the checkpoint, it is replaced as the tree grows.
+	noCache = "no-cache"
+)
+
+// ErrNotFound is returned by a bucket when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Source is the log the resources are rendered from, vct.Client reads them from the live server.
+type Source interface {
+	GetSTH(ctx context.Context) (*command.GetSTHResponse, error)
+	GetTile(ctx context.Context, level int, index uint64, width int) ([][]byte, error)
+	GetEntryBundle(ctx context.Context, index uint64, width int) ([][]byte, error)
+}
+
+// Bucket stores the resources, objects are replaced on put.
+type Bucket interface {
+	Put(ctx context.Context, key string, data []byte, contentType, cacheControl string) error
+	// Get returns ErrNotFound if the object does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Publisher publishes the log to the bucket incrementally: the full tiles published before are not rendered
+// again, only the tiles which grew since the last checkpoint.
+type Publisher struct {
+	source Source
+	bucket Bucket
+
+	mu        sync.Mutex
+	published *uint64 // the size of the published tree, nil until the checkpoint is read from the bucket
+}
+
+// New returns a publisher of the log to the bucket.
+func New(source Source, bucket Bucket) *Publisher {
+	return &Publisher{source: source, bucket: bucket}
+}
+
+// Run publishes the log every interval until the context is done, failed runs are logged and retried on
+// the next interval.
+func (p *Publisher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		size, err := p.Publish(ctx)
+		if err != nil {
+			logger.Errorf("publish log: %v", err)
+		} else {
+			logger.Debugf("published log of size %d", size)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish publishes the latest tree of the log and returns its size. The tiles and the entry bundles are put
+// before the checkpoint, so the published checkpoint never refers to resources which are not published yet.
+func (p *Publisher) Publish(ctx context.Context) (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.published == nil {
+		size, err := p.checkpointSize(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		p.published = &size
+	}
+
+	sth, err := p.source.GetSTH(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get STH: %w", err)
+	}
+
+	if sth.TreeSize <= *p.published {
+		return *p.published, nil
+	}
+
+	if err = p.publishEntries(ctx, *p.published, sth.TreeSize); err != nil {
+		return 0, err
+	}
+
+	for level := 0; level <= command.MaxTileLevel; level++ {
+		shift := uint(command.TileHeight * level)
+		if sth.TreeSize>>shift == 0 {
+			break
+		}
+
+		if err = p.publishTiles(ctx, level, *p.published>>shift, sth.TreeSize>>shift); err != nil {
+			return 0, err
+		}
+	}
+
+	checkpoint, err := json.Marshal(sth)
+	if err != nil {
+		return 0, fmt.Errorf("marshal checkpoint: %w", err)
+	}
+
+	if err = p.bucket.Put(ctx, CheckpointKey, checkpoint, applicationJSON, noCache); err != nil {
+		return 0, fmt.Errorf("put checkpoint: %w", err)
+	}
+
+	*p.published = sth.TreeSize
+
+	return sth.TreeSize, nil
+}
+
+// checkpointSize returns the size of the tree published before, zero if nothing was published.
+func (p *Publisher) checkpointSize(ctx context.Context) (uint64, error) {
+	src, err := p.bucket.Get(ctx, CheckpointKey)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("get checkpoint: %w", err)
+	}
+
+	var sth *command.GetSTHResponse
+	if err = json.Unmarshal(src, &sth); err != nil {
+		return 0, fmt.Errorf("unmarshal checkpoint: %w", err)
+	}
+
+	return sth.TreeSize, nil
+}
+
+// publishTiles publishes the tiles of the level which changed while the number of nodes of the level grew from
+// the published to the size: the tiles which became full and the last partial tile.
+func (p *Publisher) publishTiles(ctx context.Context, level int, published, size uint64) error {
+	return forEachTile(published, size, func(index uint64, width int) error {
+		hashes, err := p.source.GetTile(ctx, level, index, width)
+		if err != nil {
+			return fmt.Errorf("get tile %d/%d: %w", level, index, err)
+		}
+
+		var tile []byte
+		for _, hash := range hashes {
+			tile = append(tile, hash...)
+		}
+
+		return p.put(ctx, "tile/"+strconv.Itoa(level)+"/"+rest.TileIndexPath(int64(index), width), tile)
+	})
+}
+
+// publishEntries publishes the entry bundles which changed while the tree grew from the published to the size.
+func (p *Publisher) publishEntries(ctx context.Context, published, size uint64) error {
+	return forEachTile(published, size, func(index uint64, width int) error {
+		entries, err := p.source.GetEntryBundle(ctx, index, width)
+		if err != nil {
+			return fmt.Errorf("get entry bundle %d: %w", index, err)
+		}
+
+		var bundle []byte
+		for _, entry := range entries {
+			size := len(entry)
+			bundle = append(append(bundle, byte(size>>16), byte(size>>8), byte(size)), entry...)
+		}
+
+		return p.put(ctx, "tile/entries/"+rest.TileIndexPath(int64(index), width), bundle)
+	})
+}
+
+func (p *Publisher) put(ctx context.Context, key string, data []byte) error {
+	if err := p.bucket.Put(ctx, key, data, applicationOctetStream, immutable); err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// forEachTile calls fn for the tiles which changed while the number of nodes of their level grew from
+// the published to the size, the width is zero for a full tile.
+func forEachTile(published, size uint64, fn func(index uint64, width int) error) error {
+	if size <= published {
+		return nil
+	}
+
+	for index := published / command.TileWidth; index < size/command.TileWidth; index++ {
+		if err := fn(index, 0); err != nil {
+			return err
+		}
+	}
+
+	if width := int(size % command.TileWidth); width > 0 {
+		return fn(size/command.TileWidth, width)
+	}
+
+	return nil
+}
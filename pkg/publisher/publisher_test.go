@@ -0,0 +1,676 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package publisher_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	. "github.com/trustbloc/vct/pkg/publisher"
+)
+
+// source serves a log of the size, the hashes of the tiles are derived from their position.
+type source struct {
+	size  uint64
+	tiles []string
+	err   error
+}
+
+func (s *source) GetSTH(context.Context) (*command.GetSTHResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return &command.GetSTHResponse{TreeSize: s.size, SHA256RootHash: []byte("root")}, nil
+}
+
+func (s *source) GetTile(_ context.Context, level int, index uint64, width int) ([][]byte, error) {
+	s.tiles = append(s.tiles, fmt.Sprintf("%d/%d.%d", level, index, width))
+
+	if width == 0 {
+		width = command.TileWidth
+	}
+
+	hashes := make([][]byte, width)
+
+	for i := range hashes {
+		hash := sha256.Sum256([]byte(fmt.Sprintf("%d/%d", level, index*command.TileWidth+uint64(i))))
+		hashes[i] = hash[:]
+	}
+
+	return hashes, nil
+}
+
+func (s *source) GetEntryBundle(_ context.Context, index uint64, width int) ([][]byte, error) {
+	if width == 0 {
+		width = command.TileWidth
+	}
+
+	entries := make([][]byte, width)
+
+	for i := range entries {
+		entries[i] = []byte(fmt.Sprintf("entry %d", index*command.TileWidth+uint64(i)))
+	}
+
+	return entries, nil
+}
+
+type bucket struct {
+	objects map[string][]byte
+	headers map[string]string
+}
+
+func newBucket() *bucket {
+	return &bucket{objects: map[string][]byte{}, headers: map[string]string{}}
+}
+
+func (b *bucket) Put(_ context.Context, key string, data []byte, contentType, cacheControl string) error {
+	b.objects[key] = data
+	b.headers[key] = contentType + "; " + cacheControl
+
+	return nil
+}
+
+func (b *bucket) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (b *bucket) keys() []string {
+	var keys []string
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	ctx := context.Background()
+
+	src, dst := &source{size: 300}, newBucket()
+
+	size, err := New(src, dst).Publish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), size)
+	require.Equal(t, []string{
+		"checkpoint", "tile/0/000", "tile/0/001.p/44", "tile/1/000.p/1", "tile/entries/000", "tile/entries/001.p/44",
+	}, dst.keys())
+	require.Len(t, dst.objects["tile/0/000"], command.TileWidth*sha256.Size)
+	require.Equal(t, "application/octet-stream; public, max-age=31536000, immutable", dst.headers["tile/0/000"])
+	require.Equal(t, "application/json; no-cache", dst.headers["checkpoint"])
+	require.Equal(t, []byte("\x00\x00\x09entry 256"), dst.objects["tile/entries/001.p/44"][:12])
+
+	var sth *command.GetSTHResponse
+	require.NoError(t, json.Unmarshal(dst.objects["checkpoint"], &sth))
+	require.Equal(t, uint64(300), sth.TreeSize)
+
+	// a new publisher resumes from the checkpoint, only the tiles which grew are published
+	src = &source{size: 520}
+	publisher := New(src, dst)
+
+	size, err = publisher.Publish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(520), size)
+	require.Equal(t, []string{"0/1.0", "0/2.8", "1/0.2"}, src.tiles)
+	require.Contains(t, dst.keys(), "tile/0/002.p/8")
+
+	// nothing changed
+	src.tiles = nil
+
+	size, err = publisher.Publish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(520), size)
+	require.Empty(t, src.tiles)
+}
+
+func TestPublisher_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	dst := newBucket()
+
+	done := make(chan struct{})
+
+	go func() {
+		New(&source{err: errors.New("unavailable")}, dst).Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+
+	require.Empty(t, dst.keys())
+}
+
+func TestPublisher_Errors(t *testing.T) {
+	_, err := New(&source{err: errors.New("unavailable")}, newBucket()).Publish(context.Background())
+	require.EqualError(t, err, "get STH: unavailable")
+
+	dst := newBucket()
+	dst.objects[CheckpointKey] = []byte("{")
+
+	_, err = New(&source{size: 1}, dst).Publish(context.Background())
+	require.Contains(t, err.Error(), "unmarshal checkpoint")
+}
+
+func TestDirBucket(t *testing.T) {
+	ctx := context.Background()
+
+	b := NewDirBucket(t.TempDir())
+
+	_, err := b.Get(ctx, "tile/0/000")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, b.Put(ctx, "tile/0/000.p/1", []byte("a"), "", ""))
+	require.NoError(t, b.Put(ctx, "tile/0/000", []byte("b"), "", ""))
+	require.NoError(t, b.Put(ctx, "tile/0/000", []byte("c"), "", ""))
+
+	data, err := b.Get(ctx, "tile/0/000.p/1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("a"), data)
+
+	data, err = b.Get(ctx, "tile/0/000")
+	require.NoError(t, err)
+	require.Equal(t, []byte("c"), data)
+}
+
+type s3Client struct {
+	objects map[string]*s3.PutObjectInput
+}
+
+func (c *s3Client) PutObjectWithContext(_ aws.Context, input *s3.PutObjectInput,
+	_ ...request.Option) (*s3.PutObjectOutput, error) {
+	c.objects[*input.Bucket+"/"+*input.Key] = input
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (c *s3Client) GetObjectWithContext(_ aws.Context, input *s3.GetObjectInput,
+	_ ...request.Option) (*s3.GetObjectOutput, error) {
+	object, ok := c.objects[*input.Bucket+"/"+*input.Key]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(object.Body)}, nil
+}
+
+func TestS3Bucket(t *testing.T) {
+	ctx := context.Background()
+
+	client := &s3Client{objects: map[string]*s3.PutObjectInput{}}
+	b := NewS3Bucket(client, "logs", "maple2021")
+
+	_, err := b.Get(ctx, CheckpointKey)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, b.Put(ctx, CheckpointKey, []byte("{}"), "application/json", "no-cache"))
+
+	object := client.objects["logs/maple2021/checkpoint"]
+	require.NotNil(t, object)
+	require.Equal(t, "application/json", *object.ContentType)
+	require.Equal(t, "no-cache", *object.CacheControl)
+
+	data, err := b.Get(ctx, CheckpointKey)
+	require.NoError(t, err)
+	require.Equal(t, []byte("{}"), data)
+
+	_, err = NewS3Bucket(&failingS3{}, "logs", "").Get(ctx, CheckpointKey)
+	require.EqualError(t, err, "get object: error")
+}
+
+type failingS3 struct{}
+
+func (c *failingS3) PutObjectWithContext(aws.Context, *s3.PutObjectInput,
+	...request.Option) (*s3.PutObjectOutput, error) {
+	return nil, errors.New("error")
+}
+
+func (c *failingS3) GetObjectWithContext(aws.Context, *s3.GetObjectInput,
+	...request.Option) (*s3.GetObjectOutput, error) {
+	return nil, errors.New("error")
+}
+
+func TestOpenBucket(t *testing.T) {
+	b, err := OpenBucket(t.TempDir(), "", "")
+	require.NoError(t, err)
+	require.IsType(t, &DirBucket{}, b)
+
+	b, err = OpenBucket("s3://logs/maple2021", "https://storage.googleapis.com", "")
+	require.NoError(t, err)
+	require.IsType(t, &S3Bucket{}, b)
+
+	_, err = OpenBucket("s3:///maple2021", "", "")
+	require.EqualError(t, err, "s3:///maple2021 is not a valid bucket URL")
+}
+
+// kubo serves the block API of the Kubo RPC API from memory.
+type kubo struct {
+	mu     sync.Mutex
+	blocks map[string][]byte
+	lie    bool // answers with a CID which is not the CID of the block
+}
+
+func (k *kubo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/v0/block/put":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		data, _ := ioutil.ReadAll(file) // nolint: errcheck
+
+		codec := uint64(RawCodec)
+		if r.URL.Query().Get("cid-codec") == "dag-json" {
+			codec = DAGJSONCodec
+		}
+
+		cid := BlockCID(data, codec)
+		k.blocks[cid] = data
+
+		if k.lie {
+			cid = "bafkreilie"
+		}
+
+		fmt.Fprintf(w, `{"Key":%q,"Size":%d}`, cid, len(data))
+	case "/api/v0/block/get":
+		data, ok := k.blocks[r.URL.Query().Get("arg")]
+		if !ok {
+			http.Error(w, `{"Message":"block was not found locally (offline): ipld: could not find node"}`,
+				http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Write(data) // nolint: errcheck
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type pinningService struct {
+	pins []string
+}
+
+func (p *pinningService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/pins" || r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	var pin struct {
+		CID  string `json:"cid"`
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	p.pins = append(p.pins, pin.Name+"="+pin.CID)
+
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, `{"requestid":"%d","status":"queued","pin":{"cid":%q}}`, len(p.pins), pin.CID)
+}
+
+func TestBlockCID(t *testing.T) {
+	require.Equal(t, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku", BlockCID(nil, RawCodec))
+	require.Equal(t, command.EntryCID([]byte("entry")), BlockCID([]byte("entry"), RawCodec))
+	require.True(t, strings.HasPrefix(BlockCID([]byte("{}"), DAGJSONCodec), "baguqeera"))
+}
+
+func TestIPFSBucket(t *testing.T) { // nolint: funlen
+	ctx := context.Background()
+
+	node := &kubo{blocks: map[string][]byte{}}
+	nodeServer := httptest.NewServer(node)
+	defer nodeServer.Close()
+
+	pinning := &pinningService{}
+	pinningServer := httptest.NewServer(pinning)
+	defer pinningServer.Close()
+
+	var roots []*command.IPFSRoot
+
+	dst := NewIPFSBucket(NewKuboNode(nodeServer.URL+"/", http.DefaultClient), "maple2021",
+		WithPinningService(NewPinningServiceClient(pinningServer.URL, "secret", http.DefaultClient)),
+		WithOnRoot(func(root *command.IPFSRoot) error {
+			roots = append(roots, root)
+
+			return nil
+		}),
+	)
+
+	_, err := dst.Get(ctx, CheckpointKey)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	size, err := New(&source{size: 300}, dst).Publish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(300), size)
+	require.Len(t, roots, 1)
+	require.Equal(t, &command.IPFSRoot{CID: dst.Root(), TreeSize: 300}, roots[0])
+	require.Equal(t, []string{"maple2021-300=" + dst.Root()}, pinning.pins)
+
+	var manifest *IPFSManifest
+	require.NoError(t, json.Unmarshal(node.blocks[dst.Root()], &manifest))
+	require.Nil(t, manifest.Previous)
+	require.Equal(t, uint64(300), manifest.TreeSize)
+	require.Len(t, manifest.Resources, 5)
+
+	entries, err := dst.Get(ctx, "tile/entries/001.p/44")
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x00\x00\x09entry 256"), entries[:12])
+	require.Equal(t, manifest.Resources["tile/entries/001.p/44"].CID, BlockCID(entries, RawCodec))
+
+	// a new bucket resumes from the root, the new manifest links the previous one
+	first := dst.Root()
+	dst = NewIPFSBucket(NewKuboNode(nodeServer.URL, http.DefaultClient), "maple2021", WithRoot(first))
+
+	size, err = New(&source{size: 520}, dst).Publish(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(520), size)
+	require.NotEqual(t, first, dst.Root())
+
+	manifest = nil
+	require.NoError(t, json.Unmarshal(node.blocks[dst.Root()], &manifest))
+	require.Equal(t, &Link{CID: first}, manifest.Previous)
+	require.Equal(t, uint64(520), manifest.TreeSize)
+	require.NotContains(t, manifest.Resources, "tile/entries/000")
+
+	// resources published before the root are resolved from the previous manifests
+	entries, err = dst.Get(ctx, "tile/entries/000")
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x00\x00\x07entry 0"), entries[:10])
+
+	_, err = dst.Get(ctx, "tile/unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	var sth *command.GetSTHResponse
+
+	checkpoint, err := dst.Get(ctx, CheckpointKey)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(checkpoint, &sth))
+	require.Equal(t, uint64(520), sth.TreeSize)
+}
+
+func TestIPFSBucket_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	node := &kubo{blocks: map[string][]byte{}, lie: true}
+	nodeServer := httptest.NewServer(node)
+	defer nodeServer.Close()
+
+	dst := NewIPFSBucket(NewKuboNode(nodeServer.URL, http.DefaultClient), "maple2021")
+
+	err := dst.Put(ctx, "tile/0/000", []byte("tile"), "", "")
+	require.EqualError(t, err, fmt.Sprintf("node returned CID bafkreilie for the block %s",
+		BlockCID([]byte("tile"), RawCodec)))
+
+	node.lie = false
+
+	require.Contains(t, dst.Put(ctx, CheckpointKey, []byte("{"), "", "").Error(), "unmarshal checkpoint")
+
+	pinningServer := httptest.NewServer(&pinningService{})
+	defer pinningServer.Close()
+
+	dst = NewIPFSBucket(NewKuboNode(nodeServer.URL, http.DefaultClient), "maple2021",
+		WithPinningService(NewPinningServiceClient(pinningServer.URL, "wrong", http.DefaultClient)))
+
+	err = dst.Put(ctx, CheckpointKey, []byte(`{"tree_size":1}`), "", "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pin: status 401: unauthorized")
+	require.Empty(t, dst.Root())
+
+	dst = NewIPFSBucket(NewKuboNode(nodeServer.URL, http.DefaultClient), "maple2021", WithRoot("bafyreiunknown"))
+
+	_, err = dst.Get(ctx, CheckpointKey)
+	require.Contains(t, err.Error(), "get manifest bafyreiunknown")
+
+	_, err = NewKuboNode("http://localhost:0", http.DefaultClient).GetBlock(ctx, "bafkreinothing")
+	require.Error(t, err)
+}
+
+// distributor accepts the pushed checkpoints after failing the first ones with the status.
+type distributor struct {
+	mu          sync.Mutex
+	fail        int
+	status      int
+	checkpoints []*DistributedCheckpoint
+}
+
+func (d *distributor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	if d.fail > 0 {
+		d.fail--
+
+		http.Error(w, "failed", d.status)
+
+		return
+	}
+
+	var checkpoint *DistributedCheckpoint
+	if err := json.NewDecoder(r.Body).Decode(&checkpoint); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	d.checkpoints = append(d.checkpoints, checkpoint)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (d *distributor) pushed() []uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var sizes []uint64
+	for _, checkpoint := range d.checkpoints {
+		sizes = append(sizes, checkpoint.Checkpoint.TreeSize)
+	}
+
+	return sizes
+}
+
+func TestDistributor(t *testing.T) { // nolint: funlen
+	const logURL = "https://vct.example.com/maple2021"
+
+	hub := &distributor{}
+	witness := &distributor{fail: 2, status: http.StatusServiceUnavailable}
+
+	hubServer := httptest.NewServer(hub)
+	defer hubServer.Close()
+
+	witnessServer := httptest.NewServer(witness)
+	defer witnessServer.Close()
+
+	endpoints := []DistributorEndpoint{
+		{URL: hubServer.URL, Token: "secret"},
+		{URL: witnessServer.URL, Token: "secret"},
+	}
+
+	var (
+		mu      sync.Mutex
+		tracked []Delivery
+	)
+
+	src := &source{size: 10}
+	d := NewDistributor(src, logURL, endpoints, http.DefaultClient,
+		WithRetry(2, time.Millisecond),
+		WithOnDelivery(func(delivery *Delivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			tracked = append(tracked, *delivery)
+
+			return nil
+		}),
+	)
+
+	// the witness fails both attempts, it is retried on the next run
+	require.NoError(t, d.Distribute(context.Background()))
+	require.Equal(t, []uint64{10}, hub.pushed())
+	require.Empty(t, witness.pushed())
+	require.Equal(t, logURL, hub.checkpoints[0].Log)
+	require.Equal(t, []byte("root"), hub.checkpoints[0].Checkpoint.SHA256RootHash)
+
+	// deliveries returns the deliveries to the hub and the witness
+	deliveries := func() (Delivery, Delivery) {
+		all := d.Deliveries()
+		require.Len(t, all, 2)
+
+		if all[0].Endpoint != hubServer.URL {
+			return all[1], all[0]
+		}
+
+		return all[0], all[1]
+	}
+
+	toHub, toWitness := deliveries()
+	require.Equal(t, uint64(10), toHub.TreeSize)
+	require.NotZero(t, toHub.DeliveredAt)
+	require.Zero(t, toWitness.TreeSize)
+	require.Equal(t, 1, toWitness.Failures)
+	require.Contains(t, toWitness.LastError, "push: status 503: failed")
+	require.Len(t, tracked, 2)
+
+	// the tree head delivered is not pushed again
+	require.NoError(t, d.Distribute(context.Background()))
+	require.Equal(t, []uint64{10}, hub.pushed())
+	require.Equal(t, []uint64{10}, witness.pushed())
+
+	_, toWitness = deliveries()
+	require.Equal(t, uint64(10), toWitness.TreeSize)
+	require.Zero(t, toWitness.Failures)
+	require.Empty(t, toWitness.LastError)
+
+	src.size = 12
+
+	require.NoError(t, d.Distribute(context.Background()))
+	require.Equal(t, []uint64{10, 12}, hub.pushed())
+	require.Equal(t, []uint64{10, 12}, witness.pushed())
+	require.Len(t, tracked, 5)
+
+	t.Run("Restored deliveries", func(t *testing.T) {
+		restored := NewDistributor(src, logURL, endpoints, http.DefaultClient,
+			WithDeliveries([]*Delivery{{Endpoint: hubServer.URL, TreeSize: 12}, {Endpoint: "unknown", TreeSize: 1}}))
+		require.NoError(t, restored.Distribute(context.Background()))
+		require.Equal(t, []uint64{10, 12}, hub.pushed())
+		require.Equal(t, []uint64{10, 12, 12}, witness.pushed())
+		require.Len(t, restored.Deliveries(), 2)
+	})
+
+	t.Run("Permanent error", func(t *testing.T) {
+		rejecting := &distributor{fail: 2, status: http.StatusBadRequest}
+
+		server := httptest.NewServer(rejecting)
+		defer server.Close()
+
+		rd := NewDistributor(src, logURL, []DistributorEndpoint{{URL: server.URL, Token: "secret"}},
+			http.DefaultClient, WithRetry(3, time.Millisecond))
+		require.NoError(t, rd.Distribute(context.Background()))
+
+		// a client error is not retried in the run
+		require.Equal(t, 1, rejecting.fail)
+		require.Contains(t, rd.Deliveries()[0].LastError, "status 400")
+	})
+
+	t.Run("Signed pushes", func(t *testing.T) {
+		var signed []byte
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, string(body), r.Header.Get("Signature"))
+
+			signed = body
+		}))
+		defer server.Close()
+
+		sd := NewDistributor(src, logURL, []DistributorEndpoint{{URL: server.URL}}, http.DefaultClient,
+			WithRequestSigner(func(req *http.Request, body []byte) error {
+				req.Header.Set("Signature", string(body))
+
+				return nil
+			}))
+		require.NoError(t, sd.Distribute(context.Background()))
+		require.Contains(t, string(signed), logURL)
+
+		sd = NewDistributor(src, logURL, []DistributorEndpoint{{URL: server.URL}}, http.DefaultClient,
+			WithRetry(1, time.Millisecond),
+			WithRequestSigner(func(*http.Request, []byte) error {
+				return errors.New("no key")
+			}))
+		require.NoError(t, sd.Distribute(context.Background()))
+		require.Equal(t, "sign: no key", sd.Deliveries()[0].LastError)
+	})
+
+	t.Run("Source error", func(t *testing.T) {
+		failing := NewDistributor(&source{err: errors.New("unavailable")}, logURL, endpoints, http.DefaultClient)
+		require.EqualError(t, failing.Distribute(context.Background()), "get STH: unavailable")
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		src.size = 13
+
+		done := make(chan struct{})
+
+		go func() {
+			d.Run(ctx, time.Hour)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return len(hub.pushed()) == 3 }, time.Second, time.Millisecond)
+
+		cancel()
+		<-done
+	})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package roughtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+)
+
+var logger = log.New("roughtime")
+
+// ErrNotSynced is returned by Clock.Now if the clock was not synced within its max age.
+var ErrNotSynced = errors.New("trusted time is not synced")
+
+// Clock tells the time derived from the last response of the servers: the midpoint of the response plus the time
+// elapsed since it was received on the monotonic local clock, so the wall clock of the host is not trusted.
+type Clock struct {
+	servers []Server
+	maxAge  time.Duration
+	query   func(context.Context, Server) (*Response, error)
+
+	mu       sync.Mutex
+	response *Response
+	received time.Time
+}
+
+// NewClock returns a clock synced with the first available of the servers, the time is not told once the last
+// sync is older than the max age.
+func NewClock(servers []Server, maxAge time.Duration) *Clock {
+	return &Clock{servers: servers, maxAge: maxAge, query: Query}
+}
+
+// Sync queries the servers in order until one of them answers.
+func (c *Clock) Sync(ctx context.Context) error {
+	var errs []string
+
+	for _, server := range c.servers {
+		resp, err := c.query(ctx, server)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", server.Address, err))
+
+			continue
+		}
+
+		c.mu.Lock()
+		c.response, c.received = resp, time.Now()
+		c.mu.Unlock()
+
+		return nil
+	}
+
+	return fmt.Errorf("no server answered: %s", strings.Join(errs, "; "))
+}
+
+// Run syncs the clock at the interval until the context is done.
+func (c *Clock) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Sync(ctx); err != nil {
+				logger.Warnf("sync trusted time: %v", err)
+			}
+		}
+	}
+}
+
+// Now returns the time and the response of the server it is derived from.
+func (c *Clock) Now() (time.Time, *Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.response == nil {
+		return time.Time{}, nil, ErrNotSynced
+	}
+
+	elapsed := time.Since(c.received)
+	if elapsed > c.maxAge {
+		return time.Time{}, nil, fmt.Errorf("%w: last synced %s ago", ErrNotSynced, elapsed.Round(time.Second))
+	}
+
+	return c.response.Midpoint.Add(elapsed), c.response, nil
+}
@@ -0,0 +1,448 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package roughtime implements a client of the Roughtime protocol (the Google version spoken by the public
+// servers), an authenticated time source: the responses are signed by the time server, so the time they report
+// can be verified by anyone knowing the public key of the server.
+package roughtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// NonceSize is the size of the nonce of a request.
+	NonceSize = 64
+	// requestSize is the min size of a request, it is padded to prevent amplification attacks.
+	requestSize = 1024
+	// maxResponseSize is the max size of a response read.
+	maxResponseSize = 4096
+	// defaultTimeout is the timeout of a query if the context has no deadline.
+	defaultTimeout = 5 * time.Second
+
+	certificateContext    = "RoughTime v1 delegation signature--\x00"
+	signedResponseContext = "RoughTime v1 response signature\x00"
+)
+
+// tags of the messages.
+var (
+	tagCERT = tag("CERT")
+	tagDELE = tag("DELE")
+	tagINDX = tag("INDX")
+	tagMAXT = tag("MAXT")
+	tagMIDP = tag("MIDP")
+	tagMINT = tag("MINT")
+	tagNONC = tag("NONC")
+	tagPAD  = tag("PAD\xff")
+	tagPATH = tag("PATH")
+	tagPUBK = tag("PUBK")
+	tagRADI = tag("RADI")
+	tagROOT = tag("ROOT")
+	tagSIG  = tag("SIG\x00")
+	tagSREP = tag("SREP")
+)
+
+func tag(name string) uint32 {
+	return binary.LittleEndian.Uint32([]byte(name))
+}
+
+// Server is a Roughtime server.
+type Server struct {
+	// Address is the UDP address of the server (e.g. roughtime.example.com:2002).
+	Address string
+	// PublicKey is the long-term Ed25519 public key of the server.
+	PublicKey ed25519.PublicKey
+}
+
+// ParseServers parses the comma-separated servers <address>@<base64 public key>.
+func ParseServers(spec string) ([]Server, error) {
+	const serverParts = 2
+
+	var servers []Server
+
+	for _, val := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(val), "@", serverParts)
+		if len(parts) != serverParts || parts[0] == "" {
+			return nil, fmt.Errorf("server %q must be <address>@<public key>", val)
+		}
+
+		publicKey, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("server %q: public key is not a base64-encoded Ed25519 key", val)
+		}
+
+		servers = append(servers, Server{Address: parts[0], PublicKey: publicKey})
+	}
+
+	return servers, nil
+}
+
+// Response is the verified response of a server.
+type Response struct {
+	// Server is the address of the server, set by Query.
+	Server string
+	// Midpoint is the time reported by the server.
+	Midpoint time.Time
+	// Radius is the uncertainty of the midpoint, the true time is within Midpoint±Radius.
+	Radius time.Duration
+	// Nonce is the nonce of the request.
+	Nonce []byte
+	// Raw is the signed response, it proves the server reported the midpoint after receiving the nonce.
+	Raw []byte
+}
+
+// Query requests the time to the server and verifies its response.
+func Query(ctx context.Context, server Server) (*Response, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+
+	request, err := NewRequest(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", server.Address)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	defer conn.Close() // nolint: errcheck
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("set deadline: %w", err)
+		}
+	}
+
+	if _, err = conn.Write(request); err != nil {
+		return nil, fmt.Errorf("write request: %w", err)
+	}
+
+	buf := make([]byte, maxResponseSize)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	resp, err := Verify(server.PublicKey, nonce, buf[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Server = server.Address
+
+	return resp, nil
+}
+
+// NewRequest returns the request of the time for the nonce.
+func NewRequest(nonce []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		return nil, fmt.Errorf("nonce must be %d bytes", NonceSize)
+	}
+
+	msg := map[uint32][]byte{tagNONC: nonce, tagPAD: nil}
+	msg[tagPAD] = make([]byte, requestSize-len(Encode(msg)))
+
+	return Encode(msg), nil
+}
+
+// Verify verifies the response of the server of the public key to the request of the nonce: the delegation of
+// the online key by the long-term key, the signature of the response by the online key, the inclusion of the
+// nonce in the signed Merkle tree of the nonces and the validity of the delegation at the midpoint.
+func Verify(publicKey ed25519.PublicKey, nonce, raw []byte) (*Response, error) { // nolint: funlen
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key")
+	}
+
+	msg, err := Decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	cert, err := decodeField(msg, tagCERT)
+	if err != nil {
+		return nil, err
+	}
+
+	dele, err := field(cert, tagDELE, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	certSig, err := field(cert, tagSIG, ed25519.SignatureSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(publicKey, append([]byte(certificateContext), dele...), certSig) {
+		return nil, errors.New("invalid delegation signature")
+	}
+
+	delegation, err := Decode(dele)
+	if err != nil {
+		return nil, fmt.Errorf("decode delegation: %w", err)
+	}
+
+	onlineKey, err := field(delegation, tagPUBK, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	srep, err := field(msg, tagSREP, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := field(msg, tagSIG, ed25519.SignatureSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ed25519.Verify(onlineKey, append([]byte(signedResponseContext), srep...), sig) {
+		return nil, errors.New("invalid response signature")
+	}
+
+	signed, err := Decode(srep)
+	if err != nil {
+		return nil, fmt.Errorf("decode signed response: %w", err)
+	}
+
+	root, err := field(signed, tagROOT, sha512.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = verifyNonce(msg, nonce, root); err != nil {
+		return nil, err
+	}
+
+	midpoint, err := uint64Field(signed, tagMIDP)
+	if err != nil {
+		return nil, err
+	}
+
+	radius, err := field(signed, tagRADI, 4) // nolint: gomnd
+	if err != nil {
+		return nil, err
+	}
+
+	minTime, err := uint64Field(delegation, tagMINT)
+	if err != nil {
+		return nil, err
+	}
+
+	maxTime, err := uint64Field(delegation, tagMAXT)
+	if err != nil {
+		return nil, err
+	}
+
+	if midpoint < minTime || midpoint > maxTime {
+		return nil, errors.New("midpoint is outside of the validity of the delegation")
+	}
+
+	return &Response{
+		Midpoint: time.Unix(0, int64(midpoint)*int64(time.Microsecond)),
+		Radius:   time.Duration(binary.LittleEndian.Uint32(radius)) * time.Microsecond,
+		Nonce:    nonce,
+		Raw:      raw,
+	}, nil
+}
+
+// verifyNonce verifies the path of the nonce to the root of the Merkle tree of the nonces of the batch.
+func verifyNonce(msg map[uint32][]byte, nonce, root []byte) error {
+	index, err := field(msg, tagINDX, 4) // nolint: gomnd
+	if err != nil {
+		return err
+	}
+
+	path, err := field(msg, tagPATH, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(path)%sha512.Size != 0 {
+		return errors.New("invalid path")
+	}
+
+	i := binary.LittleEndian.Uint32(index)
+	hash := hashLeaf(nonce)
+
+	for ; len(path) > 0; path = path[sha512.Size:] {
+		if i&1 == 0 {
+			hash = hashNode(hash, path[:sha512.Size])
+		} else {
+			hash = hashNode(path[:sha512.Size], hash)
+		}
+
+		i >>= 1
+	}
+
+	if i != 0 || !bytes.Equal(hash, root) {
+		return errors.New("nonce is not included in the response")
+	}
+
+	return nil
+}
+
+func hashLeaf(leaf []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{0}) // nolint: errcheck
+	h.Write(leaf)      // nolint: errcheck
+
+	return h.Sum(nil)
+}
+
+func hashNode(left, right []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{1}) // nolint: errcheck
+	h.Write(left)      // nolint: errcheck
+	h.Write(right)     // nolint: errcheck
+
+	return h.Sum(nil)
+}
+
+// field returns the value of the tag, its size must be the size if not zero.
+func field(msg map[uint32][]byte, t uint32, size int) ([]byte, error) {
+	val, ok := msg[t]
+	if !ok {
+		return nil, fmt.Errorf("missing tag %q", tagName(t))
+	}
+
+	if size > 0 && len(val) != size {
+		return nil, fmt.Errorf("tag %q must be %d bytes", tagName(t), size)
+	}
+
+	return val, nil
+}
+
+func decodeField(msg map[uint32][]byte, t uint32) (map[uint32][]byte, error) {
+	val, err := field(msg, t, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := Decode(val)
+	if err != nil {
+		return nil, fmt.Errorf("decode %q: %w", tagName(t), err)
+	}
+
+	return decoded, nil
+}
+
+func uint64Field(msg map[uint32][]byte, t uint32) (uint64, error) {
+	val, err := field(msg, t, 8) // nolint: gomnd
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(val), nil
+}
+
+func tagName(t uint32) string {
+	name := make([]byte, 4) // nolint: gomnd
+	binary.LittleEndian.PutUint32(name, t)
+
+	return strings.TrimRight(string(name), "\x00\xff")
+}
+
+// Encode encodes the message of the tags: the number of tags, the offsets of the values (but the first), the tags
+// in ascending order and the values. The sizes of the values must be multiple of 4.
+func Encode(msg map[uint32][]byte) []byte {
+	tags := make([]uint32, 0, len(msg))
+	for t := range msg {
+		tags = append(tags, t)
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	var header, values bytes.Buffer
+
+	binary.Write(&header, binary.LittleEndian, uint32(len(tags))) // nolint: errcheck,gosec
+
+	for i, t := range tags {
+		if i > 0 {
+			binary.Write(&header, binary.LittleEndian, uint32(values.Len())) // nolint: errcheck,gosec
+		}
+
+		values.Write(msg[t])
+	}
+
+	for _, t := range tags {
+		binary.Write(&header, binary.LittleEndian, t) // nolint: errcheck,gosec
+	}
+
+	return append(header.Bytes(), values.Bytes()...)
+}
+
+// Decode decodes the message.
+func Decode(data []byte) (map[uint32][]byte, error) {
+	const word = 4
+
+	if len(data) < word || len(data)%word != 0 {
+		return nil, errors.New("message must be a non-empty multiple of 4 bytes")
+	}
+
+	n := int(binary.LittleEndian.Uint32(data))
+	if n == 0 {
+		return map[uint32][]byte{}, nil
+	}
+
+	headerSize := word * 2 * n // nolint: gomnd
+	if n > len(data)/(word*2) || headerSize > len(data) {
+		return nil, errors.New("message is too short")
+	}
+
+	values := data[headerSize:]
+
+	offsets := make([]int, n+1)
+	offsets[n] = len(values)
+
+	for i := 1; i < n; i++ {
+		offsets[i] = int(binary.LittleEndian.Uint32(data[word*i:]))
+	}
+
+	msg := make(map[uint32][]byte, n)
+
+	var prev uint32
+
+	for i := 0; i < n; i++ {
+		t := binary.LittleEndian.Uint32(data[word*(n+i):])
+		if i > 0 && t <= prev {
+			return nil, errors.New("tags are not in ascending order")
+		}
+
+		if offsets[i+1] < offsets[i] || offsets[i+1] > len(values) || offsets[i]%word != 0 {
+			return nil, errors.New("invalid offset")
+		}
+
+		msg[t] = values[offsets[i]:offsets[i+1]]
+		prev = t
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,295 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package roughtime_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/roughtime"
+)
+
+func tag(name string) uint32 {
+	return binary.LittleEndian.Uint32([]byte(name))
+}
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+
+	return b
+}
+
+func le64(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+
+	return b
+}
+
+func hashNode(prefix byte, left, right []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte{prefix}) // nolint: errcheck
+	h.Write(left)           // nolint: errcheck
+	h.Write(right)          // nolint: errcheck
+
+	return h.Sum(nil)
+}
+
+// timeServer signs the responses with an online key delegated by its long-term key.
+type timeServer struct {
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+	onlineKey  ed25519.PrivateKey
+	midpoint   time.Time
+	maxTime    time.Time
+}
+
+func newTimeServer(t *testing.T, midpoint time.Time) *timeServer {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	_, onlineKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return &timeServer{
+		publicKey:  publicKey,
+		privateKey: privateKey,
+		onlineKey:  onlineKey,
+		midpoint:   midpoint,
+		maxTime:    midpoint.Add(time.Hour),
+	}
+}
+
+// respond answers the nonce in a batch of two nonces, the nonce is the second leaf of the tree.
+func (s *timeServer) respond(nonce []byte) []byte {
+	other := make([]byte, NonceSize)
+	root := hashNode(1, hashNode(0, other, nil), hashNode(0, nonce, nil))
+
+	srep := Encode(map[uint32][]byte{
+		tag("ROOT"): root,
+		tag("MIDP"): le64(uint64(s.midpoint.UnixNano() / int64(time.Microsecond))),
+		tag("RADI"): le32(1000000),
+	})
+
+	dele := Encode(map[uint32][]byte{
+		tag("PUBK"): s.onlineKey.Public().(ed25519.PublicKey),
+		tag("MINT"): le64(0),
+		tag("MAXT"): le64(uint64(s.maxTime.UnixNano() / int64(time.Microsecond))),
+	})
+
+	cert := Encode(map[uint32][]byte{
+		tag("DELE"):    dele,
+		tag("SIG\x00"): ed25519.Sign(s.privateKey, append([]byte("RoughTime v1 delegation signature--\x00"), dele...)),
+	})
+
+	return Encode(map[uint32][]byte{
+		tag("SIG\x00"): ed25519.Sign(s.onlineKey, append([]byte("RoughTime v1 response signature\x00"), srep...)),
+		tag("SREP"):    srep,
+		tag("CERT"):    cert,
+		tag("PATH"):    hashNode(0, other, nil),
+		tag("INDX"):    le32(1),
+	})
+}
+
+// listen serves the requests over UDP.
+func (s *timeServer) listen(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { conn.Close() }) // nolint: errcheck,gosec
+
+	go func() {
+		buf := make([]byte, 2048)
+
+		for {
+			n, addr, er := conn.ReadFrom(buf)
+			if er != nil {
+				return
+			}
+
+			req, er := Decode(buf[:n])
+			if er != nil || n < 1024 {
+				continue
+			}
+
+			conn.WriteTo(s.respond(req[tag("NONC")]), addr) // nolint: errcheck,gosec
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestVerify(t *testing.T) {
+	midpoint := time.Unix(1700000000, 0)
+	server := newTimeServer(t, midpoint)
+
+	nonce := make([]byte, NonceSize)
+	nonce[0] = 1
+
+	resp, err := Verify(server.publicKey, nonce, server.respond(nonce))
+	require.NoError(t, err)
+	require.True(t, midpoint.Equal(resp.Midpoint))
+	require.Equal(t, time.Second, resp.Radius)
+
+	t.Run("Not the long-term key", func(t *testing.T) {
+		other := newTimeServer(t, midpoint)
+
+		_, err = Verify(other.publicKey, nonce, server.respond(nonce))
+		require.EqualError(t, err, "invalid delegation signature")
+	})
+
+	t.Run("Another nonce", func(t *testing.T) {
+		_, err = Verify(server.publicKey, make([]byte, NonceSize), server.respond(nonce))
+		require.EqualError(t, err, "nonce is not included in the response")
+	})
+
+	t.Run("Tampered response", func(t *testing.T) {
+		raw := server.respond(nonce)
+		msg, er := Decode(raw)
+		require.NoError(t, er)
+
+		msg[tag("SREP")] = Encode(map[uint32][]byte{tag("MIDP"): le64(0)})
+
+		_, err = Verify(server.publicKey, nonce, Encode(msg))
+		require.EqualError(t, err, "invalid response signature")
+	})
+
+	t.Run("Expired delegation", func(t *testing.T) {
+		server.maxTime = midpoint.Add(-time.Second)
+		defer func() { server.maxTime = midpoint.Add(time.Hour) }()
+
+		_, err = Verify(server.publicKey, nonce, server.respond(nonce))
+		require.EqualError(t, err, "midpoint is outside of the validity of the delegation")
+	})
+
+	t.Run("Malformed response", func(t *testing.T) {
+		_, err = Verify(server.publicKey, nonce, []byte("abc"))
+		require.Contains(t, err.Error(), "decode response")
+
+		_, err = Verify(server.publicKey, nonce, Encode(map[uint32][]byte{tag("SREP"): nil}))
+		require.EqualError(t, err, `missing tag "CERT"`)
+
+		_, err = Verify(nil, nonce, nil)
+		require.EqualError(t, err, "invalid public key")
+	})
+}
+
+func TestEncodeDecode(t *testing.T) {
+	msg := map[uint32][]byte{tag("NONC"): make([]byte, 64), tag("PAD\xff"): make([]byte, 8), tag("INDX"): le32(7)}
+
+	decoded, err := Decode(Encode(msg))
+	require.NoError(t, err)
+	require.Equal(t, msg, decoded)
+
+	decoded, err = Decode(le32(0))
+	require.NoError(t, err)
+	require.Empty(t, decoded)
+
+	_, err = Decode(le32(1000))
+	require.EqualError(t, err, "message is too short")
+
+	req, err := NewRequest(make([]byte, NonceSize))
+	require.NoError(t, err)
+	require.Len(t, req, 1024)
+
+	_, err = NewRequest([]byte("nonce"))
+	require.EqualError(t, err, "nonce must be 64 bytes")
+}
+
+func TestQuery(t *testing.T) {
+	midpoint := time.Unix(1700000000, 0)
+	server := newTimeServer(t, midpoint)
+
+	address := server.listen(t)
+
+	resp, err := Query(context.Background(), Server{Address: address, PublicKey: server.publicKey})
+	require.NoError(t, err)
+	require.True(t, midpoint.Equal(resp.Midpoint))
+	require.Equal(t, address, resp.Server)
+
+	// the response verifies against its nonce
+	_, err = Verify(server.publicKey, resp.Nonce, resp.Raw)
+	require.NoError(t, err)
+}
+
+func TestClock(t *testing.T) {
+	midpoint := time.Unix(1700000000, 0)
+	server := newTimeServer(t, midpoint)
+	address := server.listen(t)
+
+	// nothing listens on the address of the unavailable server
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	unavailable := conn.LocalAddr().String()
+	require.NoError(t, conn.Close())
+
+	clock := NewClock([]Server{
+		{Address: unavailable, PublicKey: server.publicKey},
+		{Address: address, PublicKey: server.publicKey},
+	}, time.Minute)
+
+	_, _, err = clock.Now()
+	require.True(t, errors.Is(err, ErrNotSynced))
+
+	require.NoError(t, clock.Sync(context.Background()))
+
+	now, resp, err := clock.Now()
+	require.NoError(t, err)
+	require.False(t, now.Before(midpoint))
+	require.True(t, now.Before(midpoint.Add(time.Minute)))
+	require.Equal(t, address, resp.Server)
+
+	stale := NewClock([]Server{{Address: address, PublicKey: server.publicKey}}, 0)
+	require.NoError(t, stale.Sync(context.Background()))
+
+	_, _, err = stale.Now()
+	require.True(t, errors.Is(err, ErrNotSynced))
+
+	err = NewClock([]Server{{Address: unavailable, PublicKey: server.publicKey}}, 0).Sync(context.Background())
+	require.Contains(t, err.Error(), "no server answered: "+unavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clock.Run(ctx, time.Millisecond)
+}
+
+func TestParseServers(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key := base64.StdEncoding.EncodeToString(publicKey)
+
+	servers, err := ParseServers("time1.example.com:2002@" + key + ", time2.example.com:2002@" + key)
+	require.NoError(t, err)
+	require.Equal(t, []Server{
+		{Address: "time1.example.com:2002", PublicKey: publicKey},
+		{Address: "time2.example.com:2002", PublicKey: publicKey},
+	}, servers)
+
+	_, err = ParseServers("time.example.com:2002")
+	require.EqualError(t, err, `server "time.example.com:2002" must be <address>@<public key>`)
+
+	_, err = ParseServers("time.example.com:2002@abc")
+	require.EqualError(t, err, `server "time.example.com:2002@abc": public key is not a base64-encoded Ed25519 key`)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+// KeyManager manages the keys of the signer.
+type KeyManager interface {
+	Get(keyID string) (interface{}, error)
+	ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error)
+}
+
+// Crypto signs with the keys of the signer.
+type Crypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+// healthCheckMessage is the message signed by the health check.
+const healthCheckMessage = "healthcheck"
+
+type handlerOptions struct {
+	token        string
+	certificates bool
+}
+
+// HandlerOpt represents handler option func.
+type HandlerOpt func(*handlerOptions)
+
+// WithToken requires the requests to carry the token as a bearer token.
+func WithToken(token string) HandlerOpt {
+	return func(o *handlerOptions) {
+		o.token = token
+	}
+}
+
+// WithClientCertificates requires the requests to carry a client certificate verified by the server (mTLS).
+func WithClientCertificates() HandlerOpt {
+	return func(o *handlerOptions) {
+		o.certificates = true
+	}
+}
+
+// NewHandler returns the handler of the protocol signing with the keys of the key IDs only (e.g. the key of the
+// log), the other keys of the key manager are not served. The health check signs a message with the keys.
+func NewHandler(km KeyManager, cr Crypto, keyIDs []string, opts ...HandlerOpt) http.Handler {
+	op := &handlerOptions{}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	served := map[string]bool{}
+	for _, keyID := range keyIDs {
+		served[keyID] = true
+	}
+
+	// keyID returns the ID of the key of the request, it writes an error if the key is not served
+	keyID := func(w http.ResponseWriter, r *http.Request) (string, bool) {
+		id := mux.Vars(r)["key_id"]
+		if !served[id] {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("key %s is not served", id))
+
+			return "", false
+		}
+
+		return id, true
+	}
+
+	router := mux.NewRouter()
+
+	router.HandleFunc(HealthCheckPath, func(w http.ResponseWriter, _ *http.Request) {
+		if err := healthCheck(km, cr, keyIDs); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(KeyPath, func(w http.ResponseWriter, r *http.Request) {
+		id, ok := keyID(w, r)
+		if !ok {
+			return
+		}
+
+		pubKey, keyType, err := km.ExportPubKeyBytes(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "export public key: "+err.Error())
+
+			return
+		}
+
+		writeResponse(w, &KeyResponse{PublicKey: pubKey, KeyType: keyType})
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(SignPath, func(w http.ResponseWriter, r *http.Request) {
+		var req SignRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "decode request: "+err.Error())
+
+			return
+		}
+
+		id, ok := keyID(w, r)
+		if !ok {
+			return
+		}
+
+		kh, err := km.Get(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "get key: "+err.Error())
+
+			return
+		}
+
+		signature, err := cr.Sign(req.Message, kh)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "sign: "+err.Error())
+
+			return
+		}
+
+		writeResponse(w, &SignResponse{Signature: signature})
+	}).Methods(http.MethodPost)
+
+	if op.token == "" && !op.certificates {
+		return router
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if op.certificates && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+			writeError(w, http.StatusUnauthorized, "unauthorized: no verified client certificate")
+
+			return
+		}
+
+		if op.token != "" &&
+			subtle.ConstantTimeCompare([]byte(r.Header.Get(authorizationHeader)), []byte("Bearer "+op.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+
+			return
+		}
+
+		router.ServeHTTP(w, r)
+	})
+}
+
+// healthCheck signs a message with each of the keys.
+func healthCheck(km KeyManager, cr Crypto, keyIDs []string) error {
+	if len(keyIDs) == 0 {
+		return fmt.Errorf("no key is served")
+	}
+
+	for _, id := range keyIDs {
+		kh, err := km.Get(id)
+		if err != nil {
+			return fmt.Errorf("get key %s: %w", id, err)
+		}
+
+		if _, err = cr.Sign([]byte(healthCheckMessage), kh); err != nil {
+			return fmt.Errorf("sign with key %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf("write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(&ErrorResponse{Message: message}); err != nil {
+		logger.Errorf("write error: %v", err)
+	}
+}
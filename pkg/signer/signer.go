@@ -0,0 +1,317 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package signer implements a minimal remote-signing protocol, so the signing key of the log can live in a
+// separate hardened service. The protocol is served over HTTP:
+//
+//	GET  /v1/keys/{key_id}       -> KeyResponse (the public key and its type)
+//	POST /v1/keys/{key_id}/sign  SignRequest -> SignResponse
+//	GET  /healthcheck            -> 200 if the signer is able to sign with its keys, 503 otherwise
+//
+// The signer serves the configured keys only, the other keys of its key manager are reported as not found.
+// Errors are reported with a non-2xx status and an ErrorResponse. The Client acts as the key manager and
+// the crypto of the log (command.KeyManager and command.Crypto), NewHandler serves the protocol.
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+// Paths of the protocol.
+const (
+	KeyPath         = "/v1/keys/{key_id}"
+	SignPath        = "/v1/keys/{key_id}/sign"
+	HealthCheckPath = "/healthcheck"
+)
+
+const (
+	defaultAttempts     = 3
+	defaultBackoff      = 100 * time.Millisecond
+	defaultTimeout      = 10 * time.Second
+	defaultIdleConns    = 16
+	maxErrorBody        = 512
+	authorizationHeader = "Authorization"
+)
+
+var logger = log.New("signer")
+
+// ErrKeyCreation is returned by Client.Create, keys are managed by the signer.
+var ErrKeyCreation = errors.New("keys are managed by the remote signer")
+
+// KeyResponse is the response of the key path.
+type KeyResponse struct {
+	PublicKey []byte      `json:"public_key"`
+	KeyType   kms.KeyType `json:"key_type"`
+}
+
+// SignRequest is the request of the sign path.
+type SignRequest struct {
+	Message []byte `json:"message"`
+}
+
+// SignResponse is the response of the sign path.
+type SignResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+// ErrorResponse is the body of an error response.
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// Error is the error of a response of the signer.
+type Error struct {
+	Endpoint string
+	Status   int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("signer %s responded with status %d: %s", e.Endpoint, e.Status, e.Message)
+}
+
+// HTTPClient represents HTTP client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type options struct {
+	http     HTTPClient
+	token    string
+	attempts int
+	backoff  time.Duration
+}
+
+// Opt represents client option func.
+type Opt func(*options)
+
+// WithHTTPClient allows providing HTTP client. By default connections to the replicas are pooled.
+func WithHTTPClient(client HTTPClient) Opt {
+	return func(o *options) {
+		o.http = client
+	}
+}
+
+// WithAuthToken sets the bearer token of the requests.
+func WithAuthToken(token string) Opt {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithRetries sets the number of rounds over the replicas (3 by default) and the backoff between rounds
+// (100ms by default), doubled for every round.
+func WithRetries(attempts int, backoff time.Duration) Opt {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// Client is a client of the replicas of a remote signer.
+//
+// A request goes to the replica which last succeeded and fails over to the next replicas if the replica is
+// unavailable (a transport error or a 5xx status). Requests rejected by a replica (a 4xx status) are not retried.
+type Client struct {
+	endpoints []string
+	http      HTTPClient
+	token     string
+	attempts  int
+	backoff   time.Duration
+	preferred uint32 // index of the replica which last succeeded, accessed atomically
+}
+
+// New returns a client of the replicas of the signer.
+func New(endpoints []string, opts ...Opt) (*Client, error) {
+	op := &options{attempts: defaultAttempts, backoff: defaultBackoff}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, errors.New("no signer endpoints")
+	}
+
+	if op.attempts < 1 {
+		op.attempts = 1
+	}
+
+	if op.http == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone() // nolint: forcetypeassert
+		transport.MaxIdleConnsPerHost = defaultIdleConns
+
+		op.http = &http.Client{Timeout: defaultTimeout, Transport: transport}
+	}
+
+	client := &Client{http: op.http, token: op.token, attempts: op.attempts, backoff: op.backoff}
+
+	for _, endpoint := range endpoints {
+		client.endpoints = append(client.endpoints, strings.TrimSuffix(strings.TrimSpace(endpoint), "/"))
+	}
+
+	return client, nil
+}
+
+// Create is not supported, keys are managed by the signer.
+func (c *Client) Create(kt kms.KeyType) (string, interface{}, error) {
+	return "", nil, ErrKeyCreation
+}
+
+// Get returns the handle of the key, i.e. its ID.
+func (c *Client) Get(keyID string) (interface{}, error) {
+	return keyID, nil
+}
+
+// ExportPubKeyBytes returns the public key and its type.
+func (c *Client) ExportPubKeyBytes(keyID string) ([]byte, kms.KeyType, error) {
+	var resp KeyResponse
+
+	if err := c.do(http.MethodGet, keyPath(keyID), nil, &resp); err != nil {
+		return nil, "", fmt.Errorf("export public key: %w", err)
+	}
+
+	return resp.PublicKey, resp.KeyType, nil
+}
+
+// Sign signs the message with the key of the handle (Get).
+func (c *Client) Sign(msg []byte, kh interface{}) ([]byte, error) {
+	keyID, ok := kh.(string)
+	if !ok {
+		return nil, fmt.Errorf("key handle must be a key ID, got %T", kh)
+	}
+
+	var resp SignResponse
+
+	if err := c.do(http.MethodPost, keyPath(keyID)+"/sign", &SignRequest{Message: msg}, &resp); err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	return resp.Signature, nil
+}
+
+// HealthCheck checks that a replica of the signer is available.
+func (c *Client) HealthCheck() error {
+	if err := c.do(http.MethodGet, HealthCheckPath, nil, nil); err != nil {
+		return fmt.Errorf("health check: %w", err)
+	}
+
+	return nil
+}
+
+func keyPath(keyID string) string {
+	return strings.Replace(KeyPath, "{key_id}", url.PathEscape(keyID), 1)
+}
+
+// do sends the request to the preferred replica and fails over to the next ones, the replicas are tried
+// in rounds with a backoff between rounds.
+func (c *Client) do(method, path string, req, resp interface{}) error {
+	var body []byte
+
+	if req != nil {
+		var err error
+
+		if body, err = json.Marshal(req); err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+	}
+
+	backoff := c.backoff
+
+	var err error
+
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		preferred := int(atomic.LoadUint32(&c.preferred))
+
+		for i := range c.endpoints {
+			replica := (preferred + i) % len(c.endpoints)
+
+			err = c.send(c.endpoints[replica], method, path, body, resp)
+			if err == nil {
+				atomic.StoreUint32(&c.preferred, uint32(replica))
+
+				return nil
+			}
+
+			var signerErr *Error
+			if errors.As(err, &signerErr) && signerErr.Status < http.StatusInternalServerError {
+				return err
+			}
+
+			logger.Warnf("signer %s failed: %v", c.endpoints[replica], err)
+		}
+
+		if attempt < c.attempts {
+			time.Sleep(backoff)
+
+			backoff *= 2
+		}
+	}
+
+	return err
+}
+
+func (c *Client) send(endpoint, method, path string, body []byte, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, endpoint+path, reader)
+	if err != nil {
+		return fmt.Errorf("new request with context: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		req.Header.Set(authorizationHeader, "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("http do: %w", err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody)) // nolint: errcheck
+
+		var errResp ErrorResponse
+		if json.Unmarshal(raw, &errResp) != nil || errResp.Message == "" {
+			errResp.Message = strings.TrimSpace(string(raw))
+		}
+
+		return &Error{Endpoint: endpoint, Status: resp.StatusCode, Message: errResp.Message}
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signer_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/signer"
+)
+
+const token = "token"
+
+// newSigner returns the handler of a signer, the ID of its key and the ID of a key it does not serve.
+func newSigner(t *testing.T) (http.Handler, string, string) {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://default/master/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	otherKeyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	return NewHandler(km, cr, []string{keyID}, WithToken(token)), keyID, otherKeyID
+}
+
+// newReplica serves the handler, every request fails while down is set.
+func newReplica(t *testing.T, handler http.Handler, down *int32, requests *int32) string {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		if atomic.LoadInt32(down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+
+	t.Cleanup(ts.Close)
+
+	return ts.URL
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(nil)
+	require.EqualError(t, err, "no signer endpoints")
+}
+
+func TestClient(t *testing.T) {
+	handler, keyID, otherKeyID := newSigner(t)
+
+	var firstDown, secondDown, firstRequests, secondRequests int32
+
+	first := newReplica(t, handler, &firstDown, &firstRequests)
+	second := newReplica(t, handler, &secondDown, &secondRequests)
+
+	client, err := New([]string{first, second + "/"}, WithAuthToken(token), WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+
+	_, _, err = client.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.ErrorIs(t, err, ErrKeyCreation)
+
+	t.Run("Sign", func(t *testing.T) {
+		pubKey, keyType, err := client.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+		require.NotEmpty(t, pubKey)
+		require.Equal(t, kms.ECDSAP256TypeIEEEP1363, keyType)
+
+		kh, err := client.Get(keyID)
+		require.NoError(t, err)
+
+		signature, err := client.Sign([]byte("message"), kh)
+		require.NoError(t, err)
+
+		verifierKH, err := (&localkms.LocalKMS{}).PubKeyBytesToHandle(pubKey, keyType)
+		require.NoError(t, err)
+		require.NoError(t, (&tinkcrypto.Crypto{}).Verify(signature, []byte("message"), verifierKH))
+
+		require.NoError(t, client.HealthCheck())
+		require.Zero(t, atomic.LoadInt32(&secondRequests))
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		atomic.StoreInt32(&firstDown, 1)
+
+		_, err := client.Sign([]byte("message"), keyID)
+		require.NoError(t, err)
+		require.Equal(t, int32(1), atomic.LoadInt32(&secondRequests))
+
+		// the replica which succeeded is preferred
+		atomic.StoreInt32(&firstDown, 0)
+
+		_, err = client.Sign([]byte("message"), keyID)
+		require.NoError(t, err)
+		require.Equal(t, int32(2), atomic.LoadInt32(&secondRequests))
+	})
+
+	t.Run("Every replica down", func(t *testing.T) {
+		atomic.StoreInt32(&firstDown, 1)
+		atomic.StoreInt32(&secondDown, 1)
+
+		defer func() {
+			atomic.StoreInt32(&firstDown, 0)
+			atomic.StoreInt32(&secondDown, 0)
+		}()
+
+		requests := atomic.LoadInt32(&firstRequests) + atomic.LoadInt32(&secondRequests)
+
+		_, err := client.Sign([]byte("message"), keyID)
+		require.Contains(t, err.Error(), "responded with status 503")
+		// two rounds over both replicas
+		require.Equal(t, requests+4, atomic.LoadInt32(&firstRequests)+atomic.LoadInt32(&secondRequests))
+	})
+
+	t.Run("Rejected request is not retried", func(t *testing.T) {
+		requests := atomic.LoadInt32(&firstRequests) + atomic.LoadInt32(&secondRequests)
+
+		_, err := client.Sign([]byte("message"), "unknown")
+		require.Contains(t, err.Error(), "responded with status 404: key unknown is not served")
+		require.Equal(t, requests+1, atomic.LoadInt32(&firstRequests)+atomic.LoadInt32(&secondRequests))
+
+		_, _, err = client.ExportPubKeyBytes("unknown")
+		require.Contains(t, err.Error(), "key unknown is not served")
+
+		_, err = client.Sign([]byte("message"), 1)
+		require.EqualError(t, err, "key handle must be a key ID, got int")
+	})
+
+	t.Run("Key not served", func(t *testing.T) {
+		_, err := client.Sign([]byte("message"), otherKeyID)
+		require.Contains(t, err.Error(), "responded with status 404: key "+otherKeyID+" is not served")
+
+		_, _, err = client.ExportPubKeyBytes(otherKeyID)
+		require.Contains(t, err.Error(), "responded with status 404: key "+otherKeyID+" is not served")
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		unauthorized, err := New([]string{first})
+		require.NoError(t, err)
+
+		_, err = unauthorized.Sign([]byte("message"), keyID)
+		require.Contains(t, err.Error(), "responded with status 401: unauthorized")
+	})
+}
+
+func TestHandler(t *testing.T) {
+	km, err := localkms.New("local-lock://default/master/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	t.Run("Health check fails", func(t *testing.T) {
+		for keyIDs, message := range map[string]string{
+			"":        "no key is served",
+			"unknown": "get key unknown",
+		} {
+			var ids []string
+			if keyIDs != "" {
+				ids = []string{keyIDs}
+			}
+
+			rec := httptest.NewRecorder()
+			NewHandler(km, cr, ids).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HealthCheckPath, nil))
+			require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			require.Contains(t, rec.Body.String(), message)
+		}
+	})
+
+	t.Run("Client certificate required", func(t *testing.T) {
+		keyID, _, err := km.Create(kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+
+		handler := NewHandler(km, cr, []string{keyID}, WithClientCertificates())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HealthCheckPath, nil))
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+		require.Contains(t, rec.Body.String(), "no verified client certificate")
+
+		req := httptest.NewRequest(http.MethodGet, HealthCheckPath, nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
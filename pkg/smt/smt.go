@@ -0,0 +1,293 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package smt implements a sparse Merkle tree keyed by 256-bit keys with inclusion and non-inclusion proofs.
+//
+// A subtree holding a single leaf is represented by the leaf itself, an empty subtree hashes to 32 zero bytes:
+//
+//	leaf     = SHA256(0x00 || key || SHA256(value))
+//	interior = SHA256(0x01 || left || right)
+package smt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	// KeySize is the size of a key in bytes.
+	KeySize = sha256.Size
+
+	leafPrefix     = 0
+	interiorPrefix = 1
+)
+
+// ErrInvalidProof is returned when a proof does not match the root hash.
+var ErrInvalidProof = errors.New("invalid proof")
+
+// nolint: gochecknoglobals
+var emptyHash = make([]byte, sha256.Size)
+
+type node struct {
+	left, right *node
+	// key and valueHash are set for leaves only.
+	key       []byte
+	valueHash []byte
+	hash      []byte // nil if the hash needs to be recalculated
+}
+
+func (n *node) isLeaf() bool {
+	return n.key != nil
+}
+
+// Proof is a Merkle audit path of a key.
+type Proof struct {
+	// Siblings from the root down to the subtree where the path of the key ends.
+	Siblings [][]byte `json:"siblings"`
+	// LeafKey and LeafValueHash of the leaf the path ends with, empty if the path ends with an empty subtree.
+	// For a non-inclusion proof it is a leaf with another key sharing the path.
+	LeafKey       []byte `json:"leaf_key,omitempty"`
+	LeafValueHash []byte `json:"leaf_value_hash,omitempty"`
+}
+
+// Tree is a sparse Merkle tree, safe for concurrent use.
+type Tree struct {
+	mu   sync.RWMutex
+	root *node
+	size int
+}
+
+// New returns an empty tree.
+func New() *Tree {
+	return &Tree{}
+}
+
+// Size returns the number of keys in the tree.
+func (t *Tree) Size() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.size
+}
+
+// Put sets the value of the key.
+func (t *Tree) Put(key, value []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("key size must be %d bytes", KeySize)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	leaf := &node{key: append([]byte(nil), key...), valueHash: hashValue(value)}
+
+	var added bool
+
+	t.root, added = insert(t.root, leaf, 0)
+	if added {
+		t.size++
+	}
+
+	return nil
+}
+
+// Get returns the value hash of the key, nil if the key is not in the tree.
+func (t *Tree) Get(key []byte) []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	n := t.root
+
+	for depth := 0; n != nil && !n.isLeaf(); depth++ {
+		n = child(n, key, depth)
+	}
+
+	if n == nil || !bytes.Equal(n.key, key) {
+		return nil
+	}
+
+	return n.valueHash
+}
+
+// Root returns the root hash of the tree.
+func (t *Tree) Root() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return hashNode(t.root)
+}
+
+// Prove returns the proof of the key, which is an inclusion proof if the key is in the tree
+// and a non-inclusion proof otherwise.
+func (t *Tree) Prove(key []byte) (*Proof, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key size must be %d bytes", KeySize)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	proof := &Proof{}
+	n := t.root
+
+	for depth := 0; n != nil && !n.isLeaf(); depth++ {
+		if bit(key, depth) == 0 {
+			proof.Siblings = append(proof.Siblings, hashNode(n.right))
+			n = n.left
+		} else {
+			proof.Siblings = append(proof.Siblings, hashNode(n.left))
+			n = n.right
+		}
+	}
+
+	if n != nil {
+		proof.LeafKey = n.key
+		proof.LeafValueHash = n.valueHash
+	}
+
+	return proof, nil
+}
+
+// VerifyInclusion verifies that the key has the value in the tree with the given root hash.
+func VerifyInclusion(root, key, value []byte, proof *Proof) error {
+	if proof == nil || !bytes.Equal(proof.LeafKey, key) || !bytes.Equal(proof.LeafValueHash, hashValue(value)) {
+		return fmt.Errorf("%w: proof is not for the key and value", ErrInvalidProof)
+	}
+
+	return verify(root, key, proof)
+}
+
+// VerifyNonInclusion verifies that the key is not in the tree with the given root hash.
+func VerifyNonInclusion(root, key []byte, proof *Proof) error {
+	if proof == nil {
+		return fmt.Errorf("%w: no proof", ErrInvalidProof)
+	}
+
+	if len(proof.LeafKey) != 0 {
+		if bytes.Equal(proof.LeafKey, key) {
+			return fmt.Errorf("%w: key is in the tree", ErrInvalidProof)
+		}
+
+		for depth := range proof.Siblings {
+			if bit(proof.LeafKey, depth) != bit(key, depth) {
+				return fmt.Errorf("%w: leaf does not share the path of the key", ErrInvalidProof)
+			}
+		}
+	}
+
+	return verify(root, key, proof)
+}
+
+func verify(root, key []byte, proof *Proof) error {
+	if len(key) != KeySize || len(proof.Siblings) > KeySize*8 {
+		return fmt.Errorf("%w: malformed proof", ErrInvalidProof)
+	}
+
+	current := emptyHash
+	if len(proof.LeafKey) != 0 {
+		current = hashLeaf(proof.LeafKey, proof.LeafValueHash)
+	}
+
+	for depth := len(proof.Siblings) - 1; depth >= 0; depth-- {
+		if bit(key, depth) == 0 {
+			current = hashInterior(current, proof.Siblings[depth])
+		} else {
+			current = hashInterior(proof.Siblings[depth], current)
+		}
+	}
+
+	if !bytes.Equal(current, root) {
+		return fmt.Errorf("%w: root hash mismatch", ErrInvalidProof)
+	}
+
+	return nil
+}
+
+func insert(n, leaf *node, depth int) (*node, bool) {
+	if n == nil {
+		return leaf, true
+	}
+
+	if n.isLeaf() {
+		if bytes.Equal(n.key, leaf.key) {
+			return leaf, false
+		}
+
+		// splits the leaf until the paths of the keys diverge
+		parent := &node{}
+		if bit(n.key, depth) == 0 {
+			parent.left = n
+		} else {
+			parent.right = n
+		}
+
+		return insert(parent, leaf, depth)
+	}
+
+	n.hash = nil
+
+	var added bool
+
+	if bit(leaf.key, depth) == 0 {
+		n.left, added = insert(n.left, leaf, depth+1)
+	} else {
+		n.right, added = insert(n.right, leaf, depth+1)
+	}
+
+	return n, added
+}
+
+func child(n *node, key []byte, depth int) *node {
+	if bit(key, depth) == 0 {
+		return n.left
+	}
+
+	return n.right
+}
+
+func hashNode(n *node) []byte {
+	switch {
+	case n == nil:
+		return emptyHash
+	case n.isLeaf():
+		return hashLeaf(n.key, n.valueHash)
+	case n.hash == nil:
+		n.hash = hashInterior(hashNode(n.left), hashNode(n.right))
+	}
+
+	return n.hash
+}
+
+func hashLeaf(key, valueHash []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(key)
+	h.Write(valueHash)
+
+	return h.Sum(nil)
+}
+
+func hashInterior(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{interiorPrefix})
+	h.Write(left)
+	h.Write(right)
+
+	return h.Sum(nil)
+}
+
+func hashValue(value []byte) []byte {
+	h := sha256.Sum256(value)
+
+	return h[:]
+}
+
+func bit(key []byte, depth int) byte {
+	return (key[depth/8] >> (7 - uint(depth%8))) & 1 // nolint: gomnd
+}
@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package smt_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/smt"
+)
+
+func key(s string) []byte {
+	k := sha256.Sum256([]byte(s))
+
+	return k[:]
+}
+
+func TestTree(t *testing.T) {
+	tree := New()
+	require.Equal(t, make([]byte, KeySize), tree.Root())
+
+	proof, err := tree.Prove(key("absent"))
+	require.NoError(t, err)
+	require.NoError(t, VerifyNonInclusion(tree.Root(), key("absent"), proof))
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, tree.Put(key(fmt.Sprint(i)), []byte(fmt.Sprintf("value %d", i))))
+	}
+
+	require.Equal(t, 100, tree.Size())
+
+	root := tree.Root()
+
+	for i := 0; i < 100; i++ {
+		proof, err = tree.Prove(key(fmt.Sprint(i)))
+		require.NoError(t, err)
+		require.NoError(t, VerifyInclusion(root, key(fmt.Sprint(i)), []byte(fmt.Sprintf("value %d", i)), proof))
+		require.True(t, errors.Is(VerifyNonInclusion(root, key(fmt.Sprint(i)), proof), ErrInvalidProof))
+		require.True(t, errors.Is(VerifyInclusion(root, key(fmt.Sprint(i)), []byte("other"), proof), ErrInvalidProof))
+	}
+
+	for i := 100; i < 200; i++ {
+		proof, err = tree.Prove(key(fmt.Sprint(i)))
+		require.NoError(t, err)
+		require.NoError(t, VerifyNonInclusion(root, key(fmt.Sprint(i)), proof))
+		require.Nil(t, tree.Get(key(fmt.Sprint(i))))
+	}
+
+	// updates the value
+	require.NoError(t, tree.Put(key("1"), []byte("updated")))
+	require.Equal(t, 100, tree.Size())
+	require.NotEqual(t, root, tree.Root())
+
+	valueHash := sha256.Sum256([]byte("updated"))
+	require.Equal(t, valueHash[:], tree.Get(key("1")))
+
+	proof, err = tree.Prove(key("1"))
+	require.NoError(t, err)
+	require.NoError(t, VerifyInclusion(tree.Root(), key("1"), []byte("updated"), proof))
+
+	// the proof does not match the old root
+	require.True(t, errors.Is(VerifyInclusion(root, key("1"), []byte("updated"), proof), ErrInvalidProof))
+}
+
+func TestTree_Order(t *testing.T) {
+	first, second := New(), New()
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, first.Put(key(fmt.Sprint(i)), []byte{byte(i)}))
+		require.NoError(t, second.Put(key(fmt.Sprint(49-i)), []byte{byte(49 - i)}))
+	}
+
+	require.Equal(t, first.Root(), second.Root())
+}
+
+func TestVerifyNonInclusion(t *testing.T) {
+	tree := New()
+	require.NoError(t, tree.Put(key("a"), []byte("a")))
+	require.NoError(t, tree.Put(key("b"), []byte("b")))
+
+	proof, err := tree.Prove(key("c"))
+	require.NoError(t, err)
+
+	require.NoError(t, VerifyNonInclusion(tree.Root(), key("c"), proof))
+	require.True(t, errors.Is(VerifyNonInclusion(tree.Root(), key("c"), nil), ErrInvalidProof))
+	require.True(t, errors.Is(VerifyNonInclusion(key("root"), key("c"), proof), ErrInvalidProof))
+
+	proof, err = tree.Prove(key("a"))
+	require.NoError(t, err)
+	require.True(t, errors.Is(VerifyNonInclusion(tree.Root(), key("a"), proof), ErrInvalidProof))
+
+	proof.LeafKey = key("b")
+	require.True(t, errors.Is(VerifyNonInclusion(tree.Root(), key("a"), proof), ErrInvalidProof))
+}
+
+func TestKeySize(t *testing.T) {
+	require.EqualError(t, New().Put([]byte("key"), nil), "key size must be 32 bytes")
+
+	_, err := New().Prove([]byte("key"))
+	require.EqualError(t, err, "key size must be 32 bytes")
+}
@@ -0,0 +1,152 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/aries-framework-go/pkg/common/log"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+)
+
+const (
+	storeName  = "migrations"
+	versionKey = "version"
+)
+
+var logger = log.New("storage/migration")
+
+// Migration represents a versioned change of the VCT stores (Trillian storage is not covered).
+type Migration struct {
+	// Version must be unique and greater than zero, migrations are applied in ascending order.
+	Version     uint64
+	Description string
+	Migrate     func(provider storage.Provider) error
+}
+
+type versionRecord struct {
+	Version     uint64 `json:"version"`
+	Description string `json:"description"`
+}
+
+// Migrator applies migrations to the VCT stores and keeps track of the applied version.
+type Migrator struct {
+	provider   storage.Provider
+	store      storage.Store
+	migrations []Migration
+}
+
+// New returns migrator for the given migrations.
+func New(provider storage.Provider, migrations ...Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version == 0 {
+			return nil, fmt.Errorf("migration %q: version must be greater than zero", m.Description)
+		}
+
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+
+		if m.Migrate == nil {
+			return nil, fmt.Errorf("migration %d: no migrate func", m.Version)
+		}
+	}
+
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+
+	return &Migrator{provider: provider, store: store, migrations: sorted}, nil
+}
+
+// Version returns the version the stores are migrated to (zero if no migration was applied).
+func (m *Migrator) Version() (uint64, error) {
+	src, err := m.store.Get(versionKey)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("get version: %w", err)
+	}
+
+	var record versionRecord
+	if err = json.Unmarshal(src, &record); err != nil {
+		return 0, fmt.Errorf("unmarshal version: %w", err)
+	}
+
+	return record.Version, nil
+}
+
+// LatestVersion returns the version of the latest known migration.
+func (m *Migrator) LatestVersion() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Pending returns migrations which are not applied yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	current, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if current > m.LatestVersion() {
+		return nil, fmt.Errorf("stores are migrated to version %d but the latest supported version is %d",
+			current, m.LatestVersion())
+	}
+
+	var pending []Migration
+
+	for _, migration := range m.migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate applies pending migrations. The version is stored after each migration,
+// so a failed run may be resumed from the failed migration.
+func (m *Migrator) Migrate() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range pending {
+		logger.Infof("Applying migration %d: %s", migration.Version, migration.Description)
+
+		if err = migration.Migrate(m.provider); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+
+		var src []byte
+
+		src, err = json.Marshal(versionRecord{Version: migration.Version, Description: migration.Description})
+		if err != nil {
+			return fmt.Errorf("marshal version: %w", err)
+		}
+
+		if err = m.store.Put(versionKey, src); err != nil {
+			return fmt.Errorf("put version: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package migration_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/trustbloc/vct/pkg/storage/migration"
+)
+
+func TestMigrator_Migrate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		var applied []uint64
+
+		record := func(version uint64) func(storage.Provider) error {
+			return func(storage.Provider) error {
+				applied = append(applied, version)
+
+				return nil
+			}
+		}
+
+		migrator, err := New(provider,
+			Migration{Version: 2, Description: "second", Migrate: record(2)},
+			Migration{Version: 1, Description: "first", Migrate: record(1)},
+		)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), migrator.LatestVersion())
+
+		pending, err := migrator.Pending()
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+
+		require.NoError(t, migrator.Migrate())
+		require.Equal(t, []uint64{1, 2}, applied)
+
+		version, err := migrator.Version()
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), version)
+
+		// migrations are applied once
+		require.NoError(t, migrator.Migrate())
+		require.Equal(t, []uint64{1, 2}, applied)
+
+		// a new migration is applied on top of the previous ones
+		migrator, err = New(provider,
+			Migration{Version: 1, Description: "first", Migrate: record(1)},
+			Migration{Version: 2, Description: "second", Migrate: record(2)},
+			Migration{Version: 3, Description: "third", Migrate: record(3)},
+		)
+		require.NoError(t, err)
+		require.NoError(t, migrator.Migrate())
+		require.Equal(t, []uint64{1, 2, 3}, applied)
+	})
+
+	t.Run("Failed migration is resumed", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		fail := true
+
+		migrations := []Migration{
+			{Version: 1, Migrate: func(storage.Provider) error { return nil }},
+			{Version: 2, Description: "flaky", Migrate: func(storage.Provider) error {
+				if fail {
+					return errors.New("error")
+				}
+
+				return nil
+			}},
+		}
+
+		migrator, err := New(provider, migrations...)
+		require.NoError(t, err)
+		require.EqualError(t, migrator.Migrate(), "migration 2 (flaky): error")
+
+		version, err := migrator.Version()
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), version)
+
+		fail = false
+
+		require.NoError(t, migrator.Migrate())
+
+		version, err = migrator.Version()
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), version)
+	})
+
+	t.Run("Stores are newer", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		migrator, err := New(provider, Migration{Version: 2, Migrate: func(storage.Provider) error { return nil }})
+		require.NoError(t, err)
+		require.NoError(t, migrator.Migrate())
+
+		migrator, err = New(provider, Migration{Version: 1, Migrate: func(storage.Provider) error { return nil }})
+		require.NoError(t, err)
+		require.EqualError(t, migrator.Migrate(),
+			"stores are migrated to version 2 but the latest supported version is 1")
+	})
+}
+
+func TestNew(t *testing.T) {
+	noop := func(storage.Provider) error { return nil }
+
+	_, err := New(mem.NewProvider(), Migration{Version: 0, Description: "zero", Migrate: noop})
+	require.EqualError(t, err, `migration "zero": version must be greater than zero`)
+
+	_, err = New(mem.NewProvider(), Migration{Version: 1, Migrate: noop}, Migration{Version: 1, Migrate: noop})
+	require.EqualError(t, err, "duplicate migration version 1")
+
+	_, err = New(mem.NewProvider(), Migration{Version: 1})
+	require.EqualError(t, err, "migration 1: no migrate func")
+}
@@ -33,6 +33,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/trustbloc/vct/internal/pkg/scrub"
 )
 
 // nolint: lll
@@ -516,7 +518,7 @@ func (t *logTreeTX) QueueLeaves(ctx context.Context, leaves []*trillian.LogLeaf,
 			existingCount++
 
 			queuedDupCounter.Inc(label)
-			logger.Warnf("Found duplicate %v %v", t.treeID, leaf)
+			logger.Warnf("Found duplicate %v %s", t.treeID, scrub.Leaf(leaf))
 
 			continue
 		}
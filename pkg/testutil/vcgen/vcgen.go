@@ -0,0 +1,366 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package vcgen generates signed test credentials for the tests and the demos (e.g. the sample credentials of
+// vct dev): the credentials of a generator are derived from a seed, so a scenario gets the same credentials on every
+// run. The issuers are did:key DIDs derived from the seed (or issuers set with WithIssuer), the credentials are
+// signed with one of the signature suites, padded to a size and typed with the types of the generator. The signatures
+// are deterministic: the same seed and the same options generate the same bytes.
+package vcgen
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	ldprocessor "github.com/hyperledger/aries-framework-go/pkg/doc/signature/jsonld"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	jsonld "github.com/piprate/json-gold/ld"
+
+	"github.com/trustbloc/vct/pkg/ldloader"
+)
+
+// Suite is the signature suite of the credentials.
+type Suite string
+
+// Signature suites of the credentials.
+const (
+	// JWTEdDSA signs the credentials as JWTs with an Ed25519 key.
+	JWTEdDSA Suite = "jwt-eddsa"
+	// Ed25519Signature2018 adds a Linked Data proof with an Ed25519 key.
+	Ed25519Signature2018 Suite = "Ed25519Signature2018"
+)
+
+// DefaultType is the type of the credentials, besides VerifiableCredential, unless they are set with WithTypes.
+const DefaultType = "EducationalOccupationalCredential"
+
+const (
+	credentialsContext = "https://www.w3.org/2018/credentials/v1"
+	vocab              = "https://schema.org/"
+	assertionMethod    = "assertionMethod"
+	paddingFieldName   = "description"
+)
+
+// nolint: gochecknoglobals
+var defaultIssuanceDate = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Issuer is an issuer of the credentials.
+type Issuer struct {
+	// DID of the issuer.
+	DID string
+	// KeyID is the verification method of the DID the credentials are signed with.
+	KeyID string
+	// Ed25519 is the key of the issuer.
+	Ed25519 ed25519.PrivateKey
+}
+
+type options struct {
+	issuers      int
+	custom       []*Issuer
+	types        []string
+	suites       []Suite
+	size         int
+	issuanceDate time.Time
+	loader       jsonld.DocumentLoader
+}
+
+// Opt represents generator option func.
+type Opt func(*options)
+
+// WithIssuers sets the number of the did:key issuers derived from the seed (1 by default), the credentials are
+// issued by the issuers in turn.
+func WithIssuers(n int) Opt {
+	return func(o *options) {
+		o.issuers = n
+	}
+}
+
+// WithIssuer adds an issuer with its own DID (e.g. a did:web DID served by the test), identified by the
+// verification method of its Ed25519 key. The issuers set with WithIssuer replace the issuers derived from the seed.
+func WithIssuer(did, keyID string, key ed25519.PrivateKey) Opt {
+	return func(o *options) {
+		o.custom = append(o.custom, &Issuer{DID: did, KeyID: keyID, Ed25519: key})
+	}
+}
+
+// WithTypes sets the types of the credentials, besides VerifiableCredential, used in turn (DefaultType by default).
+func WithTypes(types ...string) Opt {
+	return func(o *options) {
+		o.types = types
+	}
+}
+
+// WithSuites sets the signature suites of the credentials, used in turn (JWTEdDSA by default).
+func WithSuites(suites ...Suite) Opt {
+	return func(o *options) {
+		o.suites = suites
+	}
+}
+
+// WithSize pads the credentials to about the size (bytes), the credentials are not padded if they are larger.
+func WithSize(size int) Opt {
+	return func(o *options) {
+		o.size = size
+	}
+}
+
+// WithIssuanceDate sets the issuance date of the credentials and the creation date of their proofs (2021-01-01 by
+// default), e.g. time.Now() for credentials which must be recent.
+func WithIssuanceDate(date time.Time) Opt {
+	return func(o *options) {
+		o.issuanceDate = date
+	}
+}
+
+// WithDocumentLoader sets the JSON-LD document loader of the Linked Data proofs (the embedded contexts by default).
+func WithDocumentLoader(loader jsonld.DocumentLoader) Opt {
+	return func(o *options) {
+		o.loader = loader
+	}
+}
+
+// Generator generates the credentials of a seed.
+type Generator struct {
+	seed         string
+	issuers      []*Issuer
+	types        []string
+	suites       []Suite
+	size         int
+	issuanceDate time.Time
+	loader       jsonld.DocumentLoader
+}
+
+// New returns the generator of the credentials of the seed.
+func New(seed string, opts ...Opt) (*Generator, error) {
+	op := &options{
+		issuers:      1,
+		types:        []string{DefaultType},
+		suites:       []Suite{JWTEdDSA},
+		issuanceDate: defaultIssuanceDate,
+	}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if op.issuers < 1 && len(op.custom) == 0 {
+		return nil, errors.New("generator must have an issuer")
+	}
+
+	if len(op.types) == 0 || len(op.suites) == 0 {
+		return nil, errors.New("generator must have a type and a signature suite")
+	}
+
+	for _, s := range op.suites {
+		switch s {
+		case JWTEdDSA, Ed25519Signature2018:
+		default:
+			return nil, fmt.Errorf("signature suite %q is not supported", s)
+		}
+	}
+
+	if op.loader == nil {
+		loader, err := ldloader.New(nil)
+		if err != nil {
+			return nil, fmt.Errorf("create document loader: %w", err)
+		}
+
+		op.loader = loader
+	}
+
+	issuers := op.custom
+	if len(issuers) == 0 {
+		for i := 0; i < op.issuers; i++ {
+			key := ed25519.NewKeyFromSeed(derive(seed, "issuer", i))
+			did, keyID := fingerprint.CreateDIDKey(key.Public().(ed25519.PublicKey)) // nolint: forcetypeassert
+
+			issuers = append(issuers, &Issuer{DID: did, KeyID: keyID, Ed25519: key})
+		}
+	}
+
+	return &Generator{
+		seed:         seed,
+		issuers:      issuers,
+		types:        op.types,
+		suites:       op.suites,
+		size:         op.size,
+		issuanceDate: op.issuanceDate,
+		loader:       op.loader,
+	}, nil
+}
+
+// Issuers returns the issuers of the credentials.
+func (g *Generator) Issuers() []*Issuer {
+	return g.issuers
+}
+
+// IssuerDIDs returns the DIDs the credentials are issued by, e.g. to accept them in the issuers of a log.
+func (g *Generator) IssuerDIDs() []string {
+	dids := make([]string, 0, len(g.issuers))
+
+	for _, issuer := range g.issuers {
+		dids = append(dids, issuer.DID)
+	}
+
+	return dids
+}
+
+// Credentials returns the first n credentials.
+func (g *Generator) Credentials(n int) ([][]byte, error) {
+	credentials := make([][]byte, 0, n)
+
+	for i := 0; i < n; i++ {
+		credential, err := g.Credential(i)
+		if err != nil {
+			return nil, err
+		}
+
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, nil
+}
+
+// Credential returns the i-th credential: the issuers, the types and the suites are used in turn. The JWT
+// credentials are returned in compact serialization, the other credentials as JSON-LD documents.
+func (g *Generator) Credential(i int) ([]byte, error) {
+	if i < 0 {
+		return nil, fmt.Errorf("index %d is negative", i)
+	}
+
+	credential, err := g.sign(i, "")
+	if err != nil || len(credential) >= g.size {
+		return credential, err
+	}
+
+	padding := g.size - len(credential)
+
+	if g.suite(i) == JWTEdDSA {
+		padding = padding * 3 / 4 // nolint: gomnd // base64url
+	}
+
+	return g.sign(i, strings.Repeat("x", padding))
+}
+
+func (g *Generator) suite(i int) Suite {
+	return g.suites[i%len(g.suites)]
+}
+
+// sign returns the i-th credential, the padding is the description of its subject.
+func (g *Generator) sign(i int, padding string) ([]byte, error) {
+	vc, err := g.credential(i, padding)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := g.issuers[i%len(g.issuers)]
+
+	switch g.suite(i) {
+	case JWTEdDSA:
+		return signJWT(vc, verifiable.EdDSA, ed25519Signer(issuer.Ed25519), issuer.KeyID)
+	default:
+		return g.signLD(vc, "Ed25519Signature2018",
+			ed25519signature2018.New(suite.WithSigner(ed25519Signer(issuer.Ed25519))), issuer.KeyID)
+	}
+}
+
+// credential returns the unsigned i-th credential.
+func (g *Generator) credential(i int, padding string) (*verifiable.Credential, error) {
+	context := []interface{}{credentialsContext, map[string]string{"@vocab": vocab}}
+
+	subject := map[string]interface{}{
+		"id":   fmt.Sprintf("did:example:vcgen-subject-%x", derive(g.seed, "subject", i)[:8]),
+		"name": fmt.Sprintf("Test credential %d", i),
+	}
+
+	if padding != "" {
+		subject[paddingFieldName] = padding
+	}
+
+	src, err := json.Marshal(map[string]interface{}{
+		"@context":          context,
+		"id":                fmt.Sprintf("urn:vcgen:%x:%d", derive(g.seed, "credential", 0)[:8], i),
+		"type":              []string{"VerifiableCredential", g.types[i%len(g.types)]},
+		"issuer":            g.issuers[i%len(g.issuers)].DID,
+		"issuanceDate":      g.issuanceDate.UTC().Format(time.RFC3339),
+		"credentialSubject": subject,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal credential: %w", err)
+	}
+
+	vc, err := verifiable.ParseCredential(src, verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(g.loader))
+	if err != nil {
+		return nil, fmt.Errorf("parse credential: %w", err)
+	}
+
+	return vc, nil
+}
+
+func signJWT(vc *verifiable.Credential, alg verifiable.JWSAlgorithm, s verifiable.Signer,
+	keyID string) ([]byte, error) {
+	claims, err := vc.JWTClaims(false)
+	if err != nil {
+		return nil, fmt.Errorf("credential claims: %w", err)
+	}
+
+	jws, err := claims.MarshalJWS(alg, s, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("sign credential: %w", err)
+	}
+
+	return []byte(jws), nil
+}
+
+func (g *Generator) signLD(vc *verifiable.Credential, signatureType string, s signer.SignatureSuite,
+	keyID string) ([]byte, error) {
+	created := g.issuanceDate
+
+	err := vc.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+		SignatureType:           signatureType,
+		Suite:                   s,
+		SignatureRepresentation: verifiable.SignatureJWS,
+		VerificationMethod:      keyID,
+		Purpose:                 assertionMethod,
+		Created:                 &created,
+	}, ldprocessor.WithDocumentLoader(g.loader))
+	if err != nil {
+		return nil, fmt.Errorf("add linked data proof: %w", err)
+	}
+
+	src, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("marshal credential: %w", err)
+	}
+
+	return src, nil
+}
+
+// derive returns the 32 bytes derived from the seed for the purpose and the index.
+func derive(seed, purpose string, i int) []byte {
+	digest := sha256.Sum256([]byte(fmt.Sprintf("vcgen-%s-v1:%s:%d", purpose, seed, i)))
+
+	return digest[:]
+}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (s ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), data), nil
+}
+
+func (s ed25519Signer) Alg() string {
+	return ""
+}
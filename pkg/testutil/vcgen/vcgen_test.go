@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vcgen_test
+
+import (
+	"crypto/ed25519"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/fingerprint"
+	"github.com/hyperledger/aries-framework-go/pkg/vdr/key"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/ldloader"
+	"github.com/trustbloc/vct/pkg/testutil/vcgen"
+)
+
+// parse parses the credential and verifies its proof as the log does.
+func parse(t *testing.T, credential []byte) *verifiable.Credential {
+	t.Helper()
+
+	loader, err := ldloader.New(nil)
+	require.NoError(t, err)
+
+	registry := vdr.New(vdr.WithVDR(key.New()))
+
+	vc, err := verifiable.ParseCredential(credential,
+		verifiable.WithPublicKeyFetcher(verifiable.NewVDRKeyResolver(registry).PublicKeyFetcher()),
+		verifiable.WithJSONLDDocumentLoader(loader),
+	)
+	require.NoError(t, err)
+
+	return vc
+}
+
+func TestGenerator_Credential(t *testing.T) {
+	for _, s := range []vcgen.Suite{vcgen.JWTEdDSA, vcgen.Ed25519Signature2018} {
+		t.Run(string(s), func(t *testing.T) {
+			g, err := vcgen.New("scenario", vcgen.WithSuites(s))
+			require.NoError(t, err)
+
+			credential, err := g.Credential(0)
+			require.NoError(t, err)
+
+			vc := parse(t, credential)
+			require.Equal(t, []string{"VerifiableCredential", vcgen.DefaultType}, vc.Types)
+			require.Contains(t, g.IssuerDIDs(), vc.Issuer.ID)
+			require.Equal(t, len(vc.Proofs) == 0, s == vcgen.JWTEdDSA)
+		})
+	}
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	g, err := vcgen.New("scenario", vcgen.WithSuites(vcgen.JWTEdDSA, vcgen.Ed25519Signature2018))
+	require.NoError(t, err)
+
+	credentials, err := g.Credentials(4)
+	require.NoError(t, err)
+	require.Len(t, credentials, 4)
+	require.NotEqual(t, credentials[0], credentials[2])
+
+	again, err := vcgen.New("scenario", vcgen.WithSuites(vcgen.JWTEdDSA, vcgen.Ed25519Signature2018))
+	require.NoError(t, err)
+
+	credential, err := again.Credential(3)
+	require.NoError(t, err)
+	require.Equal(t, credentials[3], credential)
+
+	other, err := vcgen.New("another scenario")
+	require.NoError(t, err)
+	require.NotEqual(t, g.IssuerDIDs(), other.IssuerDIDs())
+}
+
+func TestGenerator_Options(t *testing.T) {
+	t.Run("Issuers and types in turn", func(t *testing.T) {
+		g, err := vcgen.New("scenario", vcgen.WithIssuers(2),
+			vcgen.WithTypes("UniversityDegreeCredential", "PermanentResidentCard"))
+		require.NoError(t, err)
+		require.Len(t, g.IssuerDIDs(), 2)
+
+		credentials, err := g.Credentials(3)
+		require.NoError(t, err)
+
+		first, second, third := parse(t, credentials[0]), parse(t, credentials[1]), parse(t, credentials[2])
+		require.NotEqual(t, first.Issuer.ID, second.Issuer.ID)
+		require.Equal(t, first.Issuer.ID, third.Issuer.ID)
+		require.Equal(t, "UniversityDegreeCredential", first.Types[1])
+		require.Equal(t, "PermanentResidentCard", second.Types[1])
+		require.NotEqual(t, first.ID, third.ID)
+	})
+
+	t.Run("Issuer", func(t *testing.T) {
+		pubKey, privKey, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+
+		did, keyID := fingerprint.CreateDIDKey(pubKey)
+
+		g, err := vcgen.New("scenario", vcgen.WithIssuer(did, keyID, privKey))
+		require.NoError(t, err)
+		require.Equal(t, []string{did}, g.IssuerDIDs())
+
+		credential, err := g.Credential(0)
+		require.NoError(t, err)
+		require.Equal(t, did, parse(t, credential).Issuer.ID)
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		for _, s := range []vcgen.Suite{vcgen.JWTEdDSA, vcgen.Ed25519Signature2018} {
+			g, err := vcgen.New("scenario", vcgen.WithSuites(s), vcgen.WithSize(10000))
+			require.NoError(t, err)
+
+			credential, err := g.Credential(0)
+			require.NoError(t, err)
+			require.InDelta(t, 10000, len(credential), 100)
+
+			parse(t, credential)
+		}
+	})
+
+	t.Run("Issuance date", func(t *testing.T) {
+		date := time.Date(2022, time.May, 1, 0, 0, 0, 0, time.UTC)
+
+		g, err := vcgen.New("scenario", vcgen.WithIssuanceDate(date))
+		require.NoError(t, err)
+
+		credential, err := g.Credential(0)
+		require.NoError(t, err)
+		require.True(t, date.Equal(parse(t, credential).Issued.Time))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := vcgen.New("scenario", vcgen.WithIssuers(0))
+		require.EqualError(t, err, "generator must have an issuer")
+
+		_, err = vcgen.New("scenario", vcgen.WithTypes())
+		require.EqualError(t, err, "generator must have a type and a signature suite")
+
+		_, err = vcgen.New("scenario", vcgen.WithSuites("BbsBlsSignature2020"))
+		require.EqualError(t, err, `signature suite "BbsBlsSignature2020" is not supported`)
+
+		g, err := vcgen.New("scenario")
+		require.NoError(t, err)
+
+		_, err = g.Credential(-1)
+		require.EqualError(t, err, "index -1 is negative")
+	})
+}
@@ -0,0 +1,156 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package trustregistry implements command.TrustRegistry for trust registries exposing the ToIP
+// Trust Registry Query Protocol (TRQP). Other registries (e.g. TRAIN trust lists) can be plugged in
+// by implementing command.TrustRegistry.
+package trustregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+)
+
+const (
+	authorizationPath = "/authorization"
+	// issueAction is the action the issuer is checked to be authorized for.
+	issueAction = "issue"
+	// maxErrorBody is the max size of the response body reported in an error.
+	maxErrorBody = 512
+)
+
+type options struct {
+	http        HTTPClient
+	authorityID string
+	resource    string
+}
+
+// Opt represents client option func.
+type Opt func(*options)
+
+// WithHTTPClient allows providing HTTP client.
+func WithHTTPClient(client HTTPClient) Opt {
+	return func(o *options) {
+		o.http = client
+	}
+}
+
+// WithAuthorityID sets the ID of the authority (e.g. the ecosystem governance authority) the issuer is
+// checked to be authorized by.
+func WithAuthorityID(authorityID string) Opt {
+	return func(o *options) {
+		o.authorityID = authorityID
+	}
+}
+
+// WithResource sets the resource (e.g. the credential type) the issuer is checked to be authorized to issue.
+func WithResource(resource string) Opt {
+	return func(o *options) {
+		o.resource = resource
+	}
+}
+
+// HTTPClient represents HTTP client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client queries a TRQP trust registry.
+type Client struct {
+	endpoint    string
+	http        HTTPClient
+	authorityID string
+	resource    string
+}
+
+// New returns a client of the trust registry with the given endpoint.
+func New(endpoint string, opts ...Opt) *Client {
+	op := &options{}
+
+	for _, fn := range opts {
+		fn(op)
+	}
+
+	if op.http == nil {
+		op.http = &http.Client{Timeout: time.Minute}
+	}
+
+	return &Client{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		http:        op.http,
+		authorityID: op.authorityID,
+		resource:    op.resource,
+	}
+}
+
+type authorizationRequest struct {
+	EntityID    string `json:"entity_id"`
+	AuthorityID string `json:"authority_id,omitempty"`
+	Action      string `json:"action"`
+	Resource    string `json:"resource,omitempty"`
+}
+
+type authorizationResponse struct {
+	Authorized bool `json:"authorized"`
+}
+
+// Check queries whether the issuer is authorized to issue (TRQP authorization query).
+// An issuer unknown to the registry (not found) is not authorized.
+func (c *Client) Check(ctx context.Context, issuer string) (*command.TrustDecision, error) {
+	src, err := json.Marshal(authorizationRequest{
+		EntityID:    issuer,
+		AuthorityID: c.authorityID,
+		Action:      issueAction,
+		Resource:    c.resource,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+authorizationPath, bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("new request with context: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http do: %w", err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	decision := &command.TrustDecision{Registry: c.endpoint}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return decision, nil
+	default:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody)) // nolint: errcheck
+
+		return nil, fmt.Errorf("registry responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result authorizationResponse
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	decision.Authorized = result.Authorized
+
+	return decision, nil
+}
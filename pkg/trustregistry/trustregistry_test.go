@@ -0,0 +1,76 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package trustregistry_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/controller/command"
+	. "github.com/trustbloc/vct/pkg/trustregistry"
+)
+
+func TestClient_Check(t *testing.T) {
+	const (
+		authorized   = "did:example:authorized"
+		unauthorized = "did:example:unauthorized"
+		unknown      = "did:example:unknown"
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/trqp/authorization", r.URL.Path)
+
+		var req map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "did:example:ecosystem", req["authority_id"])
+		require.Equal(t, "issue", req["action"])
+		require.Equal(t, "UniversityDegreeCredential", req["resource"])
+
+		switch req["entity_id"] {
+		case authorized, unauthorized:
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"entity_id":  req["entity_id"],
+				"authorized": req["entity_id"] == authorized,
+			}))
+		case unknown:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			http.Error(w, "registry is down", http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	client := New(ts.URL+"/trqp/",
+		WithAuthorityID("did:example:ecosystem"),
+		WithResource("UniversityDegreeCredential"),
+		WithHTTPClient(ts.Client()),
+	)
+
+	decision, err := client.Check(context.Background(), authorized)
+	require.NoError(t, err)
+	require.Equal(t, &command.TrustDecision{Registry: ts.URL + "/trqp", Authorized: true}, decision)
+
+	decision, err = client.Check(context.Background(), unauthorized)
+	require.NoError(t, err)
+	require.False(t, decision.Authorized)
+
+	decision, err = client.Check(context.Background(), unknown)
+	require.NoError(t, err)
+	require.False(t, decision.Authorized)
+
+	_, err = client.Check(context.Background(), "did:example:error")
+	require.EqualError(t, err, "registry responded with status 500: registry is down")
+
+	_, err = New("http://localhost:0").Check(context.Background(), authorized)
+	require.Contains(t, err.Error(), "http do")
+}
@@ -0,0 +1,272 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+// DefaultMaxAge is the max age of the webhooks accepted by default, older webhooks may be replayed.
+const DefaultMaxAge = 5 * time.Minute
+
+// ErrInvalidSignature is returned if the signature of the webhook is not valid.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// KeyResolver resolves the public key of the key ID of a webhook signature.
+type KeyResolver interface {
+	ResolveKey(keyID string) (crypto.PublicKey, error)
+}
+
+// KeyResolverFunc is a function resolving the public keys.
+type KeyResolverFunc func(keyID string) (crypto.PublicKey, error)
+
+// ResolveKey resolves the public key of the key ID.
+func (f KeyResolverFunc) ResolveKey(keyID string) (crypto.PublicKey, error) {
+	return f(keyID)
+}
+
+// StaticKeys resolves the key IDs to the pinned public keys (*ecdsa.PublicKey or ed25519.PublicKey).
+func StaticKeys(keys map[string]crypto.PublicKey) KeyResolver {
+	return KeyResolverFunc(func(keyID string) (crypto.PublicKey, error) {
+		key, ok := keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("key %q is not known", keyID)
+		}
+
+		return key, nil
+	})
+}
+
+// VDR resolves DIDs.
+type VDR interface {
+	Resolve(did string, opts ...vdrapi.DIDMethodOption) (*did.DocResolution, error)
+}
+
+// DIDKeyResolver resolves the key IDs which are DID URLs to the verification methods of the DID documents, e.g.
+// the webhook key of the log in its DID document.
+type DIDKeyResolver struct {
+	vdr VDR
+}
+
+// NewDIDKeyResolver returns a resolver of the keys of the DID documents resolved by the VDR.
+func NewDIDKeyResolver(vdr VDR) *DIDKeyResolver {
+	return &DIDKeyResolver{vdr: vdr}
+}
+
+// ResolveKey resolves the verification method of the DID URL (<did>#<fragment>), a JsonWebKey2020 or an
+// Ed25519VerificationKey2018.
+func (r *DIDKeyResolver) ResolveKey(keyID string) (crypto.PublicKey, error) {
+	const didURLParts = 2
+
+	parts := strings.SplitN(keyID, "#", didURLParts)
+	if len(parts) != didURLParts || !strings.HasPrefix(parts[0], "did:") || parts[1] == "" {
+		return nil, fmt.Errorf("key ID %q is not a DID URL", keyID)
+	}
+
+	resolution, err := r.vdr.Resolve(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("resolve DID %s: %w", parts[0], err)
+	}
+
+	for i := range resolution.DIDDocument.VerificationMethod {
+		vm := &resolution.DIDDocument.VerificationMethod[i]
+
+		if vm.ID != keyID && vm.ID != "#"+parts[1] {
+			continue
+		}
+
+		if key := vm.JSONWebKey(); key != nil {
+			return key.Key, nil
+		}
+
+		if vm.Type == "Ed25519VerificationKey2018" && len(vm.Value) == ed25519.PublicKeySize {
+			return ed25519.PublicKey(vm.Value), nil
+		}
+
+		return nil, fmt.Errorf("verification method %s of type %s is not supported", keyID, vm.Type)
+	}
+
+	return nil, fmt.Errorf("verification method %s is not found", keyID)
+}
+
+// Verifier verifies the signatures of the webhooks.
+type Verifier struct {
+	keys   KeyResolver
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// Opt is an option of the verifier.
+type Opt func(*Verifier)
+
+// WithMaxAge sets the max age of the webhooks accepted (DefaultMaxAge by default), the timestamps of the webhooks
+// may be ahead of the clock by as much.
+func WithMaxAge(maxAge time.Duration) Opt {
+	return func(v *Verifier) {
+		v.maxAge = maxAge
+	}
+}
+
+// WithClock sets the clock the age of the webhooks is checked with (time.Now by default).
+func WithClock(now func() time.Time) Opt {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier returns a verifier of the webhooks signed with the keys resolved.
+func NewVerifier(keys KeyResolver, opts ...Opt) *Verifier {
+	v := &Verifier{keys: keys, maxAge: DefaultMaxAge, now: time.Now}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// VerifyRequest verifies the signature of the webhook request, it returns the body of the request (which can be
+// read again from the request).
+func (v *Verifier) VerifyRequest(req *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if err = v.Verify(req.Header.Get(SignatureHeader), req.Header.Get(TimestampHeader), body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// Verify verifies the detached JWS of the body signed at the timestamp (the values of the SignatureHeader and of
+// the TimestampHeader), the webhook must not be older than the max age.
+func (v *Verifier) Verify(signature, timestamp string, body []byte) error {
+	ts, err := strconv.ParseUint(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp %q is not a number", ErrInvalidSignature, timestamp)
+	}
+
+	signedAt := time.Unix(0, int64(ts)*int64(time.Millisecond))
+	if age := v.now().Sub(signedAt); age > v.maxAge || age < -v.maxAge {
+		return fmt.Errorf("%w: webhook signed at %s is older than %s or ahead of the clock", ErrInvalidSignature,
+			signedAt.UTC().Format(time.RFC3339), v.maxAge)
+	}
+
+	const jwsParts = 3
+
+	parts := strings.Split(signature, ".")
+	if len(parts) != jwsParts || parts[1] != "" {
+		return fmt.Errorf("%w: signature is not a detached JWS", ErrInvalidSignature)
+	}
+
+	var header Header
+
+	src, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err == nil {
+		err = json.Unmarshal(src, &header)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: decode header: %v", ErrInvalidSignature, err)
+	}
+
+	if header.Type != Type {
+		return fmt.Errorf("%w: type %q is not %s", ErrInvalidSignature, header.Type, Type)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: decode signature: %v", ErrInvalidSignature, err)
+	}
+
+	key, err := v.keys.ResolveKey(header.KeyID)
+	if err != nil {
+		return fmt.Errorf("%w: resolve key: %v", ErrInvalidSignature, err)
+	}
+
+	if err = verifySignature(header.Algorithm, key, SigningInput(parts[0], ts, body), sig); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, data, signature []byte) error {
+	if alg == EdDSA {
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("key %T is not an Ed25519 key", key)
+		}
+
+		if !ed25519.Verify(edKey, data, signature) {
+			return errors.New("signature does not match")
+		}
+
+		return nil
+	}
+
+	var (
+		curve  elliptic.Curve
+		digest []byte
+	)
+
+	switch alg {
+	case ES256:
+		hash := sha256.Sum256(data)
+		curve, digest = elliptic.P256(), hash[:]
+	case ES384:
+		hash := sha512.Sum384(data)
+		curve, digest = elliptic.P384(), hash[:]
+	case ES512:
+		hash := sha512.Sum512(data)
+		curve, digest = elliptic.P521(), hash[:]
+	default:
+		return fmt.Errorf("algorithm %q is not supported", alg)
+	}
+
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok || ecKey.Curve != curve {
+		return fmt.Errorf("key %T is not a %s key", key, curve.Params().Name)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8 // nolint: gomnd
+	if len(signature) != 2*size {
+		return fmt.Errorf("signature is not %d bytes", 2*size)
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	if !ecdsa.Verify(ecKey, digest, r, s) {
+		return errors.New("signature does not match")
+	}
+
+	return nil
+}
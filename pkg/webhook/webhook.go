@@ -0,0 +1,158 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package webhook signs the webhooks of the log (the callbacks of the submissions and the tree heads pushed to the
+// distributors) and verifies them.
+//
+// A webhook is signed with a detached JWS (RFC 7515, appendix F) in compact serialization, sent in the
+// Vct-Webhook-Signature header: <protected header>..<signature>. The payload of the JWS is the timestamp (ms) of
+// the Vct-Webhook-Timestamp header, a dot and the body of the request, base64url-encoded as usual. The protected
+// header has the algorithm (ES256, ES384, ES512 or EdDSA), the key ID and the type vct-webhook+jws. The key ID is a
+// DID URL of a verification method of the DID document of the log if the log has a DID, the base64url-encoded
+// SHA-256 hash of the public key (the log ID of the key, as published in the webfinger of the log) otherwise.
+package webhook
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+)
+
+const (
+	// SignatureHeader is the header of the detached JWS of the webhook.
+	SignatureHeader = "Vct-Webhook-Signature"
+	// TimestampHeader is the header of the timestamp (ms) the webhook is signed at.
+	TimestampHeader = "Vct-Webhook-Timestamp"
+	// Type is the type of the protected header of the JWS.
+	Type = "vct-webhook+jws"
+)
+
+// JWS algorithms of the webhooks.
+const (
+	ES256 = "ES256"
+	ES384 = "ES384"
+	ES512 = "ES512"
+	EdDSA = "EdDSA"
+)
+
+// Header is the protected header of the JWS.
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Type      string `json:"typ"`
+}
+
+// Crypto signs with a key handle of the KMS.
+type Crypto interface {
+	Sign(msg []byte, kh interface{}) ([]byte, error)
+}
+
+// Signer signs the webhooks with a key of the KMS.
+type Signer struct {
+	crypto Crypto
+	kh     interface{}
+	header string // base64url-encoded protected header
+	curve  elliptic.Curve
+	der    bool // the KMS encodes the ECDSA signatures in ASN.1 DER, JWS concatenates R and S
+}
+
+// NewSigner returns a signer of the webhooks with the key of the type (ECDSA or Ed25519), identified by the key ID.
+func NewSigner(crypto Crypto, kh interface{}, keyType kms.KeyType, keyID string) (*Signer, error) {
+	s := &Signer{crypto: crypto, kh: kh}
+
+	var alg string
+
+	switch keyType { // nolint: exhaustive
+	case kms.ECDSAP256TypeDER, kms.ECDSAP256TypeIEEEP1363:
+		alg, s.curve = ES256, elliptic.P256()
+	case kms.ECDSAP384TypeDER, kms.ECDSAP384TypeIEEEP1363:
+		alg, s.curve = ES384, elliptic.P384()
+	case kms.ECDSAP521TypeDER, kms.ECDSAP521TypeIEEEP1363:
+		alg, s.curve = ES512, elliptic.P521()
+	case kms.ED25519Type:
+		alg = EdDSA
+	default:
+		return nil, fmt.Errorf("key type %s is not supported", keyType)
+	}
+
+	s.der = keyType == kms.ECDSAP256TypeDER || keyType == kms.ECDSAP384TypeDER || keyType == kms.ECDSAP521TypeDER
+
+	header, err := json.Marshal(Header{Algorithm: alg, KeyID: keyID, Type: Type})
+	if err != nil {
+		return nil, fmt.Errorf("marshal header: %w", err)
+	}
+
+	s.header = base64.RawURLEncoding.EncodeToString(header)
+
+	return s, nil
+}
+
+// Sign returns the detached JWS of the body signed at the timestamp (ms).
+func (s *Signer) Sign(timestamp uint64, body []byte) (string, error) {
+	signature, err := s.crypto.Sign(SigningInput(s.header, timestamp, body), s.kh)
+	if err != nil {
+		return "", fmt.Errorf("sign: %w", err)
+	}
+
+	if s.der {
+		if signature, err = derToConcat(signature, s.curve); err != nil {
+			return "", err
+		}
+	}
+
+	return s.header + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// SignRequest signs the body of the request now, the signature and the timestamp are set in the headers.
+func (s *Signer) SignRequest(req *http.Request, body []byte) error {
+	timestamp := uint64(time.Now().UnixNano()) / uint64(time.Millisecond)
+
+	signature, err := s.Sign(timestamp, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(TimestampHeader, strconv.FormatUint(timestamp, 10))
+	req.Header.Set(SignatureHeader, signature)
+
+	return nil
+}
+
+// SigningInput returns the JWS signing input of the body signed at the timestamp: the protected header, a dot and
+// the base64url-encoded payload (the timestamp, a dot and the body).
+func SigningInput(encodedHeader string, timestamp uint64, body []byte) []byte {
+	payload := append([]byte(strconv.FormatUint(timestamp, 10)+"."), body...)
+
+	return []byte(encodedHeader + "." + base64.RawURLEncoding.EncodeToString(payload))
+}
+
+// derToConcat converts the ASN.1 DER encoded ECDSA signature to the concatenation of R and S (RFC 7518, 3.4).
+func derToConcat(signature []byte, curve elliptic.Curve) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+
+	if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) > 0 {
+		return nil, errors.New("signature is not ASN.1 DER encoded")
+	}
+
+	size := (curve.Params().BitSize + 7) / 8 // nolint: gomnd
+
+	concat := make([]byte, 2*size)
+	sig.R.FillBytes(concat[:size])
+	sig.S.FillBytes(concat[size:])
+
+	return concat, nil
+}
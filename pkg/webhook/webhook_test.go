@@ -0,0 +1,245 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	vdrapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/spi/storage"
+	"github.com/square/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/vct/pkg/webhook"
+)
+
+const (
+	keyID = "did:example:log#webhook"
+	body  = `{"alias":"maple2021","tree_size":3}`
+)
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
+
+// newSigner returns a signer with a new key of the type and the public key of the signer.
+func newSigner(t *testing.T, keyType kms.KeyType) (*webhook.Signer, crypto.PublicKey) {
+	t.Helper()
+
+	km, err := localkms.New("local-lock://default/master/key/", &kmsProvider{
+		storageProvider: mem.NewProvider(),
+		secretLock:      &noop.NoLock{},
+	})
+	require.NoError(t, err)
+
+	cr, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	kid, kh, err := km.Create(keyType)
+	require.NoError(t, err)
+
+	pubKey, _, err := km.ExportPubKeyBytes(kid)
+	require.NoError(t, err)
+
+	key, err := jwksupport.PubKeyBytesToJWK(pubKey, keyType)
+	require.NoError(t, err)
+
+	signer, err := webhook.NewSigner(cr, kh, keyType, keyID)
+	require.NoError(t, err)
+
+	return signer, key.Key
+}
+
+func newRequest(t *testing.T, signer *webhook.Signer) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "https://issuer.example.com/callback", bytes.NewBufferString(body))
+	require.NoError(t, signer.SignRequest(req, []byte(body)))
+
+	return req
+}
+
+func TestSigner(t *testing.T) {
+	for _, keyType := range []kms.KeyType{
+		kms.ECDSAP256TypeIEEEP1363, kms.ECDSAP256TypeDER, kms.ECDSAP384TypeDER, kms.ED25519Type,
+	} {
+		t.Run(string(keyType), func(t *testing.T) {
+			signer, pubKey := newSigner(t, keyType)
+
+			req := newRequest(t, signer)
+
+			verified, err := webhook.NewVerifier(webhook.StaticKeys(map[string]crypto.PublicKey{keyID: pubKey})).
+				VerifyRequest(req)
+			require.NoError(t, err)
+			require.Equal(t, body, string(verified))
+
+			// the body can be read again
+			src, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			require.Equal(t, body, string(src))
+
+			// a JWS library verifies the signature with the payload attached
+			parts := strings.Split(req.Header.Get(webhook.SignatureHeader), ".")
+			require.Len(t, parts, 3)
+			require.Empty(t, parts[1])
+
+			payload := req.Header.Get(webhook.TimestampHeader) + "." + body
+
+			jws, err := jose.ParseSigned(parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+				parts[2])
+			require.NoError(t, err)
+			require.Equal(t, keyID, jws.Signatures[0].Header.KeyID)
+
+			attached, err := jws.Verify(pubKey)
+			require.NoError(t, err)
+			require.Equal(t, payload, string(attached))
+		})
+	}
+
+	t.Run("Unsupported key type", func(t *testing.T) {
+		_, err := webhook.NewSigner(nil, nil, kms.BLS12381G2Type, keyID)
+		require.EqualError(t, err, "key type BLS12381G2 is not supported")
+	})
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	signer, pubKey := newSigner(t, kms.ECDSAP256TypeIEEEP1363)
+	keys := webhook.StaticKeys(map[string]crypto.PublicKey{keyID: pubKey})
+
+	now := time.Now()
+	timestamp := uint64(now.UnixNano()) / uint64(time.Millisecond)
+
+	signature, err := signer.Sign(timestamp, []byte(body))
+	require.NoError(t, err)
+
+	ts := strconv.FormatUint(timestamp, 10)
+
+	require.NoError(t, webhook.NewVerifier(keys).Verify(signature, ts, []byte(body)))
+
+	for _, tc := range []struct {
+		name                 string
+		signature, timestamp string
+		body                 string
+		opts                 []webhook.Opt
+		err                  string
+	}{
+		{"Body", signature, ts, `{}`, nil, "signature does not match"},
+		{"Timestamp", signature, strconv.FormatUint(timestamp+1, 10), body, nil, "signature does not match"},
+		{"Not a timestamp", signature, "now", body, nil, `timestamp "now" is not a number`},
+		{
+			"Stale", signature, ts, body,
+			[]webhook.Opt{webhook.WithClock(func() time.Time { return now.Add(time.Hour) })},
+			"is older than 5m0s or ahead of the clock",
+		},
+		{
+			"Max age", signature, ts, body,
+			[]webhook.Opt{
+				webhook.WithClock(func() time.Time { return now.Add(time.Hour) }),
+				webhook.WithMaxAge(2 * time.Hour),
+			},
+			"",
+		},
+		{"Attached", strings.Replace(signature, "..", ".e30.", 1), ts, body, nil, "signature is not a detached JWS"},
+		{"Header", "e30" + signature[strings.Index(signature, ".."):], ts, body, nil, `type "" is not vct-webhook+jws`},
+		{"Signature", signature + "!", ts, body, nil, "decode signature"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err = webhook.NewVerifier(keys, tc.opts...).Verify(tc.signature, tc.timestamp, []byte(tc.body))
+			if tc.err == "" {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, webhook.ErrInvalidSignature)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+
+	t.Run("Unknown key", func(t *testing.T) {
+		err = webhook.NewVerifier(webhook.StaticKeys(nil)).Verify(signature, ts, []byte(body))
+		require.ErrorIs(t, err, webhook.ErrInvalidSignature)
+		require.Contains(t, err.Error(), `key "did:example:log#webhook" is not known`)
+	})
+
+	t.Run("Key of another type", func(t *testing.T) {
+		_, edKey := newSigner(t, kms.ED25519Type)
+
+		err = webhook.NewVerifier(webhook.StaticKeys(map[string]crypto.PublicKey{keyID: edKey})).
+			Verify(signature, ts, []byte(body))
+		require.ErrorIs(t, err, webhook.ErrInvalidSignature)
+		require.Contains(t, err.Error(), "is not a P-256 key")
+	})
+}
+
+type mockVDR struct {
+	doc *did.Doc
+	err error
+}
+
+func (m *mockVDR) Resolve(string, ...vdrapi.DIDMethodOption) (*did.DocResolution, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &did.DocResolution{DIDDocument: m.doc}, nil
+}
+
+func TestDIDKeyResolver(t *testing.T) {
+	signer, pubKey := newSigner(t, kms.ECDSAP256TypeIEEEP1363)
+
+	key, err := jwksupport.JWKFromKey(pubKey)
+	require.NoError(t, err)
+
+	vm, err := did.NewVerificationMethodFromJWK(keyID, "JsonWebKey2020", "did:example:log", key)
+	require.NoError(t, err)
+
+	vdr := &mockVDR{doc: &did.Doc{ID: "did:example:log", VerificationMethod: []did.VerificationMethod{*vm}}}
+
+	_, err = webhook.NewVerifier(webhook.NewDIDKeyResolver(vdr)).VerifyRequest(newRequest(t, signer))
+	require.NoError(t, err)
+
+	resolver := webhook.NewDIDKeyResolver(vdr)
+
+	_, err = resolver.ResolveKey("did:example:log#other")
+	require.EqualError(t, err, "verification method did:example:log#other is not found")
+
+	_, err = resolver.ResolveKey("webhook")
+	require.EqualError(t, err, `key ID "webhook" is not a DID URL`)
+
+	vdr.err = errors.New("not found")
+
+	_, err = resolver.ResolveKey(keyID)
+	require.EqualError(t, err, "resolve DID did:example:log: not found")
+}
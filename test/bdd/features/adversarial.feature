@@ -0,0 +1,50 @@
+#
+# Copyright SecureKey Technologies Inc. All Rights Reserved.
+#
+# SPDX-License-Identifier: Apache-2.0
+#
+
+@all
+Feature: Verifiable credentials transparency API rejects adversarial requests.
+  Scenario: Rejects tampered verifiable credentials
+    Given VCT agent is running on "http://localhost:5678/maple2021"
+    Then  Generate "1" credentials from "bdd-tampered" signed with "Ed25519Signature2018" and issued by "maple2021"
+    And   Add tampered verifiable credential "bdd-tampered/Ed25519Signature2018/0" to Log and check that it is rejected
+    When  Retrieve latest signed tree head and check that tree_size is "0"
+
+  Scenario: Rejects oversized verifiable credentials
+    Given VCT agent is running on "http://localhost:5678/maple2021"
+    Then  Generate "1" credentials from "bdd-oversized" signed with "Ed25519Signature2018" and issued by "maple2021"
+    And   Add verifiable credential "bdd-oversized/Ed25519Signature2018/0" padded to "100000" bytes to Log and check that it is rejected
+    When  Retrieve latest signed tree head and check that tree_size is "0"
+
+  Scenario: Does not log duplicate submissions twice
+    Given VCT agent is running on "http://localhost:5678/maple2020"
+    Then  Add "1" credentials generated from "bdd-duplicate" signed with "jwt-eddsa" and issued by "maple2020" to Log
+    When  Retrieve latest signed tree head and check that tree_size is "1"
+    Then  Add verifiable credential "bdd-duplicate/jwt-eddsa/0" to Log again and check that the timestamp is the same
+    And   Add verifiable credential "bdd-duplicate/jwt-eddsa/0" to Log again and check that the timestamp is the same
+    When  Retrieve latest signed tree head and check that tree_size is "1"
+    And   Retrieve entries from log and check that len is "1"
+
+  Scenario: Merkle audit proofs do not verify tampered leaves and proofs
+    Given VCT agent is running on "http://localhost:5678/maple2020"
+    Then  Add "2" credentials generated from "bdd-inclusion" signed with "jwt-eddsa" and issued by "maple2020" to Log
+    And   Retrieve entries from log and check that len is "2"
+    Then  Check that the merkle audit proof for "bdd-inclusion/jwt-eddsa/0" does not verify tampered data
+    And   Check that the merkle audit proof for "bdd-inclusion/jwt-eddsa/1" does not verify tampered data
+
+  Scenario: Rejects merkle audit proof requests of unknown and invalid leaf hashes
+    Given VCT agent is running on "http://localhost:5678/maple2020"
+    Then  Add "1" credentials generated from "bdd-unknown-hash" signed with "jwt-eddsa" and issued by "maple2020" to Log
+    And   Retrieve entries from log and check that len is "1"
+    Then  Retrieve merkle audit proof from log by unknown leaf hash and check that it is rejected
+    And   Retrieve merkle audit proof from log by invalid leaf hash and check that it is rejected
+
+  Scenario: Merkle consistency proofs do not verify forged signed tree heads
+    Given VCT agent is running on "http://localhost:5678/maple2021"
+    Then  Add "1" credentials generated from "bdd-consistency-first" signed with "Ed25519Signature2018" and issued by "maple2021" to Log
+    And   Remember latest signed tree head
+    Then  Add "1" credentials generated from "bdd-consistency-second" signed with "Ed25519Signature2018" and issued by "maple2021" to Log
+    Then  Check that the merkle consistency proof from the remembered signed tree head does not verify forged signed tree heads
+    And   Retrieve merkle consistency proof for an invalid range and check that it is rejected
@@ -8,7 +8,7 @@
 Feature: Verifiable credentials transparency API.
   Scenario: Adds verifiable credentials to Log
     Given VCT agent is running on "http://localhost:5678/maple2021"
-    Then  Add verifiable credential "maple2021/bachelor_degree_no_proof.json" to Log
+    Then  Add "1" credentials generated from "bdd-add-maple2021" signed with "Ed25519Signature2018" and issued by "maple2021" to Log
     When  Retrieve latest signed tree head and check that tree_size is "1"
     And   Retrieve entries from log and check that len is "1"
 
@@ -23,19 +23,16 @@ Feature: Verifiable credentials transparency API.
 
   Scenario: Retrieve merkle consistency proof between signed tree heads
     Given VCT agent is running on "http://localhost:5678/maple2021"
-    Then  Add verifiable credential "maple2021/bachelor_degree_of_arts_no_proof.json" to Log
-    And   Add verifiable credential "maple2021/bachelor_degree_of_science_no_proof.json" to Log
-    And   Add verifiable credential "maple2021/verifiable_credentials_no_proof.json" to Log
+    Then  Add "3" credentials generated from "bdd-consistency-maple2021" signed with "Ed25519Signature2018" and issued by "maple2021" to Log
     When  Retrieve latest signed tree head and check that tree_size is "3"
     Then  Retrieve merkle consistency proof between signed tree heads
 
   Scenario: Retrieve merkle audit proof from log by leaf hash
     Given VCT agent is running on "http://localhost:5678/maple2021"
-    Then  Add verifiable credential "maple2021/bachelor_degree_of_finance_no_proof.json" to Log
-    And   Add verifiable credential "maple2021/bachelor_degree_of_law_no_proof.json" to Log
+    Then  Add "2" credentials generated from "bdd-audit-maple2021" signed with "Ed25519Signature2018" and issued by "maple2021" to Log
     And   Retrieve entries from log and check that len is "2"
-    And   Retrieve merkle audit proof from log by leaf hash for "maple2021/bachelor_degree_of_finance_no_proof.json"
-    And   Retrieve merkle audit proof from log by leaf hash for "maple2021/bachelor_degree_of_law_no_proof.json"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-audit-maple2021/Ed25519Signature2018/0"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-audit-maple2021/Ed25519Signature2018/1"
 
   Scenario: Retrieve merkle audit proof from log by leaf hash (did web)
     Given VCT agent is running on "http://localhost:5678/maple2021"
@@ -43,9 +40,18 @@ Feature: Verifiable credentials transparency API.
     And   Retrieve entries from log and check that len is "1"
     And   Retrieve merkle audit proof from log by leaf hash for "maple2021/bachelor_degree_web_proof.json"
 
+  Scenario: Adds generated verifiable credentials to Log
+    Given VCT agent is running on "http://localhost:5678/maple2024"
+    Then  Add "3" credentials generated from "bdd-generated" signed with "jwt-eddsa" to Log
+    And   Add "2" credentials generated from "bdd-generated" signed with "Ed25519Signature2018" to Log
+    When  Retrieve latest signed tree head and check that tree_size is "5"
+    And   Retrieve entries from log and check that len is "5"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-generated/jwt-eddsa/2"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-generated/Ed25519Signature2018/1"
+
   Scenario: Adds verifiable credentials to Log
     Given VCT agent is running on "http://localhost:5678/maple2020"
-    Then  Add verifiable credential "maple2020/bachelor_degree_no_proof.json" to Log
+    Then  Add "1" credentials generated from "bdd-add-maple2020" signed with "jwt-eddsa" and issued by "maple2020" to Log
     When  Retrieve latest signed tree head and check that tree_size is "1"
     And   Retrieve entries from log and check that len is "1"
 
@@ -60,34 +66,35 @@ Feature: Verifiable credentials transparency API.
 
   Scenario: Retrieve merkle consistency proof between signed tree heads
     Given VCT agent is running on "http://localhost:5678/maple2020"
-    Then  Add verifiable credential "maple2020/bachelor_degree_of_arts_no_proof.json" to Log
-    And   Add verifiable credential "maple2020/bachelor_degree_of_science_no_proof.json" to Log
-    And   Add verifiable credential "maple2020/verifiable_credentials_no_proof.json" to Log
+    Then  Add "3" credentials generated from "bdd-consistency-maple2020" signed with "jwt-eddsa" and issued by "maple2020" to Log
     When  Retrieve latest signed tree head and check that tree_size is "3"
     Then  Retrieve merkle consistency proof between signed tree heads
 
   Scenario: Retrieve merkle audit proof from log by leaf hash
     Given VCT agent is running on "http://localhost:5678/maple2020"
-    Then  Add verifiable credential "maple2020/bachelor_degree_of_finance_no_proof.json" to Log
-    And   Add verifiable credential "maple2020/bachelor_degree_of_law_no_proof.json" to Log
+    Then  Add "2" credentials generated from "bdd-audit-maple2020" signed with "jwt-eddsa" and issued by "maple2020" to Log
     And   Retrieve entries from log and check that len is "2"
-    And   Retrieve merkle audit proof from log by leaf hash for "maple2020/bachelor_degree_of_finance_no_proof.json"
-    And   Retrieve merkle audit proof from log by leaf hash for "maple2020/bachelor_degree_of_law_no_proof.json"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-audit-maple2020/jwt-eddsa/0"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-audit-maple2020/jwt-eddsa/1"
 
-  Scenario: Retrieve merkle audit proof from log by leaf hash (did web)
+  Scenario: Retrieve merkle audit proof from log by leaf hash (linked data proof)
     Given VCT agent is running on "http://localhost:5678/maple2020"
-    Then  Add verifiable credential "maple2020/bachelor_degree_web_no_proof.json" to Log
+    Then  Add "1" credentials generated from "bdd-ld-maple2020" signed with "Ed25519Signature2018" and issued by "maple2020" to Log
     And   Retrieve entries from log and check that len is "1"
-    And   Retrieve merkle audit proof from log by leaf hash for "maple2020/bachelor_degree_web_no_proof.json"
+    And   Retrieve merkle audit proof from log by leaf hash for "bdd-ld-maple2020/Ed25519Signature2018/0"
 
   Scenario: Checks issuers
     Given VCT agent is running on "http://localhost:5678/maple2020"
     Then The issuer "did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N7" is supported
     And  The issuer "did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N2" is not supported
+    And  The issuer "did:key:z6Mkf2Yi7vDZMd7puqY41NM2xVxR5UVyqrKScYzzb9KJRVgA" is supported
+    And  The issuer "did:key:z6MkvdJPBii37vbUSMd2eupS3JXaNA42LcM7nLkQxDhWutgn" is not supported
 
     Given VCT agent is running on "http://localhost:5678/maple2021"
     Then The issuer "did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N2" is supported
     And  The issuer "did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N7" is not supported
+    And  The issuer "did:key:z6MkvdJPBii37vbUSMd2eupS3JXaNA42LcM7nLkQxDhWutgn" is supported
+    And  The issuer "did:key:z6Mkf2Yi7vDZMd7puqY41NM2xVxR5UVyqrKScYzzb9KJRVgA" is not supported
 
     Given VCT agent is running on "http://localhost:5678/maple2022"
     Then The issuer "did:key:zUC724vuGvHpnCGFG1qqpXb81SiBLu3KLSqVzenwEZNPoY35i2Bscb8DLaVwHvRFs6F2NkNNXRcPWvqnPDUd9ukdjLkjZd3u9zzL4wDZDUpkPAatLDGLEYVo8kkAzuAKJQMr7N2" is supported
@@ -0,0 +1,333 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/cucumber/godog"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/vct/pkg/client/vct"
+)
+
+// registerAdversarialSteps registers the steps of the negative and adversarial scenarios: the submissions and the
+// requests the log must reject, and the proofs which must not verify tampered data.
+func (s *Steps) registerAdversarialSteps(suite *godog.Suite) {
+	suite.Step(`Add tampered verifiable credential "([^"]*)" to Log and check that it is rejected$`,
+		s.addTamperedVC)
+	suite.Step(`Add verifiable credential "([^"]*)" padded to "([^"]*)" bytes to Log and check that it is rejected$`,
+		s.addOversizedVC)
+	suite.Step(`Add verifiable credential "([^"]*)" to Log again and check that the timestamp is the same$`,
+		s.addDuplicateVC)
+	suite.Step(`Check that the merkle audit proof for "([^"]*)" does not verify tampered data$`,
+		s.checkTamperedInclusion)
+	suite.Step(`Retrieve merkle audit proof from log by unknown leaf hash and check that it is rejected$`,
+		s.getProofByUnknownHash)
+	suite.Step(`Retrieve merkle audit proof from log by invalid leaf hash and check that it is rejected$`,
+		s.getProofByInvalidHash)
+	suite.Step(`Remember latest signed tree head$`, s.rememberSTH)
+	suite.Step(`Check that the merkle consistency proof from the remembered signed tree head does not verify `+
+		`forged signed tree heads$`, s.checkForgedSTHConsistency)
+	suite.Step(`Retrieve merkle consistency proof for an invalid range and check that it is rejected$`,
+		s.getSTHConsistencyInvalidRange)
+}
+
+func (s *Steps) addTamperedVC(file string) error {
+	src, err := s.readFile(file)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	var vc map[string]interface{}
+	if err = json.Unmarshal(src, &vc); err != nil {
+		return fmt.Errorf("unmarshal credential: %w", err)
+	}
+
+	if _, ok := vc["proof"]; !ok {
+		return fmt.Errorf("credential %s has no proof to be invalidated", file)
+	}
+
+	// the proof no longer matches the credential
+	vc["id"] = fmt.Sprintf("%v-tampered", vc["id"])
+
+	tampered, err := json.Marshal(vc)
+	if err != nil {
+		return fmt.Errorf("marshal credential: %w", err)
+	}
+
+	_, err = s.vct.AddVC(context.Background(), tampered)
+
+	return rejected(err, http.StatusBadRequest, "parse credential")
+}
+
+func (s *Steps) addOversizedVC(file, size string) error {
+	src, err := s.readFile(file)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	n, err := strconv.Atoi(size)
+	if err != nil {
+		return fmt.Errorf("size %q is not a number: %w", size, err)
+	}
+
+	var vc map[string]interface{}
+	if err = json.Unmarshal(src, &vc); err != nil {
+		return fmt.Errorf("unmarshal credential: %w", err)
+	}
+
+	if n > len(src) {
+		vc["padding"] = strings.Repeat("a", n-len(src))
+	}
+
+	oversized, err := json.Marshal(vc)
+	if err != nil {
+		return fmt.Errorf("marshal credential: %w", err)
+	}
+
+	_, err = s.vct.AddVC(context.Background(), oversized)
+
+	return rejected(err, http.StatusBadRequest, "exceeds the max size")
+}
+
+func (s *Steps) addDuplicateVC(file string) error {
+	added, ok := s.state.AddedCredentials[file]
+	if !ok {
+		return fmt.Errorf("credential %s was not added", file)
+	}
+
+	src, err := s.readFile(file)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	resp, err := s.vct.AddVC(context.Background(), src)
+	if err != nil {
+		return fmt.Errorf("add vc: %w", err)
+	}
+
+	// the duplicate is not logged again, the timestamp of the logged credential is returned
+	if resp.Timestamp != added.Timestamp {
+		return fmt.Errorf("expected timestamp %d of the logged credential, got %d", added.Timestamp, resp.Timestamp)
+	}
+
+	return nil
+}
+
+func (s *Steps) checkTamperedInclusion(file string) error {
+	hash, err := s.leafHash(file)
+	if err != nil {
+		return err
+	}
+
+	leafHash, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return fmt.Errorf("decode leaf hash: %w", err)
+	}
+
+	verifier := vct.NewVerifier(vct.DefaultHasher)
+
+	return backoff.Retry(func() error { // nolint: wrapcheck
+		sth, err := s.vct.GetSTH(context.Background())
+		if err != nil {
+			return fmt.Errorf("get STH: %w", err)
+		}
+
+		proof, err := s.vct.GetProofByHash(context.Background(), hash, sth.TreeSize)
+		if err != nil {
+			return fmt.Errorf("get proof by hash: %w", err)
+		}
+
+		treeSize := int64(sth.TreeSize)
+
+		err = verifier.VerifyInclusionProof(proof.LeafIndex, treeSize, proof.AuditPath, sth.SHA256RootHash, leafHash)
+		if err != nil {
+			return fmt.Errorf("verify inclusion proof: %w", err)
+		}
+
+		err = verifier.VerifyInclusionProof(proof.LeafIndex, treeSize, proof.AuditPath, sth.SHA256RootHash,
+			flip(leafHash))
+		if err == nil {
+			return backoff.Permanent(errors.New("inclusion proof verifies a tampered leaf"))
+		}
+
+		if len(proof.AuditPath) == 0 {
+			return nil
+		}
+
+		tamperedPath := append([][]byte{flip(proof.AuditPath[0])}, proof.AuditPath[1:]...)
+
+		err = verifier.VerifyInclusionProof(proof.LeafIndex, treeSize, tamperedPath, sth.SHA256RootHash, leafHash)
+		if err == nil {
+			return backoff.Permanent(errors.New("tampered inclusion proof verifies the leaf"))
+		}
+
+		return nil
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 15))
+}
+
+func (s *Steps) getProofByUnknownHash() error {
+	hash := make([]byte, sha256.Size)
+	if _, err := rand.Read(hash); err != nil {
+		return fmt.Errorf("random hash: %w", err)
+	}
+
+	sth, err := s.vct.GetSTH(context.Background())
+	if err != nil {
+		return fmt.Errorf("get STH: %w", err)
+	}
+
+	if sth.TreeSize == 0 {
+		return errors.New("the log is empty")
+	}
+
+	_, err = s.vct.GetProofByHash(context.Background(), base64.StdEncoding.EncodeToString(hash), sth.TreeSize)
+
+	return rejected(err, http.StatusNotFound, "")
+}
+
+func (s *Steps) getProofByInvalidHash() error {
+	_, err := s.vct.GetProofByHash(context.Background(), base64.StdEncoding.EncodeToString([]byte("hash")), 1)
+
+	return rejected(err, http.StatusBadRequest, "hash must be 32 bytes")
+}
+
+func (s *Steps) rememberSTH() error {
+	return backoff.Retry(func() error { // nolint: wrapcheck
+		sth, err := s.vct.GetSTH(context.Background())
+		if err != nil {
+			return fmt.Errorf("get STH: %w", err)
+		}
+
+		if sth.TreeSize == 0 {
+			return errors.New("the log is empty")
+		}
+
+		s.state.RememberedSTH = sth
+
+		return nil
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 15))
+}
+
+func (s *Steps) checkForgedSTHConsistency() error {
+	first := s.state.RememberedSTH
+	if first == nil {
+		return errors.New("no signed tree head was remembered")
+	}
+
+	verifier := vct.NewVerifier(vct.DefaultHasher)
+
+	return backoff.Retry(func() error { // nolint: wrapcheck
+		second, err := s.vct.GetSTH(context.Background())
+		if err != nil {
+			return fmt.Errorf("get STH: %w", err)
+		}
+
+		if second.TreeSize <= first.TreeSize {
+			return fmt.Errorf("tree size %d did not grow from %d", second.TreeSize, first.TreeSize)
+		}
+
+		resp, err := s.vct.GetSTHConsistency(context.Background(), first.TreeSize, second.TreeSize)
+		if err != nil {
+			return fmt.Errorf("get STH consistency: %w", err)
+		}
+
+		verify := func(root1, root2 []byte) error {
+			return verifier.VerifyConsistencyProof(int64(first.TreeSize), int64(second.TreeSize), root1, root2,
+				resp.Consistency)
+		}
+
+		if err = verify(first.SHA256RootHash, second.SHA256RootHash); err != nil {
+			return fmt.Errorf("verify consistency proof: %w", err)
+		}
+
+		if verify(flip(first.SHA256RootHash), second.SHA256RootHash) == nil {
+			return backoff.Permanent(errors.New("consistency proof verifies a forged first tree head"))
+		}
+
+		if verify(first.SHA256RootHash, flip(second.SHA256RootHash)) == nil {
+			return backoff.Permanent(errors.New("consistency proof verifies a forged second tree head"))
+		}
+
+		return nil
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 15))
+}
+
+func (s *Steps) getSTHConsistencyInvalidRange() error {
+	_, err := s.vct.GetSTHConsistency(context.Background(), 2, 1)
+
+	return rejected(err, http.StatusBadRequest, "is not a valid range")
+}
+
+// leafHash returns the leaf hash of the added credential.
+func (s *Steps) leafHash(file string) (string, error) {
+	added, ok := s.state.AddedCredentials[file]
+	if !ok {
+		return "", fmt.Errorf("credential %s was not added", file)
+	}
+
+	src, err := s.readFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	vc, err := verifiable.ParseCredential(src,
+		verifiable.WithDisabledProofCheck(),
+		verifiable.WithNoCustomSchemaCheck(),
+		verifiable.WithJSONLDDocumentLoader(getLoader()),
+	)
+	if err != nil {
+		return "", fmt.Errorf("parse credential: %w", err)
+	}
+
+	hash, err := vct.CalculateLeafHash(added.Timestamp, vc)
+	if err != nil {
+		return "", fmt.Errorf("calculate leaf hash from bytes: %w", err)
+	}
+
+	return hash, nil
+}
+
+// rejected checks the request was rejected with the status, the detail of the error contains the message.
+func rejected(err error, status int, msg string) error {
+	if err == nil {
+		return fmt.Errorf("request was accepted, expected a rejection with status %d", status)
+	}
+
+	var vctErr *vct.Error
+	if !errors.As(err, &vctErr) {
+		return fmt.Errorf("request failed: %w", err)
+	}
+
+	if vctErr.Status != status || !strings.Contains(vctErr.Detail, msg) {
+		return fmt.Errorf("expected a rejection with status %d and %q, got %d: %w", status, msg, vctErr.Status, err)
+	}
+
+	return nil
+}
+
+// flip returns a copy of the hash with its last bit flipped.
+func flip(hash []byte) []byte {
+	flipped := append([]byte(nil), hash...)
+	if len(flipped) > 0 {
+		flipped[len(flipped)-1] ^= 1
+	}
+
+	return flipped
+}
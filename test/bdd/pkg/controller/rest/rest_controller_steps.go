@@ -27,6 +27,7 @@ import (
 	"github.com/trustbloc/vct/internal/pkg/ldcontext"
 	"github.com/trustbloc/vct/pkg/client/vct"
 	"github.com/trustbloc/vct/pkg/controller/command"
+	"github.com/trustbloc/vct/pkg/testutil/vcgen"
 )
 
 //go:embed testdata/**/*.json
@@ -43,13 +44,19 @@ type state struct {
 	GetSTHResponse   *command.GetSTHResponse
 	LastEntries      []command.LeafEntry
 	AddedCredentials map[string]*command.AddVCResponse
+	RememberedSTH    *command.GetSTHResponse
+	// Generated are the credentials generated by vcgen, named "<seed>/<suite>/<index>".
+	Generated map[string][]byte
 }
 
 // New creates BDD test steps instance.
 func New() *Steps {
 	return &Steps{
 		client: &http.Client{Timeout: time.Minute},
-		state:  state{AddedCredentials: map[string]*command.AddVCResponse{}},
+		state: state{
+			AddedCredentials: map[string]*command.AddVCResponse{},
+			Generated:        map[string][]byte{},
+		},
 	}
 }
 
@@ -57,6 +64,11 @@ func New() *Steps {
 func (s *Steps) RegisterSteps(suite *godog.Suite) {
 	suite.Step(`VCT agent is running on "([^"]*)"$`, s.setVCTClient)
 	suite.Step(`Add verifiable credential "([^"]*)" to Log$`, s.addVC)
+	suite.Step(`Add "([^"]*)" credentials generated from "([^"]*)" signed with "([^"]*)" to Log$`, s.addGeneratedVCs)
+	suite.Step(`Add "([^"]*)" credentials generated from "([^"]*)" signed with "([^"]*)" and issued by "([^"]*)" `+
+		`to Log$`, s.addIssuedVCs)
+	suite.Step(`Generate "([^"]*)" credentials from "([^"]*)" signed with "([^"]*)" and issued by "([^"]*)"$`,
+		s.generateVCs)
 	suite.Step(`No permissions to write$`, s.noWritePerm)
 	suite.Step(`No permissions to read$`, s.noReadPerm)
 	suite.Step(`Retrieve latest signed tree head and check that tree_size is "([^"]*)"$`, s.getSTH)
@@ -66,6 +78,8 @@ func (s *Steps) RegisterSteps(suite *godog.Suite) {
 	suite.Step(`Retrieve merkle audit proof from log by leaf hash for "([^"]*)"$`, s.getProofByHash)
 	suite.Step(`The issuer "([^"]*)" is supported$`, s.issuerIsSupported)
 	suite.Step(`The issuer "([^"]*)" is not supported$`, s.issuerIsNotSupported)
+
+	s.registerAdversarialSteps(suite)
 }
 
 func (s *Steps) issuerIsSupported(issuer string) error {
@@ -199,7 +213,7 @@ func (s *Steps) noReadPerm() error {
 }
 
 func (s *Steps) addVC(file string) error {
-	src, err := readFile(file)
+	src, err := s.readFile(file)
 	if err != nil {
 		return fmt.Errorf("read file: %w", err)
 	}
@@ -238,30 +252,90 @@ func (s *Steps) addVC(file string) error {
 	return nil
 }
 
-func (s *Steps) setTimestamp(from, to string) error {
-	s.state.AddedCredentials[to] = s.state.AddedCredentials[from]
+// addGeneratedVCs generates the credentials of the seed signed with the suite and adds them to the log, they are
+// named "<seed>/<suite>/<index>" in the following steps.
+func (s *Steps) addGeneratedVCs(count, seed, suite string) error {
+	return s.addIssuedVCs(count, seed, suite, "")
+}
+
+// addIssuedVCs generates the credentials of the seed issued by the issuer derived from the issuer seed and adds them
+// to the log, e.g. to a log which accepts the credentials of its issuers only.
+func (s *Steps) addIssuedVCs(count, seed, suite, issuer string) error {
+	names, err := s.generate(count, seed, suite, issuer)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err = s.addVC(name); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (s *Steps) getProofByHash(file string) error {
-	src, err := readFile(file)
+// generateVCs generates the credentials without adding them to the log, e.g. to be tampered with.
+func (s *Steps) generateVCs(count, seed, suite, issuer string) error {
+	_, err := s.generate(count, seed, suite, issuer)
+
+	return err
+}
+
+// generate generates the credentials of the seed signed with the suite and returns their names. The credentials are
+// issued by the first issuer derived from the issuer seed, or by the issuer derived from the seed if it is empty.
+func (s *Steps) generate(count, seed, suite, issuer string) ([]string, error) {
+	n, err := strconv.Atoi(count)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("count: %w", err)
 	}
 
-	vc, err := verifiable.ParseCredential(src,
-		verifiable.WithDisabledProofCheck(),
-		verifiable.WithNoCustomSchemaCheck(),
-		verifiable.WithJSONLDDocumentLoader(getLoader()),
-	)
+	opts := []vcgen.Opt{vcgen.WithSuites(vcgen.Suite(suite)), vcgen.WithIssuanceDate(time.Now())}
+
+	if issuer != "" {
+		issuers, err := vcgen.New(issuer)
+		if err != nil {
+			return nil, fmt.Errorf("create issuer: %w", err)
+		}
+
+		i := issuers.Issuers()[0]
+
+		opts = append(opts, vcgen.WithIssuer(i.DID, i.KeyID, i.Ed25519))
+	}
+
+	g, err := vcgen.New(seed, opts...)
 	if err != nil {
-		return fmt.Errorf("parse credential: %w", err)
+		return nil, fmt.Errorf("create generator: %w", err)
 	}
 
-	hash, err := vct.CalculateLeafHash(s.state.AddedCredentials[file].Timestamp, vc)
+	credentials, err := g.Credentials(n)
 	if err != nil {
-		return fmt.Errorf("calculate leaf hash from bytes: %w", err)
+		return nil, fmt.Errorf("generate credentials: %w", err)
+	}
+
+	names := make([]string, 0, len(credentials))
+
+	for i, credential := range credentials {
+		name := fmt.Sprintf("%s/%s/%d", seed, suite, i)
+
+		s.state.Generated[name] = credential
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+func (s *Steps) setTimestamp(from, to string) error {
+	s.state.AddedCredentials[to] = s.state.AddedCredentials[from]
+
+	return nil
+}
+
+func (s *Steps) getProofByHash(file string) error {
+	hash, err := s.leafHash(file)
+	if err != nil {
+		return err
 	}
 
 	return backoff.Retry(func() error { // nolint: wrapcheck
@@ -350,7 +424,12 @@ func (s *Steps) getSTH(treeSize string) error {
 	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Second), 15))
 }
 
-func readFile(msgFile string) ([]byte, error) {
+// readFile returns the credential generated with the name or the content of the test data file.
+func (s *Steps) readFile(msgFile string) ([]byte, error) {
+	if credential, ok := s.state.Generated[msgFile]; ok {
+		return credential, nil
+	}
+
 	return fs.ReadFile(filepath.Clean(strings.Join([]string{ // nolint: wrapcheck
 		"testdata", msgFile,
 	}, string(filepath.Separator))))